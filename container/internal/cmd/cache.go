@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/vanpelt/catnip/internal/models"
+	"github.com/vanpelt/catnip/internal/services"
+)
+
+var cacheMaxSizeMB int64
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "📦 Show the package caches shared across worktrees",
+	Long: `# 📦 Shared Package Caches
+
+Worktree sessions and setup scripts share npm, pnpm, cargo, go and pip caches on the volume,
+so new worktrees don't download their dependencies again.
+
+Repositories with hermeticity requirements can opt out:
+` + "```bash\ngit config catnip.cache.shared false\n```" + `
+
+The combined size is capped by ` + "`git config --global catnip.cache.max-size-mb`" + ` (default 20480, ` + "`0`" + ` uncapped).`,
+	Example: `  # Show the size of every cache
+  catnip cache`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		printCacheReport(services.DependencyCacheStatus(services.DependencyCacheRoot(), services.GlobalDependencyCacheMaxSizeKB()))
+	},
+}
+
+var cacheTrimCmd = &cobra.Command{
+	Use:   "trim",
+	Short: "🧹 Remove least recently used cache entries until the caches fit their cap",
+	Example: `  # Trim to the configured cap
+  catnip cache trim
+
+  # Trim to 5 GiB
+  catnip cache trim --max-size-mb 5120`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		maxSizeKB := services.GlobalDependencyCacheMaxSizeKB()
+		if cmd.Flags().Changed("max-size-mb") {
+			maxSizeKB = cacheMaxSizeMB << 10
+		}
+		report := services.TrimDependencyCaches(services.DependencyCacheRoot(), maxSizeKB)
+		for _, entry := range report.Removed {
+			fmt.Printf("🗑️  %s\n", entry)
+		}
+		fmt.Printf("🧹 Freed %d MiB\n\n", report.FreedKB>>10)
+		printCacheReport(report)
+	},
+}
+
+func printCacheReport(report *models.DependencyCacheReport) {
+	for _, cache := range report.Caches {
+		lastUsed := "never"
+		if cache.LastUsed != nil {
+			lastUsed = cache.LastUsed.Format("2006-01-02 15:04")
+		}
+		fmt.Printf("%-6s %8d MiB  last used %s  %s\n", cache.Name, cache.SizeKB>>10, lastUsed, cache.Path)
+	}
+	if report.MaxSizeKB == 0 {
+		fmt.Printf("\nTotal: %d MiB (uncapped)\n", report.TotalKB>>10)
+	} else {
+		fmt.Printf("\nTotal: %d of %d MiB\n", report.TotalKB>>10, report.MaxSizeKB>>10)
+	}
+}
+
+func init() {
+	cacheTrimCmd.Flags().Int64Var(&cacheMaxSizeMB, "max-size-mb", 0, "Cap to trim to instead of catnip.cache.max-size-mb")
+	cacheCmd.AddCommand(cacheTrimCmd)
+	rootCmd.AddCommand(cacheCmd)
+}
@@ -13,6 +13,7 @@ import (
 
 	"github.com/spf13/cobra"
 	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/services"
 )
 
 // ClaudeSettings represents Claude Code settings structure
@@ -286,6 +287,9 @@ func runHook(cmd *cobra.Command, args []string) error {
 	}
 
 	req.Header.Set("Content-Type", "application/json")
+	if token := services.ReadAPIToken(); token != "" {
+		req.Header.Set(services.APITokenHeader, token)
+	}
 
 	resp, err := client.Do(req)
 	if err != nil {
@@ -0,0 +1,276 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/services"
+)
+
+var (
+	maintJSON        bool
+	maintLockWait    time.Duration
+	maintDryRun      bool
+	maintExportOut   string
+	maintOlderThan   time.Duration
+	maintPruneDryRun bool
+	maintMissingMode string
+)
+
+var maintCmd = &cobra.Command{
+	Use:   "maint",
+	Short: "🔧 Run maintenance on the workspace volume without the server",
+	Long: `# 🔧 Maintenance
+
+Runs the server's cleanup, reconciliation, export and pruning directly against the workspace
+volume, for cron jobs and CI. The commands read the same environment (` + "`CATNIP_VOLUME_DIR`" + `,
+` + "`CATNIP_WORKSPACE_DIR`" + `, ...) and git config as the server.
+
+A running server holds a lock on the volume; maintenance commands fail rather than change state
+underneath it, and servers starting in the meantime wait for them to finish.
+
+Pass ` + "`--json`" + ` for machine readable output. Commands exit nonzero when they fail.`,
+	Example: `  # Show what cleanup would remove
+  catnip maint cleanup --dry-run
+
+  # Back up the persisted state
+  catnip maint export --out state.tgz
+
+  # Remove repositories unused for 30 days
+  catnip maint prune-repos --older-than 720h --json`,
+}
+
+var maintCleanupCmd = &cobra.Command{
+	Use:   "cleanup",
+	Short: "🧹 Remove unused catnip branches, orphaned refs and config mappings",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withMaintenanceService(func(s *services.GitService) error {
+			report := s.CleanupAllCatnipRefs(maintDryRun)
+			if maintJSON {
+				return printMaintJSON(report)
+			}
+			verb := "Removed"
+			if report.DryRun {
+				verb = "Would remove"
+			}
+			printCleanupItems(verb, "branch", report.Branches)
+			printCleanupItems(verb, "preview branch", report.PreviewBranches)
+			printCleanupItems(verb, "remote branch", report.RemoteBranches)
+			printCleanupItems(verb, "ref", report.Refs)
+			printCleanupItems(verb, "config mapping", report.ConfigMappings)
+			fmt.Printf("🧹 %s %d branches, %d preview branches, %d remote branches, %d refs and %d config mappings\n", verb,
+				len(report.Branches), len(report.PreviewBranches), len(report.RemoteBranches), len(report.Refs), len(report.ConfigMappings))
+			for _, item := range report.Errors {
+				fmt.Printf("⚠️  %s %s: %s\n", item.RepoID, item.Name, item.Error)
+			}
+			return nil
+		})
+	},
+}
+
+var maintReconcileCmd = &cobra.Command{
+	Use:   "reconcile",
+	Short: "🔄 Reconcile persisted state with the worktrees on disk",
+	Long: `# 🔄 Reconcile
+
+Checks every repository is still on disk and recreates worktrees whose directory is missing,
+as the server does on boot. Worktrees whose repository is gone are pruned from state and
+directories that aren't git checkouts are reported as stale.
+
+Worktrees are first compared with ` + "`git worktree list`" + `: worktrees git no longer knows, e.g.
+after ` + "`git worktree remove`" + ` ran in a shell, are flagged missing instead of recreated, or removed
+from state with ` + "`--missing remove`" + ` (default from ` + "`CATNIP_RECONCILE_MISSING`" + `). Git
+worktrees catnip doesn't know are imported. Exits nonzero when worktrees remain missing.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withMaintenanceService(func(s *services.GitService) error {
+			report, err := s.Reconcile(maintMissingMode)
+			if err != nil {
+				return err
+			}
+			if maintJSON {
+				if err := printMaintJSON(report); err != nil {
+					return err
+				}
+			} else {
+				for _, id := range report.UnavailableRepositories {
+					fmt.Printf("⚠️  Repository %s is unavailable\n", id)
+				}
+				for _, name := range report.Restored {
+					fmt.Printf("✅ Restored %s\n", name)
+				}
+				for _, name := range report.Missing {
+					fmt.Printf("❌ Missing %s\n", name)
+				}
+				for _, name := range report.Pruned {
+					fmt.Printf("🧹 Pruned %s, its repository is gone\n", name)
+				}
+				for _, name := range report.Stale {
+					fmt.Printf("⚠️  Stale %s, its directory is missing or isn't a git checkout\n", name)
+				}
+				for _, entry := range report.GitWorktrees.Added {
+					fmt.Printf("📥 Imported %s from git\n", entry.Name)
+				}
+				for _, entry := range report.GitWorktrees.Recovered {
+					fmt.Printf("✅ %s is registered with git again\n", entry.Name)
+				}
+				for _, entry := range report.GitWorktrees.Removed {
+					fmt.Printf("🧹 Removed %s: %s\n", entry.Name, entry.Reason)
+				}
+				for _, entry := range report.GitWorktrees.Flagged {
+					fmt.Printf("⚠️  Flagged %s: %s\n", entry.Name, entry.Reason)
+				}
+				fmt.Printf("🔄 %d repositories, %d worktrees: %d restored, %d missing\n",
+					report.Repositories, report.Worktrees, len(report.Restored), len(report.Missing))
+			}
+			if len(report.Missing) > 0 {
+				return fmt.Errorf("%d worktrees could not be restored", len(report.Missing))
+			}
+			return nil
+		})
+	},
+}
+
+var maintExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "📦 Archive the persisted state",
+	Long: `# 📦 Export State
+
+Writes the persisted repository, worktree, pull request and activity state to a gzipped
+tarball. Repositories, worktree checkouts and caches are not included.`,
+	Example: `  catnip maint export --out state.tgz`,
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withMaintenanceService(func(s *services.GitService) error {
+			out, err := filepath.Abs(maintExportOut)
+			if err != nil {
+				return err
+			}
+			// Write next to the destination first so a failed export never leaves a partial archive
+			tmp, err := os.CreateTemp(filepath.Dir(out), ".catnip-state-*.tgz")
+			if err != nil {
+				return fmt.Errorf("failed to create %s: %v", out, err)
+			}
+			defer os.Remove(tmp.Name())
+
+			files, err := s.ExportState(tmp)
+			if closeErr := tmp.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				return err
+			}
+			if err := os.Rename(tmp.Name(), out); err != nil {
+				return fmt.Errorf("failed to write %s: %v", out, err)
+			}
+
+			if maintJSON {
+				return printMaintJSON(map[string]interface{}{"path": out, "files": files})
+			}
+			fmt.Printf("📦 Exported %d state files to %s\n", len(files), out)
+			return nil
+		})
+	},
+}
+
+var maintPruneReposCmd = &cobra.Command{
+	Use:   "prune-repos",
+	Short: "🗑️  Remove cloned repositories no worktree has used recently",
+	Long: `# 🗑️ Prune Repositories
+
+Removes cloned repositories, with all their worktrees, that haven't been used for
+` + "`--older-than`" + `. Local repositories are never removed, and repositories with uncommitted
+changes or commits that aren't on any remote are kept. Exits nonzero when a removal fails.`,
+	Example: `  # Remove repositories unused for 30 days
+  catnip maint prune-repos --older-than 720h`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if maintOlderThan <= 0 {
+			return errors.New("--older-than must be positive")
+		}
+		return withMaintenanceService(func(s *services.GitService) error {
+			report := s.PruneRepositories(maintOlderThan, maintPruneDryRun)
+			if maintJSON {
+				if err := printMaintJSON(report); err != nil {
+					return err
+				}
+			} else {
+				verb := "Removed"
+				if report.DryRun {
+					verb = "Would remove"
+				}
+				for _, repo := range report.Removed {
+					fmt.Printf("🗑️  %s %s (%d worktrees, last used %s)\n", verb, repo.RepoID, repo.Worktrees, repo.LastUsed.Format("2006-01-02"))
+				}
+				for _, repo := range report.Skipped {
+					fmt.Printf("🔒 Kept %s: %s\n", repo.RepoID, repo.Reason)
+				}
+				for _, repo := range report.Failed {
+					fmt.Printf("❌ Failed to remove %s: %s\n", repo.RepoID, repo.Reason)
+				}
+				fmt.Printf("🗑️  %s %d repositories, kept %d\n", verb, len(report.Removed), len(report.Skipped))
+			}
+			if len(report.Failed) > 0 {
+				return fmt.Errorf("failed to remove %d repositories", len(report.Failed))
+			}
+			return nil
+		})
+	},
+}
+
+// withMaintenanceService runs fn with a Git service on the volume while holding the exclusive
+// instance lock, so no server changes the same state meanwhile
+func withMaintenanceService(fn func(s *services.GitService) error) error {
+	logger.Configure(logger.GetLogLevelFromEnv(false), true)
+
+	lock, err := services.AcquireInstanceLock(services.InstanceLockFile(), true, maintLockWait)
+	if err != nil {
+		if errors.Is(err, services.ErrInstanceLocked) {
+			return fmt.Errorf("%v; stop the catnip server or retry with --wait", err)
+		}
+		return err
+	}
+	defer lock.Release()
+
+	s := services.NewMaintenanceGitService()
+	defer s.Stop()
+	return fn(s)
+}
+
+func printCleanupItems(verb, kind string, items []services.CleanupItem) {
+	for _, item := range items {
+		fmt.Printf("🗑️  %s %s %s in %s\n", verb, kind, item.Name, item.RepoID)
+	}
+}
+
+func printMaintJSON(v interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+func init() {
+	maintCmd.PersistentFlags().BoolVar(&maintJSON, "json", false, "Print results as JSON")
+	maintCmd.PersistentFlags().DurationVar(&maintLockWait, "wait", 0, "How long to wait for a running server to release the volume")
+
+	maintCleanupCmd.Flags().BoolVar(&maintDryRun, "dry-run", false, "Only report what would be removed")
+	maintReconcileCmd.Flags().StringVar(&maintMissingMode, "missing", "", "What to do with worktrees git no longer knows: flag or remove")
+	maintExportCmd.Flags().StringVar(&maintExportOut, "out", "", "Path of the archive to write")
+	_ = maintExportCmd.MarkFlagRequired("out")
+	maintPruneReposCmd.Flags().DurationVar(&maintOlderThan, "older-than", 720*time.Hour, "Remove repositories unused for longer than this")
+	maintPruneReposCmd.Flags().BoolVar(&maintPruneDryRun, "dry-run", false, "Only report what would be removed")
+
+	for _, sub := range []*cobra.Command{maintCleanupCmd, maintReconcileCmd, maintExportCmd, maintPruneReposCmd} {
+		sub.SilenceUsage = true
+		sub.SilenceErrors = true
+		maintCmd.AddCommand(sub)
+	}
+	rootCmd.AddCommand(maintCmd)
+}
@@ -223,6 +223,15 @@ func runContainer(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	// Share the host API token with the container so the TUI and host tools can authenticate
+	if os.Getenv(services.NoAuthEnv) == "true" {
+		processedEnvVars = append(processedEnvVars, fmt.Sprintf("%s=true", services.NoAuthEnv))
+	} else if token, _, err := services.LoadOrCreateAPIToken(services.GetAPITokenPath()); err == nil {
+		processedEnvVars = append(processedEnvVars, fmt.Sprintf("%s=%s", services.APITokenEnv, token))
+	} else {
+		logger.Warnf("⚠️ Failed to load API token, TUI requests may be rejected: %v", err)
+	}
+
 	// Determine container image
 	containerImage := image
 	if dev {
@@ -3,6 +3,7 @@ package cmd
 import (
 	"os"
 	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
@@ -14,9 +15,13 @@ import (
 	"github.com/vanpelt/catnip/internal/handlers"
 	"github.com/vanpelt/catnip/internal/logger"
 	"github.com/vanpelt/catnip/internal/models"
+	"github.com/vanpelt/catnip/internal/recovery"
 	"github.com/vanpelt/catnip/internal/services"
 )
 
+// serveLockWait bounds how long a starting server waits for maintenance commands to release the volume
+const serveLockWait = 10 * time.Minute
+
 var serveCmd = &cobra.Command{
 	Use:    "serve",
 	Short:  "🚀 Start the Catnip server",
@@ -68,6 +73,10 @@ func startServer(cmd *cobra.Command) {
 		}
 	}
 
+	if config.Runtime.BaseURL != "" {
+		logger.Infof("🌐 External URL: %s (base path %q)", config.Runtime.BaseURL, config.Runtime.BasePath())
+	}
+
 	// Start settings persistence manager in containerized environments
 	var settings *models.Settings
 	if config.Runtime.IsContainerized() {
@@ -76,6 +85,17 @@ func startServer(cmd *cobra.Command) {
 		defer settings.Stop()
 	}
 
+	// Track panics of background goroutines from the start, so none go unnoticed
+	healthHandler := handlers.NewHealthHandler()
+	defer recovery.RegisterPanicHook(healthHandler.RecordPanic)()
+
+	// Share the volume with other servers, but wait for maintenance commands to finish
+	instanceLock, err := services.AcquireInstanceLock(services.InstanceLockFile(), false, serveLockWait)
+	if err != nil {
+		logger.Fatalf("❌ %v", err)
+	}
+	defer instanceLock.Release()
+
 	// Initialize Git service (but don't defer Stop() yet, as we need to set up dependencies first)
 	gitService := services.NewGitService()
 	defer gitService.Stop()
@@ -103,7 +123,7 @@ func startServer(cmd *cobra.Command) {
 				}
 
 				logger.Infof("📦 Auto-checkout: %s/%s (branch: %s)", org, repo, branch)
-				if _, _, err := gitService.CheckoutRepository(org, repo, branch); err != nil {
+				if _, _, err := gitService.CheckoutRepository(org, repo, branch, services.DefaultCloneOptions()); err != nil {
 					logger.Errorf("❌ Failed to checkout repository: %v", err)
 				}
 			}
@@ -116,17 +136,19 @@ func startServer(cmd *cobra.Command) {
 	})
 
 	// Middleware
+	// Strip the CATNIP_BASE_URL subpath first so routing works behind a reverse proxy
+	app.Use(handlers.BasePathMiddleware())
 	app.Use(handlers.SamplingLogger())
 	app.Use(recover.New())
-	app.Use(cors.New(cors.Config{
-		AllowOrigins: "*",
-		AllowHeaders: "Origin, Content-Type, Accept",
-	}))
-
-	// Health check
-	app.Get("/health", func(c *fiber.Ctx) error {
-		return c.JSON(fiber.Map{"status": "ok"})
-	})
+	app.Use(cors.New(handlers.CORSConfig()))
+	app.Use(handlers.ActorMiddleware())
+
+	// Health check (registered before auth so probes never need a token)
+	app.Get("/health", healthHandler.GetHealth)
+
+	// Token authentication for the API, PTY websockets, SSE and git smart HTTP
+	tokenAuth := handlers.NewTokenAuth()
+	app.Use(tokenAuth.Middleware())
 
 	// Settings endpoint - returns environment configuration
 	app.Get("/v1/settings", func(c *fiber.Ctx) error {
@@ -168,6 +190,7 @@ func startServer(cmd *cobra.Command) {
 	// Wire up the setup executor to enable setup.sh execution in new worktrees
 	logger.Debugf("🔧 Setting up setupExecutor for gitService")
 	gitService.SetSetupExecutor(ptyHandler)
+	gitService.SetWorktreeSessionChecker(ptyHandler)
 	logger.Debugf("✅ setupExecutor configured successfully")
 
 	// Wire up the claude monitor to git service
@@ -181,7 +204,7 @@ func startServer(cmd *cobra.Command) {
 
 	// Clean up orphaned catnip refs on startup
 	logger.Debugf("🧹 Running startup cleanup of orphaned catnip refs")
-	gitService.CleanupAllCatnipRefs()
+	gitService.CleanupAllCatnipRefs(false)
 
 	// Now initialize local repositories with setup executor properly configured
 	gitService.InitializeLocalRepos()
@@ -207,6 +230,7 @@ func startServer(cmd *cobra.Command) {
 	defer eventsHandler.Stop()
 	portsHandler := handlers.NewPortsHandler(portMonitor).WithEvents(eventsHandler)
 	proxyHandler := handlers.NewProxyHandler(portMonitor)
+	healthHandler.WithEvents(eventsHandler).WithGitService(gitService)
 
 	// Connect events handler to GitService for worktree status events
 	gitService.SetEventsHandler(eventsHandler)
@@ -230,6 +254,7 @@ func startServer(cmd *cobra.Command) {
 	v1.Post("/pty/prompt", ptyHandler.HandlePTYPrompt)
 
 	// Auth routes
+	v1.Post("/auth/token/rotate", tokenAuth.RotateToken)
 	v1.Post("/auth/github/start", authHandler.StartGitHubAuth)
 	v1.Get("/auth/github/status", authHandler.GetAuthStatus)
 	v1.Post("/auth/github/reset", authHandler.ResetAuthState)
@@ -239,24 +264,120 @@ func startServer(cmd *cobra.Command) {
 
 	// Git routes
 	v1.Post("/git/checkout/:org/:repo", gitHandler.CheckoutRepository)
+	v1.Post("/git/checkout/:org/:repo/branches", gitHandler.CheckoutBranches)
 	v1.Get("/git/status", gitHandler.GetStatus)
+	v1.Post("/git/status/state-report/acknowledge", gitHandler.AcknowledgeStateReport)
 	v1.Get("/git/worktrees", gitHandler.ListWorktrees)
+	v1.Get("/git/worktrees/summary", gitHandler.GetWorktreesSummary)
 	v1.Patch("/git/worktrees/:id", gitHandler.UpdateWorktree)
 	v1.Delete("/git/worktrees/:id", gitHandler.DeleteWorktree)
 	v1.Post("/git/worktrees/cleanup", gitHandler.CleanupMergedWorktrees)
+	v1.Post("/git/worktrees/reconcile", gitHandler.ReconcileWorktrees)
 	v1.Post("/git/worktrees/:id/sync", gitHandler.SyncWorktree)
+	v1.Post("/git/worktrees/:id/stash", gitHandler.StashWorktree)
+	v1.Post("/git/worktrees/:id/unstash", gitHandler.UnstashWorktree)
+	v1.Get("/git/worktrees/:id/backups", gitHandler.ListOperationBackups)
+	v1.Post("/git/worktrees/:id/backups/:backupId/restore", gitHandler.RestoreOperationBackup)
 	v1.Get("/git/worktrees/:id/sync/check", gitHandler.CheckSyncConflicts)
+	v1.Post("/git/worktrees/:id/sync/continue", gitHandler.ContinueSync)
+	v1.Post("/git/worktrees/:id/sync/abort", gitHandler.AbortSync)
+	v1.Post("/git/worktrees/:id/cherry-pick", gitHandler.CherryPickCommits)
+	v1.Post("/git/worktrees/:id/cherry-pick/abort", gitHandler.AbortCherryPick)
+	v1.Post("/git/worktrees/:id/patch", gitHandler.ApplyPatch)
+	v1.Post("/git/worktrees/:id/patch/uploads", gitHandler.BeginPatchUpload)
+	v1.Put("/git/worktrees/:id/patch/uploads/:upload", gitHandler.AppendPatchUpload)
+	v1.Post("/git/worktrees/:id/patch/uploads/:upload/apply", gitHandler.ApplyPatchUpload)
+	v1.Delete("/git/worktrees/:id/patch/uploads/:upload", gitHandler.DiscardPatchUpload)
+	v1.Post("/git/worktrees/:id/tags", gitHandler.CreateWorktreeTag)
+	v1.Post("/git/worktrees/:id/tags/push", gitHandler.PushWorktreeTag)
+	v1.Get("/git/worktrees/:id/conflicts", gitHandler.GetWorktreeConflicts)
+	v1.Post("/git/worktrees/:id/conflicts/resolve", gitHandler.ResolveWorktreeConflict)
 	v1.Post("/git/worktrees/:id/merge", gitHandler.MergeWorktreeToMain)
+	v1.Post("/git/worktrees/:id/validate", gitHandler.ValidateWorktree)
 	v1.Get("/git/worktrees/:id/merge/check", gitHandler.CheckMergeConflicts)
+	v1.Post("/git/worktrees/:id/merge/prepare", gitHandler.PrepareMerge)
+	v1.Post("/git/merges/:token/complete", gitHandler.CompleteMerge)
 	v1.Get("/git/worktrees/:id/diff", gitHandler.GetWorktreeDiff)
+	v1.Get("/git/worktrees/:id/diff/summary", gitHandler.GetWorktreeDiffSummary)
+	v1.Get("/git/worktrees/:id/diff/file", gitHandler.GetWorktreeFileDiff)
+	v1.Get("/git/worktrees/:id/commits", gitHandler.GetWorktreeCommits)
+	v1.Get("/git/diff-cache/stats", func(c *fiber.Ctx) error {
+		return c.JSON(gitService.GetDiffCacheStats())
+	})
+	v1.Get("/git/watches/stats", func(c *fiber.Ctx) error {
+		return c.JSON(services.FileWatchStatus())
+	})
+	v1.Get("/git/worktrees/:id/operation-estimates", gitHandler.GetOperationEstimates)
+	v1.Get("/git/worktrees/:id/bundle", gitHandler.GetWorktreeBundle)
 	v1.Post("/git/worktrees/:id/preview", gitHandler.CreateWorktreePreview)
 	v1.Post("/git/worktrees/:id/pr", gitHandler.CreatePullRequest)
+	v1.Post("/git/worktrees/:id/pr/preview", gitHandler.PreviewPullRequestBody)
+	v1.Post("/git/pull-requests/bulk", gitHandler.CreatePullRequestsBulk)
+	v1.Post("/git/signing-keys", gitHandler.ImportSigningKey)
+	v1.Post("/git/worktree-groups", gitHandler.CreateWorktreeGroup)
+	v1.Get("/git/worktree-groups", gitHandler.ListWorktreeGroups)
+	v1.Get("/git/worktree-groups/:id", gitHandler.GetWorktreeGroup)
+	v1.Post("/git/worktree-groups/:id/sync", gitHandler.SyncWorktreeGroup)
+	v1.Post("/git/worktree-groups/:id/pr", gitHandler.CreateWorktreeGroupPullRequests)
+	v1.Post("/git/worktree-groups/:id/merge", gitHandler.MergeWorktreeGroup)
+	v1.Delete("/git/worktree-groups/:id", gitHandler.DeleteWorktreeGroup)
+	v1.Get("/git/archives", gitHandler.ListWorktreeArchives)
+	v1.Post("/git/archives/:id/restore", gitHandler.RestoreWorktreeArchive)
 	v1.Put("/git/worktrees/:id/pr", gitHandler.UpdatePullRequest)
 	v1.Get("/git/worktrees/:id/pr", gitHandler.GetPullRequestInfo)
+	v1.Get("/git/github/rate-limit", gitHandler.GetGitHubRateLimit)
 	v1.Post("/git/worktrees/:id/graduate", gitHandler.GraduateBranch)
 	v1.Post("/git/worktrees/:id/refresh", gitHandler.RefreshWorktreeStatus)
+	v1.Post("/git/worktrees/:id/recreate", gitHandler.RecreateWorktree)
+	v1.Post("/git/worktrees/:id/rename", gitHandler.RenameWorktree)
+	v1.Put("/git/worktrees/:id/source-branch", gitHandler.SetWorktreeSourceBranch)
+	v1.Post("/git/worktrees/:id/remote-config/repair", gitHandler.RepairWorktreeRemoteConfig)
+	v1.Post("/git/worktrees/:id/export", gitHandler.ExportWorktree)
+	v1.Get("/git/worktrees/:id/context-pack", gitHandler.GetWorktreeContextPack)
+	v1.Post("/git/worktrees/:id/context-pack", gitHandler.WriteWorktreeContextPack)
+	v1.Post("/git/worktrees/:id/branch-drift/acknowledge", gitHandler.AcknowledgeBranchDrift)
+	v1.Post("/git/worktrees/:id/toolchains/detect", gitHandler.DetectWorktreeToolchains)
+	v1.Get("/git/worktrees/:id/health", gitHandler.GetWorktreeHealth)
+	v1.Put("/git/worktrees/:id/checkpoint-config", gitHandler.SetWorktreeCheckpointConfig)
+	v1.Put("/git/worktrees/:id/handoff", gitHandler.SetWorktreeHandoff)
+	v1.Put("/git/worktrees/:id/checkpoint-hooks", gitHandler.SetWorktreeCheckpointHooks)
+	v1.Put("/git/worktrees/:id/secret-keys", gitHandler.SetWorktreeSecretKeys)
+	v1.Post("/git/worktrees/:id/setup", gitHandler.RunWorktreeSetup)
+	v1.Get("/git/worktrees/:id/setup/log", gitHandler.GetWorktreeSetupLog)
+	v1.Get("/git/worktrees/:id/debug/timeline", gitHandler.GetWorktreeDebugTimeline)
+	v1.Get("/git/caches", gitHandler.GetDependencyCaches)
+	v1.Post("/git/caches/trim", gitHandler.TrimDependencyCaches)
+	v1.Post("/git/worktrees/:id/ignore-suggestions/apply", gitHandler.ApplyIgnoreSuggestion)
+	v1.Post("/git/worktrees/:id/ignore-suggestions/dismiss", gitHandler.DismissIgnoreSuggestion)
 	v1.Get("/git/github/repos", gitHandler.ListGitHubRepositories)
 	v1.Post("/git/repositories/:id/github", gitHandler.CreateGitHubRepository)
+	v1.Put("/git/repositories/:id/mirrors", gitHandler.SetRepositoryMirrors)
+	v1.Post("/git/repositories/:id/mirrors/sync", gitHandler.SyncRepositoryMirrors)
+	v1.Get("/git/repositories/:id/issue-automation", gitHandler.GetIssueAutomation)
+	v1.Put("/git/repositories/:id/issue-automation", gitHandler.SetIssueAutomation)
+	v1.Post("/git/repositories/:id/issue-automation/poll", gitHandler.PollIssueAutomation)
+	v1.Get("/git/repositories/:id/pr-template", gitHandler.GetPullRequestTemplateSettings)
+	v1.Put("/git/repositories/:id/pr-template", gitHandler.SetPullRequestTemplateSettings)
+	v1.Post("/git/repositories/:id/migrate-legacy-refs", gitHandler.MigrateLegacyRefs)
+	v1.Post("/git/repositories/:id/cleanup", gitHandler.CleanupRepository)
+	v1.Post("/git/repositories/:id/migrate-rename", gitHandler.MigrateRenamedRepository)
+	v1.Post("/git/credentials/repair", gitHandler.RepairCredentials)
+	v1.Get("/git/repositories/:id/remote-config", gitHandler.GetRemoteConfig)
+	v1.Post("/git/repositories/:id/remote-config/repair", gitHandler.RepairRepositoryRemoteConfig)
+	v1.Post("/git/repositories/:id/size/refresh", gitHandler.RefreshRepositorySize)
+	v1.Post("/git/repositories/:id/maintenance", gitHandler.TriggerRepositoryMaintenance)
+	v1.Put("/git/repositories/:id/mirror-mode", gitHandler.SetRepositoryMirrorMode)
+	v1.Put("/git/repositories/:id/setup-commands", gitHandler.SetRepositorySetupCommands)
+	v1.Get("/git/disk-usage", gitHandler.GetDiskUsage)
+	v1.Get("/git/repositories/:id/activity", gitHandler.GetRepositoryActivity)
+	v1.Get("/git/repositories/:id/merges", gitHandler.GetMergeLedger)
+	v1.Get("/git/repositories/:id/merges/:commit", gitHandler.GetMergeByCommit)
+	v1.Get("/git/repositories/:id/tags", gitHandler.ListTags)
+	v1.Get("/git/repositories/:id/orphan-branches", gitHandler.ListOrphanBranches)
+	v1.Post("/git/repositories/:id/orphan-branches/adopt", gitHandler.AdoptOrphanBranch)
+	v1.Delete("/git/repositories/:id/tags/:tag", gitHandler.DeleteTag)
+	v1.Get("/git/operations", gitHandler.ListOperations)
+	v1.Get("/git/operations/:id", gitHandler.GetOperation)
 	v1.Delete("/git/repositories/:id", gitHandler.DeleteRepository)
 	v1.Get("/git/branches/:repo_id", gitHandler.GetRepositoryBranches)
 	v1.Post("/git/template", gitHandler.CreateFromTemplate)
@@ -271,6 +392,9 @@ func startServer(cmd *cobra.Command) {
 	v1.Get("/claude/settings", claudeHandler.GetClaudeSettings)
 	v1.Put("/claude/settings", claudeHandler.UpdateClaudeSettings)
 	v1.Post("/claude/hooks", claudeHandler.HandleClaudeHook)
+	v1.Get("/claude/monitor/stats", func(c *fiber.Ctx) error {
+		return c.JSON(claudeMonitor.GetStats())
+	})
 
 	// Claude onboarding routes
 	v1.Post("/claude/onboarding/start", claudeHandler.StartOnboarding)
@@ -311,6 +435,13 @@ func startServer(cmd *cobra.Command) {
 	notificationHandler := handlers.NewNotificationHandler(eventsHandler)
 	v1.Post("/notifications", notificationHandler.HandleNotification)
 
+	// GitHub webhook receiver, enabled by CATNIP_GITHUB_WEBHOOK_SECRET
+	webhookHandler := handlers.NewWebhookHandler(gitService)
+	if webhookHandler.Enabled() {
+		logger.Infof("🪝 GitHub webhook receiver enabled at /v1/webhooks/github")
+	}
+	v1.Post("/webhooks/github", webhookHandler.HandleGitHubWebhook)
+
 	// Proxy routes for detected services (must be before dev middleware)
 	// Will validate port numbers in handler and call Next() if invalid
 	app.All("/:port", proxyHandler.ProxyToPort)
@@ -0,0 +1,115 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Paths are compared in canonical form: absolute and clean with symlinks resolved, so a
+// symlinked workspace matches the real directories processes and fsnotify report. On
+// case-insensitive filesystems, like macOS volumes mounted into the container, comparisons also
+// ignore case. CATNIP_CASE_INSENSITIVE_PATHS=true|false overrides the detection.
+
+// caseInsensitiveDirs caches the probe result per directory
+var caseInsensitiveDirs sync.Map
+
+// CanonicalPath returns path absolute, cleaned and with symlinks resolved. Trailing components
+// that don't exist are kept as given, so paths of removed worktrees stay comparable. Case is
+// preserved, this is the form to store paths in.
+func CanonicalPath(path string) string {
+	if path == "" {
+		return ""
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return filepath.Clean(path)
+	}
+
+	var missing []string
+	for dir := abs; ; {
+		if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+			return filepath.Join(append([]string{resolved}, missing...)...)
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return abs
+		}
+		missing = append([]string{filepath.Base(dir)}, missing...)
+		dir = parent
+	}
+}
+
+// PathKey returns the form of path to compare paths by or key maps with: its canonical path,
+// lower-cased when it lives on a case-insensitive filesystem
+func PathKey(path string) string {
+	canonical := CanonicalPath(path)
+	if canonical != "" && caseInsensitive(canonical) {
+		return strings.ToLower(canonical)
+	}
+	return canonical
+}
+
+// SamePath checks if two paths refer to the same location
+func SamePath(a, b string) bool {
+	return a == b || PathKey(a) == PathKey(b)
+}
+
+// RelativePath returns the slash-separated path of path relative to root, "." for root itself,
+// and whether path is root or inside it. Components keep the case path has on disk.
+func RelativePath(root, path string) (string, bool) {
+	if root == "" || path == "" {
+		return "", false
+	}
+	rel, err := filepath.Rel(PathKey(root), PathKey(path))
+	if err != nil || (rel != "." && !filepath.IsLocal(rel)) {
+		return "", false
+	}
+	if rel == "." {
+		return rel, true
+	}
+
+	// The key may be case-folded, take the components from the canonical path instead
+	depth := len(strings.Split(rel, string(filepath.Separator)))
+	parts := strings.Split(CanonicalPath(path), string(filepath.Separator))
+	return strings.Join(parts[len(parts)-depth:], "/"), true
+}
+
+// caseInsensitive checks if the filesystem holding path ignores case, by looking up the nearest
+// existing directory with letters in its name under its case-swapped name
+func caseInsensitive(path string) bool {
+	if value, err := strconv.ParseBool(os.Getenv("CATNIP_CASE_INSENSITIVE_PATHS")); err == nil {
+		return value
+	}
+
+	for dir := path; ; dir = filepath.Dir(dir) {
+		if known, ok := caseInsensitiveDirs.Load(dir); ok {
+			return known.(bool)
+		}
+		info, err := os.Stat(dir)
+		if err == nil && info.IsDir() {
+			if swapped := swapCase(filepath.Base(dir)); swapped != filepath.Base(dir) {
+				other, err := os.Stat(filepath.Join(filepath.Dir(dir), swapped))
+				insensitive := err == nil && os.SameFile(info, other)
+				caseInsensitiveDirs.Store(dir, insensitive)
+				return insensitive
+			}
+		}
+		if filepath.Dir(dir) == dir {
+			return false
+		}
+	}
+}
+
+// swapCase turns upper case letters into lower case ones and vice versa
+func swapCase(name string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsUpper(r) {
+			return unicode.ToLower(r)
+		}
+		return unicode.ToUpper(r)
+	}, name)
+}
@@ -0,0 +1,52 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCanonicalPaths(t *testing.T) {
+	root, err := filepath.EvalSymlinks(t.TempDir())
+	require.NoError(t, err)
+	realWorkspace := filepath.Join(root, "volume", "workspace")
+	require.NoError(t, os.MkdirAll(filepath.Join(realWorkspace, "catnip", "Felix"), 0755))
+	workspace := filepath.Join(root, "workspace")
+	require.NoError(t, os.Symlink(realWorkspace, workspace))
+
+	t.Run("symlinked workspace root", func(t *testing.T) {
+		assert.Equal(t, filepath.Join(realWorkspace, "catnip", "Felix"), CanonicalPath(workspace+"/catnip/Felix/"))
+		assert.Equal(t, filepath.Join(realWorkspace, "catnip", "gone", "x"), CanonicalPath(filepath.Join(workspace, "catnip", "gone", "x")),
+			"missing components are kept")
+		assert.True(t, SamePath(filepath.Join(workspace, "catnip"), filepath.Join(realWorkspace, "catnip")))
+
+		rel, ok := RelativePath(workspace, filepath.Join(realWorkspace, "catnip", "Felix", "src"))
+		assert.True(t, ok)
+		assert.Equal(t, "catnip/Felix/src", rel)
+		rel, ok = RelativePath(realWorkspace, workspace)
+		assert.True(t, ok)
+		assert.Equal(t, ".", rel)
+		_, ok = RelativePath(workspace, filepath.Join(root, "volume"))
+		assert.False(t, ok)
+		_, ok = RelativePath(workspace, root+"/workspace-other")
+		assert.False(t, ok)
+	})
+
+	t.Run("case-insensitive filesystem", func(t *testing.T) {
+		t.Setenv("CATNIP_CASE_INSENSITIVE_PATHS", "true")
+		assert.True(t, SamePath(filepath.Join(workspace, "catnip", "felix"), filepath.Join(realWorkspace, "CATNIP", "Felix")))
+		rel, ok := RelativePath(filepath.Join(workspace, "Catnip"), filepath.Join(realWorkspace, "catnip", "Felix", "src"))
+		assert.True(t, ok)
+		assert.Equal(t, "Felix/src", rel, "components keep their case")
+	})
+
+	t.Run("case-sensitive filesystem", func(t *testing.T) {
+		t.Setenv("CATNIP_CASE_INSENSITIVE_PATHS", "false")
+		assert.False(t, SamePath(filepath.Join(workspace, "catnip", "felix"), filepath.Join(workspace, "catnip", "Felix")))
+		_, ok := RelativePath(filepath.Join(workspace, "Catnip"), filepath.Join(workspace, "catnip", "Felix"))
+		assert.False(t, ok)
+	})
+}
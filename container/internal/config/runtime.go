@@ -34,6 +34,7 @@ type RuntimeConfig struct {
 	CurrentRepo        string // For native mode, the git repo we're running from
 	SyncEnabled        bool   // Whether to sync settings to volume
 	PortMonitorEnabled bool   // Whether to use /proc for port monitoring
+	BaseURL            string // External URL catnip is served from (e.g. https://host/catnip), no trailing slash
 }
 
 var (
@@ -58,7 +59,8 @@ func DetectRuntime() *RuntimeConfig {
 	mode := detectMode()
 
 	config := &RuntimeConfig{
-		Mode: mode,
+		Mode:    mode,
+		BaseURL: strings.TrimRight(os.Getenv("CATNIP_BASE_URL"), "/"),
 	}
 
 	// Get user's home directory for defaults
@@ -270,3 +272,37 @@ func (rc *RuntimeConfig) IsNative() bool {
 func (rc *RuntimeConfig) IsContainerized() bool {
 	return rc.Mode == DockerMode || rc.Mode == ContainerMode
 }
+
+// ExternalURL returns an absolute URL for the given path as seen by clients, honoring
+// CATNIP_BASE_URL when catnip is served behind a reverse proxy
+func (rc *RuntimeConfig) ExternalURL(path string) string {
+	base := rc.BaseURL
+	if base == "" {
+		base = "http://localhost:6369"
+	}
+	if path != "" && !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return base + path
+}
+
+// BasePath returns the path prefix catnip is mounted under (e.g. "/catnip"), or "" when served from the root
+func (rc *RuntimeConfig) BasePath() string {
+	if rc.BaseURL == "" {
+		return ""
+	}
+	path := rc.BaseURL
+	if idx := strings.Index(path, "://"); idx >= 0 {
+		path = path[idx+3:]
+		slash := strings.Index(path, "/")
+		if slash < 0 {
+			return ""
+		}
+		path = path[slash:]
+	}
+	path = strings.TrimRight(path, "/")
+	if path != "" && !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return path
+}
@@ -1,8 +1,10 @@
 package git
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"regexp"
 	"strconv"
 	"sync"
 	"time"
@@ -23,10 +25,36 @@ func GetCheckpointTimeout() time.Duration {
 	return DefaultCheckpointTimeoutSeconds * time.Second
 }
 
+// DefaultMaxCheckpointHoldMinutes is the longest checkpoints may be held while Claude is in plan mode
+const DefaultMaxCheckpointHoldMinutes = 30
+
+// GetMaxCheckpointHold returns the maximum plan mode checkpoint hold from environment or default
+func GetMaxCheckpointHold() time.Duration {
+	if holdStr := os.Getenv("CATNIP_MAX_CHECKPOINT_HOLD_MINUTES"); holdStr != "" {
+		if hold, err := strconv.Atoi(holdStr); err == nil && hold > 0 {
+			return time.Duration(hold) * time.Minute
+		}
+	}
+	return DefaultMaxCheckpointHoldMinutes * time.Minute
+}
+
+// checkpointSubjectPattern matches the subjects CreateCheckpoint gives its commits
+var checkpointSubjectPattern = regexp.MustCompile(`^(.*) checkpoint: \d+$`)
+
+// ParseCheckpointSubject returns the session title of a checkpoint commit from its subject,
+// false when the commit is not a checkpoint
+func ParseCheckpointSubject(subject string) (string, bool) {
+	match := checkpointSubjectPattern.FindStringSubmatch(subject)
+	if match == nil {
+		return "", false
+	}
+	return match[1], true
+}
+
 // CheckpointManager handles checkpoint functionality for sessions
 type CheckpointManager interface {
 	ShouldCreateCheckpoint() bool
-	CreateCheckpoint(title string) error
+	CreateCheckpoint(title string) (string, error)
 	Reset()
 	UpdateLastCommitTime()
 }
@@ -35,6 +63,18 @@ type CheckpointManager interface {
 type Service interface {
 	GitAddCommitGetHash(workDir, title string) (string, error)
 	RefreshWorktreeStatus(workDir string) error
+	// RecordCheckpointError stores the outcome of a checkpoint on the worktree, nil for success
+	RecordCheckpointError(workDir string, err error)
+}
+
+// CheckpointHookError is returned along with the commit hash when the commit hooks failed on a
+// checkpoint, which was then committed without them. It is a warning rather than a failure.
+type CheckpointHookError struct {
+	Output string // What the hooks printed
+}
+
+func (e *CheckpointHookError) Error() string {
+	return "commit hooks failed, checkpoint committed without them"
 }
 
 // SessionServiceInterface defines the session operations needed by checkpoint manager
@@ -74,10 +114,11 @@ func (cm *SessionCheckpointManager) ShouldCreateCheckpoint() bool {
 	return time.Since(cm.lastCommitTime) >= GetCheckpointTimeout()
 }
 
-// CreateCheckpoint creates a checkpoint commit
-func (cm *SessionCheckpointManager) CreateCheckpoint(title string) error {
+// CreateCheckpoint creates a checkpoint commit and returns its hash, empty when there was nothing
+// to commit
+func (cm *SessionCheckpointManager) CreateCheckpoint(title string) (string, error) {
 	if cm.gitService == nil {
-		return fmt.Errorf("git service not available")
+		return "", fmt.Errorf("git service not available")
 	}
 
 	cm.checkpointMutex.Lock()
@@ -85,11 +126,16 @@ func (cm *SessionCheckpointManager) CreateCheckpoint(title string) error {
 
 	checkpointTitle := fmt.Sprintf("%s checkpoint: %d", title, cm.checkpointCount+1)
 	commitHash, err := cm.gitService.GitAddCommitGetHash(cm.workDir, checkpointTitle)
-	if err != nil {
-		return err
+	var hookErr *CheckpointHookError
+	if errors.As(err, &hookErr) {
+		logger.Warnf("⚠️  Commit hooks failed on checkpoint %q in %s, committed without them", checkpointTitle, cm.workDir)
+	} else if err != nil {
+		cm.gitService.RecordCheckpointError(cm.workDir, err)
+		return "", err
 	} else if commitHash == "" {
-		return nil
+		return "", nil
 	}
+	cm.gitService.RecordCheckpointError(cm.workDir, err)
 
 	cm.checkpointCount++
 
@@ -108,7 +154,7 @@ func (cm *SessionCheckpointManager) CreateCheckpoint(title string) error {
 		logger.Debugf("⚠️  Failed to refresh worktree status after checkpoint: %v", err)
 	}
 
-	return nil
+	return commitHash, nil
 }
 
 // Reset resets the checkpoint state for a new title
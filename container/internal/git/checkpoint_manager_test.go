@@ -15,6 +15,7 @@ type MockGitService struct {
 	lastCommitTitle string
 	returnHash      string
 	returnError     error
+	recordedError   error
 }
 
 func (m *MockGitService) GitAddCommitGetHash(workDir, title string) (string, error) {
@@ -27,6 +28,10 @@ func (m *MockGitService) RefreshWorktreeStatus(workDir string) error {
 	return nil
 }
 
+func (m *MockGitService) RecordCheckpointError(workDir string, err error) {
+	m.recordedError = err
+}
+
 // MockSessionService is a mock implementation of SessionService for testing
 type MockSessionService struct {
 	addToHistoryCalled bool
@@ -125,7 +130,7 @@ func TestCreateCheckpoint(t *testing.T) {
 			lastCommitTime:  time.Now().Add(-1 * time.Hour), // Old time
 		}
 
-		err := cm.CreateCheckpoint("Test Title")
+		_, err := cm.CreateCheckpoint("Test Title")
 		require.NoError(t, err)
 
 		assert.True(t, mockGit.addCommitCalled)
@@ -142,7 +147,7 @@ func TestCreateCheckpoint(t *testing.T) {
 			gitService: nil,
 		}
 
-		err := cm.CreateCheckpoint("Test Title")
+		_, err := cm.CreateCheckpoint("Test Title")
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "git service not available")
 	})
@@ -160,13 +165,34 @@ func TestCreateCheckpoint(t *testing.T) {
 			checkpointCount: 0,
 		}
 
-		err := cm.CreateCheckpoint("Test Title")
+		_, err := cm.CreateCheckpoint("Test Title")
 		require.NoError(t, err)
 
 		assert.True(t, mockGit.addCommitCalled)
 		assert.False(t, mockSession.addToHistoryCalled) // Should not add to history if no commit
 		assert.Equal(t, 0, cm.checkpointCount)          // Count should not increase
 	})
+	t.Run("failing commit hooks are a warning", func(t *testing.T) {
+		hookErr := &CheckpointHookError{Output: "lint failed"}
+		mockGit := &MockGitService{
+			returnHash:  "def456",
+			returnError: hookErr,
+		}
+		mockSession := &MockSessionService{}
+
+		cm := &SessionCheckpointManager{
+			gitService:     mockGit,
+			sessionService: mockSession,
+			workDir:        "/test/workspace",
+		}
+
+		_, err := cm.CreateCheckpoint("Test Title")
+		require.NoError(t, err)
+
+		assert.Equal(t, hookErr, mockGit.recordedError)
+		assert.Equal(t, "def456", mockSession.lastCommitHash)
+		assert.Equal(t, 1, cm.checkpointCount)
+	})
 }
 
 func TestReset(t *testing.T) {
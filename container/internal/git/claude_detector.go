@@ -11,6 +11,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/vanpelt/catnip/internal/config"
 	"github.com/vanpelt/catnip/internal/logger"
 )
 
@@ -221,7 +222,7 @@ func (d *ClaudeSessionDetector) isProcessInWorktree(pid int) bool {
 			if strings.HasPrefix(pathLine, "n") {
 				path := strings.TrimPrefix(pathLine, "n")
 				// Check if this path is within our worktree
-				if strings.HasPrefix(path, d.workDir) {
+				if _, ok := config.RelativePath(d.workDir, path); ok {
 					return true
 				}
 			}
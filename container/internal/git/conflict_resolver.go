@@ -2,6 +2,8 @@ package git
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/vanpelt/catnip/internal/models"
@@ -109,7 +111,9 @@ func (c *ConflictResolver) hasActiveConflictState(repoPath string) bool {
 		}
 	}
 
-	return false
+	// go-git's status reports unmerged entries as plain modifications (MM), so
+	// double-check the index for unmerged paths with git itself
+	return len(c.getConflictedFiles(repoPath)) > 0
 }
 
 // CreateMergeConflictError creates a detailed merge conflict error
@@ -133,6 +137,151 @@ func (c *ConflictResolver) GetConflictedFiles(worktreePath string) ([]string, er
 	return c.getConflictedFiles(worktreePath), nil
 }
 
+// conflictStatuses maps the index stages of a conflicted path (1 base, 2 ours, 3 theirs) to its
+// git status --porcelain code. go-git's status reports unmerged entries as plain modifications,
+// so the code is derived from git ls-files -u instead.
+var conflictStatuses = map[string]string{
+	"123": "UU", // both modified
+	"23":  "AA", // both added
+	"12":  "UD", // deleted by them
+	"13":  "DU", // deleted by us
+	"2":   "AU", // added by us
+	"3":   "UA", // added by them
+	"1":   "DD", // both deleted
+}
+
+// GetConflictDetails returns the files a failed merge or rebase left conflicted in a worktree,
+// with their base, ours and theirs versions as staged in the index
+func (c *ConflictResolver) GetConflictDetails(worktreePath string) ([]models.ConflictFile, error) {
+	entries, err := c.unmergedEntries(worktreePath)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []models.ConflictFile{}
+	byPath := make(map[string]int)
+	stages := make(map[string]string)
+	for _, entry := range entries {
+		i, seen := byPath[entry.path]
+		if !seen {
+			i = len(files)
+			byPath[entry.path] = i
+			files = append(files, models.ConflictFile{Path: entry.path})
+		}
+		blob := c.readConflictBlob(worktreePath, entry.mode, entry.hash)
+		switch entry.stage {
+		case "1":
+			files[i].Base = blob
+		case "2":
+			files[i].Ours = blob
+		case "3":
+			files[i].Theirs = blob
+		}
+		stages[entry.path] += entry.stage
+	}
+	for i := range files {
+		files[i].Status = conflictStatuses[stages[files[i].Path]]
+	}
+	return files, nil
+}
+
+// ResolveConflictFile resolves a conflicted file of a worktree with our version, their version
+// or replacement content and stages the result. Keeping the version of a side that deleted the
+// file deletes it.
+func (c *ConflictResolver) ResolveConflictFile(worktreePath, file string, resolution models.ConflictResolution) error {
+	entries, err := c.unmergedEntries(worktreePath, file)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return fmt.Errorf("file %s is not conflicted", file)
+	}
+
+	switch resolution.Resolution {
+	case models.ConflictResolutionOurs, models.ConflictResolutionTheirs:
+		stage := "2"
+		if resolution.Resolution == models.ConflictResolutionTheirs {
+			stage = "3"
+		}
+		kept := false
+		for _, entry := range entries {
+			kept = kept || entry.stage == stage
+		}
+		if !kept {
+			if _, err := c.operations.ExecuteGit(worktreePath, "rm", "-q", "-f", "--", file); err != nil {
+				return fmt.Errorf("failed to delete %s: %v", file, err)
+			}
+			return nil
+		}
+		if _, err := c.operations.ExecuteGit(worktreePath, "checkout", "--"+resolution.Resolution, "--", file); err != nil {
+			return fmt.Errorf("failed to check out %s version of %s: %v", resolution.Resolution, file, err)
+		}
+	case models.ConflictResolutionContent:
+		path := filepath.Join(worktreePath, file)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return fmt.Errorf("failed to write %s: %v", file, err)
+		}
+		if err := os.WriteFile(path, []byte(resolution.Content), 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %v", file, err)
+		}
+	default:
+		return fmt.Errorf("unknown conflict resolution %q, expected %s, %s or %s", resolution.Resolution,
+			models.ConflictResolutionOurs, models.ConflictResolutionTheirs, models.ConflictResolutionContent)
+	}
+
+	if err := c.operations.Add(worktreePath, "--", file); err != nil {
+		return fmt.Errorf("failed to stage %s: %v", file, err)
+	}
+	return nil
+}
+
+// unmergedEntry is an index entry of a conflicted path, as listed by git ls-files -u
+type unmergedEntry struct {
+	mode, hash, stage, path string
+}
+
+// unmergedEntries lists the index entries of the conflicted paths of a worktree, limited to
+// paths when given
+func (c *ConflictResolver) unmergedEntries(worktreePath string, paths ...string) ([]unmergedEntry, error) {
+	output, err := c.operations.ExecuteGit(worktreePath, append([]string{"ls-files", "-u", "-z", "--"}, paths...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list conflicted files: %v", err)
+	}
+
+	var entries []unmergedEntry
+	for _, record := range strings.Split(string(output), "\x00") {
+		// <mode> <hash> <stage>\t<path>
+		meta, path, found := strings.Cut(record, "\t")
+		fields := strings.Fields(meta)
+		if !found || len(fields) != 3 {
+			continue
+		}
+		entries = append(entries, unmergedEntry{mode: fields[0], hash: fields[1], stage: fields[2], path: path})
+	}
+	return entries, nil
+}
+
+// readConflictBlob reads one version of a conflicted file, leaving out the content of binary
+// blobs and cutting off large ones at maxContentLength
+func (c *ConflictResolver) readConflictBlob(worktreePath, mode, hash string) *models.ConflictBlob {
+	blob := &models.ConflictBlob{Hash: hash, Mode: mode}
+	content, err := c.operations.ExecuteGit(worktreePath, "cat-file", "blob", hash)
+	if err != nil {
+		return blob // e.g. a submodule commit, there is no blob to read
+	}
+	blob.Size = int64(len(content))
+	if isBinaryContent(content) {
+		blob.Binary = true
+		return blob
+	}
+	if len(content) > maxContentLength {
+		content = content[:maxContentLength]
+		blob.Truncated = true
+	}
+	blob.Content = string(content)
+	return blob
+}
+
 // checkConflicts performs conflict detection using merge-tree
 func (c *ConflictResolver) checkConflicts(worktreePath, sourceRef, operation, worktreeName, workingTreePath string) (*models.MergeConflictError, error) {
 	// Try a dry-run merge to detect conflicts
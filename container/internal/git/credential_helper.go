@@ -0,0 +1,354 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// GHCredentialHelper is the credential helper catnip configures for GitHub hosts
+	GHCredentialHelper = "!gh auth git-credential"
+	// credentialCommandTimeout bounds the git commands reading, writing and verifying helpers
+	credentialCommandTimeout = 15 * time.Second
+)
+
+// credentialHelperResetVersion is the first git version where an empty helper value clears the
+// helpers inherited from earlier config (git 2.9)
+var credentialHelperResetVersion = [2]int{2, 9}
+
+var gitVersionPattern = regexp.MustCompile(`git version (\d+)\.(\d+)`)
+
+// CredentialHelperReport describes the gh credential helper setup of every GitHub host
+type CredentialHelperReport struct {
+	GitVersion string                 `json:"git_version"`
+	Hosts      []CredentialHostStatus `json:"hosts"`
+	// Config keys rewritten while configuring
+	Changed []string `json:"changed,omitempty"`
+	// True when every host passed the credential fill check
+	Verified  bool      `json:"verified"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// CredentialHostStatus is the credential helper setup of one GitHub host
+type CredentialHostStatus struct {
+	Host string `json:"host"`
+	// Helpers git runs for the host, in order
+	Helpers []string `json:"helpers"`
+	// Problem left after configuring (empty when the setup is sound)
+	Problem string `json:"problem,omitempty"`
+	// True when `git credential fill` returned a credential for the host
+	Verified bool `json:"verified"`
+	// Why the credential fill check failed
+	VerifyError string `json:"verify_error,omitempty"`
+}
+
+// credentialHelperEntry is a credential helper config entry
+type credentialHelperEntry struct {
+	scope string
+	key   string
+	value string
+}
+
+// IsGHCredentialHelper reports whether a credential helper value runs `gh auth git-credential`,
+// however gh is referenced (`gh auth setup-git` writes an absolute path)
+func IsGHCredentialHelper(value string) bool {
+	fields := strings.Fields(strings.TrimPrefix(strings.TrimSpace(value), "!"))
+	if len(fields) != 3 || fields[1] != "auth" || fields[2] != "git-credential" {
+		return false
+	}
+	return filepath.Base(strings.Trim(fields[0], `'"`)) == "gh"
+}
+
+// credentialHelperKey is the host specific helper key of a GitHub host
+func credentialHelperKey(host string) string {
+	return "credential.https://" + host + ".helper"
+}
+
+// parseGitVersion extracts major and minor from `git version` output
+func parseGitVersion(output string) (major, minor int, ok bool) {
+	matches := gitVersionPattern.FindStringSubmatch(output)
+	if matches == nil {
+		return 0, 0, false
+	}
+	major, _ = strconv.Atoi(matches[1])
+	minor, _ = strconv.Atoi(matches[2])
+	return major, minor, true
+}
+
+// supportsHelperReset reports whether a git version understands the empty helper reset
+func supportsHelperReset(major, minor int) bool {
+	return major > credentialHelperResetVersion[0] ||
+		(major == credentialHelperResetVersion[0] && minor >= credentialHelperResetVersion[1])
+}
+
+// effectiveCredentialHelpers returns the helpers git runs for host: generic and host specific
+// entries in config order, where an empty value clears the ones before it
+func effectiveCredentialHelpers(entries []credentialHelperEntry, host string) []string {
+	helpers := []string{}
+	for _, entry := range entries {
+		if !strings.EqualFold(entry.key, "credential.helper") && !strings.EqualFold(entry.key, credentialHelperKey(host)) {
+			continue
+		}
+		if entry.value == "" {
+			helpers = helpers[:0]
+			continue
+		}
+		helpers = append(helpers, entry.value)
+	}
+	return helpers
+}
+
+// credentialHelperProblem describes what keeps gh from answering first and exactly once for a
+// list of effective helpers, or returns ""
+func credentialHelperProblem(helpers []string) string {
+	count, first := 0, -1
+	for i, helper := range helpers {
+		if IsGHCredentialHelper(helper) {
+			if first < 0 {
+				first = i
+			}
+			count++
+		}
+	}
+	switch {
+	case count == 0:
+		return "gh credential helper is not configured"
+	case count > 1:
+		return fmt.Sprintf("gh credential helper runs %d times", count)
+	case first > 0:
+		return "gh credential helper is shadowed by " + strings.Join(helpers[:first], ", ")
+	}
+	return ""
+}
+
+// planCredentialHelpers returns the values the global host specific helper key should hold so
+// gh answers first and once, or nil when the current setup is sound. Other helpers configured
+// for the host are kept after gh; generic helpers and other hosts are never touched. Without
+// reset support, helpers inherited from earlier config can't be cleared and stay in front.
+func planCredentialHelpers(entries []credentialHelperEntry, host string, canReset bool) []string {
+	if credentialHelperProblem(effectiveCredentialHelpers(entries, host)) == "" {
+		return nil
+	}
+
+	var desired []string
+	if canReset {
+		desired = append(desired, "")
+	}
+	desired = append(desired, GHCredentialHelper)
+	seen := map[string]bool{}
+	for _, entry := range entries {
+		if entry.scope != "global" || !strings.EqualFold(entry.key, credentialHelperKey(host)) {
+			continue
+		}
+		if entry.value == "" || IsGHCredentialHelper(entry.value) || seen[entry.value] {
+			continue
+		}
+		seen[entry.value] = true
+		desired = append(desired, entry.value)
+	}
+	return desired
+}
+
+// currentHostHelpers returns the global values of a host specific helper key
+func currentHostHelpers(entries []credentialHelperEntry, host string) []string {
+	var values []string
+	for _, entry := range entries {
+		if entry.scope == "global" && strings.EqualFold(entry.key, credentialHelperKey(host)) {
+			values = append(values, entry.value)
+		}
+	}
+	return values
+}
+
+// applyHostHelpers simulates rewriting the global values of a host specific helper key: the new
+// values take the place of the old ones, or go last when the key wasn't set
+func applyHostHelpers(entries []credentialHelperEntry, host string, values []string) []credentialHelperEntry {
+	var result []credentialHelperEntry
+	inserted := false
+	for _, entry := range entries {
+		if entry.scope == "global" && strings.EqualFold(entry.key, credentialHelperKey(host)) {
+			if !inserted {
+				for _, value := range values {
+					result = append(result, credentialHelperEntry{scope: "global", key: credentialHelperKey(host), value: value})
+				}
+				inserted = true
+			}
+			continue
+		}
+		result = append(result, entry)
+	}
+	if !inserted {
+		for _, value := range values {
+			result = append(result, credentialHelperEntry{scope: "global", key: credentialHelperKey(host), value: value})
+		}
+	}
+	return result
+}
+
+// credentialHelperSetup configures and verifies the gh credential helper in the global git
+// config of homeDir
+type credentialHelperSetup struct {
+	homeDir string
+}
+
+// git runs git with HOME pointing at the setup's home and without terminal prompts
+func (c credentialHelperSetup) git(stdin string, args ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), credentialCommandTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = c.homeDir
+	cmd.Env = append(os.Environ(), "HOME="+c.homeDir, "GIT_TERMINAL_PROMPT=0", "GIT_ASKPASS=", "SSH_ASKPASS=")
+	if stdin != "" {
+		cmd.Stdin = strings.NewReader(stdin)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, fmt.Errorf("git %s timed out after %v", args[0], credentialCommandTimeout)
+		}
+		return stdout.Bytes(), fmt.Errorf("git %s failed: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+// readEntries reads the system and global credential helper entries in the order git applies
+// them. Reading per scope works on every git version, unlike --show-scope (git 2.26).
+func (c credentialHelperSetup) readEntries() ([]credentialHelperEntry, error) {
+	var entries []credentialHelperEntry
+	for _, scope := range []string{"system", "global"} {
+		output, err := c.git("", "config", "--"+scope, "-z", "--get-regexp", `^credential\..*helper$`)
+		if err != nil {
+			// Exit status 1 means no entries (or no config file) for the scope
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+				continue
+			}
+			return nil, err
+		}
+		for _, field := range strings.Split(string(output), "\x00") {
+			if field == "" {
+				continue
+			}
+			key, value, _ := strings.Cut(field, "\n")
+			entries = append(entries, credentialHelperEntry{scope: scope, key: key, value: value})
+		}
+	}
+	return entries, nil
+}
+
+// writeHostHelpers replaces the global values of a host specific helper key
+func (c credentialHelperSetup) writeHostHelpers(host string, existing, values []string) error {
+	key := credentialHelperKey(host)
+	if len(existing) > 0 {
+		if _, err := c.git("", "config", "--global", "--unset-all", key); err != nil {
+			return err
+		}
+	}
+	for _, value := range values {
+		if _, err := c.git("", "config", "--global", "--add", key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// verify runs a credential fill for host; the returned credential is discarded
+func (c credentialHelperSetup) verify(host string) error {
+	output, err := c.git("protocol=https\nhost="+host+"\n\n", "credential", "fill")
+	if err != nil {
+		return err
+	}
+	if !bytes.Contains(output, []byte("\npassword=")) && !bytes.HasPrefix(output, []byte("password=")) {
+		return fmt.Errorf("no credential returned")
+	}
+	return nil
+}
+
+// configure makes gh the first and only gh credential helper of every host, then verifies
+// each host with a credential fill
+func (c credentialHelperSetup) configure(hosts []string) (*CredentialHelperReport, error) {
+	report := &CredentialHelperReport{Hosts: []CredentialHostStatus{}, CheckedAt: time.Now()}
+
+	canReset := true
+	if output, err := c.git("", "version"); err == nil {
+		report.GitVersion = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(string(output)), "git version"))
+		if major, minor, ok := parseGitVersion(string(output)); ok {
+			canReset = supportsHelperReset(major, minor)
+		}
+	}
+
+	entries, err := c.readEntries()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, host := range hosts {
+		if desired := planCredentialHelpers(entries, host, canReset); desired != nil {
+			existing := currentHostHelpers(entries, host)
+			if err := c.writeHostHelpers(host, existing, desired); err != nil {
+				return nil, fmt.Errorf("failed to configure credential helper for %s: %v", host, err)
+			}
+			entries = applyHostHelpers(entries, host, desired)
+			report.Changed = append(report.Changed, credentialHelperKey(host))
+		}
+	}
+
+	report.Verified = true
+	for _, host := range hosts {
+		helpers := effectiveCredentialHelpers(entries, host)
+		status := CredentialHostStatus{Host: host, Helpers: helpers, Problem: credentialHelperProblem(helpers)}
+		if status.Problem != "" && !canReset {
+			status.Problem += fmt.Sprintf(" (git %s can't clear inherited helpers, git %d.%d or later can)",
+				report.GitVersion, credentialHelperResetVersion[0], credentialHelperResetVersion[1])
+		}
+		if err := c.verify(host); err != nil {
+			status.VerifyError = err.Error()
+		} else {
+			status.Verified = true
+		}
+		report.Verified = report.Verified && status.Verified && status.Problem == ""
+		report.Hosts = append(report.Hosts, status)
+	}
+	return report, nil
+}
+
+// ghHosts returns github.com and the GitHub Enterprise hosts gh is logged in to
+func ghHosts(homeDir string) []string {
+	hosts := []string{"github.com"}
+
+	configDir := os.Getenv("GH_CONFIG_DIR")
+	if configDir == "" {
+		configDir = filepath.Join(homeDir, ".config", "gh")
+	}
+	data, err := os.ReadFile(filepath.Join(configDir, "hosts.yml"))
+	if err != nil {
+		return hosts
+	}
+	var configured map[string]interface{}
+	if err := yaml.Unmarshal(data, &configured); err != nil {
+		return hosts
+	}
+	var enterprise []string
+	for host := range configured {
+		if !strings.EqualFold(host, "github.com") {
+			enterprise = append(enterprise, host)
+		}
+	}
+	sort.Strings(enterprise)
+	return append(hosts, enterprise...)
+}
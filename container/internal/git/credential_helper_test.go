@@ -0,0 +1,195 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsGHCredentialHelper(t *testing.T) {
+	assert.True(t, IsGHCredentialHelper("!gh auth git-credential"))
+	assert.True(t, IsGHCredentialHelper("!/usr/bin/gh auth git-credential"))
+	assert.True(t, IsGHCredentialHelper("!'/opt/homebrew/bin/gh' auth git-credential"))
+	assert.False(t, IsGHCredentialHelper("osxkeychain"))
+	assert.False(t, IsGHCredentialHelper("!gh auth token"))
+	assert.False(t, IsGHCredentialHelper(""))
+}
+
+func TestParseGitVersion(t *testing.T) {
+	major, minor, ok := parseGitVersion("git version 2.39.3 (Apple Git-146)")
+	require.True(t, ok)
+	assert.Equal(t, []int{2, 39}, []int{major, minor})
+	assert.True(t, supportsHelperReset(major, minor))
+	assert.False(t, supportsHelperReset(2, 7))
+	assert.True(t, supportsHelperReset(3, 0))
+
+	_, _, ok = parseGitVersion("not git")
+	assert.False(t, ok)
+}
+
+// helperEntries builds config entries from "scope key=value" fixtures
+func helperEntries(lines ...string) []credentialHelperEntry {
+	var entries []credentialHelperEntry
+	for _, line := range lines {
+		scope, rest, _ := strings.Cut(line, " ")
+		key, value, _ := strings.Cut(rest, "=")
+		entries = append(entries, credentialHelperEntry{scope: scope, key: key, value: value})
+	}
+	return entries
+}
+
+func TestPlanCredentialHelpers(t *testing.T) {
+	const hostKey = "credential.https://github.com.helper"
+	tests := []struct {
+		name     string
+		entries  []credentialHelperEntry
+		canReset bool
+		expected []string
+	}{
+		{
+			name:     "nothing configured",
+			canReset: true,
+			expected: []string{"", GHCredentialHelper},
+		},
+		{
+			name:     "gh auth setup-git",
+			entries:  helperEntries("global "+hostKey+"=", "global "+hostKey+"=!/usr/bin/gh auth git-credential"),
+			canReset: true,
+		},
+		{
+			name:     "gh alone without other helpers",
+			entries:  helperEntries("global " + hostKey + "=!gh auth git-credential"),
+			canReset: true,
+		},
+		{
+			name:     "gh shadowed by the keychain",
+			entries:  helperEntries("system credential.helper=osxkeychain", "global "+hostKey+"=!gh auth git-credential"),
+			canReset: true,
+			expected: []string{"", GHCredentialHelper},
+		},
+		{
+			name:     "gh configured twice",
+			entries:  helperEntries("global "+hostKey+"=!gh auth git-credential", "global "+hostKey+"=!/usr/local/bin/gh auth git-credential"),
+			canReset: true,
+			expected: []string{"", GHCredentialHelper},
+		},
+		{
+			name: "other host helpers are kept after gh",
+			entries: helperEntries("global credential.helper=store", "global "+hostKey+"=cache --timeout=300",
+				"global "+hostKey+"=!gh auth git-credential", "global credential.https://gitlab.com.helper=manager"),
+			canReset: true,
+			expected: []string{"", GHCredentialHelper, "cache --timeout=300"},
+		},
+		{
+			name:     "generic helpers after a reset don't shadow gh",
+			entries:  helperEntries("global "+hostKey+"=", "global "+hostKey+"=!gh auth git-credential", "global credential.helper=store"),
+			canReset: true,
+		},
+		{
+			name:     "old git can't reset inherited helpers",
+			entries:  helperEntries("system credential.helper=osxkeychain"),
+			canReset: false,
+			expected: []string{GHCredentialHelper},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, planCredentialHelpers(tt.entries, "github.com", tt.canReset))
+		})
+	}
+}
+
+func TestEffectiveCredentialHelpersAfterPlan(t *testing.T) {
+	entries := helperEntries("system credential.helper=osxkeychain", "global credential.https://github.com.helper=cache",
+		"global credential.https://github.com.helper=!gh auth git-credential")
+	assert.Equal(t, "gh credential helper is shadowed by osxkeychain, cache", credentialHelperProblem(effectiveCredentialHelpers(entries, "github.com")))
+
+	entries = applyHostHelpers(entries, "github.com", planCredentialHelpers(entries, "github.com", true))
+	assert.Equal(t, []string{GHCredentialHelper, "cache"}, effectiveCredentialHelpers(entries, "github.com"))
+	assert.Empty(t, credentialHelperProblem(effectiveCredentialHelpers(entries, "github.com")))
+	assert.Nil(t, planCredentialHelpers(entries, "github.com", true), "a repaired setup is left alone")
+}
+
+// fakeGH puts a gh on PATH whose credential helper answers for github.com only
+func fakeGH(t *testing.T) {
+	t.Helper()
+	bin := t.TempDir()
+	script := `#!/bin/sh
+if [ "$1 $2 $3" = "auth git-credential get" ]; then
+  while read -r line && [ -n "$line" ]; do
+    [ "$line" = "host=github.com" ] && found=1
+  done
+  if [ -n "$found" ]; then
+    echo username=x-access-token
+    echo password=gho_fixture
+  fi
+fi
+`
+	require.NoError(t, os.WriteFile(filepath.Join(bin, "gh"), []byte(script), 0755))
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+}
+
+func readGlobalHelpers(t *testing.T, home, key string) []string {
+	t.Helper()
+	cmd := exec.Command("git", "config", "--global", "--get-all", key)
+	cmd.Env = append(os.Environ(), "HOME="+home)
+	output, _ := cmd.Output()
+	return strings.Split(strings.TrimSuffix(string(output), "\n"), "\n")
+}
+
+func TestCredentialHelperSetupConfigure(t *testing.T) {
+	fakeGH(t)
+	home := t.TempDir()
+	fixture := `[credential]
+	helper = cache
+[credential "https://github.com"]
+	helper = !gh auth git-credential
+	helper = !gh auth git-credential
+[credential "https://gitlab.com"]
+	helper = store
+`
+	require.NoError(t, os.WriteFile(filepath.Join(home, ".gitconfig"), []byte(fixture), 0644))
+
+	setup := credentialHelperSetup{homeDir: home}
+	report, err := setup.configure([]string{"github.com", "ghe.example.com"})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"credential.https://github.com.helper", "credential.https://ghe.example.com.helper"}, report.Changed)
+	assert.Equal(t, []string{"", GHCredentialHelper}, readGlobalHelpers(t, home, "credential.https://github.com.helper"))
+	assert.Equal(t, []string{"cache"}, readGlobalHelpers(t, home, "credential.helper"), "generic helpers are untouched")
+	assert.Equal(t, []string{"store"}, readGlobalHelpers(t, home, "credential.https://gitlab.com.helper"), "other hosts are untouched")
+
+	require.Len(t, report.Hosts, 2)
+	assert.Equal(t, []string{GHCredentialHelper}, report.Hosts[0].Helpers)
+	assert.Empty(t, report.Hosts[0].Problem)
+	assert.True(t, report.Hosts[0].Verified)
+	assert.False(t, report.Hosts[1].Verified, "the fake gh has no credential for the enterprise host")
+	assert.NotEmpty(t, report.Hosts[1].VerifyError)
+	assert.False(t, report.Verified)
+
+	// Configuring again changes nothing
+	report, err = setup.configure([]string{"github.com"})
+	require.NoError(t, err)
+	assert.Empty(t, report.Changed)
+	assert.True(t, report.Verified)
+	assert.Equal(t, []string{"", GHCredentialHelper}, readGlobalHelpers(t, home, "credential.https://github.com.helper"))
+}
+
+func TestGHHosts(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("GH_CONFIG_DIR", "")
+	assert.Equal(t, []string{"github.com"}, ghHosts(home))
+
+	configDir := filepath.Join(home, ".config", "gh")
+	require.NoError(t, os.MkdirAll(configDir, 0755))
+	hosts := "github.com:\n    user: alice\nghe.example.com:\n    user: alice\n"
+	require.NoError(t, os.WriteFile(filepath.Join(configDir, "hosts.yml"), []byte(hosts), 0600))
+	assert.Equal(t, []string{"github.com", "ghe.example.com"}, ghHosts(home))
+}
@@ -0,0 +1,48 @@
+package git
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// CredentialProvider resolves a credential reference into environment variables that
+// authenticate git against a remote. Credentials are passed through the environment
+// (GIT_CONFIG_COUNT/KEY/VALUE) so they never appear in command lines, logs or errors.
+type CredentialProvider interface {
+	GitEnv(remoteURL, credential string) ([]string, error)
+}
+
+// EnvCredentialProvider reads credentials from environment variables. The credential
+// reference names a variable holding either "user:token" or a bare token (sent with
+// the user "git").
+type EnvCredentialProvider struct{}
+
+// NewEnvCredentialProvider creates a provider backed by environment variables
+func NewEnvCredentialProvider() *EnvCredentialProvider {
+	return &EnvCredentialProvider{}
+}
+
+// GitEnv implements CredentialProvider
+func (p *EnvCredentialProvider) GitEnv(remoteURL, credential string) ([]string, error) {
+	if credential == "" {
+		return nil, nil
+	}
+
+	secret := os.Getenv(credential)
+	if secret == "" {
+		return nil, fmt.Errorf("credential %s is not set", credential)
+	}
+	if !strings.Contains(secret, ":") {
+		secret = "git:" + secret
+	}
+
+	header := "Authorization: Basic " + base64.StdEncoding.EncodeToString([]byte(secret))
+	return []string{
+		"GIT_CONFIG_COUNT=1",
+		"GIT_CONFIG_KEY_0=http.extraHeader",
+		"GIT_CONFIG_VALUE_0=" + header,
+		"GIT_TERMINAL_PROMPT=0",
+	}, nil
+}
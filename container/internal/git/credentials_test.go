@@ -0,0 +1,25 @@
+package git
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnvCredentialProvider(t *testing.T) {
+	p := NewEnvCredentialProvider()
+
+	env, err := p.GitEnv("https://git.example.com/repo.git", "")
+	require.NoError(t, err)
+	assert.Nil(t, env)
+
+	_, err = p.GitEnv("https://git.example.com/repo.git", "CATNIP_TEST_MIRROR_TOKEN_UNSET")
+	assert.Error(t, err)
+
+	t.Setenv("CATNIP_TEST_MIRROR_TOKEN", "secret")
+	env, err = p.GitEnv("https://git.example.com/repo.git", "CATNIP_TEST_MIRROR_TOKEN")
+	require.NoError(t, err)
+	assert.Contains(t, env, "GIT_CONFIG_KEY_0=http.extraHeader")
+	assert.Contains(t, env, "GIT_CONFIG_VALUE_0=Authorization: Basic Z2l0OnNlY3JldA==")
+}
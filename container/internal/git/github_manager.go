@@ -6,22 +6,55 @@ import (
 	"os/exec"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/vanpelt/catnip/internal/config"
 	"github.com/vanpelt/catnip/internal/logger"
 	"github.com/vanpelt/catnip/internal/models"
 )
 
+// GitHubClient is the set of GitHub operations used by the git service. GitHubManager
+// implements it with the gh CLI; tests can substitute a stub to stay hermetic.
+// nolint:revive
+type GitHubClient interface {
+	ConfigureGitCredentials() (*CredentialHelperReport, error)
+	ListRepositories() ([]GitHubRepository, error)
+	CreateRepository(name, description string, isPrivate bool) (string, error)
+	CreatePullRequest(req CreatePullRequestRequest) (*models.PullRequestResponse, error)
+	GetPullRequestInfo(worktree *models.Worktree, repository *models.Repository) (*models.PullRequestInfo, error)
+	ListLabeledIssues(ownerRepo, label string) ([]GitHubIssue, error)
+	CommentOnIssue(ownerRepo string, number int, body string) error
+	ListReviewThreads(ownerRepo string, number int) ([]models.ReviewThread, error)
+}
+
+// Ensure GitHubManager implements GitHubClient
+var _ GitHubClient = (*GitHubManager)(nil)
+
 // GitHubManager handles all GitHub CLI operations (auth, repos, pull requests, etc.)
 // nolint:revive
 type GitHubManager struct {
 	operations Operations
+	rateLimits *RateLimitTracker
 }
 
 // NewGitHubManager creates a new GitHub manager
 func NewGitHubManager(operations Operations) *GitHubManager {
 	return &GitHubManager{
 		operations: operations,
+		rateLimits: GitHubRateLimits(),
+	}
+}
+
+// RateLimits returns the tracker of the GitHub API budget this manager spends
+func (g *GitHubManager) RateLimits() *RateLimitTracker {
+	return g.rateLimits
+}
+
+// observeRateLimit records a gh command that failed because GitHub refused it for exceeding the
+// rate limit of resource
+func (g *GitHubManager) observeRateLimit(resource string, err error, output []byte) {
+	if g.rateLimits != nil && g.rateLimits.ObserveError(resource, err, output) {
+		logger.Warnf("🚦 GitHub %s rate limit exceeded", resource)
 	}
 }
 
@@ -63,6 +96,7 @@ type CreatePullRequestRequest struct {
 	Body             string
 	IsUpdate         bool
 	ForcePush        bool
+	Draft            bool // Open new pull requests as drafts
 	FetchFullHistory func(*models.Worktree)
 	CreateTempCommit func(string) (string, error)
 	RevertTempCommit func(string, string)
@@ -126,7 +160,7 @@ func (g *GitHubManager) CreatePullRequest(req CreatePullRequestRequest) (*models
 	if req.IsUpdate {
 		return g.updatePullRequestWithGH(req.Worktree, ownerRepo, req.Title, req.Body, req.ForcePush)
 	} else {
-		return g.createPullRequestWithGH(req.Worktree, ownerRepo, req.Title, req.Body, req.ForcePush)
+		return g.createPullRequestWithGH(req.Worktree, ownerRepo, req.Title, req.Body, req.ForcePush, req.Draft)
 	}
 }
 
@@ -192,6 +226,7 @@ func (g *GitHubManager) updatePullRequestWithGH(worktree *models.Worktree, owner
 
 	_, err := cmd.Output()
 	if err != nil {
+		g.observeRateLimit(RateLimitGraphQL, err, nil)
 		// For error reporting, capture stderr if available
 		if exitErr, ok := err.(*exec.ExitError); ok {
 			return nil, fmt.Errorf("failed to update PR: %v\nStderr: %s", err, string(exitErr.Stderr))
@@ -202,7 +237,7 @@ func (g *GitHubManager) updatePullRequestWithGH(worktree *models.Worktree, owner
 	logger.Infof("✅ Updated PR for branch %s", worktree.Branch)
 
 	// Get the PR details
-	cmd = g.execCommand("gh", "pr", "view", worktree.Branch, "--repo", ownerRepo, "--json", "number,url,title,body")
+	cmd = g.execCommand("gh", "pr", "view", worktree.Branch, "--repo", ownerRepo, "--json", "number,url,title,body,baseRefName")
 	output, err := cmd.Output()
 	if err != nil {
 		logger.Warnf("⚠️ Could not get PR details: %v", err)
@@ -215,10 +250,11 @@ func (g *GitHubManager) updatePullRequestWithGH(worktree *models.Worktree, owner
 	}
 
 	var result struct {
-		Number int    `json:"number"`
-		URL    string `json:"url"`
-		Title  string `json:"title"`
-		Body   string `json:"body"`
+		Number      int    `json:"number"`
+		URL         string `json:"url"`
+		Title       string `json:"title"`
+		Body        string `json:"body"`
+		BaseRefName string `json:"baseRefName"`
 	}
 	if err := json.Unmarshal(output, &result); err != nil {
 		logger.Warnf("⚠️ Could not parse PR details: %v", err)
@@ -236,12 +272,21 @@ func (g *GitHubManager) updatePullRequestWithGH(worktree *models.Worktree, owner
 		Title:      result.Title,
 		Body:       result.Body,
 		HeadBranch: branchToPush,
-		BaseBranch: worktree.SourceBranch,
+		BaseBranch: pullRequestBase(result.BaseRefName, worktree),
 	}, nil
 }
 
+// pullRequestBase returns the base branch GitHub reported for a PR, the worktree's source branch
+// when it wasn't reported
+func pullRequestBase(baseRefName string, worktree *models.Worktree) string {
+	if baseRefName != "" {
+		return baseRefName
+	}
+	return worktree.SourceBranch
+}
+
 // createPullRequestWithGH creates a new PR using GitHub CLI
-func (g *GitHubManager) createPullRequestWithGH(worktree *models.Worktree, ownerRepo, title, body string, forcePush bool) (*models.PullRequestResponse, error) {
+func (g *GitHubManager) createPullRequestWithGH(worktree *models.Worktree, ownerRepo, title, body string, forcePush, draft bool) (*models.PullRequestResponse, error) {
 	logger.Debugf("🚀 Creating PR for branch %s in %s", worktree.Branch, ownerRepo)
 
 	// Handle custom refs (e.g., refs/catnip/ninja) by using the nice branch for pushing
@@ -305,15 +350,20 @@ func (g *GitHubManager) createPullRequestWithGH(worktree *models.Worktree, owner
 
 	// Create the PR
 	logger.Debugf("🔍 PR Creation: About to create PR with gh pr create --repo %s", ownerRepo)
-	cmd := g.execCommand("gh", "pr", "create",
+	args := []string{"pr", "create",
 		"--repo", ownerRepo,
 		"--base", worktree.SourceBranch,
 		"--head", branchToPush,
 		"--title", title,
-		"--body", body)
+		"--body", body}
+	if draft {
+		args = append(args, "--draft")
+	}
+	cmd := g.execCommand("gh", args...)
 
 	output, err := cmd.Output()
 	if err != nil {
+		g.observeRateLimit(RateLimitGraphQL, err, nil)
 		// For error checking, we need to capture stderr separately
 		if exitErr, ok := err.(*exec.ExitError); ok {
 			stderr := string(exitErr.Stderr)
@@ -371,10 +421,11 @@ func (g *GitHubManager) createPullRequestWithGH(worktree *models.Worktree, owner
 // checkExistingPR checks if a PR already exists for the branch
 func (g *GitHubManager) checkExistingPR(worktree *models.Worktree, ownerRepo string, prInfo *models.PullRequestInfo) error {
 	// Use GitHub CLI to check for existing PR
-	cmd := g.execCommand("gh", "pr", "view", worktree.Branch, "--repo", ownerRepo, "--json", "number,url,title,body")
+	cmd := g.execCommand("gh", "pr", "view", worktree.Branch, "--repo", ownerRepo, "--json", "number,url,title,body,baseRefName")
 
 	output, err := cmd.Output()
 	if err != nil {
+		g.observeRateLimit(RateLimitGraphQL, err, nil)
 		// If no PR exists, that's fine
 		if strings.Contains(err.Error(), "no pull requests found") || strings.Contains(err.Error(), "not found") {
 			return nil
@@ -384,10 +435,11 @@ func (g *GitHubManager) checkExistingPR(worktree *models.Worktree, ownerRepo str
 
 	// Parse the existing PR info
 	var existingPR struct {
-		Number int    `json:"number"`
-		URL    string `json:"url"`
-		Title  string `json:"title"`
-		Body   string `json:"body"`
+		Number      int    `json:"number"`
+		URL         string `json:"url"`
+		Title       string `json:"title"`
+		Body        string `json:"body"`
+		BaseRefName string `json:"baseRefName"`
 	}
 
 	if err := json.Unmarshal(output, &existingPR); err != nil {
@@ -400,6 +452,7 @@ func (g *GitHubManager) checkExistingPR(worktree *models.Worktree, ownerRepo str
 	prInfo.URL = existingPR.URL
 	prInfo.Title = existingPR.Title
 	prInfo.Body = existingPR.Body
+	prInfo.BaseBranch = existingPR.BaseRefName
 
 	logger.Debugf("✅ Found existing PR #%d for branch %s", existingPR.Number, worktree.Branch)
 	return nil
@@ -411,22 +464,37 @@ func (g *GitHubManager) IsAuthenticated() bool {
 	return cmd.Run() == nil
 }
 
-// ConfigureGitCredentials sets up Git to use gh CLI for GitHub authentication
-func (g *GitHubManager) ConfigureGitCredentials() error {
+// ConfigureGitCredentials makes gh the credential helper of github.com and the GitHub
+// Enterprise hosts gh is logged in to. It is idempotent and surgical: the host specific helper
+// key is only rewritten when gh is missing, configured twice or shadowed by another helper,
+// other helpers are kept, and every host is verified with a credential fill.
+func (g *GitHubManager) ConfigureGitCredentials() (*CredentialHelperReport, error) {
 	if config.Runtime.IsNative() {
 		logger.Debugf("ℹ️ Running in native mode - skipping git credential configuration")
-		return nil
+		return nil, nil
 	}
 
 	if !g.IsAuthenticated() {
 		logger.Warnf("ℹ️ GitHub CLI not authenticated, Git operations will only work with public repositories")
-		return fmt.Errorf("GitHub CLI not authenticated")
+		return nil, fmt.Errorf("GitHub CLI not authenticated")
 	}
 
 	logger.Debugf("🔐 Configuring Git to use GitHub CLI for authentication")
 
-	// Configure Git to use gh as credential helper for GitHub
-	return g.operations.SetGlobalConfig("credential.https://github.com.helper", "!gh auth git-credential")
+	setup := credentialHelperSetup{homeDir: config.Runtime.HomeDir}
+	report, err := setup.configure(ghHosts(config.Runtime.HomeDir))
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range report.Changed {
+		logger.Infof("🔐 Rewrote %s so gh answers first", key)
+	}
+	for _, host := range report.Hosts {
+		if host.Problem != "" || !host.Verified {
+			logger.Warnf("⚠️  Git credentials for %s are not working: %s", host.Host, strings.TrimSpace(host.Problem+" "+host.VerifyError))
+		}
+	}
+	return report, nil
 }
 
 // GitHubRepository represents a GitHub repository from the API
@@ -456,6 +524,136 @@ func (g *GitHubManager) ListRepositories() ([]GitHubRepository, error) {
 	return repos, nil
 }
 
+// maxLabeledIssues bounds the issues ListLabeledIssues returns, most recently created first
+const maxLabeledIssues = 200
+
+// GitHubIssue is an issue returned by ListLabeledIssues
+type GitHubIssue struct {
+	Number int    `json:"number"`
+	Title  string `json:"title"`
+	Body   string `json:"body"`
+	URL    string `json:"url"`
+	State  string `json:"state"` // OPEN or CLOSED
+}
+
+// ListLabeledIssues lists the open and closed issues of a repository carrying a label
+func (g *GitHubManager) ListLabeledIssues(ownerRepo, label string) ([]GitHubIssue, error) {
+	cmd := g.execCommand("gh", "issue", "list", "--repo", ownerRepo, "--label", label, "--state", "all",
+		"--limit", fmt.Sprintf("%d", maxLabeledIssues), "--json", "number,title,body,url,state")
+
+	output, err := cmd.Output()
+	if err != nil {
+		g.observeRateLimit(RateLimitGraphQL, err, nil)
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return nil, fmt.Errorf("failed to list issues of %s: %v\nStderr: %s", ownerRepo, err, string(exitErr.Stderr))
+		}
+		return nil, fmt.Errorf("failed to list issues of %s: %w", ownerRepo, err)
+	}
+
+	var issues []GitHubIssue
+	if err := json.Unmarshal(output, &issues); err != nil {
+		return nil, fmt.Errorf("failed to parse issues of %s: %w", ownerRepo, err)
+	}
+	return issues, nil
+}
+
+// CommentOnIssue posts a comment on an issue
+func (g *GitHubManager) CommentOnIssue(ownerRepo string, number int, body string) error {
+	cmd := g.execCommand("gh", "issue", "comment", fmt.Sprintf("%d", number), "--repo", ownerRepo, "--body", body)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		g.observeRateLimit(RateLimitGraphQL, err, output)
+		return fmt.Errorf("failed to comment on issue %s#%d: %v\nOutput: %s", ownerRepo, number, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// Bounds of the review threads, and comments per thread, ListReviewThreads reads
+const (
+	maxReviewThreads        = 100
+	maxReviewThreadComments = 20
+)
+
+// reviewThreadsQuery reads the review threads of a pull request with their comments
+var reviewThreadsQuery = fmt.Sprintf(`query($owner: String!, $name: String!, $number: Int!) {
+  repository(owner: $owner, name: $name) {
+    pullRequest(number: $number) {
+      reviewThreads(first: %d) {
+        nodes {
+          isResolved
+          isOutdated
+          path
+          line
+          comments(first: %d) {
+            nodes { author { login } body url createdAt }
+          }
+        }
+      }
+    }
+  }
+}`, maxReviewThreads, maxReviewThreadComments)
+
+// ListReviewThreads lists the unresolved review threads of a pull request
+func (g *GitHubManager) ListReviewThreads(ownerRepo string, number int) ([]models.ReviewThread, error) {
+	owner, name, found := strings.Cut(ownerRepo, "/")
+	if !found {
+		return nil, fmt.Errorf("invalid repository %q, expected owner/name", ownerRepo)
+	}
+	output, err := RunGHAPI("graphql", "-f", "query="+reviewThreadsQuery,
+		"-f", "owner="+owner, "-f", "name="+name, "-F", fmt.Sprintf("number=%d", number))
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return nil, fmt.Errorf("failed to list review threads of %s#%d: %v\nStderr: %s", ownerRepo, number, err, string(exitErr.Stderr))
+		}
+		return nil, fmt.Errorf("failed to list review threads of %s#%d: %w", ownerRepo, number, err)
+	}
+
+	var response struct {
+		Data struct {
+			Repository struct {
+				PullRequest struct {
+					ReviewThreads struct {
+						Nodes []struct {
+							IsResolved bool   `json:"isResolved"`
+							IsOutdated bool   `json:"isOutdated"`
+							Path       string `json:"path"`
+							Line       int    `json:"line"`
+							Comments   struct {
+								Nodes []struct {
+									Author    struct{ Login string } `json:"author"`
+									Body      string                 `json:"body"`
+									URL       string                 `json:"url"`
+									CreatedAt time.Time              `json:"createdAt"`
+								} `json:"nodes"`
+							} `json:"comments"`
+						} `json:"nodes"`
+					} `json:"reviewThreads"`
+				} `json:"pullRequest"`
+			} `json:"repository"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(output, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse review threads of %s#%d: %w", ownerRepo, number, err)
+	}
+
+	threads := []models.ReviewThread{}
+	for _, node := range response.Data.Repository.PullRequest.ReviewThreads.Nodes {
+		if node.IsResolved {
+			continue
+		}
+		thread := models.ReviewThread{Path: node.Path, Line: node.Line, Outdated: node.IsOutdated, Comments: []models.ReviewComment{}}
+		for _, comment := range node.Comments.Nodes {
+			thread.Comments = append(thread.Comments, models.ReviewComment{
+				Author:    comment.Author.Login,
+				Body:      comment.Body,
+				URL:       comment.URL,
+				CreatedAt: comment.CreatedAt,
+			})
+		}
+		threads = append(threads, thread)
+	}
+	return threads, nil
+}
+
 // CreateRepository creates a new GitHub repository
 func (g *GitHubManager) CreateRepository(name, description string, isPrivate bool) (string, error) {
 	args := []string{"repo", "create", name, "--description", description}
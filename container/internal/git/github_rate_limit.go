@@ -0,0 +1,373 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/textproto"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// GitHub rate limit resources catnip spends
+const (
+	// RateLimitCore is the REST API, used by gh api repos/...
+	RateLimitCore = "core"
+	// RateLimitGraphQL is the GraphQL API, used by gh pr, gh issue and gh repo
+	RateLimitGraphQL = "graphql"
+)
+
+// Throttle levels, from least to most restrictive
+const (
+	// ThrottleNormal spends the budget freely
+	ThrottleNormal = "normal"
+	// ThrottleSlow stretches polling intervals
+	ThrottleSlow = "slow"
+	// ThrottlePaused pauses background refreshes and queues user operations that aren't urgent
+	ThrottlePaused = "paused"
+	// ThrottleExhausted waits for the limit to reset before anything runs
+	ThrottleExhausted = "exhausted"
+)
+
+var throttleRank = map[string]int{ThrottleNormal: 0, ThrottleSlow: 1, ThrottlePaused: 2, ThrottleExhausted: 3}
+
+const (
+	// DefaultRateLimitSlowPercent is the share of a limit left below which polling slows down
+	DefaultRateLimitSlowPercent = 20
+	// DefaultRateLimitReservePercent is the share of a limit kept for urgent operations
+	DefaultRateLimitReservePercent = 5
+	// rateLimitSlowdown is how much polling intervals are stretched while slow
+	rateLimitSlowdown = 4
+	// rateLimitRefreshAge is how old the known limits may get before gh api rate_limit, which
+	// doesn't count against them, is asked again
+	rateLimitRefreshAge = 5 * time.Minute
+	// rateLimitRetry is how long an exceeded limit whose reset is unknown is waited out
+	rateLimitRetry = time.Minute
+)
+
+// rateLimitMessages are what gh prints when GitHub refuses a request for exceeding a limit
+var rateLimitMessages = []string{"rate limit exceeded", "secondary rate limit", "abuse detection"}
+
+// RateLimitTracker keeps the GitHub rate limit budget seen in API responses and decides how
+// much polling and which operations it allows
+type RateLimitTracker struct {
+	mu             sync.Mutex
+	resources      map[string]*models.GitHubRateLimitResource
+	slowPercent    int
+	reservePercent int
+	refreshedAt    time.Time
+
+	now   func() time.Time
+	fetch func() ([]byte, error)
+}
+
+// NewRateLimitTracker creates a tracker with thresholds from CATNIP_GITHUB_RATE_SLOW_PERCENT and
+// CATNIP_GITHUB_RATE_RESERVE_PERCENT, asking gh api rate_limit for the limits
+func NewRateLimitTracker() *RateLimitTracker {
+	return &RateLimitTracker{
+		resources:      make(map[string]*models.GitHubRateLimitResource),
+		slowPercent:    percentFromEnv("CATNIP_GITHUB_RATE_SLOW_PERCENT", DefaultRateLimitSlowPercent),
+		reservePercent: percentFromEnv("CATNIP_GITHUB_RATE_RESERVE_PERCENT", DefaultRateLimitReservePercent),
+		now:            time.Now,
+		fetch: func() ([]byte, error) {
+			return exec.Command("gh", "api", "rate_limit").Output()
+		},
+	}
+}
+
+// percentFromEnv reads a percentage from key, def when unset or out of range
+func percentFromEnv(key string, def int) int {
+	if value, err := strconv.Atoi(os.Getenv(key)); err == nil && value >= 0 && value <= 100 {
+		return value
+	}
+	return def
+}
+
+var gitHubRateLimits = NewRateLimitTracker()
+
+// GitHubRateLimits returns the tracker shared by everything that talks to GitHub
+func GitHubRateLimits() *RateLimitTracker {
+	return gitHubRateLimits
+}
+
+// ObserveHeaders records the X-RateLimit-* headers of a GitHub API response
+func (t *RateLimitTracker) ObserveHeaders(header http.Header) {
+	limit, err := strconv.Atoi(header.Get("X-RateLimit-Limit"))
+	if err != nil {
+		return
+	}
+	remaining, _ := strconv.Atoi(header.Get("X-RateLimit-Remaining"))
+	used, _ := strconv.Atoi(header.Get("X-RateLimit-Used"))
+	reset, _ := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64)
+	resource := header.Get("X-RateLimit-Resource")
+	if resource == "" {
+		resource = RateLimitCore
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.record(resource, limit, remaining, used, time.Unix(reset, 0))
+}
+
+// record stores the budget of a resource, logging throttle changes. Callers hold t.mu.
+func (t *RateLimitTracker) record(resource string, limit, remaining, used int, resetAt time.Time) {
+	before := t.throttleLocked(resource)
+	t.resources[resource] = &models.GitHubRateLimitResource{
+		Resource:  resource,
+		Limit:     limit,
+		Remaining: remaining,
+		Used:      used,
+		ResetAt:   resetAt,
+		UpdatedAt: t.now(),
+	}
+	if after := t.throttleLocked(resource); after != before {
+		logger.Infof("🚦 GitHub %s rate limit: %d of %d left until %s, throttle %s -> %s",
+			resource, remaining, limit, resetAt.Format(time.Kitchen), before, after)
+	}
+}
+
+// ObserveError marks resource as exhausted when err, or output of a gh command that failed, says
+// GitHub refused the request for exceeding a rate limit. It returns whether it did.
+func (t *RateLimitTracker) ObserveError(resource string, err error, output []byte) bool {
+	if err == nil {
+		return false
+	}
+	message := err.Error() + "\n" + string(output)
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		message += "\n" + string(exitErr.Stderr)
+	}
+	message = strings.ToLower(message)
+	limited := false
+	for _, needle := range rateLimitMessages {
+		if strings.Contains(message, needle) {
+			limited = true
+			break
+		}
+	}
+	if !limited {
+		return false
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := t.now()
+	limit, resetAt := 0, now.Add(rateLimitRetry)
+	if current, ok := t.resources[resource]; ok {
+		limit = current.Limit
+		if current.ResetAt.After(resetAt) {
+			resetAt = current.ResetAt
+		}
+	}
+	t.record(resource, limit, 0, limit, resetAt)
+	return true
+}
+
+// Refresh asks GitHub for the current limits, which doesn't count against them
+func (t *RateLimitTracker) Refresh() error {
+	t.mu.Lock()
+	t.refreshedAt = t.now()
+	fetch := t.fetch
+	t.mu.Unlock()
+
+	output, err := fetch()
+	if err != nil {
+		return fmt.Errorf("failed to get GitHub rate limits: %v", err)
+	}
+	var response struct {
+		Resources map[string]struct {
+			Limit     int   `json:"limit"`
+			Remaining int   `json:"remaining"`
+			Used      int   `json:"used"`
+			Reset     int64 `json:"reset"`
+		} `json:"resources"`
+	}
+	if err := json.Unmarshal(output, &response); err != nil {
+		return fmt.Errorf("failed to parse GitHub rate limits: %v", err)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, resource := range []string{RateLimitCore, RateLimitGraphQL} {
+		if limits, ok := response.Resources[resource]; ok {
+			t.record(resource, limits.Limit, limits.Remaining, limits.Used, time.Unix(limits.Reset, 0))
+		}
+	}
+	return nil
+}
+
+// RefreshIfStale refreshes the limits when the known ones are older than rateLimitRefreshAge,
+// trying at most that often
+func (t *RateLimitTracker) RefreshIfStale() {
+	t.mu.Lock()
+	now := t.now()
+	stale := now.Sub(t.refreshedAt) >= rateLimitRefreshAge
+	for _, resource := range []string{RateLimitCore, RateLimitGraphQL} {
+		if current, ok := t.resources[resource]; ok && now.Sub(current.UpdatedAt) < rateLimitRefreshAge {
+			stale = false
+		}
+	}
+	t.mu.Unlock()
+	if !stale {
+		return
+	}
+	if err := t.Refresh(); err != nil {
+		logger.Debugf("🚦 %v", err)
+	}
+}
+
+// current returns the known budget of resource, nil when unknown or its window has reset.
+// Callers hold t.mu.
+func (t *RateLimitTracker) current(resource string) *models.GitHubRateLimitResource {
+	current, ok := t.resources[resource]
+	if !ok || !t.now().Before(current.ResetAt) {
+		return nil
+	}
+	return current
+}
+
+// throttleLocked returns the throttle level of resource. Callers hold t.mu.
+func (t *RateLimitTracker) throttleLocked(resource string) string {
+	current := t.current(resource)
+	if current == nil {
+		return ThrottleNormal
+	}
+	switch {
+	case current.Remaining <= 0:
+		return ThrottleExhausted
+	case current.Remaining*100 < current.Limit*t.reservePercent:
+		return ThrottlePaused
+	case current.Remaining*100 < current.Limit*t.slowPercent:
+		return ThrottleSlow
+	}
+	return ThrottleNormal
+}
+
+// Throttle returns how much spending of resource is held back
+func (t *RateLimitTracker) Throttle(resource string) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.throttleLocked(resource)
+}
+
+// resetAt returns when the window of resource resets, now when it isn't limited. Callers hold
+// t.mu.
+func (t *RateLimitTracker) resetAt(resource string) time.Time {
+	if current := t.current(resource); current != nil {
+		return current.ResetAt
+	}
+	return t.now()
+}
+
+// PollInterval stretches the interval of a background poll spending resource: by
+// rateLimitSlowdown while slow, until the limit resets while paused or exhausted
+func (t *RateLimitTracker) PollInterval(resource string, interval time.Duration) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	switch t.throttleLocked(resource) {
+	case ThrottleSlow:
+		return interval * rateLimitSlowdown
+	case ThrottlePaused, ThrottleExhausted:
+		if untilReset := t.resetAt(resource).Sub(t.now()); untilReset > interval {
+			return untilReset
+		}
+	}
+	return interval
+}
+
+// AllowBackground returns whether a refresh nobody is waiting for may spend resource
+func (t *RateLimitTracker) AllowBackground(resource string) bool {
+	return throttleRank[t.Throttle(resource)] < throttleRank[ThrottlePaused]
+}
+
+// ReadyAt returns when a user operation spending resource may run: now, or when the limit
+// resets. Urgent operations may spend the reserve, but not an exhausted limit.
+func (t *RateLimitTracker) ReadyAt(resource string, urgent bool) time.Time {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	switch t.throttleLocked(resource) {
+	case ThrottleExhausted:
+		return t.resetAt(resource)
+	case ThrottlePaused:
+		if !urgent {
+			return t.resetAt(resource)
+		}
+	}
+	return t.now()
+}
+
+// Status returns the known budgets and the most restrictive throttle among them
+func (t *RateLimitTracker) Status() *models.GitHubRateLimitStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	status := &models.GitHubRateLimitStatus{Throttle: ThrottleNormal, Resources: []models.GitHubRateLimitResource{}}
+	for _, resource := range []string{RateLimitCore, RateLimitGraphQL} {
+		current, ok := t.resources[resource]
+		if !ok {
+			continue
+		}
+		entry := *current
+		entry.Throttle = t.throttleLocked(resource)
+		if t.current(resource) == nil {
+			// The window reset since the budget was seen
+			entry.Remaining, entry.Used = entry.Limit, 0
+		}
+		if throttleRank[entry.Throttle] > throttleRank[status.Throttle] {
+			status.Throttle = entry.Throttle
+		}
+		status.Resources = append(status.Resources, entry)
+	}
+	return status
+}
+
+// RunGHAPI runs gh api with args, recording the rate limit headers of the response, and returns
+// the response body
+func RunGHAPI(args ...string) ([]byte, error) {
+	resource := RateLimitCore
+	if len(args) > 0 && args[0] == "graphql" {
+		resource = RateLimitGraphQL
+	}
+	output, err := exec.Command("gh", append([]string{"api", "--include"}, args...)...).Output()
+	header, body, parseErr := SplitAPIResponse(output)
+	if parseErr == nil {
+		if header.Get("X-RateLimit-Resource") == "" {
+			header.Set("X-RateLimit-Resource", resource)
+		}
+		GitHubRateLimits().ObserveHeaders(header)
+	}
+	if err != nil {
+		GitHubRateLimits().ObserveError(resource, err, output)
+		return body, err
+	}
+	if parseErr != nil {
+		return nil, parseErr
+	}
+	return body, nil
+}
+
+// SplitAPIResponse splits the output of gh api --include into the response headers and body
+func SplitAPIResponse(output []byte) (http.Header, []byte, error) {
+	reader := bufio.NewReader(bytes.NewReader(output))
+	tp := textproto.NewReader(reader)
+	status, err := tp.ReadLine()
+	if err != nil || !strings.HasPrefix(status, "HTTP/") {
+		return nil, nil, fmt.Errorf("gh api output doesn't start with an HTTP status line")
+	}
+	header, err := tp.ReadMIMEHeader()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse gh api response headers: %v", err)
+	}
+	body := bytes.NewBuffer(nil)
+	_, _ = body.ReadFrom(reader)
+	return http.Header(header), body.Bytes(), nil
+}
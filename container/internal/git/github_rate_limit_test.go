@@ -0,0 +1,139 @@
+package git
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRateLimitTracker(now *time.Time) *RateLimitTracker {
+	t := NewRateLimitTracker()
+	t.slowPercent, t.reservePercent = DefaultRateLimitSlowPercent, DefaultRateLimitReservePercent
+	t.now = func() time.Time { return *now }
+	t.fetch = func() ([]byte, error) { return nil, errors.New("offline") }
+	return t
+}
+
+func rateLimitHeaders(resource string, limit, remaining int, reset time.Time) http.Header {
+	header := http.Header{}
+	header.Set("X-RateLimit-Limit", strconv.Itoa(limit))
+	header.Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+	header.Set("X-RateLimit-Used", strconv.Itoa(limit-remaining))
+	header.Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+	header.Set("X-RateLimit-Resource", resource)
+	return header
+}
+
+func TestRateLimitTrackerThrottle(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	tracker := newTestRateLimitTracker(&now)
+	reset := now.Add(30 * time.Minute)
+
+	assert.Equal(t, ThrottleNormal, tracker.Throttle(RateLimitGraphQL), "unknown limits don't throttle")
+
+	steps := []struct {
+		remaining  int
+		throttle   string
+		interval   time.Duration
+		background bool
+	}{
+		{4000, ThrottleNormal, time.Minute, true},
+		{999, ThrottleSlow, 4 * time.Minute, true},
+		{249, ThrottlePaused, 30 * time.Minute, false},
+		{0, ThrottleExhausted, 30 * time.Minute, false},
+	}
+	for _, step := range steps {
+		tracker.ObserveHeaders(rateLimitHeaders(RateLimitGraphQL, 5000, step.remaining, reset))
+		assert.Equal(t, step.throttle, tracker.Throttle(RateLimitGraphQL), "%d remaining", step.remaining)
+		assert.Equal(t, step.interval, tracker.PollInterval(RateLimitGraphQL, time.Minute), "%d remaining", step.remaining)
+		assert.Equal(t, step.background, tracker.AllowBackground(RateLimitGraphQL), "%d remaining", step.remaining)
+	}
+	assert.Equal(t, ThrottleNormal, tracker.Throttle(RateLimitCore), "resources are tracked separately")
+
+	status := tracker.Status()
+	assert.Equal(t, ThrottleExhausted, status.Throttle)
+	require.Len(t, status.Resources, 1)
+	assert.Equal(t, 0, status.Resources[0].Remaining)
+	assert.Equal(t, 5000, status.Resources[0].Used)
+
+	now = reset
+	assert.Equal(t, ThrottleNormal, tracker.Throttle(RateLimitGraphQL), "a reset window is full again")
+	assert.True(t, tracker.AllowBackground(RateLimitGraphQL))
+	status = tracker.Status()
+	assert.Equal(t, ThrottleNormal, status.Throttle)
+	assert.Equal(t, 5000, status.Resources[0].Remaining)
+}
+
+func TestRateLimitTrackerReadyAt(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	tracker := newTestRateLimitTracker(&now)
+	reset := now.Add(10 * time.Minute)
+
+	tracker.ObserveHeaders(rateLimitHeaders(RateLimitCore, 5000, 900, reset))
+	assert.Equal(t, now, tracker.ReadyAt(RateLimitCore, false), "slow polling doesn't hold back users")
+
+	tracker.ObserveHeaders(rateLimitHeaders(RateLimitCore, 5000, 100, reset))
+	assert.Equal(t, reset, tracker.ReadyAt(RateLimitCore, false))
+	assert.Equal(t, now, tracker.ReadyAt(RateLimitCore, true), "urgent operations spend the reserve")
+
+	tracker.ObserveHeaders(rateLimitHeaders(RateLimitCore, 5000, 0, reset))
+	assert.Equal(t, reset, tracker.ReadyAt(RateLimitCore, true), "nothing runs on an exhausted limit")
+}
+
+func TestRateLimitTrackerObserveError(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	tracker := newTestRateLimitTracker(&now)
+
+	assert.False(t, tracker.ObserveError(RateLimitGraphQL, nil, nil))
+	assert.False(t, tracker.ObserveError(RateLimitGraphQL, errors.New("exit status 1"), []byte("HTTP 404: Not Found")))
+	assert.Equal(t, ThrottleNormal, tracker.Throttle(RateLimitGraphQL))
+
+	require.True(t, tracker.ObserveError(RateLimitGraphQL, errors.New("exit status 1"), []byte("GraphQL: API rate limit exceeded for user ID 1.")))
+	assert.Equal(t, ThrottleExhausted, tracker.Throttle(RateLimitGraphQL))
+	assert.Equal(t, now.Add(rateLimitRetry), tracker.ReadyAt(RateLimitGraphQL, true), "an unknown reset is retried after a minute")
+
+	reset := now.Add(time.Hour)
+	tracker.ObserveHeaders(rateLimitHeaders(RateLimitCore, 5000, 10, reset))
+	require.True(t, tracker.ObserveError(RateLimitCore, errors.New("You have exceeded a secondary rate limit"), nil))
+	assert.Equal(t, reset, tracker.ReadyAt(RateLimitCore, true), "a known reset is kept")
+}
+
+func TestRateLimitTrackerRefresh(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	tracker := newTestRateLimitTracker(&now)
+	fetches := 0
+	tracker.fetch = func() ([]byte, error) {
+		fetches++
+		return []byte(`{"resources":{"core":{"limit":5000,"remaining":4999,"used":1,"reset":1700003600},` +
+			`"graphql":{"limit":5000,"remaining":200,"used":4800,"reset":1700003600}}}`), nil
+	}
+
+	tracker.RefreshIfStale()
+	assert.Equal(t, 1, fetches)
+	assert.Equal(t, ThrottleNormal, tracker.Throttle(RateLimitCore))
+	assert.Equal(t, ThrottlePaused, tracker.Throttle(RateLimitGraphQL))
+
+	now = now.Add(time.Minute)
+	tracker.RefreshIfStale()
+	assert.Equal(t, 1, fetches, "recent limits aren't refreshed")
+
+	now = now.Add(rateLimitRefreshAge)
+	tracker.RefreshIfStale()
+	assert.Equal(t, 2, fetches)
+}
+
+func TestSplitAPIResponse(t *testing.T) {
+	output := "HTTP/2.0 200 OK\r\nX-Ratelimit-Limit: 5000\r\nX-Ratelimit-Remaining: 4321\r\n\r\n{\"data\":{}}"
+	header, body, err := SplitAPIResponse([]byte(output))
+	require.NoError(t, err)
+	assert.Equal(t, "4321", header.Get("X-RateLimit-Remaining"))
+	assert.Equal(t, `{"data":{}}`, string(body))
+
+	_, _, err = SplitAPIResponse([]byte(`{"data":{}}`))
+	assert.Error(t, err)
+}
@@ -0,0 +1,106 @@
+package git
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// DefaultMaxBranchLength caps the branch names catnip renames worktrees to
+	DefaultMaxBranchLength = 80
+	// DefaultMaxWorktreePathLength caps the absolute path of new worktrees, leaving room for
+	// the files inside them in tools that still assume short paths
+	DefaultMaxWorktreePathLength = 200
+	// maxWorktreeDirNameLength caps the directory name of a worktree, whatever its branch
+	maxWorktreeDirNameLength = 48
+	// nameHashLength is the length of the hash ending shortened names
+	nameHashLength = 7
+	// nameMax is the longest file name Linux filesystems accept (NAME_MAX)
+	nameMax = 255
+	// pathMax is the longest path Linux system calls accept, including the NUL (PATH_MAX)
+	pathMax = 4096
+)
+
+var nonSlugChars = regexp.MustCompile(`[^a-z0-9._-]+`)
+
+// nameHash returns a short hash identifying a full name after shortening
+func nameHash(name string) string {
+	sum := sha1.Sum([]byte(name))
+	return hex.EncodeToString(sum[:])[:nameHashLength]
+}
+
+// truncateUTF8 cuts s to at most n bytes without splitting a character
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// TruncateBranchName shortens a branch name longer than maxLength to its beginning followed by
+// a hash of the full name, so distinct long names stay distinct. A maxLength of 0 keeps names
+// as they are.
+func TruncateBranchName(name string, maxLength int) string {
+	if maxLength <= 0 || len(name) <= maxLength {
+		return name
+	}
+	suffix := "-" + nameHash(name)
+	// Separators can't end a ref component, and "-/" or "-." would read badly anyway
+	head := strings.TrimRight(truncateUTF8(name, maxLength-len(suffix)), "-/.")
+	return head + suffix
+}
+
+// WorktreeDirName returns the directory of a branch's worktree inside the repository's
+// workspace directory. Short workspace names are used as they are; longer ones become a
+// lowercase slug shortened to maxWorktreeDirNameLength with a hash of the full branch name, so
+// long branch names never lengthen worktree paths.
+func WorktreeDirName(branchName string) string {
+	name := ExtractWorkspaceName(branchName)
+	if len(name) <= maxWorktreeDirNameLength {
+		return name
+	}
+	suffix := "-" + nameHash(branchName)
+	slug := strings.Trim(nonSlugChars.ReplaceAllString(strings.ToLower(name), "-"), "-.")
+	if len(slug) > maxWorktreeDirNameLength-len(suffix) {
+		slug = slug[:maxWorktreeDirNameLength-len(suffix)]
+	}
+	return strings.TrimRight(slug, "-.") + suffix
+}
+
+// ValidateWorktreePath checks that git can create the worktree of branchName at worktreePath
+// before running it, so overlong names fail with a clear error rather than deep inside git:
+// the path has to fit maxPathLength (0 for no limit), and neither its directories nor the lock
+// files git writes for the branch ref in repoPath may exceed Linux filesystem limits.
+func ValidateWorktreePath(repoPath, worktreePath, branchName string, maxPathLength int) error {
+	if maxPathLength > 0 && len(worktreePath) > maxPathLength {
+		return fmt.Errorf("worktree path %s is %d characters long, over the limit of %d (git config catnip.worktree.max-path-length)",
+			worktreePath, len(worktreePath), maxPathLength)
+	}
+	for _, component := range strings.Split(worktreePath, string(filepath.Separator)) {
+		if len(component) > nameMax {
+			return fmt.Errorf("worktree path %s has a directory name longer than %d bytes", worktreePath, nameMax)
+		}
+	}
+
+	ref := branchName
+	if !strings.HasPrefix(ref, "refs/") {
+		ref = "refs/heads/" + ref
+	}
+	for _, component := range strings.Split(ref, "/") {
+		if len(component)+len(".lock") > nameMax {
+			return fmt.Errorf("branch %q has a path component longer than %d bytes, which git can't lock", branchName, nameMax-len(".lock"))
+		}
+	}
+	if lockFile := filepath.Join(repoPath, filepath.FromSlash(ref)) + ".lock"; len(lockFile) >= pathMax {
+		return fmt.Errorf("branch %q is too long for its lock file in %s (%d bytes, limit %d)", branchName, repoPath, len(lockFile), pathMax-1)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package git
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// longTitleBranch turns a 200 character session title into the branch name Claude might suggest
+func longTitleBranch() string {
+	title := strings.Repeat("refactor authentication middleware ", 6)[:200]
+	return "feature/" + strings.ReplaceAll(strings.TrimSpace(title), " ", "-")
+}
+
+func TestTruncateBranchName(t *testing.T) {
+	branch := longTitleBranch()
+	require.Greater(t, len(branch), 200)
+
+	truncated := TruncateBranchName(branch, DefaultMaxBranchLength)
+	assert.Len(t, truncated, DefaultMaxBranchLength)
+	assert.True(t, strings.HasPrefix(truncated, "feature/refactor-authentication-middleware-"))
+	assert.Regexp(t, `-[0-9a-f]{7}$`, truncated)
+	assert.Equal(t, truncated, TruncateBranchName(branch, DefaultMaxBranchLength), "truncation is stable")
+	assert.NotEqual(t, truncated, TruncateBranchName(branch+"-v2", DefaultMaxBranchLength), "distinct names stay distinct")
+
+	assert.Equal(t, "feature/add-auth", TruncateBranchName("feature/add-auth", DefaultMaxBranchLength))
+	assert.Equal(t, branch, TruncateBranchName(branch, 0))
+
+	// Cuts never leave a separator or half a character in front of the hash
+	assert.Regexp(t, `^feature-[0-9a-f]{7}$`, TruncateBranchName("feature/"+strings.Repeat("x", 40), 16))
+	assert.Regexp(t, `^fix/caf-[0-9a-f]{7}$`, TruncateBranchName("fix/café-"+strings.Repeat("x", 40), 16))
+}
+
+func TestWorktreeDirName(t *testing.T) {
+	assert.Equal(t, "felix", WorktreeDirName("refs/catnip/felix"))
+	assert.Equal(t, "feature/add-auth", WorktreeDirName("feature/add-auth"))
+
+	name := WorktreeDirName(longTitleBranch())
+	assert.LessOrEqual(t, len(name), maxWorktreeDirNameLength)
+	assert.Regexp(t, `^feature-refactor-authentication-[0-9a-z-]*[0-9a-z]-[0-9a-f]{7}$`, name)
+	assert.NotContains(t, name, "/", "long names don't nest directories")
+	assert.NotEqual(t, name, WorktreeDirName(longTitleBranch()+"-v2"))
+}
+
+func TestValidateWorktreePath(t *testing.T) {
+	repo := "/volume/repos/owner/repo.git"
+	worktree := filepath.Join("/workspace/repo", WorktreeDirName(longTitleBranch()))
+	assert.NoError(t, ValidateWorktreePath(repo, worktree, longTitleBranch(), DefaultMaxWorktreePathLength))
+
+	err := ValidateWorktreePath(repo, worktree, longTitleBranch(), 40)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "over the limit of 40")
+	assert.Contains(t, err.Error(), "catnip.worktree.max-path-length")
+
+	assert.NoError(t, ValidateWorktreePath(repo, worktree, "feature/"+strings.Repeat("a", 250), 0))
+	err = ValidateWorktreePath(repo, worktree, "feature/"+strings.Repeat("a", 251), 0)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "can't lock")
+
+	err = ValidateWorktreePath(repo, "/workspace/repo/"+strings.Repeat("a", 256), "main", 0)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "directory name longer than 255 bytes")
+
+	deepRepo := "/" + strings.Repeat(strings.Repeat("d", 200)+"/", 21)
+	err = ValidateWorktreePath(deepRepo, worktree, "feature/add-auth", 0)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "too long for its lock file")
+}
@@ -18,6 +18,8 @@ type Operations interface {
 	// Core command execution
 	ExecuteGit(workingDir string, args ...string) ([]byte, error)
 	ExecuteGitWithTimeout(workingDir string, timeout time.Duration, args ...string) ([]byte, error)
+	ExecuteGitWithEnv(workingDir string, env []string, timeout time.Duration, args ...string) ([]byte, error)
+	ExecuteGitWithStdErr(workingDir string, args ...string) (stdout []byte, stderr []byte, err error)
 	ExecuteCommand(command string, args ...string) ([]byte, error)
 
 	// Branch operations
@@ -36,6 +38,7 @@ type Operations interface {
 
 	// Worktree operations
 	CreateWorktree(repoPath, worktreePath, branch, fromRef string) error
+	CheckoutWorktree(repoPath, worktreePath, branch string) error
 	RemoveWorktree(repoPath, worktreePath string, force bool) error
 	ListWorktrees(repoPath string) ([]WorktreeInfo, error)
 	PruneWorktrees(repoPath string) error
@@ -43,6 +46,7 @@ type Operations interface {
 	// Status operations
 	IsDirty(worktreePath string) bool
 	HasConflicts(worktreePath string) bool
+	GetWorkingTreeStatus(worktreePath string) (isDirty, hasConflicts bool)
 	HasUncommittedChanges(worktreePath string) (bool, error)
 	GetConflictedFiles(worktreePath string) ([]string, error)
 	GetStatus(worktreePath string) (*WorktreeStatus, error)
@@ -54,6 +58,7 @@ type Operations interface {
 
 	// Push operations
 	PushBranch(worktreePath string, strategy PushStrategy) error
+	PushTag(worktreePath, tag string, strategy PushStrategy) error
 
 	// Remote operations
 	AddRemote(repoPath, name, url string) error
@@ -72,6 +77,7 @@ type Operations interface {
 
 	// Merge/Rebase operations
 	Merge(worktreePath, ref string) error
+	MergeFastForwardOnly(worktreePath, ref string) error
 	Rebase(worktreePath, ref string) error
 	CherryPick(worktreePath, commit string) error
 	AbortRebase(worktreePath string) error
@@ -82,13 +88,16 @@ type Operations interface {
 	MergeTree(worktreePath, base, head string) (string, error)
 
 	// Stash operations
-	Stash(worktreePath string) error
-	StashPop(worktreePath string) error
+	StashPush(worktreePath, message string) error
+	StashPop(worktreePath, ref string) error
+	StashList(worktreePath string) ([]StashEntry, error)
 
 	// Tag operations
 	CreateTag(repoPath, tag, ref string) error
+	CreateAnnotatedTag(repoPath, tag, ref, message string) error
 	DeleteTag(repoPath, tag string) error
 	ListTags(repoPath string) ([]string, error)
+	ListTagDetails(repoPath string) ([]TagInfo, error)
 
 	// Config operations
 	GetConfig(repoPath, key string) (string, error)
@@ -155,4 +164,10 @@ type WorktreeInfo struct {
 	Branch string
 	Commit string
 	Bare   bool
+	// HEAD isn't on a branch
+	Detached bool
+	// Locked with git worktree lock, so git prune leaves it alone
+	Locked bool
+	// The directory is gone, git worktree prune would drop the registration
+	Prunable bool
 }
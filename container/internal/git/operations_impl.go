@@ -17,6 +17,7 @@ import (
 // OperationsImpl implements the Operations interface using gogit where possible
 type OperationsImpl struct {
 	executor      executor.CommandExecutor
+	timed         executor.CommandExecutor // executor reporting command durations to timing spans
 	branchOps     *BranchOperations
 	fetchExecutor *FetchExecutor
 	pushExecutor  *PushExecutor
@@ -26,41 +27,47 @@ type OperationsImpl struct {
 
 // NewOperations creates a new Operations implementation using gogit by default
 func NewOperations() Operations {
-	exec := executor.NewGitExecutor() // Use gogit by default
-	return &OperationsImpl{
-		executor:      exec,
-		branchOps:     NewBranchOperations(exec),
-		fetchExecutor: NewFetchExecutor(exec),
-		pushExecutor:  NewPushExecutor(exec),
-		statusChecker: NewStatusChecker(exec),
-		urlManager:    NewURLManager(exec),
-	}
+	return NewOperationsWithExecutor(executor.NewGitExecutor()) // Use gogit by default
 }
 
 // NewOperationsWithExecutor creates Operations with a specific executor (for testing)
 func NewOperationsWithExecutor(exec executor.CommandExecutor) Operations {
+	timed := newTimedExecutor(exec)
 	return &OperationsImpl{
 		executor:      exec,
-		branchOps:     NewBranchOperations(exec),
-		fetchExecutor: NewFetchExecutor(exec),
-		pushExecutor:  NewPushExecutor(exec),
-		statusChecker: NewStatusChecker(exec),
-		urlManager:    NewURLManager(exec),
+		timed:         timed,
+		branchOps:     NewBranchOperations(timed),
+		fetchExecutor: NewFetchExecutor(timed),
+		pushExecutor:  NewPushExecutor(timed),
+		statusChecker: NewStatusChecker(timed),
+		urlManager:    NewURLManager(timed),
 	}
 }
 
 // Core command execution
 
 func (o *OperationsImpl) ExecuteGit(workingDir string, args ...string) ([]byte, error) {
-	return o.executor.ExecuteGitWithWorkingDir(workingDir, args...)
+	return o.timed.ExecuteGitWithWorkingDir(workingDir, args...)
 }
 
 func (o *OperationsImpl) ExecuteGitWithTimeout(workingDir string, timeout time.Duration, args ...string) ([]byte, error) {
-	return o.executor.ExecuteWithEnvAndTimeout(workingDir, nil, timeout, args...)
+	return o.timed.ExecuteWithEnvAndTimeout(workingDir, nil, timeout, args...)
+}
+
+// ExecuteGitWithEnv runs git in workingDir with extra environment variables, keeping them
+// out of the command line (and therefore out of logs and error messages)
+func (o *OperationsImpl) ExecuteGitWithEnv(workingDir string, env []string, timeout time.Duration, args ...string) ([]byte, error) {
+	return o.timed.ExecuteWithEnvAndTimeout(workingDir, env, timeout, args...)
+}
+
+// ExecuteGitWithStdErr runs git in workingDir and returns its stderr too, for commands that
+// report on stderr even when they succeed
+func (o *OperationsImpl) ExecuteGitWithStdErr(workingDir string, args ...string) ([]byte, []byte, error) {
+	return o.timed.ExecuteGitWithStdErr(workingDir, args...)
 }
 
 func (o *OperationsImpl) ExecuteCommand(command string, args ...string) ([]byte, error) {
-	return o.executor.ExecuteCommand(command, args...)
+	return o.timed.ExecuteCommand(command, args...)
 }
 
 // Branch operations
@@ -243,7 +250,8 @@ func (o *OperationsImpl) CreateWorktree(repoPath, worktreePath, branch, fromRef
 
 		// Then create the branch ref and check it out in the worktree
 		// First, we need to get the commit hash for the ref we want to base on
-		commitHash := fromRef
+		// (peeled, so annotated tags point the ref at their commit rather than the tag object)
+		commitHash := fromRef + "^{commit}"
 		if fromRef == "" {
 			commitHash = "HEAD"
 		}
@@ -293,6 +301,37 @@ func (o *OperationsImpl) CreateWorktree(repoPath, worktreePath, branch, fromRef
 	}
 }
 
+// CheckoutWorktree creates a worktree on a branch that already exists instead of creating one.
+// Catnip refs (refs/catnip/...) can't be checked out by git worktree add, so their worktree
+// starts detached at the ref and HEAD is pointed at it afterwards.
+func (o *OperationsImpl) CheckoutWorktree(repoPath, worktreePath, branch string) error {
+	args := []string{"worktree", "add", worktreePath, strings.TrimPrefix(branch, "refs/heads/")}
+	isCatnipRef := strings.HasPrefix(branch, "refs/catnip/")
+	if isCatnipRef {
+		args = []string{"worktree", "add", "--detach", worktreePath, branch}
+	}
+	if _, err := o.ExecuteGit(repoPath, args...); err != nil {
+		if !strings.Contains(err.Error(), "missing but already registered worktree") {
+			return err
+		}
+		logger.Debug("⚠️  Worktree registration conflict detected. Attempting targeted cleanup.")
+		if cleanupErr := o.cleanupOrphanedWorktreeRegistration(repoPath, worktreePath); cleanupErr != nil {
+			logger.Debugf("❌ Failed to cleanup orphaned worktree registration: %v", cleanupErr)
+			return err
+		}
+		if _, retryErr := o.ExecuteGit(repoPath, args...); retryErr != nil {
+			return fmt.Errorf("worktree creation failed even after cleanup: %v", retryErr)
+		}
+	}
+	if isCatnipRef {
+		if _, err := o.ExecuteGit(worktreePath, "symbolic-ref", "HEAD", branch); err != nil {
+			_ = o.RemoveWorktree(repoPath, worktreePath, true)
+			return err
+		}
+	}
+	return nil
+}
+
 func (o *OperationsImpl) RemoveWorktree(repoPath, worktreePath string, force bool) error {
 	args := []string{"worktree", "remove"}
 	if force {
@@ -327,6 +366,12 @@ func (o *OperationsImpl) ListWorktrees(repoPath string) ([]WorktreeInfo, error)
 			current.Branch = fullRef
 		} else if line == "bare" {
 			current.Bare = true
+		} else if line == "detached" {
+			current.Detached = true
+		} else if line == "locked" || strings.HasPrefix(line, "locked ") {
+			current.Locked = true
+		} else if line == "prunable" || strings.HasPrefix(line, "prunable ") {
+			current.Prunable = true
 		}
 	}
 
@@ -354,7 +399,7 @@ func (o *OperationsImpl) cleanupOrphanedWorktreeRegistration(repoPath, worktreeP
 	// Find the specific conflicting worktree
 	var conflictingWorktree *WorktreeInfo
 	for _, wt := range worktrees {
-		if wt.Path == worktreePath {
+		if config.SamePath(wt.Path, worktreePath) {
 			conflictingWorktree = &wt
 			break
 		}
@@ -399,6 +444,10 @@ func (o *OperationsImpl) HasConflicts(worktreePath string) bool {
 	return o.statusChecker.HasConflicts(worktreePath)
 }
 
+func (o *OperationsImpl) GetWorkingTreeStatus(worktreePath string) (isDirty, hasConflicts bool) {
+	return o.statusChecker.GetWorkingTreeStatus(worktreePath)
+}
+
 func (o *OperationsImpl) HasUncommittedChanges(worktreePath string) (bool, error) {
 	return o.statusChecker.HasUncommittedChanges(worktreePath)
 }
@@ -431,6 +480,10 @@ func (o *OperationsImpl) PushBranch(worktreePath string, strategy PushStrategy)
 	return o.pushExecutor.PushBranch(worktreePath, strategy)
 }
 
+func (o *OperationsImpl) PushTag(worktreePath, tag string, strategy PushStrategy) error {
+	return o.pushExecutor.PushTag(worktreePath, tag, strategy)
+}
+
 // Remote operations
 
 func (o *OperationsImpl) AddRemote(repoPath, name, url string) error {
@@ -537,6 +590,11 @@ func (o *OperationsImpl) Merge(worktreePath, ref string) error {
 	return err
 }
 
+func (o *OperationsImpl) MergeFastForwardOnly(worktreePath, ref string) error {
+	_, err := o.ExecuteGit(worktreePath, "merge", "--ff-only", ref)
+	return err
+}
+
 func (o *OperationsImpl) Rebase(worktreePath, ref string) error {
 	_, err := o.ExecuteGit(worktreePath, "rebase", ref)
 	return err
@@ -583,7 +641,7 @@ func (o *OperationsImpl) DiffNameOnly(worktreePath, filter string) ([]string, er
 func (o *OperationsImpl) MergeTree(worktreePath, base, head string) (string, error) {
 	// Use the modern merge-tree command which automatically finds the merge base
 	// We need to capture both stdout and stderr since conflict messages go to stderr
-	stdout, stderr, err := o.executor.ExecuteGitWithStdErr(worktreePath, "merge-tree", "--write-tree", base, head)
+	stdout, stderr, err := o.timed.ExecuteGitWithStdErr(worktreePath, "merge-tree", "--write-tree", base, head)
 	if err != nil {
 		return "", fmt.Errorf("merge-tree command failed: %v", err)
 	}
@@ -596,16 +654,32 @@ func (o *OperationsImpl) MergeTree(worktreePath, base, head string) (string, err
 
 // Stash operations
 
-func (o *OperationsImpl) Stash(worktreePath string) error {
-	_, err := o.ExecuteGit(worktreePath, "stash")
+// StashPush stashes the staged, unstaged and untracked changes of a worktree
+func (o *OperationsImpl) StashPush(worktreePath, message string) error {
+	_, err := o.ExecuteGit(worktreePath, "stash", "push", "--include-untracked", "-m", message)
 	return err
 }
 
-func (o *OperationsImpl) StashPop(worktreePath string) error {
-	_, err := o.ExecuteGit(worktreePath, "stash", "pop")
+// StashPop applies and drops a stash entry, the latest one when ref is empty. Entries whose
+// changes conflict are kept.
+func (o *OperationsImpl) StashPop(worktreePath, ref string) error {
+	args := []string{"stash", "pop"}
+	if ref != "" {
+		args = append(args, ref)
+	}
+	_, err := o.ExecuteGit(worktreePath, args...)
 	return err
 }
 
+// StashList returns the stash entries of the repository a worktree belongs to, latest first
+func (o *OperationsImpl) StashList(worktreePath string) ([]StashEntry, error) {
+	output, err := o.ExecuteGit(worktreePath, "stash", "list", "--format=%gd%x00%gs")
+	if err != nil {
+		return nil, err
+	}
+	return parseStashList(string(output)), nil
+}
+
 // Tag operations
 
 func (o *OperationsImpl) CreateTag(repoPath, tag, ref string) error {
@@ -617,6 +691,16 @@ func (o *OperationsImpl) CreateTag(repoPath, tag, ref string) error {
 	return err
 }
 
+// CreateAnnotatedTag creates an annotated tag with a message, at HEAD when ref is empty
+func (o *OperationsImpl) CreateAnnotatedTag(repoPath, tag, ref, message string) error {
+	args := []string{"tag", "-a", tag, "-m", message}
+	if ref != "" {
+		args = append(args, ref)
+	}
+	_, err := o.ExecuteGit(repoPath, args...)
+	return err
+}
+
 func (o *OperationsImpl) DeleteTag(repoPath, tag string) error {
 	_, err := o.ExecuteGit(repoPath, "tag", "-d", tag)
 	return err
@@ -638,6 +722,15 @@ func (o *OperationsImpl) ListTags(repoPath string) ([]string, error) {
 	return result, nil
 }
 
+// ListTagDetails returns the tags of a repository with the commits they point at, latest first
+func (o *OperationsImpl) ListTagDetails(repoPath string) ([]TagInfo, error) {
+	output, err := o.ExecuteGit(repoPath, "for-each-ref", "--sort=-creatordate", "--format="+tagListFormat, "refs/tags")
+	if err != nil {
+		return nil, err
+	}
+	return parseTagList(string(output)), nil
+}
+
 // Config operations
 
 func (o *OperationsImpl) GetConfig(repoPath, key string) (string, error) {
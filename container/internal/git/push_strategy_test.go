@@ -121,6 +121,24 @@ func TestPushStrategyConvertHTTPS(t *testing.T) {
 			assert.False(t, strings.Contains(arg, "insteadOf"))
 		}
 	})
+
+	t.Run("PushTag_uses_same_rewriting", func(t *testing.T) {
+		mockExec := &MockExecutorForURLRewrite{
+			recordedCommands: make([][]string, 0),
+		}
+
+		pushExecutor := NewPushExecutor(mockExec)
+
+		err := pushExecutor.PushTag("/test/worktree", "v1.0.0-rc1", PushStrategy{ConvertHTTPS: true})
+		assert.NoError(t, err)
+
+		assert.Len(t, mockExec.recordedCommands, 1)
+		expectedArgs := []string{"push", "origin", "refs/tags/v1.0.0-rc1"}
+		if config.Runtime.IsContainerized() {
+			expectedArgs = append([]string{"-c", "url.https://github.com/.insteadOf=git@github.com:"}, expectedArgs...)
+		}
+		assert.Equal(t, expectedArgs, mockExec.recordedCommands[0])
+	})
 }
 
 // TestPushStrategyWithGitExecutor tests the integration with the actual GitExecutor
@@ -0,0 +1,53 @@
+package git
+
+import "strings"
+
+// worktreeStashPrefix starts the message of stash entries catnip creates for a worktree. The
+// stash is shared by all worktrees of a repository, so entries are attributed by message.
+const worktreeStashPrefix = "catnip:"
+
+// StashEntry is an entry of a repository's stash
+type StashEntry struct {
+	Ref     string // e.g. stash@{0}
+	Branch  string // Branch checked out when the entry was created, "(no branch)" when detached
+	Message string
+}
+
+// WorktreeStashMessage returns the stash message attributing an entry to a worktree
+func WorktreeStashMessage(worktreeID, message string) string {
+	return strings.TrimSpace(worktreeStashPrefix + worktreeID + " " + message)
+}
+
+// WorktreeStashes returns the entries stashed for a worktree, latest first
+func WorktreeStashes(entries []StashEntry, worktreeID string) []StashEntry {
+	marker := worktreeStashPrefix + worktreeID
+	var stashes []StashEntry
+	for _, entry := range entries {
+		if entry.Message == marker || strings.HasPrefix(entry.Message, marker+" ") {
+			stashes = append(stashes, entry)
+		}
+	}
+	return stashes
+}
+
+// parseStashList parses `git stash list --format=%gd%x00%gs` output. Subjects read
+// "On <branch>: <message>" for entries with a message and "WIP on <branch>: <commit>" otherwise.
+func parseStashList(output string) []StashEntry {
+	var entries []StashEntry
+	for _, line := range strings.Split(output, "\n") {
+		ref, subject, found := strings.Cut(line, "\x00")
+		if !found || ref == "" {
+			continue
+		}
+		entry := StashEntry{Ref: ref, Message: subject}
+		subject = strings.TrimPrefix(subject, "WIP ")
+		if rest, ok := strings.CutPrefix(subject, "On "); ok {
+			if branch, message, ok := strings.Cut(rest, ": "); ok {
+				entry.Branch = branch
+				entry.Message = message
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
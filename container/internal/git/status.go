@@ -29,21 +29,7 @@ func (s *StatusChecker) IsDirty(worktreePath string) bool {
 
 // HasConflicts checks if a worktree is in a conflicted state (rebase/merge in progress)
 func (s *StatusChecker) HasConflicts(worktreePath string) bool {
-	// Check for rebase in progress
-	if _, err := os.Stat(filepath.Join(worktreePath, ".git", "rebase-apply")); err == nil {
-		return true
-	}
-	if _, err := os.Stat(filepath.Join(worktreePath, ".git", "rebase-merge")); err == nil {
-		return true
-	}
-
-	// Check for merge in progress
-	if _, err := os.Stat(filepath.Join(worktreePath, ".git", "MERGE_HEAD")); err == nil {
-		return true
-	}
-
-	// Check for cherry-pick in progress
-	if _, err := os.Stat(filepath.Join(worktreePath, ".git", "CHERRY_PICK_HEAD")); err == nil {
+	if operationInProgress(worktreePath) {
 		return true
 	}
 
@@ -52,8 +38,33 @@ func (s *StatusChecker) HasConflicts(worktreePath string) bool {
 	if err != nil {
 		return false
 	}
+	return hasUnmergedEntries(string(output))
+}
 
-	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+// GetWorkingTreeStatus reports whether a worktree is dirty and conflicted from a single
+// porcelain status
+func (s *StatusChecker) GetWorkingTreeStatus(worktreePath string) (isDirty, hasConflicts bool) {
+	output, err := s.executor.ExecuteGitWithWorkingDir(worktreePath, "status", "--porcelain")
+	if err != nil {
+		return false, operationInProgress(worktreePath)
+	}
+	isDirty = len(strings.TrimSpace(string(output))) > 0
+	return isDirty, operationInProgress(worktreePath) || hasUnmergedEntries(string(output))
+}
+
+// operationInProgress checks for a rebase, merge or cherry-pick in progress
+func operationInProgress(worktreePath string) bool {
+	for _, marker := range []string{"rebase-apply", "rebase-merge", "MERGE_HEAD", "CHERRY_PICK_HEAD"} {
+		if _, err := os.Stat(filepath.Join(worktreePath, ".git", marker)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// hasUnmergedEntries checks porcelain status output for conflict markers
+func hasUnmergedEntries(output string) bool {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
 	for _, line := range lines {
 		if len(line) >= 2 {
 			// Check for conflict markers in status (UU, AA, DD, etc.)
@@ -70,7 +81,6 @@ func (s *StatusChecker) HasConflicts(worktreePath string) bool {
 			}
 		}
 	}
-
 	return false
 }
 
@@ -205,24 +205,7 @@ func (p *PushExecutor) PushBranch(worktreePath string, strategy PushStrategy) er
 	}
 	args = append(args, strategy.Remote, strategy.Branch)
 
-	// Execute push with URL rewriting if HTTPS is needed (safer than modifying .git/config)
-	// Only apply URL rewriting in containerized mode to avoid interfering with native git config
-	var output []byte
-	var err error
-	if strategy.ConvertHTTPS && config.Runtime.IsContainerized() {
-		// Use git config URL rewriting - works for SSH (converts) and HTTPS (no-op)
-		// This avoids OAuth scope issues and doesn't modify .git/config
-		gitArgs := append([]string{"-c", "url.https://github.com/.insteadOf=git@github.com:"}, args...)
-		logger.Debugf("🔄 Executing git push with URL rewriting: %v", gitArgs)
-		output, err = p.executor.ExecuteGitWithWorkingDir(worktreePath, gitArgs...)
-	} else {
-		// Normal push execution (native mode or no HTTPS conversion needed)
-		if strategy.ConvertHTTPS && config.Runtime.IsNative() {
-			logger.Debug("🔄 Native mode: skipping URL rewriting, using existing git configuration")
-		}
-		logger.Debugf("🔄 Executing git push without URL rewriting: %v", args)
-		output, err = p.executor.ExecuteGitWithWorkingDir(worktreePath, args...)
-	}
+	output, err := p.push(worktreePath, strategy, args)
 	if err != nil {
 		// Handle push rejection with sync retry if configured
 		if strategy.SyncOnFail && IsPushRejected(err, string(output)) {
@@ -236,3 +219,39 @@ func (p *PushExecutor) PushBranch(worktreePath string, strategy PushStrategy) er
 	logger.Debugf("✅ Pushed branch %s to %s", strategy.Branch, strategy.Remote)
 	return nil
 }
+
+// PushTag pushes a tag to the strategy's remote. Only Remote and ConvertHTTPS apply.
+func (p *PushExecutor) PushTag(worktreePath, tag string, strategy PushStrategy) error {
+	if strategy.Remote == "" {
+		strategy.Remote = "origin"
+	}
+
+	args := []string{"push", strategy.Remote, "refs/tags/" + tag}
+	output, err := p.push(worktreePath, strategy, args)
+	if err != nil {
+		return fmt.Errorf("failed to push tag %s to %s: %v\n%s", tag, strategy.Remote, err, output)
+	}
+
+	logger.Debugf("✅ Pushed tag %s to %s", tag, strategy.Remote)
+	return nil
+}
+
+// push runs a git push, rewriting SSH GitHub URLs to HTTPS when the strategy asks for it
+func (p *PushExecutor) push(worktreePath string, strategy PushStrategy, args []string) ([]byte, error) {
+	// Execute push with URL rewriting if HTTPS is needed (safer than modifying .git/config)
+	// Only apply URL rewriting in containerized mode to avoid interfering with native git config
+	if strategy.ConvertHTTPS && config.Runtime.IsContainerized() {
+		// Use git config URL rewriting - works for SSH (converts) and HTTPS (no-op)
+		// This avoids OAuth scope issues and doesn't modify .git/config
+		gitArgs := append([]string{"-c", "url.https://github.com/.insteadOf=git@github.com:"}, args...)
+		logger.Debugf("🔄 Executing git push with URL rewriting: %v", gitArgs)
+		return p.executor.ExecuteGitWithWorkingDir(worktreePath, gitArgs...)
+	}
+
+	// Normal push execution (native mode or no HTTPS conversion needed)
+	if strategy.ConvertHTTPS && config.Runtime.IsNative() {
+		logger.Debug("🔄 Native mode: skipping URL rewriting, using existing git configuration")
+	}
+	logger.Debugf("🔄 Executing git push without URL rewriting: %v", args)
+	return p.executor.ExecuteGitWithWorkingDir(worktreePath, args...)
+}
@@ -0,0 +1,41 @@
+package git
+
+import (
+	"strings"
+	"time"
+)
+
+// tagListFormat is the for-each-ref format parseTagList reads. For annotated tags objectname is the
+// tag object and *objectname the commit it points at; lightweight tags have no *objectname.
+const tagListFormat = "%(refname:short)%00%(objecttype)%00%(objectname)%00%(*objectname)%00%(creatordate:iso-strict)%00%(contents:subject)"
+
+// TagInfo is a tag of a repository
+type TagInfo struct {
+	Name      string
+	Commit    string // Commit the tag points at
+	Annotated bool
+	Message   string // Subject of an annotated tag's message
+	Date      time.Time
+}
+
+// parseTagList parses `git for-each-ref --format=<tagListFormat> refs/tags` output
+func parseTagList(output string) []TagInfo {
+	var tags []TagInfo
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.SplitN(line, "\x00", 6)
+		if len(fields) < 6 || fields[0] == "" {
+			continue
+		}
+		tag := TagInfo{Name: fields[0], Commit: fields[2]}
+		if fields[1] == "tag" {
+			tag.Annotated = true
+			tag.Message = fields[5]
+			if fields[3] != "" {
+				tag.Commit = fields[3]
+			}
+		}
+		tag.Date, _ = time.Parse(time.RFC3339, fields[4])
+		tags = append(tags, tag)
+	}
+	return tags
+}
@@ -0,0 +1,183 @@
+package git
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/git/executor"
+)
+
+// networkCommands are git commands whose duration is dominated by talking to a remote
+var networkCommands = map[string]bool{
+	"fetch":     true,
+	"push":      true,
+	"pull":      true,
+	"clone":     true,
+	"ls-remote": true,
+}
+
+// TimingSpan accumulates the time spent in git commands run for a set of directories
+// while a user-facing operation (sync, merge, PR creation...) is in progress
+type TimingSpan struct {
+	dirs  []string
+	start time.Time
+
+	mu      sync.Mutex
+	network time.Duration
+	local   time.Duration
+}
+
+// TimingResult is the measured breakdown of a finished span. Time spent outside git
+// commands (GitHub API calls, bookkeeping) is the remainder of Total.
+type TimingResult struct {
+	Total   time.Duration
+	Network time.Duration
+	Local   time.Duration
+}
+
+var (
+	activeSpansMu sync.Mutex
+	activeSpans   = make(map[*TimingSpan]struct{})
+)
+
+// BeginTiming starts a span attributing every git command run in (or below) one of dirs
+// to the caller until End is called. Concurrent operations on the same directories
+// share their command timings.
+func BeginTiming(dirs ...string) *TimingSpan {
+	span := &TimingSpan{start: time.Now()}
+	for _, dir := range dirs {
+		if dir != "" {
+			span.dirs = append(span.dirs, filepath.Clean(dir))
+		}
+	}
+
+	activeSpansMu.Lock()
+	activeSpans[span] = struct{}{}
+	activeSpansMu.Unlock()
+	return span
+}
+
+// End stops the span and returns the measured durations
+func (s *TimingSpan) End() TimingResult {
+	activeSpansMu.Lock()
+	delete(activeSpans, s)
+	activeSpansMu.Unlock()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return TimingResult{
+		Total:   time.Since(s.start),
+		Network: s.network,
+		Local:   s.local,
+	}
+}
+
+// covers reports whether a command run in dir belongs to this span
+func (s *TimingSpan) covers(dir string) bool {
+	for _, spanDir := range s.dirs {
+		if dir == spanDir || strings.HasPrefix(dir, spanDir+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// recordCommandTiming adds the duration of a git command to every active span covering dir
+func recordCommandTiming(dir string, args []string, d time.Duration) {
+	if dir == "" {
+		return
+	}
+	dir = filepath.Clean(dir)
+	network := isNetworkCommand(args)
+
+	activeSpansMu.Lock()
+	defer activeSpansMu.Unlock()
+	for span := range activeSpans {
+		if !span.covers(dir) {
+			continue
+		}
+		span.mu.Lock()
+		if network {
+			span.network += d
+		} else {
+			span.local += d
+		}
+		span.mu.Unlock()
+	}
+}
+
+// isNetworkCommand reports whether git args run a command that talks to a remote
+func isNetworkCommand(args []string) bool {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "-c" || arg == "-C" {
+			i++ // Skip the option value
+			continue
+		}
+		if strings.HasPrefix(arg, "-") {
+			continue
+		}
+		if arg == "remote" && i+1 < len(args) && args[i+1] == "update" {
+			return true
+		}
+		return networkCommands[arg] && !isLocalRemote(args[i+1:])
+	}
+	return false
+}
+
+// isLocalRemote reports whether the remote argument of a fetch/push names a local path,
+// as merging a worktree into a local repository does
+func isLocalRemote(args []string) bool {
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "-") {
+			continue
+		}
+		return filepath.IsAbs(arg) || strings.HasPrefix(arg, "file://")
+	}
+	return false
+}
+
+// timedExecutor reports the duration of every git command to the active timing spans.
+// It wraps the executor handed to the operations helpers so the measurement happens at
+// the operations layer boundary regardless of how the executor runs the command.
+type timedExecutor struct {
+	executor.CommandExecutor
+}
+
+func newTimedExecutor(exec executor.CommandExecutor) executor.CommandExecutor {
+	return &timedExecutor{CommandExecutor: exec}
+}
+
+func (t *timedExecutor) Execute(dir string, args ...string) ([]byte, error) {
+	defer recordSince(dir, args, time.Now())
+	return t.CommandExecutor.Execute(dir, args...)
+}
+
+func (t *timedExecutor) ExecuteWithEnv(dir string, env []string, args ...string) ([]byte, error) {
+	defer recordSince(dir, args, time.Now())
+	return t.CommandExecutor.ExecuteWithEnv(dir, env, args...)
+}
+
+func (t *timedExecutor) ExecuteGitWithWorkingDir(workingDir string, args ...string) ([]byte, error) {
+	defer recordSince(workingDir, args, time.Now())
+	return t.CommandExecutor.ExecuteGitWithWorkingDir(workingDir, args...)
+}
+
+func (t *timedExecutor) ExecuteGitWithStdErr(workingDir string, args ...string) ([]byte, []byte, error) {
+	defer recordSince(workingDir, args, time.Now())
+	return t.CommandExecutor.ExecuteGitWithStdErr(workingDir, args...)
+}
+
+func (t *timedExecutor) ExecuteWithEnvAndTimeout(dir string, env []string, timeout time.Duration, args ...string) ([]byte, error) {
+	defer recordSince(dir, args, time.Now())
+	return t.CommandExecutor.ExecuteWithEnvAndTimeout(dir, env, timeout, args...)
+}
+
+func recordSince(dir string, args []string, start time.Time) {
+	if dir == "" && len(args) > 1 && args[0] == "-C" {
+		dir = args[1]
+	}
+	recordCommandTiming(dir, args, time.Since(start))
+}
@@ -0,0 +1,52 @@
+package git
+
+import (
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsNetworkCommand(t *testing.T) {
+	assert.True(t, isNetworkCommand([]string{"fetch", "origin", "main"}))
+	assert.True(t, isNetworkCommand([]string{"-c", "http.extraHeader=x", "push", "-u", "origin", "feature"}))
+	assert.True(t, isNetworkCommand([]string{"-C", "/repo", "ls-remote", "origin"}))
+	assert.True(t, isNetworkCommand([]string{"remote", "update"}))
+	assert.False(t, isNetworkCommand([]string{"push", "/workspace/repos/local.git", "feature:feature"}))
+	assert.False(t, isNetworkCommand([]string{"-C", "/repo", "rev-parse", "HEAD"}))
+	assert.False(t, isNetworkCommand([]string{"diff", "--name-only"}))
+	assert.False(t, isNetworkCommand(nil))
+}
+
+func TestTimingSpan(t *testing.T) {
+	root := t.TempDir()
+	repo := filepath.Join(root, "repo")
+	out, err := exec.Command("git", "init", "-b", "main", repo).CombinedOutput()
+	require.NoError(t, err, string(out))
+
+	ops := NewOperations()
+	span := BeginTiming(repo)
+	other := BeginTiming(filepath.Join(root, "elsewhere"))
+
+	_, err = ops.ExecuteGit(repo, "rev-parse", "--git-dir")
+	require.NoError(t, err)
+	_, err = ops.ExecuteGit(filepath.Join(repo, "."), "status", "--porcelain")
+	require.NoError(t, err)
+	recordCommandTiming(filepath.Join(repo, "sub"), []string{"fetch", "origin"}, 2*time.Second)
+
+	result := span.End()
+	assert.Positive(t, result.Local)
+	assert.Equal(t, 2*time.Second, result.Network)
+	assert.GreaterOrEqual(t, result.Total, result.Local)
+
+	otherResult := other.End()
+	assert.Zero(t, otherResult.Local)
+	assert.Zero(t, otherResult.Network)
+
+	// Commands after End are not attributed
+	recordCommandTiming(repo, []string{"fetch"}, time.Second)
+	assert.Equal(t, 2*time.Second, span.End().Network)
+}
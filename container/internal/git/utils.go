@@ -99,8 +99,20 @@ var (
 	// Regex patterns
 	githubURLPattern = regexp.MustCompile(`github\.com[:/]([^/]+)/([^/\s]+?)(?:\.git)?(?:/|$)`)
 	sshURLPattern    = regexp.MustCompile(`^(?:ssh://)?git@([^:]+):(.+)$`)
+	commitHashRegex  = regexp.MustCompile(`^[0-9a-f]{40}$`)
 )
 
+// IsCommitHash checks if ref is a full 40 character commit hash
+func IsCommitHash(ref string) bool {
+	return commitHashRegex.MatchString(ref)
+}
+
+// IsSourceRef checks if a worktree source is a tag (refs/tags/<name>) or commit rather than a
+// branch
+func IsSourceRef(source string) bool {
+	return strings.HasPrefix(source, "refs/tags/") || IsCommitHash(source)
+}
+
 // GenerateSessionName creates a random branch name with format refs/catnip/catname
 func GenerateSessionName() string {
 	catIndex, _ := rand.Int(rand.Reader, big.NewInt(int64(len(catNames))))
@@ -482,3 +494,11 @@ func GetDefaultBranch(ops Operations, repoPath string) string {
 	// Ultimate fallback
 	return "main"
 }
+
+// WithFileModeTracking prefixes git arguments with core.fileMode=true for that command only.
+// Inherited configs (e.g. repos cloned on filesystems without exec bits) can disable it,
+// which causes chmod +x changes to be silently dropped from checkpoint and preview commits.
+// The repository config, shared by every worktree, is left untouched.
+func WithFileModeTracking(args ...string) []string {
+	return append([]string{"-c", "core.fileMode=true"}, args...)
+}
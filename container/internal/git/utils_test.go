@@ -227,6 +227,26 @@ func TestIsMergeConflict(t *testing.T) {
 	}
 }
 
+func TestIsSourceRef(t *testing.T) {
+	testCases := []struct {
+		source   string
+		expected bool
+	}{
+		{"refs/tags/v1.0", true},
+		{"0123456789abcdef0123456789abcdef01234567", true},
+		{"0123456789ABCDEF0123456789ABCDEF01234567", false},
+		{"0123456", false},
+		{"main", false},
+		{"feature/refs/tags", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.source, func(t *testing.T) {
+			assert.Equal(t, tc.expected, IsSourceRef(tc.source))
+		})
+	}
+}
+
 func TestGenerateUniqueSessionName(t *testing.T) {
 	t.Run("FindsAvailableSimpleName", func(t *testing.T) {
 		// Mock branch checker that says nothing exists
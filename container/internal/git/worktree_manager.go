@@ -1,9 +1,11 @@
 package git
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
@@ -60,6 +62,10 @@ type CreateWorktreeRequest struct {
 	BranchName   string
 	WorkspaceDir string
 	IsInitial    bool
+	// Longest absolute worktree path accepted (0 for no limit)
+	MaxPathLength int
+	// Check out BranchName, which already exists, rather than creating it from SourceBranch
+	ExistingBranch bool
 }
 
 // CreateWorktree creates a new worktree for a repository
@@ -71,11 +77,14 @@ func (w *WorktreeManager) CreateWorktree(req CreateWorktreeRequest) (*models.Wor
 	repoName := repoParts[len(repoParts)-1]
 
 	// All worktrees use repo/branch pattern for consistency
-	workspaceName := ExtractWorkspaceName(req.BranchName)
+	workspaceName := WorktreeDirName(req.BranchName)
 	worktreePath := filepath.Join(req.WorkspaceDir, repoName, workspaceName)
+	if err := ValidateWorktreePath(req.Repository.Path, worktreePath, req.BranchName, req.MaxPathLength); err != nil {
+		return nil, err
+	}
 
-	// Create worktree with new branch using the branch name
-	err := w.operations.CreateWorktree(req.Repository.Path, worktreePath, req.BranchName, req.SourceBranch)
+	// Create worktree on its branch, creating the branch unless the request checks out an existing one
+	err := w.addWorktree(req, worktreePath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create worktree: %v", err)
 	}
@@ -86,17 +95,12 @@ func (w *WorktreeManager) CreateWorktree(req CreateWorktreeRequest) (*models.Wor
 		return nil, fmt.Errorf("failed to get commit hash: %v", err)
 	}
 
-	// Determine source branch (resolve if it's a commit or branch)
-	sourceBranch := req.SourceBranch
-	if len(req.SourceBranch) == 40 { // Looks like a commit hash
-		// Try to find which branch contains this commit
-		sourceBranch = w.findSourceBranch(req.Repository.Path, req.SourceBranch, req.BranchName)
-	}
+	sourceBranch, sourceRef := w.splitSource(req.Repository, req.SourceBranch, req.BranchName)
 
 	// Calculate commit count ahead of source
 	commitCount := 0
 	if sourceBranch != req.BranchName {
-		if count, err := w.operations.GetCommitCount(worktreePath, sourceBranch, "HEAD"); err == nil {
+		if count, err := w.operations.GetCommitCount(worktreePath, sourceBase(sourceBranch, sourceRef), "HEAD"); err == nil {
 			commitCount = count
 		}
 	}
@@ -111,6 +115,7 @@ func (w *WorktreeManager) CreateWorktree(req CreateWorktreeRequest) (*models.Wor
 		Path:         worktreePath,
 		Branch:       req.BranchName,
 		SourceBranch: sourceBranch,
+		SourceRef:    sourceRef,
 		CommitHash:   commitHash,
 		CommitCount:  commitCount,
 		IsDirty:      false,
@@ -122,22 +127,33 @@ func (w *WorktreeManager) CreateWorktree(req CreateWorktreeRequest) (*models.Wor
 	return worktree, nil
 }
 
+// addWorktree runs git worktree add for a request, creating its branch unless it already exists
+func (w *WorktreeManager) addWorktree(req CreateWorktreeRequest, worktreePath string) error {
+	if req.ExistingBranch {
+		return w.operations.CheckoutWorktree(req.Repository.Path, worktreePath, req.BranchName)
+	}
+	return w.operations.CreateWorktree(req.Repository.Path, worktreePath, req.BranchName, req.SourceBranch)
+}
+
 // CreateLocalWorktree creates a worktree for a local repository
 func (w *WorktreeManager) CreateLocalWorktree(req CreateWorktreeRequest) (*models.Worktree, error) {
 	id := uuid.New().String()
 
 	// Extract directory name from repo path
 	dirName := filepath.Base(req.Repository.Path)
-	workspaceName := ExtractWorkspaceName(req.BranchName)
+	workspaceName := WorktreeDirName(req.BranchName)
 	worktreePath := filepath.Join(req.WorkspaceDir, dirName, workspaceName)
+	if err := ValidateWorktreePath(filepath.Join(req.Repository.Path, ".git"), worktreePath, req.BranchName, req.MaxPathLength); err != nil {
+		return nil, err
+	}
 
 	// Create worktree directory first
 	if err := os.MkdirAll(filepath.Dir(worktreePath), 0755); err != nil {
 		return nil, fmt.Errorf("failed to create worktree directory: %v", err)
 	}
 
-	// Create worktree with new branch
-	err := w.operations.CreateWorktree(req.Repository.Path, worktreePath, req.BranchName, req.SourceBranch)
+	// Create worktree on its branch, creating the branch unless it already exists
+	err := w.addWorktree(req, worktreePath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create worktree: %v", err)
 	}
@@ -185,11 +201,12 @@ func (w *WorktreeManager) CreateLocalWorktree(req CreateWorktreeRequest) (*model
 	sourceBranch = strings.TrimPrefix(sourceBranch, "+")
 	sourceBranch = strings.TrimSpace(sourceBranch)
 	sourceBranch = strings.TrimPrefix(sourceBranch, "origin/")
+	sourceBranch, sourceRef := w.splitSource(req.Repository, sourceBranch, req.BranchName)
 
 	// Calculate commit count ahead of source
 	commitCount := 0
 	if sourceBranch != req.BranchName {
-		if count, err := w.operations.GetCommitCount(worktreePath, sourceBranch, "HEAD"); err == nil {
+		if count, err := w.operations.GetCommitCount(worktreePath, sourceBase(sourceBranch, sourceRef), "HEAD"); err == nil {
 			commitCount = count
 		}
 	}
@@ -204,6 +221,7 @@ func (w *WorktreeManager) CreateLocalWorktree(req CreateWorktreeRequest) (*model
 		Path:          worktreePath,
 		Branch:        req.BranchName,
 		SourceBranch:  sourceBranch,
+		SourceRef:     sourceRef,
 		CommitHash:    commitHash,
 		CommitCount:   commitCount,
 		CommitsBehind: 0, // Will be calculated later
@@ -228,8 +246,10 @@ func (w *WorktreeManager) DeleteWorktree(worktree *models.Worktree, repo *models
 		logger.Debugf("✅ Removed worktree directory: %s", worktree.Path)
 	}
 
-	// Step 2: Remove the worktree branch
-	if worktree.Branch != "" && worktree.Branch != worktree.SourceBranch {
+	// Step 2: Remove the worktree branch, protected repositories keep their branches
+	if repo.Protected {
+		logger.Debugf("📌 Keeping branches of %s in protected repository %s", worktree.Name, repo.ID)
+	} else if worktree.Branch != "" && worktree.Branch != worktree.SourceBranch {
 		if err := w.operations.DeleteBranch(repo.Path, worktree.Branch, true); err != nil {
 			logger.Warnf("⚠️ Failed to remove branch %s (may not exist or be in use): %v", worktree.Branch, err)
 		} else {
@@ -256,7 +276,9 @@ func (w *WorktreeManager) DeleteWorktree(worktree *models.Worktree, repo *models
 
 	// Step 4: Remove preview branch if it exists
 	previewBranchName := fmt.Sprintf("catnip/%s", workspaceName)
-	if err := w.operations.DeleteBranch(repo.Path, previewBranchName, true); err != nil {
+	if repo.Protected {
+		logger.Debugf("ℹ️ Keeping preview branch %s of protected repository %s", previewBranchName, repo.ID)
+	} else if err := w.operations.DeleteBranch(repo.Path, previewBranchName, true); err != nil {
 		logger.Debugf("ℹ️ No preview branch to remove: %s", previewBranchName)
 	} else {
 		logger.Debugf("✅ Removed preview branch: %s", previewBranchName)
@@ -303,12 +325,34 @@ func (w *WorktreeManager) detectWorktreeActualState(worktreePath string) (actual
 	return actualBranch, nil
 }
 
-// UpdateWorktreeStatus updates the status of a worktree with dynamic state detection
-// Note: Fetching should be handled at the service layer before calling this method
-func (w *WorktreeManager) UpdateWorktreeStatus(worktree *models.Worktree, getSourceRef func(*models.Worktree) string) {
+// StatusRefs tells UpdateWorktreeStatus what a worktree's divergence is counted against
+type StatusRefs struct {
+	// SourceRef returns the ref ahead and behind counts compare against
+	SourceRef func(*models.Worktree) string
+	// PullRequestBaseRef returns the ref of the branch the worktree's pull request targets,
+	// empty when there is none
+	PullRequestBaseRef func(*models.Worktree) string
+	// Fetch brings the refs up to date at the full tier, nil when there is nothing to fetch
+	Fetch func(*models.Worktree) error
+}
+
+// UpdateWorktreeStatus refreshes the status fields of a worktree populated at tier (see
+// models.StatusFieldTiers) and records the refresh in its StatusFreshness. The cheap tier reads
+// the working tree, the standard tier adds ahead and behind counts against refs.SourceRef and
+// the pull request base, and the full tier fetches with refs.Fetch before counting.
+func (w *WorktreeManager) UpdateWorktreeStatus(worktree *models.Worktree, tier models.StatusTier, refs StatusRefs) {
+	if !tier.Includes(models.StatusTierCheap) {
+		return
+	}
+
 	// Update basic status
-	worktree.IsDirty = w.operations.IsDirty(worktree.Path)
-	worktree.HasConflicts = w.operations.HasConflicts(worktree.Path)
+	worktree.IsDirty, worktree.HasConflicts = w.operations.GetWorkingTreeStatus(worktree.Path)
+	if entries, err := w.operations.StashList(worktree.Path); err == nil {
+		worktree.StashCount = len(WorktreeStashes(entries, worktree.ID))
+	}
+	if commitHash, err := w.operations.GetCommitHash(worktree.Path, "HEAD"); err == nil {
+		worktree.CommitHash = commitHash
+	}
 
 	// Detect actual worktree state (branch/ref only - source branch is business logic)
 	actualBranch, err := w.detectWorktreeActualState(worktree.Path)
@@ -330,17 +374,28 @@ func (w *WorktreeManager) UpdateWorktreeStatus(worktree *models.Worktree, getSou
 		}
 	}
 
-	if worktree.SourceBranch == "" || worktree.SourceBranch == worktree.Branch {
-		return
+	fetched := true
+	if tier.Includes(models.StatusTierFull) && refs.Fetch != nil {
+		if err := refs.Fetch(worktree); err != nil {
+			logger.Warnf("⚠️ Failed to fetch for the status of %s, counting against the refs at hand: %v", worktree.Name, err)
+			fetched = false
+		}
 	}
+	if tier.Includes(models.StatusTierStandard) {
+		w.updateDivergence(worktree, refs)
+	}
+	worktree.StatusFreshness = worktree.StatusFreshness.Stamped(tier, time.Now(), fetched)
+}
 
-	// Update commit hash to current HEAD
-	if commitHash, err := w.operations.GetCommitHash(worktree.Path, "HEAD"); err == nil {
-		worktree.CommitHash = commitHash
+// updateDivergence counts the commits a worktree is ahead and behind its source branch and its
+// pull request base
+func (w *WorktreeManager) updateDivergence(worktree *models.Worktree, refs StatusRefs) {
+	if worktree.SourceBranch == "" || worktree.SourceBranch == worktree.Branch {
+		return
 	}
 
 	// Get source reference
-	sourceRef := getSourceRef(worktree)
+	sourceRef := refs.SourceRef(worktree)
 
 	// Count commits ahead (our commits)
 	if count, err := w.operations.GetCommitCount(worktree.Path, sourceRef, "HEAD"); err == nil {
@@ -351,6 +406,42 @@ func (w *WorktreeManager) UpdateWorktreeStatus(worktree *models.Worktree, getSou
 	if count, err := w.operations.GetCommitCount(worktree.Path, "HEAD", sourceRef); err == nil {
 		worktree.CommitsBehind = count
 	}
+
+	// Count divergence from the pull request base, which may differ from the source branch
+	if refs.PullRequestBaseRef == nil {
+		return
+	}
+	if baseRef := refs.PullRequestBaseRef(worktree); baseRef != "" {
+		if count, err := w.operations.GetCommitCount(worktree.Path, baseRef, "HEAD"); err == nil {
+			worktree.PullRequestCommitCount = count
+		}
+		if count, err := w.operations.GetCommitCount(worktree.Path, "HEAD", baseRef); err == nil {
+			worktree.PullRequestCommitsBehind = count
+		}
+	}
+}
+
+// splitSource separates the source a worktree was created from into the branch it belongs to
+// and, for tags and commits, the exact ref. Tags and commits no branch is known to contain
+// belong to the repository's default branch.
+func (w *WorktreeManager) splitSource(repo *models.Repository, source, branchName string) (sourceBranch, sourceRef string) {
+	if !IsSourceRef(source) {
+		return source, ""
+	}
+	sourceBranch = w.findSourceBranch(repo.Path, source, branchName)
+	if sourceBranch == source && repo.DefaultBranch != "" {
+		sourceBranch = repo.DefaultBranch
+	}
+	return sourceBranch, source
+}
+
+// sourceBase returns what a worktree's commits are counted from: its source ref when it was
+// created at a tag or commit, its source branch otherwise
+func sourceBase(sourceBranch, sourceRef string) string {
+	if sourceRef != "" {
+		return sourceRef
+	}
+	return sourceBranch
 }
 
 // findSourceBranch tries to find which branch contains a commit, excluding preview branches
@@ -493,28 +584,163 @@ func (w *WorktreeManager) isWorktreeMerged(worktree *models.Worktree, repo *mode
 // FileDiff represents a file difference in a worktree
 type FileDiff struct {
 	FilePath   string `json:"file_path"`
-	ChangeType string `json:"change_type"` // "added", "deleted", "modified"
-	OldContent string `json:"old_content,omitempty"`
+	OldPath    string `json:"old_path,omitempty"`    // Previous path for renamed or copied files
+	ChangeType string `json:"change_type"`           // "added", "deleted", "modified", "renamed", "copied"
+	Similarity int    `json:"similarity,omitempty"`  // Rename/copy similarity percentage
+	OldContent string `json:"old_content,omitempty"` // Content at OldPath (or FilePath) in the fork commit
 	NewContent string `json:"new_content,omitempty"`
 	DiffText   string `json:"diff_text,omitempty"`
-	IsExpanded bool   `json:"is_expanded"` // Default expansion state
+	Additions  int    `json:"additions"`           // Lines added (content delta only for renames)
+	Deletions  int    `json:"deletions"`           // Lines deleted (content delta only for renames)
+	Binary     bool   `json:"binary,omitempty"`    // Binary files are listed without contents or diff
+	IsExpanded bool   `json:"is_expanded"`         // Default expansion state
+	Generated  bool   `json:"generated,omitempty"` // Lockfile or linguist-generated file, collapsed by default
+
+	origin diffOrigin
 }
 
 // WorktreeDiffResponse represents the diff response for a worktree
 type WorktreeDiffResponse struct {
-	WorktreeID   string     `json:"worktree_id"`
-	WorktreeName string     `json:"worktree_name"`
-	SourceBranch string     `json:"source_branch"`
-	ForkCommit   string     `json:"fork_commit"` // The commit where this worktree was forked from
-	FileDiffs    []FileDiff `json:"file_diffs"`
-	TotalFiles   int        `json:"total_files"`
-	Summary      string     `json:"summary"`
+	WorktreeID     string     `json:"worktree_id"`
+	WorktreeName   string     `json:"worktree_name"`
+	SourceBranch   string     `json:"source_branch"`
+	ForkCommit     string     `json:"fork_commit"`        // The commit where this worktree was forked from
+	BaseRef        string     `json:"base_ref,omitempty"` // Ref diffed against instead of the source branch
+	FileDiffs      []FileDiff `json:"file_diffs"`
+	TotalFiles     int        `json:"total_files"`
+	TotalAdditions int        `json:"total_additions"`
+	TotalDeletions int        `json:"total_deletions"`
+	// Files and lines written by hand, without generated files
+	AuthoredStats models.DiffStats `json:"authored_stats"`
+	Summary       string           `json:"summary"`
 }
 
-// GetWorktreeDiff calculates diff for a worktree against its source branch
-func (w *WorktreeManager) GetWorktreeDiff(worktree *models.Worktree, sourceRef string, fetchLatestRef func(*models.Worktree) error) (*WorktreeDiffResponse, error) {
-	logger.Debugf("🔍 Getting diff for worktree %s against %s", worktree.Name, sourceRef)
+// WorktreeDiffSummary lists the files changed in a worktree without their contents, see
+// WorktreeManager.GetWorktreeFileDiff for the diff of one file
+type WorktreeDiffSummary struct {
+	WorktreeID     string     `json:"worktree_id"`
+	WorktreeName   string     `json:"worktree_name"`
+	SourceBranch   string     `json:"source_branch"`
+	ForkCommit     string     `json:"fork_commit"`
+	Files          []FileDiff `json:"files"`
+	TotalFiles     int        `json:"total_files"` // All changed files, not just this page
+	TotalAdditions int        `json:"total_additions"`
+	TotalDeletions int        `json:"total_deletions"`
+	// Files and lines written by hand, without generated files, over all pages
+	AuthoredStats models.DiffStats `json:"authored_stats"`
+	Offset        int              `json:"offset"`
+	Limit         int              `json:"limit"`
+	HasMore       bool             `json:"has_more"`
+}
+
+// DefaultRenameSimilarity is the default similarity percentage for rename and copy detection in diffs
+const DefaultRenameSimilarity = 50
+
+// GetRenameSimilarity returns the rename detection similarity percentage from environment or default
+func GetRenameSimilarity() int {
+	if similarityStr := os.Getenv("CATNIP_DIFF_RENAME_SIMILARITY"); similarityStr != "" {
+		if similarity, err := strconv.Atoi(similarityStr); err == nil && similarity > 0 && similarity <= 100 {
+			return similarity
+		}
+	}
+	return DefaultRenameSimilarity
+}
+
+// renameDetectionArgs returns diff flags that report moved and copied files as a single entry
+func renameDetectionArgs() []string {
+	similarity := GetRenameSimilarity()
+	return []string{fmt.Sprintf("-M%d%%", similarity), fmt.Sprintf("-C%d%%", similarity)}
+}
+
+// diffStat holds line counts for one file from git diff --numstat
+type diffStat struct {
+	additions int
+	deletions int
+	binary    bool
+}
+
+// parseNumstat parses `git diff --numstat -z` output into counts keyed by the new path.
+// Renamed entries are "added\tdeleted\t\0old\0new\0"; binary files report "-" and are
+// flagged binary with zero counts.
+func parseNumstat(output string) map[string]diffStat {
+	stats := make(map[string]diffStat)
+	tokens := strings.Split(output, "\x00")
+	for i := 0; i < len(tokens); i++ {
+		fields := strings.SplitN(strings.TrimLeft(tokens[i], "\n"), "\t", 3)
+		if len(fields) < 3 {
+			continue
+		}
+
+		path := fields[2]
+		if path == "" {
+			// Rename or copy: old and new paths follow as separate tokens
+			if i+2 >= len(tokens) {
+				break
+			}
+			path = tokens[i+2]
+			i += 2
+		}
+
+		additions, _ := strconv.Atoi(fields[0])
+		deletions, _ := strconv.Atoi(fields[1])
+		stats[path] = diffStat{additions: additions, deletions: deletions, binary: fields[0] == "-" && fields[1] == "-"}
+	}
+	return stats
+}
 
+// parseNameStatusLine splits a `git diff --name-status` line into its status, old path and
+// new path. Old and new paths are equal unless the file was renamed or copied.
+func parseNameStatusLine(line string) (status string, similarity int, oldPath string, newPath string, ok bool) {
+	parts := strings.Split(line, "\t")
+	if len(parts) < 2 {
+		return "", 0, "", "", false
+	}
+
+	status = parts[0]
+	newPath = parts[len(parts)-1]
+	oldPath = newPath
+	if (strings.HasPrefix(status, "R") || strings.HasPrefix(status, "C")) && len(parts) >= 3 {
+		oldPath = parts[1]
+		similarity, _ = strconv.Atoi(status[1:])
+		status = status[:1]
+	}
+	return status, similarity, oldPath, newPath, true
+}
+
+// countLines counts lines in content, including a final line without a trailing newline
+func countLines(content []byte) int {
+	lines := bytes.Count(content, []byte("\n"))
+	if len(content) > 0 && content[len(content)-1] != '\n' {
+		lines++
+	}
+	return lines
+}
+
+// diffOrigin is where a file's changes in a worktree diff come from, which decides what its
+// contents and patch are compared against
+type diffOrigin int
+
+const (
+	diffCommitted         diffOrigin = iota // Committed since the fork commit
+	diffCommittedUnstaged                   // Committed, with further unstaged changes
+	diffUnstaged                            // Unstaged changes to a file unchanged since the fork commit
+	diffUntracked                           // Untracked file
+)
+
+// binarySniffLength is how much of an untracked file is checked for NUL bytes, as git does
+const binarySniffLength = 8000
+
+// isBinaryContent reports whether content looks binary to git
+func isBinaryContent(content []byte) bool {
+	if len(content) > binarySniffLength {
+		content = content[:binarySniffLength]
+	}
+	return bytes.IndexByte(content, 0) >= 0
+}
+
+// diffForkCommit finds where the worktree forked from sourceRef, fetching the ref when it
+// isn't known locally yet
+func (w *WorktreeManager) diffForkCommit(worktree *models.Worktree, sourceRef string, fetchLatestRef func(*models.Worktree) error) (string, error) {
 	// Try to get diff without fetching first (much faster for local changes)
 	// Attempt to find merge base with existing references using timeout
 	mergeBaseOutput, err := w.safeExecuteGit(worktree.Path, "merge-base", "HEAD", sourceRef)
@@ -530,45 +756,63 @@ func (w *WorktreeManager) GetWorktreeDiff(worktree *models.Worktree, sourceRef s
 
 		mergeBaseOutput, err = w.safeExecuteGit(worktree.Path, "merge-base", "HEAD", sourceRef)
 		if err != nil {
-			return nil, fmt.Errorf("failed to find merge base: %v", err)
+			return "", fmt.Errorf("failed to find merge base: %v", err)
 		}
 	}
 
 	forkCommit := strings.TrimSpace(string(mergeBaseOutput))
 	logger.Debugf("🔍 Fork commit: %s", forkCommit)
+	return forkCommit, nil
+}
 
-	// Get the list of changed files from the fork point using timeout
-	output, err := w.safeExecuteGit(worktree.Path, "diff", "--name-status", fmt.Sprintf("%s..HEAD", forkCommit))
+// GetWorktreeDiffSummary lists every file changed in a worktree against its source branch
+// (committed, unstaged and untracked) with its change type and line counts, but without
+// contents or patches. Binary files are flagged and count no lines.
+func (w *WorktreeManager) GetWorktreeDiffSummary(worktree *models.Worktree, sourceRef string, fetchLatestRef func(*models.Worktree) error) (*WorktreeDiffSummary, error) {
+	logger.Debugf("🔍 Getting diff summary for worktree %s against %s", worktree.Name, sourceRef)
+
+	forkCommit, err := w.diffForkCommit(worktree, sourceRef, fetchLatestRef)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get diff list: %v", err)
+		return nil, err
 	}
 
-	var fileDiffs []FileDiff
-	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	// Get the list of changed files from the fork point using timeout.
+	// Rename detection keeps moved files as one entry instead of a delete plus an add.
+	committedRange := fmt.Sprintf("%s..HEAD", forkCommit)
+	nameStatusArgs := append(append([]string{"diff", "--name-status"}, renameDetectionArgs()...), committedRange)
+	output, err := w.safeExecuteGit(worktree.Path, nameStatusArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get diff list: %v", err)
+	}
 
-	// Apply file count limit
-	if len(lines) > maxDiffFiles {
-		logger.Warnf("⚠️ Diff has %d files, limiting to %d files", len(lines), maxDiffFiles)
-		lines = lines[:maxDiffFiles]
+	committedStats := map[string]diffStat{}
+	numstatArgs := append(append([]string{"diff", "--numstat", "-z"}, renameDetectionArgs()...), committedRange)
+	if numstatOutput, err := w.safeExecuteGit(worktree.Path, numstatArgs...); err == nil {
+		committedStats = parseNumstat(string(numstatOutput))
 	}
 
+	var files []FileDiff
+	byPath := make(map[string]int)
+
 	// Process committed changes
-	for _, line := range lines {
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
 		if line == "" {
 			continue
 		}
 
-		parts := strings.Split(line, "\t")
-		if len(parts) < 2 {
+		changeType, similarity, oldPath, filePath, ok := parseNameStatusLine(line)
+		if !ok {
 			continue
 		}
 
-		changeType := parts[0]
-		filePath := parts[1]
-
+		stat := committedStats[filePath]
 		fileDiff := FileDiff{
 			FilePath:   filePath,
+			Additions:  stat.additions,
+			Deletions:  stat.deletions,
+			Binary:     stat.binary,
 			IsExpanded: false, // Default to collapsed for added/deleted files
+			origin:     diffCommitted,
 		}
 
 		switch changeType {
@@ -581,197 +825,261 @@ func (w *WorktreeManager) GetWorktreeDiff(worktree *models.Worktree, sourceRef s
 		case "M":
 			fileDiff.ChangeType = "modified"
 			fileDiff.IsExpanded = true // Expand by default for modifications
+		case "R", "C":
+			fileDiff.ChangeType = "renamed"
+			if changeType == "C" {
+				fileDiff.ChangeType = "copied"
+			}
+			fileDiff.OldPath = oldPath
+			fileDiff.Similarity = similarity
+			fileDiff.IsExpanded = similarity < 100 // Pure moves have nothing to review
 		default:
 			fileDiff.ChangeType = "modified"
 			fileDiff.IsExpanded = true
 		}
 
-		// Get the old content (from fork commit) with safety checks
-		if oldOutput, err := w.safeExecuteGit(worktree.Path, "show", fmt.Sprintf("%s:%s", forkCommit, filePath)); err == nil {
-			content := string(oldOutput)
-			fileDiff.OldContent = w.truncateContent(content)
-		}
-
-		// Get the new content (current HEAD) with safety checks
-		if newOutput, err := w.safeExecuteGit(worktree.Path, "show", fmt.Sprintf("HEAD:%s", filePath)); err == nil {
-			content := string(newOutput)
-			fileDiff.NewContent = w.truncateContent(content)
-		}
+		byPath[filePath] = len(files)
+		files = append(files, fileDiff)
+	}
 
-		// Also keep the unified diff for fallback with safety checks
-		if diffOutput, err := w.safeExecuteGit(worktree.Path, "diff", fmt.Sprintf("%s..HEAD", forkCommit), "--", filePath); err == nil {
-			content := string(diffOutput)
-			fileDiff.DiffText = w.truncateContent(content)
+	// Also check for unstaged changes
+	if unstagedOutput, err := w.safeExecuteGit(worktree.Path, "diff", "--name-status"); err == nil {
+		unstagedStats := map[string]diffStat{}
+		if numstatOutput, err := w.safeExecuteGit(worktree.Path, "diff", "--numstat", "-z"); err == nil {
+			unstagedStats = parseNumstat(string(numstatOutput))
 		}
 
-		fileDiffs = append(fileDiffs, fileDiff)
-	}
+		for _, line := range strings.Split(strings.TrimSpace(string(unstagedOutput)), "\n") {
+			if line == "" {
+				continue
+			}
 
-	// Also check for unstaged changes (if we haven't hit file limit yet)
-	if len(fileDiffs) < maxDiffFiles {
-		if unstagedOutput, err := w.safeExecuteGit(worktree.Path, "diff", "--name-status"); err == nil {
-			unstagedLines := strings.Split(strings.TrimSpace(string(unstagedOutput)), "\n")
+			changeType, _, _, filePath, ok := parseNameStatusLine(line)
+			if !ok {
+				continue
+			}
+			stat := unstagedStats[filePath]
 
-			for _, line := range unstagedLines {
-				// Check file limit
-				if len(fileDiffs) >= maxDiffFiles {
-					logger.Warnf("⚠️ Reached maximum diff files limit (%d), stopping unstaged file processing", maxDiffFiles)
-					break
-				}
+			// Files already committed are updated in place (renames are matched by their new path)
+			if i, found := byPath[filePath]; found {
+				files[i].Additions += stat.additions
+				files[i].Deletions += stat.deletions
+				files[i].Binary = files[i].Binary || stat.binary
 
-				if line == "" {
-					continue
+				// Update the existing entry to show it has unstaged changes
+				if files[i].ChangeType == "added" {
+					files[i].ChangeType = "added + modified (unstaged)"
+				} else {
+					files[i].ChangeType = "modified (unstaged)"
 				}
+				files[i].IsExpanded = true
+				files[i].origin = diffCommittedUnstaged
+				continue
+			}
 
-				parts := strings.Split(line, "\t")
-				if len(parts) < 2 {
-					continue
-				}
+			fileDiff := FileDiff{
+				FilePath:   filePath,
+				Additions:  stat.additions,
+				Deletions:  stat.deletions,
+				Binary:     stat.binary,
+				IsExpanded: true, // Unstaged changes should be visible
+				origin:     diffUnstaged,
+			}
 
-				changeType := parts[0]
-				filePath := parts[1]
-
-				// Check if this file already exists in our diff list
-				found := false
-				for i := range fileDiffs {
-					if fileDiffs[i].FilePath == filePath {
-						// Update the existing entry to show it has unstaged changes
-						if fileDiffs[i].ChangeType == "added" {
-							fileDiffs[i].ChangeType = "added + modified (unstaged)"
-						} else {
-							fileDiffs[i].ChangeType = "modified (unstaged)"
-						}
-
-						// Update content to show working directory state with safety checks
-						fullPath := filepath.Join(worktree.Path, filePath)
-						if w.isFileSizeAcceptable(fullPath) {
-							if newContent, err := os.ReadFile(fullPath); err == nil {
-								content := string(newContent)
-								fileDiffs[i].NewContent = w.truncateContent(content)
-							}
-						} else {
-							fileDiffs[i].NewContent = "[File too large to display]"
-						}
-
-						// Update diff to show unstaged changes with safety checks
-						if diffOutput, err := w.safeExecuteGit(worktree.Path, "diff", "--", filePath); err == nil {
-							content := string(diffOutput)
-							fileDiffs[i].DiffText = w.truncateContent(content)
-						}
-
-						fileDiffs[i].IsExpanded = true
-						found = true
-						break
-					}
-				}
+			switch changeType {
+			case "A":
+				fileDiff.ChangeType = "added (unstaged)"
+			case "D":
+				fileDiff.ChangeType = "deleted (unstaged)"
+			default:
+				fileDiff.ChangeType = "modified (unstaged)"
+			}
 
-				if !found {
-					fileDiff := FileDiff{
-						FilePath:   filePath,
-						IsExpanded: true, // Unstaged changes should be visible
-					}
+			byPath[filePath] = len(files)
+			files = append(files, fileDiff)
+		}
+	}
 
-					switch changeType {
-					case "A":
-						fileDiff.ChangeType = "added (unstaged)"
-					case "D":
-						fileDiff.ChangeType = "deleted (unstaged)"
-					case "M":
-						fileDiff.ChangeType = "modified (unstaged)"
-					default:
-						fileDiff.ChangeType = "modified (unstaged)"
-					}
+	// Check for untracked files
+	if untrackedOutput, err := w.safeExecuteGit(worktree.Path, "ls-files", "--others", "--exclude-standard"); err == nil {
+		for _, filePath := range strings.Split(strings.TrimSpace(string(untrackedOutput)), "\n") {
+			if filePath == "" {
+				continue
+			}
 
-					// Get old content (HEAD version) with safety checks
-					if oldOutput, err := w.safeExecuteGit(worktree.Path, "show", fmt.Sprintf("HEAD:%s", filePath)); err == nil {
-						content := string(oldOutput)
-						fileDiff.OldContent = w.truncateContent(content)
-					}
+			fileDiff := FileDiff{
+				FilePath:   filePath,
+				ChangeType: "added (untracked)",
+				IsExpanded: false, // Collapse by default
+				origin:     diffUntracked,
+			}
 
-					// Get new content (working directory) with safety checks
-					fullPath := filepath.Join(worktree.Path, filePath)
-					if w.isFileSizeAcceptable(fullPath) {
-						if newContent, err := os.ReadFile(fullPath); err == nil {
-							content := string(newContent)
-							fileDiff.NewContent = w.truncateContent(content)
-						}
+			// Count lines of untracked files with safety checks
+			fullPath := filepath.Join(worktree.Path, filePath)
+			if w.isFileSizeAcceptable(fullPath) {
+				if content, err := os.ReadFile(fullPath); err == nil {
+					if isBinaryContent(content) {
+						fileDiff.Binary = true
 					} else {
-						fileDiff.NewContent = "[File too large to display]"
+						fileDiff.Additions = countLines(content)
 					}
-
-					// Get unstaged diff content as fallback with safety checks
-					if diffOutput, err := w.safeExecuteGit(worktree.Path, "diff", "--", filePath); err == nil {
-						content := string(diffOutput)
-						fileDiff.DiffText = w.truncateContent(content)
-					}
-
-					fileDiffs = append(fileDiffs, fileDiff)
 				}
 			}
+
+			files = append(files, fileDiff)
 		}
 	}
 
-	// Check for untracked files (if we haven't hit file limit yet)
-	if len(fileDiffs) < maxDiffFiles {
-		if untrackedOutput, err := w.safeExecuteGit(worktree.Path, "ls-files", "--others", "--exclude-standard"); err == nil {
-			untrackedFiles := strings.Split(strings.TrimSpace(string(untrackedOutput)), "\n")
+	totalAdditions, totalDeletions := 0, 0
+	for i := range files {
+		if files[i].Binary {
+			files[i].IsExpanded = false // Nothing to show inline
+		}
+		totalAdditions += files[i].Additions
+		totalDeletions += files[i].Deletions
+	}
 
-			for _, filePath := range untrackedFiles {
-				// Check file limit
-				if len(fileDiffs) >= maxDiffFiles {
-					logger.Warnf("⚠️ Reached maximum diff files limit (%d), stopping untracked file processing", maxDiffFiles)
-					break
-				}
+	return &WorktreeDiffSummary{
+		WorktreeName:   worktree.Name,
+		SourceBranch:   worktree.SourceBranch,
+		ForkCommit:     forkCommit,
+		Files:          files,
+		TotalFiles:     len(files),
+		TotalAdditions: totalAdditions,
+		TotalDeletions: totalDeletions,
+	}, nil
+}
 
-				if filePath == "" {
-					continue
-				}
+// GetWorktreeFileDiff returns one changed file of a worktree with its old and new contents and
+// unified diff, computed against the same fork commit as GetWorktreeDiffSummary. Binary files
+// are returned without contents or patch.
+func (w *WorktreeManager) GetWorktreeFileDiff(worktree *models.Worktree, sourceRef string, fetchLatestRef func(*models.Worktree) error, path string) (*FileDiff, error) {
+	summary, err := w.GetWorktreeDiffSummary(worktree, sourceRef, fetchLatestRef)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range summary.Files {
+		if file.FilePath == path {
+			w.loadFileDiffContent(worktree, summary.ForkCommit, &file)
+			return &file, nil
+		}
+	}
+	return nil, fmt.Errorf("file %s not found in the diff of %s", path, worktree.Name)
+}
 
-				fileDiff := FileDiff{
-					FilePath:   filePath,
-					ChangeType: "added (untracked)",
-					IsExpanded: false, // Collapse by default
-				}
+// loadFileDiffContent fills in the contents and unified diff of a file from a diff summary
+func (w *WorktreeManager) loadFileDiffContent(worktree *models.Worktree, forkCommit string, fileDiff *FileDiff) {
+	if fileDiff.Binary {
+		return
+	}
 
-				// Read file content for untracked files with safety checks
-				fullPath := filepath.Join(worktree.Path, filePath)
-				if w.isFileSizeAcceptable(fullPath) {
-					if content, err := os.ReadFile(fullPath); err == nil {
-						contentStr := string(content)
-						fileDiff.NewContent = w.truncateContent(contentStr)
-					}
-				} else {
-					fileDiff.NewContent = "[File too large to display]"
-				}
+	oldPath := fileDiff.FilePath
+	if fileDiff.OldPath != "" {
+		oldPath = fileDiff.OldPath
+	}
+	readWorkingFile := func() {
+		fullPath := filepath.Join(worktree.Path, fileDiff.FilePath)
+		if !w.isFileSizeAcceptable(fullPath) {
+			fileDiff.NewContent = "[File too large to display]"
+			return
+		}
+		if newContent, err := os.ReadFile(fullPath); err == nil {
+			fileDiff.NewContent = w.truncateContent(string(newContent))
+		}
+	}
+	showUnstagedDiff := func() {
+		if diffOutput, err := w.safeExecuteGit(worktree.Path, "diff", "--", fileDiff.FilePath); err == nil {
+			fileDiff.DiffText = w.truncateContent(string(diffOutput))
+		}
+	}
 
-				fileDiffs = append(fileDiffs, fileDiff)
-			}
+	switch fileDiff.origin {
+	case diffCommitted, diffCommittedUnstaged:
+		// Get the old content (from fork commit, at the pre-rename path) with safety checks
+		if oldOutput, err := w.safeExecuteGit(worktree.Path, "show", fmt.Sprintf("%s:%s", forkCommit, oldPath)); err == nil {
+			fileDiff.OldContent = w.truncateContent(string(oldOutput))
 		}
+		if fileDiff.origin == diffCommittedUnstaged {
+			readWorkingFile()
+			showUnstagedDiff()
+			return
+		}
+
+		// Get the new content (current HEAD) with safety checks
+		if newOutput, err := w.safeExecuteGit(worktree.Path, "show", fmt.Sprintf("HEAD:%s", fileDiff.FilePath)); err == nil {
+			fileDiff.NewContent = w.truncateContent(string(newOutput))
+		}
+
+		// Also keep the unified diff for fallback with safety checks. For renames both paths are
+		// passed so git pairs them and the patch only holds the content delta.
+		diffArgs := append(append([]string{"diff"}, renameDetectionArgs()...), forkCommit+"..HEAD", "--", fileDiff.FilePath)
+		if oldPath != fileDiff.FilePath {
+			diffArgs = append(diffArgs, oldPath)
+		}
+		if diffOutput, err := w.safeExecuteGit(worktree.Path, diffArgs...); err == nil {
+			fileDiff.DiffText = w.truncateContent(string(diffOutput))
+		}
+	case diffUnstaged:
+		// Get old content (HEAD version) with safety checks
+		if oldOutput, err := w.safeExecuteGit(worktree.Path, "show", fmt.Sprintf("HEAD:%s", fileDiff.FilePath)); err == nil {
+			fileDiff.OldContent = w.truncateContent(string(oldOutput))
+		}
+		readWorkingFile()
+		showUnstagedDiff()
+	case diffUntracked:
+		readWorkingFile()
+	}
+}
+
+// GetWorktreeDiff calculates diff for a worktree against its source branch: the diff summary
+// with contents and patches of its first files
+func (w *WorktreeManager) GetWorktreeDiff(worktree *models.Worktree, sourceRef string, fetchLatestRef func(*models.Worktree) error) (*WorktreeDiffResponse, error) {
+	summary, err := w.GetWorktreeDiffSummary(worktree, sourceRef, fetchLatestRef)
+	if err != nil {
+		return nil, err
+	}
+
+	// Apply file count limit
+	fileDiffs := summary.Files
+	if len(fileDiffs) > maxDiffFiles {
+		logger.Warnf("⚠️ Diff has %d files, limiting to %d files", len(fileDiffs), maxDiffFiles)
+		fileDiffs = fileDiffs[:maxDiffFiles]
+	}
+	for i := range fileDiffs {
+		w.loadFileDiffContent(worktree, summary.ForkCommit, &fileDiffs[i])
 	}
 
 	// Generate summary
-	var summary string
+	var text string
 	totalFiles := len(fileDiffs)
 	switch totalFiles {
 	case 0:
-		summary = "No changes"
+		text = "No changes"
 	case 1:
-		summary = "1 file changed"
+		text = "1 file changed"
 	default:
-		summary = fmt.Sprintf("%d files changed", totalFiles)
+		text = fmt.Sprintf("%d files changed", totalFiles)
 	}
 
 	// Add warning if we hit the file limit
 	if totalFiles >= maxDiffFiles {
-		summary += fmt.Sprintf(" (showing first %d files)", maxDiffFiles)
+		text += fmt.Sprintf(" (showing first %d files)", maxDiffFiles)
+	}
+
+	totalAdditions, totalDeletions := 0, 0
+	for _, fileDiff := range fileDiffs {
+		totalAdditions += fileDiff.Additions
+		totalDeletions += fileDiff.Deletions
 	}
 
 	return &WorktreeDiffResponse{
-		WorktreeName: worktree.Name,
-		SourceBranch: worktree.SourceBranch,
-		ForkCommit:   forkCommit,
-		FileDiffs:    fileDiffs,
-		TotalFiles:   totalFiles,
-		Summary:      summary,
+		WorktreeName:   worktree.Name,
+		SourceBranch:   worktree.SourceBranch,
+		ForkCommit:     summary.ForkCommit,
+		FileDiffs:      fileDiffs,
+		TotalFiles:     totalFiles,
+		TotalAdditions: totalAdditions,
+		TotalDeletions: totalDeletions,
+		Summary:        text,
 	}, nil
 }
@@ -0,0 +1,294 @@
+package git
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+func TestParseNumstat(t *testing.T) {
+	output := "3\t1\tsrc/app.go\x002\t0\t\x00old/util.go\x00new/util.go\x00-\t-\tlogo.png\x00"
+	stats := parseNumstat(output)
+
+	assert.Equal(t, diffStat{additions: 3, deletions: 1}, stats["src/app.go"])
+	assert.Equal(t, diffStat{additions: 2, deletions: 0}, stats["new/util.go"])
+	assert.NotContains(t, stats, "old/util.go")
+	assert.Equal(t, diffStat{binary: true}, stats["logo.png"])
+}
+
+func TestGetWorktreeDiffRenames(t *testing.T) {
+	repo := t.TempDir()
+	runGit := func(args ...string) {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repo
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+	writeFile := func(path, content string) {
+		full := filepath.Join(repo, path)
+		require.NoError(t, os.MkdirAll(filepath.Dir(full), 0755))
+		require.NoError(t, os.WriteFile(full, []byte(content), 0644))
+	}
+
+	var body strings.Builder
+	for i := 0; i < 20; i++ {
+		body.WriteString("line of shared content\n")
+	}
+
+	runGit("init", "-b", "main")
+	runGit("config", "user.email", "test@catnip.local")
+	runGit("config", "user.name", "Catnip Test")
+	writeFile("pkg/moved.go", "package pkg\n\n"+body.String())
+	writeFile("pkg/edited.go", "package pkg\n\nfunc Edited() {}\n"+body.String())
+	runGit("add", "-A")
+	runGit("commit", "-m", "initial")
+	runGit("checkout", "-b", "feature")
+
+	require.NoError(t, os.MkdirAll(filepath.Join(repo, "lib"), 0755))
+	runGit("mv", "pkg/moved.go", "lib/moved.go")
+	runGit("mv", "pkg/edited.go", "lib/edited.go")
+	writeFile("lib/edited.go", "package lib\n\nfunc Edited() {}\n"+body.String())
+	runGit("commit", "-am", "move files")
+
+	w := NewWorktreeManager(NewOperations())
+	diff, err := w.GetWorktreeDiff(&models.Worktree{Name: "feature", Path: repo}, "main", nil)
+	require.NoError(t, err)
+
+	files := make(map[string]FileDiff)
+	for _, file := range diff.FileDiffs {
+		files[file.FilePath] = file
+	}
+	require.Len(t, files, 2, "renames must be reported as one entry each")
+
+	t.Run("PureMove", func(t *testing.T) {
+		moved := files["lib/moved.go"]
+		assert.Equal(t, "renamed", moved.ChangeType)
+		assert.Equal(t, "pkg/moved.go", moved.OldPath)
+		assert.Equal(t, 100, moved.Similarity)
+		assert.Zero(t, moved.Additions)
+		assert.Zero(t, moved.Deletions)
+		assert.False(t, moved.IsExpanded)
+		assert.NotContains(t, moved.DiffText, "@@")
+	})
+
+	t.Run("MovedAndEdited", func(t *testing.T) {
+		edited := files["lib/edited.go"]
+		assert.Equal(t, "renamed", edited.ChangeType)
+		assert.Equal(t, "pkg/edited.go", edited.OldPath)
+		assert.Less(t, edited.Similarity, 100)
+		assert.Equal(t, 1, edited.Additions)
+		assert.Equal(t, 1, edited.Deletions)
+		assert.True(t, edited.IsExpanded)
+		assert.Contains(t, edited.DiffText, "-package pkg")
+		assert.Contains(t, edited.DiffText, "+package lib")
+		assert.NotContains(t, edited.DiffText, "-line of shared content")
+		assert.Contains(t, edited.OldContent, "package pkg")
+		assert.Contains(t, edited.NewContent, "package lib")
+	})
+
+	assert.Equal(t, 1, diff.TotalAdditions)
+	assert.Equal(t, 1, diff.TotalDeletions)
+}
+
+func TestGetWorktreeDiffSummary(t *testing.T) {
+	repo := t.TempDir()
+	runGit := func(args ...string) {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repo
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+	writeFile := func(path, content string) {
+		require.NoError(t, os.WriteFile(filepath.Join(repo, path), []byte(content), 0644))
+	}
+
+	runGit("init", "-b", "main")
+	runGit("config", "user.email", "test@catnip.local")
+	runGit("config", "user.name", "Catnip Test")
+	writeFile("app.go", "package app\n")
+	writeFile("old.go", "package app\n\nfunc Old() {}\n")
+	runGit("add", "-A")
+	runGit("commit", "-m", "initial")
+	runGit("checkout", "-b", "feature")
+
+	writeFile("app.go", "package app\n\nfunc App() {}\n")
+	writeFile("logo.png", "\x89PNG\x00\x00binary")
+	runGit("add", "-A")
+	runGit("commit", "-m", "feature work")
+	writeFile("old.go", "package app\n")
+	writeFile("notes.txt", "one\ntwo\n")
+	writeFile("blob.bin", "\x00\x01\x02")
+
+	w := NewWorktreeManager(NewOperations())
+	worktree := &models.Worktree{Name: "feature", Path: repo}
+	summary, err := w.GetWorktreeDiffSummary(worktree, "main", nil)
+	require.NoError(t, err)
+
+	var paths []string
+	files := make(map[string]FileDiff)
+	for _, file := range summary.Files {
+		paths = append(paths, file.FilePath)
+		files[file.FilePath] = file
+		assert.Empty(t, file.DiffText, "summaries carry no patches")
+		assert.Empty(t, file.NewContent)
+	}
+	assert.Equal(t, []string{"app.go", "logo.png", "old.go", "blob.bin", "notes.txt"}, paths)
+	assert.Equal(t, 5, summary.TotalFiles)
+	assert.Equal(t, 2, files["app.go"].Additions)
+	assert.Equal(t, "modified (unstaged)", files["old.go"].ChangeType)
+	assert.Equal(t, 2, files["old.go"].Deletions)
+	assert.Equal(t, 2, files["notes.txt"].Additions)
+
+	t.Run("BinaryFilesFlagged", func(t *testing.T) {
+		for _, path := range []string{"logo.png", "blob.bin"} {
+			assert.True(t, files[path].Binary, path)
+			assert.False(t, files[path].IsExpanded, path)
+
+			file, err := w.GetWorktreeFileDiff(worktree, "main", nil, path)
+			require.NoError(t, err)
+			assert.Empty(t, file.DiffText, path)
+			assert.Empty(t, file.NewContent, path)
+		}
+	})
+
+	t.Run("FileDiff", func(t *testing.T) {
+		file, err := w.GetWorktreeFileDiff(worktree, "main", nil, "app.go")
+		require.NoError(t, err)
+		assert.Contains(t, file.DiffText, "+func App() {}")
+		assert.Equal(t, "package app\n", file.OldContent)
+
+		file, err = w.GetWorktreeFileDiff(worktree, "main", nil, "old.go")
+		require.NoError(t, err)
+		assert.Contains(t, file.DiffText, "-func Old() {}")
+		assert.Equal(t, "package app\n", file.NewContent)
+
+		_, err = w.GetWorktreeFileDiff(worktree, "main", nil, "missing.go")
+		assert.ErrorContains(t, err, "file missing.go not found")
+	})
+
+	t.Run("FullDiffComposesBoth", func(t *testing.T) {
+		diff, err := w.GetWorktreeDiff(worktree, "main", nil)
+		require.NoError(t, err)
+		require.Len(t, diff.FileDiffs, 5)
+		assert.Contains(t, diff.FileDiffs[0].DiffText, "+func App() {}")
+		assert.Equal(t, "one\ntwo\n", diff.FileDiffs[4].NewContent)
+		assert.Equal(t, summary.TotalAdditions, diff.TotalAdditions)
+	})
+}
+
+func TestUpdateWorktreeStatusTiers(t *testing.T) {
+	repo := t.TempDir()
+	runGit := func(args ...string) string {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repo
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+		return strings.TrimSpace(string(out))
+	}
+
+	// feature is one commit ahead and one behind main, and upstream moves one further on fetch
+	runGit("init", "-b", "main")
+	runGit("config", "user.email", "test@catnip.local")
+	runGit("config", "user.name", "Catnip Test")
+	runGit("commit", "--allow-empty", "-m", "initial")
+	runGit("checkout", "-b", "feature")
+	runGit("commit", "--allow-empty", "-m", "feature work")
+	runGit("checkout", "main")
+	runGit("commit", "--allow-empty", "-m", "main work")
+	runGit("branch", "upstream")
+	runGit("commit", "--allow-empty", "-m", "pushed elsewhere")
+	fetchedTip := runGit("rev-parse", "HEAD")
+	runGit("reset", "--hard", "HEAD~1")
+	runGit("checkout", "feature")
+	require.NoError(t, os.WriteFile(filepath.Join(repo, "wip.txt"), []byte("wip\n"), 0644))
+
+	// Every field starts out stale, so refreshed fields are told apart by their value
+	stale := func() *models.Worktree {
+		return &models.Worktree{
+			ID: "wt-1", Name: "feature", Path: repo, Branch: "stale", SourceBranch: "main",
+			CommitHash: "stale", CommitCount: 99, CommitsBehind: 99, StashCount: 7,
+			IsDirty: false, HasConflicts: true,
+			PullRequestCommitCount: 99, PullRequestCommitsBehind: 99,
+		}
+	}
+	fields := func(worktree *models.Worktree) map[string]interface{} {
+		data, err := json.Marshal(worktree)
+		require.NoError(t, err)
+		var values map[string]interface{}
+		require.NoError(t, json.Unmarshal(data, &values))
+		return values
+	}
+
+	w := NewWorktreeManager(NewOperations())
+	for _, tier := range []models.StatusTier{models.StatusTierNone, models.StatusTierCheap, models.StatusTierStandard, models.StatusTierFull} {
+		t.Run(string(tier), func(t *testing.T) {
+			runGit("branch", "-f", "upstream", "main")
+			fetches := 0
+			refs := StatusRefs{
+				SourceRef:          func(*models.Worktree) string { return "upstream" },
+				PullRequestBaseRef: func(*models.Worktree) string { return "main" },
+				Fetch: func(*models.Worktree) error {
+					fetches++
+					runGit("branch", "-f", "upstream", fetchedTip)
+					return nil
+				},
+			}
+
+			worktree := stale()
+			w.UpdateWorktreeStatus(worktree, tier, refs)
+			before, after := fields(stale()), fields(worktree)
+			for field, fieldTier := range models.StatusFieldTiers {
+				if tier.Includes(fieldTier) {
+					assert.NotEqual(t, before[field], after[field], "%s is refreshed at the %s tier", field, fieldTier)
+				} else {
+					assert.Equal(t, before[field], after[field], "%s is left alone below the %s tier", field, fieldTier)
+				}
+			}
+
+			freshness := worktree.StatusFreshness
+			switch tier {
+			case models.StatusTierNone:
+				assert.Nil(t, freshness)
+				return
+			case models.StatusTierCheap:
+				assert.Nil(t, freshness.Divergence)
+			case models.StatusTierStandard:
+				assert.Equal(t, 1, worktree.CommitsBehind)
+				assert.Nil(t, freshness.Remote)
+			case models.StatusTierFull:
+				assert.Equal(t, 1, fetches)
+				assert.Equal(t, 2, worktree.CommitsBehind, "counted against the fetched ref")
+				require.NotNil(t, freshness.Remote)
+			}
+			require.NotNil(t, freshness.WorkingTree)
+			assert.Equal(t, tier, freshness.WorkingTree.Tier)
+			if tier != models.StatusTierFull {
+				assert.Zero(t, fetches, "only the full tier fetches")
+			}
+
+			assert.Equal(t, "feature", worktree.Branch)
+			assert.True(t, worktree.IsDirty)
+			assert.False(t, worktree.HasConflicts)
+			assert.Equal(t, runGit("rev-parse", "HEAD"), worktree.CommitHash)
+		})
+	}
+
+	t.Run("FailedFetchLeavesRemoteStale", func(t *testing.T) {
+		worktree := stale()
+		w.UpdateWorktreeStatus(worktree, models.StatusTierFull, StatusRefs{
+			SourceRef: func(*models.Worktree) string { return "main" },
+			Fetch:     func(*models.Worktree) error { return errors.New("network unreachable") },
+		})
+		assert.Nil(t, worktree.StatusFreshness.Remote)
+		assert.Equal(t, models.StatusTierFull, worktree.StatusFreshness.Divergence.Tier, "counts are still refreshed")
+		assert.Equal(t, 1, worktree.CommitsBehind)
+	})
+}
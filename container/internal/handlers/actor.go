@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/redact"
+	"github.com/vanpelt/catnip/internal/services"
+)
+
+// ActorHeader is the request header clients use to identify who is performing an operation
+const ActorHeader = "X-Catnip-Actor"
+
+// actorLocalsKey is the fiber locals key holding the resolved actor for a request
+const actorLocalsKey = "catnip_actor"
+
+// ActorMiddleware resolves the actor for each request and records mutating API calls
+// The actor comes from the X-Catnip-Actor header (or ?actor= for EventSource/WebSocket clients)
+// and falls back to "unknown". No authorization is enforced - this is attribution only.
+func ActorMiddleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		// An authenticated identity set by an earlier middleware takes precedence
+		actor, _ := c.Locals(actorLocalsKey).(string)
+		if actor == "" {
+			actor = c.Get(ActorHeader)
+		}
+		if actor == "" {
+			actor = c.Query("actor")
+		}
+		actor = services.NormalizeActor(actor)
+		c.Locals(actorLocalsKey, actor)
+
+		// Audit log entry for mutating API requests
+		if c.Method() != fiber.MethodGet && c.Method() != fiber.MethodHead && c.Method() != fiber.MethodOptions &&
+			strings.HasPrefix(c.Path(), "/v1/") {
+			logger.Infof("📋 %s %s by %s", c.Method(), redact.Text(c.Path()), actor)
+
+			// What the request does to a worktree, like deleting or merging it, is attributed to
+			// the actor in events and the activity log
+			if worktreeID := mutatedWorktreeID(c.Path()); worktreeID != "" {
+				defer services.ActOnWorktree(worktreeID, actor)()
+			}
+		}
+
+		return c.Next()
+	}
+}
+
+// mutatedWorktreeID returns the worktree ID of a /v1/git/worktrees/:id request path, or ""
+func mutatedWorktreeID(path string) string {
+	rest, ok := strings.CutPrefix(path, "/v1/git/worktrees/")
+	if !ok {
+		return ""
+	}
+	worktreeID, _, _ := strings.Cut(rest, "/")
+	return worktreeID
+}
+
+// GetActor returns the actor resolved for the current request
+func GetActor(c *fiber.Ctx) string {
+	if actor, ok := c.Locals(actorLocalsKey).(string); ok && actor != "" {
+		return actor
+	}
+	return services.DefaultActor
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/services"
+)
+
+func TestActorMiddleware(t *testing.T) {
+	app := fiber.New()
+	app.Use(ActorMiddleware())
+	app.All("/v1/whoami", func(c *fiber.Ctx) error {
+		return c.SendString(GetActor(c))
+	})
+
+	tests := []struct {
+		name     string
+		method   string
+		target   string
+		header   string
+		expected string
+	}{
+		{"header", "GET", "/v1/whoami", "alice", "alice"},
+		{"query param for EventSource clients", "GET", "/v1/whoami?actor=bob", "", "bob"},
+		{"header wins over query", "POST", "/v1/whoami?actor=bob", "alice", "alice"},
+		{"default when missing", "GET", "/v1/whoami", "", services.DefaultActor},
+		{"whitespace only", "GET", "/v1/whoami", "   ", services.DefaultActor},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, nil)
+			if tt.header != "" {
+				req.Header.Set(ActorHeader, tt.header)
+			}
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			body, _ := io.ReadAll(resp.Body)
+			assert.Equal(t, tt.expected, string(body))
+		})
+	}
+}
+
+func TestActorMiddlewareAttributesWorktreeMutations(t *testing.T) {
+	app := fiber.New()
+	app.Use(ActorMiddleware())
+	app.All("/v1/git/worktrees/:id/*", func(c *fiber.Ctx) error {
+		return c.SendString(services.WorktreeActor(c.Params("id")))
+	})
+
+	tests := []struct {
+		name     string
+		method   string
+		expected string
+	}{
+		{"mutation", "POST", "alice"},
+		{"read", "GET", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/v1/git/worktrees/wt-felix/merge", nil)
+			req.Header.Set(ActorHeader, "alice")
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			body, _ := io.ReadAll(resp.Body)
+			assert.Equal(t, tt.expected, string(body))
+		})
+	}
+	assert.Empty(t, services.WorktreeActor("wt-felix"), "the attribution ends with the request")
+
+	assert.Equal(t, "wt-felix", mutatedWorktreeID("/v1/git/worktrees/wt-felix"))
+	assert.Equal(t, "", mutatedWorktreeID("/v1/git/worktrees"))
+	assert.Equal(t, "", mutatedWorktreeID("/v1/git/repositories/acme"))
+}
+
+func TestAppendActorAttribution(t *testing.T) {
+	assert.Equal(t, "Body", services.AppendActorAttribution("Body", services.DefaultActor))
+	assert.Equal(t, "Body", services.AppendActorAttribution("Body", ""))
+	assert.Equal(t, "_Requested via catnip by alice_", services.AppendActorAttribution("", "alice"))
+	assert.Equal(t, "Body\n\n---\n_Requested via catnip by alice_", services.AppendActorAttribution("Body\n", "alice"))
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"os"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/cors"
+	"github.com/vanpelt/catnip/internal/config"
+)
+
+// BasePathMiddleware strips the CATNIP_BASE_URL path prefix (e.g. /catnip) from incoming
+// requests so catnip can be mounted on a subpath behind a reverse proxy that doesn't strip it
+func BasePathMiddleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		basePath := config.Runtime.BasePath()
+		if basePath == "" {
+			return c.Next()
+		}
+
+		path := c.Path()
+		if path == basePath {
+			c.Path("/")
+		} else if strings.HasPrefix(path, basePath+"/") {
+			c.Path(strings.TrimPrefix(path, basePath))
+		}
+		return c.Next()
+	}
+}
+
+// CORSConfig builds the CORS configuration for the API and SSE endpoints.
+// CATNIP_ALLOWED_ORIGINS is a comma separated list of origins; it defaults to "*".
+func CORSConfig() cors.Config {
+	origins := parseAllowedOrigins(os.Getenv("CATNIP_ALLOWED_ORIGINS"))
+
+	cfg := cors.Config{
+		AllowOrigins:  strings.Join(origins, ","),
+		AllowHeaders:  "Origin, Content-Type, Accept, Authorization, Last-Event-ID, Cache-Control, " + ActorHeader + ", " + TokenHeader,
+		ExposeHeaders: "Content-Type, X-Accel-Buffering",
+	}
+
+	// Credentials (the token cookie) can only be shared with explicitly listed origins
+	if cfg.AllowOrigins != "*" {
+		cfg.AllowCredentials = true
+	}
+
+	return cfg
+}
+
+// parseAllowedOrigins splits and normalizes the allowed origins list
+func parseAllowedOrigins(value string) []string {
+	var origins []string
+	for _, origin := range strings.Split(value, ",") {
+		origin = strings.TrimRight(strings.TrimSpace(origin), "/")
+		if origin == "" {
+			continue
+		}
+		if origin == "*" {
+			return []string{"*"}
+		}
+		origins = append(origins, origin)
+	}
+
+	// The configured external URL is always an allowed origin
+	if external := externalOrigin(); external != "" && len(origins) > 0 {
+		found := false
+		for _, origin := range origins {
+			if origin == external {
+				found = true
+				break
+			}
+		}
+		if !found {
+			origins = append(origins, external)
+		}
+	}
+
+	if len(origins) == 0 {
+		return []string{"*"}
+	}
+	return origins
+}
+
+// externalOrigin returns scheme://host of CATNIP_BASE_URL, or "" when unset
+func externalOrigin() string {
+	baseURL := config.Runtime.BaseURL
+	idx := strings.Index(baseURL, "://")
+	if idx < 0 {
+		return ""
+	}
+	rest := baseURL[idx+3:]
+	if slash := strings.Index(rest, "/"); slash >= 0 {
+		rest = rest[:slash]
+	}
+	return baseURL[:idx+3] + rest
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/config"
+)
+
+func withBaseURL(t *testing.T, baseURL string) {
+	original := config.Runtime.BaseURL
+	config.Runtime.BaseURL = baseURL
+	t.Cleanup(func() { config.Runtime.BaseURL = original })
+}
+
+func TestBasePathMiddleware(t *testing.T) {
+	withBaseURL(t, "https://example.com/catnip")
+
+	app := fiber.New()
+	app.Use(BasePathMiddleware())
+	app.Get("/", func(c *fiber.Ctx) error { return c.SendString("root") })
+	app.Get("/v1/events", func(c *fiber.Ctx) error { return c.SendString("events") })
+
+	tests := []struct {
+		target   string
+		expected string
+	}{
+		{"/catnip/v1/events", "events"},
+		{"/catnip", "root"},
+		{"/catnip/", "root"},
+		{"/v1/events", "events"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			resp, err := app.Test(httptest.NewRequest("GET", tt.target, nil))
+			require.NoError(t, err)
+			body, _ := io.ReadAll(resp.Body)
+			assert.Equal(t, tt.expected, string(body))
+		})
+	}
+}
+
+func TestRuntimeExternalURL(t *testing.T) {
+	withBaseURL(t, "https://example.com/catnip")
+	assert.Equal(t, "/catnip", config.Runtime.BasePath())
+	assert.Equal(t, "https://example.com/catnip/workspace/repo/main", config.Runtime.ExternalURL("/workspace/repo/main"))
+	assert.Equal(t, "https://example.com/catnip/3000/app", rewriteLocalhostPort("http://localhost:3000/app", "/catnip/3000/"))
+	assert.Equal(t, "wss://example.com/catnip/5173", rewriteLocalhostPort("ws://localhost:5173", "/catnip/5173/"))
+
+	withBaseURL(t, "")
+	assert.Equal(t, "", config.Runtime.BasePath())
+	assert.Equal(t, "http://localhost:6369/workspace", config.Runtime.ExternalURL("/workspace"))
+}
+
+func TestParseAllowedOrigins(t *testing.T) {
+	withBaseURL(t, "https://example.com/catnip")
+	assert.Equal(t, []string{"*"}, parseAllowedOrigins(""))
+	assert.Equal(t, []string{"*"}, parseAllowedOrigins("https://a.dev, *"))
+	assert.Equal(t, []string{"https://a.dev", "https://example.com"}, parseAllowedOrigins(" https://a.dev/ ,"))
+}
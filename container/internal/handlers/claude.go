@@ -1,7 +1,6 @@
 package handlers
 
 import (
-	"fmt"
 	"strings"
 
 	"github.com/gofiber/fiber/v2"
@@ -411,7 +410,7 @@ func (h *ClaudeHandler) HandleClaudeHook(c *fiber.Ctx) error {
 		// Check if working directory is a subdirectory of any workspace
 		var matchingWorktree *models.Worktree
 		for _, wt := range worktrees {
-			if strings.HasPrefix(req.WorkingDirectory, wt.Path) {
+			if _, ok := config.RelativePath(wt.Path, req.WorkingDirectory); ok {
 				// Use the longest matching path (most specific workspace)
 				if matchingWorktree == nil || len(wt.Path) > len(matchingWorktree.Path) {
 					matchingWorktree = wt
@@ -441,7 +440,7 @@ func (h *ClaudeHandler) HandleClaudeHook(c *fiber.Ctx) error {
 		// Check if working directory is a subdirectory of any workspace
 		var matchingWorktree *models.Worktree
 		for _, wt := range worktrees {
-			if strings.HasPrefix(req.WorkingDirectory, wt.Path) {
+			if _, ok := config.RelativePath(wt.Path, req.WorkingDirectory); ok {
 				// Use the longest matching path (most specific workspace)
 				if matchingWorktree == nil || len(wt.Path) > len(matchingWorktree.Path) {
 					matchingWorktree = wt
@@ -516,7 +515,7 @@ func (h *ClaudeHandler) HandleClaudeHook(c *fiber.Ctx) error {
 
 				// Generate workspace URL - remove workspace prefix if present
 				workspacePath := strings.TrimPrefix(workspaceDir, config.Runtime.WorkspaceDir)
-				workspaceURL := fmt.Sprintf("http://localhost:6369/workspace%s", workspacePath)
+				workspaceURL := config.Runtime.ExternalURL("/workspace" + workspacePath)
 
 				h.eventsHandler.broadcastEvent(AppEvent{
 					Type: NotificationEvent,
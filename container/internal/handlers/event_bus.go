@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EventsGapEvent tells a client that events were dropped because it fell behind
+const EventsGapEvent EventType = "events:gap"
+
+// sseClientQueueLimit is how many events may wait for a slow SSE client before the oldest are dropped
+const sseClientQueueLimit = 256
+
+// EventsGapPayload describes events dropped for a slow client
+type EventsGapPayload struct {
+	// Number of events dropped
+	Dropped int `json:"dropped"`
+	// Sequence number of the first event after the gap
+	ResumeSeq uint64 `json:"resume_seq"`
+}
+
+// eventBus numbers broadcast events and delivers them to SSE subscribers. Events are
+// dispatched in lanes: a worktree's events are delivered in the order they were published,
+// while lanes of different worktrees are delivered concurrently. Publishing never blocks on
+// subscribers.
+type eventBus struct {
+	lastSeq atomic.Uint64
+
+	mu    sync.Mutex
+	lanes map[string]*eventLane
+
+	subscribersMu sync.RWMutex
+	subscribers   map[string]*sseSubscriber
+}
+
+// eventLane holds the events of one worktree (or of the shared "" lane) waiting for delivery
+type eventLane struct {
+	queue []SSEMessage
+}
+
+func newEventBus() *eventBus {
+	return &eventBus{
+		lanes:       make(map[string]*eventLane),
+		subscribers: make(map[string]*sseSubscriber),
+	}
+}
+
+// publish numbers an event and queues it on its lane. The sequence number is assigned under
+// the same lock that queues it, so per lane the delivery order matches the numbering.
+func (b *eventBus) publish(lane string, event AppEvent) SSEMessage {
+	b.mu.Lock()
+	message := SSEMessage{
+		Event:     event,
+		Timestamp: time.Now().UnixMilli(),
+		ID:        uuid.New().String(),
+		Seq:       b.lastSeq.Add(1),
+	}
+	l, running := b.lanes[lane]
+	if !running {
+		l = &eventLane{}
+		b.lanes[lane] = l
+	}
+	l.queue = append(l.queue, message)
+	b.mu.Unlock()
+
+	// A lane's dispatcher runs while it has events and exits once drained
+	if !running {
+		go b.dispatch(lane, l)
+	}
+	return message
+}
+
+// dispatch delivers a lane's events in order until the lane is empty
+func (b *eventBus) dispatch(name string, l *eventLane) {
+	for {
+		b.mu.Lock()
+		batch := l.queue
+		l.queue = nil
+		if len(batch) == 0 {
+			delete(b.lanes, name)
+			b.mu.Unlock()
+			return
+		}
+		b.mu.Unlock()
+
+		b.subscribersMu.RLock()
+		for _, message := range batch {
+			for _, subscriber := range b.subscribers {
+				subscriber.push(message)
+			}
+		}
+		b.subscribersMu.RUnlock()
+	}
+}
+
+// subscribe registers a subscriber under id
+func (b *eventBus) subscribe(id string, subscriber *sseSubscriber) {
+	b.subscribersMu.Lock()
+	b.subscribers[id] = subscriber
+	b.subscribersMu.Unlock()
+}
+
+// unsubscribe removes and closes a subscriber
+func (b *eventBus) unsubscribe(id string) {
+	b.subscribersMu.Lock()
+	subscriber, ok := b.subscribers[id]
+	delete(b.subscribers, id)
+	b.subscribersMu.Unlock()
+	if ok {
+		subscriber.close()
+	}
+}
+
+// closeAll removes and closes every subscriber
+func (b *eventBus) closeAll() {
+	b.subscribersMu.Lock()
+	subscribers := b.subscribers
+	b.subscribers = make(map[string]*sseSubscriber)
+	b.subscribersMu.Unlock()
+	for _, subscriber := range subscribers {
+		subscriber.close()
+	}
+}
+
+// sseSubscriber queues the events waiting to be written to one SSE client. When the queue is
+// full the oldest events are dropped, and the client gets a gap marker in their place.
+type sseSubscriber struct {
+	mu      sync.Mutex
+	queue   []SSEMessage
+	limit   int
+	dropped int
+	closed  bool
+
+	// ready is signaled when events are queued, done is closed when the client is removed
+	ready chan struct{}
+	done  chan struct{}
+}
+
+func newSSESubscriber(limit int) *sseSubscriber {
+	return &sseSubscriber{
+		limit: limit,
+		ready: make(chan struct{}, 1),
+		done:  make(chan struct{}),
+	}
+}
+
+// push queues an event without blocking
+func (s *sseSubscriber) push(message SSEMessage) {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	if len(s.queue) >= s.limit {
+		s.queue = s.queue[1:]
+		s.dropped++
+	}
+	s.queue = append(s.queue, message)
+	s.mu.Unlock()
+
+	select {
+	case s.ready <- struct{}{}:
+	default:
+	}
+}
+
+// take returns the queued events, preceded by a gap marker when events were dropped
+func (s *sseSubscriber) take() []SSEMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.queue) == 0 {
+		return nil
+	}
+	messages := s.queue
+	s.queue = nil
+	if s.dropped > 0 {
+		gap := SSEMessage{
+			Event: AppEvent{
+				Type:    EventsGapEvent,
+				Payload: EventsGapPayload{Dropped: s.dropped, ResumeSeq: messages[0].Seq},
+			},
+			Timestamp: time.Now().UnixMilli(),
+			ID:        uuid.New().String(),
+		}
+		messages = append([]SSEMessage{gap}, messages...)
+		s.dropped = 0
+	}
+	return messages
+}
+
+// close stops the subscriber; queued events are discarded
+func (s *sseSubscriber) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.closed {
+		s.closed = true
+		s.queue = nil
+		close(s.done)
+	}
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// orderedPayload identifies the publisher of a test event and its position in that publisher's stream
+type orderedPayload struct {
+	Worktree  string
+	Publisher int
+	Index     int
+}
+
+// collect takes events from a subscriber until count have arrived or the timeout passes
+func collect(t *testing.T, subscriber *sseSubscriber, count int) []SSEMessage {
+	t.Helper()
+	var messages []SSEMessage
+	timeout := time.After(10 * time.Second)
+	for len(messages) < count {
+		select {
+		case <-subscriber.ready:
+			messages = append(messages, subscriber.take()...)
+		case <-timeout:
+			require.FailNow(t, "timed out waiting for events", "received %d of %d", len(messages), count)
+		}
+	}
+	return messages
+}
+
+// waitForDrain waits until every lane has been dispatched
+func waitForDrain(t *testing.T, bus *eventBus) {
+	t.Helper()
+	require.Eventually(t, func() bool {
+		bus.mu.Lock()
+		defer bus.mu.Unlock()
+		return len(bus.lanes) == 0
+	}, 5*time.Second, time.Millisecond)
+}
+
+func TestEventBusPreservesPerWorktreeOrder(t *testing.T) {
+	const worktrees, publishersPerWorktree, eventsPerPublisher = 20, 3, 200
+	total := worktrees * publishersPerWorktree * eventsPerPublisher
+
+	bus := newEventBus()
+	subscribers := []*sseSubscriber{newSSESubscriber(total), newSSESubscriber(total)}
+	for i, subscriber := range subscribers {
+		bus.subscribe(fmt.Sprintf("client-%d", i), subscriber)
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < worktrees; w++ {
+		for p := 0; p < publishersPerWorktree; p++ {
+			wg.Add(1)
+			go func(worktree string, publisher int) {
+				defer wg.Done()
+				for i := 0; i < eventsPerPublisher; i++ {
+					bus.publish(worktree, AppEvent{
+						Type:    WorktreeUpdatedEvent,
+						Payload: orderedPayload{Worktree: worktree, Publisher: publisher, Index: i},
+					})
+				}
+			}(fmt.Sprintf("wt-%d", w), p)
+		}
+	}
+	wg.Wait()
+
+	for _, subscriber := range subscribers {
+		messages := collect(t, subscriber, total)
+		require.Len(t, messages, total)
+
+		lastSeq := map[string]uint64{}
+		lastIndex := map[string]int{}
+		seen := map[uint64]bool{}
+		for _, message := range messages {
+			require.NotEqual(t, EventsGapEvent, message.Event.Type, "nothing is dropped below the queue limit")
+			payload := message.Event.Payload.(orderedPayload)
+			assert.False(t, seen[message.Seq], "sequence numbers are unique")
+			seen[message.Seq] = true
+
+			assert.Greater(t, message.Seq, lastSeq[payload.Worktree], "sequence numbers increase per worktree")
+			lastSeq[payload.Worktree] = message.Seq
+
+			publisher := fmt.Sprintf("%s/%d", payload.Worktree, payload.Publisher)
+			if last, ok := lastIndex[publisher]; ok {
+				assert.Equal(t, last+1, payload.Index, "a publisher's events arrive in order")
+			}
+			lastIndex[publisher] = payload.Index
+		}
+		assert.Len(t, lastIndex, worktrees*publishersPerWorktree)
+	}
+
+	assert.Equal(t, uint64(total), bus.lastSeq.Load())
+	waitForDrain(t, bus)
+}
+
+func TestEventBusDropsOldestForSlowSubscriber(t *testing.T) {
+	bus := newEventBus()
+	slow := newSSESubscriber(10)
+	bus.subscribe("slow", slow)
+
+	// Nobody reads from the slow subscriber, publishing still completes
+	for i := 0; i < 100; i++ {
+		bus.publish("wt-1", AppEvent{Type: WorktreeUpdatedEvent, Payload: orderedPayload{Worktree: "wt-1", Index: i}})
+	}
+	waitForDrain(t, bus)
+
+	messages := slow.take()
+	require.Len(t, messages, 11)
+	assert.Equal(t, EventsGapEvent, messages[0].Event.Type)
+	assert.Equal(t, uint64(0), messages[0].Seq, "gap markers are not numbered")
+	assert.Equal(t, EventsGapPayload{Dropped: 90, ResumeSeq: messages[1].Seq}, messages[0].Event.Payload)
+	for i, message := range messages[1:] {
+		assert.Equal(t, 90+i, message.Event.Payload.(orderedPayload).Index, "the newest events are kept")
+	}
+
+	// Once caught up no further gap is reported
+	bus.publish("wt-1", AppEvent{Type: WorktreeUpdatedEvent})
+	waitForDrain(t, bus)
+	messages = slow.take()
+	require.Len(t, messages, 1)
+	assert.Equal(t, WorktreeUpdatedEvent, messages[0].Event.Type)
+}
+
+func TestEventBusUnsubscribe(t *testing.T) {
+	bus := newEventBus()
+	subscriber := newSSESubscriber(sseClientQueueLimit)
+	bus.subscribe("client", subscriber)
+	bus.unsubscribe("client")
+
+	select {
+	case <-subscriber.done:
+	default:
+		t.Fatal("unsubscribe closes the subscriber")
+	}
+
+	bus.publish("", AppEvent{Type: HeartbeatEvent})
+	waitForDrain(t, bus)
+	assert.Empty(t, subscriber.take())
+
+	// Closing twice is harmless
+	bus.closeAll()
+	subscriber.close()
+}
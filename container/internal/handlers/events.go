@@ -15,6 +15,7 @@ import (
 	"github.com/valyala/fasthttp"
 	"github.com/vanpelt/catnip/internal/logger"
 	"github.com/vanpelt/catnip/internal/models"
+	"github.com/vanpelt/catnip/internal/redact"
 	"github.com/vanpelt/catnip/internal/services"
 )
 
@@ -23,29 +24,50 @@ type EventType string
 
 // Event type constants that match the frontend TypeScript definitions
 const (
-	PortOpenedEvent            EventType = "port:opened"
-	PortClosedEvent            EventType = "port:closed"
-	GitDirtyEvent              EventType = "git:dirty"
-	GitCleanEvent              EventType = "git:clean"
-	ProcessStartedEvent        EventType = "process:started"
-	ProcessStoppedEvent        EventType = "process:stopped"
-	ContainerStatusEvent       EventType = "container:status"
-	PortMappedEvent            EventType = "port:mapped"
-	HeartbeatEvent             EventType = "heartbeat"
-	WorktreeStatusUpdatedEvent EventType = "worktree:status_updated"
-	WorktreeBatchUpdatedEvent  EventType = "worktree:batch_updated"
-	WorktreeDirtyEvent         EventType = "worktree:dirty"
-	WorktreeCleanEvent         EventType = "worktree:clean"
-	WorktreeUpdatedEvent       EventType = "worktree:updated"
-	WorktreeCreatedEvent       EventType = "worktree:created"
-	WorktreeDeletedEvent       EventType = "worktree:deleted"
-	WorktreeTodosUpdatedEvent  EventType = "worktree:todos_updated"
-	SessionTitleUpdatedEvent   EventType = "session:title_updated"
-	SessionStoppedEvent        EventType = "session:stopped"
-	NotificationEvent          EventType = "notification:show"
-	ClaudeMessageEvent         EventType = "claude:message"
+	PortOpenedEvent              EventType = "port:opened"
+	PortClosedEvent              EventType = "port:closed"
+	GitDirtyEvent                EventType = "git:dirty"
+	GitCleanEvent                EventType = "git:clean"
+	ProcessStartedEvent          EventType = "process:started"
+	ProcessStoppedEvent          EventType = "process:stopped"
+	ContainerStatusEvent         EventType = "container:status"
+	PortMappedEvent              EventType = "port:mapped"
+	HeartbeatEvent               EventType = "heartbeat"
+	WorktreeStatusUpdatedEvent   EventType = "worktree:status_updated"
+	WorktreeBatchUpdatedEvent    EventType = "worktree:batch_updated"
+	WorktreeDirtyEvent           EventType = "worktree:dirty"
+	WorktreeCleanEvent           EventType = "worktree:clean"
+	WorktreeUpdatedEvent         EventType = "worktree:updated"
+	WorktreeCreatedEvent         EventType = "worktree:created"
+	WorktreeDeletedEvent         EventType = "worktree:deleted"
+	WorktreeTodosUpdatedEvent    EventType = "worktree:todos_updated"
+	SessionTitleUpdatedEvent     EventType = "session:title_updated"
+	RepositoryHealthWarningEvent EventType = "repository:health_warning"
+	RepositoryRenamedEvent       EventType = "repository:renamed"
+	SystemPanicEvent             EventType = "system:panic"
+	SystemDiskStatusEvent        EventType = "system:disk_status"
+	SystemStateReportEvent       EventType = "system:state_report"
+	WorktreeBranchDriftEvent     EventType = "worktree:branch_drift"
+	WorktreeSourceRewrittenEvent EventType = "worktree:source_rewritten"
+	WorktreeBranchRenameEvent    EventType = "worktree:branch_rename"
+	WorktreeMergePreparedEvent   EventType = "worktree:merge_prepared"
+	WorktreeMergeCompletedEvent  EventType = "worktree:merge_completed"
+	WorktreeMergeExpiredEvent    EventType = "worktree:merge_expired"
+	PullRequestCreatedEvent      EventType = "worktree:pull_request_created"
+	PullRequestStatusEvent       EventType = "worktree:pull_request_status"
+	WorktreeHealthEvent          EventType = "worktree:health"
+	WorktreeFromIssueEvent       EventType = "worktree:from_issue"
+	SessionStoppedEvent          EventType = "session:stopped"
+	NotificationEvent            EventType = "notification:show"
+	ClaudeMessageEvent           EventType = "claude:message"
 )
 
+// sseKeepAliveInterval is how often idle SSE streams get a heartbeat and ping comment
+const sseKeepAliveInterval = 15 * time.Second
+
+// sseInitialPadding is written as a comment when a stream opens to push it past proxy buffers
+var sseInitialPadding = strings.Repeat(" ", 2048)
+
 type AppEvent struct {
 	Type    EventType `json:"type"`
 	Payload any       `json:"payload"`
@@ -109,18 +131,24 @@ type WorktreeDirtyPayload struct {
 	Files        []string `json:"files,omitempty"`
 }
 
+// Mutation payloads carry the actor of the API request that caused them, omitted when catnip
+// acted by itself
+
 type WorktreeUpdatedPayload struct {
 	WorktreeID string                 `json:"worktree_id"`
 	Updates    map[string]interface{} `json:"updates"`
+	Actor      string                 `json:"actor,omitempty"`
 }
 
 type WorktreeCreatedPayload struct {
 	Worktree interface{} `json:"worktree"`
+	Actor    string      `json:"actor,omitempty"`
 }
 
 type WorktreeDeletedPayload struct {
 	WorktreeID   string `json:"worktree_id"`
 	WorktreeName string `json:"worktree_name"`
+	Actor        string `json:"actor,omitempty"`
 }
 
 type WorktreeTodosUpdatedPayload struct {
@@ -135,6 +163,80 @@ type SessionTitleUpdatedPayload struct {
 	SessionTitleHistory []models.TitleEntry `json:"session_title_history"`
 }
 
+type RepositoryHealthWarningPayload struct {
+	RepoID  string `json:"repo_id"`
+	Source  string `json:"source"`
+	Message string `json:"message"`
+}
+
+type RepositoryRenamedPayload struct {
+	OldID string `json:"old_id"`
+	NewID string `json:"new_id"`
+}
+
+type SystemPanicPayload struct {
+	Subsystem string `json:"subsystem"`
+	Goroutine string `json:"goroutine"`
+	Message   string `json:"message"`
+	Panics    int    `json:"panics"`
+}
+
+type SystemDiskStatusPayload struct {
+	Disk services.DiskStatus `json:"disk"`
+}
+
+type SystemStateReportPayload struct {
+	Report *models.StateReport `json:"report"`
+}
+
+type WorktreeBranchDriftPayload struct {
+	WorktreeID string              `json:"worktree_id"`
+	Drift      *models.BranchDrift `json:"drift"`
+}
+
+type WorktreeSourceRewrittenPayload struct {
+	WorktreeID string                `json:"worktree_id"`
+	Rewrite    *models.SourceRewrite `json:"rewrite"`
+}
+
+type WorktreeBranchRenamePayload struct {
+	WorktreeID string                      `json:"worktree_id"`
+	Outcome    *models.BranchRenameOutcome `json:"outcome"`
+	Actor      string                      `json:"actor,omitempty"`
+}
+
+type WorktreeMergePayload struct {
+	WorktreeID string               `json:"worktree_id"`
+	Preview    *models.MergePreview `json:"preview"`
+	Actor      string               `json:"actor,omitempty"`
+}
+
+type PullRequestCreatedPayload struct {
+	WorktreeID  string                      `json:"worktree_id"`
+	PullRequest *models.PullRequestResponse `json:"pull_request"`
+	Actor       string                      `json:"actor,omitempty"`
+}
+
+type PullRequestStatusPayload struct {
+	WorktreeID  string                   `json:"worktree_id"`
+	PullRequest *models.PullRequestState `json:"pull_request"`
+}
+
+type WorktreeHealthPayload struct {
+	WorktreeID string                 `json:"worktree_id"`
+	Findings   []models.HealthFinding `json:"findings"`
+}
+
+type WorktreeFromIssuePayload struct {
+	WorktreeID   string                 `json:"worktree_id"`
+	WorktreeName string                 `json:"worktree_name"`
+	WorktreePath string                 `json:"worktree_path"`
+	RepoID       string                 `json:"repo_id"`
+	Issue        *models.AutomatedIssue `json:"issue"`
+	// Issue title and body, the starting point for a session in the worktree
+	Prompt string `json:"prompt,omitempty"`
+}
+
 type SessionStoppedPayload struct {
 	WorkspaceDir string  `json:"workspace_dir"`
 	WorktreeID   *string `json:"worktree_id,omitempty"`
@@ -155,18 +257,19 @@ type SSEMessage struct {
 	Event     AppEvent `json:"event"`
 	Timestamp int64    `json:"timestamp"`
 	ID        string   `json:"id"`
+	// Monotonic sequence number of broadcast events (0 for per-client messages like heartbeats)
+	Seq uint64 `json:"seq,omitempty"`
 }
 
 type EventsHandler struct {
-	portMonitor        *services.PortMonitor
-	gitService         *services.GitService
-	clients            map[string]chan SSEMessage
-	clientsMux         sync.RWMutex
-	clientConnectTimes map[string]time.Time
-	startTime          time.Time
-	stopChan           chan bool
-	lastPortCheck      time.Time
-	lastPortCheckMux   sync.RWMutex
+	portMonitor *services.PortMonitor
+	gitService  *services.GitService
+	// numbers broadcast events and delivers them to connected clients
+	bus              *eventBus
+	startTime        time.Time
+	stopChan         chan bool
+	lastPortCheck    time.Time
+	lastPortCheckMux sync.RWMutex
 	// host port mappings for container ports
 	portMappings   map[int]int
 	portMappingMux sync.RWMutex
@@ -174,13 +277,12 @@ type EventsHandler struct {
 
 func NewEventsHandler(portMonitor *services.PortMonitor, gitService *services.GitService) *EventsHandler {
 	h := &EventsHandler{
-		portMonitor:        portMonitor,
-		gitService:         gitService,
-		clients:            make(map[string]chan SSEMessage),
-		clientConnectTimes: make(map[string]time.Time),
-		startTime:          time.Now(),
-		stopChan:           make(chan bool),
-		portMappings:       make(map[int]int),
+		portMonitor:  portMonitor,
+		gitService:   gitService,
+		bus:          newEventBus(),
+		startTime:    time.Now(),
+		stopChan:     make(chan bool),
+		portMappings: make(map[int]int),
 	}
 
 	// Start listening for port changes
@@ -226,20 +328,32 @@ func NewEventsHandler(portMonitor *services.PortMonitor, gitService *services.Gi
 // @Description   - `message` (string): Optional status message
 // @Description
 // @Description ### System Events
-// @Description - **heartbeat**: Sent every 5 seconds to keep connection alive
+// @Description - **heartbeat**: Sent every 15 seconds to keep connection alive
 // @Description   - `timestamp` (int64): Current timestamp in milliseconds
 // @Description   - `uptime` (int64): Server uptime in milliseconds
+// @Description - **system:panic**: Fired when a background goroutine panicked and was recovered
+// @Description   - `subsystem` (string): Subsystem of the goroutine
+// @Description   - `goroutine` (string): Goroutine name
+// @Description   - `message` (string): Recovered panic value
+// @Description   - `panics` (int): Panics of the subsystem since the server started
+// @Description - **system:state_report**: Sent when a stream opens while loading the persisted state at startup migrated, quarantined or lost state files or found repositories or worktrees unavailable, until the report is acknowledged
+// @Description   - `report` (object): Report as in the state_report of git status
+// @Description
+// @Description - **events:gap**: Sent in place of events dropped because the client fell behind
+// @Description   - `dropped` (int): Number of dropped events
+// @Description   - `resume_seq` (int): Sequence number of the first event after the gap
 // @Description
 // @Description ## Message Format
 // @Description Each SSE message is a JSON object with:
 // @Description - `event`: Event object containing `type` and `payload`
 // @Description - `timestamp`: Event timestamp in milliseconds
 // @Description - `id`: Unique event identifier
+// @Description - `seq`: Sequence number of broadcast events; events of the same worktree arrive in sequence order
 // @Description
 // @Description ## Connection Behavior
 // @Description - Auto-reconnects on disconnection
 // @Description - Sends current state on initial connection
-// @Description - Heartbeat every 5 seconds
+// @Description - Heartbeat and `: ping` comment every 15 seconds (keeps reverse proxies from buffering)
 // @Description - Rate limited to prevent spam
 // @Tags events
 // @Accept text/event-stream
@@ -262,7 +376,7 @@ func (h *EventsHandler) HandleSSE(c *fiber.Ctx) error {
 	// 2.  Prepare HTTP headers once
 	//--------------------------------------------------------------------
 	c.Set("Content-Type", "text/event-stream")
-	c.Set("Cache-Control", "no-cache")
+	c.Set("Cache-Control", "no-cache, no-transform") // no-transform stops proxies from compressing/buffering
 	c.Set("Connection", "keep-alive")
 	c.Set("X-Accel-Buffering", "no") // disable nginx buffering
 
@@ -271,15 +385,17 @@ func (h *EventsHandler) HandleSSE(c *fiber.Ctx) error {
 	//--------------------------------------------------------------------
 	clientID := uuid.New().String()
 	clientType := c.Query("client", "unknown")
-	ch := make(chan SSEMessage, 100)
+	subscriber := newSSESubscriber(sseClientQueueLimit)
 
-	h.addClient(clientID, ch)
+	h.addClient(clientID, subscriber)
 	logger.Infof("SSE client connected: %s (%s) from %s", clientID, clientType, c.IP())
 
 	//--------------------------------------------------------------------
 	// 4.  Stream writer
 	//--------------------------------------------------------------------
 	c.Context().SetBodyStreamWriter(fasthttp.StreamWriter(func(w *bufio.Writer) {
+		defer h.removeClient(clientID)
+
 		flushOrDie := func() bool {
 			// ??? || c.Context().IsConnectionClosed()
 			if err := w.Flush(); err != nil {
@@ -308,6 +424,12 @@ func (h *EventsHandler) HandleSSE(c *fiber.Ctx) error {
 			return flushOrDie()
 		}
 
+		// Some buffering proxies hold back the first few KB of a response, so prime the
+		// stream with a padding comment before any real events
+		if _, err := fmt.Fprintf(w, ":%s\n\n", sseInitialPadding); err != nil {
+			return
+		}
+
 		// ---------------- initial state ----------------
 		if !send(h.makeHeartbeat()) {
 			return
@@ -315,6 +437,11 @@ func (h *EventsHandler) HandleSSE(c *fiber.Ctx) error {
 		if !send(h.makeContainerStatus()) {
 			return
 		}
+		if h.gitService != nil {
+			if report := h.gitService.GetStatus().StateReport; report != nil && !send(h.makeStateReport(report)) {
+				return
+			}
+		}
 		for _, p := range h.portMonitor.GetServices() {
 			if !send(h.makePortOpened(p)) {
 				return
@@ -343,19 +470,24 @@ func (h *EventsHandler) HandleSSE(c *fiber.Ctx) error {
 		h.portMappingMux.RUnlock()
 
 		// ---------------- main loop --------------------
-		tick := time.NewTicker(30 * time.Second)
+		tick := time.NewTicker(sseKeepAliveInterval)
 		defer tick.Stop()
 
 		for {
 			select {
-			case msg, ok := <-ch:
-				if !ok {
-					logger.Warnf("Event client %s is closed somehow!", clientID)
+			case <-subscriber.ready:
+				for _, msg := range subscriber.take() {
+					if !send(msg) {
+						return
+					}
 				}
-				if !ok || !send(msg) {
+			case <-subscriber.done:
+				return
+			case <-tick.C:
+				// Comment line keeps idle proxies from closing or buffering the stream
+				if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
 					return
 				}
-			case <-tick.C:
 				send(h.makeHeartbeat())
 				if !flushOrDie() {
 					return
@@ -367,23 +499,14 @@ func (h *EventsHandler) HandleSSE(c *fiber.Ctx) error {
 	return nil
 }
 
-func (h *EventsHandler) addClient(id string, ch chan SSEMessage) {
-	h.clientsMux.Lock()
-	h.clients[id] = ch
-	h.clientConnectTimes[id] = time.Now()
+func (h *EventsHandler) addClient(id string, subscriber *sseSubscriber) {
+	h.bus.subscribe(id, subscriber)
 	logger.Debugf("Added event client %s", id)
-	h.clientsMux.Unlock()
 }
 
 func (h *EventsHandler) removeClient(id string) {
-	h.clientsMux.Lock()
-	if ch, ok := h.clients[id]; ok {
-		logger.Debugf("Removing eventclient %s", id)
-		close(ch)
-		delete(h.clients, id)
-	}
-	delete(h.clientConnectTimes, id)
-	h.clientsMux.Unlock()
+	logger.Debugf("Removing event client %s", id)
+	h.bus.unsubscribe(id)
 }
 
 // --- small builders to keep main handler tiny ---
@@ -416,6 +539,17 @@ func (h *EventsHandler) makeContainerStatus() SSEMessage {
 	}
 }
 
+func (h *EventsHandler) makeStateReport(report *models.StateReport) SSEMessage {
+	return SSEMessage{
+		Event: AppEvent{
+			Type:    SystemStateReportEvent,
+			Payload: SystemStateReportPayload{Report: report},
+		},
+		Timestamp: time.Now().UnixMilli(),
+		ID:        uuid.New().String(),
+	}
+}
+
 func (h *EventsHandler) makePortOpened(s *services.ServiceInfo) SSEMessage {
 	// fill optional pointers exactly as before
 	return SSEMessage{
@@ -510,19 +644,24 @@ func (h *EventsHandler) monitorPorts() {
 	}
 }
 
+// broadcastEvent sends an event that isn't about a single worktree to all connected clients
 func (h *EventsHandler) broadcastEvent(event AppEvent) {
+	h.broadcastWorktreeEvent("", event)
+}
+
+// broadcastWorktreeEvent sends an event to all connected clients. Events of the same worktree
+// reach clients in the order they were broadcast.
+func (h *EventsHandler) broadcastWorktreeEvent(worktreeID string, event AppEvent) {
 	// Validate event before broadcasting
 	if event.Type == "" {
 		logger.Warnf("Attempting to broadcast event with empty type")
 		return
 	}
-	// Remove noisy broadcasting log - too frequent and not helpful
 
-	message := SSEMessage{
-		Event:     event,
-		Timestamp: time.Now().UnixMilli(),
-		ID:        uuid.New().String(),
-	}
+	// Secrets are masked before the event is queued, so they never reach clients or the replay
+	// buffer
+	event.Payload = redactEventPayload(event.Payload)
+	message := h.bus.publish(worktreeID, event)
 
 	// Debug JSON serialization for session:stopped events
 	if event.Type == SessionStoppedEvent {
@@ -533,44 +672,24 @@ func (h *EventsHandler) broadcastEvent(event AppEvent) {
 			logger.Debugf("🔔 Serialized session:stopped event: %s", string(jsonData))
 		}
 	}
+}
 
-	h.clientsMux.RLock()
-	clientsToRemove := []string{}
-
-	for clientID, clientChan := range h.clients {
-		select {
-		case clientChan <- message:
-			// Debug session:stopped events specifically
-			if message.Event.Type == SessionStoppedEvent {
-				logger.Debugf("🔔 Successfully sent session:stopped event to client %s", clientID)
-			}
-		default:
-			// Debug session:stopped events that fail to send
-			if message.Event.Type == SessionStoppedEvent {
-				logger.Warnf("🔔 Failed to send session:stopped event to client %s (channel full/closed)", clientID)
-			}
-
-			// Check if client is in grace period (first 2 seconds after connection)
-			connectTime, exists := h.clientConnectTimes[clientID]
-			gracePeriod := 2 * time.Second
-
-			if exists && time.Since(connectTime) < gracePeriod {
-				// Client is in grace period, don't remove yet
-				logger.Debugf("Client %s in grace period, not removing (connected %v ago)", clientID, time.Since(connectTime))
-			} else {
-				// Client channel is full or closed, mark for removal
-				clientsToRemove = append(clientsToRemove, clientID)
-			}
-		}
+// redactEventPayload returns the payload with its secrets masked as raw JSON, or the payload
+// itself when it holds none
+func redactEventPayload(payload any) any {
+	if payload == nil {
+		return nil
 	}
-	h.clientsMux.RUnlock()
-
-	// Remove disconnected clients
-	if len(clientsToRemove) > 0 {
-		for _, clientID := range clientsToRemove {
-			h.removeClient(clientID)
-		}
+	data, changed, err := redact.Default().JSON(payload)
+	if err != nil || !changed {
+		return payload
 	}
+	return data
+}
+
+// LastEventSequence returns the sequence number of the most recently broadcast event
+func (h *EventsHandler) LastEventSequence() uint64 {
+	return h.bus.lastSeq.Load()
 }
 
 // SetPortMapping records and broadcasts a host mapping for a container port
@@ -662,7 +781,7 @@ func (h *EventsHandler) EmitContainerStatus(status string, message *string) {
 
 // EmitWorktreeStatusUpdated broadcasts a single worktree status update to all connected clients
 func (h *EventsHandler) EmitWorktreeStatusUpdated(worktreeID string, status *services.CachedWorktreeStatus) {
-	h.broadcastEvent(AppEvent{
+	h.broadcastWorktreeEvent(worktreeID, AppEvent{
 		Type: WorktreeStatusUpdatedEvent,
 		Payload: WorktreeStatusPayload{
 			WorktreeID: worktreeID,
@@ -683,7 +802,7 @@ func (h *EventsHandler) EmitWorktreeBatchUpdated(updates map[string]*services.Ca
 
 // EmitWorktreeDirty broadcasts a worktree dirty event to all connected clients
 func (h *EventsHandler) EmitWorktreeDirty(worktreeID, worktreeName string, files []string) {
-	h.broadcastEvent(AppEvent{
+	h.broadcastWorktreeEvent(worktreeID, AppEvent{
 		Type: WorktreeDirtyEvent,
 		Payload: WorktreeDirtyPayload{
 			WorktreeID:   worktreeID,
@@ -695,7 +814,7 @@ func (h *EventsHandler) EmitWorktreeDirty(worktreeID, worktreeName string, files
 
 // EmitWorktreeClean broadcasts a worktree clean event to all connected clients
 func (h *EventsHandler) EmitWorktreeClean(worktreeID, worktreeName string) {
-	h.broadcastEvent(AppEvent{
+	h.broadcastWorktreeEvent(worktreeID, AppEvent{
 		Type: WorktreeCleanEvent,
 		Payload: WorktreeDirtyPayload{
 			WorktreeID:   worktreeID,
@@ -706,39 +825,42 @@ func (h *EventsHandler) EmitWorktreeClean(worktreeID, worktreeName string) {
 
 // EmitWorktreeUpdated broadcasts a worktree updated event to all connected clients
 func (h *EventsHandler) EmitWorktreeUpdated(worktreeID string, updates map[string]interface{}) {
-	h.broadcastEvent(AppEvent{
+	h.broadcastWorktreeEvent(worktreeID, AppEvent{
 		Type: WorktreeUpdatedEvent,
 		Payload: WorktreeUpdatedPayload{
 			WorktreeID: worktreeID,
 			Updates:    updates,
+			Actor:      services.WorktreeActor(worktreeID),
 		},
 	})
 }
 
 // EmitWorktreeCreated broadcasts a worktree created event to all connected clients
 func (h *EventsHandler) EmitWorktreeCreated(worktree *models.Worktree) {
-	h.broadcastEvent(AppEvent{
+	h.broadcastWorktreeEvent(worktree.ID, AppEvent{
 		Type: WorktreeCreatedEvent,
 		Payload: WorktreeCreatedPayload{
 			Worktree: worktree,
+			Actor:    worktree.CreatedBy,
 		},
 	})
 }
 
 // EmitWorktreeDeleted broadcasts a worktree deleted event to all connected clients
 func (h *EventsHandler) EmitWorktreeDeleted(worktreeID, worktreeName string) {
-	h.broadcastEvent(AppEvent{
+	h.broadcastWorktreeEvent(worktreeID, AppEvent{
 		Type: WorktreeDeletedEvent,
 		Payload: WorktreeDeletedPayload{
 			WorktreeID:   worktreeID,
 			WorktreeName: worktreeName,
+			Actor:        services.WorktreeActor(worktreeID),
 		},
 	})
 }
 
 // EmitWorktreeTodosUpdated broadcasts a worktree todos updated event to all connected clients
 func (h *EventsHandler) EmitWorktreeTodosUpdated(worktreeID string, todos []models.Todo) {
-	h.broadcastEvent(AppEvent{
+	h.broadcastWorktreeEvent(worktreeID, AppEvent{
 		Type: WorktreeTodosUpdatedEvent,
 		Payload: WorktreeTodosUpdatedPayload{
 			WorktreeID: worktreeID,
@@ -749,7 +871,7 @@ func (h *EventsHandler) EmitWorktreeTodosUpdated(worktreeID string, todos []mode
 
 // EmitSessionTitleUpdated broadcasts a session title updated event to all connected clients
 func (h *EventsHandler) EmitSessionTitleUpdated(workspaceDir, worktreeID string, sessionTitle *models.TitleEntry, sessionTitleHistory []models.TitleEntry) {
-	h.broadcastEvent(AppEvent{
+	h.broadcastWorktreeEvent(worktreeID, AppEvent{
 		Type: SessionTitleUpdatedEvent,
 		Payload: SessionTitleUpdatedPayload{
 			WorkspaceDir:        workspaceDir,
@@ -760,10 +882,165 @@ func (h *EventsHandler) EmitSessionTitleUpdated(workspaceDir, worktreeID string,
 	})
 }
 
+// EmitRepositoryHealthWarning broadcasts a repository health warning to all connected clients
+func (h *EventsHandler) EmitRepositoryHealthWarning(repoID, source, message string) {
+	h.broadcastEvent(AppEvent{
+		Type: RepositoryHealthWarningEvent,
+		Payload: RepositoryHealthWarningPayload{
+			RepoID:  repoID,
+			Source:  source,
+			Message: message,
+		},
+	})
+}
+
+// EmitRepositoryRenamed broadcasts that a repository was migrated to its new GitHub name
+func (h *EventsHandler) EmitRepositoryRenamed(oldID, newID string) {
+	h.broadcastEvent(AppEvent{
+		Type: RepositoryRenamedEvent,
+		Payload: RepositoryRenamedPayload{
+			OldID: oldID,
+			NewID: newID,
+		},
+	})
+}
+
+// EmitSystemPanic broadcasts that a background goroutine panicked
+func (h *EventsHandler) EmitSystemPanic(subsystem, goroutine, message string, panics int) {
+	h.broadcastEvent(AppEvent{
+		Type: SystemPanicEvent,
+		Payload: SystemPanicPayload{
+			Subsystem: subsystem,
+			Goroutine: goroutine,
+			Message:   message,
+			Panics:    panics,
+		},
+	})
+}
+
+// EmitDiskStatusChanged broadcasts that the workspace volume crossed the low disk space threshold
+func (h *EventsHandler) EmitDiskStatusChanged(status services.DiskStatus) {
+	h.broadcastEvent(AppEvent{
+		Type:    SystemDiskStatusEvent,
+		Payload: SystemDiskStatusPayload{Disk: status},
+	})
+}
+
+// EmitWorktreeBranchDrift broadcasts that a worktree's branch was changed outside catnip
+func (h *EventsHandler) EmitWorktreeBranchDrift(worktreeID string, drift *models.BranchDrift) {
+	h.broadcastWorktreeEvent(worktreeID, AppEvent{
+		Type: WorktreeBranchDriftEvent,
+		Payload: WorktreeBranchDriftPayload{
+			WorktreeID: worktreeID,
+			Drift:      drift,
+		},
+	})
+}
+
+// EmitWorktreeSourceRewritten broadcasts that a worktree's remote source branch was force-pushed
+func (h *EventsHandler) EmitWorktreeSourceRewritten(worktreeID string, rewrite *models.SourceRewrite) {
+	h.broadcastWorktreeEvent(worktreeID, AppEvent{
+		Type: WorktreeSourceRewrittenEvent,
+		Payload: WorktreeSourceRewrittenPayload{
+			WorktreeID: worktreeID,
+			Rewrite:    rewrite,
+		},
+	})
+}
+
+// EmitWorktreeBranchRename broadcasts the outcome of a branch rename attempt, whether the branch
+// was renamed or not
+func (h *EventsHandler) EmitWorktreeBranchRename(worktreeID string, outcome *models.BranchRenameOutcome) {
+	h.broadcastWorktreeEvent(worktreeID, AppEvent{
+		Type: WorktreeBranchRenameEvent,
+		Payload: WorktreeBranchRenamePayload{
+			WorktreeID: worktreeID,
+			Outcome:    outcome,
+			Actor:      services.WorktreeActor(worktreeID),
+		},
+	})
+}
+
+// mergeEventTypes maps merge preview phases to their event types
+var mergeEventTypes = map[services.MergePhase]EventType{
+	services.MergePrepared:  WorktreeMergePreparedEvent,
+	services.MergeCompleted: WorktreeMergeCompletedEvent,
+	services.MergeExpired:   WorktreeMergeExpiredEvent,
+}
+
+// EmitWorktreeMerge broadcasts that a reviewed merge was prepared, completed or expired
+func (h *EventsHandler) EmitWorktreeMerge(phase services.MergePhase, preview *models.MergePreview) {
+	h.broadcastWorktreeEvent(preview.WorktreeID, AppEvent{
+		Type: mergeEventTypes[phase],
+		Payload: WorktreeMergePayload{
+			WorktreeID: preview.WorktreeID,
+			Preview:    preview,
+			Actor:      services.WorktreeActor(preview.WorktreeID),
+		},
+	})
+}
+
+// EmitPullRequestCreated broadcasts that a pull request was opened for a worktree
+func (h *EventsHandler) EmitPullRequestCreated(worktreeID string, pr *models.PullRequestResponse) {
+	h.broadcastWorktreeEvent(worktreeID, AppEvent{
+		Type: PullRequestCreatedEvent,
+		Payload: PullRequestCreatedPayload{
+			WorktreeID:  worktreeID,
+			PullRequest: pr,
+			Actor:       services.WorktreeActor(worktreeID),
+		},
+	})
+}
+
+// EmitPullRequestStatus broadcasts the state of a worktree's pull request freshly read from GitHub
+func (h *EventsHandler) EmitPullRequestStatus(worktreeID string, state *models.PullRequestState) {
+	h.broadcastWorktreeEvent(worktreeID, AppEvent{
+		Type: PullRequestStatusEvent,
+		Payload: PullRequestStatusPayload{
+			WorktreeID:  worktreeID,
+			PullRequest: state,
+		},
+	})
+}
+
+// EmitWorktreeHealth broadcasts the findings of a worktree health check that found new problems
+func (h *EventsHandler) EmitWorktreeHealth(health *models.WorktreeHealth) {
+	h.broadcastWorktreeEvent(health.WorktreeID, AppEvent{
+		Type: WorktreeHealthEvent,
+		Payload: WorktreeHealthPayload{
+			WorktreeID: health.WorktreeID,
+			Findings:   health.Findings,
+		},
+	})
+}
+
+// EmitWorktreeFromIssue broadcasts that issue automation created a worktree for a labeled issue,
+// so a driver process can start a session there
+func (h *EventsHandler) EmitWorktreeFromIssue(worktree *models.Worktree, issue *models.AutomatedIssue) {
+	payload := WorktreeFromIssuePayload{
+		WorktreeID:   worktree.ID,
+		WorktreeName: worktree.Name,
+		WorktreePath: worktree.Path,
+		RepoID:       worktree.RepoID,
+		Issue:        issue,
+	}
+	if worktree.CreationContext != nil {
+		payload.Prompt = worktree.CreationContext.Prompt
+	}
+	h.broadcastWorktreeEvent(worktree.ID, AppEvent{
+		Type:    WorktreeFromIssueEvent,
+		Payload: payload,
+	})
+}
+
 // EmitSessionStopped broadcasts a session stopped event to all connected clients
 func (h *EventsHandler) EmitSessionStopped(workspaceDir string, worktreeID *string, sessionTitle *string, branchName *string, lastTodo *string) {
 	logger.Debugf("🔔 EmitSessionStopped called - WorkspaceDir: %s, WorktreeID: %v, SessionTitle: %v, BranchName: %v, LastTodo: %v", workspaceDir, worktreeID, sessionTitle, branchName, lastTodo)
-	h.broadcastEvent(AppEvent{
+	lane := ""
+	if worktreeID != nil {
+		lane = *worktreeID
+	}
+	h.broadcastWorktreeEvent(lane, AppEvent{
 		Type: SessionStoppedEvent,
 		Payload: SessionStoppedPayload{
 			WorkspaceDir: workspaceDir,
@@ -777,7 +1054,7 @@ func (h *EventsHandler) EmitSessionStopped(workspaceDir string, worktreeID *stri
 
 // EmitClaudeMessage broadcasts a Claude message event to all connected clients
 func (h *EventsHandler) EmitClaudeMessage(workspaceDir, worktreeID, message, messageType string) {
-	h.broadcastEvent(AppEvent{
+	h.broadcastWorktreeEvent(worktreeID, AppEvent{
 		Type: ClaudeMessageEvent,
 		Payload: ClaudeMessagePayload{
 			WorkspaceDir: workspaceDir,
@@ -793,12 +1070,5 @@ func (h *EventsHandler) EmitClaudeMessage(workspaceDir, worktreeID, message, mes
 func (h *EventsHandler) Stop() {
 	close(h.stopChan)
 	logger.Info("Stopping events handler...")
-	h.clientsMux.Lock()
-	defer h.clientsMux.Unlock()
-
-	for _, clientChan := range h.clients {
-		close(clientChan)
-	}
-	h.clients = make(map[string]chan SSEMessage)
-	h.clientConnectTimes = make(map[string]time.Time)
+	h.bus.closeAll()
 }
@@ -1,13 +1,16 @@
 package handlers
 
 import (
+	"bytes"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/url"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/vanpelt/catnip/internal/logger"
@@ -88,6 +91,13 @@ type CreateGitHubRepositoryResponse struct {
 	Message string `json:"message" example:"Repository created and origin updated successfully"`
 }
 
+// SetRepositoryMirrorsRequest replaces the mirrors of a repository
+// @Description Request to configure secondary remotes that pushed branches are mirrored to
+type SetRepositoryMirrorsRequest struct {
+	// Mirrors to keep in sync (an empty list removes all mirrors)
+	Mirrors []models.RepositoryMirror `json:"mirrors"`
+}
+
 // WorktreeOperationResponse represents the response for worktree operations
 // @Description Response for worktree operations like delete, sync, merge, preview
 type WorktreeOperationResponse struct {
@@ -97,6 +107,16 @@ type WorktreeOperationResponse struct {
 	ID string `json:"id" example:"abc123-def456-ghi789"`
 	// Strategy used for sync operations
 	Strategy string `json:"strategy,omitempty" example:"rebase"`
+	// Backup of the worktree's HEAD taken before the operation rewrote its history
+	Backup *models.OperationBackup `json:"backup,omitempty"`
+}
+
+// IgnoreSuggestionResponse is the worktree after applying an ignore suggestion
+// @Description Worktree after applying an ignore suggestion, with the backup taken when squashing
+type IgnoreSuggestionResponse struct {
+	*models.Worktree
+	// Backup of the worktree's HEAD taken before squashing its branch
+	Backup *models.OperationBackup `json:"backup,omitempty"`
 }
 
 // WorktreeDiffResponse represents the response containing diff information
@@ -137,28 +157,73 @@ func generateWorktreesETag(worktrees []*EnhancedWorktree) (string, error) {
 	return hex.EncodeToString(hash[:]), nil
 }
 
+// diskSpaceStatus returns 507 Insufficient Storage for operations refused by the disk guard
+// and fallback for every other error
+func diskSpaceStatus(err error, fallback int) int {
+	if errors.Is(err, services.ErrInsufficientDiskSpace) {
+		return 507
+	}
+	return fallback
+}
+
 // CheckoutRepository handles repository checkout requests
 // @Summary Checkout a GitHub repository
-// @Description Clones a GitHub repository as a bare repo and creates initial worktree
+// @Description Clones a GitHub repository as a bare repo and creates initial worktree. New clones are shallow and fetch their full history in the background unless configured otherwise; defaults come from CATNIP_CLONE_DEPTH, CATNIP_CLONE_SINGLE_BRANCH, CATNIP_UNSHALLOW, CATNIP_UNSHALLOW_DELAY_SECONDS and CATNIP_CLONE_MIRROR. Clone options are ignored when the repository is already cloned.
 // @Tags git
 // @Accept json
 // @Produce json
 // @Param org path string true "Organization name"
 // @Param repo path string true "Repository name"
-// @Param branch query string false "Branch name (optional)"
+// @Param branch query string false "Branch, tag or 40-character commit hash to start from (optional)"
+// @Param depth query int false "Commits of history to clone, 0 for the full history (default 1)"
+// @Param single_branch query bool false "Only clone the requested branch (default true)"
+// @Param unshallow query bool false "Fetch the full history in the background after a shallow clone (default true)"
+// @Param unshallow_delay query int false "Seconds to wait before unshallowing (default 5)"
+// @Param mirror query bool false "Clone every branch with its full history and keep them current in the background (default false)"
+// @Param source query string false "Where the request comes from: ui, tui, api (default) or auto"
+// @Param prompt query string false "Initial prompt of the session"
+// @Param issue query string false "Issue the session works on, as a number or URL"
 // @Success 200 {object} CheckoutResponse
+// @Failure 400 {object} map[string]string "Invalid source or clone options"
+// @Failure 507 {object} map[string]string "Not enough free space on the workspace volume"
 // @Router /v1/git/checkout/{org}/{repo} [post]
 func (h *GitHandler) CheckoutRepository(c *fiber.Ctx) error {
 	org := c.Params("org")
 	repo := c.Params("repo")
 	branch := c.Query("branch", "")
 
-	logger.Infof("📦 Checkout request: %s/%s (branch: %s)", org, repo, branch)
+	source, err := services.ParseCreationSource(c.Query("source"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	opts := services.DefaultCloneOptions()
+	opts.Depth = c.QueryInt("depth", opts.Depth)
+	opts.SingleBranch = c.QueryBool("single_branch", opts.SingleBranch)
+	opts.UnshallowInBackground = c.QueryBool("unshallow", opts.UnshallowInBackground)
+	if value := c.Query("unshallow_delay"); value != "" {
+		opts.UnshallowDelay = time.Duration(c.QueryInt("unshallow_delay")) * time.Second
+	}
+	opts.Mirror = c.QueryBool("mirror", opts.Mirror)
+	if opts.Depth < 0 || opts.UnshallowDelay < 0 {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "depth and unshallow_delay must not be negative",
+		})
+	}
+
+	logger.Infof("📦 Checkout request: %s/%s (branch: %s, depth: %d)", org, repo, branch, opts.Depth)
 
-	repository, worktree, err := h.gitService.CheckoutRepository(org, repo, branch)
+	repository, worktree, err := h.gitService.CheckoutRepositoryWithCreation(org, repo, branch, opts, &models.CreationContext{
+		Source: source,
+		Prompt: c.Query("prompt"),
+		Issue:  c.Query("issue"),
+		Actor:  GetActor(c),
+	})
 	if err != nil {
 		logger.Errorf("❌ Checkout failed: %v", err)
-		return c.Status(500).JSON(fiber.Map{
+		return c.Status(diskSpaceStatus(err, 500)).JSON(fiber.Map{
 			"error": err.Error(),
 		})
 	}
@@ -170,18 +235,135 @@ func (h *GitHandler) CheckoutRepository(c *fiber.Ctx) error {
 	})
 }
 
+// CheckoutBranchesRequest lists the branches to create worktrees for
+type CheckoutBranchesRequest struct {
+	// Branches, tags or commit hashes, one worktree each
+	Branches []string `json:"branches" example:"feature/auth,feature/auth-ui"`
+}
+
+// BranchCheckoutResult is the outcome of a batch checkout for one branch
+type BranchCheckoutResult struct {
+	Branch string `json:"branch"`
+	// Created worktree, unless creating it failed
+	Worktree *models.Worktree `json:"worktree,omitempty"`
+	// Why the worktree couldn't be created
+	Error string `json:"error,omitempty"`
+}
+
+// CheckoutBranchesResponse lists the outcome of a batch checkout per branch, in request order
+type CheckoutBranchesResponse struct {
+	Results []BranchCheckoutResult `json:"results"`
+}
+
+// CheckoutBranches creates worktrees for several branches of a repository at once
+// @Summary Checkout several branches of a GitHub repository
+// @Description Creates one worktree per branch, e.g. to review a stack of pull requests. The repository is cloned once if needed and the worktrees are created concurrently; a branch that fails doesn't stop the others.
+// @Tags git
+// @Accept json
+// @Produce json
+// @Param org path string true "Organization name"
+// @Param repo path string true "Repository name"
+// @Param source query string false "Where the request comes from: ui, tui, api (default) or auto"
+// @Param request body CheckoutBranchesRequest true "Branches to check out"
+// @Success 200 {object} CheckoutBranchesResponse
+// @Failure 400 {object} map[string]string "Invalid request"
+// @Failure 507 {object} map[string]string "Not enough free space on the workspace volume"
+// @Router /v1/git/checkout/{org}/{repo}/branches [post]
+func (h *GitHandler) CheckoutBranches(c *fiber.Ctx) error {
+	org := c.Params("org")
+	repo := c.Params("repo")
+
+	var req CheckoutBranchesRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	if len(req.Branches) == 0 {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "branches is required",
+		})
+	}
+	source, err := services.ParseCreationSource(c.Query("source"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	logger.Infof("📦 Batch checkout request: %s/%s (branches: %s)", org, repo, strings.Join(req.Branches, ", "))
+
+	results, err := h.gitService.CheckoutBranchesWithCreation(org, repo, req.Branches, &models.CreationContext{
+		Source: source,
+		Actor:  GetActor(c),
+	})
+	if err != nil {
+		logger.Errorf("❌ Batch checkout failed: %v", err)
+		return c.Status(diskSpaceStatus(err, 500)).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	response := CheckoutBranchesResponse{Results: make([]BranchCheckoutResult, 0, len(results))}
+	for _, result := range results {
+		entry := BranchCheckoutResult{Branch: result.Branch, Worktree: result.Worktree}
+		if result.Err != nil {
+			entry.Error = result.Err.Error()
+		}
+		response.Results = append(response.Results, entry)
+	}
+	return c.JSON(response)
+}
+
 // GetStatus returns the current Git status
 // @Summary Get Git status
-// @Description Returns the current repository and worktree status
+// @Description Returns the current repository and worktree status. With disk_usage=true each repository carries disk_usage_bytes from the last disk usage measurement, which is refreshed in the background when missing or expired rather than delaying the response.
 // @Tags git
 // @Produce json
+// @Param disk_usage query bool false "Embed each repository's disk usage"
 // @Success 200 {object} models.GitStatus
 // @Router /v1/git/status [get]
 func (h *GitHandler) GetStatus(c *fiber.Ctx) error {
 	status := h.gitService.GetStatus()
+	if c.QueryBool("disk_usage") {
+		repoBytes, _ := h.gitService.DiskUsageBytes()
+		for id, repo := range status.Repositories {
+			repo.DiskUsageBytes = repoBytes[id]
+		}
+	}
 	return c.JSON(status)
 }
 
+// GetDiskUsage returns how much space each repository and worktree takes on disk
+// @Summary Get disk usage
+// @Description Breaks down the space repositories and their worktrees take on the volume, largest first, with totals. Sizes are counted like du: allocated blocks, hard-linked files once, and the objects worktrees share counted with their repository. Measurements are cached and refreshed in the background every CATNIP_DISK_USAGE_REFRESH_MINUTES (default 10); pass refresh=true to measure now.
+// @Tags git
+// @Produce json
+// @Param refresh query bool false "Measure again instead of serving the cached measurement"
+// @Success 200 {object} models.DiskUsage
+// @Router /v1/git/disk-usage [get]
+func (h *GitHandler) GetDiskUsage(c *fiber.Ctx) error {
+	if c.QueryBool("refresh") {
+		return c.JSON(h.gitService.RefreshDiskUsage())
+	}
+	return c.JSON(h.gitService.GetDiskUsage())
+}
+
+// AcknowledgeStateReport dismisses the state report
+// @Summary Acknowledge state report
+// @Description Dismisses the report of state files that were migrated, quarantined or lost and repositories or worktrees found unavailable while loading the persisted state. Afterwards git status no longer includes state_report and new event streams no longer send system:state_report, until a later restart finds something new.
+// @Tags git
+// @Produce json
+// @Success 200 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /v1/git/status/state-report/acknowledge [post]
+func (h *GitHandler) AcknowledgeStateReport(c *fiber.Ctx) error {
+	if err := h.gitService.AcknowledgeStateReport(); err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"message": "State report acknowledged"})
+}
+
 // EnhancedWorktree represents a worktree with cache status metadata
 type EnhancedWorktree struct {
 	*models.Worktree
@@ -197,18 +379,71 @@ type WorktreeCacheStatus struct {
 
 // ListWorktrees returns all worktrees with cache-enhanced responses
 // @Summary List all worktrees
-// @Description Returns a list of all worktrees for the current repository with fast cache-enhanced responses. Supports conditional requests via If-None-Match header for efficient polling.
+// @Description Returns a list of all worktrees for the current repository with fast cache-enhanced responses. Statuses come from the cache. By default stale working tree fields (dirty, conflicts, stashes, branch, HEAD) are re-read with one porcelain status per worktree and ahead/behind counts are refreshed in the background; status picks another tier: none serves the cache as is, standard also counts commits against local refs, full fetches first. status_freshness tells which tier last populated each group of fields and when. Pass refresh=true to re-read every status from git first. Supports conditional requests via If-None-Match header for efficient polling.
+// @Description Worktrees are ordered most recently accessed first, then by name, unless sortBy says otherwise. With limit, X-Next-Cursor holds the cursor of the next page (absent on the last page) and X-Total-Count the number of worktrees in the whole list. Cursors keep their position when worktrees are added or removed between pages, offsets don't.
 // @Tags git
 // @Produce json
 // @Param If-None-Match header string false "ETag from previous request"
+// @Param actor query string false "Only return worktrees created by this actor"
+// @Param refresh query bool false "Re-read every worktree's git status before responding"
+// @Param status query string false "Status refresh tier, defaults to cheap" Enums(none, cheap, standard, full)
+// @Param sortBy query string false "Order of the list" Enums(lastAccessed, name, commitsAhead)
+// @Param limit query int false "Maximum number of worktrees to return"
+// @Param offset query int false "Number of worktrees to skip"
+// @Param cursor query string false "X-Next-Cursor of the previous page"
+// @Param disk_usage query bool false "Embed each worktree's disk usage from the last measurement"
 // @Success 200 {array} EnhancedWorktree
 // @Success 304 "Not Modified - content unchanged"
+// @Failure 400 {object} map[string]string "Invalid sort, page or status parameters"
 // @Router /v1/git/worktrees [get]
 func (h *GitHandler) ListWorktrees(c *fiber.Ctx) error {
-	worktrees := h.gitService.ListWorktrees()
-	enhancedWorktrees := make([]*EnhancedWorktree, 0, len(worktrees))
+	tier, err := models.ParseStatusTier(c.Query("status"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if c.QueryBool("refresh") {
+		h.gitService.RefreshWorktreeStatuses()
+	}
+
+	// Optional filter by creator; worktrees recorded before attribution count as "unknown"
+	actorFilter := c.Query("actor")
+	if actorFilter != "" {
+		actorFilter = services.NormalizeActor(actorFilter)
+	}
+	var worktrees []*models.Worktree
+	for _, worktree := range h.gitService.ListWorktreesAt(tier) {
+		if actorFilter == "" || services.NormalizeActor(worktree.CreatedBy) == actorFilter {
+			worktrees = append(worktrees, worktree)
+		}
+	}
+
+	page, err := services.PageWorktrees(worktrees, services.WorktreeListOptions{
+		SortBy: c.Query("sortBy"),
+		Limit:  c.QueryInt("limit"),
+		Offset: c.QueryInt("offset"),
+		Cursor: c.Query("cursor"),
+	})
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	c.Set("X-Total-Count", strconv.Itoa(page.Total))
+	if page.NextCursor != "" {
+		c.Set("X-Next-Cursor", page.NextCursor)
+	}
+
+	var worktreeBytes map[string]int64
+	if c.QueryBool("disk_usage") {
+		_, worktreeBytes = h.gitService.DiskUsageBytes()
+	}
+
+	enhancedWorktrees := make([]*EnhancedWorktree, 0, len(page.Worktrees))
+	for _, worktree := range page.Worktrees {
+		worktree.DiskUsageBytes = worktreeBytes[worktree.ID]
 
-	for _, worktree := range worktrees {
 		// Enhance worktrees with session information
 		if sessionInfo, exists := h.sessionService.GetActiveSession(worktree.Path); exists {
 			// Convert services.TitleEntry to models.TitleEntry
@@ -282,6 +517,17 @@ func (h *GitHandler) ListWorktrees(c *fiber.Ctx) error {
 	return c.JSON(enhancedWorktrees)
 }
 
+// GetWorktreesSummary returns compact worktree counts for frequent polling
+// @Summary Get worktrees summary
+// @Description Returns worktree counts (dirty, conflicted, with pull requests, failing checks), the last SSE event sequence number and a hash of the full list state. Served from memory without running git (the none status tier), so it is safe to poll every few seconds. Fetch /v1/git/worktrees for detailed data when the hash changes.
+// @Tags git
+// @Produce json
+// @Success 200 {object} services.WorktreesSummary
+// @Router /v1/git/worktrees/summary [get]
+func (h *GitHandler) GetWorktreesSummary(c *fiber.Ctx) error {
+	return c.JSON(h.gitService.GetWorktreesSummary())
+}
+
 // UpdateWorktree updates specific fields of a worktree
 // @Summary Update worktree fields
 // @Description Updates specific fields of a worktree (for testing purposes)
@@ -375,15 +621,30 @@ func (h *GitHandler) GetRepositoryBranches(c *fiber.Ctx) error {
 
 // DeleteWorktree removes a worktree
 // @Summary Delete worktree
-// @Description Removes a worktree from the repository
+// @Description Removes a worktree from the repository. With archive=true its tracked and untracked files are first saved to a tar.gz in the archive directory (CATNIP_ARCHIVE_DIR); the worktree is kept if archiving fails.
 // @Tags git
 // @Produce json
 // @Param id path string true "Worktree ID"
+// @Param archive query bool false "Archive the worktree's files before deleting it"
 // @Success 200 {object} WorktreeOperationResponse
 // @Router /v1/git/worktrees/{id} [delete]
 func (h *GitHandler) DeleteWorktree(c *fiber.Ctx) error {
 	worktreeID := c.Params("id")
 
+	response := fiber.Map{
+		"message": "Worktree deleted successfully",
+		"id":      worktreeID,
+	}
+	if c.QueryBool("archive") {
+		archive, err := h.gitService.ArchiveWorktree(worktreeID)
+		if err != nil {
+			return c.Status(diskSpaceStatus(err, 500)).JSON(fiber.Map{
+				"error": "Worktree was not deleted, archiving failed: " + err.Error(),
+			})
+		}
+		response["archive"] = archive
+	}
+
 	_, err := h.gitService.DeleteWorktree(worktreeID)
 	if err != nil {
 		return c.Status(400).JSON(fiber.Map{
@@ -391,15 +652,57 @@ func (h *GitHandler) DeleteWorktree(c *fiber.Ctx) error {
 		})
 	}
 
-	return c.JSON(fiber.Map{
-		"message": "Worktree deleted successfully",
-		"id":      worktreeID,
-	})
+	return c.JSON(response)
+}
+
+// ListWorktreeArchives lists the archives of deleted worktrees
+// @Summary List worktree archives
+// @Description Lists the tarballs taken of worktrees deleted with archive=true, newest first
+// @Tags git
+// @Produce json
+// @Success 200 {array} services.WorktreeArchive
+// @Router /v1/git/archives [get]
+func (h *GitHandler) ListWorktreeArchives(c *fiber.Ctx) error {
+	archives, err := h.gitService.ListWorktreeArchives()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(archives)
+}
+
+// RestoreWorktreeArchive restores an archive into a fresh worktree
+// @Summary Restore worktree archive
+// @Description Creates a new worktree on a fresh catnip branch from the archived worktree's source branch and unpacks the archived files on top of it as uncommitted changes
+// @Tags git
+// @Produce json
+// @Param id path string true "Archive ID"
+// @Success 200 {object} models.Worktree
+// @Failure 404 {object} map[string]string "Archive not found"
+// @Router /v1/git/archives/{id}/restore [post]
+func (h *GitHandler) RestoreWorktreeArchive(c *fiber.Ctx) error {
+	worktree, err := h.gitService.RestoreWorktreeArchive(c.Params("id"))
+	if err != nil {
+		status := 500
+		switch {
+		case strings.HasSuffix(err.Error(), "not found"):
+			status = 404
+		case strings.HasPrefix(err.Error(), "invalid archive ID"):
+			status = 400
+		}
+		return c.Status(status).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(worktree)
 }
 
 // SyncWorktree syncs a worktree with its source branch
 // @Summary Sync worktree with source branch
-// @Description Syncs a worktree with its source branch using merge or rebase strategy
+// @Description Syncs a worktree with its source branch using merge, rebase or ff-only strategy. When its remote source branch was force-pushed (source_branch_rewritten), the sync is refused with 409 source_rewritten unless acknowledge_rewrite is set, which rebases only the worktree's own commits onto the new tip whatever the strategy. With base "pull_request" it syncs with the branch its pull request targets on GitHub instead, which differs from the source branch when the PR was retargeted (pull_request_base_branch). ff-only returns 409 non_fast_forward with ahead/behind counts when the worktree has local commits. Rebases back up the worktree's HEAD first and return the backup, also with merge conflicts. With auto_stash, uncommitted changes are stashed for the sync and restored afterwards; they stay stashed when the sync conflicts, and conflicts restoring them are reported as a merge_conflict with operation "unstash".
 // @Tags git
 // @Accept json
 // @Produce json
@@ -411,7 +714,10 @@ func (h *GitHandler) SyncWorktree(c *fiber.Ctx) error {
 	worktreeID := c.Params("id")
 
 	var syncRequest struct {
-		Strategy string `json:"strategy"`
+		Strategy           string `json:"strategy"`
+		Base               string `json:"base"`
+		AutoStash          bool   `json:"auto_stash"`
+		AcknowledgeRewrite bool   `json:"acknowledge_rewrite"`
 	}
 
 	if err := c.BodyParser(&syncRequest); err != nil {
@@ -425,17 +731,45 @@ func (h *GitHandler) SyncWorktree(c *fiber.Ctx) error {
 		syncRequest.Strategy = "rebase"
 	}
 
-	if err := h.gitService.SyncWorktree(worktreeID, syncRequest.Strategy); err != nil {
+	backup, err := h.gitService.SyncWorktree(worktreeID, services.SyncOptions{
+		Strategy:           syncRequest.Strategy,
+		Base:               syncRequest.Base,
+		AutoStash:          syncRequest.AutoStash,
+		AcknowledgeRewrite: syncRequest.AcknowledgeRewrite,
+	})
+	if err != nil {
+		var rewrittenErr *models.SourceRewrittenError
+		if errors.As(err, &rewrittenErr) {
+			return c.Status(409).JSON(fiber.Map{
+				"error":         "source_rewritten",
+				"message":       rewrittenErr.Error(),
+				"worktree_name": rewrittenErr.WorktreeName,
+				"rewrite":       rewrittenErr.Rewrite,
+			})
+		}
 		// Check if this is a merge conflict error
 		var mergeConflictErr *models.MergeConflictError
 		if errors.As(err, &mergeConflictErr) {
 			return c.Status(409).JSON(fiber.Map{
-				"error":          "merge_conflict",
-				"message":        mergeConflictErr.Message,
-				"operation":      mergeConflictErr.Operation,
-				"worktree_name":  mergeConflictErr.WorktreeName,
-				"worktree_path":  mergeConflictErr.WorktreePath,
-				"conflict_files": mergeConflictErr.ConflictFiles,
+				"error":             "merge_conflict",
+				"message":           mergeConflictErr.Message,
+				"operation":         mergeConflictErr.Operation,
+				"worktree_name":     mergeConflictErr.WorktreeName,
+				"worktree_path":     mergeConflictErr.WorktreePath,
+				"conflict_files":    mergeConflictErr.ConflictFiles,
+				"lockfile_commands": mergeConflictErr.LockfileCommands,
+				"backup":            backup,
+			})
+		}
+		var nonFastForwardErr *models.NonFastForwardError
+		if errors.As(err, &nonFastForwardErr) {
+			return c.Status(409).JSON(fiber.Map{
+				"error":         "non_fast_forward",
+				"message":       nonFastForwardErr.Error(),
+				"worktree_name": nonFastForwardErr.WorktreeName,
+				"source_ref":    nonFastForwardErr.SourceRef,
+				"ahead":         nonFastForwardErr.Ahead,
+				"behind":        nonFastForwardErr.Behind,
 			})
 		}
 		return c.Status(400).JSON(fiber.Map{
@@ -443,10 +777,11 @@ func (h *GitHandler) SyncWorktree(c *fiber.Ctx) error {
 		})
 	}
 
-	return c.JSON(fiber.Map{
-		"message":  "Worktree synced successfully",
-		"id":       worktreeID,
-		"strategy": syncRequest.Strategy,
+	return c.JSON(WorktreeOperationResponse{
+		Message:  "Worktree synced successfully",
+		ID:       worktreeID,
+		Strategy: syncRequest.Strategy,
+		Backup:   backup,
 	})
 }
 
@@ -457,30 +792,42 @@ func (h *GitHandler) SyncWorktree(c *fiber.Ctx) error {
 // @Accept json
 // @Produce json
 // @Param id path string true "Worktree ID"
-// @Param body body map[string]string false "Merge options"
+// @Param body body map[string]string false "Merge options: squash, skip_validation and confirm"
 // @Success 200 {object} WorktreeOperationResponse
+// @Failure 422 {object} map[string]interface{} "Validation failed"
 // @Router /v1/git/worktrees/{id}/merge [post]
 func (h *GitHandler) MergeWorktreeToMain(c *fiber.Ctx) error {
 	worktreeID := c.Params("id")
 
 	var mergeRequest struct {
 		Squash bool `json:"squash"`
+		ValidationOverride
 	}
 
 	// Parse body if present, but don't require it for backwards compatibility
 	_ = c.BodyParser(&mergeRequest)
+	skipValidation, err := mergeRequest.skip()
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
 
-	if err := h.gitService.MergeWorktreeToMain(worktreeID, mergeRequest.Squash); err != nil {
+	if err := h.gitService.MergeWorktreeToMain(worktreeID, services.MergeOptions{Squash: mergeRequest.Squash, SkipValidation: skipValidation}); err != nil {
+		if response := validationFailure(err); response != nil {
+			return c.Status(422).JSON(response)
+		}
 		// Check if this is a merge conflict error
 		var mergeConflictErr *models.MergeConflictError
 		if errors.As(err, &mergeConflictErr) {
 			return c.Status(409).JSON(fiber.Map{
-				"error":          "merge_conflict",
-				"message":        mergeConflictErr.Message,
-				"operation":      mergeConflictErr.Operation,
-				"worktree_name":  mergeConflictErr.WorktreeName,
-				"worktree_path":  mergeConflictErr.WorktreePath,
-				"conflict_files": mergeConflictErr.ConflictFiles,
+				"error":             "merge_conflict",
+				"message":           mergeConflictErr.Message,
+				"operation":         mergeConflictErr.Operation,
+				"worktree_name":     mergeConflictErr.WorktreeName,
+				"worktree_path":     mergeConflictErr.WorktreePath,
+				"conflict_files":    mergeConflictErr.ConflictFiles,
+				"lockfile_commands": mergeConflictErr.LockfileCommands,
 			})
 		}
 		return c.Status(400).JSON(fiber.Map{
@@ -510,15 +857,157 @@ func (h *GitHandler) MergeWorktreeToMain(c *fiber.Ctx) error {
 	return c.JSON(response)
 }
 
+// PrepareMerge prepares a reviewed merge of a worktree to main
+// @Summary Prepare merge to main
+// @Description Returns the default commit message, diffstat and a token for merging a local repo worktree, without changing anything. Complete the merge with the token within 5 minutes.
+// @Tags git
+// @Accept json
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Param body body map[string]string false "Merge mode: squash (default) or merge"
+// @Success 200 {object} models.MergePreview
+// @Failure 400 {object} map[string]string "Invalid request"
+// @Router /v1/git/worktrees/{id}/merge/prepare [post]
+func (h *GitHandler) PrepareMerge(c *fiber.Ctx) error {
+	worktreeID := c.Params("id")
+
+	var prepareRequest struct {
+		Mode string `json:"mode"`
+	}
+	_ = c.BodyParser(&prepareRequest)
+	if prepareRequest.Mode == "" {
+		prepareRequest.Mode = "squash"
+	}
+
+	preview, err := h.gitService.PrepareMerge(worktreeID, prepareRequest.Mode)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(preview)
+}
+
+// CompleteMerge performs a prepared merge with the reviewed commit message
+// @Summary Complete prepared merge
+// @Description Merges the worktree of a prepared merge using the edited commit message (the default message when empty). Fails when the token expired or the worktree's HEAD moved since preparation.
+// @Tags git
+// @Accept json
+// @Produce json
+// @Param token path string true "Merge token"
+// @Param auto_cleanup query bool false "Delete the worktree after a successful merge"
+// @Param body body map[string]string false "Commit message, skip_validation and confirm"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string "Invalid or expired token"
+// @Failure 409 {object} map[string]interface{} "Merge conflict"
+// @Failure 422 {object} map[string]interface{} "Validation failed"
+// @Router /v1/git/merges/{token}/complete [post]
+func (h *GitHandler) CompleteMerge(c *fiber.Ctx) error {
+	var completeRequest struct {
+		Message string `json:"message"`
+		ValidationOverride
+	}
+	_ = c.BodyParser(&completeRequest)
+	skipValidation, err := completeRequest.skip()
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	preview, err := h.gitService.CompleteMerge(c.Params("token"), completeRequest.Message, skipValidation)
+	if err != nil {
+		if response := validationFailure(err); response != nil {
+			return c.Status(422).JSON(response)
+		}
+		var mergeConflictErr *models.MergeConflictError
+		if errors.As(err, &mergeConflictErr) {
+			return c.Status(409).JSON(fiber.Map{
+				"error":             "merge_conflict",
+				"message":           mergeConflictErr.Message,
+				"operation":         mergeConflictErr.Operation,
+				"worktree_name":     mergeConflictErr.WorktreeName,
+				"worktree_path":     mergeConflictErr.WorktreePath,
+				"conflict_files":    mergeConflictErr.ConflictFiles,
+				"lockfile_commands": mergeConflictErr.LockfileCommands,
+			})
+		}
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	response := fiber.Map{
+		"message": "Worktree merged to main successfully",
+		"id":      preview.WorktreeID,
+		"preview": preview,
+	}
+
+	// Clean up the worktree after the merge if requested, like the one-shot merge
+	if c.QueryBool("auto_cleanup", false) {
+		if _, cleanupErr := h.gitService.DeleteWorktree(preview.WorktreeID); cleanupErr != nil {
+			response["cleanup_warning"] = "Merge succeeded but worktree cleanup failed: " + cleanupErr.Error()
+		} else {
+			response["cleanup"] = "Worktree automatically deleted after successful merge"
+		}
+	}
+
+	return c.JSON(response)
+}
+
+// ReconcileWorktrees compares the worktrees in state with the ones git has registered
+// @Summary Reconcile worktrees with git
+// @Description Compares the worktrees in state with git worktree list of every repository on disk, as the server does on boot. Worktrees git no longer knows, e.g. after git worktree remove ran in a shell, are flagged missing (and no longer recreated on boot) or removed from state with missing=remove; CATNIP_RECONCILE_MISSING sets the default. Git worktrees on a branch that catnip doesn't know are imported once they are a minute old if they are in the workspace directory; the others are flagged. Missing worktrees git knows again are recovered.
+// @Tags git
+// @Produce json
+// @Param missing query string false "What to do with worktrees git no longer knows: flag or remove"
+// @Success 200 {object} models.WorktreeReconciliation
+// @Failure 400 {object} map[string]string "Invalid missing mode"
+// @Router /v1/git/worktrees/reconcile [post]
+func (h *GitHandler) ReconcileWorktrees(c *fiber.Ctx) error {
+	result, err := h.gitService.ReconcileWorktrees(c.Query("missing"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.JSON(result)
+}
+
+// CleanupMergedWorktreesRequest optionally limits cleanup to the worktrees of a previous dry run
+type CleanupMergedWorktreesRequest struct {
+	// Worktrees to remove; each must still qualify as merged
+	WorktreeIDs []string `json:"worktree_ids,omitempty"`
+}
+
 // CleanupMergedWorktrees removes worktrees that have been fully merged
 // @Summary Cleanup merged worktrees
-// @Description Removes worktrees that have been fully merged into their source branch
+// @Description Removes worktrees that have been fully merged into their source branch. With dry_run set, lists the worktrees that would be removed and why, without removing anything. Passing worktree_ids from a dry run removes only those worktrees.
 // @Tags git
+// @Accept json
 // @Produce json
+// @Param dry_run query bool false "Only list the worktrees that would be removed"
+// @Param request body CleanupMergedWorktreesRequest false "Worktrees to remove"
 // @Success 200 {object} map[string]interface{}
 // @Router /v1/git/worktrees/cleanup [post]
 func (h *GitHandler) CleanupMergedWorktrees(c *fiber.Ctx) error {
-	cleanedCount, cleanedNames, err := h.gitService.CleanupMergedWorktrees()
+	if c.QueryBool("dry_run", false) {
+		worktrees := h.gitService.PreviewMergedWorktreeCleanup()
+		if worktrees == nil {
+			worktrees = []services.MergedWorktree{}
+		}
+		return c.JSON(fiber.Map{
+			"dry_run":   true,
+			"worktrees": worktrees,
+		})
+	}
+
+	var req CleanupMergedWorktreesRequest
+	// Parse body if present, but don't require it for backwards compatibility
+	_ = c.BodyParser(&req)
+
+	cleanedCount, cleanedNames, err := h.gitService.CleanupMergedWorktrees(req.WorktreeIDs)
 	if err != nil {
 		return c.Status(400).JSON(fiber.Map{
 			"error":         err.Error(),
@@ -536,17 +1025,23 @@ func (h *GitHandler) CleanupMergedWorktrees(c *fiber.Ctx) error {
 
 // CreateWorktreePreview creates a preview branch for viewing changes outside container
 // @Summary Create worktree preview
-// @Description Creates a preview branch in the main repo for viewing changes outside container
+// @Description Creates a preview branch in the main repo for viewing changes outside container. Replacing the preview branch of a protected repository, such as the catnip development repository in dev mode, force pushes and needs confirm=true.
 // @Tags git
 // @Produce json
 // @Param id path string true "Worktree ID"
+// @Param confirm query bool false "Confirm force pushing to a protected repository"
 // @Success 200 {object} WorktreeOperationResponse
+// @Failure 409 {object} map[string]string "Repository is protected"
 // @Router /v1/git/worktrees/{id}/preview [post]
 func (h *GitHandler) CreateWorktreePreview(c *fiber.Ctx) error {
 	worktreeID := c.Params("id")
 
-	if err := h.gitService.CreateWorktreePreview(worktreeID); err != nil {
-		return c.Status(400).JSON(fiber.Map{
+	if err := h.gitService.CreateWorktreePreview(worktreeID, c.QueryBool("confirm")); err != nil {
+		status := 400
+		if errors.Is(err, services.ErrProtectedRepository) {
+			status = 409
+		}
+		return c.Status(status).JSON(fiber.Map{
 			"error": err.Error(),
 		})
 	}
@@ -628,15 +1123,33 @@ func (h *GitHandler) CheckMergeConflicts(c *fiber.Ctx) error {
 
 // GetWorktreeDiff returns the diff for a worktree against its source branch
 // @Summary Get worktree diff
-// @Description Returns the diff for a worktree against its source branch, including all staged/unstaged changes
+// @Description Returns the diff for a worktree against its source branch, including all staged/unstaged changes. With ref, the worktree is diffed against that branch, tag or commit instead (e.g. another worktree's branch or an earlier checkpoint), fetching it from origin for remote repositories, and the response records it as base_ref. Generated files (lockfiles, linguist-generated attributes, catnip.diff.generated paths) are flagged, collapsed and left out of authored_stats.
 // @Tags git
 // @Produce json
 // @Param id path string true "Worktree ID"
+// @Param ref query string false "Branch, tag or commit to diff against instead of the source branch"
 // @Success 200 {object} WorktreeDiffResponse
+// @Failure 400 {object} map[string]string "Invalid ref or diff failed"
 // @Router /v1/git/worktrees/{id}/diff [get]
 func (h *GitHandler) GetWorktreeDiff(c *fiber.Ctx) error {
 	worktreeID := c.Params("id")
 
+	if ref := c.Query("ref"); ref != "" {
+		diff, err := h.gitService.GetWorktreeDiffAgainst(worktreeID, ref)
+		if err != nil {
+			status := 500
+			if errors.Is(err, services.ErrInvalidDiffRef) {
+				status = 400
+			} else if strings.HasPrefix(err.Error(), "worktree not found") {
+				status = 404
+			}
+			return c.Status(status).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.JSON(diff)
+	}
+
 	diff, err := h.gitService.GetWorktreeDiff(worktreeID)
 	if err != nil {
 		return c.Status(400).JSON(fiber.Map{
@@ -647,382 +1160,3235 @@ func (h *GitHandler) GetWorktreeDiff(c *fiber.Ctx) error {
 	return c.JSON(diff)
 }
 
-// CreatePullRequestRequest represents a request to create a pull request
-type CreatePullRequestRequest struct {
-	Title     string `json:"title"`
-	Body      string `json:"body"`
-	ForcePush bool   `json:"force_push,omitempty"`
-}
-
-// CreatePullRequest creates a pull request for a worktree
-// @Summary Create pull request
-// @Description Creates a pull request for a worktree branch
+// GetWorktreeDiffSummary returns a page of the files changed in a worktree
+// @Summary Get worktree diff summary
+// @Description Lists the files changed in a worktree against its source branch (committed, unstaged and untracked) with their change type, rename source and line counts but without contents, so large changes can be paged. Binary files are flagged with binary and count no lines. Fetch the diff of a file with GET /v1/git/worktrees/{id}/diff/file. Lockfiles, files marked linguist-generated in .gitattributes and paths listed in the catnip.diff.generated git config are flagged generated, collapsed unless expand_generated=true and left out of authored_stats.
+// @Tags git
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Param limit query int false "Files per page (default 100)"
+// @Param offset query int false "Files to skip"
+// @Param expand_generated query bool false "Keep generated files expanded"
+// @Success 200 {object} git.WorktreeDiffSummary
+// @Failure 400 {object} map[string]string "Invalid page or diff failed"
+// @Failure 404 {object} map[string]string "Worktree not found"
+// @Router /v1/git/worktrees/{id}/diff/summary [get]
+func (h *GitHandler) GetWorktreeDiffSummary(c *fiber.Ctx) error {
+	summary, err := h.gitService.GetWorktreeDiffSummary(c.Params("id"), c.QueryInt("limit"), c.QueryInt("offset"), c.QueryBool("expand_generated"))
+	if err != nil {
+		status := 400
+		if strings.HasPrefix(err.Error(), "worktree not found") {
+			status = 404
+		}
+		return c.Status(status).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.JSON(summary)
+}
+
+// GetWorktreeCommits returns a page of the commits a worktree made since branching
+// @Summary Get worktree commits
+// @Description Lists the commits on a worktree's branch since it forked from its source branch, newest first, flagging checkpoint commits, naming the session title that was active when each was created and verifying its GPG or SSH signature (good, bad, unknown or unsigned, with the signer) against the signing keyring. With stats=true each commit lists the lines it changed per file, flagging generated files, and sums them in stats and, without generated files, authored_stats.
+// @Tags git
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Param limit query int false "Commits per page (default 50)"
+// @Param offset query int false "Commits to skip"
+// @Param stats query bool false "Include file change stats"
+// @Success 200 {object} models.WorktreeCommits
+// @Failure 400 {object} map[string]string "Invalid page or log failed"
+// @Failure 404 {object} map[string]string "Worktree not found"
+// @Router /v1/git/worktrees/{id}/commits [get]
+func (h *GitHandler) GetWorktreeCommits(c *fiber.Ctx) error {
+	worktreeID := c.Params("id")
+
+	commits, err := h.gitService.GetWorktreeCommits(worktreeID, c.QueryInt("limit"), c.QueryInt("offset"), c.QueryBool("stats"))
+	if err != nil {
+		status := 400
+		if strings.Contains(err.Error(), "not found") {
+			status = 404
+		}
+		return c.Status(status).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if h.claudeMonitor != nil {
+		if worktree, exists := h.gitService.GetWorktree(worktreeID); exists {
+			h.claudeMonitor.AnnotateCommitSessions(worktree.Path, commits.Commits)
+		}
+	}
+	return c.JSON(commits)
+}
+
+// GetWorktreeFileDiff returns the diff of one file changed in a worktree
+// @Summary Get worktree file diff
+// @Description Returns one file of the worktree diff with its old and new contents and unified diff, computed against the same fork commit as the diff summary. Binary files are returned without contents or diff.
+// @Tags git
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Param path query string true "File path, relative to the worktree"
+// @Success 200 {object} git.FileDiff
+// @Failure 400 {object} map[string]string "Missing path or diff failed"
+// @Failure 404 {object} map[string]string "Worktree or file not found"
+// @Router /v1/git/worktrees/{id}/diff/file [get]
+func (h *GitHandler) GetWorktreeFileDiff(c *fiber.Ctx) error {
+	path := c.Query("path")
+	if path == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "path is required",
+		})
+	}
+
+	file, err := h.gitService.GetWorktreeFileDiff(c.Params("id"), path)
+	if err != nil {
+		status := 400
+		if strings.HasPrefix(err.Error(), "worktree not found") || strings.HasPrefix(err.Error(), "file ") {
+			status = 404
+		}
+		return c.Status(status).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.JSON(file)
+}
+
+// RecreateWorktreeRequest represents a request to recreate a worktree checkout
+type RecreateWorktreeRequest struct {
+	// Carry uncommitted and untracked changes over to the fresh checkout
+	KeepUncommitted bool `json:"keep_uncommitted"`
+}
+
+// RecreateWorktree replaces a worktree's checkout with a fresh one
+// @Summary Recreate worktree from scratch
+// @Description Replaces a worktree's checkout with a fresh one for the same branch, keeping its metadata (branch, PR link, titles, todos). The old checkout is moved to the workspace trash and processes running in it are terminated. Calling this again after an interruption resumes the recreation.
+// @Tags git
+// @Accept json
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Param request body RecreateWorktreeRequest false "Recreate options"
+// @Success 200 {object} services.RecreateWorktreeResult
+// @Router /v1/git/worktrees/{id}/recreate [post]
+func (h *GitHandler) RecreateWorktree(c *fiber.Ctx) error {
+	var req RecreateWorktreeRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+	}
+
+	result, err := h.gitService.RecreateWorktree(c.Params("id"), req.KeepUncommitted)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(result)
+}
+
+// RenameWorktreeRequest represents a request to rename a worktree
+type RenameWorktreeRequest struct {
+	// New name of the worktree directory, e.g. "login-page"
+	Name string `json:"name"`
+}
+
+// RenameWorktree renames a worktree's directory and name
+// @Summary Rename worktree
+// @Description Moves a worktree to a new directory name with git worktree move and updates its name, path and catnip ref. The worktree ID stays the same. Worktrees with a running terminal session can't be renamed.
+// @Tags git
+// @Accept json
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Param request body RenameWorktreeRequest true "New name"
+// @Success 200 {object} models.Worktree
+// @Failure 400 {object} map[string]string "Invalid name"
+// @Failure 404 {object} map[string]string "Worktree not found"
+// @Failure 409 {object} map[string]string "Name taken or worktree in use"
+// @Router /v1/git/worktrees/{id}/rename [post]
+func (h *GitHandler) RenameWorktree(c *fiber.Ctx) error {
+	var req RenameWorktreeRequest
+	if err := c.BodyParser(&req); err != nil || req.Name == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid request body, name is required",
+		})
+	}
+
+	worktree, err := h.gitService.RenameWorktree(c.Params("id"), req.Name)
+	if err != nil {
+		status := 500
+		switch {
+		case strings.HasSuffix(err.Error(), "not found"):
+			status = 404
+		case strings.HasPrefix(err.Error(), "invalid worktree name"):
+			status = 400
+		case strings.HasPrefix(err.Error(), "cannot rename"):
+			status = 409
+		}
+		return c.Status(status).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(worktree)
+}
+
+// SetSourceBranchRequest represents a request to change a worktree's source branch
+type SetSourceBranchRequest struct {
+	// Branch the worktree is compared against, synced with and merged into, e.g. "release"
+	SourceBranch string `json:"source_branch"`
+}
+
+// SetWorktreeSourceBranch changes the branch a worktree is based on
+// @Summary Change worktree source branch
+// @Description Changes the branch a worktree is compared against, synced with and merged into, e.g. after its work moved to a release branch. Branches of remote repositories are fetched from origin first. Ahead/behind counts, diffs and sync conflict checks use the new branch right away. Rejected while a merge or rebase is in progress in the worktree.
+// @Tags git
+// @Accept json
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Param request body SetSourceBranchRequest true "New source branch"
+// @Success 200 {object} models.Worktree
+// @Failure 400 {object} map[string]string "Unknown or invalid branch"
+// @Failure 404 {object} map[string]string "Worktree not found"
+// @Failure 409 {object} map[string]string "Merge or rebase in progress"
+// @Router /v1/git/worktrees/{id}/source-branch [put]
+func (h *GitHandler) SetWorktreeSourceBranch(c *fiber.Ctx) error {
+	var req SetSourceBranchRequest
+	if err := c.BodyParser(&req); err != nil || req.SourceBranch == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid request body, source_branch is required",
+		})
+	}
+
+	worktree, err := h.gitService.SetWorktreeSourceBranch(c.Params("id"), req.SourceBranch)
+	if err != nil {
+		status := 500
+		switch {
+		case strings.HasSuffix(err.Error(), "not found"):
+			status = 404
+		case strings.HasPrefix(err.Error(), "invalid source branch"):
+			status = 400
+		case strings.HasPrefix(err.Error(), "cannot change source branch"):
+			status = 409
+		}
+		return c.Status(status).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(worktree)
+}
+
+// ExportWorktreeRequest represents a request to export a worktree's content
+type ExportWorktreeRequest struct {
+	// Destination directory, or tarball when it ends in .tar; relative paths are taken relative to the export root
+	Dest string `json:"dest"`
+	// Include untracked files that aren't ignored
+	IncludeUntracked bool `json:"include_untracked"`
+}
+
+// ExportWorktree materializes a worktree's content as a plain directory or tarball
+// @Summary Export worktree as plain files
+// @Description Writes the current content of a worktree, including uncommitted changes, to a directory or tarball without any .git metadata, preserving file modes and symlinks. Repeated exports to the same directory only write changed files and remove deleted ones. Destinations must be inside the export root (CATNIP_EXPORT_ROOT).
+// @Tags git
+// @Accept json
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Param request body ExportWorktreeRequest true "Export options"
+// @Success 200 {object} services.WorktreeExportResult
+// @Failure 400 {object} map[string]string "Invalid request"
+// @Failure 500 {object} map[string]string "Export failed"
+// @Failure 507 {object} map[string]string "Snapshots paused while the workspace volume is nearly full"
+// @Router /v1/git/worktrees/{id}/export [post]
+func (h *GitHandler) ExportWorktree(c *fiber.Ctx) error {
+	var req ExportWorktreeRequest
+	if err := c.BodyParser(&req); err != nil || req.Dest == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid request body: dest is required",
+		})
+	}
+
+	result, err := h.gitService.ExportWorktreeTree(c.Params("id"), req.Dest, req.IncludeUntracked)
+	if err != nil {
+		return c.Status(diskSpaceStatus(err, 500)).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(result)
+}
+
+// GetWorktreeBundle serves a worktree's branch as a git bundle
+// @Summary Download worktree bundle
+// @Description Returns a git bundle of the worktree's branch, so CI that can't reach GitHub can fetch it with git fetch or git clone. Bundles are generated on demand and cached by tip. With base, only the commits after that commit are bundled; bases that aren't ancestors of the tip get the full history. Bundles of shallow repositories list the shallow boundary as prerequisites. Always requires the API token; bundles are size capped (CATNIP_MAX_BUNDLE_MB) and each caller may fetch a limited number per minute. Fetches are recorded in the repository activity log.
+// @Tags git
+// @Produce application/x-git-bundle
+// @Param id path string true "Worktree ID"
+// @Param base query string false "Commit the receiver already has"
+// @Success 200 {file} file "Git bundle"
+// @Failure 400 {object} map[string]string "Invalid request"
+// @Failure 404 {object} map[string]string "Worktree not found"
+// @Failure 413 {object} map[string]string "Bundle over the size limit"
+// @Failure 429 {object} map[string]string "Too many bundle requests"
+// @Failure 507 {object} map[string]string "Not enough free space on the workspace volume"
+// @Router /v1/git/worktrees/{id}/bundle [get]
+func (h *GitHandler) GetWorktreeBundle(c *fiber.Ctx) error {
+	bundle, err := h.gitService.GetWorktreeBundle(c.Params("id"), c.Query("base"), GetActor(c))
+	if err != nil {
+		status := 500
+		switch {
+		case errors.Is(err, services.ErrBundleRateLimited):
+			status = 429
+		case strings.Contains(err.Error(), "not found"):
+			status = 404
+		case strings.Contains(err.Error(), "over the"):
+			status = 413
+		case strings.HasPrefix(err.Error(), "invalid base"), strings.Contains(err.Error(), "has no commits"):
+			status = 400
+		case errors.Is(err, services.ErrInsufficientDiskSpace):
+			status = 507
+		}
+		return c.Status(status).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	c.Set(fiber.HeaderContentType, "application/x-git-bundle")
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", bundle.Tip[:12]+".bundle"))
+	c.Set("X-Catnip-Bundle-Ref", bundle.Ref)
+	c.Set("X-Catnip-Bundle-Tip", bundle.Tip)
+	if len(bundle.Prerequisites) > 0 {
+		c.Set("X-Catnip-Bundle-Prerequisites", strings.Join(bundle.Prerequisites, " "))
+	}
+	return c.SendFile(bundle.Path, false)
+}
+
+// contextPackError responds to a failed context pack generation
+func contextPackError(c *fiber.Ctx, err error) error {
+	status := 500
+	switch {
+	case strings.Contains(err.Error(), "not found"):
+		status = 404
+	case strings.HasPrefix(err.Error(), "invalid token budget"), strings.Contains(err.Error(), "symlink"):
+		status = 400
+	}
+	return c.Status(status).JSON(fiber.Map{
+		"error": err.Error(),
+	})
+}
+
+// GetWorktreeContextPack generates a context pack of a worktree's session
+// @Summary Download worktree context pack
+// @Description Returns everything another agent needs to pick up a worktree's session: a summary of the repository layout, the diff against the source branch, the todo list, session titles and latest prompt, the output of the last validation when it failed and the unresolved review threads of its pull request. Each section is capped to a share of max_tokens (default CATNIP_CONTEXT_PACK_MAX_TOKENS or 32000, tokens estimated as 4 bytes) with truncation markers; binary and generated files are listed without their diff. The diff comes from the diff cache and the rest from state, only review threads are fetched from GitHub. format=markdown downloads the rendered markdown instead of JSON.
+// @Tags git
+// @Produce json
+// @Produce text/markdown
+// @Param id path string true "Worktree ID"
+// @Param format query string false "json (default) or markdown"
+// @Param max_tokens query int false "Token budget"
+// @Success 200 {object} models.WorktreeContextPack
+// @Failure 400 {object} map[string]string "Invalid request"
+// @Failure 404 {object} map[string]string "Worktree not found"
+// @Router /v1/git/worktrees/{id}/context-pack [get]
+func (h *GitHandler) GetWorktreeContextPack(c *fiber.Ctx) error {
+	format := c.Query("format", "json")
+	if format != "json" && format != "markdown" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "format must be json or markdown",
+		})
+	}
+	pack, err := h.gitService.GenerateContextPack(c.Params("id"), services.ContextPackOptions{
+		MaxTokens: c.QueryInt("max_tokens"),
+		Markdown:  format == "markdown",
+	})
+	if err != nil {
+		return contextPackError(c, err)
+	}
+	if format == "markdown" {
+		name := strings.ReplaceAll(pack.WorktreeName, "/", "-") + "-context.md"
+		c.Set(fiber.HeaderContentType, "text/markdown; charset=utf-8")
+		c.Set(fiber.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", name))
+		return c.SendString(pack.Markdown)
+	}
+	return c.JSON(pack)
+}
+
+// WriteWorktreeContextPack writes a context pack of a worktree's session into the worktree
+// @Summary Write worktree context pack
+// @Description Generates the context pack of a worktree like GET does and writes its markdown to .catnip/context.md in the worktree, for tools that read context from the tree. .catnip/ gets a .gitignore ignoring itself so the file is never committed; the repository's ignore files are left alone.
+// @Tags git
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Param max_tokens query int false "Token budget"
+// @Success 200 {object} models.WorktreeContextPack
+// @Failure 400 {object} map[string]string "Invalid request"
+// @Failure 404 {object} map[string]string "Worktree not found"
+// @Router /v1/git/worktrees/{id}/context-pack [post]
+func (h *GitHandler) WriteWorktreeContextPack(c *fiber.Ctx) error {
+	pack, err := h.gitService.GenerateContextPack(c.Params("id"), services.ContextPackOptions{
+		MaxTokens: c.QueryInt("max_tokens"),
+		Write:     true,
+	})
+	if err != nil {
+		return contextPackError(c, err)
+	}
+	return c.JSON(pack)
+}
+
+// ValidateWorktree runs the repository's validation commands in a worktree
+// @Summary Validate worktree
+// @Description Runs the validation commands configured for the worktree's repository (git config catnip.validate.command, one value per command) the way merges and pull request pushes do, so the result can be checked beforehand. A result recorded for the same HEAD and commands is returned without running anything unless force is set. Failing commands are reported in the result, not as an error.
+// @Tags git
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Param force query bool false "Run the commands even when a result for the current HEAD is recorded"
+// @Success 200 {object} models.ValidationResult
+// @Failure 404 {object} map[string]string "Worktree not found"
+// @Router /v1/git/worktrees/{id}/validate [post]
+func (h *GitHandler) ValidateWorktree(c *fiber.Ctx) error {
+	result, err := h.gitService.ValidateWorktree(c.Params("id"), c.QueryBool("force"))
+	if err != nil {
+		status := 500
+		if strings.Contains(err.Error(), "not found") {
+			status = 404
+		}
+		return c.Status(status).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(result)
+}
+
+// StashWorktree stashes the uncommitted changes of a worktree
+// @Summary Stash worktree changes
+// @Description Sets the staged, unstaged and untracked changes of a worktree aside. The number of entries stashed for a worktree is reported as stash_count.
+// @Tags git
+// @Accept json
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Param body body map[string]string false "Stash message"
+// @Success 200 {object} WorktreeOperationResponse
+// @Failure 400 {object} map[string]string "Nothing to stash"
+// @Failure 404 {object} map[string]string "Worktree not found"
+// @Router /v1/git/worktrees/{id}/stash [post]
+func (h *GitHandler) StashWorktree(c *fiber.Ctx) error {
+	worktreeID := c.Params("id")
+
+	var stashRequest struct {
+		Message string `json:"message"`
+	}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&stashRequest); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+	}
+
+	if err := h.gitService.StashWorktree(worktreeID, stashRequest.Message); err != nil {
+		status := 400
+		if strings.Contains(err.Error(), "not found") {
+			status = 404
+		}
+		return c.Status(status).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(WorktreeOperationResponse{
+		Message: "Worktree changes stashed successfully",
+		ID:      worktreeID,
+	})
+}
+
+// UnstashWorktree restores the changes most recently stashed for a worktree
+// @Summary Restore stashed worktree changes
+// @Description Pops the entry most recently stashed for a worktree. Changes conflicting with the worktree return 409 merge_conflict with operation "unstash" and the entry is kept.
+// @Tags git
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Success 200 {object} WorktreeOperationResponse
+// @Failure 400 {object} map[string]string "Nothing stashed"
+// @Failure 404 {object} map[string]string "Worktree not found"
+// @Failure 409 {object} map[string]interface{} "Restored changes conflict"
+// @Router /v1/git/worktrees/{id}/unstash [post]
+func (h *GitHandler) UnstashWorktree(c *fiber.Ctx) error {
+	worktreeID := c.Params("id")
+
+	if err := h.gitService.UnstashWorktree(worktreeID); err != nil {
+		var mergeConflictErr *models.MergeConflictError
+		if errors.As(err, &mergeConflictErr) {
+			return c.Status(409).JSON(fiber.Map{
+				"error":          "merge_conflict",
+				"message":        mergeConflictErr.Message,
+				"operation":      mergeConflictErr.Operation,
+				"worktree_name":  mergeConflictErr.WorktreeName,
+				"worktree_path":  mergeConflictErr.WorktreePath,
+				"conflict_files": mergeConflictErr.ConflictFiles,
+			})
+		}
+		status := 400
+		if strings.Contains(err.Error(), "not found") {
+			status = 404
+		}
+		return c.Status(status).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(WorktreeOperationResponse{
+		Message: "Stashed changes restored successfully",
+		ID:      worktreeID,
+	})
+}
+
+// ListOperationBackups lists the backups taken of a worktree before rewriting its history
+// @Summary List worktree operation backups
+// @Description Lists the refs catnip created at a worktree's HEAD before rebasing, squashing or restoring it, newest first. Backups expire after CATNIP_OPERATION_BACKUP_DAYS (default 7) days.
+// @Tags git
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Success 200 {array} models.OperationBackup
+// @Failure 404 {object} map[string]string "Worktree not found"
+// @Router /v1/git/worktrees/{id}/backups [get]
+func (h *GitHandler) ListOperationBackups(c *fiber.Ctx) error {
+	backups, err := h.gitService.ListOperationBackups(c.Params("id"))
+	if err != nil {
+		status := 500
+		if strings.Contains(err.Error(), "not found") {
+			status = 404
+		}
+		return c.Status(status).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(backups)
+}
+
+// RestoreOperationBackup resets a worktree's branch to a backup
+// @Summary Restore worktree operation backup
+// @Description Resets a worktree's branch to a backup taken before a history rewriting operation. The current HEAD is backed up first and returned as backup, so the restore can be undone. Refused while the worktree has uncommitted changes or a merge or rebase in progress.
+// @Tags git
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Param backupId path string true "Backup ID"
+// @Success 200 {object} WorktreeOperationResponse
+// @Failure 400 {object} map[string]string "Worktree not clean"
+// @Failure 404 {object} map[string]string "Worktree or backup not found"
+// @Router /v1/git/worktrees/{id}/backups/{backupId}/restore [post]
+func (h *GitHandler) RestoreOperationBackup(c *fiber.Ctx) error {
+	worktreeID := c.Params("id")
+
+	undo, err := h.gitService.RestoreOperationBackup(worktreeID, c.Params("backupId"))
+	if err != nil {
+		status := 400
+		if strings.Contains(err.Error(), "not found") {
+			status = 404
+		}
+		return c.Status(status).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(WorktreeOperationResponse{
+		Message: "Backup restored successfully",
+		ID:      worktreeID,
+		Backup:  undo,
+	})
+}
+
+// GetWorktreeConflicts lists the files a merge or rebase left conflicted in a worktree
+// @Summary Get worktree conflicts
+// @Description Lists the files a sync's merge or rebase left conflicted, with their base, ours and theirs versions from the index. Contents are left out for binary files and cut off at 100KB. While rebasing, ours is the branch rebased onto and theirs the commit being replayed. Cherry-picks left stopped on conflicts are listed the same way. operation is empty when no merge, rebase or cherry-pick is in progress.
+// @Tags git
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Success 200 {object} models.WorktreeConflicts
+// @Failure 404 {object} map[string]string "Worktree not found"
+// @Router /v1/git/worktrees/{id}/conflicts [get]
+func (h *GitHandler) GetWorktreeConflicts(c *fiber.Ctx) error {
+	conflicts, err := h.gitService.GetWorktreeConflicts(c.Params("id"))
+	if err != nil {
+		status := 400
+		if strings.Contains(err.Error(), "not found") {
+			status = 404
+		}
+		return c.Status(status).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(conflicts)
+}
+
+// ResolveWorktreeConflict resolves one conflicted file of a worktree
+// @Summary Resolve worktree conflict
+// @Description Resolves a conflicted file with our version, their version or replacement content and stages it, returning the conflicts left. Keeping the version of a side that deleted the file deletes it.
+// @Tags git
+// @Accept json
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Param body body map[string]string true "file, resolution (ours, theirs or content) and content"
+// @Success 200 {object} models.WorktreeConflicts
+// @Failure 400 {object} map[string]string "File not conflicted or unknown resolution"
+// @Failure 404 {object} map[string]string "Worktree not found"
+// @Router /v1/git/worktrees/{id}/conflicts/resolve [post]
+func (h *GitHandler) ResolveWorktreeConflict(c *fiber.Ctx) error {
+	var resolveRequest struct {
+		File string `json:"file"`
+		models.ConflictResolution
+	}
+
+	if err := c.BodyParser(&resolveRequest); err != nil || resolveRequest.File == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid request body, file is required",
+		})
+	}
+
+	conflicts, err := h.gitService.ResolveWorktreeConflict(c.Params("id"), resolveRequest.File, resolveRequest.ConflictResolution)
+	if err != nil {
+		status := 400
+		if strings.Contains(err.Error(), "not found") {
+			status = 404
+		}
+		return c.Status(status).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(conflicts)
+}
+
+// ContinueSync concludes a sync whose conflicts are resolved
+// @Summary Continue sync
+// @Description Runs merge --continue, rebase --continue or cherry-pick --continue once every conflict of a sync or cherry-pick is resolved. A rebase stopping at the next conflicting commit returns 409 merge_conflict.
+// @Tags git
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Success 200 {object} WorktreeOperationResponse
+// @Failure 400 {object} map[string]string "Nothing in progress or files still conflicted"
+// @Failure 404 {object} map[string]string "Worktree not found"
+// @Failure 409 {object} map[string]interface{} "Merge conflict"
+// @Router /v1/git/worktrees/{id}/sync/continue [post]
+func (h *GitHandler) ContinueSync(c *fiber.Ctx) error {
+	worktreeID := c.Params("id")
+
+	if err := h.gitService.ContinueSync(worktreeID); err != nil {
+		var mergeConflictErr *models.MergeConflictError
+		if errors.As(err, &mergeConflictErr) {
+			return c.Status(409).JSON(fiber.Map{
+				"error":             "merge_conflict",
+				"message":           mergeConflictErr.Message,
+				"operation":         mergeConflictErr.Operation,
+				"worktree_name":     mergeConflictErr.WorktreeName,
+				"worktree_path":     mergeConflictErr.WorktreePath,
+				"conflict_files":    mergeConflictErr.ConflictFiles,
+				"lockfile_commands": mergeConflictErr.LockfileCommands,
+			})
+		}
+		status := 400
+		if strings.Contains(err.Error(), "not found") {
+			status = 404
+		}
+		return c.Status(status).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(WorktreeOperationResponse{
+		Message: "Sync continued successfully",
+		ID:      worktreeID,
+	})
+}
+
+// AbortSync abandons a sync left in progress by conflicts
+// @Summary Abort sync
+// @Description Runs merge --abort or rebase --abort, returning the worktree to where it was before the sync
+// @Tags git
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Success 200 {object} WorktreeOperationResponse
+// @Failure 400 {object} map[string]string "Nothing in progress"
+// @Failure 404 {object} map[string]string "Worktree not found"
+// @Router /v1/git/worktrees/{id}/sync/abort [post]
+func (h *GitHandler) AbortSync(c *fiber.Ctx) error {
+	worktreeID := c.Params("id")
+
+	if err := h.gitService.AbortSync(worktreeID); err != nil {
+		status := 400
+		if strings.Contains(err.Error(), "not found") {
+			status = 404
+		}
+		return c.Status(status).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(WorktreeOperationResponse{
+		Message: "Sync aborted successfully",
+		ID:      worktreeID,
+	})
+}
+
+// CherryPickRequest selects the commits to cherry-pick into a worktree
+type CherryPickRequest struct {
+	// Worktree whose branch the commits are resolved against, as listed by its commit history
+	SourceWorktreeID string `json:"source_worktree_id" example:"abc123-def456-ghi789"`
+	// Full or abbreviated SHAs, in any order
+	Commits []string `json:"commits"`
+}
+
+// CherryPickCommits applies commits of another worktree to a worktree
+// @Summary Cherry-pick commits
+// @Description Applies commits to the worktree one at a time. With source_worktree_id the commits are resolved against that worktree's branch, refusing commits not on it, and applied in branch order; without it they are applied as given. Commits missing from the worktree are fetched from its repository, commits whose changes the worktree already has are skipped. A conflicting commit returns 409 merge_conflict with operation "cherry-pick", the failed_commit and the applied_commits, which stay committed; the cherry-pick is left in progress for the conflicts endpoints and sync/continue, or cherry-pick/abort.
+// @Tags git
+// @Accept json
+// @Produce json
+// @Param id path string true "Target worktree ID"
+// @Param request body CherryPickRequest true "Commits to cherry-pick"
+// @Success 200 {object} models.CherryPickResult
+// @Failure 400 {object} map[string]string "Invalid commits, uncommitted changes or an operation in progress"
+// @Failure 404 {object} map[string]string "Worktree or commit not found"
+// @Failure 409 {object} map[string]interface{} "Merge conflict"
+// @Router /v1/git/worktrees/{id}/cherry-pick [post]
+func (h *GitHandler) CherryPickCommits(c *fiber.Ctx) error {
+	worktreeID := c.Params("id")
+
+	var req CherryPickRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	commits := req.Commits
+	if req.SourceWorktreeID != "" {
+		resolved, err := h.gitService.ResolveWorktreeCommits(req.SourceWorktreeID, req.Commits)
+		if err != nil {
+			status := 400
+			if strings.Contains(err.Error(), "not found") {
+				status = 404
+			}
+			return c.Status(status).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		commits = resolved
+	}
+
+	result, err := h.gitService.CherryPickCommits(worktreeID, commits)
+	if err != nil {
+		var mergeConflictErr *models.MergeConflictError
+		if errors.As(err, &mergeConflictErr) {
+			return c.Status(409).JSON(fiber.Map{
+				"error":             "merge_conflict",
+				"message":           mergeConflictErr.Message,
+				"operation":         mergeConflictErr.Operation,
+				"worktree_name":     mergeConflictErr.WorktreeName,
+				"worktree_path":     mergeConflictErr.WorktreePath,
+				"conflict_files":    mergeConflictErr.ConflictFiles,
+				"lockfile_commands": mergeConflictErr.LockfileCommands,
+				"failed_commit":     mergeConflictErr.FailedCommit,
+				"applied_commits":   mergeConflictErr.AppliedCommits,
+			})
+		}
+		status := 400
+		if strings.Contains(err.Error(), "not found") {
+			status = 404
+		}
+		return c.Status(status).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(result)
+}
+
+// AbortCherryPick abandons a cherry-pick left in progress by conflicts
+// @Summary Abort cherry-pick
+// @Description Runs cherry-pick --abort, dropping the conflicting commit. Commits applied before it are kept.
+// @Tags git
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Success 200 {object} WorktreeOperationResponse
+// @Failure 400 {object} map[string]string "No cherry-pick in progress"
+// @Failure 404 {object} map[string]string "Worktree not found"
+// @Router /v1/git/worktrees/{id}/cherry-pick/abort [post]
+func (h *GitHandler) AbortCherryPick(c *fiber.Ctx) error {
+	worktreeID := c.Params("id")
+
+	if err := h.gitService.AbortCherryPick(worktreeID); err != nil {
+		status := 400
+		if strings.Contains(err.Error(), "not found") {
+			status = 404
+		}
+		return c.Status(status).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(WorktreeOperationResponse{
+		Message: "Cherry-pick aborted successfully",
+		ID:      worktreeID,
+	})
+}
+
+// patchApplyOptions reads the options of a patch apply from the query string
+func patchApplyOptions(c *fiber.Ctx) services.PatchApplyOptions {
+	return services.PatchApplyOptions{
+		ThreeWay: c.QueryBool("three_way", true),
+		Commit:   c.QueryBool("commit", false),
+		Message:  c.Query("message"),
+		DryRun:   c.QueryBool("dry_run", false),
+		Actor:    GetActor(c),
+	}
+}
+
+// patchApplyError responds to a failed patch apply, merge conflicts in the standard format
+func patchApplyError(c *fiber.Ctx, err error) error {
+	var mergeConflictErr *models.MergeConflictError
+	if errors.As(err, &mergeConflictErr) {
+		return c.Status(409).JSON(fiber.Map{
+			"error":             "merge_conflict",
+			"message":           mergeConflictErr.Message,
+			"operation":         mergeConflictErr.Operation,
+			"worktree_name":     mergeConflictErr.WorktreeName,
+			"worktree_path":     mergeConflictErr.WorktreePath,
+			"conflict_files":    mergeConflictErr.ConflictFiles,
+			"lockfile_commands": mergeConflictErr.LockfileCommands,
+		})
+	}
+	status := 400
+	switch {
+	case errors.Is(err, services.ErrPatchTooLarge):
+		status = 413
+	case strings.Contains(err.Error(), "not found"):
+		status = 404
+	}
+	return c.Status(status).JSON(fiber.Map{
+		"error": err.Error(),
+	})
+}
+
+// ApplyPatch applies a patch sent as the request body to a worktree
+// @Summary Apply patch
+// @Description Applies a unified diff or git format-patch output, git binary patches included, to the worktree's files and index. Paths that are absolute, contain .., point into .git or lead through a symlink are refused. With three_way (default) hunks that don't apply are merged, conflicts returning 409 merge_conflict with operation "patch" and left in the worktree to resolve; without it a patch that doesn't apply changes nothing. commit commits the patched files with message, which defaults to the subjects of a format-patch series, unless there were conflicts. dry_run only checks the patch, reporting the files a three-way apply would leave conflicted. Patches over the request size limit are uploaded in chunks instead. Applies are recorded in the repository activity log.
+// @Tags git
+// @Accept text/x-diff
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Param three_way query bool false "Merge hunks that don't apply, leaving conflicts (default true)"
+// @Param commit query bool false "Commit the patched files"
+// @Param message query string false "Commit message"
+// @Param dry_run query bool false "Only check the patch"
+// @Success 200 {object} models.PatchApplyResult
+// @Failure 400 {object} map[string]string "Invalid patch, unsafe path, patch that doesn't apply or an operation in progress"
+// @Failure 404 {object} map[string]string "Worktree not found"
+// @Failure 409 {object} map[string]interface{} "Merge conflict"
+// @Failure 413 {object} map[string]string "Patch over the size limit"
+// @Router /v1/git/worktrees/{id}/patch [post]
+func (h *GitHandler) ApplyPatch(c *fiber.Ctx) error {
+	result, err := h.gitService.ApplyPatchToWorktree(c.Params("id"), bytes.NewReader(c.Body()), patchApplyOptions(c))
+	if err != nil {
+		return patchApplyError(c, err)
+	}
+	return c.JSON(result)
+}
+
+// BeginPatchUpload starts a chunked patch upload to a worktree
+// @Summary Start chunked patch upload
+// @Description Starts an upload of a patch too large for one request. Chunks are sent in order to the upload, which is then applied like a patch sent in one request. Uploads without a chunk for an hour are discarded.
+// @Tags git
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Success 201 {object} models.PatchUpload
+// @Failure 404 {object} map[string]string "Worktree not found"
+// @Router /v1/git/worktrees/{id}/patch/uploads [post]
+func (h *GitHandler) BeginPatchUpload(c *fiber.Ctx) error {
+	upload, err := h.gitService.BeginPatchUpload(c.Params("id"))
+	if err != nil {
+		return patchApplyError(c, err)
+	}
+	return c.Status(201).JSON(upload)
+}
+
+// AppendPatchUpload adds the request body as the next chunk of a patch upload
+// @Summary Upload patch chunk
+// @Description Appends a chunk to a patch upload. offset has to be the size received so far, as returned by the previous chunk, so a retried chunk isn't appended twice.
+// @Tags git
+// @Accept application/octet-stream
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Param upload path string true "Upload ID"
+// @Param offset query int true "Bytes received so far"
+// @Success 200 {object} models.PatchUpload
+// @Failure 400 {object} map[string]string "Offset doesn't match"
+// @Failure 404 {object} map[string]string "Upload not found"
+// @Failure 413 {object} map[string]string "Patch over the size limit"
+// @Router /v1/git/worktrees/{id}/patch/uploads/{upload} [put]
+func (h *GitHandler) AppendPatchUpload(c *fiber.Ctx) error {
+	offset, err := strconv.ParseInt(c.Query("offset"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid offset",
+		})
+	}
+	upload, err := h.gitService.AppendPatchUpload(c.Params("id"), c.Params("upload"), offset, bytes.NewReader(c.Body()))
+	if err != nil {
+		return patchApplyError(c, err)
+	}
+	return c.JSON(upload)
+}
+
+// ApplyPatchUpload applies a completed patch upload to a worktree
+// @Summary Apply uploaded patch
+// @Description Applies a patch uploaded in chunks, with the options and responses of applying a patch sent in one request. The upload is removed unless dry_run is set.
+// @Tags git
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Param upload path string true "Upload ID"
+// @Param three_way query bool false "Merge hunks that don't apply, leaving conflicts (default true)"
+// @Param commit query bool false "Commit the patched files"
+// @Param message query string false "Commit message"
+// @Param dry_run query bool false "Only check the patch"
+// @Success 200 {object} models.PatchApplyResult
+// @Failure 400 {object} map[string]string "Invalid patch, unsafe path, patch that doesn't apply or an operation in progress"
+// @Failure 404 {object} map[string]string "Worktree or upload not found"
+// @Failure 409 {object} map[string]interface{} "Merge conflict"
+// @Router /v1/git/worktrees/{id}/patch/uploads/{upload}/apply [post]
+func (h *GitHandler) ApplyPatchUpload(c *fiber.Ctx) error {
+	result, err := h.gitService.ApplyPatchUpload(c.Params("id"), c.Params("upload"), patchApplyOptions(c))
+	if err != nil {
+		return patchApplyError(c, err)
+	}
+	return c.JSON(result)
+}
+
+// DiscardPatchUpload removes a patch upload without applying it
+// @Summary Discard patch upload
+// @Tags git
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Param upload path string true "Upload ID"
+// @Success 200 {object} WorktreeOperationResponse
+// @Failure 404 {object} map[string]string "Upload not found"
+// @Router /v1/git/worktrees/{id}/patch/uploads/{upload} [delete]
+func (h *GitHandler) DiscardPatchUpload(c *fiber.Ctx) error {
+	if err := h.gitService.DiscardPatchUpload(c.Params("id"), c.Params("upload")); err != nil {
+		return patchApplyError(c, err)
+	}
+	return c.JSON(WorktreeOperationResponse{
+		Message: "Patch upload discarded",
+		ID:      c.Params("upload"),
+	})
+}
+
+// CreateTagRequest names a tag to create at a worktree's HEAD
+type CreateTagRequest struct {
+	// Tag name, validated like branch names
+	Name string `json:"name" example:"v1.2.0-rc1"`
+	// Message of an annotated tag
+	Message string `json:"message,omitempty" example:"Release candidate 1"`
+	// Create an annotated tag rather than a lightweight one
+	Annotated bool `json:"annotated"`
+}
+
+// PushTagRequest names a tag to push
+type PushTagRequest struct {
+	// Tag name
+	Name string `json:"name" example:"v1.2.0-rc1"`
+}
+
+// ListTags returns the tags of a repository
+// @Summary List repository tags
+// @Description Lists the tags of a repository with the commits they point at, latest first
+// @Tags git
+// @Produce json
+// @Param id path string true "Repository ID"
+// @Success 200 {array} models.GitTag
+// @Failure 404 {object} map[string]string "Repository not found"
+// @Router /v1/git/repositories/{id}/tags [get]
+func (h *GitHandler) ListTags(c *fiber.Ctx) error {
+	repoID, err := url.QueryUnescape(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid repository ID: " + err.Error(),
+		})
+	}
+
+	tags, err := h.gitService.ListTags(repoID)
+	if err != nil {
+		status := 400
+		if strings.Contains(err.Error(), "not found") {
+			status = 404
+		}
+		return c.Status(status).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.JSON(tags)
+}
+
+// DeleteTag deletes a tag of a repository
+// @Summary Delete repository tag
+// @Description Deletes a tag locally. A tag already pushed stays on the remote.
+// @Tags git
+// @Produce json
+// @Param id path string true "Repository ID"
+// @Param tag path string true "Tag name, URL encoded"
+// @Success 200 {object} map[string]string
+// @Failure 404 {object} map[string]string "Repository or tag not found"
+// @Router /v1/git/repositories/{id}/tags/{tag} [delete]
+func (h *GitHandler) DeleteTag(c *fiber.Ctx) error {
+	repoID, err := url.QueryUnescape(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid repository ID: " + err.Error(),
+		})
+	}
+	tag, err := url.QueryUnescape(c.Params("tag"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid tag name: " + err.Error(),
+		})
+	}
+
+	if err := h.gitService.DeleteTag(repoID, tag); err != nil {
+		status := 400
+		if strings.Contains(err.Error(), "not found") {
+			status = 404
+		}
+		return c.Status(status).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.JSON(fiber.Map{
+		"message": "Tag deleted successfully",
+		"tag":     tag,
+	})
+}
+
+// CreateWorktreeTag tags the HEAD of a worktree
+// @Summary Create tag
+// @Description Tags the worktree's HEAD, e.g. to cut a release candidate from its branch. Annotated tags need a message. Names are validated with git check-ref-format; existing tags are refused, as tags are shared by all worktrees of the repository.
+// @Tags git
+// @Accept json
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Param request body CreateTagRequest true "Tag to create"
+// @Success 200 {object} models.GitTag
+// @Failure 400 {object} map[string]string "Invalid or existing tag name"
+// @Failure 404 {object} map[string]string "Worktree not found"
+// @Router /v1/git/worktrees/{id}/tags [post]
+func (h *GitHandler) CreateWorktreeTag(c *fiber.Ctx) error {
+	worktreeID := c.Params("id")
+
+	var req CreateTagRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	tag, err := h.gitService.CreateTag(worktreeID, req.Name, req.Message, req.Annotated)
+	if err != nil {
+		status := 400
+		if strings.Contains(err.Error(), "not found") {
+			status = 404
+		}
+		return c.Status(status).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.JSON(tag)
+}
+
+// PushWorktreeTag pushes a tag to the worktree repository's remote
+// @Summary Push tag
+// @Description Pushes a tag to origin with the same URL handling as branch pushes. Local repositories need a remote configured.
+// @Tags git
+// @Accept json
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Param request body PushTagRequest true "Tag to push"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string "No remote configured or push failed"
+// @Failure 404 {object} map[string]string "Worktree or tag not found"
+// @Router /v1/git/worktrees/{id}/tags/push [post]
+func (h *GitHandler) PushWorktreeTag(c *fiber.Ctx) error {
+	worktreeID := c.Params("id")
+
+	var req PushTagRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if err := h.gitService.PushTag(worktreeID, req.Name); err != nil {
+		status := 400
+		if strings.Contains(err.Error(), "not found") {
+			status = 404
+		}
+		return c.Status(status).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.JSON(fiber.Map{
+		"message": "Tag pushed successfully",
+		"tag":     req.Name,
+	})
+}
+
+// GetOperationEstimates returns expected durations of the major operations on a worktree
+// @Summary Get operation estimates
+// @Description Returns how long fetch, sync, merge, pull request and diff operations usually take on a worktree, based on recent runs, with warnings when an operation is likely slow due to shallow history or repository size
+// @Tags git
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Success 200 {object} services.OperationEstimatesResponse
+// @Router /v1/git/worktrees/{id}/operation-estimates [get]
+func (h *GitHandler) GetOperationEstimates(c *fiber.Ctx) error {
+	estimates, err := h.gitService.GetOperationEstimates(c.Params("id"))
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(estimates)
+}
+
+// CreatePullRequestRequest represents a request to create a pull request
+type CreatePullRequestRequest struct {
+	Title     string `json:"title"`
+	Body      string `json:"body"`
+	ForcePush bool   `json:"force_push,omitempty"`
+	// Spend the GitHub rate limit kept in reserve instead of queueing while it is low
+	Urgent bool `json:"urgent,omitempty"`
+	ValidationOverride
+}
+
+// ValidationOverride bypasses a repository's validation commands in an emergency
+type ValidationOverride struct {
+	// Push or merge without running the validation commands, requires confirm
+	SkipValidation bool `json:"skip_validation,omitempty"`
+	// Confirms skipping the validation
+	Confirm bool `json:"confirm,omitempty"`
+}
+
+// skip returns whether validation is skipped, refusing skips that weren't confirmed
+func (o ValidationOverride) skip() (bool, error) {
+	if o.SkipValidation && !o.Confirm {
+		return false, errors.New("skip_validation needs confirm: true, skipping validation is meant for emergencies")
+	}
+	return o.SkipValidation, nil
+}
+
+// validationFailure returns the response body for an operation blocked by failing validation
+// commands, nil for other errors
+func validationFailure(err error) fiber.Map {
+	var validationErr *services.ValidationFailedError
+	if !errors.As(err, &validationErr) {
+		return nil
+	}
+	return fiber.Map{
+		"error":      "validation_failed",
+		"message":    err.Error(),
+		"validation": validationErr.Result,
+	}
+}
+
+// CreatePullRequest creates a pull request for a worktree
+// @Summary Create pull request
+// @Description Creates a pull request for a worktree branch. While the GitHub rate limit is nearly spent the request is queued until it resets and 202 returns the queued operation; set urgent to spend the budget kept in reserve instead.
+// @Tags git
+// @Accept json
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Param request body CreatePullRequestRequest true "Pull request details"
+// @Success 200 {object} models.PullRequestResponse
+// @Success 202 {object} models.QueuedGitHubOperation "Queued until the GitHub rate limit resets"
+// @Failure 422 {object} map[string]interface{} "Validation failed"
+// @Router /v1/git/worktrees/{id}/pr [post]
+func (h *GitHandler) CreatePullRequest(c *fiber.Ctx) error {
+	worktreeID := c.Params("id")
+
+	var req CreatePullRequestRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	skipValidation, err := req.skip()
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	body := services.AppendActorAttribution(req.Body, GetActor(c))
+	pr, queued, err := h.gitService.CreatePullRequestOrQueue(worktreeID, req.Title, body, services.PullRequestOptions{
+		ForcePush:      req.ForcePush,
+		SkipValidation: skipValidation,
+		Urgent:         req.Urgent,
+	})
+	if err != nil {
+		if response := validationFailure(err); response != nil {
+			return c.Status(422).JSON(response)
+		}
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if queued != nil {
+		return c.Status(202).JSON(queued)
+	}
+
+	return c.JSON(pr)
+}
+
+// PreviewPullRequestBodyRequest is the body a pull request would be created with, before the
+// repository's template is applied
+type PreviewPullRequestBodyRequest struct {
+	Body string `json:"body"`
+}
+
+// PreviewPullRequestBody shows the body a pull request would be created with
+// @Summary Preview pull request body
+// @Description Returns the body creating a pull request for the worktree with the given body would submit. When the worktree has a pull request template (PULL_REQUEST_TEMPLATE.md in .github/, the root or docs/, or a PULL_REQUEST_TEMPLATE/ directory of templates) it is used as the scaffold and catnip's generated content (the body or session title, commits and todos) is appended after a separator or filled in under the template's matching headings, as the repository's pull request template settings say. Templates over 64KB or that aren't UTF-8 text are ignored with a warning.
+// @Tags git
+// @Accept json
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Param request body PreviewPullRequestBodyRequest true "Pull request body"
+// @Success 200 {object} models.PullRequestBodyPreview
+// @Failure 404 {object} map[string]string "Worktree not found"
+// @Router /v1/git/worktrees/{id}/pr/preview [post]
+func (h *GitHandler) PreviewPullRequestBody(c *fiber.Ctx) error {
+	var req PreviewPullRequestBodyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	body := services.AppendActorAttribution(req.Body, GetActor(c))
+	preview, err := h.gitService.PreviewPullRequestBody(c.Params("id"), body)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.JSON(preview)
+}
+
+// BulkPullRequestRequest selects the worktrees to open pull requests for and how
+type BulkPullRequestRequest struct {
+	services.BulkPullRequestSelector
+	services.BulkPullRequestOptions
+}
+
+// CreatePullRequestsBulk opens pull requests for several worktrees
+// @Summary Create pull requests in bulk
+// @Description Opens pull requests for every worktree of a repository (repo_id) or the listed worktrees (worktree_ids), one at a time with a pause between them to stay clear of GitHub's abuse detection. Worktrees that already have a pull request or no commits ahead of their source branch are skipped. With sync_first each worktree is synced with its source branch first using sync_strategy: ff-only (the default) skips worktrees that can't be fast-forwarded, reporting how far they diverged, while merge or rebase sync them anyway. Titles and bodies are generated from the session title and commit subjects (text_mode "session", the default) or the commit subjects only ("commits"). A worktree:pull_request_created event is emitted for each pull request.
+// @Tags git
+// @Accept json
+// @Produce json
+// @Param request body BulkPullRequestRequest true "Worktree selection and options"
+// @Success 200 {object} services.BulkPullRequestReport
+// @Failure 400 {object} map[string]string "Invalid request"
+// @Router /v1/git/pull-requests/bulk [post]
+func (h *GitHandler) CreatePullRequestsBulk(c *fiber.Ctx) error {
+	var req BulkPullRequestRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	req.Actor = GetActor(c)
+	report, err := h.gitService.CreatePullRequestsBulk(req.BulkPullRequestSelector, req.BulkPullRequestOptions)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(report)
+}
+
+// ImportSigningKeyRequest carries a public key to verify commit signatures with
+type ImportSigningKeyRequest struct {
+	Key string `json:"key"`
+}
+
+// ImportSigningKey adds a public key to the signing keyring
+// @Summary Import signing key
+// @Description Adds an ASCII-armored GPG public key, or SSH allowed signers lines ("principal key-type key"), to the keyring commit signatures are verified against. The keyring is the CATNIP_SIGNING_KEYRING directory, signing-keyring in the volume directory by default.
+// @Tags git
+// @Accept json
+// @Produce json
+// @Param request body ImportSigningKeyRequest true "Public key"
+// @Success 200 {object} services.SigningKeyImport
+// @Failure 400 {object} map[string]string "Invalid key"
+// @Router /v1/git/signing-keys [post]
+func (h *GitHandler) ImportSigningKey(c *fiber.Ctx) error {
+	var req ImportSigningKeyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	imported, err := h.gitService.ImportSigningKey(req.Key)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.JSON(imported)
+}
+
+// CreateWorktreeGroupRequest names a worktree group and lists its repositories in merge order
+type CreateWorktreeGroupRequest struct {
+	Name    string                       `json:"name"`
+	Members []models.WorktreeGroupMember `json:"members"`
+}
+
+// worktreeGroupError responds with 404 for unknown groups and 400 otherwise
+func worktreeGroupError(c *fiber.Ctx, err error) error {
+	status := 400
+	if strings.Contains(err.Error(), "not found") {
+		status = 404
+	}
+	return c.Status(status).JSON(fiber.Map{
+		"error": err.Error(),
+	})
+}
+
+// CreateWorktreeGroup creates linked worktrees in several repositories
+// @Summary Create worktree group
+// @Description Creates a worktree in every listed repository (repo_id, with an optional source branch) and links them into a group for a feature spanning those repositories. Members are merged in the order listed. If any worktree can't be created, the ones already created are deleted.
+// @Tags git
+// @Accept json
+// @Produce json
+// @Param request body CreateWorktreeGroupRequest true "Group name and members"
+// @Success 200 {object} models.WorktreeGroup
+// @Failure 400 {object} map[string]string "Invalid request"
+// @Router /v1/git/worktree-groups [post]
+func (h *GitHandler) CreateWorktreeGroup(c *fiber.Ctx) error {
+	var req CreateWorktreeGroupRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	group, err := h.gitService.CreateWorktreeGroup(req.Name, req.Members)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.JSON(group)
+}
+
+// ListWorktreeGroups lists the worktree groups
+// @Summary List worktree groups
+// @Description Returns all worktree groups, oldest first
+// @Tags git
+// @Produce json
+// @Success 200 {array} models.WorktreeGroup
+// @Router /v1/git/worktree-groups [get]
+func (h *GitHandler) ListWorktreeGroups(c *fiber.Ctx) error {
+	return c.JSON(h.gitService.ListWorktreeGroups())
+}
+
+// GetWorktreeGroup returns a worktree group with the status of its members
+// @Summary Get worktree group status
+// @Description Returns a worktree group with a summary of each member's worktree: commits, dirtiness, conflicts and pull request. Members whose worktree was deleted outside the group are flagged missing.
+// @Tags git
+// @Produce json
+// @Param id path string true "Worktree group ID"
+// @Success 200 {object} services.WorktreeGroupStatus
+// @Failure 404 {object} map[string]string "Worktree group not found"
+// @Router /v1/git/worktree-groups/{id} [get]
+func (h *GitHandler) GetWorktreeGroup(c *fiber.Ctx) error {
+	status, err := h.gitService.GetWorktreeGroupStatus(c.Params("id"))
+	if err != nil {
+		return worktreeGroupError(c, err)
+	}
+	return c.JSON(status)
+}
+
+// SyncWorktreeGroup syncs every worktree of a group
+// @Summary Sync worktree group
+// @Description Syncs every member of the group with its source branch. All members are checked for conflicts first; when any would conflict none is synced and the report says which.
+// @Tags git
+// @Accept json
+// @Produce json
+// @Param id path string true "Worktree group ID"
+// @Param body body map[string]string false "Sync options: strategy, rebase (default) or merge"
+// @Success 200 {object} services.WorktreeGroupReport
+// @Failure 404 {object} map[string]string "Worktree group not found"
+// @Router /v1/git/worktree-groups/{id}/sync [post]
+func (h *GitHandler) SyncWorktreeGroup(c *fiber.Ctx) error {
+	var req struct {
+		Strategy string `json:"strategy"`
+	}
+	_ = c.BodyParser(&req)
+	if req.Strategy == "" {
+		req.Strategy = "rebase"
+	}
+
+	report, err := h.gitService.SyncWorktreeGroup(c.Params("id"), req.Strategy)
+	if err != nil {
+		return worktreeGroupError(c, err)
+	}
+	return c.JSON(report)
+}
+
+// CreateWorktreeGroupPullRequests opens linked pull requests for a worktree group
+// @Summary Create worktree group pull requests
+// @Description Opens a pull request for every member with commits and no pull request yet, then updates every member's pull request body with a section linking the pull requests of the whole group.
+// @Tags git
+// @Accept json
+// @Produce json
+// @Param id path string true "Worktree group ID"
+// @Param body body map[string]bool false "Pull request options: draft"
+// @Success 200 {object} services.WorktreeGroupReport
+// @Failure 404 {object} map[string]string "Worktree group not found"
+// @Router /v1/git/worktree-groups/{id}/pr [post]
+func (h *GitHandler) CreateWorktreeGroupPullRequests(c *fiber.Ctx) error {
+	var req struct {
+		Draft bool `json:"draft"`
+	}
+	_ = c.BodyParser(&req)
+
+	report, err := h.gitService.CreateWorktreeGroupPullRequests(c.Params("id"), req.Draft)
+	if err != nil {
+		return worktreeGroupError(c, err)
+	}
+	return c.JSON(report)
+}
+
+// MergeWorktreeGroup merges every worktree of a group
+// @Summary Merge worktree group
+// @Description Merges the members into their source branches in member order. Every member must be a local repository without merge conflicts, otherwise none is merged; once a merge fails the remaining members are skipped.
+// @Tags git
+// @Accept json
+// @Produce json
+// @Param id path string true "Worktree group ID"
+// @Param body body map[string]bool false "Merge options: squash"
+// @Success 200 {object} services.WorktreeGroupReport
+// @Failure 404 {object} map[string]string "Worktree group not found"
+// @Router /v1/git/worktree-groups/{id}/merge [post]
+func (h *GitHandler) MergeWorktreeGroup(c *fiber.Ctx) error {
+	var req struct {
+		Squash bool `json:"squash"`
+	}
+	_ = c.BodyParser(&req)
+
+	report, err := h.gitService.MergeWorktreeGroup(c.Params("id"), req.Squash)
+	if err != nil {
+		return worktreeGroupError(c, err)
+	}
+	return c.JSON(report)
+}
+
+// DeleteWorktreeGroup deletes a worktree group
+// @Summary Delete worktree group
+// @Description Deletes the group. With delete_members the worktrees of its members are deleted too, otherwise they are kept and unlinked from the group.
+// @Tags git
+// @Produce json
+// @Param id path string true "Worktree group ID"
+// @Param delete_members query bool false "Also delete the member worktrees"
+// @Success 200 {object} services.WorktreeGroupReport
+// @Failure 404 {object} map[string]string "Worktree group not found"
+// @Router /v1/git/worktree-groups/{id} [delete]
+func (h *GitHandler) DeleteWorktreeGroup(c *fiber.Ctx) error {
+	report, err := h.gitService.DeleteWorktreeGroup(c.Params("id"), c.QueryBool("delete_members"))
+	if err != nil {
+		return worktreeGroupError(c, err)
+	}
+	return c.JSON(report)
+}
+
+// UpdatePullRequest updates an existing pull request for a worktree
+// @Summary Update pull request
+// @Description Updates an existing pull request for a worktree branch. While the GitHub rate limit is nearly spent the update is queued until it resets and 202 returns the queued operation; set urgent to spend the budget kept in reserve instead.
+// @Tags git
+// @Accept json
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Param request body CreatePullRequestRequest true "Pull request details"
+// @Success 200 {object} models.PullRequestResponse
+// @Success 202 {object} models.QueuedGitHubOperation "Queued until the GitHub rate limit resets"
+// @Failure 422 {object} map[string]interface{} "Validation failed"
+// @Router /v1/git/worktrees/{id}/pr [put]
+func (h *GitHandler) UpdatePullRequest(c *fiber.Ctx) error {
+	worktreeID := c.Params("id")
+
+	var req CreatePullRequestRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	skipValidation, err := req.skip()
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	pr, queued, err := h.gitService.UpdatePullRequestOrQueue(worktreeID, req.Title, req.Body, services.PullRequestOptions{
+		ForcePush:      req.ForcePush,
+		SkipValidation: skipValidation,
+		Urgent:         req.Urgent,
+	})
+	if err != nil {
+		if response := validationFailure(err); response != nil {
+			return c.Status(422).JSON(response)
+		}
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if queued != nil {
+		return c.Status(202).JSON(queued)
+	}
+
+	return c.JSON(pr)
+}
+
+// GetGitHubRateLimit returns the GitHub API budget and the operations waiting for it
+// @Summary Get GitHub rate limit
+// @Description Returns the GitHub API budget seen in recent responses (remaining requests and when each limit resets), the throttle it puts on polling and the pull request operations queued until it resets. Below CATNIP_GITHUB_RATE_SLOW_PERCENT (default 20) of a limit PR and issue polling slows down; below CATNIP_GITHUB_RATE_RESERVE_PERCENT (default 5) background refreshes pause and pull request operations are queued unless urgent.
+// @Tags git
+// @Produce json
+// @Param refresh query bool false "Ask GitHub for the current limits first"
+// @Success 200 {object} models.GitHubRateLimitStatus
+// @Failure 502 {object} map[string]string "GitHub couldn't be asked"
+// @Router /v1/git/github/rate-limit [get]
+func (h *GitHandler) GetGitHubRateLimit(c *fiber.Ctx) error {
+	status, err := h.gitService.GitHubRateLimitStatus(c.QueryBool("refresh"))
+	if err != nil {
+		return c.Status(502).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.JSON(status)
+}
+
+// GetPullRequestInfo gets information about an existing pull request for a worktree
+// @Summary Get pull request info
+// @Description Gets information about an existing pull request for a worktree branch
+// @Tags git
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Success 200 {object} models.PullRequestInfo
+// @Router /v1/git/worktrees/{id}/pr [get]
+func (h *GitHandler) GetPullRequestInfo(c *fiber.Ctx) error {
+	worktreeID := c.Params("id")
+
+	prInfo, err := h.gitService.GetPullRequestInfo(worktreeID)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(prInfo)
+}
+
+// GraduateBranchRequest represents the request to graduate a branch
+type GraduateBranchRequest struct {
+	// Optional custom branch name to graduate to
+	BranchName string `json:"branch_name,omitempty" example:"feature/add-auth"`
+}
+
+// GraduateBranch manually triggers renaming of a branch to a semantic name
+// @Summary Rename branch
+// @Description Triggers renaming of any branch to a semantic name using Claude or a custom name. Claude
+// @Description based renames wait for the attempt and return its outcome in branch_rename, which is also
+// @Description stored on the worktree; skipped attempts return 409 and failed ones 500 with the reason.
+// @Tags git
+// @Accept json
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Param request body GraduateBranchRequest false "Graduation request with optional custom branch name"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string "Bad request (invalid branch name, branch already exists, etc.)"
+// @Failure 404 {object} map[string]string "Worktree not found"
+// @Failure 409 {object} map[string]interface{} "Rename skipped, with the reason in branch_rename"
+// @Failure 422 {object} map[string]string "No title available for automatic naming"
+// @Failure 500 {object} map[string]interface{} "Rename failed, with the reason in branch_rename"
+// @Router /v1/git/worktrees/{id}/graduate [post]
+func (h *GitHandler) GraduateBranch(c *fiber.Ctx) error {
+	worktreeID := c.Params("id")
+
+	// Parse request body (optional)
+	var req GraduateBranchRequest
+	_ = c.BodyParser(&req) // Don't fail if body is empty
+
+	// Find the worktree to get its path
+	worktrees := h.gitService.ListWorktrees()
+	var workDir string
+	for _, worktree := range worktrees {
+		if worktree.ID == worktreeID {
+			workDir = worktree.Path
+			break
+		}
+	}
+
+	if workDir == "" {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Worktree not found",
+		})
+	}
+
+	// If custom branch name is provided, handle directly
+	if req.BranchName != "" {
+		req.BranchName = h.gitService.CapBranchName(workDir, req.BranchName)
+
+		// Get current branch name using public ExecuteGit method
+		output, err := h.gitService.ExecuteGit(workDir, "rev-parse", "--symbolic-full-name", "HEAD")
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to get current branch name: " + err.Error(),
+			})
+		}
+		currentBranch := strings.TrimSpace(string(output))
+
+		// Validate the custom branch name using git check-ref-format
+		if _, err := h.gitService.ExecuteGit("", "check-ref-format", "refs/heads/"+req.BranchName); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid branch name: " + req.BranchName,
+			})
+		}
+
+		// Check if the new branch already exists
+		if h.gitService.BranchExists(workDir, req.BranchName, false) {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Branch already exists: " + req.BranchName,
+			})
+		}
+
+		// Create new branch from current HEAD
+		if _, err := h.gitService.ExecuteGit(workDir, "checkout", "-b", req.BranchName); err != nil {
+			h.recordBranchRename(worktreeID, &models.BranchRenameOutcome{
+				Trigger:         services.BranchRenameTriggerManual,
+				Outcome:         services.BranchRenameFailed,
+				Reason:          services.RenameReasonRenameFailed,
+				RequestedBranch: req.BranchName,
+				Message:         "Failed to create new branch: " + err.Error(),
+				At:              time.Now(),
+			})
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to create new branch: " + err.Error(),
+			})
+		}
+
+		// Update the worktree branch name in the GitService so the UI reflects the change
+		if err := h.gitService.UpdateWorktreeBranchName(workDir, req.BranchName); err != nil {
+			logger.Warnf("⚠️  Failed to update worktree branch name in service: %v", err)
+			// Don't fail the whole operation for this, but log the error
+		}
+
+		// Delete the old branch reference if it was a catnip ref
+		if strings.HasPrefix(currentBranch, "refs/catnip/") {
+			if _, err := h.gitService.ExecuteGit(workDir, "update-ref", "-d", currentBranch); err != nil {
+				// Log but don't fail - the new branch was created successfully
+				// This is just cleanup of the old catnip ref
+				logger.Warnf("⚠️  Failed to delete old catnip ref %q: %v", currentBranch, err)
+			}
+		}
+
+		h.recordBranchRename(worktreeID, &models.BranchRenameOutcome{
+			Trigger:         services.BranchRenameTriggerManual,
+			Outcome:         services.BranchRenameRenamed,
+			RequestedBranch: req.BranchName,
+			Branch:          req.BranchName,
+			At:              time.Now(),
+		})
+	} else {
+		// For automatic naming, use the Claude monitor service
+		if h.claudeMonitor == nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Claude monitor service not available",
+			})
+		}
+
+		// Run branch graduation via Claude monitor and report its outcome
+		outcome, err := h.claudeMonitor.TriggerBranchRename(workDir, req.BranchName)
+		if err != nil {
+			// Check for specific error types to return appropriate status codes
+			errMsg := err.Error()
+
+			// No title available for automatic naming
+			if strings.Contains(errMsg, "no title available") {
+				return c.Status(422).JSON(fiber.Map{
+					"error": errMsg,
+					"code":  "NO_TITLE_AVAILABLE",
+				})
+			}
+
+			// Invalid branch name, no checkpoint manager, etc.
+			return c.Status(400).JSON(fiber.Map{
+				"error": errMsg,
+			})
+		}
+
+		switch outcome.Outcome {
+		case services.BranchRenameSkipped:
+			return c.Status(409).JSON(fiber.Map{
+				"error":         outcome.Message,
+				"code":          outcome.Reason,
+				"branch_rename": outcome,
+			})
+		case services.BranchRenameFailed:
+			return c.Status(500).JSON(fiber.Map{
+				"error":         outcome.Message,
+				"code":          outcome.Reason,
+				"branch_rename": outcome,
+			})
+		}
+
+		return c.JSON(fiber.Map{
+			"message":       "Branch renamed successfully",
+			"branch_name":   outcome.Branch,
+			"method":        "claude_generated",
+			"branch_rename": outcome,
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message":     "Branch renamed successfully",
+		"branch_name": req.BranchName,
+		"method":      "custom",
+	})
+}
+
+// recordBranchRename stores the outcome of a manual rename on the worktree and broadcasts it
+func (h *GitHandler) recordBranchRename(worktreeID string, outcome *models.BranchRenameOutcome) {
+	if err := h.gitService.GetStateManager().RecordBranchRename(worktreeID, outcome); err != nil {
+		logger.Warnf("⚠️  Failed to record branch rename outcome: %v", err)
+	}
+}
+
+// RefreshWorktreeStatus forces a refresh of a worktree's cached status
+// @Summary Force refresh worktree status
+// @Description Forces an immediate refresh of a worktree's cached status including commit counts
+// @Tags git
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Success 200 {object} map[string]string
+// @Router /v1/git/worktrees/{id}/refresh [post]
+func (h *GitHandler) RefreshWorktreeStatus(c *fiber.Ctx) error {
+	worktreeID := c.Params("id")
+
+	// Force refresh by calling the git service method that recalculates status
+	if err := h.gitService.RefreshWorktreeStatusByID(worktreeID); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Worktree status refreshed successfully",
+		"id":      worktreeID,
+	})
+}
+
+// CreateTemplateRequest defines the request body for creating from template
+type CreateTemplateRequest struct {
+	TemplateID  string `json:"template_id" binding:"required"`
+	ProjectName string `json:"project_name" binding:"required"`
+	// Where the request comes from: ui, tui, api (default) or auto
+	Source string `json:"source,omitempty"`
+	// Initial prompt of the session
+	Prompt string `json:"prompt,omitempty"`
+	// Issue the session works on, as a number or URL
+	Issue string `json:"issue,omitempty"`
+}
+
+// CreateFromTemplate creates a new workspace from a project template
+// @Summary Create workspace from template
+// @Description Creates a new Git repository and workspace from a predefined project template
+// @Tags git
+// @Accept json
+// @Produce json
+// @Param request body CreateTemplateRequest true "Template creation request"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string "Invalid request or template not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /v1/git/template [post]
+func (h *GitHandler) CreateFromTemplate(c *fiber.Ctx) error {
+	var req CreateTemplateRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid request body: " + err.Error(),
+		})
+	}
+
+	// Validate template ID
+	if req.TemplateID == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "template_id is required",
+		})
+	}
+
+	// Validate project name
+	if req.ProjectName == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "project_name is required",
+		})
+	}
+
+	source, err := services.ParseCreationSource(req.Source)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	// Create project from template
+	repo, worktree, err := h.gitService.CreateFromTemplateWithCreation(req.TemplateID, req.ProjectName, &models.CreationContext{
+		Source:   source,
+		Prompt:   req.Prompt,
+		Issue:    req.Issue,
+		Template: req.TemplateID,
+		Actor:    GetActor(c),
+	})
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	// Return success response with repository information
+	response := fiber.Map{
+		"success": true,
+		"repo_id": repo.ID,
+		"path":    repo.Path,
+		"message": fmt.Sprintf("Successfully created project %s from template %s", req.ProjectName, req.TemplateID),
+	}
+
+	// Include worktree info if one was created
+	if worktree != nil {
+		response["worktree"] = worktree.ID
+		response["worktree_path"] = worktree.Path
+		response["worktree_name"] = worktree.Name
+	}
+
+	return c.JSON(response)
+}
+
+// CreateGitHubRepository creates a GitHub repository and sets it as origin for a local repo
+// @Summary Create GitHub repository
+// @Description Creates a new GitHub repository and sets it as the origin for a local repository
+// @Tags git
+// @Accept json
+// @Produce json
+// @Param id path string true "Repository ID"
+// @Param request body CreateGitHubRepositoryRequest true "Repository creation request"
+// @Success 200 {object} CreateGitHubRepositoryResponse
+// @Failure 400 {object} map[string]string "Invalid request"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /v1/git/repositories/{id}/github [post]
+func (h *GitHandler) CreateGitHubRepository(c *fiber.Ctx) error {
+	repoID := c.Params("id")
+	logger.Infof("🔍 CreateGitHubRepository called with repoID: '%s'", repoID)
+
+	// URL decode the repository ID
+	decodedRepoID, err := url.QueryUnescape(repoID)
+	if err != nil {
+		logger.Errorf("❌ Failed to URL decode repoID '%s': %v", repoID, err)
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid repository ID: " + err.Error(),
+		})
+	}
+	repoID = decodedRepoID
+	logger.Infof("🔍 Decoded repoID: '%s'", repoID)
+
+	var req CreateGitHubRepositoryRequest
+	if err := c.BodyParser(&req); err != nil {
+		logger.Errorf("❌ Failed to parse request body: %v", err)
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid request body: " + err.Error(),
+		})
+	}
+
+	logger.Infof("📝 Request parsed: name='%s', description='%s', isPrivate=%v", req.Name, req.Description, req.IsPrivate)
+
+	// Validate request
+	if req.Name == "" {
+		logger.Errorf("❌ Repository name is required")
+		return c.Status(400).JSON(fiber.Map{
+			"error": "name is required",
+		})
+	}
+
+	// Create GitHub repository and update origin
+	logger.Infof("🚀 Calling CreateGitHubRepositoryAndSetOrigin with repoID: '%s'", repoID)
+	repoURL, err := h.gitService.CreateGitHubRepositoryAndSetOrigin(repoID, req.Name, req.Description, req.IsPrivate)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(CreateGitHubRepositoryResponse{
+		URL:     repoURL,
+		Message: "Repository created and origin updated successfully",
+	})
+}
+
+// SetRepositoryMirrors configures the mirrors of a repository
+// @Summary Configure repository mirrors
+// @Description Replaces the secondary remotes that branches pushed to origin are mirrored to. Credentials are referenced by name (an environment variable holding "user:token" or a token) and never stored.
+// @Tags git
+// @Accept json
+// @Produce json
+// @Param id path string true "Repository ID"
+// @Param request body SetRepositoryMirrorsRequest true "Mirror configuration"
+// @Success 200 {array} models.RepositoryMirror
+// @Failure 400 {object} map[string]string "Invalid request"
+// @Router /v1/git/repositories/{id}/mirrors [put]
+func (h *GitHandler) SetRepositoryMirrors(c *fiber.Ctx) error {
+	repoID, err := url.QueryUnescape(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid repository ID: " + err.Error(),
+		})
+	}
+
+	var req SetRepositoryMirrorsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid request body: " + err.Error(),
+		})
+	}
+
+	mirrors, err := h.gitService.SetRepositoryMirrors(repoID, req.Mirrors)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if mirrors == nil {
+		mirrors = []*models.RepositoryMirror{}
+	}
+
+	return c.JSON(mirrors)
+}
+
+// SyncRepositoryMirrors pushes all branches and tags to every mirror
+// @Summary Sync repository mirrors
+// @Description Forces a full push of all branches and tags to each configured mirror and returns the updated mirror status
+// @Tags git
+// @Produce json
+// @Param id path string true "Repository ID"
+// @Success 200 {array} models.RepositoryMirror
+// @Failure 400 {object} map[string]string "Invalid request"
+// @Failure 502 {object} map[string]string "One or more mirrors failed"
+// @Router /v1/git/repositories/{id}/mirrors/sync [post]
+func (h *GitHandler) SyncRepositoryMirrors(c *fiber.Ctx) error {
+	repoID, err := url.QueryUnescape(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid repository ID: " + err.Error(),
+		})
+	}
+
+	repo := h.gitService.GetRepositoryByID(repoID)
+	if repo == nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": fmt.Sprintf("repository %s not found", repoID),
+		})
+	}
+
+	if err := h.gitService.SyncMirrors(repoID); err != nil {
+		return c.Status(502).JSON(fiber.Map{
+			"error":   err.Error(),
+			"mirrors": repo.Mirrors,
+		})
+	}
+
+	return c.JSON(repo.Mirrors)
+}
+
+// IssueAutomationRequest configures automatic worktree creation from labeled issues
+type IssueAutomationRequest struct {
+	// Whether labeled issues get worktrees
+	Enabled bool `json:"enabled" example:"true"`
+	// Label requesting a worktree, "catnip" when empty
+	Label string `json:"label" example:"catnip"`
+	// Branch worktrees are created from, the repository's default branch when empty
+	Branch string `json:"branch,omitempty" example:"main"`
+}
+
+// GetIssueAutomation returns the issue automation of a repository
+// @Summary Get repository issue automation
+// @Description Returns the issue automation settings of a repository with the issues handled so far and the worktrees created for them
+// @Tags git
+// @Produce json
+// @Param id path string true "Repository ID"
+// @Success 200 {object} models.IssueAutomation
+// @Failure 404 {object} map[string]string "Repository not found"
+// @Router /v1/git/repositories/{id}/issue-automation [get]
+func (h *GitHandler) GetIssueAutomation(c *fiber.Ctx) error {
+	repoID, err := url.QueryUnescape(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid repository ID: " + err.Error(),
+		})
+	}
+
+	automation, err := h.gitService.GetIssueAutomation(repoID)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.JSON(automation)
+}
+
+// SetIssueAutomation configures the issue automation of a repository
+// @Summary Configure repository issue automation
+// @Description Enables or disables automatic worktree creation for GitHub issues carrying a label. Open labeled issues get one worktree each off the configured branch, linked to the issue in its creation context, a comment naming the worktree and a worktree:from_issue event for a driver process to start a session. Issues that lose the label or are closed are never handled again, and their worktrees are never deleted automatically. Repositories are polled every 2 minutes and on issues webhook deliveries.
+// @Tags git
+// @Accept json
+// @Produce json
+// @Param id path string true "Repository ID"
+// @Param request body IssueAutomationRequest true "Issue automation settings"
+// @Success 200 {object} models.IssueAutomation
+// @Failure 400 {object} map[string]string "Invalid request or repository without GitHub remote"
+// @Failure 404 {object} map[string]string "Repository not found"
+// @Router /v1/git/repositories/{id}/issue-automation [put]
+func (h *GitHandler) SetIssueAutomation(c *fiber.Ctx) error {
+	repoID, err := url.QueryUnescape(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid repository ID: " + err.Error(),
+		})
+	}
+
+	var req IssueAutomationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid request body: " + err.Error(),
+		})
+	}
+
+	automation, err := h.gitService.SetIssueAutomation(repoID, models.IssueAutomation{
+		Enabled: req.Enabled,
+		Label:   req.Label,
+		Branch:  req.Branch,
+	})
+	if err != nil {
+		status := 400
+		if strings.HasSuffix(err.Error(), "not found") {
+			status = 404
+		}
+		return c.Status(status).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.JSON(automation)
+}
+
+// GetPullRequestTemplateSettings returns how pull request bodies use the repository's template
+// @Summary Get repository pull request template settings
+// @Description Returns how pull request bodies of a repository use its pull request template, append when never configured
+// @Tags git
+// @Produce json
+// @Param id path string true "Repository ID"
+// @Success 200 {object} models.PullRequestTemplateSettings
+// @Failure 404 {object} map[string]string "Repository not found"
+// @Router /v1/git/repositories/{id}/pr-template [get]
+func (h *GitHandler) GetPullRequestTemplateSettings(c *fiber.Ctx) error {
+	repoID, err := url.QueryUnescape(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid repository ID: " + err.Error(),
+		})
+	}
+
+	settings, err := h.gitService.GetPullRequestTemplateSettings(repoID)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.JSON(settings)
+}
+
+// SetPullRequestTemplateSettings configures how pull request bodies use the repository's template
+// @Summary Configure repository pull request template
+// @Description Sets whether catnip's generated pull request content is appended after the repository's pull request template below a separator (append, the default), filled in under its matching headings between catnip comments (sections), or the template is ignored (off). template picks one file of a PULL_REQUEST_TEMPLATE/ directory of templates by name.
+// @Tags git
+// @Accept json
+// @Produce json
+// @Param id path string true "Repository ID"
+// @Param request body models.PullRequestTemplateSettings true "Pull request template settings"
+// @Success 200 {object} models.PullRequestTemplateSettings
+// @Failure 400 {object} map[string]string "Invalid settings"
+// @Failure 404 {object} map[string]string "Repository not found"
+// @Router /v1/git/repositories/{id}/pr-template [put]
+func (h *GitHandler) SetPullRequestTemplateSettings(c *fiber.Ctx) error {
+	repoID, err := url.QueryUnescape(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid repository ID: " + err.Error(),
+		})
+	}
+
+	var req models.PullRequestTemplateSettings
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid request body: " + err.Error(),
+		})
+	}
+
+	settings, err := h.gitService.SetPullRequestTemplateSettings(repoID, req)
+	if err != nil {
+		status := 400
+		if strings.HasSuffix(err.Error(), "not found") {
+			status = 404
+		}
+		return c.Status(status).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.JSON(settings)
+}
+
+// PollIssueAutomation checks a repository for labeled issues right away
+// @Summary Poll labeled issues now
+// @Description Runs the issue automation of a repository immediately instead of waiting for the next poll and returns the issues that got a worktree
+// @Tags git
+// @Produce json
+// @Param id path string true "Repository ID"
+// @Success 200 {array} models.AutomatedIssue
+// @Failure 400 {object} map[string]string "Issue automation disabled"
+// @Failure 404 {object} map[string]string "Repository not found"
+// @Failure 502 {object} map[string]string "GitHub request failed"
+// @Router /v1/git/repositories/{id}/issue-automation/poll [post]
+func (h *GitHandler) PollIssueAutomation(c *fiber.Ctx) error {
+	repoID, err := url.QueryUnescape(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid repository ID: " + err.Error(),
+		})
+	}
+
+	linked, err := h.gitService.PollIssueAutomation(repoID)
+	if err != nil {
+		status := 502
+		switch {
+		case strings.HasSuffix(err.Error(), "not found"):
+			status = 404
+		case strings.HasPrefix(err.Error(), "issue automation is not enabled"):
+			status = 400
+		}
+		return c.Status(status).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if linked == nil {
+		linked = []*models.AutomatedIssue{}
+	}
+	return c.JSON(linked)
+}
+
+// MigrateLegacyRefs moves legacy catnip/ branches into the refs/catnip/ namespace
+// @Summary Migrate legacy catnip branches
+// @Description Rewrites legacy catnip/ branches that are not checked out by a worktree into refs/catnip/, updating worktree records and preserving reflogs. Ambiguous branches are skipped and listed in the report.
+// @Tags git
+// @Produce json
+// @Param id path string true "Repository ID"
+// @Success 200 {object} services.LegacyRefMigrationReport
+// @Failure 400 {object} map[string]string "Invalid request"
+// @Failure 500 {object} map[string]string "Migration failed"
+// @Router /v1/git/repositories/{id}/migrate-legacy-refs [post]
+func (h *GitHandler) MigrateLegacyRefs(c *fiber.Ctx) error {
+	repoID, err := url.QueryUnescape(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid repository ID: " + err.Error(),
+		})
+	}
+
+	if h.gitService.GetRepositoryByID(repoID) == nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": fmt.Sprintf("repository %s not found", repoID),
+		})
+	}
+
+	report, err := h.gitService.MigrateLegacyRefs(repoID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(report)
+}
+
+// CleanupRepository removes the unused catnip branches, orphaned refs and config mappings of a
+// repository
+// @Summary Clean up a repository's catnip refs
+// @Description Deletes catnip branches without commits, refs/catnip/ refs no worktree uses and orphaned branch mappings of a single repository. Items kept because a worktree uses them and items that failed to be removed are listed in the report. With dry_run set nothing is deleted.
+// @Tags git
+// @Produce json
+// @Param id path string true "Repository ID"
+// @Param dry_run query bool false "Only report what would be removed"
+// @Success 200 {object} services.CleanupReport
+// @Failure 400 {object} map[string]string "Invalid request"
+// @Failure 500 {object} map[string]string "Cleanup failed"
+// @Router /v1/git/repositories/{id}/cleanup [post]
+func (h *GitHandler) CleanupRepository(c *fiber.Ctx) error {
+	repoID, err := url.QueryUnescape(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid repository ID: " + err.Error(),
+		})
+	}
+
+	if h.gitService.GetRepositoryByID(repoID) == nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": fmt.Sprintf("repository %s not found", repoID),
+		})
+	}
+
+	report, err := h.gitService.CleanupCatnipRefsForRepo(repoID, c.QueryBool("dry_run", false))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(report)
+}
+
+// MigrateRenamedRepository moves a repository renamed or transferred on GitHub to its new name
+// @Summary Migrate a renamed repository
+// @Description Asks GitHub whether the repository was renamed or transferred and, if so, moves it to the new owner/name: the origin URL, the bare repository directory, worktree references, pull request associations and state are updated, and the old ID keeps resolving as an alias. A repository:renamed event is emitted.
+// @Tags git
+// @Produce json
+// @Param id path string true "Repository ID"
+// @Success 200 {object} models.Repository
+// @Failure 400 {object} map[string]string "Invalid request or not renamed"
+// @Router /v1/git/repositories/{id}/migrate-rename [post]
+func (h *GitHandler) MigrateRenamedRepository(c *fiber.Ctx) error {
+	repoID, err := url.QueryUnescape(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid repository ID: " + err.Error(),
+		})
+	}
+
+	repo, err := h.gitService.MigrateRenamedRepository(repoID)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(repo)
+}
+
+// GetRemoteConfig reports drift in a repository's remote and credential git config
+// @Summary Check repository remote config
+// @Description Compares the origin URL, insteadOf rules and credential helpers of a repository and its worktrees with the configuration catnip sets up, listing every differing key. Drift is also recorded as a repository health warning.
+// @Tags git
+// @Produce json
+// @Param id path string true "Repository ID"
+// @Success 200 {object} services.RemoteConfigReport
+// @Failure 400 {object} map[string]string "Invalid request"
+// @Failure 500 {object} map[string]string "Check failed"
+// @Router /v1/git/repositories/{id}/remote-config [get]
+func (h *GitHandler) GetRemoteConfig(c *fiber.Ctx) error {
+	repoID, err := url.QueryUnescape(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid repository ID: " + err.Error(),
+		})
+	}
+
+	if h.gitService.GetRepositoryByID(repoID) == nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": fmt.Sprintf("repository %s not found", repoID),
+		})
+	}
+
+	report, err := h.gitService.VerifyRemoteConfig(repoID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(report)
+}
+
+// GetRepositoryActivity returns the activity feed of a repository
+// @Summary Get repository activity
+// @Description Aggregates worktree creations and deletions, session titles, worktree commits, pull requests and merges of a repository into a chronological feed grouped by worktree, with totals. Defaults to the last 24 hours; at most 31 days can be queried at once. With format=markdown a plain text summary for pasting into chat is returned instead.
+// @Tags git
+// @Produce json
+// @Produce plain
+// @Param id path string true "Repository ID"
+// @Param since query string false "Start of the period, RFC 3339 or YYYY-MM-DD (default 24 hours ago)"
+// @Param until query string false "End of the period (exclusive), RFC 3339 or YYYY-MM-DD (default now)"
+// @Param format query string false "json (default) or markdown"
+// @Success 200 {object} services.RepositoryActivity
+// @Failure 400 {object} map[string]string "Invalid request"
+// @Router /v1/git/repositories/{id}/activity [get]
+func (h *GitHandler) GetRepositoryActivity(c *fiber.Ctx) error {
+	repoID, err := url.QueryUnescape(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid repository ID: " + err.Error(),
+		})
+	}
+
+	until := time.Now()
+	if value := c.Query("until"); value != "" {
+		if until, err = parseActivityTime(value); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid until: " + err.Error(),
+			})
+		}
+	}
+	since := until.Add(-24 * time.Hour)
+	if value := c.Query("since"); value != "" {
+		if since, err = parseActivityTime(value); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid since: " + err.Error(),
+			})
+		}
+	}
+
+	activity, err := h.gitService.GetRepositoryActivity(repoID, since, until)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	switch c.Query("format", "json") {
+	case "markdown":
+		c.Set("Content-Type", "text/plain; charset=utf-8")
+		return c.SendString(activity.Markdown())
+	case "json":
+		return c.JSON(activity)
+	default:
+		return c.Status(400).JSON(fiber.Map{
+			"error": "format must be json or markdown",
+		})
+	}
+}
+
+// GetMergeLedger returns the worktree merges of a repository
+// @Summary Get repository merge ledger
+// @Description Lists the worktrees merged into their source branch, newest first, each with the merge commit, a snapshot of the worktree (branch, session, pull request, creator) the diffstat of the merged changes and the signatures of the merged commits. Merge commits carry the same provenance as Catnip-Worktree, Catnip-Branch, Catnip-Session and Catnip-PR trailers.
+// @Tags git
+// @Produce json
+// @Param id path string true "Repository ID"
+// @Param limit query int false "Maximum number of merges (default all)"
+// @Success 200 {array} models.MergeLedgerEntry
+// @Failure 404 {object} map[string]string "Repository not found"
+// @Router /v1/git/repositories/{id}/merges [get]
+func (h *GitHandler) GetMergeLedger(c *fiber.Ctx) error {
+	repoID, err := url.QueryUnescape(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid repository ID: " + err.Error(),
+		})
+	}
+
+	merges, err := h.gitService.GetMergeLedger(repoID, c.QueryInt("limit"))
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.JSON(merges)
+}
+
+// GetMergeByCommit looks up the worktree merge that created a commit
+// @Summary Get the merge of a commit
+// @Description Traces a commit on the source branch of a repository back to the worktree merge that created it, using the merge ledger. The commit may be abbreviated or any revision resolving to it.
+// @Tags git
+// @Produce json
+// @Param id path string true "Repository ID"
+// @Param commit path string true "Commit hash"
+// @Success 200 {object} models.MergeLedgerEntry
+// @Failure 404 {object} map[string]string "Repository, commit or merge not found"
+// @Router /v1/git/repositories/{id}/merges/{commit} [get]
+func (h *GitHandler) GetMergeByCommit(c *fiber.Ctx) error {
+	repoID, err := url.QueryUnescape(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid repository ID: " + err.Error(),
+		})
+	}
+
+	merge, err := h.gitService.FindMergeByCommit(repoID, c.Params("commit"))
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.JSON(merge)
+}
+
+// ListOperations returns the tracked long operations
+// @Summary List tracked operations
+// @Description Lists clones, merges, merged worktree cleanups and bulk pull request runs from the last seven days, newest first. The journal is persisted, so operations a restart interrupted are listed as interrupted along with whether they were resumed or rolled back.
+// @Tags git
+// @Produce json
+// @Success 200 {array} models.Operation
+// @Router /v1/git/operations [get]
+func (h *GitHandler) ListOperations(c *fiber.Ctx) error {
+	return c.JSON(h.gitService.ListOperations())
+}
+
+// GetOperation returns a tracked long operation
+// @Summary Get tracked operation
+// @Description Returns the phases and state of a tracked operation. Operations a restart interrupted report state interrupted while they are recovered, then the terminal state of their resumption.
+// @Tags git
+// @Produce json
+// @Param id path string true "Operation ID"
+// @Success 200 {object} models.Operation
+// @Failure 404 {object} map[string]string "Operation not found"
+// @Router /v1/git/operations/{id} [get]
+func (h *GitHandler) GetOperation(c *fiber.Ctx) error {
+	op, err := h.gitService.GetOperation(c.Params("id"))
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.JSON(op)
+}
+
+// parseActivityTime parses an RFC 3339 time or a YYYY-MM-DD date (midnight UTC)
+func parseActivityTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
+// RepairRepositoryRemoteConfig restores a repository's remote and credential git config
+// @Summary Repair repository remote config
+// @Description Resets drifted remote URLs, removes conflicting insteadOf rules and credential helpers, and restores the gh credential helper for a repository and all its worktrees. Unrelated config keys are left untouched.
+// @Tags git
+// @Produce json
+// @Param id path string true "Repository ID"
+// @Success 200 {object} services.RemoteConfigReport
+// @Failure 400 {object} map[string]string "Invalid request"
+// @Failure 500 {object} map[string]string "Repair failed"
+// @Router /v1/git/repositories/{id}/remote-config/repair [post]
+func (h *GitHandler) RepairRepositoryRemoteConfig(c *fiber.Ctx) error {
+	repoID, err := url.QueryUnescape(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid repository ID: " + err.Error(),
+		})
+	}
+
+	if h.gitService.GetRepositoryByID(repoID) == nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": fmt.Sprintf("repository %s not found", repoID),
+		})
+	}
+
+	report, err := h.gitService.RepairRemoteConfig(repoID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(report)
+}
+
+// RepairCredentials configures and verifies the gh credential helper
+// @Summary Repair git credential helpers
+// @Description Makes gh the credential helper of github.com and the GitHub Enterprise hosts gh is logged in to. The host specific helper key in the global git config is only rewritten when gh is missing, configured twice or shadowed by another helper; other helpers are kept. Every host is then verified with a credential fill, and the outcome is reported in the git subsystem of /health.
+// @Tags git
+// @Produce json
+// @Success 200 {object} git.CredentialHelperReport
+// @Failure 400 {object} map[string]string "Not running in a container"
+// @Failure 500 {object} map[string]string "Repair failed"
+// @Router /v1/git/credentials/repair [post]
+func (h *GitHandler) RepairCredentials(c *fiber.Ctx) error {
+	report, err := h.gitService.RepairCredentials()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if report == nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "credential helpers are only managed in containerized mode",
+		})
+	}
+
+	return c.JSON(report)
+}
+
+// RefreshRepositorySize measures a repository's size on disk
+// @Summary Refresh repository size
+// @Description Measures the objects directory and object count of a repository and stores them on the repository, keeping the size last reported by GitHub. Sizes are also refreshed every 30 minutes.
+// @Tags git
+// @Produce json
+// @Param id path string true "Repository ID"
+// @Success 200 {object} models.RepositorySize
+// @Failure 400 {object} map[string]string "Invalid request"
+// @Failure 500 {object} map[string]string "Measurement failed"
+// @Router /v1/git/repositories/{id}/size/refresh [post]
+func (h *GitHandler) RefreshRepositorySize(c *fiber.Ctx) error {
+	repoID, err := url.QueryUnescape(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid repository ID: " + err.Error(),
+		})
+	}
+
+	if h.gitService.GetRepositoryByID(repoID) == nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": fmt.Sprintf("repository %s not found", repoID),
+		})
+	}
+
+	size, err := h.gitService.RefreshRepositorySize(repoID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(size)
+}
+
+// RepositoryMirrorModeRequest turns mirror mode of a repository on or off
+type RepositoryMirrorModeRequest struct {
+	Enabled bool `json:"enabled" example:"true"`
+}
+
+// SetRepositoryMirrorMode turns mirror mode of a repository on or off
+// @Summary Configure repository mirror mode
+// @Description In mirror mode every branch of origin is fetched, so worktrees of branches already fetched are created without a fetch, and every CATNIP_MIRROR_UPDATE_MINUTES (default 15) a git remote update --prune keeps them current; the repository records it as last_remote_update. Turning it on fetches every branch right away, turning it off keeps the branches already fetched. Not available for local repositories.
 // @Tags git
 // @Accept json
 // @Produce json
-// @Param id path string true "Worktree ID"
-// @Param request body CreatePullRequestRequest true "Pull request details"
-// @Success 200 {object} models.PullRequestResponse
-// @Router /v1/git/worktrees/{id}/pr [post]
-func (h *GitHandler) CreatePullRequest(c *fiber.Ctx) error {
-	worktreeID := c.Params("id")
+// @Param id path string true "Repository ID"
+// @Param request body RepositoryMirrorModeRequest true "Whether mirror mode is on"
+// @Success 200 {object} models.Repository
+// @Failure 400 {object} map[string]string "Invalid request or local repository"
+// @Failure 404 {object} map[string]string "Repository not found"
+// @Failure 500 {object} map[string]string "Fetching the branches failed"
+// @Router /v1/git/repositories/{id}/mirror-mode [put]
+func (h *GitHandler) SetRepositoryMirrorMode(c *fiber.Ctx) error {
+	repoID, err := url.QueryUnescape(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid repository ID: " + err.Error(),
+		})
+	}
 
-	var req CreatePullRequestRequest
+	var req RepositoryMirrorModeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid request body: " + err.Error(),
+		})
+	}
+
+	repo, err := h.gitService.SetRepositoryMirrorMode(repoID, req.Enabled)
+	if err != nil {
+		status := 500
+		switch {
+		case strings.HasSuffix(err.Error(), "not found"):
+			status = 404
+		case strings.Contains(err.Error(), "only available for cloned repositories"):
+			status = 400
+		}
+		return c.Status(status).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.JSON(repo)
+}
+
+// TriggerRepositoryMaintenance garbage collects a repository now
+// @Summary Run repository maintenance
+// @Description Runs a full git gc on a repository once no fetch or worktree creation is using it, records it as the repository's last_gc and measures its size again. Repositories are also checked every CATNIP_GC_INTERVAL_MINUTES (default 60) and garbage collected when their loose objects or packs exceed the catnip.gc.loose-objects (default 2000), catnip.gc.packs (default 20) or catnip.gc.full-loose-size-mb (default 256) git config thresholds.
+// @Tags git
+// @Produce json
+// @Param id path string true "Repository ID"
+// @Success 200 {object} models.RepositoryMaintenance
+// @Failure 404 {object} map[string]string "Repository not found"
+// @Failure 500 {object} map[string]string "git gc failed"
+// @Router /v1/git/repositories/{id}/maintenance [post]
+func (h *GitHandler) TriggerRepositoryMaintenance(c *fiber.Ctx) error {
+	repoID, err := url.QueryUnescape(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid repository ID: " + err.Error(),
+		})
+	}
+
+	result, err := h.gitService.TriggerMaintenance(repoID)
+	if err != nil {
+		status := 500
+		if strings.Contains(err.Error(), "not found") {
+			status = 404
+		}
+		return c.Status(status).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.JSON(result)
+}
+
+// ListOrphanBranches returns the branches of a repository that have commits but no worktree
+// @Summary List orphan branches
+// @Description Lists the branches with commits ahead of the repository's default branch that no worktree has checked out, such as branches created from a terminal, most recently committed first. Branches in catnip's namespace (refs/catnip/ and catnip/) are always listed, others only when committed to within CATNIP_ORPHAN_BRANCH_DAYS (default 7). Repositories are also scanned every 15 minutes, raising an orphan-branches health warning; nothing is adopted without a call to adopt.
+// @Tags git
+// @Produce json
+// @Param id path string true "Repository ID"
+// @Success 200 {array} models.OrphanBranch
+// @Failure 404 {object} map[string]string "Repository not found"
+// @Router /v1/git/repositories/{id}/orphan-branches [get]
+func (h *GitHandler) ListOrphanBranches(c *fiber.Ctx) error {
+	repoID, err := url.QueryUnescape(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid repository ID: " + err.Error(),
+		})
+	}
+
+	orphans, err := h.gitService.GetOrphanBranches(repoID)
+	if err != nil {
+		status := 400
+		if strings.Contains(err.Error(), "not found") {
+			status = 404
+		}
+		return c.Status(status).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.JSON(orphans)
+}
+
+// AdoptBranchRequest names an orphan branch to adopt
+type AdoptBranchRequest struct {
+	// Branch name or full ref, as listed by the orphan branches
+	Branch string `json:"branch" example:"feature/retry-uploads"`
+}
+
+// AdoptOrphanBranch creates a worktree checked out on an orphan branch
+// @Summary Adopt orphan branch
+// @Description Creates a worktree checked out on one of the repository's orphan branches, keeping the branch and its commits, so work started outside catnip can be managed again. The branch has to be listed by the orphan branches endpoint.
+// @Tags git
+// @Accept json
+// @Produce json
+// @Param id path string true "Repository ID"
+// @Param request body AdoptBranchRequest true "Branch to adopt"
+// @Param source query string false "Where the request comes from: ui, tui, api (default) or auto"
+// @Success 200 {object} models.Worktree
+// @Failure 400 {object} map[string]string "Not an orphan branch"
+// @Failure 404 {object} map[string]string "Repository not found"
+// @Router /v1/git/repositories/{id}/orphan-branches/adopt [post]
+func (h *GitHandler) AdoptOrphanBranch(c *fiber.Ctx) error {
+	repoID, err := url.QueryUnescape(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid repository ID: " + err.Error(),
+		})
+	}
+	var req AdoptBranchRequest
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(400).JSON(fiber.Map{
 			"error": "Invalid request body",
 		})
 	}
+	if req.Branch == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "branch is required",
+		})
+	}
+	source, err := services.ParseCreationSource(c.Query("source"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	worktree, err := h.gitService.AdoptBranch(repoID, req.Branch, &models.CreationContext{Source: source, Actor: GetActor(c)})
+	if err != nil {
+		status := 400
+		if strings.HasPrefix(err.Error(), "repository ") && strings.HasSuffix(err.Error(), " not found") {
+			status = 404
+		}
+		return c.Status(diskSpaceStatus(err, status)).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.JSON(worktree)
+}
+
+// RepairWorktreeRemoteConfig restores the remote and credential git config seen by a worktree
+// @Summary Repair worktree remote config
+// @Description Repairs drift in the repository's shared config and the worktree specific config of a single worktree. Unrelated config keys are left untouched.
+// @Tags git
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Success 200 {object} services.RemoteConfigReport
+// @Failure 400 {object} map[string]string "Invalid request"
+// @Failure 500 {object} map[string]string "Repair failed"
+// @Router /v1/git/worktrees/{id}/remote-config/repair [post]
+func (h *GitHandler) RepairWorktreeRemoteConfig(c *fiber.Ctx) error {
+	worktreeID := c.Params("id")
+
+	if _, exists := h.gitService.GetWorktree(worktreeID); !exists {
+		return c.Status(400).JSON(fiber.Map{
+			"error": fmt.Sprintf("worktree %s not found", worktreeID),
+		})
+	}
 
-	pr, err := h.gitService.CreatePullRequest(worktreeID, req.Title, req.Body, req.ForcePush)
+	report, err := h.gitService.RepairRemoteConfig(worktreeID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(report)
+}
+
+// AcknowledgeBranchDrift accepts a branch switch made outside catnip
+// @Summary Acknowledge worktree branch drift
+// @Description Adopts the branch currently checked out in a worktree flagged for manual intervention, unblocking pull requests and merges
+// @Tags git
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Success 200 {object} models.Worktree
+// @Failure 404 {object} map[string]string "Worktree not found"
+// @Failure 400 {object} map[string]string "Nothing to acknowledge"
+// @Router /v1/git/worktrees/{id}/branch-drift/acknowledge [post]
+func (h *GitHandler) AcknowledgeBranchDrift(c *fiber.Ctx) error {
+	worktreeID := c.Params("id")
+
+	if _, exists := h.gitService.GetWorktree(worktreeID); !exists {
+		return c.Status(404).JSON(fiber.Map{
+			"error": fmt.Sprintf("worktree %s not found", worktreeID),
+		})
+	}
+
+	worktree, err := h.gitService.AcknowledgeBranchDrift(worktreeID)
 	if err != nil {
 		return c.Status(400).JSON(fiber.Map{
 			"error": err.Error(),
 		})
 	}
 
-	return c.JSON(pr)
+	return c.JSON(worktree)
 }
 
-// UpdatePullRequest updates an existing pull request for a worktree
-// @Summary Update pull request
-// @Description Updates an existing pull request for a worktree branch
+// GetWorktreeHealth reports everything known to be wrong with a worktree
+// @Summary Check worktree health
+// @Description Lists the problems of a worktree (missing directory, conflicts, branch drift, remote config drift, failing mirrors, diverged upstream, stale base, failed validation, low disk space, ...) with their severity and, where one exists, the remediation action that fixes them: recreate, repair-remote-config, acknowledge-branch-drift, sync, validate or trim-caches
+// @Tags git
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Success 200 {object} models.WorktreeHealth
+// @Failure 404 {object} map[string]string "Worktree not found"
+// @Router /v1/git/worktrees/{id}/health [get]
+func (h *GitHandler) GetWorktreeHealth(c *fiber.Ctx) error {
+	health, err := h.gitService.CheckWorktreeHealth(c.Params("id"))
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(health)
+}
+
+// SetWorktreeCheckpointConfig enables or disables checkpoint commits for a worktree
+// @Summary Set worktree checkpoint configuration
+// @Description Turns the automatic checkpoint and title change commits of a worktree on or off and sets the seconds between checkpoints (0 for the default). Session titles are tracked either way.
 // @Tags git
 // @Accept json
 // @Produce json
 // @Param id path string true "Worktree ID"
-// @Param request body CreatePullRequestRequest true "Pull request details"
-// @Success 200 {object} models.PullRequestResponse
-// @Router /v1/git/worktrees/{id}/pr [put]
-func (h *GitHandler) UpdatePullRequest(c *fiber.Ctx) error {
+// @Param body body models.CheckpointConfig true "Checkpoint configuration"
+// @Success 200 {object} models.Worktree
+// @Failure 400 {object} map[string]string "Invalid configuration"
+// @Failure 404 {object} map[string]string "Worktree not found"
+// @Router /v1/git/worktrees/{id}/checkpoint-config [put]
+func (h *GitHandler) SetWorktreeCheckpointConfig(c *fiber.Ctx) error {
 	worktreeID := c.Params("id")
 
-	var req CreatePullRequestRequest
+	if _, exists := h.gitService.GetWorktree(worktreeID); !exists {
+		return c.Status(404).JSON(fiber.Map{
+			"error": fmt.Sprintf("worktree %s not found", worktreeID),
+		})
+	}
+
+	var cfg models.CheckpointConfig
+	if err := c.BodyParser(&cfg); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if err := h.gitService.SetWorktreeCheckpointConfig(worktreeID, cfg); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	worktree, _ := h.gitService.GetWorktree(worktreeID)
+	return c.JSON(worktree)
+}
+
+// WorktreeHandoffRequest hands a worktree off to a human or back to catnip
+type WorktreeHandoffRequest struct {
+	// true to hand off, false to resume automation
+	HandedOff bool `json:"handed_off" example:"true"`
+	// Create or update the preview branch as the handoff artifact (local repositories only)
+	Preview bool `json:"preview,omitempty" example:"true"`
+}
+
+// SetWorktreeHandoff marks a worktree as handed off to a human, or hands it back
+// @Summary Hand a worktree off to a human
+// @Description Handing off commits a final checkpoint of uncommitted work, optionally creates or updates the preview branch of a local repository, and records who took over and when. Until handed back, checkpoints, automatic branch renames and commit syncing stop and Claude's title events are ignored; operations triggered through the API still work. Handing back resumes automation with fresh timers and refreshes the status. Updating the preview branch of a protected repository needs confirm=true.
+// @Tags git
+// @Accept json
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Param body body WorktreeHandoffRequest true "Handoff state"
+// @Param confirm query bool false "Confirm force pushing the preview branch of a protected repository"
+// @Success 200 {object} models.Worktree
+// @Failure 400 {object} map[string]string "Invalid request or handoff failed"
+// @Failure 404 {object} map[string]string "Worktree not found"
+// @Failure 409 {object} map[string]string "Repository is protected"
+// @Router /v1/git/worktrees/{id}/handoff [put]
+func (h *GitHandler) SetWorktreeHandoff(c *fiber.Ctx) error {
+	worktreeID := c.Params("id")
+
+	var req WorktreeHandoffRequest
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(400).JSON(fiber.Map{
 			"error": "Invalid request body",
 		})
 	}
 
-	pr, err := h.gitService.UpdatePullRequest(worktreeID, req.Title, req.Body, req.ForcePush)
+	if _, err := h.gitService.SetWorktreeHandedOff(worktreeID, req.HandedOff, services.HandoffOptions{
+		Actor:   GetActor(c),
+		Preview: req.Preview,
+		Confirm: c.QueryBool("confirm"),
+	}); err != nil {
+		status := 400
+		if errors.Is(err, services.ErrProtectedRepository) {
+			status = 409
+		} else if strings.Contains(err.Error(), "not found") {
+			status = 404
+		}
+		return c.Status(status).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	worktree, _ := h.gitService.GetWorktree(worktreeID)
+	return c.JSON(worktree)
+}
+
+// RepositorySetupCommandsRequest sets the commands run in new worktrees of a repository
+type RepositorySetupCommandsRequest struct {
+	// Shell commands run in order, replacing those set before; empty turns setup off
+	Commands []string `json:"commands" example:"pnpm install"`
+}
+
+// SetRepositorySetupCommands sets the commands run in every new worktree of a repository
+// @Summary Configure repository setup commands
+// @Description Setup commands run through the shell in every new worktree of the repository after checkout, in order, until one fails, so sessions start with dependencies installed. Worktree creation returns right away with setup_status pending; it moves to running and then succeeded or failed. The output goes to the worktree's setup log with secrets masked. Each command times out after 30 minutes. This runs in addition to a setup.sh in the worktree.
+// @Tags git
+// @Accept json
+// @Produce json
+// @Param id path string true "Repository ID"
+// @Param request body RepositorySetupCommandsRequest true "Setup commands"
+// @Success 200 {object} models.Repository
+// @Failure 400 {object} map[string]string "Invalid request"
+// @Failure 404 {object} map[string]string "Repository not found"
+// @Router /v1/git/repositories/{id}/setup-commands [put]
+func (h *GitHandler) SetRepositorySetupCommands(c *fiber.Ctx) error {
+	repoID, err := url.QueryUnescape(c.Params("id"))
 	if err != nil {
 		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid repository ID: " + err.Error(),
+		})
+	}
+
+	var req RepositorySetupCommandsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid request body: " + err.Error(),
+		})
+	}
+
+	repo, err := h.gitService.SetRepositorySetupCommands(repoID, req.Commands)
+	if err != nil {
+		status := 500
+		if strings.HasSuffix(err.Error(), "not found") {
+			status = 404
+		}
+		return c.Status(status).JSON(fiber.Map{
 			"error": err.Error(),
 		})
 	}
+	return c.JSON(repo)
+}
 
-	return c.JSON(pr)
+// RunWorktreeSetup runs the repository's setup commands in a worktree again
+// @Summary Re-run worktree setup
+// @Description Runs the repository's setup commands in the worktree again in the background, replacing the setup log. Returns the worktree with setup_status pending; poll it or follow worktree events for the outcome.
+// @Tags git
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Success 202 {object} models.Worktree
+// @Failure 400 {object} map[string]string "The repository has no setup commands"
+// @Failure 404 {object} map[string]string "Worktree not found"
+// @Failure 409 {object} map[string]string "Setup is already running"
+// @Router /v1/git/worktrees/{id}/setup [post]
+func (h *GitHandler) RunWorktreeSetup(c *fiber.Ctx) error {
+	worktreeID := c.Params("id")
+
+	if err := h.gitService.RunWorktreeSetup(worktreeID); err != nil {
+		status := 400
+		switch {
+		case errors.Is(err, services.ErrSetupRunning):
+			status = 409
+		case strings.Contains(err.Error(), "not found"):
+			status = 404
+		}
+		return c.Status(status).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	worktree, _ := h.gitService.GetWorktree(worktreeID)
+	return c.Status(202).JSON(worktree)
 }
 
-// GetPullRequestInfo gets information about an existing pull request for a worktree
-// @Summary Get pull request info
-// @Description Gets information about an existing pull request for a worktree branch
+// GetWorktreeSetupLog returns the output of the last setup run of a worktree
+// @Summary Get worktree setup log
+// @Description Returns the output of the last run of the repository's setup commands in the worktree, each command preceded by a $ line, with secrets masked. While setup runs it holds the output so far.
 // @Tags git
+// @Produce text/plain
+// @Param id path string true "Worktree ID"
+// @Success 200 {string} string "Setup output"
+// @Failure 404 {object} map[string]string "Worktree or setup log not found"
+// @Router /v1/git/worktrees/{id}/setup/log [get]
+func (h *GitHandler) GetWorktreeSetupLog(c *fiber.Ctx) error {
+	content, err := h.gitService.WorktreeSetupLog(c.Params("id"))
+	if err != nil {
+		status := 500
+		if strings.Contains(err.Error(), "not found") {
+			status = 404
+		}
+		return c.Status(status).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	c.Set("Content-Type", "text/plain; charset=utf-8")
+	return c.Send(content)
+}
+
+// WorktreeCheckpointHooksRequest sets whether checkpoints of a worktree run commit hooks
+type WorktreeCheckpointHooksRequest struct {
+	// Run the repository's commit hooks, e.g. a formatting pre-commit hook, on checkpoints
+	Enabled bool `json:"enabled" example:"true"`
+}
+
+// SetWorktreeCheckpointHooks sets whether checkpoint commits of a worktree run commit hooks
+// @Summary Run commit hooks on checkpoints
+// @Description Checkpoint commits skip the repository's commit hooks by default. When enabled they run them: files a hook reformats are staged and amended into the checkpoint, and when a hook fails the checkpoint is committed without hooks and the hook output is reported in last_checkpoint_error, so work is never lost. Disabling clears the last error.
+// @Tags git
+// @Accept json
 // @Produce json
 // @Param id path string true "Worktree ID"
-// @Success 200 {object} models.PullRequestInfo
-// @Router /v1/git/worktrees/{id}/pr [get]
-func (h *GitHandler) GetPullRequestInfo(c *fiber.Ctx) error {
+// @Param body body WorktreeCheckpointHooksRequest true "Hook setting"
+// @Success 200 {object} models.Worktree
+// @Failure 400 {object} map[string]string "Invalid request"
+// @Failure 404 {object} map[string]string "Worktree not found"
+// @Router /v1/git/worktrees/{id}/checkpoint-hooks [put]
+func (h *GitHandler) SetWorktreeCheckpointHooks(c *fiber.Ctx) error {
 	worktreeID := c.Params("id")
 
-	prInfo, err := h.gitService.GetPullRequestInfo(worktreeID)
-	if err != nil {
+	var req WorktreeCheckpointHooksRequest
+	if err := c.BodyParser(&req); err != nil {
 		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if err := h.gitService.SetWorktreeCheckpointHooks(worktreeID, req.Enabled); err != nil {
+		status := 400
+		if strings.Contains(err.Error(), "not found") {
+			status = 404
+		}
+		return c.Status(status).JSON(fiber.Map{
 			"error": err.Error(),
 		})
 	}
 
-	return c.JSON(prInfo)
+	worktree, _ := h.gitService.GetWorktree(worktreeID)
+	return c.JSON(worktree)
 }
 
-// GraduateBranchRequest represents the request to graduate a branch
-type GraduateBranchRequest struct {
-	// Optional custom branch name to graduate to
-	BranchName string `json:"branch_name,omitempty" example:"feature/add-auth"`
+// WorktreeSecretKeysRequest declares the environment variables holding secrets for a worktree
+type WorktreeSecretKeysRequest struct {
+	// Environment variable names whose values are masked, replacing those declared before
+	Keys []string `json:"keys" example:"STRIPE_LIVE,DATABASE_URL"`
 }
 
-// GraduateBranch manually triggers renaming of a branch to a semantic name
-// @Summary Rename branch
-// @Description Triggers renaming of any branch to a semantic name using Claude or a custom name
+// SetWorktreeSecretKeys declares which environment variables of a worktree hold secrets
+// @Summary Declare worktree secret keys
+// @Description Values of environment variables with sensitive names (containing TOKEN, SECRET, PASSWORD, API_KEY and the like, plus the substrings in CATNIP_REDACT_KEYS) and well-known token formats are always masked. This declares further variables whose values are secret for the worktree. Masked secrets keep their first 4 characters followed by [REDACTED] in validation output, setup logs, the activity log, the operation journal, worktree events and context packs. An empty list clears the declared keys.
 // @Tags git
 // @Accept json
 // @Produce json
 // @Param id path string true "Worktree ID"
-// @Param request body GraduateBranchRequest false "Graduation request with optional custom branch name"
-// @Success 200 {object} map[string]string
-// @Failure 400 {object} map[string]string "Bad request (invalid branch name, branch already exists, etc.)"
+// @Param body body WorktreeSecretKeysRequest true "Secret keys"
+// @Success 200 {object} models.Worktree
+// @Failure 400 {object} map[string]string "Invalid request"
 // @Failure 404 {object} map[string]string "Worktree not found"
-// @Failure 422 {object} map[string]string "No title available for automatic naming"
-// @Failure 500 {object} map[string]string "Internal server error"
-// @Router /v1/git/worktrees/{id}/graduate [post]
-func (h *GitHandler) GraduateBranch(c *fiber.Ctx) error {
+// @Router /v1/git/worktrees/{id}/secret-keys [put]
+func (h *GitHandler) SetWorktreeSecretKeys(c *fiber.Ctx) error {
 	worktreeID := c.Params("id")
 
-	// Parse request body (optional)
-	var req GraduateBranchRequest
-	_ = c.BodyParser(&req) // Don't fail if body is empty
-
-	// Find the worktree to get its path
-	worktrees := h.gitService.ListWorktrees()
-	var workDir string
-	for _, worktree := range worktrees {
-		if worktree.ID == worktreeID {
-			workDir = worktree.Path
-			break
-		}
-	}
-
-	if workDir == "" {
-		return c.Status(404).JSON(fiber.Map{
-			"error": "Worktree not found",
+	var req WorktreeSecretKeysRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid request body",
 		})
 	}
 
-	// If custom branch name is provided, handle directly
-	if req.BranchName != "" {
-		// Get current branch name using public ExecuteGit method
-		output, err := h.gitService.ExecuteGit(workDir, "rev-parse", "--symbolic-full-name", "HEAD")
-		if err != nil {
-			return c.Status(500).JSON(fiber.Map{
-				"error": "Failed to get current branch name: " + err.Error(),
-			})
-		}
-		currentBranch := strings.TrimSpace(string(output))
-
-		// Validate the custom branch name using git check-ref-format
-		if _, err := h.gitService.ExecuteGit("", "check-ref-format", "refs/heads/"+req.BranchName); err != nil {
-			return c.Status(400).JSON(fiber.Map{
-				"error": "Invalid branch name: " + req.BranchName,
-			})
-		}
-
-		// Check if the new branch already exists
-		if h.gitService.BranchExists(workDir, req.BranchName, false) {
-			return c.Status(400).JSON(fiber.Map{
-				"error": "Branch already exists: " + req.BranchName,
-			})
-		}
-
-		// Create new branch from current HEAD
-		if _, err := h.gitService.ExecuteGit(workDir, "checkout", "-b", req.BranchName); err != nil {
-			return c.Status(500).JSON(fiber.Map{
-				"error": "Failed to create new branch: " + err.Error(),
-			})
-		}
-
-		// Update the worktree branch name in the GitService so the UI reflects the change
-		if err := h.gitService.UpdateWorktreeBranchName(workDir, req.BranchName); err != nil {
-			logger.Warnf("⚠️  Failed to update worktree branch name in service: %v", err)
-			// Don't fail the whole operation for this, but log the error
-		}
-
-		// Delete the old branch reference if it was a catnip ref
-		if strings.HasPrefix(currentBranch, "refs/catnip/") {
-			if _, err := h.gitService.ExecuteGit(workDir, "update-ref", "-d", currentBranch); err != nil {
-				// Log but don't fail - the new branch was created successfully
-				// This is just cleanup of the old catnip ref
-				logger.Warnf("⚠️  Failed to delete old catnip ref %q: %v", currentBranch, err)
-			}
-		}
-	} else {
-		// For automatic naming, use the Claude monitor service
-		if h.claudeMonitor == nil {
-			return c.Status(500).JSON(fiber.Map{
-				"error": "Claude monitor service not available",
-			})
+	if err := h.gitService.SetWorktreeSecretKeys(worktreeID, req.Keys); err != nil {
+		status := 400
+		if strings.Contains(err.Error(), "not found") {
+			status = 404
 		}
+		return c.Status(status).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
 
-		// Trigger branch graduation via Claude monitor
-		if err := h.claudeMonitor.TriggerBranchRename(workDir, req.BranchName); err != nil {
-			// Check for specific error types to return appropriate status codes
-			errMsg := err.Error()
+	worktree, _ := h.gitService.GetWorktree(worktreeID)
+	return c.JSON(worktree)
+}
 
-			// No title available for automatic naming
-			if strings.Contains(errMsg, "no title available") {
-				return c.Status(422).JSON(fiber.Map{
-					"error": errMsg,
-					"code":  "NO_TITLE_AVAILABLE",
-				})
-			}
+// GetWorktreeDebugTimeline returns the recent title event and checkpoint decisions of a worktree
+// @Summary Get worktree debug timeline
+// @Description Returns the last 500 decisions the Claude monitor made for a worktree, oldest first: title events with their source and whether they were accepted, deduplicated or suppressed, title change commits, checkpoint timer fires (no changes, the committed hash or the error) and branch graduation attempts, with the reason for anything suppressed or skipped. Meant for answering why a worktree did or didn't commit. Decisions are kept in memory and lost on restart.
+// @Tags git
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Param since query string false "Only return decisions after this time, RFC 3339"
+// @Success 200 {array} models.DebugTimelineEntry
+// @Failure 400 {object} map[string]string "Invalid since"
+// @Failure 404 {object} map[string]string "Worktree not found"
+// @Router /v1/git/worktrees/{id}/debug/timeline [get]
+func (h *GitHandler) GetWorktreeDebugTimeline(c *fiber.Ctx) error {
+	worktreeID := c.Params("id")
 
-			// Not a catnip branch, invalid branch name, branch already exists, etc.
+	var since time.Time
+	if value := c.Query("since"); value != "" {
+		var err error
+		if since, err = time.Parse(time.RFC3339, value); err != nil {
 			return c.Status(400).JSON(fiber.Map{
-				"error": errMsg,
+				"error": "Invalid since: " + err.Error(),
 			})
 		}
 	}
 
-	response := fiber.Map{
-		"message": "Branch rename triggered successfully",
+	worktree, exists := h.gitService.GetWorktree(worktreeID)
+	if !exists {
+		return c.Status(404).JSON(fiber.Map{
+			"error": fmt.Sprintf("worktree %s not found", worktreeID),
+		})
 	}
-
-	if req.BranchName != "" {
-		response["branch_name"] = req.BranchName
-		response["method"] = "custom"
-	} else {
-		response["method"] = "claude_generated"
+	if h.claudeMonitor == nil {
+		return c.JSON([]models.DebugTimelineEntry{})
 	}
-
-	return c.JSON(response)
+	return c.JSON(h.claudeMonitor.GetDebugTimeline(worktree.Path, since))
 }
 
-// RefreshWorktreeStatus forces a refresh of a worktree's cached status
-// @Summary Force refresh worktree status
-// @Description Forces an immediate refresh of a worktree's cached status including commit counts
+// DetectWorktreeToolchains re-runs toolchain detection for a worktree
+// @Summary Detect worktree toolchains
+// @Description Inspects the manifest files of a worktree (package.json, go.mod, Cargo.toml, pyproject.toml, ...) and records the detected toolchains. Only the filesystem is read.
 // @Tags git
 // @Produce json
 // @Param id path string true "Worktree ID"
-// @Success 200 {object} map[string]string
-// @Router /v1/git/worktrees/{id}/refresh [post]
-func (h *GitHandler) RefreshWorktreeStatus(c *fiber.Ctx) error {
+// @Success 200 {array} models.Toolchain
+// @Failure 404 {object} map[string]string "Worktree not found"
+// @Failure 500 {object} map[string]string "Detection failed"
+// @Router /v1/git/worktrees/{id}/toolchains/detect [post]
+func (h *GitHandler) DetectWorktreeToolchains(c *fiber.Ctx) error {
 	worktreeID := c.Params("id")
 
-	// Force refresh by calling the git service method that recalculates status
-	if err := h.gitService.RefreshWorktreeStatusByID(worktreeID); err != nil {
-		return c.Status(400).JSON(fiber.Map{
+	if _, exists := h.gitService.GetWorktree(worktreeID); !exists {
+		return c.Status(404).JSON(fiber.Map{
+			"error": fmt.Sprintf("worktree %s not found", worktreeID),
+		})
+	}
+
+	toolchains, err := h.gitService.DetectWorktreeToolchains(worktreeID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
 			"error": err.Error(),
 		})
 	}
+	if toolchains == nil {
+		toolchains = []models.Toolchain{}
+	}
 
-	return c.JSON(fiber.Map{
-		"message": "Worktree status refreshed successfully",
-		"id":      worktreeID,
-	})
+	return c.JSON(toolchains)
 }
 
-// CreateTemplateRequest defines the request body for creating from template
-type CreateTemplateRequest struct {
-	TemplateID  string `json:"template_id" binding:"required"`
-	ProjectName string `json:"project_name" binding:"required"`
+// GetDependencyCaches reports the size of the package caches shared across worktrees
+// @Summary Get shared package caches
+// @Description Measures the npm, pnpm, cargo, go and pip caches shared by worktree sessions and setup scripts, against the cap set with git config catnip.cache.max-size-mb
+// @Tags git
+// @Produce json
+// @Success 200 {object} models.DependencyCacheReport
+// @Router /v1/git/caches [get]
+func (h *GitHandler) GetDependencyCaches(c *fiber.Ctx) error {
+	return c.JSON(services.DependencyCacheStatus(services.DependencyCacheRoot(), services.GlobalDependencyCacheMaxSizeKB()))
 }
 
-// CreateFromTemplate creates a new workspace from a project template
-// @Summary Create workspace from template
-// @Description Creates a new Git repository and workspace from a predefined project template
+// TrimDependencyCaches shrinks the shared package caches below their cap
+// @Summary Trim shared package caches
+// @Description Removes the least recently used entries of the shared package caches until they fit the cap. Entries used within the last hour are kept. Caches are also trimmed every 6 hours.
+// @Tags git
+// @Produce json
+// @Success 200 {object} models.DependencyCacheReport
+// @Router /v1/git/caches/trim [post]
+func (h *GitHandler) TrimDependencyCaches(c *fiber.Ctx) error {
+	return c.JSON(services.TrimDependencyCaches(services.DependencyCacheRoot(), services.GlobalDependencyCacheMaxSizeKB()))
+}
+
+// ApplyIgnoreSuggestion ignores a directory that keeps showing up in checkpoints
+// @Summary Apply worktree ignore suggestion
+// @Description Ignores a suggested directory, in the info/exclude of the repository catnip cloned (catnipignore, default) or the worktree's .gitignore, and stops tracking its files. catnipignore is refused for local repositories, whose info/exclude belongs to the user's checkout. With squash the branch is squashed into one commit so the directory leaves its history, after backing up its HEAD (backup); refused when the worktree has a pull request.
 // @Tags git
 // @Accept json
 // @Produce json
-// @Param request body CreateTemplateRequest true "Template creation request"
-// @Success 200 {object} map[string]string
-// @Failure 400 {object} map[string]string "Invalid request or template not found"
-// @Failure 500 {object} map[string]string "Internal server error"
-// @Router /v1/git/template [post]
-func (h *GitHandler) CreateFromTemplate(c *fiber.Ctx) error {
-	var req CreateTemplateRequest
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "Invalid request body: " + err.Error(),
-		})
-	}
+// @Param id path string true "Worktree ID"
+// @Param body body map[string]interface{} true "pattern, target (catnipignore or gitignore) and squash"
+// @Success 200 {object} IgnoreSuggestionResponse
+// @Failure 404 {object} map[string]string "Worktree not found"
+// @Failure 400 {object} map[string]string "Invalid request"
+// @Router /v1/git/worktrees/{id}/ignore-suggestions/apply [post]
+func (h *GitHandler) ApplyIgnoreSuggestion(c *fiber.Ctx) error {
+	worktreeID := c.Params("id")
 
-	// Validate template ID
-	if req.TemplateID == "" {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "template_id is required",
+	if _, exists := h.gitService.GetWorktree(worktreeID); !exists {
+		return c.Status(404).JSON(fiber.Map{
+			"error": fmt.Sprintf("worktree %s not found", worktreeID),
 		})
 	}
 
-	// Validate project name
-	if req.ProjectName == "" {
+	var applyRequest struct {
+		Pattern string `json:"pattern"`
+		Target  string `json:"target"`
+		Squash  bool   `json:"squash"`
+	}
+	if err := c.BodyParser(&applyRequest); err != nil || applyRequest.Pattern == "" {
 		return c.Status(400).JSON(fiber.Map{
-			"error": "project_name is required",
+			"error": "pattern is required",
 		})
 	}
 
-	// Create project from template
-	repo, worktree, err := h.gitService.CreateFromTemplate(req.TemplateID, req.ProjectName)
+	worktree, backup, err := h.gitService.ApplyIgnoreSuggestion(worktreeID, applyRequest.Pattern, services.IgnoreTarget(applyRequest.Target), applyRequest.Squash)
 	if err != nil {
 		return c.Status(400).JSON(fiber.Map{
 			"error": err.Error(),
 		})
 	}
 
-	// Return success response with repository information
-	response := fiber.Map{
-		"success": true,
-		"repo_id": repo.ID,
-		"path":    repo.Path,
-		"message": fmt.Sprintf("Successfully created project %s from template %s", req.ProjectName, req.TemplateID),
-	}
-
-	// Include worktree info if one was created
-	if worktree != nil {
-		response["worktree"] = worktree.ID
-		response["worktree_path"] = worktree.Path
-		response["worktree_name"] = worktree.Name
-	}
-
-	return c.JSON(response)
+	return c.JSON(IgnoreSuggestionResponse{Worktree: worktree, Backup: backup})
 }
 
-// CreateGitHubRepository creates a GitHub repository and sets it as origin for a local repo
-// @Summary Create GitHub repository
-// @Description Creates a new GitHub repository and sets it as the origin for a local repository
+// DismissIgnoreSuggestion hides an ignore suggestion
+// @Summary Dismiss worktree ignore suggestion
+// @Description Removes an ignore suggestion; the directory is not suggested again for the worktree
 // @Tags git
 // @Accept json
 // @Produce json
-// @Param id path string true "Repository ID"
-// @Param request body CreateGitHubRepositoryRequest true "Repository creation request"
-// @Success 200 {object} CreateGitHubRepositoryResponse
+// @Param id path string true "Worktree ID"
+// @Param body body map[string]string true "pattern"
+// @Success 200 {object} map[string]string
+// @Failure 404 {object} map[string]string "Worktree not found"
 // @Failure 400 {object} map[string]string "Invalid request"
-// @Failure 500 {object} map[string]string "Internal server error"
-// @Router /v1/git/repositories/{id}/github [post]
-func (h *GitHandler) CreateGitHubRepository(c *fiber.Ctx) error {
-	repoID := c.Params("id")
-	logger.Infof("🔍 CreateGitHubRepository called with repoID: '%s'", repoID)
+// @Router /v1/git/worktrees/{id}/ignore-suggestions/dismiss [post]
+func (h *GitHandler) DismissIgnoreSuggestion(c *fiber.Ctx) error {
+	worktreeID := c.Params("id")
 
-	// URL decode the repository ID
-	decodedRepoID, err := url.QueryUnescape(repoID)
-	if err != nil {
-		logger.Errorf("❌ Failed to URL decode repoID '%s': %v", repoID, err)
-		return c.Status(400).JSON(fiber.Map{
-			"error": "Invalid repository ID: " + err.Error(),
+	if _, exists := h.gitService.GetWorktree(worktreeID); !exists {
+		return c.Status(404).JSON(fiber.Map{
+			"error": fmt.Sprintf("worktree %s not found", worktreeID),
 		})
 	}
-	repoID = decodedRepoID
-	logger.Infof("🔍 Decoded repoID: '%s'", repoID)
 
-	var req CreateGitHubRepositoryRequest
-	if err := c.BodyParser(&req); err != nil {
-		logger.Errorf("❌ Failed to parse request body: %v", err)
-		return c.Status(400).JSON(fiber.Map{
-			"error": "Invalid request body: " + err.Error(),
-		})
+	var dismissRequest struct {
+		Pattern string `json:"pattern"`
 	}
-
-	logger.Infof("📝 Request parsed: name='%s', description='%s', isPrivate=%v", req.Name, req.Description, req.IsPrivate)
-
-	// Validate request
-	if req.Name == "" {
-		logger.Errorf("❌ Repository name is required")
+	if err := c.BodyParser(&dismissRequest); err != nil || dismissRequest.Pattern == "" {
 		return c.Status(400).JSON(fiber.Map{
-			"error": "name is required",
+			"error": "pattern is required",
 		})
 	}
 
-	// Create GitHub repository and update origin
-	logger.Infof("🚀 Calling CreateGitHubRepositoryAndSetOrigin with repoID: '%s'", repoID)
-	repoURL, err := h.gitService.CreateGitHubRepositoryAndSetOrigin(repoID, req.Name, req.Description, req.IsPrivate)
-	if err != nil {
-		return c.Status(500).JSON(fiber.Map{
+	if err := h.gitService.DismissIgnoreSuggestion(worktreeID, dismissRequest.Pattern); err != nil {
+		return c.Status(400).JSON(fiber.Map{
 			"error": err.Error(),
 		})
 	}
 
-	return c.JSON(CreateGitHubRepositoryResponse{
-		URL:     repoURL,
-		Message: "Repository created and origin updated successfully",
+	return c.JSON(fiber.Map{
+		"message": fmt.Sprintf("Dismissed ignore suggestion %s", dismissRequest.Pattern),
 	})
 }
 
-// DeleteRepository removes a repository and all its worktrees
+// DeleteRepository removes a repository
 // @Summary Delete repository
-// @Description Removes a repository and all its associated worktrees from disk and state management
+// @Description Removes a repository from disk and state management. While the repository has worktrees the deletion is refused with 409 repository_in_use listing them, unless force is set, which deletes them first. Local repositories are mounted, not cloned, so they are only forgotten and their directory is left alone.
 // @Tags git
 // @Produce json
 // @Param id path string true "Repository ID"
+// @Param force query bool false "Delete the repository's worktrees too"
 // @Success 200 {object} map[string]interface{}
 // @Failure 400 {object} map[string]string "Repository not found or deletion failed"
+// @Failure 409 {object} map[string]interface{} "Repository has worktrees"
 // @Failure 500 {object} map[string]string "Internal server error"
 // @Router /v1/git/repositories/{id} [delete]
 func (h *GitHandler) DeleteRepository(c *fiber.Ctx) error {
@@ -1041,7 +4407,16 @@ func (h *GitHandler) DeleteRepository(c *fiber.Ctx) error {
 	logger.Infof("🗑️ Decoded repoID: '%s'", repoID)
 
 	// Delete the repository
-	if err := h.gitService.DeleteRepository(repoID); err != nil {
+	if err := h.gitService.DeleteRepository(repoID, c.QueryBool("force")); err != nil {
+		var inUseErr *services.RepositoryInUseError
+		if errors.As(err, &inUseErr) {
+			return c.Status(409).JSON(fiber.Map{
+				"error":     "repository_in_use",
+				"message":   inUseErr.Error(),
+				"repo_id":   inUseErr.RepoID,
+				"worktrees": inUseErr.Worktrees,
+			})
+		}
 		logger.Errorf("❌ Failed to delete repository '%s': %v", repoID, err)
 		return c.Status(400).JSON(fiber.Map{
 			"error": err.Error(),
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/vanpelt/catnip/internal/git"
+	"github.com/vanpelt/catnip/internal/recovery"
+	"github.com/vanpelt/catnip/internal/services"
+)
+
+// PanicReport describes the last panic recovered in a subsystem. /health is unauthenticated, so
+// the panic message and stack trace are only logged.
+type PanicReport struct {
+	Goroutine string    `json:"goroutine"`
+	At        time.Time `json:"at"`
+}
+
+// SubsystemStatus is the health of a subsystem whose goroutines panicked
+type SubsystemStatus struct {
+	Panics    int          `json:"panics"`
+	LastPanic *PanicReport `json:"last_panic,omitempty"`
+	// Credential helper setup (git subsystem only)
+	Credentials *git.CredentialHelperReport `json:"credentials,omitempty"`
+}
+
+// HealthHandler serves the health endpoint and tracks the panics recovered in background goroutines
+type HealthHandler struct {
+	mu         sync.RWMutex
+	subsystems map[string]*SubsystemStatus
+	events     *EventsHandler
+	gitService *services.GitService
+}
+
+// NewHealthHandler creates a health handler
+func NewHealthHandler() *HealthHandler {
+	return &HealthHandler{
+		subsystems: make(map[string]*SubsystemStatus),
+	}
+}
+
+// WithEvents attaches an events handler for broadcasting panics
+func (h *HealthHandler) WithEvents(events *EventsHandler) *HealthHandler {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.events = events
+	return h
+}
+
+// WithGitService attaches the git service whose credential helper setup is reported
+func (h *HealthHandler) WithGitService(gitService *services.GitService) *HealthHandler {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.gitService = gitService
+	return h
+}
+
+// RecordPanic is a recovery.PanicHook counting panics per subsystem and broadcasting them
+func (h *HealthHandler) RecordPanic(name string, recovered interface{}, _ []byte) {
+	subsystem := recovery.Subsystem(name)
+	message := fmt.Sprint(recovered)
+
+	h.mu.Lock()
+	status := h.subsystems[subsystem]
+	if status == nil {
+		status = &SubsystemStatus{}
+		h.subsystems[subsystem] = status
+	}
+	status.Panics++
+	status.LastPanic = &PanicReport{Goroutine: name, At: time.Now()}
+	panics := status.Panics
+	events := h.events
+	h.mu.Unlock()
+
+	if events != nil {
+		events.EmitSystemPanic(subsystem, name, message, panics)
+	}
+}
+
+// GetHealth reports that the server is up, with the subsystems whose goroutines panicked and
+// the free space on the workspace volume
+// @Summary Health check
+// @Description Returns ok while the server is up. Subsystems lists, per subsystem whose background goroutines panicked since startup, the panic count and the goroutine and time of the last panic; panic messages and stack traces are only written to the logs. The git subsystem also reports the credential helper setup verified at startup or by the last repair. Disk reports free and total bytes of the workspace volume and the disk guard state; while the volume is below the warning threshold (CATNIP_DISK_WARNING_MB) its warning is also listed in warnings. Watches reports the file watches held per subsystem, the inotify limits and the worktrees polled instead of watched; while any are, guidance on raising the limits is listed in warnings.
+// @Tags health
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /health [get]
+func (h *HealthHandler) GetHealth(c *fiber.Ctx) error {
+	h.mu.RLock()
+	subsystems := make(map[string]SubsystemStatus, len(h.subsystems))
+	for name, status := range h.subsystems {
+		subsystems[name] = *status
+	}
+	gitService := h.gitService
+	h.mu.RUnlock()
+
+	watches := services.FileWatchStatus()
+	var warnings []string
+	if watches.Guidance != "" {
+		warnings = append(warnings, watches.Guidance)
+	}
+	response := fiber.Map{
+		"status":     "ok",
+		"subsystems": subsystems,
+		"watches":    watches,
+	}
+	if gitService != nil {
+		if credentials := gitService.CredentialHelperStatus(); credentials != nil {
+			status := subsystems["git"]
+			status.Credentials = credentials
+			subsystems["git"] = status
+		}
+
+		disk := gitService.DiskStatus()
+		response["disk"] = disk
+		if disk.Warning != "" {
+			warnings = append(warnings, disk.Warning)
+		}
+	}
+	if len(warnings) > 0 {
+		response["warnings"] = warnings
+	}
+
+	return c.JSON(response)
+}
@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthLeavesPanicDetailsOut(t *testing.T) {
+	health := NewHealthHandler()
+	health.RecordPanic("git:fetch", "token ghp_secret leaked", []byte("goroutine 7 [running]:\nmain.fetch()"))
+	health.RecordPanic("git:fetch", "again", []byte("goroutine 8 [running]:"))
+
+	app := fiber.New()
+	app.Get("/health", health.GetHealth)
+	resp, err := app.Test(httptest.NewRequest("GET", "/health", nil))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var body struct {
+		Subsystems map[string]map[string]interface{} `json:"subsystems"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	git := body.Subsystems["git"]
+	require.NotNil(t, git)
+	assert.EqualValues(t, 2, git["panics"])
+	lastPanic, ok := git["last_panic"].(map[string]interface{})
+	require.True(t, ok)
+	assert.Equal(t, "git:fetch", lastPanic["goroutine"])
+	assert.NotEmpty(t, lastPanic["at"])
+	assert.NotContains(t, lastPanic, "message")
+	assert.NotContains(t, lastPanic, "stack")
+}
@@ -23,6 +23,7 @@ import (
 	"github.com/gofiber/websocket/v2"
 	gorilla_websocket "github.com/gorilla/websocket"
 	"github.com/vanpelt/catnip/internal/assets"
+	"github.com/vanpelt/catnip/internal/config"
 	"github.com/vanpelt/catnip/internal/recovery"
 	"github.com/vanpelt/catnip/internal/services"
 )
@@ -157,6 +158,20 @@ func rewriteLocalhostPort(val string, basePath string) string {
 		newPath += "#" + u.Fragment
 	}
 
+	// Behind a reverse proxy, point at the external URL instead of localhost
+	if external, err := url.Parse(config.Runtime.BaseURL); err == nil && config.Runtime.BaseURL != "" && external.Host != "" {
+		scheme := u.Scheme
+		if external.Scheme == "https" {
+			switch scheme {
+			case "http":
+				scheme = "https"
+			case "ws":
+				scheme = "wss"
+			}
+		}
+		return fmt.Sprintf("%s://%s%s%s", scheme, external.Host, config.Runtime.BasePath(), newPath)
+	}
+
 	// Return the rewritten URL
 	return fmt.Sprintf("%s://localhost:8080%s", u.Scheme, newPath)
 }
@@ -281,7 +296,7 @@ func (h *ProxyHandler) ProxyToPort(c *fiber.Ctx) error {
 	if strings.Contains(strings.ToLower(contentType), "javascript") ||
 		strings.Contains(strings.ToLower(contentType), "application/javascript") ||
 		strings.Contains(strings.ToLower(contentType), "text/javascript") {
-		c.Response().Header.Set("Service-Worker-Allowed", fmt.Sprintf("/%d/", port))
+		c.Response().Header.Set("Service-Worker-Allowed", fmt.Sprintf("%s/%d/", config.Runtime.BasePath(), port))
 	}
 
 	// Read response body and handle decompression if needed
@@ -341,7 +356,7 @@ func (h *ProxyHandler) ProxyToPort(c *fiber.Ctx) error {
 
 // modifyHTMLContent injects base tag and JavaScript to handle SPA routing
 func (h *ProxyHandler) modifyHTMLContent(content string, port int) string {
-	basePath := fmt.Sprintf("/%d/", port)
+	basePath := fmt.Sprintf("%s/%d/", config.Runtime.BasePath(), port)
 
 	// Rewrite absolute paths in HTML content
 	content = rewriteHTMLAbsolutePaths(content, basePath)
@@ -407,7 +422,7 @@ func (h *ProxyHandler) modifyHTMLContent(content string, port int) string {
 
 // modifyJavaScriptContent rewrites import paths and other absolute paths in JavaScript content
 func (h *ProxyHandler) modifyJavaScriptContent(content string, port int) string {
-	basePath := fmt.Sprintf("/%d", port)
+	basePath := fmt.Sprintf("%s/%d", config.Runtime.BasePath(), port)
 
 	// Regex patterns to match various import and path patterns in JavaScript
 	patterns := []struct {
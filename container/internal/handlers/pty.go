@@ -5,6 +5,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -50,8 +51,19 @@ type ControlMessage struct {
 	Cols    uint16 `json:"cols,omitempty"`
 	Rows    uint16 `json:"rows,omitempty"`
 	Focused bool   `json:"focused,omitempty"`
+	ConnID  string `json:"connId,omitempty"`  // Requesting connection for control-response
+	Granted bool   `json:"granted,omitempty"` // Answer for control-response
 }
 
+// controlRequestMsg asks the current writer to hand over control, or tells an observer its request was denied
+type controlRequestMsg struct {
+	Type   string `json:"type"`
+	ConnID string `json:"connId"`
+}
+
+// controlRequestTimeout is how long a writer has to answer a control request before it is denied
+const controlRequestTimeout = 30 * time.Second
+
 // WebSocketConnection implements PTYConnection for WebSocket connections
 type WebSocketConnection struct {
 	conn *websocket.Conn
@@ -153,6 +165,7 @@ type ConnectionInfo struct {
 	ConnID      string
 	IsReadOnly  bool
 	IsFocused   bool
+	IsObserver  bool   // Attached in observe mode: output only until control is granted
 	ConnType    string // "websocket" or "sse"
 }
 
@@ -170,6 +183,8 @@ type Session struct {
 	ClaudeSessionID string // Track Claude session UUID for resume functionality
 	connections     map[PTYConnection]*ConnectionInfo
 	connMutex       sync.RWMutex
+	// Observer control requests awaiting the writer's answer, keyed by requesting connection ID
+	pendingControlRequests map[string]PTYConnection
 	// Buffer to store PTY output for replay
 	outputBuffer  []byte
 	bufferMutex   sync.RWMutex
@@ -272,18 +287,9 @@ func (h *PTYHandler) isExternalWorkspace(workDir string) bool {
 		return false
 	}
 
-	// Clean both paths for comparison
-	cleanWorkspaceDir := filepath.Clean(workspaceDir)
-	cleanWorkDir := filepath.Clean(workDir)
-
-	// Check if workDir is under workspaceDir
-	relPath, err := filepath.Rel(cleanWorkspaceDir, cleanWorkDir)
-	if err != nil {
-		return true // If we can't determine relationship, assume external for safety
-	}
-
-	// If the relative path starts with "..", it means workDir is outside workspaceDir
-	return strings.HasPrefix(relPath, "..")
+	// Check if workDir is under workspaceDir, comparing canonical paths
+	_, inWorkspace := config.RelativePath(workspaceDir, workDir)
+	return !inWorkspace
 }
 
 // findWorktreeByName finds a worktree by its name in the state
@@ -396,6 +402,7 @@ func (h *PTYHandler) HandleWebSocket(c *fiber.Ctx) error {
 		sessionID := c.Query("session", defaultSession)
 		agent := c.Query("agent", "")
 		reset := c.Query("reset", "false") == "true"
+		observe := c.Query("observe", "false") == "true"
 
 		// Debug logging to understand what session ID we're actually receiving
 		logger.Debugf("🔍 WebSocket PTY request - Raw session param: %q, Default session: %q, Final sessionID: %q", c.Query("session"), defaultSession, sessionID)
@@ -407,7 +414,7 @@ func (h *PTYHandler) HandleWebSocket(c *fiber.Ctx) error {
 		}
 
 		return websocket.New(func(conn *websocket.Conn) {
-			h.handlePTYConnection(conn, compositeSessionID, agent, reset)
+			h.handlePTYConnection(conn, compositeSessionID, agent, reset, observe)
 		})(c)
 	}
 	return fiber.ErrUpgradeRequired
@@ -456,6 +463,13 @@ func (h *PTYHandler) HandlePTYStart(c *fiber.Ctx) error {
 		})
 	}
 
+	// Attribute the Claude session to the requesting actor
+	if agent == "claude" {
+		if err := h.sessionService.SetActiveSessionActor(session.WorkDir, GetActor(c)); err != nil {
+			logger.Debugf("⚠️ Could not record session actor for %s: %v", session.WorkDir, err)
+		}
+	}
+
 	logger.Infof("✅ PTY session started successfully: %s", compositeSessionID)
 	return c.JSON(fiber.Map{
 		"status":     "started",
@@ -575,15 +589,17 @@ func (h *PTYHandler) HandlePTYPrompt(c *fiber.Ctx) error {
 	})
 }
 
-func (h *PTYHandler) handlePTYConnection(conn *websocket.Conn, sessionID, agent string, reset bool) {
+func (h *PTYHandler) handlePTYConnection(conn *websocket.Conn, sessionID, agent string, reset, observe bool) {
 	// Wrap WebSocket connection in transport abstraction
 	wsConn := NewWebSocketConnection(context.Background(), conn)
 
 	// Use the unified handler with the wrapped connection
-	h.handleConnection(wsConn, sessionID, agent, reset)
+	h.handleConnection(wsConn, sessionID, agent, reset, observe)
 }
 
-func (h *PTYHandler) handleConnection(conn PTYConnection, sessionID, agent string, reset bool) {
+// handleConnection attaches a connection to a PTY session. Observe mode attaches to an existing
+// session only, receives output without input, and never displaces the other connections.
+func (h *PTYHandler) handleConnection(conn PTYConnection, sessionID, agent string, reset, observe bool) {
 	// Generate unique connection ID for logging and tracking
 	connID := fmt.Sprintf("%p", conn)
 
@@ -595,8 +611,8 @@ func (h *PTYHandler) handleConnection(conn PTYConnection, sessionID, agent strin
 			conn.Type(), connID, sessionID, reset)
 	}
 
-	// Handle reset logic for Claude agent
-	if reset && agent == "claude" {
+	// Handle reset logic for Claude agent (observers must never restart the session)
+	if reset && agent == "claude" && !observe {
 		logger.Infof("🔄 Reset requested for Claude session: %s", sessionID)
 		// Shutdown any existing PTY session for this sessionID
 		h.sessionMutex.Lock()
@@ -608,8 +624,15 @@ func (h *PTYHandler) handleConnection(conn PTYConnection, sessionID, agent strin
 		h.sessionMutex.Unlock()
 	}
 
-	// Get or create session
-	session := h.getOrCreateSession(sessionID, agent, reset)
+	// Get or create session; observers only attach to sessions that are already running
+	var session *Session
+	if observe {
+		h.sessionMutex.RLock()
+		session = h.sessions[h.sanitizeSessionID(sessionID)]
+		h.sessionMutex.RUnlock()
+	} else {
+		session = h.getOrCreateSession(sessionID, agent, reset)
+	}
 	if session == nil {
 		logger.Errorf("❌ Failed to create session: %s", sessionID)
 
@@ -675,16 +698,20 @@ func (h *PTYHandler) handleConnection(conn PTYConnection, sessionID, agent strin
 	// TODO: Consider implementing proper connection lifecycle management in the future
 	// For now, this ensures terminals work reliably in single-tab scenarios
 
-	existingConnectionCount := len(session.connections)
+	// Observers are exempt in both directions: they don't displace the attached terminal and
+	// aren't displaced by it.
+	var connectionsToClose []PTYConnection
+	if !observe {
+		for conn, info := range session.connections {
+			if !info.IsObserver {
+				connectionsToClose = append(connectionsToClose, conn)
+			}
+		}
+	}
+	existingConnectionCount := len(connectionsToClose)
 	if existingConnectionCount > 0 {
 		logger.Infof("🧹 FORCE CLEANUP: Found %d existing connections in session %s, closing all", existingConnectionCount, sessionID)
 
-		// Force close all existing connections
-		var connectionsToClose []PTYConnection
-		for conn := range session.connections {
-			connectionsToClose = append(connectionsToClose, conn)
-		}
-
 		// Close them outside the range loop to avoid map modification during iteration
 		for _, existingConn := range connectionsToClose {
 			if _, exists := session.connections[existingConn]; exists {
@@ -699,11 +726,22 @@ func (h *PTYHandler) handleConnection(conn PTYConnection, sessionID, agent strin
 	connectionCount := len(session.connections)
 	logger.Debugf("🔍 Connection count for session %s: %d (after cleanup)", sessionID, connectionCount)
 
+	// A writer can remain after cleanup when an observer has been granted control
+	hasWriter := false
+	for _, info := range session.connections {
+		if !info.IsReadOnly {
+			hasWriter = true
+			break
+		}
+	}
+
 	// Determine read-only status: external workspaces are always read-only for safety
-	isReadOnly := session.IsReadOnlyWorkspace || connectionCount > 0
+	isReadOnly := session.IsReadOnlyWorkspace || observe || hasWriter
 	if session.IsReadOnlyWorkspace {
 		logger.Debugf("🔒 Setting connection [%s] to read-ONLY mode (external workspace)", connID)
-	} else if connectionCount > 0 {
+	} else if observe {
+		logger.Debugf("👀 Setting connection [%s] to read-ONLY mode (observer)", connID)
+	} else if hasWriter {
 		logger.Debugf("🔒 Setting connection [%s] to read-ONLY mode (existing connections: %d)", connID, connectionCount)
 	} else {
 		logger.Debugf("✍️ Setting connection [%s] to WRITE mode (first connection)", connID)
@@ -715,6 +753,7 @@ func (h *PTYHandler) handleConnection(conn PTYConnection, sessionID, agent strin
 		ConnID:      connID,
 		IsReadOnly:  isReadOnly,
 		IsFocused:   false, // Will be updated when focus event is received
+		IsObserver:  observe,
 		ConnType:    conn.Type(),
 	}
 	newConnectionCount := len(session.connections)
@@ -776,16 +815,20 @@ func (h *PTYHandler) handleConnection(conn PTYConnection, sessionID, agent strin
 		}
 
 		delete(session.connections, conn)
+		if exists {
+			delete(session.pendingControlRequests, connInfo.ConnID)
+		}
 		connectionCount := len(session.connections)
 		logger.Debugf("🔍 Connection count for session %s: %d (after removal)", session.ID, connectionCount)
 
-		// If the write connection disconnected, promote the oldest read-only connection
+		// If the write connection disconnected, promote the oldest read-only connection.
+		// Observers are skipped; they take over only by requesting control.
 		if wasWriteConnection && connectionCount > 0 {
 			var oldestConn PTYConnection
 			var oldestTime time.Time
 
 			for c, info := range session.connections {
-				if info.IsReadOnly && (oldestConn == nil || info.ConnectedAt.Before(oldestTime)) {
+				if info.IsReadOnly && !info.IsObserver && (oldestConn == nil || info.ConnectedAt.Before(oldestTime)) {
 					oldestConn = c
 					oldestTime = info.ConnectedAt
 				}
@@ -840,6 +883,16 @@ func (h *PTYHandler) handleConnection(conn PTYConnection, sessionID, agent strin
 		// Handle control message
 		if controlMsg != nil {
 			logger.Infof("🔧 Received control message type: %s", controlMsg.Type)
+
+			// Observers without control can't affect the session: no input, prompts, resizes or resets
+			switch controlMsg.Type {
+			case "input", "prompt", "resize", "reset":
+				if session.isObservingWithoutControl(conn) {
+					logger.Debugf("👀 Ignoring %s from observer [%s] in session %s", controlMsg.Type, connID, sessionID)
+					continue
+				}
+			}
+
 			switch controlMsg.Type {
 			case "reset":
 				logger.Infof("🔄 Reset command received for session: %s", sessionID)
@@ -872,9 +925,11 @@ func (h *PTYHandler) handleConnection(conn PTYConnection, sessionID, agent strin
 					session.bufferMutex.RUnlock()
 
 					if hasBuffer && bufferCols > 0 && bufferRows > 0 {
-						// First, resize PTY to match buffered dimensions
-						logger.Infof("📐 Resizing PTY to buffered dimensions %dx%d before replay", bufferCols, bufferRows)
-						_ = h.resizePTY(session.PTY, bufferCols, bufferRows)
+						// First, resize PTY to match buffered dimensions (observers leave the PTY size alone)
+						if !session.isObservingWithoutControl(conn) {
+							logger.Infof("📐 Resizing PTY to buffered dimensions %dx%d before replay", bufferCols, bufferRows)
+							_ = h.resizePTY(session.PTY, bufferCols, bufferRows)
+						}
 
 						// Tell client what size to use for replay
 						sizeMsg := struct {
@@ -964,8 +1019,21 @@ func (h *PTYHandler) handleConnection(conn PTYConnection, sessionID, agent strin
 			case "promote":
 				// Handle connection promotion request (swap read/write permissions)
 				logger.Infof("🔄 Promotion request received from connection [%s] in session %s", connID, sessionID)
+				if session.isObservingWithoutControl(conn) {
+					// Observers can't force a takeover; route through the writer's approval
+					h.requestControl(session, conn)
+					continue
+				}
 				h.promoteConnection(session, conn)
 				continue
+			case "request-control":
+				// Observer asks for write access; the current writer decides
+				h.requestControl(session, conn)
+				continue
+			case "control-response":
+				// Current writer answers an observer's control request
+				h.resolveControlRequest(session, conn, controlMsg.ConnID, controlMsg.Granted)
+				continue
 			case "focus":
 				// Handle focus state change
 				h.handleFocusChange(session, conn, controlMsg.Focused)
@@ -1624,7 +1692,7 @@ func (h *PTYHandler) monitorCheckpoints(session *Session) {
 			shouldCreate := session.checkpointManager.ShouldCreateCheckpoint()
 
 			if shouldCreate {
-				err := session.checkpointManager.CreateCheckpoint(session.Title)
+				_, err := session.checkpointManager.CreateCheckpoint(session.Title)
 				if err != nil {
 					logger.Infof("⚠️  Failed to create checkpoint for session %s: %v", session.ID, err)
 				}
@@ -1652,6 +1720,11 @@ func (h *PTYHandler) createCommand(sessionID, agent, workDir, resumeSessionID st
 		logger.Infof("⚠️  Failed to get port environment variables for session %s: %v", sessionID, err)
 		portEnvVars = []string{} // fallback to empty
 	}
+	// Point package managers at the caches shared across worktrees
+	var cacheEnvVars []string
+	if h.gitService != nil {
+		cacheEnvVars = h.gitService.DependencyCacheEnv(workDir)
+	}
 
 	switch agent {
 	case "claude":
@@ -1680,8 +1753,9 @@ func (h *PTYHandler) createCommand(sessionID, agent, workDir, resumeSessionID st
 			"TERM=xterm-direct",
 			"COLORTERM=truecolor",
 		)
-		// Add port environment variables
+		// Add port and package cache environment variables
 		cmd.Env = append(cmd.Env, portEnvVars...)
+		cmd.Env = append(cmd.Env, cacheEnvVars...)
 	case "setup":
 		// For setup sessions, run bash that cats the setup log file
 		// Replace slashes in sessionID with underscores for valid filename
@@ -1704,8 +1778,9 @@ func (h *PTYHandler) createCommand(sessionID, agent, workDir, resumeSessionID st
 			"TERM=xterm-direct",
 			"COLORTERM=truecolor",
 		)
-		// Add port environment variables
+		// Add port and package cache environment variables
 		cmd.Env = append(cmd.Env, portEnvVars...)
+		cmd.Env = append(cmd.Env, cacheEnvVars...)
 		logger.Infof("🐚 Starting bash shell for session: %s", sessionID)
 	}
 	if cmd != nil {
@@ -2286,11 +2361,7 @@ func (h *PTYHandler) getClaudeSessionLogModTime(workDir string) time.Time {
 	homeDir := config.Runtime.HomeDir
 
 	// Transform workDir path to Claude projects directory format
-	transformedPath := strings.ReplaceAll(workDir, "/", "-")
-	transformedPath = strings.TrimPrefix(transformedPath, "-")
-	transformedPath = "-" + transformedPath // Add back the leading dash
-
-	claudeProjectsDir := filepath.Join(homeDir, ".claude", "projects", transformedPath)
+	claudeProjectsDir := filepath.Join(homeDir, ".claude", "projects", services.WorktreePathToProjectDir(workDir))
 
 	// Check if .claude/projects directory exists
 	if _, err := os.Stat(claudeProjectsDir); os.IsNotExist(err) {
@@ -2381,6 +2452,10 @@ func (h *PTYHandler) findNewestClaudeSession(claudeProjectsDir string) string {
 
 // handleTitleUpdate processes a new terminal title, committing previous work and updating session state
 func (h *PTYHandler) handleTitleUpdate(session *Session, title string) {
+	title = services.SanitizeTitle(title)
+	if title == "" {
+		return
+	}
 	logger.Infof("🪧 New terminal title detected: %q", title)
 
 	// Get the previous title before updating
@@ -2410,13 +2485,19 @@ func (h *PTYHandler) handleTitleUpdate(session *Session, title string) {
 
 // commitPreviousWork commits the previous work with the given title and updates the commit hash
 func (h *PTYHandler) commitPreviousWork(session *Session, previousTitle string) {
+	previousTitle = services.SanitizeTitle(previousTitle)
+	if previousTitle == "" {
+		return
+	}
 	if h.gitService == nil {
 		logger.Infof("⚠️  GitService is nil, skipping git operations")
 		return
 	}
 
 	commitHash, err := h.gitService.GitAddCommitGetHash(session.WorkDir, previousTitle)
-	if err != nil {
+	h.gitService.RecordCheckpointError(session.WorkDir, err)
+	var hookErr *git.CheckpointHookError
+	if err != nil && !errors.As(err, &hookErr) {
 		logger.Infof("⚠️  Git operations failed for previous title '%s': %v", previousTitle, err)
 		return
 	}
@@ -2447,9 +2528,22 @@ func (h *PTYHandler) GetSessionService() *services.SessionService {
 }
 
 // ExecuteSetupScript checks for and executes setup.sh in a worktree's PTY session
-func (h *PTYHandler) ExecuteSetupScript(worktreePath string) {
+func (h *PTYHandler) ExecuteSetupScript(worktreePath, defaultScript string, env []string) {
 	// Delegate to PTY service
-	h.ptyService.ExecuteSetupScript(worktreePath)
+	h.ptyService.ExecuteSetupScript(worktreePath, defaultScript, env)
+}
+
+// HasSessionsIn reports whether a terminal or agent session is running in a worktree
+func (h *PTYHandler) HasSessionsIn(worktreePath string) bool {
+	h.sessionMutex.RLock()
+	defer h.sessionMutex.RUnlock()
+
+	for _, session := range h.sessions {
+		if filepath.Clean(session.WorkDir) == filepath.Clean(worktreePath) {
+			return true
+		}
+	}
+	return false
 }
 
 // GetPTYService returns the PTY service for external access
@@ -2460,18 +2554,26 @@ func (h *PTYHandler) GetPTYService() *services.PTYService {
 // promoteConnection promotes a read-only connection to write access and demotes the current write connection
 func (h *PTYHandler) promoteConnection(session *Session, requestingConn PTYConnection) {
 	session.connMutex.Lock()
-	defer session.connMutex.Unlock()
+	notices := h.promoteConnectionLocked(session, requestingConn)
+	session.connMutex.Unlock()
 
+	// Notify outside connMutex: writeJSONToConnection takes the lock itself
+	session.sendNotices(notices)
+}
+
+// promoteConnectionLocked swaps write access to requestingConn and returns the notices to send.
+// The caller must hold session.connMutex.
+func (h *PTYHandler) promoteConnectionLocked(session *Session, requestingConn PTYConnection) []connNotice {
 	// External workspaces are always read-only - no promotions allowed
 	if session.IsReadOnlyWorkspace {
 		logger.Infof("🚫 Connection promotion denied for external workspace (read-only): %s", session.ID)
-		return
+		return nil
 	}
 
 	requestingConnInfo, exists := session.connections[requestingConn]
 	if !exists {
 		logger.Warnf("❌ Requesting connection not found in session connections")
-		return
+		return nil
 	}
 
 	// Find the current write connection (if any)
@@ -2488,44 +2590,136 @@ func (h *PTYHandler) promoteConnection(session *Session, requestingConn PTYConne
 	// If requesting connection is already the write connection, do nothing
 	if currentWriteConn == requestingConn {
 		logger.Infof("🔄 Connection [%s] is already the write connection", requestingConnInfo.ConnID)
-		return
+		return nil
 	}
 
+	var notices []connNotice
+
 	// If there's a current write connection, demote it to read-only
 	if currentWriteConn != nil && currentWriteConnInfo != nil {
 		currentWriteConnInfo.IsReadOnly = true
 		logger.Infof("🔒 Demoted connection [%s] to read-only mode", currentWriteConnInfo.ConnID)
-
-		// Notify the demoted connection
-		readOnlyMsg := struct {
-			Type string `json:"type"`
-			Data bool   `json:"data"`
-		}{
-			Type: "read-only",
-			Data: true,
-		}
-		if data, err := json.Marshal(readOnlyMsg); err == nil {
-			_ = session.writeJSONToConnection(currentWriteConn, data)
-		}
+		notices = append(notices, readOnlyNotice(currentWriteConn, true))
 	}
 
 	// Promote the requesting connection to write access
 	requestingConnInfo.IsReadOnly = false
 	logger.Infof("✍️ Promoted connection [%s] to write mode", requestingConnInfo.ConnID)
+	notices = append(notices, readOnlyNotice(requestingConn, false))
+
+	logger.Infof("🔄 Connection promotion completed in session %s", session.ID)
+	return notices
+}
+
+// requestControl handles an observer asking for write access. With a current writer the
+// request is forwarded to it for approval; otherwise control is granted right away.
+func (h *PTYHandler) requestControl(session *Session, requestingConn PTYConnection) {
+	session.connMutex.Lock()
+
+	requestingConnInfo, exists := session.connections[requestingConn]
+	if !exists || !requestingConnInfo.IsReadOnly {
+		session.connMutex.Unlock()
+		return
+	}
+
+	var writerConn PTYConnection
+	for conn, info := range session.connections {
+		if !info.IsReadOnly {
+			writerConn = conn
+			break
+		}
+	}
+
+	if writerConn == nil {
+		logger.Infof("✍️ No writer in session %s, granting control to [%s]", session.ID, requestingConnInfo.ConnID)
+		notices := h.promoteConnectionLocked(session, requestingConn)
+		session.connMutex.Unlock()
+		session.sendNotices(notices)
+		return
+	}
+
+	if session.pendingControlRequests == nil {
+		session.pendingControlRequests = make(map[string]PTYConnection)
+	}
+	connID := requestingConnInfo.ConnID
+	session.pendingControlRequests[connID] = requestingConn
+	session.connMutex.Unlock()
+
+	logger.Infof("🙋 Connection [%s] requested control of session %s, asking current writer", connID, session.ID)
+	if data, err := json.Marshal(controlRequestMsg{Type: "control-request", ConnID: connID}); err == nil {
+		_ = session.writeJSONToConnection(writerConn, data)
+	}
+
+	time.AfterFunc(controlRequestTimeout, func() {
+		h.resolveControlRequest(session, nil, connID, false)
+	})
+}
+
+// resolveControlRequest grants or denies a pending control request. A non-nil responder must
+// be the current writer; a nil responder is the timeout, which denies the request.
+func (h *PTYHandler) resolveControlRequest(session *Session, responder PTYConnection, connID string, granted bool) {
+	session.connMutex.Lock()
+
+	requestingConn, pending := session.pendingControlRequests[connID]
+	if !pending {
+		session.connMutex.Unlock()
+		return
+	}
+	if responder != nil {
+		if info, exists := session.connections[responder]; !exists || info.IsReadOnly {
+			session.connMutex.Unlock()
+			logger.Warnf("🚫 Ignoring control response for [%s] from a connection without write access", connID)
+			return
+		}
+	}
+	delete(session.pendingControlRequests, connID)
+
+	if granted {
+		notices := h.promoteConnectionLocked(session, requestingConn)
+		session.connMutex.Unlock()
+		session.sendNotices(notices)
+		return
+	}
+	session.connMutex.Unlock()
+
+	logger.Infof("🙅 Control request from [%s] denied in session %s", connID, session.ID)
+	if data, err := json.Marshal(controlRequestMsg{Type: "control-denied", ConnID: connID}); err == nil {
+		_ = session.writeJSONToConnection(requestingConn, data)
+	}
+}
 
-	// Notify the promoted connection
-	writeAccessMsg := struct {
+// isObservingWithoutControl reports whether conn attached in observe mode and has not been granted control
+func (s *Session) isObservingWithoutControl(conn PTYConnection) bool {
+	s.connMutex.RLock()
+	defer s.connMutex.RUnlock()
+
+	info, exists := s.connections[conn]
+	return exists && info.IsObserver && info.IsReadOnly
+}
+
+// connNotice is a JSON control message queued for a connection while connMutex is held
+type connNotice struct {
+	conn PTYConnection
+	data []byte
+}
+
+// readOnlyNotice builds the read-only status message for a connection
+func readOnlyNotice(conn PTYConnection, readOnly bool) connNotice {
+	data, _ := json.Marshal(struct {
 		Type string `json:"type"`
 		Data bool   `json:"data"`
 	}{
 		Type: "read-only",
-		Data: false,
-	}
-	if data, err := json.Marshal(writeAccessMsg); err == nil {
-		_ = session.writeJSONToConnection(requestingConn, data)
-	}
+		Data: readOnly,
+	})
+	return connNotice{conn: conn, data: data}
+}
 
-	logger.Infof("🔄 Connection promotion completed in session %s", session.ID)
+// sendNotices writes queued control messages. It must be called without holding connMutex.
+func (s *Session) sendNotices(notices []connNotice) {
+	for _, notice := range notices {
+		_ = s.writeJSONToConnection(notice.conn, notice.data)
+	}
 }
 
 // processTerminalOutput scans terminal output for localhost:XXXX patterns
@@ -2561,7 +2755,11 @@ func (h *PTYHandler) processTerminalOutput(data []byte, session *Session) []byte
 // handleFocusChange handles focus state changes and auto-promotes focused connections
 func (h *PTYHandler) handleFocusChange(session *Session, conn PTYConnection, focused bool) {
 	session.connMutex.Lock()
-	defer session.connMutex.Unlock()
+	var notices []connNotice
+	defer func() {
+		session.connMutex.Unlock()
+		session.sendNotices(notices)
+	}()
 
 	connInfo, exists := session.connections[conn]
 	if !exists {
@@ -2581,8 +2779,9 @@ func (h *PTYHandler) handleFocusChange(session *Session, conn PTYConnection, foc
 		// Debug logging for promotion logic
 		logger.Debugf("🔍 Focus promotion check - IsReadOnly: %v, IsReadOnlyWorkspace: %v", connInfo.IsReadOnly, session.IsReadOnlyWorkspace)
 
-		// Auto-promote focused connection if it's read-only (but not for external workspaces)
-		if connInfo.IsReadOnly && !session.IsReadOnlyWorkspace {
+		// Auto-promote focused connection if it's read-only (but not for external workspaces,
+		// and never for observers, which have to request control explicitly)
+		if connInfo.IsReadOnly && !session.IsReadOnlyWorkspace && !connInfo.IsObserver {
 			// Find and demote the current write connection
 			var currentWriteConn PTYConnection
 			var currentWriteConnInfo *ConnectionInfo
@@ -2598,37 +2797,15 @@ func (h *PTYHandler) handleFocusChange(session *Session, conn PTYConnection, foc
 			if currentWriteConn != nil && currentWriteConnInfo != nil {
 				currentWriteConnInfo.IsReadOnly = true
 				logger.Infof("🔒 Auto-demoted connection [%s] to read-only (focus lost)", currentWriteConnInfo.ConnID)
-
-				// Notify the demoted connection
-				readOnlyMsg := struct {
-					Type string `json:"type"`
-					Data bool   `json:"data"`
-				}{
-					Type: "read-only",
-					Data: true,
-				}
-				if data, err := json.Marshal(readOnlyMsg); err == nil {
-					_ = session.writeJSONToConnection(currentWriteConn, data)
-				}
+				notices = append(notices, readOnlyNotice(currentWriteConn, true))
 			}
 
 			// Promote the focused connection
 			connInfo.IsReadOnly = false
 			logger.Infof("✍️ Auto-promoted focused connection [%s] to write mode", connID)
-
-			// Notify the promoted connection
-			writeAccessMsg := struct {
-				Type string `json:"type"`
-				Data bool   `json:"data"`
-			}{
-				Type: "read-only",
-				Data: false,
-			}
-			if data, err := json.Marshal(writeAccessMsg); err == nil {
-				_ = session.writeJSONToConnection(conn, data)
-			}
+			notices = append(notices, readOnlyNotice(conn, false))
 		} else {
-			logger.Debugf("🔍 Skipping auto-promotion - connection is already write-enabled or external workspace")
+			logger.Debugf("🔍 Skipping auto-promotion - connection is already write-enabled, an observer, or external workspace")
 		}
 	} else {
 		logger.Infof("👁️ Connection [%s] lost focus in session %s", connID, session.ID)
@@ -2643,9 +2820,7 @@ func (h *PTYHandler) detectClaudeErrorsFromJSONL(session *Session) []string {
 
 	// Construct path to Claude JSONL files
 	homeDir := config.Runtime.HomeDir
-	transformedPath := strings.ReplaceAll(session.WorkDir, "/", "-")
-	transformedPath = strings.TrimPrefix(transformedPath, "-")
-	projectsDir := filepath.Join(homeDir, ".claude", "projects", "-"+transformedPath)
+	projectsDir := filepath.Join(homeDir, ".claude", "projects", services.WorktreePathToProjectDir(session.WorkDir))
 
 	// Find the most recent JSONL file
 	entries, err := os.ReadDir(projectsDir)
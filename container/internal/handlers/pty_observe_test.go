@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakePTYConnection records the JSON control messages written to it
+type fakePTYConnection struct {
+	mu       sync.Mutex
+	messages []map[string]interface{}
+}
+
+func (f *fakePTYConnection) WriteMessage(data []byte) error { return nil }
+func (f *fakePTYConnection) WriteJSONMessage(data []byte) error {
+	var msg map[string]interface{}
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.messages = append(f.messages, msg)
+	return nil
+}
+func (f *fakePTYConnection) ReadControlMessage() (*ControlMessage, error) { return nil, nil }
+func (f *fakePTYConnection) Close() error                                 { return nil }
+func (f *fakePTYConnection) RemoteAddr() string                           { return "test" }
+func (f *fakePTYConnection) IsReadOnly() bool                             { return false }
+func (f *fakePTYConnection) Type() string                                 { return "websocket" }
+func (f *fakePTYConnection) Context() context.Context                     { return context.Background() }
+
+func (f *fakePTYConnection) last() map[string]interface{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.messages) == 0 {
+		return nil
+	}
+	return f.messages[len(f.messages)-1]
+}
+
+func newObserveTestSession() (*Session, *fakePTYConnection, *fakePTYConnection) {
+	writer := &fakePTYConnection{}
+	observer := &fakePTYConnection{}
+	session := &Session{
+		ID: "repo/felix:claude",
+		connections: map[PTYConnection]*ConnectionInfo{
+			writer:   {ConnID: "writer", IsReadOnly: false},
+			observer: {ConnID: "observer", IsReadOnly: true, IsObserver: true},
+		},
+	}
+	return session, writer, observer
+}
+
+func TestObserverRequestControl(t *testing.T) {
+	h := &PTYHandler{}
+
+	t.Run("WriterApproves", func(t *testing.T) {
+		session, writer, observer := newObserveTestSession()
+		assert.True(t, session.isObservingWithoutControl(observer))
+		assert.False(t, session.isObservingWithoutControl(writer))
+
+		h.requestControl(session, observer)
+		request := writer.last()
+		require.NotNil(t, request)
+		assert.Equal(t, "control-request", request["type"])
+		assert.Equal(t, "observer", request["connId"])
+		assert.True(t, session.connections[observer].IsReadOnly, "request alone must not grant control")
+
+		// Only the writer may answer
+		h.resolveControlRequest(session, observer, "observer", true)
+		assert.True(t, session.connections[observer].IsReadOnly)
+
+		h.resolveControlRequest(session, writer, "observer", true)
+		assert.False(t, session.connections[observer].IsReadOnly)
+		assert.True(t, session.connections[writer].IsReadOnly)
+		assert.False(t, session.isObservingWithoutControl(observer))
+		assert.Equal(t, map[string]interface{}{"type": "read-only", "data": false}, observer.last())
+		assert.Equal(t, map[string]interface{}{"type": "read-only", "data": true}, writer.last())
+	})
+
+	t.Run("WriterDenies", func(t *testing.T) {
+		session, writer, observer := newObserveTestSession()
+		h.requestControl(session, observer)
+		h.resolveControlRequest(session, writer, "observer", false)
+
+		assert.True(t, session.connections[observer].IsReadOnly)
+		assert.Equal(t, "control-denied", observer.last()["type"])
+		assert.Empty(t, session.pendingControlRequests)
+	})
+
+	t.Run("NoWriterGrantsImmediately", func(t *testing.T) {
+		session, writer, observer := newObserveTestSession()
+		delete(session.connections, writer)
+
+		h.requestControl(session, observer)
+		assert.False(t, session.connections[observer].IsReadOnly)
+		assert.Equal(t, map[string]interface{}{"type": "read-only", "data": false}, observer.last())
+	})
+}
+
+func TestFocusDoesNotPromoteObserver(t *testing.T) {
+	h := &PTYHandler{}
+	session, writer, observer := newObserveTestSession()
+
+	h.handleFocusChange(session, observer, true)
+	assert.True(t, session.connections[observer].IsReadOnly)
+	assert.False(t, session.connections[writer].IsReadOnly)
+}
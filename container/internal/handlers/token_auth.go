@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/websocket/v2"
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/services"
+)
+
+// TokenHeader carries the API token for clients whose Authorization header is already
+// taken (e.g. the codespace port-forwarding proxy token)
+const TokenHeader = services.APITokenHeader
+
+// tokenCookieName is set after a successful ?token= login so the browser UI keeps working
+const tokenCookieName = "catnip_token"
+
+// TokenAuth protects the HTTP API with a bearer token
+type TokenAuth struct {
+	mu           sync.RWMutex
+	token        string
+	tokenPath    string
+	disabled     bool
+	protectReads bool
+}
+
+// TokenRotateResponse is returned when the API token is rotated
+// @Description Newly generated API token
+type TokenRotateResponse struct {
+	// The new bearer token; the previous token stops working immediately
+	Token string `json:"token" example:"3f2a..."`
+}
+
+// NewTokenAuth loads (or generates) the API token and configures the auth layer
+// CATNIP_NO_AUTH=true disables authentication and CATNIP_AUTH_READS=true also protects GET requests
+func NewTokenAuth() *TokenAuth {
+	auth := &TokenAuth{
+		tokenPath:    services.GetAPITokenPath(),
+		disabled:     os.Getenv(services.NoAuthEnv) == "true",
+		protectReads: os.Getenv("CATNIP_AUTH_READS") == "true",
+	}
+
+	if auth.disabled {
+		logger.Warnf("⚠️ API authentication disabled via %s", services.NoAuthEnv)
+		return auth
+	}
+
+	token, created, err := services.LoadOrCreateAPIToken(auth.tokenPath)
+	if err != nil {
+		// Fail closed: without a token nobody can call protected endpoints
+		logger.Errorf("❌ Failed to initialize API token: %v", err)
+		return auth
+	}
+	auth.token = token
+
+	if created {
+		// Printed once - afterwards the token lives in ~/.catnip/api-token
+		fmt.Printf("🔑 Catnip API token: %s (stored in %s)\n", token, auth.tokenPath)
+	} else {
+		logger.Infof("🔑 API authentication enabled (token from %s or %s)", services.APITokenEnv, auth.tokenPath)
+	}
+
+	return auth
+}
+
+// Middleware returns a fiber handler enforcing the API token
+func (a *TokenAuth) Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if a.disabled {
+			return c.Next()
+		}
+
+		provided, fromQuery, ok := a.findValidToken(c)
+
+		// Remember browser logins (e.g. opening /?token=...) so subsequent requests and
+		// EventSource connections authenticate via cookie
+		if ok && fromQuery {
+			c.Cookie(&fiber.Cookie{
+				Name:     tokenCookieName,
+				Value:    provided,
+				Path:     "/",
+				HTTPOnly: true,
+				SameSite: "Strict",
+			})
+		}
+
+		if ok || !a.requiresAuth(c) {
+			return c.Next()
+		}
+
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "unauthorized: missing or invalid API token",
+		})
+	}
+}
+
+// requiresAuth decides whether a request must carry a valid token
+func (a *TokenAuth) requiresAuth(c *fiber.Ctx) bool {
+	path := c.Path()
+
+	// Health checks stay open for probes
+	if path == "/health" {
+		return false
+	}
+
+	// GitHub can't send the API token, webhook deliveries are authenticated by their signature
+	if path == "/v1/webhooks/github" {
+		return false
+	}
+
+	// Git smart HTTP (clone/fetch/push) and the API are protected; static assets are not
+	isAPI := strings.HasPrefix(path, "/v1/")
+	isGitHTTP := strings.Contains(path, ".git/") || strings.HasSuffix(path, ".git")
+	if !isAPI && !isGitHTTP {
+		return false
+	}
+
+	// WebSocket upgrades (PTY) can execute commands and are always protected
+	if websocket.IsWebSocketUpgrade(c) {
+		return true
+	}
+
+	// Bundles hand out a worktree's whole history, so reading them always needs the token
+	if isAPI && strings.HasSuffix(path, "/bundle") {
+		return true
+	}
+
+	switch c.Method() {
+	case fiber.MethodGet, fiber.MethodHead, fiber.MethodOptions:
+		return a.protectReads
+	default:
+		return true
+	}
+}
+
+// findValidToken checks the X-Catnip-Token and Authorization headers, the basic auth
+// password (for git clients), the ?token= query param (for EventSource) and the login
+// cookie. Every candidate is tried because proxies may occupy the Authorization header.
+func (a *TokenAuth) findValidToken(c *fiber.Ctx) (token string, fromQuery bool, ok bool) {
+	var candidates []string
+
+	candidates = append(candidates, c.Get(TokenHeader))
+
+	authHeader := c.Get(fiber.HeaderAuthorization)
+	if strings.HasPrefix(authHeader, "Bearer ") {
+		candidates = append(candidates, strings.TrimSpace(strings.TrimPrefix(authHeader, "Bearer ")))
+	} else if strings.HasPrefix(authHeader, "Basic ") {
+		if decoded, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(authHeader, "Basic ")); err == nil {
+			if _, password, found := strings.Cut(string(decoded), ":"); found {
+				candidates = append(candidates, password)
+			}
+		}
+	}
+
+	candidates = append(candidates, c.Cookies(tokenCookieName))
+
+	for _, candidate := range candidates {
+		if a.validToken(candidate) {
+			return candidate, false, true
+		}
+	}
+
+	if queryToken := c.Query("token"); a.validToken(queryToken) {
+		return queryToken, true, true
+	}
+
+	return "", false, false
+}
+
+// validToken compares the provided token in constant time
+func (a *TokenAuth) validToken(provided string) bool {
+	a.mu.RLock()
+	expected := a.token
+	a.mu.RUnlock()
+
+	if expected == "" || provided == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(provided), []byte(expected)) == 1
+}
+
+// RotateToken generates a new API token and invalidates the previous one
+// @Summary Rotate API token
+// @Description Generates a new API bearer token, persists it and invalidates the previous token
+// @Tags auth
+// @Produce json
+// @Success 200 {object} TokenRotateResponse
+// @Failure 400 {object} map[string]string "Authentication disabled"
+// @Failure 500 {object} map[string]string "Failed to rotate token"
+// @Router /v1/auth/token/rotate [post]
+func (a *TokenAuth) RotateToken(c *fiber.Ctx) error {
+	if a.disabled {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "API authentication is disabled",
+		})
+	}
+
+	token, err := services.GenerateAPIToken()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err := services.SaveAPIToken(a.tokenPath, token); err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	a.mu.Lock()
+	a.token = token
+	a.mu.Unlock()
+
+	logger.Infof("🔑 API token rotated by %s", GetActor(c))
+	return c.JSON(TokenRotateResponse{Token: token})
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTokenAuthTestApp(auth *TokenAuth) *fiber.App {
+	app := fiber.New()
+	app.Get("/health", func(c *fiber.Ctx) error { return c.SendString("ok") })
+	app.Use(auth.Middleware())
+	app.Get("/v1/git/worktrees", func(c *fiber.Ctx) error { return c.SendString("list") })
+	app.Get("/v1/git/worktrees/:id/bundle", func(c *fiber.Ctx) error { return c.SendString("bundle") })
+	app.Delete("/v1/git/worktrees/:id", func(c *fiber.Ctx) error { return c.SendString("deleted") })
+	app.Post("/v1/auth/token/rotate", auth.RotateToken)
+	app.Get("/", func(c *fiber.Ctx) error { return c.SendString("index") })
+	return app
+}
+
+func TestTokenAuthMiddleware(t *testing.T) {
+	auth := &TokenAuth{token: "secret-token", tokenPath: t.TempDir() + "/api-token"}
+	app := newTokenAuthTestApp(auth)
+
+	do := func(method, target string, headers map[string]string) int {
+		req := httptest.NewRequest(method, target, nil)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		return resp.StatusCode
+	}
+
+	t.Run("HealthIsOpen", func(t *testing.T) {
+		assert.Equal(t, 200, do("GET", "/health", nil))
+	})
+
+	t.Run("ReadsOpenByDefault", func(t *testing.T) {
+		assert.Equal(t, 200, do("GET", "/v1/git/worktrees", nil))
+	})
+
+	t.Run("BundlesRequireToken", func(t *testing.T) {
+		assert.Equal(t, 401, do("GET", "/v1/git/worktrees/abc/bundle", nil))
+		assert.Equal(t, 200, do("GET", "/v1/git/worktrees/abc/bundle", map[string]string{TokenHeader: "secret-token"}))
+	})
+
+	t.Run("MutationsRequireToken", func(t *testing.T) {
+		assert.Equal(t, 401, do("DELETE", "/v1/git/worktrees/abc", nil))
+		assert.Equal(t, 401, do("DELETE", "/v1/git/worktrees/abc", map[string]string{"Authorization": "Bearer wrong"}))
+		assert.Equal(t, 200, do("DELETE", "/v1/git/worktrees/abc", map[string]string{"Authorization": "Bearer secret-token"}))
+		assert.Equal(t, 200, do("DELETE", "/v1/git/worktrees/abc", map[string]string{TokenHeader: "secret-token"}))
+		assert.Equal(t, 200, do("DELETE", "/v1/git/worktrees/abc?token=secret-token", nil))
+	})
+
+	t.Run("TokenHeaderWinsOverForeignAuthorization", func(t *testing.T) {
+		headers := map[string]string{"Authorization": "Bearer codespace-token", TokenHeader: "secret-token"}
+		assert.Equal(t, 200, do("DELETE", "/v1/git/worktrees/abc", headers))
+	})
+
+	t.Run("QueryTokenSetsCookie", func(t *testing.T) {
+		resp, err := app.Test(httptest.NewRequest("GET", "/?token=secret-token", nil))
+		require.NoError(t, err)
+		assert.Contains(t, resp.Header.Get("Set-Cookie"), tokenCookieName+"=secret-token")
+
+		assert.Equal(t, 200, do("DELETE", "/v1/git/worktrees/abc", map[string]string{"Cookie": tokenCookieName + "=secret-token"}))
+	})
+
+	t.Run("ProtectReads", func(t *testing.T) {
+		auth.protectReads = true
+		defer func() { auth.protectReads = false }()
+		assert.Equal(t, 401, do("GET", "/v1/git/worktrees", nil))
+		assert.Equal(t, 200, do("GET", "/v1/git/worktrees?token=secret-token", nil))
+	})
+
+	t.Run("RotateToken", func(t *testing.T) {
+		assert.Equal(t, 200, do("POST", "/v1/auth/token/rotate", map[string]string{TokenHeader: "secret-token"}))
+		assert.NotEqual(t, "secret-token", auth.token)
+		assert.Equal(t, 401, do("DELETE", "/v1/git/worktrees/abc", map[string]string{TokenHeader: "secret-token"}))
+		assert.Equal(t, 200, do("DELETE", "/v1/git/worktrees/abc", map[string]string{TokenHeader: auth.token}))
+	})
+}
+
+func TestTokenAuthDisabled(t *testing.T) {
+	app := newTokenAuthTestApp(&TokenAuth{disabled: true})
+	resp, err := app.Test(httptest.NewRequest("DELETE", "/v1/git/worktrees/abc", nil))
+	require.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+}
@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/services"
+)
+
+// GitHubWebhookSecretEnv enables the GitHub webhook receiver with the secret configured on the webhook
+const GitHubWebhookSecretEnv = "CATNIP_GITHUB_WEBHOOK_SECRET"
+
+// WebhookResponse reports what a webhook delivery refreshed
+// @Description Outcome of a GitHub webhook delivery
+type WebhookResponse struct {
+	// GitHub event name from the X-GitHub-Event header
+	Event string `json:"event" example:"pull_request"`
+	// Number of pull requests tracked by worktrees that are being refreshed
+	Refreshed int `json:"refreshed" example:"1"`
+	// Whether an issues event started an issue automation poll of the repository
+	IssuesPolled bool `json:"issues_polled,omitempty" example:"true"`
+}
+
+// issueAutomationActions are the issues event actions that can change which issues get worktrees
+var issueAutomationActions = map[string]bool{"opened": true, "reopened": true, "closed": true, "labeled": true, "unlabeled": true}
+
+// githubWebhookPayload holds the parts of pull_request, check_suite and issues events used to
+// find the affected pull requests and repository
+type githubWebhookPayload struct {
+	Action      string `json:"action"`
+	PullRequest *struct {
+		HTMLURL string `json:"html_url"`
+	} `json:"pull_request"`
+	CheckSuite *struct {
+		PullRequests []struct {
+			Number int `json:"number"`
+		} `json:"pull_requests"`
+	} `json:"check_suite"`
+	Repository struct {
+		FullName string `json:"full_name"`
+	} `json:"repository"`
+}
+
+// WebhookHandler receives GitHub webhooks to refresh pull request states proactively
+type WebhookHandler struct {
+	secret             string
+	refreshPullRequest func(prURL string) bool
+	pollIssues         func(ownerRepo string) bool
+}
+
+// NewWebhookHandler creates a webhook handler, disabled unless CATNIP_GITHUB_WEBHOOK_SECRET is set
+func NewWebhookHandler(gitService *services.GitService) *WebhookHandler {
+	return &WebhookHandler{
+		secret:             os.Getenv(GitHubWebhookSecretEnv),
+		refreshPullRequest: gitService.RefreshPullRequestByURL,
+		pollIssues:         gitService.RequestIssueAutomationPoll,
+	}
+}
+
+// Enabled reports whether a webhook secret is configured
+func (h *WebhookHandler) Enabled() bool {
+	return h.secret != ""
+}
+
+// HandleGitHubWebhook refreshes the state of pull requests GitHub reports changes to
+// @Summary Receive GitHub webhook
+// @Description Receives pull_request and check_suite webhook deliveries and refreshes the state of the pull requests worktrees track, emitting worktree:pull_request_status events. issues deliveries (opened, reopened, closed, labeled, unlabeled) poll repositories with issue automation enabled. Deliveries must be signed with the secret in CATNIP_GITHUB_WEBHOOK_SECRET (X-Hub-Signature-256); without it the endpoint is disabled.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param X-GitHub-Event header string true "GitHub event name"
+// @Param X-Hub-Signature-256 header string true "HMAC-SHA256 signature of the body"
+// @Success 202 {object} WebhookResponse
+// @Failure 400 {object} map[string]string "Invalid payload"
+// @Failure 401 {object} map[string]string "Missing or invalid signature"
+// @Failure 404 {object} map[string]string "Webhooks disabled"
+// @Router /v1/webhooks/github [post]
+func (h *WebhookHandler) HandleGitHubWebhook(c *fiber.Ctx) error {
+	if !h.Enabled() {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": fmt.Sprintf("GitHub webhooks are disabled, set %s to enable them", GitHubWebhookSecretEnv),
+		})
+	}
+	if !h.validSignature(c.Body(), c.Get("X-Hub-Signature-256")) {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "missing or invalid webhook signature",
+		})
+	}
+
+	event := c.Get("X-GitHub-Event")
+	response := WebhookResponse{Event: event}
+	if event != "pull_request" && event != "check_suite" && event != "issues" {
+		// ping and events we don't act on are acknowledged
+		return c.Status(fiber.StatusAccepted).JSON(response)
+	}
+
+	var payload githubWebhookPayload
+	if err := json.Unmarshal(c.Body(), &payload); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid webhook payload",
+		})
+	}
+
+	if event == "issues" {
+		if issueAutomationActions[payload.Action] && payload.Repository.FullName != "" && h.pollIssues != nil {
+			response.IssuesPolled = h.pollIssues(payload.Repository.FullName)
+		}
+		return c.Status(fiber.StatusAccepted).JSON(response)
+	}
+
+	var urls []string
+	if event == "pull_request" && payload.PullRequest != nil {
+		urls = append(urls, payload.PullRequest.HTMLURL)
+	}
+	if event == "check_suite" && payload.CheckSuite != nil && payload.Repository.FullName != "" {
+		for _, pr := range payload.CheckSuite.PullRequests {
+			urls = append(urls, fmt.Sprintf("https://github.com/%s/pull/%d", payload.Repository.FullName, pr.Number))
+		}
+	}
+	for _, url := range urls {
+		if h.refreshPullRequest(url) {
+			response.Refreshed++
+		}
+	}
+
+	if response.Refreshed > 0 {
+		logger.Debugf("🪝 GitHub %s webhook refreshing %d pull requests", event, response.Refreshed)
+	}
+	return c.Status(fiber.StatusAccepted).JSON(response)
+}
+
+// validSignature checks the X-Hub-Signature-256 header against the body in constant time
+func (h *WebhookHandler) validSignature(body []byte, signature string) bool {
+	digest, found := strings.CutPrefix(signature, "sha256=")
+	if !found {
+		return false
+	}
+	provided, err := hex.DecodeString(digest)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(h.secret))
+	mac.Write(body)
+	return hmac.Equal(provided, mac.Sum(nil))
+}
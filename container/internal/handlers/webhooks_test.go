@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGitHubWebhook(t *testing.T) {
+	tracked := map[string]bool{"https://github.com/owner/repo/pull/7": true, "https://github.com/owner/repo/pull/9": true}
+	var refreshed []string
+	handler := &WebhookHandler{secret: "webhook-secret", refreshPullRequest: func(prURL string) bool {
+		refreshed = append(refreshed, prURL)
+		return tracked[prURL]
+	}}
+	auth := &TokenAuth{token: "secret-token", tokenPath: t.TempDir() + "/api-token"}
+	app := fiber.New()
+	app.Use(auth.Middleware())
+	app.Post("/v1/webhooks/github", handler.HandleGitHubWebhook)
+
+	sign := func(secret, body string) string {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(body))
+		return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	}
+	deliver := func(event, body, signature string) (int, WebhookResponse) {
+		req := httptest.NewRequest("POST", "/v1/webhooks/github", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-GitHub-Event", event)
+		if signature != "" {
+			req.Header.Set("X-Hub-Signature-256", signature)
+		}
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		data, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		var response WebhookResponse
+		_ = json.Unmarshal(data, &response)
+		return resp.StatusCode, response
+	}
+
+	t.Run("SignatureRequired", func(t *testing.T) {
+		body := `{"pull_request":{"html_url":"https://github.com/owner/repo/pull/7"}}`
+		status, _ := deliver("pull_request", body, "")
+		assert.Equal(t, 401, status)
+		status, _ = deliver("pull_request", body, sign("wrong", body))
+		assert.Equal(t, 401, status)
+		status, _ = deliver("pull_request", body, sign("webhook-secret", body+" "))
+		assert.Equal(t, 401, status)
+		assert.Empty(t, refreshed)
+	})
+
+	t.Run("PullRequest", func(t *testing.T) {
+		refreshed = nil
+		body := `{"action":"closed","pull_request":{"html_url":"https://github.com/owner/repo/pull/7"}}`
+		status, response := deliver("pull_request", body, sign("webhook-secret", body))
+		assert.Equal(t, 202, status, "deliveries don't need the API token")
+		assert.Equal(t, WebhookResponse{Event: "pull_request", Refreshed: 1}, response)
+		assert.Equal(t, []string{"https://github.com/owner/repo/pull/7"}, refreshed)
+	})
+
+	t.Run("CheckSuite", func(t *testing.T) {
+		refreshed = nil
+		body := `{"check_suite":{"pull_requests":[{"number":8},{"number":9}]},"repository":{"full_name":"owner/repo"}}`
+		status, response := deliver("check_suite", body, sign("webhook-secret", body))
+		assert.Equal(t, 202, status)
+		assert.Equal(t, 1, response.Refreshed, "untracked pull requests aren't counted")
+		assert.Equal(t, []string{"https://github.com/owner/repo/pull/8", "https://github.com/owner/repo/pull/9"}, refreshed)
+	})
+
+	t.Run("Issues", func(t *testing.T) {
+		var polled []string
+		handler.pollIssues = func(ownerRepo string) bool {
+			polled = append(polled, ownerRepo)
+			return ownerRepo == "owner/repo"
+		}
+		body := `{"action":"labeled","issue":{"number":3},"repository":{"full_name":"owner/repo"}}`
+		status, response := deliver("issues", body, sign("webhook-secret", body))
+		assert.Equal(t, 202, status)
+		assert.True(t, response.IssuesPolled)
+
+		body = `{"action":"assigned","issue":{"number":3},"repository":{"full_name":"owner/repo"}}`
+		_, response = deliver("issues", body, sign("webhook-secret", body))
+		assert.False(t, response.IssuesPolled, "actions that can't change labels or state are ignored")
+		assert.Equal(t, []string{"owner/repo"}, polled)
+	})
+
+	t.Run("PingIsAcknowledged", func(t *testing.T) {
+		refreshed = nil
+		body := `{"zen":"Keep it logically awesome."}`
+		status, response := deliver("ping", body, sign("webhook-secret", body))
+		assert.Equal(t, 202, status)
+		assert.Equal(t, "ping", response.Event)
+		assert.Empty(t, refreshed)
+	})
+
+	t.Run("DisabledWithoutSecret", func(t *testing.T) {
+		handler.secret = ""
+		body := `{}`
+		status, _ := deliver("ping", body, sign("", body))
+		assert.Equal(t, 404, status)
+	})
+}
@@ -46,17 +46,18 @@ type ClaudeHistoryEntry struct {
 
 // ClaudeSessionMessage represents a message in a Claude session file
 type ClaudeSessionMessage struct {
-	Cwd         string         `json:"cwd"`
-	IsMeta      bool           `json:"isMeta"`
-	IsSidechain bool           `json:"isSidechain"`
-	Message     map[string]any `json:"message"`
-	ParentUuid  string         `json:"parentUuid"`
-	SessionId   string         `json:"sessionId"`
-	Timestamp   string         `json:"timestamp"`
-	Type        string         `json:"type"`
-	UserType    string         `json:"userType"`
-	Uuid        string         `json:"uuid"`
-	Version     string         `json:"version"`
+	Cwd            string         `json:"cwd"`
+	IsMeta         bool           `json:"isMeta"`
+	IsSidechain    bool           `json:"isSidechain"`
+	Message        map[string]any `json:"message"`
+	ParentUuid     string         `json:"parentUuid"`
+	PermissionMode string         `json:"permissionMode,omitempty"` // "default", "plan", "acceptEdits", ...
+	SessionId      string         `json:"sessionId"`
+	Timestamp      string         `json:"timestamp"`
+	Type           string         `json:"type"`
+	UserType       string         `json:"userType"`
+	Uuid           string         `json:"uuid"`
+	Version        string         `json:"version"`
 }
 
 // ClaudeSessionSummary represents aggregated session information
@@ -229,3 +230,22 @@ type ClaudeOnboardingSubmitCodeRequest struct {
 	// OAuth code obtained from the authentication flow
 	Code string `json:"code" example:"abc123def456" binding:"required"`
 }
+
+// DebugTimelineEntry is one decision of the Claude monitor about a worktree
+// @Description Title event, checkpoint or branch graduation decision of the Claude monitor
+type DebugTimelineEntry struct {
+	// When the decision was made
+	Time time.Time `json:"time" example:"2024-01-15T16:45:30Z"`
+	// What was decided on
+	Kind string `json:"kind" example:"title_event" enums:"title_event,title_commit,checkpoint_timer,graduation"`
+	// Where a title event came from (log, pty)
+	Source string `json:"source,omitempty" example:"log"`
+	// Session title the decision was about
+	Title string `json:"title,omitempty" example:"Add user authentication"`
+	// What happened (accepted, duplicate, suppressed, committed, skipped, no_changes, error, renamed, ...)
+	Outcome string `json:"outcome" example:"committed"`
+	// Why, for suppressed, skipped and failed decisions
+	Reason string `json:"reason,omitempty" example:"plan_mode"`
+	// Commit created by the decision, if any
+	Commit string `json:"commit,omitempty" example:"abc123def456"`
+}
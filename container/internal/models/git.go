@@ -1,6 +1,8 @@
 package models
 
 import (
+	"errors"
+	"fmt"
 	"time"
 )
 
@@ -25,17 +27,145 @@ type TitleEntry struct {
 
 // MergeConflictError represents a merge conflict that occurred during sync or merge operations
 type MergeConflictError struct {
-	Operation     string   `json:"operation"`      // "sync" or "merge"
+	Operation     string   `json:"operation"`      // "sync", "merge" or "cherry-pick"
 	WorktreeName  string   `json:"worktree_name"`  // Name of the worktree
 	WorktreePath  string   `json:"worktree_path"`  // Path to the worktree
 	ConflictFiles []string `json:"conflict_files"` // List of files with conflicts
 	Message       string   `json:"message"`        // Human-readable error message
+	// Conflicted lockfiles mapped to the command regenerating them
+	LockfileCommands map[string]string `json:"lockfile_commands,omitempty"`
+	// Commit a cherry-pick stopped at, left in progress
+	FailedCommit string `json:"failed_commit,omitempty"`
+	// Commits a cherry-pick applied before stopping
+	AppliedCommits []string `json:"applied_commits,omitempty"`
 }
 
 func (e *MergeConflictError) Error() string {
 	return e.Message
 }
 
+// ErrNonFastForward is returned when a fast-forward only sync is refused
+var ErrNonFastForward = errors.New("not possible to fast-forward")
+
+// NonFastForwardError reports that a worktree has local commits, so it can't be
+// fast-forwarded to its source branch
+type NonFastForwardError struct {
+	WorktreeName string `json:"worktree_name"`
+	SourceRef    string `json:"source_ref"`
+	Ahead        int    `json:"ahead"`  // Local commits not in the source branch
+	Behind       int    `json:"behind"` // Source branch commits not in the worktree
+}
+
+func (e *NonFastForwardError) Error() string {
+	return fmt.Sprintf("cannot fast-forward %s to %s: %d local commit(s) ahead and %d behind, rebase or merge instead",
+		e.WorktreeName, e.SourceRef, e.Ahead, e.Behind)
+}
+
+func (e *NonFastForwardError) Unwrap() error {
+	return ErrNonFastForward
+}
+
+// SourceRewrittenError reports that a sync was refused because the worktree's source branch was
+// force-pushed, so merging or rebasing onto it would replay the rewritten commits
+type SourceRewrittenError struct {
+	WorktreeName string         `json:"worktree_name"`
+	Rewrite      *SourceRewrite `json:"rewrite"`
+}
+
+func (e *SourceRewrittenError) Error() string {
+	return fmt.Sprintf("source branch %s of %s was force-pushed (%.8s is no longer an ancestor of %.8s), acknowledge the rewrite to rebase onto the new tip",
+		e.Rewrite.Branch, e.WorktreeName, e.Rewrite.PreviousTip, e.Rewrite.NewTip)
+}
+
+// MergePreview is a prepared merge of a local repo worktree into its source branch, waiting
+// for the commit message to be reviewed
+// @Description Prepared merge with the default commit message and diffstat
+type MergePreview struct {
+	// Token completing the merge
+	Token string `json:"token" example:"9f86d081884c7d65"`
+	// Worktree being merged
+	WorktreeID string `json:"worktree_id" example:"abc123-def456-ghi789"`
+	// "squash" or "merge"
+	Mode string `json:"mode" example:"squash"`
+	// Branch receiving the merge
+	TargetBranch string `json:"target_branch" example:"main"`
+	// Default commit message, generated from the session title and commit subjects
+	Message string `json:"message" example:"Add user authentication"`
+	// Output of git diff --stat against the target branch
+	DiffStat string `json:"diff_stat"`
+	// Worktree HEAD when the merge was prepared; the merge is refused if it moved
+	HeadCommit string `json:"head_commit" example:"abc123def456"`
+	// When the token stops being accepted
+	ExpiresAt time.Time `json:"expires_at" example:"2024-01-15T16:50:30Z"`
+}
+
+// MergeLedgerEntry records a worktree merged into its source branch, linking the commit the
+// merge created back to the worktree and session it came from
+// @Description Merge of a worktree with a snapshot of the worktree at merge time
+type MergeLedgerEntry struct {
+	// Commit the merge created on the target branch
+	CommitHash string `json:"commit_hash" example:"abc123def456"`
+	// Repository merged into
+	RepoID string `json:"repo_id" example:"local/catnip"`
+	// "squash" or "merge"
+	Mode string `json:"mode" example:"squash"`
+	// Branch receiving the merge
+	TargetBranch string `json:"target_branch" example:"main"`
+	// Worktree branch that was merged
+	Branch string `json:"branch" example:"feature/login"`
+	// Worktree HEAD that was merged
+	HeadCommit string `json:"head_commit" example:"def456abc123"`
+	// Worktree that was merged
+	WorktreeID string `json:"worktree_id" example:"abc123-def456-ghi789"`
+	// Worktree name at merge time
+	WorktreeName string `json:"worktree_name" example:"catnip/felix"`
+	// Claude session of the worktree, when one was found
+	SessionID string `json:"session_id,omitempty" example:"b5d3c1a2-6f7e-4d8c-9a0b-1c2d3e4f5a6b"`
+	// Session title at merge time
+	SessionTitle string `json:"session_title,omitempty" example:"Add OAuth login"`
+	// Pull request of the worktree, if one existed
+	PullRequestURL string `json:"pull_request_url,omitempty" example:"https://github.com/owner/repo/pull/123"`
+	// Who created the worktree
+	CreatedBy string `json:"created_by,omitempty" example:"alice"`
+	// Commits merged
+	CommitCount int `json:"commit_count" example:"3"`
+	// Output of git diff --stat of the merged changes
+	DiffStat string `json:"diff_stat"`
+	// all_signed, mixed or none, summarizing the signatures of the merged commits
+	CommitSignatures string `json:"commit_signatures,omitempty" example:"all_signed"`
+	// Signatures of the merged commits, newest first
+	Signatures []MergedCommitSignature `json:"signatures,omitempty"`
+	// When the worktree was created
+	WorktreeCreatedAt time.Time `json:"worktree_created_at" example:"2024-01-15T14:00:00Z"`
+	// When the merge landed
+	MergedAt time.Time `json:"merged_at" example:"2024-01-15T16:45:30Z"`
+}
+
+// WorktreeGroup links worktrees of several repositories working on one feature, so they can be
+// synced, opened as pull requests and merged together
+// @Description Worktrees of several repositories making up one feature
+type WorktreeGroup struct {
+	// Unique identifier for the group
+	ID string `json:"id" example:"abc123-def456-ghi789"`
+	// Name of the feature the worktrees work on
+	Name string `json:"name" example:"oauth-login"`
+	// Worktrees of the group, in merge order
+	Members []WorktreeGroupMember `json:"members"`
+	// When the group was created
+	CreatedAt time.Time `json:"created_at" example:"2024-01-15T14:00:00Z"`
+}
+
+// WorktreeGroupMember is one repository's worktree in a worktree group
+// @Description Worktree of a repository in a worktree group
+type WorktreeGroupMember struct {
+	// Repository of the worktree
+	RepoID string `json:"repo_id" example:"acme/api"`
+	// Source branch the worktree was created from
+	Branch string `json:"branch" example:"main"`
+	// Worktree created for the member
+	WorktreeID string `json:"worktree_id" example:"abc123-def456-ghi789"`
+}
+
 // Repository represents a Git repository
 // @Description Git repository information and metadata
 type Repository struct {
@@ -49,6 +179,12 @@ type Repository struct {
 	DefaultBranch string `json:"default_branch" example:"main"`
 	// Whether the repository is currently available on disk
 	Available bool `json:"available" example:"true"`
+	// How HEAD was found by local repo detection: branch, detached or unborn
+	HeadState string `json:"head_state,omitempty" example:"branch"`
+	// Why no worktree could be created for this local repository
+	NotReadyReason string `json:"not_ready_reason,omitempty" example:"Repository has no commits yet (branch main is unborn)"`
+	// Toolchains detected in the working copy of local repositories
+	Toolchains []Toolchain `json:"toolchains,omitempty"`
 	// When this repository was first cloned
 	CreatedAt time.Time `json:"created_at" example:"2024-01-15T10:30:00Z"`
 	// When this repository was last accessed
@@ -59,6 +195,593 @@ type Repository struct {
 	RemoteOrigin string `json:"remote_origin,omitempty" example:"https://github.com/anthropics/claude-code.git"`
 	// Whether the remote origin is a GitHub repository
 	HasGitHubRemote bool `json:"has_github_remote" example:"true"`
+	// Whether destructive operations on the main repository need an explicit confirm and its
+	// branches are kept by cleanup, set for the catnip development repository in dev mode and
+	// for paths listed in CATNIP_PROTECTED_REPOS
+	Protected bool `json:"protected,omitempty" example:"true"`
+	// Why the repository is protected
+	ProtectedReason string `json:"protected_reason,omitempty" example:"catnip development repository serving the running code"`
+	// Additional remotes that pushed branches are mirrored to
+	Mirrors []*RepositoryMirror `json:"mirrors,omitempty"`
+	// Active health warnings keyed by source (e.g. "mirror:compliance")
+	HealthWarnings map[string]string `json:"health_warnings,omitempty"`
+	// Recent durations of major operations across all worktrees, keyed by operation (fetch, sync, merge, pull_request, diff)
+	OperationTimings map[string]*OperationTimings `json:"operation_timings,omitempty"`
+	// Size of the repository on GitHub and on disk
+	Size *RepositorySize `json:"size,omitempty"`
+	// When git gc last ran on the repository
+	LastGC *time.Time `json:"last_gc,omitempty" example:"2024-01-15T16:45:30Z"`
+	// Bytes the repository directory takes on disk, without its worktrees, from the last disk
+	// usage measurement; only set when a listing asks for disk usage
+	DiskUsageBytes int64 `json:"disk_usage_bytes,omitempty" example:"104857600"`
+	// Previous IDs of a repository renamed or transferred on GitHub, which still resolve to it
+	Aliases []string `json:"aliases,omitempty" example:"[\"anthropics/claude\"]"`
+	// Automatic worktree creation for labeled GitHub issues
+	IssueAutomation *IssueAutomation `json:"issue_automation,omitempty"`
+	// How pull request bodies use the repository's pull request template
+	PullRequestTemplate *PullRequestTemplateSettings `json:"pull_request_template,omitempty"`
+	// Whether the clone has its full history: shallow, unshallowing (being fetched in the
+	// background) or full; empty when unknown
+	History string `json:"history,omitempty" example:"shallow" enums:"shallow,unshallowing,full"`
+	// Whether the clone is in mirror mode: every branch of origin is fetched, so worktrees of
+	// branches already present are created without a fetch, and a periodic git remote update
+	// keeps them current
+	Mirror bool `json:"mirror,omitempty" example:"true"`
+	// When the branches of a mirrored repository were last updated from origin
+	LastRemoteUpdate *time.Time `json:"last_remote_update,omitempty" example:"2024-01-15T16:45:30Z"`
+	// Shell commands run in every new worktree after checkout, like installing dependencies
+	SetupCommands []string `json:"setup_commands,omitempty" example:"pnpm install"`
+}
+
+// Repository history states
+const (
+	RepositoryHistoryShallow      = "shallow"
+	RepositoryHistoryUnshallowing = "unshallowing"
+	RepositoryHistoryFull         = "full"
+)
+
+// Operation is a long running operation recorded in the operation journal, so its outcome
+// survives a server restart
+// @Description Tracked operation with its phase transitions and terminal state
+type Operation struct {
+	// Unique operation identifier
+	ID string `json:"id" example:"5f0c6a9e-8d1b-4c53-9a57-1f2e3d4c5b6a"`
+	// What the operation does: clone, merge, cleanup_merged or bulk_pull_requests
+	Kind string `json:"kind" example:"clone"`
+	// Repository or worktree the operation works on
+	Target string `json:"target" example:"anthropics/claude-code"`
+	// Parameters needed to resume or roll back the operation
+	Params map[string]string `json:"params,omitempty"`
+	// running, succeeded, failed or interrupted
+	State string `json:"state" example:"succeeded"`
+	// Phases entered so far, oldest first
+	Phases []OperationPhase `json:"phases"`
+	// Why the operation failed
+	Error string `json:"error,omitempty"`
+	// What startup did with an operation a restart interrupted: resuming, resumed, rolled_back or none
+	Recovery string `json:"recovery,omitempty" example:"resumed"`
+	// When the operation started
+	StartedAt time.Time `json:"started_at" example:"2024-01-15T16:45:30Z"`
+	// When the operation reached its terminal state
+	FinishedAt *time.Time `json:"finished_at,omitempty" example:"2024-01-15T16:46:02Z"`
+}
+
+// OperationPhase is a phase transition of a tracked operation
+type OperationPhase struct {
+	// Phase name, e.g. clone or merge
+	Name string `json:"name" example:"clone"`
+	// When the phase was entered
+	At time.Time `json:"at" example:"2024-01-15T16:45:30Z"`
+}
+
+// OperationBackup is a ref catnip points at a worktree's HEAD before rewriting its history, so
+// commits lost to a wrong choice can be restored
+// @Description Backup ref of a worktree's HEAD taken before a history rewriting operation
+type OperationBackup struct {
+	// Backup identifier, the UTC time it was taken
+	ID string `json:"id" example:"20240115T164530.123456789Z"`
+	// Worktree the backup belongs to
+	WorktreeID string `json:"worktree_id" example:"abc123-def456-ghi789"`
+	// Full name of the backup ref
+	Ref string `json:"ref" example:"refs/catnip/backup-ops/abc123-def456-ghi789/20240115T164530.123456789Z"`
+	// Commit HEAD pointed at before the operation
+	Commit string `json:"commit" example:"abc123def456"`
+	// Subject of that commit
+	Subject string `json:"subject,omitempty" example:"Add OAuth login"`
+	// Operation that rewrote the history, e.g. "rebase onto main"
+	Operation string `json:"operation,omitempty" example:"rebase onto main"`
+	// When the backup was taken
+	CreatedAt time.Time `json:"created_at" example:"2024-01-15T16:45:30Z"`
+	// When the refs cleanup deletes the backup
+	ExpiresAt time.Time `json:"expires_at" example:"2024-01-22T16:45:30Z"`
+}
+
+// WorktreeCommit is a commit on a worktree's branch since it forked from its source branch
+// @Description Commit made in a worktree, annotated with the session that created it
+type WorktreeCommit struct {
+	// Full commit hash
+	Hash string `json:"hash" example:"abc123def456"`
+	// Author name
+	Author string `json:"author" example:"Catnip"`
+	// Author email
+	AuthorEmail string `json:"author_email" example:"catnip@example.com"`
+	// Author date
+	Timestamp time.Time `json:"timestamp" example:"2024-01-15T16:45:30Z"`
+	// First line of the commit message
+	Subject string `json:"subject" example:"Add OAuth login checkpoint: 2"`
+	// Whether catnip created the commit as a checkpoint of a Claude session
+	IsCheckpoint bool `json:"is_checkpoint" example:"true"`
+	// Session title that was active when the commit was created
+	SessionTitle string `json:"session_title,omitempty" example:"Add OAuth login"`
+	// GPG or SSH signature of the commit
+	Signature CommitSignature `json:"signature"`
+	// Files changed by the commit, only when stats were requested
+	Files []CommitFileStat `json:"files,omitempty"`
+	// Lines changed in all files, only when stats were requested
+	Stats *DiffStats `json:"stats,omitempty"`
+	// Lines changed in files written by hand, without generated files, only when stats were
+	// requested
+	AuthoredStats *DiffStats `json:"authored_stats,omitempty"`
+}
+
+// Commit signature statuses
+const (
+	// Signed with a key in the keyring
+	SignatureGood = "good"
+	// Signature invalid, expired or made with an expired or revoked key
+	SignatureBad = "bad"
+	// Signed, but the key isn't in the keyring or the signature couldn't be checked
+	SignatureUnknown  = "unknown"
+	SignatureUnsigned = "unsigned"
+)
+
+// Summaries of the signatures of a range of commits
+const (
+	// Every commit has a good signature
+	SignaturesAllSigned = "all_signed"
+	// Some commits have a good signature
+	SignaturesMixed = "mixed"
+	// No commit has a good signature
+	SignaturesNone = "none"
+)
+
+// CommitSignature is the verification status of a commit's GPG or SSH signature
+type CommitSignature struct {
+	// good, bad, unknown or unsigned
+	Status string `json:"status" example:"good"`
+	// Identity the signature was made by
+	Signer string `json:"signer,omitempty" example:"Alice <alice@example.com>"`
+	// Fingerprint or ID of the signing key
+	Key string `json:"key,omitempty" example:"4AEE18F83AFDEB23"`
+}
+
+// MergedCommitSignature is the signature of one commit of a merge
+type MergedCommitSignature struct {
+	// Full commit hash
+	Hash string `json:"hash" example:"abc123def456"`
+	CommitSignature
+}
+
+// CommitFileStat counts the lines a commit changed in one file
+type CommitFileStat struct {
+	// File path, relative to the repository
+	Path string `json:"path" example:"src/login.ts"`
+	// Added lines
+	Additions int `json:"additions" example:"12"`
+	// Deleted lines
+	Deletions int `json:"deletions" example:"3"`
+	// Binary files count no lines
+	Binary bool `json:"binary,omitempty" example:"false"`
+	// Lockfile or file marked linguist-generated, not counted in AuthoredStats
+	Generated bool `json:"generated,omitempty" example:"false"`
+}
+
+// DiffStats counts the files and lines a change touched
+type DiffStats struct {
+	// Changed files
+	Files int `json:"files" example:"3"`
+	// Added lines
+	Additions int `json:"additions" example:"120"`
+	// Deleted lines
+	Deletions int `json:"deletions" example:"14"`
+}
+
+// WorktreeCommits is a page of the commits a worktree made since branching, newest first
+// @Description Page of a worktree's commits since it forked from its source branch
+type WorktreeCommits struct {
+	// Worktree ID
+	WorktreeID string `json:"worktree_id" example:"abc123-def456-ghi789"`
+	// Ref the commits are counted from
+	SourceRef string `json:"source_ref" example:"main"`
+	// Commits of this page, newest first
+	Commits []WorktreeCommit `json:"commits"`
+	// All commits since branching, not just this page
+	Total int `json:"total" example:"42"`
+	// Commits skipped
+	Offset int `json:"offset" example:"0"`
+	// Page size
+	Limit int `json:"limit" example:"50"`
+	// Whether more commits follow this page
+	HasMore bool `json:"has_more" example:"false"`
+}
+
+// ConflictBlob is one version of a conflicted file, as staged in the index
+type ConflictBlob struct {
+	// Blob hash
+	Hash string `json:"hash" example:"e69de29bb2d1d6434b8b29ae775ad8c2e48c5391"`
+	// File mode, e.g. 100644
+	Mode string `json:"mode" example:"100644"`
+	// Blob size in bytes
+	Size int64 `json:"size" example:"1024"`
+	// Blob content, empty for binary files and cut off for large ones
+	Content string `json:"content"`
+	// Whether Content was cut off
+	Truncated bool `json:"truncated,omitempty"`
+	// Whether the blob is binary, without content
+	Binary bool `json:"binary,omitempty"`
+}
+
+// ConflictFile is a file left conflicted by a merge, rebase or cherry-pick. Ours and theirs follow git: while
+// rebasing, ours is the branch rebased onto and theirs the commit being replayed. A version is
+// missing when that side deleted the file, or didn't have it.
+type ConflictFile struct {
+	// Path relative to the worktree
+	Path string `json:"path" example:"src/app.ts"`
+	// Status as git status --porcelain shows it, e.g. UU (both modified) or DU (deleted by us)
+	Status string `json:"status" example:"UU"`
+	// Common ancestor version
+	Base *ConflictBlob `json:"base,omitempty"`
+	// Our version
+	Ours *ConflictBlob `json:"ours,omitempty"`
+	// Their version
+	Theirs *ConflictBlob `json:"theirs,omitempty"`
+}
+
+// Conflict resolutions of ResolveConflictFile
+const (
+	// ConflictResolutionOurs keeps our version of the file
+	ConflictResolutionOurs = "ours"
+	// ConflictResolutionTheirs keeps their version of the file
+	ConflictResolutionTheirs = "theirs"
+	// ConflictResolutionContent replaces the file with given content
+	ConflictResolutionContent = "content"
+)
+
+// ConflictResolution resolves a conflicted file
+type ConflictResolution struct {
+	// ours, theirs or content
+	Resolution string `json:"resolution" example:"ours"`
+	// Resolved file content, with resolution content
+	Content string `json:"content,omitempty"`
+}
+
+// WorktreeConflicts lists the conflicts of a merge, rebase or cherry-pick in progress in a worktree
+type WorktreeConflicts struct {
+	// Worktree ID
+	WorktreeID string `json:"worktree_id" example:"abc123-def456-ghi789"`
+	// Operation in progress: merge, rebase or cherry-pick, empty when there is none
+	Operation string `json:"operation" example:"rebase"`
+	// Files still conflicted
+	Files []ConflictFile `json:"files"`
+}
+
+// IssueAutomation creates a worktree for each GitHub issue of a repository carrying a label
+// @Description Settings and issue to worktree mapping of automatic worktree creation from issues
+type IssueAutomation struct {
+	// Whether labeled issues get worktrees
+	Enabled bool `json:"enabled" example:"true"`
+	// Label requesting a worktree
+	Label string `json:"label" example:"catnip"`
+	// Branch worktrees are created from, the repository's default branch when empty
+	Branch string `json:"branch,omitempty" example:"main"`
+	// Issues seen with the label, keyed by number. Entries are kept so an issue never gets a second worktree.
+	Issues map[int]*AutomatedIssue `json:"issues,omitempty"`
+	// When GitHub was last asked for labeled issues
+	LastPolledAt *time.Time `json:"last_polled_at,omitempty" example:"2024-01-15T16:45:30Z"`
+	// Error of the last poll, cleared by a successful one
+	LastError string `json:"last_error,omitempty" example:"gh issue list failed: exit status 1"`
+}
+
+// CherryPickResult reports the commits CherryPickCommits applied to a worktree
+// @Description Outcome of cherry-picking commits into a worktree
+type CherryPickResult struct {
+	WorktreeID string `json:"worktree_id" example:"abc123-def456-ghi789"`
+	// Picked commits, oldest first, with the commits they became in the worktree
+	Applied []CherryPickedCommit `json:"applied"`
+	// Picked commits whose changes the worktree already had, so nothing was committed
+	Skipped []string `json:"skipped"`
+	// HEAD of the worktree afterwards
+	Head string `json:"head" example:"7f3c2a1b9e8d4c5f6a7b8c9d0e1f2a3b4c5d6e7f"`
+}
+
+// CherryPickedCommit is a commit cherry-picked into a worktree
+type CherryPickedCommit struct {
+	// Commit that was picked
+	Source string `json:"source" example:"1a2b3c4d5e6f7a8b9c0d1e2f3a4b5c6d7e8f9a0b"`
+	// Commit it became in the worktree
+	Commit string `json:"commit" example:"7f3c2a1b9e8d4c5f6a7b8c9d0e1f2a3b4c5d6e7f"`
+}
+
+// PatchApplyResult reports a patch applied to a worktree, or what applying it would do
+// @Description Outcome of applying an uploaded patch to a worktree
+type PatchApplyResult struct {
+	WorktreeID string `json:"worktree_id" example:"abc123-def456-ghi789"`
+	// "diff" for unified diffs, "format-patch" for git format-patch mails
+	Format string `json:"format" example:"format-patch"`
+	// Mails in a format-patch series, applied as one change; 1 for a diff
+	Patches int `json:"patches" example:"1"`
+	// Paths the patch touches, as they are after it applies
+	Files []string `json:"files"`
+	// Lines added and removed, binary files not counted
+	Additions int `json:"additions" example:"12"`
+	Deletions int `json:"deletions" example:"3"`
+	// Files changed by git binary patches
+	BinaryFiles []string `json:"binary_files,omitempty"`
+	// Set when nothing was written: the result is what applying the patch would do
+	DryRun bool `json:"dry_run"`
+	// Files a dry run of a three-way apply would leave conflicted
+	ConflictFiles []string `json:"conflict_files,omitempty"`
+	// Commit created after applying, when asked for
+	Commit string `json:"commit,omitempty" example:"7f3c2a1b9e8d4c5f6a7b8c9d0e1f2a3b4c5d6e7f"`
+}
+
+// PatchUpload is a patch being uploaded to a worktree in chunks
+// @Description Chunked patch upload, applied once complete
+type PatchUpload struct {
+	ID         string `json:"id" example:"3f9a1c2b7d4e5f60"`
+	WorktreeID string `json:"worktree_id" example:"abc123-def456-ghi789"`
+	// Bytes received so far, the offset of the next chunk
+	Size int64 `json:"size" example:"1048576"`
+	// Largest patch accepted, in bytes
+	MaxSize int64 `json:"max_size" example:"268435456"`
+	// When the upload is discarded unless applied
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// CheckpointError is a problem a checkpoint commit of a worktree ran into
+// @Description Failed checkpoint, or commit hooks that failed on a checkpoint
+type CheckpointError struct {
+	// What went wrong
+	Message string `json:"message" example:"commit hooks failed, checkpoint committed without them"`
+	// Output of the commit hooks, when they failed
+	HookOutput string `json:"hook_output,omitempty" example:"prettier: src/app.ts needs formatting"`
+	// Whether the checkpoint was committed anyway, without running the hooks
+	Committed bool `json:"committed" example:"true"`
+	// When it happened
+	At time.Time `json:"at" example:"2024-01-15T16:30:00Z"`
+}
+
+// WorktreeHandoff records a worktree being handed off to a human
+// @Description Who took a worktree over from catnip's automation, and what was left for them
+type WorktreeHandoff struct {
+	// Actor who took the worktree over (from the X-Catnip-Actor header, "unknown" if not provided)
+	By string `json:"by" example:"alice"`
+	// When the worktree was handed off
+	At time.Time `json:"at" example:"2024-01-15T16:30:00Z"`
+	// Final checkpoint committed before handing off, empty when there was nothing to commit
+	Checkpoint string `json:"checkpoint,omitempty" example:"7f3c2a1b9e8d4c5f6a7b8c9d0e1f2a3b4c5d6e7f"`
+	// Preview branch created or updated in the local repository for the human to pick up
+	PreviewBranch string `json:"preview_branch,omitempty" example:"catnip/felix"`
+}
+
+// GitTag is a tag of a repository
+// @Description Tag of a repository and the commit it points at
+type GitTag struct {
+	// Tag name
+	Name string `json:"name" example:"v1.2.0-rc1"`
+	// Commit the tag points at
+	Commit string `json:"commit" example:"7f3c2a1b9e8d4c5f6a7b8c9d0e1f2a3b4c5d6e7f"`
+	// Whether the tag is annotated, with a message, rather than lightweight
+	Annotated bool `json:"annotated"`
+	// Subject of an annotated tag's message
+	Message string `json:"message,omitempty" example:"Release candidate 1"`
+	// When an annotated tag was created, or the commit of a lightweight tag was
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// PullRequestTemplateSettings configures how catnip's generated pull request content is combined
+// with a repository's pull request template
+// @Description Per-repository pull request template settings
+type PullRequestTemplateSettings struct {
+	// append (the default) adds the generated content after the template with a separator,
+	// sections fills it in under the template's matching headings, off ignores the template
+	Mode string `json:"mode" example:"append" enums:"append,sections,off"`
+	// Template to use from a directory of multiple templates, by file name; the first
+	// alphabetically when empty
+	Template string `json:"template,omitempty" example:"feature.md"`
+}
+
+// PullRequestBodyPreview is the body a pull request of a worktree would be created with
+// @Description Pull request body with the repository's template applied
+type PullRequestBodyPreview struct {
+	// Body the pull request would be created with
+	Body string `json:"body"`
+	// Template used, relative to the worktree; empty when none was
+	Template string `json:"template,omitempty" example:".github/PULL_REQUEST_TEMPLATE.md"`
+	// Templates found in the worktree, in the order GitHub looks them up
+	Templates []string `json:"templates"`
+	// How the template was applied: append, sections or off
+	Mode string `json:"mode" example:"append"`
+	// Why a template that was found wasn't used
+	Warning string `json:"warning,omitempty" example:"pull request template .github/PULL_REQUEST_TEMPLATE.md is larger than 64KB"`
+}
+
+// AutomatedIssue links a labeled GitHub issue to the worktree created for it
+// @Description GitHub issue handled by issue automation
+type AutomatedIssue struct {
+	// Issue number
+	Number int `json:"number" example:"42"`
+	// Issue title when it was first seen
+	Title string `json:"title" example:"Login fails with SSO"`
+	// Issue URL
+	URL string `json:"url" example:"https://github.com/anthropics/claude-code/issues/42"`
+	// Worktree created for the issue, empty until creation succeeded
+	WorktreeID string `json:"worktree_id,omitempty" example:"abc123-def456-ghi789"`
+	// Name of the worktree created for the issue
+	WorktreeName string `json:"worktree_name,omitempty" example:"claude-code/felix"`
+	// When the worktree was created
+	LinkedAt *time.Time `json:"linked_at,omitempty" example:"2024-01-15T16:45:30Z"`
+	// Whether the comment naming the worktree was posted on the issue
+	Commented bool `json:"commented" example:"true"`
+	// Why automation stopped for the issue: unlabeled or closed. The worktree is kept.
+	Stopped string `json:"stopped,omitempty" example:"closed"`
+	// Error of the last attempt to create the worktree or post the comment
+	LastError string `json:"last_error,omitempty"`
+}
+
+// RepositorySize describes how large a repository is, remotely and on the volume
+// @Description Repository size reported by GitHub and measured on disk
+type RepositorySize struct {
+	// Full repository size reported by GitHub in KiB (0 when unknown)
+	RemoteKB int64 `json:"remote_kb,omitempty" example:"524288"`
+	// Size of the local objects directory in KiB
+	LocalKB int64 `json:"local_kb" example:"102400"`
+	// Number of loose and packed objects
+	ObjectCount int64 `json:"object_count" example:"48213"`
+	// Number of pack files
+	PackCount int64 `json:"pack_count" example:"2"`
+	// Whether the local repository has shallow history
+	Shallow bool `json:"shallow" example:"true"`
+	// When the local size was last measured
+	MeasuredAt time.Time `json:"measured_at" example:"2024-01-15T16:45:30Z"`
+}
+
+// ObjectStats counts the objects of a repository, as reported by git count-objects
+type ObjectStats struct {
+	// Number of loose objects
+	LooseObjects int64 `json:"loose_objects" example:"5230"`
+	// Size of the loose objects in KiB
+	LooseKB int64 `json:"loose_kb" example:"81920"`
+	// Number of pack files
+	Packs int64 `json:"packs" example:"12"`
+	// Size of the pack files in KiB
+	PackKB int64 `json:"pack_kb" example:"102400"`
+	// Size of garbage files in the objects directory in KiB
+	GarbageKB int64 `json:"garbage_kb,omitempty" example:"0"`
+}
+
+// RepositoryMaintenance is the outcome of checking a repository for garbage collection
+// @Description Which git gc ran on a repository and why, with the object counts before and after
+type RepositoryMaintenance struct {
+	RepoID string `json:"repo_id" example:"anthropics/claude-code"`
+	// gc that ran: none when no threshold was exceeded, auto for git gc --auto, full for git gc
+	Action string `json:"action" example:"auto" enums:"none,auto,full"`
+	// Threshold that was exceeded, or requested for a full gc asked for explicitly
+	Reason string `json:"reason,omitempty" example:"5230 loose objects, more than 2000"`
+	// Objects before the gc
+	Before ObjectStats `json:"before"`
+	// Objects after the gc; absent when none ran
+	After *ObjectStats `json:"after,omitempty"`
+	// How long the gc took in milliseconds
+	DurationMS int64 `json:"duration_ms" example:"4210"`
+}
+
+// DiskUsage breaks down the space repositories and their worktrees take on disk
+// @Description Disk usage per repository and worktree, with totals
+type DiskUsage struct {
+	// Repositories with their worktrees, largest first
+	Repositories []RepositoryDiskUsage `json:"repositories"`
+	// Bytes of all repository directories, including their objects
+	RepositoryBytes int64 `json:"repository_bytes" example:"104857600"`
+	// Bytes of all worktrees
+	WorktreeBytes int64 `json:"worktree_bytes" example:"41943040"`
+	// Sum of repository and worktree bytes
+	TotalBytes int64 `json:"total_bytes" example:"146800640"`
+	// When the measurement finished
+	MeasuredAt time.Time `json:"measured_at" example:"2024-01-15T16:45:30Z"`
+	// How long the measurement took in milliseconds
+	DurationMS int64 `json:"duration_ms" example:"850"`
+}
+
+// RepositoryDiskUsage is the space one repository and its worktrees take on disk
+type RepositoryDiskUsage struct {
+	// Repository identifier in owner/repo format
+	RepoID string `json:"repo_id" example:"anthropics/claude-code"`
+	// Repository directory, empty for worktrees whose repository is gone
+	Path string `json:"path,omitempty" example:"/workspace/repos/anthropics_claude-code.git"`
+	// Bytes of the repository directory, including the objects its worktrees share
+	Bytes int64 `json:"bytes" example:"104857600"`
+	// Bytes of the repository's worktrees
+	WorktreeBytes int64 `json:"worktree_bytes" example:"41943040"`
+	// Sum of repository and worktree bytes
+	TotalBytes int64 `json:"total_bytes" example:"146800640"`
+	// Worktrees of the repository, largest first
+	Worktrees []WorktreeDiskUsage `json:"worktrees"`
+}
+
+// WorktreeDiskUsage is the space one worktree takes on disk
+type WorktreeDiskUsage struct {
+	WorktreeID string `json:"worktree_id" example:"abc123-def456-ghi789"`
+	Name       string `json:"name" example:"claude-code/felix"`
+	Path       string `json:"path" example:"/workspace/claude-code/felix"`
+	// Bytes of the worktree's files, without the repository objects it shares
+	Bytes int64 `json:"bytes" example:"20971520"`
+	// Whether the worktree directory is missing
+	Missing bool `json:"missing,omitempty" example:"false"`
+}
+
+// OrphanBranch is a branch with commits ahead of the default branch that no worktree has
+// checked out, typically created from a terminal
+// @Description Branch with unmerged commits and no worktree, which can be adopted into a new worktree
+type OrphanBranch struct {
+	// Branch name as a worktree would record it: the short name, or the full ref for catnip refs
+	Branch string `json:"branch" example:"feature/retry-uploads"`
+	// Full ref name
+	Ref string `json:"ref" example:"refs/heads/feature/retry-uploads"`
+	// Commit the branch points to
+	Commit string `json:"commit" example:"9f2c1e4b7a3d5e6f8091a2b3c4d5e6f708192a3b"`
+	// Commits on the branch that aren't on the default branch
+	CommitsAhead int `json:"commits_ahead" example:"3"`
+	// Committer date of the branch tip
+	CommittedAt time.Time `json:"committed_at" example:"2024-01-15T16:45:30Z"`
+	// Whether the branch is in catnip's namespace (refs/catnip/ or catnip/)
+	Catnip bool `json:"catnip,omitempty" example:"false"`
+}
+
+// OperationTimings is a rolling window of recent durations for one kind of operation
+// @Description Recent operation durations with averages split into network and local git time
+type OperationTimings struct {
+	// Most recent samples, oldest first
+	Samples []OperationTimingSample `json:"samples"`
+	// Average total duration of the samples in milliseconds
+	AverageMs int64 `json:"average_ms" example:"35000"`
+	// Average time spent in network git commands (fetch/push) in milliseconds
+	AverageNetworkMs int64 `json:"average_network_ms" example:"28000"`
+	// Average time spent in local git commands in milliseconds
+	AverageLocalMs int64 `json:"average_local_ms" example:"4000"`
+}
+
+// OperationTimingSample is a single measured operation
+type OperationTimingSample struct {
+	// When the operation finished
+	At time.Time `json:"at" example:"2024-01-15T16:45:30Z"`
+	// Wall clock duration in milliseconds
+	DurationMs int64 `json:"duration_ms" example:"35120"`
+	// Time spent in network git commands in milliseconds
+	NetworkMs int64 `json:"network_ms" example:"28010"`
+	// Time spent in local git commands in milliseconds
+	LocalMs int64 `json:"local_ms" example:"4230"`
+	// Whether the operation succeeded
+	Success bool `json:"success" example:"true"`
+}
+
+// RepositoryMirror is a secondary remote kept in sync with branches catnip pushes
+// @Description Mirror remote configuration and sync status
+type RepositoryMirror struct {
+	// Unique mirror name within the repository
+	Name string `json:"name" example:"compliance"`
+	// Remote URL to push to
+	URL string `json:"url" example:"https://git.internal.example.com/team/repo.git"`
+	// Credential reference resolved by the credential provider (an environment variable name by default)
+	Credential string `json:"credential,omitempty" example:"CATNIP_MIRROR_TOKEN"`
+	// When the last push to this mirror succeeded
+	LastSuccess *time.Time `json:"last_success,omitempty" example:"2024-01-15T16:45:30Z"`
+	// Error from the last failed push (cleared on success)
+	LastError string `json:"last_error,omitempty" example:"git push failed: authentication required"`
+	// When the last push to this mirror failed
+	LastErrorAt *time.Time `json:"last_error_at,omitempty" example:"2024-01-15T16:40:00Z"`
+	// Number of failed pushes since the last success
+	ConsecutiveFailures int `json:"consecutive_failures" example:"0"`
 }
 
 // Worktree represents a Git worktree
@@ -76,6 +799,10 @@ type Worktree struct {
 	Branch string `json:"branch" example:"feature/api-docs"`
 	// Branch this worktree was originally created from
 	SourceBranch string `json:"source_branch" example:"main"`
+	// Tag (refs/tags/<name>) or commit this worktree was created at when checked out at one
+	// instead of a branch tip. Diffs and commit counts compare against it, SourceBranch is the
+	// branch containing it.
+	SourceRef string `json:"source_ref,omitempty" example:"refs/tags/v1.2.0"`
 	// Whether this worktree's branch has been renamed from its original catnip ref
 	HasBeenRenamed bool `json:"has_been_renamed" example:"true"`
 	// Commit hash where this worktree diverged from source branch (updated after merges)
@@ -84,12 +811,29 @@ type Worktree struct {
 	CommitCount int `json:"commit_count" example:"3"`
 	// Number of commits the source branch is ahead of our divergence point
 	CommitsBehind int `json:"commits_behind" example:"2"`
+	// Tip of the remote source branch seen by the last fetch, to detect force pushes
+	SourceTip string `json:"source_tip,omitempty" example:"abc123def456"`
+	// Set when the remote source branch was force-pushed; commits behind are unreliable and syncs
+	// are refused until the rewrite is acknowledged
+	SourceBranchRewritten *SourceRewrite `json:"source_branch_rewritten,omitempty"`
+	// Worktree group the worktree belongs to, if any
+	GroupID string `json:"group_id,omitempty" example:"abc123-def456-ghi789"`
+	// all_signed, mixed or none, summarizing the signatures of the commits ahead of the source
+	// branch; empty without commits ahead
+	CommitSignatures string `json:"commit_signatures,omitempty" example:"mixed"`
+	// Bytes the worktree takes on disk, without the repository objects it shares, from the last
+	// disk usage measurement; only set when a listing asks for disk usage
+	DiskUsageBytes int64 `json:"disk_usage_bytes,omitempty" example:"20971520"`
+	// Number of stash entries holding work set aside in this worktree
+	StashCount int `json:"stash_count" example:"1"`
 	// Whether there are uncommitted changes in the worktree
 	IsDirty bool `json:"is_dirty" example:"true"`
 	// Whether the worktree is in a conflicted state (rebase/merge conflicts)
 	HasConflicts bool `json:"has_conflicts" example:"false"`
 	// When this worktree was created
 	CreatedAt time.Time `json:"created_at" example:"2024-01-15T14:00:00Z"`
+	// Actor who created this worktree (from the X-Catnip-Actor header, "unknown" if not provided)
+	CreatedBy string `json:"created_by,omitempty" example:"alice"`
 	// When this worktree was last accessed
 	LastAccessed time.Time `json:"last_accessed" example:"2024-01-15T16:30:00Z"`
 	// Current session title (from terminal title escape sequences)
@@ -110,12 +854,392 @@ type Worktree struct {
 	PullRequestState string `json:"pull_request_state,omitempty" example:"open"`
 	// Last time the PR state was synced
 	PullRequestLastSynced *time.Time `json:"pull_request_last_synced,omitempty"`
+	// Branch the pull request targets on GitHub, which may differ from the source branch when the PR was retargeted
+	PullRequestBaseBranch string `json:"pull_request_base_branch,omitempty" example:"release/1.2"`
+	// Number of commits ahead of the pull request base branch (only set when a PR base is known)
+	PullRequestCommitCount int `json:"pull_request_commit_count,omitempty" example:"3"`
+	// Number of commits the pull request base branch is ahead of the worktree (only set when a PR base is known)
+	PullRequestCommitsBehind int `json:"pull_request_commits_behind,omitempty" example:"5"`
 	// Current todos from the most recent TodoWrite in Claude session
 	Todos []Todo `json:"todos,omitempty"`
 	// Latest user prompt from ~/.claude.json history
 	LatestUserPrompt string `json:"latest_user_prompt,omitempty"`
 	// Latest session title from the current session (simplified string version)
 	LatestSessionTitle string `json:"latest_session_title,omitempty"`
+	// Whether checkpoint commits are on hold because Claude is in plan mode
+	CheckpointsHeld bool `json:"checkpoints_held,omitempty" example:"false"`
+	// Checkpoint settings of this worktree, nil for the defaults (enabled, CATNIP_COMMIT_TIMEOUT_SECONDS)
+	CheckpointConfig *CheckpointConfig `json:"checkpoint_config,omitempty"`
+	// Whether checkpoint commits run the repository's commit hooks, e.g. a formatting pre-commit
+	// hook, instead of skipping them
+	RunHooksOnCheckpoint bool `json:"run_hooks_on_checkpoint,omitempty" example:"false"`
+	// Last problem a checkpoint commit ran into, cleared by the next successful checkpoint
+	LastCheckpointError *CheckpointError `json:"last_checkpoint_error,omitempty"`
+	// Names of environment variables whose values are secret, masked like those with sensitive
+	// names in captured output, events, logs and exports
+	SecretKeys []string `json:"secret_keys,omitempty" example:"STRIPE_KEY"`
+	// Progress of the repository's setup commands in this worktree (pending, running, succeeded
+	// or failed), empty when the repository has none
+	SetupStatus string `json:"setup_status,omitempty" example:"running"`
+	// Set while a human has taken the worktree over; checkpoints, branch renames, commit syncing
+	// and session title tracking are suspended, API-triggered operations still work
+	HandedOff *WorktreeHandoff `json:"handed_off,omitempty"`
+	// Recent durations of major operations on this worktree, keyed by operation (fetch, sync, merge, pull_request, diff)
+	OperationTimings map[string]*OperationTimings `json:"operation_timings,omitempty"`
+	// Outcome of the last branch rename attempt, explaining why the branch kept its catnip name
+	// or how a name collision was handled
+	BranchRename *BranchRenameOutcome `json:"branch_rename,omitempty"`
+	// Worktrees linked to this one, e.g. because they targeted the same branch name
+	RelatedWorktreeIDs []string `json:"related_worktree_ids,omitempty"`
+	// Free-form notes recorded by catnip about this worktree
+	Notes []string `json:"notes,omitempty"`
+	// Set when the checked-out branch was changed outside catnip; pull requests and merges are
+	// blocked until the change is acknowledged
+	ManualIntervention *BranchDrift `json:"manual_intervention,omitempty"`
+	// Toolchains detected from manifest files when the worktree was created
+	Toolchains []Toolchain `json:"toolchains,omitempty"`
+	// Directories that keep getting committed by checkpoints and probably should be ignored
+	IgnoreSuggestions []IgnoreSuggestion `json:"ignore_suggestions,omitempty"`
+	// Why and how this worktree was created
+	CreationContext *CreationContext `json:"creation_context,omitempty"`
+	// Outcome of the last run of the repository's validation commands in this worktree
+	Validation *ValidationResult `json:"validation,omitempty"`
+	// Which status refresh tier last populated each group of status fields, and when
+	StatusFreshness *WorktreeStatusFreshness `json:"status_freshness,omitempty"`
+	// Set when git no longer knows the worktree, e.g. after git worktree remove ran outside
+	// catnip; missing worktrees aren't recreated on boot and can only be deleted
+	Missing bool `json:"missing,omitempty" example:"false"`
+}
+
+// CreationSource is where a worktree creation came from
+type CreationSource string
+
+const (
+	// CreationSourceUI is the web UI
+	CreationSourceUI CreationSource = "ui"
+	// CreationSourceTUI is the terminal UI
+	CreationSourceTUI CreationSource = "tui"
+	// CreationSourceAPI is any other API caller, like automation scripts
+	CreationSourceAPI CreationSource = "api"
+	// CreationSourceAuto is catnip itself: CATNIP_REPO checkout, restoration, external workspaces
+	CreationSourceAuto CreationSource = "auto"
+)
+
+// CreationContext records why a worktree exists
+// @Description Source, initial prompt and linked issue of a worktree creation
+type CreationContext struct {
+	// Where the creation came from (ui, tui, api, auto)
+	Source CreationSource `json:"source" example:"ui"`
+	// Initial prompt, given at creation or captured from the first session title event
+	Prompt string `json:"prompt,omitempty" example:"Add OAuth login"`
+	// Linked issue, as a number or URL
+	Issue string `json:"issue,omitempty" example:"#123"`
+	// Template the project was created from
+	Template string `json:"template,omitempty" example:"react-vite"`
+	// Actor who requested the creation
+	Actor string `json:"actor,omitempty" example:"alice"`
+	// When the worktree was created
+	CreatedAt time.Time `json:"created_at" example:"2024-01-15T14:00:00Z"`
+}
+
+// IgnoreSuggestion is a directory committed by several consecutive checkpoints that is not
+// part of the source branch, like build output or a virtualenv
+// @Description Directory that keeps appearing in checkpoints and could be ignored
+type IgnoreSuggestion struct {
+	// Ignore pattern for the directory
+	Pattern string `json:"pattern" example:"dist/"`
+	// Files of the directory committed across the checkpoints
+	Files int `json:"files" example:"1240"`
+	// Consecutive checkpoints that committed files of the directory
+	Checkpoints int `json:"checkpoints" example:"6"`
+}
+
+// Toolchain is a language toolchain detected from the manifest files of a checkout
+// @Description Language toolchain, package manager and version detected from manifest files
+type Toolchain struct {
+	// Toolchain name: node, typescript, go, rust, python, ruby or java
+	Name string `json:"name" example:"node"`
+	// Package manager used by the project
+	PackageManager string `json:"package_manager,omitempty" example:"pnpm"`
+	// Version requested by the project, when declared
+	Version string `json:"version,omitempty" example:"20"`
+	// Manifest file the toolchain was detected from
+	Manifest string `json:"manifest" example:"package.json"`
+	// Lockfile maintained by the package manager, when present
+	Lockfile string `json:"lockfile,omitempty" example:"pnpm-lock.yaml"`
+}
+
+// DependencyCacheUsage is the size of a package cache shared across worktrees
+// @Description Location, size and last use of a shared package cache
+type DependencyCacheUsage struct {
+	// Cache name: npm, pnpm, cargo, go or pip
+	Name string `json:"name" example:"pnpm"`
+	// Environment variable pointing the package manager at the cache
+	EnvVar string `json:"env_var" example:"PNPM_STORE_DIR"`
+	// Cache directory on the volume
+	Path string `json:"path" example:"/volume/cache/pnpm"`
+	// Size of the cache in KiB
+	SizeKB int64 `json:"size_kb" example:"1048576"`
+	// Most recent access to a file of the cache (nil when the cache is empty)
+	LastUsed *time.Time `json:"last_used,omitempty"`
+}
+
+// DependencyCacheReport describes the shared package caches and the outcome of a trim
+// @Description Sizes of the shared package caches against their cap
+type DependencyCacheReport struct {
+	Caches []DependencyCacheUsage `json:"caches"`
+	// Combined size of all caches in KiB
+	TotalKB int64 `json:"total_kb" example:"2097152"`
+	// Cap trims shrink the caches below in KiB (0 when uncapped)
+	MaxSizeKB int64 `json:"max_size_kb" example:"20971520"`
+	// Cache entries removed by a trim, least recently used first
+	Removed []string `json:"removed,omitempty" example:"[\"go/github.com\"]"`
+	// Space freed by a trim in KiB
+	FreedKB int64 `json:"freed_kb,omitempty" example:"524288"`
+}
+
+// Setup statuses of a worktree
+const (
+	SetupPending   = "pending"
+	SetupRunning   = "running"
+	SetupSucceeded = "succeeded"
+	SetupFailed    = "failed"
+)
+
+// Validation statuses
+const (
+	ValidationPassed  = "passed"
+	ValidationFailed  = "failed"
+	ValidationSkipped = "skipped"
+	// No validation commands are configured for the repository; never recorded on worktrees
+	ValidationNotConfigured = "not_configured"
+)
+
+// ValidationResult is the outcome of running a repository's validation commands in a worktree
+// @Description Outcome of the validation commands run before merges and pull request pushes
+type ValidationResult struct {
+	// passed, failed, skipped (bypassed for an emergency) or not_configured
+	Status string `json:"status" example:"failed"`
+	// HEAD the validation ran on; results are reused while HEAD and the commands stay the same
+	CommitHash string `json:"commit_hash,omitempty" example:"abc123def456"`
+	// Operation that ran or skipped the validation (merge, pull_request, manual)
+	Operation string `json:"operation,omitempty" example:"merge"`
+	// Commands run, in order, up to the first failure
+	Commands   []ValidationCommandResult `json:"commands,omitempty"`
+	StartedAt  time.Time                 `json:"started_at" example:"2024-01-15T16:45:30Z"`
+	FinishedAt time.Time                 `json:"finished_at" example:"2024-01-15T16:46:10Z"`
+}
+
+// ValidationCommandResult is the outcome of a single validation command
+// @Description Exit code, duration and output of a validation command
+type ValidationCommandResult struct {
+	Command  string `json:"command" example:"go test ./..."`
+	ExitCode int    `json:"exit_code" example:"1"`
+	// Whether the command was killed for running longer than the validation timeout
+	TimedOut   bool  `json:"timed_out,omitempty" example:"false"`
+	DurationMS int64 `json:"duration_ms" example:"5320"`
+	// Combined stdout and stderr, keeping only the end of long output
+	Output string `json:"output,omitempty" example:"--- FAIL: TestLogin"`
+}
+
+// StatusTier is how much work a worktree status refresh does. Each tier includes the ones
+// before it.
+type StatusTier string
+
+const (
+	// StatusTierNone runs no git at all, statuses are served as last stored
+	StatusTierNone StatusTier = "none"
+	// StatusTierCheap reads the working tree: one porcelain status, stashes, HEAD and branch
+	StatusTierCheap StatusTier = "cheap"
+	// StatusTierStandard adds ahead/behind counts against the refs already present locally
+	StatusTierStandard StatusTier = "standard"
+	// StatusTierFull fetches the source and pull request base branches before counting
+	StatusTierFull StatusTier = "full"
+)
+
+var statusTierRanks = map[StatusTier]int{
+	StatusTierNone:     0,
+	StatusTierCheap:    1,
+	StatusTierStandard: 2,
+	StatusTierFull:     3,
+}
+
+// ParseStatusTier returns the tier named by s, StatusTierCheap when s is empty
+func ParseStatusTier(s string) (StatusTier, error) {
+	if s == "" {
+		return StatusTierCheap, nil
+	}
+	tier := StatusTier(s)
+	if _, ok := statusTierRanks[tier]; !ok {
+		return "", fmt.Errorf("unknown status tier %q, expected none, cheap, standard or full", s)
+	}
+	return tier, nil
+}
+
+// Includes reports whether a refresh at t does all the work of a refresh at other
+func (t StatusTier) Includes(other StatusTier) bool {
+	return statusTierRanks[t] >= statusTierRanks[other]
+}
+
+// StatusFieldTiers maps each refreshed status field, by JSON name, to the lowest tier that
+// populates it
+var StatusFieldTiers = map[string]StatusTier{
+	"is_dirty":                    StatusTierCheap,
+	"has_conflicts":               StatusTierCheap,
+	"stash_count":                 StatusTierCheap,
+	"branch":                      StatusTierCheap,
+	"commit_hash":                 StatusTierCheap,
+	"commit_count":                StatusTierStandard,
+	"commits_behind":              StatusTierStandard,
+	"pull_request_commit_count":   StatusTierStandard,
+	"pull_request_commits_behind": StatusTierStandard,
+}
+
+// StatusFreshness records the refresh that last populated a group of status fields
+// @Description Tier and time of the refresh that last populated a group of status fields
+type StatusFreshness struct {
+	// Tier of the refresh
+	Tier StatusTier `json:"tier" example:"standard"`
+	// When the refresh ran
+	RefreshedAt time.Time `json:"refreshed_at" example:"2024-01-15T16:45:30Z"`
+}
+
+// WorktreeStatusFreshness tells how stale each group of a worktree's status fields is. A nil
+// group was never refreshed since catnip started.
+// @Description Last refresh of the working tree, divergence and remote status field groups
+type WorktreeStatusFreshness struct {
+	// is_dirty, has_conflicts, stash_count, branch and commit_hash
+	WorkingTree *StatusFreshness `json:"working_tree,omitempty"`
+	// commit_count, commits_behind and the pull request counts, against local refs
+	Divergence *StatusFreshness `json:"divergence,omitempty"`
+	// Remote-tracking refs the divergence is counted against, updated by fetching
+	Remote *StatusFreshness `json:"remote,omitempty"`
+}
+
+// Stamped returns a copy of f with the groups a refresh at tier populates set to it. The
+// remote group is only stamped when fetched is set, as full refreshes may fail to fetch.
+func (f *WorktreeStatusFreshness) Stamped(tier StatusTier, at time.Time, fetched bool) *WorktreeStatusFreshness {
+	stamped := &WorktreeStatusFreshness{}
+	if f != nil {
+		*stamped = *f
+	}
+	stamp := &StatusFreshness{Tier: tier, RefreshedAt: at}
+	if tier.Includes(StatusTierCheap) {
+		stamped.WorkingTree = stamp
+	}
+	if tier.Includes(StatusTierStandard) {
+		stamped.Divergence = stamp
+	}
+	if tier.Includes(StatusTierFull) && fetched {
+		stamped.Remote = stamp
+	}
+	return stamped
+}
+
+// Merged returns the most recent refresh of each group of f and other
+func (f *WorktreeStatusFreshness) Merged(other *WorktreeStatusFreshness) *WorktreeStatusFreshness {
+	if f == nil {
+		return other
+	}
+	if other == nil {
+		return f
+	}
+	latest := func(a, b *StatusFreshness) *StatusFreshness {
+		if a == nil || (b != nil && b.RefreshedAt.After(a.RefreshedAt)) {
+			return b
+		}
+		return a
+	}
+	return &WorktreeStatusFreshness{
+		WorkingTree: latest(f.WorkingTree, other.WorkingTree),
+		Divergence:  latest(f.Divergence, other.Divergence),
+		Remote:      latest(f.Remote, other.Remote),
+	}
+}
+
+// BranchDrift describes a worktree whose checked-out branch no longer matches catnip's state
+// @Description Expected and actual branch and commit of a worktree changed outside catnip
+type BranchDrift struct {
+	// Branch catnip's state says the worktree is on
+	ExpectedBranch string `json:"expected_branch" example:"feature/add-auth"`
+	// Branch actually checked out (empty when HEAD is detached)
+	ActualBranch string `json:"actual_branch" example:"main"`
+	// Commit recorded in catnip's state
+	ExpectedCommit string `json:"expected_commit,omitempty" example:"abc123def456"`
+	// Commit HEAD actually points at
+	ActualCommit string `json:"actual_commit,omitempty" example:"789abc012def"`
+	// Whether catnip adopted the actual branch automatically
+	Adopted bool `json:"adopted,omitempty" example:"false"`
+	// When the drift was detected
+	DetectedAt time.Time `json:"detected_at" example:"2024-01-15T16:45:30Z"`
+}
+
+// SourceRewrite describes a force push of a worktree's remote source branch
+// @Description Tips of a source branch before and after it was force-pushed
+type SourceRewrite struct {
+	// Source branch that was rewritten
+	Branch string `json:"branch" example:"main"`
+	// Tip the worktree last saw, no longer an ancestor of the new tip
+	PreviousTip string `json:"previous_tip" example:"abc123def456"`
+	// Tip after the force push
+	NewTip string `json:"new_tip" example:"789abc012def"`
+	// When the rewrite was detected
+	DetectedAt time.Time `json:"detected_at" example:"2024-01-15T16:45:30Z"`
+}
+
+// HealthFinding is a problem found by a worktree health check
+// @Description Severity, code, explanation and remediation of a worktree problem
+type HealthFinding struct {
+	// error, warning or info
+	Severity string `json:"severity" example:"warning"`
+	// Stable identifier of the problem (missing_path, branch_drift, stale_base, ...)
+	Code string `json:"code" example:"remote_config_drift"`
+	// Human readable explanation for the UI
+	Message string `json:"message" example:"Remote configuration of anthropics/claude-code drifted: origin points at a different repository"`
+	// Remediation action that fixes the problem, empty when it needs manual attention
+	// (recreate, repair-remote-config, acknowledge-branch-drift, sync, validate, trim-caches)
+	Action string `json:"action,omitempty" example:"repair-remote-config"`
+}
+
+// WorktreeHealth lists everything known to be wrong with a worktree
+// @Description Findings of a worktree health check, empty when the worktree is healthy
+type WorktreeHealth struct {
+	WorktreeID string          `json:"worktree_id" example:"abc123-def456-ghi789"`
+	Findings   []HealthFinding `json:"findings"`
+	CheckedAt  time.Time       `json:"checked_at" example:"2024-01-15T16:45:30Z"`
+}
+
+// CheckpointConfig controls the automatic commits catnip makes in a worktree
+// @Description Whether a worktree takes checkpoint commits and how often
+type CheckpointConfig struct {
+	// Whether checkpoints and title change commits are made. Session titles are tracked either way.
+	Enabled bool `json:"enabled" example:"true"`
+	// Seconds between checkpoints, 0 for the CATNIP_COMMIT_TIMEOUT_SECONDS default
+	TimeoutSeconds int `json:"timeout_seconds,omitempty" example:"60"`
+}
+
+// BranchRenameOutcome describes what happened when catnip tried to give a worktree a nice branch name
+// @Description Result of a branch rename attempt including why it was skipped and collision handling
+type BranchRenameOutcome struct {
+	// What started the attempt (title, todo, manual)
+	Trigger string `json:"trigger,omitempty" example:"title"`
+	// Collision policy in effect (suffix, skip, adopt)
+	Policy string `json:"policy,omitempty" example:"adopt"`
+	// What happened (renamed, suffixed, adopted, skipped, failed)
+	Outcome string `json:"outcome" example:"adopted"`
+	// Why the rename was skipped or failed (claude_timeout, claude_error, empty_suggestion,
+	// invalid_suggestion, not_catnip_branch, rename_in_progress, branch_exists, ...)
+	Reason string `json:"reason,omitempty" example:"claude_timeout"`
+	// Branch name that was suggested for the worktree, if any
+	RequestedBranch string `json:"requested_branch,omitempty" example:"feature/add-auth"`
+	// Branch name the worktree ended up with (the catnip ref when skipped)
+	Branch string `json:"branch" example:"feature/add-auth-1"`
+	// Worktree that already owned the requested branch, if any
+	CollidingWorktreeID string `json:"colliding_worktree_id,omitempty" example:"abc123-def456-ghi789"`
+	// Human readable explanation for the UI
+	Message string `json:"message,omitempty" example:"feature/add-auth is used by worktree repo/luna; renamed to feature/add-auth-1 and linked the worktrees"`
+	// When the rename was attempted
+	At time.Time `json:"at" example:"2024-01-15T16:45:30Z"`
 }
 
 // WorktreeCreateRequest represents a request to create a new worktree
@@ -138,6 +1262,133 @@ type GitStatus struct {
 	Repositories map[string]*Repository `json:"repositories"`
 	// Total number of worktrees across all repositories
 	WorktreeCount int `json:"worktree_count" example:"3"`
+	// What loading the persisted state healed or lost, until acknowledged
+	StateReport *StateReport `json:"state_report,omitempty"`
+	// Set when state files were found corrupt on load, until the state report is acknowledged
+	Degraded bool `json:"degraded,omitempty" example:"false"`
+	// Why the state is degraded
+	DegradedReason string `json:"degraded_reason,omitempty" example:"1 corrupt state files, 1 restored from backup and 0 lost: worktrees/abc.json"`
+	// GitHub API budget seen in recent responses and how much polling is held back
+	GitHubRateLimit *GitHubRateLimitStatus `json:"github_rate_limit,omitempty"`
+}
+
+// GitHubRateLimitStatus describes the GitHub API budget catnip is spending
+// @Description GitHub API rate limits and the throttle they put on polling
+type GitHubRateLimitStatus struct {
+	// Most restrictive throttle among the resources: normal, slow, paused or exhausted
+	Throttle string `json:"throttle" example:"normal"`
+	// Budget of each API resource seen so far
+	Resources []GitHubRateLimitResource `json:"resources"`
+	// User operations waiting for the limit to reset
+	Queued []QueuedGitHubOperation `json:"queued,omitempty"`
+}
+
+// GitHubRateLimitResource is the budget of one GitHub API resource
+// @Description Rate limit of one GitHub API resource, from the X-RateLimit headers
+type GitHubRateLimitResource struct {
+	// API resource, core for REST and graphql
+	Resource string `json:"resource" example:"graphql"`
+	// Requests or points allowed per window
+	Limit int `json:"limit" example:"5000"`
+	// Requests or points left in the current window
+	Remaining int `json:"remaining" example:"4210"`
+	// Requests or points spent in the current window
+	Used int `json:"used" example:"790"`
+	// When the current window resets
+	ResetAt time.Time `json:"reset_at"`
+	// When the budget was last seen
+	UpdatedAt time.Time `json:"updated_at"`
+	// How much spending of this resource is held back: normal, slow, paused or exhausted
+	Throttle string `json:"throttle" example:"normal"`
+}
+
+// QueuedGitHubOperation is a user operation held back until the GitHub rate limit resets
+// @Description GitHub operation queued until the rate limit resets
+type QueuedGitHubOperation struct {
+	// Unique identifier of the queued operation
+	ID string `json:"id" example:"3f1c2a4e-9b7d-4c2a-8e1f-5d6b7a8c9d0e"`
+	// Operation, create_pr or update_pr
+	Kind string `json:"kind" example:"create_pr"`
+	// Worktree the operation applies to
+	WorktreeID string `json:"worktree_id" example:"abc123-def456-ghi789"`
+	// When the operation was queued
+	QueuedAt time.Time `json:"queued_at"`
+	// When the operation is expected to run
+	RunAt time.Time `json:"run_at"`
+	// queued, running, done or failed
+	Status string `json:"status" example:"queued"`
+	// Why the operation failed
+	Error string `json:"error,omitempty"`
+	// URL of the pull request, once done
+	URL string `json:"url,omitempty" example:"https://github.com/owner/repo/pull/42"`
+}
+
+// StateReport describes what loading the persisted state at startup had to heal or give up on,
+// accumulated over restarts until acknowledged
+type StateReport struct {
+	// When the report was last added to
+	At time.Time `json:"at"`
+	// Schema migrations applied
+	Migrations []string `json:"migrations"`
+	// State files moved aside with a .corrupt suffix because they couldn't be parsed
+	Quarantined []string `json:"quarantined"`
+	// Quarantined state files replaced by their previous version, which may miss the last change
+	Restored []string `json:"restored"`
+	// Repositories and worktrees listed in the state index that could not be loaded
+	Lost []StateReportEntry `json:"lost"`
+	// Repositories whose path vanished and worktrees that could not be restored
+	Unavailable []StateReportEntry `json:"unavailable"`
+	// Worktree paths rewritten while loading
+	PathRewrites []StatePathRewrite `json:"path_rewrites"`
+	// Worktrees dropped by state validation because they can never be restored
+	Pruned []StateReportEntry `json:"pruned"`
+	// Worktrees kept by state validation whose directory is missing or isn't a git checkout
+	Stale []StateReportEntry `json:"stale"`
+	// When the report was acknowledged, after which status no longer returns it
+	AcknowledgedAt *time.Time `json:"acknowledged_at,omitempty"`
+}
+
+// StateReportEntry is a repository or worktree in a StateReport
+type StateReportEntry struct {
+	// repository, worktree, or git_worktree for a git worktree that isn't in state
+	Kind string `json:"kind" example:"worktree"`
+	// ID of the repository or worktree, the path of a git_worktree
+	ID string `json:"id" example:"abc123-def456-ghi789"`
+	// Display name, when known
+	Name   string `json:"name,omitempty" example:"catnip/felix"`
+	Reason string `json:"reason" example:"path /workspace/repo no longer exists"`
+}
+
+// StateValidation is the outcome of cross-checking the loaded worktrees against the filesystem
+type StateValidation struct {
+	// Worktrees dropped from state because they can never be restored
+	Pruned []StateReportEntry `json:"pruned"`
+	// Worktrees kept in state whose directory is missing or isn't a git checkout
+	Stale []StateReportEntry `json:"stale"`
+}
+
+// WorktreeReconciliation is the outcome of comparing the worktrees in state with the ones git
+// has registered for each repository
+// @Description Worktrees imported into, removed from or flagged in state by a reconciliation
+type WorktreeReconciliation struct {
+	// flag or remove, what happened to worktrees git no longer knows
+	MissingMode string `json:"missing_mode" example:"flag"`
+	// Worktrees found on disk and imported into state
+	Added []StateReportEntry `json:"added"`
+	// Worktrees git no longer knows, removed from state
+	Removed []StateReportEntry `json:"removed"`
+	// Worktrees git no longer knows, kept in state as missing, and git worktrees that couldn't be imported
+	Flagged []StateReportEntry `json:"flagged"`
+	// Missing worktrees git knows again
+	Recovered []StateReportEntry `json:"recovered"`
+}
+
+// StatePathRewrite is a worktree path rewritten while loading the persisted state
+type StatePathRewrite struct {
+	WorktreeID string `json:"worktree_id" example:"abc123-def456-ghi789"`
+	Name       string `json:"name" example:"catnip/felix"`
+	From       string `json:"from" example:"/workspace/link/felix"`
+	To         string `json:"to" example:"/workspace/repo/felix"`
 }
 
 // PullRequestResponse represents the response from creating a pull request
@@ -159,6 +1410,116 @@ type PullRequestResponse struct {
 	Repository string `json:"repository" example:"owner/repo"`
 }
 
+// ReviewThread is an unresolved review conversation on a pull request
+// @Description Unresolved review thread of a pull request
+type ReviewThread struct {
+	// File the thread is attached to
+	Path string `json:"path" example:"internal/auth/login.go"`
+	// Line of the file, 0 for threads on a whole file
+	Line int `json:"line,omitempty" example:"42"`
+	// Whether the code the thread was started on has changed since
+	Outdated bool `json:"outdated,omitempty" example:"false"`
+	// Comments of the thread, oldest first
+	Comments []ReviewComment `json:"comments"`
+}
+
+// ReviewComment is a comment of a review thread
+type ReviewComment struct {
+	Author    string    `json:"author" example:"octocat"`
+	Body      string    `json:"body" example:"This should handle expired tokens"`
+	URL       string    `json:"url,omitempty" example:"https://github.com/owner/repo/pull/42#discussion_r1"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// WorktreeContextPack describes the state of a worktree's session for handing it to another tool
+// @Description Self-contained summary of a worktree's session: layout, diff, todos, titles, failing validation and open review comments, capped to a token budget
+type WorktreeContextPack struct {
+	WorktreeID   string `json:"worktree_id" example:"abc123-def456-ghi789"`
+	WorktreeName string `json:"worktree_name" example:"catnip/felix"`
+	Repository   string `json:"repository" example:"owner/repo"`
+	Branch       string `json:"branch" example:"feature/login"`
+	SourceBranch string `json:"source_branch" example:"main"`
+	// HEAD of the worktree when the pack was generated
+	CommitHash  string    `json:"commit_hash,omitempty" example:"abc123def456"`
+	GeneratedAt time.Time `json:"generated_at"`
+	// Token budget the pack was capped to, tokens estimated as 4 bytes each
+	MaxTokens int `json:"max_tokens" example:"32000"`
+	// Estimated tokens of the pack, without the rendered markdown
+	EstimatedTokens int `json:"estimated_tokens" example:"12800"`
+	// Why the worktree was created
+	CreationContext *CreationContext  `json:"creation_context,omitempty"`
+	Layout          ContextPackLayout `json:"layout"`
+	Diff            ContextPackDiff   `json:"diff"`
+	// Todos of the Claude session
+	Todos []Todo `json:"todos,omitempty"`
+	// Session titles, oldest first
+	Titles []TitleEntry `json:"titles,omitempty"`
+	// Most recent prompt of the Claude session
+	LatestPrompt string `json:"latest_prompt,omitempty" example:"Add OAuth login"`
+	// Last validation run when it failed, with the output of the failing commands
+	FailedValidation *ValidationResult `json:"failed_validation,omitempty"`
+	// Pull request of the worktree and its unresolved review threads
+	PullRequest *ContextPackPullRequest `json:"pull_request,omitempty"`
+	// Sections cut short to fit the budget
+	Truncated []string `json:"truncated,omitempty" example:"diff"`
+	// What couldn't be included and why
+	Notes []string `json:"notes,omitempty"`
+	// The pack rendered as markdown, when asked for
+	Markdown string `json:"markdown,omitempty"`
+	// Where the markdown was written in the worktree, when asked for
+	WrittenTo string `json:"written_to,omitempty" example:"/workspace/repo/felix/.catnip/context.md"`
+}
+
+// ContextPackLayout summarizes the files of a repository
+type ContextPackLayout struct {
+	// Tracked files in the worktree
+	Files int `json:"files" example:"412"`
+	// Top-level directories and files with how many tracked files each holds, largest first
+	Entries []ContextPackLayoutEntry `json:"entries"`
+	// Toolchains detected when the worktree was created
+	Toolchains []Toolchain `json:"toolchains,omitempty"`
+}
+
+// ContextPackLayoutEntry is a top-level directory or file of a repository
+type ContextPackLayoutEntry struct {
+	// Path, ending in / for directories
+	Path  string `json:"path" example:"internal/"`
+	Files int    `json:"files" example:"230"`
+}
+
+// ContextPackDiff is the diff of a worktree against its source branch, capped to a budget
+type ContextPackDiff struct {
+	Summary        string            `json:"summary,omitempty"`
+	TotalFiles     int               `json:"total_files" example:"4"`
+	TotalAdditions int               `json:"total_additions" example:"120"`
+	TotalDeletions int               `json:"total_deletions" example:"30"`
+	Files          []ContextPackFile `json:"files"`
+}
+
+// ContextPackFile is a changed file of a context pack
+type ContextPackFile struct {
+	Path       string `json:"path" example:"internal/auth/login.go"`
+	ChangeType string `json:"change_type" example:"modified"`
+	Additions  int    `json:"additions" example:"40"`
+	Deletions  int    `json:"deletions" example:"3"`
+	// Unified diff of the file, cut short when Truncated
+	Diff      string `json:"diff,omitempty"`
+	Truncated bool   `json:"truncated,omitempty"`
+	// Why the diff is left out: binary, generated or budget
+	Omitted string `json:"omitted,omitempty" example:"generated"`
+}
+
+// ContextPackPullRequest is the pull request of a context pack
+type ContextPackPullRequest struct {
+	URL   string `json:"url" example:"https://github.com/owner/repo/pull/42"`
+	Title string `json:"title,omitempty" example:"Add OAuth login"`
+	// OPEN, CLOSED or MERGED, as last synced
+	State string `json:"state,omitempty" example:"OPEN"`
+	// Combined status of the checks, as last synced
+	ChecksState   string         `json:"checks_state,omitempty" example:"FAILURE"`
+	ReviewThreads []ReviewThread `json:"review_threads,omitempty"`
+}
+
 // PullRequestInfo represents information about an existing pull request
 // @Description Information about an existing pull request for a worktree
 type PullRequestInfo struct {
@@ -174,6 +1535,8 @@ type PullRequestInfo struct {
 	Number int `json:"number,omitempty" example:"123"`
 	// URL to the pull request (if exists)
 	URL string `json:"url,omitempty" example:"https://github.com/owner/repo/pull/123"`
+	// Branch the existing pull request targets (if exists)
+	BaseBranch string `json:"base_branch,omitempty" example:"main"`
 }
 
 // PullRequestState represents the cached state of a pull request
@@ -189,6 +1552,8 @@ type PullRequestState struct {
 	URL string `json:"url" example:"https://github.com/anthropics/claude-code/pull/123"`
 	// Title of the pull request
 	Title string `json:"title" example:"Feature: Add new functionality"`
+	// Combined status of the checks on the head commit (SUCCESS, FAILURE, ERROR, PENDING, EXPECTED), empty if none
+	ChecksState string `json:"checks_state,omitempty" example:"FAILURE"`
 	// When this state was last synced from GitHub
 	LastSynced time.Time `json:"last_synced" example:"2024-01-15T16:45:30Z"`
 	// List of worktree IDs that reference this PR
@@ -2,6 +2,7 @@ package recovery
 
 import (
 	"runtime/debug"
+	"time"
 
 	"github.com/vanpelt/catnip/internal/logger"
 )
@@ -12,8 +13,7 @@ func SafeGo(name string, fn func()) {
 	go func() {
 		defer func() {
 			if r := recover(); r != nil {
-				logger.Errorf("🚨 PANIC recovered in goroutine '%s': %v", name, r)
-				logger.Errorf("Stack trace:\n%s", debug.Stack())
+				reportPanic(name, r, debug.Stack())
 			}
 		}()
 		fn()
@@ -28,14 +28,51 @@ func SafeGoWithCleanup(name string, fn func(), cleanup func()) {
 				cleanup()
 			}
 			if r := recover(); r != nil {
-				logger.Errorf("🚨 PANIC recovered in goroutine '%s': %v", name, r)
-				logger.Errorf("Stack trace:\n%s", debug.Stack())
+				reportPanic(name, r, debug.Stack())
 			}
 		}()
 		fn()
 	}()
 }
 
+// MaxRestarts is how many times SafeGoRestartable restarts a goroutine after panics before giving up
+const MaxRestarts = 5
+
+// maxRestartBackoff caps the delay between restarts
+const maxRestartBackoff = 5 * time.Minute
+
+// SafeGoRestartable runs a long-lived function in a goroutine and restarts it after a panic,
+// waiting backoff before the first restart and doubling the wait after each further panic.
+// The goroutine stays down after MaxRestarts restarts. A normal return is not restarted.
+func SafeGoRestartable(name string, fn func(), backoff time.Duration) {
+	go func() {
+		for restarts := 0; ; restarts++ {
+			if !runRecovered(name, fn) {
+				return
+			}
+			if restarts >= MaxRestarts {
+				logger.Errorf("🚨 Goroutine '%s' panicked %d times, not restarting it again", name, restarts+1)
+				return
+			}
+			logger.Warnf("🔁 Restarting goroutine '%s' in %v (restart %d/%d)", name, backoff, restarts+1, MaxRestarts)
+			time.Sleep(backoff)
+			backoff = min(backoff*2, maxRestartBackoff)
+		}
+	}()
+}
+
+// runRecovered runs fn and reports whether it panicked
+func runRecovered(name string, fn func()) (panicked bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			reportPanic(name, r, debug.Stack())
+			panicked = true
+		}
+	}()
+	fn()
+	return false
+}
+
 // SafeGoContext runs a function with a context-like pattern for WebSocket handlers
 func SafeGoContext(name string, fn func()) {
 	go func() {
@@ -0,0 +1,89 @@
+package recovery
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// panicRecorder is a PanicHook collecting the goroutine names it was called for
+type panicRecorder struct {
+	mu    sync.Mutex
+	names []string
+	calls chan struct{}
+}
+
+func newPanicRecorder(t *testing.T) *panicRecorder {
+	r := &panicRecorder{calls: make(chan struct{}, 100)}
+	t.Cleanup(RegisterPanicHook(r.hook))
+	return r
+}
+
+func (r *panicRecorder) hook(name string, recovered interface{}, stack []byte) {
+	r.mu.Lock()
+	r.names = append(r.names, name)
+	r.mu.Unlock()
+	r.calls <- struct{}{}
+}
+
+func (r *panicRecorder) wait(t *testing.T, n int) []string {
+	for i := 0; i < n; i++ {
+		select {
+		case <-r.calls:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("panic hook called %d times, expected %d", i, n)
+		}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.names...)
+}
+
+func TestPanicHooks(t *testing.T) {
+	recorder := newPanicRecorder(t)
+	unregisterBroken := RegisterPanicHook(func(string, interface{}, []byte) { panic("broken hook") })
+
+	SafeGo("setup-script:/workspace/felix", func() { panic("boom") })
+	assert.Equal(t, []string{"setup-script:/workspace/felix"}, recorder.wait(t, 1))
+
+	unregisterBroken()
+	cleaned := make(chan struct{})
+	SafeGoWithCleanup("websocket", func() { panic("boom") }, func() { close(cleaned) })
+	<-cleaned
+	assert.Equal(t, []string{"setup-script:/workspace/felix", "websocket"}, recorder.wait(t, 1))
+
+	assert.Equal(t, "setup-script", Subsystem("setup-script:/workspace/felix"))
+	assert.Equal(t, "websocket", Subsystem("websocket"))
+}
+
+func TestSafeGoRestartable(t *testing.T) {
+	recorder := newPanicRecorder(t)
+
+	// Panics twice, then returns normally and is not started again
+	var runs atomic.Int32
+	done := make(chan struct{})
+	SafeGoRestartable("flaky", func() {
+		if runs.Add(1) <= 2 {
+			panic("flaky")
+		}
+		close(done)
+	}, time.Millisecond)
+	<-done
+	assert.Len(t, recorder.wait(t, 2), 2)
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, int32(3), runs.Load())
+
+	// Always panics, and gives up after MaxRestarts restarts
+	var crashes atomic.Int32
+	SafeGoRestartable("broken", func() {
+		crashes.Add(1)
+		panic("broken")
+	}, time.Millisecond)
+	require.Len(t, recorder.wait(t, MaxRestarts+1), 2+MaxRestarts+1)
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, int32(MaxRestarts+1), crashes.Load())
+}
@@ -0,0 +1,67 @@
+package recovery
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/vanpelt/catnip/internal/logger"
+)
+
+// PanicHook is told about every panic recovered by SafeGo, SafeGoWithCleanup and
+// SafeGoRestartable, with the goroutine name, the recovered value and the stack trace
+type PanicHook func(name string, recovered interface{}, stack []byte)
+
+var (
+	hooksMu    sync.RWMutex
+	hooks      = make(map[int]PanicHook)
+	nextHookID int
+)
+
+// RegisterPanicHook adds a hook called for every recovered panic and returns a function
+// that removes it again
+func RegisterPanicHook(hook PanicHook) func() {
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+
+	id := nextHookID
+	nextHookID++
+	hooks[id] = hook
+
+	return func() {
+		hooksMu.Lock()
+		defer hooksMu.Unlock()
+		delete(hooks, id)
+	}
+}
+
+// Subsystem returns the subsystem of a goroutine name: the part before the first colon, so
+// "setup-script:/workspace/repo/felix" belongs to "setup-script"
+func Subsystem(name string) string {
+	subsystem, _, _ := strings.Cut(name, ":")
+	return subsystem
+}
+
+// reportPanic logs a recovered panic and passes it to the registered hooks. A panicking hook
+// is logged and skipped.
+func reportPanic(name string, recovered interface{}, stack []byte) {
+	logger.Errorf("🚨 PANIC recovered in goroutine '%s': %v", name, recovered)
+	logger.Errorf("Stack trace:\n%s", stack)
+
+	hooksMu.RLock()
+	registered := make([]PanicHook, 0, len(hooks))
+	for _, hook := range hooks {
+		registered = append(registered, hook)
+	}
+	hooksMu.RUnlock()
+
+	for _, hook := range registered {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					logger.Errorf("🚨 PANIC in panic hook for goroutine '%s': %v", name, r)
+				}
+			}()
+			hook(name, recovered, stack)
+		}()
+	}
+}
@@ -0,0 +1,55 @@
+package redact
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// maxPendingLine is how much of an unterminated line a Writer holds before writing it anyway
+const maxPendingLine = 64 << 10
+
+// Writer masks secrets in what is written through it line by line, so a secret split across
+// writes is still found. Close writes the last unterminated line.
+type Writer struct {
+	mu       sync.Mutex
+	w        io.Writer
+	redactor *Redactor
+	pending  []byte
+}
+
+// NewWriter returns a Writer masking secrets with redactor before writing to w
+func NewWriter(w io.Writer, redactor *Redactor) *Writer {
+	return &Writer{w: w, redactor: redactor}
+}
+
+// Write masks the complete lines of p and what came before them, holding the rest back
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.pending = append(w.pending, p...)
+	end := bytes.LastIndexByte(w.pending, '\n') + 1
+	if end == 0 && len(w.pending) >= maxPendingLine {
+		end = len(w.pending)
+	}
+	if end == 0 {
+		return len(p), nil
+	}
+	if _, err := io.WriteString(w.w, w.redactor.Text(string(w.pending[:end]))); err != nil {
+		return 0, err
+	}
+	w.pending = append(w.pending[:0], w.pending[end:]...)
+	return len(p), nil
+}
+
+// Close writes the held back line, masked
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.pending) == 0 {
+		return nil
+	}
+	_, err := io.WriteString(w.w, w.redactor.Text(string(w.pending)))
+	w.pending = nil
+	return err
+}
@@ -0,0 +1,477 @@
+package services
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+	"github.com/vanpelt/catnip/internal/redact"
+)
+
+// ActivityKind is the type of an activity feed entry
+type ActivityKind string
+
+const (
+	ActivityWorktreeCreated   ActivityKind = "worktree_created"
+	ActivityWorktreeDeleted   ActivityKind = "worktree_deleted"
+	ActivitySessionTitle      ActivityKind = "session_title"
+	ActivityCommit            ActivityKind = "commit"
+	ActivityPullRequestOpened ActivityKind = "pull_request_opened"
+	ActivityPullRequestMerged ActivityKind = "pull_request_merged"
+	ActivityMerged            ActivityKind = "merged"
+	ActivityBundleFetched     ActivityKind = "bundle_fetched"
+	ActivityValidationSkipped ActivityKind = "validation_skipped"
+	ActivityOperationBackup   ActivityKind = "operation_backup"
+	ActivityBackupRestored    ActivityKind = "backup_restored"
+	ActivityHandedOff         ActivityKind = "handed_off"
+	ActivityAutomationResumed ActivityKind = "automation_resumed"
+	ActivityPatchApplied      ActivityKind = "patch_applied"
+)
+
+// activityDayLayout names the activity log files, one per UTC day
+const activityDayLayout = "2006-01-02"
+
+// maxActivityRange bounds how many days a single activity query may cover
+const maxActivityRange = 31 * 24 * time.Hour
+
+// ActivityEntry is a single event in a repository's activity feed
+type ActivityEntry struct {
+	// When it happened
+	Time time.Time `json:"time" example:"2024-01-15T14:00:00Z"`
+	// Kind of event
+	Kind ActivityKind `json:"kind" example:"pull_request_opened"`
+	// Repository the event belongs to
+	RepoID string `json:"repo_id" example:"anthropics/claude-code"`
+	// Worktree the event belongs to, if any
+	WorktreeID string `json:"worktree_id,omitempty" example:"abc123-def456"`
+	// Worktree name at the time of the event
+	WorktreeName string `json:"worktree_name,omitempty" example:"felix/tabby"`
+	// Who triggered it, when known
+	Actor string `json:"actor,omitempty" example:"alice"`
+	// One line description
+	Summary string `json:"summary" example:"Add OAuth login"`
+	// Related URL, like the pull request
+	URL string `json:"url,omitempty" example:"https://github.com/owner/repo/pull/123"`
+}
+
+// ActivityLog is the persisted audit log behind the activity feed. Entries are appended to one
+// JSON lines file per UTC day and indexed in memory by day and repository, so a query only
+// reads the days it covers.
+type ActivityLog struct {
+	dir  string
+	mu   sync.Mutex
+	days map[string]map[string][]ActivityEntry
+}
+
+// NewActivityLog creates an activity log stored in dir
+func NewActivityLog(dir string) *ActivityLog {
+	return &ActivityLog{dir: dir, days: make(map[string]map[string][]ActivityEntry)}
+}
+
+// Record appends an entry to the log
+func (l *ActivityLog) Record(entry ActivityEntry) {
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+	entry.Time = entry.Time.UTC()
+	day := entry.Time.Format(activityDayLayout)
+	// Secrets never reach the log files
+	entry.Summary, entry.URL = redact.Text(entry.Summary), redact.Text(entry.URL)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	byRepo := l.loadDay(day)
+	byRepo[entry.RepoID] = append(byRepo[entry.RepoID], entry)
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		logger.Warnf("⚠️ Failed to encode activity entry: %v", err)
+		return
+	}
+	if err := os.MkdirAll(l.dir, 0755); err != nil {
+		logger.Warnf("⚠️ Failed to create activity log directory: %v", err)
+		return
+	}
+	file, err := os.OpenFile(filepath.Join(l.dir, day+".jsonl"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		logger.Warnf("⚠️ Failed to open activity log: %v", err)
+		return
+	}
+	defer file.Close()
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		logger.Warnf("⚠️ Failed to write activity log: %v", err)
+	}
+}
+
+// Entries returns the entries of a repository in [since, until), oldest first
+func (l *ActivityLog) Entries(repoID string, since, until time.Time) []ActivityEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var entries []ActivityEntry
+	for day := since.UTC().Truncate(24 * time.Hour); day.Before(until); day = day.Add(24 * time.Hour) {
+		for _, entry := range l.loadDay(day.Format(activityDayLayout))[repoID] {
+			if !entry.Time.Before(since) && entry.Time.Before(until) {
+				entries = append(entries, entry)
+			}
+		}
+	}
+	return entries
+}
+
+// loadDay returns the index of a day, reading its file the first time (must be called with lock held)
+func (l *ActivityLog) loadDay(day string) map[string][]ActivityEntry {
+	if byRepo, loaded := l.days[day]; loaded {
+		return byRepo
+	}
+	byRepo := make(map[string][]ActivityEntry)
+	l.days[day] = byRepo
+
+	file, err := os.Open(filepath.Join(l.dir, day+".jsonl"))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logger.Warnf("⚠️ Failed to read activity log for %s: %v", day, err)
+		}
+		return byRepo
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry ActivityEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			// A partially written last line is skipped, the rest of the day still loads
+			logger.Debugf("Skipping unreadable activity entry in %s: %v", day, err)
+			continue
+		}
+		byRepo[entry.RepoID] = append(byRepo[entry.RepoID], entry)
+	}
+	return byRepo
+}
+
+// recordWorktreeActivity records an activity log entry for a worktree. Without an actor, the
+// entry is attributed to the API request changing the worktree, if any.
+func (wsm *WorktreeStateManager) recordWorktreeActivity(kind ActivityKind, worktree *models.Worktree, actor, summary, url string) {
+	if wsm.activity == nil {
+		return
+	}
+	if actor == "" {
+		actor = WorktreeActor(worktree.ID)
+	}
+	wsm.activity.Record(ActivityEntry{
+		Kind:         kind,
+		RepoID:       worktree.RepoID,
+		WorktreeID:   worktree.ID,
+		WorktreeName: worktree.Name,
+		Actor:        actor,
+		Summary:      summary,
+		URL:          url,
+	})
+}
+
+// ActivityTotals sums up a repository's activity
+type ActivityTotals struct {
+	// Worktrees with session or commit activity
+	SessionsActive int `json:"sessions_active" example:"3"`
+	// Commits made on worktree branches
+	Commits int `json:"commits" example:"12"`
+	// Distinct files changed by those commits, per worktree
+	FilesChanged int `json:"files_changed" example:"40"`
+	// Pull requests opened
+	PullRequestsOpened int `json:"pull_requests_opened" example:"1"`
+	// Pull requests merged and local merges
+	PullRequestsMerged int `json:"pull_requests_merged" example:"1"`
+}
+
+// WorktreeActivity groups a repository's activity by worktree
+type WorktreeActivity struct {
+	// Worktree ID
+	ID string `json:"id" example:"abc123-def456"`
+	// Worktree name
+	Name string `json:"name" example:"felix/tabby"`
+	// Branch of the worktree, empty once deleted
+	Branch string `json:"branch,omitempty" example:"feature/login"`
+	// Pull request of the worktree
+	PullRequestURL string `json:"pull_request_url,omitempty" example:"https://github.com/owner/repo/pull/123"`
+	// Pull request state
+	PullRequestState string `json:"pull_request_state,omitempty" example:"OPEN"`
+	// Commits in the period
+	Commits int `json:"commits" example:"5"`
+	// Distinct files changed by those commits
+	FilesChanged int `json:"files_changed" example:"10"`
+	// Events of the worktree, oldest first
+	Events []ActivityEntry `json:"events"`
+}
+
+// RepositoryActivity is the activity feed of a repository over a period
+type RepositoryActivity struct {
+	// Repository ID
+	RepoID string `json:"repo_id" example:"anthropics/claude-code"`
+	// Start of the period
+	Since time.Time `json:"since" example:"2024-01-14T00:00:00Z"`
+	// End of the period (exclusive)
+	Until time.Time `json:"until" example:"2024-01-15T00:00:00Z"`
+	// Totals over the period
+	Totals ActivityTotals `json:"totals"`
+	// Activity grouped by worktree, most recently active first
+	Worktrees []*WorktreeActivity `json:"worktrees"`
+	// All events, oldest first
+	Events []ActivityEntry `json:"events"`
+}
+
+// GetRepositoryActivity aggregates the activity log, session title histories, worktree commits
+// and pull request state of a repository into a feed for [since, until)
+func (s *GitService) GetRepositoryActivity(repoID string, since, until time.Time) (*RepositoryActivity, error) {
+	if _, exists := s.stateManager.GetRepository(repoID); !exists {
+		return nil, fmt.Errorf("repository %s not found", repoID)
+	}
+	if !since.Before(until) {
+		return nil, fmt.Errorf("since must be before until")
+	}
+	if until.Sub(since) > maxActivityRange {
+		return nil, fmt.Errorf("activity can be queried for at most %d days at a time", int(maxActivityRange.Hours()/24))
+	}
+
+	activity := &RepositoryActivity{RepoID: repoID, Since: since, Until: until, Worktrees: []*WorktreeActivity{}, Events: []ActivityEntry{}}
+	byWorktree := make(map[string]*WorktreeActivity)
+	group := func(entry ActivityEntry) *WorktreeActivity {
+		wa, exists := byWorktree[entry.WorktreeID]
+		if !exists {
+			wa = &WorktreeActivity{ID: entry.WorktreeID, Name: entry.WorktreeName, Events: []ActivityEntry{}}
+			byWorktree[entry.WorktreeID] = wa
+		}
+		return wa
+	}
+	add := func(entry ActivityEntry) {
+		activity.Events = append(activity.Events, entry)
+		if entry.WorktreeID != "" {
+			wa := group(entry)
+			wa.Events = append(wa.Events, entry)
+		}
+		switch entry.Kind {
+		case ActivityPullRequestOpened:
+			activity.Totals.PullRequestsOpened++
+		case ActivityPullRequestMerged, ActivityMerged:
+			activity.Totals.PullRequestsMerged++
+		}
+	}
+
+	allWorktrees := s.stateManager.GetAllWorktrees()
+	if s.stateManager.activity != nil {
+		for _, entry := range s.stateManager.activity.Entries(repoID, since, until) {
+			// Creation context is recorded after the worktree, so it comes from the live state
+			if wt, exists := allWorktrees[entry.WorktreeID]; exists && entry.Kind == ActivityWorktreeCreated && wt.CreationContext != nil {
+				entry.Summary = creationActivitySummary(entry.Summary, wt.CreationContext)
+				if entry.Actor == "" {
+					entry.Actor = wt.CreationContext.Actor
+				}
+			}
+			add(entry)
+		}
+	}
+
+	active := make(map[string]bool)
+	for _, wt := range allWorktrees {
+		if wt.RepoID != repoID {
+			continue
+		}
+		for _, title := range wt.SessionTitleHistory {
+			if title.Timestamp.Before(since) || !title.Timestamp.Before(until) {
+				continue
+			}
+			add(ActivityEntry{
+				Time: title.Timestamp, Kind: ActivitySessionTitle, RepoID: repoID,
+				WorktreeID: wt.ID, WorktreeName: wt.Name, Summary: title.Title,
+			})
+			active[wt.ID] = true
+		}
+
+		commits, files := s.worktreeCommitActivity(wt, since, until)
+		for _, commit := range commits {
+			add(commit)
+		}
+		if len(commits) > 0 {
+			active[wt.ID] = true
+			wa := group(ActivityEntry{WorktreeID: wt.ID, WorktreeName: wt.Name})
+			wa.Commits = len(commits)
+			wa.FilesChanged = files
+			activity.Totals.Commits += len(commits)
+			activity.Totals.FilesChanged += files
+		}
+
+		if wa, exists := byWorktree[wt.ID]; exists {
+			wa.Name = wt.Name
+			wa.Branch = wt.Branch
+			wa.PullRequestURL = wt.PullRequestURL
+			wa.PullRequestState = wt.PullRequestState
+			if prState := cachedPRState(wt.PullRequestURL); prState != nil {
+				wa.PullRequestState = prState.State
+			}
+		}
+	}
+	activity.Totals.SessionsActive = len(active)
+
+	sortActivity(activity.Events)
+	for _, wa := range byWorktree {
+		sortActivity(wa.Events)
+		activity.Worktrees = append(activity.Worktrees, wa)
+	}
+	sort.Slice(activity.Worktrees, func(i, j int) bool {
+		a, b := activity.Worktrees[i], activity.Worktrees[j]
+		if len(a.Events) == 0 || len(b.Events) == 0 {
+			return len(a.Events) > len(b.Events)
+		}
+		return a.Events[len(a.Events)-1].Time.After(b.Events[len(b.Events)-1].Time)
+	})
+
+	return activity, nil
+}
+
+// creationActivitySummary adds the source, issue and prompt of a worktree to its created entry
+func creationActivitySummary(summary string, creation *models.CreationContext) string {
+	if creation.Source != "" && creation.Source != models.CreationSourceAuto {
+		summary += " via " + string(creation.Source)
+	}
+	if creation.Issue != "" {
+		summary += " for " + creation.Issue
+	}
+	if creation.Prompt != "" {
+		prompt := strings.Join(strings.Fields(creation.Prompt), " ")
+		if runes := []rune(prompt); len(runes) > maxCreationPromptSummary {
+			prompt = string(runes[:maxCreationPromptSummary]) + "…"
+		}
+		summary += ": " + prompt
+	}
+	return summary
+}
+
+// worktreeCommitActivity returns the commits of a worktree branch in [since, until) and the
+// number of distinct files they changed
+func (s *GitService) worktreeCommitActivity(wt *models.Worktree, since, until time.Time) ([]ActivityEntry, int) {
+	if wt.CommitHash == "" || wt.Path == "" {
+		return nil, 0
+	}
+	output, err := s.runGitCommand(wt.Path, "log", "--no-color", "--name-only", "--format=%x1e%H%x1f%ct%x1f%an%x1f%s",
+		"--since="+since.Format(time.RFC3339), "--until="+until.Format(time.RFC3339), wt.CommitHash+"..HEAD")
+	if err != nil {
+		logger.Debugf("Failed to read commit activity of %s: %v", wt.Name, err)
+		return nil, 0
+	}
+
+	var commits []ActivityEntry
+	files := make(map[string]bool)
+	for _, record := range strings.Split(string(output), "\x1e") {
+		lines := strings.Split(strings.TrimSpace(record), "\n")
+		fields := strings.SplitN(lines[0], "\x1f", 4)
+		if len(fields) != 4 {
+			continue
+		}
+		seconds, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		at := time.Unix(seconds, 0).UTC()
+		if at.Before(since) || !at.Before(until) {
+			continue
+		}
+		for _, file := range lines[1:] {
+			if file = strings.TrimSpace(file); file != "" {
+				files[file] = true
+			}
+		}
+		commits = append(commits, ActivityEntry{
+			Time: at, Kind: ActivityCommit, RepoID: wt.RepoID, WorktreeID: wt.ID, WorktreeName: wt.Name,
+			Actor: fields[2], Summary: fields[3],
+		})
+	}
+	return commits, len(files)
+}
+
+// sortActivity orders entries oldest first
+func sortActivity(entries []ActivityEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Time.Before(entries[j].Time)
+	})
+}
+
+// Markdown renders the activity as a plain text summary that reads well pasted into chat
+func (a *RepositoryActivity) Markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "**%s** activity, %s to %s UTC\n", a.RepoID,
+		a.Since.UTC().Format("Jan 2 15:04"), a.Until.UTC().Format("Jan 2 15:04"))
+	fmt.Fprintf(&b, "%s, %s, %s changed, %s opened, %d merged\n",
+		plural(a.Totals.SessionsActive, "session"), plural(a.Totals.Commits, "commit"),
+		plural(a.Totals.FilesChanged, "file"), plural(a.Totals.PullRequestsOpened, "PR"), a.Totals.PullRequestsMerged)
+
+	if len(a.Worktrees) == 0 {
+		b.WriteString("\nNo activity.\n")
+		return b.String()
+	}
+
+	for _, wt := range a.Worktrees {
+		b.WriteString("\n**" + wt.Name + "**")
+		if wt.Branch != "" {
+			b.WriteString(" (`" + wt.Branch + "`)")
+		}
+		if wt.Commits > 0 {
+			fmt.Fprintf(&b, ": %s, %s", plural(wt.Commits, "commit"), plural(wt.FilesChanged, "file"))
+		}
+		if wt.PullRequestURL != "" {
+			b.WriteString(", " + wt.PullRequestURL)
+			if wt.PullRequestState != "" {
+				b.WriteString(" (" + strings.ToLower(wt.PullRequestState) + ")")
+			}
+		}
+		b.WriteString("\n")
+
+		for _, entry := range wt.Events {
+			if entry.Kind == ActivityCommit {
+				continue // summarized by the commit count
+			}
+			fmt.Fprintf(&b, "- %s %s\n", entry.Time.UTC().Format("15:04"), activityLine(entry))
+		}
+	}
+	return b.String()
+}
+
+// activityLine describes an entry for the markdown rendering
+func activityLine(entry ActivityEntry) string {
+	line := entry.Summary
+	switch entry.Kind {
+	case ActivityWorktreeCreated:
+		line = "Created " + line
+	case ActivityWorktreeDeleted:
+		line = "Deleted"
+	case ActivitySessionTitle:
+		line = "Session: " + line
+	case ActivityPullRequestOpened:
+		line = "Opened " + entry.URL
+	case ActivityPullRequestMerged:
+		line = "Merged " + entry.URL
+	case ActivityMerged:
+		line = "Merged " + line
+	}
+	if entry.Actor != "" && entry.Actor != DefaultActor {
+		line += " (" + entry.Actor + ")"
+	}
+	return strings.TrimSpace(line)
+}
+
+// plural formats a count with its noun
+func plural(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+func TestActivityLogIndexesByRepoAndDay(t *testing.T) {
+	dir := t.TempDir()
+	day := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	log := NewActivityLog(dir)
+	log.Record(ActivityEntry{Time: day.Add(-time.Hour), Kind: ActivityWorktreeCreated, RepoID: "acme/felix", WorktreeID: "wt-1"})
+	log.Record(ActivityEntry{Time: day.Add(9 * time.Hour), Kind: ActivityWorktreeDeleted, RepoID: "acme/felix", WorktreeID: "wt-1"})
+	log.Record(ActivityEntry{Time: day.Add(10 * time.Hour), Kind: ActivityWorktreeCreated, RepoID: "acme/tabby", WorktreeID: "wt-2"})
+
+	assert.FileExists(t, filepath.Join(dir, "2024-01-14.jsonl"))
+	assert.FileExists(t, filepath.Join(dir, "2024-01-15.jsonl"))
+
+	// A fresh log reads only the files of the days it is asked about
+	reloaded := NewActivityLog(dir)
+	entries := reloaded.Entries("acme/felix", day, day.Add(24*time.Hour))
+	require.Len(t, entries, 1)
+	assert.Equal(t, ActivityWorktreeDeleted, entries[0].Kind)
+	assert.Len(t, reloaded.days, 1)
+
+	assert.Len(t, reloaded.Entries("acme/felix", day.Add(-2*time.Hour), day.Add(24*time.Hour)), 2)
+	assert.Empty(t, reloaded.Entries("acme/felix", day.Add(10*time.Hour), day.Add(24*time.Hour)))
+}
+
+func TestGetRepositoryActivity(t *testing.T) {
+	root := t.TempDir()
+	repoPath := initTestRepo(t, filepath.Join(root, "repo"), map[string]string{"README.md": "# felix\n"})
+	base := runTestGit(t, repoPath, "rev-parse", "HEAD")
+	runTestGit(t, repoPath, "checkout", "-b", "feature/login")
+	for i, file := range []string{"login.go", "login.go", "login_test.go"} {
+		require.NoError(t, os.WriteFile(filepath.Join(repoPath, file), []byte(strings.Repeat("x", i+1)), 0644))
+		runTestGit(t, repoPath, "add", "-A")
+		runTestGit(t, repoPath, "commit", "-m", "Checkpoint "+file)
+	}
+
+	now := time.Now().UTC()
+	s := newTestGitService(t, root)
+	stateManager := s.stateManager
+	require.NoError(t, stateManager.AddRepository(&models.Repository{ID: "local/repo", Path: repoPath}))
+	worktree := &models.Worktree{
+		ID: "wt-felix", RepoID: "local/repo", Name: "felix/tabby", Path: repoPath, Branch: "feature/login",
+		SourceBranch: "main", CommitHash: base, PullRequestURL: "https://github.com/acme/felix/pull/7",
+		SessionTitleHistory: []models.TitleEntry{
+			{Title: "Planning yesterday", Timestamp: now.Add(-72 * time.Hour)},
+			{Title: "Add OAuth login", Timestamp: now.Add(-time.Hour)},
+		},
+		CreationContext: &models.CreationContext{Source: models.CreationSourceUI, Issue: "#12", Actor: "alice"},
+	}
+	require.NoError(t, stateManager.AddWorktree(worktree))
+	stateManager.recordWorktreeActivity(ActivityPullRequestOpened, worktree, "", "Add OAuth login", worktree.PullRequestURL)
+	stateManager.recordWorktreeActivity(ActivityWorktreeCreated, &models.Worktree{ID: "wt-other", RepoID: "local/other"}, "", "", "")
+
+	activity, err := s.GetRepositoryActivity("local/repo", now.Add(-24*time.Hour), now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, ActivityTotals{SessionsActive: 1, Commits: 3, FilesChanged: 2, PullRequestsOpened: 1}, activity.Totals)
+	require.Len(t, activity.Worktrees, 1)
+	wa := activity.Worktrees[0]
+	assert.Equal(t, "feature/login", wa.Branch)
+	assert.Equal(t, 3, wa.Commits)
+	assert.Equal(t, 2, wa.FilesChanged)
+
+	kinds := map[ActivityKind]int{}
+	for i, entry := range activity.Events {
+		kinds[entry.Kind]++
+		if i > 0 {
+			assert.False(t, entry.Time.Before(activity.Events[i-1].Time), "events are chronological")
+		}
+	}
+	assert.Equal(t, map[ActivityKind]int{ActivityWorktreeCreated: 1, ActivitySessionTitle: 1, ActivityCommit: 3, ActivityPullRequestOpened: 1}, kinds)
+	assert.Len(t, wa.Events, len(activity.Events))
+
+	markdown := activity.Markdown()
+	assert.Contains(t, markdown, "1 session, 3 commits, 2 files changed, 1 PR opened, 0 merged")
+	assert.Contains(t, markdown, "**felix/tabby** (`feature/login`): 3 commits, 2 files, https://github.com/acme/felix/pull/7")
+	assert.Contains(t, markdown, "Created from main via ui for #12 (alice)")
+	assert.Contains(t, markdown, "Session: Add OAuth login")
+	assert.NotContains(t, markdown, "Planning yesterday")
+
+	_, err = s.GetRepositoryActivity("local/repo", now, now.Add(-time.Hour))
+	assert.Error(t, err)
+	_, err = s.GetRepositoryActivity("local/repo", now.Add(-60*24*time.Hour), now)
+	assert.Error(t, err)
+	_, err = s.GetRepositoryActivity("local/missing", now.Add(-time.Hour), now)
+	assert.Error(t, err)
+}
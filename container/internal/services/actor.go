@@ -0,0 +1,72 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// DefaultActor is recorded when a request doesn't identify who made it
+const DefaultActor = "unknown"
+
+// maxActorLength keeps actor identities short enough for logs, PR bodies and the TUI
+const maxActorLength = 64
+
+// worktreeActors holds the actor of the API request currently changing each worktree
+var worktreeActors sync.Map
+
+// worktreeActing is the registration of one request in worktreeActors
+type worktreeActing struct {
+	actor string
+}
+
+// ActOnWorktree attributes the events and activity entries of a worktree to actor until the
+// returned func is called, for the duration of a request that changes it. When requests on the
+// same worktree overlap, the latest one wins.
+func ActOnWorktree(worktreeID, actor string) func() {
+	acting := &worktreeActing{actor: NormalizeActor(actor)}
+	worktreeActors.Store(worktreeID, acting)
+	return func() { worktreeActors.CompareAndDelete(worktreeID, acting) }
+}
+
+// WorktreeActor returns who is currently changing a worktree through the API, or "" when no
+// request is. Changes catnip makes by itself have no actor.
+func WorktreeActor(worktreeID string) string {
+	if acting, ok := worktreeActors.Load(worktreeID); ok {
+		return acting.(*worktreeActing).actor
+	}
+	return ""
+}
+
+// NormalizeActor trims an actor identity, strips control characters and applies the default
+func NormalizeActor(actor string) string {
+	actor = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, actor)
+	actor = strings.TrimSpace(actor)
+	if runes := []rune(actor); len(runes) > maxActorLength {
+		actor = string(runes[:maxActorLength])
+	}
+	if actor == "" {
+		return DefaultActor
+	}
+	return actor
+}
+
+// AppendActorAttribution appends a "created by" line to a pull request body
+// Unknown actors are not recorded to avoid noise in single-user setups
+func AppendActorAttribution(body, actor string) string {
+	actor = NormalizeActor(actor)
+	if actor == DefaultActor {
+		return body
+	}
+	attribution := fmt.Sprintf("_Requested via catnip by %s_", actor)
+	if strings.TrimSpace(body) == "" {
+		return attribution
+	}
+	return fmt.Sprintf("%s\n\n---\n%s", strings.TrimRight(body, "\n"), attribution)
+}
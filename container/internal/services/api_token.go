@@ -0,0 +1,82 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/vanpelt/catnip/internal/config"
+)
+
+// APITokenEnv overrides the stored API token (used to hand the host token to the container)
+const APITokenEnv = "CATNIP_API_TOKEN"
+
+// APITokenHeader is the request header clients use to send the API token
+const APITokenHeader = "X-Catnip-Token"
+
+// NoAuthEnv disables API token authentication entirely (local development only)
+const NoAuthEnv = "CATNIP_NO_AUTH"
+
+// GetAPITokenPath returns the location of the persisted API token (~/.catnip/api-token)
+func GetAPITokenPath() string {
+	return filepath.Join(config.Runtime.HomeDir, ".catnip", "api-token")
+}
+
+// GenerateAPIToken creates a new random API token
+func GenerateAPIToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate API token: %v", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// SaveAPIToken persists the API token with owner-only permissions
+func SaveAPIToken(path, token string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create token directory: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(token+"\n"), 0600); err != nil {
+		return fmt.Errorf("failed to write API token: %v", err)
+	}
+	return nil
+}
+
+// LoadOrCreateAPIToken returns the API token from the environment or the token file,
+// generating and saving a new one when neither exists. created reports whether a new
+// token was generated so callers can print it once.
+func LoadOrCreateAPIToken(path string) (token string, created bool, err error) {
+	if token = strings.TrimSpace(os.Getenv(APITokenEnv)); token != "" {
+		return token, false, nil
+	}
+
+	if data, readErr := os.ReadFile(path); readErr == nil {
+		if token = strings.TrimSpace(string(data)); token != "" {
+			return token, false, nil
+		}
+	}
+
+	token, err = GenerateAPIToken()
+	if err != nil {
+		return "", false, err
+	}
+	if err := SaveAPIToken(path, token); err != nil {
+		return "", false, err
+	}
+	return token, true, nil
+}
+
+// ReadAPIToken returns the API token clients should send, or "" if none is configured
+func ReadAPIToken() string {
+	if token := strings.TrimSpace(os.Getenv(APITokenEnv)); token != "" {
+		return token
+	}
+	data, err := os.ReadFile(GetAPITokenPath())
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
@@ -0,0 +1,134 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// Per-repo git config key selecting how automatic branch renames handle existing branches
+const branchCollisionPolicyKey = "catnip.branch.collision-policy"
+
+// BranchCollisionPolicy decides what happens when the suggested branch name already exists
+type BranchCollisionPolicy string
+
+const (
+	// CollisionSuffix appends -1, -2, ... until the name is free (the default)
+	CollisionSuffix BranchCollisionPolicy = "suffix"
+	// CollisionSkip keeps the catnip ref and asks the user to resolve the collision manually; no
+	// further automatic renames are attempted for the worktree
+	CollisionSkip BranchCollisionPolicy = "skip"
+	// CollisionAdopt suffixes the name and links the worktree to the clean catnip worktree
+	// that already owns the branch, so the two efforts can be merged
+	CollisionAdopt BranchCollisionPolicy = "adopt"
+)
+
+// Outcomes reported in BranchRenameOutcome
+const (
+	BranchRenameRenamed  = "renamed"
+	BranchRenameSuffixed = "suffixed"
+	BranchRenameSkipped  = "skipped"
+	BranchRenameAdopted  = "adopted"
+)
+
+// maxBranchSuffix bounds the search for a free suffixed branch name
+const maxBranchSuffix = 100
+
+// loadBranchCollisionPolicy reads the collision policy from the repository's git config,
+// falling back to suffix for unset or invalid values
+func loadBranchCollisionPolicy(getConfig func(key string) (string, error)) BranchCollisionPolicy {
+	value, err := getConfig(branchCollisionPolicyKey)
+	if err != nil || value == "" {
+		return CollisionSuffix
+	}
+
+	switch policy := BranchCollisionPolicy(value); policy {
+	case CollisionSuffix, CollisionSkip, CollisionAdopt:
+		return policy
+	default:
+		logger.Warnf("⚠️  Ignoring invalid %s value %q", branchCollisionPolicyKey, value)
+		return CollisionSuffix
+	}
+}
+
+// nextFreeBranchName returns the first of name, name-1, name-2, ... that doesn't exist
+func nextFreeBranchName(name string, exists func(branch string) bool) (string, error) {
+	candidate := name
+	for counter := 1; exists(candidate); counter++ {
+		if counter > maxBranchSuffix { // Safety limit to prevent infinite loops
+			return "", fmt.Errorf("too many similar branches exist for %q", name)
+		}
+		logger.Debugf("🔍 Branch %q exists, trying next...", candidate)
+		candidate = fmt.Sprintf("%s-%d", name, counter)
+	}
+	return candidate, nil
+}
+
+// findBranchOwner returns the other catnip worktree of the repository displaying the given branch
+func findBranchOwner(worktrees map[string]*models.Worktree, repoID, branch, excludeID string) *models.Worktree {
+	for _, wt := range worktrees {
+		if wt.ID != excludeID && wt.RepoID == repoID && wt.HasBeenRenamed && wt.Branch == branch {
+			return wt
+		}
+	}
+	return nil
+}
+
+// isAdoptionCandidate reports whether a worktree can be linked by the adopt policy: it must
+// have no uncommitted work or conflicts and no Claude session actively working in it
+func isAdoptionCandidate(wt *models.Worktree) bool {
+	return !wt.IsDirty && !wt.HasConflicts && wt.ClaudeActivityState != models.ClaudeActive
+}
+
+// resolveBranchCollision picks the branch name for an automatic rename according to the policy.
+// The returned outcome has an empty Branch when the rename should be skipped.
+func resolveBranchCollision(policy BranchCollisionPolicy, requested, currentBranch string, exists func(branch string) bool, owner *models.Worktree) (*models.BranchRenameOutcome, error) {
+	outcome := &models.BranchRenameOutcome{
+		Policy:          string(policy),
+		Outcome:         BranchRenameRenamed,
+		RequestedBranch: requested,
+		Branch:          requested,
+		At:              time.Now(),
+	}
+	if !exists(requested) {
+		return outcome, nil
+	}
+
+	ownerDescription := "an existing branch"
+	if owner != nil {
+		outcome.CollidingWorktreeID = owner.ID
+		ownerDescription = fmt.Sprintf("worktree %s", owner.Name)
+	}
+
+	if policy == CollisionSkip {
+		outcome.Outcome = BranchRenameSkipped
+		outcome.Branch = ""
+		outcome.Message = fmt.Sprintf("%s is already used by %s; kept %s. Pick another name or merge the work manually.",
+			requested, ownerDescription, currentBranch)
+		return outcome, nil
+	}
+
+	branch, err := nextFreeBranchName(requested, exists)
+	if err != nil {
+		return nil, err
+	}
+	outcome.Branch = branch
+	outcome.Outcome = BranchRenameSuffixed
+	outcome.Message = fmt.Sprintf("%s is already used by %s; renamed to %s", requested, ownerDescription, branch)
+
+	if policy == CollisionAdopt {
+		switch {
+		case owner == nil:
+			outcome.Message += " (not linked: the branch doesn't belong to another catnip worktree)"
+		case !isAdoptionCandidate(owner):
+			outcome.Message += fmt.Sprintf(" (not linked: worktree %s has work in progress)", owner.Name)
+		default:
+			outcome.Outcome = BranchRenameAdopted
+			outcome.Message += " and linked the worktrees as related"
+		}
+	}
+
+	return outcome, nil
+}
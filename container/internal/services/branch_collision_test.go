@@ -0,0 +1,77 @@
+package services
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+func TestLoadBranchCollisionPolicy(t *testing.T) {
+	config := func(value string, err error) func(string) (string, error) {
+		return func(string) (string, error) { return value, err }
+	}
+
+	assert.Equal(t, CollisionSuffix, loadBranchCollisionPolicy(config("", errors.New("unset"))))
+	assert.Equal(t, CollisionSkip, loadBranchCollisionPolicy(config("skip", nil)))
+	assert.Equal(t, CollisionAdopt, loadBranchCollisionPolicy(config("adopt", nil)))
+	assert.Equal(t, CollisionSuffix, loadBranchCollisionPolicy(config("merge", nil)))
+}
+
+func TestResolveBranchCollision(t *testing.T) {
+	existing := map[string]bool{"feature/add-auth": true, "feature/add-auth-1": true}
+	exists := func(branch string) bool { return existing[branch] }
+	owner := &models.Worktree{ID: "wt-luna", Name: "repo/luna", Branch: "feature/add-auth", HasBeenRenamed: true}
+
+	outcome, err := resolveBranchCollision(CollisionSkip, "feature/login", "refs/catnip/felix", exists, nil)
+	require.NoError(t, err)
+	assert.Equal(t, BranchRenameRenamed, outcome.Outcome)
+	assert.Equal(t, "feature/login", outcome.Branch)
+
+	outcome, err = resolveBranchCollision(CollisionSuffix, "feature/add-auth", "refs/catnip/felix", exists, owner)
+	require.NoError(t, err)
+	assert.Equal(t, BranchRenameSuffixed, outcome.Outcome)
+	assert.Equal(t, "feature/add-auth-2", outcome.Branch)
+	assert.Equal(t, "wt-luna", outcome.CollidingWorktreeID)
+
+	outcome, err = resolveBranchCollision(CollisionSkip, "feature/add-auth", "refs/catnip/felix", exists, owner)
+	require.NoError(t, err)
+	assert.Equal(t, BranchRenameSkipped, outcome.Outcome)
+	assert.Empty(t, outcome.Branch)
+	assert.Contains(t, outcome.Message, "refs/catnip/felix")
+
+	outcome, err = resolveBranchCollision(CollisionAdopt, "feature/add-auth", "refs/catnip/felix", exists, owner)
+	require.NoError(t, err)
+	assert.Equal(t, BranchRenameAdopted, outcome.Outcome)
+	assert.Equal(t, "feature/add-auth-2", outcome.Branch)
+
+	busy := *owner
+	busy.IsDirty = true
+	outcome, err = resolveBranchCollision(CollisionAdopt, "feature/add-auth", "refs/catnip/felix", exists, &busy)
+	require.NoError(t, err)
+	assert.Equal(t, BranchRenameSuffixed, outcome.Outcome, "worktrees with work in progress are not adopted")
+	assert.Contains(t, outcome.Message, "not linked")
+}
+
+func TestLinkRelatedWorktrees(t *testing.T) {
+	root := t.TempDir()
+	stateManager := NewWorktreeStateManager(filepath.Join(root, "state"), nil)
+	t.Cleanup(stateManager.Stop)
+
+	require.NoError(t, stateManager.AddRepository(&models.Repository{ID: "local/repo", Path: filepath.Join(root, "repo")}))
+	require.NoError(t, stateManager.AddWorktree(&models.Worktree{ID: "wt-felix", RepoID: "local/repo", Name: "felix"}))
+	require.NoError(t, stateManager.AddWorktree(&models.Worktree{ID: "wt-luna", RepoID: "local/repo", Name: "luna"}))
+
+	require.NoError(t, stateManager.LinkRelatedWorktrees("wt-felix", "wt-luna", "both targeted feature/add-auth"))
+	require.NoError(t, stateManager.LinkRelatedWorktrees("wt-felix", "wt-luna", ""))
+
+	felix, _ := stateManager.GetWorktree("wt-felix")
+	luna, _ := stateManager.GetWorktree("wt-luna")
+	assert.Equal(t, []string{"wt-luna"}, felix.RelatedWorktreeIDs)
+	assert.Equal(t, []string{"wt-felix"}, luna.RelatedWorktreeIDs)
+	assert.Equal(t, []string{"both targeted feature/add-auth"}, luna.Notes)
+	assert.Error(t, stateManager.LinkRelatedWorktrees("wt-felix", "wt-missing", ""))
+}
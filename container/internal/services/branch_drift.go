@@ -0,0 +1,186 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// Git config key selecting how catnip reacts when a worktree's branch is switched outside
+// catnip. Can be set per repository or globally.
+const branchDriftPolicyKey = "catnip.branch.drift-policy"
+
+// BranchDriftPolicy decides what happens when the checked-out branch no longer matches state
+type BranchDriftPolicy string
+
+const (
+	// DriftFlag records the drift and blocks pull requests and merges until acknowledged (the default)
+	DriftFlag BranchDriftPolicy = "flag"
+	// DriftAdopt updates state to the checked-out branch
+	DriftAdopt BranchDriftPolicy = "adopt"
+)
+
+// gitOperationStateFiles mark a rebase, merge, cherry-pick, revert or bisect in progress,
+// during which HEAD is expected to move away from the branch
+var gitOperationStateFiles = []string{"rebase-merge", "rebase-apply", "MERGE_HEAD", "CHERRY_PICK_HEAD", "REVERT_HEAD", "BISECT_LOG"}
+
+// loadBranchDriftPolicy reads the drift policy from git config, falling back to flag for
+// unset or invalid values
+func loadBranchDriftPolicy(getConfig func(key string) (string, error)) BranchDriftPolicy {
+	value, err := getConfig(branchDriftPolicyKey)
+	if err != nil || value == "" {
+		return DriftFlag
+	}
+
+	switch policy := BranchDriftPolicy(value); policy {
+	case DriftFlag, DriftAdopt:
+		return policy
+	default:
+		logger.Warnf("⚠️  Ignoring invalid %s value %q", branchDriftPolicyKey, value)
+		return DriftFlag
+	}
+}
+
+// gitOperationInProgress reports whether a multi-step git operation is underway in a worktree
+func (s *GitService) gitOperationInProgress(worktreePath string) bool {
+	for _, name := range gitOperationStateFiles {
+		if s.gitStateFileExists(worktreePath, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// gitStateFileExists reports whether the state file or directory name exists in the git
+// directory of a worktree
+func (s *GitService) gitStateFileExists(worktreePath, name string) bool {
+	output, err := s.operations.ExecuteGit(worktreePath, "rev-parse", "--git-path", name)
+	if err != nil {
+		return false
+	}
+	path := strings.TrimSpace(string(output))
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(worktreePath, path)
+	}
+	_, err = os.Stat(path)
+	return err == nil
+}
+
+// checkBranchDrift compares the branch found by a status refresh with the branch recorded in
+// state. A different branch must be seen by two consecutive refreshes, so catnip's own branch
+// switches have time to update state. Depending on the drift policy the change is then adopted,
+// or flagged on the worktree. Until adopted, the status branch is cleared so the refresh doesn't
+// overwrite state.
+func (s *GitService) checkBranchDrift(worktree *models.Worktree, worktreePath string, status *CachedWorktreeStatus) {
+	if worktree.Branch == "" || status.CommitHash == "" {
+		return
+	}
+
+	if status.Branch == worktree.Branch {
+		s.driftObservations.Delete(worktree.ID)
+		if worktree.ManualIntervention != nil {
+			logger.Infof("✅ Worktree %s is back on %s, clearing branch drift", worktree.Name, worktree.Branch)
+			if err := s.stateManager.UpdateWorktree(worktree.ID, map[string]interface{}{"manual_intervention": (*models.BranchDrift)(nil)}); err != nil {
+				logger.Warnf("⚠️  Failed to clear branch drift for %s: %v", worktree.Name, err)
+			}
+		}
+		return
+	}
+
+	if s.gitOperationInProgress(worktreePath) {
+		status.Branch = ""
+		return
+	}
+	if previous, seen := s.driftObservations.Swap(worktree.ID, status.Branch); !seen || previous != status.Branch {
+		status.Branch = ""
+		return
+	}
+
+	drift := &models.BranchDrift{
+		ExpectedBranch: worktree.Branch,
+		ActualBranch:   status.Branch,
+		ExpectedCommit: worktree.CommitHash,
+		ActualCommit:   status.CommitHash,
+		DetectedAt:     time.Now(),
+	}
+
+	policy := loadBranchDriftPolicy(func(key string) (string, error) {
+		return s.operations.GetConfig(worktreePath, key)
+	})
+	if policy == DriftAdopt && drift.ActualBranch != "" {
+		drift.Adopted = true
+		logger.Infof("🔀 Worktree %s switched from %s to %s outside catnip, adopting", worktree.Name, drift.ExpectedBranch, drift.ActualBranch)
+		if worktree.ManualIntervention != nil {
+			if err := s.stateManager.UpdateWorktree(worktree.ID, map[string]interface{}{"manual_intervention": (*models.BranchDrift)(nil)}); err != nil {
+				logger.Warnf("⚠️  Failed to clear branch drift for %s: %v", worktree.Name, err)
+			}
+		}
+	} else {
+		status.Branch = ""
+		if previous := worktree.ManualIntervention; previous != nil &&
+			previous.ActualBranch == drift.ActualBranch && previous.ActualCommit == drift.ActualCommit {
+			return // Already flagged
+		}
+		logger.Warnf("⚠️  Worktree %s is on %q but catnip expects %s, flagging for manual intervention", worktree.Name, drift.ActualBranch, drift.ExpectedBranch)
+		if err := s.stateManager.UpdateWorktree(worktree.ID, map[string]interface{}{"manual_intervention": drift}); err != nil {
+			logger.Warnf("⚠️  Failed to flag branch drift for %s: %v", worktree.Name, err)
+			return
+		}
+	}
+
+	if s.eventsEmitter != nil {
+		s.eventsEmitter.EmitWorktreeBranchDrift(worktree.ID, drift)
+	}
+}
+
+// AcknowledgeBranchDrift accepts the branch currently checked out in a flagged worktree,
+// updating state to match it and unblocking pull requests and merges
+func (s *GitService) AcknowledgeBranchDrift(worktreeID string) (*models.Worktree, error) {
+	worktree, exists := s.stateManager.GetWorktree(worktreeID)
+	if !exists {
+		return nil, fmt.Errorf("worktree %s not found", worktreeID)
+	}
+	if worktree.ManualIntervention == nil {
+		return nil, fmt.Errorf("worktree %s has no branch drift to acknowledge", worktree.Name)
+	}
+
+	branch, err := s.operations.GetDisplayBranch(worktree.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read current branch: %v", err)
+	}
+	if branch == "" {
+		return nil, fmt.Errorf("worktree %s has a detached HEAD, check out a branch before acknowledging", worktree.Name)
+	}
+
+	expected := worktree.ManualIntervention.ExpectedBranch
+	updates := map[string]interface{}{
+		"branch":              branch,
+		"manual_intervention": (*models.BranchDrift)(nil),
+	}
+	if commitHash, err := s.operations.GetCommitHash(worktree.Path, "HEAD"); err == nil {
+		updates["commit_hash"] = commitHash
+	}
+	if err := s.stateManager.UpdateWorktree(worktreeID, updates); err != nil {
+		return nil, err
+	}
+
+	logger.Infof("🔀 Acknowledged branch %s for worktree %s (was %s)", branch, worktree.Name, expected)
+	return worktree, nil
+}
+
+// requireNoBranchDrift refuses operations on worktrees whose branch changed outside catnip
+func requireNoBranchDrift(worktree *models.Worktree) error {
+	if drift := worktree.ManualIntervention; drift != nil {
+		actual := drift.ActualBranch
+		if actual == "" {
+			actual = "a detached HEAD"
+		}
+		return fmt.Errorf("worktree %s is on %s but catnip expects %s; acknowledge the branch change first", worktree.Name, actual, drift.ExpectedBranch)
+	}
+	return nil
+}
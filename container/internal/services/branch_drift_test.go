@@ -0,0 +1,116 @@
+package services
+
+import (
+	"errors"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// driftRecorder records branch drift events; other events are not expected
+type driftRecorder struct {
+	EventsEmitter
+	drifts []models.BranchDrift
+}
+
+func (r *driftRecorder) EmitWorktreeBranchDrift(worktreeID string, drift *models.BranchDrift) {
+	r.drifts = append(r.drifts, *drift)
+}
+
+func TestLoadBranchDriftPolicy(t *testing.T) {
+	config := func(value string, err error) func(string) (string, error) {
+		return func(string) (string, error) { return value, err }
+	}
+	assert.Equal(t, DriftFlag, loadBranchDriftPolicy(config("", errors.New("unset"))))
+	assert.Equal(t, DriftAdopt, loadBranchDriftPolicy(config("adopt", nil)))
+	assert.Equal(t, DriftFlag, loadBranchDriftPolicy(config("ignore", nil)))
+}
+
+func TestCheckBranchDrift(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	root := t.TempDir()
+	repoPath := initTestRepo(t, filepath.Join(root, "repo"), nil)
+	runTestGit(t, repoPath, "checkout", "-b", "feature")
+
+	s := newTestGitService(t, root)
+	stateManager := s.stateManager
+	require.NoError(t, stateManager.AddRepository(&models.Repository{ID: "local/repo", Path: repoPath}))
+	require.NoError(t, stateManager.AddWorktree(&models.Worktree{
+		ID: "wt-felix", RepoID: "local/repo", Name: "felix", Path: repoPath, Branch: "feature", SourceBranch: "main",
+	}))
+
+	cache := NewWorktreeStatusCache(s.operations, stateManager)
+	t.Cleanup(cache.Stop)
+	events := &driftRecorder{}
+	s.worktreeCache = cache
+	s.eventsEmitter = events
+	cache.SetWorktreePathResolver(func(worktreeID string) (string, *models.Worktree) {
+		worktree, _ := stateManager.GetWorktree(worktreeID)
+		return worktree.Path, worktree
+	})
+	cache.SetBranchDriftChecker(s.checkBranchDrift)
+
+	cache.statuses["wt-felix"] = &CachedWorktreeStatus{WorktreeID: "wt-felix"}
+	refresh := func() *models.Worktree {
+		cache.processBatchUpdates(map[string]bool{"wt-felix": true}, models.StatusTierStandard, true)
+		worktree, _ := stateManager.GetWorktree("wt-felix")
+		return worktree
+	}
+
+	assert.Nil(t, refresh().ManualIntervention)
+
+	// The user switches branches between refreshes; the second refresh confirms the drift
+	runTestGit(t, repoPath, "checkout", "-b", "experiment")
+	worktree := refresh()
+	assert.Nil(t, worktree.ManualIntervention)
+	assert.Equal(t, "feature", worktree.Branch)
+
+	worktree = refresh()
+	require.NotNil(t, worktree.ManualIntervention)
+	assert.Equal(t, "feature", worktree.Branch, "state keeps the expected branch")
+	assert.Equal(t, "experiment", worktree.ManualIntervention.ActualBranch)
+	assert.Equal(t, runTestGit(t, repoPath, "rev-parse", "HEAD"), worktree.ManualIntervention.ActualCommit)
+	require.Len(t, events.drifts, 1)
+	assert.False(t, events.drifts[0].Adopted)
+
+	refresh()
+	assert.Len(t, events.drifts, 1, "drift is reported once")
+
+	_, err := s.CreatePullRequest("wt-felix", "Title", "Body", PullRequestOptions{})
+	assert.ErrorContains(t, err, "acknowledge")
+	_, err = s.UpdatePullRequest("wt-felix", "Title", "Body", PullRequestOptions{})
+	assert.ErrorContains(t, err, "acknowledge")
+	assert.ErrorContains(t, s.MergeWorktreeToMain("wt-felix", MergeOptions{}), "acknowledge")
+
+	// Switching back clears the flag
+	runTestGit(t, repoPath, "checkout", "feature")
+	assert.Nil(t, refresh().ManualIntervention)
+
+	// Acknowledging adopts the checked-out branch
+	runTestGit(t, repoPath, "checkout", "experiment")
+	refresh()
+	require.NotNil(t, refresh().ManualIntervention)
+	worktree, err = s.AcknowledgeBranchDrift("wt-felix")
+	require.NoError(t, err)
+	assert.Equal(t, "experiment", worktree.Branch)
+	assert.Nil(t, worktree.ManualIntervention)
+	_, err = s.AcknowledgeBranchDrift("wt-felix")
+	assert.Error(t, err)
+
+	// With the adopt policy state follows the checkout
+	runTestGit(t, repoPath, "config", branchDriftPolicyKey, "adopt")
+	runTestGit(t, repoPath, "checkout", "feature")
+	refresh()
+	worktree = refresh()
+	assert.Equal(t, "feature", worktree.Branch)
+	assert.Nil(t, worktree.ManualIntervention)
+	require.Len(t, events.drifts, 3)
+	assert.True(t, events.drifts[2].Adopted)
+}
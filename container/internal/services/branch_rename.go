@@ -0,0 +1,74 @@
+package services
+
+import (
+	"time"
+
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// What started a branch rename attempt, reported in BranchRenameOutcome.Trigger
+const (
+	BranchRenameTriggerTitle  = "title"
+	BranchRenameTriggerTodo   = "todo"
+	BranchRenameTriggerManual = "manual"
+)
+
+// BranchRenameFailed is the outcome of rename attempts that ran into an error
+const BranchRenameFailed = "failed"
+
+// Why a branch rename was skipped or failed, reported in BranchRenameOutcome.Reason
+const (
+	RenameReasonEmptyTitle        = "empty_title"
+	RenameReasonNotCatnipBranch   = "not_catnip_branch"
+	RenameReasonInProgress        = "rename_in_progress"
+	RenameReasonBranchLookup      = "branch_lookup_failed"
+	RenameReasonClaudeTimeout     = "claude_timeout"
+	RenameReasonClaudeError       = "claude_error"
+	RenameReasonEmptySuggestion   = "empty_suggestion"
+	RenameReasonInvalidSuggestion = "invalid_suggestion"
+	RenameReasonWorktreeNotFound  = "worktree_not_found"
+	RenameReasonBranchExists      = "branch_exists"
+	RenameReasonNoFreeName        = "no_free_name"
+	RenameReasonRenameFailed      = "rename_failed"
+	RenameReasonHandedOff         = "handed_off"
+)
+
+// newBranchRenameOutcome returns the outcome of a rename attempt that didn't rename the branch
+func newBranchRenameOutcome(trigger, outcome, reason, requested, message string) *models.BranchRenameOutcome {
+	return &models.BranchRenameOutcome{
+		Trigger:         trigger,
+		Outcome:         outcome,
+		Reason:          reason,
+		RequestedBranch: requested,
+		Message:         message,
+		At:              time.Now(),
+	}
+}
+
+// RecordBranchRename stores the outcome of a rename attempt that didn't rename the branch on the
+// worktree and broadcasts it, so users can see why the branch kept its name
+func (wsm *WorktreeStateManager) RecordBranchRename(worktreeID string, outcome *models.BranchRenameOutcome) error {
+	if err := wsm.UpdateWorktree(worktreeID, map[string]interface{}{"branch_rename": outcome}); err != nil {
+		return err
+	}
+	if wsm.eventsEmitter != nil {
+		wsm.eventsEmitter.EmitWorktreeBranchRename(worktreeID, outcome)
+	}
+	return nil
+}
+
+// branchRenameSettled reports whether automatic renames of a worktree are over because the skip
+// collision policy kept its catnip ref, leaving the name to the user. Only manual renames are
+// attempted for it, so Claude isn't asked again on every title or todo change.
+func (wsm *WorktreeStateManager) branchRenameSettled(worktreeID string) bool {
+	if wsm == nil {
+		return false
+	}
+	worktree, exists := wsm.GetWorktree(worktreeID)
+	if !exists || worktree.BranchRename == nil {
+		return false
+	}
+	outcome := worktree.BranchRename
+	return outcome.Outcome == BranchRenameSkipped && outcome.Reason == RenameReasonBranchExists &&
+		outcome.Policy == string(CollisionSkip)
+}
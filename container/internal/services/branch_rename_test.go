@@ -0,0 +1,129 @@
+package services
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// branchRenameRecorder records branch rename events and ignores worktree updates; other events
+// are not expected
+type branchRenameRecorder struct {
+	EventsEmitter
+	mu       sync.Mutex
+	outcomes []*models.BranchRenameOutcome
+}
+
+func (r *branchRenameRecorder) EmitWorktreeBranchRename(worktreeID string, outcome *models.BranchRenameOutcome) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.outcomes = append(r.outcomes, outcome)
+}
+
+func (r *branchRenameRecorder) EmitWorktreeUpdated(worktreeID string, updates map[string]interface{}) {
+}
+
+func TestBranchRenameRecordsWhyItWasSkipped(t *testing.T) {
+	root := t.TempDir()
+	repoPath := initTestRepo(t, filepath.Join(root, "repo"), nil)
+
+	recorder := &branchRenameRecorder{}
+	gitService := newTestGitService(t, root)
+	stateManager := gitService.stateManager
+	require.NoError(t, stateManager.AddRepository(&models.Repository{ID: "acme/felix", Path: repoPath, DefaultBranch: "main", Available: true}))
+	require.NoError(t, stateManager.AddWorktree(&models.Worktree{
+		ID: "wt-felix", RepoID: "acme/felix", Name: "felix/tabby", Path: repoPath, Branch: "main",
+	}))
+	stateManager.eventsEmitter = recorder
+
+	wrapper := NewMockClaudeSubprocessWrapper()
+	m := &WorktreeCheckpointManager{
+		workDir:       repoPath,
+		worktreeID:    "wt-felix",
+		gitService:    gitService,
+		claudeService: NewClaudeServiceWithWrapper(wrapper),
+		stateManager:  stateManager,
+	}
+
+	// Branches the user picked are never renamed
+	outcome := m.checkAndRenameBranch("Add login page", BranchRenameTriggerTitle)
+	assert.Equal(t, BranchRenameSkipped, outcome.Outcome)
+	assert.Equal(t, RenameReasonNotCatnipBranch, outcome.Reason)
+	assert.Equal(t, BranchRenameTriggerTitle, outcome.Trigger)
+
+	worktree, _ := stateManager.GetWorktree("wt-felix")
+	require.NotNil(t, worktree.BranchRename)
+	assert.Equal(t, RenameReasonNotCatnipBranch, worktree.BranchRename.Reason)
+
+	// Claude failing to suggest a name is recorded as a failure
+	runTestGit(t, repoPath, "update-ref", "refs/catnip/felix", "HEAD")
+	runTestGit(t, repoPath, "symbolic-ref", "HEAD", "refs/catnip/felix")
+	wrapper.ShouldFail = true
+	outcome = m.checkAndRenameBranch("Add login page", BranchRenameTriggerTodo)
+	assert.Equal(t, BranchRenameFailed, outcome.Outcome)
+	assert.Equal(t, RenameReasonClaudeError, outcome.Reason)
+
+	worktree, _ = stateManager.GetWorktree("wt-felix")
+	assert.Equal(t, RenameReasonClaudeError, worktree.BranchRename.Reason)
+	assert.Equal(t, BranchRenameTriggerTodo, worktree.BranchRename.Trigger)
+
+	// Manual renames don't run while another rename is in progress
+	m.currentTitle = "Add login page"
+	m.renamingInProgress = true
+	monitor := &ClaudeMonitorService{gitService: m.gitService, stateManager: stateManager, checkpointManagers: map[string]*WorktreeCheckpointManager{repoPath: m}}
+	outcome, err := monitor.TriggerBranchRename(repoPath, "")
+	require.NoError(t, err)
+	assert.Equal(t, RenameReasonInProgress, outcome.Reason)
+	assert.Equal(t, BranchRenameTriggerManual, outcome.Trigger)
+
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	require.Len(t, recorder.outcomes, 3)
+	assert.Equal(t, []string{RenameReasonNotCatnipBranch, RenameReasonClaudeError, RenameReasonInProgress},
+		[]string{recorder.outcomes[0].Reason, recorder.outcomes[1].Reason, recorder.outcomes[2].Reason})
+}
+
+func TestBranchRenameSettlesWhenTheSkipPolicyKeepsTheCatnipRef(t *testing.T) {
+	root := t.TempDir()
+	repoPath := initTestRepo(t, filepath.Join(root, "repo"), nil)
+	runTestGit(t, repoPath, "branch", "feature/add-login-page")
+	runTestGit(t, repoPath, "update-ref", "refs/catnip/felix", "HEAD")
+	runTestGit(t, repoPath, "symbolic-ref", "HEAD", "refs/catnip/felix")
+
+	gitService := newTestGitService(t, root)
+	stateManager := gitService.stateManager
+	require.NoError(t, stateManager.AddRepository(&models.Repository{ID: "acme/felix", Path: repoPath, DefaultBranch: "main", Available: true}))
+	require.NoError(t, stateManager.AddWorktree(&models.Worktree{
+		ID: "wt-felix", RepoID: "acme/felix", Name: "felix/tabby", Path: repoPath, Branch: "refs/catnip/felix",
+	}))
+	stateManager.eventsEmitter = &branchRenameRecorder{}
+
+	wrapper := NewMockClaudeSubprocessWrapper()
+	m := &WorktreeCheckpointManager{
+		workDir:       repoPath,
+		worktreeID:    "wt-felix",
+		gitService:    gitService,
+		claudeService: NewClaudeServiceWithWrapper(wrapper),
+		stateManager:  stateManager,
+	}
+
+	// Suffixing never settles the worktree
+	assert.False(t, stateManager.branchRenameSettled("wt-felix"))
+	require.NoError(t, stateManager.RecordBranchRename("wt-felix", &models.BranchRenameOutcome{
+		Outcome: BranchRenameSkipped, Reason: RenameReasonBranchExists, Policy: string(CollisionSuffix),
+	}))
+	assert.False(t, stateManager.branchRenameSettled("wt-felix"))
+
+	runTestGit(t, repoPath, "config", branchCollisionPolicyKey, "skip")
+	outcome := m.checkAndRenameBranch("Add login page", BranchRenameTriggerTitle)
+	assert.Equal(t, BranchRenameSkipped, outcome.Outcome)
+	assert.Equal(t, RenameReasonBranchExists, outcome.Reason)
+	assert.True(t, stateManager.branchRenameSettled("wt-felix"), "Claude isn't asked again for automatic renames")
+	worktree, _ := stateManager.GetWorktree("wt-felix")
+	assert.False(t, worktree.HasBeenRenamed, "manual renames stay possible")
+	assert.Equal(t, "refs/catnip/felix", runTestGit(t, repoPath, "symbolic-ref", "HEAD"))
+}
@@ -0,0 +1,264 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// bulkPullRequestDelay spaces out the pull requests CreatePullRequestsBulk opens, as GitHub's
+// abuse detection flags bursts of content-creating requests
+const bulkPullRequestDelay = time.Second
+
+// How CreatePullRequestsBulk generates pull request titles and bodies
+const (
+	// PullRequestTextSession titles pull requests after the latest session title, falling back
+	// to the latest commit subject, and lists the commit subjects in the body
+	PullRequestTextSession = "session"
+	// PullRequestTextCommits titles pull requests after the latest commit subject and lists the
+	// other commit subjects in the body
+	PullRequestTextCommits = "commits"
+)
+
+// Outcomes of a worktree in CreatePullRequestsBulk
+const (
+	BulkPullRequestCreated = "created"
+	BulkPullRequestSkipped = "skipped"
+	BulkPullRequestFailed  = "failed"
+)
+
+// BulkPullRequestSelector picks the worktrees CreatePullRequestsBulk opens pull requests for:
+// every worktree of a repository, or the listed worktrees
+type BulkPullRequestSelector struct {
+	RepoID      string   `json:"repo_id,omitempty"`
+	WorktreeIDs []string `json:"worktree_ids,omitempty"`
+}
+
+// BulkPullRequestOptions configures CreatePullRequestsBulk
+type BulkPullRequestOptions struct {
+	Draft bool `json:"draft"`
+	// Sync each worktree with its source branch before opening its pull request
+	SyncFirst bool `json:"sync_first"`
+	// How sync_first syncs: ff-only (the default), merge or rebase. Worktrees ff-only can't
+	// fast-forward are skipped rather than rewritten.
+	SyncStrategy string `json:"sync_strategy"`
+	// PullRequestTextSession (the default) or PullRequestTextCommits
+	TextMode string `json:"text_mode"`
+	// Who requested the pull requests, attributed in their bodies
+	Actor string `json:"-"`
+}
+
+// BulkPullRequestResult is the outcome of CreatePullRequestsBulk for one worktree
+type BulkPullRequestResult struct {
+	WorktreeID string `json:"worktree_id"`
+	Name       string `json:"name"`
+	Outcome    string `json:"outcome"`
+	URL        string `json:"url,omitempty"`
+	// Why the worktree was skipped or failed
+	Reason string `json:"reason,omitempty"`
+}
+
+// BulkPullRequestReport lists the pull requests CreatePullRequestsBulk opened and what happened
+// to every selected worktree, in the order they were processed
+type BulkPullRequestReport struct {
+	Created []string                `json:"created"`
+	Results []BulkPullRequestResult `json:"results"`
+}
+
+// selectBulkPullRequestWorktrees returns the selected worktrees, oldest first
+func (s *GitService) selectBulkPullRequestWorktrees(selector BulkPullRequestSelector) ([]*models.Worktree, error) {
+	var worktrees []*models.Worktree
+	switch {
+	case selector.RepoID != "" && len(selector.WorktreeIDs) > 0:
+		return nil, fmt.Errorf("select worktrees by repository or by ID, not both")
+	case selector.RepoID != "":
+		repo, exists := s.stateManager.GetRepository(selector.RepoID)
+		if !exists {
+			return nil, fmt.Errorf("repository %s not found", selector.RepoID)
+		}
+		for _, worktree := range s.stateManager.GetAllWorktrees() {
+			if worktree.RepoID == repo.ID {
+				worktrees = append(worktrees, worktree)
+			}
+		}
+	case len(selector.WorktreeIDs) > 0:
+		seen := make(map[string]bool, len(selector.WorktreeIDs))
+		for _, id := range selector.WorktreeIDs {
+			worktree, exists := s.stateManager.GetWorktree(id)
+			if !exists {
+				return nil, fmt.Errorf("worktree %s not found", id)
+			}
+			if !seen[worktree.ID] {
+				seen[worktree.ID] = true
+				worktrees = append(worktrees, worktree)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("no worktrees selected")
+	}
+
+	sort.Slice(worktrees, func(i, j int) bool {
+		if !worktrees[i].CreatedAt.Equal(worktrees[j].CreatedAt) {
+			return worktrees[i].CreatedAt.Before(worktrees[j].CreatedAt)
+		}
+		return worktrees[i].Name < worktrees[j].Name
+	})
+	return worktrees, nil
+}
+
+// pullRequestText generates the title and body of a worktree's pull request from its commit
+// subjects, oldest first
+func pullRequestText(worktree *models.Worktree, mode string, subjects []string) (string, string) {
+	var commits []string
+	for _, subject := range subjects {
+		if subject = strings.TrimSpace(subject); subject != "" {
+			commits = append(commits, subject)
+		}
+	}
+
+	title := worktree.Name
+	if len(commits) > 0 {
+		title = commits[len(commits)-1]
+	}
+	if mode == PullRequestTextSession && worktree.SessionTitle != nil && strings.TrimSpace(worktree.SessionTitle.Title) != "" {
+		title = strings.TrimSpace(worktree.SessionTitle.Title)
+	}
+
+	var body []string
+	for _, subject := range commits {
+		if subject != title {
+			body = append(body, "- "+subject)
+		}
+	}
+	return title, strings.Join(body, "\n")
+}
+
+// CreatePullRequestsBulk opens pull requests for the selected worktrees one at a time, pausing
+// between them so GitHub doesn't flag the burst. Worktrees that already have a pull request or
+// no commits ahead of their source branch are skipped, and so are worktrees sync_first can't
+// fast-forward unless another sync strategy is asked for. Each pull request opened is also
+// reported as a pull request created event.
+func (s *GitService) CreatePullRequestsBulk(selector BulkPullRequestSelector, options BulkPullRequestOptions) (report *BulkPullRequestReport, err error) {
+	if options.TextMode == "" {
+		options.TextMode = PullRequestTextSession
+	}
+	if options.TextMode != PullRequestTextSession && options.TextMode != PullRequestTextCommits {
+		return nil, fmt.Errorf("unknown text mode %q, expected %s or %s", options.TextMode, PullRequestTextSession, PullRequestTextCommits)
+	}
+	switch options.SyncStrategy {
+	case "":
+		options.SyncStrategy = "ff-only"
+	case "ff-only", "merge", "rebase":
+	default:
+		return nil, fmt.Errorf("unknown sync strategy %q, expected ff-only, merge or rebase", options.SyncStrategy)
+	}
+	worktrees, err := s.selectBulkPullRequestWorktrees(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, len(worktrees))
+	for i, worktree := range worktrees {
+		ids[i] = worktree.ID
+	}
+	target := selector.RepoID
+	if target == "" {
+		target = fmt.Sprintf("%d worktrees", len(worktrees))
+	}
+	op := s.journal.begin(OperationBulkPullRequests, target, map[string]string{
+		"worktree_ids":  strings.Join(ids, ","),
+		"draft":         strconv.FormatBool(options.Draft),
+		"sync_first":    strconv.FormatBool(options.SyncFirst),
+		"sync_strategy": options.SyncStrategy,
+		"text_mode":     options.TextMode,
+		"actor":         options.Actor,
+	})
+	defer func() { op.finish(err) }()
+
+	report = &BulkPullRequestReport{Created: []string{}, Results: []BulkPullRequestResult{}}
+	var lastCreated time.Time
+	for i, worktree := range worktrees {
+		op.phase(worktree.Name)
+		result := s.bulkPullRequest(worktree, options, &lastCreated)
+		if result.Outcome == BulkPullRequestCreated {
+			report.Created = append(report.Created, result.URL)
+		}
+		report.Results = append(report.Results, result)
+
+		detail := result.URL
+		if result.Reason != "" {
+			detail = result.Reason
+		}
+		logger.Infof("📬 Bulk pull requests [%d/%d] %s: %s %s", i+1, len(worktrees), worktree.Name, result.Outcome, detail)
+	}
+	return report, nil
+}
+
+// bulkPullRequest opens the pull request of one worktree for CreatePullRequestsBulk, waiting
+// until bulkPullRequestDelay has passed since lastCreated
+func (s *GitService) bulkPullRequest(worktree *models.Worktree, options BulkPullRequestOptions, lastCreated *time.Time) BulkPullRequestResult {
+	result := BulkPullRequestResult{WorktreeID: worktree.ID, Name: worktree.Name}
+	fail := func(format string, args ...interface{}) BulkPullRequestResult {
+		result.Outcome = BulkPullRequestFailed
+		result.Reason = fmt.Sprintf(format, args...)
+		return result
+	}
+
+	if worktree.PullRequestURL != "" {
+		result.Outcome = BulkPullRequestSkipped
+		result.Reason = "pull request already exists: " + worktree.PullRequestURL
+		return result
+	}
+	ahead, err := s.checkHasCommitsAhead(worktree)
+	if err != nil {
+		return fail("%v", err)
+	}
+	if !ahead {
+		result.Outcome = BulkPullRequestSkipped
+		result.Reason = fmt.Sprintf("no commits ahead of %s", worktree.SourceBranch)
+		return result
+	}
+
+	if options.SyncFirst {
+		if _, err := s.SyncWorktree(worktree.ID, SyncOptions{Strategy: options.SyncStrategy, Base: SyncBaseSource}); err != nil {
+			if errors.Is(err, models.ErrNonFastForward) {
+				result.Outcome = BulkPullRequestSkipped
+				result.Reason = err.Error()
+				return result
+			}
+			return fail("sync failed: %v", err)
+		}
+	}
+
+	output, err := s.operations.ExecuteGit(worktree.Path, "log", "--reverse", "--format=%s", s.getSourceRef(worktree)+"..HEAD")
+	if err != nil {
+		return fail("failed to list commits: %v", err)
+	}
+	title, body := pullRequestText(worktree, options.TextMode, strings.Split(string(output), "\n"))
+	body = AppendActorAttribution(body, options.Actor)
+
+	if wait := bulkPullRequestDelay - time.Since(*lastCreated); !lastCreated.IsZero() && wait > 0 {
+		time.Sleep(wait)
+	}
+	pr, err := s.CreatePullRequest(worktree.ID, title, body, PullRequestOptions{Draft: options.Draft})
+	*lastCreated = time.Now()
+	if err != nil {
+		// Pull requests opened outside catnip aren't recorded on the worktree
+		if strings.HasPrefix(err.Error(), "PR_ALREADY_EXISTS") {
+			result.Outcome = BulkPullRequestSkipped
+			result.Reason = "pull request already exists on GitHub"
+			return result
+		}
+		return fail("%v", err)
+	}
+
+	result.Outcome = BulkPullRequestCreated
+	result.URL = pr.URL
+	return result
+}
@@ -0,0 +1,139 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+	"github.com/vanpelt/catnip/internal/recovery"
+)
+
+// BranchCheckoutResult is the outcome of CheckoutBranches for one branch: the worktree created
+// for it, or why none was
+type BranchCheckoutResult struct {
+	Branch   string
+	Worktree *models.Worktree
+	Err      error
+}
+
+// CheckoutBranches creates one worktree per branch of a GitHub repository, e.g. to review a stack
+// of pull requests. The bare repo is cloned once if it isn't there yet, then the worktrees are
+// created concurrently and saved to state together. A branch that fails doesn't stop the others;
+// results are in the order of branches. An error is only returned when the repository itself
+// can't be checked out.
+func (s *GitService) CheckoutBranches(org, repo string, branches []string) ([]BranchCheckoutResult, error) {
+	return s.CheckoutBranchesWithCreation(org, repo, branches, nil)
+}
+
+// CheckoutBranchesWithCreation is CheckoutBranches with the creation context shared by all the
+// new worktrees
+func (s *GitService) CheckoutBranchesWithCreation(org, repo string, branches []string, creation *models.CreationContext) ([]BranchCheckoutResult, error) {
+	if len(branches) == 0 {
+		return nil, fmt.Errorf("no branches to check out")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repoID := fmt.Sprintf("%s/%s", org, repo)
+	if s.isLocalRepo(repoID) {
+		return nil, fmt.Errorf("checking out several branches at once isn't supported for local repository %s", repoID)
+	}
+
+	repository, err := s.ensureBareRepository(repoID, org, repo)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.ensureDiskSpace(fmt.Sprintf("create %d worktrees of %s", len(branches), repoID),
+		uint64(len(branches))*worktreeEstimateBytes(repository)); err != nil {
+		return nil, err
+	}
+
+	results := make([]BranchCheckoutResult, len(branches))
+	names := make([]string, len(branches))
+	var wg sync.WaitGroup
+	for i, branch := range branches {
+		if branch == "" {
+			branch = repository.DefaultBranch
+		}
+		results[i] = BranchCheckoutResult{Branch: branch, Err: fmt.Errorf("creating a worktree for %s panicked", branch)}
+
+		wg.Add(1)
+		i, branch := i, branch
+		recovery.SafeGo("checkout-branch:"+repoID+":"+branch, func() {
+			defer wg.Done()
+			worktree, name, err := s.createBranchWorktree(repository, branch, i == 0, creation)
+			results[i].Worktree, results[i].Err = worktree, err
+			names[i] = name
+		})
+	}
+	wg.Wait()
+
+	created := make([]*models.Worktree, 0, len(results))
+	for _, result := range results {
+		if result.Err == nil {
+			created = append(created, result.Worktree)
+		}
+	}
+	if err := s.stateManager.AddWorktrees(created); err != nil {
+		logger.Warnf("⚠️ Failed to add worktrees to state: %v", err)
+	}
+	for i, result := range results {
+		if names[i] != "" {
+			s.releaseSessionName(names[i])
+		}
+		if result.Err != nil {
+			logger.Warnf("⚠️ Failed to check out %s of %s: %v", result.Branch, repoID, result.Err)
+			continue
+		}
+		s.startWorktree(result.Worktree, i == 0)
+	}
+
+	logger.Infof("✅ Created %d of %d worktrees for %s", len(created), len(branches), repoID)
+	return results, nil
+}
+
+// ensureBareRepository returns the repository with the given ID, loading its bare repo from disk
+// or cloning it with its default branch if needed (must be called with s.mu held)
+func (s *GitService) ensureBareRepository(repoID, org, repo string) (*models.Repository, error) {
+	repoURL, barePath, err := s.checkoutLocation(org, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	if existingRepo, exists := s.stateManager.GetRepository(repoID); exists {
+		return existingRepo, nil
+	}
+	if renamed := s.findRenamedRepository(repoID); renamed != nil {
+		logger.Infof("🚚 %s is the new name of %s, migrating instead of cloning", repoID, renamed.ID)
+		return s.migrateRepositoryRenameLocked(renamed.ID, repoID)
+	}
+	if _, err := os.Stat(barePath); err == nil {
+		return s.loadExistingRepository(repoID, repoURL, barePath)
+	}
+
+	if err := s.ensureDiskSpace("clone "+repoID, cloneEstimateBytes); err != nil {
+		return nil, err
+	}
+	logger.Debugf("🔄 Cloning new repository: %s", repoID)
+	repository, _, err := s.cloneBareRepository(repoID, repoURL, barePath, "", DefaultCloneOptions())
+	return repository, err
+}
+
+// createBranchWorktree fetches branch and creates a worktree from it without adding it to state.
+// The reserved session name of the worktree is returned for the caller to release.
+func (s *GitService) createBranchWorktree(repo *models.Repository, branch string, isInitial bool, creation *models.CreationContext) (*models.Worktree, string, error) {
+	logger.Infof("🔄 Fetching latest state for %s", branch)
+	source, err := s.fetchCheckoutSource(repo.Path, branch, 0)
+	if err != nil {
+		return nil, "", err
+	}
+
+	worktree, name, err := s.addRepoWorktree(repo, source, s.generateUniqueSessionName(repo.Path), isInitial, true, creation)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create worktree: %v", err)
+	}
+	return worktree, name, nil
+}
@@ -0,0 +1,126 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/vanpelt/catnip/internal/git"
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// Checkouts accept a branch, a tag (plain or as refs/tags/<name>) or a full commit hash. Tags
+// and commits are passed on as the worktree source in their canonical form, refs/tags/<name>
+// or the lower case hash, which is what the worktree records as its SourceRef.
+
+// canonicalSourceRef returns the canonical form of ref when it is unambiguously a tag or commit,
+// "" for anything that may name a branch
+func canonicalSourceRef(ref string) string {
+	if strings.HasPrefix(ref, "refs/tags/") {
+		return ref
+	}
+	if lower := strings.ToLower(ref); git.IsCommitHash(lower) {
+		return lower
+	}
+	return ""
+}
+
+// hasCommit checks if ref resolves to a commit in the repository at repoPath
+func (s *GitService) hasCommit(repoPath, ref string) bool {
+	_, err := s.runGitCommand(repoPath, "rev-parse", "--quiet", ref+"^{commit}")
+	return err == nil
+}
+
+// resolveLocalCheckoutSource resolves what to create a worktree of a local repository from:
+// branches win over tags of the same name
+func (s *GitService) resolveLocalCheckoutSource(repo *models.Repository, ref string) (string, error) {
+	if source := canonicalSourceRef(ref); source != "" {
+		if !s.hasCommit(repo.Path, source) {
+			return "", fmt.Errorf("%s does not exist in repository %s", ref, repo.ID)
+		}
+		return source, nil
+	}
+	if s.branchExists(repo.Path, ref, false) {
+		return ref, nil
+	}
+	if s.hasCommit(repo.Path, "refs/tags/"+ref) {
+		return "refs/tags/" + ref, nil
+	}
+	return "", fmt.Errorf("branch %s does not exist in repository %s", ref, repo.ID)
+}
+
+// remoteSourceRef checks before cloning whether ref is a tag or commit rather than a branch,
+// returning its canonical form or "" for branches and names the remote doesn't know
+func (s *GitService) remoteSourceRef(repoURL, ref string) string {
+	if source := canonicalSourceRef(ref); source != "" {
+		return source
+	}
+	output, err := s.runGitCommand("", "ls-remote", repoURL, "refs/heads/"+ref, "refs/tags/"+ref)
+	if err != nil {
+		return ""
+	}
+	isTag := false
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		switch fields[1] {
+		case "refs/heads/" + ref:
+			return ""
+		case "refs/tags/" + ref:
+			isTag = true
+		}
+	}
+	if isTag {
+		return "refs/tags/" + ref
+	}
+	return ""
+}
+
+// fetchSourceRef fetches a tag or commit from origin into the bare repository at repoPath,
+// shallowly when the repository is shallow. Commits already present are not fetched.
+func (s *GitService) fetchSourceRef(repoPath, source string) error {
+	strategy := git.FetchStrategy{RefSpec: source}
+	if strings.HasPrefix(source, "refs/tags/") {
+		strategy.RefSpec = fmt.Sprintf("+%s:%s", source, source)
+	} else if s.hasCommit(repoPath, source) {
+		return nil
+	}
+	if _, err := os.Stat(filepath.Join(repoPath, "shallow")); err == nil {
+		strategy.Depth = 1
+	}
+	if err := s.fetchBranch(repoPath, strategy); err != nil {
+		return fmt.Errorf("failed to fetch %s: %v", source, err)
+	}
+	return nil
+}
+
+// fetchCheckoutSource fetches what to create a worktree of a bare repository from and returns
+// it. Names are fetched as branches first, falling back to a local copy of the branch and then
+// to a tag of that name.
+func (s *GitService) fetchCheckoutSource(repoPath, ref string, depth int) (string, error) {
+	if source := canonicalSourceRef(ref); source != "" {
+		return source, s.fetchSourceRef(repoPath, source)
+	}
+
+	err := s.fetchBranch(repoPath, git.FetchStrategy{
+		Branch:         ref,
+		Depth:          depth,
+		UpdateLocalRef: true,
+	})
+	if err == nil {
+		return ref, nil
+	}
+	if s.branchExists(repoPath, ref, true) {
+		logger.Warnf("⚠️ Fetch failed but branch exists locally, proceeding with checkout")
+		return ref, nil
+	}
+	if tagErr := s.fetchSourceRef(repoPath, "refs/tags/"+ref); tagErr == nil {
+		logger.Infof("🏷️ %s is a tag, checking it out", ref)
+		return "refs/tags/" + ref, nil
+	}
+	return "", fmt.Errorf("failed to fetch branch %s: %v", ref, err)
+}
@@ -23,6 +23,11 @@ func (a *GitServiceAdapter) GitAddCommitGetHash(workDir, title string) (string,
 	return a.GitService.GitAddCommitGetHash(workDir, title)
 }
 
+// RecordCheckpointError implements git.Service interface
+func (a *GitServiceAdapter) RecordCheckpointError(workDir string, err error) {
+	a.GitService.RecordCheckpointError(workDir, err)
+}
+
 // RefreshWorktreeStatus implements git.Service interface
 func (a *GitServiceAdapter) RefreshWorktreeStatus(workDir string) error {
 	return a.GitService.RefreshWorktreeStatus(workDir)
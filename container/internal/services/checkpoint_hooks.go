@@ -0,0 +1,114 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/git"
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+	"github.com/vanpelt/catnip/internal/redact"
+)
+
+// checkpointHookOutputLimit caps the hook output kept on a worktree
+const checkpointHookOutputLimit = 4 * 1024
+
+// SetWorktreeCheckpointHooks sets whether checkpoint commits of a worktree run the repository's
+// commit hooks. Checkpoints skip them by default so a slow or failing hook never blocks one.
+func (s *GitService) SetWorktreeCheckpointHooks(worktreeID string, enabled bool) error {
+	s.mu.RLock()
+	worktree, exists := s.stateManager.GetWorktree(worktreeID)
+	s.mu.RUnlock()
+
+	if !exists {
+		return fmt.Errorf("worktree %s not found", worktreeID)
+	}
+	if worktree.RunHooksOnCheckpoint == enabled {
+		return nil
+	}
+	updates := map[string]interface{}{"run_hooks_on_checkpoint": enabled}
+	if !enabled {
+		updates["last_checkpoint_error"] = (*models.CheckpointError)(nil)
+	}
+	if err := s.stateManager.UpdateWorktree(worktreeID, updates); err != nil {
+		return err
+	}
+	logger.Infof("🪝 Commit hooks on checkpoints of %s: %v", worktree.Name, enabled)
+	return nil
+}
+
+// RecordCheckpointError stores the outcome of a checkpoint of the worktree at workDir, clearing
+// the last error when err is nil. Hook failures are recorded as committed without the hooks.
+func (s *GitService) RecordCheckpointError(workDir string, err error) {
+	worktree, exists := s.stateManager.FindWorktreeByPath(workDir)
+	if !exists || (err == nil && worktree.LastCheckpointError == nil) {
+		return
+	}
+
+	var checkpointErr *models.CheckpointError
+	if err != nil {
+		checkpointErr = &models.CheckpointError{Message: redact.Text(err.Error()), At: time.Now()}
+		var hookErr *git.CheckpointHookError
+		if errors.As(err, &hookErr) {
+			checkpointErr.HookOutput = redact.Text(hookErr.Output)
+			checkpointErr.Committed = true
+		}
+	}
+	if updateErr := s.stateManager.UpdateWorktree(worktree.ID, map[string]interface{}{
+		"last_checkpoint_error": checkpointErr,
+	}); updateErr != nil {
+		logger.Warnf("⚠️  Failed to record checkpoint error of %s: %v", worktree.Name, updateErr)
+	}
+}
+
+// commitCheckpoint commits the staged checkpoint of workspaceDir. Hooks are skipped unless the
+// worktree runs them on checkpoints; then files a hook reformats are staged and amended into the
+// checkpoint, and when the hooks fail the checkpoint is committed without them, returning a
+// git.CheckpointHookError as a warning so the work is never lost.
+func (s *GitService) commitCheckpoint(workspaceDir, message string) error {
+	worktree, exists := s.stateManager.FindWorktreeByPath(workspaceDir)
+	if !exists || !worktree.RunHooksOnCheckpoint {
+		_, err := s.runGitCommitWithGPGFallback(workspaceDir, "commit", "-m", message, "-n")
+		return err
+	}
+
+	_, err := s.runGitCommitWithGPGFallback(workspaceDir, "commit", "-m", message)
+	if err != nil && s.restageHookChanges(workspaceDir) {
+		// Hooks like lint-staged fail after fixing files, retry with the fixes staged
+		_, err = s.runGitCommitWithGPGFallback(workspaceDir, "commit", "-m", message)
+	}
+	if err != nil {
+		hookOutput := tailOutput(redact.Text(err.Error()), checkpointHookOutputLimit)
+		logger.Warnf("🪝 Commit hooks failed on checkpoint in %s, committing without them", workspaceDir)
+		if _, err := s.runGitCommitWithGPGFallback(workspaceDir, "commit", "-m", message, "-n"); err != nil {
+			return err
+		}
+		return &git.CheckpointHookError{Output: hookOutput}
+	}
+
+	if s.restageHookChanges(workspaceDir) {
+		if _, err := s.runGitCommand(workspaceDir, "commit", "--amend", "--no-edit", "-n"); err != nil {
+			logger.Warnf("⚠️  Failed to amend hook changes into checkpoint in %s: %v", workspaceDir, err)
+		}
+	}
+	return nil
+}
+
+// restageHookChanges stages files the commit hooks modified, reporting whether there were any
+func (s *GitService) restageHookChanges(workspaceDir string) bool {
+	if _, err := s.runGitCommand(workspaceDir, "diff", "--quiet"); err == nil {
+		return false
+	}
+	if _, err := s.runGitCommand(workspaceDir, "add", "-A"); err != nil {
+		logger.Warnf("⚠️  Failed to stage hook changes in %s: %v", workspaceDir, err)
+		return false
+	}
+	return true
+}
+
+// isCheckpointHookError reports whether err only warns that hooks failed on a committed checkpoint
+func isCheckpointHookError(err error) bool {
+	var hookErr *git.CheckpointHookError
+	return errors.As(err, &hookErr)
+}
@@ -0,0 +1,61 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckpointHooks(t *testing.T) {
+	s, stateManager, _, worktreePath := newRecreateTestService(t)
+	commonDir := runTestGit(t, worktreePath, "rev-parse", "--path-format=absolute", "--git-common-dir")
+	hookPath := filepath.Join(commonDir, "hooks", "pre-commit")
+	require.NoError(t, os.MkdirAll(filepath.Dir(hookPath), 0755))
+	writeHook := func(script string) {
+		require.NoError(t, os.WriteFile(hookPath, []byte("#!/bin/sh\n"+script), 0755))
+	}
+	checkpoint := func(content, title string) (string, error) {
+		require.NoError(t, os.WriteFile(filepath.Join(worktreePath, "app.txt"), []byte(content), 0644))
+		return s.GitAddCommitGetHash(worktreePath, title)
+	}
+	writeHook("echo 'hooks ran' >&2\nexit 1\n")
+
+	t.Run("SkippedByDefault", func(t *testing.T) {
+		hash, err := checkpoint("v2\n", "Skip hooks")
+		require.NoError(t, err)
+		assert.Equal(t, runTestGit(t, worktreePath, "rev-parse", "HEAD"), hash)
+	})
+
+	require.NoError(t, s.SetWorktreeCheckpointHooks("wt-felix", true))
+
+	t.Run("FailingHooksCommitWithoutThem", func(t *testing.T) {
+		hash, err := checkpoint("v3\n", "Failing hooks")
+		require.Error(t, err)
+		assert.True(t, isCheckpointHookError(err))
+		assert.Equal(t, runTestGit(t, worktreePath, "rev-parse", "HEAD"), hash)
+		assert.Equal(t, "Failing hooks", runTestGit(t, worktreePath, "log", "-1", "--format=%s"))
+
+		s.RecordCheckpointError(worktreePath, err)
+		worktree, _ := stateManager.GetWorktree("wt-felix")
+		require.NotNil(t, worktree.LastCheckpointError)
+		assert.True(t, worktree.LastCheckpointError.Committed)
+		assert.Contains(t, worktree.LastCheckpointError.HookOutput, "hooks ran")
+	})
+
+	t.Run("ReformattedFilesAreAmended", func(t *testing.T) {
+		writeHook("tr a-z A-Z < app.txt > app.tmp && mv app.tmp app.txt\n")
+		hash, err := checkpoint("v4\n", "Formatting hooks")
+		require.NoError(t, err)
+		assert.Equal(t, runTestGit(t, worktreePath, "rev-parse", "HEAD"), hash)
+		assert.Equal(t, "V4", runTestGit(t, worktreePath, "show", "HEAD:app.txt"))
+		assert.Equal(t, "Formatting hooks", runTestGit(t, worktreePath, "log", "-1", "--format=%s"))
+		assert.Empty(t, runTestGit(t, worktreePath, "status", "--porcelain"))
+
+		s.RecordCheckpointError(worktreePath, err)
+		worktree, _ := stateManager.GetWorktree("wt-felix")
+		assert.Nil(t, worktree.LastCheckpointError, "a successful checkpoint clears the error")
+	})
+}
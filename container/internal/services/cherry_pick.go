@@ -0,0 +1,182 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// commitSHAPattern matches full and abbreviated commit SHAs
+var commitSHAPattern = regexp.MustCompile(`^[0-9a-fA-F]{4,40}$`)
+
+// ResolveWorktreeCommits resolves commits, full or abbreviated SHAs as listed by the commit
+// history, against the branch of a worktree and returns their full SHAs, oldest first. Commits
+// not on the worktree's branch are refused.
+func (s *GitService) ResolveWorktreeCommits(worktreeID string, commits []string) ([]string, error) {
+	worktree, exists := s.stateManager.GetWorktree(worktreeID)
+	if !exists {
+		return nil, fmt.Errorf("worktree %s not found", worktreeID)
+	}
+	if len(commits) == 0 {
+		return nil, fmt.Errorf("no commits given")
+	}
+
+	resolved := make([]string, 0, len(commits))
+	for _, commit := range commits {
+		if !commitSHAPattern.MatchString(commit) {
+			return nil, fmt.Errorf("invalid commit SHA %q", commit)
+		}
+		hash, err := s.operations.GetCommitHash(worktree.Path, commit+"^{commit}")
+		if err != nil {
+			return nil, fmt.Errorf("commit %s not found in %s", commit, worktree.Name)
+		}
+		if _, err := s.operations.ExecuteGit(worktree.Path, "merge-base", "--is-ancestor", hash, "HEAD"); err != nil {
+			return nil, fmt.Errorf("commit %s is not on branch %s of %s", commit, worktree.Branch, worktree.Name)
+		}
+		resolved = append(resolved, hash)
+	}
+
+	// Picked in branch order, whatever order they were selected in. Commit dates are too coarse
+	// for checkpoints, so the branch is walked from the commits' common ancestor instead.
+	base, err := s.operations.ExecuteGit(worktree.Path, append([]string{"merge-base", "--octopus"}, resolved...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to order commits: %v", err)
+	}
+	output, err := s.operations.ExecuteGit(worktree.Path, "rev-list", "--topo-order", "--reverse", "HEAD", "--not", strings.TrimSpace(string(base))+"^@")
+	if err != nil {
+		return nil, fmt.Errorf("failed to order commits: %v", err)
+	}
+	selected := make(map[string]bool, len(resolved))
+	for _, hash := range resolved {
+		selected[hash] = true
+	}
+	ordered := make([]string, 0, len(resolved))
+	for _, hash := range strings.Fields(string(output)) {
+		if selected[hash] {
+			ordered = append(ordered, hash)
+			delete(selected, hash)
+		}
+	}
+	return ordered, nil
+}
+
+// CherryPickCommits applies commits to a worktree one at a time, in the given order. Commits
+// missing from the worktree are fetched from its repository first; commits the worktree already
+// has the changes of are skipped. A conflicting commit stops the cherry-pick in progress with a
+// MergeConflictError naming it and the commits applied before it, which stay committed; the
+// conflicts can be resolved and continued like a sync's, or abandoned with AbortCherryPick.
+func (s *GitService) CherryPickCommits(targetWorktreeID string, commits []string) (*models.CherryPickResult, error) {
+	s.mu.RLock()
+	worktree, exists := s.stateManager.GetWorktree(targetWorktreeID)
+	s.mu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("worktree %s not found", targetWorktreeID)
+	}
+	if len(commits) == 0 {
+		return nil, fmt.Errorf("no commits to cherry-pick")
+	}
+	if s.gitOperationInProgress(worktree.Path) {
+		return nil, fmt.Errorf("cannot cherry-pick into %s while a merge, rebase or cherry-pick is in progress, finish or abort it first", worktree.Name)
+	}
+	if s.operations.IsDirty(worktree.Path) {
+		return nil, fmt.Errorf("cannot cherry-pick into %s with uncommitted changes, commit or stash them first", worktree.Name)
+	}
+
+	// Every commit is checked before the first is applied
+	hashes := make([]string, 0, len(commits))
+	for _, commit := range commits {
+		hash, err := s.cherryPickSource(worktree, commit)
+		if err != nil {
+			return nil, err
+		}
+		hashes = append(hashes, hash)
+	}
+
+	result := &models.CherryPickResult{WorktreeID: worktree.ID, Applied: []models.CherryPickedCommit{}, Skipped: []string{}}
+	defer func() {
+		if s.worktreeCache != nil {
+			s.worktreeCache.ForceRefresh(targetWorktreeID)
+		}
+	}()
+	for _, hash := range hashes {
+		output, err := s.operations.ExecuteGit(worktree.Path, "cherry-pick", hash)
+		if err != nil {
+			// git's explanation ends up in the error, with stderr
+			output := string(output) + err.Error()
+			if strings.Contains(output, "cherry-pick is now empty") {
+				// The worktree already has these changes, nothing to commit
+				if _, err := s.operations.ExecuteGit(worktree.Path, "cherry-pick", "--skip"); err != nil {
+					return nil, fmt.Errorf("failed to skip cherry-pick of %.8s: %v", hash, err)
+				}
+				result.Skipped = append(result.Skipped, hash)
+				continue
+			}
+			if s.isMergeConflict(worktree.Path, output) {
+				conflict := s.createMergeConflictError("cherry-pick", worktree, output)
+				conflict.FailedCommit = hash
+				for _, applied := range result.Applied {
+					conflict.AppliedCommits = append(conflict.AppliedCommits, applied.Source)
+				}
+				conflict.Message = fmt.Sprintf("Cherry-pick of %.8s into worktree '%s' stopped on conflicts after applying %d of %d commits. Resolve them and continue, or abort the cherry-pick.",
+					hash, worktree.Name, len(result.Applied), len(hashes))
+				return nil, conflict
+			}
+			return nil, fmt.Errorf("failed to cherry-pick %.8s into %s: %v", hash, worktree.Name, err)
+		}
+
+		head, err := s.operations.GetCommitHash(worktree.Path, "HEAD")
+		if err != nil {
+			return nil, err
+		}
+		result.Applied = append(result.Applied, models.CherryPickedCommit{Source: hash, Commit: head})
+	}
+
+	result.Head, _ = s.operations.GetCommitHash(worktree.Path, "HEAD")
+	logger.Infof("🍒 Cherry-picked %d commits into %s (%d already applied)", len(result.Applied), worktree.Name, len(result.Skipped))
+	return result, nil
+}
+
+// cherryPickSource resolves a commit to cherry-pick into a worktree to its full SHA, fetching it
+// from the worktree's repository when the worktree doesn't have it
+func (s *GitService) cherryPickSource(worktree *models.Worktree, commit string) (string, error) {
+	if !commitSHAPattern.MatchString(commit) {
+		return "", fmt.Errorf("invalid commit SHA %q", commit)
+	}
+	if hash, err := s.operations.GetCommitHash(worktree.Path, commit+"^{commit}"); err == nil {
+		return hash, nil
+	}
+
+	repo, exists := s.stateManager.GetRepository(worktree.RepoID)
+	if !exists || len(commit) < 40 {
+		// Only full SHAs can be fetched
+		return "", fmt.Errorf("commit %s not found in %s", commit, worktree.Name)
+	}
+	if _, err := s.operations.ExecuteGit(worktree.Path, "fetch", "--no-tags", repo.Path, commit); err != nil {
+		return "", fmt.Errorf("commit %s not found in %s or its repository: %v", commit, worktree.Name, err)
+	}
+	hash, err := s.operations.GetCommitHash(worktree.Path, commit+"^{commit}")
+	if err != nil {
+		return "", fmt.Errorf("commit %s not found in %s", commit, worktree.Name)
+	}
+	return hash, nil
+}
+
+// AbortCherryPick abandons the cherry-pick CherryPickCommits left in progress in a worktree.
+// Commits applied before the conflicting one are kept.
+func (s *GitService) AbortCherryPick(worktreeID string) error {
+	s.mu.RLock()
+	worktree, exists := s.stateManager.GetWorktree(worktreeID)
+	s.mu.RUnlock()
+
+	if !exists {
+		return fmt.Errorf("worktree %s not found", worktreeID)
+	}
+	if s.syncOperationInProgress(worktree.Path) != "cherry-pick" {
+		return fmt.Errorf("no cherry-pick in progress in worktree %s", worktree.Name)
+	}
+	return s.finishSync(worktreeID, "abort")
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/git"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+func TestCherryPickCommits(t *testing.T) {
+	s, stateManager, repoPath, felixPath := newRecreateTestService(t)
+	s.conflictResolver = git.NewConflictResolver(s.operations)
+
+	tabbyPath := filepath.Join(filepath.Dir(felixPath), "tabby")
+	runTestGit(t, repoPath, "worktree", "add", "-b", "feature/tabby", tabbyPath)
+	require.NoError(t, stateManager.AddWorktree(&models.Worktree{
+		ID: "wt-tabby", RepoID: "local/repo", Name: "repo/tabby", Path: tabbyPath, Branch: "feature/tabby", SourceBranch: "main",
+	}))
+
+	commit := func(dir, file, content, subject string) string {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, file), []byte(content), 0644))
+		runTestGit(t, dir, "add", file)
+		runTestGit(t, dir, "commit", "-m", subject)
+		return runTestGit(t, dir, "rev-parse", "HEAD")
+	}
+	fix := commit(felixPath, "fix.txt", "fix\n", "Fix crash")
+	feature := commit(felixPath, "app.txt", "felix\n", "Felix feature")
+	docs := commit(felixPath, "docs.txt", "docs\n", "Document fix")
+	shared := commit(tabbyPath, "docs.txt", "docs\n", "Same docs")
+	commit(tabbyPath, "app.txt", "tabby\n", "Tabby feature")
+
+	resolved, err := s.ResolveWorktreeCommits("wt-felix", []string{docs[:8], fix})
+	require.NoError(t, err)
+	assert.Equal(t, []string{fix, docs}, resolved, "oldest first")
+	_, err = s.ResolveWorktreeCommits("wt-felix", []string{shared})
+	assert.ErrorContains(t, err, "not on branch feature/felix")
+	_, err = s.ResolveWorktreeCommits("wt-felix", []string{"--all"})
+	assert.ErrorContains(t, err, "invalid commit SHA")
+
+	result, err := s.CherryPickCommits("wt-tabby", resolved)
+	require.NoError(t, err)
+	require.Len(t, result.Applied, 1)
+	assert.Equal(t, fix, result.Applied[0].Source)
+	assert.Equal(t, []string{docs}, result.Skipped, "tabby already has the docs")
+	assert.Equal(t, runTestGit(t, tabbyPath, "rev-parse", "HEAD"), result.Head)
+	assert.Equal(t, "Fix crash", runTestGit(t, tabbyPath, "log", "-1", "--format=%s"))
+
+	t.Run("StopsOnConflicts", func(t *testing.T) {
+		head := runTestGit(t, tabbyPath, "rev-parse", "HEAD")
+		cleanup := commit(felixPath, "cleanup.txt", "tidy\n", "Tidy up")
+
+		_, err := s.CherryPickCommits("wt-tabby", []string{cleanup, feature})
+		var conflictErr *models.MergeConflictError
+		require.True(t, errors.As(err, &conflictErr), "got %v", err)
+		assert.Equal(t, "cherry-pick", conflictErr.Operation)
+		assert.Equal(t, feature, conflictErr.FailedCommit)
+		assert.Equal(t, []string{cleanup}, conflictErr.AppliedCommits)
+		assert.Equal(t, []string{"app.txt"}, conflictErr.ConflictFiles)
+
+		conflicts, err := s.GetWorktreeConflicts("wt-tabby")
+		require.NoError(t, err)
+		assert.Equal(t, "cherry-pick", conflicts.Operation)
+		_, err = s.CherryPickCommits("wt-tabby", []string{fix})
+		assert.ErrorContains(t, err, "in progress")
+
+		require.NoError(t, s.AbortCherryPick("wt-tabby"))
+		assert.Equal(t, "Tidy up", runTestGit(t, tabbyPath, "log", "-1", "--format=%s"), "applied commits are kept")
+		assert.Equal(t, head, runTestGit(t, tabbyPath, "rev-parse", "HEAD~1"))
+		assert.Equal(t, "tabby", runTestGit(t, tabbyPath, "show", "HEAD:app.txt"))
+		assert.ErrorContains(t, s.AbortCherryPick("wt-tabby"), "no cherry-pick in progress")
+	})
+
+	t.Run("RefusesUnknownCommits", func(t *testing.T) {
+		_, err := s.CherryPickCommits("wt-tabby", []string{"0123456789abcdef0123456789abcdef01234567"})
+		assert.ErrorContains(t, err, "not found")
+		_, err = s.CherryPickCommits("missing", []string{fix})
+		assert.ErrorContains(t, err, "not found")
+	})
+}
@@ -88,6 +88,8 @@ func readJSONLines(filePath string, handler func([]byte) error) error {
 	return nil
 }
 
+// WorktreePathToProjectDir returns the directory name Claude uses for a project under
+// ~/.claude/projects. The whole absolute path is encoded, whatever the workspace root is.
 func WorktreePathToProjectDir(worktreePath string) string {
 	// Claude replaces both "/" and "." with "-"
 	projectDirName := strings.ReplaceAll(worktreePath, "/", "-")
@@ -1759,6 +1761,20 @@ func (s *ClaudeService) IsActiveSession(worktreePath string, within time.Duratio
 	return time.Since(lastActivity) <= within
 }
 
+// AgeActivity moves every recorded activity and hook event back by d. After the container was
+// suspended for d, the monotonic clock behind time.Since hasn't counted the sleep, so activity
+// from before it would otherwise still look recent.
+func (s *ClaudeService) AgeActivity(d time.Duration) {
+	s.activityMutex.Lock()
+	defer s.activityMutex.Unlock()
+
+	for _, times := range []map[string]time.Time{s.lastActivity, s.lastUserPromptSubmit, s.lastPostToolUse, s.lastStopEvent, s.lastSessionStart} {
+		for path, t := range times {
+			times[path] = t.Add(-d)
+		}
+	}
+}
+
 // SetSuppressEvents sets event suppression for a worktree with a 30-second timeout (dead man switch)
 func (s *ClaudeService) SetSuppressEvents(worktreePath string, suppress bool) {
 	s.suppressEventsMutex.Lock()
@@ -1802,30 +1818,16 @@ func (s *ClaudeService) IsSuppressingEvents(worktreePath string) bool {
 // normalizeToWorktreeRoot normalizes a subdirectory path to its worktree root using path prefix matching
 func (s *ClaudeService) normalizeToWorktreeRoot(workingDir string) string {
 	// If not under workspace directory, return as-is
-	workspacePrefix := config.Runtime.WorkspaceDir + "/"
-	if !strings.HasPrefix(workingDir, workspacePrefix) {
+	rel, inWorkspace := workspaceRelativePath(workingDir)
+	if !inWorkspace {
 		return workingDir
 	}
 
 	// Extract the worktree root pattern: {workspaceDir}/{repo}/{worktree}
 	// Example: /worktrees/catnip/earl/container -> /worktrees/catnip/earl
-	parts := strings.Split(workingDir, "/")
-	workspaceDirName := filepath.Base(config.Runtime.WorkspaceDir)
-
-	// Find the workspace directory in the path parts
-	workspaceDirIndex := -1
-	for i, part := range parts {
-		if part == workspaceDirName {
-			workspaceDirIndex = i
-			break
-		}
-	}
-
-	// If we found the workspace directory and have enough parts for repo/worktree
-	if workspaceDirIndex >= 0 && len(parts) >= workspaceDirIndex+3 {
-		// Reconstruct the worktree root path: {workspaceDir}/{repo}/{worktree}
-		worktreeRoot := "/" + strings.Join(parts[1:workspaceDirIndex+3], "/")
-		return worktreeRoot
+	parts := strings.Split(rel, "/")
+	if len(parts) >= 2 {
+		return filepath.Join(getWorkspaceDir(), parts[0], parts[1])
 	}
 
 	// If pattern doesn't match expected structure, return original
@@ -19,6 +19,7 @@ import (
 	"github.com/vanpelt/catnip/internal/git"
 	"github.com/vanpelt/catnip/internal/logger"
 	"github.com/vanpelt/catnip/internal/models"
+	"github.com/vanpelt/catnip/internal/recovery"
 )
 
 // ClaudeMonitorService monitors all worktrees for Claude sessions and manages checkpoints
@@ -35,17 +36,21 @@ type ClaudeMonitorService struct {
 	lastLogPosition    int64
 	recentTitles       map[string]titleEvent // Track recent titles to avoid duplicates
 	recentTitlesMutex  sync.RWMutex
+	diffFingerprints   *diffFingerprints    // Fingerprints of uncommitted work, nil without a GitService
+	timeline           *debugTimeline       // Recent title event and checkpoint decisions per worktree
 	lastActivityTimes  map[string]time.Time // Track last activity per worktree path
 	activityMutex      sync.RWMutex
 	todoMonitors       map[string]*WorktreeTodoMonitor // Map of worktree path to todo monitor
 	todoMonitorsMutex  sync.RWMutex
+	clock              clock
 }
 
 // titleEvent represents a title change event with timestamp
 type titleEvent struct {
-	title     string
-	timestamp time.Time
-	source    string // "log" or "pty"
+	title       string
+	timestamp   time.Time
+	source      string // "log" or "pty"
+	fingerprint string // Diff fingerprint of the worktree when the event arrived
 }
 
 // WorktreeCheckpointManager manages checkpoints for a single worktree
@@ -58,9 +63,16 @@ type WorktreeCheckpointManager struct {
 	claudeService      *ClaudeService
 	stateManager       *WorktreeStateManager
 	currentTitle       string
-	checkpointTimer    *time.Timer
+	checkpointTimer    clockTimer
 	timerMutex         sync.Mutex
+	clock              clock
 	renamingInProgress bool // Track if a rename is currently in progress
+	checkpointsHeld    bool // Checkpoints are buffered while Claude is in plan mode
+	heldSince          time.Time
+	holdExpired        bool // Max hold elapsed; ignore plan mode until it is seen to end
+	diffFingerprints   *diffFingerprints
+	lastFingerprint    string // Diff fingerprint of the uncommitted work at the last title event
+	timeline           *debugTimeline
 }
 
 // WorktreeTodoMonitor monitors Todo updates for a single worktree
@@ -86,6 +98,11 @@ func NewClaudeMonitorService(gitService *GitService, sessionService *SessionServ
 		titlesLogPath = filepath.Join(config.Runtime.HomeDir, ".catnip", "title_events.log")
 	}
 
+	var fingerprints *diffFingerprints
+	if gitService != nil {
+		fingerprints = newDiffFingerprints(gitService.operations, stateManager)
+	}
+
 	return &ClaudeMonitorService{
 		gitService:         gitService,
 		sessionService:     sessionService,
@@ -95,8 +112,11 @@ func NewClaudeMonitorService(gitService *GitService, sessionService *SessionServ
 		stopCh:             make(chan struct{}),
 		titlesLogPath:      titlesLogPath,
 		recentTitles:       make(map[string]titleEvent),
+		diffFingerprints:   fingerprints,
+		timeline:           newDebugTimeline(),
 		lastActivityTimes:  make(map[string]time.Time),
 		todoMonitors:       make(map[string]*WorktreeTodoMonitor),
+		clock:              systemClock{},
 	}
 }
 
@@ -109,19 +129,32 @@ func (s *ClaudeMonitorService) Start() error {
 		logger.Warnf("⚠️  Failed to ensure titles log file exists: %v", err)
 	}
 
-	// Create file watcher for titles log
-	watcher, err := fsnotify.NewWatcher()
-	if err != nil {
+	// Worktrees with recent title events keep real file watches when they run short
+	fileWatches.setActivitySource(s.GetLastActivityTime)
+
+	// Create file watcher for titles log, the log is polled when the kernel is out of watches
+	watcher, err := fileWatches.newWatcher(WatchSubsystemClaudeMonitor, "")
+	if err != nil && !isWatchLimitError(err) {
 		return fmt.Errorf("failed to create titles watcher: %w", err)
 	}
-	s.titlesWatcher = watcher
+	if err != nil {
+		fileWatches.degrade(WatchSubsystemClaudeMonitor, "", WatchDegradedLimit, err)
+	} else {
+		s.titlesWatcher = watcher
+	}
 
-	// Start monitoring the titles log file
-	go s.monitorTitlesLog()
+	// Start monitoring the titles log file, restarted should it panic
+	recovery.SafeGoRestartable("claude-titles-monitor", s.monitorTitlesLog, time.Second)
 
 	// Start Todo monitoring for all existing worktrees
 	go s.startTodoMonitoring()
 
+	// Periodically evict state of worktrees that no longer exist
+	go s.runJanitor(claudeMonitorJanitorInterval)
+
+	// Reset timers and activity when the container resumes after the host slept
+	go s.runResumeDetector(resumeCheckInterval)
+
 	return nil
 }
 
@@ -184,8 +217,16 @@ func (s *ClaudeMonitorService) monitorTitlesLog() {
 
 	// Watch for changes to the log file
 	dir := filepath.Dir(s.titlesLogPath)
-	if err := s.titlesWatcher.Add(dir); err != nil {
+	if s.titlesWatcher == nil {
+		s.pollTitlesLog()
+		return
+	}
+	if err := fileWatches.add(WatchSubsystemClaudeMonitor, "", s.titlesWatcher, dir); err != nil {
 		logger.Warnf("⚠️  Failed to watch titles log directory: %v", err)
+		if isWatchLimitError(err) {
+			fileWatches.degrade(WatchSubsystemClaudeMonitor, "", WatchDegradedLimit, err)
+			s.pollTitlesLog()
+		}
 		return
 	}
 
@@ -209,6 +250,15 @@ func (s *ClaudeMonitorService) monitorTitlesLog() {
 	}
 }
 
+// pollTitlesLog reads new entries from the titles log whenever it changes, for when it can't be
+// watched. It returns when the service stops.
+func (s *ClaudeMonitorService) pollTitlesLog() {
+	fingerprint := func() string {
+		return pathFingerprint(filepath.Dir(s.titlesLogPath), s.titlesLogPath)
+	}
+	pollForChanges(s.stopCh, fingerprint, s.readTitlesLog, nil)
+}
+
 // readTitlesLog reads new entries from the titles log
 func (s *ClaudeMonitorService) readTitlesLog() {
 	file, err := os.Open(s.titlesLogPath)
@@ -237,8 +287,8 @@ func (s *ClaudeMonitorService) readTitlesLog() {
 			continue
 		}
 
-		// Parse log entry: timestamp|pid|cwd|title
-		parts := strings.Split(line, "|")
+		// Parse log entry: timestamp|pid|cwd|title (the title itself may contain pipes)
+		parts := strings.SplitN(line, "|", 4)
 		if len(parts) != 4 {
 			logger.Warnf("⚠️  Invalid log entry format: %s", line)
 			continue
@@ -246,7 +296,7 @@ func (s *ClaudeMonitorService) readTitlesLog() {
 
 		timestampStr := parts[0]
 		// pid := parts[1]
-		cwd := parts[2]
+		cwd := s.canonicalWorkDir(parts[2])
 		title := parts[3]
 
 		// Parse timestamp and filter out old events (only process events from last 30 seconds)
@@ -269,11 +319,11 @@ func (s *ClaudeMonitorService) readTitlesLog() {
 		isExternal := s.isExternalGitRepository(cwd)
 
 		logger.Debugf("📁 Path analysis for %s: isWorktree=%v, isExternal=%v, workspaceDir=%s",
-			cwd, isWorktree, isExternal, config.Runtime.WorkspaceDir)
+			cwd, isWorktree, isExternal, getWorkspaceDir())
 
 		if isWorktree {
 			// Clean the title before processing
-			cleanedTitle := cleanTitle(title)
+			cleanedTitle := SanitizeTitle(title)
 			if cleanedTitle != "" { // Only process if title isn't empty after cleaning
 				s.handleTitleChange(cwd, cleanedTitle, "log")
 			}
@@ -298,11 +348,21 @@ func (s *ClaudeMonitorService) readTitlesLog() {
 	}
 }
 
+// canonicalWorkDir returns the recorded path of the worktree at dir, so checkpoint managers and
+// monitors are keyed the same however the path was spelled, or dir itself for other paths
+func (s *ClaudeMonitorService) canonicalWorkDir(dir string) string {
+	if s.stateManager != nil {
+		if worktree, exists := s.stateManager.FindWorktreeByPath(dir); exists {
+			return worktree.Path
+		}
+	}
+	return dir
+}
+
 // isWorktreeDirectory checks if a directory is a git worktree
 func (s *ClaudeMonitorService) isWorktreeDirectory(dir string) bool {
 	// Check if directory is under the configured workspace directory (managed worktrees)
-	workspaceDir := config.Runtime.WorkspaceDir
-	if workspaceDir != "" && strings.HasPrefix(dir, workspaceDir+"/") {
+	if _, inWorkspace := workspaceRelativePath(dir); inWorkspace {
 		// Check if it's a git repository
 		gitDir := filepath.Join(dir, ".git")
 		if _, err := os.Stat(gitDir); err != nil {
@@ -316,8 +376,7 @@ func (s *ClaudeMonitorService) isWorktreeDirectory(dir string) bool {
 // isExternalGitRepository checks if a directory is a Git repository outside our managed workspace
 func (s *ClaudeMonitorService) isExternalGitRepository(dir string) bool {
 	// Skip if it's already under our managed workspace
-	workspaceDir := config.Runtime.WorkspaceDir
-	if workspaceDir != "" && strings.HasPrefix(dir, workspaceDir+"/") {
+	if _, inWorkspace := workspaceRelativePath(dir); inWorkspace {
 		return false
 	}
 
@@ -375,7 +434,7 @@ func (s *ClaudeMonitorService) createAutoWorkspaceForExternalRepo(repoPath strin
 			// Find worktree ID by exact path match
 			allWorktrees := s.stateManager.GetAllWorktrees()
 			for worktreeID, wt := range allWorktrees {
-				if wt.Path == repoPath {
+				if config.SamePath(wt.Path, repoPath) {
 					if err := s.stateManager.UpdateWorktree(worktreeID, map[string]interface{}{
 						"branch": currentBranch,
 					}); err != nil {
@@ -424,13 +483,8 @@ func (s *ClaudeMonitorService) createAutoWorkspaceForExternalRepo(repoPath strin
 
 // findWorktreeByExternalPath finds an existing worktree that has the exact external path
 func (s *ClaudeMonitorService) findWorktreeByExternalPath(externalPath string) *models.Worktree {
-	// Get all worktrees from the state manager
-	allWorktrees := s.stateManager.GetAllWorktrees()
-	for _, worktree := range allWorktrees {
-		// Check if the worktree path exactly matches the external path
-		if worktree.Path == externalPath {
-			return worktree
-		}
+	if worktree, exists := s.stateManager.FindWorktreeByPath(externalPath); exists {
+		return worktree
 	}
 	return nil
 }
@@ -495,6 +549,7 @@ func (s *ClaudeMonitorService) createWorkspaceReference(repo *models.Repository,
 		ClaudeActivityState:    models.ClaudeInactive,
 		Todos:                  []models.Todo{},
 	}
+	worktree.CreationContext = autoCreationContext(worktree)
 
 	// Add the workspace reference to the state manager
 	if err := s.stateManager.AddWorktree(worktree); err != nil {
@@ -514,39 +569,46 @@ func (s *ClaudeMonitorService) createWorkspaceReference(repo *models.Repository,
 
 // handleTitleChange processes a title change for a worktree with duplicate detection
 func (s *ClaudeMonitorService) handleTitleChange(workDir, newTitle, source string) {
-	// Check for recent duplicate events
+	// A human has taken the worktree over, the session's titles no longer drive anything
+	if worktree, exists := s.findHandedOffWorktree(workDir); exists {
+		logger.Infof("🤝 Ignoring title %q for %s, handed off to %s", newTitle, worktree.Name, worktree.HandedOff.By)
+		s.recordTitleEvent(workDir, newTitle, source, TimelineSuppressed, TimelineReasonHandedOff)
+		return
+	}
+
+	// Check for recent duplicate events. A repeated title only counts as a duplicate while the
+	// uncommitted work is unchanged, Claude reuses titles for distinct phases of work.
 	key := workDir + ":" + newTitle
+	fingerprint := s.diffFingerprints.get(workDir)
 	s.recentTitlesMutex.Lock()
 
-	// Clean up old events (older than 5 seconds)
-	cutoff := time.Now().Add(-5 * time.Second)
-	for k, event := range s.recentTitles {
-		if event.timestamp.Before(cutoff) {
-			delete(s.recentTitles, k)
-		}
-	}
+	s.pruneRecentTitlesLocked(time.Now())
 
 	// Check if we've seen this exact title recently
-	if recent, exists := s.recentTitles[key]; exists {
+	if recent, exists := s.recentTitles[key]; exists && recent.fingerprint == fingerprint {
 		// If log source and we already have a log entry, skip
 		// If pty source and we already have any entry from last 2 seconds, skip
 		if source == "log" && recent.source == "log" {
 			s.recentTitlesMutex.Unlock()
+			s.recordTitleEvent(workDir, newTitle, source, TimelineDuplicate, "")
 			return
 		}
 		if source == "pty" && time.Since(recent.timestamp) < 2*time.Second {
 			s.recentTitlesMutex.Unlock()
+			s.recordTitleEvent(workDir, newTitle, source, TimelineDuplicate, "")
 			return
 		}
 	}
 
 	// Record this title event
 	s.recentTitles[key] = titleEvent{
-		title:     newTitle,
-		timestamp: time.Now(),
-		source:    source,
+		title:       newTitle,
+		timestamp:   time.Now(),
+		source:      source,
+		fingerprint: fingerprint,
 	}
 	s.recentTitlesMutex.Unlock()
+	s.recordTitleEvent(workDir, newTitle, source, TimelineAccepted, "")
 
 	// Update activity time for title changes (but don't update Claude service activity
 	// as title changes are passive monitoring, not active Claude usage)
@@ -576,13 +638,9 @@ func (s *ClaudeMonitorService) handleTitleChange(workDir, newTitle, source strin
 
 	if !todoMonitorExists {
 		// Get worktree ID from GitService
-		worktrees := s.gitService.stateManager.GetAllWorktrees()
-		for worktreeID, worktree := range worktrees {
-			if worktree.Path == workDir {
-				logger.Debugf("🔍 Starting todo monitor for worktree %s after title change", workDir)
-				s.startWorktreeTodoMonitor(worktreeID, workDir)
-				break
-			}
+		if worktree, exists := s.gitService.stateManager.FindWorktreeByPath(workDir); exists {
+			logger.Debugf("🔍 Starting todo monitor for worktree %s after title change", workDir)
+			s.startWorktreeTodoMonitor(worktree.ID, workDir)
 		}
 	}
 
@@ -592,16 +650,23 @@ func (s *ClaudeMonitorService) handleTitleChange(workDir, newTitle, source strin
 	manager.HandleTitleChange(newTitle)
 }
 
+// recordTitleEvent adds a title event and what was done with it to the worktree's debug timeline
+func (s *ClaudeMonitorService) recordTitleEvent(workDir, title, source, outcome, reason string) {
+	s.timeline.record(workDir, models.DebugTimelineEntry{
+		Kind:    TimelineTitleEvent,
+		Source:  source,
+		Title:   title,
+		Outcome: outcome,
+		Reason:  reason,
+	})
+}
+
 // updateWorktreePromptAndTitleData updates the worktree state with latest session title and user prompt
 func (s *ClaudeMonitorService) updateWorktreePromptAndTitleData(workDir, latestSessionTitle string) {
 	// Find the worktree ID for this path
-	worktrees := s.gitService.stateManager.GetAllWorktrees()
 	var worktreeID string
-	for id, worktree := range worktrees {
-		if worktree.Path == workDir {
-			worktreeID = id
-			break
-		}
+	if worktree, exists := s.gitService.stateManager.FindWorktreeByPath(workDir); exists {
+		worktreeID = worktree.ID
 	}
 
 	if worktreeID == "" {
@@ -633,14 +698,19 @@ func (s *ClaudeMonitorService) updateWorktreePromptAndTitleData(workDir, latestS
 			logger.Debugf("✅ Updated worktree %s with latest session title and user prompt", worktreeID)
 		}
 	}
+
+	// The prompt at the first title event is the one the session started with
+	s.gitService.CaptureInitialPrompt(worktreeID, latestUserPrompt)
 }
 
 // NotifyTitleChange allows direct notification of title changes (fallback for when log monitoring fails)
 func (s *ClaudeMonitorService) NotifyTitleChange(workDir, newTitle string) {
+	workDir = s.canonicalWorkDir(workDir)
+
 	// Check if this is a worktree directory
 	if s.isWorktreeDirectory(workDir) {
 		// Clean the title before processing
-		cleanedTitle := cleanTitle(newTitle)
+		cleanedTitle := SanitizeTitle(newTitle)
 		if cleanedTitle != "" { // Only process if title isn't empty after cleaning
 			s.handleTitleChange(workDir, cleanedTitle, "pty")
 		}
@@ -649,11 +719,8 @@ func (s *ClaudeMonitorService) NotifyTitleChange(workDir, newTitle string) {
 
 // findWorktreeIDByPath finds the worktree ID for a given workDir path (expensive - use sparingly)
 func (s *ClaudeMonitorService) findWorktreeIDByPath(workDir string) string {
-	allWorktrees := s.stateManager.GetAllWorktrees()
-	for id, worktree := range allWorktrees {
-		if worktree.Path == workDir {
-			return id
-		}
+	if worktree, exists := s.stateManager.FindWorktreeByPath(workDir); exists {
+		return worktree.ID
 	}
 	logger.Warnf("⚠️  Failed to find worktree ID for path %s", workDir)
 	return ""
@@ -672,6 +739,9 @@ func (s *ClaudeMonitorService) createCheckpointManager(workDir string) *Worktree
 		sessionService:    s.sessionService,
 		claudeService:     s.claudeService,
 		stateManager:      s.stateManager,
+		clock:             s.clock,
+		diffFingerprints:  s.diffFingerprints,
+		timeline:          s.timeline,
 	}
 }
 
@@ -683,10 +753,21 @@ func (m *WorktreeCheckpointManager) HandleTitleChange(newTitle string) {
 	// Get the previous title from session service
 	previousTitle := m.sessionService.GetPreviousTitle(m.workDir)
 
-	// If we have a different title, commit the previous work
-	if previousTitle != "" && previousTitle != newTitle {
-		logger.Debugf("🪧 Title change detected in %s: %q -> %q", m.workDir, previousTitle, newTitle)
-		m.commitPreviousWork(previousTitle)
+	// Commit the previous work when the title event marks new work (unless plan mode is holding
+	// checkpoints or checkpoints are disabled for this worktree)
+	if previousTitle != "" {
+		switch {
+		case m.checkpointsHeld:
+			m.recordDecision(TimelineTitleCommit, previousTitle, TimelineSuppressed, TimelineReasonPlanMode, "")
+		case !m.checkpointsEnabled():
+			m.recordDecision(TimelineTitleCommit, previousTitle, TimelineSuppressed, TimelineReasonDisabled, "")
+		default:
+			if commit, reason := m.shouldCommitPreviousWork(previousTitle, newTitle); commit {
+				m.commitPreviousWork(previousTitle, reason)
+			} else {
+				m.recordDecision(TimelineTitleCommit, previousTitle, TimelineKept, reason, "")
+			}
+		}
 	}
 
 	// Update session service with the new title (no commit hash yet)
@@ -705,40 +786,197 @@ func (m *WorktreeCheckpointManager) HandleTitleChange(newTitle string) {
 
 	// Check if we need to rename the branch based on the new title
 	// Only rename if we're currently on a catnip branch and not already renaming
-	if !m.renamingInProgress && m.currentTitle != "" && m.isCurrentBranchCatnip() {
+	if !m.renamingInProgress && m.currentTitle != "" && m.isCurrentBranchCatnip() &&
+		!m.stateManager.branchRenameSettled(m.worktreeID) {
 		m.renamingInProgress = true // Set flag to prevent multiple simultaneous attempts
-		go m.checkAndRenameBranch(newTitle)
+		go m.checkAndRenameBranch(newTitle, BranchRenameTriggerTitle)
 	}
 
 	// Start checkpoint timer
 	m.startCheckpointTimer()
 }
 
-// startCheckpointTimer starts or restarts the checkpoint timer
+// startCheckpointTimer starts or restarts the checkpoint timer, unless checkpoints are disabled for
+// the worktree. The caller must hold timerMutex.
 func (m *WorktreeCheckpointManager) startCheckpointTimer() {
-	timeout := git.GetCheckpointTimeout()
+	c := m.clock
+	if c == nil {
+		c = systemClock{}
+	}
+	enabled, timeout := m.checkpointSettings()
+	if !enabled {
+		m.checkpointTimer = nil
+		return
+	}
 	// Start timer silently
-	m.checkpointTimer = time.AfterFunc(timeout, func() {
+	var timer clockTimer
+	timer = c.AfterFunc(timeout, func() {
 		m.timerMutex.Lock()
 		defer m.timerMutex.Unlock()
 
+		// A timer replaced while this callback waited for the lock must not keep a second chain going
+		if m.checkpointTimer != timer {
+			return
+		}
+
 		// Timer fired, check for changes
 		if m.currentTitle != "" {
-			// Check if there are any uncommitted changes using git operations
-			if hasChanges, err := m.gitService.operations.HasUncommittedChanges(m.workDir); err != nil {
-				logger.Warnf("⚠️  Failed to check for uncommitted changes: %v", err)
-			} else if hasChanges {
-				if err := m.checkpointManager.CreateCheckpoint(m.currentTitle); err != nil {
-					logger.Warnf("⚠️  Failed to create checkpoint: %v", err)
-				} else {
-					logger.Infof("✅ Created checkpoint for %s: %q", m.workDir, m.currentTitle)
-				}
+			if m.checkpointsHeld && time.Since(m.heldSince) >= git.GetMaxCheckpointHold() {
+				logger.Warnf("⚠️  Plan mode checkpoint hold for %s exceeded %v, resuming checkpoints", m.workDir, git.GetMaxCheckpointHold())
+				m.holdExpired = true
+				m.setCheckpointsHeld(false)
+			}
+			if m.checkpointsHeld {
+				m.recordDecision(TimelineCheckpointTimer, m.currentTitle, TimelineSuppressed, TimelineReasonPlanMode, "")
+			} else {
+				m.createCheckpointIfChanged()
 			}
-			// Skip logging when no changes - this is normal
 			// Always restart the timer as long as we have a title
 			m.startCheckpointTimer()
 		}
 	})
+	m.checkpointTimer = timer
+}
+
+// checkpointSettings returns whether the worktree takes checkpoint commits and the time between
+// checkpoints, from its CheckpointConfig or the defaults. Worktrees handed off to a human take none.
+func (m *WorktreeCheckpointManager) checkpointSettings() (bool, time.Duration) {
+	timeout := git.GetCheckpointTimeout()
+	if m.stateManager == nil || m.worktreeID == "" {
+		return true, timeout
+	}
+	worktree, exists := m.stateManager.GetWorktree(m.worktreeID)
+	if exists && worktree.HandedOff != nil {
+		return false, timeout
+	}
+	if !exists || worktree.CheckpointConfig == nil {
+		return true, timeout
+	}
+	if worktree.CheckpointConfig.TimeoutSeconds > 0 {
+		timeout = time.Duration(worktree.CheckpointConfig.TimeoutSeconds) * time.Second
+	}
+	return worktree.CheckpointConfig.Enabled, timeout
+}
+
+// checkpointsEnabled reports whether the worktree takes checkpoint and title change commits
+func (m *WorktreeCheckpointManager) checkpointsEnabled() bool {
+	enabled, _ := m.checkpointSettings()
+	return enabled
+}
+
+// ApplyCheckpointConfig restarts the checkpoint timer with the worktree's current checkpoint
+// settings, stopping it when checkpoints were disabled
+func (m *WorktreeCheckpointManager) ApplyCheckpointConfig() {
+	m.timerMutex.Lock()
+	defer m.timerMutex.Unlock()
+
+	if m.checkpointTimer != nil {
+		m.checkpointTimer.Stop()
+		m.checkpointTimer = nil
+	}
+	if m.currentTitle != "" {
+		m.startCheckpointTimer()
+	}
+}
+
+// ResetAutomation restarts checkpointing from scratch when a worktree is handed back to catnip:
+// any plan mode hold is dropped and the checkpoint timer starts over
+func (m *WorktreeCheckpointManager) ResetAutomation() {
+	m.timerMutex.Lock()
+	defer m.timerMutex.Unlock()
+
+	if m.checkpointTimer != nil {
+		m.checkpointTimer.Stop()
+		m.checkpointTimer = nil
+	}
+	m.holdExpired = false
+	if m.checkpointsHeld {
+		m.setCheckpointsHeld(false)
+	}
+	m.checkpointManager.Reset()
+	if m.currentTitle != "" {
+		m.startCheckpointTimer()
+	}
+}
+
+// createCheckpointIfChanged creates a checkpoint for the current title when the worktree has uncommitted changes
+func (m *WorktreeCheckpointManager) createCheckpointIfChanged() {
+	if !m.checkpointsEnabled() {
+		m.recordDecision(TimelineCheckpointTimer, m.currentTitle, TimelineSuppressed, TimelineReasonDisabled, "")
+		return
+	}
+	// Check if there are any uncommitted changes using git operations
+	if hasChanges, err := m.gitService.operations.HasUncommittedChanges(m.workDir); err != nil {
+		logger.Warnf("⚠️  Failed to check for uncommitted changes: %v", err)
+		m.recordDecision(TimelineCheckpointTimer, m.currentTitle, TimelineError, err.Error(), "")
+	} else if hasChanges {
+		title := SanitizeTitle(m.currentTitle)
+		if title == "" {
+			return
+		}
+		if hash, err := m.checkpointManager.CreateCheckpoint(title); err != nil {
+			logger.Warnf("⚠️  Failed to create checkpoint: %v", err)
+			m.recordDecision(TimelineCheckpointTimer, m.currentTitle, TimelineError, err.Error(), "")
+		} else if hash == "" {
+			m.recordDecision(TimelineCheckpointTimer, m.currentTitle, TimelineNoChanges, "", "")
+		} else {
+			m.lastFingerprint = ""
+			logger.Infof("✅ Created checkpoint for %s: %q", m.workDir, m.currentTitle)
+			m.recordDecision(TimelineCheckpointTimer, m.currentTitle, TimelineCommitted, "", hash)
+		}
+	} else {
+		// Skip logging when no changes - this is normal
+		m.recordDecision(TimelineCheckpointTimer, m.currentTitle, TimelineNoChanges, "", "")
+	}
+}
+
+// recordDecision adds a checkpoint or title commit decision to the worktree's debug timeline
+func (m *WorktreeCheckpointManager) recordDecision(kind, title, outcome, reason, commit string) {
+	m.timeline.record(m.workDir, models.DebugTimelineEntry{
+		Kind:    kind,
+		Title:   title,
+		Outcome: outcome,
+		Reason:  reason,
+		Commit:  commit,
+	})
+}
+
+// SetPlanMode holds checkpoints while Claude is planning and takes a single checkpoint
+// covering the post-plan changes once execution resumes
+func (m *WorktreeCheckpointManager) SetPlanMode(active bool) {
+	m.timerMutex.Lock()
+	defer m.timerMutex.Unlock()
+
+	if !active {
+		m.holdExpired = false
+		if !m.checkpointsHeld {
+			return
+		}
+		logger.Infof("▶️  Plan mode ended in %s, resuming checkpoints", m.workDir)
+		m.setCheckpointsHeld(false)
+		if m.currentTitle != "" {
+			m.createCheckpointIfChanged()
+		}
+		return
+	}
+
+	if m.checkpointsHeld || m.holdExpired {
+		return
+	}
+	logger.Infof("⏸️  Plan mode detected in %s, holding checkpoints", m.workDir)
+	m.heldSince = time.Now()
+	m.setCheckpointsHeld(true)
+}
+
+// setCheckpointsHeld records the hold state on the worktree so the UI can explain missing checkpoints
+func (m *WorktreeCheckpointManager) setCheckpointsHeld(held bool) {
+	m.checkpointsHeld = held
+	if m.stateManager == nil || m.worktreeID == "" {
+		return
+	}
+	if err := m.stateManager.UpdateWorktree(m.worktreeID, map[string]interface{}{"checkpoints_held": held}); err != nil {
+		logger.Warnf("⚠️  Failed to update checkpoint hold state for %s: %v", m.worktreeID, err)
+	}
 }
 
 // Stop stops the checkpoint manager and cancels any pending timers
@@ -751,26 +989,33 @@ func (m *WorktreeCheckpointManager) Stop() {
 	}
 
 	// Commit any pending work
-	if m.currentTitle != "" {
-		m.commitPreviousWork(m.currentTitle)
+	if m.currentTitle != "" && m.checkpointsEnabled() {
+		m.commitPreviousWork(m.currentTitle, "stopped")
 	}
 }
 
-// commitPreviousWork commits the previous work with the given title
-func (m *WorktreeCheckpointManager) commitPreviousWork(title string) {
-	if m.gitService == nil {
+// commitPreviousWork commits the previous work with the given title, reason being why it was
+// decided to commit
+func (m *WorktreeCheckpointManager) commitPreviousWork(title, reason string) {
+	title = SanitizeTitle(title)
+	if m.gitService == nil || title == "" {
 		return
 	}
 
 	commitHash, err := m.gitService.GitAddCommitGetHash(m.workDir, title)
-	if err != nil {
+	m.gitService.RecordCheckpointError(m.workDir, err)
+	if err != nil && !isCheckpointHookError(err) {
 		logger.Warnf("⚠️  Failed to commit previous work: %v", err)
+		m.recordDecision(TimelineTitleCommit, title, TimelineError, err.Error(), "")
 		return
 	}
-
-	if commitHash != "" {
+	if commitHash == "" {
+		m.recordDecision(TimelineTitleCommit, title, TimelineNoChanges, reason, "")
+	} else {
+		m.recordDecision(TimelineTitleCommit, title, TimelineCommitted, reason, commitHash)
 		logger.Infof("✅ Committed previous work in %s: %q (hash: %s)", m.workDir, title, commitHash)
 		m.checkpointManager.UpdateLastCommitTime()
+		m.lastFingerprint = ""
 
 		// Update the previous title's commit hash
 		if err := m.sessionService.UpdatePreviousTitleCommitHash(m.workDir, commitHash); err != nil {
@@ -784,14 +1029,78 @@ func (m *WorktreeCheckpointManager) commitPreviousWork(title string) {
 	}
 }
 
-// checkAndRenameBranch checks if we need to graduate a catnip branch to a semantic name based on the title
-func (m *WorktreeCheckpointManager) checkAndRenameBranch(title string) {
-	// Clean the title before processing
-	cleanedTitle := cleanTitle(title)
-	if cleanedTitle == "" {
-		return // Skip if title becomes empty after cleaning
+// shouldCommitPreviousWork decides whether a title event commits the work done under the previous
+// title. The uncommitted work having the same diff fingerprint as at the last title event means
+// nothing new was done, so nothing is committed whatever the titles; a changed fingerprint commits
+// even when Claude reuses the title. Without a fingerprint only title changes commit, subject to
+// the repo's commit triggers. The reason for the decision is returned for the debug timeline. The
+// caller must hold timerMutex.
+func (m *WorktreeCheckpointManager) shouldCommitPreviousWork(previousTitle, newTitle string) (bool, string) {
+	fingerprint := m.diffFingerprints.get(m.workDir)
+	last := m.lastFingerprint
+	m.lastFingerprint = fingerprint
+
+	switch {
+	case fingerprint == "":
+		if previousTitle == newTitle {
+			return false, "same_title"
+		}
+	case fingerprint == last:
+		logger.Debugf("🪧 Keeping work under %q in %s: no changes since the last title event", newTitle, m.workDir)
+		return false, "unchanged_work"
+	case previousTitle == newTitle:
+		logger.Debugf("🪧 Committing repeated title %q in %s: the work changed", newTitle, m.workDir)
+		return true, "changed_work"
+	}
+	logger.Debugf("🪧 Title change detected in %s: %q -> %q", m.workDir, previousTitle, newTitle)
+	if m.shouldCommitOnTitleChange(previousTitle, newTitle) {
+		return true, "title_changed"
+	}
+	return false, "commit_triggers"
+}
+
+// shouldCommitOnTitleChange applies the repo's commit triggers to a title change. When it
+// returns false the uncommitted work simply carries over to the new title.
+func (m *WorktreeCheckpointManager) shouldCommitOnTitleChange(previousTitle, newTitle string) bool {
+	if m.gitService == nil {
+		return true
+	}
+
+	cfg := loadCommitTriggerConfig(func(key string) (string, error) {
+		return m.gitService.operations.GetConfig(m.workDir, key)
+	})
+	if !cfg.enabled() {
+		return true
 	}
+	similarity := titleSimilarity(previousTitle, newTitle)
 
+	if cfg.similarityEnabled() && similarity < cfg.MaxTitleSimilarity {
+		logger.Debugf("🪧 Committing on title change in %s: similarity %.2f below cutoff %.2f", m.workDir, similarity, cfg.MaxTitleSimilarity)
+		return true
+	}
+
+	diffLines := -1
+	if cfg.diffThresholdEnabled() {
+		lines, err := uncommittedDiffLines(m.gitService.operations, m.workDir)
+		if err != nil {
+			logger.Debugf("🪧 Committing on title change in %s: similarity %.2f, diff size unknown: %v", m.workDir, similarity, err)
+			return true
+		}
+		if lines >= cfg.MinDiffLines {
+			logger.Debugf("🪧 Committing on title change in %s: similarity %.2f, diff %d lines >= %d", m.workDir, similarity, lines, cfg.MinDiffLines)
+			return true
+		}
+		diffLines = lines
+	}
+
+	logger.Debugf("🪧 Keeping work under new title in %s: similarity %.2f (cutoff %.2f), diff %d lines (threshold %d)", m.workDir, similarity, cfg.MaxTitleSimilarity, diffLines, cfg.MinDiffLines)
+	return false
+}
+
+// checkAndRenameBranch checks if we need to graduate a catnip branch to a semantic name based on
+// the title. The caller sets renamingInProgress, which is cleared when done. Attempts that don't
+// rename the branch are recorded on the worktree with the reason, and the outcome is returned.
+func (m *WorktreeCheckpointManager) checkAndRenameBranch(title, trigger string) *models.BranchRenameOutcome {
 	// Ensure we clear the renamingInProgress flag when done
 	defer func() {
 		m.timerMutex.Lock()
@@ -799,11 +1108,46 @@ func (m *WorktreeCheckpointManager) checkAndRenameBranch(title string) {
 		m.timerMutex.Unlock()
 	}()
 
+	outcome := m.graduateBranch(title, trigger)
+	m.timeline.record(m.workDir, models.DebugTimelineEntry{
+		Kind:    TimelineGraduation,
+		Source:  trigger,
+		Title:   title,
+		Outcome: outcome.Outcome,
+		Reason:  outcome.Reason,
+	})
+	if outcome.Outcome == BranchRenameSkipped || outcome.Outcome == BranchRenameFailed {
+		logger.Infof("⏭️  Branch rename for %s %s (%s): %s", m.workDir, outcome.Outcome, outcome.Reason, outcome.Message)
+		if worktreeID := m.findWorktreeIDByPath(); worktreeID != "" {
+			if err := m.stateManager.RecordBranchRename(worktreeID, outcome); err != nil {
+				logger.Warnf("⚠️  Failed to record branch rename outcome: %v", err)
+			}
+		}
+	}
+	return outcome
+}
+
+// graduateBranch asks Claude for a semantic branch name for the title and renames the current
+// catnip branch to it, returning what happened
+func (m *WorktreeCheckpointManager) graduateBranch(title, trigger string) *models.BranchRenameOutcome {
+	if trigger != BranchRenameTriggerManual && m.stateManager != nil {
+		if worktree, exists := m.stateManager.FindWorktreeByPath(m.workDir); exists && worktree.HandedOff != nil {
+			return newBranchRenameOutcome(trigger, BranchRenameSkipped, RenameReasonHandedOff, "",
+				fmt.Sprintf("The worktree was handed off to %s, branches are only renamed on request", worktree.HandedOff.By))
+		}
+	}
+
+	// Clean the title before processing
+	cleanedTitle := SanitizeTitle(title)
+	if cleanedTitle == "" {
+		return newBranchRenameOutcome(trigger, BranchRenameSkipped, RenameReasonEmptyTitle, "", "The session title is empty once cleaned up")
+	}
+
 	// Get current branch name (full ref) - handle detached HEAD state
 	output, err := m.gitService.operations.ExecuteGit(m.workDir, "rev-parse", "--symbolic-full-name", "HEAD")
 	if err != nil {
-		logger.Warnf("⚠️  Failed to get current branch name: %v", err)
-		return
+		return newBranchRenameOutcome(trigger, BranchRenameFailed, RenameReasonBranchLookup, "",
+			fmt.Sprintf("Failed to get current branch name: %v", err))
 	}
 	currentBranch := strings.TrimSpace(string(output))
 
@@ -829,7 +1173,8 @@ func (m *WorktreeCheckpointManager) checkAndRenameBranch(title string) {
 
 	// Check if we're on a catnip branch that should be graduated
 	if !git.IsCatnipBranch(currentBranch) {
-		return
+		return newBranchRenameOutcome(trigger, BranchRenameSkipped, RenameReasonNotCatnipBranch, "",
+			fmt.Sprintf("%s is not a catnip branch, so it is never renamed automatically", currentBranch))
 	}
 
 	// Call Claude to generate a nice branch name
@@ -842,7 +1187,7 @@ func (m *WorktreeCheckpointManager) checkAndRenameBranch(title string) {
 Generate a git branch name that:
 1. Follows conventional patterns like: feature/add-auth, chore/update-deps, refactor/cleanup-api, bug/fix-login, docs/update-readme
 2. Uses only lowercase letters, numbers, hyphens, and forward slashes
-3. Is concise but descriptive (max 60 characters)
+3. Is short: at most 40 characters, ideally a prefix and 2-4 words
 4. Common prefixes: feature, chore, refactor, bug, docs, test, style, perf, fix
 
 Respond with ONLY the branch name, nothing else.`, cleanedTitle),
@@ -857,69 +1202,88 @@ Respond with ONLY the branch name, nothing else.`, cleanedTitle),
 	if err != nil {
 		if ctx.Err() == context.DeadlineExceeded {
 			logger.Warnf("⏰ Claude request timed out after 60 seconds for title: %q", title)
-		} else {
-			logger.Warnf("⚠️  Failed to get branch name suggestion from Claude: %v", err)
+			return newBranchRenameOutcome(trigger, BranchRenameFailed, RenameReasonClaudeTimeout, "",
+				"Claude didn't suggest a branch name within 60 seconds")
 		}
-		return
+		logger.Warnf("⚠️  Failed to get branch name suggestion from Claude: %v", err)
+		return newBranchRenameOutcome(trigger, BranchRenameFailed, RenameReasonClaudeError, "",
+			fmt.Sprintf("Failed to get a branch name suggestion from Claude: %v", err))
 	}
 
-	if response == nil || response.Response == "" {
-		logger.Warnf("⚠️  Claude returned empty response for branch name")
-		return
+	if response == nil || strings.TrimSpace(response.Response) == "" {
+		return newBranchRenameOutcome(trigger, BranchRenameFailed, RenameReasonEmptySuggestion, "",
+			"Claude returned an empty branch name")
 	}
 
-	newBranch := strings.TrimSpace(response.Response)
+	newBranch := m.gitService.CapBranchName(m.workDir, strings.TrimSpace(response.Response))
 
 	// Basic validation - just check for valid git branch name
 	if !m.isValidGitBranchName(newBranch) {
-		logger.Warnf("⚠️  Claude suggested invalid branch name: %q", newBranch)
-		return
+		return newBranchRenameOutcome(trigger, BranchRenameSkipped, RenameReasonInvalidSuggestion, newBranch,
+			fmt.Sprintf("Claude suggested %q, which is not a valid branch name", newBranch))
+	}
+
+	// Use cached worktree ID to avoid expensive lookup
+	worktreeID := m.findWorktreeIDByPath()
+	if worktreeID == "" {
+		return newBranchRenameOutcome(trigger, BranchRenameFailed, RenameReasonWorktreeNotFound, newBranch,
+			fmt.Sprintf("No worktree found for %s", m.workDir))
 	}
 
-	// Check if the new branch name already exists and append numbers if needed
+	// Resolve collisions with existing branches according to the repo's policy
 	logger.Debugf("🔍 Checking if branch %q exists in %s", newBranch, m.workDir)
-	finalBranch := newBranch
-	counter := 1
-	for m.gitService.branchExists(m.workDir, finalBranch, false) ||
-		m.gitService.branchExists(m.workDir, "refs/heads/"+finalBranch, false) {
-		logger.Debugf("🔍 Branch %q exists, trying next...", finalBranch)
-		finalBranch = fmt.Sprintf("%s-%d", newBranch, counter)
-		counter++
-		if counter > 100 { // Safety limit to prevent infinite loops
-			logger.Warnf("⚠️  Too many similar branches exist for %q, skipping graduation", newBranch)
-			return
-		}
+	branchExists := func(branch string) bool {
+		return m.gitService.branchExists(m.workDir, branch, false) ||
+			m.gitService.branchExists(m.workDir, "refs/heads/"+branch, false)
+	}
+	policy := loadBranchCollisionPolicy(func(key string) (string, error) {
+		return m.gitService.operations.GetConfig(m.workDir, key)
+	})
+	var owner *models.Worktree
+	if worktree, exists := m.stateManager.GetWorktree(worktreeID); exists {
+		owner = findBranchOwner(m.stateManager.GetAllWorktrees(), worktree.RepoID, newBranch, worktreeID)
+	}
+	outcome, err := resolveBranchCollision(policy, newBranch, currentBranch, branchExists, owner)
+	if err != nil {
+		return newBranchRenameOutcome(trigger, BranchRenameFailed, RenameReasonNoFreeName, newBranch, err.Error())
+	}
+	outcome.Trigger = trigger
+
+	if outcome.Outcome == BranchRenameSkipped {
+		outcome.Reason = RenameReasonBranchExists
+		return outcome
 	}
 
-	if finalBranch != newBranch {
-		logger.Debugf("📝 Branch %q already exists, using %q instead", newBranch, finalBranch)
+	if outcome.Branch != newBranch {
+		logger.Debugf("📝 Branch %q already exists, using %q instead", newBranch, outcome.Branch)
 	}
-	newBranch = finalBranch
+	newBranch = outcome.Branch
 
 	// Double-check that the final branch name doesn't exist
-	if m.gitService.branchExists(m.workDir, newBranch, false) ||
-		m.gitService.branchExists(m.workDir, "refs/heads/"+newBranch, false) {
+	if branchExists(newBranch) {
 		logger.Errorf("❌ ERROR: Branch %q still exists after collision detection!", newBranch)
-		return
+		return newBranchRenameOutcome(trigger, BranchRenameFailed, RenameReasonBranchExists, outcome.RequestedBranch,
+			fmt.Sprintf("%s appeared while the rename was in progress", newBranch))
 	}
 
 	// Rename the branch to the new name using centralized state management
 	logger.Debugf("🎓 Renaming branch %q to %q", currentBranch, newBranch)
 
-	// Use cached worktree ID to avoid expensive lookup
-	worktreeID := m.findWorktreeIDByPath()
-	if worktreeID == "" {
-		logger.Warnf("⚠️  Failed to find worktree ID for path %s", m.workDir)
-		return
+	logger.Debugf("🔄 performBranchRename: calling RenameWorktreeBranch for %s -> %s", worktreeID, newBranch)
+	if err := m.stateManager.RenameWorktreeBranch(worktreeID, newBranch, m.gitService.operations, outcome); err != nil {
+		return newBranchRenameOutcome(trigger, BranchRenameFailed, RenameReasonRenameFailed, outcome.RequestedBranch,
+			fmt.Sprintf("Failed to rename branch: %v", err))
 	}
 
-	logger.Debugf("🔄 performBranchRename: calling RenameWorktreeBranch for %s -> %s", worktreeID, newBranch)
-	if err := m.stateManager.RenameWorktreeBranch(worktreeID, newBranch, m.gitService.operations); err != nil {
-		logger.Warnf("⚠️  Failed to rename branch: %v", err)
-		return
+	if outcome.Outcome == BranchRenameAdopted {
+		note := fmt.Sprintf("Related work: both worktrees targeted branch %s", outcome.RequestedBranch)
+		if err := m.stateManager.LinkRelatedWorktrees(worktreeID, outcome.CollidingWorktreeID, note); err != nil {
+			logger.Warnf("⚠️  Failed to link related worktrees: %v", err)
+		}
 	}
 
 	logger.Infof("✅ Successfully renamed to branch %q", newBranch)
+	return outcome
 }
 
 // findWorktreeIDByPath returns the cached worktree ID for this checkpoint manager
@@ -932,8 +1296,8 @@ func (m *WorktreeCheckpointManager) findWorktreeIDByPath() string {
 
 // isValidGitBranchName validates basic git branch name rules
 func (m *WorktreeCheckpointManager) isValidGitBranchName(branchName string) bool {
-	// Check length (reasonable limits)
-	if len(branchName) == 0 || len(branchName) > 100 {
+	// Names are capped before validation (see CapBranchName)
+	if len(branchName) == 0 {
 		return false
 	}
 
@@ -1020,88 +1384,109 @@ func (m *WorktreeCheckpointManager) isCurrentBranchCatnip() bool {
 	return git.IsCatnipBranch(currentBranch)
 }
 
-// cleanTitle removes unwanted characters and symbols from titles
-func cleanTitle(title string) string {
-	// Remove the ✳ emoji symbol and any leading/trailing whitespace
-	cleaned := strings.TrimSpace(strings.ReplaceAll(title, "✳", ""))
-	// Remove any other common prefix symbols that might appear
-	cleaned = strings.TrimSpace(strings.TrimPrefix(cleaned, "*"))
-	return cleaned
-}
-
-// TriggerBranchRename manually triggers branch renaming for a worktree
-func (s *ClaudeMonitorService) TriggerBranchRename(workDir string, customBranchName string) error {
+// TriggerBranchRename manually renames the branch of a worktree to customBranchName, or to a name
+// suggested by Claude for the current session title, and returns the outcome. Errors are
+// returned for requests that can't be attempted; attempts that don't rename the branch return an
+// outcome saying why, which is also recorded on the worktree.
+func (s *ClaudeMonitorService) TriggerBranchRename(workDir string, customBranchName string) (*models.BranchRenameOutcome, error) {
+	workDir = s.canonicalWorkDir(workDir)
 	s.managersMutex.RLock()
 	manager, exists := s.checkpointManagers[workDir]
 	s.managersMutex.RUnlock()
 
 	if !exists {
-		return fmt.Errorf("no checkpoint manager found for worktree: %s", workDir)
+		return nil, fmt.Errorf("no checkpoint manager found for worktree: %s", workDir)
 	}
 
 	// Get current branch name (full ref)
 	output, err := s.gitService.operations.ExecuteGit(workDir, "rev-parse", "--symbolic-full-name", "HEAD")
 	if err != nil {
-		return fmt.Errorf("failed to get current branch name: %v", err)
+		return nil, fmt.Errorf("failed to get current branch name: %v", err)
 	}
 	currentBranch := strings.TrimSpace(string(output))
 
 	// Allow renaming any branch (not just catnip branches)
 	// This enables users to rename branches multiple times if needed
 
-	// If custom branch name is provided, validate it
+	// If custom branch name is provided, cap and validate it
 	if customBranchName != "" {
+		customBranchName = s.gitService.CapBranchName(workDir, customBranchName)
 		if !manager.isValidGitBranchName(customBranchName) {
-			return fmt.Errorf("invalid branch name: %q", customBranchName)
+			return nil, fmt.Errorf("invalid branch name: %q", customBranchName)
 		}
 
 		// Check if the branch already exists and append numbers if needed
-		finalBranch := customBranchName
-		counter := 1
-		for s.gitService.branchExists(workDir, finalBranch, false) ||
-			s.gitService.branchExists(workDir, "refs/heads/"+finalBranch, false) {
-			finalBranch = fmt.Sprintf("%s-%d", customBranchName, counter)
-			counter++
-			if counter > 100 { // Safety limit
-				return fmt.Errorf("too many similar branches exist for %q", customBranchName)
-			}
+		finalBranch, err := nextFreeBranchName(customBranchName, func(branch string) bool {
+			return s.gitService.branchExists(workDir, branch, false) ||
+				s.gitService.branchExists(workDir, "refs/heads/"+branch, false)
+		})
+		if err != nil {
+			return nil, err
 		}
 
 		if finalBranch != customBranchName {
 			logger.Debugf("📝 Branch %q already exists, using %q instead", customBranchName, finalBranch)
 		}
-		customBranchName = finalBranch
-
-		// Rename directly to the custom name using centralized state management
-		logger.Debugf("🎓 Renaming branch %q to custom name %q", currentBranch, customBranchName)
 
 		// Use cached worktree ID to avoid expensive lookup
 		worktreeID := manager.findWorktreeIDByPath()
 		if worktreeID == "" {
-			return fmt.Errorf("failed to find worktree ID for path %s", workDir)
+			return nil, fmt.Errorf("failed to find worktree ID for path %s", workDir)
 		}
 
-		logger.Debugf("🔄 TriggerBranchRename: calling RenameWorktreeBranch for %s -> %s", worktreeID, customBranchName)
-		if err := s.stateManager.RenameWorktreeBranch(worktreeID, customBranchName, s.gitService.operations); err != nil {
-			return fmt.Errorf("failed to rename branch: %v", err)
+		outcome := &models.BranchRenameOutcome{
+			Trigger:         BranchRenameTriggerManual,
+			Outcome:         BranchRenameRenamed,
+			RequestedBranch: customBranchName,
+			Branch:          finalBranch,
+			At:              time.Now(),
+		}
+		if finalBranch != customBranchName {
+			outcome.Outcome = BranchRenameSuffixed
+			outcome.Message = fmt.Sprintf("%s already exists; renamed to %s", customBranchName, finalBranch)
 		}
 
-		logger.Infof("✅ Successfully renamed to custom branch %q", customBranchName)
-		return nil
+		// Rename directly to the custom name using centralized state management
+		logger.Debugf("🎓 Renaming branch %q to custom name %q", currentBranch, finalBranch)
+		logger.Debugf("🔄 TriggerBranchRename: calling RenameWorktreeBranch for %s -> %s", worktreeID, finalBranch)
+		if err := s.stateManager.RenameWorktreeBranch(worktreeID, finalBranch, s.gitService.operations, outcome); err != nil {
+			outcome = newBranchRenameOutcome(BranchRenameTriggerManual, BranchRenameFailed, RenameReasonRenameFailed, customBranchName,
+				fmt.Sprintf("Failed to rename branch: %v", err))
+			if recordErr := s.stateManager.RecordBranchRename(worktreeID, outcome); recordErr != nil {
+				logger.Warnf("⚠️  Failed to record branch rename outcome: %v", recordErr)
+			}
+			return outcome, nil
+		}
+
+		logger.Infof("✅ Successfully renamed to custom branch %q", finalBranch)
+		return outcome, nil
 	}
 
 	// For automatic naming, we need a title
 	manager.timerMutex.Lock()
 	currentTitle := manager.currentTitle
+	alreadyRenaming := manager.renamingInProgress
+	if currentTitle != "" && !alreadyRenaming {
+		manager.renamingInProgress = true
+	}
 	manager.timerMutex.Unlock()
 
 	if currentTitle == "" {
-		return fmt.Errorf("no title available for Claude-based naming. Please specify a custom branch name or use Claude to set a title first")
+		return nil, fmt.Errorf("no title available for Claude-based naming. Please specify a custom branch name or use Claude to set a title first")
 	}
 
-	// Trigger the automatic branch rename
-	go manager.checkAndRenameBranch(currentTitle)
-	return nil
+	if alreadyRenaming {
+		outcome := newBranchRenameOutcome(BranchRenameTriggerManual, BranchRenameSkipped, RenameReasonInProgress, "",
+			"A branch rename is already in progress for this worktree")
+		if worktreeID := manager.findWorktreeIDByPath(); worktreeID != "" {
+			if err := s.stateManager.RecordBranchRename(worktreeID, outcome); err != nil {
+				logger.Warnf("⚠️  Failed to record branch rename outcome: %v", err)
+			}
+		}
+		return outcome, nil
+	}
+
+	return manager.checkAndRenameBranch(currentTitle, BranchRenameTriggerManual), nil
 }
 
 // startTodoMonitoring starts monitoring todos for all existing worktrees
@@ -1207,6 +1592,11 @@ func (m *WorktreeTodoMonitor) checkForTodoUpdates(worktreeID string) {
 		return // No changes
 	}
 
+	// Hold or resume checkpoints based on the session's permission mode
+	if active, found := m.readPlanModeFromEnd(latestFile); found {
+		m.updatePlanMode(active)
+	}
+
 	// Read todos from the end of the file
 	todos, err := m.readTodosFromEnd(latestFile)
 	if err != nil {
@@ -1383,6 +1773,91 @@ func (m *WorktreeTodoMonitor) readTodosFromEnd(filePath string) ([]models.Todo,
 	return todos, nil
 }
 
+// readPlanModeFromEnd reports whether the most recent mode signal in the session's tail
+// indicates plan mode. found is false when the tail has no signal either way.
+func (m *WorktreeTodoMonitor) readPlanModeFromEnd(filePath string) (active bool, found bool) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return false, false
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return false, false
+	}
+
+	const chunkSize = 64 * 1024
+	offset := stat.Size() - chunkSize
+	if offset < 0 {
+		offset = 0
+	}
+	chunk := make([]byte, stat.Size()-offset)
+	if _, err := file.ReadAt(chunk, offset); err != nil {
+		return false, false
+	}
+
+	return detectPlanMode(m.extractCompleteLines(chunk, offset == 0))
+}
+
+// detectPlanMode walks session lines newest first. An ExitPlanMode tool call ends plan mode;
+// otherwise the latest user message's permissionMode decides.
+func detectPlanMode(lines [][]byte) (active bool, found bool) {
+	for i := len(lines) - 1; i >= 0; i-- {
+		var message models.ClaudeSessionMessage
+		if err := json.Unmarshal(lines[i], &message); err != nil || message.IsSidechain {
+			continue
+		}
+
+		if message.Type == "assistant" && message.Message != nil && hasToolUse(message.Message, "ExitPlanMode") {
+			return false, true
+		}
+		if message.Type == "user" && message.PermissionMode != "" {
+			return message.PermissionMode == "plan", true
+		}
+	}
+	return false, false
+}
+
+// hasToolUse reports whether a Claude message contains a tool_use block for the named tool
+func hasToolUse(messageData map[string]interface{}, toolName string) bool {
+	contentArray, ok := messageData["content"].([]interface{})
+	if !ok {
+		return false
+	}
+	for _, contentItem := range contentArray {
+		if contentMap, ok := contentItem.(map[string]interface{}); ok {
+			if contentMap["type"] == "tool_use" && contentMap["name"] == toolName {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// updatePlanMode forwards the detected plan mode state to the worktree's checkpoint manager
+func (m *WorktreeTodoMonitor) updatePlanMode(active bool) {
+	claudeMonitor := m.getClaudeMonitorService()
+	if claudeMonitor == nil {
+		return
+	}
+
+	claudeMonitor.managersMutex.Lock()
+	manager, exists := claudeMonitor.checkpointManagers[m.workDir]
+	if !exists {
+		if !active {
+			claudeMonitor.managersMutex.Unlock()
+			return // Nothing is held
+		}
+		manager = claudeMonitor.createCheckpointManager(m.workDir)
+		claudeMonitor.checkpointManagers[m.workDir] = manager
+		logger.Debugf("📝 Created checkpoint manager for plan mode tracking: %s", m.workDir)
+	}
+	claudeMonitor.managersMutex.Unlock()
+
+	manager.SetPlanMode(active)
+}
+
 // extractCompleteLines extracts complete JSON lines from a chunk
 func (m *WorktreeTodoMonitor) extractCompleteLines(chunk []byte, isStart bool) [][]byte {
 	var lines [][]byte
@@ -1516,6 +1991,10 @@ func (m *WorktreeTodoMonitor) checkTodoBasedBranchRenaming(todos []models.Todo)
 			logger.Debugf("🔍 Nice branch %q already exists for %s, skipping todo-based renaming", strings.TrimSpace(existingNiceBranch), currentBranch)
 			return
 		}
+		if manager.stateManager.branchRenameSettled(manager.worktreeID) {
+			logger.Debugf("🔍 Branch %s was kept by the skip collision policy, skipping todo-based renaming", currentBranch)
+			return
+		}
 
 		// Only trigger if not already renaming
 		manager.timerMutex.Lock()
@@ -1528,7 +2007,7 @@ func (m *WorktreeTodoMonitor) checkTodoBasedBranchRenaming(todos []models.Todo)
 
 		if !alreadyRenaming {
 			// Trigger branch renaming in a goroutine
-			go manager.checkAndRenameBranch(todos[0].Content)
+			go manager.checkAndRenameBranch(todos[0].Content, BranchRenameTriggerTodo)
 		}
 	}
 }
@@ -1591,12 +2070,91 @@ func (s *ClaudeMonitorService) GetTodos(worktreePath string) ([]models.Todo, err
 	return s.claudeService.GetLatestTodos(worktreePath)
 }
 
+// AnnotateCommitSessions sets the session title of each commit of the worktree at worktreePath
+// to the title that was active when the commit was created, from its session's title history
+func (s *ClaudeMonitorService) AnnotateCommitSessions(worktreePath string, commits []models.WorktreeCommit) {
+	if s.sessionService == nil {
+		return
+	}
+	session, exists := s.sessionService.GetActiveSession(worktreePath)
+	if !exists {
+		return
+	}
+	for i := range commits {
+		commits[i].SessionTitle = sessionTitleAt(session.TitleHistory, commits[i].Hash, commits[i].Timestamp)
+	}
+}
+
+// sessionTitleAt returns the title of a chronological title history a commit was made under: the
+// title recorded with the commit's hash, otherwise the last one set before the commit was
+// authored. Checkpoint entries count as the title they checkpointed.
+func sessionTitleAt(history []models.TitleEntry, hash string, authoredAt time.Time) string {
+	title := ""
+	for _, entry := range history {
+		entryTitle := entry.Title
+		if checkpointed, ok := git.ParseCheckpointSubject(entry.Title); ok {
+			entryTitle = checkpointed
+		}
+		if entry.CommitHash == hash {
+			return entryTitle
+		}
+		// Commit dates have whole seconds
+		if !entry.Timestamp.Truncate(time.Second).After(authoredAt) {
+			title = entryTitle
+		}
+	}
+	return title
+}
+
 // OnWorktreeCreated handles when a new worktree is created
 func (s *ClaudeMonitorService) OnWorktreeCreated(worktreeID, worktreePath string) {
 	// Start monitoring todos for this new worktree
 	s.startWorktreeTodoMonitor(worktreeID, worktreePath)
 }
 
+// ApplyCheckpointConfig makes the checkpoint manager of a worktree, if it has one, pick up a
+// changed checkpoint configuration
+func (s *ClaudeMonitorService) ApplyCheckpointConfig(worktreePath string) {
+	s.managersMutex.RLock()
+	manager, exists := s.checkpointManagers[s.canonicalWorkDir(worktreePath)]
+	s.managersMutex.RUnlock()
+	if exists {
+		manager.ApplyCheckpointConfig()
+	}
+}
+
+// findHandedOffWorktree returns the worktree at workDir when it was handed off to a human
+func (s *ClaudeMonitorService) findHandedOffWorktree(workDir string) (*models.Worktree, bool) {
+	if s.stateManager == nil {
+		return nil, false
+	}
+	worktree, exists := s.stateManager.FindWorktreeByPath(workDir)
+	if !exists || worktree.HandedOff == nil {
+		return nil, false
+	}
+	return worktree, true
+}
+
+// ResetWorktreeAutomation restarts the checkpointing of a worktree handed back to catnip
+func (s *ClaudeMonitorService) ResetWorktreeAutomation(worktreePath string) {
+	workDir := s.canonicalWorkDir(worktreePath)
+
+	s.recentTitlesMutex.Lock()
+	for key := range s.recentTitles {
+		if strings.HasPrefix(key, workDir+":") {
+			delete(s.recentTitles, key)
+		}
+	}
+	s.recentTitlesMutex.Unlock()
+
+	s.managersMutex.RLock()
+	manager, exists := s.checkpointManagers[workDir]
+	s.managersMutex.RUnlock()
+	if exists {
+		manager.ResetAutomation()
+	}
+}
+
 // OnWorktreeDeleted removes checkpoint manager and todo monitor for the deleted worktree
 func (s *ClaudeMonitorService) OnWorktreeDeleted(worktreeID, worktreePath string) {
 	logger.Infof("📂 Worktree deleted: %s -> %s", worktreeID, worktreePath)
@@ -0,0 +1,138 @@
+package services
+
+import (
+	"os"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/logger"
+)
+
+// claudeMonitorJanitorInterval is how often the Claude monitor evicts stale state
+const claudeMonitorJanitorInterval = 5 * time.Minute
+
+// recentTitleTTL is how long a title is remembered for duplicate detection
+const recentTitleTTL = 5 * time.Second
+
+// ClaudeMonitorStats reports the size of the Claude monitor's per-worktree state
+type ClaudeMonitorStats struct {
+	CheckpointManagers int `json:"checkpoint_managers"`
+	TodoMonitors       int `json:"todo_monitors"`
+	RecentTitles       int `json:"recent_titles"`
+	DiffFingerprints   int `json:"diff_fingerprints"`
+	DebugTimelines     int `json:"debug_timelines"`
+	ActivityTimes      int `json:"activity_times"`
+}
+
+// GetStats returns the current size of the monitor's maps, so leaks are observable
+func (s *ClaudeMonitorService) GetStats() ClaudeMonitorStats {
+	var stats ClaudeMonitorStats
+
+	s.managersMutex.RLock()
+	stats.CheckpointManagers = len(s.checkpointManagers)
+	s.managersMutex.RUnlock()
+
+	s.todoMonitorsMutex.RLock()
+	stats.TodoMonitors = len(s.todoMonitors)
+	s.todoMonitorsMutex.RUnlock()
+
+	s.recentTitlesMutex.RLock()
+	stats.RecentTitles = len(s.recentTitles)
+	s.recentTitlesMutex.RUnlock()
+
+	stats.DiffFingerprints = s.diffFingerprints.size()
+	stats.DebugTimelines = s.timeline.size()
+
+	s.activityMutex.RLock()
+	stats.ActivityTimes = len(s.lastActivityTimes)
+	s.activityMutex.RUnlock()
+
+	return stats
+}
+
+// runJanitor sweeps stale state every interval until the monitor is stopped
+func (s *ClaudeMonitorService) runJanitor(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.sweep(time.Now())
+		case <-s.stopCh:
+			return
+		}
+	}
+}
+
+// sweep prunes expired recent titles and drops the checkpoint managers, todo monitors and
+// activity times of worktree directories that no longer exist
+func (s *ClaudeMonitorService) sweep(now time.Time) {
+	s.recentTitlesMutex.Lock()
+	s.pruneRecentTitlesLocked(now)
+	s.recentTitlesMutex.Unlock()
+
+	removed := 0
+
+	s.managersMutex.Lock()
+	for workDir, manager := range s.checkpointManagers {
+		if pathRemoved(workDir) {
+			manager.Discard()
+			delete(s.checkpointManagers, workDir)
+			removed++
+		}
+	}
+	s.managersMutex.Unlock()
+
+	s.todoMonitorsMutex.Lock()
+	for key, monitor := range s.todoMonitors {
+		if pathRemoved(monitor.workDir) {
+			monitor.Stop()
+			delete(s.todoMonitors, key)
+			removed++
+		}
+	}
+	s.todoMonitorsMutex.Unlock()
+
+	s.activityMutex.Lock()
+	for workDir := range s.lastActivityTimes {
+		if pathRemoved(workDir) {
+			delete(s.lastActivityTimes, workDir)
+			removed++
+		}
+	}
+	s.activityMutex.Unlock()
+
+	removed += s.diffFingerprints.forgetRemoved()
+	removed += s.timeline.forgetRemoved()
+
+	if removed > 0 {
+		logger.Debugf("🧹 Claude monitor janitor evicted %d entries for removed worktrees", removed)
+	}
+}
+
+// pruneRecentTitlesLocked forgets titles older than recentTitleTTL. The caller must hold
+// recentTitlesMutex.
+func (s *ClaudeMonitorService) pruneRecentTitlesLocked(now time.Time) {
+	cutoff := now.Add(-recentTitleTTL)
+	for key, event := range s.recentTitles {
+		if event.timestamp.Before(cutoff) {
+			delete(s.recentTitles, key)
+		}
+	}
+}
+
+// Discard stops the checkpoint timer without committing, for worktrees that no longer exist
+func (m *WorktreeCheckpointManager) Discard() {
+	m.timerMutex.Lock()
+	defer m.timerMutex.Unlock()
+
+	if m.checkpointTimer != nil {
+		m.checkpointTimer.Stop()
+	}
+}
+
+// pathRemoved reports whether path no longer exists. Other stat errors keep the entry.
+func pathRemoved(path string) bool {
+	_, err := os.Stat(path)
+	return os.IsNotExist(err)
+}
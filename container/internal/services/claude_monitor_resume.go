@@ -0,0 +1,136 @@
+package services
+
+import (
+	"time"
+
+	"github.com/vanpelt/catnip/internal/logger"
+)
+
+// resumeCheckInterval is how often the Claude monitor compares the wall clock with the
+// monotonic clock to detect that the container was suspended
+const resumeCheckInterval = 10 * time.Second
+
+// resumeSkewThreshold is how far the wall clock must jump ahead of the monotonic clock between
+// two checks to be treated as a resume from sleep rather than an NTP adjustment
+const resumeSkewThreshold = time.Minute
+
+// clock is the time source of the Claude monitor, replaced in tests to simulate the container
+// being suspended
+type clock interface {
+	// Now returns the wall clock time
+	Now() time.Time
+	// Monotonic returns the time elapsed on a clock that stops while the container is suspended
+	Monotonic() time.Duration
+	AfterFunc(d time.Duration, f func()) clockTimer
+}
+
+// clockTimer is a timer started by clock.AfterFunc
+type clockTimer interface {
+	Stop() bool
+}
+
+// systemClock is the real clock. Go timers and time.Since use the monotonic clock, which
+// doesn't advance while a laptop running the container sleeps.
+type systemClock struct{}
+
+var systemClockStart = time.Now()
+
+func (systemClock) Now() time.Time { return time.Now().Round(0) }
+
+func (systemClock) Monotonic() time.Duration { return time.Since(systemClockStart) }
+
+func (systemClock) AfterFunc(d time.Duration, f func()) clockTimer { return time.AfterFunc(d, f) }
+
+// resumeDetector notices the wall clock jumping ahead of the monotonic clock, which happens
+// when the container resumes after the host slept
+type resumeDetector struct {
+	clock    clock
+	lastWall time.Time
+	lastMono time.Duration
+}
+
+func newResumeDetector(c clock) *resumeDetector {
+	return &resumeDetector{clock: c, lastWall: c.Now(), lastMono: c.Monotonic()}
+}
+
+// check returns how far the wall clock moved ahead of the monotonic clock since the previous
+// check, and whether that amounts to a resume
+func (d *resumeDetector) check() (time.Duration, bool) {
+	wall, mono := d.clock.Now(), d.clock.Monotonic()
+	skew := wall.Sub(d.lastWall) - (mono - d.lastMono)
+	d.lastWall, d.lastMono = wall, mono
+	return skew, skew > resumeSkewThreshold
+}
+
+// runResumeDetector checks for resumes every interval until the monitor is stopped
+func (s *ClaudeMonitorService) runResumeDetector(interval time.Duration) {
+	detector := newResumeDetector(s.clock)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if skew, resumed := detector.check(); resumed {
+				s.handleResume(skew)
+			}
+		case <-s.stopCh:
+			return
+		}
+	}
+}
+
+// handleResume brings time-based state in line with a wall clock that jumped by skew while the
+// container was suspended. Activity recorded before the sleep is aged by skew so idle states
+// are re-evaluated, duplicate title windows are forgotten, and each checkpoint timer is
+// replaced by a single catch-up checkpoint instead of whatever was due during the sleep.
+func (s *ClaudeMonitorService) handleResume(skew time.Duration) {
+	logger.Warnf("⏰ Wall clock jumped %v ahead of the monotonic clock, the container was likely suspended; resetting Claude monitor timers", skew.Round(time.Second))
+
+	s.recentTitlesMutex.Lock()
+	s.recentTitles = make(map[string]titleEvent)
+	s.recentTitlesMutex.Unlock()
+
+	s.activityMutex.Lock()
+	for workDir, last := range s.lastActivityTimes {
+		s.lastActivityTimes[workDir] = last.Add(-skew)
+	}
+	s.activityMutex.Unlock()
+
+	if s.claudeService != nil {
+		s.claudeService.AgeActivity(skew)
+	}
+
+	s.managersMutex.RLock()
+	managers := make([]*WorktreeCheckpointManager, 0, len(s.checkpointManagers))
+	for _, manager := range s.checkpointManagers {
+		managers = append(managers, manager)
+	}
+	s.managersMutex.RUnlock()
+	for _, manager := range managers {
+		manager.Resume()
+	}
+
+	if s.stateManager != nil {
+		s.stateManager.TriggerClaudeActivitySync()
+	}
+}
+
+// Resume takes one catch-up checkpoint after the container was suspended and restarts the
+// checkpoint timer from now
+func (m *WorktreeCheckpointManager) Resume() {
+	m.timerMutex.Lock()
+	defer m.timerMutex.Unlock()
+
+	if m.checkpointTimer != nil {
+		m.checkpointTimer.Stop()
+		m.checkpointTimer = nil
+	}
+	if m.currentTitle == "" {
+		return
+	}
+	if !m.checkpointsHeld {
+		m.createCheckpointIfChanged()
+	}
+	m.startCheckpointTimer()
+}
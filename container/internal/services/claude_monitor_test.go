@@ -0,0 +1,382 @@
+package services
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/git"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+func TestDetectPlanMode(t *testing.T) {
+	userPlan := `{"type":"user","permissionMode":"plan","message":{"role":"user","content":"plan it"}}`
+	userDefault := `{"type":"user","permissionMode":"default","message":{"role":"user","content":"go"}}`
+	exitPlan := `{"type":"assistant","message":{"role":"assistant","content":[{"type":"tool_use","name":"ExitPlanMode","input":{"plan":"..."}}]}}`
+	sidechainPlan := `{"type":"user","isSidechain":true,"permissionMode":"plan","message":{}}`
+	noMode := `{"type":"assistant","message":{"role":"assistant","content":[{"type":"text","text":"thinking"}]}}`
+
+	tests := []struct {
+		name       string
+		lines      []string
+		wantActive bool
+		wantFound  bool
+	}{
+		{"no signal", []string{noMode}, false, false},
+		{"plan mode user message", []string{userDefault, userPlan, noMode}, true, true},
+		{"back to default mode", []string{userPlan, userDefault}, false, true},
+		{"exit plan mode tool call", []string{userPlan, exitPlan}, false, true},
+		{"plan rejected after exit", []string{exitPlan, userPlan}, true, true},
+		{"sidechain ignored", []string{userDefault, sidechainPlan}, false, true},
+		{"invalid json ignored", []string{userPlan, "{not json"}, true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines := make([][]byte, len(tt.lines))
+			for i, line := range tt.lines {
+				lines[i] = []byte(line)
+			}
+			active, found := detectPlanMode(lines)
+			assert.Equal(t, tt.wantActive, active)
+			assert.Equal(t, tt.wantFound, found)
+		})
+	}
+}
+
+func TestCheckpointManagerPlanModeHold(t *testing.T) {
+	m := &WorktreeCheckpointManager{workDir: "/workspace/test/felix"}
+
+	m.SetPlanMode(true)
+	assert.True(t, m.checkpointsHeld)
+	heldSince := m.heldSince
+
+	// Repeated detection keeps the original hold start
+	m.SetPlanMode(true)
+	assert.Equal(t, heldSince, m.heldSince)
+
+	m.SetPlanMode(false)
+	assert.False(t, m.checkpointsHeld)
+
+	// Once the max hold expires, plan mode is ignored until it is seen to end
+	m.holdExpired = true
+	m.SetPlanMode(true)
+	assert.False(t, m.checkpointsHeld)
+	m.SetPlanMode(false)
+	m.SetPlanMode(true)
+	assert.True(t, m.checkpointsHeld)
+	assert.WithinDuration(t, time.Now(), m.heldSince, time.Minute)
+}
+
+func TestClaudeMonitorRespectsWorkspaceDir(t *testing.T) {
+	workspaceDir := filepath.Join(t.TempDir(), ".catnip", "workspace")
+	t.Setenv("CATNIP_WORKSPACE_DIR", workspaceDir+"/")
+
+	worktreePath := filepath.Join(workspaceDir, "catnip", "felix")
+	require.NoError(t, os.MkdirAll(filepath.Join(worktreePath, ".git"), 0755))
+	externalPath := filepath.Join(t.TempDir(), "external")
+	require.NoError(t, os.MkdirAll(filepath.Join(externalPath, ".git"), 0755))
+
+	s := &ClaudeMonitorService{}
+	assert.True(t, s.isWorktreeDirectory(worktreePath), "title events from the custom workspace are processed")
+	assert.False(t, s.isExternalGitRepository(worktreePath))
+	assert.False(t, s.isWorktreeDirectory("/workspace/catnip/felix"))
+	assert.False(t, s.isWorktreeDirectory(workspaceDir))
+	assert.True(t, s.isExternalGitRepository(externalPath))
+
+	claude := &ClaudeService{}
+	assert.Equal(t, worktreePath, claude.normalizeToWorktreeRoot(filepath.Join(worktreePath, "container", "internal")))
+	assert.Equal(t, externalPath, claude.normalizeToWorktreeRoot(externalPath))
+
+	// Claude replaces the dots of hidden directories in the workspace path too
+	assert.NotContains(t, WorktreePathToProjectDir(worktreePath), ".")
+}
+
+func TestClaudeMonitorJanitorBoundsState(t *testing.T) {
+	s := NewClaudeMonitorService(nil, nil, nil, nil)
+	root := t.TempDir()
+	live := filepath.Join(root, "live")
+	require.NoError(t, os.MkdirAll(live, 0755))
+
+	track := func(workDir string, seen time.Time) {
+		s.checkpointManagers[workDir] = &WorktreeCheckpointManager{workDir: workDir, checkpointTimer: time.NewTimer(time.Hour)}
+		s.todoMonitors[workDir] = &WorktreeTodoMonitor{workDir: workDir, stopCh: make(chan struct{})}
+		s.lastActivityTimes[workDir] = seen
+		s.recentTitles[workDir+":title"] = titleEvent{title: "title", timestamp: seen, source: "log"}
+		s.timeline.record(workDir, models.DebugTimelineEntry{Kind: TimelineTitleEvent, Title: "title", Outcome: TimelineAccepted})
+	}
+	track(live, time.Now())
+
+	// Soak: worktrees come and go between sweeps; state never outgrows the live worktrees
+	start := time.Now()
+	for round := 0; round < 20; round++ {
+		now := start.Add(time.Duration(round) * recentTitleTTL)
+		for i := 0; i < 50; i++ {
+			workDir := filepath.Join(root, "workspace", "felix-"+strconv.Itoa(round*50+i))
+			require.NoError(t, os.MkdirAll(workDir, 0755))
+			track(workDir, now)
+			require.NoError(t, os.RemoveAll(workDir))
+		}
+		s.sweep(now.Add(recentTitleTTL + time.Second))
+
+		stats := s.GetStats()
+		assert.Equal(t, ClaudeMonitorStats{CheckpointManagers: 1, TodoMonitors: 1, RecentTitles: 0, DebugTimelines: 1, ActivityTimes: 1}, stats, "round %d", round)
+	}
+	assert.Contains(t, s.checkpointManagers, live)
+
+	// The janitor goroutine sweeps on its own timer and ends with the monitor
+	s.recentTitles["fresh"] = titleEvent{timestamp: time.Now().Add(-time.Minute)}
+	done := make(chan struct{})
+	go func() {
+		s.runJanitor(10 * time.Millisecond)
+		close(done)
+	}()
+	assert.Eventually(t, func() bool { return s.GetStats().RecentTitles == 0 }, time.Second, 10*time.Millisecond)
+	close(s.stopCh)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("janitor did not stop")
+	}
+}
+
+// fakeClock is a manually advanced clock whose wall time can jump without the monotonic time
+// advancing, as when the host running the container sleeps
+type fakeClock struct {
+	mu     sync.Mutex
+	wall   time.Time
+	mono   time.Duration
+	timers []*fakeTimer
+}
+
+type fakeTimer struct {
+	clock   *fakeClock
+	at      time.Duration
+	f       func()
+	stopped bool
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.wall
+}
+
+func (c *fakeClock) Monotonic() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.mono
+}
+
+func (c *fakeClock) AfterFunc(d time.Duration, f func()) clockTimer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	timer := &fakeTimer{clock: c, at: c.mono + d, f: f}
+	c.timers = append(c.timers, timer)
+	return timer
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	wasPending := !t.stopped
+	t.stopped = true
+	return wasPending
+}
+
+// Advance moves both clocks forward and runs the timers that come due
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.wall = c.wall.Add(d)
+	c.mono += d
+	var due, pending []*fakeTimer
+	for _, timer := range c.timers {
+		switch {
+		case timer.stopped:
+		case timer.at <= c.mono:
+			timer.stopped = true
+			due = append(due, timer)
+		default:
+			pending = append(pending, timer)
+		}
+	}
+	c.timers = pending
+	c.mu.Unlock()
+
+	for _, timer := range due {
+		timer.f()
+	}
+}
+
+// Suspend moves only the wall clock forward, so no timer fires
+func (c *fakeClock) Suspend(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.wall = c.wall.Add(d)
+}
+
+// countingCheckpointGit counts checkpoint commits without touching the worktree
+type countingCheckpointGit struct {
+	mu      sync.Mutex
+	commits int
+}
+
+func (g *countingCheckpointGit) GitAddCommitGetHash(workDir, title string) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.commits++
+	return "abc" + strconv.Itoa(g.commits), nil
+}
+
+func (g *countingCheckpointGit) RefreshWorktreeStatus(workDir string) error      { return nil }
+func (g *countingCheckpointGit) RecordCheckpointError(workDir string, err error) {}
+
+func (g *countingCheckpointGit) count() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.commits
+}
+
+type noopCheckpointSessions struct{}
+
+func (noopCheckpointSessions) AddToSessionHistory(workDir, title, commitHash string) error {
+	return nil
+}
+
+func (noopCheckpointSessions) GetActiveSession(workDir string) (interface{}, bool) {
+	return nil, false
+}
+
+func (noopCheckpointSessions) UpdateSessionTitle(workDir, title, commitHash string) error {
+	return nil
+}
+
+func (noopCheckpointSessions) GetPreviousTitle(workDir string) string {
+	return ""
+}
+
+func (noopCheckpointSessions) UpdatePreviousTitleCommitHash(workDir, commitHash string) error {
+	return nil
+}
+
+func TestClaudeMonitorResumeAfterSleep(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	// A worktree with uncommitted changes, so every checkpoint opportunity commits
+	workDir := initTestRepo(t, t.TempDir(), map[string]string{"app.txt": "one\n"})
+	require.NoError(t, os.WriteFile(filepath.Join(workDir, "app.txt"), []byte("one\ntwo\n"), 0644))
+
+	clock := &fakeClock{wall: time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC)}
+	commits := &countingCheckpointGit{}
+	claude := &ClaudeService{lastActivity: map[string]time.Time{workDir: time.Now()}}
+	s := NewClaudeMonitorService(nil, nil, claude, nil)
+	s.clock = clock
+
+	m := &WorktreeCheckpointManager{
+		workDir:           workDir,
+		checkpointManager: git.NewSessionCheckpointManager(workDir, commits, noopCheckpointSessions{}),
+		gitService:        &GitService{operations: git.NewOperations()},
+		clock:             clock,
+		currentTitle:      "Add login page",
+	}
+	m.timerMutex.Lock()
+	m.startCheckpointTimer()
+	m.timerMutex.Unlock()
+	s.checkpointManagers[workDir] = m
+	s.recentTitles[workDir+":Add login page"] = titleEvent{title: "Add login page", timestamp: time.Now(), source: "log"}
+
+	detector := newResumeDetector(clock)
+	clock.Advance(10 * time.Second)
+	_, resumed := detector.check()
+	assert.False(t, resumed)
+	assert.Equal(t, 0, commits.count())
+
+	// The laptop sleeps for two hours mid-session: the wall clock jumps, timers stand still
+	clock.Suspend(2 * time.Hour)
+	skew, resumed := detector.check()
+	require.True(t, resumed)
+	assert.Equal(t, 2*time.Hour, skew)
+	s.handleResume(skew)
+
+	assert.Equal(t, 1, commits.count(), "exactly one catch-up checkpoint")
+	assert.Zero(t, s.GetStats().RecentTitles, "duplicate title windows are forgotten")
+	assert.False(t, claude.IsActiveSession(workDir, 10*time.Minute), "activity before the sleep is idle")
+
+	// The checkpoint timer restarted at resume rather than firing for the time slept
+	clock.Advance(git.GetCheckpointTimeout() - time.Second)
+	_, resumed = detector.check()
+	assert.False(t, resumed)
+	assert.Equal(t, 1, commits.count())
+	clock.Advance(time.Second)
+	assert.Equal(t, 2, commits.count())
+}
+
+func TestCheckpointConfigDisablesCommits(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	workDir := initTestRepo(t, t.TempDir(), map[string]string{"app.txt": "one\n"})
+	require.NoError(t, os.WriteFile(filepath.Join(workDir, "app.txt"), []byte("one\ntwo\n"), 0644))
+	commitCount := func() string {
+		return strings.TrimSpace(runTestGit(t, workDir, "rev-list", "--count", "HEAD"))
+	}
+
+	gitService := newTestGitService(t, t.TempDir())
+	stateManager := gitService.stateManager
+	require.NoError(t, stateManager.AddRepository(&models.Repository{ID: "local/repo", Path: workDir}))
+	require.NoError(t, stateManager.AddWorktree(&models.Worktree{ID: "wt-felix", RepoID: "local/repo", Name: "repo/felix", Path: workDir, Branch: "main"}))
+	sessions := &SessionService{stateDir: t.TempDir(), activeSessions: map[string]*ActiveSessionInfo{workDir: {}}}
+	monitor := NewClaudeMonitorService(gitService, sessions, nil, stateManager)
+	gitService.claudeMonitor = monitor
+
+	clock := &fakeClock{wall: time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC)}
+	checkpoints := &countingCheckpointGit{}
+	m := &WorktreeCheckpointManager{
+		workDir:           workDir,
+		worktreeID:        "wt-felix",
+		checkpointManager: git.NewSessionCheckpointManager(workDir, checkpoints, noopCheckpointSessions{}),
+		gitService:        gitService,
+		sessionService:    sessions,
+		stateManager:      stateManager,
+		clock:             clock,
+	}
+	monitor.checkpointManagers[workDir] = m
+
+	require.NoError(t, gitService.SetWorktreeCheckpointConfig("wt-felix", models.CheckpointConfig{Enabled: false}))
+	worktree, _ := stateManager.GetWorktree("wt-felix")
+	assert.Equal(t, &models.CheckpointConfig{Enabled: false}, worktree.CheckpointConfig)
+
+	// Titles are still tracked, but neither title changes nor timers commit
+	m.HandleTitleChange("Add login page")
+	m.HandleTitleChange("Fix signup form")
+	clock.Advance(time.Hour)
+	assert.Equal(t, "1", commitCount())
+	assert.Zero(t, checkpoints.count())
+	session, _ := sessions.GetActiveSession(workDir)
+	require.Len(t, session.TitleHistory, 2)
+	assert.Equal(t, "Fix signup form", session.Title.Title)
+	assert.Empty(t, session.TitleHistory[0].CommitHash)
+
+	// Enabling picks up the running session with its own timeout
+	require.NoError(t, gitService.SetWorktreeCheckpointConfig("wt-felix", models.CheckpointConfig{Enabled: true, TimeoutSeconds: 5}))
+	clock.Advance(4 * time.Second)
+	assert.Zero(t, checkpoints.count())
+	clock.Advance(time.Second)
+	assert.Equal(t, 1, checkpoints.count())
+
+	m.HandleTitleChange("Add password reset")
+	assert.Equal(t, "2", commitCount())
+
+	assert.Error(t, gitService.SetWorktreeCheckpointConfig("wt-felix", models.CheckpointConfig{Enabled: true, TimeoutSeconds: -1}))
+	assert.Error(t, gitService.SetWorktreeCheckpointConfig("wt-oscar", models.CheckpointConfig{}))
+}
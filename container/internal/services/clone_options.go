@@ -0,0 +1,240 @@
+package services
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/config"
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+const (
+	// defaultCloneDepth is how many commits a new clone fetches before unshallowing
+	defaultCloneDepth = 1
+	// defaultUnshallowDelay leaves the initial worktree setup the connection before unshallowing
+	defaultUnshallowDelay = 5 * time.Second
+)
+
+// CloneOptions configures how CheckoutRepository clones a repository that isn't on disk yet.
+// Checkouts of repositories already cloned ignore them.
+type CloneOptions struct {
+	// Commits of history to clone, 0 for the full history
+	Depth int `json:"depth"`
+	// Only clone the requested (or default) branch
+	SingleBranch bool `json:"single_branch"`
+	// Fetch the full history in the background after a shallow clone, subject to the
+	// catnip.clone.unshallow-max-size-mb threshold
+	UnshallowInBackground bool `json:"unshallow_in_background"`
+	// How long to wait after cloning before unshallowing
+	UnshallowDelay time.Duration `json:"unshallow_delay"`
+	// Clone in mirror mode: the full history of every branch, kept current in the background.
+	// Overrides Depth and SingleBranch.
+	Mirror bool `json:"mirror"`
+}
+
+// DefaultCloneOptions returns the clone options from the environment: CATNIP_CLONE_DEPTH
+// (default 1, 0 for full clones), CATNIP_CLONE_SINGLE_BRANCH (default true), CATNIP_UNSHALLOW
+// (default true), CATNIP_UNSHALLOW_DELAY_SECONDS (default 5) and CATNIP_CLONE_MIRROR (default
+// false)
+func DefaultCloneOptions() CloneOptions {
+	opts := CloneOptions{
+		Depth:                 defaultCloneDepth,
+		SingleBranch:          true,
+		UnshallowInBackground: true,
+		UnshallowDelay:        defaultUnshallowDelay,
+	}
+	if value := os.Getenv("CATNIP_CLONE_DEPTH"); value != "" {
+		if depth, err := strconv.Atoi(value); err == nil && depth >= 0 {
+			opts.Depth = depth
+		}
+	}
+	if value := os.Getenv("CATNIP_CLONE_SINGLE_BRANCH"); value != "" {
+		if singleBranch, err := strconv.ParseBool(value); err == nil {
+			opts.SingleBranch = singleBranch
+		}
+	}
+	if value := os.Getenv("CATNIP_UNSHALLOW"); value != "" {
+		if unshallow, err := strconv.ParseBool(value); err == nil {
+			opts.UnshallowInBackground = unshallow
+		}
+	}
+	if value := os.Getenv("CATNIP_UNSHALLOW_DELAY_SECONDS"); value != "" {
+		if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+			opts.UnshallowDelay = time.Duration(seconds) * time.Second
+		}
+	}
+	if value := os.Getenv("CATNIP_CLONE_MIRROR"); value != "" {
+		if mirror, err := strconv.ParseBool(value); err == nil {
+			opts.Mirror = mirror
+		}
+	}
+	return opts
+}
+
+// cloneArgs returns the git clone arguments for the options, before the branch and locations
+func (o CloneOptions) cloneArgs() []string {
+	args := []string{"clone", "--bare"}
+	if o.Mirror {
+		// Every branch with its full history
+		return args
+	}
+	if o.Depth > 0 {
+		args = append(args, "--depth", strconv.Itoa(o.Depth))
+	}
+	if o.SingleBranch {
+		args = append(args, "--single-branch")
+	} else if o.Depth > 0 {
+		// --depth implies --single-branch
+		args = append(args, "--no-single-branch")
+	}
+	return args
+}
+
+// unshallowRegistry tracks the background unshallows, so they can be cancelled when their
+// repository is deleted or the service stops
+type unshallowRegistry struct {
+	mu      sync.Mutex
+	running map[string]*runningUnshallow // repo ID -> its unshallow
+	stopped bool
+}
+
+type runningUnshallow struct {
+	cancel context.CancelFunc
+}
+
+// start registers an unshallow of the repository, returning its context and a handle to pass to
+// finish. It refuses when one is already running or the service stopped.
+func (r *unshallowRegistry) start(repoID string) (context.Context, *runningUnshallow, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.stopped || r.running[repoID] != nil {
+		return nil, nil, false
+	}
+	if r.running == nil {
+		r.running = make(map[string]*runningUnshallow)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	unshallow := &runningUnshallow{cancel: cancel}
+	r.running[repoID] = unshallow
+	return ctx, unshallow, true
+}
+
+// finish unregisters an unshallow that ended
+func (r *unshallowRegistry) finish(repoID string, unshallow *runningUnshallow) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	unshallow.cancel()
+	if r.running[repoID] == unshallow {
+		delete(r.running, repoID)
+	}
+}
+
+// cancel cancels the unshallow of a repository, reporting whether one was running
+func (r *unshallowRegistry) cancel(repoID string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	unshallow := r.running[repoID]
+	if unshallow == nil {
+		return false
+	}
+	unshallow.cancel()
+	delete(r.running, repoID)
+	return true
+}
+
+// stop cancels all unshallows and refuses new ones
+func (r *unshallowRegistry) stop() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stopped = true
+	for repoID, unshallow := range r.running {
+		unshallow.cancel()
+		delete(r.running, repoID)
+	}
+}
+
+// startUnshallow fetches the full history of a shallow clone in the background after the delay
+func (s *GitService) startUnshallow(repoID, barePath, branch string, delay time.Duration) {
+	ctx, unshallow, ok := s.unshallows.start(repoID)
+	if !ok {
+		return
+	}
+	s.setRepositoryHistory(repoID, models.RepositoryHistoryUnshallowing)
+	go func() {
+		defer s.unshallows.finish(repoID, unshallow)
+		s.unshallowRepository(ctx, repoID, barePath, branch, delay)
+	}()
+}
+
+// unshallowRepository unshallows a specific branch, until ctx is cancelled
+func (s *GitService) unshallowRepository(ctx context.Context, repoID, barePath, branch string, delay time.Duration) {
+	// Wait a bit before starting to avoid interfering with initial setup
+	select {
+	case <-time.After(delay):
+	case <-ctx.Done():
+		logger.Infof("⏹️  Cancelled unshallowing %s", repoID)
+		s.setRepositoryHistory(repoID, models.RepositoryHistoryShallow)
+		return
+	}
+
+	// Only fetch the specific branch to be more efficient
+	cmd := exec.CommandContext(ctx, "git", "-C", barePath, "fetch", "origin", "--unshallow", branch)
+	cmd.Env = append(os.Environ(), "HOME="+config.Runtime.HomeDir)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		if ctx.Err() != nil {
+			logger.Infof("⏹️  Cancelled unshallowing %s", repoID)
+			// A killed fetch leaves its lock behind
+			_ = os.Remove(filepath.Join(barePath, "shallow.lock"))
+		} else {
+			// Silent failure - unshallow is optional optimization
+			logger.Debugf("⚠️  Failed to unshallow %s: %v\n%s", repoID, err, output)
+		}
+		s.setRepositoryHistory(repoID, models.RepositoryHistoryShallow)
+		return
+	}
+	s.setRepositoryHistory(repoID, models.RepositoryHistoryFull)
+
+	// The full history changes the size considerably
+	if _, err := s.RefreshRepositorySize(repoID); err != nil {
+		logger.Debugf("⚠️  Failed to measure size of %s: %v", repoID, err)
+	}
+}
+
+// CancelUnshallow stops the background unshallow of a repository, reporting whether one was
+// running. The repository stays shallow.
+func (s *GitService) CancelUnshallow(repoID string) bool {
+	return s.unshallows.cancel(repoID)
+}
+
+// setRepositoryHistory records whether a repository has its full history
+func (s *GitService) setRepositoryHistory(repoID, history string) {
+	if err := s.stateManager.UpdateRepository(repoID, func(repo *models.Repository) {
+		repo.History = history
+	}); err != nil {
+		logger.Debugf("⚠️  Failed to record history of %s: %v", repoID, err)
+	}
+}
+
+// repositoryHistory returns the history state of the repository at barePath
+func (s *GitService) repositoryHistory(barePath string) string {
+	if s.isShallowRepository(barePath) {
+		return models.RepositoryHistoryShallow
+	}
+	return models.RepositoryHistoryFull
+}
+
+// fetchHistoryForPullRequest fetches the worktree's source branch before creating a pull
+// request, which isn't needed once the repository has its full history
+func (s *GitService) fetchHistoryForPullRequest(worktree *models.Worktree) {
+	if repo, exists := s.stateManager.GetRepository(worktree.RepoID); exists && repo.History == models.RepositoryHistoryFull {
+		logger.Debugf("📚 %s has its full history, not fetching before the pull request", repo.ID)
+		return
+	}
+	s.fetchFullHistory(worktree)
+}
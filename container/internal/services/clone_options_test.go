@@ -0,0 +1,91 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+func TestDefaultCloneOptions(t *testing.T) {
+	assert.Equal(t, CloneOptions{Depth: 1, SingleBranch: true, UnshallowInBackground: true, UnshallowDelay: 5 * time.Second}, DefaultCloneOptions())
+
+	t.Setenv("CATNIP_CLONE_DEPTH", "0")
+	t.Setenv("CATNIP_CLONE_SINGLE_BRANCH", "false")
+	t.Setenv("CATNIP_UNSHALLOW", "false")
+	t.Setenv("CATNIP_UNSHALLOW_DELAY_SECONDS", "30")
+	opts := DefaultCloneOptions()
+	assert.Equal(t, CloneOptions{Depth: 0, SingleBranch: false, UnshallowInBackground: false, UnshallowDelay: 30 * time.Second}, opts)
+	assert.Equal(t, []string{"clone", "--bare"}, opts.cloneArgs())
+
+	t.Setenv("CATNIP_CLONE_DEPTH", "-3")
+	assert.Equal(t, 1, DefaultCloneOptions().Depth, "invalid values keep the default")
+
+	t.Setenv("CATNIP_CLONE_DEPTH", "1")
+	t.Setenv("CATNIP_CLONE_MIRROR", "true")
+	opts = DefaultCloneOptions()
+	assert.True(t, opts.Mirror)
+	assert.Equal(t, []string{"clone", "--bare"}, opts.cloneArgs(), "mirrors clone every branch in full")
+}
+
+func TestCloneUnshallow(t *testing.T) {
+	s, stateManager, repoPath, _ := newRecreateTestService(t)
+	require.NoError(t, os.WriteFile(filepath.Join(repoPath, "app.txt"), []byte("v2\n"), 0644))
+	runTestGit(t, repoPath, "commit", "-am", "v2")
+	clonesDir := t.TempDir()
+	clone := func(repoID string, opts CloneOptions) *models.Repository {
+		barePath := filepath.Join(clonesDir, filepath.Base(repoID)+".git")
+		repository, _, err := s.cloneBareRepository(repoID, "file://"+repoPath, barePath, "", opts)
+		require.NoError(t, err)
+		return repository
+	}
+	history := func(repoID string) string {
+		repo, exists := stateManager.GetRepository(repoID)
+		require.True(t, exists)
+		return repo.History
+	}
+
+	t.Run("FullClone", func(t *testing.T) {
+		repository := clone("acme/full", CloneOptions{Depth: 0, SingleBranch: true, UnshallowInBackground: true})
+		assert.Equal(t, models.RepositoryHistoryFull, repository.History)
+		assert.Equal(t, "2", runTestGit(t, repository.Path, "rev-list", "--count", "HEAD"))
+		assert.False(t, s.CancelUnshallow("acme/full"), "full clones have nothing to unshallow")
+	})
+
+	t.Run("ShallowCloneUnshallowsInBackground", func(t *testing.T) {
+		repository := clone("acme/background", CloneOptions{Depth: 1, SingleBranch: true, UnshallowInBackground: true})
+		require.Eventually(t, func() bool {
+			return history("acme/background") == models.RepositoryHistoryFull
+		}, 10*time.Second, 20*time.Millisecond)
+		assert.Equal(t, "2", runTestGit(t, repository.Path, "rev-list", "--count", "HEAD"))
+	})
+
+	t.Run("ShallowCloneWithoutUnshallow", func(t *testing.T) {
+		repository := clone("acme/shallow", CloneOptions{Depth: 1, SingleBranch: true})
+		assert.Equal(t, models.RepositoryHistoryShallow, history("acme/shallow"))
+		assert.Equal(t, "1", runTestGit(t, repository.Path, "rev-list", "--count", "HEAD"))
+	})
+
+	t.Run("DeletingRepositoryCancelsUnshallow", func(t *testing.T) {
+		clone("acme/deleted", CloneOptions{Depth: 1, SingleBranch: true, UnshallowInBackground: true, UnshallowDelay: time.Hour})
+		assert.Equal(t, models.RepositoryHistoryUnshallowing, history("acme/deleted"))
+
+		require.NoError(t, s.DeleteRepository("acme/deleted", false))
+		assert.False(t, s.CancelUnshallow("acme/deleted"), "already cancelled")
+	})
+
+	t.Run("StopCancelsUnshallow", func(t *testing.T) {
+		clone("acme/stopped", CloneOptions{Depth: 1, SingleBranch: true, UnshallowInBackground: true, UnshallowDelay: time.Hour})
+		s.unshallows.stop()
+		require.Eventually(t, func() bool {
+			return history("acme/stopped") == models.RepositoryHistoryShallow
+		}, 5*time.Second, 20*time.Millisecond)
+
+		clone("acme/after-stop", CloneOptions{Depth: 1, SingleBranch: true, UnshallowInBackground: true})
+		assert.Equal(t, models.RepositoryHistoryShallow, history("acme/after-stop"), "no unshallows start once stopped")
+	})
+}
@@ -0,0 +1,218 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/config"
+	"github.com/vanpelt/catnip/internal/git"
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+const (
+	// signingKeyringEnv overrides the directory holding the keys commit signatures are verified
+	// against: a GnuPG home for GPG keys and an allowed signers file for SSH keys
+	signingKeyringEnv = "CATNIP_SIGNING_KEYRING"
+	// allowedSignersFile lists the SSH keys trusted for signatures, in the keyring directory
+	allowedSignersFile = "allowed_signers"
+	// signatureVerifyTimeout bounds a git log verifying signatures, which runs gpg per commit
+	signatureVerifyTimeout = 30 * time.Second
+	// requireSignedCommitsKey makes validation fail unless every commit ahead of the source
+	// branch has a good signature
+	requireSignedCommitsKey = "catnip.validate.require-signed-commits"
+	// signatureValidationCommand stands for the signature check among the validation commands
+	signatureValidationCommand = "catnip: verify commit signatures"
+)
+
+// commitSignatureFormat is the git log format of the signature fields, in the order
+// parseCommitSignature takes them
+const commitSignatureFormat = "%G?%x00%GS%x00%GK"
+
+// SigningKeyImport describes a key added to the signing keyring
+type SigningKeyImport struct {
+	// "gpg" or "ssh"
+	Kind string `json:"kind"`
+	// Keyring directory the key was added to
+	Keyring string `json:"keyring"`
+}
+
+// SigningKeyringDir returns the directory of the keyring commit signatures are verified
+// against, CATNIP_SIGNING_KEYRING or signing-keyring in the volume directory
+func SigningKeyringDir() string {
+	if dir := os.Getenv(signingKeyringEnv); dir != "" {
+		return dir
+	}
+	return filepath.Join(config.Runtime.VolumeDir, "signing-keyring")
+}
+
+// signatureVerification returns the environment and leading git arguments that verify
+// signatures against the keyring. Without a keyring git's defaults apply.
+func signatureVerification() ([]string, []string) {
+	dir := SigningKeyringDir()
+	var env, args []string
+	if info, err := os.Stat(dir); err == nil && info.IsDir() {
+		env = append(env, "GNUPGHOME="+dir)
+	}
+	if fileExists(dir, allowedSignersFile) {
+		args = append(args, "-c", "gpg.ssh.allowedSignersFile="+filepath.Join(dir, allowedSignersFile))
+	}
+	return env, args
+}
+
+// runSignatureLog runs git log with the signing keyring, for formats including
+// commitSignatureFormat
+func runSignatureLog(operations git.Operations, repoPath string, args ...string) ([]byte, error) {
+	env, verifyArgs := signatureVerification()
+	return operations.ExecuteGitWithEnv(repoPath, env, signatureVerifyTimeout, append(verifyArgs, args...)...)
+}
+
+// parseCommitSignature converts the %G?, %GS and %GK fields of git log
+func parseCommitSignature(code, signer, key string) models.CommitSignature {
+	signature := models.CommitSignature{Status: models.SignatureUnsigned, Signer: signer, Key: key}
+	switch code {
+	case "G", "U":
+		signature.Status = models.SignatureGood
+	case "B", "X", "Y", "R":
+		signature.Status = models.SignatureBad
+	case "E":
+		signature.Status = models.SignatureUnknown
+	default:
+		signature.Signer, signature.Key = "", ""
+	}
+	return signature
+}
+
+// commitSignatures returns the signatures of the commits in revRange, newest first
+func commitSignatures(operations git.Operations, repoPath, revRange string) ([]models.MergedCommitSignature, error) {
+	output, err := runSignatureLog(operations, repoPath, "log", "--format=%H%x00"+commitSignatureFormat, revRange, "--")
+	if err != nil {
+		return nil, err
+	}
+	signatures := []models.MergedCommitSignature{}
+	for _, line := range strings.Split(string(output), "\n") {
+		fields := strings.SplitN(line, "\x00", 4)
+		if len(fields) < 4 {
+			continue
+		}
+		signatures = append(signatures, models.MergedCommitSignature{
+			Hash:            fields[0],
+			CommitSignature: parseCommitSignature(fields[1], fields[2], fields[3]),
+		})
+	}
+	return signatures, nil
+}
+
+// summarizeSignatures returns all_signed, mixed or none for the signatures of a range of
+// commits, or empty when the range has none
+func summarizeSignatures(signatures []models.MergedCommitSignature) string {
+	if len(signatures) == 0 {
+		return ""
+	}
+	good := 0
+	for _, signature := range signatures {
+		if signature.Status == models.SignatureGood {
+			good++
+		}
+	}
+	switch good {
+	case len(signatures):
+		return models.SignaturesAllSigned
+	case 0:
+		return models.SignaturesNone
+	}
+	return models.SignaturesMixed
+}
+
+// requireSignedCommits reports whether the repository of the worktree at worktreePath requires
+// good signatures on the commits it validates
+func (s *GitService) requireSignedCommits(worktreePath string) bool {
+	value, err := s.operations.GetConfig(worktreePath, requireSignedCommitsKey)
+	return err == nil && strings.TrimSpace(value) == "true"
+}
+
+// runSignatureValidation checks that every commit of a worktree ahead of its source branch has
+// a good signature, as a validation command result listing the commits that don't
+func (s *GitService) runSignatureValidation(worktree *models.Worktree) models.ValidationCommandResult {
+	started := time.Now()
+	result := models.ValidationCommandResult{Command: signatureValidationCommand}
+	signatures, err := commitSignatures(s.operations, worktree.Path, s.getSourceRef(worktree)+"..HEAD")
+	if err != nil {
+		result.ExitCode = 1
+		result.Output = fmt.Sprintf("failed to verify commit signatures: %v", err)
+	}
+	var unverified []string
+	for _, signature := range signatures {
+		if signature.Status != models.SignatureGood {
+			unverified = append(unverified, fmt.Sprintf("%.12s %s", signature.Hash, signature.Status))
+		}
+	}
+	if len(unverified) > 0 {
+		result.ExitCode = 1
+		result.Output = fmt.Sprintf("%d of %d commits lack a good signature:\n%s", len(unverified), len(signatures), strings.Join(unverified, "\n"))
+	}
+	result.DurationMS = time.Since(started).Milliseconds()
+	return result
+}
+
+// ImportSigningKey adds a key to the keyring commit signatures are verified against: an
+// ASCII-armored GPG public key, or SSH allowed signers lines ("principal key-type key")
+func (s *GitService) ImportSigningKey(key string) (*SigningKeyImport, error) {
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return nil, fmt.Errorf("no key given")
+	}
+	dir := SigningKeyringDir()
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create signing keyring: %v", err)
+	}
+
+	if strings.HasPrefix(key, "-----BEGIN PGP PUBLIC KEY BLOCK-----") {
+		cmd := exec.Command("gpg", "--homedir", dir, "--batch", "--import")
+		cmd.Stdin = strings.NewReader(key + "\n")
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return nil, fmt.Errorf("failed to import GPG key: %v\n%s", err, strings.TrimSpace(string(output)))
+		}
+		logger.Infof("🔏 Imported GPG key into %s", dir)
+		return &SigningKeyImport{Kind: "gpg", Keyring: dir}, nil
+	}
+
+	var lines []string
+	for _, line := range strings.Split(key, "\n") {
+		if line = strings.TrimSpace(line); line == "" {
+			continue
+		}
+		if !isAllowedSignersLine(line) {
+			return nil, fmt.Errorf("expected an armored GPG public key or SSH allowed signers lines, got %q", line)
+		}
+		lines = append(lines, line)
+	}
+	file, err := os.OpenFile(filepath.Join(dir, allowedSignersFile), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open allowed signers: %v", err)
+	}
+	defer file.Close()
+	if _, err := file.WriteString(strings.Join(lines, "\n") + "\n"); err != nil {
+		return nil, fmt.Errorf("failed to write allowed signers: %v", err)
+	}
+	logger.Infof("🔏 Added %d SSH signers to %s", len(lines), dir)
+	return &SigningKeyImport{Kind: "ssh", Keyring: dir}, nil
+}
+
+// isAllowedSignersLine reports whether line looks like an entry of an SSH allowed signers file
+func isAllowedSignersLine(line string) bool {
+	fields := strings.Fields(line)
+	if len(fields) < 3 {
+		return false
+	}
+	for _, field := range fields[1:] {
+		if strings.HasPrefix(field, "ssh-") || strings.HasPrefix(field, "ecdsa-sha2-") || strings.HasPrefix(field, "sk-") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+package services
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+func TestCommitSignatures(t *testing.T) {
+	if _, err := exec.LookPath("ssh-keygen"); err != nil {
+		t.Skip("ssh-keygen not available")
+	}
+	s, _, _, worktreePath := newRecreateTestService(t)
+	t.Setenv(signingKeyringEnv, filepath.Join(t.TempDir(), "keyring"))
+
+	key := filepath.Join(t.TempDir(), "id_ed25519")
+	output, err := exec.Command("ssh-keygen", "-q", "-t", "ed25519", "-N", "", "-C", "alice", "-f", key).CombinedOutput()
+	require.NoError(t, err, string(output))
+	publicKey, err := os.ReadFile(key + ".pub")
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(filepath.Join(worktreePath, "app.txt"), []byte("v2\n"), 0644))
+	runTestGit(t, worktreePath, "commit", "-am", "Unsigned change")
+	unsigned := runTestGit(t, worktreePath, "rev-parse", "HEAD")
+	require.NoError(t, os.WriteFile(filepath.Join(worktreePath, "app.txt"), []byte("v3\n"), 0644))
+	runTestGit(t, worktreePath, "-c", "gpg.format=ssh", "-c", "user.signingkey="+key, "commit", "-S", "-am", "Signed change")
+
+	_, err = s.ImportSigningKey("not a key")
+	assert.ErrorContains(t, err, "expected an armored GPG public key or SSH allowed signers lines")
+	imported, err := s.ImportSigningKey("alice@example.com " + string(publicKey))
+	require.NoError(t, err)
+	assert.Equal(t, "ssh", imported.Kind)
+
+	page, err := s.GetWorktreeCommits("wt-felix", 0, 0, false)
+	require.NoError(t, err)
+	require.Len(t, page.Commits, 2)
+	assert.Equal(t, models.SignatureGood, page.Commits[0].Signature.Status)
+	assert.Equal(t, "alice@example.com", page.Commits[0].Signature.Signer)
+	assert.NotEmpty(t, page.Commits[0].Signature.Key)
+	assert.Equal(t, models.CommitSignature{Status: models.SignatureUnsigned}, page.Commits[1].Signature)
+
+	signatures, err := commitSignatures(s.operations, worktreePath, "main..HEAD")
+	require.NoError(t, err)
+	assert.Equal(t, models.SignaturesMixed, summarizeSignatures(signatures))
+	assert.Equal(t, models.SignaturesNone, summarizeSignatures(signatures[1:]))
+	assert.Equal(t, models.SignaturesAllSigned, summarizeSignatures(signatures[:1]))
+	assert.Empty(t, summarizeSignatures(nil))
+
+	t.Run("ValidationGatesOnSignatures", func(t *testing.T) {
+		result, err := s.ValidateWorktree("wt-felix", true)
+		require.NoError(t, err)
+		assert.Equal(t, models.ValidationNotConfigured, result.Status)
+
+		runTestGit(t, worktreePath, "config", requireSignedCommitsKey, "true")
+		result, err = s.ValidateWorktree("wt-felix", true)
+		require.NoError(t, err)
+		assert.Equal(t, models.ValidationFailed, result.Status)
+		require.Len(t, result.Commands, 1)
+		assert.Equal(t, signatureValidationCommand, result.Commands[0].Command)
+		assert.Contains(t, result.Commands[0].Output, unsigned[:12]+" unsigned")
+	})
+}
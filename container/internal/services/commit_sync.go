@@ -13,6 +13,7 @@ import (
 	"github.com/vanpelt/catnip/internal/git"
 	"github.com/vanpelt/catnip/internal/logger"
 	"github.com/vanpelt/catnip/internal/models"
+	"github.com/vanpelt/catnip/internal/recovery"
 )
 
 // CommitSyncService monitors worktrees for commits and syncs them to the bare repository
@@ -24,6 +25,8 @@ type CommitSyncService struct {
 	mu           sync.RWMutex
 	stopChan     chan struct{}
 	running      bool
+	pollersMu    sync.Mutex
+	pollers      map[string]chan struct{} // worktree path -> closed to stop polling a worktree that can't be watched
 }
 
 // CommitInfo represents information about a detected commit
@@ -43,6 +46,7 @@ func NewCommitSyncService(gitService *GitService) *CommitSyncService {
 		operations:   git.NewOperations(),
 		syncInterval: 30 * time.Second, // Less aggressive - only syncing existing commits
 		stopChan:     make(chan struct{}),
+		pollers:      make(map[string]chan struct{}),
 	}
 }
 
@@ -53,6 +57,7 @@ func NewCommitSyncServiceWithOperations(gitService *GitService, operations git.O
 		operations:   operations,
 		syncInterval: 30 * time.Second, // Less aggressive - only syncing existing commits
 		stopChan:     make(chan struct{}),
+		pollers:      make(map[string]chan struct{}),
 	}
 }
 
@@ -61,7 +66,7 @@ func (css *CommitSyncService) findRepositoryForWorktree(worktreePath string) (*m
 	// Get all worktrees and find the one matching this path
 	worktrees := css.gitService.ListWorktrees()
 	for _, worktree := range worktrees {
-		if worktree.Path == worktreePath {
+		if config.SamePath(worktree.Path, worktreePath) {
 			// Get the repository for this worktree
 			status := css.gitService.GetStatus()
 			if repo, exists := status.Repositories[worktree.RepoID]; exists {
@@ -146,6 +151,34 @@ func (css *CommitSyncService) AddWorktreeWatcher(worktreePath string) {
 	css.addWorktreeWatcher(worktreePath)
 }
 
+// MoveWorktreeWatcher re-registers the watcher of a worktree that moved from oldPath to newPath
+func (css *CommitSyncService) MoveWorktreeWatcher(oldPath, newPath string) {
+	css.mu.RLock()
+	defer css.mu.RUnlock()
+
+	if !css.running || css.watcher == nil {
+		return
+	}
+
+	// The shared refs/catnip directory of the main repository stays watched
+	_ = css.watcher.Remove(filepath.Join(oldPath, ".git", "refs", "heads"))
+	css.stopPolling(oldPath)
+	fileWatches.release(WatchSubsystemCommitSync, oldPath)
+	css.addWorktreeWatcher(newPath)
+}
+
+// RemoveWorktreeWatcher stops watching or polling a worktree that is being deleted
+func (css *CommitSyncService) RemoveWorktreeWatcher(worktreePath string) {
+	css.mu.RLock()
+	defer css.mu.RUnlock()
+
+	if css.watcher != nil {
+		_ = css.watcher.Remove(filepath.Join(worktreePath, ".git", "refs", "heads"))
+	}
+	css.stopPolling(worktreePath)
+	fileWatches.release(WatchSubsystemCommitSync, worktreePath)
+}
+
 // addWorktreeWatcher adds a watcher for a specific worktree (internal)
 func (css *CommitSyncService) addWorktreeWatcher(worktreePath string) {
 	if css.watcher == nil {
@@ -155,7 +188,7 @@ func (css *CommitSyncService) addWorktreeWatcher(worktreePath string) {
 	// Skip worktrees that are outside our managed workspace directory or in temp directories
 	workspaceDir := config.Runtime.WorkspaceDir
 	isTemporaryPath := css.isTemporaryPath(worktreePath)
-	if workspaceDir != "" && !strings.HasPrefix(worktreePath, workspaceDir+"/") && !isTemporaryPath {
+	if workspaceDir != "" && !isWithinDir(workspaceDir, worktreePath) && !isTemporaryPath {
 		logger.Debugf("🚫 Skipping filesystem watcher for worktree outside WORKSPACE_DIR: %s", worktreePath)
 		return
 	}
@@ -206,14 +239,51 @@ func (css *CommitSyncService) addWorktreeWatcher(worktreePath string) {
 	}
 
 	for _, refsDir := range refsDirsToWatch {
-		if err := css.watcher.Add(refsDir); err != nil {
+		if err := fileWatches.add(WatchSubsystemCommitSync, worktreePath, css.watcher, refsDir); err != nil {
 			logger.Warnf("⚠️ Failed to watch refs directory %s: %v", refsDir, err)
+			if isWatchLimitError(err) {
+				fileWatches.degrade(WatchSubsystemCommitSync, worktreePath, WatchDegradedLimit, err)
+				css.startPolling(worktreePath)
+				return
+			}
 		} else {
 			logger.Debugf("👀 Watching refs directory: %s", refsDir)
 		}
 	}
 }
 
+// startPolling polls the branch of a worktree whose refs can't be watched for new commits
+func (css *CommitSyncService) startPolling(worktreePath string) {
+	css.pollersMu.Lock()
+	defer css.pollersMu.Unlock()
+	if _, polling := css.pollers[worktreePath]; polling {
+		return
+	}
+	stop := make(chan struct{})
+	css.pollers[worktreePath] = stop
+
+	fingerprint := func() string {
+		root := pathFingerprint(worktreePath)
+		if root == "" {
+			return ""
+		}
+		return root + statusFingerprint(worktreePath)
+	}
+	recovery.SafeGo("commit-sync-poller:"+worktreePath, func() {
+		pollForChanges(mergeStop(stop, css.stopChan), fingerprint, func() { css.handleWorktreeCommit(worktreePath) }, nil)
+	})
+}
+
+// stopPolling stops polling a worktree
+func (css *CommitSyncService) stopPolling(worktreePath string) {
+	css.pollersMu.Lock()
+	defer css.pollersMu.Unlock()
+	if stop, exists := css.pollers[worktreePath]; exists {
+		close(stop)
+		delete(css.pollers, worktreePath)
+	}
+}
+
 // monitorFilesystem monitors filesystem events for Git commits
 func (css *CommitSyncService) monitorFilesystem() {
 	for {
@@ -258,9 +328,19 @@ func (css *CommitSyncService) handleCommitEvent(event fsnotify.Event) {
 	if worktreePath == "" {
 		return
 	}
+	css.handleWorktreeCommit(worktreePath)
+}
 
+// handleWorktreeCommit syncs the latest commit of a worktree whose branch moved
+func (css *CommitSyncService) handleWorktreeCommit(worktreePath string) {
 	logger.Debugf("📝 Detected commit in worktree: %s", worktreePath)
 
+	// Commits of a worktree handed off to a human are picked up once it is handed back
+	if css.isHandedOff(worktreePath) {
+		css.invalidateStatus(worktreePath)
+		return
+	}
+
 	// Get commit information
 	commitInfo, err := css.getCommitInfo(worktreePath)
 	if err != nil {
@@ -274,6 +354,21 @@ func (css *CommitSyncService) handleCommitEvent(event fsnotify.Event) {
 	} else {
 		logger.Infof("✅ Synced commit %s to bare repository", commitInfo.CommitHash[:8])
 	}
+	css.invalidateStatus(worktreePath)
+}
+
+// isHandedOff reports whether the worktree at worktreePath was handed off to a human
+func (css *CommitSyncService) isHandedOff(worktreePath string) bool {
+	worktree, exists := css.gitService.stateManager.FindWorktreeByPath(worktreePath)
+	return exists && worktree.HandedOff != nil
+}
+
+// invalidateStatus drops the cached git status of a worktree whose branch moved, so it is
+// re-read rather than trusted until the status cache TTL runs out
+func (css *CommitSyncService) invalidateStatus(worktreePath string) {
+	if err := css.gitService.RefreshWorktreeStatus(worktreePath); err != nil {
+		logger.Debugf("🔍 Not refreshing status of %s: %v", worktreePath, err)
+	}
 }
 
 // extractWorktreePath extracts the worktree path from a Git refs file path
@@ -316,8 +411,8 @@ func (css *CommitSyncService) extractWorktreePath(refsPath string) string {
 	// Regular case: find .git directory and return path up to it
 	for i, part := range parts {
 		if part == ".git" && i > 0 {
-			// Return path up to but not including .git
-			return filepath.Join(parts[:i]...)
+			// Return path up to but not including .git, keeping it absolute
+			return strings.Join(parts[:i], string(filepath.Separator))
 		}
 	}
 
@@ -529,7 +624,7 @@ func (css *CommitSyncService) performPeriodicSync() {
 		// Exception: Allow temporary test paths (don't sync them, but don't log errors)
 		workspaceDir := config.Runtime.WorkspaceDir
 		isTemporaryPath := css.isTemporaryPath(worktree.Path)
-		if workspaceDir != "" && !strings.HasPrefix(worktree.Path, workspaceDir+"/") && !isTemporaryPath {
+		if workspaceDir != "" && !isWithinDir(workspaceDir, worktree.Path) && !isTemporaryPath {
 			logger.Debugf("🚫 Skipping commit sync for worktree outside WORKSPACE_DIR: %s", worktree.Path)
 			continue
 		}
@@ -539,6 +634,11 @@ func (css *CommitSyncService) performPeriodicSync() {
 			continue
 		}
 
+		// Leave worktrees a human took over alone, including their nice branches
+		if worktree.HandedOff != nil {
+			continue
+		}
+
 		// Only sync existing commits to bare repo (no auto-commits)
 		// Let the session-aware CheckpointManager handle creating commits
 		hasUnsynced := css.hasUnsyncedCommits(worktree.Path)
@@ -666,8 +766,13 @@ func (css *CommitSyncService) syncToNiceBranch(commitInfo *CommitInfo) error {
 	repo, err := css.findRepositoryForWorktree(commitInfo.WorktreePath)
 	if err == nil {
 		if strings.HasPrefix(repo.ID, "local/") {
-			// Push the nice branch to the catnip-live remote (which points to the main repo)
-			_, pushErr := css.operations.ExecuteGit(commitInfo.WorktreePath, "push", "catnip-live", fmt.Sprintf("%s:%s", niceBranch, niceBranch), "--force-with-lease")
+			// Push the nice branch to the catnip-live remote (which points to the main repo).
+			// Branches of protected repositories are only fast-forwarded.
+			pushArgs := []string{"push", "catnip-live", fmt.Sprintf("%s:%s", niceBranch, niceBranch)}
+			if !repo.Protected {
+				pushArgs = append(pushArgs, "--force-with-lease")
+			}
+			_, pushErr := css.operations.ExecuteGit(commitInfo.WorktreePath, pushArgs...)
 			if pushErr != nil {
 				logger.Warnf("⚠️ Failed to push nice branch to catnip-live remote: %v", pushErr)
 			} else {
@@ -743,6 +848,7 @@ func (css *CommitSyncService) syncFromNiceBranch(worktreePath string, customRef
 		}
 
 		logger.Infof("✅ Fast-forwarded %s to %s (%s)", customRef, niceBranchHash[:8], niceBranch)
+		css.invalidateStatus(worktreePath)
 		return nil
 	}
 
@@ -775,6 +881,7 @@ func (css *CommitSyncService) syncFromNiceBranch(worktreePath string, customRef
 			logger.Warnf("⚠️ Failed to switch back to original HEAD: %v", err)
 		}
 	}
+	css.invalidateStatus(worktreePath)
 
 	if mergeErr != nil {
 		logger.Warnf("⚠️ Failed to merge %s into %s: %v", niceBranch, customRef, mergeErr)
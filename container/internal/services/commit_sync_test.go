@@ -8,6 +8,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/models"
 )
 
 func TestCommitSyncService(t *testing.T) {
@@ -132,4 +133,21 @@ func TestCommitSyncServiceMethods(t *testing.T) {
 		assert.Error(t, err)
 		assert.Nil(t, repo)
 	})
+
+	t.Run("MixedCaseCommitEvent", func(t *testing.T) {
+		t.Setenv("CATNIP_CASE_INSENSITIVE_PATHS", "true")
+		stateManager := gitService.stateManager
+		require.NoError(t, stateManager.AddRepository(&models.Repository{ID: "local/repo", Path: "/live/repo", Available: true}))
+		require.NoError(t, stateManager.AddWorktree(&models.Worktree{
+			ID: "wt-felix", RepoID: "local/repo", Name: "repo/felix", Path: "/workspace/repo/felix", Branch: "main",
+		}))
+
+		// fsnotify reports paths as they were spelled when the watch was added
+		worktreePath := commitSync.extractWorktreePath("/Workspace/Repo/Felix/.git/refs/heads/main")
+		assert.Equal(t, "/Workspace/Repo/Felix", worktreePath)
+		repo, err := commitSync.findRepositoryForWorktree(worktreePath)
+		require.NoError(t, err)
+		assert.Equal(t, "local/repo", repo.ID)
+		assert.NoError(t, gitService.RefreshWorktreeStatus(worktreePath))
+	})
 }
@@ -0,0 +1,251 @@
+package services
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/vanpelt/catnip/internal/git"
+	"github.com/vanpelt/catnip/internal/logger"
+)
+
+// Per-repo git config keys controlling when a title change commits the previous work
+const (
+	commitTriggerMinDiffLinesKey  = "catnip.checkpoint.min-diff-lines"
+	commitTriggerMaxSimilarityKey = "catnip.checkpoint.title-similarity"
+)
+
+// CommitTriggerConfig decides whether a title change should commit work under the old title.
+// With a trigger enabled, a title change only commits when the uncommitted diff has at least
+// MinDiffLines changed lines or the titles are less similar than MaxTitleSimilarity (an
+// actual task switch). The defaults disable both triggers and commit on every title change.
+type CommitTriggerConfig struct {
+	MinDiffLines       int
+	MaxTitleSimilarity float64
+}
+
+// DefaultCommitTriggerConfig returns the trigger settings matching the original behavior
+func DefaultCommitTriggerConfig() CommitTriggerConfig {
+	return CommitTriggerConfig{
+		MinDiffLines:       0,
+		MaxTitleSimilarity: 1.0,
+	}
+}
+
+// enabled reports whether any trigger is configured
+func (c CommitTriggerConfig) enabled() bool {
+	return c.diffThresholdEnabled() || c.similarityEnabled()
+}
+
+// diffThresholdEnabled reports whether the diff size needs to be computed at all
+func (c CommitTriggerConfig) diffThresholdEnabled() bool {
+	return c.MinDiffLines > 0
+}
+
+// similarityEnabled reports whether a title similarity cutoff is configured
+func (c CommitTriggerConfig) similarityEnabled() bool {
+	return c.MaxTitleSimilarity < 1
+}
+
+// loadCommitTriggerConfig reads the trigger settings from the repository's git config,
+// keeping defaults for unset or invalid values
+func loadCommitTriggerConfig(getConfig func(key string) (string, error)) CommitTriggerConfig {
+	cfg := DefaultCommitTriggerConfig()
+
+	if value, err := getConfig(commitTriggerMinDiffLinesKey); err == nil && value != "" {
+		if lines, err := strconv.Atoi(value); err == nil && lines >= 0 {
+			cfg.MinDiffLines = lines
+		} else {
+			logger.Warnf("⚠️  Ignoring invalid %s value %q", commitTriggerMinDiffLinesKey, value)
+		}
+	}
+
+	if value, err := getConfig(commitTriggerMaxSimilarityKey); err == nil && value != "" {
+		if similarity, err := strconv.ParseFloat(value, 64); err == nil && similarity >= 0 && similarity <= 1 {
+			cfg.MaxTitleSimilarity = similarity
+		} else {
+			logger.Warnf("⚠️  Ignoring invalid %s value %q", commitTriggerMaxSimilarityKey, value)
+		}
+	}
+
+	return cfg
+}
+
+// titleSimilarity returns the normalized token overlap (Jaccard index) of two titles,
+// from 0 (no shared words) to 1 (same words)
+func titleSimilarity(a, b string) float64 {
+	tokensA := titleTokens(a)
+	tokensB := titleTokens(b)
+	if len(tokensA) == 0 && len(tokensB) == 0 {
+		return 1
+	}
+
+	shared := 0
+	for token := range tokensA {
+		if tokensB[token] {
+			shared++
+		}
+	}
+	union := len(tokensA) + len(tokensB) - shared
+	return float64(shared) / float64(union)
+}
+
+// titleTokens lowercases a title and splits it into a set of alphanumeric words
+func titleTokens(title string) map[string]bool {
+	tokens := make(map[string]bool)
+	for _, word := range strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		tokens[word] = true
+	}
+	return tokens
+}
+
+// uncommittedDiffLines counts lines changed since HEAD, including lines in untracked files.
+// An error means the size is unknown (e.g. no commits yet).
+func uncommittedDiffLines(operations git.Operations, workDir string) (int, error) {
+	output, err := operations.ExecuteGit(workDir, "diff", "--numstat", "HEAD")
+	if err != nil {
+		return 0, err
+	}
+
+	total := 0
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			continue
+		}
+		// Binary files report "-" and count as a single change
+		added, addErr := strconv.Atoi(fields[0])
+		deleted, delErr := strconv.Atoi(fields[1])
+		if addErr != nil || delErr != nil {
+			total++
+			continue
+		}
+		total += added + deleted
+	}
+
+	untracked, err := operations.ExecuteGit(workDir, "ls-files", "--others", "--exclude-standard")
+	if err != nil {
+		return total, nil
+	}
+	for _, file := range strings.Split(strings.TrimSpace(string(untracked)), "\n") {
+		if file == "" {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(workDir, file))
+		if err != nil {
+			total++
+			continue
+		}
+		total += bytes.Count(content, []byte("\n"))
+		if len(content) > 0 && content[len(content)-1] != '\n' {
+			total++
+		}
+	}
+
+	return total, nil
+}
+
+// diffFingerprintTTL is how long a worktree's diff fingerprint is reused while its HEAD and
+// index are unchanged, so a burst of title events costs a single git diff
+const diffFingerprintTTL = 2 * time.Second
+
+// diffFingerprints caches a fingerprint of each worktree's uncommitted work, used to tell
+// whether a title event marks new work regardless of the title text
+type diffFingerprints struct {
+	operations   git.Operations
+	stateManager *WorktreeStateManager
+	mu           sync.Mutex
+	entries      map[string]diffFingerprintEntry
+}
+
+type diffFingerprintEntry struct {
+	fingerprint string
+	status      string // statusFingerprint when computed, a commit or staging invalidates it
+	at          time.Time
+}
+
+func newDiffFingerprints(operations git.Operations, stateManager *WorktreeStateManager) *diffFingerprints {
+	return &diffFingerprints{
+		operations:   operations,
+		stateManager: stateManager,
+		entries:      make(map[string]diffFingerprintEntry),
+	}
+}
+
+// get returns the fingerprint of the uncommitted work in workDir: a hash of its diff stat
+// against HEAD and its untracked files. Empty means clean or unknown, in which case callers
+// fall back to comparing titles. Worktrees the status cache knows are clean cost no git calls.
+func (f *diffFingerprints) get(workDir string) string {
+	if f == nil {
+		return ""
+	}
+	if f.stateManager != nil {
+		if worktree, exists := f.stateManager.FindWorktreeByPath(workDir); exists && !worktree.IsDirty {
+			return ""
+		}
+	}
+
+	status := statusFingerprint(workDir)
+	f.mu.Lock()
+	entry, cached := f.entries[workDir]
+	f.mu.Unlock()
+	if cached && entry.status == status && time.Since(entry.at) < diffFingerprintTTL {
+		return entry.fingerprint
+	}
+
+	stat, err := f.operations.ExecuteGit(workDir, "diff", "--stat", "HEAD")
+	if err != nil {
+		return ""
+	}
+	untracked, err := f.operations.ExecuteGit(workDir, "ls-files", "--others", "--exclude-standard")
+	if err != nil {
+		return ""
+	}
+	fingerprint := ""
+	if len(bytes.TrimSpace(stat)) > 0 || len(bytes.TrimSpace(untracked)) > 0 {
+		sum := sha256.Sum256(append(append(stat, 0), untracked...))
+		fingerprint = hex.EncodeToString(sum[:8])
+	}
+
+	f.mu.Lock()
+	f.entries[workDir] = diffFingerprintEntry{fingerprint: fingerprint, status: status, at: time.Now()}
+	f.mu.Unlock()
+	return fingerprint
+}
+
+// forgetRemoved drops the fingerprints of worktree directories that no longer exist, returning
+// how many were dropped
+func (f *diffFingerprints) forgetRemoved() int {
+	if f == nil {
+		return 0
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	removed := 0
+	for workDir := range f.entries {
+		if pathRemoved(workDir) {
+			delete(f.entries, workDir)
+			removed++
+		}
+	}
+	return removed
+}
+
+// size returns the number of cached fingerprints
+func (f *diffFingerprints) size() int {
+	if f == nil {
+		return 0
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.entries)
+}
@@ -0,0 +1,138 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/git"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+func TestTitleSimilarity(t *testing.T) {
+	assert.Equal(t, 1.0, titleSimilarity("Fix login bug", "fix LOGIN bug!"))
+	assert.Equal(t, 0.0, titleSimilarity("Fix login bug", "Write API docs"))
+	assert.InDelta(t, 0.6, titleSimilarity("Fix login bug", "Fix login redirect bug handling"), 0.001)
+	assert.Equal(t, 1.0, titleSimilarity("", "  "))
+	assert.Equal(t, 0.0, titleSimilarity("", "Something"))
+}
+
+func TestLoadCommitTriggerConfig(t *testing.T) {
+	values := map[string]string{}
+	get := func(key string) (string, error) {
+		if value, ok := values[key]; ok {
+			return value, nil
+		}
+		return "", fmt.Errorf("key %s not set", key)
+	}
+
+	cfg := loadCommitTriggerConfig(get)
+	assert.Equal(t, DefaultCommitTriggerConfig(), cfg)
+	assert.False(t, cfg.enabled(), "defaults must commit on every title change")
+
+	values[commitTriggerMinDiffLinesKey] = "40"
+	values[commitTriggerMaxSimilarityKey] = "0.6"
+	cfg = loadCommitTriggerConfig(get)
+	assert.Equal(t, 40, cfg.MinDiffLines)
+	assert.Equal(t, 0.6, cfg.MaxTitleSimilarity)
+	assert.True(t, cfg.enabled())
+
+	values[commitTriggerMinDiffLinesKey] = "lots"
+	values[commitTriggerMaxSimilarityKey] = "1.5"
+	assert.Equal(t, DefaultCommitTriggerConfig(), loadCommitTriggerConfig(get))
+}
+
+func TestShouldCommitOnTitleChange(t *testing.T) {
+	repo := initTestRepo(t, t.TempDir(), map[string]string{"app.txt": "one\n"})
+	runGit := func(args ...string) {
+		runTestGit(t, repo, args...)
+	}
+
+	m := &WorktreeCheckpointManager{
+		workDir:    repo,
+		gitService: &GitService{operations: git.NewOperations()},
+	}
+
+	// Defaults keep the original behavior
+	assert.True(t, m.shouldCommitOnTitleChange("Fix login bug", "Fix the login bug"))
+
+	runGit("config", commitTriggerMinDiffLinesKey, "3")
+	runGit("config", commitTriggerMaxSimilarityKey, "0.5")
+
+	// Reworded title with a small diff keeps the work under the new title
+	require.NoError(t, os.WriteFile(filepath.Join(repo, "app.txt"), []byte("one\ntwo\n"), 0644))
+	assert.False(t, m.shouldCommitOnTitleChange("Fix login bug", "Fix the login bug"))
+
+	// An actual task switch commits regardless of diff size
+	assert.True(t, m.shouldCommitOnTitleChange("Fix login bug", "Write API docs"))
+
+	// Enough changed lines (including untracked files) commits a reworded title
+	require.NoError(t, os.WriteFile(filepath.Join(repo, "notes.md"), []byte("a\nb\n"), 0644))
+	assert.True(t, m.shouldCommitOnTitleChange("Fix login bug", "Fix the login bug"))
+}
+
+func TestRepeatedTitlesFollowContent(t *testing.T) {
+	workDir := initTestRepo(t, t.TempDir(), map[string]string{"app.txt": "one\n"})
+	commitSubjects := func() []string {
+		return strings.Split(strings.TrimSpace(runTestGit(t, workDir, "log", "--format=%s")), "\n")
+	}
+
+	gitService := newTestGitService(t, t.TempDir())
+	stateManager := gitService.stateManager
+	require.NoError(t, stateManager.AddRepository(&models.Repository{ID: "local/repo", Path: workDir}))
+	require.NoError(t, stateManager.AddWorktree(&models.Worktree{ID: "wt-felix", RepoID: "local/repo", Name: "repo/felix", Path: workDir, Branch: "main", IsDirty: true}))
+	sessions := &SessionService{stateDir: t.TempDir(), activeSessions: map[string]*ActiveSessionInfo{workDir: {}}}
+	claude := &ClaudeService{claudeConfigPath: filepath.Join(t.TempDir(), ".claude.json")}
+	monitor := NewClaudeMonitorService(gitService, sessions, claude, stateManager)
+	monitor.checkpointManagers[workDir] = &WorktreeCheckpointManager{
+		workDir:           workDir,
+		worktreeID:        "wt-felix",
+		checkpointManager: git.NewSessionCheckpointManager(workDir, &countingCheckpointGit{}, noopCheckpointSessions{}),
+		gitService:        gitService,
+		sessionService:    sessions,
+		stateManager:      stateManager,
+		clock:             &fakeClock{wall: time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC)},
+		diffFingerprints:  monitor.diffFingerprints,
+	}
+	edit := func(content string) {
+		require.NoError(t, os.WriteFile(filepath.Join(workDir, "app.txt"), []byte(content), 0644))
+		// Outlive the fingerprint cache, the index doesn't change while editing
+		monitor.diffFingerprints.entries = make(map[string]diffFingerprintEntry)
+	}
+
+	edit("one\ntwo\n")
+	monitor.handleTitleChange(workDir, "Running tests", "log")
+	assert.Len(t, commitSubjects(), 1)
+
+	t.Run("RepeatedTitleWithoutChangesIsSkipped", func(t *testing.T) {
+		monitor.handleTitleChange(workDir, "Running tests", "log")
+		monitor.handleTitleChange(workDir, "Running tests", "pty")
+		assert.Len(t, commitSubjects(), 1)
+	})
+
+	t.Run("RepeatedTitleWithNewWorkCommits", func(t *testing.T) {
+		edit("one\ntwo\nthree\n")
+		monitor.handleTitleChange(workDir, "Running tests", "log")
+		assert.Equal(t, []string{"Running tests", "Initial commit"}, commitSubjects())
+	})
+
+	t.Run("RewordedTitleWithoutNewWorkIsSkipped", func(t *testing.T) {
+		edit("one\ntwo\nthree\nfour\n")
+		monitor.handleTitleChange(workDir, "Running the tests", "log")
+		assert.Len(t, commitSubjects(), 3, "new work under a new title commits")
+
+		// Work carried over by the commit triggers isn't committed by a mere rewording
+		runTestGit(t, workDir, "config", commitTriggerMaxSimilarityKey, "0.3")
+		edit("one\ntwo\nthree\nfour\nfive\n")
+		monitor.handleTitleChange(workDir, "Running all the tests", "log")
+		assert.Len(t, commitSubjects(), 3)
+		runTestGit(t, workDir, "config", "--unset", commitTriggerMaxSimilarityKey)
+		monitor.handleTitleChange(workDir, "Run the tests", "log")
+		assert.Len(t, commitSubjects(), 3)
+	})
+}
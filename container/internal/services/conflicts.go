@@ -0,0 +1,114 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// syncOperationInProgress returns the operation a sync or cherry-pick left in progress in a
+// worktree, rebase, merge or cherry-pick, or empty when there is none
+func (s *GitService) syncOperationInProgress(worktreePath string) string {
+	switch {
+	case s.gitStateFileExists(worktreePath, "rebase-merge"), s.gitStateFileExists(worktreePath, "rebase-apply"):
+		return "rebase"
+	case s.gitStateFileExists(worktreePath, "MERGE_HEAD"):
+		return "merge"
+	case s.gitStateFileExists(worktreePath, "CHERRY_PICK_HEAD"):
+		return "cherry-pick"
+	}
+	return ""
+}
+
+// GetWorktreeConflicts returns the files a merge, rebase or cherry-pick left conflicted in a
+// worktree, with their base, ours and theirs versions
+func (s *GitService) GetWorktreeConflicts(worktreeID string) (*models.WorktreeConflicts, error) {
+	s.mu.RLock()
+	worktree, exists := s.stateManager.GetWorktree(worktreeID)
+	s.mu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("worktree %s not found", worktreeID)
+	}
+
+	files, err := s.conflictResolver.GetConflictDetails(worktree.Path)
+	if err != nil {
+		return nil, err
+	}
+	return &models.WorktreeConflicts{
+		WorktreeID: worktree.ID,
+		Operation:  s.syncOperationInProgress(worktree.Path),
+		Files:      files,
+	}, nil
+}
+
+// ResolveWorktreeConflict resolves one conflicted file of a worktree and returns the conflicts
+// left to resolve
+func (s *GitService) ResolveWorktreeConflict(worktreeID, file string, resolution models.ConflictResolution) (*models.WorktreeConflicts, error) {
+	s.mu.RLock()
+	worktree, exists := s.stateManager.GetWorktree(worktreeID)
+	s.mu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("worktree %s not found", worktreeID)
+	}
+
+	if err := s.conflictResolver.ResolveConflictFile(worktree.Path, file, resolution); err != nil {
+		return nil, err
+	}
+	logger.Infof("🩹 Resolved conflict in %s of %s with %s", file, worktree.Name, resolution.Resolution)
+	return s.GetWorktreeConflicts(worktreeID)
+}
+
+// ContinueSync concludes the merge, rebase or cherry-pick left in progress once its conflicts
+// are resolved. A rebase stopping at the next conflicting commit returns a MergeConflictError.
+func (s *GitService) ContinueSync(worktreeID string) error {
+	return s.finishSync(worktreeID, "continue")
+}
+
+// AbortSync abandons the merge or rebase a sync left in progress, returning the worktree to
+// where it was before the sync
+func (s *GitService) AbortSync(worktreeID string) error {
+	return s.finishSync(worktreeID, "abort")
+}
+
+// finishSync runs merge, rebase or cherry-pick with --continue or --abort in a worktree and
+// refreshes its status
+func (s *GitService) finishSync(worktreeID, action string) error {
+	s.mu.RLock()
+	worktree, exists := s.stateManager.GetWorktree(worktreeID)
+	s.mu.RUnlock()
+
+	if !exists {
+		return fmt.Errorf("worktree %s not found", worktreeID)
+	}
+	operation := s.syncOperationInProgress(worktree.Path)
+	if operation == "" {
+		return fmt.Errorf("no merge, rebase or cherry-pick in progress in worktree %s", worktree.Name)
+	}
+	if action == "continue" {
+		if conflicted, _ := s.conflictResolver.GetConflictedFiles(worktree.Path); len(conflicted) > 0 {
+			return fmt.Errorf("cannot continue the %s of %s, %d files are still conflicted", operation, worktree.Name, len(conflicted))
+		}
+	}
+
+	// Nobody is there to edit the commit message, keep git's
+	_, err := s.operations.ExecuteGit(worktree.Path, "-c", "core.editor=true", operation, "--"+action)
+	if s.worktreeCache != nil {
+		s.worktreeCache.ForceRefresh(worktreeID)
+	}
+	if err != nil {
+		if action == "continue" && s.isMergeConflict(worktree.Path, err.Error()) {
+			return s.createMergeConflictError("sync", worktree, err.Error())
+		}
+		return fmt.Errorf("failed to %s the %s of %s: %v", action, operation, worktree.Name, err)
+	}
+
+	if action == "continue" {
+		logger.Infof("✅ Continued %s of worktree %s", operation, worktree.Name)
+	} else {
+		logger.Infof("↩️ Aborted %s of worktree %s", operation, worktree.Name)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package services
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/git"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+func TestSyncConflictResolution(t *testing.T) {
+	s, _, repoPath, worktreePath := newRecreateTestService(t)
+	s.conflictResolver = git.NewConflictResolver(s.operations)
+
+	commit := func(dir, file, content, subject string) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, file), []byte(content), 0644))
+		runTestGit(t, dir, "add", file)
+		runTestGit(t, dir, "commit", "-m", subject)
+	}
+	commit(worktreePath, "app.txt", "feature\n", "feature work")
+	commit(worktreePath, "notes.txt", "ours\n", "feature notes")
+	commit(repoPath, "app.txt", "upstream\n", "upstream work")
+	commit(repoPath, "notes.txt", "theirs\n", "upstream notes")
+
+	_, err := s.SyncWorktree("wt-felix", SyncOptions{Strategy: "merge"})
+	var conflictErr *models.MergeConflictError
+	require.True(t, errors.As(err, &conflictErr), "got %v", err)
+
+	conflicts, err := s.GetWorktreeConflicts("wt-felix")
+	require.NoError(t, err)
+	assert.Equal(t, "merge", conflicts.Operation)
+	require.Len(t, conflicts.Files, 2)
+	app := conflicts.Files[0]
+	assert.Equal(t, "app.txt", app.Path)
+	assert.Equal(t, "UU", app.Status)
+	assert.Equal(t, "v1\n", app.Base.Content)
+	assert.Equal(t, "feature\n", app.Ours.Content)
+	assert.Equal(t, "upstream\n", app.Theirs.Content)
+	assert.Equal(t, "100644", app.Ours.Mode)
+	assert.Equal(t, int64(9), app.Theirs.Size)
+	assert.Equal(t, "AA", conflicts.Files[1].Status)
+	assert.Nil(t, conflicts.Files[1].Base, "added on both sides")
+
+	assert.ErrorContains(t, s.ContinueSync("wt-felix"), "2 files are still conflicted")
+	_, err = s.ResolveWorktreeConflict("wt-felix", "app.txt", models.ConflictResolution{Resolution: "both"})
+	assert.ErrorContains(t, err, "unknown conflict resolution")
+	_, err = s.ResolveWorktreeConflict("wt-felix", "missing.txt", models.ConflictResolution{Resolution: models.ConflictResolutionOurs})
+	assert.ErrorContains(t, err, "not conflicted")
+
+	conflicts, err = s.ResolveWorktreeConflict("wt-felix", "app.txt", models.ConflictResolution{Resolution: models.ConflictResolutionTheirs})
+	require.NoError(t, err)
+	require.Len(t, conflicts.Files, 1)
+	conflicts, err = s.ResolveWorktreeConflict("wt-felix", "notes.txt", models.ConflictResolution{
+		Resolution: models.ConflictResolutionContent,
+		Content:    "ours\ntheirs\n",
+	})
+	require.NoError(t, err)
+	assert.Empty(t, conflicts.Files)
+
+	require.NoError(t, s.ContinueSync("wt-felix"))
+	assert.Equal(t, "upstream", runTestGit(t, worktreePath, "show", "HEAD:app.txt"))
+	assert.Equal(t, "ours\ntheirs", runTestGit(t, worktreePath, "show", "HEAD:notes.txt"))
+	assert.NotEmpty(t, runTestGit(t, worktreePath, "rev-parse", "HEAD^2"), "the merge is committed")
+	assert.ErrorContains(t, s.ContinueSync("wt-felix"), "no merge, rebase or cherry-pick in progress")
+
+	t.Run("AbortRebase", func(t *testing.T) {
+		commit(worktreePath, "app.txt", "feature again\n", "more feature work")
+		commit(repoPath, "app.txt", "upstream again\n", "more upstream work")
+		head := runTestGit(t, worktreePath, "rev-parse", "HEAD")
+
+		_, err := s.SyncWorktree("wt-felix", SyncOptions{Strategy: "rebase"})
+		require.True(t, errors.As(err, &conflictErr), "got %v", err)
+		conflicts, err := s.GetWorktreeConflicts("wt-felix")
+		require.NoError(t, err)
+		assert.Equal(t, "rebase", conflicts.Operation)
+		require.Len(t, conflicts.Files, 1)
+		assert.Equal(t, "upstream again\n", conflicts.Files[0].Ours.Content, "ours is the branch rebased onto")
+
+		require.NoError(t, s.AbortSync("wt-felix"))
+		assert.Equal(t, head, runTestGit(t, worktreePath, "rev-parse", "HEAD"))
+		conflicts, err = s.GetWorktreeConflicts("wt-felix")
+		require.NoError(t, err)
+		assert.Empty(t, conflicts.Operation)
+		assert.Empty(t, conflicts.Files)
+	})
+}
@@ -0,0 +1,597 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/vanpelt/catnip/internal/git"
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+	"github.com/vanpelt/catnip/internal/redact"
+)
+
+const (
+	// DefaultContextPackMaxTokens is the token budget of context packs unless configured
+	DefaultContextPackMaxTokens = 32000
+	// minContextPackTokens is the smallest budget a pack can be capped to
+	minContextPackTokens = 2000
+	// contextPackDir and contextPackFile are where a pack's markdown is written in the worktree
+	contextPackDir  = ".catnip"
+	contextPackFile = "context.md"
+	// bytesPerToken estimates tokens from bytes, close enough for English and code
+	bytesPerToken = 4
+)
+
+// Shares of a context pack's budget in percent. The rest is left for the JSON structure.
+const (
+	contextPackLayoutShare     = 5
+	contextPackSessionShare    = 10
+	contextPackValidationShare = 15
+	contextPackReviewShare     = 15
+	contextPackDiffShare       = 45
+)
+
+// ContextPackOptions configures GenerateContextPack
+type ContextPackOptions struct {
+	// Token budget, 0 for CATNIP_CONTEXT_PACK_MAX_TOKENS or DefaultContextPackMaxTokens
+	MaxTokens int
+	// Render the pack as markdown too
+	Markdown bool
+	// Write the markdown to .catnip/context.md in the worktree, for tools that read from the
+	// tree; implies Markdown
+	Write bool
+}
+
+// getContextPackMaxTokens returns the default token budget of context packs, from
+// CATNIP_CONTEXT_PACK_MAX_TOKENS
+func getContextPackMaxTokens() int {
+	if value, err := strconv.Atoi(os.Getenv("CATNIP_CONTEXT_PACK_MAX_TOKENS")); err == nil && value > 0 {
+		return value
+	}
+	return DefaultContextPackMaxTokens
+}
+
+// estimateTokens estimates the tokens of text
+func estimateTokens(text string) int {
+	return (len(text) + bytesPerToken - 1) / bytesPerToken
+}
+
+// truncateText cuts text to about tokens, at a line boundary when one is close, keeping its end
+// instead of its start when keepEnd is set. The cut is marked with how many lines were dropped.
+func truncateText(text string, tokens int, keepEnd bool) (string, bool) {
+	limit := tokens * bytesPerToken
+	if len(text) <= limit {
+		return text, false
+	}
+	if limit < 0 {
+		limit = 0
+	}
+
+	var kept, dropped string
+	if keepEnd {
+		kept = text[len(text)-limit:]
+		if i := strings.IndexByte(kept, '\n'); i >= 0 && i < len(kept)/2 {
+			kept = kept[i+1:]
+		}
+		for len(kept) > 0 && !utf8.RuneStart(kept[0]) {
+			kept = kept[1:]
+		}
+		dropped = text[:len(text)-len(kept)]
+	} else {
+		kept = text[:limit]
+		if i := strings.LastIndexByte(kept, '\n'); i >= len(kept)/2 {
+			kept = kept[:i+1]
+		}
+		for len(kept) > 0 && !utf8.ValidString(kept) {
+			kept = kept[:len(kept)-1]
+		}
+		dropped = text[len(kept):]
+	}
+
+	marker := fmt.Sprintf("[… %s truncated]", plural(strings.Count(strings.TrimSuffix(dropped, "\n"), "\n")+1, "line"))
+	if keepEnd {
+		return marker + "\n" + kept, true
+	}
+	if !strings.HasSuffix(kept, "\n") && kept != "" {
+		kept += "\n"
+	}
+	return kept + marker + "\n", true
+}
+
+// GenerateContextPack describes the state of a worktree's session in one artifact, to move the
+// session to another tool: the repository layout, the diff against the source branch, the todo
+// list, session titles and latest prompt, the output of a failed validation and the unresolved
+// review threads of its pull request. Each section is capped to a share of the token budget with
+// truncation markers, binary and generated files are listed without their diff. The diff comes
+// from the diff cache and the rest from state, only review threads are asked from GitHub.
+func (s *GitService) GenerateContextPack(worktreeID string, opts ContextPackOptions) (*models.WorktreeContextPack, error) {
+	worktree, exists := s.stateManager.GetWorktree(worktreeID)
+	if !exists {
+		return nil, fmt.Errorf("worktree %s not found", worktreeID)
+	}
+	if opts.MaxTokens == 0 {
+		opts.MaxTokens = getContextPackMaxTokens()
+	}
+	if opts.MaxTokens < minContextPackTokens {
+		return nil, fmt.Errorf("invalid token budget %d, expected at least %d", opts.MaxTokens, minContextPackTokens)
+	}
+
+	pack := &models.WorktreeContextPack{
+		WorktreeID:      worktree.ID,
+		WorktreeName:    worktree.Name,
+		Repository:      worktree.RepoID,
+		Branch:          worktree.Branch,
+		SourceBranch:    worktree.SourceBranch,
+		CommitHash:      worktree.CommitHash,
+		GeneratedAt:     time.Now(),
+		MaxTokens:       opts.MaxTokens,
+		CreationContext: worktree.CreationContext,
+		Diff:            models.ContextPackDiff{Files: []models.ContextPackFile{}},
+	}
+	if output, err := s.operations.ExecuteGit(worktree.Path, "rev-parse", "HEAD"); err == nil {
+		pack.CommitHash = strings.TrimSpace(string(output))
+	}
+	share := func(percent int) int { return opts.MaxTokens * percent / 100 }
+
+	s.contextPackLayout(worktree, pack, share(contextPackLayoutShare))
+	s.contextPackSession(worktree, pack, share(contextPackSessionShare))
+	s.contextPackValidation(worktree, pack, share(contextPackValidationShare))
+	s.contextPackPullRequest(worktree, pack, share(contextPackReviewShare))
+	diff, err := s.GetWorktreeDiff(worktreeID)
+	if err != nil {
+		pack.Notes = append(pack.Notes, fmt.Sprintf("Diff unavailable: %v", err))
+	} else {
+		s.contextPackDiff(diff, pack, share(contextPackDiffShare))
+	}
+	fitContextPack(pack)
+
+	// Packs are handed to other tools and written into the worktree
+	if err := redact.Default().Into(pack); err != nil {
+		return nil, fmt.Errorf("failed to redact context pack: %v", err)
+	}
+	if opts.Markdown || opts.Write {
+		pack.Markdown = renderContextPackMarkdown(pack)
+	}
+	if opts.Write {
+		path, err := s.writeContextPack(worktree, pack.Markdown)
+		if err != nil {
+			return nil, err
+		}
+		pack.WrittenTo = path
+		logger.Infof("📦 Wrote context pack of %s to %s (~%d tokens)", worktree.Name, path, pack.EstimatedTokens)
+	}
+	return pack, nil
+}
+
+// addTruncated records that a section of a pack was cut short, once
+func addTruncated(pack *models.WorktreeContextPack, section string) {
+	for _, existing := range pack.Truncated {
+		if existing == section {
+			return
+		}
+	}
+	pack.Truncated = append(pack.Truncated, section)
+}
+
+// contextPackLayout counts the tracked files under each top-level entry of the worktree
+func (s *GitService) contextPackLayout(worktree *models.Worktree, pack *models.WorktreeContextPack, budget int) {
+	pack.Layout = models.ContextPackLayout{Entries: []models.ContextPackLayoutEntry{}, Toolchains: worktree.Toolchains}
+	output, err := s.operations.ExecuteGit(worktree.Path, "ls-files", "-z")
+	if err != nil {
+		pack.Notes = append(pack.Notes, fmt.Sprintf("Layout unavailable: %v", err))
+		return
+	}
+
+	counts := make(map[string]int)
+	for _, path := range strings.Split(string(output), "\x00") {
+		if path == "" {
+			continue
+		}
+		pack.Layout.Files++
+		if top, _, nested := strings.Cut(path, "/"); nested {
+			counts[top+"/"]++
+		} else {
+			counts[top]++
+		}
+	}
+	for path, files := range counts {
+		pack.Layout.Entries = append(pack.Layout.Entries, models.ContextPackLayoutEntry{Path: path, Files: files})
+	}
+	sort.Slice(pack.Layout.Entries, func(i, j int) bool {
+		a, b := pack.Layout.Entries[i], pack.Layout.Entries[j]
+		if a.Files != b.Files {
+			return a.Files > b.Files
+		}
+		return a.Path < b.Path
+	})
+
+	used := 0
+	for i, entry := range pack.Layout.Entries {
+		if used += estimateTokens(entry.Path) + 8; used > budget {
+			pack.Layout.Entries = pack.Layout.Entries[:i]
+			addTruncated(pack, "layout")
+			break
+		}
+	}
+}
+
+// contextPackSession adds the latest prompt, the session titles and the todos, newest titles
+// first when they don't all fit
+func (s *GitService) contextPackSession(worktree *models.Worktree, pack *models.WorktreeContextPack, budget int) {
+	var truncated bool
+	pack.LatestPrompt, truncated = truncateText(worktree.LatestUserPrompt, budget/3, false)
+	if truncated {
+		addTruncated(pack, "session")
+	}
+	used := estimateTokens(pack.LatestPrompt)
+
+	pack.Todos = []models.Todo{}
+	for _, todo := range worktree.Todos {
+		if used += estimateTokens(todo.Content) + 10; used > budget {
+			addTruncated(pack, "session")
+			break
+		}
+		pack.Todos = append(pack.Todos, todo)
+	}
+
+	history := worktree.SessionTitleHistory
+	if len(history) == 0 && worktree.SessionTitle != nil {
+		history = []models.TitleEntry{*worktree.SessionTitle}
+	}
+	first := len(history)
+	for first > 0 {
+		if used += estimateTokens(history[first-1].Title) + 10; used > budget {
+			addTruncated(pack, "session")
+			break
+		}
+		first--
+	}
+	pack.Titles = append([]models.TitleEntry{}, history[first:]...)
+}
+
+// contextPackValidation adds the last validation run when it failed, keeping the end of the
+// output of the commands that failed
+func (s *GitService) contextPackValidation(worktree *models.Worktree, pack *models.WorktreeContextPack, budget int) {
+	if worktree.Validation == nil || worktree.Validation.Status != models.ValidationFailed {
+		return
+	}
+	validation := *worktree.Validation
+	validation.Commands = make([]models.ValidationCommandResult, len(worktree.Validation.Commands))
+	failed := 0
+	for _, command := range worktree.Validation.Commands {
+		if command.ExitCode != 0 || command.TimedOut {
+			failed++
+		}
+	}
+	for i, command := range worktree.Validation.Commands {
+		if command.ExitCode == 0 && !command.TimedOut {
+			command.Output = ""
+		} else {
+			var truncated bool
+			if command.Output, truncated = truncateText(command.Output, budget/failed, true); truncated {
+				addTruncated(pack, "validation")
+			}
+		}
+		validation.Commands[i] = command
+	}
+	pack.FailedValidation = &validation
+}
+
+// contextPackPullRequest adds the worktree's pull request as last synced and its unresolved
+// review threads, unless the GitHub rate limit is reserved for urgent operations
+func (s *GitService) contextPackPullRequest(worktree *models.Worktree, pack *models.WorktreeContextPack, budget int) {
+	if worktree.PullRequestURL == "" {
+		return
+	}
+	pr := &models.ContextPackPullRequest{URL: worktree.PullRequestURL, Title: worktree.PullRequestTitle}
+	pack.PullRequest = pr
+	repoID, number, ok := parsePullRequestURL(worktree.PullRequestURL)
+	if !ok {
+		return
+	}
+	if state := GetPRSyncManager(nil).GetPRState(repoID, number); state != nil {
+		pr.State, pr.ChecksState = state.State, state.ChecksState
+		if state.Title != "" {
+			pr.Title = state.Title
+		}
+	}
+	if pr.State == "MERGED" || pr.State == "CLOSED" {
+		return
+	}
+	if s.githubManager == nil {
+		return
+	}
+	if s.rateLimits != nil && s.rateLimits.ReadyAt(git.RateLimitGraphQL, false).After(time.Now()) {
+		pack.Notes = append(pack.Notes, "Review threads left out, the GitHub rate limit is low")
+		return
+	}
+	threads, err := s.githubManager.ListReviewThreads(repoID, number)
+	if err != nil {
+		pack.Notes = append(pack.Notes, fmt.Sprintf("Review threads unavailable: %v", err))
+		return
+	}
+
+	comments := 0
+	for _, thread := range threads {
+		comments += len(thread.Comments)
+	}
+	used := 0
+	for i, thread := range threads {
+		kept := thread
+		kept.Comments = make([]models.ReviewComment, len(thread.Comments))
+		for j, comment := range thread.Comments {
+			var truncated bool
+			if comment.Body, truncated = truncateText(comment.Body, max(budget/comments, 50), false); truncated {
+				addTruncated(pack, "review")
+			}
+			kept.Comments[j] = comment
+			used += estimateTokens(comment.Body) + estimateTokens(comment.Author+comment.URL) + 10
+		}
+		if used += estimateTokens(thread.Path) + 10; used > budget && i > 0 {
+			pack.Notes = append(pack.Notes, fmt.Sprintf("%s left out to fit the budget", plural(len(threads)-i, "review thread")))
+			addTruncated(pack, "review")
+			break
+		}
+		pr.ReviewThreads = append(pr.ReviewThreads, kept)
+	}
+}
+
+// contextPackDiff adds the cached diff of the worktree, sharing the budget between the text files
+// and listing binary and generated files without their diff
+func (s *GitService) contextPackDiff(diff *git.WorktreeDiffResponse, pack *models.WorktreeContextPack, budget int) {
+	pack.Diff.Summary = diff.Summary
+	pack.Diff.TotalFiles = diff.TotalFiles
+	pack.Diff.TotalAdditions = diff.TotalAdditions
+	pack.Diff.TotalDeletions = diff.TotalDeletions
+
+	text := 0
+	for _, file := range diff.FileDiffs {
+		if !file.Binary && !file.Generated {
+			text++
+		}
+	}
+	perFile := budget
+	if text > 0 {
+		perFile = max(budget/text, 256)
+	}
+	remaining := budget
+	for _, file := range diff.FileDiffs {
+		entry := models.ContextPackFile{
+			Path:       file.FilePath,
+			ChangeType: file.ChangeType,
+			Additions:  file.Additions,
+			Deletions:  file.Deletions,
+		}
+		switch {
+		case file.Binary:
+			entry.Omitted = "binary"
+		case file.Generated:
+			entry.Omitted = "generated"
+		case remaining < 64:
+			entry.Omitted = "budget"
+			addTruncated(pack, "diff")
+		default:
+			entry.Diff, entry.Truncated = truncateText(file.DiffText, min(perFile, remaining), false)
+			if entry.Truncated {
+				addTruncated(pack, "diff")
+			}
+			remaining -= estimateTokens(entry.Diff) + estimateTokens(entry.Path) + 10
+		}
+		pack.Diff.Files = append(pack.Diff.Files, entry)
+	}
+}
+
+// fitContextPack estimates the tokens of a pack and drops the largest file diffs while the
+// overhead of the JSON structure pushes it over budget
+func fitContextPack(pack *models.WorktreeContextPack) {
+	for {
+		encoded, _ := json.Marshal(pack)
+		pack.EstimatedTokens = estimateTokens(string(encoded))
+		if pack.EstimatedTokens <= pack.MaxTokens {
+			return
+		}
+		largest := -1
+		for i, file := range pack.Diff.Files {
+			if file.Diff != "" && (largest < 0 || len(file.Diff) > len(pack.Diff.Files[largest].Diff)) {
+				largest = i
+			}
+		}
+		if largest < 0 {
+			return
+		}
+		pack.Diff.Files[largest].Diff, pack.Diff.Files[largest].Truncated = "", false
+		pack.Diff.Files[largest].Omitted = "budget"
+		addTruncated(pack, "diff")
+	}
+}
+
+// writeContextPack writes the markdown of a pack to .catnip/context.md in the worktree. The
+// directory ignores itself with a .gitignore of its own, so the file is never committed without
+// touching the repository's ignore files, which for local repositories are the user's.
+func (s *GitService) writeContextPack(worktree *models.Worktree, markdown string) (string, error) {
+	dir := filepath.Join(worktree.Path, contextPackDir)
+	path := filepath.Join(dir, contextPackFile)
+	ignore := filepath.Join(dir, ".gitignore")
+	// A symlink committed in the branch mustn't redirect the write outside the worktree
+	for _, p := range []string{dir, path, ignore} {
+		if info, err := os.Lstat(p); err == nil && info.Mode()&os.ModeSymlink != 0 {
+			return "", fmt.Errorf("refusing to write the context pack through the symlink %s", p)
+		}
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create %s: %v", dir, err)
+	}
+	if err := appendIgnorePattern(ignore, "", "*"); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, []byte(markdown), 0644); err != nil {
+		return "", fmt.Errorf("failed to write the context pack: %v", err)
+	}
+	return path, nil
+}
+
+// codeFence returns a fence for content that doesn't close early on fences inside it
+func codeFence(content string) string {
+	fence := "```"
+	for strings.Contains(content, fence) {
+		fence += "`"
+	}
+	return fence
+}
+
+// writeCodeBlock writes content as a fenced code block
+func writeCodeBlock(b *strings.Builder, lang, content string) {
+	fence := codeFence(content)
+	fmt.Fprintf(b, "%s%s\n%s", fence, lang, content)
+	if !strings.HasSuffix(content, "\n") {
+		b.WriteString("\n")
+	}
+	b.WriteString(fence + "\n")
+}
+
+// renderContextPackMarkdown renders a pack for tools and people reading markdown
+func renderContextPackMarkdown(pack *models.WorktreeContextPack) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Context: %s\n\n", pack.WorktreeName)
+	fmt.Fprintf(&b, "Repository **%s**, branch `%s` from `%s`", pack.Repository, pack.Branch, pack.SourceBranch)
+	if pack.CommitHash != "" {
+		fmt.Fprintf(&b, " at `%s`", shortCommit(pack.CommitHash))
+	}
+	fmt.Fprintf(&b, ". Generated %s UTC.\n", pack.GeneratedAt.UTC().Format("Jan 2 15:04"))
+	if ctx := pack.CreationContext; ctx != nil && (ctx.Prompt != "" || ctx.Issue != "") {
+		b.WriteString("\n## Goal\n\n")
+		if ctx.Prompt != "" {
+			b.WriteString(ctx.Prompt + "\n")
+		}
+		if ctx.Issue != "" {
+			fmt.Fprintf(&b, "\nIssue: %s\n", ctx.Issue)
+		}
+	}
+
+	b.WriteString("\n## Layout\n\n")
+	fmt.Fprintf(&b, "%s tracked.\n\n", plural(pack.Layout.Files, "file"))
+	for _, entry := range pack.Layout.Entries {
+		fmt.Fprintf(&b, "- `%s` %s\n", entry.Path, plural(entry.Files, "file"))
+	}
+	if len(pack.Layout.Toolchains) > 0 {
+		names := make([]string, len(pack.Layout.Toolchains))
+		for i, toolchain := range pack.Layout.Toolchains {
+			names[i] = toolchain.Name
+			if toolchain.PackageManager != "" {
+				names[i] += " (" + toolchain.PackageManager + ")"
+			}
+		}
+		fmt.Fprintf(&b, "\nToolchains: %s\n", strings.Join(names, ", "))
+	}
+
+	if pack.LatestPrompt != "" || len(pack.Titles) > 0 || len(pack.Todos) > 0 {
+		b.WriteString("\n## Session\n")
+		if pack.LatestPrompt != "" {
+			b.WriteString("\nLatest prompt:\n\n")
+			for _, line := range strings.Split(strings.TrimRight(pack.LatestPrompt, "\n"), "\n") {
+				b.WriteString(strings.TrimRight("> "+line, " ") + "\n")
+			}
+		}
+		if len(pack.Titles) > 0 {
+			b.WriteString("\nTitles:\n\n")
+			for _, title := range pack.Titles {
+				fmt.Fprintf(&b, "- %s %s\n", title.Timestamp.UTC().Format("Jan 2 15:04"), title.Title)
+			}
+		}
+		if len(pack.Todos) > 0 {
+			b.WriteString("\nTodos:\n\n")
+			for _, todo := range pack.Todos {
+				box := "[ ]"
+				switch todo.Status {
+				case "completed":
+					box = "[x]"
+				case "in_progress":
+					box = "[~]"
+				}
+				fmt.Fprintf(&b, "- %s %s\n", box, todo.Content)
+			}
+		}
+	}
+
+	if validation := pack.FailedValidation; validation != nil {
+		b.WriteString("\n## Failing validation\n")
+		for _, command := range validation.Commands {
+			if command.Output == "" {
+				continue
+			}
+			outcome := fmt.Sprintf("exited %d", command.ExitCode)
+			if command.TimedOut {
+				outcome = "timed out"
+			}
+			fmt.Fprintf(&b, "\n`%s` %s:\n\n", command.Command, outcome)
+			writeCodeBlock(&b, "text", command.Output)
+		}
+	}
+
+	if pr := pack.PullRequest; pr != nil {
+		b.WriteString("\n## Pull request\n\n")
+		title := pr.Title
+		if title == "" {
+			title = pr.URL
+		}
+		fmt.Fprintf(&b, "[%s](%s)", title, pr.URL)
+		if pr.State != "" {
+			b.WriteString(", " + strings.ToLower(pr.State))
+		}
+		if pr.ChecksState != "" {
+			b.WriteString(", checks " + strings.ToLower(pr.ChecksState))
+		}
+		b.WriteString("\n")
+		if len(pr.ReviewThreads) > 0 {
+			b.WriteString("\nUnresolved review threads:\n")
+			for _, thread := range pr.ReviewThreads {
+				location := thread.Path
+				if thread.Line > 0 {
+					location = fmt.Sprintf("%s:%d", thread.Path, thread.Line)
+				}
+				fmt.Fprintf(&b, "\n- `%s`", location)
+				if thread.Outdated {
+					b.WriteString(" (outdated)")
+				}
+				b.WriteString("\n")
+				for _, comment := range thread.Comments {
+					body := strings.ReplaceAll(strings.TrimSpace(comment.Body), "\n", "\n    ")
+					fmt.Fprintf(&b, "  - **%s**: %s\n", comment.Author, body)
+				}
+			}
+		}
+	}
+
+	b.WriteString("\n## Diff\n\n")
+	fmt.Fprintf(&b, "%s changed, +%d -%d against `%s`.\n", plural(pack.Diff.TotalFiles, "file"),
+		pack.Diff.TotalAdditions, pack.Diff.TotalDeletions, pack.SourceBranch)
+	for _, file := range pack.Diff.Files {
+		fmt.Fprintf(&b, "\n### %s (%s, +%d -%d)\n\n", file.Path, file.ChangeType, file.Additions, file.Deletions)
+		if file.Omitted != "" {
+			fmt.Fprintf(&b, "Diff left out: %s.\n", file.Omitted)
+			continue
+		}
+		if file.Diff != "" {
+			writeCodeBlock(&b, "diff", file.Diff)
+		}
+	}
+
+	if len(pack.Truncated) > 0 || len(pack.Notes) > 0 {
+		b.WriteString("\n## Notes\n\n")
+		if len(pack.Truncated) > 0 {
+			fmt.Fprintf(&b, "- Cut short to fit ~%d tokens: %s\n", pack.MaxTokens, strings.Join(pack.Truncated, ", "))
+		}
+		for _, note := range pack.Notes {
+			b.WriteString("- " + note + "\n")
+		}
+	}
+	return b.String()
+}
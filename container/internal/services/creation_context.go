@@ -0,0 +1,131 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// maxCreationPromptSummary is how much of the initial prompt a pull request body quotes
+const maxCreationPromptSummary = 280
+
+// ParseCreationSource validates a creation source, defaulting to api
+func ParseCreationSource(source string) (models.CreationSource, error) {
+	switch models.CreationSource(source) {
+	case "":
+		return models.CreationSourceAPI, nil
+	case models.CreationSourceUI, models.CreationSourceTUI, models.CreationSourceAPI, models.CreationSourceAuto:
+		return models.CreationSource(source), nil
+	}
+	return "", fmt.Errorf("unknown creation source %q, expected ui, tui, api or auto", source)
+}
+
+// autoCreationContext is the creation context of worktrees catnip creates by itself. Callers
+// creating worktrees on behalf of someone pass their creation context to the checkout instead.
+func autoCreationContext(worktree *models.Worktree) *models.CreationContext {
+	createdAt := worktree.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+	return &models.CreationContext{Source: models.CreationSourceAuto, CreatedAt: createdAt}
+}
+
+// normalizeCreationContext applies the defaults to a creation context of worktree
+func normalizeCreationContext(worktree *models.Worktree, creation models.CreationContext) *models.CreationContext {
+	if creation.Source == "" {
+		creation.Source = models.CreationSourceAPI
+	}
+	if creation.Actor != "" {
+		creation.Actor = NormalizeActor(creation.Actor)
+	}
+	creation.Prompt = strings.TrimSpace(creation.Prompt)
+	creation.Issue = strings.TrimSpace(creation.Issue)
+	if creation.CreatedAt.IsZero() {
+		creation.CreatedAt = autoCreationContext(worktree).CreatedAt
+	}
+	return &creation
+}
+
+// applyCreationContext records why a new worktree is created and by whom before it is added to
+// state, so the worktree:created event carries both. A nil creation means catnip created it by
+// itself.
+func applyCreationContext(worktree *models.Worktree, creation *models.CreationContext) {
+	if creation == nil {
+		worktree.CreationContext = autoCreationContext(worktree)
+		return
+	}
+	worktree.CreationContext = normalizeCreationContext(worktree, *creation)
+	if worktree.CreationContext.Actor != "" {
+		worktree.CreatedBy = worktree.CreationContext.Actor
+	}
+}
+
+// SetWorktreeCreationContext records why a worktree was created, keeping its creation time
+func (s *GitService) SetWorktreeCreationContext(worktreeID string, creation models.CreationContext) error {
+	worktree, exists := s.stateManager.GetWorktree(worktreeID)
+	if !exists {
+		return fmt.Errorf("worktree %s not found", worktreeID)
+	}
+	return s.stateManager.UpdateWorktree(worktreeID, map[string]interface{}{
+		"creation_context": normalizeCreationContext(worktree, creation),
+	})
+}
+
+// CaptureInitialPrompt records prompt as the worktree's initial prompt unless one is known
+// already. ClaudeMonitorService calls it on title events.
+func (s *GitService) CaptureInitialPrompt(worktreeID, prompt string) {
+	prompt = strings.TrimSpace(prompt)
+	worktree, exists := s.stateManager.GetWorktree(worktreeID)
+	if !exists || prompt == "" || worktree.CreationContext == nil || worktree.CreationContext.Prompt != "" {
+		return
+	}
+
+	creation := *worktree.CreationContext
+	creation.Prompt = prompt
+	if err := s.stateManager.UpdateWorktree(worktreeID, map[string]interface{}{
+		"creation_context": &creation,
+	}); err != nil {
+		logger.Warnf("⚠️ Failed to record initial prompt for %s: %v", worktree.Name, err)
+		return
+	}
+	logger.Debugf("📝 Captured initial prompt for worktree %s", worktree.Name)
+}
+
+// creationSummary describes the issue and prompt a session started from, or "" when neither is known
+func creationSummary(creation *models.CreationContext) string {
+	if creation == nil || (creation.Issue == "" && creation.Prompt == "") {
+		return ""
+	}
+
+	summary := "Session started"
+	if creation.Issue != "" {
+		issue := creation.Issue
+		if strings.Trim(issue, "0123456789") == "" {
+			issue = "#" + issue
+		}
+		summary += " from issue " + issue
+	}
+	if creation.Prompt != "" {
+		prompt := strings.Join(strings.Fields(creation.Prompt), " ")
+		if runes := []rune(prompt); len(runes) > maxCreationPromptSummary {
+			prompt = string(runes[:maxCreationPromptSummary]) + "…"
+		}
+		summary += " with prompt: " + prompt
+	}
+	return summary
+}
+
+// withCreationSummary appends the creation summary to a pull request body
+func withCreationSummary(body string, creation *models.CreationContext) string {
+	summary := creationSummary(creation)
+	if summary == "" || strings.Contains(body, summary) {
+		return body
+	}
+	if strings.TrimSpace(body) == "" {
+		return summary
+	}
+	return strings.TrimRight(body, "\n") + "\n\n---\n" + summary
+}
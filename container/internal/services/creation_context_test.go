@@ -0,0 +1,113 @@
+package services
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+func TestParseCreationSource(t *testing.T) {
+	source, err := ParseCreationSource("")
+	require.NoError(t, err)
+	assert.Equal(t, models.CreationSourceAPI, source)
+
+	source, err = ParseCreationSource("tui")
+	require.NoError(t, err)
+	assert.Equal(t, models.CreationSourceTUI, source)
+
+	_, err = ParseCreationSource("cron")
+	assert.Error(t, err)
+}
+
+func TestWithCreationSummary(t *testing.T) {
+	assert.Equal(t, "Body", withCreationSummary("Body", nil))
+	assert.Equal(t, "Body", withCreationSummary("Body", &models.CreationContext{Source: models.CreationSourceUI}))
+
+	creation := &models.CreationContext{Issue: "123", Prompt: "Fix the\n  login   page"}
+	body := withCreationSummary("Adds OAuth.\n", creation)
+	assert.Equal(t, "Adds OAuth.\n\n---\nSession started from issue #123 with prompt: Fix the login page", body)
+	assert.Equal(t, body, withCreationSummary(body, creation), "the summary is added once")
+
+	assert.Equal(t, "Session started with prompt: Go", withCreationSummary("", &models.CreationContext{Prompt: "Go"}))
+	assert.Equal(t, "Session started from issue https://github.com/acme/felix/issues/7",
+		creationSummary(&models.CreationContext{Issue: "https://github.com/acme/felix/issues/7"}))
+
+	long := creationSummary(&models.CreationContext{Prompt: strings.Repeat("a", 1000)})
+	assert.True(t, strings.HasSuffix(long, "…"))
+	assert.Len(t, []rune(long), len("Session started with prompt: ")+maxCreationPromptSummary+1)
+}
+
+func TestCreationContext(t *testing.T) {
+	root := t.TempDir()
+	stateManager := NewWorktreeStateManager(filepath.Join(root, "state"), nil)
+	t.Cleanup(stateManager.Stop)
+	createdAt := time.Date(2024, 1, 15, 14, 0, 0, 0, time.UTC)
+	require.NoError(t, stateManager.AddRepository(&models.Repository{ID: "local/repo", Path: root}))
+	worktree := &models.Worktree{ID: "wt-felix", RepoID: "local/repo", Name: "felix", Path: root, CreatedAt: createdAt}
+	worktree.CreationContext = autoCreationContext(worktree)
+	require.NoError(t, stateManager.AddWorktree(worktree))
+	s := &GitService{stateManager: stateManager}
+
+	// Without an explicit prompt the first one seen is captured
+	require.NoError(t, s.SetWorktreeCreationContext("wt-felix", models.CreationContext{
+		Source: models.CreationSourceUI, Issue: " #12 ", Actor: "alice",
+	}))
+	s.CaptureInitialPrompt("wt-felix", "Add OAuth login")
+	s.CaptureInitialPrompt("wt-felix", "Now add tests")
+
+	worktree, _ = stateManager.GetWorktree("wt-felix")
+	assert.Equal(t, &models.CreationContext{
+		Source: models.CreationSourceUI, Prompt: "Add OAuth login", Issue: "#12", Actor: "alice", CreatedAt: createdAt,
+	}, worktree.CreationContext)
+
+	// Explicit prompts are never replaced
+	require.NoError(t, s.SetWorktreeCreationContext("wt-felix", models.CreationContext{Prompt: "Refactor auth"}))
+	s.CaptureInitialPrompt("wt-felix", "Something else")
+	worktree, _ = stateManager.GetWorktree("wt-felix")
+	assert.Equal(t, models.CreationSourceAPI, worktree.CreationContext.Source)
+	assert.Equal(t, "Refactor auth", worktree.CreationContext.Prompt)
+
+	assert.Error(t, s.SetWorktreeCreationContext("wt-missing", models.CreationContext{}))
+}
+
+// createdRecorder records the worktrees announced as created and ignores updates
+type createdRecorder struct {
+	EventsEmitter
+	created []models.Worktree
+}
+
+func (r *createdRecorder) EmitWorktreeCreated(worktree *models.Worktree) {
+	r.created = append(r.created, *worktree)
+}
+
+func (r *createdRecorder) EmitWorktreeUpdated(string, map[string]interface{}) {}
+
+func (r *createdRecorder) EmitWorktreeBatchUpdated(map[string]*CachedWorktreeStatus) {}
+
+func TestCreationContextIsSetBeforeCreatedEvent(t *testing.T) {
+	s, stateManager, repoPath, _ := newRecreateTestService(t)
+	s.worktreeCache = NewWorktreeStatusCache(s.operations, stateManager)
+	t.Cleanup(s.worktreeCache.Stop)
+	events := &createdRecorder{}
+	stateManager.SetEventsEmitter(events)
+	runTestGit(t, repoPath, "checkout", "-q", "-b", "spike/retry")
+	runTestGit(t, repoPath, "commit", "--allow-empty", "-m", "retry spike")
+	runTestGit(t, repoPath, "checkout", "-q", "main")
+
+	worktree, err := s.AdoptBranch("local/repo", "spike/retry", &models.CreationContext{
+		Source: models.CreationSourceUI, Actor: " alice ",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "alice", worktree.CreatedBy)
+
+	require.Len(t, events.created, 1)
+	assert.Equal(t, "alice", events.created[0].CreatedBy, "the created event names the actor")
+	require.NotNil(t, events.created[0].CreationContext)
+	assert.Equal(t, models.CreationSourceUI, events.created[0].CreationContext.Source)
+	assert.Equal(t, "alice", events.created[0].CreationContext.Actor)
+}
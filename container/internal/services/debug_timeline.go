@@ -0,0 +1,117 @@
+package services
+
+import (
+	"sync"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/models"
+	"github.com/vanpelt/catnip/internal/redact"
+)
+
+// debugTimelineLimit is how many decisions are kept per worktree
+const debugTimelineLimit = 500
+
+// Kinds of debug timeline entries
+const (
+	TimelineTitleEvent      = "title_event"
+	TimelineTitleCommit     = "title_commit"
+	TimelineCheckpointTimer = "checkpoint_timer"
+	TimelineGraduation      = "graduation"
+)
+
+// Outcomes of debug timeline entries, graduations use the branch rename outcomes
+const (
+	TimelineAccepted   = "accepted"
+	TimelineDuplicate  = "duplicate"
+	TimelineSuppressed = "suppressed"
+	TimelineCommitted  = "committed"
+	TimelineKept       = "kept"
+	TimelineNoChanges  = "no_changes"
+	TimelineError      = "error"
+)
+
+// Reasons title events and checkpoints are suppressed
+const (
+	TimelineReasonHandedOff = "handed_off"
+	TimelineReasonPlanMode  = "plan_mode"
+	TimelineReasonDisabled  = "checkpoints_disabled"
+)
+
+// debugTimeline keeps the last decisions of the Claude monitor per worktree, so "why didn't it
+// commit" can be answered without reading logs. A nil timeline records nothing.
+type debugTimeline struct {
+	mu      sync.Mutex
+	entries map[string][]models.DebugTimelineEntry
+}
+
+func newDebugTimeline() *debugTimeline {
+	return &debugTimeline{entries: make(map[string][]models.DebugTimelineEntry)}
+}
+
+// record appends an entry to the timeline of workDir, dropping the oldest beyond the limit
+func (t *debugTimeline) record(workDir string, entry models.DebugTimelineEntry) {
+	if t == nil {
+		return
+	}
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+	entry.Title, entry.Reason = redact.Text(entry.Title), redact.Text(entry.Reason)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	entries := append(t.entries[workDir], entry)
+	if len(entries) > debugTimelineLimit {
+		entries = append([]models.DebugTimelineEntry(nil), entries[len(entries)-debugTimelineLimit:]...)
+	}
+	t.entries[workDir] = entries
+}
+
+// since returns the entries of workDir recorded after since, oldest first
+func (t *debugTimeline) since(workDir string, since time.Time) []models.DebugTimelineEntry {
+	result := []models.DebugTimelineEntry{}
+	if t == nil {
+		return result
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, entry := range t.entries[workDir] {
+		if entry.Time.After(since) {
+			result = append(result, entry)
+		}
+	}
+	return result
+}
+
+// forgetRemoved drops the timelines of worktree directories that no longer exist, returning how
+// many were dropped
+func (t *debugTimeline) forgetRemoved() int {
+	if t == nil {
+		return 0
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	removed := 0
+	for workDir := range t.entries {
+		if pathRemoved(workDir) {
+			delete(t.entries, workDir)
+			removed++
+		}
+	}
+	return removed
+}
+
+// size returns the number of worktrees with a timeline
+func (t *debugTimeline) size() int {
+	if t == nil {
+		return 0
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.entries)
+}
+
+// GetDebugTimeline returns the monitor's title event, checkpoint and graduation decisions for the
+// worktree at worktreePath made after since, oldest first
+func (s *ClaudeMonitorService) GetDebugTimeline(worktreePath string, since time.Time) []models.DebugTimelineEntry {
+	return s.timeline.since(worktreePath, since)
+}
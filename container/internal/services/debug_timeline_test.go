@@ -0,0 +1,84 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/git"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+func TestDebugTimeline(t *testing.T) {
+	workDir := initTestRepo(t, t.TempDir(), map[string]string{"app.txt": "one\n"})
+
+	gitService := newTestGitService(t, t.TempDir())
+	stateManager := gitService.stateManager
+	require.NoError(t, stateManager.AddRepository(&models.Repository{ID: "local/repo", Path: workDir}))
+	require.NoError(t, stateManager.AddWorktree(&models.Worktree{ID: "wt-felix", RepoID: "local/repo", Name: "repo/felix", Path: workDir, Branch: "main", IsDirty: true}))
+	sessions := &SessionService{stateDir: t.TempDir(), activeSessions: map[string]*ActiveSessionInfo{workDir: {}}}
+	claude := &ClaudeService{claudeConfigPath: filepath.Join(t.TempDir(), ".claude.json")}
+	monitor := NewClaudeMonitorService(gitService, sessions, claude, stateManager)
+	manager := &WorktreeCheckpointManager{
+		workDir:           workDir,
+		worktreeID:        "wt-felix",
+		checkpointManager: git.NewSessionCheckpointManager(workDir, &countingCheckpointGit{}, noopCheckpointSessions{}),
+		gitService:        gitService,
+		sessionService:    sessions,
+		stateManager:      stateManager,
+		clock:             &fakeClock{wall: time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC)},
+		diffFingerprints:  monitor.diffFingerprints,
+		timeline:          monitor.timeline,
+	}
+	monitor.checkpointManagers[workDir] = manager
+
+	require.NoError(t, os.WriteFile(filepath.Join(workDir, "app.txt"), []byte("one\ntwo\n"), 0644))
+	monitor.handleTitleChange(workDir, "Add felix mode", "log")
+	monitor.handleTitleChange(workDir, "Add felix mode", "log")
+	monitor.handleTitleChange(workDir, "Write felix docs", "pty")
+	manager.createCheckpointIfChanged()
+
+	entries := monitor.GetDebugTimeline(workDir, time.Time{})
+	type decision struct{ kind, source, title, outcome, reason string }
+	var decisions []decision
+	for _, entry := range entries {
+		decisions = append(decisions, decision{entry.Kind, entry.Source, entry.Title, entry.Outcome, entry.Reason})
+	}
+	assert.Equal(t, []decision{
+		{TimelineTitleEvent, "log", "Add felix mode", TimelineAccepted, ""},
+		{TimelineTitleEvent, "log", "Add felix mode", TimelineDuplicate, ""},
+		{TimelineTitleEvent, "pty", "Write felix docs", TimelineAccepted, ""},
+		{TimelineTitleCommit, "", "Add felix mode", TimelineCommitted, "title_changed"},
+		{TimelineCheckpointTimer, "", "Write felix docs", TimelineNoChanges, ""},
+	}, decisions)
+	assert.Equal(t, runTestGit(t, workDir, "rev-parse", "HEAD"), entries[3].Commit)
+
+	t.Run("SuppressionsExplainMissingCommits", func(t *testing.T) {
+		since := entries[len(entries)-1].Time
+		manager.checkpointsHeld = true
+		monitor.handleTitleChange(workDir, "Plan felix rollout", "log")
+		manager.checkpointsHeld = false
+
+		suppressed := monitor.GetDebugTimeline(workDir, since)
+		require.Len(t, suppressed, 2)
+		assert.Equal(t, TimelineTitleCommit, suppressed[1].Kind)
+		assert.Equal(t, TimelineSuppressed, suppressed[1].Outcome)
+		assert.Equal(t, TimelineReasonPlanMode, suppressed[1].Reason)
+	})
+
+	t.Run("KeepsTheLastEntries", func(t *testing.T) {
+		timeline := newDebugTimeline()
+		start := time.Now()
+		for i := 0; i < debugTimelineLimit+20; i++ {
+			timeline.record(workDir, models.DebugTimelineEntry{Time: start.Add(time.Duration(i) * time.Second), Kind: TimelineCheckpointTimer})
+		}
+		all := timeline.since(workDir, time.Time{})
+		require.Len(t, all, debugTimelineLimit)
+		assert.Equal(t, start.Add(20*time.Second), all[0].Time)
+		assert.Len(t, timeline.since(workDir, start.Add(time.Duration(debugTimelineLimit+10)*time.Second)), 9)
+		assert.Empty(t, timeline.since(filepath.Join(workDir, "elsewhere"), time.Time{}))
+	})
+}
@@ -0,0 +1,322 @@
+package services
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/config"
+	"github.com/vanpelt/catnip/internal/git"
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// Git config key turning off the shared package caches for repositories with hermeticity
+// requirements ("false"). Can be set per repository or globally.
+const dependencyCacheSharedKey = "catnip.cache.shared"
+
+// Global git config key holding the combined size, in MiB, trims shrink the shared caches below
+const dependencyCacheMaxSizeKey = "catnip.cache.max-size-mb"
+
+const (
+	// defaultDependencyCacheMaxSizeMB caps the shared caches at 20 GiB
+	defaultDependencyCacheMaxSizeMB = 20 << 10
+	// dependencyCacheMinIdle protects entries used this recently from trims, so a running
+	// install never loses files it just fetched
+	dependencyCacheMinIdle = time.Hour
+	// dependencyCacheTrimInterval is how often the caches are trimmed to their cap
+	dependencyCacheTrimInterval = 6 * time.Hour
+)
+
+// dependencyCache is a package cache shared by the worktrees using one of its package managers.
+// The package managers all lock their caches, so concurrent installs are safe.
+type dependencyCache struct {
+	Name     string
+	EnvVar   string
+	Managers []string
+	// Directories whose children are trimmed as a whole, relative to the cache ("" for the
+	// cache itself). Package managers fetch a missing child again; files outside them, like
+	// cargo's installed binaries, are never trimmed.
+	TrimDirs []string
+}
+
+var dependencyCaches = []dependencyCache{
+	{Name: "npm", EnvVar: "npm_config_cache", Managers: []string{"npm"}, TrimDirs: []string{""}},
+	{Name: "pnpm", EnvVar: "PNPM_STORE_DIR", Managers: []string{"pnpm"}, TrimDirs: []string{""}},
+	{Name: "cargo", EnvVar: "CARGO_HOME", Managers: []string{"cargo"}, TrimDirs: []string{"registry", "git"}},
+	{Name: "go", EnvVar: "GOMODCACHE", Managers: []string{"go"}, TrimDirs: []string{""}},
+	{Name: "pip", EnvVar: "PIP_CACHE_DIR", Managers: []string{"pip", "pipenv", "poetry", "pdm"}, TrimDirs: []string{""}},
+}
+
+// dependencyCacheTrimMu keeps periodic and requested trims from racing each other
+var dependencyCacheTrimMu sync.Mutex
+
+// DependencyCacheRoot returns the directory holding the shared package caches
+func DependencyCacheRoot() string {
+	return filepath.Join(config.Runtime.VolumeDir, "cache")
+}
+
+// GlobalDependencyCacheMaxSizeKB reads the cap of the shared caches from the global git config
+func GlobalDependencyCacheMaxSizeKB() int64 {
+	operations := git.NewOperations()
+	return loadDependencyCacheMaxSizeKB(func(key string) (string, error) {
+		output, err := operations.ExecuteGit(config.Runtime.HomeDir, "config", "--global", "--get", key)
+		return strings.TrimSpace(string(output)), err
+	})
+}
+
+// loadDependencyCacheMaxSizeKB reads the cap of the shared caches from git config, keeping the
+// default for unset or invalid values. Zero leaves the caches uncapped.
+func loadDependencyCacheMaxSizeKB(getConfig func(key string) (string, error)) int64 {
+	if value, err := getConfig(dependencyCacheMaxSizeKey); err == nil && value != "" {
+		if mb, err := strconv.ParseInt(value, 10, 64); err == nil && mb >= 0 {
+			return mb << 10
+		}
+		logger.Warnf("⚠️  Ignoring invalid %s value %q", dependencyCacheMaxSizeKey, value)
+	}
+	return defaultDependencyCacheMaxSizeMB << 10
+}
+
+// dependencyCachesFor returns the caches used by the package managers of toolchains
+func dependencyCachesFor(toolchains []models.Toolchain) []dependencyCache {
+	var caches []dependencyCache
+	for _, cache := range dependencyCaches {
+		for _, toolchain := range toolchains {
+			if slices.Contains(cache.Managers, toolchain.PackageManager) {
+				caches = append(caches, cache)
+				break
+			}
+		}
+	}
+	return caches
+}
+
+// dependencyCacheEnv returns the environment variables pointing the package managers of
+// toolchains at their caches under root, creating the cache directories
+func dependencyCacheEnv(root string, toolchains []models.Toolchain) []string {
+	var env []string
+	for _, cache := range dependencyCachesFor(toolchains) {
+		dir := filepath.Join(root, cache.Name)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			logger.Warnf("⚠️  Failed to create %s cache %s: %v", cache.Name, dir, err)
+			continue
+		}
+		env = append(env, cache.EnvVar+"="+dir)
+	}
+	return env
+}
+
+// DependencyCacheEnv returns the environment variables sharing package caches with the
+// worktree at worktreePath, or nil when sharing is off. Caches are only shared in containers
+// (native installs already share the user's own caches) and never from inside the workspace,
+// so they don't count towards worktree sizes and can't be picked up by checkpoints.
+func (s *GitService) DependencyCacheEnv(worktreePath string) []string {
+	if !config.Runtime.IsContainerized() {
+		return nil
+	}
+
+	worktree, _ := s.stateManager.FindWorktreeByPath(worktreePath)
+	if worktree == nil {
+		return nil
+	}
+	if value, err := s.operations.GetConfig(worktree.Path, dependencyCacheSharedKey); err == nil && value == "false" {
+		return nil
+	}
+
+	root := DependencyCacheRoot()
+	if _, inWorkspace := workspaceRelativePath(root); inWorkspace {
+		logger.Warnf("⚠️  Not sharing package caches: %s is inside the workspace", root)
+		return nil
+	}
+
+	toolchains := worktree.Toolchains
+	if toolchains == nil {
+		toolchains = DetectToolchains(worktree.Path)
+	}
+	return dependencyCacheEnv(root, toolchains)
+}
+
+// dependencyCacheEntry is a unit trims remove from a cache
+type dependencyCacheEntry struct {
+	path     string
+	sizeKB   int64
+	lastUsed time.Time
+}
+
+// scanDependencyCache measures a cache and collects its trimmable entries
+func scanDependencyCache(root string, cache dependencyCache) (models.DependencyCacheUsage, []dependencyCacheEntry) {
+	usage := models.DependencyCacheUsage{Name: cache.Name, EnvVar: cache.EnvVar, Path: filepath.Join(root, cache.Name)}
+	var bytes int64
+	var lastUsed time.Time
+	entries := map[string]*dependencyCacheEntry{}
+	entryBytes := map[string]int64{}
+
+	_ = filepath.WalkDir(usage.Path, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil
+		}
+		used := fileLastUsed(info)
+		bytes += info.Size()
+		if used.After(lastUsed) {
+			lastUsed = used
+		}
+
+		rel, err := filepath.Rel(usage.Path, path)
+		if err != nil {
+			return nil
+		}
+		key := trimEntryKey(filepath.ToSlash(rel), cache.TrimDirs)
+		if key == "" {
+			return nil
+		}
+		if entries[key] == nil {
+			entries[key] = &dependencyCacheEntry{path: filepath.Join(usage.Path, filepath.FromSlash(key))}
+		}
+		entryBytes[key] += info.Size()
+		if used.After(entries[key].lastUsed) {
+			entries[key].lastUsed = used
+		}
+		return nil
+	})
+
+	usage.SizeKB = bytes >> 10
+	if !lastUsed.IsZero() {
+		usage.LastUsed = &lastUsed
+	}
+	var result []dependencyCacheEntry
+	for key, entry := range entries {
+		entry.sizeKB = entryBytes[key] >> 10
+		result = append(result, *entry)
+	}
+	return usage, result
+}
+
+// trimEntryKey returns the trimmable entry a file of a cache belongs to, or "" when the file
+// is outside the trim directories. Files directly in a trim directory are entries themselves.
+func trimEntryKey(rel string, trimDirs []string) string {
+	for _, dir := range trimDirs {
+		inside := rel
+		if dir != "" {
+			var found bool
+			if inside, found = strings.CutPrefix(rel, dir+"/"); !found {
+				continue
+			}
+		}
+		child, _, _ := strings.Cut(inside, "/")
+		if dir == "" {
+			return child
+		}
+		return dir + "/" + child
+	}
+	return ""
+}
+
+// scanDependencyCaches measures every cache under root
+func scanDependencyCaches(root string, maxSizeKB int64) (*models.DependencyCacheReport, []dependencyCacheEntry) {
+	report := &models.DependencyCacheReport{Caches: []models.DependencyCacheUsage{}, MaxSizeKB: maxSizeKB}
+	var entries []dependencyCacheEntry
+	for _, cache := range dependencyCaches {
+		usage, cacheEntries := scanDependencyCache(root, cache)
+		report.Caches = append(report.Caches, usage)
+		report.TotalKB += usage.SizeKB
+		entries = append(entries, cacheEntries...)
+	}
+	return report, entries
+}
+
+// DependencyCacheStatus measures the shared caches under root against maxSizeKB
+func DependencyCacheStatus(root string, maxSizeKB int64) *models.DependencyCacheReport {
+	report, _ := scanDependencyCaches(root, maxSizeKB)
+	return report
+}
+
+// TrimDependencyCaches removes the least recently used cache entries under root until the
+// caches fit in maxSizeKB. Entries used within the last hour are kept even when the caches
+// stay over the cap; a zero cap never trims.
+func TrimDependencyCaches(root string, maxSizeKB int64) *models.DependencyCacheReport {
+	return trimDependencyCaches(root, maxSizeKB, time.Now())
+}
+
+func trimDependencyCaches(root string, maxSizeKB int64, now time.Time) *models.DependencyCacheReport {
+	dependencyCacheTrimMu.Lock()
+	defer dependencyCacheTrimMu.Unlock()
+
+	report, entries := scanDependencyCaches(root, maxSizeKB)
+	if maxSizeKB == 0 || report.TotalKB <= maxSizeKB {
+		return report
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if !entries[i].lastUsed.Equal(entries[j].lastUsed) {
+			return entries[i].lastUsed.Before(entries[j].lastUsed)
+		}
+		return entries[i].path < entries[j].path
+	})
+	total := report.TotalKB
+	var removed []string
+	var freed int64
+	for _, entry := range entries {
+		if total <= maxSizeKB {
+			break
+		}
+		if now.Sub(entry.lastUsed) < dependencyCacheMinIdle {
+			continue
+		}
+		if err := removeCacheEntry(entry.path); err != nil {
+			logger.Warnf("⚠️  Failed to trim cache entry %s: %v", entry.path, err)
+			continue
+		}
+		rel, _ := filepath.Rel(root, entry.path)
+		removed = append(removed, filepath.ToSlash(rel))
+		total -= entry.sizeKB
+		freed += entry.sizeKB
+	}
+
+	report, _ = scanDependencyCaches(root, maxSizeKB)
+	report.Removed = removed
+	report.FreedKB = freed
+	if len(removed) > 0 {
+		logger.Infof("🧹 Trimmed %d package cache entries, freeing %d MiB", len(removed), freed>>10)
+	}
+	return report
+}
+
+// removeCacheEntry deletes a cache entry, making it writable first when needed: the Go module
+// cache stores its files read-only
+func removeCacheEntry(path string) error {
+	if err := os.RemoveAll(path); err == nil {
+		return nil
+	}
+	_ = filepath.WalkDir(path, func(dir string, entry fs.DirEntry, err error) error {
+		if err == nil && entry.IsDir() {
+			_ = os.Chmod(dir, 0755)
+		}
+		return nil
+	})
+	return os.RemoveAll(path)
+}
+
+// startDependencyCacheTrimmer keeps the shared caches under their cap
+func (s *GitService) startDependencyCacheTrimmer() {
+	ticker := time.NewTicker(dependencyCacheTrimInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			TrimDependencyCaches(DependencyCacheRoot(), GlobalDependencyCacheMaxSizeKB())
+		case <-s.stopCh:
+			return
+		}
+	}
+}
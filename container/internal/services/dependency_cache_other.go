@@ -0,0 +1,14 @@
+//go:build !linux
+
+package services
+
+import (
+	"io/fs"
+	"time"
+)
+
+// fileLastUsed returns when a file was last written; caches are only shared on Linux
+// containers, so other platforms don't need access times
+func fileLastUsed(info fs.FileInfo) time.Time {
+	return info.ModTime()
+}
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+func TestDependencyCacheEnv(t *testing.T) {
+	root := t.TempDir()
+	env := dependencyCacheEnv(root, []models.Toolchain{
+		{Name: "node", PackageManager: "pnpm"},
+		{Name: "typescript"},
+		{Name: "go", PackageManager: "go"},
+		{Name: "python", PackageManager: "poetry"},
+	})
+	assert.Equal(t, []string{
+		"PNPM_STORE_DIR=" + filepath.Join(root, "pnpm"),
+		"GOMODCACHE=" + filepath.Join(root, "go"),
+		"PIP_CACHE_DIR=" + filepath.Join(root, "pip"),
+	}, env)
+	assert.DirExists(t, filepath.Join(root, "pnpm"))
+
+	assert.Empty(t, dependencyCacheEnv(root, []models.Toolchain{{Name: "ruby", PackageManager: "bundler"}}))
+}
+
+func TestLoadDependencyCacheMaxSizeKB(t *testing.T) {
+	configValue := func(value string) func(string) (string, error) {
+		return func(string) (string, error) { return value, nil }
+	}
+	assert.Equal(t, int64(defaultDependencyCacheMaxSizeMB<<10), loadDependencyCacheMaxSizeKB(configValue("")))
+	assert.Equal(t, int64(512<<10), loadDependencyCacheMaxSizeKB(configValue("512")))
+	assert.Equal(t, int64(0), loadDependencyCacheMaxSizeKB(configValue("0")))
+	assert.Equal(t, int64(defaultDependencyCacheMaxSizeMB<<10), loadDependencyCacheMaxSizeKB(configValue("-1")))
+}
+
+func TestTrimEntryKey(t *testing.T) {
+	assert.Equal(t, "github.com", trimEntryKey("github.com/pkg/errors@v0.9.1/errors.go", []string{""}))
+	assert.Equal(t, "index.db", trimEntryKey("index.db", []string{""}))
+	assert.Equal(t, "registry/cache", trimEntryKey("registry/cache/index.crates.io/serde.crate", []string{"registry", "git"}))
+	assert.Equal(t, "git/db", trimEntryKey("git/db/repo/HEAD", []string{"registry", "git"}))
+	assert.Empty(t, trimEntryKey("bin/cargo-nextest", []string{"registry", "git"}))
+}
+
+// writeCacheFile writes a file of size KiB last used at the given time
+func writeCacheFile(t *testing.T, path string, sizeKB int, used time.Time) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, make([]byte, sizeKB<<10), 0644))
+	require.NoError(t, os.Chtimes(path, used, used))
+}
+
+func TestTrimDependencyCaches(t *testing.T) {
+	root := t.TempDir()
+	now := time.Now()
+	day := 24 * time.Hour
+
+	writeCacheFile(t, filepath.Join(root, "go", "golang.org", "x", "mod@v0.1.0", "go.mod"), 100, now.Add(-5*day))
+	writeCacheFile(t, filepath.Join(root, "go", "cache", "download", "list"), 100, now.Add(-3*day))
+	writeCacheFile(t, filepath.Join(root, "cargo", "bin", "cargo-nextest"), 100, now.Add(-10*day))
+	writeCacheFile(t, filepath.Join(root, "cargo", "registry", "cache", "serde.crate"), 100, now.Add(-4*day))
+	writeCacheFile(t, filepath.Join(root, "pnpm", "v3", "files", "00", "abc"), 100, now.Add(-time.Minute))
+	// The Go module cache is read-only
+	require.NoError(t, os.Chmod(filepath.Join(root, "go", "golang.org", "x", "mod@v0.1.0"), 0555))
+
+	report := DependencyCacheStatus(root, 150)
+	assert.Equal(t, int64(500), report.TotalKB)
+	for _, cache := range report.Caches {
+		if cache.Name == "npm" {
+			assert.Zero(t, cache.SizeKB)
+			assert.Nil(t, cache.LastUsed)
+		}
+	}
+
+	report = trimDependencyCaches(root, 150, now)
+	assert.Equal(t, []string{"go/golang.org", "cargo/registry/cache", "go/cache"}, report.Removed, "least recently used first")
+	assert.Equal(t, int64(300), report.FreedKB)
+	assert.Equal(t, int64(200), report.TotalKB, "recently used entries are kept even over the cap")
+	assert.FileExists(t, filepath.Join(root, "cargo", "bin", "cargo-nextest"), "installed binaries are never trimmed")
+	assert.FileExists(t, filepath.Join(root, "pnpm", "v3", "files", "00", "abc"))
+	assert.NoDirExists(t, filepath.Join(root, "go", "golang.org"))
+
+	writeCacheFile(t, filepath.Join(root, "go", "cache", "download", "list"), 100, now.Add(-3*day))
+	report = trimDependencyCaches(root, 0, now)
+	assert.Empty(t, report.Removed, "a zero cap never trims")
+}
@@ -0,0 +1,99 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/vanpelt/catnip/internal/git"
+	"github.com/vanpelt/catnip/internal/logger"
+)
+
+// ErrInvalidDiffRef is returned, wrapped in an InvalidDiffRefError, when a worktree can't be
+// diffed against the requested ref
+var ErrInvalidDiffRef = errors.New("invalid diff ref")
+
+// InvalidDiffRefError describes a ref GetWorktreeDiffAgainst couldn't resolve
+type InvalidDiffRefError struct {
+	Ref    string
+	Reason string
+}
+
+func (e *InvalidDiffRefError) Error() string {
+	return fmt.Sprintf("invalid ref %q: %s", e.Ref, e.Reason)
+}
+
+func (e *InvalidDiffRefError) Unwrap() error {
+	return ErrInvalidDiffRef
+}
+
+// GetWorktreeDiffAgainst diffs a worktree against any ref instead of its source branch: a tag,
+// another worktree's branch or a commit, e.g. an earlier checkpoint. Branches, tags and commits
+// of remote repositories that aren't known locally are fetched from origin first. The diff isn't
+// cached and records the ref it was computed against as BaseRef.
+func (s *GitService) GetWorktreeDiffAgainst(worktreeID, ref string) (*git.WorktreeDiffResponse, error) {
+	s.mu.RLock()
+	worktree, exists := s.stateManager.GetWorktree(worktreeID)
+	s.mu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("worktree not found: %s", worktreeID)
+	}
+	ref = strings.TrimSpace(ref)
+	if ref == "" || strings.HasPrefix(ref, "-") {
+		return nil, &InvalidDiffRefError{Ref: ref, Reason: "a branch, tag or commit is required"}
+	}
+
+	base := ref
+	if !s.hasCommit(worktree.Path, base) {
+		base = s.fetchDiffRef(worktree.RepoID, ref)
+		if base == "" {
+			return nil, &InvalidDiffRefError{Ref: ref, Reason: fmt.Sprintf("no such branch, tag or commit in %s", worktree.RepoID)}
+		}
+	}
+
+	result, err := s.gitWorktreeManager.GetWorktreeDiff(worktree, base, nil)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "failed to find merge base") {
+			return nil, &InvalidDiffRefError{Ref: ref, Reason: "it shares no history with the worktree"}
+		}
+		return nil, err
+	}
+	result.WorktreeID = worktree.ID
+	result.BaseRef = base
+	result.AuthoredStats = s.classifyDiffFiles(worktree.Path, result.FileDiffs, false)
+	return result, nil
+}
+
+// fetchDiffRef fetches a ref missing from a remote repository and returns what it resolves to
+// afterwards, "" when origin doesn't know it either. Local repositories have nothing to fetch.
+func (s *GitService) fetchDiffRef(repoID, ref string) string {
+	repo, exists := s.stateManager.GetRepository(repoID)
+	if !exists || s.isLocalRepo(repoID) {
+		return ""
+	}
+
+	if source := canonicalSourceRef(ref); source != "" {
+		if err := s.fetchSourceRef(repo.Path, source); err != nil {
+			logger.Debugf("🔍 Failed to fetch %s for diff: %v", ref, err)
+			return ""
+		}
+		if s.hasCommit(repo.Path, source) {
+			return source
+		}
+		return ""
+	}
+
+	branch := strings.TrimPrefix(ref, "origin/")
+	if err := s.fetchBranch(repo.Path, git.FetchStrategy{Branch: branch}); err == nil {
+		for _, candidate := range []string{"origin/" + branch, branch} {
+			if s.hasCommit(repo.Path, candidate) {
+				return candidate
+			}
+		}
+	}
+	if err := s.fetchSourceRef(repo.Path, "refs/tags/"+ref); err == nil && s.hasCommit(repo.Path, "refs/tags/"+ref) {
+		return "refs/tags/" + ref
+	}
+	return ""
+}
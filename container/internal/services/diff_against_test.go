@@ -0,0 +1,44 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetWorktreeDiffAgainst(t *testing.T) {
+	s, _, repoPath := newDiffTestService(t)
+	runTestGit(t, repoPath, "tag", "v1", "main")
+
+	t.Run("Tag", func(t *testing.T) {
+		diff, err := s.GetWorktreeDiffAgainst("wt-felix", "v1")
+		require.NoError(t, err)
+		assert.Equal(t, "v1", diff.BaseRef)
+		assert.Equal(t, "wt-felix", diff.WorktreeID)
+		assert.Len(t, diff.FileDiffs, 5)
+	})
+
+	t.Run("Checkpoint", func(t *testing.T) {
+		head := runTestGit(t, repoPath, "rev-parse", "HEAD")
+		diff, err := s.GetWorktreeDiffAgainst("wt-felix", head)
+		require.NoError(t, err)
+		assert.Equal(t, head, diff.ForkCommit)
+		require.Len(t, diff.FileDiffs, 1, "only the unstaged change is newer than HEAD")
+		assert.Equal(t, "file0.go", diff.FileDiffs[0].FilePath)
+	})
+
+	t.Run("InvalidRefs", func(t *testing.T) {
+		unrelated := runTestGit(t, repoPath, "commit-tree", "HEAD^{tree}", "-m", "Unrelated root")
+		for _, ref := range []string{"nope", "", "--output=/tmp/x", unrelated} {
+			_, err := s.GetWorktreeDiffAgainst("wt-felix", ref)
+			var refErr *InvalidDiffRefError
+			require.True(t, errors.As(err, &refErr), "%q: %v", ref, err)
+			assert.ErrorIs(t, err, ErrInvalidDiffRef)
+		}
+	})
+
+	_, err := s.GetWorktreeDiffAgainst("wt-missing", "v1")
+	assert.ErrorContains(t, err, "worktree not found")
+}
@@ -0,0 +1,227 @@
+package services
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/git"
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+	"golang.org/x/sync/singleflight"
+)
+
+// diffCacheKeyTimeout bounds the git commands reading a worktree's diff cache key
+const diffCacheKeyTimeout = 30 * time.Second
+
+// DiffCacheStats counts how worktree diff requests were answered since the service started
+type DiffCacheStats struct {
+	// Worktrees with a cached diff
+	Entries int `json:"entries"`
+	// Requests answered from the cache
+	Hits int64 `json:"hits"`
+	// Requests that computed the diff
+	Misses int64 `json:"misses"`
+	// Requests that waited for an identical request already computing the diff
+	Shared int64 `json:"shared"`
+	// Cached diffs dropped because files or refs changed
+	Invalidations int64 `json:"invalidations"`
+}
+
+// diffCacheKey identifies the worktree state a diff was computed from
+type diffCacheKey struct {
+	head      string // HEAD commit
+	index     string // Checksum of git status and the size and mtime of every listed file
+	sourceTip string // Commit the source ref points to
+}
+
+type diffCacheEntry struct {
+	key  diffCacheKey
+	diff *git.WorktreeDiffResponse
+}
+
+// worktreeDiffCache keeps the last diff of each worktree with the state it was computed from,
+// and lets concurrent requests for the same worktree share one computation. Its zero value is
+// ready to use.
+type worktreeDiffCache struct {
+	mu          sync.Mutex
+	entries     map[string]*diffCacheEntry // key: worktreeID
+	generations map[string]uint64          // Bumped by invalidate so diffs computed meanwhile aren't stored
+	flight      singleflight.Group
+	stats       DiffCacheStats
+}
+
+// lookup returns the cached diff of a worktree if it was computed from key, and the generation
+// to pass to store otherwise
+func (c *worktreeDiffCache) lookup(worktreeID string, key diffCacheKey) (*git.WorktreeDiffResponse, uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if entry, exists := c.entries[worktreeID]; exists && entry.key == key {
+		c.stats.Hits++
+		return entry.diff, 0
+	}
+	c.stats.Misses++
+	return nil, c.generations[worktreeID]
+}
+
+// store caches a diff unless the worktree was invalidated since lookup returned generation
+func (c *worktreeDiffCache) store(worktreeID string, key diffCacheKey, generation uint64, diff *git.WorktreeDiffResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.generations[worktreeID] != generation {
+		return
+	}
+	if c.entries == nil {
+		c.entries = make(map[string]*diffCacheEntry)
+	}
+	c.entries[worktreeID] = &diffCacheEntry{key: key, diff: diff}
+}
+
+// invalidate drops the cached diff of a worktree
+func (c *worktreeDiffCache) invalidate(worktreeID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.generations == nil {
+		c.generations = make(map[string]uint64)
+	}
+	c.generations[worktreeID]++
+	if _, exists := c.entries[worktreeID]; exists {
+		delete(c.entries, worktreeID)
+		c.stats.Invalidations++
+	}
+}
+
+// forget drops everything kept for a deleted worktree
+func (c *worktreeDiffCache) forget(worktreeID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.entries, worktreeID)
+	delete(c.generations, worktreeID)
+}
+
+// recordMiss counts a request whose diff couldn't be cached
+func (c *worktreeDiffCache) recordMiss() {
+	c.mu.Lock()
+	c.stats.Misses++
+	c.mu.Unlock()
+}
+
+// recordShared counts a request answered by another request's computation
+func (c *worktreeDiffCache) recordShared() {
+	c.mu.Lock()
+	c.stats.Shared++
+	c.mu.Unlock()
+}
+
+func (c *worktreeDiffCache) getStats() DiffCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := c.stats
+	stats.Entries = len(c.entries)
+	return stats
+}
+
+// GetDiffCacheStats returns how worktree diff requests were answered, so the cache's
+// effectiveness is observable
+func (s *GitService) GetDiffCacheStats() DiffCacheStats {
+	return s.diffCache.getStats()
+}
+
+// diffCacheKey reads the state a worktree's diff depends on: HEAD, the status of the index and
+// working tree, and the tip of the source ref. Files are stat'ed so edits to files that were
+// already modified change the key too.
+func (s *GitService) diffCacheKey(worktree *models.Worktree, sourceRef string) (diffCacheKey, error) {
+	output, err := s.operations.ExecuteGitWithTimeout(worktree.Path, diffCacheKeyTimeout, "rev-parse", "HEAD", sourceRef)
+	if err != nil {
+		return diffCacheKey{}, fmt.Errorf("failed to resolve HEAD and %s: %v", sourceRef, err)
+	}
+	commits := strings.Fields(string(output))
+	if len(commits) != 2 {
+		return diffCacheKey{}, fmt.Errorf("unexpected rev-parse output %q", output)
+	}
+
+	status, err := s.operations.ExecuteGitWithTimeout(worktree.Path, diffCacheKeyTimeout, "status", "--porcelain=v1", "-z", "--untracked-files=all")
+	if err != nil {
+		return diffCacheKey{}, fmt.Errorf("failed to get status: %v", err)
+	}
+
+	hash := sha256.New()
+	hash.Write(status)
+	entries := bytes.Split(status, []byte{0})
+	for i := 0; i < len(entries); i++ {
+		entry := entries[i]
+		if len(entry) < 4 {
+			continue
+		}
+		// Renames and copies are followed by their original path, which needs no stat
+		if entry[0] == 'R' || entry[0] == 'C' {
+			i++
+		}
+		var stat [16]byte
+		if info, err := os.Lstat(filepath.Join(worktree.Path, string(entry[3:]))); err == nil {
+			binary.LittleEndian.PutUint64(stat[:8], uint64(info.Size()))
+			binary.LittleEndian.PutUint64(stat[8:], uint64(info.ModTime().UnixNano()))
+		}
+		hash.Write(stat[:])
+	}
+
+	return diffCacheKey{head: commits[0], index: hex.EncodeToString(hash.Sum(nil)), sourceTip: commits[1]}, nil
+}
+
+// cachedWorktreeDiff returns the cached diff of a worktree while its state is unchanged, and
+// computes and caches it otherwise
+func (s *GitService) cachedWorktreeDiff(worktree *models.Worktree) (*git.WorktreeDiffResponse, error) {
+	sourceRef := s.getSourceRef(worktree)
+
+	key, err := s.diffCacheKey(worktree, sourceRef)
+	if err != nil {
+		// The diff itself may still work, e.g. by fetching a missing source ref
+		logger.Debugf("🔍 Not caching diff for %s: %v", worktree.Name, err)
+		s.diffCache.recordMiss()
+		return s.computeWorktreeDiff(worktree, sourceRef)
+	}
+
+	diff, generation := s.diffCache.lookup(worktree.ID, key)
+	if diff != nil {
+		return diff, nil
+	}
+	diff, err = s.computeWorktreeDiff(worktree, sourceRef)
+	if err != nil {
+		return nil, err
+	}
+	s.diffCache.store(worktree.ID, key, generation, diff)
+	return diff, nil
+}
+
+// computeWorktreeDiff diffs a worktree against its source ref
+func (s *GitService) computeWorktreeDiff(worktree *models.Worktree, sourceRef string) (*git.WorktreeDiffResponse, error) {
+	done := s.timeOperation(worktree, OperationDiff)
+	result, err := s.gitWorktreeManager.GetWorktreeDiff(worktree, sourceRef, s.fetchLatestRefForDiff)
+	done(err)
+	if err != nil {
+		return nil, err
+	}
+
+	// Set the worktreeID since git WorktreeManager doesn't have access to it
+	result.WorktreeID = worktree.ID
+	result.AuthoredStats = s.classifyDiffFiles(worktree.Path, result.FileDiffs, false)
+	return result, nil
+}
+
+// fetchLatestRefForDiff is called by the WorktreeManager when a diff's source ref is missing
+func (s *GitService) fetchLatestRefForDiff(worktree *models.Worktree) error {
+	s.fetchLatestReference(worktree)
+	return nil
+}
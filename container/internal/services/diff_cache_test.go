@@ -0,0 +1,178 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/git"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// countingOperations counts the git subprocesses run through it
+type countingOperations struct {
+	git.Operations
+	calls atomic.Int64
+}
+
+func (o *countingOperations) ExecuteGit(workingDir string, args ...string) ([]byte, error) {
+	o.calls.Add(1)
+	return o.Operations.ExecuteGit(workingDir, args...)
+}
+
+func (o *countingOperations) ExecuteGitWithTimeout(workingDir string, timeout time.Duration, args ...string) ([]byte, error) {
+	o.calls.Add(1)
+	return o.Operations.ExecuteGitWithTimeout(workingDir, timeout, args...)
+}
+
+// newDiffTestService creates a worktree on feature/login with committed, staged and unstaged
+// changes against main
+func newDiffTestService(tb testing.TB) (*GitService, *countingOperations, string) {
+	tb.Helper()
+	root := tb.TempDir()
+	files := make(map[string]string)
+	for i := 0; i < 5; i++ {
+		files[fmt.Sprintf("file%d.go", i)] = "package felix\n"
+	}
+	repoPath := initTestRepo(tb, filepath.Join(root, "repo"), files)
+
+	runTestGit(tb, repoPath, "checkout", "-b", "feature/login")
+	for i := 0; i < 5; i++ {
+		require.NoError(tb, os.WriteFile(filepath.Join(repoPath, fmt.Sprintf("file%d.go", i)), []byte("package felix\n\nfunc Login() {}\n"), 0644))
+	}
+	runTestGit(tb, repoPath, "commit", "-am", "Add login")
+	require.NoError(tb, os.WriteFile(filepath.Join(repoPath, "file0.go"), []byte("package felix\n\nfunc Logout() {}\n"), 0644))
+	require.NoError(tb, os.WriteFile(filepath.Join(repoPath, "staged.go"), []byte("package felix\n"), 0644))
+	runTestGit(tb, repoPath, "add", "staged.go")
+
+	s := newTestGitService(tb, root)
+	ops := &countingOperations{Operations: s.operations}
+	s.operations, s.gitWorktreeManager = ops, git.NewWorktreeManager(ops)
+	stateManager := s.stateManager
+	require.NoError(tb, stateManager.AddRepository(&models.Repository{ID: "local/repo", Path: repoPath, DefaultBranch: "main", Available: true}))
+	require.NoError(tb, stateManager.AddWorktree(&models.Worktree{
+		ID: "wt-felix", RepoID: "local/repo", Name: "felix", Path: repoPath, Branch: "feature/login", SourceBranch: "main",
+	}))
+	return s, ops, repoPath
+}
+
+func TestWorktreeDiffCache(t *testing.T) {
+	s, ops, repoPath := newDiffTestService(t)
+
+	first, err := s.GetWorktreeDiff("wt-felix")
+	require.NoError(t, err)
+	assert.Len(t, first.FileDiffs, 5)
+	computeCalls := ops.calls.Load()
+
+	// Unchanged worktrees are answered from the cache with only the key lookups
+	ops.calls.Store(0)
+	second, err := s.GetWorktreeDiff("wt-felix")
+	require.NoError(t, err)
+	assert.Same(t, first, second)
+	assert.Equal(t, int64(2), ops.calls.Load())
+	assert.Less(t, ops.calls.Load(), computeCalls)
+
+	// Editing a file that is already modified changes the key even though git status doesn't
+	require.NoError(t, os.WriteFile(filepath.Join(repoPath, "file0.go"), []byte("package felix\n\nfunc Logout() { return }\n"), 0644))
+	third, err := s.GetWorktreeDiff("wt-felix")
+	require.NoError(t, err)
+	assert.NotSame(t, second, third)
+	assert.Contains(t, third.FileDiffs[0].NewContent+third.FileDiffs[0].DiffText, "return")
+
+	// File watcher changes and git operations drop the cached diff
+	s.diffCache.invalidate("wt-felix")
+	s.timeOperation(&models.Worktree{ID: "wt-felix", RepoID: "local/repo", Path: repoPath}, OperationSync)(nil)
+	fourth, err := s.GetWorktreeDiff("wt-felix")
+	require.NoError(t, err)
+	assert.NotSame(t, third, fourth)
+
+	stats := s.GetDiffCacheStats()
+	assert.Equal(t, DiffCacheStats{Entries: 1, Hits: 1, Misses: 3, Invalidations: 1}, stats)
+}
+
+func TestWorktreeDiffCacheSharesConcurrentRequests(t *testing.T) {
+	s, _, _ := newDiffTestService(t)
+
+	var wg sync.WaitGroup
+	diffs := make([]*git.WorktreeDiffResponse, 8)
+	for i := range diffs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			diff, err := s.GetWorktreeDiff("wt-felix")
+			assert.NoError(t, err)
+			diffs[i] = diff
+		}(i)
+	}
+	wg.Wait()
+
+	for _, diff := range diffs {
+		assert.Same(t, diffs[0], diff)
+	}
+	stats := s.GetDiffCacheStats()
+	assert.Equal(t, int64(1), stats.Misses, "the diff is computed once")
+	assert.Equal(t, int64(len(diffs)), stats.Hits+stats.Misses+stats.Shared)
+}
+
+// BenchmarkWorktreeDiffPolling measures the git subprocesses run while the UI polls the diff of
+// an unchanged worktree, next to what computing the diff on every request runs
+func BenchmarkWorktreeDiffPolling(b *testing.B) {
+	s, ops, _ := newDiffTestService(b)
+	worktree, _ := s.stateManager.GetWorktree("wt-felix")
+
+	ops.calls.Store(0)
+	if _, err := s.computeWorktreeDiff(worktree, s.getSourceRef(worktree)); err != nil {
+		b.Fatal(err)
+	}
+	uncached := ops.calls.Load()
+
+	ops.calls.Store(0)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := s.GetWorktreeDiff("wt-felix"); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.StopTimer()
+
+	b.ReportMetric(float64(ops.calls.Load())/float64(b.N), "git-calls/op")
+	b.ReportMetric(float64(uncached), "uncached-git-calls/op")
+}
+
+func TestWorktreeDiffSummaryPages(t *testing.T) {
+	s, _, _ := newDiffTestService(t)
+
+	var paths []string
+	for offset := 0; ; offset += 2 {
+		page, err := s.GetWorktreeDiffSummary("wt-felix", 2, offset, false)
+		require.NoError(t, err)
+		assert.Equal(t, 5, page.TotalFiles)
+		for _, file := range page.Files {
+			paths = append(paths, file.FilePath)
+		}
+		if !page.HasMore {
+			break
+		}
+	}
+	assert.Equal(t, []string{"file0.go", "file1.go", "file2.go", "file3.go", "file4.go"}, paths)
+
+	past, err := s.GetWorktreeDiffSummary("wt-felix", 0, 10, false)
+	require.NoError(t, err)
+	assert.Empty(t, past.Files)
+	assert.Equal(t, defaultDiffSummaryLimit, past.Limit)
+
+	file, err := s.GetWorktreeFileDiff("wt-felix", "file0.go")
+	require.NoError(t, err)
+	assert.Contains(t, file.DiffText, "+func Logout() {}")
+
+	_, err = s.GetWorktreeDiffSummary("wt-felix", -1, 0, false)
+	assert.ErrorContains(t, err, "invalid page")
+	_, err = s.GetWorktreeFileDiff("wt-missing", "file0.go")
+	assert.ErrorContains(t, err, "worktree not found")
+}
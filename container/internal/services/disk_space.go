@@ -0,0 +1,212 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+const (
+	// diskCheckInterval is how often free space on the workspace volume is measured
+	diskCheckInterval = 30 * time.Second
+	// defaultDiskWarningMB is the free space below which the volume counts as nearly full,
+	// configurable via CATNIP_DISK_WARNING_MB
+	defaultDiskWarningMB = 2048
+	// diskReserveBytes is kept free on top of every estimate, so state files and git locks can
+	// still be written after a heavy operation
+	diskReserveBytes = 256 << 20
+	// cloneEstimateBytes is assumed for clones, whose size isn't known until they finish
+	cloneEstimateBytes = 512 << 20
+	// minWorktreeEstimateBytes is assumed for worktrees of repositories not measured yet
+	minWorktreeEstimateBytes = 64 << 20
+	// checkpointPauseFiles is the number of changed files above which checkpoints are skipped
+	// while the volume is nearly full
+	checkpointPauseFiles = 500
+)
+
+// Disk guard states
+const (
+	DiskStateOK      = "ok"
+	DiskStateLow     = "low"
+	DiskStateUnknown = "unknown"
+)
+
+// ErrInsufficientDiskSpace is returned, wrapped in an InsufficientDiskSpaceError, when an
+// operation is refused because the workspace volume is too full for it
+var ErrInsufficientDiskSpace = errors.New("insufficient disk space")
+
+// InsufficientDiskSpaceError describes an operation refused by the disk guard
+type InsufficientDiskSpaceError struct {
+	Operation string
+	Path      string
+	// Estimated bytes the operation needs, including the reserve
+	Required uint64
+	// Bytes free on the volume
+	Available uint64
+}
+
+func (e *InsufficientDiskSpaceError) Error() string {
+	return fmt.Sprintf("insufficient disk space to %s: about %d MiB needed on %s but only %d MiB available",
+		e.Operation, e.Required>>20, e.Path, e.Available>>20)
+}
+
+func (e *InsufficientDiskSpaceError) Unwrap() error {
+	return ErrInsufficientDiskSpace
+}
+
+// DiskStatus is the last measurement of the workspace volume
+type DiskStatus struct {
+	Path         string `json:"path"`
+	FreeBytes    uint64 `json:"free_bytes"`
+	TotalBytes   uint64 `json:"total_bytes"`
+	WarningBytes uint64 `json:"warning_bytes"`
+	// ok, low (below the warning threshold) or unknown (the volume couldn't be measured)
+	State string `json:"state"`
+	// What is paused while the volume is low
+	Warning   string    `json:"warning,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// diskGuard holds the last disk status of the git service
+type diskGuard struct {
+	mu     sync.RWMutex
+	status DiskStatus
+	// Replaced in tests, statDiskSpace when nil
+	statfs func(path string) (free, total uint64, err error)
+}
+
+// loadDiskWarningBytes reads the warning threshold from CATNIP_DISK_WARNING_MB, keeping the
+// default for unset or invalid values
+func loadDiskWarningBytes() uint64 {
+	if value := os.Getenv("CATNIP_DISK_WARNING_MB"); value != "" {
+		if mb, err := strconv.ParseUint(value, 10, 64); err == nil {
+			return mb << 20
+		}
+		logger.Warnf("⚠️  Ignoring invalid CATNIP_DISK_WARNING_MB value %q", value)
+	}
+	return defaultDiskWarningMB << 20
+}
+
+// DiskStatus returns the last measurement of the workspace volume
+func (s *GitService) DiskStatus() DiskStatus {
+	s.disk.mu.RLock()
+	defer s.disk.mu.RUnlock()
+	if s.disk.status.State == "" {
+		return DiskStatus{Path: getWorkspaceDir(), State: DiskStateUnknown}
+	}
+	return s.disk.status
+}
+
+// refreshDiskStatus measures the workspace volume. Crossing the warning threshold in either
+// direction is logged and broadcast.
+func (s *GitService) refreshDiskStatus() DiskStatus {
+	path := getWorkspaceDir()
+	status := DiskStatus{Path: path, WarningBytes: loadDiskWarningBytes(), State: DiskStateUnknown, CheckedAt: time.Now()}
+
+	statfs := s.disk.statfs
+	if statfs == nil {
+		statfs = statDiskSpace
+	}
+	if free, total, err := statfs(path); err != nil {
+		logger.Debugf("⚠️  Failed to measure free space on %s: %v", path, err)
+	} else {
+		status.FreeBytes, status.TotalBytes = free, total
+		status.State = DiskStateOK
+		if free < status.WarningBytes {
+			status.State = DiskStateLow
+			status.Warning = fmt.Sprintf("Only %d MiB free on %s, large checkpoints and snapshots are paused", free>>20, path)
+		}
+	}
+
+	s.disk.mu.Lock()
+	previous := s.disk.status.State
+	s.disk.status = status
+	s.disk.mu.Unlock()
+
+	if status.State == previous || status.State == DiskStateUnknown {
+		return status
+	}
+	if status.State == DiskStateLow {
+		logger.Warnf("💾 %s", status.Warning)
+	} else if previous == DiskStateLow {
+		logger.Infof("💾 %d MiB free on %s again, resuming checkpoints and snapshots", status.FreeBytes>>20, path)
+	} else {
+		return status
+	}
+	if s.eventsEmitter != nil {
+		s.eventsEmitter.EmitDiskStatusChanged(status)
+	}
+	return status
+}
+
+// ensureDiskSpace refuses operation with an InsufficientDiskSpaceError when the workspace
+// volume has less than required bytes plus the reserve free. Volumes that can't be measured
+// are not guarded.
+func (s *GitService) ensureDiskSpace(operation string, required uint64) error {
+	status := s.refreshDiskStatus()
+	if status.State == DiskStateUnknown {
+		return nil
+	}
+	required += diskReserveBytes
+	if status.FreeBytes < required {
+		logger.Warnf("💾 Refusing to %s: about %d MiB needed, %d MiB free", operation, required>>20, status.FreeBytes>>20)
+		return &InsufficientDiskSpaceError{Operation: operation, Path: status.Path, Required: required, Available: status.FreeBytes}
+	}
+	return nil
+}
+
+// pausedForDiskSpace returns an InsufficientDiskSpaceError for a non-essential write while the
+// workspace volume is below the warning threshold, nil otherwise
+func (s *GitService) pausedForDiskSpace(operation string) error {
+	status := s.DiskStatus()
+	if status.State != DiskStateLow {
+		return nil
+	}
+	return &InsufficientDiskSpaceError{Operation: operation, Path: status.Path, Required: status.WarningBytes, Available: status.FreeBytes}
+}
+
+// worktreeEstimateBytes guesses how much a new worktree of repo takes from the measured size
+// of its objects
+func worktreeEstimateBytes(repo *models.Repository) uint64 {
+	if repo.Size != nil && repo.Size.LocalKB<<10 > minWorktreeEstimateBytes {
+		return uint64(repo.Size.LocalKB) << 10
+	}
+	return minWorktreeEstimateBytes
+}
+
+// countChangedFiles returns the number of entries git status lists for a worktree
+func (s *GitService) countChangedFiles(worktreePath string) (int, error) {
+	output, err := s.operations.ExecuteGit(worktreePath, "status", "--porcelain", "--untracked-files=all")
+	if err != nil {
+		return 0, err
+	}
+	trimmed := strings.TrimSpace(string(output))
+	if trimmed == "" {
+		return 0, nil
+	}
+	return strings.Count(trimmed, "\n") + 1, nil
+}
+
+// startDiskMonitor measures the workspace volume until the service stops
+func (s *GitService) startDiskMonitor() {
+	s.refreshDiskStatus()
+
+	ticker := time.NewTicker(diskCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.refreshDiskStatus()
+		case <-s.stopCh:
+			return
+		}
+	}
+}
@@ -0,0 +1,19 @@
+//go:build !linux && !darwin
+
+package services
+
+import (
+	"errors"
+	"io/fs"
+)
+
+// statDiskSpace isn't supported here; the disk guard reports an unknown state and lets every
+// operation through
+func statDiskSpace(path string) (free, total uint64, err error) {
+	return 0, 0, errors.New("disk space checks are not supported on this platform")
+}
+
+// fileDiskUsage counts a file's apparent size; hard links can't be told apart here
+func fileDiskUsage(info fs.FileInfo) (int64, fileID, bool) {
+	return info.Size(), fileID{}, false
+}
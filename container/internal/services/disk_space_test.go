@@ -0,0 +1,116 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// diskRecorder records disk status events; other events are not expected
+type diskRecorder struct {
+	EventsEmitter
+	mu     sync.Mutex
+	states []string
+}
+
+func (r *diskRecorder) EmitDiskStatusChanged(status DiskStatus) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.states = append(r.states, status.State)
+}
+
+func TestDiskGuard(t *testing.T) {
+	t.Setenv("CATNIP_DISK_WARNING_MB", "256")
+
+	root := t.TempDir()
+	repoPath := initTestRepo(t, filepath.Join(root, "repo"), nil)
+
+	s := newTestGitService(t, root)
+	stateManager := s.stateManager
+	require.NoError(t, stateManager.AddRepository(&models.Repository{
+		ID: "local/repo", Path: repoPath, Size: &models.RepositorySize{LocalKB: 300 << 10},
+	}))
+	require.NoError(t, stateManager.AddWorktree(&models.Worktree{
+		ID: "wt-felix", RepoID: "local/repo", Name: "felix", Path: repoPath, Branch: "main", SourceBranch: "main",
+	}))
+
+	var free uint64 = 4 << 30
+	events := &diskRecorder{}
+	s.eventsEmitter = events
+	s.disk.statfs = func(string) (uint64, uint64, error) { return free, 8 << 30, nil }
+
+	assert.Equal(t, DiskStateUnknown, s.DiskStatus().State, "nothing measured yet")
+	status := s.refreshDiskStatus()
+	assert.Equal(t, DiskStateOK, status.State)
+	assert.Equal(t, uint64(4<<30), status.FreeBytes)
+	assert.Equal(t, uint64(8<<30), status.TotalBytes)
+	assert.Equal(t, uint64(256<<20), status.WarningBytes)
+	assert.Empty(t, status.Warning)
+	assert.Empty(t, events.states, "starting healthy is not news")
+
+	// Estimates include the reserve: a clone is refused where a bundle of the measured
+	// repository still fits
+	free = 600 << 20
+	err := s.ensureDiskSpace("clone local/repo", cloneEstimateBytes)
+	var diskErr *InsufficientDiskSpaceError
+	require.ErrorAs(t, err, &diskErr)
+	assert.True(t, errors.Is(err, ErrInsufficientDiskSpace))
+	assert.Equal(t, "clone local/repo", diskErr.Operation)
+	assert.Equal(t, uint64(cloneEstimateBytes+diskReserveBytes), diskErr.Required)
+	assert.Equal(t, uint64(600<<20), diskErr.Available)
+	assert.Contains(t, err.Error(), "about 768 MiB needed")
+	assert.Contains(t, err.Error(), "only 600 MiB available")
+	_, err = s.GetWorktreeBundle("wt-felix", "", "test")
+	require.NoError(t, err)
+	assert.Empty(t, events.states)
+
+	// Crossing the warning threshold pauses snapshots and large checkpoints
+	free = 200 << 20
+	status = s.refreshDiskStatus()
+	assert.Equal(t, DiskStateLow, status.State)
+	assert.Contains(t, status.Warning, "Only 200 MiB free")
+	assert.Equal(t, []string{DiskStateLow}, events.states)
+	s.refreshDiskStatus()
+	assert.Len(t, events.states, 1, "staying low is reported once")
+
+	worktree, _ := stateManager.GetWorktree("wt-felix")
+	_, err = s.snapshotWorktreeTree(worktree, true)
+	assert.ErrorIs(t, err, ErrInsufficientDiskSpace)
+	_, err = s.GetWorktreeBundle("wt-felix", "", "test")
+	assert.ErrorIs(t, err, ErrInsufficientDiskSpace)
+
+	for i := 0; i <= checkpointPauseFiles; i++ {
+		require.NoError(t, os.WriteFile(filepath.Join(repoPath, fmt.Sprintf("file-%d.txt", i)), []byte("data"), 0644))
+	}
+	hash, err := s.GitAddCommitGetHash(repoPath, "Large checkpoint")
+	require.NoError(t, err)
+	assert.Empty(t, hash, "large checkpoints are skipped")
+	assert.NotEmpty(t, runTestGit(t, repoPath, "status", "--porcelain"), "changes stay uncommitted")
+
+	// Small checkpoints still go through
+	for i := 10; i <= checkpointPauseFiles; i++ {
+		require.NoError(t, os.Remove(filepath.Join(repoPath, fmt.Sprintf("file-%d.txt", i))))
+	}
+	hash, err = s.GitAddCommitGetHash(repoPath, "Small checkpoint")
+	require.NoError(t, err)
+	assert.NotEmpty(t, hash)
+
+	// Recovering resumes everything
+	free = 2 << 30
+	assert.Equal(t, DiskStateOK, s.refreshDiskStatus().State)
+	assert.Equal(t, []string{DiskStateLow, DiskStateOK}, events.states)
+	_, err = s.snapshotWorktreeTree(worktree, true)
+	assert.NoError(t, err)
+
+	// Volumes that can't be measured are not guarded
+	s.disk.statfs = func(string) (uint64, uint64, error) { return 0, 0, errors.New("unsupported") }
+	assert.NoError(t, s.ensureDiskSpace("clone local/repo", cloneEstimateBytes))
+	assert.Equal(t, DiskStateUnknown, s.DiskStatus().State)
+}
@@ -0,0 +1,28 @@
+//go:build linux || darwin
+
+package services
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// statDiskSpace returns the bytes available to unprivileged users and the total size of the
+// filesystem holding path
+func statDiskSpace(path string) (free, total uint64, err error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, 0, err
+	}
+	return uint64(stat.Bavail) * uint64(stat.Bsize), uint64(stat.Blocks) * uint64(stat.Bsize), nil
+}
+
+// fileDiskUsage returns the bytes a file occupies on disk as du counts them, the allocated
+// blocks, and its identity when it has other hard links that must not be counted again
+func fileDiskUsage(info fs.FileInfo) (int64, fileID, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return info.Size(), fileID{}, false
+	}
+	return int64(stat.Blocks) * 512, fileID{dev: uint64(stat.Dev), ino: uint64(stat.Ino)}, stat.Nlink > 1
+}
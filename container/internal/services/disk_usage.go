@@ -0,0 +1,274 @@
+package services
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+const (
+	// defaultDiskUsageRefresh is how often repositories and worktrees are measured in the
+	// background, configurable in minutes via CATNIP_DISK_USAGE_REFRESH_MINUTES (0 disables)
+	defaultDiskUsageRefresh = 10 * time.Minute
+	// minDiskUsageTTL is how long a measurement is served before GetDiskUsage measures again;
+	// longer refresh intervals stretch it so background refreshes keep requests from waiting
+	minDiskUsageTTL = 15 * time.Minute
+	// diskUsageInitialDelay postpones the first background measurement until startup work has
+	// settled
+	diskUsageInitialDelay = 2 * time.Minute
+)
+
+// fileID identifies a file across hard links
+type fileID struct {
+	dev uint64
+	ino uint64
+}
+
+// diskUsageCache holds the last disk usage measurement of the git service. Its zero value is
+// ready to use.
+type diskUsageCache struct {
+	measureMu  sync.Mutex // Serializes measurements
+	mu         sync.RWMutex
+	usage      *models.DiskUsage
+	refreshing atomic.Bool // Set while a refresh started by a listing runs
+}
+
+func (c *diskUsageCache) get() *models.DiskUsage {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.usage
+}
+
+func (c *diskUsageCache) set(usage *models.DiskUsage) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.usage = usage
+}
+
+// diskUsageRefreshInterval reads CATNIP_DISK_USAGE_REFRESH_MINUTES, keeping the default for
+// unset or invalid values. Zero disables the background refresh.
+func diskUsageRefreshInterval() time.Duration {
+	if value := os.Getenv("CATNIP_DISK_USAGE_REFRESH_MINUTES"); value != "" {
+		if minutes, err := strconv.Atoi(value); err == nil && minutes >= 0 {
+			return time.Duration(minutes) * time.Minute
+		}
+		logger.Warnf("⚠️  Ignoring invalid CATNIP_DISK_USAGE_REFRESH_MINUTES value %q", value)
+	}
+	return defaultDiskUsageRefresh
+}
+
+// diskUsageTTL is how long a measurement stays fresh: half again the refresh interval, so the
+// next background refresh lands before it expires, and at least minDiskUsageTTL
+func diskUsageTTL() time.Duration {
+	return max(minDiskUsageTTL, diskUsageRefreshInterval()*3/2)
+}
+
+// diskUsageWalker sums the space of directory trees like du does: allocated blocks rather
+// than apparent sizes, and files with several hard links only once across all trees walked
+type diskUsageWalker struct {
+	seen map[fileID]bool
+}
+
+// size returns the bytes under root, leaving out the entries skip returns true for
+func (w *diskUsageWalker) size(root string, skip func(path string) bool) int64 {
+	var total int64
+	_ = filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			// Unreadable entries count as empty rather than failing the whole measurement
+			return nil
+		}
+		if path != root && skip(path) {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil
+		}
+		bytes, id, linked := fileDiskUsage(info)
+		if linked {
+			if w.seen[id] {
+				return nil
+			}
+			w.seen[id] = true
+		}
+		total += bytes
+		return nil
+	})
+	return total
+}
+
+// resolveDiskUsagePath cleans a path and resolves its symlinks, so nested repositories and
+// worktrees are recognized however their paths were recorded
+func resolveDiskUsagePath(path string) string {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return resolved
+	}
+	return filepath.Clean(path)
+}
+
+// measureDiskUsage walks every repository and worktree. Repositories are walked first, without
+// the worktrees nested in them, so objects shared through hard links count towards the
+// repository; a worktree's own .git, which only points at the repository, isn't counted.
+func (s *GitService) measureDiskUsage() *models.DiskUsage {
+	started := time.Now()
+	repos := s.stateManager.GetAllRepositories()
+	worktrees := s.stateManager.GetAllWorktrees()
+
+	worktreePaths := make(map[string]bool, len(worktrees))
+	for _, worktree := range worktrees {
+		if worktree.Path != "" {
+			worktreePaths[resolveDiskUsagePath(worktree.Path)] = true
+		}
+	}
+	repoIDs := make([]string, 0, len(repos))
+	for id := range repos {
+		repoIDs = append(repoIDs, id)
+	}
+	sort.Strings(repoIDs)
+
+	walker := &diskUsageWalker{seen: make(map[fileID]bool)}
+	byRepo := make(map[string]*models.RepositoryDiskUsage, len(repos))
+	repoPaths := make(map[string]bool, len(repos))
+	for _, id := range repoIDs {
+		repo := repos[id]
+		entry := &models.RepositoryDiskUsage{RepoID: id, Path: repo.Path, Worktrees: []models.WorktreeDiskUsage{}}
+		if repo.Path != "" {
+			path := resolveDiskUsagePath(repo.Path)
+			entry.Bytes = walker.size(path, func(path string) bool { return worktreePaths[path] })
+			repoPaths[path] = true
+		}
+		byRepo[id] = entry
+	}
+
+	worktreeIDs := make([]string, 0, len(worktrees))
+	for id := range worktrees {
+		worktreeIDs = append(worktreeIDs, id)
+	}
+	sort.Strings(worktreeIDs)
+	for _, id := range worktreeIDs {
+		worktree := worktrees[id]
+		entry := models.WorktreeDiskUsage{WorktreeID: id, Name: worktree.Name, Path: worktree.Path}
+		if _, err := os.Stat(worktree.Path); err != nil {
+			entry.Missing = true
+		} else if path := resolveDiskUsagePath(worktree.Path); !repoPaths[path] {
+			// A worktree that is the repository's own checkout was counted with the repository
+			gitDir := filepath.Join(path, ".git")
+			entry.Bytes = walker.size(path, func(path string) bool { return path == gitDir })
+		}
+
+		repo, exists := byRepo[worktree.RepoID]
+		if !exists {
+			repo = &models.RepositoryDiskUsage{RepoID: worktree.RepoID, Worktrees: []models.WorktreeDiskUsage{}}
+			byRepo[worktree.RepoID] = repo
+		}
+		repo.Worktrees = append(repo.Worktrees, entry)
+		repo.WorktreeBytes += entry.Bytes
+	}
+
+	usage := &models.DiskUsage{Repositories: make([]models.RepositoryDiskUsage, 0, len(byRepo))}
+	for _, repo := range byRepo {
+		repo.TotalBytes = repo.Bytes + repo.WorktreeBytes
+		sort.SliceStable(repo.Worktrees, func(i, j int) bool {
+			if repo.Worktrees[i].Bytes != repo.Worktrees[j].Bytes {
+				return repo.Worktrees[i].Bytes > repo.Worktrees[j].Bytes
+			}
+			return repo.Worktrees[i].Name < repo.Worktrees[j].Name
+		})
+		usage.Repositories = append(usage.Repositories, *repo)
+		usage.RepositoryBytes += repo.Bytes
+		usage.WorktreeBytes += repo.WorktreeBytes
+	}
+	sort.Slice(usage.Repositories, func(i, j int) bool {
+		if usage.Repositories[i].TotalBytes != usage.Repositories[j].TotalBytes {
+			return usage.Repositories[i].TotalBytes > usage.Repositories[j].TotalBytes
+		}
+		return usage.Repositories[i].RepoID < usage.Repositories[j].RepoID
+	})
+	usage.TotalBytes = usage.RepositoryBytes + usage.WorktreeBytes
+	usage.MeasuredAt = time.Now()
+	usage.DurationMS = usage.MeasuredAt.Sub(started).Milliseconds()
+	return usage
+}
+
+// GetDiskUsage returns how much space each repository and worktree takes on disk, measuring
+// again only when the last measurement is older than its TTL
+func (s *GitService) GetDiskUsage() *models.DiskUsage {
+	if usage := s.diskUsage.get(); usage != nil && time.Since(usage.MeasuredAt) < diskUsageTTL() {
+		return usage
+	}
+	return s.RefreshDiskUsage()
+}
+
+// RefreshDiskUsage measures every repository and worktree now. Concurrent calls share one
+// measurement.
+func (s *GitService) RefreshDiskUsage() *models.DiskUsage {
+	requested := time.Now()
+	s.diskUsage.measureMu.Lock()
+	defer s.diskUsage.measureMu.Unlock()
+	if usage := s.diskUsage.get(); usage != nil && usage.MeasuredAt.After(requested) {
+		return usage
+	}
+
+	usage := s.measureDiskUsage()
+	s.diskUsage.set(usage)
+	logger.Debugf("💾 Measured disk usage of %d repositories in %dms: %d MiB", len(usage.Repositories), usage.DurationMS, usage.TotalBytes>>20)
+	return usage
+}
+
+// DiskUsageBytes returns the bytes of each repository and worktree from the last measurement,
+// keyed by ID, for embedding in listings without making them wait. A missing or expired
+// measurement is refreshed in the background.
+func (s *GitService) DiskUsageBytes() (repos, worktrees map[string]int64) {
+	repos, worktrees = make(map[string]int64), make(map[string]int64)
+	usage := s.diskUsage.get()
+	if (usage == nil || time.Since(usage.MeasuredAt) >= diskUsageTTL()) && s.diskUsage.refreshing.CompareAndSwap(false, true) {
+		go func() {
+			defer s.diskUsage.refreshing.Store(false)
+			s.RefreshDiskUsage()
+		}()
+	}
+	if usage == nil {
+		return repos, worktrees
+	}
+	for _, repo := range usage.Repositories {
+		repos[repo.RepoID] = repo.Bytes
+		for _, worktree := range repo.Worktrees {
+			worktrees[worktree.WorktreeID] = worktree.Bytes
+		}
+	}
+	return repos, worktrees
+}
+
+// startDiskUsageRefresher keeps the disk usage measurement warm until the service stops
+func (s *GitService) startDiskUsageRefresher() {
+	interval := diskUsageRefreshInterval()
+	if interval == 0 {
+		return
+	}
+	initial := time.NewTimer(diskUsageInitialDelay)
+	defer initial.Stop()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-initial.C:
+			s.RefreshDiskUsage()
+		case <-ticker.C:
+			s.RefreshDiskUsage()
+		case <-s.stopCh:
+			return
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package services
+
+import (
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+func TestDiskUsage(t *testing.T) {
+	s, stateManager, repoPath, worktreePath := newRecreateTestService(t)
+	writeRandom := func(path string, size int) {
+		data := make([]byte, size)
+		_, err := rand.Read(data)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(path, data, 0644))
+	}
+	writeRandom(filepath.Join(repoPath, "shared.bin"), 256<<10)
+	writeRandom(filepath.Join(worktreePath, "build.bin"), 128<<10)
+	require.NoError(t, os.Link(filepath.Join(repoPath, "shared.bin"), filepath.Join(worktreePath, "shared.bin")))
+	gonePath := filepath.Join(t.TempDir(), "gone")
+	require.NoError(t, stateManager.AddWorktree(&models.Worktree{ID: "wt-gone", RepoID: "local/repo", Name: "repo/gone", Path: gonePath}))
+
+	usage := s.GetDiskUsage()
+	require.Len(t, usage.Repositories, 1)
+	repo := usage.Repositories[0]
+	assert.Equal(t, "local/repo", repo.RepoID)
+	assert.GreaterOrEqual(t, repo.Bytes, int64(256<<10), "hard-linked files count with the repository")
+	require.Len(t, repo.Worktrees, 2)
+	felix := repo.Worktrees[0]
+	assert.Equal(t, "wt-felix", felix.WorktreeID)
+	assert.GreaterOrEqual(t, felix.Bytes, int64(128<<10))
+	assert.Less(t, felix.Bytes, int64(256<<10), "shared files aren't counted twice")
+	assert.Equal(t, models.WorktreeDiskUsage{WorktreeID: "wt-gone", Name: "repo/gone", Path: gonePath, Missing: true}, repo.Worktrees[1])
+	assert.Equal(t, felix.Bytes, repo.WorktreeBytes)
+	assert.Equal(t, repo.Bytes+repo.WorktreeBytes, usage.TotalBytes)
+
+	t.Run("Cached", func(t *testing.T) {
+		assert.Same(t, usage, s.GetDiskUsage())
+		refreshed := s.RefreshDiskUsage()
+		assert.NotSame(t, usage, refreshed)
+		assert.Same(t, refreshed, s.GetDiskUsage())
+	})
+
+	t.Run("BytesForListings", func(t *testing.T) {
+		repoBytes, worktreeBytes := s.DiskUsageBytes()
+		assert.Equal(t, repo.Bytes, repoBytes["local/repo"])
+		assert.Equal(t, felix.Bytes, worktreeBytes["wt-felix"])
+	})
+
+	t.Run("RefreshInterval", func(t *testing.T) {
+		assert.Equal(t, defaultDiskUsageRefresh, diskUsageRefreshInterval())
+		assert.Equal(t, minDiskUsageTTL, diskUsageTTL())
+		t.Setenv("CATNIP_DISK_USAGE_REFRESH_MINUTES", "60")
+		assert.Equal(t, 90*time.Minute, diskUsageTTL())
+		t.Setenv("CATNIP_DISK_USAGE_REFRESH_MINUTES", "soon")
+		assert.Equal(t, defaultDiskUsageRefresh, diskUsageRefreshInterval())
+	})
+}
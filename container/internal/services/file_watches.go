@@ -0,0 +1,404 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/vanpelt/catnip/internal/logger"
+)
+
+// Subsystems holding file watches
+const (
+	// WatchSubsystemStatusCache watches the git directory and root of each worktree
+	WatchSubsystemStatusCache = "status_cache"
+	// WatchSubsystemCommitSync watches the refs directories of each worktree
+	WatchSubsystemCommitSync = "commit_sync"
+	// WatchSubsystemClaudeMonitor watches the session titles log shared by all worktrees
+	WatchSubsystemClaudeMonitor = "claude_monitor"
+)
+
+// Why a subsystem polls a worktree instead of watching it
+const (
+	// WatchDegradedLimit means the kernel refused more watches or inotify instances
+	WatchDegradedLimit = "limit"
+	// WatchDegradedRationed means the watches were given up for a more active worktree
+	WatchDegradedRationed = "rationed"
+)
+
+// defaultWatchPollInterval is how often degraded worktrees are polled for changes
+const defaultWatchPollInterval = 10 * time.Second
+
+// inotifyGuidance explains how to raise the inotify limits. They aren't namespaced, so they
+// are raised on the Docker host or VM rather than in the container.
+const inotifyGuidance = "File watches hit the inotify limit, so some worktrees are polled for changes instead. " +
+	"Raise the limits on the Docker host or VM, e.g. `sudo sysctl -w fs.inotify.max_user_watches=524288 fs.inotify.max_user_instances=1024`, " +
+	"and persist them in /etc/sysctl.d/ to survive reboots."
+
+// WatchDegradation records a worktree whose changes a subsystem polls for because it couldn't
+// watch them
+type WatchDegradation struct {
+	// Empty when the subsystem's watch covers every worktree
+	WorktreePath string `json:"worktree_path,omitempty"`
+	Subsystem    string `json:"subsystem"`
+	// limit or rationed
+	Reason string    `json:"reason"`
+	Error  string    `json:"error,omitempty"`
+	Since  time.Time `json:"since"`
+}
+
+// WatchStatus reports the file watches catnip holds and the worktrees it polls instead
+type WatchStatus struct {
+	// Watched paths per subsystem
+	Watches map[string]int `json:"watches"`
+	Total   int            `json:"total"`
+	// Kernel limits from /proc/sys/fs/inotify, 0 when unknown
+	MaxUserWatches   int `json:"max_user_watches"`
+	MaxUserInstances int `json:"max_user_instances"`
+	// Watches or inotify instances refused by the kernel per subsystem since startup
+	LimitErrors         map[string]int     `json:"limit_errors"`
+	Degraded            []WatchDegradation `json:"degraded"`
+	PollIntervalSeconds int                `json:"poll_interval_seconds"`
+	// How to raise the inotify limits, while any watch is degraded
+	Guidance string `json:"guidance,omitempty"`
+}
+
+type watchKey struct {
+	subsystem    string
+	worktreePath string
+}
+
+// watchHolder is what one subsystem watches for one worktree
+type watchHolder struct {
+	paths map[string]bool
+	// Gives the watches up for polling, nil when the subsystem can't
+	demote func()
+}
+
+// watchAccounting tracks the file watches of all subsystems, so watch failures are visible and
+// watches can be rationed between worktrees when the kernel runs out of them
+type watchAccounting struct {
+	mu          sync.Mutex
+	holders     map[watchKey]*watchHolder
+	degraded    map[watchKey]*WatchDegradation
+	limitErrors map[string]int
+	// Last title event of a worktree, which decides who keeps real watches when rationing
+	activity func(worktreePath string) time.Time
+	// Replaced in tests
+	addWatch func(watcher *fsnotify.Watcher, path string) error
+}
+
+// fileWatches is shared by the subsystems of the server
+var fileWatches = newWatchAccounting()
+
+func newWatchAccounting() *watchAccounting {
+	return &watchAccounting{
+		holders:     make(map[watchKey]*watchHolder),
+		degraded:    make(map[watchKey]*WatchDegradation),
+		limitErrors: make(map[string]int),
+		addWatch: func(watcher *fsnotify.Watcher, path string) error {
+			return watcher.Add(path)
+		},
+	}
+}
+
+// FileWatchStatus reports the file watches of the server
+func FileWatchStatus() WatchStatus {
+	return fileWatches.status()
+}
+
+// isWatchLimitError reports whether err is the kernel refusing more watches (ENOSPC) or
+// inotify instances (EMFILE)
+func isWatchLimitError(err error) bool {
+	return errors.Is(err, syscall.ENOSPC) || errors.Is(err, syscall.EMFILE)
+}
+
+// watchPollInterval reads the polling interval of degraded worktrees from
+// CATNIP_WATCH_POLL_SECONDS, keeping the default for unset or invalid values
+func watchPollInterval() time.Duration {
+	if value := os.Getenv("CATNIP_WATCH_POLL_SECONDS"); value != "" {
+		if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+			return time.Duration(seconds) * time.Second
+		}
+	}
+	return defaultWatchPollInterval
+}
+
+// setActivitySource sets how the last activity of a worktree is looked up
+func (a *watchAccounting) setActivitySource(activity func(worktreePath string) time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.activity = activity
+}
+
+// lastActivity returns the last activity of a worktree, zero when unknown
+func (a *watchAccounting) lastActivity(worktreePath string) time.Time {
+	a.mu.Lock()
+	activity := a.activity
+	a.mu.Unlock()
+	if activity == nil || worktreePath == "" {
+		return time.Time{}
+	}
+	return activity(worktreePath)
+}
+
+// newWatcher creates an inotify instance for subsystem, rationing instances of less active
+// worktrees when the kernel refuses
+func (a *watchAccounting) newWatcher(subsystem, worktreePath string) (*fsnotify.Watcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil && isWatchLimitError(err) && a.reclaim(worktreePath) {
+		watcher, err = fsnotify.NewWatcher()
+	}
+	if err != nil && isWatchLimitError(err) {
+		a.countLimitError(subsystem)
+	}
+	return watcher, err
+}
+
+// add watches path for the worktree, rationing watches of less active worktrees when the kernel
+// refuses
+func (a *watchAccounting) add(subsystem, worktreePath string, watcher *fsnotify.Watcher, path string) error {
+	err := a.addWatch(watcher, path)
+	if err != nil && isWatchLimitError(err) && a.reclaim(worktreePath) {
+		err = a.addWatch(watcher, path)
+	}
+	if err != nil {
+		if isWatchLimitError(err) {
+			a.countLimitError(subsystem)
+		}
+		return err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	key := watchKey{subsystem, worktreePath}
+	holder := a.holders[key]
+	if holder == nil {
+		holder = &watchHolder{paths: make(map[string]bool)}
+		a.holders[key] = holder
+	}
+	holder.paths[path] = true
+	delete(a.degraded, key)
+	return nil
+}
+
+// setDemote registers how the watches of a worktree are given up when a more active worktree
+// needs them
+func (a *watchAccounting) setDemote(subsystem, worktreePath string, demote func()) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if holder := a.holders[watchKey{subsystem, worktreePath}]; holder != nil {
+		holder.demote = demote
+	}
+}
+
+// release forgets the watches and degradation of a worktree, once it is no longer watched or
+// polled
+func (a *watchAccounting) release(subsystem, worktreePath string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	key := watchKey{subsystem, worktreePath}
+	delete(a.holders, key)
+	delete(a.degraded, key)
+}
+
+// degrade records that a subsystem polls a worktree for changes instead of watching it
+func (a *watchAccounting) degrade(subsystem, worktreePath, reason string, err error) {
+	a.mu.Lock()
+	key := watchKey{subsystem, worktreePath}
+	delete(a.holders, key)
+	degradation := &WatchDegradation{WorktreePath: worktreePath, Subsystem: subsystem, Reason: reason, Since: time.Now()}
+	if err != nil {
+		degradation.Error = err.Error()
+	}
+	a.degraded[key] = degradation
+	a.mu.Unlock()
+
+	target := worktreePath
+	if target == "" {
+		target = "all worktrees"
+	}
+	logger.Warnf("👀 %s polls %s every %s instead of watching (%s): %v", subsystem, target, watchPollInterval(), reason, err)
+}
+
+func (a *watchAccounting) countLimitError(subsystem string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.limitErrors[subsystem]++
+}
+
+// reclaim demotes the watches of the least recently active worktree that was active less
+// recently than the one asking, returning whether any were given up
+func (a *watchAccounting) reclaim(worktreePath string) bool {
+	requester := a.lastActivity(worktreePath)
+	if requester.IsZero() {
+		return false
+	}
+
+	a.mu.Lock()
+	var candidate *watchHolder
+	var candidateKey watchKey
+	var candidateActivity time.Time
+	activity := a.activity
+	for key, holder := range a.holders {
+		if holder.demote == nil || key.worktreePath == worktreePath {
+			continue
+		}
+		last := activity(key.worktreePath)
+		if !last.Before(requester) {
+			continue
+		}
+		if candidate == nil || last.Before(candidateActivity) ||
+			(last.Equal(candidateActivity) && key.worktreePath < candidateKey.worktreePath) {
+			candidate, candidateKey, candidateActivity = holder, key, last
+		}
+	}
+	if candidate == nil {
+		a.mu.Unlock()
+		return false
+	}
+	delete(a.holders, candidateKey)
+	a.degraded[candidateKey] = &WatchDegradation{
+		WorktreePath: candidateKey.worktreePath,
+		Subsystem:    candidateKey.subsystem,
+		Reason:       WatchDegradedRationed,
+		Since:        time.Now(),
+	}
+	a.mu.Unlock()
+
+	logger.Infof("👀 Rationing file watches: %s polls %s so the more active %s can be watched", candidateKey.subsystem, candidateKey.worktreePath, worktreePath)
+	candidate.demote()
+	return true
+}
+
+// degradations returns how the watches of a worktree are degraded, including those of watches
+// covering every worktree
+func (a *watchAccounting) degradations(worktreePath string) []WatchDegradation {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var result []WatchDegradation
+	for key, degradation := range a.degraded {
+		if key.worktreePath == worktreePath || key.worktreePath == "" {
+			result = append(result, *degradation)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Subsystem < result[j].Subsystem })
+	return result
+}
+
+// degradedSince returns when a subsystem started polling a worktree, zero while it is watched
+func (a *watchAccounting) degradedSince(subsystem, worktreePath string) time.Time {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if degradation := a.degraded[watchKey{subsystem, worktreePath}]; degradation != nil {
+		return degradation.Since
+	}
+	return time.Time{}
+}
+
+func (a *watchAccounting) status() WatchStatus {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	status := WatchStatus{
+		Watches:             make(map[string]int),
+		LimitErrors:         make(map[string]int, len(a.limitErrors)),
+		Degraded:            make([]WatchDegradation, 0, len(a.degraded)),
+		MaxUserWatches:      readInotifyLimit("max_user_watches"),
+		MaxUserInstances:    readInotifyLimit("max_user_instances"),
+		PollIntervalSeconds: int(watchPollInterval() / time.Second),
+	}
+	// Subsystems sharing a watcher between worktrees hold each path once
+	paths := make(map[string]map[string]bool)
+	for key, holder := range a.holders {
+		if paths[key.subsystem] == nil {
+			paths[key.subsystem] = make(map[string]bool)
+		}
+		for path := range holder.paths {
+			paths[key.subsystem][path] = true
+		}
+	}
+	for subsystem, watched := range paths {
+		status.Watches[subsystem] = len(watched)
+		status.Total += len(watched)
+	}
+	for subsystem, count := range a.limitErrors {
+		status.LimitErrors[subsystem] = count
+	}
+	for _, degradation := range a.degraded {
+		status.Degraded = append(status.Degraded, *degradation)
+	}
+	sort.Slice(status.Degraded, func(i, j int) bool {
+		if status.Degraded[i].Subsystem != status.Degraded[j].Subsystem {
+			return status.Degraded[i].Subsystem < status.Degraded[j].Subsystem
+		}
+		return status.Degraded[i].WorktreePath < status.Degraded[j].WorktreePath
+	})
+	if len(status.Degraded) > 0 {
+		status.Guidance = inotifyGuidance
+	}
+	return status
+}
+
+// readInotifyLimit reads a limit from /proc/sys/fs/inotify, 0 when unknown
+func readInotifyLimit(name string) int {
+	data, err := os.ReadFile("/proc/sys/fs/inotify/" + name)
+	if err != nil {
+		return 0
+	}
+	limit, _ := strconv.Atoi(strings.TrimSpace(string(data)))
+	return limit
+}
+
+// pollForChanges calls onChange whenever fingerprint changes, checking every watchPollInterval
+// until stop is closed or the fingerprint becomes empty (the path is gone). onTick runs after
+// each check, if set.
+func pollForChanges(stop <-chan struct{}, fingerprint func() string, onChange func(), onTick func()) {
+	ticker := time.NewTicker(watchPollInterval())
+	defer ticker.Stop()
+
+	last := fingerprint()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			current := fingerprint()
+			if current == "" {
+				return
+			}
+			if current != last {
+				last = current
+				onChange()
+			}
+			if onTick != nil {
+				onTick()
+			}
+		}
+	}
+}
+
+// pathFingerprint combines the modification times and sizes of paths, empty when the first
+// doesn't exist
+func pathFingerprint(paths ...string) string {
+	var fingerprint strings.Builder
+	for i, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			if i == 0 {
+				return ""
+			}
+			fingerprint.WriteString("-;")
+			continue
+		}
+		fmt.Fprintf(&fingerprint, "%d:%d;", info.ModTime().UnixNano(), info.Size())
+	}
+	return fingerprint.String()
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchAccounting(t *testing.T) {
+	// The fake kernel hands out 3 watches
+	held := 0
+	a := newWatchAccounting()
+	a.addWatch = func(_ *fsnotify.Watcher, _ string) error {
+		if held >= 3 {
+			return syscall.ENOSPC
+		}
+		held++
+		return nil
+	}
+	now := time.Now()
+	activity := map[string]time.Time{
+		"/workspace/repo/felix": now.Add(-time.Hour),
+		"/workspace/repo/luna":  now,
+	}
+	a.setActivitySource(func(path string) time.Time { return activity[path] })
+
+	require.NoError(t, a.add(WatchSubsystemStatusCache, "/workspace/repo/felix", nil, "/workspace/repo/felix/.git"))
+	require.NoError(t, a.add(WatchSubsystemStatusCache, "/workspace/repo/felix", nil, "/workspace/repo/felix"))
+	var demoted []string
+	a.setDemote(WatchSubsystemStatusCache, "/workspace/repo/felix", func() {
+		held -= 2
+		demoted = append(demoted, "/workspace/repo/felix")
+	})
+	require.NoError(t, a.add(WatchSubsystemCommitSync, "/workspace/repo/luna", nil, "/workspace/repo/.git/refs/catnip"))
+	assert.Equal(t, 3, a.status().Total)
+
+	// The more active worktree gets the watches of the less active one
+	require.NoError(t, a.add(WatchSubsystemStatusCache, "/workspace/repo/luna", nil, "/workspace/repo/luna/.git"))
+	assert.Equal(t, []string{"/workspace/repo/felix"}, demoted)
+	status := a.status()
+	assert.Equal(t, map[string]int{WatchSubsystemStatusCache: 1, WatchSubsystemCommitSync: 1}, status.Watches)
+	require.Len(t, status.Degraded, 1)
+	assert.Equal(t, WatchDegradedRationed, status.Degraded[0].Reason)
+	assert.NotEmpty(t, status.Guidance)
+	assert.False(t, a.degradedSince(WatchSubsystemStatusCache, "/workspace/repo/felix").IsZero())
+
+	// Worktrees without activity can't take watches from others
+	require.NoError(t, a.add(WatchSubsystemStatusCache, "/workspace/repo/milo", nil, "/workspace/repo/milo/.git"))
+	err := a.add(WatchSubsystemStatusCache, "/workspace/repo/milo", nil, "/workspace/repo/milo")
+	require.True(t, isWatchLimitError(err))
+	a.degrade(WatchSubsystemStatusCache, "/workspace/repo/milo", WatchDegradedLimit, err)
+	a.degrade(WatchSubsystemClaudeMonitor, "", WatchDegradedLimit, syscall.EMFILE)
+	status = a.status()
+	assert.Equal(t, map[string]int{WatchSubsystemStatusCache: 1}, status.LimitErrors)
+	assert.Equal(t, 2, status.Total, "degraded worktrees don't hold watches")
+	degradations := a.degradations("/workspace/repo/milo")
+	require.Len(t, degradations, 2, "watches covering every worktree apply to each")
+	assert.Equal(t, WatchSubsystemClaudeMonitor, degradations[0].Subsystem)
+	assert.Equal(t, WatchDegradedLimit, degradations[1].Reason)
+
+	a.release(WatchSubsystemStatusCache, "/workspace/repo/milo")
+	a.release(WatchSubsystemClaudeMonitor, "")
+	held--
+	assert.Empty(t, a.degradations("/workspace/repo/milo"))
+
+	// Watching a degraded worktree again clears its degradation
+	require.NoError(t, a.add(WatchSubsystemStatusCache, "/workspace/repo/felix", nil, "/workspace/repo/felix/.git"))
+	assert.True(t, a.degradedSince(WatchSubsystemStatusCache, "/workspace/repo/felix").IsZero())
+	assert.Empty(t, a.status().Guidance)
+}
+
+func TestPollForChanges(t *testing.T) {
+	t.Setenv("CATNIP_WATCH_POLL_SECONDS", "1")
+	dir := t.TempDir()
+	file := filepath.Join(dir, "HEAD")
+	require.NoError(t, os.WriteFile(file, []byte("ref: refs/heads/main\n"), 0644))
+
+	changes := make(chan struct{}, 10)
+	ticks := make(chan struct{}, 10)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		pollForChanges(make(chan struct{}), func() string { return pathFingerprint(dir, file) },
+			func() { changes <- struct{}{} }, func() { ticks <- struct{}{} })
+	}()
+
+	<-ticks
+	require.NoError(t, os.WriteFile(file, []byte("ref: refs/heads/feature\n"), 0644))
+	select {
+	case <-changes:
+	case <-time.After(5 * time.Second):
+		t.Fatal("change wasn't noticed")
+	}
+
+	// Polling ends once the path is gone
+	require.NoError(t, os.RemoveAll(dir))
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("polling didn't stop")
+	}
+}
@@ -0,0 +1,206 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/vanpelt/catnip/internal/git"
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+const (
+	// generatedPathsKey lists path patterns of generated files, one value per pattern (git
+	// config --add), in addition to the lockfiles and linguist-generated attributes
+	generatedPathsKey = "catnip.diff.generated"
+	// generatedAttribute marks files as generated in .gitattributes, as GitHub's linguist does
+	generatedAttribute = "linguist-generated"
+	// maxGeneratedFileEntries bounds the classifications kept; the cache starts over beyond it
+	maxGeneratedFileEntries = 256
+	// checkAttrBatch is how many paths one git check-attr is given
+	checkAttrBatch = 500
+)
+
+// defaultGeneratedPaths are the lockfiles package managers rewrite wholesale, generated unless
+// a .gitattributes sets linguist-generated=false for them
+var defaultGeneratedPaths = []string{
+	"package-lock.json", "npm-shrinkwrap.json", "yarn.lock", "pnpm-lock.yaml", "bun.lock", "bun.lockb",
+	"uv.lock", "poetry.lock", "Pipfile.lock", "pdm.lock",
+	"go.sum", "Cargo.lock", "Gemfile.lock", "composer.lock", "flake.lock",
+}
+
+// generatedFileCache keeps which files of a file list are generated, keyed by the list, the
+// configured patterns and the .gitattributes files that apply to it. Its zero value is ready
+// to use.
+type generatedFileCache struct {
+	mu      sync.Mutex
+	entries map[string]map[string]bool // key: checksum of the inputs, see generatedFilesKey
+}
+
+func (c *generatedFileCache) get(key string) (map[string]bool, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	generated, exists := c.entries[key]
+	return generated, exists
+}
+
+func (c *generatedFileCache) put(key string, generated map[string]bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil || len(c.entries) >= maxGeneratedFileEntries {
+		c.entries = make(map[string]map[string]bool)
+	}
+	c.entries[key] = generated
+}
+
+// generatedPathPatterns returns the patterns classifying files as generated for the repository
+// of dir: the default lockfiles and the configured ones
+func (s *GitService) generatedPathPatterns(dir string) []string {
+	patterns := append([]string{}, defaultGeneratedPaths...)
+	output, _ := s.operations.ExecuteGit(dir, "config", "--get-all", generatedPathsKey)
+	for _, pattern := range strings.Split(string(output), "\n") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			patterns = append(patterns, pattern)
+		}
+	}
+	return patterns
+}
+
+// matchesGeneratedPattern reports whether a slash-separated path matches a pattern. Patterns
+// without a slash match the file name in any directory, patterns ending in / or /** match
+// everything below a directory.
+func matchesGeneratedPattern(pattern, file string) bool {
+	if dir, isDir := strings.CutSuffix(strings.TrimSuffix(pattern, "**"), "/"); isDir {
+		return strings.HasPrefix(file, strings.TrimPrefix(dir, "/")+"/")
+	}
+	if !strings.Contains(pattern, "/") {
+		matched, _ := path.Match(pattern, path.Base(file))
+		return matched
+	}
+	matched, _ := path.Match(strings.TrimPrefix(pattern, "/"), file)
+	return matched
+}
+
+// generatedFilesKey checksums what classifying files in dir depends on: the files, the
+// patterns and the size and mtime of the .gitattributes files of their directories
+func generatedFilesKey(dir string, patterns, files []string) string {
+	hash := sha256.New()
+	hash.Write([]byte(dir + "\x00" + strings.Join(patterns, "\x00") + "\x00\x00" + strings.Join(files, "\x00")))
+
+	dirs := map[string]bool{".": true}
+	for _, file := range files {
+		for parent := path.Dir(file); !dirs[parent]; parent = path.Dir(parent) {
+			dirs[parent] = true
+		}
+	}
+	sorted := make([]string, 0, len(dirs))
+	for parent := range dirs {
+		sorted = append(sorted, parent)
+	}
+	sort.Strings(sorted)
+	for _, parent := range sorted {
+		var stat [16]byte
+		if info, err := os.Stat(filepath.Join(dir, filepath.FromSlash(parent), ".gitattributes")); err == nil {
+			binary.LittleEndian.PutUint64(stat[:8], uint64(info.Size()))
+			binary.LittleEndian.PutUint64(stat[8:], uint64(info.ModTime().UnixNano()))
+		}
+		hash.Write(stat[:])
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// generatedFiles returns which of files, relative to dir, are generated: those with the
+// linguist-generated attribute set and those matching a generated path pattern, unless their
+// attributes set linguist-generated=false. The classification of a file list is cached.
+func (s *GitService) generatedFiles(dir string, files []string) map[string]bool {
+	if len(files) == 0 {
+		return map[string]bool{}
+	}
+	files = append([]string{}, files...)
+	sort.Strings(files)
+	patterns := s.generatedPathPatterns(dir)
+	key := generatedFilesKey(dir, patterns, files)
+	if generated, exists := s.generatedFileCache.get(key); exists {
+		return generated
+	}
+
+	attributes := make(map[string]string)
+	for start := 0; start < len(files); start += checkAttrBatch {
+		batch := files[start:min(start+checkAttrBatch, len(files))]
+		output, err := s.operations.ExecuteGit(dir, append([]string{"check-attr", "-z", generatedAttribute, "--"}, batch...)...)
+		if err != nil {
+			// Bare repositories and missing worktrees fall back to the patterns
+			logger.Debugf("🔍 Failed to read %s attributes in %s: %v", generatedAttribute, dir, err)
+			break
+		}
+		fields := strings.Split(string(output), "\x00")
+		for i := 0; i+2 < len(fields); i += 3 {
+			attributes[fields[i]] = fields[i+2]
+		}
+	}
+
+	generated := make(map[string]bool)
+	for _, file := range files {
+		switch attributes[file] {
+		case "set", "true":
+			generated[file] = true
+		case "unset", "false":
+		default:
+			for _, pattern := range patterns {
+				if matchesGeneratedPattern(pattern, file) {
+					generated[file] = true
+					break
+				}
+			}
+		}
+	}
+	s.generatedFileCache.put(key, generated)
+	return generated
+}
+
+// classifyDiffFiles flags the generated files of a diff, collapsing them unless expand is set,
+// and returns the stats of the files written by hand
+func (s *GitService) classifyDiffFiles(dir string, files []git.FileDiff, expand bool) models.DiffStats {
+	paths := make([]string, len(files))
+	for i, file := range files {
+		paths[i] = file.FilePath
+	}
+	generated := s.generatedFiles(dir, paths)
+
+	var authored models.DiffStats
+	for i := range files {
+		if generated[files[i].FilePath] {
+			files[i].Generated = true
+			if !expand {
+				files[i].IsExpanded = false
+			}
+			continue
+		}
+		authored.Files++
+		authored.Additions += files[i].Additions
+		authored.Deletions += files[i].Deletions
+	}
+	return authored
+}
+
+// onlyGeneratedFiles reports whether every one of files, relative to dir, is generated, so
+// changing them alone isn't work worth keeping. It is false for no files.
+func (s *GitService) onlyGeneratedFiles(dir string, files []string) bool {
+	if len(files) == 0 {
+		return false
+	}
+	generated := s.generatedFiles(dir, files)
+	for _, file := range files {
+		if !generated[file] {
+			return false
+		}
+	}
+	return true
+}
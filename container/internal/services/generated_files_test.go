@@ -0,0 +1,109 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchesGeneratedPattern(t *testing.T) {
+	assert.True(t, matchesGeneratedPattern("package-lock.json", "web/package-lock.json"))
+	assert.True(t, matchesGeneratedPattern("*.pb.go", "api/v1/service.pb.go"))
+	assert.True(t, matchesGeneratedPattern("dist/", "dist/app.js"))
+	assert.True(t, matchesGeneratedPattern("/web/dist/**", "web/dist/css/app.css"))
+	assert.True(t, matchesGeneratedPattern("web/*.gen.ts", "web/api.gen.ts"))
+	assert.False(t, matchesGeneratedPattern("web/*.gen.ts", "src/web/api.gen.ts"))
+	assert.False(t, matchesGeneratedPattern("dist/", "src/dist.go"))
+}
+
+func TestGeneratedFiles(t *testing.T) {
+	s, stateManager, repoPath, worktreePath := newRecreateTestService(t)
+	require.NoError(t, os.WriteFile(filepath.Join(repoPath, "package-lock.json"), []byte("{}\n"), 0644))
+	runTestGit(t, repoPath, "add", "package-lock.json")
+	runTestGit(t, repoPath, "commit", "-m", "Add lockfile")
+	runTestGit(t, repoPath, "update-ref", "refs/remotes/origin/main", "main")
+	runTestGit(t, worktreePath, "merge", "--ff-only", "main")
+	write := func(name, content string) {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(worktreePath, name)), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(worktreePath, name), []byte(content), 0644))
+	}
+
+	t.Run("LockfileChurnDoesNotBlockPruning", func(t *testing.T) {
+		write("package-lock.json", "{\n  \"lockfileVersion\": 3\n}\n")
+		runTestGit(t, worktreePath, "commit", "-am", "Bump lockfile")
+		write("package-lock.json", "{\n  \"lockfileVersion\": 3,\n  \"packages\": {}\n}\n")
+		assert.Empty(t, s.pruneBlocker(repoPath, "repo/felix", worktreePath, "feature/felix"))
+
+		write("app.txt", "v2\n")
+		assert.Equal(t, "worktree repo/felix has uncommitted changes", s.pruneBlocker(repoPath, "repo/felix", worktreePath, "feature/felix"))
+		runTestGit(t, worktreePath, "commit", "-am", "Change app")
+		assert.Equal(t, "worktree repo/felix has 2 commits that aren't on any remote", s.pruneBlocker(repoPath, "repo/felix", worktreePath, "feature/felix"))
+	})
+
+	write(".gitattributes", "gen/** linguist-generated\nyarn.lock -linguist-generated\n")
+	write("gen/api.pb.go", "package gen\n\nvar A = 1\n")
+	write("yarn.lock", "# reviewed by hand\n")
+	write("dist/app.js", "console.log(1)\n")
+	runTestGit(t, worktreePath, "add", "-A")
+	runTestGit(t, worktreePath, "commit", "-m", "Add generated code")
+	runTestGit(t, repoPath, "config", "--add", generatedPathsKey, "dist/")
+
+	t.Run("DiffSummaryCollapsesGeneratedFiles", func(t *testing.T) {
+		summary, err := s.GetWorktreeDiffSummary("wt-felix", 0, 0, false)
+		require.NoError(t, err)
+		generated := map[string]bool{}
+		for _, file := range summary.Files {
+			generated[file.FilePath] = file.Generated
+			if file.Generated {
+				assert.False(t, file.IsExpanded, file.FilePath)
+			}
+		}
+		assert.Equal(t, map[string]bool{
+			".gitattributes": false, "app.txt": false, "yarn.lock": false,
+			"package-lock.json": true, "gen/api.pb.go": true, "dist/app.js": true,
+		}, generated)
+		assert.Equal(t, 3, summary.AuthoredStats.Files)
+		assert.Equal(t, 4, summary.AuthoredStats.Additions)
+		assert.Equal(t, 1, summary.AuthoredStats.Deletions)
+		assert.Greater(t, summary.TotalAdditions, summary.AuthoredStats.Additions)
+
+		expanded, err := s.GetWorktreeDiffSummary("wt-felix", 0, 0, true)
+		require.NoError(t, err)
+		for _, file := range expanded.Files {
+			if file.FilePath == "package-lock.json" {
+				assert.True(t, file.Generated)
+				assert.True(t, file.IsExpanded, "modified files expand when asked to")
+			}
+		}
+	})
+
+	t.Run("CommitsCountAuthoredLines", func(t *testing.T) {
+		page, err := s.GetWorktreeCommits("wt-felix", 0, 0, true)
+		require.NoError(t, err)
+		require.Len(t, page.Commits, 3)
+		bump := page.Commits[2]
+		assert.True(t, bump.Files[0].Generated)
+		assert.Equal(t, 1, bump.Stats.Files)
+		assert.Zero(t, *bump.AuthoredStats)
+	})
+
+	t.Run("PullRequestSize", func(t *testing.T) {
+		worktree, _ := stateManager.GetWorktree("wt-felix")
+		assert.Equal(t, "- 3 files changed by hand (+4 -1)\n- 6 files changed in total (+12 -2), including 3 generated: dist/app.js, gen/api.pb.go, package-lock.json",
+			s.pullRequestSize(worktree))
+	})
+
+	t.Run("ClassificationIsCachedPerFileList", func(t *testing.T) {
+		files := []string{"gen/api.pb.go", "app.txt"}
+		assert.Equal(t, map[string]bool{"gen/api.pb.go": true}, s.generatedFiles(worktreePath, files))
+		entries := len(s.generatedFileCache.entries)
+		s.generatedFiles(worktreePath, []string{"app.txt", "gen/api.pb.go"})
+		assert.Len(t, s.generatedFileCache.entries, entries, "same list in another order")
+
+		write(".gitattributes", "yarn.lock -linguist-generated\n")
+		assert.Empty(t, s.generatedFiles(worktreePath, files), "edited attributes invalidate the classification")
+	})
+}
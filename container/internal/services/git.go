@@ -1,14 +1,16 @@
 package services
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
-	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/vanpelt/catnip/internal/config"
@@ -27,370 +29,396 @@ func getWorkspaceDir() string {
 	return config.Runtime.WorkspaceDir
 }
 
+// workspaceRelativePath returns path relative to the workspace directory and whether path is
+// inside it. GitService and ClaudeMonitorService both use it so they agree on managed paths.
+func workspaceRelativePath(path string) (string, bool) {
+	workspaceDir := getWorkspaceDir()
+	if workspaceDir == "" {
+		return "", false
+	}
+	rel, ok := config.RelativePath(workspaceDir, path)
+	if !ok || rel == "." {
+		return "", false
+	}
+	return rel, true
+}
+
+// isWithinDir checks if path is inside dir, comparing canonical paths
+func isWithinDir(dir, path string) bool {
+	rel, ok := config.RelativePath(dir, path)
+	return ok && rel != "."
+}
+
 // getGitStateDir returns the git state directory based on volume dir
 func getGitStateDir() string {
 	return config.Runtime.VolumeDir
 }
 
-// generateUniqueSessionName generates a unique session name that doesn't already exist as a branch
-func (s *GitService) generateUniqueSessionName(repoPath string) string {
-	// Use the shared function with GitService's branch checking logic
-	return git.GenerateUniqueSessionName(func(name string) bool {
-		return s.branchExists(repoPath, name, false)
-	})
-}
-
 // isCatnipBranch checks if a branch name has a catnip/ prefix
 func isCatnipBranch(branchName string) bool {
 	return git.IsCatnipBranch(branchName)
 }
 
-// cleanupUnusedBranches removes catnip branches that have no commits
-func (s *GitService) cleanupUnusedBranches() {
-	logger.Debug("🧹 Starting cleanup of unused catnip branches...")
+// checkedOutBranches returns the branches and refs checked out by the worktrees of repo
+func (s *GitService) checkedOutBranches(repo *models.Repository) (map[string]bool, error) {
+	worktrees, err := s.operations.ListWorktrees(repo.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list worktrees: %v", err)
+	}
+	checkedOut := make(map[string]bool, len(worktrees))
+	for _, wt := range worktrees {
+		checkedOut[wt.Branch] = true
+	}
+	return checkedOut, nil
+}
 
-	s.mu.RLock()
-	reposMap := s.stateManager.GetAllRepositories()
-	s.mu.RUnlock()
+// cleanupUnusedBranches removes catnip branches of repo that have no commits, adding them to
+// report. Nothing is deleted when report.DryRun is set.
+func (s *GitService) cleanupUnusedBranches(repo *models.Repository, report *CleanupReport) error {
+	// List all branches in the bare repository
+	branches, err := s.operations.ListBranches(repo.Path, git.ListBranchesOptions{All: true})
+	if err != nil {
+		return fmt.Errorf("failed to list branches: %v", err)
+	}
 
-	totalDeleted := 0
+	baseRef := s.cleanupBaseRef(repo)
+	if baseRef == "" {
+		return nil // Skip if we can't find a base branch
+	}
 
-	for _, repo := range reposMap {
-		// Skip unavailable repositories to prevent boot failures
-		if !repo.Available {
-			logger.Debugf("🔍 Skipping cleanup for unavailable repository %s", repo.ID)
-			continue
-		}
+	checkedOut, err := s.checkedOutBranches(repo)
+	if err != nil {
+		return err
+	}
+
+	// Once the legacy migration has run, catnip/ branches still around were deliberately
+	// left in place (checked out, tracking an upstream, or ambiguous) and are not ours to delete
+	skipLegacy := s.legacyRefsMigrated()
+	deleted := 0
+
+	for _, branch := range branches {
+		// Clean up branch name
+		branchName := strings.TrimSpace(branch)
+		branchName = strings.TrimPrefix(branchName, "*")
+		branchName = strings.TrimPrefix(branchName, "+")
+		branchName = strings.TrimSpace(branchName)
+		branchName = strings.TrimPrefix(branchName, "remotes/origin/")
 
-		// Check if repository path exists before trying to clean it up
-		if _, err := os.Stat(repo.Path); os.IsNotExist(err) {
-			// Mark repository as unavailable and skip cleanup
-			logger.Warnf("⚠️ Repository %s not available at %s, marking as unavailable", repo.ID, repo.Path)
-			repo.Available = false
+		// Skip if not a catnip branch
+		if !isCatnipBranch(branchName) {
 			continue
 		}
-
-		// List all branches in the bare repository
-		branches, err := s.operations.ListBranches(repo.Path, git.ListBranchesOptions{All: true})
-		if err != nil {
-			logger.Warnf("⚠️  Failed to list branches for %s: %v", repo.ID, err)
-			// Check if it's a directory access issue and mark repo as unavailable
-			if strings.Contains(err.Error(), "cannot change to") || strings.Contains(err.Error(), "No such file or directory") {
-				logger.Warnf("⚠️ Repository %s appears to be inaccessible, marking as unavailable", repo.ID)
-				repo.Available = false
-			}
+		if skipLegacy && strings.HasPrefix(branchName, "catnip/") {
 			continue
 		}
-		deletedInRepo := 0
-
-		for _, branch := range branches {
-			// Clean up branch name
-			branchName := strings.TrimSpace(branch)
-			branchName = strings.TrimPrefix(branchName, "*")
-			branchName = strings.TrimPrefix(branchName, "+")
-			branchName = strings.TrimSpace(branchName)
-			branchName = strings.TrimPrefix(branchName, "remotes/origin/")
-
-			// Skip if not a catnip branch
-			if !isCatnipBranch(branchName) {
-				continue
-			}
 
-			// Check if branch has any commits different from its parent
-			// First, try to find the merge-base with main/master
-			var baseRef string
-			for _, ref := range []string{"main", "master"} {
-				if err := s.operations.ShowRef(repo.Path, ref, git.ShowRefOptions{Verify: true, Quiet: true}); err == nil {
-					baseRef = ref
-					break
-				}
-			}
-
-			if baseRef == "" {
-				continue // Skip if we can't find a base branch
-			}
+		// Check if branch exists locally
+		if !s.operations.BranchExists(repo.Path, branchName, false) {
+			continue // Branch doesn't exist locally
+		}
 
-			// Check if branch exists locally
-			if !s.operations.BranchExists(repo.Path, branchName, false) {
-				continue // Branch doesn't exist locally
-			}
+		// Count commits ahead of base
+		commitCount, err := s.operations.GetCommitCount(repo.Path, baseRef, branchName)
+		if err != nil || commitCount > 0 {
+			continue // Skip if there are commits or error parsing
+		}
 
-			// Count commits ahead of base
-			commitCount, err := s.operations.GetCommitCount(repo.Path, baseRef, branchName)
-			if err != nil || commitCount > 0 {
-				continue // Skip if there are commits or error parsing
-			}
+		if checkedOut[branchName] {
+			report.CheckedOut = append(report.CheckedOut, CleanupItem{RepoID: repo.ID, Name: branchName})
+			continue
+		}
 
-			// Also check if there's an active worktree using this branch
-			worktrees, err := s.operations.ListWorktrees(repo.Path)
-			if err == nil {
-				var skipBranch bool
-				for _, wt := range worktrees {
-					if wt.Branch == branchName {
-						skipBranch = true
-						break
-					}
-				}
-				if skipBranch {
-					continue // Skip if branch is currently checked out in a worktree
-				}
-			}
+		if repo.Protected {
+			report.Pinned = append(report.Pinned, CleanupItem{RepoID: repo.ID, Name: branchName})
+			continue
+		}
 
-			// Delete the branch (local)
-			if err := s.operations.DeleteBranch(repo.Path, branchName, true); err == nil {
-				deletedInRepo++
-				totalDeleted++
-				logger.Debugf("🗑️  Deleted unused branch: %s in %s", branchName, repo.ID)
-			}
+		if report.DryRun {
+			report.Branches = append(report.Branches, CleanupItem{RepoID: repo.ID, Name: branchName})
+			continue
 		}
 
-		if deletedInRepo > 0 {
-			logger.Infof("✅ Cleaned up %d unused branches in %s", deletedInRepo, repo.ID)
+		// Delete the branch (local)
+		if err := s.operations.DeleteBranch(repo.Path, branchName, true); err != nil {
+			report.addError(repo.ID, branchName, err)
+			continue
 		}
+		report.Branches = append(report.Branches, CleanupItem{RepoID: repo.ID, Name: branchName})
+		deleted++
+		logger.Debugf("🗑️  Deleted unused branch: %s in %s", branchName, repo.ID)
 	}
 
-	if totalDeleted > 0 {
-		logger.Infof("🧹 Cleanup complete: removed %d unused catnip branches", totalDeleted)
-	} else {
-		logger.Debug("✅ No unused catnip branches found")
+	if deleted > 0 {
+		logger.Infof("✅ Cleaned up %d unused branches in %s", deleted, repo.ID)
 	}
+	return nil
 }
 
-// cleanupCatnipRefs provides comprehensive cleanup of refs/catnip/ namespace, checking against state.json
-func (s *GitService) cleanupCatnipRefs() {
-	logger.Debug("🧹 Starting cleanup of catnip refs namespace...")
-
-	s.mu.RLock()
-	reposMap := s.stateManager.GetAllRepositories()
-	worktreesMap := s.stateManager.GetAllWorktrees()
-	s.mu.RUnlock()
-
-	// Build a set of workspace names that should be preserved
-	preservedWorkspaces := make(map[string]bool)
-	for _, worktree := range worktreesMap {
+// preservedCatnipRefs returns the names under refs/catnip/ of the workspaces in persisted state
+func (s *GitService) preservedCatnipRefs() map[string]bool {
+	preserved := make(map[string]bool)
+	for _, worktree := range s.stateManager.GetAllWorktrees() {
 		// Extract workspace name from display name (e.g., "catnip/mini-milo" -> "mini-milo")
 		if parts := strings.Split(worktree.Name, "/"); len(parts) >= 2 {
-			workspaceName := parts[len(parts)-1]
-			preservedWorkspaces[workspaceName] = true
+			preserved[parts[len(parts)-1]] = true
 		}
 
 		// Also preserve if the branch is already a catnip ref
 		if strings.HasPrefix(worktree.Branch, "refs/catnip/") {
-			refName := strings.TrimPrefix(worktree.Branch, "refs/catnip/")
-			preservedWorkspaces[refName] = true
+			preserved[strings.TrimPrefix(worktree.Branch, "refs/catnip/")] = true
 		}
 	}
+	return preserved
+}
 
-	logger.Debugf("🔍 Preserving %d workspace refs: %v", len(preservedWorkspaces), preservedWorkspaces)
+// cleanupCatnipRefs removes refs/catnip/ refs of repo that no workspace in persisted state or
+// worktree uses, and operation backups past their retention, adding them to report. Nothing is
+// deleted when report.DryRun is set.
+func (s *GitService) cleanupCatnipRefs(repo *models.Repository, report *CleanupReport) error {
+	// Use git for-each-ref to list all refs/catnip/ references
+	output, err := s.operations.ExecuteGit(repo.Path, "for-each-ref", "--format=%(refname)", "refs/catnip/")
+	if err != nil {
+		return fmt.Errorf("failed to list catnip refs: %v", err)
+	}
+	if strings.TrimSpace(string(output)) == "" {
+		return nil // No catnip refs to clean up
+	}
 
-	totalDeleted := 0
+	preserved := s.preservedCatnipRefs()
+	checkedOut, err := s.checkedOutBranches(repo)
+	if err != nil {
+		return err
+	}
+	deleted := 0
 
-	for _, repo := range reposMap {
-		// Skip unavailable repositories to prevent boot failures
-		if !repo.Available {
-			logger.Debugf("🔍 Skipping catnip refs cleanup for unavailable repository %s", repo.ID)
+	for _, ref := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		ref = strings.TrimSpace(ref)
+		if ref == "" {
 			continue
 		}
 
-		// Check if repository path exists before trying to list refs
-		if _, err := os.Stat(repo.Path); os.IsNotExist(err) {
-			// Mark repository as unavailable and skip cleanup
-			logger.Warnf("⚠️ Repository %s not available at %s, marking as unavailable", repo.ID, repo.Path)
-			repo.Available = false
+		// Operation backups expire by age alone, whether or not their worktree still exists
+		backup := strings.HasPrefix(ref, operationBackupNamespace)
+		if backup {
+			if createdAt, ok := operationBackupTime(ref); ok && time.Since(createdAt) < getOperationBackupRetention() {
+				continue
+			}
+		} else if preserved[strings.TrimPrefix(ref, "refs/catnip/")] || checkedOut[ref] {
+			// Keep refs of workspaces tracked in persisted state, and of active worktrees as a
+			// fallback
+			logger.Debugf("🔒 Preserving ref in use by a worktree: %s", ref)
+			report.CheckedOut = append(report.CheckedOut, CleanupItem{RepoID: repo.ID, Name: ref})
 			continue
 		}
 
-		// Use git for-each-ref to list all refs/catnip/ references
-		output, err := s.operations.ExecuteGit(repo.Path, "for-each-ref", "--format=%(refname)", "refs/catnip/")
-		if err != nil {
-			logger.Warnf("⚠️  Failed to list catnip refs for %s: %v", repo.ID, err)
-			// Check if it's a directory access issue and mark repo as unavailable
-			if strings.Contains(err.Error(), "cannot change to") || strings.Contains(err.Error(), "No such file or directory") {
-				logger.Warnf("⚠️ Repository %s appears to be inaccessible, marking as unavailable", repo.ID)
-				repo.Available = false
-			}
+		if report.DryRun {
+			report.Refs = append(report.Refs, CleanupItem{RepoID: repo.ID, Name: ref})
 			continue
 		}
 
-		if strings.TrimSpace(string(output)) == "" {
-			continue // No catnip refs to clean up
+		// Delete the orphaned ref using update-ref
+		if _, err := s.operations.ExecuteGit(repo.Path, "update-ref", "-d", ref); err != nil {
+			logger.Warnf("⚠️  Failed to delete catnip ref %s: %v", ref, err)
+			report.addError(repo.ID, ref, err)
+			continue
 		}
-
-		deletedInRepo := 0
-		refs := strings.Split(strings.TrimSpace(string(output)), "\n")
-
-		for _, ref := range refs {
-			ref = strings.TrimSpace(ref)
-			if ref == "" {
-				continue
-			}
-
-			// Extract workspace name from ref (refs/catnip/workspace-name)
-			refWorkspace := strings.TrimPrefix(ref, "refs/catnip/")
-
-			// Check if this workspace is tracked in state.json
-			if preservedWorkspaces[refWorkspace] {
-				logger.Debugf("🔒 Preserving tracked ref: %s", ref)
-				continue
-			}
-
-			// Double-check if there's an active worktree using this ref (fallback safety)
-			worktrees, err := s.operations.ListWorktrees(repo.Path)
-			if err == nil {
-				var skipRef bool
-				for _, wt := range worktrees {
-					if wt.Branch == ref {
-						skipRef = true
-						logger.Debugf("🔒 Preserving ref with active worktree: %s", ref)
-						break
-					}
-				}
-				if skipRef {
-					continue
-				}
-			}
-
-			// Delete the orphaned ref using update-ref
-			if _, err := s.operations.ExecuteGit(repo.Path, "update-ref", "-d", ref); err == nil {
-				deletedInRepo++
-				totalDeleted++
-				logger.Debugf("🗑️  Deleted orphaned catnip ref: %s in %s", ref, repo.ID)
-
-				// Also clean up the git config mapping for this ref
-				configKey := fmt.Sprintf("catnip.branch-map.%s", strings.ReplaceAll(ref, "/", "."))
-				if configErr := s.operations.UnsetConfig(repo.Path, configKey); configErr != nil {
-					// Don't log as error since config might not exist - this is cleanup
-					logger.Debugf("🧹 Config mapping %s didn't exist or was already clean", configKey)
-				} else {
-					logger.Debugf("🧹 Cleaned up config mapping: %s", configKey)
-				}
-			} else {
-				logger.Warnf("⚠️  Failed to delete catnip ref %s: %v", ref, err)
-			}
+		report.Refs = append(report.Refs, CleanupItem{RepoID: repo.ID, Name: ref})
+		deleted++
+		logger.Debugf("🗑️  Deleted orphaned catnip ref: %s in %s", ref, repo.ID)
+		if backup {
+			continue
 		}
 
-		if deletedInRepo > 0 {
-			logger.Infof("✅ Cleaned up %d orphaned catnip refs in %s", deletedInRepo, repo.ID)
-			// Run garbage collection to clean up unreachable objects
-			if err := s.operations.GarbageCollect(repo.Path); err != nil {
-				logger.Warnf("⚠️ Failed to run garbage collection for %s: %v", repo.ID, err)
-			}
+		// Also clean up the git config mapping for this ref
+		configKey := fmt.Sprintf("catnip.branch-map.%s", strings.ReplaceAll(ref, "/", "."))
+		if configErr := s.operations.UnsetConfig(repo.Path, configKey); configErr != nil {
+			// Don't log as error since config might not exist - this is cleanup
+			logger.Debugf("🧹 Config mapping %s didn't exist or was already clean", configKey)
+		} else {
+			logger.Debugf("🧹 Cleaned up config mapping: %s", configKey)
 		}
 	}
 
-	if totalDeleted > 0 {
-		logger.Infof("🧹 Catnip refs cleanup complete: removed %d orphaned refs", totalDeleted)
-	} else {
-		logger.Debug("✅ No orphaned catnip refs found")
+	if deleted > 0 {
+		logger.Infof("✅ Cleaned up %d orphaned catnip refs in %s", deleted, repo.ID)
+		// Run garbage collection to clean up unreachable objects, once no fetch or worktree
+		// creation is using the repository
+		lock := s.repositoryLock(repo.Path)
+		lock.Lock()
+		ranAt := time.Now()
+		err := s.operations.GarbageCollect(repo.Path)
+		lock.Unlock()
+		if err != nil {
+			logger.Warnf("⚠️ Failed to run garbage collection for %s: %v", repo.ID, err)
+		} else {
+			s.recordGC(repo.ID, ranAt)
+		}
 	}
-
-	// Also clean up orphaned config mappings (even when no refs were deleted)
-	s.cleanupOrphanedConfigMappings()
+	return nil
 }
 
-// CleanupAllCatnipRefs provides a comprehensive cleanup that handles both legacy catnip/ branches and new refs/catnip/ refs
-func (s *GitService) CleanupAllCatnipRefs() {
-	logger.Debug("🧹 Starting comprehensive catnip cleanup...")
-
-	// Clean up legacy catnip/ branches first
-	s.cleanupUnusedBranches()
-
-	// Then clean up new refs/catnip/ namespace
-	s.cleanupCatnipRefs()
-
-	logger.Debug("✅ Comprehensive catnip cleanup complete")
-}
+// cleanupOrphanedConfigMappings removes git config mappings of repo for refs that no longer
+// exist. Mappings of refs cleanupCatnipRefs deletes are removed along with them and not listed
+// here.
+func (s *GitService) cleanupOrphanedConfigMappings(repo *models.Repository, report *CleanupReport) {
+	// Get all existing refs/catnip/ refs
+	existingRefs := make(map[string]bool)
+	output, err := s.operations.ExecuteGit(repo.Path, "for-each-ref", "--format=%(refname)", "refs/catnip/")
+	if err == nil && strings.TrimSpace(string(output)) != "" {
+		for _, ref := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+			if ref = strings.TrimSpace(ref); ref != "" {
+				existingRefs[ref] = true
+			}
+		}
+	}
 
-// cleanupOrphanedConfigMappings removes git config mappings for refs that no longer exist
-func (s *GitService) cleanupOrphanedConfigMappings() {
-	logger.Debug("🧹 Starting cleanup of orphaned git config mappings...")
+	// Get all catnip.branch-map config entries
+	configOutput, err := s.operations.ExecuteGit(repo.Path, "config", "--get-regexp", "catnip\\.branch-map\\.")
+	if err != nil {
+		return // No config mappings or error
+	}
 
-	s.mu.RLock()
-	reposMap := s.stateManager.GetAllRepositories()
-	s.mu.RUnlock()
+	cleaned := 0
+	for _, line := range strings.Split(strings.TrimSpace(string(configOutput)), "\n") {
+		// Parse config line: "catnip.branch-map.refs.catnip.name value"
+		parts := strings.SplitN(strings.TrimSpace(line), " ", 2)
+		if len(parts) != 2 {
+			continue
+		}
 
-	totalCleaned := 0
+		configKey := parts[0]
+		// Extract ref from config key: catnip.branch-map.refs.catnip.name -> refs/catnip/name
+		refName := strings.ReplaceAll(strings.TrimPrefix(configKey, "catnip.branch-map."), ".", "/")
 
-	for _, repo := range reposMap {
-		// Skip unavailable repositories to prevent boot failures
-		if !repo.Available {
-			logger.Debugf("🔍 Skipping config cleanup for unavailable repository %s", repo.ID)
+		// Check if this ref still exists
+		if existingRefs[refName] {
 			continue
 		}
-
-		// Check if repository path exists before trying to clean it up
-		if _, err := os.Stat(repo.Path); os.IsNotExist(err) {
-			// Mark repository as unavailable and skip cleanup
-			logger.Warnf("⚠️ Repository %s not available at %s, marking as unavailable", repo.ID, repo.Path)
-			repo.Available = false
+		if report.DryRun {
+			report.ConfigMappings = append(report.ConfigMappings, CleanupItem{RepoID: repo.ID, Name: configKey})
 			continue
 		}
 
-		// Get all existing refs/catnip/ refs
-		existingRefs := make(map[string]bool)
-		output, err := s.operations.ExecuteGit(repo.Path, "for-each-ref", "--format=%(refname)", "refs/catnip/")
-		if err == nil && strings.TrimSpace(string(output)) != "" {
-			refs := strings.Split(strings.TrimSpace(string(output)), "\n")
-			for _, ref := range refs {
-				ref = strings.TrimSpace(ref)
-				if ref != "" {
-					existingRefs[ref] = true
-				}
-			}
+		// This config mapping is orphaned, remove it
+		if err := s.operations.UnsetConfig(repo.Path, configKey); err != nil {
+			logger.Debugf("⚠️ Failed to unset config %s: %v", configKey, err)
+			report.addError(repo.ID, configKey, err)
+			continue
 		}
+		report.ConfigMappings = append(report.ConfigMappings, CleanupItem{RepoID: repo.ID, Name: configKey})
+		cleaned++
+		logger.Debugf("🧹 Cleaned up orphaned config mapping: %s", configKey)
+	}
 
-		// Get all catnip.branch-map config entries
-		configOutput, err := s.operations.ExecuteGit(repo.Path, "config", "--get-regexp", "catnip\\.branch-map\\.")
-		if err != nil {
-			continue // No config mappings or error
-		}
+	if cleaned > 0 {
+		logger.Infof("✅ Cleaned up %d orphaned config mappings in %s", cleaned, repo.ID)
+	}
+}
 
-		cleanedInRepo := 0
-		lines := strings.Split(strings.TrimSpace(string(configOutput)), "\n")
-		for _, line := range lines {
-			line = strings.TrimSpace(line)
-			if line == "" {
-				continue
-			}
+// cleanupRepository cleans up the catnip branches (when withBranches is set, including the
+// remote ones when withRemote is set), refs and config mappings of one repository. Failures of
+// individual steps are listed in the report; an error is returned when the repository can't be
+// cleaned up at all.
+func (s *GitService) cleanupRepository(repoID string, withBranches, withRemote, dryRun bool) (*CleanupReport, error) {
+	repo, exists := s.stateManager.GetRepository(repoID)
+	if !exists {
+		return nil, fmt.Errorf("repository %s not found", repoID)
+	}
+	if !repo.Available {
+		return nil, fmt.Errorf("repository %s is unavailable", repoID)
+	}
+	if _, err := os.Stat(repo.Path); os.IsNotExist(err) {
+		return nil, fmt.Errorf("repository %s not found at %s", repoID, repo.Path)
+	}
 
-			// Parse config line: "catnip.branch-map.refs.catnip.name value"
-			parts := strings.SplitN(line, " ", 2)
-			if len(parts) != 2 {
-				continue
+	report := newCleanupReport(dryRun)
+	if withBranches {
+		if err := s.cleanupUnusedBranches(repo, report); err != nil {
+			logger.Warnf("⚠️  Failed to clean up branches of %s: %v", repo.ID, err)
+			report.addError(repo.ID, "", err)
+		}
+		if s.isLocalRepo(repo.ID) {
+			if err := s.cleanupPreviewBranches(repo, report); err != nil {
+				logger.Warnf("⚠️  Failed to clean up preview branches of %s: %v", repo.ID, err)
+				report.addError(repo.ID, "", err)
 			}
-
-			configKey := parts[0]
-			// Extract ref from config key: catnip.branch-map.refs.catnip.name -> refs/catnip/name
-			refName := strings.ReplaceAll(strings.TrimPrefix(configKey, "catnip.branch-map."), ".", "/")
-
-			// Check if this ref still exists
-			if !existingRefs[refName] {
-				// This config mapping is orphaned, remove it
-				if err := s.operations.UnsetConfig(repo.Path, configKey); err != nil {
-					logger.Debugf("⚠️ Failed to unset config %s: %v", configKey, err)
-				} else {
-					cleanedInRepo++
-					totalCleaned++
-					logger.Debugf("🧹 Cleaned up orphaned config mapping: %s", configKey)
-				}
+		} else if withRemote {
+			if err := s.cleanupRemoteBranches(repo, report); err != nil {
+				logger.Warnf("⚠️  Failed to clean up remote branches of %s: %v", repo.ID, err)
+				report.addError(repo.ID, "", err)
 			}
 		}
+	}
+	if err := s.cleanupCatnipRefs(repo, report); err != nil {
+		logger.Warnf("⚠️  Failed to clean up catnip refs of %s: %v", repo.ID, err)
+		report.addError(repo.ID, "", err)
+	}
+	s.cleanupOrphanedConfigMappings(repo, report)
+	return report, nil
+}
+
+// CleanupCatnipRefsForRepo removes the unused catnip branches, stale preview and remote catnip
+// branches, orphaned refs/catnip/ refs and orphaned config mappings of one repository. With
+// dryRun set it only reports what would be removed.
+func (s *GitService) CleanupCatnipRefsForRepo(repoID string, dryRun bool) (*CleanupReport, error) {
+	logger.Debugf("🧹 Starting catnip cleanup of %s...", repoID)
+	return s.cleanupRepository(repoID, true, true, dryRun)
+}
 
-		if cleanedInRepo > 0 {
-			logger.Infof("✅ Cleaned up %d orphaned config mappings in %s", cleanedInRepo, repo.ID)
+// cleanupRepositories cleans up every available repository, one at a time, and aggregates
+// their reports. Branches are only cleaned up when withBranches is set, remote ones only when
+// withRemote is set too.
+func (s *GitService) cleanupRepositories(withBranches, withRemote, dryRun bool) *CleanupReport {
+	repos := s.stateManager.GetAllRepositories()
+	ids := make([]string, 0, len(repos))
+	for id := range repos {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	report := newCleanupReport(dryRun)
+	for _, id := range ids {
+		// Skip unavailable repositories to prevent boot failures
+		if !repos[id].Available {
+			logger.Debugf("🔍 Skipping cleanup for unavailable repository %s", id)
+			continue
 		}
+		repoReport, err := s.cleanupRepository(id, withBranches, withRemote, dryRun)
+		if err != nil {
+			logger.Warnf("⚠️ Skipping cleanup of %s: %v", id, err)
+			continue
+		}
+		report.merge(repoReport)
 	}
 
-	if totalCleaned > 0 {
-		logger.Infof("🧹 Config mappings cleanup complete: removed %d orphaned mappings", totalCleaned)
-	} else {
-		logger.Debug("✅ No orphaned config mappings found")
+	switch {
+	case report.removed() == 0:
+		logger.Debug("✅ No unused catnip branches or refs found")
+	case !dryRun:
+		logger.Infof("🧹 Cleanup complete: removed %d unused, %d preview and %d remote branches, %d orphaned refs and %d orphaned config mappings",
+			len(report.Branches), len(report.PreviewBranches), len(report.RemoteBranches), len(report.Refs), len(report.ConfigMappings))
 	}
+	return report
+}
+
+// CleanupAllCatnipRefs provides a comprehensive cleanup that handles both legacy catnip/ branches
+// and new refs/catnip/ refs of every repository. With dryRun set it only reports what would be
+// removed.
+func (s *GitService) CleanupAllCatnipRefs(dryRun bool) *CleanupReport {
+	logger.Debug("🧹 Starting comprehensive catnip cleanup...")
+	return s.cleanupRepositories(true, true, dryRun)
 }
 
-// SetupExecutor interface for executing setup.sh scripts in worktrees
+// SetupExecutor interface for executing setup.sh scripts in worktrees. defaultScript is run
+// instead when the worktree has no setup.sh ("" to skip setup); env is added to the script's
+// environment.
 type SetupExecutor interface {
-	ExecuteSetupScript(worktreePath string)
+	ExecuteSetupScript(worktreePath, defaultScript string, env []string)
+}
+
+// WorktreeSessionChecker reports terminal sessions running inside a worktree
+type WorktreeSessionChecker interface {
+	HasSessionsIn(worktreePath string) bool
 }
 
 // EventsEmitter interface for emitting worktree status events
@@ -404,21 +432,59 @@ type EventsEmitter interface {
 	EmitWorktreeDeleted(worktreeID, worktreeName string)
 	EmitWorktreeTodosUpdated(worktreeID string, todos []models.Todo)
 	EmitSessionTitleUpdated(workspaceDir, worktreeID string, sessionTitle *models.TitleEntry, sessionTitleHistory []models.TitleEntry)
+	EmitRepositoryHealthWarning(repoID, source, message string)
+	EmitWorktreeBranchDrift(worktreeID string, drift *models.BranchDrift)
+	EmitWorktreeSourceRewritten(worktreeID string, rewrite *models.SourceRewrite)
+	EmitWorktreeBranchRename(worktreeID string, outcome *models.BranchRenameOutcome)
+	EmitWorktreeMerge(phase MergePhase, preview *models.MergePreview)
+	EmitPullRequestCreated(worktreeID string, pr *models.PullRequestResponse)
+	EmitPullRequestStatus(worktreeID string, state *models.PullRequestState)
+	EmitRepositoryRenamed(oldID, newID string)
+	EmitDiskStatusChanged(status DiskStatus)
+	EmitWorktreeHealth(health *models.WorktreeHealth)
+	EmitWorktreeFromIssue(worktree *models.Worktree, issue *models.AutomatedIssue)
 }
 
 type GitService struct {
-	stateManager       *WorktreeStateManager // Centralized state management
-	operations         git.Operations        // All git operations through this interface
-	gitWorktreeManager *git.WorktreeManager  // Git layer worktree operations
-	conflictResolver   *git.ConflictResolver // Handles conflict detection/resolution
-	githubManager      *git.GitHubManager    // Handles all GitHub CLI operations
-	localRepoManager   *LocalRepoManager     // Handles local repository detection
-	commitSync         *CommitSyncService    // Handles automatic checkpointing and commit sync
-	setupExecutor      SetupExecutor         // Handles setup.sh execution in PTY sessions
-	worktreeCache      *WorktreeStatusCache  // Handles worktree status caching with event updates
-	eventsEmitter      EventsEmitter         // Handles emitting events to connected clients
-	claudeMonitor      *ClaudeMonitorService // Handles Claude session monitoring
+	stateManager       *WorktreeStateManager  // Centralized state management
+	operations         git.Operations         // All git operations through this interface
+	gitWorktreeManager *git.WorktreeManager   // Git layer worktree operations
+	conflictResolver   *git.ConflictResolver  // Handles conflict detection/resolution
+	githubManager      git.GitHubClient       // Handles all GitHub CLI operations
+	mirrorManager      *MirrorManager         // Pushes to secondary remotes after publishing to origin
+	localRepoManager   *LocalRepoManager      // Handles local repository detection
+	commitSync         *CommitSyncService     // Handles automatic checkpointing and commit sync
+	setupExecutor      SetupExecutor          // Handles setup.sh execution in PTY sessions
+	sessionChecker     WorktreeSessionChecker // Reports PTY sessions that keep a worktree in use
+	worktreeCache      *WorktreeStatusCache   // Handles worktree status caching with event updates
+	eventsEmitter      EventsEmitter          // Handles emitting events to connected clients
+	claudeMonitor      *ClaudeMonitorService  // Handles Claude session monitoring
+	stopCh             chan struct{}          // Closed by Stop to end background checks
+	stopOnce           sync.Once
+	driftObservations  sync.Map                // worktreeID -> unexpected branch seen by the last status refresh
+	sessionNames       sessionNameReservations // Fun names handed out to worktrees still being created
+	pendingMerges      pendingMergeRegistry    // Prepared merges waiting for their commit message
+	diffCache          worktreeDiffCache       // Last diff of each worktree, reused while nothing changed
+	generatedFileCache generatedFileCache      // Generated files of the file lists classified so far
+	checkpointNoise    checkpointNoiseTracker  // Directories committed by consecutive checkpoints
+	worktreeAddLocks   sync.Map                // repo path -> *sync.Mutex serializing git worktree add
+	validationLocks    sync.Map                // worktree ID -> *sync.Mutex serializing validation runs
+	setupRuns          sync.Map                // worktree ID -> *worktreeSetupRun of the running setup commands
+	renameChecks       sync.Map                // repo ID -> time of the last GitHub rename check
+	issuePollLocks     sync.Map                // repo ID -> *sync.Mutex serializing issue automation polls
+	repositoryLocks    sync.Map                // repo path -> *sync.RWMutex keeping git gc from racing fetches and worktree creation
+	patchUploadMu      sync.Mutex              // Serializes chunks appended to patch uploads
+	rateLimits         *git.RateLimitTracker   // GitHub API budget deciding whether user operations are queued
+	githubQueue        githubOperationQueue    // User GitHub operations waiting for the rate limit to reset
+	bundles            worktreeBundleRegistry  // Serializes bundle generation and rate limits bundle fetches
+	disk               diskGuard               // Last measurement of free space on the workspace volume
+	diskUsage          diskUsageCache          // Last measurement of the space each repository and worktree takes
+	health             worktreeHealthTracker   // Findings reported by the last periodic health check
+	journal            *operationJournal       // Long operations, persisted so restarts can't lose them
+	unshallows         unshallowRegistry       // Background unshallows of new clones, cancellable
 	mu                 sync.RWMutex
+	// Outcome of the last credential helper configuration
+	credentialStatus atomic.Pointer[git.CredentialHelperReport]
 }
 
 // Helper functions for standardized command execution
@@ -430,6 +496,13 @@ func (s *GitService) SetSetupExecutor(executor SetupExecutor) {
 	s.setupExecutor = executor
 }
 
+// SetWorktreeSessionChecker sets the checker consulted before moving a worktree
+func (s *GitService) SetWorktreeSessionChecker(checker WorktreeSessionChecker) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessionChecker = checker
+}
+
 // SetClaudeMonitor sets the claude monitor service
 func (s *GitService) SetClaudeMonitor(monitor *ClaudeMonitorService) {
 	s.mu.Lock()
@@ -487,27 +560,45 @@ func (s *GitService) runGitCommand(workingDir string, args ...string) ([]byte, e
 
 // getSourceRef returns the appropriate source reference for a worktree
 func (s *GitService) getSourceRef(worktree *models.Worktree) string {
+	// Worktrees created at a tag or commit compare against it, it doesn't move
+	if worktree.SourceRef != "" {
+		return worktree.SourceRef
+	}
+	return s.branchRef(worktree, worktree.SourceBranch)
+}
+
+// getPullRequestBaseRef returns the reference of the branch the worktree's pull request targets,
+// empty when the worktree has no pull request or its base isn't known
+func (s *GitService) getPullRequestBaseRef(worktree *models.Worktree) string {
+	if worktree.PullRequestURL == "" || worktree.PullRequestBaseBranch == "" {
+		return ""
+	}
+	return s.branchRef(worktree, worktree.PullRequestBaseBranch)
+}
+
+// branchRef returns the reference a worktree compares a branch of its repository against
+func (s *GitService) branchRef(worktree *models.Worktree, branch string) string {
 	if s.isLocalRepo(worktree.RepoID) {
 		// For local repos, use the local branch directly since it's the source of truth
 		// The live remote can become stale and doesn't represent the current state
-		return worktree.SourceBranch
+		return branch
 	}
 
 	// Check if origin remote exists and is valid in the worktree
 	remotes, err := s.operations.GetRemotes(worktree.Path)
 	if err != nil || remotes["origin"] == "" {
 		// No valid origin remote, use local branch
-		return worktree.SourceBranch
+		return branch
 	}
 
 	// Check if origin points to a temp directory (template repos)
 	originURL := remotes["origin"]
 	if strings.HasPrefix(originURL, "/tmp/template-") {
 		// Template repo with invalid temp directory origin, use local branch
-		return worktree.SourceBranch
+		return branch
 	}
 
-	return fmt.Sprintf("origin/%s", worktree.SourceBranch)
+	return fmt.Sprintf("origin/%s", branch)
 }
 
 // Removed RemoteURLManager - functionality moved to git.URLManager
@@ -560,6 +651,22 @@ func (s *GitService) pushBranch(worktree *models.Worktree, repo *models.Reposito
 		return s.pushBranch(worktree, repo, retryStrategy)
 	}
 
+	if err == nil && repo != nil {
+		go s.checkRepositoryRename(repo.ID)
+	}
+	if err == nil {
+		s.refreshPullRequestStatus(worktree.ID)
+	}
+
+	// Failures other than rejections are often caused by broken remote or credential config
+	if err != nil && repo != nil && !git.IsPushRejected(err, err.Error()) {
+		go func() {
+			if _, verifyErr := s.VerifyRemoteConfig(repo.ID); verifyErr != nil {
+				logger.Debugf("⚠️  Remote config check after failed push failed: %v", verifyErr)
+			}
+		}()
+	}
+
 	return err
 }
 
@@ -580,6 +687,7 @@ func (s *GitService) getDefaultBranch(repoPath string) (string, error) {
 
 // fetchBranch unified fetch method with strategy pattern
 func (s *GitService) fetchBranch(repoPath string, strategy git.FetchStrategy) error {
+	defer s.shareRepository(repoPath)()
 	return s.operations.FetchBranch(repoPath, strategy)
 }
 
@@ -595,6 +703,11 @@ func NewGitServiceWithOperations(operations git.Operations) *GitService {
 
 // NewGitServiceWithStateDir creates a new Git service instance with custom state directory (for testing)
 func NewGitServiceWithStateDir(operations git.Operations, stateDir string) *GitService {
+	return NewGitServiceWithGitHub(operations, stateDir, git.NewGitHubManager(operations))
+}
+
+// NewGitServiceWithGitHub creates a new Git service instance with a custom GitHub client (for hermetic tests)
+func NewGitServiceWithGitHub(operations git.Operations, stateDir string, github git.GitHubClient) *GitService {
 	// Create state manager first (it will be connected to events handler later)
 	stateManager := NewWorktreeStateManager(stateDir, nil)
 
@@ -603,10 +716,15 @@ func NewGitServiceWithStateDir(operations git.Operations, stateDir string) *GitS
 		operations:         operations,
 		gitWorktreeManager: git.NewWorktreeManager(operations),
 		conflictResolver:   git.NewConflictResolver(operations),
-		githubManager:      git.NewGitHubManager(operations),
+		githubManager:      github,
 		localRepoManager:   NewLocalRepoManager(operations),
+		stopCh:             make(chan struct{}),
+		rateLimits:         git.GitHubRateLimits(),
 	}
 
+	// Mirror pushes to secondary remotes in the background
+	s.mirrorManager = NewMirrorManager(operations, stateManager, git.NewEnvCredentialProvider())
+
 	// Initialize CommitSync service
 	s.commitSync = NewCommitSyncServiceWithOperations(s, operations)
 
@@ -621,6 +739,9 @@ func NewGitServiceWithStateDir(operations git.Operations, stateDir string) *GitS
 		}
 		return worktree.Path, worktree
 	})
+	s.worktreeCache.SetBranchDriftChecker(s.checkBranchDrift)
+	s.worktreeCache.SetStatusFetcher(s.fetchForStatus)
+	s.worktreeCache.SetChangeListener(s.diffCache.invalidate)
 
 	// Ensure workspace directory exists
 	_ = os.MkdirAll(getWorkspaceDir(), 0755)
@@ -637,15 +758,30 @@ func NewGitServiceWithStateDir(operations git.Operations, stateDir string) *GitS
 
 	// Note: detectLocalRepos() will be called after setupExecutor is configured
 
-	// Clean up unused catnip branches (skip in dev mode to avoid deleting active branches)
-	if os.Getenv("CATNIP_DEV") != "true" {
-		s.cleanupUnusedBranches()
-	} else {
+	// Mask the values of the secret keys worktrees declared before anything is captured
+	s.registerSecretKeys()
+
+	// Setups cut short by the last shutdown never finish
+	s.failInterruptedSetups()
+
+	// Move legacy catnip/ branches into refs/catnip/ once, before cleanup looks at them
+	s.migrateLegacyRefsOnStartup()
+
+	// Finish recreations of worktrees that were interrupted by a restart
+	s.resumeInterruptedRecreations()
+
+	// Mark operations a restart interrupted, then resume or roll them back in the background
+	s.journal = newOperationJournal(stateManager.stateDir)
+	s.recoverInterruptedOperations()
+
+	// Clean up orphaned catnip refs and config mappings, and unused catnip branches except in
+	// dev mode to avoid deleting active branches
+	cleanupBranches := os.Getenv("CATNIP_DEV") != "true"
+	if !cleanupBranches {
 		logger.Debug("🔧 Skipping branch cleanup in dev mode")
 	}
-
-	// Always clean up orphaned catnip refs and config mappings (safe in both dev and prod)
-	s.cleanupCatnipRefs()
+	// Remote branches are only cleaned up on request, startup shouldn't wait on the network
+	s.cleanupRepositories(cleanupBranches, false, false)
 
 	// Start CommitSync service for automatic checkpointing
 	if err := s.commitSync.Start(); err != nil {
@@ -659,11 +795,53 @@ func NewGitServiceWithStateDir(operations git.Operations, stateDir string) *GitS
 	prSyncManager := GetPRSyncManager(stateManager)
 	prSyncManager.Start()
 
+	// Periodically measure repository sizes so users can see what fills the volume
+	go s.startRepositorySizeRefresher()
+
+	// Watch free space so a nearly full volume is reported before writes start failing
+	go s.startDiskMonitor()
+
+	// Keep the per repository and worktree disk usage warm so listings never wait for it
+	go s.startDiskUsageRefresher()
+
+	// Look for branches with commits but no worktree, so they can be adopted
+	go s.startOrphanBranchScanner()
+
+	// Garbage collect repositories whose loose objects or packs pile up, like bare repositories
+	// receiving checkpoint pushes
+	go s.startMaintenanceScheduler()
+
+	// Keep every branch of repositories in mirror mode current
+	go s.startMirrorUpdater()
+
+	// Periodically check worktrees for problems and report new ones
+	go s.startWorktreeHealthMonitor()
+
+	// Create worktrees for newly labeled issues of repositories with issue automation
+	go s.startIssueAutomationPoller()
+
+	// Periodically verify that remote URLs and credential helpers haven't drifted, and keep the
+	// package caches shared across worktrees under their cap
+	if config.Runtime.IsContainerized() {
+		go s.startRemoteConfigVerifier()
+		go s.startDependencyCacheTrimmer()
+	}
+
 	return s
 }
 
 // Stop properly shuts down the git service and its components
 func (s *GitService) Stop() {
+	// Stop background remote config checks
+	s.stopOnce.Do(func() {
+		if s.stopCh != nil {
+			close(s.stopCh)
+		}
+	})
+
+	// Cancel background unshallows
+	s.unshallows.stop()
+
 	// Stop CommitSync service
 	if s.commitSync != nil {
 		s.commitSync.Stop()
@@ -674,6 +852,11 @@ func (s *GitService) Stop() {
 		s.worktreeCache.Stop()
 	}
 
+	// Stop mirror pushes
+	if s.mirrorManager != nil {
+		s.mirrorManager.Stop()
+	}
+
 	// Stop state manager
 	if s.stateManager != nil {
 		s.stateManager.Stop()
@@ -685,8 +868,15 @@ func (s *GitService) Stop() {
 	}
 }
 
-// CheckoutRepository clones a GitHub repository as a bare repo and creates initial worktree
-func (s *GitService) CheckoutRepository(org, repo, branch string) (*models.Repository, *models.Worktree, error) {
+// CheckoutRepository clones a GitHub repository as a bare repo and creates initial worktree. The
+// clone options only apply when the repository isn't cloned yet.
+func (s *GitService) CheckoutRepository(org, repo, branch string, opts CloneOptions) (*models.Repository, *models.Worktree, error) {
+	return s.CheckoutRepositoryWithCreation(org, repo, branch, opts, nil)
+}
+
+// CheckoutRepositoryWithCreation is CheckoutRepository for a worktree created on someone's
+// behalf. The creation context and its actor are on the worktree before it is announced.
+func (s *GitService) CheckoutRepositoryWithCreation(org, repo, branch string, opts CloneOptions, creation *models.CreationContext) (*models.Repository, *models.Worktree, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -694,10 +884,46 @@ func (s *GitService) CheckoutRepository(org, repo, branch string) (*models.Repos
 
 	// Handle local repo specially
 	if s.isLocalRepo(repoID) {
-		return s.handleLocalRepoWorktree(repoID, branch)
+		return s.handleLocalRepoWorktree(repoID, branch, creation)
+	}
+
+	repoURL, barePath, err := s.checkoutLocation(org, repo)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Check if repository already exists in our map
+	if existingRepo, exists := s.stateManager.GetRepository(repoID); exists {
+		logger.Debugf("🔄 Repository already loaded, creating new worktree: %s", repoID)
+		return s.createWorktreeForExistingRepo(existingRepo, branch, creation)
+	}
+
+	// A checkout of the new name of a renamed repository reuses the clone of the old name
+	if renamed := s.findRenamedRepository(repoID); renamed != nil {
+		logger.Infof("🚚 %s is the new name of %s, migrating instead of cloning", repoID, renamed.ID)
+		migrated, err := s.migrateRepositoryRenameLocked(renamed.ID, repoID)
+		if err != nil {
+			return nil, nil, err
+		}
+		return s.createWorktreeForExistingRepo(migrated, branch, creation)
+	}
+
+	// Check if bare repository already exists on disk
+	if _, err := os.Stat(barePath); err == nil {
+		logger.Debugf("🔄 Found existing bare repository, loading and creating new worktree: %s", repoID)
+		return s.handleExistingRepository(repoID, repoURL, barePath, branch, creation)
+	}
+
+	if err := s.ensureDiskSpace("clone "+repoID, cloneEstimateBytes); err != nil {
+		return nil, nil, err
 	}
+	logger.Debugf("🔄 Cloning new repository: %s", repoID)
+	return s.cloneNewRepository(repoID, repoURL, barePath, branch, opts, creation)
+}
 
-	var repoURL string
+// checkoutLocation returns the URL a GitHub repository is cloned from and the path of its bare
+// repo, refusing repositories that are already mounted into the workspace
+func (s *GitService) checkoutLocation(org, repo string) (repoURL, barePath string, err error) {
 	if os.Getenv("CATNIP_TEST_MODE") == "1" {
 		// In test mode, use local test repositories
 		repoURL = filepath.Join("/tmp", "test-repos", repo)
@@ -711,31 +937,16 @@ func (s *GitService) CheckoutRepository(org, repo, branch string) (*models.Repos
 
 	// Ensure repos directory exists
 	if err := os.MkdirAll(reposDir, 0755); err != nil {
-		return nil, nil, fmt.Errorf("failed to create repos directory: %v", err)
+		return "", "", fmt.Errorf("failed to create repos directory: %v", err)
 	}
 
-	barePath := filepath.Join(reposDir, fmt.Sprintf("%s.git", repoName))
-
 	// Check if a directory is already mounted at the repo location
 	if s.isRepoMounted(getWorkspaceDir(), repoName) {
-		return nil, nil, fmt.Errorf("a repository already exists at %s (possibly mounted)",
+		return "", "", fmt.Errorf("a repository already exists at %s (possibly mounted)",
 			filepath.Join(getWorkspaceDir(), repoName))
 	}
 
-	// Check if repository already exists in our map
-	if existingRepo, exists := s.stateManager.GetRepository(repoID); exists {
-		logger.Debugf("🔄 Repository already loaded, creating new worktree: %s", repoID)
-		return s.createWorktreeForExistingRepo(existingRepo, branch)
-	}
-
-	// Check if bare repository already exists on disk
-	if _, err := os.Stat(barePath); err == nil {
-		logger.Debugf("🔄 Found existing bare repository, loading and creating new worktree: %s", repoID)
-		return s.handleExistingRepository(repoID, repoURL, barePath, branch)
-	}
-
-	logger.Debugf("🔄 Cloning new repository: %s", repoID)
-	return s.cloneNewRepository(repoID, repoURL, barePath, branch)
+	return repoURL, filepath.Join(reposDir, fmt.Sprintf("%s.git", repoName)), nil
 }
 
 // isRepoMounted checks if a repo directory is already mounted
@@ -751,30 +962,10 @@ func (s *GitService) isRepoMounted(workspaceDir, repoName string) bool {
 }
 
 // handleExistingRepository handles checkout when bare repo already exists
-func (s *GitService) handleExistingRepository(repoID, repoURL, barePath, branch string) (*models.Repository, *models.Worktree, error) {
-	// Load existing repository if we have state
-	var repo *models.Repository
-	if existingRepo, exists := s.stateManager.GetRepository(repoID); exists {
-		logger.Debugf("📦 Repository already loaded: %s", repoID)
-		repo = existingRepo
-	} else {
-		// Create repository object for existing bare repo
-		defaultBranch, err := s.getDefaultBranch(barePath)
-		if err != nil {
-			return nil, nil, fmt.Errorf("failed to get default branch: %v", err)
-		}
-
-		repo = &models.Repository{
-			ID:            repoID,
-			URL:           repoURL,
-			Path:          barePath,
-			DefaultBranch: defaultBranch,
-			CreatedAt:     time.Now(),
-			LastAccessed:  time.Now(),
-		}
-		if err := s.stateManager.AddRepository(repo); err != nil {
-			logger.Warnf("⚠️ Failed to add repository to state: %v", err)
-		}
+func (s *GitService) handleExistingRepository(repoID, repoURL, barePath, branch string, creation *models.CreationContext) (*models.Repository, *models.Worktree, error) {
+	repo, err := s.loadExistingRepository(repoID, repoURL, barePath)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	// If no branch specified, use default
@@ -782,21 +973,19 @@ func (s *GitService) handleExistingRepository(repoID, repoURL, barePath, branch
 		branch = repo.DefaultBranch
 	}
 
-	// Check if the requested branch exists in the bare repo
-	if !s.branchExists(barePath, branch, true) {
-		logger.Infof("🔄 Branch %s not found, fetching from remote", branch)
-		if err := s.fetchBranch(barePath, git.FetchStrategy{
-			Branch:         branch,
-			Depth:          1,
-			UpdateLocalRef: true,
-		}); err != nil {
-			return nil, nil, fmt.Errorf("failed to fetch branch %s: %v", branch, err)
+	// Check if the requested branch exists in the bare repo, tags and commits are always fetched
+	source := branch
+	if canonicalSourceRef(branch) != "" || !s.branchExists(barePath, branch, true) {
+		logger.Infof("🔄 %s not found, fetching from remote", branch)
+		var err error
+		if source, err = s.fetchCheckoutSource(barePath, branch, 1); err != nil {
+			return nil, nil, err
 		}
 	}
 
 	// Create new worktree with fun name
 	funName := s.generateUniqueSessionName(repo.Path)
-	worktree, err := s.createWorktreeInternalForRepo(repo, branch, funName, true)
+	worktree, err := s.createWorktreeInternalForRepo(repo, source, funName, true, creation)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to create worktree: %v", err)
 	}
@@ -806,26 +995,108 @@ func (s *GitService) handleExistingRepository(repoID, repoURL, barePath, branch
 	return repo, worktree, nil
 }
 
+// loadExistingRepository returns the repository of a bare repo already on disk, adding it to
+// state if it isn't loaded yet
+func (s *GitService) loadExistingRepository(repoID, repoURL, barePath string) (*models.Repository, error) {
+	if existingRepo, exists := s.stateManager.GetRepository(repoID); exists {
+		logger.Debugf("📦 Repository already loaded: %s", repoID)
+		return existingRepo, nil
+	}
+
+	// Create repository object for existing bare repo
+	defaultBranch, err := s.getDefaultBranch(barePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get default branch: %v", err)
+	}
+
+	repo := &models.Repository{
+		ID:            repoID,
+		URL:           repoURL,
+		Path:          barePath,
+		DefaultBranch: defaultBranch,
+		History:       s.repositoryHistory(barePath),
+		Mirror:        s.isMirrorClone(barePath),
+		CreatedAt:     time.Now(),
+		LastAccessed:  time.Now(),
+	}
+	if err := s.stateManager.AddRepository(repo); err != nil {
+		logger.Warnf("⚠️ Failed to add repository to state: %v", err)
+	}
+	return repo, nil
+}
+
 // cloneNewRepository clones a new bare repository
-func (s *GitService) cloneNewRepository(repoID, repoURL, barePath, branch string) (*models.Repository, *models.Worktree, error) {
-	// Clone as bare repository with shallow depth
-	args := []string{"clone", "--bare", "--depth", "1", "--single-branch"}
+func (s *GitService) cloneNewRepository(repoID, repoURL, barePath, branch string, opts CloneOptions, creation *models.CreationContext) (repository *models.Repository, worktree *models.Worktree, err error) {
+	op := s.journal.begin(OperationClone, repoID, map[string]string{"branch": branch, "bare_path": barePath})
+	defer func() { op.finish(err) }()
+
+	op.phase("clone")
+	repository, source, err := s.cloneBareRepository(repoID, repoURL, barePath, branch, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Create initial worktree with fun name to avoid conflicts with local branches
+	op.phase("worktree")
+	funName := s.generateUniqueSessionName(repository.Path)
+	worktree, err = s.createWorktreeInternalForRepo(repository, source, funName, true, creation)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create initial worktree: %v", err)
+	}
+
+	// State persistence handled by state manager
+	logger.Infof("✅ Repository cloned successfully: %s", repository.ID)
+	return repository, worktree, nil
+}
+
+// cloneBareRepository clones a new bare repository with the requested branch, or the default
+// branch when branch is empty, and returns it with the source to create worktrees from
+func (s *GitService) cloneBareRepository(repoID, repoURL, barePath, branch string, opts CloneOptions) (*models.Repository, string, error) {
+	// Ask GitHub for the full size while cloning, it decides whether to unshallow later
+	remoteSize := make(chan int64, 1)
+	go func() {
+		var kb int64
+		if strings.HasPrefix(repoURL, "https://github.com/") {
+			var err error
+			if kb, err = githubDiskUsageKB(repoID); err != nil {
+				logger.Debugf("⚠️  %v", err)
+			}
+		}
+		remoteSize <- kb
+	}()
+
+	// Tags and commits are fetched after cloning the default branch
+	var sourceRef string
 	if branch != "" {
+		sourceRef = s.remoteSourceRef(repoURL, branch)
+	}
+
+	// Clone as bare repository, shallow unless a full clone was asked for
+	args := opts.cloneArgs()
+	if branch != "" && sourceRef == "" {
 		args = append(args, "--branch", branch)
 	}
 	args = append(args, repoURL, barePath)
 
 	if _, err := s.runGitCommand("", args...); err != nil {
-		return nil, nil, fmt.Errorf("failed to clone repository: %v", err)
+		return nil, "", fmt.Errorf("failed to clone repository: %v", err)
 	}
 
 	// Get default branch if not specified
-	if branch == "" {
+	if branch == "" || sourceRef != "" {
 		var err error
 		branch, err = s.getDefaultBranch(barePath)
 		if err != nil {
-			return nil, nil, fmt.Errorf("failed to get default branch: %v", err)
+			return nil, "", fmt.Errorf("failed to get default branch: %v", err)
+		}
+	}
+	source := branch
+	if sourceRef != "" {
+		if err := s.fetchSourceRef(barePath, sourceRef); err != nil {
+			_ = os.RemoveAll(barePath)
+			return nil, "", err
 		}
+		source = sourceRef
 	}
 
 	// Create repository object
@@ -834,27 +1105,48 @@ func (s *GitService) cloneNewRepository(repoID, repoURL, barePath, branch string
 		URL:           repoURL,
 		Path:          barePath,
 		DefaultBranch: branch,
+		History:       s.repositoryHistory(barePath),
 		CreatedAt:     time.Now(),
 		LastAccessed:  time.Now(),
 	}
+	if opts.Mirror {
+		// Mirror every branch of origin into the remote-tracking refs checkouts look at
+		if err := s.configureMirror(barePath); err != nil {
+			_ = os.RemoveAll(barePath)
+			return nil, "", err
+		}
+		if err := s.fetchMirror(barePath); err != nil {
+			_ = os.RemoveAll(barePath)
+			return nil, "", err
+		}
+		updatedAt := time.Now()
+		repository.Mirror, repository.LastRemoteUpdate = true, &updatedAt
+	}
+	size, err := s.measureRepositorySize(barePath)
+	if err != nil {
+		logger.Debugf("⚠️  Failed to measure size of %s: %v", repoID, err)
+		size = &models.RepositorySize{}
+	}
+	size.RemoteKB = <-remoteSize
+	repository.Size = size
 
 	if err := s.stateManager.AddRepository(repository); err != nil {
 		logger.Warnf("⚠️ Failed to add repository to state: %v", err)
 	}
 
-	// Start background unshallow process for the requested branch
-	go s.unshallowRepository(barePath, branch)
-
-	// Create initial worktree with fun name to avoid conflicts with local branches
-	funName := s.generateUniqueSessionName(repository.Path)
-	worktree, err := s.createWorktreeInternalForRepo(repository, branch, funName, true)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to create initial worktree: %v", err)
+	// Start background unshallow process for the requested branch, unless the repository is too large
+	maxSizeKB := loadUnshallowMaxSizeKB(func(key string) (string, error) {
+		return s.operations.GetConfig(barePath, key)
+	})
+	if repository.History != models.RepositoryHistoryShallow || !opts.UnshallowInBackground {
+		logger.Debugf("📚 Not unshallowing %s (history: %s)", repoID, repository.History)
+	} else if shouldUnshallow(size, maxSizeKB) {
+		s.startUnshallow(repoID, barePath, branch, opts.UnshallowDelay)
+	} else {
+		logger.Infof("📏 Keeping %s shallow: estimated full size %d MiB exceeds %s (%d MiB)", repoID, size.RemoteKB>>10, unshallowMaxSizeKey, maxSizeKB>>10)
 	}
 
-	// State persistence handled by state manager
-	logger.Infof("✅ Repository cloned successfully: %s", repository.ID)
-	return repository, worktree, nil
+	return repository, source, nil
 }
 
 // ListWorktrees returns all worktrees with fast cache-enhanced responses
@@ -878,9 +1170,91 @@ func (s *GitService) ListWorktrees() []*models.Worktree {
 		worktrees = append(worktrees, &worktreeCopy)
 	}
 
+	// Map iteration order is random, keep lists stable between calls
+	_ = SortWorktrees(worktrees, WorktreeSortLastAccessed)
 	return worktrees
 }
 
+// ListWorktreesAt returns all worktrees like ListWorktrees, after refreshing the statuses that
+// aren't fresh at tier. Below the standard tier a standard refresh is queued in the background,
+// so ahead and behind counts catch up shortly after.
+func (s *GitService) ListWorktreesAt(tier models.StatusTier) []*models.Worktree {
+	if s.worktreeCache != nil && tier.Includes(models.StatusTierCheap) {
+		s.worktreeCache.RefreshAll(tier, false)
+		if !tier.Includes(models.StatusTierStandard) {
+			s.worktreeCache.QueueRefreshAll()
+		}
+	}
+	return s.ListWorktrees()
+}
+
+// RefreshWorktreeStatuses re-reads the git status of every worktree now rather than waiting for
+// the background refresh or trusting cached statuses, so the next ListWorktrees reflects it.
+// Worktrees are refreshed concurrently and without holding the service lock.
+func (s *GitService) RefreshWorktreeStatuses() {
+	s.worktreeCache.RefreshAll(models.StatusTierStandard, true)
+}
+
+// refreshWorktreeStatusNow re-reads a worktree's git status at tier into the cache and state,
+// for callers that can't act on a cached status
+func (s *GitService) refreshWorktreeStatusNow(worktreeID string, tier models.StatusTier) {
+	if s.worktreeCache != nil {
+		s.worktreeCache.Refresh(worktreeID, tier, true)
+	}
+}
+
+// statusRefs returns what a worktree's status is counted against
+func (s *GitService) statusRefs() git.StatusRefs {
+	return git.StatusRefs{
+		SourceRef:          s.getSourceRef,
+		PullRequestBaseRef: s.getPullRequestBaseRef,
+		Fetch:              s.fetchForStatus,
+	}
+}
+
+// fetchForStatus fetches the source and pull request base branches of a remote repo worktree
+// before a full tier status refresh. Local repos share their branches, there's nothing to fetch.
+func (s *GitService) fetchForStatus(worktree *models.Worktree) error {
+	if s.isLocalRepo(worktree.RepoID) || worktree.SourceBranch == "" {
+		return nil
+	}
+	done := s.timeOperation(worktree, OperationFetch)
+	err := s.fetchBranchFast(worktree.Path, worktree.SourceBranch)
+	done(err)
+	if err != nil {
+		return err
+	}
+	if worktree.PullRequestURL != "" && worktree.PullRequestBaseBranch != "" && worktree.PullRequestBaseBranch != worktree.SourceBranch {
+		return s.fetchBranchFast(worktree.Path, worktree.PullRequestBaseBranch)
+	}
+	return nil
+}
+
+// refreshPullRequestStatus drops the cached state of a worktree's pull request and refreshes it
+// in the background, after the branch behind it was pushed, merged or renamed
+func (s *GitService) refreshPullRequestStatus(worktreeID string) {
+	if worktree, exists := s.stateManager.GetWorktree(worktreeID); exists && worktree.PullRequestURL != "" {
+		GetPRSyncManager(nil).RefreshPullRequest(worktree.PullRequestURL)
+	}
+}
+
+// RefreshPullRequestByURL refreshes the state of a pull request when a worktree references it,
+// for changes reported by GitHub. It returns false for pull requests no worktree references.
+func (s *GitService) RefreshPullRequestByURL(prURL string) bool {
+	repoID, prNumber, ok := parsePullRequestURL(prURL)
+	if !ok {
+		return false
+	}
+	for _, worktree := range s.stateManager.GetAllWorktrees() {
+		worktreeRepoID, worktreePRNumber, ok := parsePullRequestURL(worktree.PullRequestURL)
+		if ok && worktreePRNumber == prNumber && strings.EqualFold(worktreeRepoID, repoID) {
+			GetPRSyncManager(nil).RefreshPullRequest(worktree.PullRequestURL)
+			return true
+		}
+	}
+	return false
+}
+
 // enhanceWorktreeWithPRState adds PR state information to a worktree if available
 func (s *GitService) enhanceWorktreeWithPRState(wt *models.Worktree) {
 	// Only enhance if the worktree has a PR URL
@@ -888,26 +1262,24 @@ func (s *GitService) enhanceWorktreeWithPRState(wt *models.Worktree) {
 		return
 	}
 
-	// Extract repo ID and PR number from the PR URL
-	prPattern := regexp.MustCompile(`github\.com/([^/]+/[^/]+)/pull/(\d+)`)
-	matches := prPattern.FindStringSubmatch(wt.PullRequestURL)
-	if len(matches) != 3 {
-		return
+	if prState := cachedPRState(wt.PullRequestURL); prState != nil {
+		wt.PullRequestState = prState.State
+		wt.PullRequestLastSynced = &prState.LastSynced
 	}
+}
 
-	repoID := matches[1]
-	prNumber, err := strconv.Atoi(matches[2])
-	if err != nil {
-		return
+// cachedPRState returns the PR sync manager's cached state for a pull request URL, if any
+func cachedPRState(prURL string) *models.PullRequestState {
+	repoID, prNumber, ok := parsePullRequestURL(prURL)
+	if !ok {
+		return nil
 	}
 
 	// Get PR state from the sync manager
 	if prSyncManager := GetPRSyncManager(nil); prSyncManager != nil {
-		if prState := prSyncManager.GetPRState(repoID, prNumber); prState != nil {
-			wt.PullRequestState = prState.State
-			wt.PullRequestLastSynced = &prState.LastSynced
-		}
+		return prSyncManager.GetPRState(repoID, prNumber)
 	}
+	return nil
 }
 
 // GetStatus returns the current Git status
@@ -920,10 +1292,23 @@ func (s *GitService) GetStatus() *models.GitStatus {
 		repos[repo.ID] = repo
 	}
 
-	return &models.GitStatus{
+	status := &models.GitStatus{
 		Repositories:  repos, // All repositories
 		WorktreeCount: len(s.stateManager.GetAllWorktrees()),
+		StateReport:   s.stateManager.PendingStateReport(),
+	}
+	if err := s.stateManager.StateLoadError(); err != nil {
+		status.Degraded = true
+		status.DegradedReason = err.Error()
 	}
+	status.GitHubRateLimit, _ = s.GitHubRateLimitStatus(false)
+	return status
+}
+
+// AcknowledgeStateReport dismisses the report of what loading the state healed or lost, so
+// status and new event streams stop carrying it
+func (s *GitService) AcknowledgeStateReport() error {
+	return s.stateManager.AcknowledgeStateReport()
 }
 
 // UpdateWorktreeFields updates specific fields of a worktree
@@ -931,6 +1316,28 @@ func (s *GitService) UpdateWorktreeFields(worktreeID string, updates map[string]
 	return s.stateManager.UpdateWorktree(worktreeID, updates)
 }
 
+// SetWorktreeCheckpointConfig enables or disables checkpoint commits for a worktree and sets the
+// time between checkpoints. Disabled worktrees still track session titles.
+func (s *GitService) SetWorktreeCheckpointConfig(worktreeID string, cfg models.CheckpointConfig) error {
+	worktree, exists := s.stateManager.GetWorktree(worktreeID)
+	if !exists {
+		return fmt.Errorf("worktree %s not found", worktreeID)
+	}
+	if cfg.TimeoutSeconds < 0 {
+		return fmt.Errorf("checkpoint timeout can't be negative")
+	}
+	if err := s.stateManager.UpdateWorktree(worktreeID, map[string]interface{}{
+		"checkpoint_config": &cfg,
+	}); err != nil {
+		return err
+	}
+	if s.claudeMonitor != nil {
+		s.claudeMonitor.ApplyCheckpointConfig(worktree.Path)
+	}
+	logger.Infof("⏱️ Checkpoints for %s: enabled=%t timeout=%ds", worktree.Name, cfg.Enabled, cfg.TimeoutSeconds)
+	return nil
+}
+
 // GetWorktree returns a worktree by ID
 func (s *GitService) GetWorktree(worktreeID string) (*models.Worktree, bool) {
 	return s.stateManager.GetWorktree(worktreeID)
@@ -975,7 +1382,7 @@ func (s *GitService) GetDefaultWorktreePath() string {
 
 // configureGitCredentials sets up Git to use gh CLI for GitHub authentication
 func (s *GitService) configureGitCredentials() {
-	if err := s.githubManager.ConfigureGitCredentials(); err != nil {
+	if _, err := s.RepairCredentials(); err != nil {
 		logger.Warnf("❌ Failed to configure Git credential helper: %v", err)
 	} else {
 		logger.Infof("✅ Git credential helper configured successfully")
@@ -1047,6 +1454,11 @@ func (s *GitService) detectLocalRepos() {
 			existingRepo.LastAccessed = repo.LastAccessed
 			existingRepo.HasGitHubRemote = repo.HasGitHubRemote
 			existingRepo.RemoteOrigin = repo.RemoteOrigin
+			existingRepo.HeadState = repo.HeadState
+			existingRepo.NotReadyReason = repo.NotReadyReason
+			existingRepo.Toolchains = repo.Toolchains
+			existingRepo.Protected = repo.Protected
+			existingRepo.ProtectedReason = repo.ProtectedReason
 
 			// Log if GitHub remote detection changed
 			if existingRepo.HasGitHubRemote != repo.HasGitHubRemote {
@@ -1056,13 +1468,16 @@ func (s *GitService) detectLocalRepos() {
 			repo = existingRepo // Use the existing repo with updated fields
 		}
 
+		// Repositories without commits need an initial commit before worktrees can be created
+		ready := s.prepareUnbornRepo(repo)
+
 		if err := s.stateManager.AddRepository(repo); err != nil {
 			logger.Warnf("⚠️ Failed to add repository %s to state: %v", repoID, err)
 			continue
 		}
 
 		// Check if any worktrees exist for this repo
-		if s.shouldCreateInitialWorktree(repoID) {
+		if ready && s.shouldCreateInitialWorktree(repoID) {
 			logger.Infof("🌱 Creating initial worktree for %s", repoID)
 
 			// For shallow clones or when on a non-default branch, we need to ensure
@@ -1149,8 +1564,12 @@ func (s *GitService) detectLocalRepos() {
 			// 	logger.Warnf("⚠️  Failed to prune worktrees for %s: %v", repoID, pruneErr)
 			// }
 
-			if _, worktree, err := s.handleLocalRepoWorktree(repoID, defaultBranch); err != nil {
+			if _, worktree, err := s.handleLocalRepoWorktree(repoID, defaultBranch, nil); err != nil {
 				logger.Warnf("❌ Failed to create initial worktree for %s: %v", repoID, err)
+				repo.NotReadyReason = fmt.Sprintf("Failed to create an initial worktree from %s: %v", defaultBranch, err)
+				if err := s.stateManager.AddRepository(repo); err != nil {
+					logger.Warnf("⚠️ Failed to update repository status in state: %v", err)
+				}
 			} else {
 				logger.Infof("✅ Initial worktree created: %s", worktree.Name)
 			}
@@ -1255,7 +1674,7 @@ func (s *GitService) getLocalRepoDefaultBranch(repoPath string) string {
 }
 
 // handleLocalRepoWorktree creates a worktree for any local repo
-func (s *GitService) handleLocalRepoWorktree(repoID, branch string) (*models.Repository, *models.Worktree, error) {
+func (s *GitService) handleLocalRepoWorktree(repoID, branch string, creation *models.CreationContext) (*models.Repository, *models.Worktree, error) {
 	// Get the local repo from repositories map
 	localRepo, exists := s.stateManager.GetRepository(repoID)
 	if !exists {
@@ -1267,16 +1686,17 @@ func (s *GitService) handleLocalRepoWorktree(repoID, branch string) (*models.Rep
 		branch = localRepo.DefaultBranch
 	}
 
-	// Check if branch exists in the local repo
-	if !s.branchExists(localRepo.Path, branch, false) {
-		return nil, nil, fmt.Errorf("branch %s does not exist in repository %s", branch, repoID)
+	// Check if branch, tag or commit exists in the local repo
+	source, err := s.resolveLocalCheckoutSource(localRepo, branch)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	// Create new worktree with fun name
 	funName := s.generateUniqueSessionName(localRepo.Path)
 
 	// Create worktree for local repo
-	worktree, err := s.createLocalRepoWorktree(localRepo, branch, funName)
+	worktree, err := s.createLocalRepoWorktree(localRepo, source, funName, creation)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to create worktree for local repo: %v", err)
 	}
@@ -1289,17 +1709,24 @@ func (s *GitService) handleLocalRepoWorktree(repoID, branch string) (*models.Rep
 }
 
 // createLocalRepoWorktree creates a worktree for any local repo
-func (s *GitService) createLocalRepoWorktree(repo *models.Repository, branch, name string) (*models.Worktree, error) {
+func (s *GitService) createLocalRepoWorktree(repo *models.Repository, branch, name string, creation *models.CreationContext) (*models.Worktree, error) {
+	defer s.releaseSessionName(name)
+
 	// Use git WorktreeManager to create the local worktree
+	unlock := s.lockWorktreeAdd(repo.Path)
 	worktree, err := s.gitWorktreeManager.CreateLocalWorktree(git.CreateWorktreeRequest{
-		Repository:   repo,
-		SourceBranch: branch,
-		BranchName:   name,
-		WorkspaceDir: getWorkspaceDir(),
+		Repository:    repo,
+		SourceBranch:  branch,
+		BranchName:    name,
+		WorkspaceDir:  getWorkspaceDir(),
+		MaxPathLength: s.worktreeMaxPathLength(repo.Path),
 	})
+	unlock()
 	if err != nil {
 		return nil, err
 	}
+	worktree.Toolchains = DetectToolchains(worktree.Path)
+	applyCreationContext(worktree, creation)
 
 	// Store worktree in service map
 	if err := s.stateManager.AddWorktree(worktree); err != nil {
@@ -1320,16 +1747,19 @@ func (s *GitService) createLocalRepoWorktree(repo *models.Repository, branch, na
 	if s.setupExecutor != nil {
 		logger.Infof("🚀 Scheduling setup.sh execution for local worktree: %s", worktree.Path)
 		// Run setup.sh execution in a goroutine to avoid blocking worktree creation
-		recovery.SafeGo("setup-script-local-"+worktree.Path, func() {
+		recovery.SafeGo("setup-script:local:"+worktree.Path, func() {
 			// Wait a moment to ensure the worktree is fully ready
 			time.Sleep(2 * time.Second)
 			logger.Infof("⏰ Starting setup.sh execution for local worktree: %s", worktree.Path)
-			s.setupExecutor.ExecuteSetupScript(worktree.Path)
+			s.setupExecutor.ExecuteSetupScript(worktree.Path, s.defaultSetupScript(worktree), s.DependencyCacheEnv(worktree.Path))
 		})
 	} else {
 		logger.Warnf("⚠️ No setup executor configured, skipping setup.sh execution for local worktree: %s", worktree.Path)
 	}
 
+	// Run the repository's setup commands, reported in the worktree's setup_status
+	s.scheduleWorktreeSetup(worktree)
+
 	return worktree, nil
 }
 
@@ -1413,7 +1843,7 @@ func (s *GitService) DeleteWorktree(worktreeID string) (<-chan error, error) {
 	// Exception: Allow deletion during tests (temp directories on Linux/macOS)
 	workspaceDir := config.Runtime.WorkspaceDir
 	isTestPath := s.isTemporaryPath(worktree.Path)
-	if workspaceDir != "" && !strings.HasPrefix(worktree.Path, workspaceDir+"/") && !isTestPath {
+	if workspaceDir != "" && !isWithinDir(workspaceDir, worktree.Path) && !isTestPath {
 		return nil, fmt.Errorf("cannot delete worktree %s: path %s is outside managed workspace directory %s", worktree.Name, worktree.Path, workspaceDir)
 	}
 
@@ -1422,6 +1852,13 @@ func (s *GitService) DeleteWorktree(worktreeID string) (<-chan error, error) {
 
 	// Remove from cache immediately (for fast UI response)
 	s.worktreeCache.RemoveWorktree(worktreeID, worktree.Path)
+	if s.commitSync != nil {
+		s.commitSync.RemoveWorktreeWatcher(worktree.Path)
+	}
+	s.diffCache.forget(worktreeID)
+	s.removeWorktreeBundles(worktreeID)
+	s.removeWorktreePatches(worktreeID)
+	s.stopWorktreeSetup(worktreeID)
 
 	// Remove from service memory immediately
 	if err := s.stateManager.DeleteWorktree(worktreeID); err != nil {
@@ -1481,13 +1918,7 @@ func (s *GitService) UpdateWorktreeBranchName(worktreePath, newBranchName string
 	defer s.mu.Unlock()
 
 	// Find worktree by path
-	var targetWorktree *models.Worktree
-	for _, worktree := range s.stateManager.GetAllWorktrees() {
-		if worktree.Path == worktreePath {
-			targetWorktree = worktree
-			break
-		}
-	}
+	targetWorktree, _ := s.stateManager.FindWorktreeByPath(worktreePath)
 
 	if targetWorktree == nil {
 		return fmt.Errorf("worktree not found for path: %s", worktreePath)
@@ -1500,6 +1931,9 @@ func (s *GitService) UpdateWorktreeBranchName(worktreePath, newBranchName string
 	updates := map[string]interface{}{
 		"branch": newBranchName,
 	}
+	if drift := targetWorktree.ManualIntervention; drift != nil && drift.ActualBranch == newBranchName {
+		updates["manual_intervention"] = (*models.BranchDrift)(nil)
+	}
 
 	if err := s.stateManager.UpdateWorktree(targetWorktree.ID, updates); err != nil {
 		return fmt.Errorf("failed to update worktree branch: %v", err)
@@ -1510,15 +1944,29 @@ func (s *GitService) UpdateWorktreeBranchName(worktreePath, newBranchName string
 	return nil
 }
 
-// CleanupMergedWorktrees removes worktrees that have been fully merged into their source branch
-func (s *GitService) CleanupMergedWorktrees() (int, []string, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// Why a worktree qualifies for merged worktree cleanup
+const (
+	// MergedReasonBranchMissing means the branch of a local repository's worktree no longer
+	// exists in the repository, as it was likely deleted after being merged
+	MergedReasonBranchMissing = "branch_missing"
+	// MergedReasonListedMerged means git lists the branch as merged into its source branch
+	MergedReasonListedMerged = "listed_merged"
+)
 
-	var cleanedUp []string
-	var errors []error
+// MergedWorktree is a worktree merged worktree cleanup removes, and why it qualified
+type MergedWorktree struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Branch string `json:"branch"`
+	Reason string `json:"reason"`
+}
+
+// findMergedWorktrees returns the clean worktrees without commits ahead whose branch has been
+// merged into their source branch. The caller must hold s.mu.
+func (s *GitService) findMergedWorktrees() []MergedWorktree {
+	var merged []MergedWorktree
 
-	logger.Infof("🧹 Starting cleanup of merged worktrees, checking %d worktrees", len(s.stateManager.GetAllWorktrees()))
+	logger.Infof("🧹 Checking %d worktrees for merged branches", len(s.stateManager.GetAllWorktrees()))
 
 	for _, worktree := range s.stateManager.GetAllWorktrees() {
 		logger.Debugf("🔍 Checking worktree %s: dirty=%v, conflicts=%v, commits_ahead=%d, source=%s",
@@ -1546,39 +1994,12 @@ func (s *GitService) CleanupMergedWorktrees() (int, []string, error) {
 			continue
 		}
 
-		// For local repos, check if the worktree branch no longer exists or if it matches the source branch
-		isLocal := s.isLocalRepo(worktree.RepoID)
-		var isMerged bool
-
-		if isLocal {
-			logger.Debugf("🔍 Checking local worktree %s: branch=%s, source=%s", worktree.Name, worktree.Branch, worktree.SourceBranch)
-
-			// For local repos, check if the branch exists in the main repo
-			// If it doesn't exist, it was likely deleted after merge
-			branchExists := s.operations.BranchExists(repo.Path, worktree.Branch, false)
-
-			if !branchExists {
-				logger.Infof("✅ Branch %s no longer exists in main repo (likely merged and deleted)", worktree.Branch)
-				isMerged = true
-			} else {
-				// Branch still exists, check if it's merged
-				branches, err := s.operations.ListBranches(repo.Path, git.ListBranchesOptions{Merged: worktree.SourceBranch})
-				if err != nil {
-					logger.Warnf("⚠️ Failed to check merged status for %s: %v", worktree.Name, err)
-					continue
-				}
-
-				for _, branch := range branches {
-					branch = git.CleanBranchName(branch)
-					if branch == worktree.Branch {
-						isMerged = true
-						logger.Infof("✅ Found %s in merged branches list", worktree.Branch)
-						break
-					}
-				}
-			}
+		reason := ""
+		// For local repos, a branch that no longer exists in the main repo was likely deleted after merge
+		if s.isLocalRepo(worktree.RepoID) && !s.operations.BranchExists(repo.Path, worktree.Branch, false) {
+			logger.Infof("✅ Branch %s no longer exists in main repo (likely merged and deleted)", worktree.Branch)
+			reason = MergedReasonBranchMissing
 		} else {
-			// Regular repo logic (existing code)
 			logger.Debugf("🔍 Checking if branch %s is merged into %s in repo %s", worktree.Branch, worktree.SourceBranch, repo.Path)
 			branches, err := s.operations.ListBranches(repo.Path, git.ListBranchesOptions{Merged: worktree.SourceBranch})
 			if err != nil {
@@ -1586,41 +2007,86 @@ func (s *GitService) CleanupMergedWorktrees() (int, []string, error) {
 				continue
 			}
 
-			// Check if our branch appears in the merged branches list
-			logger.Infof("📋 Merged branches into %s: %d branches found", worktree.SourceBranch, len(branches))
-
 			for _, branch := range branches {
 				// Handle both regular branches and worktree branches (marked with +)
-				branch = git.CleanBranchName(branch)
-				if branch == worktree.Branch {
-					isMerged = true
+				if git.CleanBranchName(branch) == worktree.Branch {
 					logger.Infof("✅ Found %s in merged branches list", worktree.Branch)
+					reason = MergedReasonListedMerged
 					break
 				}
 			}
 		}
 
-		if !isMerged {
+		if reason == "" {
 			logger.Debugf("❌ Branch %s not eligible for cleanup", worktree.Branch)
+			continue
+		}
+		merged = append(merged, MergedWorktree{ID: worktree.ID, Name: worktree.Name, Branch: worktree.Branch, Reason: reason})
+	}
+
+	sort.Slice(merged, func(i, j int) bool { return merged[i].Name < merged[j].Name })
+	return merged
+}
+
+// PreviewMergedWorktreeCleanup returns the worktrees CleanupMergedWorktrees would remove,
+// without removing anything
+func (s *GitService) PreviewMergedWorktreeCleanup() []MergedWorktree {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.findMergedWorktrees()
+}
+
+// CleanupMergedWorktrees removes worktrees that have been fully merged into their source branch.
+// With worktreeIDs, typically from PreviewMergedWorktreeCleanup, only those worktrees are
+// removed, and listed worktrees that no longer qualify are reported as errors.
+func (s *GitService) CleanupMergedWorktrees(worktreeIDs []string) (count int, cleanedUp []string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	params := map[string]string{}
+	if worktreeIDs != nil {
+		params["worktree_ids"] = strings.Join(worktreeIDs, ",")
+	}
+	op := s.journal.begin(OperationCleanupMerged, "merged worktrees", params)
+	defer func() { op.finish(err) }()
+
+	var errors []error
+
+	merged := s.findMergedWorktrees()
+	if worktreeIDs != nil {
+		byID := make(map[string]MergedWorktree, len(merged))
+		for _, worktree := range merged {
+			byID[worktree.ID] = worktree
 		}
+		merged = merged[:0]
+		for _, id := range worktreeIDs {
+			worktree, ok := byID[id]
+			if !ok {
+				errors = append(errors, fmt.Errorf("worktree %s no longer qualifies for cleanup", id))
+				continue
+			}
+			merged = append(merged, worktree)
+		}
+	}
 
-		if isMerged {
-			logger.Infof("🧹 Found merged worktree to cleanup: %s", worktree.Name)
+	for _, worktree := range merged {
+		logger.Infof("🧹 Found merged worktree to cleanup: %s (%s)", worktree.Name, worktree.Reason)
 
-			// Use the existing deletion logic but don't hold the mutex
-			s.mu.Unlock()
-			if done, cleanupErr := s.DeleteWorktree(worktree.ID); cleanupErr != nil {
-				errors = append(errors, fmt.Errorf("failed to cleanup worktree %s: %v", worktree.Name, cleanupErr))
+		// Use the existing deletion logic but don't hold the mutex
+		op.phase("delete " + worktree.Name)
+		s.mu.Unlock()
+		if done, cleanupErr := s.DeleteWorktree(worktree.ID); cleanupErr != nil {
+			errors = append(errors, fmt.Errorf("failed to cleanup worktree %s: %v", worktree.Name, cleanupErr))
+		} else {
+			// Wait for cleanup to complete
+			if waitErr := <-done; waitErr != nil {
+				errors = append(errors, fmt.Errorf("failed to complete cleanup for worktree %s: %v", worktree.Name, waitErr))
 			} else {
-				// Wait for cleanup to complete
-				if waitErr := <-done; waitErr != nil {
-					errors = append(errors, fmt.Errorf("failed to complete cleanup for worktree %s: %v", worktree.Name, waitErr))
-				} else {
-					cleanedUp = append(cleanedUp, worktree.Name)
-				}
+				cleanedUp = append(cleanedUp, worktree.Name)
 			}
-			s.mu.Lock()
 		}
+		s.mu.Lock()
 	}
 
 	if len(cleanedUp) > 0 {
@@ -1648,14 +2114,14 @@ func (s *GitService) cleanupActiveSessions(worktreePath string) {
 
 	// Also try to cleanup any session directories that might exist
 	// Session IDs are typically derived from worktree names
-	workspaceDir := getWorkspaceDir()
-	parts := strings.Split(strings.TrimPrefix(worktreePath, workspaceDir+"/"), "/")
-	if len(parts) >= 2 {
+	rel, inWorkspace := workspaceRelativePath(worktreePath)
+	parts := strings.Split(rel, "/")
+	if inWorkspace && len(parts) >= 2 {
 		sessionID := fmt.Sprintf("%s/%s", parts[0], parts[1])
-		sessionWorkDir := filepath.Join(workspaceDir, sessionID)
+		sessionWorkDir := filepath.Join(getWorkspaceDir(), sessionID)
 
 		// If there's a session directory different from the worktree, clean it up too
-		if sessionWorkDir != worktreePath {
+		if !config.SamePath(sessionWorkDir, worktreePath) {
 			if _, err := os.Stat(sessionWorkDir); err == nil {
 				if removeErr := os.RemoveAll(sessionWorkDir); removeErr != nil {
 					logger.Warnf("⚠️ Failed to remove session directory %s: %v", sessionWorkDir, removeErr)
@@ -1685,51 +2151,149 @@ func (s *GitService) fetchLatestReferenceWithDepth(worktree *models.Worktree, sh
 		return
 	} else {
 		// Remote repos: use shallow or full fetch based on need
+		done := s.timeOperation(worktree, OperationFetch)
+		var err error
 		if shallow {
-			_ = s.fetchBranchFast(worktree.Path, worktree.SourceBranch)
+			err = s.fetchBranchFast(worktree.Path, worktree.SourceBranch)
 		} else {
-			_ = s.fetchBranchFull(worktree.Path, worktree.SourceBranch)
+			err = s.fetchBranchFull(worktree.Path, worktree.SourceBranch)
+		}
+		done(err)
+		if err == nil {
+			s.checkSourceRewrite(worktree.ID)
+			go s.checkRepositoryRename(worktree.RepoID)
 		}
 	}
 }
 
 // fetchBranchFast performs a highly optimized fetch for status updates
 func (s *GitService) fetchBranchFast(repoPath, branch string) error {
+	defer s.shareRepository(repoPath)()
 	return s.operations.FetchBranchFast(repoPath, branch)
 }
 
 // fetchBranchFull performs a full fetch for operations that need complete history
 func (s *GitService) fetchBranchFull(repoPath, branch string) error {
+	defer s.shareRepository(repoPath)()
 	return s.operations.FetchBranchFull(repoPath, branch)
 }
 
 // These fetchLocalBranch functions have been removed as they used the deprecated "live" remote approach.
 // Local repos now work directly with the shared git repository without needing separate remotes.
 
-// SyncWorktree syncs a worktree with its source branch
-func (s *GitService) SyncWorktree(worktreeID string, strategy string) error {
+// Branches a worktree can be synced against
+const (
+	// SyncBaseSource syncs with the source branch the worktree was created from (the default)
+	SyncBaseSource = "source"
+	// SyncBasePullRequest syncs with the branch the worktree's pull request targets on GitHub
+	SyncBasePullRequest = "pull_request"
+)
+
+// SyncOptions says how SyncWorktree syncs a worktree
+type SyncOptions struct {
+	// merge, rebase or ff-only
+	Strategy string
+	// SyncBaseSource (the default) or SyncBasePullRequest
+	Base string
+	// Stash uncommitted changes for the sync and restore them afterwards
+	AutoStash bool
+	// Go ahead although the source branch was force-pushed, rebasing the worktree's own commits
+	// onto the new tip whatever the strategy
+	AcknowledgeRewrite bool
+}
+
+// SyncWorktree syncs a worktree with its source branch, or with the branch its pull request
+// targets when the base is SyncBasePullRequest. Rebases back up the worktree's HEAD first and
+// return the backup, also along with errors of the rebase itself. Syncs with a force-pushed source
+// branch are refused with a SourceRewrittenError unless the rewrite is acknowledged.
+func (s *GitService) SyncWorktree(worktreeID string, options SyncOptions) (*models.OperationBackup, error) {
 	s.mu.RLock()
 	worktree, exists := s.stateManager.GetWorktree(worktreeID)
 	s.mu.RUnlock()
 
 	if !exists {
-		return fmt.Errorf("worktree %s not found", worktreeID)
+		return nil, fmt.Errorf("worktree %s not found", worktreeID)
 	}
 
-	return s.syncWorktreeInternal(worktree, strategy)
+	done := s.timeOperation(worktree, OperationSync)
+	backup, err := s.syncWorktreeInternal(worktree, options)
+	done(err)
+	return backup, err
 }
 
 // syncWorktreeInternal consolidated sync logic for both local and regular repos
-func (s *GitService) syncWorktreeInternal(worktree *models.Worktree, strategy string) error {
+func (s *GitService) syncWorktreeInternal(worktree *models.Worktree, options SyncOptions) (*models.OperationBackup, error) {
+	strategy, base := options.Strategy, options.Base
+
 	// Ensure we have full history for sync operations
 	s.fetchFullHistory(worktree)
 
-	// Get the appropriate source reference (fetch already done by fetchFullHistory)
-	sourceRef := s.getSourceRef(worktree)
+	// The fetch may have just found the source branch force-pushed
+	if current, exists := s.stateManager.GetWorktree(worktree.ID); exists {
+		worktree = current
+	}
+	rewritten := worktree.SourceBranchRewritten != nil && (base == "" || base == SyncBaseSource)
+	if rewritten && !options.AcknowledgeRewrite {
+		return nil, &models.SourceRewrittenError{WorktreeName: worktree.Name, Rewrite: worktree.SourceBranchRewritten}
+	}
+	if rewritten {
+		strategy = "rebase"
+	}
+
+	// Get the appropriate source reference (fetch already done by fetchFullHistory)
+	sourceRef := s.getSourceRef(worktree)
+	switch base {
+	case "", SyncBaseSource:
+	case SyncBasePullRequest:
+		sourceRef = s.getPullRequestBaseRef(worktree)
+		if sourceRef == "" {
+			return nil, fmt.Errorf("worktree %s has no pull request with a known base branch", worktree.Name)
+		}
+		if !s.isLocalRepo(worktree.RepoID) && worktree.PullRequestBaseBranch != worktree.SourceBranch {
+			if err := s.fetchBranchFull(worktree.Path, worktree.PullRequestBaseBranch); err != nil {
+				logger.Warnf("⚠️ Could not fetch pull request base %s: %v", worktree.PullRequestBaseBranch, err)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unknown sync base %q, expected %s or %s", base, SyncBaseSource, SyncBasePullRequest)
+	}
+
+	stashed := false
+	if options.AutoStash && s.operations.IsDirty(worktree.Path) {
+		if err := s.stashWorktree(worktree, fmt.Sprintf("auto-stash before %s sync", strategy)); err != nil {
+			return nil, err
+		}
+		stashed = true
+	}
+
+	// Rebases rewrite the worktree's commits, keep them reachable in case it was the wrong choice
+	var backup *models.OperationBackup
+	var err error
+	if strategy == "rebase" {
+		if backup, err = s.backupBeforeRewrite(worktree, "rebase onto "+sourceRef); err != nil {
+			if stashed {
+				return nil, s.restoreAutoStash(worktree, err)
+			}
+			return nil, err
+		}
+	}
 
-	// Apply the sync strategy
-	if err := s.applySyncStrategy(worktree, strategy, sourceRef); err != nil {
-		return err
+	// Apply the sync strategy, a rewritten source only gets the worktree's own commits replayed
+	if rewritten {
+		err = s.rebaseOntoRewrittenSource(worktree, sourceRef)
+	} else {
+		err = s.applySyncStrategy(worktree, strategy, sourceRef)
+	}
+	if err != nil {
+		if stashed {
+			return backup, s.restoreAutoStash(worktree, err)
+		}
+		return backup, err
+	}
+	if stashed {
+		if err := s.unstashWorktree(worktree); err != nil {
+			return backup, err
+		}
 	}
 
 	// Update worktree status (no need to fetch since we already did fetchFullHistory)
@@ -1740,17 +2304,37 @@ func (s *GitService) syncWorktreeInternal(worktree *models.Worktree, strategy st
 			return fmt.Sprintf("origin/%s", w.SourceBranch) // Remote repos use origin prefix
 		}
 	}
-	s.gitWorktreeManager.UpdateWorktreeStatus(worktree, getSourceRef)
+	s.gitWorktreeManager.UpdateWorktreeStatus(worktree, models.StatusTierStandard, git.StatusRefs{
+		SourceRef:          getSourceRef,
+		PullRequestBaseRef: s.getPullRequestBaseRef,
+	})
 
-	logger.Infof("✅ Synced worktree %s with %s strategy", worktree.Name, strategy)
-	return nil
+	logger.Infof("✅ Synced worktree %s onto %s with %s strategy", worktree.Name, sourceRef, strategy)
+	return backup, nil
+}
+
+// restoreAutoStash handles a sync that failed after stashing the worktree's changes. They stay
+// stashed while the sync's conflicts are resolved, otherwise they are restored right away.
+func (s *GitService) restoreAutoStash(worktree *models.Worktree, syncErr error) error {
+	var conflictErr *models.MergeConflictError
+	if errors.As(syncErr, &conflictErr) {
+		conflictErr.Message += " Your uncommitted changes were stashed and can be restored once the conflicts are resolved."
+		return syncErr
+	}
+	if err := s.unstashWorktree(worktree); err != nil {
+		logger.Warnf("⚠️ Failed to restore auto-stashed changes of %s: %v", worktree.Name, err)
+		return fmt.Errorf("%v (restoring stashed changes also failed: %v)", syncErr, err)
+	}
+	return syncErr
 }
 
-// applySyncStrategy applies merge or rebase strategy
+// applySyncStrategy applies merge, rebase or ff-only strategy
 func (s *GitService) applySyncStrategy(worktree *models.Worktree, strategy, sourceRef string) error {
 	var err error
 
 	switch strategy {
+	case "ff-only":
+		return s.fastForwardWorktree(worktree, sourceRef)
 	case "merge":
 		err = s.operations.Merge(worktree.Path, sourceRef)
 	case "rebase":
@@ -1760,48 +2344,137 @@ func (s *GitService) applySyncStrategy(worktree *models.Worktree, strategy, sour
 	}
 
 	if err != nil {
-		// Check if this is an uncommitted changes error (not a conflict)
-		if s.isUncommittedChangesError(err.Error()) {
-			return fmt.Errorf("cannot %s: worktree has staged changes. Please commit or unstage your changes first", strategy)
-		}
+		return s.syncError(worktree, strategy, err)
+	}
+	return nil
+}
 
-		// Check if this is a merge conflict
-		if s.isMergeConflict(worktree.Path, err.Error()) {
-			return s.createMergeConflictError("sync", worktree, err.Error())
-		}
-		return fmt.Errorf("failed to %s: %v", strategy, err)
+// syncError explains a failed merge or rebase of a sync: staged changes in the way, conflicts to
+// resolve, or anything else
+func (s *GitService) syncError(worktree *models.Worktree, strategy string, err error) error {
+	// Check if this is an uncommitted changes error (not a conflict)
+	if s.isUncommittedChangesError(err.Error()) {
+		return fmt.Errorf("cannot %s: worktree has staged changes. Please commit or unstage your changes first, or sync with auto_stash", strategy)
+	}
+
+	// Check if this is a merge conflict
+	if s.isMergeConflict(worktree.Path, err.Error()) {
+		return s.createMergeConflictError("sync", worktree, err.Error())
+	}
+	return fmt.Errorf("failed to %s: %v", strategy, err)
+}
+
+// fastForwardWorktree moves a worktree to sourceRef without creating a merge commit. Worktrees
+// with local commits are refused with a NonFastForwardError so a rebase can be offered instead.
+func (s *GitService) fastForwardWorktree(worktree *models.Worktree, sourceRef string) error {
+	ahead, err := s.operations.GetCommitCount(worktree.Path, sourceRef, "HEAD")
+	if err != nil {
+		return fmt.Errorf("failed to count local commits: %v", err)
+	}
+	behind, err := s.operations.GetCommitCount(worktree.Path, "HEAD", sourceRef)
+	if err != nil {
+		return fmt.Errorf("failed to count source commits: %v", err)
+	}
+	if ahead > 0 {
+		return &models.NonFastForwardError{WorktreeName: worktree.Name, SourceRef: sourceRef, Ahead: ahead, Behind: behind}
+	}
+	if behind == 0 {
+		logger.Infof("📋 Worktree %s is already up to date with %s", worktree.Name, sourceRef)
+		return nil
+	}
+
+	oldHead, err := s.operations.GetCommitHash(worktree.Path, "HEAD")
+	if err != nil {
+		return fmt.Errorf("failed to read HEAD: %v", err)
+	}
+	if err := s.operations.MergeFastForwardOnly(worktree.Path, sourceRef); err != nil {
+		return fmt.Errorf("failed to fast-forward: %v", err)
+	}
+	newHead, err := s.operations.GetCommitHash(worktree.Path, "HEAD")
+	if err != nil {
+		return fmt.Errorf("failed to read HEAD: %v", err)
 	}
 
+	logger.Infof("📋 Fast-forwarded worktree %s to %s: %s -> %s", worktree.Name, sourceRef, oldHead, newHead)
 	return nil
 }
 
+// MergeOptions says how a worktree is merged back to its local repository
+type MergeOptions struct {
+	// Squash the worktree's commits into a single commit
+	Squash bool
+	// Commit message, empty for the default merge commit message
+	Message string
+	// Merge although the worktree hasn't passed validation
+	SkipValidation bool
+}
+
 // MergeWorktreeToMain merges a local repo worktree's changes back to the main repository
-func (s *GitService) MergeWorktreeToMain(worktreeID string, squash bool) error {
+func (s *GitService) MergeWorktreeToMain(worktreeID string, options MergeOptions) error {
+	worktree, repo, err := s.mergeTarget(worktreeID)
+	if err != nil {
+		return err
+	}
+	if err := s.requireValidation(worktree, ValidationForMerge, options.SkipValidation); err != nil {
+		return err
+	}
+
+	done := s.timeOperation(worktree, OperationMerge)
+	err = s.mergeWorktreeToMain(worktree, repo, options)
+	done(err)
+	if err == nil {
+		s.refreshPullRequestStatus(worktreeID)
+	}
+	return err
+}
+
+// mergeTarget returns a worktree that can be merged back to its local repository, and the
+// repository, after refreshing its full status
+func (s *GitService) mergeTarget(worktreeID string) (*models.Worktree, *models.Repository, error) {
+	s.refreshWorktreeStatusNow(worktreeID, models.StatusTierFull)
+
 	s.mu.RLock()
 	worktree, exists := s.stateManager.GetWorktree(worktreeID)
 	s.mu.RUnlock()
 
 	if !exists {
-		return fmt.Errorf("worktree %s not found", worktreeID)
+		return nil, nil, fmt.Errorf("worktree %s not found", worktreeID)
+	}
+	if err := requireNoBranchDrift(worktree); err != nil {
+		return nil, nil, err
 	}
 
 	// Only works for local repos
 	if !s.isLocalRepo(worktree.RepoID) {
-		return fmt.Errorf("merge to main only supported for local repositories")
+		return nil, nil, fmt.Errorf("merge to main only supported for local repositories")
 	}
 
 	// Get the local repo
 	repo, exists := s.stateManager.GetRepository(worktree.RepoID)
 	if !exists {
-		return fmt.Errorf("local repository %s not found", worktree.RepoID)
+		return nil, nil, fmt.Errorf("local repository %s not found", worktree.RepoID)
 	}
+	return worktree, repo, nil
+}
 
+// mergeWorktreeToMain merges a local repo worktree's branch into its source branch in the main
+// repository. The commit gets provenance trailers and the merge is recorded in the repository's
+// merge ledger. Validation is left to the caller.
+func (s *GitService) mergeWorktreeToMain(worktree *models.Worktree, repo *models.Repository, options MergeOptions) (err error) {
+	squash, message := options.Squash, options.Message
 	logger.Infof("🔄 Merging worktree %s back to main repository", worktree.Name)
+	op := s.journal.begin(OperationMerge, worktree.Name, map[string]string{
+		"worktree_id": worktree.ID,
+		"repo_path":   repo.Path,
+		"squash":      strconv.FormatBool(squash),
+	})
+	defer func() { op.finish(err) }()
 
 	// Ensure we have full history for merge operations
 	s.fetchFullHistory(worktree)
 
 	// First, push the worktree branch to the main repo
+	op.phase("push")
 	output, err := s.runGitCommand(worktree.Path, "push", repo.Path, fmt.Sprintf("%s:%s", worktree.Branch, worktree.Branch))
 	if err != nil {
 		return fmt.Errorf("failed to push worktree branch to main repo: %v\n%s", err, output)
@@ -1813,13 +2486,19 @@ func (s *GitService) MergeWorktreeToMain(worktreeID string, squash bool) error {
 		return fmt.Errorf("failed to checkout source branch in main repo: %v\n%s", err, output)
 	}
 
-	// Merge the worktree branch
+	// Merge the worktree branch, with trailers linking the merge commit back to the worktree
+	if message == "" {
+		message = defaultMergeSubject(worktree.Branch, squash)
+	}
+	ledgerEntry := s.newMergeLedgerEntry(worktree, repo, squash)
+	message = withMergeTrailers(message, ledgerEntry)
 	var mergeArgs []string
 	if squash {
 		mergeArgs = []string{"merge", worktree.Branch, "--squash"}
 	} else {
-		mergeArgs = []string{"merge", worktree.Branch, "--no-ff", "-m", fmt.Sprintf("Merge branch '%s' from worktree", worktree.Branch)}
+		mergeArgs = []string{"merge", worktree.Branch, "--no-ff", "-m", message}
 	}
+	op.phase("merge")
 	output, err = s.runGitCommand(repo.Path, mergeArgs...)
 	if err != nil {
 		// Check if this is a merge conflict
@@ -1831,14 +2510,19 @@ func (s *GitService) MergeWorktreeToMain(worktreeID string, squash bool) error {
 
 	// For squash merges, we need to commit the staged changes
 	if squash {
-		_, err = s.runGitCommitWithGPGFallback(repo.Path, "commit", "-m", fmt.Sprintf("Squash merge branch '%s' from worktree", worktree.Branch))
+		op.phase("commit")
+		_, err = s.runGitCommitWithGPGFallback(repo.Path, "commit", "-m", message)
 		if err != nil {
 			return fmt.Errorf("failed to commit squash merge: %v", err)
 		}
 	}
 
-	// Delete the feature branch from main repo (cleanup)
-	_ = s.operations.DeleteBranch(repo.Path, worktree.Branch, false) // Ignore errors - branch might be in use
+	// Delete the feature branch from main repo (cleanup), protected repositories keep theirs
+	if repo.Protected {
+		logger.Infof("📌 Keeping branch %s in protected repository %s", worktree.Branch, repo.ID)
+	} else {
+		_ = s.operations.DeleteBranch(repo.Path, worktree.Branch, false) // Ignore errors - branch might be in use
+	}
 
 	// Get the new commit hash from the main branch after merge
 	if newCommitHash, err := s.operations.GetCommitHash(repo.Path, "HEAD"); err != nil {
@@ -1849,14 +2533,18 @@ func (s *GitService) MergeWorktreeToMain(worktreeID string, squash bool) error {
 		worktree.CommitHash = newCommitHash
 		s.mu.Unlock()
 		logger.Warnf("📝 Updated worktree %s CommitHash to %s", worktree.Name, newCommitHash)
+		s.recordMerge(ledgerEntry, newCommitHash, time.Now())
 	}
 
+	s.stateManager.recordWorktreeActivity(ActivityMerged, worktree, "", "into "+worktree.SourceBranch, "")
 	logger.Infof("✅ Merged worktree %s to main repository", worktree.Name)
 	return nil
 }
 
-// CreateWorktreePreview creates a preview branch in the main repo for viewing changes outside container
-func (s *GitService) CreateWorktreePreview(worktreeID string) error {
+// CreateWorktreePreview creates a preview branch in the main repo for viewing changes outside
+// container. Replacing an existing preview branch of a protected repository force pushes, which
+// needs confirm.
+func (s *GitService) CreateWorktreePreview(worktreeID string, confirm bool) error {
 	s.mu.RLock()
 	worktree, exists := s.stateManager.GetWorktree(worktreeID)
 	s.mu.RUnlock()
@@ -1881,7 +2569,7 @@ func (s *GitService) CreateWorktreePreview(worktreeID string) error {
 		return fmt.Errorf("repository %s is not available", worktree.RepoID)
 	}
 
-	previewBranchName := fmt.Sprintf("catnip/%s", git.ExtractWorkspaceName(worktree.Branch))
+	previewBranchName := worktreePreviewBranch(worktree)
 	logger.Debugf("🔍 Creating preview branch %s for worktree %s", previewBranchName, worktree.Name)
 
 	// Check if there are uncommitted changes (staged, unstaged, or untracked)
@@ -1898,7 +2586,8 @@ func (s *GitService) CreateWorktreePreview(worktreeID string) error {
 			return fmt.Errorf("failed to create temporary commit: %v", err)
 		}
 		defer func() {
-			// Reset to remove the temporary commit after pushing
+			// Reset to remove the temporary commit after pushing. A mixed reset only touches
+			// the index, so exec bits and symlinks in the working tree are left as they were
 			if tempCommitHash != "" {
 				_, _ = s.runGitCommand(worktree.Path, "reset", "--mixed", "HEAD~1")
 			}
@@ -1910,6 +2599,11 @@ func (s *GitService) CreateWorktreePreview(worktreeID string) error {
 	if err != nil {
 		return fmt.Errorf("failed to check preview branch status: %v", err)
 	}
+	if shouldForceUpdate {
+		if err := requireConfirmed(repo, "force push "+previewBranchName, confirm); err != nil {
+			return err
+		}
+	}
 
 	// Push the worktree branch to a preview branch in main repo
 	pushArgs := []string{"push"}
@@ -1937,6 +2631,12 @@ func (s *GitService) CreateWorktreePreview(worktreeID string) error {
 	return nil
 }
 
+// worktreePreviewBranch returns the name of the preview branch CreateWorktreePreview creates
+// for a worktree in its local repository
+func worktreePreviewBranch(worktree *models.Worktree) string {
+	return fmt.Sprintf("catnip/%s", git.ExtractWorkspaceName(worktree.Branch))
+}
+
 // shouldForceUpdatePreviewBranch determines if we should force-update an existing preview branch
 func (s *GitService) shouldForceUpdatePreviewBranch(repoPath, previewBranchName string) (bool, error) {
 	// Check if the preview branch exists
@@ -1963,8 +2663,9 @@ func (s *GitService) hasUncommittedChanges(worktreePath string) (bool, error) {
 
 // createTemporaryCommit creates a temporary commit with all uncommitted changes
 func (s *GitService) createTemporaryCommit(worktreePath string) (string, error) {
-	// Add all changes (staged, unstaged, and untracked)
-	if output, err := s.runGitCommand(worktreePath, "add", "."); err != nil {
+	// Add all changes (staged, unstaged, untracked, deletions, mode changes and symlinks).
+	// Mode-only changes (chmod +x) are only detected when core.fileMode is on.
+	if output, err := s.runGitCommand(worktreePath, git.WithFileModeTracking("add", "-A")...); err != nil {
 		return "", fmt.Errorf("failed to stage changes: %v\n%s", err, output)
 	}
 
@@ -2012,7 +2713,7 @@ func (s *GitService) isUncommittedChangesError(output string) bool {
 
 // createMergeConflictError creates a detailed merge conflict error
 func (s *GitService) createMergeConflictError(operation string, worktree *models.Worktree, output string) *models.MergeConflictError {
-	return s.conflictResolver.CreateMergeConflictError(operation, worktree.Name, worktree.Path, output)
+	return s.annotateLockfileConflicts(s.conflictResolver.CreateMergeConflictError(operation, worktree.Name, worktree.Path, output), worktree)
 }
 
 // CheckSyncConflicts checks if syncing a worktree would cause merge conflicts
@@ -2031,7 +2732,8 @@ func (s *GitService) CheckSyncConflicts(worktreeID string) (*models.MergeConflic
 	// Get the appropriate source reference
 	sourceRef := s.getSourceRef(worktree)
 
-	return s.conflictResolver.CheckSyncConflicts(worktree.Path, sourceRef)
+	conflict, err := s.conflictResolver.CheckSyncConflicts(worktree.Path, sourceRef)
+	return s.annotateLockfileConflicts(conflict, worktree), err
 }
 
 // CheckMergeConflicts checks if merging a worktree to main would cause conflicts
@@ -2055,7 +2757,8 @@ func (s *GitService) CheckMergeConflicts(worktreeID string) (*models.MergeConfli
 		return nil, fmt.Errorf("local repository %s not found", worktree.RepoID)
 	}
 
-	return s.conflictResolver.CheckMergeConflicts(repo.Path, worktree.Path, worktree.Branch, worktree.SourceBranch, worktree.Name)
+	conflict, err := s.conflictResolver.CheckMergeConflicts(repo.Path, worktree.Path, worktree.Branch, worktree.SourceBranch, worktree.Name)
+	return s.annotateLockfileConflicts(conflict, worktree), err
 }
 
 // GetStateManager returns the worktree state manager
@@ -2093,22 +2796,21 @@ func (s *GitService) RefreshWorktreeStatus(workDir string) error {
 	defer s.mu.RUnlock()
 
 	// Find worktree by path
-	for _, worktree := range s.stateManager.GetAllWorktrees() {
-		if worktree.Path == workDir {
-			// Trigger cache refresh if available
-			if s.worktreeCache != nil {
-				s.worktreeCache.ForceRefresh(worktree.ID)
-				logger.Infof("🔄 Triggered worktree status refresh for %s", worktree.Name)
-			}
-			return nil
+	if worktree, exists := s.stateManager.FindWorktreeByPath(workDir); exists {
+		// Trigger cache refresh if available
+		if s.worktreeCache != nil {
+			s.worktreeCache.ForceRefresh(worktree.ID)
+			logger.Infof("🔄 Triggered worktree status refresh for %s", worktree.Name)
 		}
+		return nil
 	}
 
 	return fmt.Errorf("worktree not found for path: %s", workDir)
 }
 
 // GitAddCommitGetHash performs git add, commit, and returns the commit hash
-// Returns empty string if not a git repository or no changes to commit
+// Returns empty string if not a git repository or no changes to commit, and the hash along with
+// a git.CheckpointHookError when the commit hooks failed and the checkpoint skipped them
 func (s *GitService) GitAddCommitGetHash(workspaceDir, message string) (string, error) {
 	// Check if it's a git repository
 	if !s.operations.IsGitRepository(workspaceDir) {
@@ -2116,9 +2818,21 @@ func (s *GitService) GitAddCommitGetHash(workspaceDir, message string) (string,
 		return "", nil
 	}
 
-	// Stage all changes
-	if output, err := s.runGitCommand(workspaceDir, "add", "."); err != nil {
-		return "", fmt.Errorf("git add failed: %v, output: %s", err, string(output))
+	// Large change sets are left uncommitted while the volume is nearly full, the commit
+	// would copy every changed file into the object store
+	if paused := s.pausedForDiskSpace("checkpoint " + workspaceDir); paused != nil {
+		if changed, err := s.countChangedFiles(workspaceDir); err == nil && changed > checkpointPauseFiles {
+			logger.Warnf("💾 Skipping checkpoint of %d changed files in %s: %v", changed, workspaceDir, paused)
+			return "", nil
+		}
+	}
+
+	// Stage all changes, including deletions, mode changes and symlinks, picking up mode-only
+	// changes regardless of inherited config. The verbose listing of staged files feeds the
+	// ignore suggestions.
+	staged, err := s.runGitCommand(workspaceDir, git.WithFileModeTracking("add", "-A", "--verbose")...)
+	if err != nil {
+		return "", fmt.Errorf("git add failed: %v, output: %s", err, string(staged))
 	}
 
 	// Check if there are staged changes to commit
@@ -2126,11 +2840,14 @@ func (s *GitService) GitAddCommitGetHash(workspaceDir, message string) (string,
 		return "", nil
 	}
 
-	// Commit with the message (with GPG error handling)
-	if _, err := s.runGitCommitWithGPGFallback(workspaceDir, "commit", "-m", message, "-n"); err != nil {
-		return "", fmt.Errorf("git commit failed: %v", err)
+	// Commit with the message, running the commit hooks if the worktree asks for it
+	hookErr := s.commitCheckpoint(workspaceDir, message)
+	if hookErr != nil && !isCheckpointHookError(hookErr) {
+		return "", fmt.Errorf("git commit failed: %v", hookErr)
 	}
 
+	s.recordCheckpointNoise(workspaceDir, staged)
+
 	// Get the commit hash
 	output, err := s.runGitCommand(workspaceDir, "rev-parse", "HEAD")
 	if err != nil {
@@ -2138,7 +2855,7 @@ func (s *GitService) GitAddCommitGetHash(workspaceDir, message string) (string,
 	}
 
 	hash := strings.TrimSpace(string(output))
-	return hash, nil
+	return hash, hookErr
 }
 
 // isGPGSigningError checks if the error output indicates a GPG signing failure
@@ -2208,7 +2925,7 @@ func (s *GitService) runGitCommitWithGPGFallback(workspaceDir string, args ...st
 }
 
 // createWorktreeForExistingRepo creates a worktree for an already loaded repository
-func (s *GitService) createWorktreeForExistingRepo(repo *models.Repository, branch string) (*models.Repository, *models.Worktree, error) {
+func (s *GitService) createWorktreeForExistingRepo(repo *models.Repository, branch string, creation *models.CreationContext) (*models.Repository, *models.Worktree, error) {
 	// If no branch specified, use default
 	if branch == "" {
 		branch = repo.DefaultBranch
@@ -2216,26 +2933,30 @@ func (s *GitService) createWorktreeForExistingRepo(repo *models.Repository, bran
 
 	// Handle local repos specially (they don't have a bare repo)
 	if s.isLocalRepo(repo.ID) {
-		return s.handleLocalRepoWorktree(repo.ID, branch)
+		return s.handleLocalRepoWorktree(repo.ID, branch, creation)
 	}
 
-	// Always fetch the latest state for checkout operations (full history)
-	logger.Infof("🔄 Fetching latest state for branch %s", branch)
-	if err := s.fetchBranch(repo.Path, git.FetchStrategy{
-		Branch:         branch,
-		UpdateLocalRef: true,
-	}); err != nil {
-		// If fetch fails, check if branch exists locally and proceed if so
-		if !s.branchExists(repo.Path, branch, true) {
-			return nil, nil, fmt.Errorf("failed to fetch branch %s: %v", branch, err)
+	if err := s.ensureDiskSpace("create a worktree of "+repo.ID, worktreeEstimateBytes(repo)); err != nil {
+		return nil, nil, err
+	}
+
+	// Mirrored branches are created without a round-trip, everything else fetches the latest
+	// state (full history)
+	source, mirrored := s.mirroredSource(repo, branch)
+	if mirrored {
+		logger.Infof("🪞 %s is mirrored, not fetching it", branch)
+	} else {
+		logger.Infof("🔄 Fetching latest state for %s", branch)
+		var err error
+		if source, err = s.fetchCheckoutSource(repo.Path, branch, 0); err != nil {
+			return nil, nil, err
 		}
-		logger.Warnf("⚠️ Fetch failed but branch exists locally, proceeding with checkout")
 	}
 
 	// Create new worktree with fun name
 	funName := s.generateUniqueSessionName(repo.Path)
 	// Creating worktree
-	worktree, err := s.createWorktreeInternalForRepo(repo, branch, funName, true)
+	worktree, err := s.createWorktreeInternalForRepo(repo, source, funName, true, creation)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to create worktree: %v", err)
 	}
@@ -2247,40 +2968,75 @@ func (s *GitService) createWorktreeForExistingRepo(repo *models.Repository, bran
 	return repo, worktree, nil
 }
 
+// lockWorktreeAdd serializes worktree creation per repository, as concurrent git worktree add
+// calls read each other's half-written administrative files. It holds the repository lock
+// shared, so git gc waits for the worktree to be added.
+func (s *GitService) lockWorktreeAdd(repoPath string) func() {
+	unshare := s.shareRepository(repoPath)
+	value, _ := s.worktreeAddLocks.LoadOrStore(repoPath, &sync.Mutex{})
+	mutex := value.(*sync.Mutex)
+	mutex.Lock()
+	return func() {
+		mutex.Unlock()
+		unshare()
+	}
+}
+
 // createWorktreeInternalForRepo creates a worktree for a specific repository
-func (s *GitService) createWorktreeInternalForRepo(repo *models.Repository, source, name string, isInitial bool) (*models.Worktree, error) {
-	return s.createWorktreeInternalForRepoWithOptions(repo, source, name, isInitial, true)
+func (s *GitService) createWorktreeInternalForRepo(repo *models.Repository, source, name string, isInitial bool, creation *models.CreationContext) (*models.Worktree, error) {
+	return s.createWorktreeInternalForRepoWithOptions(repo, source, name, isInitial, true, creation)
 }
 
 // createWorktreeInternalForRepoWithOptions creates a worktree with option to skip Claude cleanup (for restoration)
-func (s *GitService) createWorktreeInternalForRepoWithOptions(repo *models.Repository, source, name string, isInitial bool, shouldCleanupClaude bool) (*models.Worktree, error) {
+func (s *GitService) createWorktreeInternalForRepoWithOptions(repo *models.Repository, source, name string, isInitial bool, shouldCleanupClaude bool, creation *models.CreationContext) (*models.Worktree, error) {
+	worktree, name, err := s.addRepoWorktree(repo, source, name, isInitial, shouldCleanupClaude, creation)
+	if err != nil {
+		return nil, err
+	}
+	defer s.releaseSessionName(name)
+
+	// Store worktree in service map
+	if err := s.stateManager.AddWorktree(worktree); err != nil {
+		logger.Warnf("⚠️ Failed to add worktree to state: %v", err)
+	}
+
+	s.startWorktree(worktree, isInitial)
+	return worktree, nil
+}
+
+// addRepoWorktree runs git worktree add for a new worktree of repo and prepares it, trying new
+// names while the branch or worktree is taken. The name the worktree was created with stays
+// reserved and is returned for the caller to release once the worktree is in state.
+func (s *GitService) addRepoWorktree(repo *models.Repository, source, name string, isInitial bool, shouldCleanupClaude bool, creation *models.CreationContext) (*models.Worktree, string, error) {
 	// Use git WorktreeManager to create the worktree
+	unlock := s.lockWorktreeAdd(repo.Path)
 	worktree, err := s.gitWorktreeManager.CreateWorktree(git.CreateWorktreeRequest{
-		Repository:   repo,
-		SourceBranch: source,
-		BranchName:   name,
-		WorkspaceDir: getWorkspaceDir(),
-		IsInitial:    isInitial,
+		Repository:    repo,
+		SourceBranch:  source,
+		BranchName:    name,
+		WorkspaceDir:  getWorkspaceDir(),
+		IsInitial:     isInitial,
+		MaxPathLength: s.worktreeMaxPathLength(repo.Path),
 	})
+	unlock()
 	if err != nil {
 		// Check if the error is because branch already exists or worktree registration conflict
+		var reason string
 		if strings.Contains(err.Error(), "already exists") {
-			logger.Warnf("⚠️  Branch %s already exists, trying a new name...", name)
-			// Generate a unique name that doesn't already exist
-			newName := s.generateUniqueSessionName(repo.Path)
-			return s.createWorktreeInternalForRepoWithOptions(repo, source, newName, isInitial, shouldCleanupClaude)
+			reason = fmt.Sprintf("Branch %s already exists", name)
 		} else if strings.Contains(err.Error(), "missing but already registered worktree") {
-			logger.Warnf("⚠️  Worktree registration conflict for %s, trying a new name...", name)
-			// Generate a unique name that doesn't already exist
-			newName := s.generateUniqueSessionName(repo.Path)
-			return s.createWorktreeInternalForRepoWithOptions(repo, source, newName, isInitial, shouldCleanupClaude)
+			reason = fmt.Sprintf("Worktree registration conflict for %s", name)
 		} else if strings.Contains(err.Error(), "worktree creation failed even after cleanup") {
-			logger.Warnf("⚠️  Worktree creation failed even after cleanup for %s, trying a new name...", name)
-			// Generate a unique name that doesn't already exist
-			newName := s.generateUniqueSessionName(repo.Path)
-			return s.createWorktreeInternalForRepoWithOptions(repo, source, newName, isInitial, shouldCleanupClaude)
+			reason = fmt.Sprintf("Worktree creation failed even after cleanup for %s", name)
 		}
-		return nil, err
+		// The failed name stays reserved until the retry returns, so it isn't picked again
+		defer s.releaseSessionName(name)
+		if reason == "" {
+			return nil, "", err
+		}
+		logger.Warnf("⚠️  %s, trying a new name...", reason)
+		newName := s.generateUniqueSessionName(repo.Path)
+		return s.addRepoWorktree(repo, source, newName, isInitial, shouldCleanupClaude, creation)
 	}
 
 	// CRITICAL: Clean up any existing Claude session files for this worktree path BEFORE any other initialization
@@ -2293,11 +3049,13 @@ func (s *GitService) createWorktreeInternalForRepoWithOptions(repo *models.Repos
 		}
 	}
 
-	// Store worktree in service map
-	if err := s.stateManager.AddWorktree(worktree); err != nil {
-		logger.Warnf("⚠️ Failed to add worktree to state: %v", err)
-	}
+	worktree.Toolchains = DetectToolchains(worktree.Path)
+	applyCreationContext(worktree, creation)
+	return worktree, name, nil
+}
 
+// startWorktree starts watching a worktree that was just added to state and schedules its setup
+func (s *GitService) startWorktree(worktree *models.Worktree, isInitial bool) {
 	// Add to cache and start watching
 	s.worktreeCache.AddWorktree(worktree.ID, worktree.Path)
 
@@ -2320,30 +3078,18 @@ func (s *GitService) createWorktreeInternalForRepoWithOptions(repo *models.Repos
 	if s.setupExecutor != nil {
 		logger.Infof("🚀 Scheduling setup.sh execution for worktree: %s", worktree.Path)
 		// Run setup.sh execution in a goroutine to avoid blocking worktree creation
-		recovery.SafeGo("setup-script-"+worktree.Path, func() {
+		recovery.SafeGo("setup-script:"+worktree.Path, func() {
 			// Wait a moment to ensure the worktree is fully ready
 			time.Sleep(2 * time.Second)
 			logger.Infof("⏰ Starting setup.sh execution for worktree: %s", worktree.Path)
-			s.setupExecutor.ExecuteSetupScript(worktree.Path)
+			s.setupExecutor.ExecuteSetupScript(worktree.Path, s.defaultSetupScript(worktree), s.DependencyCacheEnv(worktree.Path))
 		})
 	} else {
 		logger.Warnf("⚠️ No setup executor configured, skipping setup.sh execution for worktree: %s", worktree.Path)
 	}
 
-	return worktree, nil
-}
-
-// unshallowRepository unshallows a specific branch in the background
-func (s *GitService) unshallowRepository(barePath, branch string) {
-	// Wait a bit before starting to avoid interfering with initial setup
-	time.Sleep(5 * time.Second)
-
-	// Only fetch the specific branch to be more efficient
-	if output, err := s.runGitCommand(barePath, "fetch", "origin", "--unshallow", branch); err != nil {
-		// Silent failure - unshallow is optional optimization
-		_ = output // Avoid unused variable
-		_ = err
-	}
+	// Run the repository's setup commands, reported in the worktree's setup_status
+	s.scheduleWorktreeSetup(worktree)
 }
 
 // GetRepositoryByID returns a repository by its ID
@@ -2378,27 +3124,91 @@ func (s *GitService) GetWorktreeDiff(worktreeID string) (*git.WorktreeDiffRespon
 		return nil, fmt.Errorf("worktree not found: %s", worktreeID)
 	}
 
-	// Get source reference and delegate to WorktreeManager
-	sourceRef := s.getSourceRef(worktree)
+	// The UI polls diffs, so identical concurrent requests share one computation
+	leader := false
+	result, err, _ := s.diffCache.flight.Do(worktreeID, func() (interface{}, error) {
+		leader = true
+		return s.cachedWorktreeDiff(worktree)
+	})
+	if !leader {
+		s.diffCache.recordShared()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return result.(*git.WorktreeDiffResponse), nil
+}
 
-	// Create fetch function that the WorktreeManager can call if needed
-	fetchLatestRef := func(w *models.Worktree) error {
-		s.fetchLatestReference(w)
-		return nil
+// defaultDiffSummaryLimit is the page size of GetWorktreeDiffSummary when none is given
+const defaultDiffSummaryLimit = 100
+
+// GetWorktreeDiffSummary returns a page of the files changed in a worktree against its source
+// branch, without contents. A limit of 0 uses defaultDiffSummaryLimit. Generated files are
+// collapsed unless expandGenerated is set.
+func (s *GitService) GetWorktreeDiffSummary(worktreeID string, limit, offset int, expandGenerated bool) (*git.WorktreeDiffSummary, error) {
+	s.mu.RLock()
+	worktree, exists := s.stateManager.GetWorktree(worktreeID)
+	s.mu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("worktree not found: %s", worktreeID)
+	}
+	if limit < 0 || offset < 0 {
+		return nil, fmt.Errorf("invalid page, limit and offset must not be negative")
+	}
+	if limit == 0 {
+		limit = defaultDiffSummaryLimit
 	}
 
-	result, err := s.gitWorktreeManager.GetWorktreeDiff(worktree, sourceRef, fetchLatestRef)
+	summary, err := s.gitWorktreeManager.GetWorktreeDiffSummary(worktree, s.getSourceRef(worktree), s.fetchLatestRefForDiff)
 	if err != nil {
 		return nil, err
 	}
+	summary.WorktreeID = worktree.ID
+	summary.AuthoredStats = s.classifyDiffFiles(worktree.Path, summary.Files, expandGenerated)
+	summary.Offset = offset
+	summary.Limit = limit
+	end := min(offset+limit, len(summary.Files))
+	summary.HasMore = end < len(summary.Files)
+	summary.Files = summary.Files[min(offset, end):end]
+	return summary, nil
+}
+
+// GetWorktreeFileDiff returns the contents and unified diff of one file changed in a worktree
+func (s *GitService) GetWorktreeFileDiff(worktreeID, path string) (*git.FileDiff, error) {
+	s.mu.RLock()
+	worktree, exists := s.stateManager.GetWorktree(worktreeID)
+	s.mu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("worktree not found: %s", worktreeID)
+	}
+	fileDiff, err := s.gitWorktreeManager.GetWorktreeFileDiff(worktree, s.getSourceRef(worktree), s.fetchLatestRefForDiff, path)
+	if err != nil {
+		return nil, err
+	}
+	files := []git.FileDiff{*fileDiff}
+	s.classifyDiffFiles(worktree.Path, files, true)
+	return &files[0], nil
+}
 
-	// Set the worktreeID since git WorktreeManager doesn't have access to it
-	result.WorktreeID = worktreeID
-	return result, nil
+// PullRequestOptions says how a worktree's pull request is created or updated
+type PullRequestOptions struct {
+	// Force push the worktree branch
+	ForcePush bool
+	// Open the pull request as a draft, ignored by updates
+	Draft bool
+	// Go ahead although the worktree hasn't passed validation
+	SkipValidation bool
+	// Spend the GitHub rate limit budget kept in reserve rather than queueing, for the OrQueue
+	// variants
+	Urgent bool
 }
 
 // CreatePullRequest creates a pull request for a worktree branch
-func (s *GitService) CreatePullRequest(worktreeID, title, body string, forcePush bool) (*models.PullRequestResponse, error) {
+func (s *GitService) CreatePullRequest(worktreeID, title, body string, options PullRequestOptions) (*models.PullRequestResponse, error) {
+	s.refreshWorktreeStatusNow(worktreeID, models.StatusTierFull)
+
 	s.mu.RLock()
 	worktree, exists := s.stateManager.GetWorktree(worktreeID)
 	if !exists {
@@ -2413,6 +3223,10 @@ func (s *GitService) CreatePullRequest(worktreeID, title, body string, forcePush
 	}
 	s.mu.RUnlock()
 
+	if err := requireNoBranchDrift(worktree); err != nil {
+		return nil, err
+	}
+
 	// Check if this is a local repository without a GitHub remote
 	if strings.HasPrefix(worktree.RepoID, "local/") && !repo.HasGitHubRemote {
 		return nil, fmt.Errorf("NO_GITHUB_REMOTE: this local repository does not have a GitHub remote configured. Please create a GitHub repository first")
@@ -2420,44 +3234,42 @@ func (s *GitService) CreatePullRequest(worktreeID, title, body string, forcePush
 
 	logger.Infof("🔄 Creating pull request for worktree %s", worktree.Name)
 
-	// Check if base branch exists on remote and push if needed
-	if err := s.ensureBaseBranchOnRemote(worktree, repo); err != nil {
-		return nil, fmt.Errorf("failed to ensure base branch exists on remote: %v", err)
+	composed := s.composePullRequestBody(worktree, repo, body)
+	if composed.Warning != "" {
+		logger.Warnf("⚠️ Creating pull request for %s without template: %s", worktree.Name, composed.Warning)
 	}
-
-	pr, err := s.githubManager.CreatePullRequest(git.CreatePullRequestRequest{
-		Worktree:         worktree,
-		Repository:       repo,
-		Title:            title,
-		Body:             body,
-		IsUpdate:         false,
-		ForcePush:        forcePush,
-		FetchFullHistory: s.fetchFullHistory,
-		CreateTempCommit: s.createTemporaryCommit,
-		RevertTempCommit: s.revertTemporaryCommit,
-	})
-
+	body = composed.Body
+	pr, err := s.submitPullRequest(worktree, repo, title, body, false, options)
 	if err != nil {
 		return nil, err
 	}
+	s.mirrorManager.EnqueueBranch(repo.ID, pr.HeadBranch)
+	s.stateManager.recordWorktreeActivity(ActivityPullRequestOpened, worktree, "", title, pr.URL)
 
 	// Save PR metadata to worktree state and emit events
 	s.mu.Lock()
 	updates := map[string]interface{}{
-		"pull_request_url":   pr.URL,
-		"pull_request_title": title,
-		"pull_request_body":  body,
+		"pull_request_url":         pr.URL,
+		"pull_request_title":       title,
+		"pull_request_body":        body,
+		"pull_request_base_branch": pr.BaseBranch,
 	}
 	if err := s.stateManager.UpdateWorktree(worktreeID, updates); err != nil {
 		logger.Warnf("Failed to update worktree %s with PR metadata: %v", worktreeID, err)
 	}
 	s.mu.Unlock()
+	s.refreshPullRequestStatus(worktreeID)
 
+	if s.eventsEmitter != nil {
+		s.eventsEmitter.EmitPullRequestCreated(worktreeID, pr)
+	}
 	return pr, nil
 }
 
 // UpdatePullRequest updates an existing pull request for a worktree branch
-func (s *GitService) UpdatePullRequest(worktreeID, title, body string, forcePush bool) (*models.PullRequestResponse, error) {
+func (s *GitService) UpdatePullRequest(worktreeID, title, body string, options PullRequestOptions) (*models.PullRequestResponse, error) {
+	s.refreshWorktreeStatusNow(worktreeID, models.StatusTierFull)
+
 	s.mu.RLock()
 	worktree, exists := s.stateManager.GetWorktree(worktreeID)
 	if !exists {
@@ -2472,44 +3284,68 @@ func (s *GitService) UpdatePullRequest(worktreeID, title, body string, forcePush
 	}
 	s.mu.RUnlock()
 
-	logger.Infof("🔄 Updating pull request for worktree %s", worktree.Name)
-
-	// Check if base branch exists on remote and push if needed
-	if err := s.ensureBaseBranchOnRemote(worktree, repo); err != nil {
-		return nil, fmt.Errorf("failed to ensure base branch exists on remote: %v", err)
+	if err := requireNoBranchDrift(worktree); err != nil {
+		return nil, err
 	}
 
-	pr, err := s.githubManager.CreatePullRequest(git.CreatePullRequestRequest{
-		Worktree:         worktree,
-		Repository:       repo,
-		Title:            title,
-		Body:             body,
-		IsUpdate:         true,
-		ForcePush:        forcePush,
-		FetchFullHistory: s.fetchFullHistory,
-		CreateTempCommit: s.createTemporaryCommit,
-		RevertTempCommit: s.revertTemporaryCommit,
-	})
+	logger.Infof("🔄 Updating pull request for worktree %s", worktree.Name)
 
+	options.Draft = false
+	pr, err := s.submitPullRequest(worktree, repo, title, body, true, options)
 	if err != nil {
 		return nil, err
 	}
+	s.mirrorManager.EnqueueBranch(repo.ID, pr.HeadBranch)
 
 	// Save PR metadata to worktree state (in case it changed) and emit events
 	s.mu.Lock()
 	updates := map[string]interface{}{
-		"pull_request_url":   pr.URL,
-		"pull_request_title": title,
-		"pull_request_body":  body,
+		"pull_request_url":         pr.URL,
+		"pull_request_title":       title,
+		"pull_request_body":        body,
+		"pull_request_base_branch": pr.BaseBranch,
 	}
 	if err := s.stateManager.UpdateWorktree(worktreeID, updates); err != nil {
 		logger.Warnf("Failed to update worktree %s with PR metadata: %v", worktreeID, err)
 	}
 	s.mu.Unlock()
+	s.refreshPullRequestStatus(worktreeID)
 
 	return pr, nil
 }
 
+// submitPullRequest validates and pushes the worktree branch and creates or updates its pull
+// request, recording how long it took
+func (s *GitService) submitPullRequest(worktree *models.Worktree, repo *models.Repository, title, body string, isUpdate bool, options PullRequestOptions) (*models.PullRequestResponse, error) {
+	if err := s.requireValidation(worktree, ValidationForPullRequest, options.SkipValidation); err != nil {
+		return nil, err
+	}
+
+	done := s.timeOperation(worktree, OperationPullRequest)
+
+	// Check if base branch exists on remote and push if needed
+	if err := s.ensureBaseBranchOnRemote(worktree, repo); err != nil {
+		err = fmt.Errorf("failed to ensure base branch exists on remote: %v", err)
+		done(err)
+		return nil, err
+	}
+
+	pr, err := s.githubManager.CreatePullRequest(git.CreatePullRequestRequest{
+		Worktree:         worktree,
+		Repository:       repo,
+		Title:            title,
+		Body:             body,
+		IsUpdate:         isUpdate,
+		ForcePush:        options.ForcePush,
+		Draft:            options.Draft,
+		FetchFullHistory: s.fetchHistoryForPullRequest,
+		CreateTempCommit: s.createTemporaryCommit,
+		RevertTempCommit: s.revertTemporaryCommit,
+	})
+	done(err)
+	return pr, err
+}
+
 // ensureBaseBranchOnRemote checks if the base branch exists on remote and pushes it if needed
 func (s *GitService) ensureBaseBranchOnRemote(worktree *models.Worktree, repo *models.Repository) error {
 	// For local repositories, check if base branch exists on remote
@@ -2572,7 +3408,11 @@ func (s *GitService) pushBaseBranchToRemote(worktree *models.Worktree, repo *mod
 		ConvertHTTPS: true,
 	}
 
-	return s.pushBranch(worktree, repo, strategy)
+	if err := s.pushBranch(worktree, repo, strategy); err != nil {
+		return err
+	}
+	s.mirrorManager.EnqueueBranch(repo.ID, worktree.SourceBranch)
+	return nil
 }
 
 // fetchBaseBranchFromOrigin fetches the latest base branch from origin
@@ -2610,7 +3450,10 @@ func (s *GitService) syncBranchWithUpstream(worktree *models.Worktree) error {
 
 	logger.Infof("🔄 Branch %s is %d commits behind remote, syncing", worktree.Branch, behindCount)
 
-	// Rebase our changes on top of the remote branch
+	// Rebase our changes on top of the remote branch, the backup is in the activity log
+	if _, err := s.backupBeforeRewrite(worktree, fmt.Sprintf("rebase onto origin/%s", worktree.Branch)); err != nil {
+		return err
+	}
 	output, err = s.runGitCommand(worktree.Path, "rebase", fmt.Sprintf("origin/%s", worktree.Branch))
 	if err != nil {
 		// Check if this is a rebase conflict
@@ -2642,18 +3485,7 @@ func (s *GitService) GetPullRequestInfo(worktreeID string) (*models.PullRequestI
 		return nil, fmt.Errorf("repository %s not found", worktree.RepoID)
 	}
 
-	// Check if branch has commits ahead of the base branch
-	hasCommitsAhead, err := s.checkHasCommitsAhead(worktree)
-	if err != nil {
-		logger.Warnf("⚠️ Could not check commits ahead: %v", err)
-		hasCommitsAhead = false // Default to false if we can't determine
-	}
-
-	// Initialize PR info with commits ahead status
-	prInfo := &models.PullRequestInfo{
-		HasCommitsAhead: hasCommitsAhead,
-		Exists:          false,
-	}
+	prInfo := &models.PullRequestInfo{Exists: false}
 
 	// GitHubManager handles URL parsing and PR checking internally
 
@@ -2664,6 +3496,25 @@ func (s *GitService) GetPullRequestInfo(worktreeID string) (*models.PullRequestI
 		prInfo = ghPrInfo
 	}
 
+	// The PR may have been retargeted on GitHub, later divergence is counted against its base
+	if prInfo.BaseBranch != "" && prInfo.BaseBranch != worktree.PullRequestBaseBranch {
+		logger.Infof("🎯 Pull request of %s targets %s", worktree.Name, prInfo.BaseBranch)
+		if err := s.stateManager.UpdateWorktree(worktreeID, map[string]interface{}{"pull_request_base_branch": prInfo.BaseBranch}); err != nil {
+			logger.Warnf("⚠️ Failed to record pull request base of %s: %v", worktree.Name, err)
+		}
+		if s.worktreeCache != nil {
+			s.worktreeCache.ForceRefresh(worktreeID)
+		}
+	}
+
+	// Check if branch has commits ahead of the base branch
+	hasCommitsAhead, err := s.checkHasCommitsAhead(worktree)
+	if err != nil {
+		logger.Warnf("⚠️ Could not check commits ahead: %v", err)
+		hasCommitsAhead = false // Default to false if we can't determine
+	}
+	prInfo.HasCommitsAhead = hasCommitsAhead
+
 	// Override with persisted PR data if available (gives precedence to locally stored data)
 	if worktree.PullRequestURL != "" {
 		prInfo.Exists = true
@@ -2681,19 +3532,25 @@ func (s *GitService) GetPullRequestInfo(worktreeID string) (*models.PullRequestI
 	return prInfo, nil
 }
 
-// checkHasCommitsAhead checks if the worktree branch has commits ahead of the base branch
+// checkHasCommitsAhead checks if the worktree branch has commits ahead of the base branch: the
+// branch its pull request targets when there is one, its source branch otherwise
 func (s *GitService) checkHasCommitsAhead(worktree *models.Worktree) (bool, error) {
+	base := worktree.SourceBranch
+	if worktree.PullRequestURL != "" && worktree.PullRequestBaseBranch != "" {
+		base = worktree.PullRequestBaseBranch
+	}
+
 	// Ensure we have the latest base branch reference
 	var baseRef string
 	if s.isLocalRepo(worktree.RepoID) {
 		// For local repos, use the local base branch reference
-		baseRef = worktree.SourceBranch
+		baseRef = base
 	} else {
 		// For remote repos, fetch the latest base branch and use origin reference
-		if _, err := s.runGitCommand(worktree.Path, "fetch", "origin", worktree.SourceBranch); err != nil {
-			logger.Warnf("⚠️ Could not fetch base branch %s: %v", worktree.SourceBranch, err)
+		if _, err := s.runGitCommand(worktree.Path, "fetch", "origin", base); err != nil {
+			logger.Warnf("⚠️ Could not fetch base branch %s: %v", base, err)
 		}
-		baseRef = fmt.Sprintf("origin/%s", worktree.SourceBranch)
+		baseRef = fmt.Sprintf("origin/%s", base)
 	}
 
 	// Count commits ahead of base branch
@@ -2733,27 +3590,30 @@ func (s *GitService) RefreshWorktreeStatusByID(worktreeID string) error {
 		return fmt.Errorf("worktree %s not found", worktreeID)
 	}
 
-	// Create a function that provides the source reference
-	getSourceRefFunc := func(w *models.Worktree) string {
-		return s.getSourceRef(w)
-	}
-
 	// Force update the worktree status using the WorktreeManager
-	s.gitWorktreeManager.UpdateWorktreeStatus(worktree, getSourceRefFunc)
+	s.gitWorktreeManager.UpdateWorktreeStatus(worktree, models.StatusTierStandard, s.statusRefs())
 
 	// Create updates map for the state manager
 	updates := map[string]interface{}{
 		"commit_hash":    worktree.CommitHash,
 		"commit_count":   worktree.CommitCount,
 		"commits_behind": worktree.CommitsBehind,
+		"stash_count":    worktree.StashCount,
 		"is_dirty":       worktree.IsDirty,
 		"has_conflicts":  worktree.HasConflicts,
 	}
+	if worktree.PullRequestBaseBranch != "" && worktree.PullRequestURL != "" {
+		updates["pull_request_commit_count"] = worktree.PullRequestCommitCount
+		updates["pull_request_commits_behind"] = worktree.PullRequestCommitsBehind
+	}
 
 	// Update the state manager with the new values
 	if err := s.stateManager.UpdateWorktree(worktreeID, updates); err != nil {
 		return fmt.Errorf("failed to update worktree state: %v", err)
 	}
+	if s.worktreeCache != nil {
+		s.worktreeCache.ForceRefresh(worktreeID)
+	}
 
 	logger.Infof("✅ Force refreshed worktree %s status: %d commits ahead", worktree.Name, worktree.CommitCount)
 	return nil
@@ -2761,6 +3621,12 @@ func (s *GitService) RefreshWorktreeStatusByID(worktreeID string) error {
 
 // CreateFromTemplate creates a new project from a template using bare repository approach
 func (s *GitService) CreateFromTemplate(templateID, projectName string) (*models.Repository, *models.Worktree, error) {
+	return s.CreateFromTemplateWithCreation(templateID, projectName, nil)
+}
+
+// CreateFromTemplateWithCreation is CreateFromTemplate with the creation context of the initial
+// worktree
+func (s *GitService) CreateFromTemplateWithCreation(templateID, projectName string, creation *models.CreationContext) (*models.Repository, *models.Worktree, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -2939,7 +3805,7 @@ func (s *GitService) CreateFromTemplate(templateID, projectName string) (*models
 	funName := s.generateUniqueSessionName(repo.Path)
 
 	// Create worktree using the bare repository (similar to remote repos)
-	worktree, err := s.createWorktreeInternalForRepo(repo, defaultBranch, funName, true)
+	worktree, err := s.createWorktreeInternalForRepo(repo, defaultBranch, funName, true, creation)
 	if err != nil {
 		logger.Warnf("⚠️ Failed to create initial worktree for template project: %v", err)
 		// Still return success since the repository was created successfully
@@ -2952,10 +3818,10 @@ func (s *GitService) CreateFromTemplate(templateID, projectName string) (*models
 	return repo, worktree, nil
 }
 
-// RecreateWorktree implements the WorktreeRestorer interface
+// RestoreWorktree implements the WorktreeRestorer interface
 // This method manually restores worktrees by leveraging existing git metadata
 // instead of using `git worktree add` which fails due to registration conflicts
-func (s *GitService) RecreateWorktree(worktree *models.Worktree, repo *models.Repository) error {
+func (s *GitService) RestoreWorktree(worktree *models.Worktree, repo *models.Repository) error {
 	logger.Infof("🔄 Manually restoring worktree %s at %s (from repo %s)", worktree.Name, worktree.Path, repo.Path)
 
 	// Step 1: Create the workspace directory
@@ -2997,7 +3863,7 @@ func (s *GitService) RecreateWorktree(worktree *models.Worktree, repo *models.Re
 		logger.Warnf("🔧 Creating fresh worktree during restoration (no Claude cleanup): repo=%s, sourceBranch=%s, branchName=%s",
 			repo.Path, worktree.SourceBranch, branchRef)
 
-		_, err := s.createWorktreeInternalForRepoWithOptions(repo, worktree.SourceBranch, branchRef, false, false)
+		_, err := s.createWorktreeInternalForRepoWithOptions(repo, worktree.SourceBranch, branchRef, false, false, nil)
 
 		if err != nil {
 			logger.Warnf("❌ Fresh worktree creation failed for %s: %v", worktree.Name, err)
@@ -3107,9 +3973,17 @@ func (s *GitService) RecreateWorktree(worktree *models.Worktree, repo *models.Re
 	return nil
 }
 
-// RestoreState restores worktree state from persistent storage
+// RestoreState reconciles the worktrees in state with git, restores them from persistent
+// storage, then prunes and flags the worktrees that don't match the filesystem
 func (s *GitService) RestoreState() error {
-	return s.stateManager.RestoreState()
+	if _, err := s.reconcileWorktrees("", 0); err != nil {
+		logger.Warnf("⚠️ Failed to reconcile worktrees with git: %v", err)
+	}
+	if err := s.stateManager.RestoreState(); err != nil {
+		return err
+	}
+	s.stateManager.ValidateState()
+	return nil
 }
 
 // CreateGitHubRepositoryAndSetOrigin creates a GitHub repository and sets it as origin for a local repo
@@ -3183,53 +4057,71 @@ func (s *GitService) CreateGitHubRepositoryAndSetOrigin(repoID, name, descriptio
 		// Don't fail the entire operation if push fails - the repo is created and origin is set
 	} else {
 		logger.Infof("✅ Successfully pushed %s branch to GitHub", repo.DefaultBranch)
+		s.mirrorManager.EnqueueBranch(repoID, repo.DefaultBranch)
 	}
 
 	return repoURL, nil
 }
 
-// DeleteRepository removes a repository and all its worktrees from disk and state management
-func (s *GitService) DeleteRepository(repoID string) error {
-	logger.Infof("🗑️  Delete repository request: %s", repoID)
+// SetRepositoryMirrors replaces the secondary remotes that pushes to origin are mirrored to
+func (s *GitService) SetRepositoryMirrors(repoID string, mirrors []models.RepositoryMirror) ([]*models.RepositoryMirror, error) {
+	return s.mirrorManager.SetMirrors(repoID, mirrors)
+}
 
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// SyncMirrors forces a push of all branches and tags to every mirror of the repository
+func (s *GitService) SyncMirrors(repoID string) error {
+	return s.mirrorManager.SyncMirrors(repoID)
+}
+
+// DeleteRepository removes a repository from disk and state management. It refuses with a
+// RepositoryInUseError while the repository has worktrees, unless force is set, in which case
+// they are deleted first. Local repositories are mounted rather than cloned, so only their
+// entry is dropped.
+func (s *GitService) DeleteRepository(repoID string, force bool) error {
+	logger.Infof("🗑️  Delete repository request: %s", repoID)
 
-	// Get the repository
 	repo, exists := s.stateManager.GetRepository(repoID)
 	if !exists {
 		return fmt.Errorf("repository not found: %s", repoID)
 	}
 
-	// Get all worktrees for this repository
-	allWorktrees := s.stateManager.GetAllWorktrees()
 	var repoWorktrees []*models.Worktree
-	for _, worktree := range allWorktrees {
-		if worktree.RepoID == repoID {
+	for _, worktree := range s.stateManager.GetAllWorktrees() {
+		if worktree.RepoID == repo.ID {
 			repoWorktrees = append(repoWorktrees, worktree)
 		}
 	}
+	if len(repoWorktrees) > 0 && !force {
+		inUse := &RepositoryInUseError{RepoID: repo.ID}
+		for _, worktree := range repoWorktrees {
+			inUse.Worktrees = append(inUse.Worktrees, BlockingWorktree{ID: worktree.ID, Name: worktree.Name, Branch: worktree.Branch})
+		}
+		return inUse
+	}
 
-	// Delete all worktrees first
+	// Delete the worktrees first, waiting for their cleanup since it runs against the repository
 	for _, worktree := range repoWorktrees {
 		logger.Infof("🗑️  Deleting worktree %s (%s)", worktree.Name, worktree.ID)
-
-		// Remove worktree directory from disk
-		if _, err := os.Stat(worktree.Path); err == nil {
-			if err := os.RemoveAll(worktree.Path); err != nil {
-				logger.Warnf("⚠️  Failed to remove worktree directory %s: %v", worktree.Path, err)
-				// Continue with deletion even if directory removal fails
-			}
+		done, err := s.DeleteWorktree(worktree.ID)
+		if err != nil {
+			return fmt.Errorf("failed to delete worktree %s: %v", worktree.Name, err)
 		}
-
-		// Remove from state management
-		if err := s.stateManager.DeleteWorktree(worktree.ID); err != nil {
-			logger.Warnf("⚠️  Failed to remove worktree from state: %v", err)
+		if err := <-done; err != nil {
+			logger.Warnf("⚠️  Cleanup of worktree %s failed: %v", worktree.Name, err)
 		}
 	}
 
-	// Remove repository directory from disk
-	if _, err := os.Stat(repo.Path); err == nil {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Stop fetching history into the repository about to be removed
+	if s.unshallows.cancel(repo.ID) {
+		logger.Infof("⏹️  Cancelled background unshallow of %s", repo.ID)
+	}
+
+	if s.isLocalRepo(repo.ID) {
+		logger.Infof("📁 Keeping %s, local repositories are only forgotten", repo.Path)
+	} else if _, err := os.Stat(repo.Path); err == nil {
 		if err := os.RemoveAll(repo.Path); err != nil {
 			logger.Warnf("⚠️  Failed to remove repository directory %s: %v", repo.Path, err)
 			// Don't fail the entire operation if directory removal fails
@@ -3239,16 +4131,11 @@ func (s *GitService) DeleteRepository(repoID string) error {
 	}
 
 	// Remove from state management
-	if err := s.stateManager.DeleteRepository(repoID); err != nil {
+	if err := s.stateManager.DeleteRepository(repo.ID); err != nil {
 		return fmt.Errorf("failed to remove repository from state: %v", err)
 	}
 
-	// Clear any cached status for all worktrees
-	for _, worktree := range repoWorktrees {
-		s.worktreeCache.RemoveWorktree(worktree.ID, worktree.Path)
-	}
-
-	logger.Infof("✅ Successfully deleted repository %s and %d worktrees", repoID, len(repoWorktrees))
+	logger.Infof("✅ Successfully deleted repository %s and %d worktrees", repo.ID, len(repoWorktrees))
 	return nil
 }
 
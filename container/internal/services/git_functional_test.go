@@ -53,7 +53,7 @@ func testRepositoryOperations(t *testing.T, service *GitService) {
 
 	t.Run("CheckoutRepository_InvalidInput", func(t *testing.T) {
 		// Test invalid repository URL with proper signature
-		repo, worktree, err := service.CheckoutRepository("invalid", "url", "test-branch")
+		repo, worktree, err := service.CheckoutRepository("invalid", "url", "test-branch", DefaultCloneOptions())
 		assert.Error(t, err)
 		assert.Nil(t, repo)
 		assert.Nil(t, worktree)
@@ -330,20 +330,20 @@ func TestGitServiceGitHubOperationsFunctional(t *testing.T) {
 
 	t.Run("CreatePullRequest_ValidatesWorktree", func(t *testing.T) {
 		// Test with non-existent worktree
-		pr, err := service.CreatePullRequest("non-existent", "Test PR", "Test body", false)
+		pr, err := service.CreatePullRequest("non-existent", "Test PR", "Test body", PullRequestOptions{})
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "worktree non-existent not found")
 		assert.Nil(t, pr)
 
 		// Test with valid worktree (will fail at git operations, but validates worktree exists)
-		pr, err = service.CreatePullRequest("gh-test-worktree", "Test PR", "Test body", false)
+		pr, err = service.CreatePullRequest("gh-test-worktree", "Test PR", "Test body", PullRequestOptions{})
 		assert.Error(t, err) // Expected - no real git repo
 		assert.Nil(t, pr)
 	})
 
 	t.Run("UpdatePullRequest_ValidatesWorktree", func(t *testing.T) {
 		// Test with non-existent worktree
-		pr, err := service.UpdatePullRequest("non-existent", "Updated PR", "Updated body", false)
+		pr, err := service.UpdatePullRequest("non-existent", "Updated PR", "Updated body", PullRequestOptions{})
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "worktree non-existent not found")
 		assert.Nil(t, pr)
@@ -408,18 +408,18 @@ func TestGitServiceConflictOperationsFunctional(t *testing.T) {
 
 	t.Run("SyncWorktree_ValidatesWorktree", func(t *testing.T) {
 		// Test with non-existent worktree
-		err := service.SyncWorktree("non-existent", "merge")
+		_, err := service.SyncWorktree("non-existent", SyncOptions{Strategy: "merge"})
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "worktree non-existent not found")
 
 		// Test with invalid strategy
-		err = service.SyncWorktree("conflict-worktree", "invalid-strategy")
+		_, err = service.SyncWorktree("conflict-worktree", SyncOptions{Strategy: "invalid-strategy"})
 		assert.Error(t, err) // Should validate strategy
 	})
 
 	t.Run("MergeWorktreeToMain_ValidatesWorktree", func(t *testing.T) {
 		// Test with non-existent worktree
-		err := service.MergeWorktreeToMain("non-existent", false)
+		err := service.MergeWorktreeToMain("non-existent", MergeOptions{})
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "worktree non-existent not found")
 	})
@@ -441,7 +441,7 @@ func TestGitServiceCleanupOperationsFunctional(t *testing.T) {
 
 	t.Run("CleanupMergedWorktrees", func(t *testing.T) {
 		// Should not error even with no worktrees
-		_, _, err := service.CleanupMergedWorktrees()
+		_, _, err := service.CleanupMergedWorktrees(nil)
 		assert.NoError(t, err)
 
 		// Add some test worktrees
@@ -462,13 +462,13 @@ func TestGitServiceCleanupOperationsFunctional(t *testing.T) {
 		_ = service.stateManager.AddWorktree(worktree2)
 
 		// Should not error with worktrees (though cleanup may not work without real git repos)
-		_, _, err = service.CleanupMergedWorktrees()
+		_, _, err = service.CleanupMergedWorktrees(nil)
 		assert.NoError(t, err)
 	})
 
-	t.Run("CleanupUnusedBranches", func(t *testing.T) {
+	t.Run("CleanupAllCatnipRefs", func(t *testing.T) {
 		// Should not error
-		service.cleanupUnusedBranches()
+		service.CleanupAllCatnipRefs(false)
 	})
 
 	t.Run("Stop", func(t *testing.T) {
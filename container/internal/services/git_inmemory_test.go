@@ -405,7 +405,7 @@ func testLocalRepositoryOperations(t *testing.T, service *GitService, exec *exec
 
 	t.Run("HandleLocalRepoWorktree", func(t *testing.T) {
 		// Test creating a worktree for the local repository
-		repo, worktree, err := service.handleLocalRepoWorktree(repoID, "main")
+		repo, worktree, err := service.handleLocalRepoWorktree(repoID, "main", nil)
 		assert.NoError(t, err)
 		assert.NotNil(t, repo)
 		assert.NotNil(t, worktree)
@@ -451,7 +451,7 @@ func testLocalRepositoryOperations(t *testing.T, service *GitService, exec *exec
 
 		// Generate a unique name for this test
 		uniqueName := service.generateUniqueSessionName(repo.Path)
-		worktree, err := service.createLocalRepoWorktree(repo, "main", uniqueName)
+		worktree, err := service.createLocalRepoWorktree(repo, "main", uniqueName, nil)
 
 		assert.NoError(t, err)
 		assert.NotNil(t, worktree)
@@ -515,7 +515,7 @@ func testLocalRepositoryWorktreeLifecycle(t *testing.T, service *GitService, exe
 		require.True(t, exists)
 
 		// Create a worktree
-		repoObj, worktree, err := service.handleLocalRepoWorktree(repoID, "main")
+		repoObj, worktree, err := service.handleLocalRepoWorktree(repoID, "main", nil)
 		require.NoError(t, err)
 		require.NotNil(t, repoObj)
 		require.NotNil(t, worktree)
@@ -570,10 +570,10 @@ func testLocalRepositoryWorktreeLifecycle(t *testing.T, service *GitService, exe
 		}
 
 		// Create two worktrees
-		_, worktree1, err := service.handleLocalRepoWorktree(repoID, "main")
+		_, worktree1, err := service.handleLocalRepoWorktree(repoID, "main", nil)
 		require.NoError(t, err)
 
-		_, worktree2, err := service.handleLocalRepoWorktree(repoID, "feature/test")
+		_, worktree2, err := service.handleLocalRepoWorktree(repoID, "feature/test", nil)
 		require.NoError(t, err)
 
 		// Verify both worktrees exist
@@ -2,11 +2,16 @@ package services
 
 import (
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/git"
+	"github.com/vanpelt/catnip/internal/models"
 )
 
 // setupTestWorkspace creates an isolated workspace for tests and returns a cleanup function
@@ -99,7 +104,7 @@ func TestGitServiceGitHubOperations(t *testing.T) {
 	})
 
 	t.Run("CreatePullRequest", func(t *testing.T) {
-		pr, err := service.CreatePullRequest("worktree-id", "title", "body", false)
+		pr, err := service.CreatePullRequest("worktree-id", "title", "body", PullRequestOptions{})
 		// Should error for non-existent worktree
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "worktree worktree-id not found")
@@ -107,7 +112,7 @@ func TestGitServiceGitHubOperations(t *testing.T) {
 	})
 
 	t.Run("UpdatePullRequest", func(t *testing.T) {
-		pr, err := service.UpdatePullRequest("worktree-id", "title", "body", false)
+		pr, err := service.UpdatePullRequest("worktree-id", "title", "body", PullRequestOptions{})
 		// Should error for non-existent worktree
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "worktree worktree-id not found")
@@ -148,14 +153,14 @@ func TestGitServiceConflictOperations(t *testing.T) {
 	})
 
 	t.Run("SyncWorktree", func(t *testing.T) {
-		err := service.SyncWorktree("worktree-id", "rebase")
+		_, err := service.SyncWorktree("worktree-id", SyncOptions{Strategy: "rebase"})
 		// Should error for non-existent worktree
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "worktree worktree-id not found")
 	})
 
 	t.Run("MergeWorktreeToMain", func(t *testing.T) {
-		err := service.MergeWorktreeToMain("worktree-id", true)
+		err := service.MergeWorktreeToMain("worktree-id", MergeOptions{Squash: true})
 		// Should error for non-existent worktree
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "worktree worktree-id not found")
@@ -260,7 +265,7 @@ func TestGitServiceCleanupOperations(t *testing.T) {
 
 	t.Run("CleanupMergedWorktrees", func(t *testing.T) {
 		// Should not error even with no worktrees
-		_, _, err := service.CleanupMergedWorktrees()
+		_, _, err := service.CleanupMergedWorktrees(nil)
 		assert.NoError(t, err)
 	})
 
@@ -276,7 +281,7 @@ type mockSetupExecutor struct {
 	executed      bool
 }
 
-func (m *mockSetupExecutor) ExecuteSetupScript(worktreePath string) {
+func (m *mockSetupExecutor) ExecuteSetupScript(worktreePath, defaultScript string, env []string) {
 	m.executedPaths = append(m.executedPaths, worktreePath)
 	m.executed = true
 }
@@ -301,10 +306,228 @@ func TestGitServiceSetupExecutor(t *testing.T) {
 	t.Run("SetupExecutorInterface", func(t *testing.T) {
 		// Test that our mock implements the interface correctly
 		var executor SetupExecutor = &mockSetupExecutor{}
-		executor.ExecuteSetupScript("/test/path")
+		executor.ExecuteSetupScript("/test/path", "", nil)
 
 		mock := executor.(*mockSetupExecutor)
 		assert.True(t, mock.executed)
 		assert.Contains(t, mock.executedPaths, "/test/path")
 	})
 }
+
+// runTestGit runs a git command in dir and fails the test on error
+func runTestGit(t testing.TB, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	require.NoError(t, err, "git %v failed: %s", args, string(output))
+	return strings.TrimSpace(string(output))
+}
+
+// initUnbornTestRepo creates a repository on main at dir with a test identity and no commits yet,
+// and returns dir
+func initUnbornTestRepo(t testing.TB, dir string) string {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	runTestGit(t, dir, "init", "-q", "-b", "main")
+	runTestGit(t, dir, "config", "user.email", "test@example.com")
+	runTestGit(t, dir, "config", "user.name", "Test")
+	runTestGit(t, dir, "config", "commit.gpgsign", "false")
+	return dir
+}
+
+// initTestRepo creates a repository like initUnbornTestRepo with one "Initial commit" holding
+// files (path -> content), empty when there are none, and returns dir
+func initTestRepo(t testing.TB, dir string, files map[string]string) string {
+	t.Helper()
+	initUnbornTestRepo(t, dir)
+	if len(files) == 0 {
+		runTestGit(t, dir, "commit", "-q", "--allow-empty", "-m", "Initial commit")
+		return dir
+	}
+	for name, content := range files {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+	runTestGit(t, dir, "add", "-A")
+	runTestGit(t, dir, "commit", "-q", "-m", "Initial commit")
+	return dir
+}
+
+// newTestGitService returns a GitService running real git over a state manager persisting under
+// root, which is stopped when the test ends
+func newTestGitService(t testing.TB, root string) *GitService {
+	t.Helper()
+	ops := git.NewOperations()
+	stateManager := NewWorktreeStateManager(filepath.Join(root, "state"), nil)
+	t.Cleanup(stateManager.Stop)
+	return &GitService{operations: ops, stateManager: stateManager, gitWorktreeManager: git.NewWorktreeManager(ops)}
+}
+
+func TestGitServicePreviewPreservesModesAndSymlinks(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	cleanup := setupTestWorkspace(t)
+	defer cleanup()
+
+	service := createTestGitService(t)
+	require.NotNil(t, service)
+
+	// Main "local" repository with fileMode disabled, as inherited from some host filesystems
+	repoPath := initTestRepo(t, filepath.Join(t.TempDir(), "modes-repo"), map[string]string{"script.sh": "#!/bin/sh\necho hi\n"})
+	runTestGit(t, repoPath, "config", "core.fileMode", "false")
+
+	worktreePath := filepath.Join(t.TempDir(), "modes-worktree")
+	runTestGit(t, repoPath, "worktree", "add", "-b", "refs/catnip/modes", worktreePath)
+
+	repo := &models.Repository{
+		ID:            "local/modes-repo",
+		Path:          repoPath,
+		DefaultBranch: "main",
+		Available:     true,
+		CreatedAt:     time.Now(),
+	}
+	require.NoError(t, service.stateManager.AddRepository(repo))
+	worktree := &models.Worktree{
+		ID:           "modes-worktree-id",
+		RepoID:       repo.ID,
+		Name:         "modes-repo/modes",
+		Path:         worktreePath,
+		Branch:       "refs/catnip/modes",
+		SourceBranch: "main",
+		CreatedAt:    time.Now(),
+	}
+	require.NoError(t, service.stateManager.AddWorktree(worktree))
+
+	// Uncommitted exec-bit change and a brand new symlink
+	require.NoError(t, os.Chmod(filepath.Join(worktreePath, "script.sh"), 0755))
+	require.NoError(t, os.Symlink("script.sh", filepath.Join(worktreePath, "run.sh")))
+
+	require.NoError(t, service.CreateWorktreePreview(worktree.ID, false))
+
+	t.Run("PreviewBranchContent", func(t *testing.T) {
+		scriptEntry := runTestGit(t, repoPath, "ls-tree", "catnip/modes", "script.sh")
+		assert.True(t, strings.HasPrefix(scriptEntry, "100755 "), "expected exec bit on preview branch, got %q", scriptEntry)
+
+		linkEntry := runTestGit(t, repoPath, "ls-tree", "catnip/modes", "run.sh")
+		assert.True(t, strings.HasPrefix(linkEntry, "120000 "), "expected symlink on preview branch, got %q", linkEntry)
+	})
+
+	t.Run("RepositoryConfigUntouched", func(t *testing.T) {
+		assert.Equal(t, "false", runTestGit(t, repoPath, "config", "core.fileMode"))
+	})
+
+	t.Run("WorktreeRestored", func(t *testing.T) {
+		// Temporary commit must be gone again
+		assert.Equal(t, "Initial commit", runTestGit(t, worktreePath, "log", "-1", "--pretty=format:%s"))
+
+		info, err := os.Stat(filepath.Join(worktreePath, "script.sh"))
+		require.NoError(t, err)
+		assert.NotZero(t, info.Mode().Perm()&0100, "exec bit should survive the reset")
+
+		target, err := os.Readlink(filepath.Join(worktreePath, "run.sh"))
+		require.NoError(t, err)
+		assert.Equal(t, "script.sh", target)
+
+		// Both changes should still show up as uncommitted work once modes are compared
+		status := runTestGit(t, worktreePath, "-c", "core.fileMode=true", "status", "--porcelain")
+		assert.Contains(t, status, "script.sh")
+		assert.Contains(t, status, "run.sh")
+	})
+}
+
+func TestCleanupActiveSessionsRespectsWorkspaceDir(t *testing.T) {
+	workspaceDir := filepath.Join(t.TempDir(), "custom-workspace")
+	t.Setenv("CATNIP_WORKSPACE_DIR", workspaceDir)
+
+	worktreePath := filepath.Join(workspaceDir, "catnip", "felix", "nested")
+	sessionDir := filepath.Join(workspaceDir, "catnip", "felix")
+	require.NoError(t, os.MkdirAll(worktreePath, 0755))
+
+	rel, ok := workspaceRelativePath(worktreePath)
+	assert.True(t, ok)
+	assert.Equal(t, "catnip/felix/nested", rel)
+	_, ok = workspaceRelativePath("/workspace/catnip/felix")
+	assert.False(t, ok)
+
+	s := &GitService{}
+	s.cleanupActiveSessions(worktreePath)
+	assert.NoDirExists(t, sessionDir, "session directory derived from the custom workspace is removed")
+}
+
+func TestGitServiceFastForwardSync(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	root := t.TempDir()
+	repoPath := initTestRepo(t, filepath.Join(root, "repo"), nil)
+	worktreePath := filepath.Join(root, "felix")
+	runTestGit(t, repoPath, "worktree", "add", "-b", "feature", worktreePath)
+	runTestGit(t, repoPath, "commit", "--allow-empty", "-m", "Upstream 1")
+	runTestGit(t, repoPath, "commit", "--allow-empty", "-m", "Upstream 2")
+
+	s := &GitService{operations: git.NewOperations()}
+	worktree := &models.Worktree{Name: "felix", Path: worktreePath, Branch: "feature", SourceBranch: "main"}
+	counts := func() (int, int) {
+		ahead, err := s.operations.GetCommitCount(worktreePath, "main", "HEAD")
+		require.NoError(t, err)
+		behind, err := s.operations.GetCommitCount(worktreePath, "HEAD", "main")
+		require.NoError(t, err)
+		return ahead, behind
+	}
+
+	require.NoError(t, s.applySyncStrategy(worktree, "ff-only", "main"))
+	ahead, behind := counts()
+	assert.Zero(t, ahead)
+	assert.Zero(t, behind)
+	assert.Equal(t, runTestGit(t, repoPath, "rev-parse", "main"), runTestGit(t, worktreePath, "rev-parse", "HEAD"))
+
+	// Local commits make a fast-forward impossible
+	runTestGit(t, worktreePath, "commit", "--allow-empty", "-m", "Local work")
+	runTestGit(t, repoPath, "commit", "--allow-empty", "-m", "Upstream 3")
+	head := runTestGit(t, worktreePath, "rev-parse", "HEAD")
+
+	err := s.applySyncStrategy(worktree, "ff-only", "main")
+	require.ErrorIs(t, err, models.ErrNonFastForward)
+	var nonFastForward *models.NonFastForwardError
+	require.ErrorAs(t, err, &nonFastForward)
+	assert.Equal(t, 1, nonFastForward.Ahead)
+	assert.Equal(t, 1, nonFastForward.Behind)
+	assert.Equal(t, head, runTestGit(t, worktreePath, "rev-parse", "HEAD"), "refused sync leaves HEAD alone")
+}
+
+func TestGitServiceDeleteRepository(t *testing.T) {
+	s, stateManager, repoPath, worktreePath := newRecreateTestService(t)
+	s.worktreeCache = NewWorktreeStatusCache(s.operations, stateManager)
+	t.Cleanup(s.worktreeCache.Stop)
+
+	err := s.DeleteRepository("local/repo", false)
+	require.ErrorIs(t, err, ErrRepositoryInUse)
+	var inUse *RepositoryInUseError
+	require.ErrorAs(t, err, &inUse)
+	assert.Equal(t, []BlockingWorktree{{ID: "wt-felix", Name: "repo/felix", Branch: "feature/felix"}}, inUse.Worktrees)
+	_, exists := stateManager.GetRepository("local/repo")
+	assert.True(t, exists, "refused deletion keeps the repository")
+
+	require.NoError(t, s.DeleteRepository("local/repo", true))
+	_, exists = stateManager.GetRepository("local/repo")
+	assert.False(t, exists)
+	_, exists = stateManager.GetWorktree("wt-felix")
+	assert.False(t, exists)
+	assert.NoDirExists(t, worktreePath)
+	assert.FileExists(t, filepath.Join(repoPath, "app.txt"), "local repositories are only forgotten")
+
+	barePath := filepath.Join(t.TempDir(), "api.git")
+	runTestGit(t, repoPath, "clone", "--bare", repoPath, barePath)
+	require.NoError(t, stateManager.AddRepository(&models.Repository{ID: "acme/old-api", Path: barePath}))
+	require.NoError(t, stateManager.RenameRepository("acme/old-api", "acme/api", func(*models.Repository) {}))
+	require.NoError(t, s.DeleteRepository("acme/api", false))
+	assert.NoDirExists(t, barePath)
+	_, exists = stateManager.GetRepository("acme/old-api")
+	assert.False(t, exists, "aliases are forgotten with the repository")
+
+	assert.ErrorContains(t, s.DeleteRepository("acme/api", false), "repository not found")
+}
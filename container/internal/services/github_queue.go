@@ -0,0 +1,232 @@
+package services
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vanpelt/catnip/internal/git"
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+	"github.com/vanpelt/catnip/internal/recovery"
+)
+
+// User GitHub operations that are queued while the rate limit is low
+const (
+	GitHubOperationCreatePR = "create_pr"
+	GitHubOperationUpdatePR = "update_pr"
+)
+
+// Statuses of queued GitHub operations
+const (
+	GitHubOperationQueued  = "queued"
+	GitHubOperationRunning = "running"
+	GitHubOperationDone    = "done"
+	GitHubOperationFailed  = "failed"
+)
+
+// finishedGitHubOperationTTL is how long queued operations stay listed after they ran
+const finishedGitHubOperationTTL = time.Hour
+
+// githubOperationQueue holds user GitHub operations until the rate limit they spend resets
+type githubOperationQueue struct {
+	mu  sync.Mutex
+	ops []*queuedGitHubOperation
+}
+
+// queuedGitHubOperation is a queued operation and the timer running it
+type queuedGitHubOperation struct {
+	op    models.QueuedGitHubOperation
+	timer *time.Timer
+}
+
+// add queues op, calling fire at op.RunAt, and replaces an operation of the same kind for the
+// same worktree that hasn't run yet
+func (q *githubOperationQueue) add(op models.QueuedGitHubOperation, fire func()) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.removeQueuedLocked(op.Kind, op.WorktreeID)
+	q.ops = append(q.ops, &queuedGitHubOperation{op: op, timer: time.AfterFunc(time.Until(op.RunAt), fire)})
+}
+
+// cancel drops an operation of kind for a worktree that hasn't run yet
+func (q *githubOperationQueue) cancel(kind, worktreeID string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.removeQueuedLocked(kind, worktreeID)
+}
+
+// removeQueuedLocked drops operations of kind for a worktree that haven't run yet, and those that
+// finished longer than finishedGitHubOperationTTL ago. Callers hold q.mu.
+func (q *githubOperationQueue) removeQueuedLocked(kind, worktreeID string) {
+	kept := q.ops[:0]
+	for _, queued := range q.ops {
+		finished := queued.op.Status == GitHubOperationDone || queued.op.Status == GitHubOperationFailed
+		switch {
+		case queued.op.Status == GitHubOperationQueued && queued.op.Kind == kind && queued.op.WorktreeID == worktreeID:
+			queued.timer.Stop()
+		case finished && time.Since(queued.op.RunAt) > finishedGitHubOperationTTL:
+		default:
+			kept = append(kept, queued)
+		}
+	}
+	q.ops = kept
+}
+
+// find returns the operation with id. Callers hold q.mu.
+func (q *githubOperationQueue) find(id string) *queuedGitHubOperation {
+	for _, queued := range q.ops {
+		if queued.op.ID == id {
+			return queued
+		}
+	}
+	return nil
+}
+
+// postpone moves a queued operation to runAt, returning false when it was replaced or canceled
+func (q *githubOperationQueue) postpone(id string, runAt time.Time) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	queued := q.find(id)
+	if queued == nil || queued.op.Status != GitHubOperationQueued {
+		return false
+	}
+	queued.op.RunAt = runAt
+	queued.timer.Reset(time.Until(runAt))
+	return true
+}
+
+// start marks a queued operation running, returning false when it was replaced or canceled
+func (q *githubOperationQueue) start(id string) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	queued := q.find(id)
+	if queued == nil || queued.op.Status != GitHubOperationQueued {
+		return false
+	}
+	queued.op.Status = GitHubOperationRunning
+	queued.op.RunAt = time.Now()
+	return true
+}
+
+// finish records the outcome of a running operation
+func (q *githubOperationQueue) finish(id string, pr *models.PullRequestResponse, err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	queued := q.find(id)
+	if queued == nil {
+		return
+	}
+	queued.op.Status = GitHubOperationDone
+	if err != nil {
+		queued.op.Status, queued.op.Error = GitHubOperationFailed, err.Error()
+	} else if pr != nil {
+		queued.op.URL = pr.URL
+	}
+}
+
+// list returns the queued, running and recently finished operations, oldest first
+func (q *githubOperationQueue) list() []models.QueuedGitHubOperation {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.removeQueuedLocked("", "")
+	ops := make([]models.QueuedGitHubOperation, len(q.ops))
+	for i, queued := range q.ops {
+		ops[i] = queued.op
+	}
+	return ops
+}
+
+// runOrQueueGitHubOperation runs a user operation spending resource right away, or queues it
+// until the rate limit resets while the limit is reserved for urgent operations or exhausted.
+// It returns the result of running it or the queued operation.
+func (s *GitService) runOrQueueGitHubOperation(kind, worktreeID, resource string, urgent bool, run func() (*models.PullRequestResponse, error)) (*models.PullRequestResponse, *models.QueuedGitHubOperation, error) {
+	runAt := s.rateLimits.ReadyAt(resource, urgent)
+	if !runAt.After(time.Now()) {
+		// Running it now supersedes a queued one
+		s.githubQueue.cancel(kind, worktreeID)
+		pr, err := run()
+		return pr, nil, err
+	}
+
+	op := models.QueuedGitHubOperation{
+		ID:         uuid.New().String(),
+		Kind:       kind,
+		WorktreeID: worktreeID,
+		QueuedAt:   time.Now(),
+		RunAt:      runAt,
+		Status:     GitHubOperationQueued,
+	}
+	s.githubQueue.add(op, func() {
+		recovery.SafeGo("github-queue:"+op.ID, func() {
+			s.runQueuedGitHubOperation(op, resource, urgent, run)
+		})
+	})
+	logger.Infof("🚦 Queued %s for worktree %s until %s, the GitHub %s rate limit is low",
+		kind, worktreeID, runAt.Format(time.Kitchen), resource)
+	return nil, &op, nil
+}
+
+// runQueuedGitHubOperation runs a queued operation once the rate limit allows it
+func (s *GitService) runQueuedGitHubOperation(op models.QueuedGitHubOperation, resource string, urgent bool, run func() (*models.PullRequestResponse, error)) {
+	select {
+	case <-s.stopCh:
+		return
+	default:
+	}
+	if runAt := s.rateLimits.ReadyAt(resource, urgent); runAt.After(time.Now()) {
+		s.githubQueue.postpone(op.ID, runAt)
+		return
+	}
+	if !s.githubQueue.start(op.ID) {
+		return
+	}
+	pr, err := run()
+	if err != nil {
+		logger.Warnf("⚠️ Queued %s for worktree %s failed: %v", op.Kind, op.WorktreeID, err)
+	} else {
+		logger.Infof("✅ Ran queued %s for worktree %s", op.Kind, op.WorktreeID)
+	}
+	s.githubQueue.finish(op.ID, pr, err)
+}
+
+// CreatePullRequestOrQueue creates a pull request like CreatePullRequest, or queues it until the
+// GitHub rate limit resets while it is low. Urgent requests spend the budget kept in reserve.
+func (s *GitService) CreatePullRequestOrQueue(worktreeID, title, body string, options PullRequestOptions) (*models.PullRequestResponse, *models.QueuedGitHubOperation, error) {
+	if _, exists := s.stateManager.GetWorktree(worktreeID); !exists {
+		return nil, nil, fmt.Errorf("worktree %s not found", worktreeID)
+	}
+	return s.runOrQueueGitHubOperation(GitHubOperationCreatePR, worktreeID, git.RateLimitGraphQL, options.Urgent, func() (*models.PullRequestResponse, error) {
+		return s.CreatePullRequest(worktreeID, title, body, options)
+	})
+}
+
+// UpdatePullRequestOrQueue updates a pull request like UpdatePullRequest, or queues the update
+// until the GitHub rate limit resets while it is low. Urgent requests spend the budget kept in
+// reserve.
+func (s *GitService) UpdatePullRequestOrQueue(worktreeID, title, body string, options PullRequestOptions) (*models.PullRequestResponse, *models.QueuedGitHubOperation, error) {
+	if _, exists := s.stateManager.GetWorktree(worktreeID); !exists {
+		return nil, nil, fmt.Errorf("worktree %s not found", worktreeID)
+	}
+	return s.runOrQueueGitHubOperation(GitHubOperationUpdatePR, worktreeID, git.RateLimitGraphQL, options.Urgent, func() (*models.PullRequestResponse, error) {
+		return s.UpdatePullRequest(worktreeID, title, body, options)
+	})
+}
+
+// GitHubRateLimitStatus returns the GitHub API budget, how much it throttles polling and the
+// operations queued until it resets, asking GitHub for the current limits first when refresh is
+// set
+func (s *GitService) GitHubRateLimitStatus(refresh bool) (*models.GitHubRateLimitStatus, error) {
+	if s.rateLimits == nil {
+		return nil, nil
+	}
+	if refresh {
+		if err := s.rateLimits.Refresh(); err != nil {
+			return nil, err
+		}
+	}
+	status := s.rateLimits.Status()
+	status.Queued = s.githubQueue.list()
+	return status, nil
+}
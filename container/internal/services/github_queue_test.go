@@ -0,0 +1,86 @@
+package services
+
+import (
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/git"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// observeGraphQLLimit feeds a tracker the rate limit headers of a GraphQL response
+func observeGraphQLLimit(tracker *git.RateLimitTracker, remaining int, reset time.Time) {
+	header := http.Header{}
+	header.Set("X-RateLimit-Limit", "5000")
+	header.Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+	header.Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+	header.Set("X-RateLimit-Resource", git.RateLimitGraphQL)
+	tracker.ObserveHeaders(header)
+}
+
+func TestRunOrQueueGitHubOperation(t *testing.T) {
+	stateManager := NewWorktreeStateManager(filepath.Join(t.TempDir(), "state"), nil)
+	t.Cleanup(stateManager.Stop)
+	s := &GitService{stateManager: stateManager, rateLimits: git.NewRateLimitTracker(), stopCh: make(chan struct{})}
+
+	var runs int32
+	run := func() (*models.PullRequestResponse, error) {
+		atomic.AddInt32(&runs, 1)
+		return &models.PullRequestResponse{URL: "https://github.com/owner/repo/pull/7"}, nil
+	}
+
+	// Plenty of budget runs right away
+	pr, queued, err := s.runOrQueueGitHubOperation(GitHubOperationCreatePR, "wt-felix", git.RateLimitGraphQL, false, run)
+	require.NoError(t, err)
+	assert.Nil(t, queued)
+	assert.Equal(t, "https://github.com/owner/repo/pull/7", pr.URL)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&runs))
+
+	// Below the reserve it waits for the reset, and a newer request replaces the queued one
+	reset := time.Now().Add(2 * time.Second)
+	observeGraphQLLimit(s.rateLimits, 100, reset)
+	_, first, err := s.runOrQueueGitHubOperation(GitHubOperationCreatePR, "wt-felix", git.RateLimitGraphQL, false, run)
+	require.NoError(t, err)
+	require.NotNil(t, first)
+	assert.Equal(t, GitHubOperationQueued, first.Status)
+	assert.WithinDuration(t, reset, first.RunAt, time.Second)
+	_, second, err := s.runOrQueueGitHubOperation(GitHubOperationCreatePR, "wt-felix", git.RateLimitGraphQL, false, run)
+	require.NoError(t, err)
+	require.NotNil(t, second)
+	status, err := s.GitHubRateLimitStatus(false)
+	require.NoError(t, err)
+	assert.Equal(t, git.ThrottlePaused, status.Throttle)
+	require.Len(t, status.Queued, 1)
+	assert.Equal(t, second.ID, status.Queued[0].ID)
+
+	// Urgent requests spend the reserve and supersede the queued one
+	_, queued, err = s.runOrQueueGitHubOperation(GitHubOperationCreatePR, "wt-felix", git.RateLimitGraphQL, true, run)
+	require.NoError(t, err)
+	assert.Nil(t, queued)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&runs))
+	status, _ = s.GitHubRateLimitStatus(false)
+	assert.Empty(t, status.Queued)
+
+	// Nothing runs on an exhausted limit, the queued operation runs once it resets
+	observeGraphQLLimit(s.rateLimits, 0, reset)
+	_, queued, err = s.runOrQueueGitHubOperation(GitHubOperationUpdatePR, "wt-felix", git.RateLimitGraphQL, true, run)
+	require.NoError(t, err)
+	require.NotNil(t, queued)
+	require.Eventually(t, func() bool {
+		status, _ := s.GitHubRateLimitStatus(false)
+		return len(status.Queued) == 1 && status.Queued[0].Status == GitHubOperationDone
+	}, 5*time.Second, 20*time.Millisecond)
+	status, _ = s.GitHubRateLimitStatus(false)
+	assert.Equal(t, "https://github.com/owner/repo/pull/7", status.Queued[0].URL)
+	assert.Equal(t, git.ThrottleNormal, status.Throttle)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&runs))
+
+	_, _, err = s.CreatePullRequestOrQueue("wt-missing", "Title", "", PullRequestOptions{})
+	assert.ErrorContains(t, err, "not found")
+}
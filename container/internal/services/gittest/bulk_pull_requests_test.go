@@ -0,0 +1,101 @@
+package gittest
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/services"
+)
+
+// TestBulkPullRequests opens pull requests for every finished worktree of a repository, then
+// runs again to check worktrees with pull requests are skipped
+func TestBulkPullRequests(t *testing.T) {
+	Run(t, Scenario{
+		Name: "bulk_pull_requests",
+		Setup: func(e *Env) {
+			livePath := e.CreateLiveRepo("demo", map[string]string{"README.md": "# demo\n"})
+			// Origins mentioning github.com count as GitHub remotes; this one is still a local bare repo
+			origin := filepath.Join(e.RemotesDir, "github.com", "catnip-test", "demo.git")
+			e.Git(e.Root, "clone", "--bare", filepath.Join(e.RemotesDir, "demo.git"), origin)
+			e.Git(livePath, "remote", "set-url", "origin", origin)
+		},
+		Steps: []Step{
+			{"worktrees", func(e *Env) {
+				e.InitialWorktree("initial", "demo")
+				e.Checkout("login", "demo", "main")
+				e.Checkout("logout", "demo", "main")
+				e.Checkpoint("login", "login.txt", "login\n", "Add login page")
+				e.Checkpoint("logout", "logout.txt", "logout\n", "Add logout button")
+				e.Checkpoint("logout", "logout.txt", "logout\nstyled\n", "Style logout button")
+			}},
+			{"bulk by repository", func(e *Env) {
+				report, err := e.Service.CreatePullRequestsBulk(
+					services.BulkPullRequestSelector{RepoID: "local/demo"},
+					services.BulkPullRequestOptions{Draft: true, TextMode: services.PullRequestTextCommits},
+				)
+				require.NoError(e.t, err)
+				require.Len(e.t, report.Results, 3)
+				assert.Equal(e.t, services.BulkPullRequestSkipped, report.Results[0].Outcome)
+				assert.Equal(e.t, "no commits ahead of main", report.Results[0].Reason)
+				assert.Equal(e.t, services.BulkPullRequestCreated, report.Results[1].Outcome)
+				assert.Equal(e.t, services.BulkPullRequestCreated, report.Results[2].Outcome)
+				assert.Equal(e.t, []string{report.Results[1].URL, report.Results[2].URL}, report.Created)
+				assert.Equal(e.t, "Style logout button", e.Labelled("logout").PullRequestTitle)
+				assert.Equal(e.t, "- Add logout button", e.Labelled("logout").PullRequestBody)
+			}},
+			{"bulk by ID skips existing pull requests", func(e *Env) {
+				report, err := e.Service.CreatePullRequestsBulk(
+					services.BulkPullRequestSelector{WorktreeIDs: []string{e.ID("login"), e.ID("logout")}},
+					services.BulkPullRequestOptions{},
+				)
+				require.NoError(e.t, err)
+				assert.Empty(e.t, report.Created)
+				for _, result := range report.Results {
+					assert.Equal(e.t, services.BulkPullRequestSkipped, result.Outcome)
+					assert.Contains(e.t, result.Reason, "pull request already exists")
+				}
+			}},
+			{"sync first skips worktrees that can't fast-forward", func(e *Env) {
+				e.Checkout("signup", "demo", "main")
+				e.Checkpoint("signup", "signup.txt", "signup\n", "Add signup form")
+				e.CommitFile(filepath.Join(e.LiveDir, "demo"), "NOTES.md", "notes\n", "Add notes")
+				head := e.Git(e.Labelled("signup").Path, "rev-parse", "HEAD")
+
+				report, err := e.Service.CreatePullRequestsBulk(
+					services.BulkPullRequestSelector{WorktreeIDs: []string{e.ID("signup")}},
+					services.BulkPullRequestOptions{SyncFirst: true},
+				)
+				require.NoError(e.t, err)
+				require.Len(e.t, report.Results, 1)
+				assert.Equal(e.t, services.BulkPullRequestSkipped, report.Results[0].Outcome)
+				assert.Contains(e.t, report.Results[0].Reason, "cannot fast-forward")
+				assert.Equal(e.t, head, e.Git(e.Labelled("signup").Path, "rev-parse", "HEAD"), "ff-only leaves the branch alone")
+
+				report, err = e.Service.CreatePullRequestsBulk(
+					services.BulkPullRequestSelector{WorktreeIDs: []string{e.ID("signup")}},
+					services.BulkPullRequestOptions{SyncFirst: true, SyncStrategy: "rebase"},
+				)
+				require.NoError(e.t, err)
+				require.Len(e.t, report.Results, 1)
+				assert.Equal(e.t, services.BulkPullRequestCreated, report.Results[0].Outcome)
+				assert.FileExists(e.t, filepath.Join(e.Labelled("signup").Path, "NOTES.md"))
+			}},
+			{"invalid selections", func(e *Env) {
+				_, err := e.Service.CreatePullRequestsBulk(services.BulkPullRequestSelector{}, services.BulkPullRequestOptions{})
+				assert.EqualError(e.t, err, "no worktrees selected")
+				_, err = e.Service.CreatePullRequestsBulk(
+					services.BulkPullRequestSelector{RepoID: "local/demo"},
+					services.BulkPullRequestOptions{TextMode: "haiku"},
+				)
+				assert.Error(e.t, err)
+				_, err = e.Service.CreatePullRequestsBulk(
+					services.BulkPullRequestSelector{RepoID: "local/demo"},
+					services.BulkPullRequestOptions{SyncStrategy: "squash"},
+				)
+				assert.ErrorContains(e.t, err, "unknown sync strategy")
+			}},
+		},
+	})
+}
@@ -0,0 +1,56 @@
+package gittest
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCheckoutBranches creates worktrees for a stack of branches of an already cloned repository
+// at once, with one branch that doesn't exist
+func TestCheckoutBranches(t *testing.T) {
+	Run(t, Scenario{
+		Name: "checkout_branches",
+		Setup: func(e *Env) {
+			origin := filepath.Join(e.RemotesDir, "stack.git")
+			work := filepath.Join(e.Root, "stack")
+			e.Git(e.Root, "init", "--bare", "-b", "main", origin)
+			e.Git(e.Root, "init", "-b", "main", work)
+			e.CommitFile(work, "README.md", "# stack\n", "Initial commit")
+			e.Git(work, "checkout", "-b", "stack-1")
+			e.CommitFile(work, "one.txt", "one\n", "First change")
+			e.Git(work, "checkout", "-b", "stack-2")
+			e.CommitFile(work, "two.txt", "two\n", "Second change")
+			e.Git(work, "remote", "add", "origin", origin)
+			e.Git(work, "push", "origin", "main", "stack-1", "stack-2")
+
+			// The bare repo is already on disk, so nothing is cloned from GitHub
+			e.Git(e.Root, "clone", "--bare", "--single-branch", origin, filepath.Join(e.StateDir, "repos", "stack.git"))
+		},
+		Steps: []Step{
+			{"checkout stack", func(e *Env) {
+				results, err := e.Service.CheckoutBranches("acme", "stack", []string{"stack-1", "stack-2", "stack-3"})
+				require.NoError(e.t, err)
+				require.Len(e.t, results, 3)
+
+				for i, label := range []string{"first", "second"} {
+					require.NoError(e.t, results[i].Err)
+					e.Label(label, results[i].Worktree)
+				}
+				assert.FileExists(e.t, filepath.Join(e.Labelled("first").Path, "one.txt"))
+				assert.NoFileExists(e.t, filepath.Join(e.Labelled("first").Path, "two.txt"))
+				assert.FileExists(e.t, filepath.Join(e.Labelled("second").Path, "two.txt"))
+				assert.Equal(e.t, "stack-3", results[2].Branch)
+				assert.Error(e.t, results[2].Err)
+				assert.Nil(e.t, results[2].Worktree)
+				assert.Len(e.t, e.Service.ListWorktrees(), 2)
+			}},
+			{"no branches", func(e *Env) {
+				_, err := e.Service.CheckoutBranches("acme", "stack", nil)
+				assert.Error(e.t, err)
+			}},
+		},
+	})
+}
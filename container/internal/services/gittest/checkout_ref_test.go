@@ -0,0 +1,56 @@
+package gittest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/services"
+)
+
+// TestCheckoutRef checks out a live repo at a tag and at a commit, then checks that diffs and
+// commit counts compare against them rather than the tip of the branch they belong to
+func TestCheckoutRef(t *testing.T) {
+	var livePath, initialHash, releaseHash string
+
+	Run(t, Scenario{
+		Name: "checkout_ref",
+		Setup: func(e *Env) {
+			livePath = e.CreateLiveRepo("demo", map[string]string{"README.md": "# demo\n"})
+			initialHash = e.Git(livePath, "rev-parse", "HEAD")
+			releaseHash = e.CommitFile(livePath, "release.txt", "1.0\n", "Release 1.0")
+			e.Git(livePath, "tag", "-a", "v1.0", "-m", "Version 1.0")
+			e.CommitFile(livePath, "next.txt", "next\n", "Start 1.1")
+		},
+		Steps: []Step{
+			{"checkout tag", func(e *Env) {
+				wt := e.Checkout("tagged", "demo", "v1.0")
+				assert.Equal(e.t, "refs/tags/v1.0", wt.SourceRef)
+				assert.Equal(e.t, "main", wt.SourceBranch)
+				assert.Equal(e.t, releaseHash, wt.CommitHash)
+				assert.Equal(e.t, 0, wt.CommitCount)
+				assert.NoFileExists(e.t, wt.Path+"/next.txt")
+			}},
+			{"checkout commit", func(e *Env) {
+				wt := e.Checkout("pinned", "demo", initialHash)
+				assert.Equal(e.t, initialHash, wt.SourceRef)
+				assert.Equal(e.t, "main", wt.SourceBranch)
+				assert.Equal(e.t, initialHash, wt.CommitHash)
+				assert.NoFileExists(e.t, wt.Path+"/release.txt")
+			}},
+			{"diff against tag", func(e *Env) {
+				e.Checkpoint("tagged", "fix.txt", "fix\n", "Fix release")
+				diff, err := e.Service.GetWorktreeDiff(e.ID("tagged"))
+				require.NoError(e.t, err)
+				require.Len(e.t, diff.FileDiffs, 1, "later commits on main are not part of the diff")
+				assert.Equal(e.t, "fix.txt", diff.FileDiffs[0].FilePath)
+			}},
+			{"unknown ref", func(e *Env) {
+				_, _, err := e.Service.CheckoutRepository("local", "demo", "v9.9", services.DefaultCloneOptions())
+				assert.ErrorContains(e.t, err, "branch v9.9 does not exist")
+				_, _, err = e.Service.CheckoutRepository("local", "demo", "0123456789abcdef0123456789abcdef01234567", services.DefaultCloneOptions())
+				assert.ErrorContains(e.t, err, "does not exist")
+			}},
+		},
+	})
+}
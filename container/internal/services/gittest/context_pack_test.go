@@ -0,0 +1,101 @@
+package gittest
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/models"
+	"github.com/vanpelt/catnip/internal/services"
+)
+
+// TestContextPack generates the context pack of a worktree with a pull request under review and
+// a failed validation, caps it to a small budget and writes it into the worktree
+func TestContextPack(t *testing.T) {
+	Run(t, Scenario{
+		Name: "context_pack",
+		Setup: func(e *Env) {
+			e.CreateLiveRepo("demo", map[string]string{"README.md": "# demo\n", "src/app.go": "package app\n"})
+			// Origins mentioning github.com count as GitHub remotes; this one is still a local bare repo
+			origin := filepath.Join(e.RemotesDir, "github.com", "catnip-test", "demo.git")
+			e.Git(e.Root, "clone", "--bare", filepath.Join(e.RemotesDir, "demo.git"), origin)
+			e.Git(filepath.Join(e.LiveDir, "demo"), "remote", "set-url", "origin", origin)
+		},
+		Steps: []Step{
+			{"session with a pull request", func(e *Env) {
+				e.Checkout("login", "demo", "main")
+				e.Checkpoint("login", "src/login.go", "package app\n\nfunc Login() {}\n", "Add login")
+				e.Checkpoint("login", "package-lock.json", "{}\n", "Lock dependencies")
+				_, err := e.Service.CreatePullRequest(e.ID("login"), "Add login", "", services.PullRequestOptions{SkipValidation: true})
+				require.NoError(e.t, err)
+				e.GitHub.AddReviewThread(1, models.ReviewThread{Path: "src/login.go", Line: 3, Comments: []models.ReviewComment{
+					{Author: "octocat", Body: "Login should take credentials"},
+				}})
+
+				at := time.Date(2024, 1, 15, 14, 0, 0, 0, time.UTC)
+				require.NoError(e.t, e.Service.UpdateWorktreeFields(e.ID("login"), map[string]interface{}{
+					"latest_user_prompt":    "Add a login page",
+					"session_title_history": []models.TitleEntry{{Title: "Adding login", Timestamp: at}},
+					"todos": []models.Todo{
+						{ID: "1", Content: "Write Login", Status: "completed"},
+						{ID: "2", Content: "Handle credentials", Status: "pending"},
+					},
+					"validation": &models.ValidationResult{Status: models.ValidationFailed, Commands: []models.ValidationCommandResult{
+						{Command: "go vet ./...", ExitCode: 0, Output: "ok\n"},
+						{Command: "go test ./...", ExitCode: 1, Output: strings.Repeat("=== RUN TestLogin\n", 300) + "--- FAIL: TestLogin\n"},
+					}},
+				}))
+			}},
+			{"generate", func(e *Env) {
+				pack, err := e.Service.GenerateContextPack(e.ID("login"), services.ContextPackOptions{MaxTokens: 4000, Markdown: true})
+				require.NoError(e.t, err)
+				assert.LessOrEqual(e.t, pack.EstimatedTokens, 4000)
+				assert.Equal(e.t, 4, pack.Layout.Files)
+				assert.Equal(e.t, "Add a login page", pack.LatestPrompt)
+				assert.Len(e.t, pack.Todos, 2)
+				assert.Len(e.t, pack.Titles, 1)
+
+				require.NotNil(e.t, pack.FailedValidation)
+				commands := pack.FailedValidation.Commands
+				assert.Empty(e.t, commands[0].Output, "output of passing commands is left out")
+				assert.True(e.t, strings.HasSuffix(commands[1].Output, "--- FAIL: TestLogin\n"), "the end of failing output is kept")
+				assert.Contains(e.t, commands[1].Output, "lines truncated]")
+				assert.Contains(e.t, pack.Truncated, "validation")
+
+				require.NotNil(e.t, pack.PullRequest)
+				require.Len(e.t, pack.PullRequest.ReviewThreads, 1)
+				assert.Equal(e.t, "octocat", pack.PullRequest.ReviewThreads[0].Comments[0].Author)
+
+				files := map[string]models.ContextPackFile{}
+				for _, file := range pack.Diff.Files {
+					files[file.Path] = file
+				}
+				assert.Contains(e.t, files["src/login.go"].Diff, "+func Login() {}")
+				assert.Equal(e.t, "generated", files["package-lock.json"].Omitted)
+				assert.Empty(e.t, files["package-lock.json"].Diff)
+
+				for _, section := range []string{"## Layout", "- [x] Write Login", "`go test ./...` exited 1", "**octocat**: Login should take credentials", "```diff"} {
+					assert.Contains(e.t, pack.Markdown, section)
+				}
+				assert.Empty(e.t, pack.WrittenTo)
+
+				_, err = e.Service.GenerateContextPack(e.ID("login"), services.ContextPackOptions{MaxTokens: 100})
+				assert.ErrorContains(e.t, err, "invalid token budget")
+			}},
+			{"write into the worktree", func(e *Env) {
+				pack, err := e.Service.GenerateContextPack(e.ID("login"), services.ContextPackOptions{MaxTokens: 4000, Write: true})
+				require.NoError(e.t, err)
+				path := filepath.Join(e.Labelled("login").Path, ".catnip", "context.md")
+				assert.Equal(e.t, path, pack.WrittenTo)
+				written, err := os.ReadFile(path)
+				require.NoError(e.t, err)
+				assert.Equal(e.t, pack.Markdown, string(written))
+				assert.Empty(e.t, e.Git(e.Labelled("login").Path, "status", "--porcelain"), "the pack is never committed")
+			}},
+		},
+	})
+}
@@ -0,0 +1,248 @@
+// Package gittest drives a real GitService against throwaway git repositories so
+// worktree lifecycle features can be covered by scripted scenarios and golden
+// snapshots instead of bespoke tests.
+//
+// Everything runs hermetically: repositories live in a temp dir, the fake "live"
+// repository's origin is a local bare repo, git config is isolated from the host
+// and GitHub access goes through StubGitHub. Because the harness mutates the global
+// runtime configuration, scenarios must not run in parallel.
+//
+// To cover a new feature, add a Scenario next to lifecycle_test.go and record its
+// golden file with:
+//
+//	CATNIP_UPDATE_SNAPSHOTS=1 go test ./internal/services/gittest/
+package gittest
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/vanpelt/catnip/internal/config"
+	"github.com/vanpelt/catnip/internal/git"
+	"github.com/vanpelt/catnip/internal/models"
+	"github.com/vanpelt/catnip/internal/services"
+)
+
+// fixedGitDate keeps commit hashes stable between runs
+const fixedGitDate = "2024-01-01T00:00:00Z"
+
+// Env is an isolated catnip installation: live dir, workspace, state dir and a GitService
+type Env struct {
+	t testing.TB
+
+	Root         string // Temp directory holding everything below
+	LiveDir      string // Fake /live mount with local repositories
+	RemotesDir   string // Bare repositories used as origin for live repos
+	WorkspaceDir string // Where worktrees are created
+	StateDir     string // Where the state is persisted
+	HomeDir      string // Isolated HOME with its own .gitconfig
+
+	Service *services.GitService
+	Events  *EventRecorder
+	GitHub  *StubGitHub
+
+	mu      sync.Mutex
+	aliases []alias           // Nondeterministic names (fun names, IDs) mapped to stable placeholders
+	labels  map[string]string // Scenario labels -> worktree IDs
+	stopped bool
+}
+
+type alias struct {
+	value       string
+	placeholder string
+}
+
+// NewEnv creates the directory layout and points the runtime configuration at it.
+// The original configuration is restored when the test finishes.
+func NewEnv(t testing.TB) *Env {
+	t.Helper()
+
+	root := t.TempDir()
+	e := &Env{
+		t:            t,
+		Root:         root,
+		LiveDir:      filepath.Join(root, "live"),
+		RemotesDir:   filepath.Join(root, "remotes"),
+		WorkspaceDir: filepath.Join(root, "workspace"),
+		StateDir:     filepath.Join(root, "volume"),
+		HomeDir:      filepath.Join(root, "home"),
+		Events:       NewEventRecorder(),
+		GitHub:       NewStubGitHub(),
+	}
+
+	for _, dir := range []string{e.LiveDir, e.RemotesDir, e.WorkspaceDir, e.StateDir, e.HomeDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("failed to create %s: %v", dir, err)
+		}
+	}
+
+	gitconfig := "[user]\n\tname = Catnip Test\n\temail = test@catnip.local\n[init]\n\tdefaultBranch = main\n[commit]\n\tgpgsign = false\n"
+	if err := os.WriteFile(filepath.Join(e.HomeDir, ".gitconfig"), []byte(gitconfig), 0644); err != nil {
+		t.Fatalf("failed to write gitconfig: %v", err)
+	}
+
+	// Isolate git from the host configuration and pin commit dates
+	t.Setenv("HOME", e.HomeDir)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(e.HomeDir, ".config"))
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("GIT_AUTHOR_DATE", fixedGitDate)
+	t.Setenv("GIT_COMMITTER_DATE", fixedGitDate)
+	t.Setenv("GIT_TERMINAL_PROMPT", "0")
+	t.Setenv("CATNIP_WORKSPACE_DIR", e.WorkspaceDir)
+
+	original := *config.Runtime
+	config.Runtime.Mode = config.DockerMode // Docker mode scans LiveDir for local repos
+	config.Runtime.WorkspaceDir = e.WorkspaceDir
+	config.Runtime.VolumeDir = e.StateDir
+	config.Runtime.LiveDir = e.LiveDir
+	config.Runtime.HomeDir = e.HomeDir
+	config.Runtime.TempDir = filepath.Join(root, "tmp")
+	config.Runtime.CurrentRepo = ""
+	config.Runtime.SyncEnabled = false
+	config.Runtime.PortMonitorEnabled = false
+
+	t.Cleanup(func() {
+		e.Stop()
+		*config.Runtime = original
+	})
+
+	return e
+}
+
+// Start creates the GitService, wires the event recorder and detects live repositories
+// (which creates their initial worktrees)
+func (e *Env) Start() *services.GitService {
+	e.t.Helper()
+
+	e.Service = services.NewGitServiceWithGitHub(git.NewOperations(), e.StateDir, e.GitHub)
+	e.Service.SetEventsEmitter(e.Events)
+	e.Service.InitializeLocalRepos()
+
+	for _, wt := range e.Service.ListWorktrees() {
+		e.TrackWorktree(wt)
+	}
+	return e.Service
+}
+
+// Stop shuts the GitService down so the state is no longer written
+func (e *Env) Stop() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.stopped || e.Service == nil {
+		return
+	}
+	e.stopped = true
+	e.Service.Stop()
+}
+
+// Alias replaces value with placeholder in snapshots (e.g. random fun names)
+func (e *Env) Alias(value, placeholder string) {
+	if value == "" {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, a := range e.aliases {
+		if a.value == value {
+			return
+		}
+	}
+	e.aliases = append(e.aliases, alias{value: value, placeholder: placeholder})
+}
+
+// TrackWorktree registers a worktree's ID and generated name so snapshots stay stable.
+// Worktrees are numbered in the order they are tracked: <wt1>, <wt2>, ...
+func (e *Env) TrackWorktree(wt *models.Worktree) string {
+	e.mu.Lock()
+	n := 1
+	for _, a := range e.aliases {
+		if strings.HasPrefix(a.placeholder, "<wt") && strings.HasSuffix(a.placeholder, "-id>") {
+			if a.value == wt.ID {
+				e.mu.Unlock()
+				return strings.TrimSuffix(a.placeholder, "-id>") + ">"
+			}
+			n++
+		}
+	}
+	e.mu.Unlock()
+
+	placeholder := "<wt" + strconv.Itoa(n) + ">"
+	e.Alias(wt.ID, "<wt"+strconv.Itoa(n)+"-id>")
+	e.Alias(git.ExtractWorkspaceName(wt.Branch), placeholder)
+	return placeholder
+}
+
+// Worktree returns the current state of a worktree, failing the test if it is gone
+func (e *Env) Worktree(id string) *models.Worktree {
+	e.t.Helper()
+	wt, ok := e.Service.GetWorktree(id)
+	if !ok {
+		e.t.Fatalf("worktree %s not found", id)
+	}
+	return wt
+}
+
+// Git runs a git command in dir and returns trimmed output, failing the test on error
+func (e *Env) Git(dir string, args ...string) string {
+	e.t.Helper()
+	out, err := e.tryGit(dir, args...)
+	if err != nil {
+		e.t.Fatalf("git %s failed in %s: %v\n%s", strings.Join(args, " "), dir, err, out)
+	}
+	return out
+}
+
+func (e *Env) tryGit(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = os.Environ()
+	out, err := cmd.CombinedOutput()
+	return strings.TrimSpace(string(out)), err
+}
+
+// WriteFile writes content to a path relative to dir, creating parent directories
+func (e *Env) WriteFile(dir, name, content string) {
+	e.t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		e.t.Fatalf("failed to create directory for %s: %v", path, err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		e.t.Fatalf("failed to write %s: %v", path, err)
+	}
+}
+
+// CreateLiveRepo creates a bare origin under RemotesDir and a clone of it under
+// LiveDir with an initial commit containing files. It returns the live repo path.
+func (e *Env) CreateLiveRepo(name string, files map[string]string) string {
+	e.t.Helper()
+
+	barePath := filepath.Join(e.RemotesDir, name+".git")
+	livePath := filepath.Join(e.LiveDir, name)
+
+	e.Git(e.Root, "init", "--bare", "-b", "main", barePath)
+	e.Git(e.Root, "init", "-b", "main", livePath)
+	for _, file := range sortedKeys(files) {
+		e.WriteFile(livePath, file, files[file])
+	}
+	e.Git(livePath, "add", "-A")
+	e.Git(livePath, "commit", "-m", "Initial commit")
+	e.Git(livePath, "remote", "add", "origin", barePath)
+	e.Git(livePath, "push", "-u", "origin", "main")
+
+	return livePath
+}
+
+// CommitFile writes a file in dir and commits it directly with git (bypassing GitService)
+func (e *Env) CommitFile(dir, name, content, message string) string {
+	e.t.Helper()
+	e.WriteFile(dir, name, content)
+	e.Git(dir, "add", "--", name)
+	e.Git(dir, "commit", "-m", message)
+	return e.Git(dir, "rev-parse", "HEAD")
+}
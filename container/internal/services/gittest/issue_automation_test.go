@@ -0,0 +1,79 @@
+package gittest
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/models"
+	"github.com/vanpelt/catnip/internal/services"
+)
+
+// TestIssueAutomation labels issues on GitHub and checks each gets exactly one worktree and a
+// comment, and that unlabeling or closing an issue stops automation without deleting anything
+func TestIssueAutomation(t *testing.T) {
+	Run(t, Scenario{
+		Name: "issue_automation",
+		Setup: func(e *Env) {
+			livePath := e.CreateLiveRepo("demo", map[string]string{"README.md": "# demo\n"})
+			// Origins mentioning github.com count as GitHub remotes; this one is still a local bare repo
+			origin := filepath.Join(e.RemotesDir, "github.com", "catnip-test", "demo.git")
+			e.Git(e.Root, "clone", "--bare", filepath.Join(e.RemotesDir, "demo.git"), origin)
+			e.Git(livePath, "remote", "set-url", "origin", origin)
+		},
+		Steps: []Step{
+			{"disabled until configured", func(e *Env) {
+				_, err := e.Service.PollIssueAutomation("local/demo")
+				assert.ErrorContains(e.t, err, "issue automation is not enabled")
+				automation, err := e.Service.SetIssueAutomation("local/demo", models.IssueAutomation{Enabled: true})
+				require.NoError(e.t, err)
+				assert.Equal(e.t, services.DefaultIssueAutomationLabel, automation.Label)
+			}},
+			{"labeled issues get worktrees", func(e *Env) {
+				e.GitHub.OpenIssue(7, "Login fails with SSO", "Steps to reproduce...", "catnip", "bug")
+				e.GitHub.OpenIssue(8, "Dark mode", "", "enhancement")
+				e.GitHub.OpenIssue(9, "Flaky export", "", "catnip")
+
+				linked, err := e.Service.PollIssueAutomation("local/demo")
+				require.NoError(e.t, err)
+				require.Len(e.t, linked, 2)
+				assert.Equal(e.t, 7, linked[0].Number)
+				assert.Equal(e.t, 9, linked[1].Number)
+				assert.True(e.t, linked[0].Commented)
+
+				sso := e.Worktree(linked[0].WorktreeID)
+				e.Label("sso", sso)
+				e.Label("export", e.Worktree(linked[1].WorktreeID))
+				assert.Equal(e.t, "main", sso.SourceBranch)
+				require.NotNil(e.t, sso.CreationContext)
+				assert.Equal(e.t, "https://github.com/catnip-test/repo/issues/7", sso.CreationContext.Issue)
+				assert.Equal(e.t, "Login fails with SSO\n\nSteps to reproduce...", sso.CreationContext.Prompt)
+			}},
+			{"one worktree per issue", func(e *Env) {
+				linked, err := e.Service.PollIssueAutomation("local/demo")
+				require.NoError(e.t, err)
+				assert.Empty(e.t, linked)
+				assert.Len(e.t, e.Service.ListWorktrees(), 3, "the initial worktree and one per issue")
+			}},
+			{"unlabeling and closing stop automation", func(e *Env) {
+				e.GitHub.LabelIssue(7, "catnip", true)
+				e.GitHub.CloseIssue(9)
+				_, err := e.Service.PollIssueAutomation("local/demo")
+				require.NoError(e.t, err)
+
+				// Labeling the issue again doesn't create a second worktree
+				e.GitHub.LabelIssue(7, "catnip", false)
+				linked, err := e.Service.PollIssueAutomation("local/demo")
+				require.NoError(e.t, err)
+				assert.Empty(e.t, linked)
+
+				automation, err := e.Service.GetIssueAutomation("local/demo")
+				require.NoError(e.t, err)
+				assert.Equal(e.t, services.IssueStoppedUnlabeled, automation.Issues[7].Stopped)
+				assert.Equal(e.t, services.IssueStoppedClosed, automation.Issues[9].Stopped)
+				assert.Len(e.t, e.Service.ListWorktrees(), 3, "worktrees are never deleted automatically")
+			}},
+		},
+	})
+}
@@ -0,0 +1,65 @@
+package gittest
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestLocalRepoLifecycle drives a local repo worktree through
+// checkout → checkpoint → rename → sync with conflict → resolve → merge → cleanup
+func TestLocalRepoLifecycle(t *testing.T) {
+	var livePath string
+
+	Run(t, Scenario{
+		Name: "local_repo_lifecycle",
+		Setup: func(e *Env) {
+			livePath = e.CreateLiveRepo("demo", map[string]string{
+				"README.md": "# demo\n",
+				"app.txt":   "line one\n",
+			})
+		},
+		Steps: []Step{
+			{"initial worktree", func(e *Env) {
+				wt := e.InitialWorktree("initial", "demo")
+				assert.Equal(e.t, "main", wt.SourceBranch)
+			}},
+			{"checkout", func(e *Env) {
+				wt := e.Checkout("feature", "demo", "main")
+				assert.Equal(e.t, filepath.Join(e.WorkspaceDir, "demo"), filepath.Dir(wt.Path))
+			}},
+			{"checkpoint", func(e *Env) {
+				e.Checkpoint("feature", "app.txt", "line one\nfeature change\n", "Add feature change")
+			}},
+			{"rename", func(e *Env) {
+				e.RenameBranch("feature", "feature/lifecycle")
+				assert.Equal(e.t, "feature/lifecycle", e.Labelled("feature").Branch)
+			}},
+			{"upstream change", func(e *Env) {
+				e.CommitFile(livePath, "app.txt", "line one\nupstream change\n", "Upstream change")
+			}},
+			{"sync with conflict", func(e *Env) {
+				conflict := e.Sync("feature", "merge")
+				require.NotNil(e.t, conflict, "expected sync to conflict")
+				assert.Equal(e.t, "sync", conflict.Operation)
+				assert.Contains(e.t, conflict.ConflictFiles, "app.txt")
+			}},
+			{"resolve", func(e *Env) {
+				e.ResolveConflict("feature", "app.txt", "line one\nupstream change\nfeature change\n", "Resolve conflict")
+				assert.Nil(e.t, e.Sync("feature", "merge"))
+			}},
+			{"merge", func(e *Env) {
+				e.Merge("feature", false)
+				assert.Equal(e.t, "line one\nupstream change\nfeature change", e.Git(livePath, "show", "main:app.txt"))
+			}},
+			{"cleanup", func(e *Env) {
+				e.Delete("feature")
+			}},
+		},
+		SnapshotRepos: func(e *Env) []string {
+			return []string{livePath}
+		},
+	})
+}
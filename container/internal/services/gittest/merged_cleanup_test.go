@@ -0,0 +1,55 @@
+package gittest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/services"
+)
+
+// TestMergedCleanupDryRun previews merged worktree cleanup, then applies it to the previewed
+// worktrees only
+func TestMergedCleanupDryRun(t *testing.T) {
+	var livePath string
+
+	Run(t, Scenario{
+		Name: "merged_cleanup_dry_run",
+		Setup: func(e *Env) {
+			livePath = e.CreateLiveRepo("demo", map[string]string{"README.md": "# demo\n"})
+		},
+		Steps: []Step{
+			{"worktrees", func(e *Env) {
+				e.InitialWorktree("initial", "demo")
+				e.Checkout("merged", "demo", "main")
+				e.Checkout("deleted", "demo", "main")
+				e.Checkout("wip", "demo", "main")
+				e.RenameBranch("merged", "feature/merged")
+				e.Checkpoint("merged", "login.txt", "login\n", "Add login page")
+				e.Merge("merged", false)
+				e.Git(livePath, "update-ref", "-d", e.Labelled("deleted").Branch)
+			}},
+			{"preview", func(e *Env) {
+				preview := e.Service.PreviewMergedWorktreeCleanup()
+				reasons := map[string]string{}
+				for _, wt := range preview {
+					reasons[wt.ID] = wt.Reason
+				}
+				assert.Equal(e.t, map[string]string{
+					e.ID("merged"):  services.MergedReasonListedMerged,
+					e.ID("deleted"): services.MergedReasonBranchMissing,
+				}, reasons)
+				assert.Len(e.t, e.Service.ListWorktrees(), 4, "a dry run removes nothing")
+			}},
+			{"apply previewed worktrees", func(e *Env) {
+				name := e.Labelled("merged").Name
+				count, cleaned, err := e.Service.CleanupMergedWorktrees([]string{e.ID("merged"), e.ID("wip")})
+				require.Error(e.t, err)
+				assert.Contains(e.t, err.Error(), "worktree "+e.ID("wip")+" no longer qualifies for cleanup")
+				assert.Equal(e.t, 1, count)
+				assert.Equal(e.t, []string{name}, cleaned)
+				assert.Len(e.t, e.Service.ListWorktrees(), 3, "worktrees left out of the list are kept")
+			}},
+		},
+	})
+}
@@ -0,0 +1,67 @@
+package gittest
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/services"
+)
+
+// TestPullRequestBase retargets a worktree's pull request to a release branch on GitHub, then
+// checks divergence is tracked against both bases and the worktree syncs onto the new one
+func TestPullRequestBase(t *testing.T) {
+	Run(t, Scenario{
+		Name: "pull_request_base",
+		Setup: func(e *Env) {
+			livePath := e.CreateLiveRepo("demo", map[string]string{"README.md": "# demo\n"})
+			// Origins mentioning github.com count as GitHub remotes; this one is still a local bare repo
+			origin := filepath.Join(e.RemotesDir, "github.com", "catnip-test", "demo.git")
+			e.Git(e.Root, "clone", "--bare", filepath.Join(e.RemotesDir, "demo.git"), origin)
+			e.Git(livePath, "remote", "set-url", "origin", origin)
+			e.Git(livePath, "branch", "release")
+		},
+		Steps: []Step{
+			{"open pull request", func(e *Env) {
+				e.Checkout("login", "demo", "main")
+				e.Checkpoint("login", "login.txt", "login\n", "Add login page")
+				_, err := e.Service.CreatePullRequest(e.ID("login"), "Add login page", "", services.PullRequestOptions{SkipValidation: true})
+				require.NoError(e.t, err)
+				assert.Equal(e.t, "main", e.Labelled("login").PullRequestBaseBranch)
+			}},
+			{"retarget to release", func(e *Env) {
+				livePath := filepath.Join(e.LiveDir, "demo")
+				e.Git(livePath, "checkout", "release")
+				e.CommitFile(livePath, "CHANGELOG.md", "# 1.0\n", "Prepare release")
+				e.Git(livePath, "checkout", "main")
+				e.GitHub.RetargetPullRequest(e.Labelled("login").Branch, "release")
+
+				info, err := e.Service.GetPullRequestInfo(e.ID("login"))
+				require.NoError(e.t, err)
+				assert.Equal(e.t, "release", info.BaseBranch)
+				assert.True(e.t, info.HasCommitsAhead)
+
+				require.NoError(e.t, e.Service.RefreshWorktreeStatusByID(e.ID("login")))
+				login := e.Labelled("login")
+				assert.Equal(e.t, "release", login.PullRequestBaseBranch)
+				assert.Equal(e.t, 0, login.CommitsBehind)
+				assert.Equal(e.t, 1, login.PullRequestCommitsBehind)
+				assert.Equal(e.t, 1, login.PullRequestCommitCount)
+			}},
+			{"sync onto pull request base", func(e *Env) {
+				require.Nil(e.t, e.SyncOnto("login", "rebase", services.SyncBasePullRequest))
+				assert.FileExists(e.t, filepath.Join(e.Labelled("login").Path, "CHANGELOG.md"))
+				require.NoError(e.t, e.Service.RefreshWorktreeStatusByID(e.ID("login")))
+				assert.Equal(e.t, 0, e.Labelled("login").PullRequestCommitsBehind)
+			}},
+			{"worktree without pull request", func(e *Env) {
+				e.Checkout("logout", "demo", "main")
+				_, err := e.Service.SyncWorktree(e.ID("logout"), services.SyncOptions{Strategy: "rebase", Base: services.SyncBasePullRequest})
+				assert.ErrorContains(e.t, err, "no pull request with a known base branch")
+				_, err = e.Service.SyncWorktree(e.ID("logout"), services.SyncOptions{Strategy: "rebase", Base: "upstream"})
+				assert.ErrorContains(e.t, err, "unknown sync base")
+			}},
+		},
+	})
+}
@@ -0,0 +1,401 @@
+package gittest
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/vanpelt/catnip/internal/git"
+	"github.com/vanpelt/catnip/internal/models"
+	"github.com/vanpelt/catnip/internal/services"
+)
+
+// Ensure the recorder and stub satisfy the interfaces GitService depends on
+var (
+	_ services.EventsEmitter = (*EventRecorder)(nil)
+	_ git.GitHubClient       = (*StubGitHub)(nil)
+)
+
+// statusFields are updated asynchronously by the status cache and Claude activity sync,
+// or hold measured durations, so they are left out of event snapshots to keep them deterministic
+var statusFields = map[string]bool{
+	"is_dirty":                    true,
+	"has_conflicts":               true,
+	"commit_hash":                 true,
+	"commit_count":                true,
+	"commits_behind":              true,
+	"pull_request_commit_count":   true,
+	"pull_request_commits_behind": true,
+	"has_active_claude_session":   true,
+	"claude_activity_state":       true,
+	"pull_request_state":          true,
+	"last_accessed":               true,
+	"operation_timings":           true,
+	"manual_intervention":         true,
+}
+
+// RecordedEvent is a lifecycle event emitted by GitService
+type RecordedEvent struct {
+	Type       string
+	WorktreeID string
+	Detail     string
+}
+
+// EventRecorder implements services.EventsEmitter and keeps lifecycle events in order.
+// Status, dirty/clean and batch updates are ignored since their timing is racy.
+type EventRecorder struct {
+	mu         sync.Mutex
+	events     []RecordedEvent
+	lastValues map[string]string // worktreeID + field -> last recorded value
+}
+
+// NewEventRecorder creates an empty recorder
+func NewEventRecorder() *EventRecorder {
+	return &EventRecorder{lastValues: make(map[string]string)}
+}
+
+// Events returns a copy of the recorded lifecycle events
+func (r *EventRecorder) Events() []RecordedEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]RecordedEvent(nil), r.events...)
+}
+
+func (r *EventRecorder) record(eventType, worktreeID, detail string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, RecordedEvent{Type: eventType, WorktreeID: worktreeID, Detail: detail})
+}
+
+// recordUpdate records a field update only when its value changed. The same update is
+// often emitted by both the explicit call and a later status cache refresh.
+func (r *EventRecorder) recordUpdate(worktreeID, field, value string) {
+	r.mu.Lock()
+	key := worktreeID + "\x00" + field
+	if last, ok := r.lastValues[key]; ok && last == value {
+		r.mu.Unlock()
+		return
+	}
+	r.lastValues[key] = value
+	r.mu.Unlock()
+
+	r.record("worktree:updated", worktreeID, field+"="+value)
+}
+
+// EmitWorktreeStatusUpdated implements services.EventsEmitter
+func (r *EventRecorder) EmitWorktreeStatusUpdated(worktreeID string, status *services.CachedWorktreeStatus) {
+}
+
+// EmitWorktreeBatchUpdated implements services.EventsEmitter
+func (r *EventRecorder) EmitWorktreeBatchUpdated(updates map[string]*services.CachedWorktreeStatus) {}
+
+// EmitWorktreeDirty implements services.EventsEmitter
+func (r *EventRecorder) EmitWorktreeDirty(worktreeID, worktreeName string, files []string) {}
+
+// EmitWorktreeClean implements services.EventsEmitter
+func (r *EventRecorder) EmitWorktreeClean(worktreeID, worktreeName string) {}
+
+// EmitWorktreeUpdated implements services.EventsEmitter
+func (r *EventRecorder) EmitWorktreeUpdated(worktreeID string, updates map[string]interface{}) {
+	keys := make([]string, 0, len(updates))
+	for key := range updates {
+		if !statusFields[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		r.recordUpdate(worktreeID, key, eventValue(updates[key]))
+	}
+}
+
+// eventValue formats an updated field for snapshots, leaving out timestamps
+func eventValue(value interface{}) string {
+	if creation, ok := value.(*models.CreationContext); ok && creation != nil {
+		return fmt.Sprintf("source=%s issue=%s", creation.Source, creation.Issue)
+	}
+	return fmt.Sprint(value)
+}
+
+// EmitWorktreeCreated implements services.EventsEmitter
+func (r *EventRecorder) EmitWorktreeCreated(worktree *models.Worktree) {
+	r.mu.Lock()
+	r.lastValues[worktree.ID+"\x00branch"] = worktree.Branch
+	r.mu.Unlock()
+	r.record("worktree:created", worktree.ID, fmt.Sprintf("branch=%s source=%s", worktree.Branch, worktree.SourceBranch))
+}
+
+// EmitWorktreeDeleted implements services.EventsEmitter
+func (r *EventRecorder) EmitWorktreeDeleted(worktreeID, worktreeName string) {
+	r.record("worktree:deleted", worktreeID, "name="+worktreeName)
+}
+
+// EmitWorktreeTodosUpdated implements services.EventsEmitter
+func (r *EventRecorder) EmitWorktreeTodosUpdated(worktreeID string, todos []models.Todo) {
+	r.record("worktree:todos_updated", worktreeID, fmt.Sprintf("count=%d", len(todos)))
+}
+
+// EmitSessionTitleUpdated implements services.EventsEmitter
+func (r *EventRecorder) EmitSessionTitleUpdated(workspaceDir, worktreeID string, sessionTitle *models.TitleEntry, sessionTitleHistory []models.TitleEntry) {
+	title := ""
+	if sessionTitle != nil {
+		title = sessionTitle.Title
+	}
+	r.record("session:title_updated", worktreeID, "title="+title)
+}
+
+// EmitRepositoryHealthWarning implements services.EventsEmitter
+func (r *EventRecorder) EmitRepositoryHealthWarning(repoID, source, message string) {
+	r.record("repository:health_warning", repoID, source+": "+message)
+}
+
+// EmitRepositoryRenamed implements services.EventsEmitter
+func (r *EventRecorder) EmitRepositoryRenamed(oldID, newID string) {
+	r.record("repository:renamed", newID, "from="+oldID)
+}
+
+// EmitDiskStatusChanged implements services.EventsEmitter
+func (r *EventRecorder) EmitDiskStatusChanged(status services.DiskStatus) {
+	r.record("system:disk_status", "", "state="+status.State)
+}
+
+// EmitWorktreeHealth implements services.EventsEmitter
+func (r *EventRecorder) EmitWorktreeHealth(health *models.WorktreeHealth) {
+	codes := make([]string, 0, len(health.Findings))
+	for _, finding := range health.Findings {
+		codes = append(codes, finding.Code)
+	}
+	r.record("worktree:health", health.WorktreeID, "findings="+strings.Join(codes, ","))
+}
+
+// EmitWorktreeFromIssue implements services.EventsEmitter
+func (r *EventRecorder) EmitWorktreeFromIssue(worktree *models.Worktree, issue *models.AutomatedIssue) {
+	r.record("worktree:from_issue", worktree.ID, fmt.Sprintf("issue=%d", issue.Number))
+}
+
+// EmitWorktreeBranchDrift implements services.EventsEmitter
+func (r *EventRecorder) EmitWorktreeBranchDrift(worktreeID string, drift *models.BranchDrift) {
+	r.record("worktree:branch_drift", worktreeID, fmt.Sprintf("expected=%s actual=%s adopted=%v", drift.ExpectedBranch, drift.ActualBranch, drift.Adopted))
+}
+
+// EmitWorktreeSourceRewritten implements services.EventsEmitter
+func (r *EventRecorder) EmitWorktreeSourceRewritten(worktreeID string, rewrite *models.SourceRewrite) {
+	r.record("worktree:source_rewritten", worktreeID, fmt.Sprintf("branch=%s previous=%.8s new=%.8s", rewrite.Branch, rewrite.PreviousTip, rewrite.NewTip))
+}
+
+// EmitWorktreeBranchRename implements services.EventsEmitter
+func (r *EventRecorder) EmitWorktreeBranchRename(worktreeID string, outcome *models.BranchRenameOutcome) {
+	r.record("worktree:branch_rename", worktreeID, fmt.Sprintf("outcome=%s reason=%s", outcome.Outcome, outcome.Reason))
+}
+
+// EmitWorktreeMerge implements services.EventsEmitter
+func (r *EventRecorder) EmitWorktreeMerge(phase services.MergePhase, preview *models.MergePreview) {
+	r.record("worktree:merge_"+string(phase), preview.WorktreeID, fmt.Sprintf("mode=%s target=%s", preview.Mode, preview.TargetBranch))
+}
+
+// EmitPullRequestCreated implements services.EventsEmitter
+func (r *EventRecorder) EmitPullRequestCreated(worktreeID string, pr *models.PullRequestResponse) {
+	r.record("worktree:pull_request_created", worktreeID, "url="+pr.URL)
+}
+
+// EmitPullRequestStatus implements services.EventsEmitter
+func (r *EventRecorder) EmitPullRequestStatus(worktreeID string, state *models.PullRequestState) {
+	r.record("worktree:pull_request_status", worktreeID, fmt.Sprintf("url=%s state=%s", state.URL, state.State))
+}
+
+// StubGitHub implements git.GitHubClient without touching the network or the gh CLI
+type StubGitHub struct {
+	mu           sync.Mutex
+	calls        []string
+	pullRequests map[string]*models.PullRequestResponse // keyed by head branch
+	issues       map[int]*stubIssue
+	reviews      map[int][]models.ReviewThread // keyed by pull request number
+}
+
+// stubIssue is an issue of the stub with its labels
+type stubIssue struct {
+	git.GitHubIssue
+	labels map[string]bool
+}
+
+// NewStubGitHub creates a stub with no pull requests or issues
+func NewStubGitHub() *StubGitHub {
+	return &StubGitHub{
+		pullRequests: make(map[string]*models.PullRequestResponse),
+		issues:       make(map[int]*stubIssue),
+		reviews:      make(map[int][]models.ReviewThread),
+	}
+}
+
+// Calls returns the GitHub operations invoked so far
+func (g *StubGitHub) Calls() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]string(nil), g.calls...)
+}
+
+func (g *StubGitHub) record(call string) {
+	g.mu.Lock()
+	g.calls = append(g.calls, call)
+	g.mu.Unlock()
+}
+
+// ConfigureGitCredentials implements git.GitHubClient
+func (g *StubGitHub) ConfigureGitCredentials() (*git.CredentialHelperReport, error) {
+	g.record("configure-credentials")
+	return &git.CredentialHelperReport{Hosts: []git.CredentialHostStatus{}, Verified: true}, nil
+}
+
+// ListRepositories implements git.GitHubClient
+func (g *StubGitHub) ListRepositories() ([]git.GitHubRepository, error) {
+	g.record("list-repositories")
+	return nil, nil
+}
+
+// CreateRepository implements git.GitHubClient
+func (g *StubGitHub) CreateRepository(name, description string, isPrivate bool) (string, error) {
+	g.record("create-repository " + name)
+	return fmt.Sprintf("https://github.com/catnip-test/%s", name), nil
+}
+
+// CreatePullRequest implements git.GitHubClient. Pull requests are numbered from 1
+// and updating an existing branch's PR keeps its number.
+func (g *StubGitHub) CreatePullRequest(req git.CreatePullRequestRequest) (*models.PullRequestResponse, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	branch := req.Worktree.Branch
+	pr, exists := g.pullRequests[branch]
+	if !exists {
+		number := len(g.pullRequests) + 1
+		pr = &models.PullRequestResponse{
+			Number:     number,
+			URL:        fmt.Sprintf("https://github.com/catnip-test/repo/pull/%d", number),
+			HeadBranch: branch,
+			BaseBranch: req.Worktree.SourceBranch,
+			Repository: "catnip-test/repo",
+		}
+		g.pullRequests[branch] = pr
+	}
+	pr.Title = req.Title
+	pr.Body = req.Body
+
+	call := fmt.Sprintf("create-pull-request %s -> %s", branch, req.Worktree.SourceBranch)
+	if req.Draft && !req.IsUpdate {
+		call += " (draft)"
+	}
+	g.calls = append(g.calls, call)
+	copied := *pr
+	return &copied, nil
+}
+
+// GetPullRequestInfo implements git.GitHubClient
+func (g *StubGitHub) GetPullRequestInfo(worktree *models.Worktree, repository *models.Repository) (*models.PullRequestInfo, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	info := &models.PullRequestInfo{HasCommitsAhead: worktree.CommitCount > 0}
+	if pr, exists := g.pullRequests[worktree.Branch]; exists {
+		info.Exists = true
+		info.Number = pr.Number
+		info.URL = pr.URL
+		info.Title = pr.Title
+		info.Body = pr.Body
+		info.BaseBranch = pr.BaseBranch
+	}
+	return info, nil
+}
+
+// RetargetPullRequest changes the base branch of the pull request of a head branch, like
+// editing it on GitHub
+func (g *StubGitHub) RetargetPullRequest(branch, base string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if pr, exists := g.pullRequests[branch]; exists {
+		pr.BaseBranch = base
+	}
+	g.calls = append(g.calls, fmt.Sprintf("retarget-pull-request %s -> %s", branch, base))
+}
+
+// ListLabeledIssues implements git.GitHubClient, newest issues first like gh issue list
+func (g *StubGitHub) ListLabeledIssues(ownerRepo, label string) ([]git.GitHubIssue, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var issues []git.GitHubIssue
+	for _, issue := range g.issues {
+		if issue.labels[label] {
+			issues = append(issues, issue.GitHubIssue)
+		}
+	}
+	sort.Slice(issues, func(i, j int) bool { return issues[i].Number > issues[j].Number })
+	return issues, nil
+}
+
+// CommentOnIssue implements git.GitHubClient
+func (g *StubGitHub) CommentOnIssue(ownerRepo string, number int, body string) error {
+	g.record(fmt.Sprintf("comment-issue %s#%d: %s", ownerRepo, number, body))
+	return nil
+}
+
+// ListReviewThreads implements git.GitHubClient
+func (g *StubGitHub) ListReviewThreads(ownerRepo string, number int) ([]models.ReviewThread, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.calls = append(g.calls, fmt.Sprintf("list-review-threads %s#%d", ownerRepo, number))
+	return append([]models.ReviewThread{}, g.reviews[number]...), nil
+}
+
+// AddReviewThread starts an unresolved review thread on a pull request, like reviewing it on
+// GitHub
+func (g *StubGitHub) AddReviewThread(number int, thread models.ReviewThread) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.reviews[number] = append(g.reviews[number], thread)
+}
+
+// OpenIssue opens an issue with labels, like filing it on GitHub
+func (g *StubGitHub) OpenIssue(number int, title, body string, labels ...string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	issue := &stubIssue{
+		GitHubIssue: git.GitHubIssue{
+			Number: number,
+			Title:  title,
+			Body:   body,
+			URL:    fmt.Sprintf("https://github.com/catnip-test/repo/issues/%d", number),
+			State:  "OPEN",
+		},
+		labels: make(map[string]bool),
+	}
+	for _, label := range labels {
+		issue.labels[label] = true
+	}
+	g.issues[number] = issue
+}
+
+// LabelIssue adds (or with remove set, removes) a label of an issue
+func (g *StubGitHub) LabelIssue(number int, label string, remove bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if issue, exists := g.issues[number]; exists {
+		issue.labels[label] = !remove
+	}
+}
+
+// CloseIssue closes an issue
+func (g *StubGitHub) CloseIssue(number int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if issue, exists := g.issues[number]; exists {
+		issue.State = "CLOSED"
+	}
+}
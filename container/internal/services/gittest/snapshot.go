@@ -0,0 +1,223 @@
+package gittest
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// UpdateSnapshotsEnv rewrites golden files instead of comparing against them
+const UpdateSnapshotsEnv = "CATNIP_UPDATE_SNAPSHOTS"
+
+var (
+	fullHashPattern  = regexp.MustCompile(`\b[0-9a-f]{40}\b`)
+	timestampPattern = regexp.MustCompile(`\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:\d{2})`)
+	uuidPattern      = regexp.MustCompile(`\b[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\b`)
+)
+
+// Snapshot renders the persisted state, the refs and commit graph of each repository and the
+// recorded lifecycle events as normalized text suitable for golden comparison.
+// The service is stopped first so the state is no longer being written.
+func (e *Env) Snapshot(repoPaths ...string) string {
+	e.t.Helper()
+	e.Stop()
+
+	var b strings.Builder
+
+	b.WriteString("## state\n")
+	b.WriteString(e.stateSnapshot())
+	b.WriteString("\n")
+
+	for _, repoPath := range repoPaths {
+		fmt.Fprintf(&b, "\n## refs %s\n", repoPath)
+		refs := e.Git(repoPath, "for-each-ref", "--format=%(refname) %(objectname)")
+		if refs != "" {
+			b.WriteString(refs)
+			b.WriteString("\n")
+		}
+
+		fmt.Fprintf(&b, "\n## history %s\n", repoPath)
+		b.WriteString(e.Git(repoPath, "log", "--all", "--topo-order", "--format=%H %P %s"))
+		b.WriteString("\n")
+	}
+
+	b.WriteString("\n## events\n")
+	for _, event := range e.Events.Events() {
+		fmt.Fprintf(&b, "%s %s %s\n", event.Type, event.WorktreeID, event.Detail)
+	}
+
+	if calls := e.GitHub.Calls(); len(calls) > 0 {
+		b.WriteString("\n## github\n")
+		for _, call := range calls {
+			b.WriteString(call)
+			b.WriteString("\n")
+		}
+	}
+
+	return e.Normalize(b.String())
+}
+
+// stateSnapshot returns the persisted state, composed from its per-entity files, with status
+// cache fields (refreshed asynchronously) and measured operation timings removed and keys sorted
+func (e *Env) stateSnapshot() string {
+	e.t.Helper()
+
+	var prStates interface{}
+	e.readStateFile("pull_request_states.json", &prStates)
+	state := map[string]interface{}{
+		"pull_request_states": prStates,
+		"repositories":        e.readStateEntities("repositories"),
+		"worktrees":           e.readStateEntities("worktrees"),
+	}
+
+	if worktrees, ok := state["worktrees"].(map[string]interface{}); ok {
+		for _, raw := range worktrees {
+			if wt, ok := raw.(map[string]interface{}); ok {
+				for field := range statusFields {
+					delete(wt, field)
+				}
+			}
+		}
+	}
+
+	if repos, ok := state["repositories"].(map[string]interface{}); ok {
+		for _, raw := range repos {
+			if repo, ok := raw.(map[string]interface{}); ok {
+				delete(repo, "operation_timings")
+			}
+		}
+	}
+
+	// Entities are keyed by placeholders, which must stay readable
+	var out strings.Builder
+	encoder := json.NewEncoder(&out)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(state); err != nil {
+		e.t.Fatalf("failed to encode state snapshot: %v", err)
+	}
+	return strings.TrimSuffix(out.String(), "\n")
+}
+
+// readStateEntities returns the entity files of a state subdirectory keyed by normalized entity
+// ID, so they are ordered by placeholder rather than by their random IDs
+func (e *Env) readStateEntities(dir string) map[string]interface{} {
+	e.t.Helper()
+
+	entities := make(map[string]interface{})
+	paths, err := filepath.Glob(filepath.Join(e.StateDir, dir, "*.json"))
+	if err != nil {
+		e.t.Fatalf("failed to list state %s: %v", dir, err)
+	}
+	for _, path := range paths {
+		var entity map[string]interface{}
+		e.readStateFile(filepath.Join(dir, filepath.Base(path)), &entity)
+		id, _ := entity["id"].(string)
+		entities[e.Normalize(id)] = entity
+	}
+	return entities
+}
+
+// readStateFile parses a file of the state directory
+func (e *Env) readStateFile(name string, v interface{}) {
+	e.t.Helper()
+
+	data, err := os.ReadFile(filepath.Join(e.StateDir, name))
+	if err != nil {
+		e.t.Fatalf("failed to read state file %s: %v", name, err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		e.t.Fatalf("failed to parse state file %s: %v", name, err)
+	}
+}
+
+// Normalize replaces temp paths, aliases, timestamps, UUIDs and commit hashes with stable
+// placeholders. Hashes are numbered by first appearance so equal commits stay equal.
+func (e *Env) Normalize(text string) string {
+	text = strings.ReplaceAll(text, e.Root, "$ROOT")
+
+	e.mu.Lock()
+	aliases := append([]alias(nil), e.aliases...)
+	e.mu.Unlock()
+
+	// Longest first so IDs and names containing other aliases are replaced whole
+	sort.SliceStable(aliases, func(i, j int) bool { return len(aliases[i].value) > len(aliases[j].value) })
+	for _, a := range aliases {
+		text = regexp.MustCompile(`\b`+regexp.QuoteMeta(a.value)+`\b`).ReplaceAllString(text, a.placeholder)
+	}
+
+	text = timestampPattern.ReplaceAllString(text, "<time>")
+	text = uuidPattern.ReplaceAllString(text, "<uuid>")
+
+	hashes := make(map[string]string)
+	text = fullHashPattern.ReplaceAllStringFunc(text, func(hash string) string {
+		if placeholder, ok := hashes[hash]; ok {
+			return placeholder
+		}
+		placeholder := fmt.Sprintf("<hash%d>", len(hashes)+1)
+		hashes[hash] = placeholder
+		return placeholder
+	})
+
+	return text
+}
+
+// AssertGolden compares actual with testdata/<name>.golden, rewriting the file when
+// CATNIP_UPDATE_SNAPSHOTS=1
+func (e *Env) AssertGolden(name, actual string) {
+	e.t.Helper()
+
+	path := filepath.Join("testdata", name+".golden")
+	if os.Getenv(UpdateSnapshotsEnv) == "1" {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			e.t.Fatalf("failed to create testdata: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(actual), 0644); err != nil {
+			e.t.Fatalf("failed to update golden file: %v", err)
+		}
+		return
+	}
+
+	expected, err := os.ReadFile(path)
+	if err != nil {
+		e.t.Fatalf("failed to read golden file %s (run with %s=1 to create it): %v", path, UpdateSnapshotsEnv, err)
+	}
+	if string(expected) != actual {
+		e.t.Errorf("snapshot %s does not match golden file %s (run with %s=1 to update)\n%s",
+			name, path, UpdateSnapshotsEnv, lineDiff(string(expected), actual))
+	}
+}
+
+// lineDiff returns a minimal line-by-line diff for failure messages
+func lineDiff(expected, actual string) string {
+	expectedLines := strings.Split(expected, "\n")
+	actualLines := strings.Split(actual, "\n")
+
+	var b strings.Builder
+	for i := 0; i < len(expectedLines) || i < len(actualLines); i++ {
+		var want, got string
+		if i < len(expectedLines) {
+			want = expectedLines[i]
+		}
+		if i < len(actualLines) {
+			got = actualLines[i]
+		}
+		if want != got {
+			fmt.Fprintf(&b, "line %d:\n  - %s\n  + %s\n", i+1, want, got)
+		}
+	}
+	return b.String()
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
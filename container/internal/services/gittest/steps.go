@@ -0,0 +1,202 @@
+package gittest
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/models"
+	"github.com/vanpelt/catnip/internal/services"
+)
+
+// Step is a single named action in a scenario
+type Step struct {
+	Name string
+	Run  func(e *Env)
+}
+
+// Scenario is an ordered list of steps run against a fresh Env and compared with
+// testdata/<Name>.golden. Setup runs before the GitService starts, so live repos it
+// creates get their initial worktree. SnapshotRepos lists repositories whose refs are
+// included in the snapshot.
+type Scenario struct {
+	Name          string
+	Setup         func(e *Env)
+	Steps         []Step
+	SnapshotRepos func(e *Env) []string
+}
+
+// Run executes the scenario and asserts its golden snapshot
+func Run(t *testing.T, scenario Scenario) {
+	t.Helper()
+
+	e := NewEnv(t)
+	if scenario.Setup != nil {
+		scenario.Setup(e)
+	}
+	e.Start()
+
+	for _, step := range scenario.Steps {
+		t.Logf("▶ %s", step.Name)
+		step.Run(e)
+		if t.Failed() {
+			t.Fatalf("step %q failed", step.Name)
+		}
+	}
+
+	var repos []string
+	if scenario.SnapshotRepos != nil {
+		repos = scenario.SnapshotRepos(e)
+	}
+	e.AssertGolden(scenario.Name, e.Snapshot(repos...))
+}
+
+// Label remembers a worktree under a scenario-defined name
+func (e *Env) Label(label string, wt *models.Worktree) {
+	e.mu.Lock()
+	if e.labels == nil {
+		e.labels = make(map[string]string)
+	}
+	e.labels[label] = wt.ID
+	e.mu.Unlock()
+	e.TrackWorktree(wt)
+}
+
+// ID returns the worktree ID registered under label
+func (e *Env) ID(label string) string {
+	e.t.Helper()
+	e.mu.Lock()
+	id, ok := e.labels[label]
+	e.mu.Unlock()
+	if !ok {
+		e.t.Fatalf("no worktree labelled %q", label)
+	}
+	return id
+}
+
+// Labelled returns the current state of the worktree registered under label
+func (e *Env) Labelled(label string) *models.Worktree {
+	e.t.Helper()
+	return e.Worktree(e.ID(label))
+}
+
+// InitialWorktree labels the worktree created automatically for a live repo on startup
+func (e *Env) InitialWorktree(label, repoName string) *models.Worktree {
+	e.t.Helper()
+	for _, wt := range e.Service.ListWorktrees() {
+		if wt.RepoID == "local/"+repoName {
+			e.Label(label, wt)
+			return wt
+		}
+	}
+	e.t.Fatalf("no initial worktree for local/%s", repoName)
+	return nil
+}
+
+// Checkout creates a new worktree for a live repo from branch
+func (e *Env) Checkout(label, repoName, branch string) *models.Worktree {
+	e.t.Helper()
+	_, wt, err := e.Service.CheckoutRepository("local", repoName, branch, services.DefaultCloneOptions())
+	if err != nil {
+		e.t.Fatalf("checkout local/%s@%s failed: %v", repoName, branch, err)
+	}
+	e.Label(label, wt)
+	return wt
+}
+
+// Checkpoint writes a file in the worktree and commits it the way the checkpoint
+// manager does. It returns the new commit hash.
+func (e *Env) Checkpoint(label, file, content, title string) string {
+	e.t.Helper()
+	wt := e.Labelled(label)
+	e.WriteFile(wt.Path, file, content)
+	hash, err := e.Service.GitAddCommitGetHash(wt.Path, title)
+	if err != nil {
+		e.t.Fatalf("checkpoint in %s failed: %v", label, err)
+	}
+	if hash == "" {
+		e.t.Fatalf("checkpoint in %s produced no commit", label)
+	}
+	return hash
+}
+
+// RenameBranch moves the worktree from its catnip ref to a named branch, mirroring
+// the manual rename endpoint
+func (e *Env) RenameBranch(label, newBranch string) {
+	e.t.Helper()
+	wt := e.Labelled(label)
+	oldBranch := e.Git(wt.Path, "rev-parse", "--symbolic-full-name", "HEAD")
+
+	e.Git(wt.Path, "checkout", "-b", newBranch)
+	if err := e.Service.UpdateWorktreeBranchName(wt.Path, newBranch); err != nil {
+		e.t.Fatalf("failed to record branch rename for %s: %v", label, err)
+	}
+	if strings.HasPrefix(oldBranch, "refs/catnip/") {
+		e.Git(wt.Path, "update-ref", "-d", oldBranch)
+	}
+}
+
+// Sync syncs the worktree with its source branch and returns the conflict, if any.
+// Any other error fails the test.
+func (e *Env) Sync(label, strategy string) *models.MergeConflictError {
+	e.t.Helper()
+	return e.SyncOnto(label, strategy, services.SyncBaseSource)
+}
+
+// SyncOnto syncs the worktree with the given base (services.SyncBaseSource or
+// services.SyncBasePullRequest) and returns the conflict, if any. Any other error fails the test.
+func (e *Env) SyncOnto(label, strategy, base string) *models.MergeConflictError {
+	e.t.Helper()
+	_, err := e.Service.SyncWorktree(e.ID(label), services.SyncOptions{Strategy: strategy, Base: base})
+	if err == nil {
+		return nil
+	}
+	var conflict *models.MergeConflictError
+	if errors.As(err, &conflict) {
+		return conflict
+	}
+	e.t.Fatalf("sync of %s failed: %v", label, err)
+	return nil
+}
+
+// ResolveConflict overwrites a conflicted file and concludes the merge with a checkpoint commit
+func (e *Env) ResolveConflict(label, file, content, title string) string {
+	e.t.Helper()
+	return e.Checkpoint(label, file, content, title)
+}
+
+// Merge merges the worktree back into the live repo's source branch
+func (e *Env) Merge(label string, squash bool) {
+	e.t.Helper()
+	if err := e.Service.MergeWorktreeToMain(e.ID(label), services.MergeOptions{Squash: squash}); err != nil {
+		e.t.Fatalf("merge of %s failed: %v", label, err)
+	}
+}
+
+// CleanupMerged runs merged-worktree cleanup and returns the names it removed
+func (e *Env) CleanupMerged() []string {
+	e.t.Helper()
+	_, cleaned, err := e.Service.CleanupMergedWorktrees(nil)
+	if err != nil {
+		e.t.Fatalf("cleanup of merged worktrees failed: %v", err)
+	}
+	return cleaned
+}
+
+// Delete removes a worktree and waits for background cleanup to finish
+func (e *Env) Delete(label string) {
+	e.t.Helper()
+	done, err := e.Service.DeleteWorktree(e.ID(label))
+	if err != nil {
+		e.t.Fatalf("delete of %s failed: %v", label, err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			e.t.Fatalf("background cleanup of %s failed: %v", label, err)
+		}
+	case <-time.After(30 * time.Second):
+		e.t.Fatalf("timed out waiting for %s to be deleted", label)
+	}
+}
@@ -0,0 +1,73 @@
+package gittest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestWorktreeArchive archives a worktree with uncommitted work, deletes it and restores the
+// archive into a fresh worktree
+func TestWorktreeArchive(t *testing.T) {
+	var archiveID string
+
+	Run(t, Scenario{
+		Name: "worktree_archive",
+		Setup: func(e *Env) {
+			e.CreateLiveRepo("demo", map[string]string{"README.md": "# demo\n", ".gitignore": "*.log\n"})
+		},
+		Steps: []Step{
+			{"worktrees", func(e *Env) {
+				e.InitialWorktree("initial", "demo")
+				e.Checkout("experiment", "demo", "main")
+				e.Checkpoint("experiment", "login.txt", "login\n", "Add login page")
+				path := e.Labelled("experiment").Path
+				e.WriteFile(path, "login.txt", "login\nuncommitted\n")
+				e.WriteFile(path, "notes/idea.md", "untracked idea\n")
+				e.WriteFile(path, "debug.log", "ignored\n")
+			}},
+			{"archive and delete", func(e *Env) {
+				wt := e.Labelled("experiment")
+				archive, err := e.Service.ArchiveWorktree(wt.ID)
+				require.NoError(e.t, err)
+				archiveID = archive.ID
+
+				assert.Equal(e.t, wt.Branch, archive.Branch)
+				assert.Equal(e.t, "main", archive.SourceBranch)
+				assert.Equal(e.t, e.Git(wt.Path, "rev-parse", "HEAD"), archive.CommitHash)
+				assert.True(e.t, archive.IsDirty)
+				assert.Equal(e.t, 4, archive.Files, ".gitignore, README.md, login.txt and notes/idea.md")
+				assert.FileExists(e.t, archive.Path)
+				e.Delete("experiment")
+
+				archives, err := e.Service.ListWorktreeArchives()
+				require.NoError(e.t, err)
+				require.Len(e.t, archives, 1)
+				assert.Equal(e.t, archiveID, archives[0].ID)
+				assert.Equal(e.t, wt.Name, archives[0].WorktreeName)
+			}},
+			{"restore", func(e *Env) {
+				wt, err := e.Service.RestoreWorktreeArchive(archiveID)
+				require.NoError(e.t, err)
+				e.Label("restored", wt)
+
+				assert.Regexp(e.t, `^refs/catnip/`, wt.Branch)
+				content, err := os.ReadFile(filepath.Join(wt.Path, "login.txt"))
+				require.NoError(e.t, err)
+				assert.Equal(e.t, "login\nuncommitted\n", string(content))
+				assert.FileExists(e.t, filepath.Join(wt.Path, "notes", "idea.md"))
+				assert.NoFileExists(e.t, filepath.Join(wt.Path, "debug.log"), "ignored files aren't archived")
+				assert.NotEmpty(e.t, e.Git(wt.Path, "status", "--porcelain"), "restored files are left uncommitted")
+			}},
+			{"invalid archives", func(e *Env) {
+				_, err := e.Service.RestoreWorktreeArchive("../state")
+				assert.ErrorContains(e.t, err, "invalid archive ID")
+				_, err = e.Service.RestoreWorktreeArchive("missing")
+				assert.ErrorContains(e.t, err, "not found")
+			}},
+		},
+	})
+}
@@ -0,0 +1,78 @@
+package gittest
+
+import (
+	"crypto/rand"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/services"
+)
+
+// TestWorktreeBundle fetches a worktree's branch as full and incremental git bundles, checks
+// they unpack with plain git, and exercises the rate limit and size cap
+func TestWorktreeBundle(t *testing.T) {
+	var base string
+
+	Run(t, Scenario{
+		Name: "worktree_bundle",
+		Setup: func(e *Env) {
+			e.CreateLiveRepo("demo", map[string]string{"README.md": "# demo\n"})
+		},
+		Steps: []Step{
+			{"worktree", func(e *Env) {
+				e.InitialWorktree("initial", "demo")
+				base = e.Checkpoint("initial", "login.txt", "login\n", "Add login page")
+				e.Checkpoint("initial", "login.txt", "login\nstyled\n", "Style login page")
+			}},
+			{"full bundle clones", func(e *Env) {
+				bundle, err := e.Service.GetWorktreeBundle(e.ID("initial"), "", "ci")
+				require.NoError(e.t, err)
+				assert.Empty(e.t, bundle.Prerequisites)
+				assert.Equal(e.t, e.Labelled("initial").Branch, bundle.Ref)
+
+				clone := filepath.Join(e.Root, "clone")
+				e.Git(e.Root, "clone", "--quiet", bundle.Path, clone)
+				assert.Equal(e.t, bundle.Tip, e.Git(clone, "rev-parse", "HEAD"))
+
+				again, err := e.Service.GetWorktreeBundle(e.ID("initial"), "", "ci")
+				require.NoError(e.t, err)
+				assert.Equal(e.t, bundle.Path, again.Path, "unchanged tips reuse the cached bundle")
+			}},
+			{"incremental bundle", func(e *Env) {
+				bundle, err := e.Service.GetWorktreeBundle(e.ID("initial"), base, "ci")
+				require.NoError(e.t, err)
+				assert.Equal(e.t, []string{base}, bundle.Prerequisites)
+				assert.Contains(e.t, e.Git(e.Root, "bundle", "list-heads", bundle.Path), bundle.Tip)
+
+				bundle, err = e.Service.GetWorktreeBundle(e.ID("initial"), "0123456789abcdef0123456789abcdef01234567", "ci")
+				require.NoError(e.t, err)
+				assert.Empty(e.t, bundle.Prerequisites, "bases that aren't ancestors fall back to the full history")
+			}},
+			{"rate limit", func(e *Env) {
+				var err error
+				for i := 0; i < 20 && err == nil; i++ {
+					_, err = e.Service.GetWorktreeBundle(e.ID("initial"), "", "impatient")
+				}
+				assert.True(e.t, errors.Is(err, services.ErrBundleRateLimited))
+				_, err = e.Service.GetWorktreeBundle(e.ID("initial"), base, "someone-else")
+				assert.NoError(e.t, err, "the limit is per caller")
+			}},
+			{"invalid requests", func(e *Env) {
+				_, err := e.Service.GetWorktreeBundle(e.ID("initial"), "--output=/tmp/x", "ci")
+				assert.ErrorContains(e.t, err, "invalid base")
+				_, err = e.Service.GetWorktreeBundle("missing", "", "ci")
+				assert.ErrorContains(e.t, err, "not found")
+
+				e.t.Setenv("CATNIP_MAX_BUNDLE_MB", "1")
+				noise := make([]byte, 2<<20)
+				_, _ = rand.Read(noise)
+				e.Checkpoint("initial", "noise.bin", string(noise), "Add noise")
+				_, err = e.Service.GetWorktreeBundle(e.ID("initial"), "", "ci")
+				assert.ErrorContains(e.t, err, "over the 1 MiB limit")
+			}},
+		},
+	})
+}
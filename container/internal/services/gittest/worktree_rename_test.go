@@ -0,0 +1,88 @@
+package gittest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// busyWorktrees is a session checker reporting a session in every worktree
+type busyWorktrees struct{}
+
+func (busyWorktrees) HasSessionsIn(string) bool {
+	return true
+}
+
+// TestWorktreeRename renames a worktree twice, once on its catnip ref and once after its branch
+// was graduated, then checks the names and situations that are refused
+func TestWorktreeRename(t *testing.T) {
+	var livePath string
+
+	Run(t, Scenario{
+		Name: "worktree_rename",
+		Setup: func(e *Env) {
+			livePath = e.CreateLiveRepo("demo", map[string]string{"README.md": "# demo\n"})
+		},
+		Steps: []Step{
+			{"worktrees", func(e *Env) {
+				e.InitialWorktree("initial", "demo")
+				e.Checkout("other", "demo", "main")
+				e.Checkpoint("initial", "login.txt", "login\n", "Add login page")
+				// Creating a worktree points current at it, point it back at the one being renamed
+				current := filepath.Join(e.WorkspaceDir, "current")
+				require.NoError(e.t, os.Remove(current))
+				require.NoError(e.t, os.Symlink(e.Labelled("initial").Path, current))
+			}},
+			{"rename on catnip ref", func(e *Env) {
+				oldPath := e.Labelled("initial").Path
+				wt, err := e.Service.RenameWorktree(e.ID("initial"), "login-page")
+				require.NoError(e.t, err)
+
+				assert.Equal(e.t, e.ID("initial"), wt.ID)
+				assert.Equal(e.t, "demo/login-page", wt.Name)
+				assert.Equal(e.t, filepath.Join(e.WorkspaceDir, "demo", "login-page"), wt.Path)
+				assert.Equal(e.t, "refs/catnip/login-page", wt.Branch)
+				assert.Equal(e.t, "refs/catnip/login-page", e.Git(wt.Path, "symbolic-ref", "HEAD"))
+				assert.FileExists(e.t, filepath.Join(wt.Path, "login.txt"))
+				assert.NoDirExists(e.t, oldPath)
+
+				target, err := os.Readlink(filepath.Join(e.WorkspaceDir, "current"))
+				require.NoError(e.t, err)
+				assert.Equal(e.t, wt.Path, target)
+			}},
+			{"rename after graduation", func(e *Env) {
+				path := e.Labelled("initial").Path
+				e.Git(path, "update-ref", "refs/heads/feature/login", "HEAD")
+				e.Git(livePath, "config", "catnip.branch-map.refs.catnip.login-page", "feature/login")
+				require.NoError(e.t, e.Service.UpdateWorktreeFields(e.ID("initial"), map[string]interface{}{"branch": "feature/login"}))
+
+				wt, err := e.Service.RenameWorktree(e.ID("initial"), "login-v2")
+				require.NoError(e.t, err)
+				assert.Equal(e.t, "feature/login", wt.Branch, "graduated branches keep their name")
+				assert.Equal(e.t, "refs/catnip/login-v2", e.Git(wt.Path, "symbolic-ref", "HEAD"))
+				assert.Equal(e.t, "feature/login", e.Git(livePath, "config", "catnip.branch-map.refs.catnip.login-v2"))
+				_, err = e.tryGit(livePath, "config", "catnip.branch-map.refs.catnip.login-page")
+				assert.Error(e.t, err, "the mapping of the old ref is removed")
+			}},
+			{"refusals", func(e *Env) {
+				for _, name := range []string{"", "../escape", "a/b", ".hidden", "x.lock"} {
+					_, err := e.Service.RenameWorktree(e.ID("initial"), name)
+					assert.ErrorContains(e.t, err, "invalid worktree name", name)
+				}
+
+				_, err := e.Service.RenameWorktree(e.ID("initial"), filepath.Base(e.Labelled("other").Path))
+				assert.ErrorContains(e.t, err, "already exists")
+
+				e.Service.SetWorktreeSessionChecker(busyWorktrees{})
+				_, err = e.Service.RenameWorktree(e.ID("initial"), "login-v3")
+				assert.ErrorContains(e.t, err, "while a terminal session is running in it")
+				e.Service.SetWorktreeSessionChecker(nil)
+
+				assert.Equal(e.t, "demo/login-v2", e.Labelled("initial").Name)
+			}},
+		},
+	})
+}
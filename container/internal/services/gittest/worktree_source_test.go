@@ -0,0 +1,68 @@
+package gittest
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestWorktreeSource moves a worktree created from main onto a release branch, then checks the
+// counts, diff and conflict check follow the new base and the change is refused mid-merge
+func TestWorktreeSource(t *testing.T) {
+	Run(t, Scenario{
+		Name: "worktree_source",
+		Setup: func(e *Env) {
+			livePath := e.CreateLiveRepo("demo", map[string]string{"README.md": "# demo\n"})
+			e.Git(livePath, "checkout", "-b", "release")
+			e.CommitFile(livePath, "CHANGELOG.md", "# 1.0\n", "Prepare release")
+			e.CommitFile(livePath, "VERSION", "1.0\n", "Bump version")
+			e.Git(livePath, "checkout", "main")
+		},
+		Steps: []Step{
+			{"checkout from main", func(e *Env) {
+				e.Checkout("login", "demo", "main")
+				e.Checkpoint("login", "login.txt", "login\n", "Add login page")
+				require.NoError(e.t, e.Service.RefreshWorktreeStatusByID(e.ID("login")))
+				assert.Equal(e.t, 0, e.Labelled("login").CommitsBehind)
+			}},
+			{"move onto release", func(e *Env) {
+				worktree, err := e.Service.SetWorktreeSourceBranch(e.ID("login"), "release")
+				require.NoError(e.t, err)
+				assert.Equal(e.t, "release", worktree.SourceBranch)
+				assert.Equal(e.t, 1, worktree.CommitCount)
+				assert.Equal(e.t, 2, worktree.CommitsBehind)
+
+				diff, err := e.Service.GetWorktreeDiff(e.ID("login"))
+				require.NoError(e.t, err)
+				assert.Equal(e.t, "release", diff.SourceBranch)
+				require.Len(e.t, diff.FileDiffs, 1)
+				assert.Equal(e.t, "login.txt", diff.FileDiffs[0].FilePath)
+
+				conflict, err := e.Service.CheckSyncConflicts(e.ID("login"))
+				require.NoError(e.t, err)
+				assert.Nil(e.t, conflict)
+			}},
+			{"sync onto release", func(e *Env) {
+				require.Nil(e.t, e.Sync("login", "rebase"))
+				assert.FileExists(e.t, filepath.Join(e.Labelled("login").Path, "VERSION"))
+				require.NoError(e.t, e.Service.RefreshWorktreeStatusByID(e.ID("login")))
+				assert.Equal(e.t, 0, e.Labelled("login").CommitsBehind)
+			}},
+			{"refused while merging", func(e *Env) {
+				e.CommitFile(filepath.Join(e.LiveDir, "demo"), "NOTES.md", "notes\n", "Add notes")
+				path := e.Labelled("login").Path
+				e.Git(path, "merge", "--no-commit", "--no-ff", "main")
+				_, err := e.Service.SetWorktreeSourceBranch(e.ID("login"), "main")
+				assert.ErrorContains(e.t, err, "merge or rebase is in progress")
+				e.Git(path, "merge", "--abort")
+				assert.Equal(e.t, "release", e.Labelled("login").SourceBranch)
+			}},
+			{"unknown branch", func(e *Env) {
+				_, err := e.Service.SetWorktreeSourceBranch(e.ID("login"), "hotfix")
+				assert.ErrorContains(e.t, err, "invalid source branch hotfix")
+			}},
+		},
+	})
+}
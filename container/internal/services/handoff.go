@@ -0,0 +1,104 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// HandoffOptions configures handing a worktree off to a human
+type HandoffOptions struct {
+	// Who is taking the worktree over
+	Actor string
+	// Create or update the preview branch of a local repository for the human to check out
+	Preview bool
+	// Confirms force pushing an existing preview branch of a protected repository
+	Confirm bool
+}
+
+// SetWorktreeHandedOff hands a worktree off to a human, or gives it back to catnip. Handing off
+// commits a final checkpoint of the uncommitted work first, then suspends checkpoints, automatic
+// branch renames and commit syncing, and ignores Claude's title events, so none of it fights
+// manual edits. Operations triggered through the API keep working. Clearing the handoff resumes
+// automation with fresh timers and a refreshed status. Returns the handoff, nil once cleared.
+func (s *GitService) SetWorktreeHandedOff(worktreeID string, handedOff bool, opts HandoffOptions) (*models.WorktreeHandoff, error) {
+	s.mu.RLock()
+	worktree, exists := s.stateManager.GetWorktree(worktreeID)
+	s.mu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("worktree %s not found", worktreeID)
+	}
+	if !handedOff {
+		return nil, s.resumeWorktreeAutomation(worktree, opts.Actor)
+	}
+	if worktree.HandedOff != nil {
+		return worktree.HandedOff, nil
+	}
+	if opts.Preview && !s.isLocalRepo(worktree.RepoID) {
+		return nil, fmt.Errorf("preview branches are only supported for local repositories")
+	}
+
+	handoff := &models.WorktreeHandoff{By: NormalizeActor(opts.Actor), At: time.Now()}
+	if s.operations.IsDirty(worktree.Path) {
+		hash, err := s.GitAddCommitGetHash(worktree.Path, handoffCheckpointMessage(worktree))
+		s.RecordCheckpointError(worktree.Path, err)
+		if err != nil && !isCheckpointHookError(err) {
+			return nil, fmt.Errorf("failed to commit final checkpoint: %v", err)
+		}
+		handoff.Checkpoint = hash
+	}
+	if opts.Preview {
+		if err := s.CreateWorktreePreview(worktreeID, opts.Confirm); err != nil {
+			return nil, err
+		}
+		handoff.PreviewBranch = worktreePreviewBranch(worktree)
+	}
+
+	if err := s.stateManager.UpdateWorktree(worktreeID, map[string]interface{}{
+		"handed_off": handoff,
+	}); err != nil {
+		return nil, err
+	}
+	// Checkpoints are disabled while handed off, which stops the timer
+	if s.claudeMonitor != nil {
+		s.claudeMonitor.ApplyCheckpointConfig(worktree.Path)
+	}
+	s.stateManager.recordWorktreeActivity(ActivityHandedOff, worktree, handoff.By, "handed off, automation suspended", "")
+	logger.Infof("🤝 Handed %s off to %s, automation suspended", worktree.Name, handoff.By)
+	return handoff, nil
+}
+
+// resumeWorktreeAutomation clears the handoff of a worktree and restarts its automation from a
+// clean slate
+func (s *GitService) resumeWorktreeAutomation(worktree *models.Worktree, actor string) error {
+	if worktree.HandedOff == nil {
+		return nil
+	}
+	if err := s.stateManager.UpdateWorktree(worktree.ID, map[string]interface{}{
+		"handed_off": (*models.WorktreeHandoff)(nil),
+	}); err != nil {
+		return err
+	}
+	if s.claudeMonitor != nil {
+		s.claudeMonitor.ResetWorktreeAutomation(worktree.Path)
+	}
+	if err := s.RefreshWorktreeStatus(worktree.Path); err != nil {
+		logger.Debugf("🔍 Not refreshing status of %s: %v", worktree.Path, err)
+	}
+	s.stateManager.recordWorktreeActivity(ActivityAutomationResumed, worktree, NormalizeActor(actor), "handed back, automation resumed", "")
+	logger.Infof("🤖 Resumed automation for %s", worktree.Name)
+	return nil
+}
+
+// handoffCheckpointMessage is the commit message of the checkpoint taken when handing off
+func handoffCheckpointMessage(worktree *models.Worktree) string {
+	if worktree.SessionTitle != nil {
+		if title := SanitizeTitle(worktree.SessionTitle.Title); title != "" {
+			return title + " (handoff checkpoint)"
+		}
+	}
+	return "Handoff checkpoint"
+}
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+func TestSetWorktreeHandedOff(t *testing.T) {
+	s, stateManager, repoPath, worktreePath := newRecreateTestService(t)
+	require.NoError(t, stateManager.AddRepository(&models.Repository{ID: "local/repo", Path: repoPath, Available: true}))
+	require.NoError(t, stateManager.UpdateWorktree("wt-felix", map[string]interface{}{
+		"session_title": &models.TitleEntry{Title: "Add felix mode"},
+	}))
+	require.NoError(t, os.WriteFile(filepath.Join(worktreePath, "app.txt"), []byte("v2\n"), 0644))
+
+	handoff, err := s.SetWorktreeHandedOff("wt-felix", true, HandoffOptions{Actor: "alice", Preview: true})
+	require.NoError(t, err)
+	assert.Equal(t, "alice", handoff.By)
+	assert.False(t, handoff.At.IsZero())
+
+	t.Run("CommitsFinalCheckpoint", func(t *testing.T) {
+		assert.Equal(t, runTestGit(t, worktreePath, "rev-parse", "HEAD"), handoff.Checkpoint)
+		assert.Equal(t, "Add felix mode (handoff checkpoint)", runTestGit(t, worktreePath, "log", "-1", "--format=%s"))
+		assert.Empty(t, runTestGit(t, worktreePath, "status", "--porcelain"))
+	})
+
+	t.Run("CreatesPreviewBranch", func(t *testing.T) {
+		worktree, _ := stateManager.GetWorktree("wt-felix")
+		assert.Equal(t, worktreePreviewBranch(worktree), handoff.PreviewBranch)
+		assert.Equal(t, handoff.Checkpoint, runTestGit(t, repoPath, "rev-parse", "refs/heads/"+handoff.PreviewBranch))
+	})
+
+	t.Run("SuspendsAutomation", func(t *testing.T) {
+		worktree, _ := stateManager.GetWorktree("wt-felix")
+		assert.Equal(t, handoff, worktree.HandedOff)
+
+		manager := &WorktreeCheckpointManager{workDir: worktreePath, worktreeID: "wt-felix", stateManager: stateManager}
+		assert.False(t, manager.checkpointsEnabled())
+		outcome := manager.graduateBranch("Add felix mode", BranchRenameTriggerTitle)
+		assert.Equal(t, BranchRenameSkipped, outcome.Outcome)
+		assert.Equal(t, RenameReasonHandedOff, outcome.Reason)
+	})
+
+	t.Run("HandingOffAgainKeepsHandoff", func(t *testing.T) {
+		again, err := s.SetWorktreeHandedOff("wt-felix", true, HandoffOptions{Actor: "bob"})
+		require.NoError(t, err)
+		assert.Equal(t, "alice", again.By)
+	})
+
+	t.Run("HandingBackResumesAutomation", func(t *testing.T) {
+		cleared, err := s.SetWorktreeHandedOff("wt-felix", false, HandoffOptions{Actor: "alice"})
+		require.NoError(t, err)
+		assert.Nil(t, cleared)
+
+		worktree, _ := stateManager.GetWorktree("wt-felix")
+		assert.Nil(t, worktree.HandedOff)
+		manager := &WorktreeCheckpointManager{workDir: worktreePath, worktreeID: "wt-felix", stateManager: stateManager}
+		assert.True(t, manager.checkpointsEnabled())
+	})
+
+	t.Run("PreviewNeedsLocalRepository", func(t *testing.T) {
+		require.NoError(t, stateManager.AddRepository(&models.Repository{ID: "owner/repo", Path: repoPath}))
+		require.NoError(t, stateManager.AddWorktree(&models.Worktree{
+			ID: "wt-remote", RepoID: "owner/repo", Name: "repo/remote", Path: worktreePath, Branch: "feature/felix", SourceBranch: "main",
+		}))
+		_, err := s.SetWorktreeHandedOff("wt-remote", true, HandoffOptions{Preview: true})
+		assert.ErrorContains(t, err, "only supported for local repositories")
+	})
+}
@@ -0,0 +1,329 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/vanpelt/catnip/internal/git"
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+const (
+	// noiseMinCheckpoints is how many consecutive checkpoints must commit files of a
+	// directory before ignoring it is suggested
+	noiseMinCheckpoints = 3
+	// noiseMinFiles is how many files of a directory a checkpoint must commit to count
+	noiseMinFiles = 20
+	// catnipIgnoreHeader marks the patterns catnip added to the info/exclude of a repository it
+	// cloned
+	catnipIgnoreHeader = "# .catnipignore: added by catnip"
+)
+
+// IgnoreTarget is where an ignore suggestion's pattern is written
+type IgnoreTarget string
+
+const (
+	// IgnoreInCatnip adds the pattern to the info/exclude of the bare repository catnip cloned,
+	// which is never committed (the default). Local repositories share info/exclude with the
+	// user's own checkout, so it is refused for them.
+	IgnoreInCatnip IgnoreTarget = "catnipignore"
+	// IgnoreInGitignore appends the pattern to the worktree's .gitignore and commits it
+	IgnoreInGitignore IgnoreTarget = "gitignore"
+)
+
+// checkpointNoiseTracker counts, per worktree, the files each top-level directory
+// contributed to consecutive checkpoints
+type checkpointNoiseTracker struct {
+	mu        sync.Mutex
+	streaks   map[string]map[string]*models.IgnoreSuggestion // workDir -> directory -> streak
+	dismissed map[string]map[string]bool                     // workDir -> dismissed directories
+}
+
+// record adds a checkpoint's files per top-level directory and returns the suggestions for
+// directories that reached noiseMinCheckpoints consecutive checkpoints, sorted by pattern.
+// Directories missing from the checkpoint, or with too few files, start over.
+func (t *checkpointNoiseTracker) record(workDir string, counts map[string]int) []models.IgnoreSuggestion {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.streaks == nil {
+		t.streaks = make(map[string]map[string]*models.IgnoreSuggestion)
+	}
+	streaks := t.streaks[workDir]
+	if streaks == nil {
+		streaks = make(map[string]*models.IgnoreSuggestion)
+		t.streaks[workDir] = streaks
+	}
+
+	for dir := range streaks {
+		if counts[dir] < noiseMinFiles {
+			delete(streaks, dir)
+		}
+	}
+
+	var suggestions []models.IgnoreSuggestion
+	for dir, files := range counts {
+		if files < noiseMinFiles || t.dismissed[workDir][dir] {
+			continue
+		}
+		streak := streaks[dir]
+		if streak == nil {
+			streak = &models.IgnoreSuggestion{Pattern: dir + "/"}
+			streaks[dir] = streak
+		}
+		streak.Files += files
+		streak.Checkpoints++
+		if streak.Checkpoints >= noiseMinCheckpoints {
+			suggestions = append(suggestions, *streak)
+		}
+	}
+
+	sort.Slice(suggestions, func(i, j int) bool { return suggestions[i].Pattern < suggestions[j].Pattern })
+	return suggestions
+}
+
+// forget drops the streak of a directory, and keeps suggesting it unless dismissed
+func (t *checkpointNoiseTracker) forget(workDir, dir string, dismiss bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.streaks[workDir], dir)
+	if dismiss {
+		if t.dismissed == nil {
+			t.dismissed = make(map[string]map[string]bool)
+		}
+		if t.dismissed[workDir] == nil {
+			t.dismissed[workDir] = make(map[string]bool)
+		}
+		t.dismissed[workDir][dir] = true
+	}
+}
+
+// stagedFilesPerDirectory counts the files staged by `git add --verbose` per top-level
+// directory, from lines like "add 'dist/app.js'". Files in the worktree root are skipped.
+func stagedFilesPerDirectory(addOutput []byte) map[string]int {
+	counts := make(map[string]int)
+	for _, line := range strings.Split(string(addOutput), "\n") {
+		path, found := strings.CutPrefix(line, "add '")
+		if !found {
+			continue
+		}
+		path = strings.TrimSuffix(path, "'")
+		if dir, _, nested := strings.Cut(path, "/"); nested {
+			counts[dir]++
+		}
+	}
+	return counts
+}
+
+// recordCheckpointNoise updates the ignore suggestions of the worktree at workDir from the
+// output of the checkpoint's `git add`. Only directories absent from the source branch are
+// suggested, so regular source directories that change a lot never are.
+func (s *GitService) recordCheckpointNoise(workDir string, addOutput []byte) {
+	if s.stateManager == nil {
+		return
+	}
+	worktree, _ := s.stateManager.FindWorktreeByPath(workDir)
+	if worktree == nil {
+		return
+	}
+
+	candidates := s.checkpointNoise.record(workDir, stagedFilesPerDirectory(addOutput))
+	suggestions := []models.IgnoreSuggestion{}
+	if len(candidates) > 0 {
+		sourceRef := s.getSourceRef(worktree)
+		for _, suggestion := range candidates {
+			dir := strings.TrimSuffix(suggestion.Pattern, "/")
+			if _, err := s.operations.ExecuteGit(workDir, "cat-file", "-e", sourceRef+":"+dir); err == nil {
+				continue // Part of the source branch
+			}
+			suggestions = append(suggestions, suggestion)
+		}
+	}
+
+	if len(suggestions) == 0 && len(worktree.IgnoreSuggestions) == 0 {
+		return
+	}
+	if len(suggestions) > len(worktree.IgnoreSuggestions) {
+		for _, suggestion := range suggestions {
+			logger.Infof("🙈 Consider ignoring %s in %s: %d files committed across %d checkpoints", suggestion.Pattern, worktree.Name, suggestion.Files, suggestion.Checkpoints)
+		}
+	}
+	if err := s.stateManager.UpdateWorktree(worktree.ID, map[string]interface{}{"ignore_suggestions": suggestions}); err != nil {
+		logger.Warnf("⚠️  Failed to update ignore suggestions for %s: %v", worktree.Name, err)
+	}
+}
+
+// ApplyIgnoreSuggestion ignores a suggested directory and stops tracking its files. The
+// pattern goes to the info/exclude of a repository catnip cloned (catnipignore) or the
+// worktree's .gitignore.
+// With squash, the branch's commits since the source branch are squashed into one so the
+// directory also disappears from the branch history; worktrees with a pull request are
+// refused, as that would rewrite published history.
+func (s *GitService) ApplyIgnoreSuggestion(worktreeID, pattern string, target IgnoreTarget, squash bool) (*models.Worktree, *models.OperationBackup, error) {
+	worktree, suggestion, err := s.findIgnoreSuggestion(worktreeID, pattern)
+	if err != nil {
+		return nil, nil, err
+	}
+	if target == "" {
+		target = IgnoreInCatnip
+	}
+	if target != IgnoreInCatnip && target != IgnoreInGitignore {
+		return nil, nil, fmt.Errorf("unknown ignore target %q, expected catnipignore or gitignore", target)
+	}
+	if target == IgnoreInCatnip && s.isLocalRepo(worktree.RepoID) {
+		return nil, nil, fmt.Errorf("%s is a local repository whose info/exclude belongs to your own checkout, use the gitignore target instead", worktree.RepoID)
+	}
+	if squash && worktree.PullRequestURL != "" {
+		return nil, nil, fmt.Errorf("worktree %s has a pull request, squashing would rewrite its published history", worktree.Name)
+	}
+	dir := strings.TrimSuffix(suggestion.Pattern, "/")
+
+	var mergeBase string
+	var backup *models.OperationBackup
+	if squash {
+		output, err := s.operations.ExecuteGit(worktree.Path, "merge-base", "HEAD", s.getSourceRef(worktree))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to find where the branch started: %v", err)
+		}
+		mergeBase = strings.TrimSpace(string(output))
+		if backup, err = s.backupBeforeRewrite(worktree, "squash without "+suggestion.Pattern); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	ignoreFile := filepath.Join(worktree.Path, ".gitignore")
+	header := ""
+	if target == IgnoreInCatnip {
+		if ignoreFile, err = s.infoExcludePath(worktree); err != nil {
+			return nil, nil, err
+		}
+		header = catnipIgnoreHeader
+	}
+	if err := appendIgnorePattern(ignoreFile, header, "/"+suggestion.Pattern); err != nil {
+		return nil, nil, err
+	}
+
+	message := fmt.Sprintf("Stop tracking %s", suggestion.Pattern)
+	if squash {
+		if _, err := s.operations.ExecuteGit(worktree.Path, "reset", "--soft", mergeBase); err != nil {
+			return nil, nil, fmt.Errorf("failed to squash the branch: %v", err)
+		}
+		message = fmt.Sprintf("Squash %s without %s", git.ExtractWorkspaceName(worktree.Branch), suggestion.Pattern)
+		if worktree.SessionTitle != nil && worktree.SessionTitle.Title != "" {
+			message = worktree.SessionTitle.Title
+		}
+	}
+	if _, err := s.operations.ExecuteGit(worktree.Path, "rm", "-r", "--cached", "--quiet", "--ignore-unmatch", "--", dir); err != nil {
+		return nil, nil, fmt.Errorf("failed to stop tracking %s: %v", suggestion.Pattern, err)
+	}
+	hash, err := s.GitAddCommitGetHash(worktree.Path, message)
+	if err != nil && !isCheckpointHookError(err) {
+		return nil, nil, err
+	}
+
+	s.checkpointNoise.forget(worktree.Path, dir, true)
+	if err := s.stateManager.UpdateWorktree(worktreeID, map[string]interface{}{
+		"ignore_suggestions": withoutIgnoreSuggestion(worktree.IgnoreSuggestions, suggestion.Pattern),
+	}); err != nil {
+		return nil, nil, err
+	}
+	_ = s.RefreshWorktreeStatus(worktree.Path)
+
+	logger.Infof("🙈 Ignored %s in %s via %s (commit %s, squashed: %v)", suggestion.Pattern, worktree.Name, target, hash, squash)
+	return worktree, backup, nil
+}
+
+// DismissIgnoreSuggestion hides a suggestion; the directory is not suggested again for the worktree
+func (s *GitService) DismissIgnoreSuggestion(worktreeID, pattern string) error {
+	worktree, suggestion, err := s.findIgnoreSuggestion(worktreeID, pattern)
+	if err != nil {
+		return err
+	}
+	s.checkpointNoise.forget(worktree.Path, strings.TrimSuffix(suggestion.Pattern, "/"), true)
+	return s.stateManager.UpdateWorktree(worktreeID, map[string]interface{}{
+		"ignore_suggestions": withoutIgnoreSuggestion(worktree.IgnoreSuggestions, suggestion.Pattern),
+	})
+}
+
+// findIgnoreSuggestion returns a worktree and its suggestion for pattern
+func (s *GitService) findIgnoreSuggestion(worktreeID, pattern string) (*models.Worktree, models.IgnoreSuggestion, error) {
+	worktree, exists := s.stateManager.GetWorktree(worktreeID)
+	if !exists {
+		return nil, models.IgnoreSuggestion{}, fmt.Errorf("worktree %s not found", worktreeID)
+	}
+	for _, suggestion := range worktree.IgnoreSuggestions {
+		if suggestion.Pattern == pattern {
+			return worktree, suggestion, nil
+		}
+	}
+	return nil, models.IgnoreSuggestion{}, fmt.Errorf("worktree %s has no ignore suggestion for %s", worktree.Name, pattern)
+}
+
+// withoutIgnoreSuggestion returns the suggestions except the one for pattern
+func withoutIgnoreSuggestion(suggestions []models.IgnoreSuggestion, pattern string) []models.IgnoreSuggestion {
+	result := []models.IgnoreSuggestion{}
+	for _, suggestion := range suggestions {
+		if suggestion.Pattern != pattern {
+			result = append(result, suggestion)
+		}
+	}
+	return result
+}
+
+// infoExcludePath returns the info/exclude file of a worktree's repository, whose patterns are
+// never committed. Only write it for repositories catnip cloned.
+func (s *GitService) infoExcludePath(worktree *models.Worktree) (string, error) {
+	output, err := s.operations.ExecuteGit(worktree.Path, "rev-parse", "--git-path", "info/exclude")
+	if err != nil {
+		return "", fmt.Errorf("failed to locate info/exclude: %v", err)
+	}
+	path := strings.TrimSpace(string(output))
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(worktree.Path, path)
+	}
+	return path, nil
+}
+
+// appendIgnorePattern adds pattern to an ignore file unless it is already listed, writing
+// header first when the file doesn't contain it yet
+func appendIgnorePattern(path, header, pattern string) error {
+	content, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read %s: %v", path, err)
+	}
+
+	lines := strings.Split(string(content), "\n")
+	for _, line := range lines {
+		if strings.TrimSpace(line) == pattern {
+			return nil
+		}
+	}
+
+	var addition strings.Builder
+	if len(content) > 0 && !strings.HasSuffix(string(content), "\n") {
+		addition.WriteString("\n")
+	}
+	if header != "" && !strings.Contains(string(content), header) {
+		addition.WriteString(header + "\n")
+	}
+	addition.WriteString(pattern + "\n")
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %v", filepath.Dir(path), err)
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %v", path, err)
+	}
+	defer file.Close()
+	if _, err := file.WriteString(addition.String()); err != nil {
+		return fmt.Errorf("failed to write %s: %v", path, err)
+	}
+	return nil
+}
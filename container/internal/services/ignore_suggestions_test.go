@@ -0,0 +1,170 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+func TestStagedFilesPerDirectory(t *testing.T) {
+	output := []byte("add 'dist/app.js'\nadd 'dist/chunks/a.js'\nadd 'README.md'\nremove 'src/old.go'\nadd 'src/main.go'\n")
+	assert.Equal(t, map[string]int{"dist": 2, "src": 1}, stagedFilesPerDirectory(output))
+	assert.Empty(t, stagedFilesPerDirectory(nil))
+}
+
+func TestCheckpointNoiseTracker(t *testing.T) {
+	var tracker checkpointNoiseTracker
+	noisy := map[string]int{"dist": 25, "src": 3}
+
+	assert.Empty(t, tracker.record("/w", noisy))
+	assert.Empty(t, tracker.record("/w", noisy))
+	assert.Equal(t, []models.IgnoreSuggestion{{Pattern: "dist/", Files: 75, Checkpoints: 3}}, tracker.record("/w", noisy))
+
+	// A quiet checkpoint breaks the streak
+	assert.Empty(t, tracker.record("/w", map[string]int{"dist": 2}))
+	assert.Empty(t, tracker.record("/w", noisy))
+	assert.Empty(t, tracker.record("/other", noisy), "streaks are per worktree")
+
+	tracker.forget("/w", "dist", true)
+	for i := 0; i < noiseMinCheckpoints; i++ {
+		assert.Empty(t, tracker.record("/w", noisy), "dismissed directories are not suggested again")
+	}
+}
+
+// setupIgnoreSuggestionRepo creates repository repoID whose feature branch is checked out as
+// worktree wt-felix
+func setupIgnoreSuggestionRepo(t *testing.T, repoID string) (*GitService, string) {
+	root := t.TempDir()
+	repoPath := initTestRepo(t, filepath.Join(root, "repo"), map[string]string{"src/main.go": "package main\n"})
+	runTestGit(t, repoPath, "checkout", "-b", "feature")
+
+	s := newTestGitService(t, root)
+	require.NoError(t, s.stateManager.AddRepository(&models.Repository{ID: repoID, Path: repoPath}))
+	require.NoError(t, s.stateManager.AddWorktree(&models.Worktree{
+		ID: "wt-felix", RepoID: repoID, Name: "felix", Path: repoPath, Branch: "feature", SourceBranch: "main",
+	}))
+
+	return s, repoPath
+}
+
+// writeCheckpoint writes 20 files into dist/ and src/ and commits them as a checkpoint
+func writeCheckpoint(t *testing.T, s *GitService, repoPath string, n int) {
+	for _, dir := range []string{"dist", "src"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(repoPath, dir), 0755))
+		for i := 0; i < noiseMinFiles; i++ {
+			name := filepath.Join(repoPath, dir, fmt.Sprintf("file-%d-%d.txt", n, i))
+			require.NoError(t, os.WriteFile(name, []byte("generated\n"), 0644))
+		}
+	}
+	_, err := s.GitAddCommitGetHash(repoPath, fmt.Sprintf("checkpoint %d", n))
+	require.NoError(t, err)
+}
+
+func TestApplyIgnoreSuggestion(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	s, repoPath := setupIgnoreSuggestionRepo(t, "acme/repo")
+	for n := 1; n <= noiseMinCheckpoints; n++ {
+		worktree, _ := s.stateManager.GetWorktree("wt-felix")
+		assert.Empty(t, worktree.IgnoreSuggestions)
+		writeCheckpoint(t, s, repoPath, n)
+	}
+
+	worktree, _ := s.stateManager.GetWorktree("wt-felix")
+	require.Equal(t, []models.IgnoreSuggestion{{Pattern: "dist/", Files: 60, Checkpoints: 3}}, worktree.IgnoreSuggestions,
+		"src/ exists on the source branch and is never suggested")
+
+	_, _, err := s.ApplyIgnoreSuggestion("wt-felix", "dist/", "vendor", false)
+	assert.Error(t, err)
+	_, _, err = s.ApplyIgnoreSuggestion("wt-felix", "build/", IgnoreInCatnip, false)
+	assert.Error(t, err)
+
+	worktree, backup, err := s.ApplyIgnoreSuggestion("wt-felix", "dist/", "", false)
+	require.NoError(t, err)
+	assert.Nil(t, backup, "only squashing rewrites history")
+	assert.Empty(t, worktree.IgnoreSuggestions)
+
+	exclude, err := os.ReadFile(filepath.Join(repoPath, ".git", "info", "exclude"))
+	require.NoError(t, err)
+	assert.Contains(t, string(exclude), catnipIgnoreHeader+"\n/dist/\n")
+	assert.Empty(t, runTestGit(t, repoPath, "ls-files", "dist"))
+	assert.Empty(t, runTestGit(t, repoPath, "status", "--porcelain"))
+	assert.FileExists(t, filepath.Join(repoPath, "dist", "file-1-0.txt"), "ignored files stay on disk")
+	assert.Equal(t, "Stop tracking dist/", runTestGit(t, repoPath, "log", "-1", "--format=%s"))
+
+	// Applying the same pattern again doesn't duplicate it
+	require.NoError(t, appendIgnorePattern(filepath.Join(repoPath, ".git", "info", "exclude"), catnipIgnoreHeader, "/dist/"))
+	exclude, _ = os.ReadFile(filepath.Join(repoPath, ".git", "info", "exclude"))
+	assert.Equal(t, 1, strings.Count(string(exclude), "/dist/"))
+}
+
+func TestApplyIgnoreSuggestionSquash(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	s, repoPath := setupIgnoreSuggestionRepo(t, "acme/repo")
+	for n := 1; n <= noiseMinCheckpoints; n++ {
+		writeCheckpoint(t, s, repoPath, n)
+	}
+
+	require.NoError(t, s.stateManager.UpdateWorktree("wt-felix", map[string]interface{}{"pull_request_url": "https://github.com/acme/felix/pull/1"}))
+	_, _, err := s.ApplyIgnoreSuggestion("wt-felix", "dist/", IgnoreInGitignore, true)
+	assert.ErrorContains(t, err, "pull request")
+	require.NoError(t, s.stateManager.UpdateWorktree("wt-felix", map[string]interface{}{"pull_request_url": ""}))
+
+	head := runTestGit(t, repoPath, "rev-parse", "HEAD")
+	_, backup, err := s.ApplyIgnoreSuggestion("wt-felix", "dist/", IgnoreInGitignore, true)
+	require.NoError(t, err)
+	require.NotNil(t, backup, "squashing backs up the branch first")
+	assert.Equal(t, head, runTestGit(t, repoPath, "rev-parse", backup.Ref))
+
+	assert.Equal(t, "1", runTestGit(t, repoPath, "rev-list", "--count", "main..HEAD"))
+	assert.Equal(t, "Squash feature without dist/", runTestGit(t, repoPath, "log", "-1", "--format=%s"))
+	assert.Empty(t, runTestGit(t, repoPath, "log", "--format=%H", "main..HEAD", "--", "dist"), "dist/ left the branch history")
+	assert.Equal(t, "/dist/", runTestGit(t, repoPath, "show", "HEAD:.gitignore"))
+	assert.Empty(t, runTestGit(t, repoPath, "status", "--porcelain"))
+
+	// Dismissing forgets the directory for good
+	require.NoError(t, s.stateManager.UpdateWorktree("wt-felix", map[string]interface{}{
+		"ignore_suggestions": []models.IgnoreSuggestion{{Pattern: "build/", Files: 20, Checkpoints: 3}},
+	}))
+	require.NoError(t, s.DismissIgnoreSuggestion("wt-felix", "build/"))
+	worktree, _ := s.stateManager.GetWorktree("wt-felix")
+	assert.Empty(t, worktree.IgnoreSuggestions)
+	assert.Error(t, s.DismissIgnoreSuggestion("wt-felix", "build/"))
+}
+
+func TestIgnoreInCatnipLeavesLocalRepositoriesAlone(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	s, repoPath := setupIgnoreSuggestionRepo(t, "local/repo")
+	for n := 1; n <= noiseMinCheckpoints; n++ {
+		writeCheckpoint(t, s, repoPath, n)
+	}
+	excludePath := filepath.Join(repoPath, ".git", "info", "exclude")
+	before, _ := os.ReadFile(excludePath)
+
+	_, _, err := s.ApplyIgnoreSuggestion("wt-felix", "dist/", "", false)
+	assert.ErrorContains(t, err, "use the gitignore target")
+
+	worktree, _ := s.stateManager.GetWorktree("wt-felix")
+	path, err := s.writeContextPack(worktree, "# Context\n")
+	require.NoError(t, err)
+	assert.FileExists(t, path)
+	assert.Empty(t, runTestGit(t, repoPath, "status", "--porcelain", "--untracked-files=all"), ".catnip/ ignores itself")
+
+	after, _ := os.ReadFile(excludePath)
+	assert.Equal(t, string(before), string(after), "the user's info/exclude is untouched")
+}
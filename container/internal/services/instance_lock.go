@@ -0,0 +1,30 @@
+package services
+
+import (
+	"errors"
+	"path/filepath"
+
+	"github.com/vanpelt/catnip/internal/config"
+)
+
+// ErrInstanceLocked is returned when the instance lock is held by someone else
+var ErrInstanceLocked = errors.New("volume is in use by another catnip instance")
+
+// InstanceLock is held by servers (shared) and maintenance commands (exclusive) using a volume,
+// so maintenance never runs while a server is changing the same state
+type InstanceLock struct {
+	release func()
+}
+
+// InstanceLockFile returns the path of the lock file of the current volume
+func InstanceLockFile() string {
+	return filepath.Join(config.Runtime.VolumeDir, "catnip.lock")
+}
+
+// Release gives up the lock
+func (l *InstanceLock) Release() {
+	if l != nil && l.release != nil {
+		l.release()
+		l.release = nil
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package services
+
+import "time"
+
+// AcquireInstanceLock is a no-op on platforms without flock; catnip only runs on unix systems
+func AcquireInstanceLock(path string, exclusive bool, wait time.Duration) (*InstanceLock, error) {
+	return &InstanceLock{}, nil
+}
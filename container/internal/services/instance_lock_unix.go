@@ -0,0 +1,58 @@
+//go:build unix
+
+package services
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/logger"
+)
+
+// instanceLockPollInterval is how often a blocked AcquireInstanceLock retries
+const instanceLockPollInterval = 200 * time.Millisecond
+
+// AcquireInstanceLock takes the lock at path, shared unless exclusive is set, waiting up to
+// wait for a conflicting holder to release it. The lock is released when the process exits.
+func AcquireInstanceLock(path string, exclusive bool, wait time.Duration) (*InstanceLock, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create lock directory: %v", err)
+	}
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %v", err)
+	}
+
+	how := syscall.LOCK_SH
+	if exclusive {
+		how = syscall.LOCK_EX
+	}
+	deadline := time.Now().Add(wait)
+	for waiting := false; ; waiting = true {
+		err := syscall.Flock(int(file.Fd()), how|syscall.LOCK_NB)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, syscall.EWOULDBLOCK) {
+			file.Close()
+			return nil, fmt.Errorf("failed to lock %s: %v", path, err)
+		}
+		if !time.Now().Before(deadline) {
+			file.Close()
+			return nil, fmt.Errorf("%w (%s is locked)", ErrInstanceLocked, path)
+		}
+		if !waiting {
+			logger.Infof("⏳ Waiting for another catnip instance to release %s", path)
+		}
+		time.Sleep(instanceLockPollInterval)
+	}
+
+	return &InstanceLock{release: func() {
+		_ = syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+		file.Close()
+	}}, nil
+}
@@ -0,0 +1,38 @@
+//go:build unix
+
+package services
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstanceLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "catnip.lock")
+
+	// Servers share the volume
+	server, err := AcquireInstanceLock(path, false, 0)
+	require.NoError(t, err)
+	other, err := AcquireInstanceLock(path, false, 0)
+	require.NoError(t, err)
+
+	_, err = AcquireInstanceLock(path, true, 0)
+	assert.ErrorIs(t, err, ErrInstanceLocked, "maintenance doesn't run next to a server")
+
+	server.Release()
+	other.Release()
+	other.Release()
+
+	maint, err := AcquireInstanceLock(path, true, 0)
+	require.NoError(t, err)
+	_, err = AcquireInstanceLock(path, false, 2*instanceLockPollInterval)
+	assert.ErrorIs(t, err, ErrInstanceLocked, "servers wait for maintenance to finish")
+	maint.Release()
+
+	server, err = AcquireInstanceLock(path, false, 0)
+	require.NoError(t, err)
+	server.Release()
+}
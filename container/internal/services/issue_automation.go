@@ -0,0 +1,334 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/git"
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+	"github.com/vanpelt/catnip/internal/recovery"
+)
+
+// issueAutomationInterval is how often repositories with issue automation are polled for
+// labeled issues; webhook deliveries trigger a poll in between
+const issueAutomationInterval = 2 * time.Minute
+
+// DefaultIssueAutomationLabel is the label requesting a worktree when none is configured
+const DefaultIssueAutomationLabel = "catnip"
+
+// Why automation stopped for an issue, reported in AutomatedIssue.Stopped
+const (
+	IssueStoppedUnlabeled = "unlabeled"
+	IssueStoppedClosed    = "closed"
+)
+
+// IssueAutomation returns a copy of a repository's issue automation settings and issue mapping
+func (wsm *WorktreeStateManager) IssueAutomation(repoID string) (*models.IssueAutomation, bool) {
+	wsm.mu.RLock()
+	defer wsm.mu.RUnlock()
+
+	repo, exists := wsm.repositories[repoID]
+	if !exists || repo.IssueAutomation == nil {
+		return nil, false
+	}
+	automation := *repo.IssueAutomation
+	automation.Issues = make(map[int]*models.AutomatedIssue, len(repo.IssueAutomation.Issues))
+	for number, issue := range repo.IssueAutomation.Issues {
+		copied := *issue
+		automation.Issues[number] = &copied
+	}
+	return &automation, true
+}
+
+// updateIssueAutomation applies update to the issue automation of a repository, which must be configured
+func (wsm *WorktreeStateManager) updateIssueAutomation(repoID string, update func(automation *models.IssueAutomation)) error {
+	return wsm.UpdateRepository(repoID, func(repo *models.Repository) {
+		if repo.IssueAutomation != nil {
+			update(repo.IssueAutomation)
+		}
+	})
+}
+
+// issueAutomationLock returns the mutex serializing polls of a repository, so the periodic
+// poll and webhook deliveries never create two worktrees for one issue
+func (s *GitService) issueAutomationLock(repoID string) *sync.Mutex {
+	lock, _ := s.issuePollLocks.LoadOrStore(repoID, &sync.Mutex{})
+	return lock.(*sync.Mutex)
+}
+
+// gitHubRepositoryOf returns the owner/repo of a repository on GitHub, empty when it has none.
+// Local repositories use their origin.
+func gitHubRepositoryOf(repo *models.Repository) string {
+	if !strings.HasPrefix(repo.ID, "local/") {
+		return repo.ID
+	}
+	if !repo.HasGitHubRemote {
+		return ""
+	}
+	if _, ownerRepo, found := strings.Cut(githubRepoIdentity(repo.RemoteOrigin), "github.com/"); found {
+		return ownerRepo
+	}
+	return ""
+}
+
+// SetIssueAutomation configures automatic worktree creation for issues of a repository carrying
+// a label. The issue mapping is kept, so re-enabling automation never duplicates worktrees.
+func (s *GitService) SetIssueAutomation(repoID string, settings models.IssueAutomation) (*models.IssueAutomation, error) {
+	repo, exists := s.stateManager.GetRepository(repoID)
+	if !exists {
+		return nil, fmt.Errorf("repository %s not found", repoID)
+	}
+	if gitHubRepositoryOf(repo) == "" {
+		return nil, fmt.Errorf("repository %s has no GitHub remote to watch issues of", repo.ID)
+	}
+	label := strings.TrimSpace(settings.Label)
+	if label == "" {
+		label = DefaultIssueAutomationLabel
+	}
+	branch := strings.TrimSpace(settings.Branch)
+
+	err := s.stateManager.UpdateRepository(repo.ID, func(repo *models.Repository) {
+		if repo.IssueAutomation == nil {
+			repo.IssueAutomation = &models.IssueAutomation{}
+		}
+		repo.IssueAutomation.Enabled = settings.Enabled
+		repo.IssueAutomation.Label = label
+		repo.IssueAutomation.Branch = branch
+	})
+	if err != nil {
+		return nil, err
+	}
+	automation, _ := s.stateManager.IssueAutomation(repo.ID)
+	logger.Infof("🏷️ Issue automation for %s: enabled=%v label=%s", repo.ID, settings.Enabled, label)
+	return automation, nil
+}
+
+// GetIssueAutomation returns the issue automation of a repository, disabled when it was never configured
+func (s *GitService) GetIssueAutomation(repoID string) (*models.IssueAutomation, error) {
+	repo, exists := s.stateManager.GetRepository(repoID)
+	if !exists {
+		return nil, fmt.Errorf("repository %s not found", repoID)
+	}
+	if automation, configured := s.stateManager.IssueAutomation(repo.ID); configured {
+		return automation, nil
+	}
+	return &models.IssueAutomation{Label: DefaultIssueAutomationLabel}, nil
+}
+
+// PollIssueAutomation creates worktrees for open issues carrying the repository's automation
+// label that don't have one yet, comments on them and emits worktree:from_issue events. Issues
+// that lost the label or were closed are marked stopped and never handled again; their
+// worktrees are kept. It returns the issues that got a worktree.
+func (s *GitService) PollIssueAutomation(repoID string) ([]*models.AutomatedIssue, error) {
+	repo, exists := s.stateManager.GetRepository(repoID)
+	if !exists {
+		return nil, fmt.Errorf("repository %s not found", repoID)
+	}
+	repoID = repo.ID
+	lock := s.issueAutomationLock(repoID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	automation, configured := s.stateManager.IssueAutomation(repoID)
+	if !configured || !automation.Enabled {
+		return nil, fmt.Errorf("issue automation is not enabled for %s", repoID)
+	}
+	ownerRepo := gitHubRepositoryOf(repo)
+
+	issues, listErr := s.githubManager.ListLabeledIssues(ownerRepo, automation.Label)
+	polledAt := time.Now()
+	if err := s.stateManager.updateIssueAutomation(repoID, func(automation *models.IssueAutomation) {
+		automation.LastPolledAt = &polledAt
+		automation.LastError = ""
+		if listErr != nil {
+			automation.LastError = listErr.Error()
+		}
+	}); err != nil {
+		return nil, err
+	}
+	if listErr != nil {
+		return nil, listErr
+	}
+
+	// Oldest issues first, so worktrees are created in the order issues were filed
+	sort.Slice(issues, func(i, j int) bool { return issues[i].Number < issues[j].Number })
+	labeled := make(map[int]bool, len(issues))
+	var linked []*models.AutomatedIssue
+	for _, issue := range issues {
+		labeled[issue.Number] = true
+		entry := automation.Issues[issue.Number]
+		if entry != nil && entry.Stopped != "" {
+			continue
+		}
+		if issue.State != "OPEN" {
+			if entry != nil {
+				s.stopIssueAutomation(repoID, entry, IssueStoppedClosed)
+			}
+			continue
+		}
+		if entry == nil {
+			entry = &models.AutomatedIssue{Number: issue.Number, Title: issue.Title, URL: issue.URL}
+		}
+		if s.automateIssue(repo, ownerRepo, automation.Branch, issue, entry) {
+			linked = append(linked, entry)
+		}
+	}
+
+	for _, entry := range automation.Issues {
+		if entry.Stopped == "" && !labeled[entry.Number] {
+			s.stopIssueAutomation(repoID, entry, IssueStoppedUnlabeled)
+		}
+	}
+	return linked, nil
+}
+
+// automateIssue creates the worktree of an issue unless it has one and posts the comment naming
+// it unless that was done, recording progress in the issue mapping. It reports whether a
+// worktree was created.
+func (s *GitService) automateIssue(repo *models.Repository, ownerRepo, branch string, issue git.GitHubIssue, entry *models.AutomatedIssue) bool {
+	created := false
+	entry.LastError = ""
+	if entry.WorktreeID == "" {
+		worktree, err := s.createIssueWorktree(repo, branch, issue)
+		if err != nil {
+			logger.Warnf("⚠️ Failed to create a worktree for issue %s#%d: %v", ownerRepo, issue.Number, err)
+			entry.LastError = err.Error()
+			s.saveAutomatedIssue(repo.ID, entry)
+			return false
+		}
+		linkedAt := time.Now()
+		entry.WorktreeID = worktree.ID
+		entry.WorktreeName = worktree.Name
+		entry.LinkedAt = &linkedAt
+		created = true
+		s.saveAutomatedIssue(repo.ID, entry)
+
+		logger.Infof("🏷️ Created worktree %s for issue %s#%d", worktree.Name, ownerRepo, issue.Number)
+		if s.eventsEmitter != nil {
+			copied := *entry
+			s.eventsEmitter.EmitWorktreeFromIssue(worktree, &copied)
+		}
+	}
+
+	if !entry.Commented {
+		body := fmt.Sprintf("Catnip created worktree `%s` for this issue.", entry.WorktreeName)
+		if err := s.githubManager.CommentOnIssue(ownerRepo, issue.Number, body); err != nil {
+			logger.Warnf("⚠️ Failed to comment on issue %s#%d: %v", ownerRepo, issue.Number, err)
+			entry.LastError = err.Error()
+		} else {
+			entry.Commented = true
+		}
+		s.saveAutomatedIssue(repo.ID, entry)
+	}
+	return created
+}
+
+// createIssueWorktree creates a worktree for an issue off the automation branch and links the
+// issue in its creation context, with the issue text as its initial prompt
+func (s *GitService) createIssueWorktree(repo *models.Repository, branch string, issue git.GitHubIssue) (*models.Worktree, error) {
+	if branch == "" {
+		branch = repo.DefaultBranch
+	}
+	prompt := issue.Title
+	if body := strings.TrimSpace(issue.Body); body != "" {
+		prompt += "\n\n" + body
+	}
+	org, name, _ := strings.Cut(repo.ID, "/")
+	_, worktree, err := s.CheckoutRepositoryWithCreation(org, name, branch, DefaultCloneOptions(), &models.CreationContext{
+		Source: models.CreationSourceAuto,
+		Prompt: prompt,
+		Issue:  issue.URL,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if worktree == nil {
+		return nil, fmt.Errorf("no worktree was created for %s", repo.ID)
+	}
+	return worktree, nil
+}
+
+// stopIssueAutomation marks an issue as no longer automated, keeping its worktree
+func (s *GitService) stopIssueAutomation(repoID string, entry *models.AutomatedIssue, reason string) {
+	entry.Stopped = reason
+	s.saveAutomatedIssue(repoID, entry)
+	logger.Infof("🏷️ Stopped issue automation for %s#%d: %s", repoID, entry.Number, reason)
+}
+
+// saveAutomatedIssue stores an issue mapping entry
+func (s *GitService) saveAutomatedIssue(repoID string, entry *models.AutomatedIssue) {
+	copied := *entry
+	if err := s.stateManager.updateIssueAutomation(repoID, func(automation *models.IssueAutomation) {
+		if automation.Issues == nil {
+			automation.Issues = make(map[int]*models.AutomatedIssue)
+		}
+		automation.Issues[copied.Number] = &copied
+	}); err != nil {
+		logger.Warnf("⚠️ Failed to save issue automation of %s#%d: %v", repoID, entry.Number, err)
+	}
+}
+
+// RequestIssueAutomationPoll polls the repositories with issue automation enabled that track a
+// GitHub repository in the background, for webhook deliveries about its issues. It reports
+// whether any repository is polled.
+func (s *GitService) RequestIssueAutomationPoll(ownerRepo string) bool {
+	polled := false
+	for _, repo := range s.stateManager.GetAllRepositories() {
+		if !strings.EqualFold(gitHubRepositoryOf(repo), ownerRepo) {
+			continue
+		}
+		if automation, configured := s.stateManager.IssueAutomation(repo.ID); !configured || !automation.Enabled {
+			continue
+		}
+		polled = true
+		repoID := repo.ID
+		recovery.SafeGo("issue-automation:"+repoID, func() {
+			if _, err := s.PollIssueAutomation(repoID); err != nil {
+				logger.Warnf("⚠️ Issue automation poll of %s failed: %v", repoID, err)
+			}
+		})
+	}
+	return polled
+}
+
+// pollAllIssueAutomation polls every repository with issue automation enabled
+func (s *GitService) pollAllIssueAutomation() {
+	for _, repo := range s.stateManager.GetAllRepositories() {
+		if automation, configured := s.stateManager.IssueAutomation(repo.ID); !configured || !automation.Enabled {
+			continue
+		}
+		if _, err := s.PollIssueAutomation(repo.ID); err != nil {
+			logger.Warnf("⚠️ Issue automation poll of %s failed: %v", repo.ID, err)
+		}
+	}
+}
+
+// startIssueAutomationPoller periodically polls repositories with issue automation enabled, less
+// often while the GitHub rate limit is low and not at all once it is nearly spent
+func (s *GitService) startIssueAutomationPoller() {
+	ticker := time.NewTicker(issueAutomationInterval)
+	defer ticker.Stop()
+
+	var nextPoll time.Time
+	for {
+		select {
+		case <-ticker.C:
+			limits := git.GitHubRateLimits()
+			now := time.Now()
+			if now.Before(nextPoll) || !limits.AllowBackground(git.RateLimitGraphQL) {
+				continue
+			}
+			nextPoll = time.Time{}
+			if interval := limits.PollInterval(git.RateLimitGraphQL, issueAutomationInterval); interval > issueAutomationInterval {
+				nextPoll = now.Add(interval - issueAutomationInterval/2)
+			}
+			s.pollAllIssueAutomation()
+		case <-s.stopCh:
+			return
+		}
+	}
+}
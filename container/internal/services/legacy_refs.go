@@ -0,0 +1,282 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/logger"
+)
+
+const (
+	legacyBranchPrefix = "refs/heads/catnip/"
+	catnipRefPrefix    = "refs/catnip/"
+
+	// legacyRefsMarkerFile is written to the state directory once the startup migration has run
+	legacyRefsMarkerFile = "legacy-refs-migrated"
+	// legacyRefsReportFile holds the reports of every migration run
+	legacyRefsReportFile = "legacy-refs-migration.json"
+)
+
+// LegacyRefMigration records a legacy catnip/ branch moved into the refs/catnip/ namespace
+type LegacyRefMigration struct {
+	OldRef    string   `json:"old_ref"`
+	NewRef    string   `json:"new_ref"`
+	Commit    string   `json:"commit"`
+	Worktrees []string `json:"worktrees,omitempty"` // Worktree IDs whose models were updated
+}
+
+// LegacyRefSkip records a legacy branch left in place and why
+type LegacyRefSkip struct {
+	Ref    string `json:"ref"`
+	Reason string `json:"reason"`
+}
+
+// LegacyRefMigrationReport describes the outcome of migrating one repository
+type LegacyRefMigrationReport struct {
+	RepoID     string               `json:"repo_id"`
+	MigratedAt time.Time            `json:"migrated_at"`
+	Migrated   []LegacyRefMigration `json:"migrated"`
+	Skipped    []LegacyRefSkip      `json:"skipped"`
+}
+
+// MigrateLegacyRefs moves legacy catnip/ branches of a repository into the refs/catnip/ namespace.
+// Branches checked out by a worktree, tracking an upstream, or colliding with a different
+// refs/catnip/ ref are skipped and reported. Local repositories, whose catnip/ branches are the
+// live preview branches, and protected repositories are left alone entirely. Reflogs are
+// carried over and running the migration again is a no-op.
+func (s *GitService) MigrateLegacyRefs(repoID string) (*LegacyRefMigrationReport, error) {
+	repo, exists := s.stateManager.GetRepository(repoID)
+	if !exists {
+		return nil, fmt.Errorf("repository %s not found", repoID)
+	}
+
+	report := &LegacyRefMigrationReport{
+		RepoID:     repoID,
+		MigratedAt: time.Now(),
+		Migrated:   []LegacyRefMigration{},
+		Skipped:    []LegacyRefSkip{},
+	}
+
+	output, err := s.operations.ExecuteGit(repo.Path, "for-each-ref", "--format=%(refname) %(objectname) %(upstream)", legacyBranchPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list legacy branches: %v", err)
+	}
+	if strings.TrimSpace(string(output)) == "" {
+		return report, nil
+	}
+
+	// Preview branches of local repositories share the catnip/ namespace, and protected
+	// repositories never have their branches rewritten
+	skipReason := ""
+	switch {
+	case s.isLocalRepo(repoID):
+		skipReason = "local repository, catnip/ branches are live preview branches"
+	case repo.Protected:
+		skipReason = "repository is protected"
+	}
+	if skipReason != "" {
+		for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+			if fields := strings.Fields(line); len(fields) > 0 {
+				report.Skipped = append(report.Skipped, LegacyRefSkip{Ref: fields[0], Reason: skipReason})
+			}
+		}
+		return report, nil
+	}
+
+	checkedOut := make(map[string]bool)
+	worktrees, err := s.operations.ListWorktrees(repo.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list worktrees: %v", err)
+	}
+	for _, wt := range worktrees {
+		checkedOut[wt.Branch] = true
+	}
+
+	gitDir, err := s.gitCommonDir(repo.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		oldRef, commit := fields[0], fields[1]
+		newRef := catnipRefPrefix + strings.TrimPrefix(oldRef, legacyBranchPrefix)
+
+		if checkedOut[oldRef] {
+			report.Skipped = append(report.Skipped, LegacyRefSkip{Ref: oldRef, Reason: "checked out by a worktree"})
+			continue
+		}
+		if len(fields) > 2 {
+			report.Skipped = append(report.Skipped, LegacyRefSkip{Ref: oldRef, Reason: "tracks upstream " + fields[2]})
+			continue
+		}
+
+		// rev-parse <ref>^{commit} prints the hash; go-git's --verify handling prints nothing
+		if existing, err := s.operations.ExecuteGit(repo.Path, "rev-parse", newRef+"^{commit}"); err == nil {
+			if strings.TrimSpace(string(existing)) != commit {
+				report.Skipped = append(report.Skipped, LegacyRefSkip{Ref: oldRef, Reason: newRef + " already exists at a different commit"})
+				continue
+			}
+		} else {
+			copyLegacyReflog(gitDir, oldRef, newRef)
+			// An empty old value makes update-ref fail if the ref appeared in the meantime
+			if _, err := s.operations.ExecuteGit(repo.Path, "update-ref", "-m", "catnip: migrate legacy branch "+oldRef, newRef, commit, ""); err != nil {
+				report.Skipped = append(report.Skipped, LegacyRefSkip{Ref: oldRef, Reason: fmt.Sprintf("failed to create %s: %v", newRef, err)})
+				continue
+			}
+		}
+
+		// Only delete the legacy branch if it still points at the migrated commit
+		if _, err := s.operations.ExecuteGit(repo.Path, "update-ref", "-d", oldRef, commit); err != nil {
+			report.Skipped = append(report.Skipped, LegacyRefSkip{Ref: oldRef, Reason: fmt.Sprintf("failed to delete legacy branch: %v", err)})
+			continue
+		}
+
+		migration := LegacyRefMigration{OldRef: oldRef, NewRef: newRef, Commit: commit}
+		migration.Worktrees = s.updateWorktreesForMigratedRef(repoID, oldRef, newRef)
+		report.Migrated = append(report.Migrated, migration)
+		logger.Infof("🔀 Migrated legacy branch %s to %s in %s", oldRef, newRef, repoID)
+	}
+
+	s.recordLegacyRefReport(report)
+	return report, nil
+}
+
+// updateWorktreesForMigratedRef points worktree models that reference a migrated legacy branch at its new ref
+func (s *GitService) updateWorktreesForMigratedRef(repoID, oldRef, newRef string) []string {
+	shortRef := strings.TrimPrefix(oldRef, "refs/heads/")
+	var updated []string
+
+	for id, worktree := range s.stateManager.GetAllWorktrees() {
+		if worktree.RepoID != repoID {
+			continue
+		}
+
+		updates := make(map[string]interface{})
+		if worktree.Branch == oldRef || worktree.Branch == shortRef {
+			updates["branch"] = newRef
+		}
+		if worktree.SourceBranch == oldRef || worktree.SourceBranch == shortRef {
+			updates["source_branch"] = newRef
+		}
+		if len(updates) == 0 {
+			continue
+		}
+
+		if err := s.stateManager.UpdateWorktree(id, updates); err != nil {
+			logger.Warnf("⚠️  Failed to update worktree %s after migrating %s: %v", id, oldRef, err)
+			continue
+		}
+		updated = append(updated, id)
+	}
+	return updated
+}
+
+// migrateLegacyRefsOnStartup runs the legacy branch migration for every repository once,
+// guarded by a marker file in the state directory
+func (s *GitService) migrateLegacyRefsOnStartup() {
+	markerPath := filepath.Join(s.stateManager.stateDir, legacyRefsMarkerFile)
+	if _, err := os.Stat(markerPath); err == nil {
+		return
+	}
+
+	for repoID, repo := range s.stateManager.GetAllRepositories() {
+		if !repo.Available {
+			continue
+		}
+		if _, err := os.Stat(repo.Path); err != nil {
+			continue
+		}
+		report, err := s.MigrateLegacyRefs(repoID)
+		if err != nil {
+			logger.Warnf("⚠️  Legacy branch migration failed for %s: %v", repoID, err)
+			// Leave the marker unwritten so the migration is retried on the next start
+			return
+		}
+		if len(report.Skipped) > 0 {
+			logger.Infof("ℹ️ Left %d legacy catnip/ branches in place in %s", len(report.Skipped), repoID)
+		}
+	}
+
+	if err := os.MkdirAll(s.stateManager.stateDir, 0755); err != nil {
+		logger.Warnf("⚠️  Failed to create state directory: %v", err)
+		return
+	}
+	if err := os.WriteFile(markerPath, []byte(time.Now().Format(time.RFC3339)+"\n"), 0644); err != nil {
+		logger.Warnf("⚠️  Failed to write legacy branch migration marker: %v", err)
+	}
+}
+
+// legacyRefsMigrated reports whether the startup migration has completed. Legacy branches
+// remaining after it are treated as foreign and left alone by cleanup.
+func (s *GitService) legacyRefsMigrated() bool {
+	_, err := os.Stat(filepath.Join(s.stateManager.stateDir, legacyRefsMarkerFile))
+	return err == nil
+}
+
+// recordLegacyRefReport appends a migration report to the report file in the state directory
+func (s *GitService) recordLegacyRefReport(report *LegacyRefMigrationReport) {
+	if len(report.Migrated) == 0 && len(report.Skipped) == 0 {
+		return
+	}
+
+	reportPath := filepath.Join(s.stateManager.stateDir, legacyRefsReportFile)
+	var reports []*LegacyRefMigrationReport
+	if data, err := os.ReadFile(reportPath); err == nil {
+		if err := json.Unmarshal(data, &reports); err != nil {
+			logger.Warnf("⚠️  Ignoring unreadable legacy branch migration report: %v", err)
+			reports = nil
+		}
+	}
+	reports = append(reports, report)
+
+	data, err := json.MarshalIndent(reports, "", "  ")
+	if err != nil {
+		logger.Warnf("⚠️  Failed to encode legacy branch migration report: %v", err)
+		return
+	}
+	if err := os.MkdirAll(s.stateManager.stateDir, 0755); err != nil {
+		logger.Warnf("⚠️  Failed to create state directory: %v", err)
+		return
+	}
+	if err := os.WriteFile(reportPath, data, 0644); err != nil {
+		logger.Warnf("⚠️  Failed to write legacy branch migration report: %v", err)
+	}
+}
+
+// gitCommonDir returns the absolute path of the repository's shared git directory
+func (s *GitService) gitCommonDir(repoPath string) (string, error) {
+	output, err := s.operations.ExecuteGit(repoPath, "rev-parse", "--git-common-dir")
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve git directory: %v", err)
+	}
+	gitDir := strings.TrimSpace(string(output))
+	if !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(repoPath, gitDir)
+	}
+	return gitDir, nil
+}
+
+// copyLegacyReflog copies the reflog of oldRef to newRef so history survives the move.
+// git only keeps reflogs outside refs/heads when the log file already exists.
+func copyLegacyReflog(gitDir, oldRef, newRef string) {
+	data, err := os.ReadFile(filepath.Join(gitDir, "logs", oldRef))
+	if err != nil {
+		return
+	}
+	newLog := filepath.Join(gitDir, "logs", newRef)
+	if err := os.MkdirAll(filepath.Dir(newLog), 0755); err != nil {
+		logger.Warnf("⚠️  Failed to preserve reflog for %s: %v", oldRef, err)
+		return
+	}
+	if err := os.WriteFile(newLog, data, 0644); err != nil {
+		logger.Warnf("⚠️  Failed to preserve reflog for %s: %v", oldRef, err)
+	}
+}
@@ -0,0 +1,116 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+func TestMigrateLegacyRefs(t *testing.T) {
+	root := t.TempDir()
+	repoPath := initTestRepo(t, filepath.Join(root, "repo"), nil)
+	runTestGit(t, repoPath, "branch", "catnip/felix")
+	runTestGit(t, repoPath, "branch", "catnip/luna")
+	runTestGit(t, repoPath, "branch", "catnip/ziggy")
+	runTestGit(t, repoPath, "worktree", "add", filepath.Join(root, "luna"), "catnip/luna")
+
+	// A refs/catnip/ ref already exists for ziggy at a different commit
+	runTestGit(t, repoPath, "commit", "--allow-empty", "-m", "second")
+	runTestGit(t, repoPath, "update-ref", "refs/catnip/ziggy", "HEAD")
+
+	s := newTestGitService(t, root)
+	stateManager, stateDir := s.stateManager, s.stateManager.stateDir
+	require.NoError(t, stateManager.AddRepository(&models.Repository{ID: "acme/repo", Path: repoPath, Available: true}))
+	require.NoError(t, stateManager.AddWorktree(&models.Worktree{ID: "wt-felix", RepoID: "acme/repo", Name: "felix", Branch: "catnip/felix", SourceBranch: "main"}))
+
+	felixCommit := runTestGit(t, repoPath, "rev-parse", "catnip/felix")
+
+	s.migrateLegacyRefsOnStartup()
+	assert.True(t, s.legacyRefsMigrated())
+
+	t.Run("MigratesUnusedBranch", func(t *testing.T) {
+		assert.Equal(t, felixCommit, runTestGit(t, repoPath, "rev-parse", "refs/catnip/felix"))
+		assert.Empty(t, runTestGit(t, repoPath, "for-each-ref", "refs/heads/catnip/felix"))
+		assert.Contains(t, runTestGit(t, repoPath, "reflog", "show", "refs/catnip/felix"), "branch: Created from")
+
+		worktree, _ := stateManager.GetWorktree("wt-felix")
+		assert.Equal(t, "refs/catnip/felix", worktree.Branch)
+		assert.Equal(t, "main", worktree.SourceBranch)
+	})
+
+	t.Run("SkipsCheckedOutAndAmbiguous", func(t *testing.T) {
+		assert.NotEmpty(t, runTestGit(t, repoPath, "for-each-ref", "refs/heads/catnip/luna"))
+		assert.NotEmpty(t, runTestGit(t, repoPath, "for-each-ref", "refs/heads/catnip/ziggy"))
+		assert.FileExists(t, filepath.Join(stateDir, legacyRefsReportFile))
+	})
+
+	t.Run("Idempotent", func(t *testing.T) {
+		report, err := s.MigrateLegacyRefs("acme/repo")
+		require.NoError(t, err)
+		assert.Empty(t, report.Migrated)
+
+		reasons := map[string]string{}
+		for _, skip := range report.Skipped {
+			reasons[skip.Ref] = skip.Reason
+		}
+		assert.Equal(t, "checked out by a worktree", reasons["refs/heads/catnip/luna"])
+		assert.Contains(t, reasons["refs/heads/catnip/ziggy"], "already exists")
+		assert.Equal(t, felixCommit, runTestGit(t, repoPath, "rev-parse", "refs/catnip/felix"))
+	})
+
+	t.Run("FinishesHalfMigratedBranch", func(t *testing.T) {
+		// refs/catnip/ already points at the legacy branch's commit, e.g. after an interrupted run
+		runTestGit(t, repoPath, "branch", "catnip/milo", felixCommit)
+		runTestGit(t, repoPath, "update-ref", "refs/catnip/milo", felixCommit)
+
+		report, err := s.MigrateLegacyRefs("acme/repo")
+		require.NoError(t, err)
+		require.Len(t, report.Migrated, 1)
+		assert.Equal(t, "refs/heads/catnip/milo", report.Migrated[0].OldRef)
+		assert.Empty(t, runTestGit(t, repoPath, "for-each-ref", "refs/heads/catnip/milo"))
+	})
+
+	t.Run("CleanupLeavesRemainingLegacyBranches", func(t *testing.T) {
+		repo, _ := s.stateManager.GetRepository("acme/repo")
+		require.NoError(t, s.cleanupUnusedBranches(repo, newCleanupReport(false)))
+		assert.NotEmpty(t, runTestGit(t, repoPath, "for-each-ref", "refs/heads/catnip/ziggy"))
+	})
+
+	t.Run("MarkerSkipsStartupMigration", func(t *testing.T) {
+		runTestGit(t, repoPath, "branch", "catnip/oscar")
+		s.migrateLegacyRefsOnStartup()
+		assert.NotEmpty(t, runTestGit(t, repoPath, "for-each-ref", "refs/heads/catnip/oscar"))
+		_, err := os.Stat(filepath.Join(stateDir, legacyRefsMarkerFile))
+		assert.NoError(t, err)
+	})
+}
+
+func TestMigrateLegacyRefsLeavesPreviewBranches(t *testing.T) {
+	root := t.TempDir()
+	repoPath := initTestRepo(t, filepath.Join(root, "repo"), nil)
+	// The live preview branch of the felix worktree, next to its session ref
+	runTestGit(t, repoPath, "branch", worktreePreviewBranch(&models.Worktree{Branch: "refs/catnip/felix"}))
+	runTestGit(t, repoPath, "update-ref", "refs/catnip/felix", "HEAD")
+	runTestGit(t, repoPath, "branch", "catnip/luna")
+
+	s := newTestGitService(t, root)
+	require.NoError(t, s.stateManager.AddRepository(&models.Repository{ID: "local/repo", Path: repoPath, Available: true}))
+	require.NoError(t, s.stateManager.AddRepository(&models.Repository{ID: "acme/protected", Path: repoPath, Available: true, Protected: true}))
+
+	s.migrateLegacyRefsOnStartup()
+	assert.True(t, s.legacyRefsMigrated())
+	assert.NotEmpty(t, runTestGit(t, repoPath, "for-each-ref", "refs/heads/catnip/felix"), "preview branches stay")
+	assert.NotEmpty(t, runTestGit(t, repoPath, "for-each-ref", "refs/heads/catnip/luna"))
+	assert.Empty(t, runTestGit(t, repoPath, "for-each-ref", "refs/catnip/luna"))
+
+	for _, repoID := range []string{"local/repo", "acme/protected"} {
+		report, err := s.MigrateLegacyRefs(repoID)
+		require.NoError(t, err)
+		assert.Empty(t, report.Migrated)
+		assert.Len(t, report.Skipped, 2, repoID)
+	}
+}
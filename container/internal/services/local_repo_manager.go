@@ -88,6 +88,9 @@ func (lrm *LocalRepoManager) DetectLocalRepos() map[string]*models.Repository {
 			RemoteOrigin:    remoteOrigin,
 			HasGitHubRemote: hasGitHubRemote,
 		}
+		applyHeadInspection(repo, lrm.inspectHead(repoPath))
+		repo.Toolchains = DetectToolchains(repoPath)
+		applyRepositoryProtection(repo)
 
 		repositories[repoID] = repo
 		logger.Debugf("✅ Local repository loaded: %s", repoID)
@@ -118,15 +121,9 @@ func (lrm *LocalRepoManager) detectCurrentRepo() map[string]*models.Repository {
 		return repositories
 	}
 
-	// Get current branch
-	branchOutput, err := lrm.operations.ExecuteGit(repoPath, "branch", "--show-current")
-	branch := "main"
-	if err == nil {
-		branch = strings.TrimSpace(string(branchOutput))
-		if branch == "" {
-			branch = "main"
-		}
-	}
+	// Get current branch, resolving detached and unborn HEADs
+	head := lrm.inspectHead(repoPath)
+	branch := head.Branch
 
 	// Create repository object
 	repoName := config.Runtime.CurrentRepo
@@ -144,8 +141,11 @@ func (lrm *LocalRepoManager) detectCurrentRepo() map[string]*models.Repository {
 		RemoteOrigin:    remoteOrigin,
 		HasGitHubRemote: hasGitHubRemote,
 	}
+	applyHeadInspection(repo, head)
+	repo.Toolchains = DetectToolchains(repoPath)
+	applyRepositoryProtection(repo)
 
-	logger.Infof("✅ Detected current repository: %s (branch: %s)", repoName, branch)
+	logger.Infof("✅ Detected current repository: %s (branch: %s, HEAD: %s)", repoName, branch, head.State)
 	repositories[repoID] = repo
 
 	return repositories
@@ -274,9 +274,10 @@ func (lrm *LocalRepoManager) shouldForceUpdatePreviewBranch(repoPath, previewBra
 
 // createTemporaryCommit creates a temporary commit with all uncommitted changes
 func (lrm *LocalRepoManager) createTemporaryCommit(worktreePath string) (string, error) {
-	// Add all changes (staged, unstaged, and untracked)
-	if err := lrm.operations.Add(worktreePath); err != nil {
-		return "", fmt.Errorf("failed to stage changes: %v", err)
+	// Add all changes (staged, unstaged, untracked, deletions, mode changes and symlinks).
+	// Mode-only changes (chmod +x) are only detected when core.fileMode is on.
+	if output, err := lrm.operations.ExecuteGit(worktreePath, git.WithFileModeTracking("add", "-A")...); err != nil {
+		return "", fmt.Errorf("failed to stage changes: %v\n%s", err, output)
 	}
 
 	// Create the commit
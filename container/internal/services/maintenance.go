@@ -0,0 +1,394 @@
+package services
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/git"
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// CleanupItem is a catnip branch, ref or config mapping removed (or, in a dry run, that would be
+// removed) by a cleanup
+type CleanupItem struct {
+	RepoID string `json:"repo_id"`
+	Name   string `json:"name"`
+	// Why the item couldn't be removed, for items listed in CleanupReport.Errors
+	Error string `json:"error,omitempty"`
+}
+
+// CleanupReport lists what CleanupAllCatnipRefs or CleanupCatnipRefsForRepo removed
+type CleanupReport struct {
+	DryRun bool `json:"dry_run"`
+	// Local catnip branches without commits
+	Branches []CleanupItem `json:"branches"`
+	// Preview branches of local repositories whose workspace is gone
+	PreviewBranches []CleanupItem `json:"preview_branches"`
+	// Branches on origin (as origin/<branch>) whose workspace is gone
+	RemoteBranches []CleanupItem `json:"remote_branches"`
+	Refs           []CleanupItem `json:"refs"`
+	ConfigMappings []CleanupItem `json:"config_mappings"`
+	// Unused branches and refs kept because a worktree uses them
+	CheckedOut []CleanupItem `json:"checked_out"`
+	// Unused branches kept because their repository is protected
+	Pinned []CleanupItem `json:"pinned"`
+	// Items, or whole steps when Name is empty, that failed
+	Errors []CleanupItem `json:"errors"`
+}
+
+func newCleanupReport(dryRun bool) *CleanupReport {
+	return &CleanupReport{
+		DryRun:          dryRun,
+		Branches:        []CleanupItem{},
+		PreviewBranches: []CleanupItem{},
+		RemoteBranches:  []CleanupItem{},
+		Refs:            []CleanupItem{},
+		ConfigMappings:  []CleanupItem{},
+		CheckedOut:      []CleanupItem{},
+		Pinned:          []CleanupItem{},
+		Errors:          []CleanupItem{},
+	}
+}
+
+func (r *CleanupReport) addError(repoID, name string, err error) {
+	r.Errors = append(r.Errors, CleanupItem{RepoID: repoID, Name: name, Error: err.Error()})
+}
+
+// removed counts the branches, refs and config mappings removed, or that would be in a dry run
+func (r *CleanupReport) removed() int {
+	return len(r.Branches) + len(r.PreviewBranches) + len(r.RemoteBranches) + len(r.Refs) + len(r.ConfigMappings)
+}
+
+// merge appends the items of another repository's report
+func (r *CleanupReport) merge(other *CleanupReport) {
+	r.Branches = append(r.Branches, other.Branches...)
+	r.PreviewBranches = append(r.PreviewBranches, other.PreviewBranches...)
+	r.RemoteBranches = append(r.RemoteBranches, other.RemoteBranches...)
+	r.Refs = append(r.Refs, other.Refs...)
+	r.ConfigMappings = append(r.ConfigMappings, other.ConfigMappings...)
+	r.CheckedOut = append(r.CheckedOut, other.CheckedOut...)
+	r.Pinned = append(r.Pinned, other.Pinned...)
+	r.Errors = append(r.Errors, other.Errors...)
+}
+
+// ReconcileReport is the outcome of reconciling persisted state with the worktrees on disk
+type ReconcileReport struct {
+	Repositories int `json:"repositories"`
+	Worktrees    int `json:"worktrees"`
+	// Repositories whose directory is missing
+	UnavailableRepositories []string `json:"unavailable_repositories"`
+	// Worktrees that were missing on disk and have been recreated
+	Restored []string `json:"restored"`
+	// Worktrees still missing on disk
+	Missing []string `json:"missing"`
+	// Worktrees dropped from state because their repository is gone
+	Pruned []string `json:"pruned"`
+	// Worktrees whose directory is missing or isn't a git checkout
+	Stale []string `json:"stale"`
+	// Worktrees imported from, removed or flagged after comparing state with git worktree list
+	GitWorktrees *models.WorktreeReconciliation `json:"git_worktrees"`
+}
+
+// RepositoryPruneResult is a repository considered by PruneRepositories
+type RepositoryPruneResult struct {
+	RepoID    string    `json:"repo_id"`
+	LastUsed  time.Time `json:"last_used"`
+	Worktrees int       `json:"worktrees"`
+	// Why the repository was kept or failed to be removed
+	Reason string `json:"reason,omitempty"`
+}
+
+// PruneReport lists the repositories PruneRepositories removed, kept and failed to remove
+type PruneReport struct {
+	DryRun  bool                    `json:"dry_run"`
+	Removed []RepositoryPruneResult `json:"removed"`
+	Skipped []RepositoryPruneResult `json:"skipped"`
+	Failed  []RepositoryPruneResult `json:"failed"`
+}
+
+// NewMaintenanceGitService creates a Git service for one-off maintenance commands on the
+// current volume. Unlike NewGitService it changes nothing on creation and starts no background
+// work; callers hold the exclusive instance lock and Stop the service when done.
+func NewMaintenanceGitService() *GitService {
+	operations := git.NewOperations()
+	stateManager := NewWorktreeStateManager(getGitStateDir(), nil)
+
+	s := &GitService{
+		stateManager:       stateManager,
+		operations:         operations,
+		gitWorktreeManager: git.NewWorktreeManager(operations),
+		conflictResolver:   git.NewConflictResolver(operations),
+		githubManager:      git.NewGitHubManager(operations),
+		localRepoManager:   NewLocalRepoManager(operations),
+		worktreeCache:      NewWorktreeStatusCache(operations, stateManager),
+		stopCh:             make(chan struct{}),
+	}
+	stateManager.SetWorktreeRestorer(s)
+	return s
+}
+
+// missingWorktrees returns the names of worktrees whose directory doesn't exist, sorted.
+// Worktrees git no longer knows aren't restored and are left out.
+func (s *GitService) missingWorktrees() []string {
+	missing := []string{}
+	for _, worktree := range s.stateManager.GetAllWorktrees() {
+		if worktree.Missing {
+			continue
+		}
+		if _, err := os.Stat(worktree.Path); os.IsNotExist(err) {
+			missing = append(missing, worktree.Name)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// Reconcile restores state the way the server does on boot: worktrees are reconciled with git
+// (see ReconcileWorktrees, with missingMode), repositories are checked for availability and
+// worktrees missing on disk are recreated
+func (s *GitService) Reconcile(missingMode string) (*ReconcileReport, error) {
+	gitWorktrees, err := s.reconcileWorktrees(missingMode, 0)
+	if err != nil {
+		return nil, err
+	}
+	before := s.missingWorktrees()
+	if err := s.stateManager.RestoreState(); err != nil {
+		return nil, err
+	}
+	validation := s.stateManager.ValidateState()
+
+	report := &ReconcileReport{
+		UnavailableRepositories: []string{},
+		Restored:                []string{},
+		Missing:                 s.missingWorktrees(),
+		Pruned:                  []string{},
+		Stale:                   []string{},
+		GitWorktrees:            gitWorktrees,
+	}
+	for _, entry := range validation.Pruned {
+		report.Pruned = append(report.Pruned, entry.Name)
+	}
+	for _, entry := range validation.Stale {
+		report.Stale = append(report.Stale, entry.Name)
+	}
+	repos := s.stateManager.GetAllRepositories()
+	report.Repositories = len(repos)
+	report.Worktrees = len(s.stateManager.GetAllWorktrees())
+	for id, repo := range repos {
+		if !repo.Available {
+			report.UnavailableRepositories = append(report.UnavailableRepositories, id)
+		}
+	}
+	sort.Strings(report.UnavailableRepositories)
+
+	stillMissing := make(map[string]bool, len(report.Missing))
+	for _, name := range report.Missing {
+		stillMissing[name] = true
+	}
+	for _, name := range before {
+		if !stillMissing[name] {
+			report.Restored = append(report.Restored, name)
+		}
+	}
+	return report, nil
+}
+
+// exportedStateFiles are the entries of the state directory ExportState archives. Repositories,
+// worktree checkouts, caches and exports are left out.
+var exportedStateFiles = []string{
+	stateIndexFile,
+	legacyStateFile,
+	statePRStatesFile,
+	stateRepositoriesDir,
+	stateWorktreesDir,
+	stateActivityDir,
+	stateMergesDir,
+	recreateJournalDir,
+	operationJournalDir,
+	stateReportFile,
+}
+
+// ExportState writes the persisted state to w as a gzipped tarball and returns the archived
+// files, relative to the state directory
+func (s *GitService) ExportState(w io.Writer) ([]string, error) {
+	s.stateManager.mu.RLock()
+	defer s.stateManager.mu.RUnlock()
+
+	root := s.stateManager.stateDir
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	files := []string{}
+
+	for _, name := range exportedStateFiles {
+		err := filepath.WalkDir(filepath.Join(root, name), func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if os.IsNotExist(err) {
+					return nil
+				}
+				return err
+			}
+			if strings.HasSuffix(path, ".tmp") || !(d.IsDir() || d.Type().IsRegular()) {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(root, path)
+			if err != nil {
+				return err
+			}
+			header, err := tar.FileInfoHeader(info, "")
+			if err != nil {
+				return err
+			}
+			header.Name = filepath.ToSlash(rel)
+			if d.IsDir() {
+				header.Name += "/"
+			}
+			if err := tw.WriteHeader(header); err != nil {
+				return err
+			}
+			if d.IsDir() {
+				return nil
+			}
+
+			file, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			if _, err := io.Copy(tw, file); err != nil {
+				return err
+			}
+			files = append(files, header.Name)
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to archive %s: %v", name, err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// pruneBlocker returns why a repository's worktrees can't be removed without losing work, or "".
+// Changes and commits touching only generated files, such as lockfile churn, aren't work.
+func (s *GitService) pruneBlocker(repoPath, worktreeName, worktreePath, branch string) string {
+	attributesDir := repoPath
+	if _, err := os.Stat(worktreePath); err == nil {
+		attributesDir = worktreePath
+		if dirty, err := s.operations.HasUncommittedChanges(worktreePath); err != nil || (dirty && !s.onlyGeneratedFiles(worktreePath, s.uncommittedFiles(worktreePath))) {
+			return fmt.Sprintf("worktree %s has uncommitted changes", worktreeName)
+		}
+	}
+	output, err := s.operations.ExecuteGit(repoPath, "rev-list", "--count", branch, "--not", "--remotes")
+	if err != nil {
+		// The branch is gone, so there is nothing left to lose
+		return ""
+	}
+	if count, _ := strconv.Atoi(strings.TrimSpace(string(output))); count > 0 {
+		files, err := s.operations.ExecuteGit(repoPath, "log", "--format=", "--name-only", "--no-renames", branch, "--not", "--remotes")
+		if err == nil && s.onlyGeneratedFiles(attributesDir, strings.Split(strings.TrimSpace(string(files)), "\n")) {
+			return ""
+		}
+		return fmt.Sprintf("worktree %s has %d commits that aren't on any remote", worktreeName, count)
+	}
+	return ""
+}
+
+// uncommittedFiles returns the files git status lists for a worktree, nil when it fails
+func (s *GitService) uncommittedFiles(worktreePath string) []string {
+	output, err := s.operations.ExecuteGit(worktreePath, "status", "--porcelain=v1", "-z", "--untracked-files=all", "--no-renames")
+	if err != nil {
+		return nil
+	}
+	var files []string
+	for _, entry := range strings.Split(string(output), "\x00") {
+		if len(entry) > 3 {
+			files = append(files, entry[3:])
+		}
+	}
+	return files
+}
+
+// PruneRepositories removes cloned repositories, with all their worktrees, that no worktree has
+// used for olderThan. Local repositories are never removed, nor are repositories with
+// uncommitted changes or commits that only exist locally. With dryRun set it only reports what
+// would be removed.
+func (s *GitService) PruneRepositories(olderThan time.Duration, dryRun bool) *PruneReport {
+	report := &PruneReport{
+		DryRun:  dryRun,
+		Removed: []RepositoryPruneResult{},
+		Skipped: []RepositoryPruneResult{},
+		Failed:  []RepositoryPruneResult{},
+	}
+	cutoff := time.Now().Add(-olderThan)
+
+	repos := s.stateManager.GetAllRepositories()
+	worktrees := s.stateManager.GetAllWorktrees()
+	ids := make([]string, 0, len(repos))
+	for id := range repos {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		repo := repos[id]
+		if s.isLocalRepo(id) {
+			continue
+		}
+
+		result := RepositoryPruneResult{RepoID: id, LastUsed: repo.LastAccessed}
+		if repo.CreatedAt.After(result.LastUsed) {
+			result.LastUsed = repo.CreatedAt
+		}
+		for _, worktree := range worktrees {
+			if worktree.RepoID != id {
+				continue
+			}
+			result.Worktrees++
+			if worktree.LastAccessed.After(result.LastUsed) {
+				result.LastUsed = worktree.LastAccessed
+			}
+			if result.Reason == "" {
+				result.Reason = s.pruneBlocker(repo.Path, worktree.Name, worktree.Path, worktree.Branch)
+			}
+		}
+		if !result.LastUsed.Before(cutoff) {
+			continue
+		}
+
+		switch {
+		case result.Reason != "":
+			report.Skipped = append(report.Skipped, result)
+		case dryRun:
+			report.Removed = append(report.Removed, result)
+		default:
+			if err := s.DeleteRepository(id, true); err != nil {
+				result.Reason = err.Error()
+				report.Failed = append(report.Failed, result)
+				continue
+			}
+			logger.Infof("🗑️  Pruned repository %s, last used %s", id, result.LastUsed.Format(time.RFC3339))
+			report.Removed = append(report.Removed, result)
+		}
+	}
+	return report
+}
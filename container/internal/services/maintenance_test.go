@@ -0,0 +1,272 @@
+package services
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// newMaintenanceTestService returns a service on a fresh state directory inside root
+func newMaintenanceTestService(t *testing.T, root string) *GitService {
+	t.Helper()
+	s := newTestGitService(t, root)
+	s.worktreeCache = NewWorktreeStatusCache(s.operations, s.stateManager)
+	t.Cleanup(s.worktreeCache.Stop)
+	s.stateManager.SetWorktreeRestorer(s)
+	return s
+}
+
+func TestCleanupAllCatnipRefsDryRun(t *testing.T) {
+	root := t.TempDir()
+	repoPath := initTestRepo(t, filepath.Join(root, "repo"), nil)
+	runTestGit(t, repoPath, "update-ref", "refs/catnip/felix", "HEAD")
+	runTestGit(t, repoPath, "update-ref", "refs/catnip/orphan", "HEAD")
+	runTestGit(t, repoPath, "config", "catnip.branch-map.refs.catnip.gone", "feature/gone")
+
+	s := newMaintenanceTestService(t, root)
+	require.NoError(t, s.stateManager.AddRepository(&models.Repository{ID: "local/repo", Path: repoPath, Available: true}))
+	require.NoError(t, s.stateManager.AddWorktree(&models.Worktree{ID: "wt-felix", RepoID: "local/repo", Name: "repo/felix", Branch: "refs/catnip/felix"}))
+
+	report := s.CleanupAllCatnipRefs(true)
+	assert.True(t, report.DryRun)
+	assert.Equal(t, []CleanupItem{{RepoID: "local/repo", Name: "refs/catnip/orphan"}}, report.Refs)
+	assert.Equal(t, []CleanupItem{{RepoID: "local/repo", Name: "catnip.branch-map.refs.catnip.gone"}}, report.ConfigMappings)
+	assert.NotEmpty(t, runTestGit(t, repoPath, "for-each-ref", "refs/catnip/orphan"), "dry runs delete nothing")
+	assert.Equal(t, "feature/gone", runTestGit(t, repoPath, "config", "catnip.branch-map.refs.catnip.gone"))
+
+	report = s.CleanupAllCatnipRefs(false)
+	assert.Equal(t, []CleanupItem{{RepoID: "local/repo", Name: "refs/catnip/orphan"}}, report.Refs)
+	assert.Len(t, report.ConfigMappings, 1)
+	assert.Empty(t, runTestGit(t, repoPath, "for-each-ref", "refs/catnip/orphan"))
+	assert.NotEmpty(t, runTestGit(t, repoPath, "for-each-ref", "refs/catnip/felix"), "tracked refs are kept")
+}
+
+func TestCleanupCatnipRefsForRepo(t *testing.T) {
+	root := t.TempDir()
+	felixPath := initTestRepo(t, filepath.Join(root, "felix"), nil)
+	lunaPath := initTestRepo(t, filepath.Join(root, "luna"), nil)
+	for _, repoPath := range []string{felixPath, lunaPath} {
+		runTestGit(t, repoPath, "update-ref", "refs/catnip/felix", "HEAD")
+		runTestGit(t, repoPath, "update-ref", "refs/catnip/orphan", "HEAD")
+	}
+
+	s := newMaintenanceTestService(t, root)
+	require.NoError(t, s.stateManager.AddRepository(&models.Repository{ID: "local/felix", Path: felixPath, Available: true}))
+	require.NoError(t, s.stateManager.AddRepository(&models.Repository{ID: "local/luna", Path: lunaPath, Available: true}))
+	require.NoError(t, s.stateManager.AddRepository(&models.Repository{ID: "local/gone", Path: filepath.Join(root, "gone")}))
+	require.NoError(t, s.stateManager.AddWorktree(&models.Worktree{ID: "wt-felix", RepoID: "local/felix", Name: "felix/felix", Branch: "refs/catnip/felix"}))
+
+	report, err := s.CleanupCatnipRefsForRepo("local/felix", false)
+	require.NoError(t, err)
+	assert.Equal(t, []CleanupItem{{RepoID: "local/felix", Name: "refs/catnip/orphan"}}, report.Refs)
+	assert.Equal(t, []CleanupItem{{RepoID: "local/felix", Name: "refs/catnip/felix"}}, report.CheckedOut)
+	assert.Empty(t, report.Errors)
+	assert.Empty(t, runTestGit(t, felixPath, "for-each-ref", "refs/catnip/orphan"))
+	assert.NotEmpty(t, runTestGit(t, lunaPath, "for-each-ref", "refs/catnip/orphan"), "other repositories are left alone")
+
+	_, err = s.CleanupCatnipRefsForRepo("local/missing", false)
+	assert.EqualError(t, err, "repository local/missing not found")
+	_, err = s.CleanupCatnipRefsForRepo("local/gone", false)
+	assert.ErrorContains(t, err, "repository local/gone not found at")
+
+	// The global cleanup aggregates the reports of every available repository
+	report = s.CleanupAllCatnipRefs(false)
+	assert.Equal(t, []CleanupItem{{RepoID: "local/luna", Name: "refs/catnip/orphan"}}, report.Refs)
+	assert.ElementsMatch(t, []CleanupItem{
+		{RepoID: "local/felix", Name: "refs/catnip/felix"},
+		{RepoID: "local/luna", Name: "refs/catnip/felix"},
+	}, report.CheckedOut)
+}
+
+func TestCleanupStaleCatnipBranches(t *testing.T) {
+	root := t.TempDir()
+	// branchOff commits on a new branch of repoPath, committed at date when given
+	branchOff := func(repoPath, branch, date string) {
+		runTestGit(t, repoPath, "checkout", "-q", "-b", branch, "main")
+		cmd := exec.Command("git", "commit", "--allow-empty", "-m", "work on "+branch)
+		cmd.Dir = repoPath
+		cmd.Env = os.Environ()
+		if date != "" {
+			cmd.Env = append(cmd.Env, "GIT_AUTHOR_DATE="+date, "GIT_COMMITTER_DATE="+date)
+		}
+		output, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(output))
+		runTestGit(t, repoPath, "checkout", "-q", "main")
+	}
+
+	// Preview branches of a local repository
+	localPath := initTestRepo(t, filepath.Join(root, "app"), nil)
+	branchOff(localPath, "catnip/merged", "")
+	runTestGit(t, localPath, "merge", "-q", "--no-ff", "-m", "merge preview", "catnip/merged")
+	branchOff(localPath, "catnip/old", "2020-01-01T00:00:00Z")
+	branchOff(localPath, "catnip/fresh", "")
+	branchOff(localPath, "catnip/felix", "2020-01-01T00:00:00Z")
+
+	// catnip/ branches pushed to the origin of a cloned repository
+	originPath := filepath.Join(root, "origin.git")
+	runTestGit(t, root, "init", "-q", "--bare", "-b", "main", originPath)
+	clonePath := initTestRepo(t, filepath.Join(root, "clone"), nil)
+	runTestGit(t, clonePath, "remote", "add", "origin", originPath)
+	branchOff(clonePath, "catnip/old", "2020-01-01T00:00:00Z")
+	branchOff(clonePath, "catnip/fresh", "")
+	runTestGit(t, clonePath, "push", "-q", "origin", "main", "catnip/old", "catnip/fresh")
+
+	s := newMaintenanceTestService(t, root)
+	require.NoError(t, os.MkdirAll(s.stateManager.stateDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(s.stateManager.stateDir, legacyRefsMarkerFile), nil, 0644))
+	require.NoError(t, s.stateManager.AddRepository(&models.Repository{ID: "local/app", Path: localPath, Available: true}))
+	require.NoError(t, s.stateManager.AddRepository(&models.Repository{ID: "owner/clone", Path: clonePath, Available: true}))
+	require.NoError(t, s.stateManager.AddWorktree(&models.Worktree{ID: "wt-felix", RepoID: "local/app", Name: "app/felix", Branch: "refs/catnip/felix"}))
+
+	report := s.CleanupAllCatnipRefs(true)
+	assert.ElementsMatch(t, []CleanupItem{
+		{RepoID: "local/app", Name: "catnip/merged"},
+		{RepoID: "local/app", Name: "catnip/old"},
+	}, report.PreviewBranches)
+	assert.Equal(t, []CleanupItem{{RepoID: "owner/clone", Name: "origin/catnip/old"}}, report.RemoteBranches)
+	assert.Empty(t, report.Branches)
+	assert.NotEmpty(t, runTestGit(t, originPath, "for-each-ref", "refs/heads/catnip/old"), "dry runs delete nothing")
+
+	report = s.CleanupAllCatnipRefs(false)
+	assert.Len(t, report.PreviewBranches, 2)
+	assert.Equal(t, []CleanupItem{{RepoID: "owner/clone", Name: "origin/catnip/old"}}, report.RemoteBranches)
+	assert.Empty(t, report.Errors)
+	assert.Equal(t, "catnip/felix\ncatnip/fresh", runTestGit(t, localPath, "for-each-ref", "--format=%(refname:short)", "refs/heads/catnip/"),
+		"branches of workspaces in state and fresh unmerged ones are kept")
+	assert.Equal(t, "refs/heads/catnip/fresh\nrefs/heads/main", runTestGit(t, originPath, "for-each-ref", "--format=%(refname)"))
+
+	t.Run("ProtectedRepositoriesArePinned", func(t *testing.T) {
+		branchOff(clonePath, "catnip/stale", "2020-01-01T00:00:00Z")
+		runTestGit(t, clonePath, "push", "-q", "origin", "catnip/stale")
+		repo, _ := s.stateManager.GetRepository("owner/clone")
+		repo.Protected = true
+		require.NoError(t, s.stateManager.AddRepository(repo))
+
+		report, err := s.CleanupCatnipRefsForRepo("owner/clone", false)
+		require.NoError(t, err)
+		assert.Empty(t, report.RemoteBranches)
+		assert.Equal(t, []CleanupItem{{RepoID: "owner/clone", Name: "origin/catnip/stale"}}, report.Pinned)
+		assert.NotEmpty(t, runTestGit(t, originPath, "for-each-ref", "refs/heads/catnip/stale"))
+	})
+}
+
+func TestReconcile(t *testing.T) {
+	root := t.TempDir()
+	repoPath := initTestRepo(t, filepath.Join(root, "repo"), nil)
+	worktreePath := filepath.Join(root, "felix")
+	runTestGit(t, repoPath, "worktree", "add", "-b", "felix", worktreePath)
+
+	s := newMaintenanceTestService(t, root)
+	require.NoError(t, s.stateManager.AddRepository(&models.Repository{ID: "local/repo", Path: repoPath, Available: true}))
+	require.NoError(t, s.stateManager.AddRepository(&models.Repository{ID: "owner/gone", Path: filepath.Join(root, "gone.git"), Available: true}))
+	require.NoError(t, s.stateManager.AddWorktree(&models.Worktree{ID: "wt-felix", RepoID: "local/repo", Name: "repo/felix", Path: worktreePath, Branch: "felix"}))
+	require.NoError(t, s.stateManager.AddWorktree(&models.Worktree{ID: "wt-luna", RepoID: "owner/gone", Name: "gone/luna", Path: filepath.Join(root, "luna"), Branch: "luna"}))
+
+	report, err := s.Reconcile("")
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.Repositories)
+	assert.Equal(t, 2, report.Worktrees)
+	assert.Equal(t, []string{"owner/gone"}, report.UnavailableRepositories)
+	assert.Empty(t, report.Restored)
+	assert.Equal(t, []string{"gone/luna"}, report.Missing)
+}
+
+func TestExportState(t *testing.T) {
+	root := t.TempDir()
+	s := newMaintenanceTestService(t, root)
+	require.NoError(t, s.stateManager.AddRepository(&models.Repository{ID: "owner/repo", Path: filepath.Join(root, "repo.git"), Available: true}))
+	require.NoError(t, s.stateManager.AddWorktree(&models.Worktree{ID: "wt-felix", RepoID: "owner/repo", Name: "repo/felix"}))
+	// Clones and exports in the state directory are left out
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "state", "repos", "repo.git"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "state", "repos", "repo.git", "HEAD"), []byte("ref: refs/heads/main\n"), 0644))
+
+	var buf bytes.Buffer
+	files, err := s.ExportState(&buf)
+	require.NoError(t, err)
+	assert.Contains(t, files, stateIndexFile)
+	assert.Contains(t, files, "repositories/owner%2Frepo.json")
+	assert.Contains(t, files, "worktrees/wt-felix.json")
+
+	gz, err := gzip.NewReader(&buf)
+	require.NoError(t, err)
+	tr := tar.NewReader(gz)
+	archived := map[string]string{}
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		data, err := io.ReadAll(tr)
+		require.NoError(t, err)
+		archived[header.Name] = string(data)
+	}
+	assert.Contains(t, archived["worktrees/wt-felix.json"], `"repo/felix"`)
+	assert.NotContains(t, archived, "repos/repo.git/HEAD")
+}
+
+func TestPruneRepositories(t *testing.T) {
+	root := t.TempDir()
+	s := newMaintenanceTestService(t, root)
+	old := time.Now().Add(-60 * 24 * time.Hour)
+
+	addRepo := func(id string, lastAccessed time.Time) string {
+		repoPath := initTestRepo(t, filepath.Join(root, filepath.Base(id)), nil)
+		// main has been pushed
+		runTestGit(t, repoPath, "update-ref", "refs/remotes/origin/main", "HEAD")
+		require.NoError(t, s.stateManager.AddRepository(&models.Repository{ID: id, Path: repoPath, Available: true, CreatedAt: old, LastAccessed: lastAccessed}))
+		return repoPath
+	}
+
+	stalePath := addRepo("owner/stale", old)
+	require.NoError(t, s.stateManager.AddWorktree(&models.Worktree{ID: "wt-stale", RepoID: "owner/stale", Name: "stale/main", Path: filepath.Join(root, "missing"), Branch: "main", LastAccessed: old}))
+
+	// Recently used through a worktree
+	addRepo("owner/active", old)
+	require.NoError(t, s.stateManager.AddWorktree(&models.Worktree{ID: "wt-active", RepoID: "owner/active", Name: "active/main", Branch: "main", LastAccessed: time.Now()}))
+
+	unpushedPath := addRepo("owner/unpushed", old)
+	runTestGit(t, unpushedPath, "update-ref", "refs/heads/felix", runTestGit(t, unpushedPath, "commit-tree", "-p", "HEAD", "-m", "wip", "HEAD^{tree}"))
+	require.NoError(t, s.stateManager.AddWorktree(&models.Worktree{ID: "wt-felix", RepoID: "owner/unpushed", Name: "unpushed/felix", Branch: "felix", LastAccessed: old}))
+
+	dirtyPath := addRepo("owner/dirty", old)
+	dirtyWorktree := filepath.Join(root, "dirty-luna")
+	runTestGit(t, dirtyPath, "worktree", "add", "--detach", dirtyWorktree, "main")
+	require.NoError(t, os.WriteFile(filepath.Join(dirtyWorktree, "notes.txt"), []byte("wip"), 0644))
+	require.NoError(t, s.stateManager.AddWorktree(&models.Worktree{ID: "wt-luna", RepoID: "owner/dirty", Name: "dirty/luna", Path: dirtyWorktree, Branch: "main", LastAccessed: old}))
+
+	localPath := addRepo("local/mine", old)
+
+	report := s.PruneRepositories(30*24*time.Hour, true)
+	require.Len(t, report.Removed, 1)
+	assert.Equal(t, "owner/stale", report.Removed[0].RepoID)
+	assert.Equal(t, 1, report.Removed[0].Worktrees)
+	assert.DirExists(t, stalePath, "dry runs delete nothing")
+
+	reasons := map[string]string{}
+	for _, skipped := range report.Skipped {
+		reasons[skipped.RepoID] = skipped.Reason
+	}
+	assert.Equal(t, map[string]string{
+		"owner/unpushed": "worktree unpushed/felix has 1 commits that aren't on any remote",
+		"owner/dirty":    "worktree dirty/luna has uncommitted changes",
+	}, reasons)
+
+	report = s.PruneRepositories(30*24*time.Hour, false)
+	require.Len(t, report.Removed, 1)
+	assert.Empty(t, report.Failed)
+	assert.NoDirExists(t, stalePath)
+	_, exists := s.stateManager.GetRepository("owner/stale")
+	assert.False(t, exists)
+	assert.DirExists(t, localPath, "local repositories are never pruned")
+}
@@ -0,0 +1,187 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// maxMergeLedgerEntries bounds the merges kept per repository, the oldest are dropped first
+const maxMergeLedgerEntries = 1000
+
+// Trailers appended to merge commits, linking them back to the worktree they came from
+const (
+	TrailerWorktree = "Catnip-Worktree"
+	TrailerBranch   = "Catnip-Branch"
+	TrailerSession  = "Catnip-Session"
+	TrailerPR       = "Catnip-PR"
+)
+
+// RecordMerge appends a merge to the ledger of its repository
+func (wsm *WorktreeStateManager) RecordMerge(entry models.MergeLedgerEntry) error {
+	wsm.mu.Lock()
+	defer wsm.mu.Unlock()
+
+	if wsm.merges == nil {
+		wsm.merges = make(map[string][]models.MergeLedgerEntry)
+	}
+	merges := append(wsm.merges[entry.RepoID], entry)
+	if len(merges) > maxMergeLedgerEntries {
+		merges = merges[len(merges)-maxMergeLedgerEntries:]
+	}
+	wsm.merges[entry.RepoID] = merges
+	return wsm.saveStateInternal()
+}
+
+// GetMergeLedger returns up to limit merges of a repository, newest first. A limit of 0 or
+// less returns all of them.
+func (wsm *WorktreeStateManager) GetMergeLedger(repoID string, limit int) []models.MergeLedgerEntry {
+	wsm.mu.RLock()
+	defer wsm.mu.RUnlock()
+
+	merges := wsm.merges[repoID]
+	if limit <= 0 || limit > len(merges) {
+		limit = len(merges)
+	}
+	ledger := make([]models.MergeLedgerEntry, 0, limit)
+	for i := len(merges) - 1; i >= 0 && len(ledger) < limit; i-- {
+		ledger = append(ledger, merges[i])
+	}
+	return ledger
+}
+
+// FindMerge returns the ledger entry of the merge that created commit in a repository
+func (wsm *WorktreeStateManager) FindMerge(repoID, commit string) (models.MergeLedgerEntry, bool) {
+	wsm.mu.RLock()
+	defer wsm.mu.RUnlock()
+
+	for _, entry := range wsm.merges[repoID] {
+		if entry.CommitHash == commit {
+			return entry, true
+		}
+	}
+	return models.MergeLedgerEntry{}, false
+}
+
+// worktreeSessionID returns the Claude session of a worktree, empty when none is known
+func (wsm *WorktreeStateManager) worktreeSessionID(worktreePath string) string {
+	wsm.mu.RLock()
+	sessionService := wsm.sessionService
+	wsm.mu.RUnlock()
+
+	if sessionService == nil {
+		return ""
+	}
+	session, err := sessionService.FindSessionByDirectory(worktreePath)
+	if err != nil || session == nil {
+		return ""
+	}
+	return session.ClaudeSessionID
+}
+
+// mergeTrailers returns the provenance trailers of a merge, in the order they are appended
+func mergeTrailers(entry models.MergeLedgerEntry) []string {
+	trailers := []string{
+		TrailerWorktree + ": " + entry.WorktreeName,
+		TrailerBranch + ": " + entry.Branch,
+	}
+	if entry.SessionID != "" {
+		trailers = append(trailers, TrailerSession+": "+entry.SessionID)
+	}
+	if entry.PullRequestURL != "" {
+		trailers = append(trailers, TrailerPR+": "+entry.PullRequestURL)
+	}
+	return trailers
+}
+
+// withMergeTrailers appends the provenance trailers of a merge to its commit message as a
+// separate paragraph, which is where git interpret-trailers looks for them
+func withMergeTrailers(message string, entry models.MergeLedgerEntry) string {
+	return strings.TrimRight(message, "\n") + "\n\n" + strings.Join(mergeTrailers(entry), "\n")
+}
+
+// newMergeLedgerEntry snapshots a worktree about to be merged. The diffstat and commit count
+// are read from the main repository after the worktree branch was pushed there.
+func (s *GitService) newMergeLedgerEntry(worktree *models.Worktree, repo *models.Repository, squash bool) models.MergeLedgerEntry {
+	mode := "merge"
+	if squash {
+		mode = "squash"
+	}
+	entry := models.MergeLedgerEntry{
+		RepoID:            repo.ID,
+		Mode:              mode,
+		TargetBranch:      worktree.SourceBranch,
+		Branch:            worktree.Branch,
+		WorktreeID:        worktree.ID,
+		WorktreeName:      worktree.Name,
+		SessionID:         s.stateManager.worktreeSessionID(worktree.Path),
+		PullRequestURL:    worktree.PullRequestURL,
+		CreatedBy:         worktree.CreatedBy,
+		WorktreeCreatedAt: worktree.CreatedAt,
+	}
+	if worktree.SessionTitle != nil {
+		entry.SessionTitle = strings.TrimSpace(worktree.SessionTitle.Title)
+	}
+
+	if head, err := s.operations.GetCommitHash(repo.Path, worktree.Branch); err == nil {
+		entry.HeadCommit = head
+	}
+	mergedRange := worktree.SourceBranch + ".." + worktree.Branch
+	if output, err := s.operations.ExecuteGit(repo.Path, "rev-list", "--count", mergedRange); err == nil {
+		_, _ = fmt.Sscanf(strings.TrimSpace(string(output)), "%d", &entry.CommitCount)
+	}
+	if signatures, err := commitSignatures(s.operations, repo.Path, mergedRange); err == nil {
+		entry.CommitSignatures = summarizeSignatures(signatures)
+		if len(signatures) > 0 {
+			entry.Signatures = signatures
+		}
+	} else {
+		logger.Debugf("Failed to verify commit signatures of %s for the merge ledger: %v", worktree.Name, err)
+	}
+	if output, err := s.operations.ExecuteGit(repo.Path, "diff", "--stat", worktree.SourceBranch+"..."+worktree.Branch); err == nil {
+		entry.DiffStat = strings.TrimRight(string(output), "\n")
+	} else {
+		logger.Debugf("Failed to compute diffstat of %s for the merge ledger: %v", worktree.Name, err)
+	}
+	return entry
+}
+
+// GetMergeLedger returns up to limit merges of a repository, newest first (all of them when
+// limit is 0 or less)
+func (s *GitService) GetMergeLedger(repoID string, limit int) ([]models.MergeLedgerEntry, error) {
+	repo, exists := s.stateManager.GetRepository(repoID)
+	if !exists {
+		return nil, fmt.Errorf("repository %s not found", repoID)
+	}
+	return s.stateManager.GetMergeLedger(repo.ID, limit), nil
+}
+
+// FindMergeByCommit returns the ledger entry of the merge that created commit, which may be
+// abbreviated or any revision of the repository that resolves to it
+func (s *GitService) FindMergeByCommit(repoID, commit string) (*models.MergeLedgerEntry, error) {
+	repo, exists := s.stateManager.GetRepository(repoID)
+	if !exists {
+		return nil, fmt.Errorf("repository %s not found", repoID)
+	}
+	hash, err := s.operations.GetCommitHash(repo.Path, commit+"^{commit}")
+	if err != nil {
+		return nil, fmt.Errorf("commit %s not found in %s", commit, repo.ID)
+	}
+	entry, found := s.stateManager.FindMerge(repo.ID, hash)
+	if !found {
+		return nil, fmt.Errorf("commit %s was not created by a worktree merge", commit)
+	}
+	return &entry, nil
+}
+
+// recordMerge completes a ledger entry with the commit the merge created and records it
+func (s *GitService) recordMerge(entry models.MergeLedgerEntry, commit string, mergedAt time.Time) {
+	entry.CommitHash = commit
+	entry.MergedAt = mergedAt
+	if err := s.stateManager.RecordMerge(entry); err != nil {
+		logger.Warnf("⚠️ Failed to record merge of %s in the merge ledger: %v", entry.WorktreeName, err)
+	}
+}
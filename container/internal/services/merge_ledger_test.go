@@ -0,0 +1,91 @@
+package services
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+func TestMergeLedger(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	root := t.TempDir()
+	repoPath := initTestRepo(t, filepath.Join(root, "repo"), nil)
+
+	s := newTestGitService(t, root)
+	stateManager, stateDir := s.stateManager, s.stateManager.stateDir
+	require.NoError(t, stateManager.AddRepository(&models.Repository{ID: "local/repo", Path: repoPath, Available: true}))
+
+	addWorktree := func(name, branch, file string) *models.Worktree {
+		path := filepath.Join(root, name)
+		runTestGit(t, repoPath, "worktree", "add", "-b", branch, path, "main")
+		require.NoError(t, os.WriteFile(filepath.Join(path, file), []byte("package main\n"), 0644))
+		runTestGit(t, path, "add", file)
+		runTestGit(t, path, "commit", "-m", "Add "+file)
+		worktree := &models.Worktree{
+			ID: "wt-" + name, RepoID: "local/repo", Name: "repo/" + name, Path: path, Branch: branch, SourceBranch: "main",
+		}
+		require.NoError(t, stateManager.AddWorktree(worktree))
+		return worktree
+	}
+
+	// Squash merges get the trailers too, the pull request one only when there is a pull request
+	felix := addWorktree("felix", "feature/login", "login.go")
+	felix.PullRequestURL = "https://github.com/acme/repo/pull/7"
+	felix.SessionTitle = &models.TitleEntry{Title: "Add login"}
+	repo, _ := stateManager.GetRepository("local/repo")
+	require.NoError(t, s.mergeWorktreeToMain(felix, repo, MergeOptions{Squash: true}))
+	assert.Equal(t, "Squash merge branch 'feature/login' from worktree\n\n"+
+		"Catnip-Worktree: repo/felix\nCatnip-Branch: feature/login\nCatnip-PR: https://github.com/acme/repo/pull/7",
+		runTestGit(t, repoPath, "log", "-1", "--format=%B", "main"))
+	assert.Equal(t, "repo/felix", runTestGit(t, repoPath, "log", "-1", "--format=%(trailers:key=Catnip-Worktree,valueonly)", "main"))
+
+	luna := addWorktree("luna", "feature/logout", "logout.go")
+	require.NoError(t, s.mergeWorktreeToMain(luna, repo, MergeOptions{Message: "Add logout"}))
+
+	ledger, err := s.GetMergeLedger("local/repo", 0)
+	require.NoError(t, err)
+	require.Len(t, ledger, 2)
+	assert.Equal(t, "repo/luna", ledger[0].WorktreeName, "newest first")
+	assert.Equal(t, "merge", ledger[0].Mode)
+	squash := ledger[1]
+	assert.Equal(t, "squash", squash.Mode)
+	assert.Equal(t, "Add login", squash.SessionTitle)
+	assert.Equal(t, 1, squash.CommitCount)
+	assert.Contains(t, squash.DiffStat, "login.go")
+	assert.False(t, squash.MergedAt.IsZero())
+	assert.Equal(t, strings.TrimSpace(runTestGit(t, repoPath, "rev-parse", "main~1")), squash.CommitHash)
+
+	limited, err := s.GetMergeLedger("local/repo", 1)
+	require.NoError(t, err)
+	assert.Len(t, limited, 1)
+
+	// Commits of the source branch trace back to their merge, abbreviated or not
+	merge, err := s.FindMergeByCommit("local/repo", squash.CommitHash[:8])
+	require.NoError(t, err)
+	assert.Equal(t, "wt-felix", merge.WorktreeID)
+	merge, err = s.FindMergeByCommit("local/repo", "main")
+	require.NoError(t, err)
+	assert.Equal(t, "wt-luna", merge.WorktreeID)
+	_, err = s.FindMergeByCommit("local/repo", "main~2")
+	assert.ErrorContains(t, err, "not created by a worktree merge")
+	_, err = s.GetMergeLedger("local/oscar", 0)
+	assert.Error(t, err)
+
+	// The ledger survives a restart
+	reloaded := NewWorktreeStateManager(stateDir, nil)
+	t.Cleanup(reloaded.Stop)
+	persisted := reloaded.GetMergeLedger("local/repo", 0)
+	require.Len(t, persisted, 2)
+	assert.Equal(t, squash.CommitHash, persisted[1].CommitHash)
+	assert.Equal(t, squash.DiffStat, persisted[1].DiffStat)
+	assert.True(t, squash.MergedAt.Equal(persisted[1].MergedAt))
+}
@@ -0,0 +1,206 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// mergePreviewTTL is how long a prepared merge waits for its commit message
+const mergePreviewTTL = 5 * time.Minute
+
+// MergePhase identifies the merge preview events
+type MergePhase string
+
+const (
+	// MergePrepared is emitted when a merge is prepared for review
+	MergePrepared MergePhase = "prepared"
+	// MergeCompleted is emitted when a prepared merge landed
+	MergeCompleted MergePhase = "completed"
+	// MergeExpired is emitted when a prepared merge was not completed in time
+	MergeExpired MergePhase = "expired"
+)
+
+// pendingMerge is a prepared merge and the timer expiring it
+type pendingMerge struct {
+	preview *models.MergePreview
+	timer   *time.Timer
+}
+
+// pendingMergeRegistry holds prepared merges by token
+type pendingMergeRegistry struct {
+	mu      sync.Mutex
+	byToken map[string]*pendingMerge
+}
+
+// add stores a prepared merge and calls expire with it once the TTL passed without completion
+func (r *pendingMergeRegistry) add(preview *models.MergePreview, ttl time.Duration, expire func(*models.MergePreview)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.byToken == nil {
+		r.byToken = make(map[string]*pendingMerge)
+	}
+	r.byToken[preview.Token] = &pendingMerge{
+		preview: preview,
+		timer: time.AfterFunc(ttl, func() {
+			if r.take(preview.Token) != nil {
+				expire(preview)
+			}
+		}),
+	}
+}
+
+// take removes and returns a prepared merge, or nil when the token is unknown or expired
+func (r *pendingMergeRegistry) take(token string) *models.MergePreview {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	pending, exists := r.byToken[token]
+	if !exists {
+		return nil
+	}
+	delete(r.byToken, token)
+	pending.timer.Stop()
+	return pending.preview
+}
+
+// defaultMergeSubject is the commit message used when merging without review
+func defaultMergeSubject(branch string, squash bool) string {
+	if squash {
+		return fmt.Sprintf("Squash merge branch '%s' from worktree", branch)
+	}
+	return fmt.Sprintf("Merge branch '%s' from worktree", branch)
+}
+
+// defaultMergeMessage suggests a commit message for a merge: the latest session title (or the
+// default subject) followed by the subjects of the merged commits
+func defaultMergeMessage(worktree *models.Worktree, squash bool, subjects []string) string {
+	subject := defaultMergeSubject(worktree.Branch, squash)
+	if worktree.SessionTitle != nil && strings.TrimSpace(worktree.SessionTitle.Title) != "" {
+		subject = strings.TrimSpace(worktree.SessionTitle.Title)
+	}
+
+	var body []string
+	for _, line := range subjects {
+		if line = strings.TrimSpace(line); line != "" && line != subject {
+			body = append(body, "- "+line)
+		}
+	}
+	if len(body) == 0 {
+		return subject
+	}
+	return subject + "\n\n" + strings.Join(body, "\n")
+}
+
+// newMergeToken returns a random token identifying a prepared merge
+func newMergeToken() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// PrepareMerge gathers what a merge of a local repo worktree would land, mode being "squash" or
+// "merge", without changing anything. The returned token completes the merge with
+// CompleteMerge until it expires.
+func (s *GitService) PrepareMerge(worktreeID, mode string) (*models.MergePreview, error) {
+	if mode != "squash" && mode != "merge" {
+		return nil, fmt.Errorf("unknown merge mode %q, expected squash or merge", mode)
+	}
+	worktree, _, err := s.mergeTarget(worktreeID)
+	if err != nil {
+		return nil, err
+	}
+
+	head, err := s.operations.GetCommitHash(worktree.Path, "HEAD")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read HEAD: %v", err)
+	}
+	sourceRef := s.getSourceRef(worktree)
+	output, err := s.operations.ExecuteGit(worktree.Path, "log", "--reverse", "--format=%s", sourceRef+"..HEAD")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list commits: %v", err)
+	}
+	subjects := strings.Split(strings.TrimSpace(string(output)), "\n")
+	diffStat, err := s.operations.ExecuteGit(worktree.Path, "diff", "--stat", sourceRef+"...HEAD")
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute diffstat: %v", err)
+	}
+
+	token, err := newMergeToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate merge token: %v", err)
+	}
+	preview := &models.MergePreview{
+		Token:        token,
+		WorktreeID:   worktreeID,
+		Mode:         mode,
+		TargetBranch: worktree.SourceBranch,
+		Message:      defaultMergeMessage(worktree, mode == "squash", subjects),
+		DiffStat:     strings.TrimRight(string(diffStat), "\n"),
+		HeadCommit:   head,
+		ExpiresAt:    time.Now().Add(mergePreviewTTL),
+	}
+
+	s.pendingMerges.add(preview, mergePreviewTTL, func(preview *models.MergePreview) {
+		logger.Infof("⌛ Prepared merge of worktree %s expired", worktree.Name)
+		s.emitMerge(MergeExpired, preview)
+	})
+	s.emitMerge(MergePrepared, preview)
+	return preview, nil
+}
+
+// CompleteMerge performs a prepared merge with the reviewed commit message (the default
+// message when empty). The merge is refused when the worktree's HEAD moved since it was
+// prepared or its validation fails. A token can only be used once.
+func (s *GitService) CompleteMerge(token, message string, skipValidation bool) (*models.MergePreview, error) {
+	preview := s.pendingMerges.take(token)
+	if preview == nil {
+		return nil, fmt.Errorf("merge token is unknown or expired, prepare the merge again")
+	}
+
+	worktree, repo, err := s.mergeTarget(preview.WorktreeID)
+	if err != nil {
+		return nil, err
+	}
+	head, err := s.operations.GetCommitHash(worktree.Path, "HEAD")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read HEAD: %v", err)
+	}
+	if head != preview.HeadCommit {
+		return nil, fmt.Errorf("worktree %s changed since the merge was prepared, prepare the merge again", worktree.Name)
+	}
+	if err := s.requireValidation(worktree, ValidationForMerge, skipValidation); err != nil {
+		return nil, err
+	}
+
+	if strings.TrimSpace(message) == "" {
+		message = preview.Message
+	}
+	done := s.timeOperation(worktree, OperationMerge)
+	err = s.mergeWorktreeToMain(worktree, repo, MergeOptions{Squash: preview.Mode == "squash", Message: message})
+	done(err)
+	if err != nil {
+		return nil, err
+	}
+	s.refreshPullRequestStatus(worktree.ID)
+
+	preview.Message = message
+	s.emitMerge(MergeCompleted, preview)
+	return preview, nil
+}
+
+// emitMerge reports a merge preview phase to connected clients
+func (s *GitService) emitMerge(phase MergePhase, preview *models.MergePreview) {
+	if s.eventsEmitter != nil {
+		s.eventsEmitter.EmitWorktreeMerge(phase, preview)
+	}
+}
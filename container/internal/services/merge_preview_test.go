@@ -0,0 +1,112 @@
+package services
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// mergeRecorder records merge preview events; other events are not expected
+type mergeRecorder struct {
+	EventsEmitter
+	mu     sync.Mutex
+	phases []MergePhase
+}
+
+func (r *mergeRecorder) EmitWorktreeMerge(phase MergePhase, preview *models.MergePreview) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.phases = append(r.phases, phase)
+}
+
+func (r *mergeRecorder) recorded() []MergePhase {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]MergePhase(nil), r.phases...)
+}
+
+func TestDefaultMergeMessage(t *testing.T) {
+	worktree := &models.Worktree{Branch: "refs/catnip/felix"}
+	assert.Equal(t, "Squash merge branch 'refs/catnip/felix' from worktree", defaultMergeMessage(worktree, true, []string{""}))
+	assert.Equal(t, "Merge branch 'refs/catnip/felix' from worktree\n\n- Add login\n- Fix typo",
+		defaultMergeMessage(worktree, false, []string{"Add login", "Fix typo"}))
+
+	worktree.SessionTitle = &models.TitleEntry{Title: "Add user authentication"}
+	assert.Equal(t, "Add user authentication\n\n- Add login",
+		defaultMergeMessage(worktree, true, []string{"Add user authentication", "Add login"}))
+}
+
+func TestPendingMergeExpiry(t *testing.T) {
+	var registry pendingMergeRegistry
+	expired := make(chan string, 1)
+	registry.add(&models.MergePreview{Token: "a"}, 10*time.Millisecond, func(p *models.MergePreview) { expired <- p.Token })
+	registry.add(&models.MergePreview{Token: "b"}, time.Hour, func(p *models.MergePreview) { expired <- p.Token })
+
+	select {
+	case token := <-expired:
+		assert.Equal(t, "a", token)
+	case <-time.After(time.Second):
+		t.Fatal("prepared merge did not expire")
+	}
+	assert.Nil(t, registry.take("a"))
+	assert.NotNil(t, registry.take("b"))
+	assert.Nil(t, registry.take("b"), "tokens are single use")
+}
+
+func TestPrepareAndCompleteMerge(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	root := t.TempDir()
+	repoPath := initTestRepo(t, filepath.Join(root, "repo"), nil)
+	worktreePath := filepath.Join(root, "felix")
+	runTestGit(t, repoPath, "worktree", "add", "-b", "feature", worktreePath)
+	require.NoError(t, os.WriteFile(filepath.Join(worktreePath, "login.go"), []byte("package main\n"), 0644))
+	runTestGit(t, worktreePath, "add", "login.go")
+	runTestGit(t, worktreePath, "commit", "-m", "Add login")
+
+	s := newTestGitService(t, root)
+	stateManager := s.stateManager
+	require.NoError(t, stateManager.AddRepository(&models.Repository{ID: "local/repo", Path: repoPath, Available: true}))
+	require.NoError(t, stateManager.AddWorktree(&models.Worktree{
+		ID: "wt-felix", RepoID: "local/repo", Name: "repo/felix", Path: worktreePath, Branch: "feature", SourceBranch: "main",
+		SessionTitle: &models.TitleEntry{Title: "Add user authentication"},
+	}))
+	events := &mergeRecorder{}
+	s.eventsEmitter = events
+
+	_, err := s.PrepareMerge("wt-felix", "octopus")
+	assert.Error(t, err)
+
+	// A moved HEAD invalidates the prepared merge
+	preview, err := s.PrepareMerge("wt-felix", "squash")
+	require.NoError(t, err)
+	assert.Equal(t, "Add user authentication\n\n- Add login", preview.Message)
+	assert.Contains(t, preview.DiffStat, "login.go")
+	assert.Equal(t, "main", preview.TargetBranch)
+	runTestGit(t, worktreePath, "commit", "--allow-empty", "-m", "Tweak")
+	_, err = s.CompleteMerge(preview.Token, "", false)
+	assert.ErrorContains(t, err, "changed since the merge was prepared")
+
+	preview, err = s.PrepareMerge("wt-felix", "squash")
+	require.NoError(t, err)
+	assert.Equal(t, "Add user authentication\n\n- Add login\n- Tweak", preview.Message)
+	completed, err := s.CompleteMerge(preview.Token, "Add authentication\n\nReviewed message", false)
+	require.NoError(t, err)
+	assert.Equal(t, "Add authentication\n\nReviewed message", completed.Message)
+	assert.Equal(t, "Add authentication\n\nReviewed message\n\nCatnip-Worktree: repo/felix\nCatnip-Branch: feature",
+		runTestGit(t, repoPath, "log", "-1", "--format=%B", "main"))
+	assert.FileExists(t, filepath.Join(repoPath, "login.go"))
+
+	_, err = s.CompleteMerge(preview.Token, "", false)
+	assert.ErrorContains(t, err, "unknown or expired")
+	assert.Equal(t, []MergePhase{MergePrepared, MergePrepared, MergeCompleted}, events.recorded())
+}
@@ -0,0 +1,285 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/git"
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+const (
+	// mirrorPushTimeout bounds a single push to a mirror
+	mirrorPushTimeout = 2 * time.Minute
+	// mirrorFailureWarningThreshold is the number of consecutive failed pushes before a repo health warning
+	mirrorFailureWarningThreshold = 3
+	// mirrorQueueSize is the number of pending mirror pushes before new ones are dropped
+	mirrorQueueSize = 64
+)
+
+// Refspecs pushed by a full mirror sync
+var fullMirrorRefspecs = []string{"+refs/heads/*:refs/heads/*", "+refs/tags/*:refs/tags/*"}
+
+// mirrorJob is a pending push of refspecs from a repository to all of its mirrors
+type mirrorJob struct {
+	repoID   string
+	refspecs []string
+}
+
+// MirrorManager pushes refs that catnip publishes to origin to each configured mirror.
+// Pushes run in the background with retries; failures are tracked per mirror and never
+// surface to the operation that triggered them.
+type MirrorManager struct {
+	operations   git.Operations
+	stateManager *WorktreeStateManager
+	credentials  git.CredentialProvider
+	jobs         chan mirrorJob
+	stopCh       chan struct{}
+	stopOnce     sync.Once
+	wg           sync.WaitGroup
+	maxAttempts  int
+	retryDelay   time.Duration // Doubled after each failed attempt
+}
+
+// NewMirrorManager creates a mirror manager and starts its background worker
+func NewMirrorManager(operations git.Operations, stateManager *WorktreeStateManager, credentials git.CredentialProvider) *MirrorManager {
+	m := &MirrorManager{
+		operations:   operations,
+		stateManager: stateManager,
+		credentials:  credentials,
+		jobs:         make(chan mirrorJob, mirrorQueueSize),
+		stopCh:       make(chan struct{}),
+		maxAttempts:  3,
+		retryDelay:   5 * time.Second,
+	}
+
+	m.wg.Add(1)
+	go m.run()
+	return m
+}
+
+// Stop stops the background worker. Pending pushes are dropped; the next full sync catches up.
+func (m *MirrorManager) Stop() {
+	m.stopOnce.Do(func() {
+		close(m.stopCh)
+	})
+	m.wg.Wait()
+}
+
+// EnqueueBranch schedules pushing branch to every mirror of the repository
+func (m *MirrorManager) EnqueueBranch(repoID, branch string) {
+	if m == nil || branch == "" {
+		return
+	}
+	ref := "refs/heads/" + strings.TrimPrefix(branch, "refs/heads/")
+	m.enqueue(mirrorJob{repoID: repoID, refspecs: []string{"+" + ref + ":" + ref}})
+}
+
+func (m *MirrorManager) enqueue(job mirrorJob) {
+	repo, exists := m.stateManager.GetRepository(job.repoID)
+	if !exists || len(repo.Mirrors) == 0 {
+		return
+	}
+
+	select {
+	case m.jobs <- job:
+	default:
+		logger.Warnf("⚠️  Mirror queue full, dropping push of %v for %s", job.refspecs, job.repoID)
+	}
+}
+
+func (m *MirrorManager) run() {
+	defer m.wg.Done()
+	for {
+		select {
+		case job := <-m.jobs:
+			_ = m.pushToMirrors(job)
+		case <-m.stopCh:
+			return
+		}
+	}
+}
+
+// SyncMirrors pushes all branches and tags of the repository to every mirror and waits
+// for the result. The returned error lists the mirrors that still failed after retries.
+func (m *MirrorManager) SyncMirrors(repoID string) error {
+	repo, exists := m.stateManager.GetRepository(repoID)
+	if !exists {
+		return fmt.Errorf("repository %s not found", repoID)
+	}
+	if len(repo.Mirrors) == 0 {
+		return fmt.Errorf("repository %s has no mirrors configured", repoID)
+	}
+	return m.pushToMirrors(mirrorJob{repoID: repoID, refspecs: fullMirrorRefspecs})
+}
+
+// pushToMirrors pushes the job's refspecs to every mirror of its repository
+func (m *MirrorManager) pushToMirrors(job mirrorJob) error {
+	repo, exists := m.stateManager.GetRepository(job.repoID)
+	if !exists {
+		return fmt.Errorf("repository %s not found", job.repoID)
+	}
+
+	var failed []string
+	for _, mirror := range snapshotMirrors(repo) {
+		if err := m.pushWithRetry(repo.Path, mirror, job.refspecs); err != nil {
+			logger.Warnf("⚠️  Mirror %s push failed for %s: %v", mirror.Name, job.repoID, err)
+			m.recordFailure(job.repoID, mirror.Name, err)
+			failed = append(failed, fmt.Sprintf("%s: %v", mirror.Name, err))
+			continue
+		}
+		logger.Debugf("🪞 Mirrored %v to %s for %s", job.refspecs, mirror.Name, job.repoID)
+		m.recordSuccess(job.repoID, mirror.Name)
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to push to mirrors: %s", strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// pushWithRetry pushes refspecs to one mirror, retrying with exponential backoff
+func (m *MirrorManager) pushWithRetry(repoPath string, mirror models.RepositoryMirror, refspecs []string) error {
+	env, err := m.credentials.GitEnv(mirror.URL, mirror.Credential)
+	if err != nil {
+		return err
+	}
+
+	args := append([]string{"push", "--porcelain", mirror.URL}, refspecs...)
+	delay := m.retryDelay
+	for attempt := 1; ; attempt++ {
+		_, err := m.operations.ExecuteGitWithEnv(repoPath, env, mirrorPushTimeout, args...)
+		if err == nil {
+			return nil
+		}
+		if attempt >= m.maxAttempts {
+			return err
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-m.stopCh:
+			return fmt.Errorf("mirror push cancelled: %v", err)
+		}
+		delay *= 2
+	}
+}
+
+// recordSuccess resets a mirror's failure tracking and clears its health warning
+func (m *MirrorManager) recordSuccess(repoID, mirrorName string) {
+	now := time.Now()
+	if err := m.stateManager.UpdateRepository(repoID, func(repo *models.Repository) {
+		if mirror := findMirror(repo, mirrorName); mirror != nil {
+			mirror.LastSuccess = &now
+			mirror.LastError = ""
+			mirror.ConsecutiveFailures = 0
+		}
+	}); err != nil {
+		logger.Warnf("⚠️  Failed to record mirror status for %s: %v", repoID, err)
+		return
+	}
+
+	if err := m.stateManager.SetRepositoryHealthWarning(repoID, mirrorWarningSource(mirrorName), ""); err != nil {
+		logger.Warnf("⚠️  Failed to clear mirror health warning for %s: %v", repoID, err)
+	}
+}
+
+// recordFailure tracks a failed push and raises a health warning once failures persist
+func (m *MirrorManager) recordFailure(repoID, mirrorName string, pushErr error) {
+	now := time.Now()
+	failures := 0
+	if err := m.stateManager.UpdateRepository(repoID, func(repo *models.Repository) {
+		if mirror := findMirror(repo, mirrorName); mirror != nil {
+			mirror.LastError = pushErr.Error()
+			mirror.LastErrorAt = &now
+			mirror.ConsecutiveFailures++
+			failures = mirror.ConsecutiveFailures
+		}
+	}); err != nil {
+		logger.Warnf("⚠️  Failed to record mirror status for %s: %v", repoID, err)
+		return
+	}
+
+	if failures >= mirrorFailureWarningThreshold {
+		message := fmt.Sprintf("mirror %s has failed %d consecutive pushes", mirrorName, failures)
+		if err := m.stateManager.SetRepositoryHealthWarning(repoID, mirrorWarningSource(mirrorName), message); err != nil {
+			logger.Warnf("⚠️  Failed to raise mirror health warning for %s: %v", repoID, err)
+		}
+	}
+}
+
+// SetMirrors replaces the repository's mirror configuration. Status is kept for mirrors
+// whose name and URL are unchanged; warnings of removed mirrors are cleared.
+func (m *MirrorManager) SetMirrors(repoID string, mirrors []models.RepositoryMirror) ([]*models.RepositoryMirror, error) {
+	seen := make(map[string]bool)
+	for _, mirror := range mirrors {
+		if mirror.Name == "" || mirror.URL == "" {
+			return nil, fmt.Errorf("mirror name and url are required")
+		}
+		if seen[mirror.Name] {
+			return nil, fmt.Errorf("duplicate mirror name %q", mirror.Name)
+		}
+		seen[mirror.Name] = true
+	}
+
+	var removed []string
+	var result []*models.RepositoryMirror
+	err := m.stateManager.UpdateRepository(repoID, func(repo *models.Repository) {
+		updated := make([]*models.RepositoryMirror, 0, len(mirrors))
+		for _, mirror := range mirrors {
+			next := &models.RepositoryMirror{Name: mirror.Name, URL: mirror.URL, Credential: mirror.Credential}
+			if existing := findMirror(repo, mirror.Name); existing != nil && existing.URL == mirror.URL {
+				next.LastSuccess = existing.LastSuccess
+				next.LastError = existing.LastError
+				next.LastErrorAt = existing.LastErrorAt
+				next.ConsecutiveFailures = existing.ConsecutiveFailures
+			}
+			updated = append(updated, next)
+		}
+		for _, existing := range repo.Mirrors {
+			if !seen[existing.Name] {
+				removed = append(removed, existing.Name)
+			}
+		}
+		if len(updated) == 0 {
+			updated = nil
+		}
+		repo.Mirrors = updated
+		result = updated
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range removed {
+		if err := m.stateManager.SetRepositoryHealthWarning(repoID, mirrorWarningSource(name), ""); err != nil {
+			logger.Warnf("⚠️  Failed to clear mirror health warning for %s: %v", repoID, err)
+		}
+	}
+	return result, nil
+}
+
+// snapshotMirrors copies the mirror configuration so pushes don't race with updates
+func snapshotMirrors(repo *models.Repository) []models.RepositoryMirror {
+	mirrors := make([]models.RepositoryMirror, 0, len(repo.Mirrors))
+	for _, mirror := range repo.Mirrors {
+		mirrors = append(mirrors, *mirror)
+	}
+	return mirrors
+}
+
+func findMirror(repo *models.Repository, name string) *models.RepositoryMirror {
+	for _, mirror := range repo.Mirrors {
+		if mirror.Name == name {
+			return mirror
+		}
+	}
+	return nil
+}
+
+func mirrorWarningSource(mirrorName string) string {
+	return "mirror:" + mirrorName
+}
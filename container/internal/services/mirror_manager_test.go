@@ -0,0 +1,91 @@
+package services
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/git"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+func newTestMirrorManager(t *testing.T) (*MirrorManager, *WorktreeStateManager, string) {
+	t.Helper()
+	root := t.TempDir()
+
+	repoPath := initTestRepo(t, filepath.Join(root, "repo"), nil)
+	runTestGit(t, repoPath, "branch", "feature/mirror")
+
+	stateManager := NewWorktreeStateManager(filepath.Join(root, "state"), nil)
+	t.Cleanup(stateManager.Stop)
+	require.NoError(t, stateManager.AddRepository(&models.Repository{ID: "local/repo", Path: repoPath}))
+
+	m := NewMirrorManager(git.NewOperations(), stateManager, git.NewEnvCredentialProvider())
+	m.retryDelay = time.Millisecond
+	t.Cleanup(m.Stop)
+	return m, stateManager, root
+}
+
+func TestMirrorManagerSyncMirrors(t *testing.T) {
+	m, stateManager, root := newTestMirrorManager(t)
+
+	mirrorPath := filepath.Join(root, "mirror.git")
+	runTestGit(t, root, "init", "--bare", mirrorPath)
+
+	_, err := m.SetMirrors("local/repo", []models.RepositoryMirror{{Name: "backup", URL: mirrorPath}})
+	require.NoError(t, err)
+
+	require.NoError(t, m.SyncMirrors("local/repo"))
+	refs := runTestGit(t, mirrorPath, "for-each-ref", "--format=%(refname)")
+	assert.Contains(t, refs, "refs/heads/main")
+	assert.Contains(t, refs, "refs/heads/feature/mirror")
+
+	repo, _ := stateManager.GetRepository("local/repo")
+	require.Len(t, repo.Mirrors, 1)
+	assert.NotNil(t, repo.Mirrors[0].LastSuccess)
+	assert.Empty(t, repo.Mirrors[0].LastError)
+	assert.Zero(t, repo.Mirrors[0].ConsecutiveFailures)
+}
+
+func TestMirrorManagerFailuresRaiseHealthWarning(t *testing.T) {
+	m, stateManager, root := newTestMirrorManager(t)
+
+	missing := filepath.Join(root, "missing.git")
+	_, err := m.SetMirrors("local/repo", []models.RepositoryMirror{{Name: "broken", URL: missing}})
+	require.NoError(t, err)
+
+	for i := 0; i < mirrorFailureWarningThreshold; i++ {
+		assert.Error(t, m.SyncMirrors("local/repo"))
+	}
+
+	repo, _ := stateManager.GetRepository("local/repo")
+	assert.Equal(t, mirrorFailureWarningThreshold, repo.Mirrors[0].ConsecutiveFailures)
+	assert.NotEmpty(t, repo.Mirrors[0].LastError)
+	assert.Contains(t, repo.HealthWarnings, "mirror:broken")
+
+	// Fixing the mirror clears the failure tracking and the warning
+	runTestGit(t, root, "init", "--bare", missing)
+	require.NoError(t, m.SyncMirrors("local/repo"))
+	repo, _ = stateManager.GetRepository("local/repo")
+	assert.Zero(t, repo.Mirrors[0].ConsecutiveFailures)
+	assert.Empty(t, repo.HealthWarnings)
+}
+
+func TestMirrorManagerSetMirrorsValidation(t *testing.T) {
+	m, _, _ := newTestMirrorManager(t)
+
+	_, err := m.SetMirrors("local/repo", []models.RepositoryMirror{{Name: "a"}})
+	assert.Error(t, err)
+
+	_, err = m.SetMirrors("local/repo", []models.RepositoryMirror{{Name: "a", URL: "/x"}, {Name: "a", URL: "/y"}})
+	assert.Error(t, err)
+
+	_, err = m.SetMirrors("local/missing", []models.RepositoryMirror{{Name: "a", URL: "/x"}})
+	assert.Error(t, err)
+
+	mirrors, err := m.SetMirrors("local/repo", nil)
+	require.NoError(t, err)
+	assert.Nil(t, mirrors)
+}
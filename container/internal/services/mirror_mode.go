@@ -0,0 +1,162 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+const (
+	// mirrorRefSpec fetches every branch of origin into its remote-tracking ref, where
+	// checkouts look for branches. Local branches and refs/catnip/ are left alone, so pruning
+	// never touches session branches.
+	mirrorRefSpec = "+refs/heads/*:refs/remotes/origin/*"
+	// defaultMirrorUpdateInterval is how often mirrored repositories are updated from origin,
+	// configurable in minutes via CATNIP_MIRROR_UPDATE_MINUTES (0 disables)
+	defaultMirrorUpdateInterval = 15 * time.Minute
+	// mirrorUpdateInitialDelay postpones the first update until startup work has settled
+	mirrorUpdateInitialDelay = 2 * time.Minute
+	// mirrorUpdateTimeout bounds one git remote update
+	mirrorUpdateTimeout = 10 * time.Minute
+)
+
+// mirrorUpdateInterval reads CATNIP_MIRROR_UPDATE_MINUTES, keeping the default for unset or
+// invalid values. Zero disables the periodic update.
+func mirrorUpdateInterval() time.Duration {
+	if value := os.Getenv("CATNIP_MIRROR_UPDATE_MINUTES"); value != "" {
+		if minutes, err := strconv.Atoi(value); err == nil && minutes >= 0 {
+			return time.Duration(minutes) * time.Minute
+		}
+		logger.Warnf("⚠️  Ignoring invalid CATNIP_MIRROR_UPDATE_MINUTES value %q", value)
+	}
+	return defaultMirrorUpdateInterval
+}
+
+// isMirrorClone reports whether the bare repository at barePath fetches every branch of origin
+func (s *GitService) isMirrorClone(barePath string) bool {
+	refspec, err := s.operations.GetConfig(barePath, "remote.origin.fetch")
+	return err == nil && strings.TrimSpace(refspec) == mirrorRefSpec
+}
+
+// configureMirror makes the bare repository at barePath fetch every branch of origin
+func (s *GitService) configureMirror(barePath string) error {
+	if _, err := s.operations.ExecuteGit(barePath, "config", "--replace-all", "remote.origin.fetch", mirrorRefSpec); err != nil {
+		return fmt.Errorf("failed to configure mirror refspec: %v", err)
+	}
+	return nil
+}
+
+// fetchMirror fetches every branch of origin into the repository at barePath, pruning branches
+// deleted on origin
+func (s *GitService) fetchMirror(barePath string) error {
+	defer s.shareRepository(barePath)()
+	output, err := s.operations.ExecuteGitWithTimeout(barePath, mirrorUpdateTimeout, "remote", "update", "--prune")
+	if err != nil {
+		return fmt.Errorf("git remote update failed: %v: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// updateMirror updates a repository in mirror mode from origin and records when it did
+func (s *GitService) updateMirror(repoID, barePath string) error {
+	if err := s.fetchMirror(barePath); err != nil {
+		return err
+	}
+	updatedAt := time.Now()
+	return s.stateManager.UpdateRepository(repoID, func(repo *models.Repository) {
+		repo.LastRemoteUpdate = &updatedAt
+	})
+}
+
+// SetRepositoryMirrorMode turns mirror mode of a cloned repository on or off. Turning it on
+// fetches every branch of origin right away; turning it off stops fetching them, keeping the
+// branches already fetched.
+func (s *GitService) SetRepositoryMirrorMode(repoID string, enabled bool) (*models.Repository, error) {
+	repo, exists := s.stateManager.GetRepository(repoID)
+	if !exists {
+		return nil, fmt.Errorf("repository %s not found", repoID)
+	}
+	if s.isLocalRepo(repoID) {
+		return nil, fmt.Errorf("mirror mode is only available for cloned repositories")
+	}
+
+	if enabled {
+		if err := s.configureMirror(repo.Path); err != nil {
+			return nil, err
+		}
+		if err := s.updateMirror(repoID, repo.Path); err != nil {
+			return nil, err
+		}
+	} else if s.isMirrorClone(repo.Path) {
+		if err := s.operations.UnsetConfig(repo.Path, "remote.origin.fetch"); err != nil {
+			return nil, fmt.Errorf("failed to remove mirror refspec: %v", err)
+		}
+	}
+
+	if err := s.stateManager.UpdateRepository(repoID, func(repo *models.Repository) {
+		repo.Mirror = enabled
+	}); err != nil {
+		return nil, err
+	}
+	state := "off"
+	if enabled {
+		state = "on"
+	}
+	logger.Infof("🪞 Turned mirror mode of %s %s", repoID, state)
+	repo, _ = s.stateManager.GetRepository(repoID)
+	return repo, nil
+}
+
+// mirroredSource returns the branch to create a worktree of a mirrored repository from without
+// fetching, pointing the local branch at the mirrored one like a fetch would. It returns false
+// when the repository isn't mirrored or the branch isn't mirrored yet.
+func (s *GitService) mirroredSource(repo *models.Repository, branch string) (string, bool) {
+	if !repo.Mirror || canonicalSourceRef(branch) != "" || !s.branchExists(repo.Path, branch, true) {
+		return "", false
+	}
+	if _, err := s.operations.ExecuteGit(repo.Path, "update-ref", "refs/heads/"+branch, "refs/remotes/origin/"+branch); err != nil {
+		logger.Debugf("⚠️ Could not update local branch ref of %s: %v", branch, err)
+		return "", false
+	}
+	return branch, true
+}
+
+// updateAllMirrors updates every available repository in mirror mode from origin
+func (s *GitService) updateAllMirrors() {
+	for _, repo := range s.stateManager.GetAllRepositories() {
+		if !repo.Mirror || !repo.Available {
+			continue
+		}
+		if err := s.updateMirror(repo.ID, repo.Path); err != nil {
+			logger.Warnf("⚠️  Failed to update mirror %s: %v", repo.ID, err)
+		}
+	}
+}
+
+// startMirrorUpdater periodically updates repositories in mirror mode until the service stops
+func (s *GitService) startMirrorUpdater() {
+	interval := mirrorUpdateInterval()
+	if interval == 0 {
+		return
+	}
+	initial := time.NewTimer(mirrorUpdateInitialDelay)
+	defer initial.Stop()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-initial.C:
+			s.updateAllMirrors()
+		case <-ticker.C:
+			s.updateAllMirrors()
+		case <-s.stopCh:
+			return
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package services
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMirrorMode(t *testing.T) {
+	s, stateManager, repoPath, _ := newRecreateTestService(t)
+	runTestGit(t, repoPath, "branch", "feature/other", "main")
+	clonesDir := t.TempDir()
+
+	mirrorPath := filepath.Join(clonesDir, "mirror.git")
+	repo, _, err := s.cloneBareRepository("acme/mirror", "file://"+repoPath, mirrorPath, "", CloneOptions{Depth: 1, SingleBranch: true, Mirror: true})
+	require.NoError(t, err)
+	assert.True(t, repo.Mirror)
+	require.NotNil(t, repo.LastRemoteUpdate)
+	assert.True(t, s.isMirrorClone(mirrorPath))
+	for _, branch := range []string{"main", "feature/felix", "feature/other"} {
+		assert.True(t, s.branchExists(mirrorPath, branch, true), "%s is mirrored", branch)
+	}
+	assert.Equal(t, "false", runTestGit(t, mirrorPath, "rev-parse", "--is-shallow-repository"), "mirrors have the full history")
+
+	t.Run("BranchesAppearAfterRemoteUpdate", func(t *testing.T) {
+		runTestGit(t, repoPath, "branch", "feature/new", "main")
+		_, mirrored := s.mirroredSource(repo, "feature/new")
+		assert.False(t, mirrored, "not mirrored until the next update")
+
+		previous := *repo.LastRemoteUpdate
+		require.NoError(t, s.updateMirror("acme/mirror", mirrorPath))
+		updated, _ := stateManager.GetRepository("acme/mirror")
+		assert.True(t, updated.LastRemoteUpdate.After(previous))
+
+		// Mirrored branches are checked out without reaching origin
+		runTestGit(t, mirrorPath, "remote", "set-url", "origin", filepath.Join(clonesDir, "gone.git"))
+		source, mirrored := s.mirroredSource(repo, "feature/new")
+		require.True(t, mirrored)
+		assert.Equal(t, "feature/new", source)
+		assert.Equal(t, runTestGit(t, repoPath, "rev-parse", "main"), runTestGit(t, mirrorPath, "rev-parse", "refs/heads/feature/new"))
+		runTestGit(t, mirrorPath, "remote", "set-url", "origin", "file://"+repoPath)
+	})
+
+	t.Run("UpdatePrunesDeletedBranches", func(t *testing.T) {
+		runTestGit(t, mirrorPath, "update-ref", "refs/catnip/felix", "main")
+		runTestGit(t, repoPath, "branch", "-D", "feature/other")
+		require.NoError(t, s.updateMirror("acme/mirror", mirrorPath))
+		assert.False(t, s.branchExists(mirrorPath, "feature/other", true))
+		assert.True(t, s.branchExists(mirrorPath, "refs/catnip/felix", false), "session branches are never pruned")
+	})
+
+	t.Run("ToggleOnExistingClone", func(t *testing.T) {
+		plainPath := filepath.Join(clonesDir, "plain.git")
+		plain, _, err := s.cloneBareRepository("acme/plain", "file://"+repoPath, plainPath, "", CloneOptions{SingleBranch: true})
+		require.NoError(t, err)
+		assert.False(t, plain.Mirror)
+		_, mirrored := s.mirroredSource(plain, "feature/felix")
+		assert.False(t, mirrored, "branches of regular clones are always fetched")
+
+		plain, err = s.SetRepositoryMirrorMode("acme/plain", true)
+		require.NoError(t, err)
+		assert.True(t, plain.Mirror)
+		assert.NotNil(t, plain.LastRemoteUpdate)
+		assert.True(t, s.branchExists(plainPath, "feature/felix", true))
+
+		plain, err = s.SetRepositoryMirrorMode("acme/plain", false)
+		require.NoError(t, err)
+		assert.False(t, plain.Mirror)
+		assert.False(t, s.isMirrorClone(plainPath))
+		assert.True(t, s.branchExists(plainPath, "feature/felix", true), "fetched branches are kept")
+	})
+
+	_, err = s.SetRepositoryMirrorMode("local/repo", true)
+	assert.ErrorContains(t, err, "only available for cloned repositories")
+	_, err = s.SetRepositoryMirrorMode("acme/missing", true)
+	assert.ErrorContains(t, err, "not found")
+}
@@ -0,0 +1,63 @@
+package services
+
+import (
+	"strconv"
+
+	"github.com/vanpelt/catnip/internal/git"
+	"github.com/vanpelt/catnip/internal/logger"
+)
+
+// Git config keys capping the branch names catnip renames worktrees to and the absolute paths
+// of new worktrees. Both can be set per repository or globally.
+const (
+	branchMaxLengthKey       = "catnip.branch.max-length"
+	worktreeMaxPathLengthKey = "catnip.worktree.max-path-length"
+)
+
+// minBranchMaxLength keeps a readable beginning in front of the hash of shortened names
+const minBranchMaxLength = 20
+
+// loadBranchMaxLength reads the branch name cap from git config, keeping the default for unset
+// or invalid values
+func loadBranchMaxLength(getConfig func(key string) (string, error)) int {
+	if value, err := getConfig(branchMaxLengthKey); err == nil && value != "" {
+		if n, err := strconv.Atoi(value); err == nil && n >= minBranchMaxLength {
+			return n
+		}
+		logger.Warnf("⚠️  Ignoring invalid %s value %q (minimum %d)", branchMaxLengthKey, value, minBranchMaxLength)
+	}
+	return git.DefaultMaxBranchLength
+}
+
+// loadWorktreeMaxPathLength reads the worktree path cap from git config, keeping the default
+// for unset or invalid values. Zero disables the check.
+func loadWorktreeMaxPathLength(getConfig func(key string) (string, error)) int {
+	if value, err := getConfig(worktreeMaxPathLengthKey); err == nil && value != "" {
+		if n, err := strconv.Atoi(value); err == nil && n >= 0 {
+			return n
+		}
+		logger.Warnf("⚠️  Ignoring invalid %s value %q", worktreeMaxPathLengthKey, value)
+	}
+	return git.DefaultMaxWorktreePathLength
+}
+
+// CapBranchName shortens a branch name over the cap configured for the repository at
+// repoPath, ending it with a hash of the full name. Collision suffixes (-1, -2, ...) are added
+// after capping.
+func (s *GitService) CapBranchName(repoPath, branch string) string {
+	maxLength := loadBranchMaxLength(func(key string) (string, error) {
+		return s.operations.GetConfig(repoPath, key)
+	})
+	capped := git.TruncateBranchName(branch, maxLength)
+	if capped != branch {
+		logger.Infof("✂️  Shortened %d character branch name to %q", len(branch), capped)
+	}
+	return capped
+}
+
+// worktreeMaxPathLength returns the worktree path cap configured for the repository at repoPath
+func (s *GitService) worktreeMaxPathLength(repoPath string) int {
+	return loadWorktreeMaxPathLength(func(key string) (string, error) {
+		return s.operations.GetConfig(repoPath, key)
+	})
+}
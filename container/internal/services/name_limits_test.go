@@ -0,0 +1,72 @@
+package services
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/git"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+func TestLoadNameLimits(t *testing.T) {
+	configValue := func(value string) func(string) (string, error) {
+		return func(string) (string, error) { return value, nil }
+	}
+	assert.Equal(t, git.DefaultMaxBranchLength, loadBranchMaxLength(configValue("")))
+	assert.Equal(t, 50, loadBranchMaxLength(configValue("50")))
+	assert.Equal(t, git.DefaultMaxBranchLength, loadBranchMaxLength(configValue("5")), "too short to keep a readable name")
+
+	assert.Equal(t, git.DefaultMaxWorktreePathLength, loadWorktreeMaxPathLength(configValue("")))
+	assert.Equal(t, 0, loadWorktreeMaxPathLength(configValue("0")))
+	assert.Equal(t, git.DefaultMaxWorktreePathLength, loadWorktreeMaxPathLength(configValue("long")))
+}
+
+func TestLongBranchNames(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	root := t.TempDir()
+	workspace := filepath.Join(root, "workspace")
+	t.Setenv("CATNIP_WORKSPACE_DIR", workspace)
+	repoPath := initTestRepo(t, filepath.Join(root, "repo"), nil)
+
+	s := newTestGitService(t, root)
+	stateManager := s.stateManager
+	s.worktreeCache = NewWorktreeStatusCache(s.operations, stateManager)
+	t.Cleanup(s.worktreeCache.Stop)
+	repo := &models.Repository{ID: "owner/repo", Path: repoPath, DefaultBranch: "main", Available: true}
+	require.NoError(t, stateManager.AddRepository(repo))
+
+	// A branch name suggested from a 200 character session title
+	title := SanitizeTitle(strings.Repeat("Migrate the billing service to the new event pipeline ", 4)[:200])
+	require.Len(t, title, 200)
+	suggested := "feature/" + strings.ReplaceAll(strings.ToLower(title), " ", "-")
+
+	branch := s.CapBranchName(repoPath, suggested)
+	assert.Len(t, branch, git.DefaultMaxBranchLength)
+	runTestGit(t, repoPath, "check-ref-format", "refs/heads/"+branch)
+
+	runTestGit(t, repoPath, "config", branchMaxLengthKey, "40")
+	assert.Len(t, s.CapBranchName(repoPath, suggested), 40)
+
+	// Worktree directories don't grow with the branch name
+	worktree, err := s.createWorktreeInternalForRepo(repo, "main", suggested, false, nil)
+	require.NoError(t, err)
+	assert.Equal(t, suggested, worktree.Branch)
+	assert.Equal(t, filepath.Join(workspace, "repo", git.WorktreeDirName(suggested)), worktree.Path)
+	assert.LessOrEqual(t, len(filepath.Base(worktree.Path)), 48)
+
+	// Paths over the cap fail before git runs
+	runTestGit(t, repoPath, "config", worktreeMaxPathLengthKey, "20")
+	_, err = s.createWorktreeInternalForRepo(repo, "main", "refs/catnip/felix", false, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "over the limit of 20")
+	_, statErr := os.Stat(filepath.Join(workspace, "repo", "felix"))
+	assert.True(t, os.IsNotExist(statErr))
+}
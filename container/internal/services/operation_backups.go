@@ -0,0 +1,153 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+const (
+	// operationBackupNamespace holds the HEADs of worktrees from before their history was
+	// rewritten, as refs/catnip/backup-ops/<worktree id>/<time>
+	operationBackupNamespace = "refs/catnip/backup-ops/"
+	// operationBackupTimeLayout names backups; fixed width, so names sort chronologically
+	operationBackupTimeLayout = "20060102T150405.000000000Z"
+)
+
+// getOperationBackupRetention returns how long backups are kept before the refs cleanup deletes
+// them, configurable via CATNIP_OPERATION_BACKUP_DAYS
+func getOperationBackupRetention() time.Duration {
+	if envDays := os.Getenv("CATNIP_OPERATION_BACKUP_DAYS"); envDays != "" {
+		if days, err := strconv.Atoi(envDays); err == nil && days > 0 {
+			return time.Duration(days) * 24 * time.Hour
+		}
+	}
+	return 7 * 24 * time.Hour // Default: 7 days
+}
+
+// operationBackupTime returns when the backup behind ref was taken, false for refs outside the
+// backup namespace or with a malformed name
+func operationBackupTime(ref string) (time.Time, bool) {
+	if !strings.HasPrefix(ref, operationBackupNamespace) {
+		return time.Time{}, false
+	}
+	createdAt, err := time.Parse(operationBackupTimeLayout, ref[strings.LastIndex(ref, "/")+1:])
+	return createdAt, err == nil
+}
+
+// backupBeforeRewrite points a new backup ref at a worktree's HEAD before operation rewrites
+// its history and records the backup in the activity log. The operation must not proceed when
+// the backup fails.
+func (s *GitService) backupBeforeRewrite(worktree *models.Worktree, operation string) (*models.OperationBackup, error) {
+	head, err := s.operations.GetCommitHash(worktree.Path, "HEAD")
+	if err != nil {
+		return nil, fmt.Errorf("failed to back up %s before %s: %v", worktree.Name, operation, err)
+	}
+	createdAt := time.Now().UTC()
+	backup := &models.OperationBackup{
+		ID:         createdAt.Format(operationBackupTimeLayout),
+		WorktreeID: worktree.ID,
+		Commit:     head,
+		Operation:  operation,
+		CreatedAt:  createdAt,
+		ExpiresAt:  createdAt.Add(getOperationBackupRetention()),
+	}
+	backup.Ref = operationBackupNamespace + worktree.ID + "/" + backup.ID
+
+	// The reflog keeps the operation, the empty old value refuses to overwrite another backup
+	if _, err := s.operations.ExecuteGit(worktree.Path, "update-ref", "--create-reflog", "-m", operation, backup.Ref, head, ""); err != nil {
+		return nil, fmt.Errorf("failed to back up %s before %s: %v", worktree.Name, operation, err)
+	}
+
+	s.stateManager.recordWorktreeActivity(ActivityOperationBackup, worktree, "", fmt.Sprintf("before %s: %s", operation, backup.Ref), "")
+	logger.Infof("💾 Backed up %s at %.8s before %s: %s", worktree.Name, head, operation, backup.Ref)
+	return backup, nil
+}
+
+// ListOperationBackups returns the backups taken of a worktree before history rewriting
+// operations that the refs cleanup hasn't expired yet, newest first
+func (s *GitService) ListOperationBackups(worktreeID string) ([]*models.OperationBackup, error) {
+	worktree, exists := s.stateManager.GetWorktree(worktreeID)
+	if !exists {
+		return nil, fmt.Errorf("worktree %s not found", worktreeID)
+	}
+
+	output, err := s.operations.ExecuteGit(worktree.Path, "for-each-ref", "--sort=-refname",
+		"--format=%(refname)%09%(objectname)%09%(subject)", operationBackupNamespace+worktree.ID+"/")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list backups of %s: %v", worktree.Name, err)
+	}
+
+	backups := []*models.OperationBackup{}
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) < 2 {
+			continue
+		}
+		createdAt, ok := operationBackupTime(fields[0])
+		if !ok {
+			continue
+		}
+		backup := &models.OperationBackup{
+			ID:         createdAt.Format(operationBackupTimeLayout),
+			WorktreeID: worktree.ID,
+			Ref:        fields[0],
+			Commit:     fields[1],
+			CreatedAt:  createdAt,
+			ExpiresAt:  createdAt.Add(getOperationBackupRetention()),
+		}
+		if len(fields) == 3 {
+			backup.Subject = fields[2]
+		}
+		if reflog, err := s.operations.ExecuteGit(worktree.Path, "log", "-g", "-1", "--format=%gs", backup.Ref); err == nil {
+			backup.Operation = strings.TrimSpace(string(reflog))
+		}
+		backups = append(backups, backup)
+	}
+	return backups, nil
+}
+
+// RestoreOperationBackup resets a worktree's branch to a backup. The current HEAD is backed up
+// first, so the restore can itself be undone; worktrees with uncommitted changes or an operation
+// in progress are refused.
+func (s *GitService) RestoreOperationBackup(worktreeID, backupID string) (*models.OperationBackup, error) {
+	worktree, exists := s.stateManager.GetWorktree(worktreeID)
+	if !exists {
+		return nil, fmt.Errorf("worktree %s not found", worktreeID)
+	}
+	if _, err := time.Parse(operationBackupTimeLayout, backupID); err != nil {
+		return nil, fmt.Errorf("backup %s not found", backupID)
+	}
+	ref := operationBackupNamespace + worktree.ID + "/" + backupID
+	commit, err := s.operations.GetCommitHash(worktree.Path, ref)
+	if err != nil {
+		return nil, fmt.Errorf("backup %s not found", backupID)
+	}
+
+	if s.gitOperationInProgress(worktree.Path) {
+		return nil, fmt.Errorf("cannot restore a backup of %s while a merge or rebase is in progress, finish or abort it first", worktree.Name)
+	}
+	if s.operations.IsDirty(worktree.Path) {
+		return nil, fmt.Errorf("cannot restore a backup of %s with uncommitted changes, commit or stash them first", worktree.Name)
+	}
+
+	undo, err := s.backupBeforeRewrite(worktree, "restore of backup "+backupID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.operations.ExecuteGit(worktree.Path, "reset", "--hard", commit); err != nil {
+		return nil, fmt.Errorf("failed to restore backup %s: %v", backupID, err)
+	}
+
+	s.stateManager.recordWorktreeActivity(ActivityBackupRestored, worktree, "", fmt.Sprintf("restored %s, previous HEAD in %s", ref, undo.Ref), "")
+	if s.worktreeCache != nil {
+		s.worktreeCache.ForceRefresh(worktreeID)
+	}
+	logger.Infof("⏪ Restored %s to backup %s at %.8s", worktree.Name, backupID, commit)
+	return undo, nil
+}
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/git"
+)
+
+func TestOperationBackups(t *testing.T) {
+	s, stateManager, repoPath, worktreePath := newRecreateTestService(t)
+	s.conflictResolver = git.NewConflictResolver(s.operations)
+
+	require.NoError(t, os.WriteFile(filepath.Join(worktreePath, "feature.txt"), []byte("feature\n"), 0644))
+	runTestGit(t, worktreePath, "add", "feature.txt")
+	runTestGit(t, worktreePath, "commit", "-m", "feature work")
+	require.NoError(t, os.WriteFile(filepath.Join(repoPath, "app.txt"), []byte("v2\n"), 0644))
+	runTestGit(t, repoPath, "commit", "-am", "upstream work")
+	original := runTestGit(t, worktreePath, "rev-parse", "HEAD")
+
+	backups, err := s.ListOperationBackups("wt-felix")
+	require.NoError(t, err)
+	assert.Empty(t, backups)
+
+	backup, err := s.SyncWorktree("wt-felix", SyncOptions{Strategy: "rebase"})
+	require.NoError(t, err)
+	require.NotNil(t, backup)
+	rebased := runTestGit(t, worktreePath, "rev-parse", "HEAD")
+	require.NotEqual(t, original, rebased)
+	assert.Equal(t, original, runTestGit(t, repoPath, "rev-parse", backup.Ref))
+
+	backups, err = s.ListOperationBackups("wt-felix")
+	require.NoError(t, err)
+	require.Len(t, backups, 1)
+	assert.Equal(t, backup.ID, backups[0].ID)
+	assert.Equal(t, original, backups[0].Commit)
+	assert.Equal(t, "feature work", backups[0].Subject)
+	assert.Equal(t, "rebase onto main", backups[0].Operation)
+
+	t.Run("RestoreRefusesUncommittedChanges", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(filepath.Join(worktreePath, "feature.txt"), []byte("wip\n"), 0644))
+		_, err := s.RestoreOperationBackup("wt-felix", backup.ID)
+		assert.ErrorContains(t, err, "uncommitted changes")
+		runTestGit(t, worktreePath, "checkout", "--", "feature.txt")
+
+		_, err = s.RestoreOperationBackup("wt-felix", "20200101T000000.000000000Z")
+		assert.ErrorContains(t, err, "not found")
+		_, err = s.RestoreOperationBackup("wt-felix", "../../heads/main")
+		assert.ErrorContains(t, err, "not found")
+	})
+
+	t.Run("RestoreCanBeUndone", func(t *testing.T) {
+		undo, err := s.RestoreOperationBackup("wt-felix", backup.ID)
+		require.NoError(t, err)
+		assert.Equal(t, original, runTestGit(t, worktreePath, "rev-parse", "HEAD"))
+		assert.Equal(t, rebased, undo.Commit)
+
+		backups, err := s.ListOperationBackups("wt-felix")
+		require.NoError(t, err)
+		require.Len(t, backups, 2)
+		assert.Equal(t, undo.ID, backups[0].ID, "newest first")
+		assert.Equal(t, "restore of backup "+backup.ID, backups[0].Operation)
+	})
+
+	t.Run("CleanupExpiresByAge", func(t *testing.T) {
+		expired := operationBackupNamespace + "wt-felix/20200101T000000.000000000Z"
+		malformed := operationBackupNamespace + "wt-felix/yesterday"
+		runTestGit(t, repoPath, "update-ref", expired, original)
+		runTestGit(t, repoPath, "update-ref", malformed, original)
+
+		repo, _ := stateManager.GetRepository("local/repo")
+		report := newCleanupReport(false)
+		require.NoError(t, s.cleanupCatnipRefs(repo, report))
+		assert.ElementsMatch(t, []CleanupItem{{RepoID: "local/repo", Name: expired}, {RepoID: "local/repo", Name: malformed}}, report.Refs)
+
+		backups, err := s.ListOperationBackups("wt-felix")
+		require.NoError(t, err)
+		assert.Len(t, backups, 2, "backups within the retention are kept")
+	})
+}
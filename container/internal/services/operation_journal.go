@@ -0,0 +1,404 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+	"github.com/vanpelt/catnip/internal/recovery"
+	"github.com/vanpelt/catnip/internal/redact"
+)
+
+const (
+	// operationJournalDir holds one descriptor per tracked operation inside the state directory
+	operationJournalDir = "operations"
+	// operationJournalRetention is how long finished operations stay queryable
+	operationJournalRetention = 7 * 24 * time.Hour
+)
+
+// Kinds of tracked operations, besides OperationMerge
+const (
+	OperationClone            = "clone"
+	OperationCleanupMerged    = "cleanup_merged"
+	OperationBulkPullRequests = "bulk_pull_requests"
+)
+
+// States of a tracked operation. Interrupted operations were running when the server stopped.
+const (
+	OperationRunning     = "running"
+	OperationSucceeded   = "succeeded"
+	OperationFailed      = "failed"
+	OperationInterrupted = "interrupted"
+)
+
+// What startup did with an interrupted operation
+const (
+	OperationRecoveryResuming   = "resuming"
+	OperationRecoveryResumed    = "resumed"
+	OperationRecoveryRolledBack = "rolled_back"
+	OperationRecoveryNone       = "none"
+)
+
+// operationJournal persists tracked operations so their outcome survives a restart
+type operationJournal struct {
+	mu         sync.RWMutex
+	dir        string
+	operations map[string]*models.Operation
+}
+
+// trackedOperation is the handle a running operation records its progress through. A nil
+// handle, as returned when the service has no journal, ignores every call.
+type trackedOperation struct {
+	journal *operationJournal
+	id      string
+}
+
+// newOperationJournal loads the journal in stateDir, dropping operations past the retention period
+func newOperationJournal(stateDir string) *operationJournal {
+	j := &operationJournal{
+		dir:        filepath.Join(stateDir, operationJournalDir),
+		operations: make(map[string]*models.Operation),
+	}
+	entries, err := os.ReadDir(j.dir)
+	if err != nil {
+		return j
+	}
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		path := filepath.Join(j.dir, entry.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			logger.Warnf("⚠️  Failed to read operation %s: %v", entry.Name(), err)
+			continue
+		}
+		var op models.Operation
+		if err := json.Unmarshal(data, &op); err != nil || op.ID == "" {
+			logger.Warnf("⚠️  Dropping unreadable operation %s: %v", entry.Name(), err)
+			_ = os.Remove(path)
+			continue
+		}
+		j.operations[op.ID] = &op
+	}
+	j.prune(time.Now())
+	return j
+}
+
+// begin records a new running operation
+func (j *operationJournal) begin(kind, target string, params map[string]string) *trackedOperation {
+	if j == nil {
+		return nil
+	}
+	now := time.Now()
+	op := &models.Operation{
+		ID:        uuid.New().String(),
+		Kind:      kind,
+		Target:    target,
+		Params:    redact.Default().Env(params),
+		State:     OperationRunning,
+		Phases:    []models.OperationPhase{},
+		StartedAt: now,
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.operations[op.ID] = op
+	j.save(op)
+	return &trackedOperation{journal: j, id: op.ID}
+}
+
+// phase records that the operation entered a new phase
+func (t *trackedOperation) phase(name string) {
+	if t == nil {
+		return
+	}
+	t.journal.update(t.id, func(op *models.Operation) {
+		op.Phases = append(op.Phases, models.OperationPhase{Name: name, At: time.Now()})
+	})
+}
+
+// finish records the terminal state of the operation, failed when err is set
+func (t *trackedOperation) finish(err error) {
+	if t == nil {
+		return
+	}
+	t.journal.update(t.id, func(op *models.Operation) {
+		op.State = OperationSucceeded
+		if err != nil {
+			op.State = OperationFailed
+			op.Error = redact.Text(err.Error())
+		}
+		now := time.Now()
+		op.FinishedAt = &now
+	})
+	t.journal.mu.Lock()
+	t.journal.prune(time.Now())
+	t.journal.mu.Unlock()
+}
+
+// update applies fn to an operation and persists it
+func (j *operationJournal) update(id string, fn func(op *models.Operation)) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	op, exists := j.operations[id]
+	if !exists {
+		return
+	}
+	fn(op)
+	j.save(op)
+}
+
+// save writes an operation's descriptor, callers hold j.mu
+func (j *operationJournal) save(op *models.Operation) {
+	data, err := json.MarshalIndent(op, "", "  ")
+	if err != nil {
+		logger.Warnf("⚠️  Failed to encode operation %s: %v", op.ID, err)
+		return
+	}
+	if err := os.MkdirAll(j.dir, 0755); err != nil {
+		logger.Warnf("⚠️  Failed to create operations directory: %v", err)
+		return
+	}
+	if err := writeFileAtomic(filepath.Join(j.dir, op.ID+".json"), data); err != nil {
+		logger.Warnf("⚠️  Failed to write operation %s: %v", op.ID, err)
+	}
+}
+
+// prune drops operations that finished more than operationJournalRetention ago, callers hold j.mu
+func (j *operationJournal) prune(now time.Time) {
+	for id, op := range j.operations {
+		if op.FinishedAt == nil || now.Sub(*op.FinishedAt) < operationJournalRetention {
+			continue
+		}
+		delete(j.operations, id)
+		if err := os.Remove(filepath.Join(j.dir, id+".json")); err != nil && !os.IsNotExist(err) {
+			logger.Warnf("⚠️  Failed to remove operation %s: %v", id, err)
+		}
+	}
+}
+
+// get returns a copy of an operation
+func (j *operationJournal) get(id string) (*models.Operation, bool) {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	op, exists := j.operations[id]
+	if !exists {
+		return nil, false
+	}
+	return copyOperation(op), true
+}
+
+// list returns copies of all operations, newest first
+func (j *operationJournal) list() []*models.Operation {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	ops := make([]*models.Operation, 0, len(j.operations))
+	for _, op := range j.operations {
+		ops = append(ops, copyOperation(op))
+	}
+	sort.Slice(ops, func(a, b int) bool { return ops[a].StartedAt.After(ops[b].StartedAt) })
+	return ops
+}
+
+func copyOperation(op *models.Operation) *models.Operation {
+	c := *op
+	c.Phases = append([]models.OperationPhase{}, op.Phases...)
+	if op.Params != nil {
+		c.Params = make(map[string]string, len(op.Params))
+		for k, v := range op.Params {
+			c.Params[k] = v
+		}
+	}
+	return &c
+}
+
+// lastPhase is the phase an operation was in, empty before its first phase
+func lastPhase(op *models.Operation) string {
+	if len(op.Phases) == 0 {
+		return ""
+	}
+	return op.Phases[len(op.Phases)-1].Name
+}
+
+// GetOperation returns a tracked operation. Operations that were running when the server
+// stopped are reported as interrupted, with what startup did to resume or roll them back.
+func (s *GitService) GetOperation(id string) (*models.Operation, error) {
+	if s.journal == nil {
+		return nil, fmt.Errorf("operation %s not found", id)
+	}
+	op, exists := s.journal.get(id)
+	if !exists {
+		return nil, fmt.Errorf("operation %s not found", id)
+	}
+	return op, nil
+}
+
+// ListOperations returns the tracked operations within the retention period, newest first
+func (s *GitService) ListOperations() []*models.Operation {
+	if s.journal == nil {
+		return []*models.Operation{}
+	}
+	return s.journal.list()
+}
+
+// recoverInterruptedOperations marks operations a restart interrupted and resumes the ones that
+// can safely run again (clone, cleanup, bulk pull requests) in the background. Interrupted
+// merges are rolled back instead, their outcome can't be known.
+func (s *GitService) recoverInterruptedOperations() {
+	// Oldest first, so operations resume in the order they were started
+	ops := s.journal.list()
+	var interrupted []*models.Operation
+	for i := len(ops) - 1; i >= 0; i-- {
+		// Includes operations whose recovery a second restart interrupted
+		op := ops[i]
+		if op.FinishedAt != nil {
+			continue
+		}
+		outcome := OperationRecoveryResuming
+		if op.Kind == OperationMerge {
+			outcome = OperationRecoveryNone
+		}
+		s.journal.update(op.ID, func(op *models.Operation) {
+			op.State = OperationInterrupted
+			op.Recovery = outcome
+		})
+		logger.Warnf("⚠️  Operation %s (%s %s) was interrupted in phase %q", op.ID, op.Kind, op.Target, lastPhase(op))
+		interrupted = append(interrupted, op)
+	}
+	if len(interrupted) == 0 {
+		return
+	}
+
+	recovery.SafeGo("operation-recovery", func() {
+		for _, op := range interrupted {
+			s.recoverOperation(op)
+		}
+	})
+}
+
+// recoverOperation resumes or rolls back one interrupted operation and records the outcome
+func (s *GitService) recoverOperation(op *models.Operation) {
+	if op.Kind == OperationMerge {
+		rolledBack := s.rollBackInterruptedMerge(op)
+		s.journal.update(op.ID, func(op *models.Operation) {
+			if rolledBack {
+				op.Recovery = OperationRecoveryRolledBack
+			}
+			now := time.Now()
+			op.FinishedAt = &now
+		})
+		return
+	}
+
+	var err error
+	switch op.Kind {
+	case OperationClone:
+		err = s.resumeClone(op)
+	case OperationCleanupMerged:
+		err = s.resumeCleanup(op)
+	case OperationBulkPullRequests:
+		err = s.resumeBulkPullRequests(op)
+	default:
+		err = fmt.Errorf("operations of kind %s can't be resumed", op.Kind)
+	}
+
+	s.journal.update(op.ID, func(op *models.Operation) {
+		op.Recovery = OperationRecoveryResumed
+		op.State = OperationSucceeded
+		if err != nil {
+			op.State = OperationFailed
+			op.Error = err.Error()
+		}
+		now := time.Now()
+		op.FinishedAt = &now
+	})
+	if err != nil {
+		logger.Warnf("⚠️  Failed to resume %s of %s: %v", op.Kind, op.Target, err)
+	} else {
+		logger.Infof("🔁 Resumed interrupted %s of %s", op.Kind, op.Target)
+	}
+}
+
+// resumeClone clones again, removing a partial bare repository left by the interrupted clone
+func (s *GitService) resumeClone(op *models.Operation) error {
+	org, repo, ok := strings.Cut(op.Target, "/")
+	if !ok {
+		return fmt.Errorf("invalid repository %s", op.Target)
+	}
+	if _, exists := s.stateManager.GetRepository(op.Target); !exists {
+		if barePath := op.Params["bare_path"]; barePath != "" {
+			if err := os.RemoveAll(barePath); err != nil {
+				return fmt.Errorf("failed to remove partial clone %s: %v", barePath, err)
+			}
+		}
+	}
+	_, _, err := s.CheckoutRepository(org, repo, op.Params["branch"], DefaultCloneOptions())
+	return err
+}
+
+// resumeCleanup cleans up the requested worktrees that still exist, or every merged worktree
+// when the interrupted cleanup wasn't limited to some
+func (s *GitService) resumeCleanup(op *models.Operation) error {
+	ids, limited := op.Params["worktree_ids"]
+	var remaining []string
+	if limited {
+		for _, id := range strings.Split(ids, ",") {
+			if _, exists := s.stateManager.GetWorktree(id); exists {
+				remaining = append(remaining, id)
+			}
+		}
+		if len(remaining) == 0 {
+			return nil
+		}
+	}
+	_, _, err := s.CleanupMergedWorktrees(remaining)
+	return err
+}
+
+// resumeBulkPullRequests opens the pull requests that are still missing, worktrees that got
+// theirs before the restart are skipped
+func (s *GitService) resumeBulkPullRequests(op *models.Operation) error {
+	selector := BulkPullRequestSelector{}
+	for _, id := range strings.Split(op.Params["worktree_ids"], ",") {
+		if _, exists := s.stateManager.GetWorktree(id); exists {
+			selector.WorktreeIDs = append(selector.WorktreeIDs, id)
+		}
+	}
+	if len(selector.WorktreeIDs) == 0 {
+		return nil
+	}
+	draft, _ := strconv.ParseBool(op.Params["draft"])
+	syncFirst, _ := strconv.ParseBool(op.Params["sync_first"])
+	_, err := s.CreatePullRequestsBulk(selector, BulkPullRequestOptions{
+		Draft:        draft,
+		SyncFirst:    syncFirst,
+		SyncStrategy: op.Params["sync_strategy"],
+		TextMode:     op.Params["text_mode"],
+		Actor:        op.Params["actor"],
+	})
+	return err
+}
+
+// rollBackInterruptedMerge aborts a merge the restart left half done in the repository.
+// Returns whether anything was rolled back.
+func (s *GitService) rollBackInterruptedMerge(op *models.Operation) bool {
+	repoPath := op.Params["repo_path"]
+	if lastPhase(op) != "merge" || repoPath == "" || !s.gitOperationInProgress(repoPath) {
+		return false
+	}
+	if output, err := s.runGitCommand(repoPath, "merge", "--abort"); err != nil {
+		logger.Warnf("⚠️  Failed to roll back interrupted merge in %s: %v\n%s", repoPath, err, output)
+		return false
+	}
+	logger.Infof("↩️  Rolled back interrupted merge of %s in %s", op.Target, repoPath)
+	return true
+}
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOperationJournal(t *testing.T) {
+	stateDir := t.TempDir()
+	journal := newOperationJournal(stateDir)
+
+	cloned := journal.begin(OperationClone, "owner/repo", map[string]string{"branch": "main"})
+	cloned.phase("clone")
+	cloned.phase("worktree")
+	cloned.finish(nil)
+	failed := journal.begin(OperationCleanupMerged, "merged worktrees", nil)
+	failed.finish(errors.New("cleanup completed with 1 errors"))
+	running := journal.begin(OperationBulkPullRequests, "owner/repo", nil)
+	running.phase("repo/felix")
+
+	// A nil journal, as in services built without one, ignores tracking
+	var none *operationJournal
+	none.begin(OperationClone, "owner/other", nil).finish(nil)
+
+	reloaded := newOperationJournal(stateDir)
+	require.Len(t, reloaded.list(), 3)
+
+	op, exists := reloaded.get(cloned.id)
+	require.True(t, exists)
+	assert.Equal(t, OperationSucceeded, op.State)
+	assert.Equal(t, "worktree", lastPhase(op))
+	assert.Equal(t, "main", op.Params["branch"])
+	require.NotNil(t, op.FinishedAt)
+
+	op, _ = reloaded.get(failed.id)
+	assert.Equal(t, OperationFailed, op.State)
+	assert.Equal(t, "cleanup completed with 1 errors", op.Error)
+
+	op, _ = reloaded.get(running.id)
+	assert.Equal(t, OperationRunning, op.State)
+	assert.Nil(t, op.FinishedAt)
+
+	t.Run("PrunedAfterRetention", func(t *testing.T) {
+		reloaded.mu.Lock()
+		reloaded.prune(time.Now().Add(operationJournalRetention + time.Minute))
+		reloaded.mu.Unlock()
+
+		ops := reloaded.list()
+		require.Len(t, ops, 1, "running operations are kept")
+		assert.Equal(t, running.id, ops[0].ID)
+		assert.NoFileExists(t, filepath.Join(stateDir, operationJournalDir, cloned.id+".json"))
+	})
+}
+
+func TestRecoverInterruptedOperations(t *testing.T) {
+	s, stateManager, repoPath, worktreePath := newRecreateTestService(t)
+	s.journal = newOperationJournal(stateManager.stateDir)
+
+	// A merge that stopped with the repository mid-merge
+	require.NoError(t, os.WriteFile(filepath.Join(worktreePath, "app.txt"), []byte("feature\n"), 0644))
+	runTestGit(t, worktreePath, "commit", "-am", "feature change")
+	runTestGit(t, repoPath, "merge", "--no-commit", "--no-ff", "feature/felix")
+	merge := s.journal.begin(OperationMerge, "repo/felix", map[string]string{"repo_path": repoPath})
+	merge.phase("push")
+	merge.phase("merge")
+
+	// A cleanup whose worktrees are gone by now resumes with nothing left to do
+	cleanup := s.journal.begin(OperationCleanupMerged, "merged worktrees", map[string]string{"worktree_ids": "wt-gone"})
+	cleanup.phase("delete repo/gone")
+
+	s.recoverInterruptedOperations()
+
+	op, err := s.GetOperation(merge.id)
+	require.NoError(t, err)
+	assert.Equal(t, OperationInterrupted, op.State)
+
+	require.Eventually(t, func() bool {
+		cleaned, _ := s.GetOperation(cleanup.id)
+		merged, _ := s.GetOperation(merge.id)
+		return cleaned.State == OperationSucceeded && merged.FinishedAt != nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	op, _ = s.GetOperation(cleanup.id)
+	assert.Equal(t, OperationRecoveryResumed, op.Recovery)
+	op, _ = s.GetOperation(merge.id)
+	assert.Equal(t, OperationInterrupted, op.State, "a merge's outcome can't be known, it stays interrupted")
+	assert.Equal(t, OperationRecoveryRolledBack, op.Recovery)
+	assert.False(t, s.gitOperationInProgress(repoPath))
+
+	_, err = s.GetOperation("missing")
+	assert.ErrorContains(t, err, "operation missing not found")
+}
@@ -0,0 +1,206 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/git"
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// Operations whose durations are tracked on worktrees and repositories
+const (
+	OperationFetch       = "fetch"
+	OperationSync        = "sync"
+	OperationMerge       = "merge"
+	OperationPullRequest = "pull_request"
+	OperationDiff        = "diff"
+)
+
+const (
+	// operationTimingWindow is the number of samples kept per operation
+	operationTimingWindow = 10
+	// diffTimingInterval limits how often fast diff computations are recorded, since the UI polls diffs
+	diffTimingInterval = time.Minute
+	// slowDiffDuration is the duration above which a diff is always recorded
+	slowDiffDuration = time.Second
+	// slowRepoSizeKB is the packed repository size above which operations are flagged as likely slow
+	slowRepoSizeKB = 1 << 20 // 1 GiB
+)
+
+// OperationEstimate is the expected duration of an operation on a worktree
+type OperationEstimate struct {
+	Operation string `json:"operation"`
+	// Average duration of recent runs in milliseconds (0 when no samples exist)
+	EstimatedMs int64 `json:"estimated_ms"`
+	// Average network and local git time of recent runs in milliseconds
+	NetworkMs int64 `json:"network_ms"`
+	LocalMs   int64 `json:"local_ms"`
+	// Number of samples the estimate is based on
+	SampleCount int `json:"sample_count"`
+	// Whether the samples come from the repository because the worktree has none yet
+	FromRepository bool `json:"from_repository,omitempty"`
+	// Why the operation is likely to be slow, if it is
+	Warning string `json:"warning,omitempty"`
+}
+
+// OperationEstimatesResponse holds the estimates for all tracked operations of a worktree
+type OperationEstimatesResponse struct {
+	WorktreeID       string                        `json:"worktree_id"`
+	IsShallow        bool                          `json:"is_shallow"`
+	RepositorySizeKB int64                         `json:"repository_size_kb"`
+	Estimates        map[string]*OperationEstimate `json:"estimates"`
+}
+
+// timeOperation starts measuring an operation on a worktree. The returned function records
+// the sample on the worktree and its repository once the operation finished.
+func (s *GitService) timeOperation(worktree *models.Worktree, operation string) func(err error) {
+	dirs := []string{worktree.Path}
+	if repo, exists := s.stateManager.GetRepository(worktree.RepoID); exists {
+		dirs = append(dirs, repo.Path)
+	}
+	span := git.BeginTiming(dirs...)
+
+	return func(err error) {
+		result := span.End()
+		if operation != OperationDiff {
+			// Operations may have moved HEAD or the source ref
+			s.diffCache.invalidate(worktree.ID)
+		}
+		if operation == OperationDiff && result.Total < slowDiffDuration && recentlySampled(worktree.OperationTimings, operation, diffTimingInterval) {
+			return
+		}
+
+		sample := models.OperationTimingSample{
+			At:         time.Now(),
+			DurationMs: result.Total.Milliseconds(),
+			NetworkMs:  result.Network.Milliseconds(),
+			LocalMs:    result.Local.Milliseconds(),
+			Success:    err == nil,
+		}
+		if err := s.stateManager.RecordOperationTiming(worktree.ID, worktree.RepoID, operation, sample); err != nil {
+			logger.Warnf("⚠️  Failed to record %s timing for %s: %v", operation, worktree.Name, err)
+		}
+	}
+}
+
+// GetOperationEstimates returns expected durations for the tracked operations of a worktree,
+// flagging operations likely to be slow because of shallow history or repository size
+func (s *GitService) GetOperationEstimates(worktreeID string) (*OperationEstimatesResponse, error) {
+	worktree, exists := s.stateManager.GetWorktree(worktreeID)
+	if !exists {
+		return nil, fmt.Errorf("worktree %s not found", worktreeID)
+	}
+
+	var repoTimings map[string]*models.OperationTimings
+	response := &OperationEstimatesResponse{
+		WorktreeID: worktreeID,
+		Estimates:  make(map[string]*OperationEstimate),
+	}
+	if repo, exists := s.stateManager.GetRepository(worktree.RepoID); exists {
+		repoTimings = repo.OperationTimings
+		response.RepositorySizeKB = s.repositorySizeKB(repo.Path)
+	}
+	if output, err := s.operations.ExecuteGit(worktree.Path, "rev-parse", "--is-shallow-repository"); err == nil {
+		response.IsShallow = strings.TrimSpace(string(output)) == "true"
+	}
+
+	for _, operation := range []string{OperationFetch, OperationSync, OperationMerge, OperationPullRequest, OperationDiff} {
+		estimate := &OperationEstimate{Operation: operation}
+		timings := worktree.OperationTimings[operation]
+		if timings == nil || len(timings.Samples) == 0 {
+			timings = repoTimings[operation]
+			estimate.FromRepository = timings != nil
+		}
+		if timings != nil {
+			estimate.EstimatedMs = timings.AverageMs
+			estimate.NetworkMs = timings.AverageNetworkMs
+			estimate.LocalMs = timings.AverageLocalMs
+			estimate.SampleCount = len(timings.Samples)
+		}
+		estimate.Warning = slowOperationWarning(operation, response.IsShallow, response.RepositorySizeKB)
+		response.Estimates[operation] = estimate
+	}
+
+	return response, nil
+}
+
+// repositorySizeKB returns the on-disk size of the repository's objects in KiB
+func (s *GitService) repositorySizeKB(repoPath string) int64 {
+	output, err := s.operations.ExecuteGit(repoPath, "count-objects", "-v")
+	if err != nil {
+		return 0
+	}
+
+	var total int64
+	for _, line := range strings.Split(string(output), "\n") {
+		key, value, found := strings.Cut(line, ": ")
+		if !found || (key != "size" && key != "size-pack") {
+			continue
+		}
+		if kb, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64); err == nil {
+			total += kb
+		}
+	}
+	return total
+}
+
+// slowOperationWarning explains why an operation is likely to be slow before it runs
+func slowOperationWarning(operation string, isShallow bool, sizeKB int64) string {
+	var reasons []string
+	if isShallow && (operation == OperationSync || operation == OperationMerge || operation == OperationPullRequest) {
+		reasons = append(reasons, "this is a shallow clone, so the full history has to be fetched first")
+	}
+	if sizeKB >= slowRepoSizeKB && operation != OperationPullRequest {
+		reasons = append(reasons, fmt.Sprintf("the repository is large (%.1f GB)", float64(sizeKB)/float64(1<<20)))
+	}
+	if len(reasons) == 0 {
+		return ""
+	}
+	return "May be slow: " + strings.Join(reasons, " and ")
+}
+
+// withOperationTimingSample returns a copy of timings with sample added to the operation's
+// rolling window. Copying keeps readers holding the previous map consistent.
+func withOperationTimingSample(timings map[string]*models.OperationTimings, operation string, sample models.OperationTimingSample) map[string]*models.OperationTimings {
+	result := make(map[string]*models.OperationTimings, len(timings)+1)
+	for op, t := range timings {
+		result[op] = t
+	}
+
+	var samples []models.OperationTimingSample
+	if existing := timings[operation]; existing != nil {
+		samples = append(samples, existing.Samples...)
+	}
+	samples = append(samples, sample)
+	if len(samples) > operationTimingWindow {
+		samples = samples[len(samples)-operationTimingWindow:]
+	}
+
+	updated := &models.OperationTimings{Samples: samples}
+	var total, network, local int64
+	for _, s := range samples {
+		total += s.DurationMs
+		network += s.NetworkMs
+		local += s.LocalMs
+	}
+	count := int64(len(samples))
+	updated.AverageMs = total / count
+	updated.AverageNetworkMs = network / count
+	updated.AverageLocalMs = local / count
+
+	result[operation] = updated
+	return result
+}
+
+// recentlySampled reports whether the operation was recorded within the interval
+func recentlySampled(timings map[string]*models.OperationTimings, operation string, interval time.Duration) bool {
+	existing := timings[operation]
+	if existing == nil || len(existing.Samples) == 0 {
+		return false
+	}
+	return time.Since(existing.Samples[len(existing.Samples)-1].At) < interval
+}
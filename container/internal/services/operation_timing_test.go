@@ -0,0 +1,75 @@
+package services
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+func TestWithOperationTimingSample(t *testing.T) {
+	var timings map[string]*models.OperationTimings
+	for i := 1; i <= operationTimingWindow+2; i++ {
+		previous := timings
+		timings = withOperationTimingSample(timings, OperationSync, models.OperationTimingSample{
+			DurationMs: int64(i * 1000),
+			NetworkMs:  int64(i * 800),
+			LocalMs:    int64(i * 100),
+			Success:    true,
+		})
+		if previous != nil {
+			assert.Len(t, previous[OperationSync].Samples, min(i-1, operationTimingWindow), "previous window must not be mutated")
+		}
+	}
+
+	sync := timings[OperationSync]
+	require.Len(t, sync.Samples, operationTimingWindow)
+	assert.Equal(t, int64(3000), sync.Samples[0].DurationMs, "oldest samples are dropped first")
+	assert.Equal(t, int64(7500), sync.AverageMs)
+	assert.Equal(t, int64(6000), sync.AverageNetworkMs)
+	assert.Equal(t, int64(750), sync.AverageLocalMs)
+	assert.NotContains(t, timings, OperationFetch)
+}
+
+func TestSlowOperationWarning(t *testing.T) {
+	assert.Empty(t, slowOperationWarning(OperationSync, false, 1024))
+	assert.Contains(t, slowOperationWarning(OperationSync, true, 0), "shallow clone")
+	assert.Empty(t, slowOperationWarning(OperationDiff, true, 0))
+	assert.Contains(t, slowOperationWarning(OperationDiff, false, 2<<20), "2.0 GB")
+}
+
+func TestGetOperationEstimates(t *testing.T) {
+	root := t.TempDir()
+	repoPath := initUnbornTestRepo(t, filepath.Join(root, "repo"))
+
+	s := newTestGitService(t, root)
+	stateManager := s.stateManager
+	require.NoError(t, stateManager.AddRepository(&models.Repository{ID: "local/repo", Path: repoPath}))
+	require.NoError(t, stateManager.AddWorktree(&models.Worktree{ID: "wt-felix", RepoID: "local/repo", Name: "felix", Path: repoPath}))
+	require.NoError(t, stateManager.AddWorktree(&models.Worktree{ID: "wt-luna", RepoID: "local/repo", Name: "luna", Path: repoPath}))
+
+	felix, _ := stateManager.GetWorktree("wt-felix")
+	done := s.timeOperation(felix, OperationSync)
+	_, err := s.operations.ExecuteGit(repoPath, "status", "--porcelain")
+	require.NoError(t, err)
+	done(nil)
+
+	felix, _ = stateManager.GetWorktree("wt-felix")
+	require.Len(t, felix.OperationTimings[OperationSync].Samples, 1)
+	sample := felix.OperationTimings[OperationSync].Samples[0]
+	assert.True(t, sample.Success)
+	assert.GreaterOrEqual(t, sample.DurationMs, sample.LocalMs)
+
+	estimates, err := s.GetOperationEstimates("wt-luna")
+	require.NoError(t, err)
+	assert.False(t, estimates.IsShallow)
+	syncEstimate := estimates.Estimates[OperationSync]
+	assert.True(t, syncEstimate.FromRepository, "worktrees without samples fall back to the repository")
+	assert.Equal(t, 1, syncEstimate.SampleCount)
+	assert.Zero(t, estimates.Estimates[OperationMerge].SampleCount)
+
+	_, err = s.GetOperationEstimates("missing")
+	assert.Error(t, err)
+}
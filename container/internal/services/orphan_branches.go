@@ -0,0 +1,279 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/git"
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+const (
+	// orphanBranchWarningSource is the repository health warning source for branches with
+	// commits that no worktree has checked out
+	orphanBranchWarningSource = "orphan-branches"
+	// defaultOrphanBranchWindow is how recently a branch outside catnip's namespace has to have
+	// been committed to for it to count as an orphan, configurable via CATNIP_ORPHAN_BRANCH_DAYS
+	defaultOrphanBranchWindow = 7 * 24 * time.Hour
+	// orphanBranchScanInterval is how often every repository is scanned for orphan branches
+	orphanBranchScanInterval = 15 * time.Minute
+	// orphanBranchInitialDelay postpones the first scan until startup work has settled
+	orphanBranchInitialDelay = time.Minute
+	// maxOrphanBranches bounds the orphan branches listed for one repository
+	maxOrphanBranches = 50
+	// maxWarnedOrphanBranches is how many orphan branches the health warning names
+	maxWarnedOrphanBranches = 5
+)
+
+// getOrphanBranchWindow returns how recently a branch outside catnip's namespace has to have
+// been committed to for it to count as an orphan
+func getOrphanBranchWindow() time.Duration {
+	if envDays := os.Getenv("CATNIP_ORPHAN_BRANCH_DAYS"); envDays != "" {
+		if days, err := strconv.Atoi(envDays); err == nil && days > 0 {
+			return time.Duration(days) * 24 * time.Hour
+		}
+	}
+	return defaultOrphanBranchWindow
+}
+
+// orphanBranchBase returns the branch orphans of repo are compared against: its default branch,
+// or main or master when that isn't a local branch. Empty when there is none.
+func (s *GitService) orphanBranchBase(repo *models.Repository) string {
+	if repo.DefaultBranch != "" {
+		if err := s.operations.ShowRef(repo.Path, "refs/heads/"+repo.DefaultBranch, git.ShowRefOptions{Verify: true, Quiet: true}); err == nil {
+			return repo.DefaultBranch
+		}
+	}
+	return s.cleanupBaseRef(repo)
+}
+
+// claimedBranchRefs returns the refs of repo that belong to a worktree: those checked out by
+// git or recorded in state, the nice branches mapped to them and their preview branches
+func (s *GitService) claimedBranchRefs(repo *models.Repository) (map[string]bool, error) {
+	checkedOut, err := s.checkedOutBranches(repo)
+	if err != nil {
+		return nil, err
+	}
+	claimed := make(map[string]bool, len(checkedOut))
+	claim := func(branch string) {
+		if branch == "" {
+			return
+		}
+		if !strings.HasPrefix(branch, "refs/") {
+			branch = "refs/heads/" + branch
+		}
+		claimed[branch] = true
+		if name, isCatnip := strings.CutPrefix(branch, "refs/catnip/"); isCatnip {
+			claimed["refs/heads/"+catnipBranchPrefix+name] = true
+		}
+	}
+	for branch := range checkedOut {
+		claim(branch)
+	}
+	for _, worktree := range s.stateManager.GetAllWorktrees() {
+		if worktree.RepoID == repo.ID {
+			claim(worktree.Branch)
+		}
+	}
+
+	// Nice branches belong to the worktree of the catnip ref they are mapped from
+	output, _ := s.operations.ExecuteGit(repo.Path, "config", "--get-regexp", "catnip\\.branch-map\\.")
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		key, branch, found := strings.Cut(strings.TrimSpace(line), " ")
+		if !found {
+			continue
+		}
+		ref := strings.ReplaceAll(strings.TrimPrefix(key, "catnip.branch-map."), ".", "/")
+		if claimed[ref] {
+			claim(branch)
+		}
+	}
+	return claimed, nil
+}
+
+// findOrphanBranches lists the branches of repo with commits ahead of its default branch that
+// no worktree has: every one in catnip's namespace and others committed to within the orphan
+// window, most recently committed first. One for-each-ref lists the candidates.
+func (s *GitService) findOrphanBranches(repo *models.Repository) ([]models.OrphanBranch, error) {
+	base := s.orphanBranchBase(repo)
+	if base == "" {
+		return []models.OrphanBranch{}, nil
+	}
+	claimed, err := s.claimedBranchRefs(repo)
+	if err != nil {
+		return nil, err
+	}
+	output, err := s.operations.ExecuteGit(repo.Path, "for-each-ref", "--no-merged=refs/heads/"+base, "--sort=-committerdate",
+		"--format=%(refname)%00%(objectname)%00%(committerdate:unix)", "refs/heads/", "refs/catnip/")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list branches: %v", err)
+	}
+
+	cutoff := time.Now().Add(-getOrphanBranchWindow())
+	orphans := []models.OrphanBranch{}
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		fields := strings.Split(line, "\x00")
+		if len(fields) != 3 || claimed[fields[0]] {
+			continue
+		}
+		ref, commit := fields[0], fields[1]
+		committed, _ := strconv.ParseInt(fields[2], 10, 64)
+		orphan := models.OrphanBranch{
+			Branch:      strings.TrimPrefix(ref, "refs/heads/"),
+			Ref:         ref,
+			Commit:      commit,
+			CommittedAt: time.Unix(committed, 0),
+			Catnip:      strings.HasPrefix(ref, "refs/catnip/") || strings.HasPrefix(ref, "refs/heads/"+catnipBranchPrefix),
+		}
+		if !orphan.Catnip && orphan.CommittedAt.Before(cutoff) {
+			continue
+		}
+		count, err := s.operations.GetCommitCount(repo.Path, "refs/heads/"+base, commit)
+		if err != nil || count == 0 {
+			continue
+		}
+		orphan.CommitsAhead = count
+		orphans = append(orphans, orphan)
+		if len(orphans) == maxOrphanBranches {
+			break
+		}
+	}
+	return orphans, nil
+}
+
+// recordOrphanBranches raises or clears the repository's orphan branch health warning
+func (s *GitService) recordOrphanBranches(repoID string, orphans []models.OrphanBranch) {
+	message := ""
+	if len(orphans) > 0 {
+		names := make([]string, 0, maxWarnedOrphanBranches)
+		for _, orphan := range orphans[:min(len(orphans), maxWarnedOrphanBranches)] {
+			names = append(names, orphan.Branch)
+		}
+		if len(orphans) > maxWarnedOrphanBranches {
+			names = append(names, fmt.Sprintf("and %d more", len(orphans)-maxWarnedOrphanBranches))
+		}
+		message = fmt.Sprintf("%d branches have commits but no worktree, adopt them to keep working on them: %s", len(orphans), strings.Join(names, ", "))
+		if len(orphans) == 1 {
+			message = "1 branch has commits but no worktree, adopt it to keep working on it: " + names[0]
+		}
+	}
+	if previous := s.stateManager.RepositoryHealthWarnings(repoID)[orphanBranchWarningSource]; message != "" && message != previous {
+		logger.Infof("🌿 Found branches without a worktree in %s: %s", repoID, message)
+	}
+	if err := s.stateManager.SetRepositoryHealthWarning(repoID, orphanBranchWarningSource, message); err != nil {
+		logger.Warnf("⚠️  Failed to record orphan branches for %s: %v", repoID, err)
+	}
+}
+
+// GetOrphanBranches returns the branches of a repository with commits ahead of its default
+// branch that no worktree has, such as branches created from a terminal, and updates the
+// repository's health warning about them
+func (s *GitService) GetOrphanBranches(repoID string) ([]models.OrphanBranch, error) {
+	repo, exists := s.stateManager.GetRepository(repoID)
+	if !exists {
+		return nil, fmt.Errorf("repository %s not found", repoID)
+	}
+	orphans, err := s.findOrphanBranches(repo)
+	if err != nil {
+		return nil, err
+	}
+	s.recordOrphanBranches(repoID, orphans)
+	return orphans, nil
+}
+
+// AdoptBranch creates a worktree checked out on an orphan branch of a repository, so work done
+// on it outside catnip becomes manageable again. The branch has to be listed by
+// GetOrphanBranches; it is given by its short name or full ref. creation says on whose behalf,
+// nil when catnip adopts it by itself.
+func (s *GitService) AdoptBranch(repoID, branch string, creation *models.CreationContext) (*models.Worktree, error) {
+	repo, exists := s.stateManager.GetRepository(repoID)
+	if !exists {
+		return nil, fmt.Errorf("repository %s not found", repoID)
+	}
+	orphans, err := s.findOrphanBranches(repo)
+	if err != nil {
+		return nil, err
+	}
+	var orphan *models.OrphanBranch
+	for i := range orphans {
+		if orphans[i].Branch == branch || orphans[i].Ref == branch {
+			orphan = &orphans[i]
+			break
+		}
+	}
+	if orphan == nil {
+		return nil, fmt.Errorf("branch %s of %s isn't an orphan branch: it is merged, checked out by a worktree or doesn't exist", branch, repoID)
+	}
+
+	req := git.CreateWorktreeRequest{
+		Repository:     repo,
+		SourceBranch:   s.orphanBranchBase(repo),
+		BranchName:     orphan.Branch,
+		WorkspaceDir:   getWorkspaceDir(),
+		MaxPathLength:  s.worktreeMaxPathLength(repo.Path),
+		ExistingBranch: true,
+	}
+	unlock := s.lockWorktreeAdd(repo.Path)
+	var worktree *models.Worktree
+	if strings.HasPrefix(repo.ID, "local/") {
+		worktree, err = s.gitWorktreeManager.CreateLocalWorktree(req)
+	} else {
+		worktree, err = s.gitWorktreeManager.CreateWorktree(req)
+	}
+	unlock()
+	if err != nil {
+		return nil, fmt.Errorf("failed to adopt branch %s: %v", orphan.Branch, err)
+	}
+	worktree.Toolchains = DetectToolchains(worktree.Path)
+	applyCreationContext(worktree, creation)
+
+	if err := s.stateManager.AddWorktree(worktree); err != nil {
+		logger.Warnf("⚠️ Failed to add worktree to state: %v", err)
+	}
+	s.startWorktree(worktree, false)
+	logger.Infof("✅ Adopted branch %s of %s into worktree %s", orphan.Branch, repoID, worktree.Name)
+
+	if orphans, err := s.findOrphanBranches(repo); err == nil {
+		s.recordOrphanBranches(repoID, orphans)
+	}
+	return worktree, nil
+}
+
+// scanOrphanBranches refreshes the orphan branch health warning of every available repository.
+// Worktrees are only ever created for orphans by AdoptBranch.
+func (s *GitService) scanOrphanBranches() {
+	for _, repo := range s.stateManager.GetAllRepositories() {
+		if !repo.Available {
+			continue
+		}
+		orphans, err := s.findOrphanBranches(repo)
+		if err != nil {
+			logger.Debugf("🌿 Failed to scan %s for orphan branches: %v", repo.ID, err)
+			continue
+		}
+		s.recordOrphanBranches(repo.ID, orphans)
+	}
+}
+
+// startOrphanBranchScanner periodically looks for orphan branches until the service stops
+func (s *GitService) startOrphanBranchScanner() {
+	initial := time.NewTimer(orphanBranchInitialDelay)
+	defer initial.Stop()
+	ticker := time.NewTicker(orphanBranchScanInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-initial.C:
+			s.scanOrphanBranches()
+		case <-ticker.C:
+			s.scanOrphanBranches()
+		case <-s.stopCh:
+			return
+		}
+	}
+}
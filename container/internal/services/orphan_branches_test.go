@@ -0,0 +1,85 @@
+package services
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrphanBranches(t *testing.T) {
+	s, stateManager, repoPath, worktreePath := newRecreateTestService(t)
+	s.worktreeCache = NewWorktreeStatusCache(s.operations, stateManager)
+	t.Cleanup(s.worktreeCache.Stop)
+	// branchOff commits on a new branch of repoPath, committed at date when given
+	branchOff := func(branch, date string) string {
+		runTestGit(t, repoPath, "checkout", "-q", "-b", branch, "main")
+		cmd := exec.Command("git", "commit", "--allow-empty", "-m", "work on "+branch)
+		cmd.Dir = repoPath
+		cmd.Env = os.Environ()
+		if date != "" {
+			cmd.Env = append(cmd.Env, "GIT_AUTHOR_DATE="+date, "GIT_COMMITTER_DATE="+date)
+		}
+		output, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(output))
+		commit := runTestGit(t, repoPath, "rev-parse", "HEAD")
+		runTestGit(t, repoPath, "checkout", "-q", "main")
+		return commit
+	}
+	old := time.Now().AddDate(0, 0, -30).Format(time.RFC3339)
+	spike := branchOff("spike/retry", "")
+	branchOff("old/idea", old)
+	runTestGit(t, repoPath, "update-ref", "refs/catnip/ghost", branchOff("ghost-base", old))
+	runTestGit(t, repoPath, "branch", "-D", "ghost-base")
+	runTestGit(t, repoPath, "branch", "merged/fix", "main")
+	runTestGit(t, worktreePath, "commit", "--allow-empty", "-m", "felix at work")
+
+	orphans, err := s.GetOrphanBranches("local/repo")
+	require.NoError(t, err)
+	require.Len(t, orphans, 2, "merged, stale and checked out branches aren't orphans")
+	assert.Equal(t, "spike/retry", orphans[0].Branch)
+	assert.Equal(t, "refs/heads/spike/retry", orphans[0].Ref)
+	assert.Equal(t, spike, orphans[0].Commit)
+	assert.Equal(t, 1, orphans[0].CommitsAhead)
+	assert.False(t, orphans[0].Catnip)
+	assert.Equal(t, "refs/catnip/ghost", orphans[1].Branch)
+	assert.True(t, orphans[1].Catnip, "catnip refs are orphans however old")
+	assert.Equal(t, "2 branches have commits but no worktree, adopt them to keep working on them: spike/retry, refs/catnip/ghost",
+		stateManager.RepositoryHealthWarnings("local/repo")[orphanBranchWarningSource])
+
+	t.Run("WindowIsConfigurable", func(t *testing.T) {
+		t.Setenv("CATNIP_ORPHAN_BRANCH_DAYS", "60")
+		orphans, err := s.GetOrphanBranches("local/repo")
+		require.NoError(t, err)
+		assert.Len(t, orphans, 3)
+	})
+
+	t.Run("AdoptChecksOutTheExistingBranch", func(t *testing.T) {
+		_, err := s.AdoptBranch("local/repo", "merged/fix", nil)
+		assert.ErrorContains(t, err, "isn't an orphan branch")
+		_, err = s.AdoptBranch("local/repo", "feature/felix", nil)
+		assert.ErrorContains(t, err, "isn't an orphan branch")
+
+		worktree, err := s.AdoptBranch("local/repo", "spike/retry", nil)
+		require.NoError(t, err)
+		assert.Equal(t, "spike/retry", worktree.Branch)
+		assert.Equal(t, "main", worktree.SourceBranch)
+		assert.Equal(t, 1, worktree.CommitCount)
+		assert.Equal(t, filepath.Join(getWorkspaceDir(), "repo", "spike", "retry"), worktree.Path)
+		assert.Equal(t, "refs/heads/spike/retry", runTestGit(t, worktree.Path, "symbolic-ref", "HEAD"))
+		assert.Equal(t, spike, runTestGit(t, worktree.Path, "rev-parse", "HEAD"), "the branch isn't recreated")
+		_, exists := stateManager.GetWorktree(worktree.ID)
+		assert.True(t, exists)
+		assert.Equal(t, "1 branch has commits but no worktree, adopt it to keep working on it: refs/catnip/ghost",
+			stateManager.RepositoryHealthWarnings("local/repo")[orphanBranchWarningSource])
+
+		worktree, err = s.AdoptBranch("local/repo", "refs/catnip/ghost", nil)
+		require.NoError(t, err)
+		assert.Equal(t, "refs/catnip/ghost", runTestGit(t, worktree.Path, "symbolic-ref", "HEAD"))
+		assert.NotContains(t, stateManager.RepositoryHealthWarnings("local/repo"), orphanBranchWarningSource)
+	})
+}
@@ -660,7 +660,7 @@ func (pm *PortMonitor) shouldTrackPort(workingDir string) bool {
 	}
 
 	// Always track ports from our workspace directory
-	if strings.HasPrefix(workingDir, config.Runtime.WorkspaceDir) {
+	if _, ok := config.RelativePath(config.Runtime.WorkspaceDir, workingDir); ok {
 		return true
 	}
 
@@ -668,7 +668,7 @@ func (pm *PortMonitor) shouldTrackPort(workingDir string) bool {
 	if config.Runtime.CurrentRepo != "" {
 		// Get current working directory (where catnip serve was started)
 		if cwd, err := os.Getwd(); err == nil {
-			if strings.HasPrefix(workingDir, cwd) {
+			if _, ok := config.RelativePath(cwd, workingDir); ok {
 				return true
 			}
 		}
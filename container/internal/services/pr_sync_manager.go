@@ -3,17 +3,21 @@ package services
 import (
 	"encoding/json"
 	"fmt"
-	"os/exec"
 	"regexp"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/vanpelt/catnip/internal/git"
 	"github.com/vanpelt/catnip/internal/logger"
 	"github.com/vanpelt/catnip/internal/models"
+	"github.com/vanpelt/catnip/internal/recovery"
 )
 
+// pullRequestURLPattern extracts the repository and number from a GitHub pull request URL
+var pullRequestURLPattern = regexp.MustCompile(`github\.com/([^/]+/[^/]+)/pull/(\d+)`)
+
 // PRSyncManager handles periodic synchronization of pull request states
 type PRSyncManager struct {
 	stateManager  *WorktreeStateManager
@@ -24,6 +28,13 @@ type PRSyncManager struct {
 	mutex         sync.RWMutex
 	isRunning     bool
 	isInitialized bool // Prevents worktree updates during startup
+	rateLimits    *git.RateLimitTracker
+	nextSync      time.Time // Syncs are skipped until then while the GitHub rate limit is low
+
+	// Fetches the states of pull requests in a repository, syncRepositoryPRs unless replaced by tests
+	fetchStates func(repoID string, prNumbers []int) (map[string]*models.PullRequestState, error)
+	refreshMu   sync.Mutex
+	refreshing  map[string]bool // PR key -> another refresh was requested while one runs
 }
 
 var (
@@ -39,7 +50,10 @@ func GetPRSyncManager(stateManager *WorktreeStateManager) *PRSyncManager {
 			prStateCache: make(map[string]*models.PullRequestState),
 			syncInterval: time.Minute, // Sync every minute
 			stopChan:     make(chan bool),
+			refreshing:   make(map[string]bool),
+			rateLimits:   git.GitHubRateLimits(),
 		}
+		prSyncManagerInstance.fetchStates = prSyncManagerInstance.syncRepositoryPRs
 	})
 
 	// If stateManager provided and instance exists but has nil stateManager, set it
@@ -105,7 +119,8 @@ func (pm *PRSyncManager) syncLoop() {
 	}
 }
 
-// performSync executes a single sync cycle
+// performSync executes a single sync cycle, stretching the interval between cycles while the
+// GitHub GraphQL rate limit is low and skipping them until it resets once it is nearly spent
 func (pm *PRSyncManager) performSync() {
 	// logger.Debug("Starting PR sync cycle")
 
@@ -117,11 +132,30 @@ func (pm *PRSyncManager) performSync() {
 		return
 	}
 
+	pm.rateLimits.RefreshIfStale()
+	now := time.Now()
+	pm.mutex.Lock()
+	if now.Before(pm.nextSync) {
+		pm.mutex.Unlock()
+		return
+	}
+	interval := pm.rateLimits.PollInterval(git.RateLimitGraphQL, pm.syncInterval)
+	pm.nextSync = time.Time{}
+	if interval > pm.syncInterval {
+		// Half a tick early so the ticker doesn't skip one more cycle
+		pm.nextSync = now.Add(interval - pm.syncInterval/2)
+		logger.Debugf("🚦 GitHub rate limit is low, next PR sync in %v", interval)
+	}
+	pm.mutex.Unlock()
+	if !pm.rateLimits.AllowBackground(git.RateLimitGraphQL) {
+		return
+	}
+
 	// logger.Debugf("Found %d repositories with PRs to sync", len(prRequests))
 
 	// Sync PR states for each repository
 	for repoID, prNumbers := range prRequests {
-		states, err := pm.syncRepositoryPRs(repoID, prNumbers)
+		states, err := pm.fetchStates(repoID, prNumbers)
 		if err != nil {
 			logger.Warnf("Failed to sync PRs for repository %s: %v", repoID, err)
 			continue
@@ -141,7 +175,6 @@ func (pm *PRSyncManager) collectPRRequests() map[string][]int {
 	}
 
 	prRequests := make(map[string][]int)
-	prPattern := regexp.MustCompile(`github\.com/([^/]+/[^/]+)/pull/(\d+)`)
 
 	// Get all worktrees from state manager
 	allWorktrees := pm.stateManager.GetAllWorktrees()
@@ -150,15 +183,8 @@ func (pm *PRSyncManager) collectPRRequests() map[string][]int {
 			continue
 		}
 
-		matches := prPattern.FindStringSubmatch(worktree.PullRequestURL)
-		if len(matches) != 3 {
-			continue
-		}
-
-		repoID := matches[1]
-		prNumber, err := strconv.Atoi(matches[2])
-		if err != nil {
-			logger.Warnf("Invalid PR number in URL %s: %v", worktree.PullRequestURL, err)
+		repoID, prNumber, ok := parsePullRequestURL(worktree.PullRequestURL)
+		if !ok {
 			continue
 		}
 
@@ -191,9 +217,8 @@ func (pm *PRSyncManager) syncRepositoryPRs(repoID string, prNumbers []int) (map[
 
 	query := pm.buildBatchPRQuery(repoID, prNumbers)
 
-	// Execute GraphQL query via gh cli
-	cmd := exec.Command("gh", "api", "graphql", "-f", fmt.Sprintf("query=%s", query))
-	output, err := cmd.Output()
+	// Execute GraphQL query via gh cli, recording the rate limit it leaves
+	output, err := git.RunGHAPI("graphql", "-f", fmt.Sprintf("query=%s", query))
 	if err != nil {
 		return nil, fmt.Errorf("GraphQL query failed: %v", err)
 	}
@@ -217,7 +242,7 @@ func (pm *PRSyncManager) buildBatchPRQuery(repoID string, prNumbers []int) strin
 
 	var aliases []string
 	for _, num := range prNumbers {
-		aliases = append(aliases, fmt.Sprintf("pr%d: pullRequest(number: %d) { number title state url }", num, num))
+		aliases = append(aliases, fmt.Sprintf("pr%d: pullRequest(number: %d) { number title state url commits(last: 1) { nodes { commit { statusCheckRollup { state } } } } }", num, num))
 	}
 
 	return fmt.Sprintf(`query { repository(owner: "%s", name: "%s") { %s } }`,
@@ -229,10 +254,19 @@ func (pm *PRSyncManager) parseBatchPRResponse(output []byte, repoID string, prNu
 	var response struct {
 		Data struct {
 			Repository map[string]struct {
-				Number int    `json:"number"`
-				Title  string `json:"title"`
-				State  string `json:"state"`
-				URL    string `json:"url"`
+				Number  int    `json:"number"`
+				Title   string `json:"title"`
+				State   string `json:"state"`
+				URL     string `json:"url"`
+				Commits struct {
+					Nodes []struct {
+						Commit struct {
+							StatusCheckRollup *struct {
+								State string `json:"state"`
+							} `json:"statusCheckRollup"`
+						} `json:"commit"`
+					} `json:"nodes"`
+				} `json:"commits"`
 			} `json:"repository"`
 		} `json:"data"`
 	}
@@ -249,6 +283,11 @@ func (pm *PRSyncManager) parseBatchPRResponse(output []byte, repoID string, prNu
 			continue // PR not found or error
 		}
 
+		checksState := ""
+		if nodes := pr.Commits.Nodes; len(nodes) > 0 && nodes[0].Commit.StatusCheckRollup != nil {
+			checksState = nodes[0].Commit.StatusCheckRollup.State
+		}
+
 		key := fmt.Sprintf("%s#%d", repoID, pr.Number)
 		states[key] = &models.PullRequestState{
 			Number:      pr.Number,
@@ -256,6 +295,7 @@ func (pm *PRSyncManager) parseBatchPRResponse(output []byte, repoID string, prNu
 			Repository:  repoID,
 			URL:         pr.URL,
 			Title:       pr.Title,
+			ChecksState: checksState,
 			LastSynced:  now,
 			WorktreeIDs: pm.getWorktreeIDsForPR(repoID, pr.Number),
 		}
@@ -335,8 +375,6 @@ func (pm *PRSyncManager) triggerWorktreeUpdatesForPRChanges(changedStates map[st
 		return
 	}
 
-	prPattern := regexp.MustCompile(`github\.com/([^/]+/[^/]+)/pull/(\d+)`)
-
 	// Get all worktrees to find which ones are affected by the changed PRs
 	allWorktrees := pm.stateManager.GetAllWorktrees()
 	updateCount := 0
@@ -346,14 +384,8 @@ func (pm *PRSyncManager) triggerWorktreeUpdatesForPRChanges(changedStates map[st
 			continue
 		}
 
-		matches := prPattern.FindStringSubmatch(worktree.PullRequestURL)
-		if len(matches) != 3 {
-			continue
-		}
-
-		repoID := matches[1]
-		prNumber, err := strconv.Atoi(matches[2])
-		if err != nil {
+		repoID, prNumber, ok := parsePullRequestURL(worktree.PullRequestURL)
+		if !ok {
 			continue
 		}
 
@@ -393,6 +425,23 @@ func (pm *PRSyncManager) GetAllPRStates() map[string]*models.PullRequestState {
 	return result
 }
 
+// RenameRepository re-keys the cached states of a repository renamed or transferred on GitHub
+func (pm *PRSyncManager) RenameRepository(oldID, newID string) {
+	pm.mutex.Lock()
+	defer pm.mutex.Unlock()
+
+	for key, state := range pm.prStateCache {
+		if !strings.HasPrefix(key, oldID+"#") {
+			continue
+		}
+		renamed := *state
+		renamed.Repository = newID
+		renamed.URL = renamedPullRequestURL(state.URL, oldID, newID)
+		delete(pm.prStateCache, key)
+		pm.prStateCache[newID+strings.TrimPrefix(key, oldID)] = &renamed
+	}
+}
+
 // LoadPersistedStates loads PR states from disk into memory cache
 func (pm *PRSyncManager) LoadPersistedStates() error {
 	// For now, we'll load from the state manager's state file since it's already integrated
@@ -431,3 +480,90 @@ func (pm *PRSyncManager) MarkInitializationComplete() {
 	pm.isInitialized = true
 	logger.Debug("PR sync manager initialization marked complete - worktree updates now enabled")
 }
+
+// parsePullRequestURL returns the repository ID and number of a GitHub pull request URL
+func parsePullRequestURL(prURL string) (string, int, bool) {
+	matches := pullRequestURLPattern.FindStringSubmatch(prURL)
+	if len(matches) != 3 {
+		return "", 0, false
+	}
+	prNumber, err := strconv.Atoi(matches[2])
+	if err != nil {
+		return "", 0, false
+	}
+	return matches[1], prNumber, true
+}
+
+// RefreshPullRequest drops the cached state of a pull request and fetches it again in the
+// background, emitting the result to the worktrees referencing it. Requests for a pull request
+// already being refreshed are coalesced into one more fetch after it. Nothing happens until the
+// manager is running and initialized, or while the GitHub rate limit is reserved for users.
+func (pm *PRSyncManager) RefreshPullRequest(prURL string) {
+	repoID, prNumber, ok := parsePullRequestURL(prURL)
+	if !ok {
+		return
+	}
+
+	key := fmt.Sprintf("%s#%d", repoID, prNumber)
+	pm.mutex.Lock()
+	if !pm.isRunning || !pm.isInitialized {
+		pm.mutex.Unlock()
+		return
+	}
+	if !pm.rateLimits.AllowBackground(git.RateLimitGraphQL) {
+		pm.mutex.Unlock()
+		logger.Debugf("🚦 Not refreshing PR %s, the GitHub rate limit is low", key)
+		return
+	}
+	delete(pm.prStateCache, key)
+	pm.mutex.Unlock()
+
+	pm.refreshMu.Lock()
+	defer pm.refreshMu.Unlock()
+	if _, running := pm.refreshing[key]; running {
+		pm.refreshing[key] = true
+		return
+	}
+	pm.refreshing[key] = false
+
+	recovery.SafeGo("pr-refresh:"+key, func() {
+		for {
+			pm.refreshPullRequest(repoID, prNumber)
+
+			pm.refreshMu.Lock()
+			again := pm.refreshing[key]
+			if !again {
+				delete(pm.refreshing, key)
+			} else {
+				pm.refreshing[key] = false
+			}
+			pm.refreshMu.Unlock()
+			if !again {
+				return
+			}
+		}
+	})
+}
+
+// refreshPullRequest fetches the state of one pull request into the cache and emits it
+func (pm *PRSyncManager) refreshPullRequest(repoID string, prNumber int) {
+	states, err := pm.fetchStates(repoID, []int{prNumber})
+	if err != nil {
+		logger.Warnf("Failed to refresh PR %s#%d: %v", repoID, prNumber, err)
+		return
+	}
+	pm.updateCache(states)
+
+	if pm.stateManager == nil {
+		return
+	}
+	emitter := pm.stateManager.getEventsEmitter()
+	if emitter == nil {
+		return
+	}
+	for _, state := range states {
+		for _, worktreeID := range state.WorktreeIDs {
+			emitter.EmitPullRequestStatus(worktreeID, state)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/git"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// prStatusRecorder records pull request status events and ignores worktree creation and updates
+type prStatusRecorder struct {
+	EventsEmitter
+	mu       sync.Mutex
+	statuses map[string][]*models.PullRequestState
+}
+
+func (r *prStatusRecorder) EmitPullRequestStatus(worktreeID string, state *models.PullRequestState) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.statuses[worktreeID] = append(r.statuses[worktreeID], state)
+}
+
+func (r *prStatusRecorder) EmitWorktreeCreated(*models.Worktree) {}
+
+func (r *prStatusRecorder) EmitWorktreeUpdated(string, map[string]interface{}) {}
+
+func (r *prStatusRecorder) received(worktreeID string) []*models.PullRequestState {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*models.PullRequestState(nil), r.statuses[worktreeID]...)
+}
+
+func TestRefreshPullRequest(t *testing.T) {
+	root := t.TempDir()
+	events := &prStatusRecorder{statuses: make(map[string][]*models.PullRequestState)}
+	stateManager := NewWorktreeStateManager(filepath.Join(root, "state"), events)
+	t.Cleanup(stateManager.Stop)
+	require.NoError(t, stateManager.AddRepository(&models.Repository{ID: "owner/repo", Path: filepath.Join(root, "repo")}))
+	require.NoError(t, stateManager.AddWorktree(&models.Worktree{ID: "wt-felix", RepoID: "owner/repo", Name: "felix",
+		PullRequestURL: "https://github.com/owner/repo/pull/7"}))
+
+	pm := GetPRSyncManager(nil)
+	pm.mutex.Lock()
+	previousStateManager, previousFetch, previousRunning, previousInitialized := pm.stateManager, pm.fetchStates, pm.isRunning, pm.isInitialized
+	previousRateLimits := pm.rateLimits
+	pm.stateManager, pm.rateLimits = stateManager, git.NewRateLimitTracker()
+	pm.mutex.Unlock()
+	t.Cleanup(func() {
+		pm.mutex.Lock()
+		pm.stateManager, pm.fetchStates, pm.isRunning, pm.isInitialized = previousStateManager, previousFetch, previousRunning, previousInitialized
+		pm.rateLimits, pm.nextSync = previousRateLimits, time.Time{}
+		pm.mutex.Unlock()
+		pm.LoadStatesFromData(nil)
+	})
+
+	var fetches int32
+	release := make(chan struct{})
+	pm.fetchStates = func(repoID string, prNumbers []int) (map[string]*models.PullRequestState, error) {
+		call := atomic.AddInt32(&fetches, 1)
+		if call == 2 {
+			<-release
+		}
+		return map[string]*models.PullRequestState{
+			"owner/repo#7": {Number: 7, State: "OPEN", Repository: repoID, URL: "https://github.com/owner/repo/pull/7",
+				ChecksState: "PENDING", WorktreeIDs: pm.getWorktreeIDsForPR(repoID, prNumbers[0])},
+		}, nil
+	}
+	s := &GitService{stateManager: stateManager}
+
+	// Nothing is fetched before the manager is running and initialized
+	pm.RefreshPullRequest("https://github.com/owner/repo/pull/7")
+	assert.Equal(t, int32(0), atomic.LoadInt32(&fetches))
+
+	pm.mutex.Lock()
+	pm.isRunning, pm.isInitialized = true, true
+	pm.mutex.Unlock()
+	pm.LoadStatesFromData(map[string]*models.PullRequestState{
+		"owner/repo#7": {Number: 7, State: "OPEN", Repository: "owner/repo", ChecksState: "FAILURE"},
+	})
+
+	// The push invalidates the cached state and the refreshed one is emitted
+	s.refreshPullRequestStatus("wt-felix")
+	require.Eventually(t, func() bool { return len(events.received("wt-felix")) == 1 }, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, "PENDING", events.received("wt-felix")[0].ChecksState)
+	assert.Equal(t, "PENDING", pm.GetPRState("owner/repo", 7).ChecksState)
+
+	// Requests while a refresh runs are coalesced into one more fetch
+	s.refreshPullRequestStatus("wt-felix")
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&fetches) == 2 }, 5*time.Second, 10*time.Millisecond)
+	assert.Nil(t, pm.GetPRState("owner/repo", 7), "the cached state is dropped right away")
+	assert.True(t, s.RefreshPullRequestByURL("https://github.com/Owner/Repo/pull/7"))
+	s.refreshPullRequestStatus("wt-felix")
+	close(release)
+	require.Eventually(t, func() bool { return len(events.received("wt-felix")) == 3 }, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&fetches))
+
+	// Pull requests no worktree references aren't fetched
+	assert.False(t, s.RefreshPullRequestByURL("https://github.com/owner/repo/pull/8"))
+	assert.False(t, s.RefreshPullRequestByURL("not a pull request"))
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&fetches))
+
+	// A low GitHub rate limit stretches syncs, and pauses them and refreshes once nearly spent
+	observeGraphQLLimit(pm.rateLimits, 900, time.Now().Add(time.Hour))
+	pm.performSync()
+	pm.performSync()
+	assert.Equal(t, int32(4), atomic.LoadInt32(&fetches), "the second sync waits for the stretched interval")
+	observeGraphQLLimit(pm.rateLimits, 100, time.Now().Add(time.Hour))
+	pm.mutex.Lock()
+	pm.nextSync = time.Time{}
+	pm.mutex.Unlock()
+	pm.performSync()
+	s.refreshPullRequestStatus("wt-felix")
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, int32(4), atomic.LoadInt32(&fetches))
+	assert.NotNil(t, pm.GetPRState("owner/repo", 7), "the cached state is kept while refreshes are paused")
+}
@@ -0,0 +1,369 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// How pull request bodies use a repository's pull request template
+const (
+	// PullRequestTemplateAppend adds catnip's generated content after the template, below a
+	// separator
+	PullRequestTemplateAppend = "append"
+	// PullRequestTemplateSections fills catnip's generated content in under the template's
+	// matching headings, adding sections for the parts without one
+	PullRequestTemplateSections = "sections"
+	// PullRequestTemplateOff ignores the template
+	PullRequestTemplateOff = "off"
+)
+
+// maxPullRequestTemplateSize is the largest template used; bigger ones are more likely a
+// mistake than a scaffold
+const maxPullRequestTemplateSize = 64 * 1024
+
+// pullRequestTemplateName is the file name GitHub looks for, in any case
+const pullRequestTemplateName = "pull_request_template.md"
+
+// pullRequestTemplateDirs are the directories GitHub looks for templates in, in its order
+var pullRequestTemplateDirs = []string{".github", "", "docs"}
+
+// pullRequestSection is a part of catnip's generated pull request content, filled in under the
+// first template heading containing one of its keywords in sections mode
+type pullRequestSection struct {
+	key      string
+	heading  string
+	keywords []string
+	content  string
+}
+
+// findPullRequestTemplates returns the pull request templates of a worktree relative to it, in
+// the order GitHub looks them up: in .github/, the root and docs/, a single template file
+// before a PULL_REQUEST_TEMPLATE/ directory of templates, sorted by name
+func findPullRequestTemplates(worktreePath string) []string {
+	var single, multiple []string
+	for _, dir := range pullRequestTemplateDirs {
+		entries, err := os.ReadDir(filepath.Join(worktreePath, dir))
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			switch {
+			case !entry.IsDir() && strings.EqualFold(entry.Name(), pullRequestTemplateName):
+				single = append(single, filepath.Join(dir, entry.Name()))
+			case entry.IsDir() && strings.EqualFold(entry.Name(), strings.TrimSuffix(pullRequestTemplateName, ".md")):
+				templates, err := os.ReadDir(filepath.Join(worktreePath, dir, entry.Name()))
+				if err != nil {
+					continue
+				}
+				var names []string
+				for _, template := range templates {
+					if !template.IsDir() && strings.EqualFold(filepath.Ext(template.Name()), ".md") {
+						names = append(names, template.Name())
+					}
+				}
+				sort.Strings(names)
+				for _, name := range names {
+					multiple = append(multiple, filepath.Join(dir, entry.Name(), name))
+				}
+			}
+		}
+	}
+	return append(single, multiple...)
+}
+
+// pickPullRequestTemplate returns the template to use: the configured one of a directory of
+// templates when there is one, else the first found
+func pickPullRequestTemplate(templates []string, configured string) string {
+	if len(templates) == 0 {
+		return ""
+	}
+	for _, template := range templates {
+		inDir := strings.EqualFold(filepath.Base(filepath.Dir(template)), strings.TrimSuffix(pullRequestTemplateName, ".md"))
+		if configured != "" && inDir && strings.EqualFold(filepath.Base(template), configured) {
+			return template
+		}
+	}
+	return templates[0]
+}
+
+// readPullRequestTemplate reads a template as UTF-8 text with LF line endings, refusing ones
+// over maxPullRequestTemplateSize and ones that aren't UTF-8 text
+func readPullRequestTemplate(worktreePath, template string) (string, error) {
+	path := filepath.Join(worktreePath, template)
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read pull request template %s: %v", template, err)
+	}
+	if info.Size() > maxPullRequestTemplateSize {
+		return "", fmt.Errorf("pull request template %s is larger than %dKB", template, maxPullRequestTemplateSize/1024)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read pull request template %s: %v", template, err)
+	}
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	if !utf8.Valid(data) || bytes.IndexByte(data, 0) >= 0 {
+		return "", fmt.Errorf("pull request template %s is not UTF-8 text", template)
+	}
+	return strings.ReplaceAll(string(data), "\r\n", "\n"), nil
+}
+
+// maxListedGeneratedFiles is how many generated files the size section of a pull request names
+const maxListedGeneratedFiles = 5
+
+// pullRequestSections returns catnip's generated content for a worktree's pull request: the
+// given body (the session title when it is empty) with the creation summary, the commit
+// subjects the body doesn't list yet, the size of the change when generated files inflate it,
+// and the session's todos. Empty parts are left out.
+func (s *GitService) pullRequestSections(worktree *models.Worktree, body string) []pullRequestSection {
+	summary := strings.TrimSpace(body)
+	if summary == "" && worktree.SessionTitle != nil {
+		summary = strings.TrimSpace(worktree.SessionTitle.Title)
+	}
+	summary = withCreationSummary(summary, worktree.CreationContext)
+
+	var commits []string
+	if output, err := s.operations.ExecuteGit(worktree.Path, "log", "--reverse", "--format=%s", s.getSourceRef(worktree)+"..HEAD"); err == nil {
+		for _, subject := range strings.Split(string(output), "\n") {
+			if line := "- " + strings.TrimSpace(subject); subject != "" && !strings.Contains(summary, line) {
+				commits = append(commits, line)
+			}
+		}
+	}
+
+	var todos []string
+	for _, todo := range worktree.Todos {
+		box := "[ ]"
+		if todo.Status == "completed" {
+			box = "[x]"
+		}
+		todos = append(todos, fmt.Sprintf("- %s %s", box, todo.Content))
+	}
+
+	sections := []pullRequestSection{
+		{key: "summary", heading: "Summary", keywords: []string{"summary", "description", "overview"}, content: summary},
+		{key: "commits", heading: "Commits", keywords: []string{"commits", "changes", "changelog"}, content: strings.Join(commits, "\n")},
+		{key: "size", heading: "Size", keywords: []string{"size", "stats"}, content: s.pullRequestSize(worktree)},
+		{key: "todos", heading: "Todos", keywords: []string{"todo", "todos", "tasks"}, content: strings.Join(todos, "\n")},
+	}
+	var present []pullRequestSection
+	for _, section := range sections {
+		if strings.TrimSpace(section.content) != "" {
+			present = append(present, section)
+		}
+	}
+	return present
+}
+
+// pullRequestSize describes the lines a worktree's pull request changes in total and in files
+// written by hand, or "" when it changes no generated files and the totals say it all
+func (s *GitService) pullRequestSize(worktree *models.Worktree) string {
+	output, err := s.operations.ExecuteGit(worktree.Path, "diff", "--numstat", "--no-renames", s.getSourceRef(worktree)+"...HEAD")
+	if err != nil {
+		return ""
+	}
+	var files []models.CommitFileStat
+	for _, line := range strings.Split(string(output), "\n") {
+		stat := strings.SplitN(line, "\t", 3)
+		if len(stat) < 3 {
+			continue
+		}
+		file := models.CommitFileStat{Path: stat[2]}
+		file.Additions, _ = strconv.Atoi(stat[0])
+		file.Deletions, _ = strconv.Atoi(stat[1])
+		files = append(files, file)
+	}
+	commits := []models.WorktreeCommit{{Files: files}}
+	s.classifyCommitFiles(worktree.Path, commits)
+	commit := commits[0]
+
+	var generated []string
+	for _, file := range commit.Files {
+		if file.Generated {
+			generated = append(generated, file.Path)
+		}
+	}
+	if len(generated) == 0 {
+		return ""
+	}
+	listed := strings.Join(generated[:min(len(generated), maxListedGeneratedFiles)], ", ")
+	if len(generated) > maxListedGeneratedFiles {
+		listed += fmt.Sprintf(" and %d more", len(generated)-maxListedGeneratedFiles)
+	}
+	return fmt.Sprintf("- %s changed by hand (+%d -%d)\n- %s changed in total (+%d -%d), including %d generated: %s",
+		pluralizeFiles(commit.AuthoredStats.Files), commit.AuthoredStats.Additions, commit.AuthoredStats.Deletions,
+		pluralizeFiles(commit.Stats.Files), commit.Stats.Additions, commit.Stats.Deletions, len(generated), listed)
+}
+
+// pluralizeFiles returns "1 file" or "n files"
+func pluralizeFiles(n int) string {
+	if n == 1 {
+		return "1 file"
+	}
+	return fmt.Sprintf("%d files", n)
+}
+
+// appendToPullRequestTemplate adds the generated sections after the template, below a separator
+func appendToPullRequestTemplate(template string, sections []pullRequestSection) string {
+	var parts []string
+	for _, section := range sections {
+		parts = append(parts, "## "+section.heading+"\n\n"+section.content)
+	}
+	if len(parts) == 0 {
+		return template
+	}
+	return strings.TrimRight(template, "\n") + "\n\n---\n\n" + strings.Join(parts, "\n\n") + "\n"
+}
+
+// fillPullRequestTemplate inserts each generated section right below the first template
+// heading naming one of its keywords, between catnip:<key> comments so it is clearly marked.
+// Sections without a heading are added at the end.
+func fillPullRequestTemplate(template string, sections []pullRequestSection) string {
+	lines := strings.Split(strings.TrimRight(template, "\n"), "\n")
+	insertions := make(map[int][]string)
+	var unplaced []string
+	for _, section := range sections {
+		marked := fmt.Sprintf("<!-- catnip:%s -->\n%s\n<!-- /catnip:%s -->", section.key, section.content, section.key)
+		line := pullRequestTemplateHeading(lines, section.keywords)
+		if line < 0 {
+			unplaced = append(unplaced, "## "+section.heading+"\n\n"+marked)
+			continue
+		}
+		insertions[line] = append(insertions[line], marked)
+	}
+
+	var filled []string
+	for i, line := range lines {
+		filled = append(filled, line)
+		for _, marked := range insertions[i] {
+			filled = append(filled, "", marked)
+		}
+	}
+	body := strings.Join(filled, "\n")
+	if len(unplaced) > 0 {
+		body += "\n\n" + strings.Join(unplaced, "\n\n")
+	}
+	return body + "\n"
+}
+
+// pullRequestTemplateHeading returns the line of the first Markdown heading containing one of
+// the keywords as a word, -1 when there is none
+func pullRequestTemplateHeading(lines []string, keywords []string) int {
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if !strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		words := strings.FieldsFunc(strings.ToLower(strings.TrimLeft(trimmed, "#")), func(r rune) bool {
+			return !('a' <= r && r <= 'z')
+		})
+		for _, word := range words {
+			for _, keyword := range keywords {
+				if word == keyword {
+					return i
+				}
+			}
+		}
+	}
+	return -1
+}
+
+// composePullRequestBody returns the body a worktree's pull request is created with: body
+// scaffolded by the repository's pull request template according to its settings. Without a
+// usable template it is body with the creation summary, as always; templates that are too large
+// or not UTF-8 text are reported in the warning.
+func (s *GitService) composePullRequestBody(worktree *models.Worktree, repo *models.Repository, body string) *models.PullRequestBodyPreview {
+	settings := models.PullRequestTemplateSettings{Mode: PullRequestTemplateAppend}
+	if repo.PullRequestTemplate != nil && repo.PullRequestTemplate.Mode != "" {
+		settings = *repo.PullRequestTemplate
+	}
+	preview := &models.PullRequestBodyPreview{
+		Body:      withCreationSummary(body, worktree.CreationContext),
+		Templates: findPullRequestTemplates(worktree.Path),
+		Mode:      settings.Mode,
+	}
+	if preview.Templates == nil {
+		preview.Templates = []string{}
+	}
+	if settings.Mode == PullRequestTemplateOff || len(preview.Templates) == 0 {
+		return preview
+	}
+
+	template := pickPullRequestTemplate(preview.Templates, settings.Template)
+	content, err := readPullRequestTemplate(worktree.Path, template)
+	if err != nil {
+		preview.Warning = err.Error()
+		return preview
+	}
+	preview.Template = template
+	if settings.Mode == PullRequestTemplateSections {
+		preview.Body = fillPullRequestTemplate(content, s.pullRequestSections(worktree, body))
+	} else {
+		preview.Body = appendToPullRequestTemplate(content, s.pullRequestSections(worktree, body))
+	}
+	return preview
+}
+
+// PreviewPullRequestBody returns the body a pull request of a worktree would be created with
+// for the given body, with the repository's pull request template applied
+func (s *GitService) PreviewPullRequestBody(worktreeID, body string) (*models.PullRequestBodyPreview, error) {
+	worktree, exists := s.stateManager.GetWorktree(worktreeID)
+	if !exists {
+		return nil, fmt.Errorf("worktree %s not found", worktreeID)
+	}
+	repo, exists := s.stateManager.GetRepository(worktree.RepoID)
+	if !exists {
+		return nil, fmt.Errorf("repository %s not found", worktree.RepoID)
+	}
+	return s.composePullRequestBody(worktree, repo, body), nil
+}
+
+// SetPullRequestTemplateSettings configures how pull request bodies of a repository use its
+// pull request template
+func (s *GitService) SetPullRequestTemplateSettings(repoID string, settings models.PullRequestTemplateSettings) (*models.PullRequestTemplateSettings, error) {
+	repo, exists := s.stateManager.GetRepository(repoID)
+	if !exists {
+		return nil, fmt.Errorf("repository %s not found", repoID)
+	}
+	if settings.Mode == "" {
+		settings.Mode = PullRequestTemplateAppend
+	}
+	if settings.Mode != PullRequestTemplateAppend && settings.Mode != PullRequestTemplateSections && settings.Mode != PullRequestTemplateOff {
+		return nil, fmt.Errorf("unknown template mode %q, expected %s, %s or %s", settings.Mode, PullRequestTemplateAppend, PullRequestTemplateSections, PullRequestTemplateOff)
+	}
+	settings.Template = strings.TrimSpace(settings.Template)
+	if strings.ContainsAny(settings.Template, `/\`) {
+		return nil, fmt.Errorf("template must be a file name in the PULL_REQUEST_TEMPLATE directory, got %q", settings.Template)
+	}
+
+	if err := s.stateManager.UpdateRepository(repo.ID, func(repo *models.Repository) {
+		repo.PullRequestTemplate = &settings
+	}); err != nil {
+		return nil, err
+	}
+	logger.Infof("📝 Pull request template for %s: mode=%s template=%s", repo.ID, settings.Mode, settings.Template)
+	return &settings, nil
+}
+
+// GetPullRequestTemplateSettings returns how pull request bodies of a repository use its pull
+// request template, appending to it when never configured
+func (s *GitService) GetPullRequestTemplateSettings(repoID string) (*models.PullRequestTemplateSettings, error) {
+	repo, exists := s.stateManager.GetRepository(repoID)
+	if !exists {
+		return nil, fmt.Errorf("repository %s not found", repoID)
+	}
+	if repo.PullRequestTemplate != nil {
+		settings := *repo.PullRequestTemplate
+		return &settings, nil
+	}
+	return &models.PullRequestTemplateSettings{Mode: PullRequestTemplateAppend}, nil
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+func TestPullRequestTemplates(t *testing.T) {
+	s, stateManager, _, worktreePath := newRecreateTestService(t)
+
+	require.NoError(t, os.WriteFile(filepath.Join(worktreePath, "login.ts"), []byte("login\n"), 0644))
+	runTestGit(t, worktreePath, "add", "login.ts")
+	runTestGit(t, worktreePath, "commit", "-m", "Add login form")
+	require.NoError(t, stateManager.UpdateWorktree("wt-felix", map[string]interface{}{
+		"todos": []models.Todo{{ID: "1", Content: "Write tests", Status: "completed"}, {ID: "2", Content: "Update docs", Status: "pending"}},
+	}))
+
+	preview, err := s.PreviewPullRequestBody("wt-felix", "Adds a login form")
+	require.NoError(t, err)
+	assert.Equal(t, "Adds a login form", preview.Body, "without a template the body is used as is")
+	assert.Empty(t, preview.Templates)
+
+	githubDir := filepath.Join(worktreePath, ".github")
+	require.NoError(t, os.MkdirAll(filepath.Join(githubDir, "PULL_REQUEST_TEMPLATE"), 0755))
+	template := "## Description\r\n\r\n<!-- What does this change? -->\r\n\r\n## Checklist\r\n\r\n- [ ] Tests pass\r\n"
+	require.NoError(t, os.WriteFile(filepath.Join(githubDir, "pull_request_template.md"), []byte("\xef\xbb\xbf"+template), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(githubDir, "PULL_REQUEST_TEMPLATE", "bugfix.md"), []byte("## Bug\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(githubDir, "PULL_REQUEST_TEMPLATE", "feature.md"), []byte("## Feature\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(worktreePath, "PULL_REQUEST_TEMPLATE.md"), []byte("## Root\n"), 0644))
+
+	t.Run("Append", func(t *testing.T) {
+		preview, err := s.PreviewPullRequestBody("wt-felix", "Adds a login form")
+		require.NoError(t, err)
+		assert.Equal(t, []string{
+			filepath.Join(".github", "pull_request_template.md"),
+			"PULL_REQUEST_TEMPLATE.md",
+			filepath.Join(".github", "PULL_REQUEST_TEMPLATE", "bugfix.md"),
+			filepath.Join(".github", "PULL_REQUEST_TEMPLATE", "feature.md"),
+		}, preview.Templates)
+		assert.Equal(t, filepath.Join(".github", "pull_request_template.md"), preview.Template)
+		assert.Equal(t, strings.ReplaceAll(template, "\r\n", "\n")+
+			"\n---\n\n## Summary\n\nAdds a login form\n\n## Commits\n\n- Add login form\n\n## Todos\n\n- [x] Write tests\n- [ ] Update docs\n", preview.Body)
+	})
+
+	t.Run("Sections", func(t *testing.T) {
+		_, err := s.SetPullRequestTemplateSettings("local/repo", models.PullRequestTemplateSettings{Mode: PullRequestTemplateSections})
+		require.NoError(t, err)
+
+		preview, err := s.PreviewPullRequestBody("wt-felix", "Adds a login form\n- Add login form")
+		require.NoError(t, err)
+		assert.Equal(t, "## Description\n\n<!-- catnip:summary -->\nAdds a login form\n- Add login form\n<!-- /catnip:summary -->\n\n"+
+			"<!-- What does this change? -->\n\n## Checklist\n\n- [ ] Tests pass\n\n"+
+			"## Todos\n\n<!-- catnip:todos -->\n- [x] Write tests\n- [ ] Update docs\n<!-- /catnip:todos -->\n", preview.Body,
+			"commits the body already lists aren't repeated")
+	})
+
+	t.Run("PicksConfiguredTemplate", func(t *testing.T) {
+		_, err := s.SetPullRequestTemplateSettings("local/repo", models.PullRequestTemplateSettings{Mode: PullRequestTemplateAppend, Template: "feature.md"})
+		require.NoError(t, err)
+		preview, err := s.PreviewPullRequestBody("wt-felix", "")
+		require.NoError(t, err)
+		assert.Equal(t, filepath.Join(".github", "PULL_REQUEST_TEMPLATE", "feature.md"), preview.Template)
+		assert.True(t, strings.HasPrefix(preview.Body, "## Feature\n\n---\n\n## Commits\n"), preview.Body)
+
+		_, err = s.SetPullRequestTemplateSettings("local/repo", models.PullRequestTemplateSettings{Mode: "replace"})
+		assert.ErrorContains(t, err, "unknown template mode")
+		_, err = s.SetPullRequestTemplateSettings("local/repo", models.PullRequestTemplateSettings{Template: "../feature.md"})
+		assert.Error(t, err)
+	})
+
+	t.Run("FallsBackOnUnusableTemplates", func(t *testing.T) {
+		_, err := s.SetPullRequestTemplateSettings("local/repo", models.PullRequestTemplateSettings{})
+		require.NoError(t, err)
+		templatePath := filepath.Join(githubDir, "pull_request_template.md")
+
+		require.NoError(t, os.WriteFile(templatePath, []byte("\xff\xfe#\x00 \x00"), 0644))
+		preview, err := s.PreviewPullRequestBody("wt-felix", "Adds a login form")
+		require.NoError(t, err)
+		assert.Equal(t, "Adds a login form", preview.Body)
+		assert.Empty(t, preview.Template)
+		assert.Contains(t, preview.Warning, "not UTF-8 text")
+
+		require.NoError(t, os.WriteFile(templatePath, []byte(strings.Repeat("x", maxPullRequestTemplateSize+1)), 0644))
+		preview, err = s.PreviewPullRequestBody("wt-felix", "Adds a login form")
+		require.NoError(t, err)
+		assert.Equal(t, "Adds a login form", preview.Body)
+		assert.Contains(t, preview.Warning, "larger than 64KB")
+	})
+
+	t.Run("Off", func(t *testing.T) {
+		_, err := s.SetPullRequestTemplateSettings("local/repo", models.PullRequestTemplateSettings{Mode: PullRequestTemplateOff})
+		require.NoError(t, err)
+		preview, err := s.PreviewPullRequestBody("wt-felix", "Adds a login form")
+		require.NoError(t, err)
+		assert.Equal(t, "Adds a login form", preview.Body)
+		assert.Empty(t, preview.Warning)
+	})
+}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/vanpelt/catnip/internal/config"
 	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/redact"
 )
 
 // PTYService manages PTY sessions and setup script execution
@@ -37,22 +38,29 @@ func NewPTYService() *PTYService {
 	}
 }
 
-// ExecuteSetupScript checks for and executes setup.sh in a worktree's PTY session
-func (s *PTYService) ExecuteSetupScript(worktreePath string) {
+// ExecuteSetupScript checks for and executes setup.sh in a worktree's PTY session. Without a
+// setup.sh, defaultScript is run instead when set. env is added to the script's environment.
+func (s *PTYService) ExecuteSetupScript(worktreePath, defaultScript string, env []string) {
 	setupScriptPath := filepath.Join(worktreePath, "setup.sh")
+	script := "chmod +x setup.sh && echo '🔧 Running setup.sh...' && ./setup.sh && echo '\n✅ Setup completed'"
 
 	// Check if setup.sh exists and is executable
 	if _, err := os.Stat(setupScriptPath); os.IsNotExist(err) {
-		logger.Debugf("📄 No setup.sh found in %s, skipping setup", worktreePath)
-		return
+		if defaultScript == "" {
+			logger.Debugf("📄 No setup.sh found in %s, skipping setup", worktreePath)
+			return
+		}
+		logger.Debugf("🔧 No setup.sh found in %s, running toolchain defaults: %s", worktreePath, defaultScript)
+		script = fmt.Sprintf("echo '🔧 Running toolchain setup...' && %s && echo '\n✅ Setup completed'", defaultScript)
+	} else {
+		logger.Debugf("🔧 Found setup.sh in %s, executing in terminal", worktreePath)
 	}
 
-	logger.Debugf("🔧 Found setup.sh in %s, executing in terminal", worktreePath)
-
 	// Extract workspace name from worktree path for session ID
 	// Format: workspace/repo/branch -> repo/branch
-	parts := strings.Split(strings.TrimPrefix(worktreePath, config.Runtime.WorkspaceDir+"/"), "/")
-	if len(parts) < 2 {
+	rel, inWorkspace := workspaceRelativePath(worktreePath)
+	parts := strings.Split(rel, "/")
+	if !inWorkspace || len(parts) < 2 {
 		logger.Warnf("⚠️ Cannot determine session ID from worktree path: %s", worktreePath)
 		return
 	}
@@ -61,7 +69,7 @@ func (s *PTYService) ExecuteSetupScript(worktreePath string) {
 	compositeSessionID := fmt.Sprintf("%s:setup", sessionID)
 
 	// Create or get existing session for this worktree
-	session := s.getOrCreateSetupSession(compositeSessionID, worktreePath)
+	session := s.getOrCreateSetupSession(compositeSessionID, worktreePath, script, env)
 	if session == nil {
 		logger.Errorf("❌ Failed to create/get session for setup.sh execution: %s", compositeSessionID)
 		return
@@ -71,7 +79,7 @@ func (s *PTYService) ExecuteSetupScript(worktreePath string) {
 }
 
 // getOrCreateSetupSession creates or retrieves a setup session for the given session ID
-func (s *PTYService) getOrCreateSetupSession(sessionID, workDir string) *SetupSession {
+func (s *PTYService) getOrCreateSetupSession(sessionID, workDir, script string, env []string) *SetupSession {
 	s.sessionMutex.Lock()
 	defer s.sessionMutex.Unlock()
 
@@ -102,7 +110,7 @@ func (s *PTYService) getOrCreateSetupSession(sessionID, workDir string) *SetupSe
 	}
 
 	// Create command to run setup script and capture output to file
-	cmd := exec.Command("bash", "-c", "chmod +x setup.sh && echo '🔧 Running setup.sh...' && ./setup.sh && echo '\n✅ Setup completed'")
+	cmd := exec.Command("bash", "-c", script)
 	// Set environment for setup script execution
 	cmd.Env = append(os.Environ(),
 		fmt.Sprintf("SESSION_ID=%s", sessionID),
@@ -110,9 +118,12 @@ func (s *PTYService) getOrCreateSetupSession(sessionID, workDir string) *SetupSe
 		"TERM=xterm-direct",
 		"COLORTERM=truecolor",
 	)
+	cmd.Env = append(cmd.Env, env...)
 	cmd.Dir = workDir
-	cmd.Stdout = logFile
-	cmd.Stderr = logFile
+	// Secrets in the output are masked before it reaches the log file
+	output := redact.NewWriter(logFile, redact.Default())
+	cmd.Stdout = output
+	cmd.Stderr = output
 
 	session.Cmd = cmd
 	session.PTY = nil // No PTY needed for setup
@@ -120,12 +131,13 @@ func (s *PTYService) getOrCreateSetupSession(sessionID, workDir string) *SetupSe
 	// Start the command and wait for completion in a goroutine
 	go func() {
 		defer logFile.Close()
+		defer output.Close()
 
 		logger.Debugf("🔧 Starting setup script execution for session: %s", sessionID)
 		if err := cmd.Run(); err != nil {
 			logger.Errorf("❌ Setup script failed for session %s: %v", sessionID, err)
 			// Write error to log file
-			if _, writeErr := fmt.Fprintf(logFile, "\n❌ Setup script failed: %v\n", err); writeErr != nil {
+			if _, writeErr := fmt.Fprintf(output, "\n❌ Setup script failed: %v\n", err); writeErr != nil {
 				logger.Warnf("⚠️ Failed to write error to setup log: %v", writeErr)
 			}
 		} else {
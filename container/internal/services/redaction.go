@@ -0,0 +1,54 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/redact"
+)
+
+// secretKeyPattern matches the environment variable names a worktree can declare secret
+var secretKeyPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// SetWorktreeSecretKeys declares which environment variables hold secrets for a worktree, in
+// addition to those with sensitive names. Their values are masked in captured command output,
+// events, logs and exports from now on; an empty list clears them.
+func (s *GitService) SetWorktreeSecretKeys(worktreeID string, keys []string) error {
+	worktree, exists := s.stateManager.GetWorktree(worktreeID)
+	if !exists {
+		return fmt.Errorf("worktree %s not found", worktreeID)
+	}
+
+	seen := make(map[string]bool, len(keys))
+	cleaned := []string{}
+	for _, key := range keys {
+		key = strings.TrimSpace(key)
+		if !secretKeyPattern.MatchString(key) {
+			return fmt.Errorf("invalid secret key %q, expected an environment variable name", key)
+		}
+		if !seen[key] {
+			seen[key] = true
+			cleaned = append(cleaned, key)
+		}
+	}
+	sort.Strings(cleaned)
+
+	if err := s.stateManager.UpdateWorktree(worktreeID, map[string]interface{}{"secret_keys": cleaned}); err != nil {
+		return err
+	}
+	redact.Default().SetKeys(worktreeID, cleaned)
+	logger.Infof("🔒 %s declares %d secret keys", worktree.Name, len(cleaned))
+	return nil
+}
+
+// registerSecretKeys hands the secret keys of the worktrees in state to the redactor
+func (s *GitService) registerSecretKeys() {
+	for _, worktree := range s.stateManager.GetAllWorktrees() {
+		if len(worktree.SecretKeys) > 0 {
+			redact.Default().SetKeys(worktree.ID, worktree.SecretKeys)
+		}
+	}
+}
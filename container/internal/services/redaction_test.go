@@ -0,0 +1,37 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/models"
+	"github.com/vanpelt/catnip/internal/redact"
+)
+
+func TestWorktreeSecretKeys(t *testing.T) {
+	s, stateManager, repoPath, _ := newRecreateTestService(t)
+	t.Setenv("CATNIP_TEST_STRIPE_LIVE", "rk_live_51Hc0ffee")
+	t.Cleanup(func() { redact.Default().SetKeys("wt-felix", nil) })
+
+	assert.Error(t, s.SetWorktreeSecretKeys("wt-missing", []string{"CATNIP_TEST_STRIPE_LIVE"}))
+	assert.Error(t, s.SetWorktreeSecretKeys("wt-felix", []string{"NOT A KEY"}))
+
+	require.NoError(t, s.SetWorktreeSecretKeys("wt-felix", []string{"CATNIP_TEST_STRIPE_LIVE", " CATNIP_TEST_STRIPE_LIVE"}))
+	worktree, _ := stateManager.GetWorktree("wt-felix")
+	assert.Equal(t, []string{"CATNIP_TEST_STRIPE_LIVE"}, worktree.SecretKeys)
+
+	// Validation output is masked before it is recorded
+	runTestGit(t, repoPath, "config", "--add", validationCommandKey, `echo "deploying with $CATNIP_TEST_STRIPE_LIVE"`)
+	result, err := s.ValidateWorktree("wt-felix", true)
+	require.NoError(t, err)
+	require.Equal(t, models.ValidationPassed, result.Status)
+	assert.Equal(t, "deploying with rk_l[REDACTED]\n", result.Commands[0].Output)
+	worktree, _ = stateManager.GetWorktree("wt-felix")
+	assert.NotContains(t, worktree.Validation.Commands[0].Output, "rk_live_51Hc0ffee")
+
+	require.NoError(t, s.SetWorktreeSecretKeys("wt-felix", nil))
+	worktree, _ = stateManager.GetWorktree("wt-felix")
+	assert.Empty(t, worktree.SecretKeys)
+	assert.False(t, redact.Default().IsSensitiveKey("CATNIP_TEST_STRIPE_LIVE"))
+}
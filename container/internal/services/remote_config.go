@@ -0,0 +1,358 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/config"
+	"github.com/vanpelt/catnip/internal/git"
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+const (
+	// remoteConfigWarningSource is the repository health warning source for config drift
+	remoteConfigWarningSource = "remote-config"
+	// remoteConfigCheckInterval is how often the remote config of every repository is verified
+	remoteConfigCheckInterval = 10 * time.Minute
+	// remoteConfigReadTimeout bounds reading a repository's git config
+	remoteConfigReadTimeout = 10 * time.Second
+	// githubCredentialHelperKey is the global credential helper catnip configures for GitHub
+	githubCredentialHelperKey = "credential.https://github.com.helper"
+	// ghCredentialHelper is the expected value of githubCredentialHelperKey
+	ghCredentialHelper = git.GHCredentialHelper
+	// githubHTTPSPrefix is the URL prefix the gh credential helper authenticates
+	githubHTTPSPrefix = "https://github.com/"
+)
+
+// Git config scopes reported by `git config --show-scope`
+const (
+	configScopeGlobal   = "global"
+	configScopeLocal    = "local"
+	configScopeWorktree = "worktree"
+)
+
+// RemoteConfigDrift is a git config entry that differs from what catnip expects
+type RemoteConfigDrift struct {
+	// Repository or worktree path the entry was read from
+	Path string `json:"path"`
+	// Config scope (local, worktree or global)
+	Scope string `json:"scope"`
+	// Config key as reported by git
+	Key string `json:"key"`
+	// Expected value (empty when the key should not be set)
+	Expected string `json:"expected,omitempty"`
+	// Actual value (empty when the key is missing)
+	Actual string `json:"actual,omitempty"`
+}
+
+// RemoteConfigReport lists the remote config drift of a repository or worktree
+type RemoteConfigReport struct {
+	RepoID     string              `json:"repo_id"`
+	WorktreeID string              `json:"worktree_id,omitempty"`
+	Drift      []RemoteConfigDrift `json:"drift"`
+	// Keys changed by a repair
+	Repaired []string `json:"repaired,omitempty"`
+}
+
+// configEntry is a single line of `git config --list --show-scope`
+type configEntry struct {
+	scope string
+	key   string
+	value string
+}
+
+// parseConfigList parses the NUL separated output of `git config --list --show-scope -z`
+func parseConfigList(output []byte) []configEntry {
+	fields := strings.Split(string(output), "\x00")
+	var entries []configEntry
+	for i := 0; i+1 < len(fields); i += 2 {
+		key, value, _ := strings.Cut(fields[i+1], "\n")
+		entries = append(entries, configEntry{scope: fields[i], key: key, value: value})
+	}
+	return entries
+}
+
+// githubRepoIdentity reduces a GitHub https or ssh URL to "github.com/owner/repo"
+func githubRepoIdentity(remoteURL string) string {
+	identity := strings.TrimSpace(remoteURL)
+	for _, prefix := range []string{"https://", "ssh://git@", "git@"} {
+		identity = strings.TrimPrefix(identity, prefix)
+	}
+	identity = strings.Replace(identity, "github.com:", "github.com/", 1)
+	return strings.TrimSuffix(strings.TrimSuffix(identity, "/"), ".git")
+}
+
+// isSupportedRemoteScheme reports whether a remote URL uses a scheme catnip can authenticate
+func isSupportedRemoteScheme(remoteURL string) bool {
+	return strings.HasPrefix(remoteURL, "https://") || strings.HasPrefix(remoteURL, "git@") ||
+		strings.HasPrefix(remoteURL, "ssh://")
+}
+
+// detectRemoteConfigDrift compares config entries read from path with the configuration catnip
+// sets up: an origin pointing at the expected repository over https or ssh, no insteadOf rules
+// rewriting GitHub https URLs, no local credential helpers overriding gh, and (when checkGlobal
+// is set) the global gh credential helper. Only entries of the given scopes are considered.
+func detectRemoteConfigDrift(path string, entries []configEntry, expectedOrigin string, scopes []string, checkGlobal bool) []RemoteConfigDrift {
+	inScope := func(scope string) bool {
+		for _, s := range scopes {
+			if s == scope {
+				return true
+			}
+		}
+		return false
+	}
+
+	var drift []RemoteConfigDrift
+	hasGlobalHelper := false
+	originSeen := false
+	for _, entry := range entries {
+		if entry.scope == configScopeGlobal && entry.key == githubCredentialHelperKey && git.IsGHCredentialHelper(entry.value) {
+			hasGlobalHelper = true
+		}
+		if !inScope(entry.scope) {
+			continue
+		}
+		key := strings.ToLower(entry.key)
+
+		switch {
+		case key == "remote.origin.url":
+			originSeen = true
+			if expectedOrigin != "" && (githubRepoIdentity(entry.value) != githubRepoIdentity(expectedOrigin) ||
+				!isSupportedRemoteScheme(entry.value)) {
+				drift = append(drift, RemoteConfigDrift{Path: path, Scope: entry.scope, Key: entry.key, Expected: expectedOrigin, Actual: entry.value})
+			}
+		case strings.HasPrefix(key, "url.") && (strings.HasSuffix(key, ".insteadof") || strings.HasSuffix(key, ".pushinsteadof")):
+			// A rule applies when its value is a prefix of the URL; only rules moving GitHub https
+			// URLs away from https://github.com/ break the gh credential helper
+			base := entry.key[len("url."):strings.LastIndex(entry.key, ".")]
+			if strings.HasPrefix(githubHTTPSPrefix, entry.value) && !strings.HasPrefix(base, githubHTTPSPrefix) {
+				drift = append(drift, RemoteConfigDrift{Path: path, Scope: entry.scope, Key: entry.key, Actual: entry.value})
+			}
+		case key == "credential.helper" || key == strings.ToLower(githubCredentialHelperKey):
+			if !git.IsGHCredentialHelper(entry.value) {
+				drift = append(drift, RemoteConfigDrift{Path: path, Scope: entry.scope, Key: entry.key, Actual: entry.value})
+			}
+		}
+	}
+
+	if expectedOrigin != "" && !originSeen && inScope(configScopeLocal) {
+		drift = append(drift, RemoteConfigDrift{Path: path, Scope: configScopeLocal, Key: "remote.origin.url", Expected: expectedOrigin})
+	}
+	if checkGlobal && !hasGlobalHelper {
+		drift = append(drift, RemoteConfigDrift{Path: path, Scope: configScopeGlobal, Key: githubCredentialHelperKey, Expected: ghCredentialHelper})
+	}
+	return drift
+}
+
+// expectedOriginURL returns the origin URL catnip configured for a repository
+func expectedOriginURL(repo *models.Repository) string {
+	if repo.RemoteOrigin != "" {
+		return repo.RemoteOrigin
+	}
+	return repo.URL
+}
+
+// isManagedRemoteRepo reports whether catnip owns the remote configuration of a repository.
+// Local repositories (like /live) keep the user's own configuration and are never verified.
+func isManagedRemoteRepo(repo *models.Repository) bool {
+	return !strings.HasPrefix(repo.ID, "local/") && expectedOriginURL(repo) != ""
+}
+
+// readConfigEntries reads every config entry visible from path, including global ones
+func (s *GitService) readConfigEntries(path string) ([]configEntry, error) {
+	env := []string{"HOME=" + config.Runtime.HomeDir}
+	output, err := s.operations.ExecuteGitWithEnv(path, env, remoteConfigReadTimeout, "config", "--list", "--show-scope", "-z")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read git config in %s: %v", path, err)
+	}
+	return parseConfigList(output), nil
+}
+
+// resolveRemoteConfigTarget resolves an ID to a repository and, for worktree IDs, the worktree
+func (s *GitService) resolveRemoteConfigTarget(id string) (*models.Repository, *models.Worktree, error) {
+	if repo, exists := s.stateManager.GetRepository(id); exists {
+		return repo, nil, nil
+	}
+	worktree, exists := s.stateManager.GetWorktree(id)
+	if !exists {
+		return nil, nil, fmt.Errorf("repository or worktree %s not found", id)
+	}
+	repo, exists := s.stateManager.GetRepository(worktree.RepoID)
+	if !exists {
+		return nil, nil, fmt.Errorf("repository %s not found", worktree.RepoID)
+	}
+	return repo, worktree, nil
+}
+
+// collectRemoteConfigDrift checks the repository's shared config and the worktree specific
+// config of the given worktrees
+func (s *GitService) collectRemoteConfigDrift(repo *models.Repository, worktrees []*models.Worktree) ([]RemoteConfigDrift, error) {
+	checkGlobal := config.Runtime.IsContainerized()
+
+	entries, err := s.readConfigEntries(repo.Path)
+	if err != nil {
+		return nil, err
+	}
+	drift := detectRemoteConfigDrift(repo.Path, entries, expectedOriginURL(repo), []string{configScopeLocal}, checkGlobal)
+
+	for _, wt := range worktrees {
+		entries, err := s.readConfigEntries(wt.Path)
+		if err != nil {
+			logger.Debugf("⚠️  Skipping remote config check of worktree %s: %v", wt.Name, err)
+			continue
+		}
+		drift = append(drift, detectRemoteConfigDrift(wt.Path, entries, "", []string{configScopeWorktree}, false)...)
+	}
+	return drift, nil
+}
+
+// repositoryWorktrees returns the worktrees of a repository sorted by name
+func (s *GitService) repositoryWorktrees(repoID string) []*models.Worktree {
+	var worktrees []*models.Worktree
+	for _, wt := range s.stateManager.GetAllWorktrees() {
+		if wt.RepoID == repoID {
+			worktrees = append(worktrees, wt)
+		}
+	}
+	sort.Slice(worktrees, func(i, j int) bool { return worktrees[i].Name < worktrees[j].Name })
+	return worktrees
+}
+
+// VerifyRemoteConfig checks the remote URL, insteadOf rules and credential helpers of a
+// repository (and all its worktrees) or a single worktree, and records drift as a health warning
+func (s *GitService) VerifyRemoteConfig(id string) (*RemoteConfigReport, error) {
+	repo, worktree, err := s.resolveRemoteConfigTarget(id)
+	if err != nil {
+		return nil, err
+	}
+	report := &RemoteConfigReport{RepoID: repo.ID, Drift: []RemoteConfigDrift{}}
+	if !isManagedRemoteRepo(repo) {
+		return report, nil
+	}
+
+	worktrees := s.repositoryWorktrees(repo.ID)
+	if worktree != nil {
+		report.WorktreeID = worktree.ID
+		worktrees = []*models.Worktree{worktree}
+	}
+
+	drift, err := s.collectRemoteConfigDrift(repo, worktrees)
+	if err != nil {
+		return nil, err
+	}
+	report.Drift = append(report.Drift, drift...)
+
+	// Only a full repository check can clear the warning
+	if worktree == nil || len(drift) > 0 {
+		s.recordRemoteConfigDrift(repo.ID, drift)
+	}
+	return report, nil
+}
+
+// recordRemoteConfigDrift raises or clears the repository's remote config health warning
+func (s *GitService) recordRemoteConfigDrift(repoID string, drift []RemoteConfigDrift) {
+	message := ""
+	if len(drift) > 0 {
+		keys := make([]string, 0, len(drift))
+		for _, d := range drift {
+			keys = append(keys, fmt.Sprintf("%s (%s, %s)", d.Key, d.Scope, d.Path))
+		}
+		message = "git remote config drifted from catnip's setup: " + strings.Join(keys, ", ")
+		logger.Warnf("⚠️  Remote config drift in %s: %s", repoID, strings.Join(keys, ", "))
+	}
+	if err := s.stateManager.SetRepositoryHealthWarning(repoID, remoteConfigWarningSource, message); err != nil {
+		logger.Warnf("⚠️  Failed to record remote config health for %s: %v", repoID, err)
+	}
+}
+
+// RepairRemoteConfig restores the remote configuration catnip expects for a repository or
+// worktree. Only the drifted keys are changed; every change is written to the audit log.
+func (s *GitService) RepairRemoteConfig(id string) (*RemoteConfigReport, error) {
+	report, err := s.VerifyRemoteConfig(id)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, d := range report.Drift {
+		if err := s.repairConfigDrift(d); err != nil {
+			return nil, fmt.Errorf("failed to repair %s in %s: %v", d.Key, d.Path, err)
+		}
+		logger.Infof("📋 Repaired git config %s (%s scope) in %s: %q -> %q", d.Key, d.Scope, d.Path, d.Actual, d.Expected)
+		report.Repaired = append(report.Repaired, d.Key)
+	}
+
+	repaired := report.Repaired
+	report, err = s.VerifyRemoteConfig(id)
+	if err != nil {
+		return nil, err
+	}
+	report.Repaired = repaired
+	return report, nil
+}
+
+// repairConfigDrift fixes a single drifted config entry
+func (s *GitService) repairConfigDrift(d RemoteConfigDrift) error {
+	switch {
+	case d.Scope == configScopeGlobal:
+		_, err := s.RepairCredentials()
+		return err
+	case strings.EqualFold(d.Key, "remote.origin.url"):
+		if d.Actual == "" {
+			return s.operations.AddRemote(d.Path, "origin", d.Expected)
+		}
+		return s.operations.SetRemoteURL(d.Path, "origin", d.Expected)
+	default:
+		_, err := s.operations.ExecuteGit(d.Path, "config", "--"+d.Scope, "--unset-all", d.Key)
+		return err
+	}
+}
+
+// RepairCredentials makes gh the credential helper of the GitHub hosts in the global git config
+// without disturbing other helpers, verifies them with a credential fill and records the outcome
+// for the git subsystem health
+func (s *GitService) RepairCredentials() (*git.CredentialHelperReport, error) {
+	report, err := s.githubManager.ConfigureGitCredentials()
+	if err != nil {
+		return nil, err
+	}
+	if report != nil {
+		s.credentialStatus.Store(report)
+	}
+	return report, nil
+}
+
+// CredentialHelperStatus returns the outcome of the last credential helper configuration, or
+// nil before the first one
+func (s *GitService) CredentialHelperStatus() *git.CredentialHelperReport {
+	return s.credentialStatus.Load()
+}
+
+// verifyAllRemoteConfigs checks the remote configuration of every managed repository
+func (s *GitService) verifyAllRemoteConfigs() {
+	for _, repo := range s.stateManager.GetAllRepositories() {
+		if !isManagedRemoteRepo(repo) || !repo.Available {
+			continue
+		}
+		if _, err := s.VerifyRemoteConfig(repo.ID); err != nil {
+			logger.Debugf("⚠️  Remote config check failed for %s: %v", repo.ID, err)
+		}
+	}
+}
+
+// startRemoteConfigVerifier periodically verifies the remote configuration of all repositories
+func (s *GitService) startRemoteConfigVerifier() {
+	ticker := time.NewTicker(remoteConfigCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.verifyAllRemoteConfigs()
+		case <-s.stopCh:
+			return
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package services
+
+import (
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/config"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+func TestParseConfigList(t *testing.T) {
+	output := []byte("global\x00credential.https://github.com.helper\n!gh auth git-credential\x00local\x00core.bare\nfalse\x00")
+	assert.Equal(t, []configEntry{
+		{scope: "global", key: "credential.https://github.com.helper", value: "!gh auth git-credential"},
+		{scope: "local", key: "core.bare", value: "false"},
+	}, parseConfigList(output))
+}
+
+func TestDetectRemoteConfigDrift(t *testing.T) {
+	expected := "https://github.com/owner/repo.git"
+	healthy := []configEntry{
+		{scope: "global", key: githubCredentialHelperKey, value: ghCredentialHelper},
+		{scope: "local", key: "remote.origin.url", value: "git@github.com:owner/repo.git"},
+		{scope: "local", key: "url.https://github.com/.insteadof", value: "git@github.com:"},
+		{scope: "local", key: "user.name", value: "Felix"},
+	}
+	assert.Empty(t, detectRemoteConfigDrift("/repo", healthy, expected, []string{"local"}, true))
+
+	drifted := []configEntry{
+		{scope: "local", key: "remote.origin.url", value: "https://github.com/someone/else.git"},
+		{scope: "local", key: "url.git@github.com:.insteadof", value: "https://github.com/"},
+		{scope: "local", key: "credential.helper", value: "store"},
+		{scope: "global", key: "credential.helper", value: "osxkeychain"},
+	}
+	drift := detectRemoteConfigDrift("/repo", drifted, expected, []string{"local"}, true)
+	var keys []string
+	for _, d := range drift {
+		keys = append(keys, d.Scope+":"+d.Key)
+	}
+	assert.Equal(t, []string{
+		"local:remote.origin.url",
+		"local:url.git@github.com:.insteadof",
+		"local:credential.helper",
+		"global:" + githubCredentialHelperKey,
+	}, keys)
+	assert.Equal(t, expected, drift[0].Expected)
+}
+
+func TestRepairRemoteConfig(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	originalMode := config.Runtime.Mode
+	config.Runtime.Mode = config.NativeMode
+	t.Cleanup(func() { config.Runtime.Mode = originalMode })
+
+	root := t.TempDir()
+	repoPath := initUnbornTestRepo(t, filepath.Join(root, "repo"))
+	runTestGit(t, repoPath, "remote", "add", "origin", "https://github.com/someone/else.git")
+	runTestGit(t, repoPath, "config", "url.git@github.com:.insteadOf", "https://github.com/")
+	runTestGit(t, repoPath, "config", "user.name", "Felix")
+
+	s := newTestGitService(t, root)
+	stateManager := s.stateManager
+	require.NoError(t, stateManager.AddRepository(&models.Repository{
+		ID:           "owner/repo",
+		Path:         repoPath,
+		RemoteOrigin: "https://github.com/owner/repo.git",
+		Available:    true,
+	}))
+
+	report, err := s.VerifyRemoteConfig("owner/repo")
+	require.NoError(t, err)
+	assert.Len(t, report.Drift, 2)
+	repo, _ := stateManager.GetRepository("owner/repo")
+	assert.Contains(t, repo.HealthWarnings[remoteConfigWarningSource], "url.git@github.com:.insteadof")
+
+	report, err = s.RepairRemoteConfig("owner/repo")
+	require.NoError(t, err)
+	assert.Empty(t, report.Drift)
+	assert.Equal(t, []string{"remote.origin.url", "url.git@github.com:.insteadof"}, report.Repaired)
+
+	assert.Equal(t, "https://github.com/owner/repo.git", runTestGit(t, repoPath, "remote", "get-url", "origin"))
+	assert.Equal(t, "Felix", runTestGit(t, repoPath, "config", "user.name"), "unrelated keys are untouched")
+	repo, _ = stateManager.GetRepository("owner/repo")
+	assert.NotContains(t, repo.HealthWarnings, remoteConfigWarningSource)
+}
@@ -0,0 +1,277 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// Git config keys holding the thresholds that make the maintenance scheduler run git gc on a
+// repository. Can be set per repository or globally; 0 disables a threshold.
+const (
+	// Loose objects above which git gc --auto runs
+	gcLooseObjectsKey = "catnip.gc.loose-objects"
+	// Pack files above which git gc --auto runs, consolidating them
+	gcPacksKey = "catnip.gc.packs"
+	// MiB of loose objects above which a full git gc runs
+	gcFullLooseSizeKey = "catnip.gc.full-loose-size-mb"
+)
+
+const (
+	defaultGCLooseObjects    = 2000
+	defaultGCPacks           = 20
+	defaultGCFullLooseSizeMB = 256
+	// defaultGCInterval is how often repositories are checked against the gc thresholds,
+	// configurable in minutes via CATNIP_GC_INTERVAL_MINUTES (0 disables)
+	defaultGCInterval = time.Hour
+	// gcInitialDelay postpones the first check until startup work has settled
+	gcInitialDelay = 5 * time.Minute
+	// gcTimeout bounds one git gc run
+	gcTimeout = 30 * time.Minute
+)
+
+// Garbage collections of a repository
+const (
+	GCActionNone = "none"
+	GCActionAuto = "auto"
+	GCActionFull = "full"
+)
+
+// gcThresholds are the limits above which a repository is garbage collected, 0 for disabled
+type gcThresholds struct {
+	looseObjects int64
+	packs        int64
+	fullLooseKB  int64
+}
+
+// gcInterval reads CATNIP_GC_INTERVAL_MINUTES, keeping the default for unset or invalid values.
+// Zero disables scheduled garbage collection.
+func gcInterval() time.Duration {
+	if value := os.Getenv("CATNIP_GC_INTERVAL_MINUTES"); value != "" {
+		if minutes, err := strconv.Atoi(value); err == nil && minutes >= 0 {
+			return time.Duration(minutes) * time.Minute
+		}
+		logger.Warnf("⚠️  Ignoring invalid CATNIP_GC_INTERVAL_MINUTES value %q", value)
+	}
+	return defaultGCInterval
+}
+
+// loadGCThresholds reads the gc thresholds of a repository from git config, keeping the
+// defaults for unset or invalid values
+func loadGCThresholds(getConfig func(key string) (string, error)) gcThresholds {
+	load := func(key string, fallback int64) int64 {
+		if value, err := getConfig(key); err == nil && value != "" {
+			if n, err := strconv.ParseInt(value, 10, 64); err == nil && n >= 0 {
+				return n
+			}
+			logger.Warnf("⚠️  Ignoring invalid %s value %q", key, value)
+		}
+		return fallback
+	}
+	return gcThresholds{
+		looseObjects: load(gcLooseObjectsKey, defaultGCLooseObjects),
+		packs:        load(gcPacksKey, defaultGCPacks),
+		fullLooseKB:  load(gcFullLooseSizeKey, defaultGCFullLooseSizeMB) << 10,
+	}
+}
+
+// gcAction decides which gc a repository with stats needs and why. A full gc is asked for with
+// force, or needed once loose objects take too much space; git gc --auto packs loose objects
+// and consolidates packs when there are too many of either.
+func gcAction(stats models.ObjectStats, thresholds gcThresholds, force bool) (action, reason string) {
+	switch {
+	case thresholds.fullLooseKB > 0 && stats.LooseKB > thresholds.fullLooseKB:
+		return GCActionFull, fmt.Sprintf("%d MiB of loose objects, more than %d", stats.LooseKB>>10, thresholds.fullLooseKB>>10)
+	case force:
+		return GCActionFull, "requested"
+	case thresholds.looseObjects > 0 && stats.LooseObjects > thresholds.looseObjects:
+		return GCActionAuto, fmt.Sprintf("%d loose objects, more than %d", stats.LooseObjects, thresholds.looseObjects)
+	case thresholds.packs > 0 && stats.Packs > thresholds.packs:
+		return GCActionAuto, fmt.Sprintf("%d packs, more than %d", stats.Packs, thresholds.packs)
+	}
+	return GCActionNone, ""
+}
+
+// gcArgs returns the git arguments running a gc. git gc --auto is told catnip's thresholds, so
+// it agrees about what is too much; a disabled threshold keeps git's default.
+func gcArgs(action string, thresholds gcThresholds) []string {
+	if action == GCActionFull {
+		return []string{"gc", "--quiet"}
+	}
+	var args []string
+	if thresholds.looseObjects > 0 {
+		args = append(args, "-c", fmt.Sprintf("gc.auto=%d", thresholds.looseObjects))
+	}
+	if thresholds.packs > 0 {
+		args = append(args, "-c", fmt.Sprintf("gc.autoPackLimit=%d", thresholds.packs))
+	}
+	return append(args, "gc", "--auto", "--quiet")
+}
+
+// objectStats counts the loose and packed objects of a repository
+func (s *GitService) objectStats(repoPath string) (models.ObjectStats, error) {
+	var stats models.ObjectStats
+	output, err := s.operations.ExecuteGit(repoPath, "count-objects", "-v")
+	if err != nil {
+		return stats, fmt.Errorf("failed to count objects: %v", err)
+	}
+	for _, line := range strings.Split(string(output), "\n") {
+		key, value, found := strings.Cut(line, ": ")
+		if !found {
+			continue
+		}
+		n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err != nil {
+			continue
+		}
+		switch key {
+		case "count":
+			stats.LooseObjects = n
+		case "size":
+			stats.LooseKB = n
+		case "packs":
+			stats.Packs = n
+		case "size-pack":
+			stats.PackKB = n
+		case "size-garbage":
+			stats.GarbageKB = n
+		}
+	}
+	return stats, nil
+}
+
+// repositoryLock returns the lock of the repository a repository or worktree path belongs to.
+// Fetches and worktree creation hold it shared, garbage collection exclusively, so git gc
+// never repacks or prunes objects another git command is writing.
+func (s *GitService) repositoryLock(path string) *sync.RWMutex {
+	if worktree, exists := s.stateManager.FindWorktreeByPath(path); exists {
+		if repo, exists := s.stateManager.GetRepository(worktree.RepoID); exists && repo.Path != "" {
+			path = repo.Path
+		}
+	}
+	value, _ := s.repositoryLocks.LoadOrStore(path, &sync.RWMutex{})
+	return value.(*sync.RWMutex)
+}
+
+// shareRepository holds the lock of the repository of path shared until the returned function
+// is called
+func (s *GitService) shareRepository(path string) func() {
+	lock := s.repositoryLock(path)
+	lock.RLock()
+	return lock.RUnlock
+}
+
+// recordGC stores when git gc last ran on a repository
+func (s *GitService) recordGC(repoID string, ranAt time.Time) {
+	if err := s.stateManager.UpdateRepository(repoID, func(repo *models.Repository) {
+		repo.LastGC = &ranAt
+	}); err != nil {
+		logger.Warnf("⚠️  Failed to record gc of %s: %v", repoID, err)
+	}
+}
+
+// maintainRepository checks a repository against its gc thresholds and runs the gc it needs,
+// a full one when forced. With wait unset a repository busy with fetches or worktree creation
+// is skipped, returning nil.
+func (s *GitService) maintainRepository(repo *models.Repository, force, wait bool) (*models.RepositoryMaintenance, error) {
+	lock := s.repositoryLock(repo.Path)
+	if wait {
+		lock.Lock()
+	} else if !lock.TryLock() {
+		logger.Debugf("🧹 Skipping gc check of %s, it is busy", repo.ID)
+		return nil, nil
+	}
+	defer lock.Unlock()
+
+	before, err := s.objectStats(repo.Path)
+	if err != nil {
+		return nil, err
+	}
+	thresholds := loadGCThresholds(func(key string) (string, error) {
+		output, err := s.operations.ExecuteGit(repo.Path, "config", "--get", key)
+		return strings.TrimSpace(string(output)), err
+	})
+	result := &models.RepositoryMaintenance{RepoID: repo.ID, Before: before}
+	result.Action, result.Reason = gcAction(before, thresholds, force)
+	if result.Action == GCActionNone {
+		return result, nil
+	}
+
+	started := time.Now()
+	if output, err := s.operations.ExecuteGitWithTimeout(repo.Path, gcTimeout, gcArgs(result.Action, thresholds)...); err != nil {
+		return nil, fmt.Errorf("git gc failed: %v: %s", err, strings.TrimSpace(string(output)))
+	}
+	result.DurationMS = time.Since(started).Milliseconds()
+	if after, err := s.objectStats(repo.Path); err == nil {
+		result.After = &after
+	}
+	s.recordGC(repo.ID, started)
+	logger.Infof("🧹 Ran %s gc on %s in %dms (%s)", result.Action, repo.ID, result.DurationMS, result.Reason)
+	return result, nil
+}
+
+// TriggerMaintenance runs a full git gc on a repository now, once no fetch or worktree creation
+// is using it
+func (s *GitService) TriggerMaintenance(repoID string) (*models.RepositoryMaintenance, error) {
+	repo, exists := s.stateManager.GetRepository(repoID)
+	if !exists {
+		return nil, fmt.Errorf("repository %s not found", repoID)
+	}
+	result, err := s.maintainRepository(repo, true, true)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.RefreshRepositorySize(repoID); err != nil {
+		logger.Debugf("⚠️  Failed to measure size of %s: %v", repoID, err)
+	}
+	return result, nil
+}
+
+// maintainAllRepositories checks every available repository against its gc thresholds
+func (s *GitService) maintainAllRepositories() {
+	for _, repo := range s.stateManager.GetAllRepositories() {
+		if !repo.Available {
+			continue
+		}
+		result, err := s.maintainRepository(repo, false, false)
+		if err != nil {
+			logger.Warnf("⚠️  Failed to maintain %s: %v", repo.ID, err)
+			continue
+		}
+		if result != nil && result.Action != GCActionNone {
+			if _, err := s.RefreshRepositorySize(repo.ID); err != nil {
+				logger.Debugf("⚠️  Failed to measure size of %s: %v", repo.ID, err)
+			}
+		}
+	}
+}
+
+// startMaintenanceScheduler periodically garbage collects repositories that exceed their
+// thresholds until the service stops
+func (s *GitService) startMaintenanceScheduler() {
+	interval := gcInterval()
+	if interval == 0 {
+		return
+	}
+	initial := time.NewTimer(gcInitialDelay)
+	defer initial.Stop()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-initial.C:
+			s.maintainAllRepositories()
+		case <-ticker.C:
+			s.maintainAllRepositories()
+		case <-s.stopCh:
+			return
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+func TestGCAction(t *testing.T) {
+	thresholds := gcThresholds{looseObjects: 100, packs: 10, fullLooseKB: 64 << 10}
+	cases := []struct {
+		stats  models.ObjectStats
+		force  bool
+		action string
+		reason string
+	}{
+		{models.ObjectStats{LooseObjects: 100, Packs: 10}, false, GCActionNone, ""},
+		{models.ObjectStats{LooseObjects: 101}, false, GCActionAuto, "101 loose objects, more than 100"},
+		{models.ObjectStats{Packs: 11}, false, GCActionAuto, "11 packs, more than 10"},
+		{models.ObjectStats{LooseObjects: 5000, LooseKB: 80 << 10}, false, GCActionFull, "80 MiB of loose objects, more than 64"},
+		{models.ObjectStats{}, true, GCActionFull, "requested"},
+	}
+	for _, c := range cases {
+		action, reason := gcAction(c.stats, thresholds, c.force)
+		assert.Equal(t, c.action, action, "%+v", c.stats)
+		assert.Equal(t, c.reason, reason, "%+v", c.stats)
+	}
+
+	action, _ := gcAction(models.ObjectStats{LooseObjects: 1 << 20, Packs: 1 << 10}, gcThresholds{}, false)
+	assert.Equal(t, GCActionNone, action, "disabled thresholds")
+
+	assert.Equal(t, []string{"-c", "gc.auto=100", "-c", "gc.autoPackLimit=10", "gc", "--auto", "--quiet"}, gcArgs(GCActionAuto, thresholds))
+	assert.Equal(t, []string{"-c", "gc.autoPackLimit=10", "gc", "--auto", "--quiet"}, gcArgs(GCActionAuto, gcThresholds{packs: 10}))
+	assert.Equal(t, []string{"gc", "--quiet"}, gcArgs(GCActionFull, thresholds))
+}
+
+func TestRepositoryMaintenance(t *testing.T) {
+	s, stateManager, repoPath, worktreePath := newRecreateTestService(t)
+	for i := range 5 {
+		require.NoError(t, os.WriteFile(filepath.Join(worktreePath, fmt.Sprintf("file%d.txt", i)), []byte(fmt.Sprintf("content %d\n", i)), 0644))
+		runTestGit(t, worktreePath, "add", "-A")
+		runTestGit(t, worktreePath, "commit", "-m", fmt.Sprintf("commit %d", i))
+	}
+	repo, _ := stateManager.GetRepository("local/repo")
+
+	t.Run("ThresholdsFromGitConfig", func(t *testing.T) {
+		result, err := s.maintainRepository(repo, false, true)
+		require.NoError(t, err)
+		assert.Equal(t, GCActionNone, result.Action)
+		assert.Nil(t, result.After)
+		repo, _ := stateManager.GetRepository("local/repo")
+		assert.Nil(t, repo.LastGC, "nothing ran")
+
+		runTestGit(t, repoPath, "config", gcLooseObjectsKey, "10")
+		result, err = s.maintainRepository(repo, false, true)
+		require.NoError(t, err)
+		assert.Equal(t, GCActionAuto, result.Action)
+		assert.Contains(t, result.Reason, "more than 10")
+		assert.Greater(t, result.Before.LooseObjects, int64(10))
+		require.NotNil(t, result.After)
+	})
+
+	t.Run("SkippedWhileFetching", func(t *testing.T) {
+		unshare := s.shareRepository(worktreePath)
+		result, err := s.maintainRepository(repo, true, false)
+		require.NoError(t, err)
+		assert.Nil(t, result, "a worktree's fetch holds its repository")
+
+		done := make(chan *models.RepositoryMaintenance)
+		go func() {
+			result, _ := s.TriggerMaintenance("local/repo")
+			done <- result
+		}()
+		select {
+		case <-done:
+			t.Fatal("gc ran during a fetch")
+		case <-time.After(50 * time.Millisecond):
+		}
+		unshare()
+		result = <-done
+		require.NotNil(t, result)
+		assert.Equal(t, GCActionFull, result.Action)
+		assert.Equal(t, "requested", result.Reason)
+		assert.Zero(t, result.After.LooseObjects)
+		assert.Equal(t, int64(1), result.After.Packs)
+
+		repo, _ := stateManager.GetRepository("local/repo")
+		require.NotNil(t, repo.LastGC)
+		assert.WithinDuration(t, time.Now(), *repo.LastGC, time.Minute)
+		require.NotNil(t, repo.Size)
+		assert.Equal(t, int64(1), repo.Size.PackCount)
+	})
+
+	_, err := s.TriggerMaintenance("acme/missing")
+	assert.ErrorContains(t, err, "not found")
+}
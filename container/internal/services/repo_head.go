@@ -0,0 +1,142 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// Git config key allowing catnip to create an empty initial commit in repositories without
+// commits, so a worktree can be created. Can be set per repository or globally.
+const createInitialCommitKey = "catnip.init.create-initial-commit"
+
+// HEAD states recorded on repositories by local repo detection
+const (
+	HeadStateBranch   = "branch"   // HEAD points at an existing branch
+	HeadStateDetached = "detached" // HEAD points directly at a commit
+	HeadStateUnborn   = "unborn"   // HEAD points at a branch without commits
+)
+
+// headInspection is the interpreted HEAD of a local repository
+type headInspection struct {
+	State string
+	// Branch to base worktrees on: the current branch, the nearest branch containing a
+	// detached HEAD, or the branch the first commit will be created on
+	Branch string
+}
+
+// inspectHead determines whether a repository's HEAD is on a branch, detached or unborn
+func (lrm *LocalRepoManager) inspectHead(repoPath string) headInspection {
+	symbolic, symbolicErr := lrm.operations.ExecuteGit(repoPath, "symbolic-ref", "-q", "--short", "HEAD")
+	branch := strings.TrimSpace(string(symbolic))
+	_, headErr := lrm.operations.ExecuteGit(repoPath, "cat-file", "-e", "HEAD^{commit}")
+
+	switch {
+	case headErr != nil:
+		if symbolicErr != nil || branch == "" {
+			branch = lrm.initDefaultBranch(repoPath)
+		}
+		return headInspection{State: HeadStateUnborn, Branch: branch}
+	case symbolicErr == nil && branch != "":
+		return headInspection{State: HeadStateBranch, Branch: branch}
+	default:
+		if nearest := lrm.nearestBranchContainingHead(repoPath); nearest != "" {
+			return headInspection{State: HeadStateDetached, Branch: nearest}
+		}
+		return headInspection{State: HeadStateDetached, Branch: lrm.initDefaultBranch(repoPath)}
+	}
+}
+
+// nearestBranchContainingHead returns the local branch containing HEAD with the fewest commits
+// on top of it, or "" when no branch contains HEAD
+func (lrm *LocalRepoManager) nearestBranchContainingHead(repoPath string) string {
+	output, err := lrm.operations.ExecuteGit(repoPath, "branch", "--contains", "HEAD", "--format=%(refname:short)")
+	if err != nil {
+		return ""
+	}
+
+	nearest, nearestDistance := "", -1
+	for _, branch := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		branch = strings.TrimSpace(branch)
+		if branch == "" || strings.HasPrefix(branch, "(") {
+			continue
+		}
+		countOutput, err := lrm.operations.ExecuteGit(repoPath, "rev-list", "--count", "HEAD.."+branch)
+		if err != nil {
+			continue
+		}
+		distance, err := strconv.Atoi(strings.TrimSpace(string(countOutput)))
+		if err != nil {
+			continue
+		}
+		if nearestDistance == -1 || distance < nearestDistance {
+			nearest, nearestDistance = branch, distance
+		}
+	}
+	return nearest
+}
+
+// initDefaultBranch returns the repository's init.defaultBranch, falling back to main
+func (lrm *LocalRepoManager) initDefaultBranch(repoPath string) string {
+	if branch, err := lrm.operations.GetConfig(repoPath, "init.defaultBranch"); err == nil && branch != "" {
+		return branch
+	}
+	return "main"
+}
+
+// applyHeadInspection records the HEAD state on a detected repository. Unborn repositories are
+// marked as not ready, since worktrees can't be created without a commit.
+func applyHeadInspection(repo *models.Repository, head headInspection) {
+	repo.HeadState = head.State
+	repo.NotReadyReason = ""
+	switch head.State {
+	case HeadStateUnborn:
+		repo.DefaultBranch = head.Branch
+		repo.NotReadyReason = fmt.Sprintf("Repository has no commits yet (branch %s is unborn). Create a commit, or run `git config %s true` to let catnip create an empty initial commit.",
+			head.Branch, createInitialCommitKey)
+	case HeadStateDetached:
+		logger.Infof("🔍 %s has a detached HEAD, using branch %s", repo.ID, head.Branch)
+	}
+}
+
+// prepareUnbornRepo creates an empty initial commit on the unborn branch of a repository when
+// allowed by createInitialCommitKey. The user's index and working tree are left untouched.
+// Returns whether the repository is ready for worktrees.
+func (s *GitService) prepareUnbornRepo(repo *models.Repository) bool {
+	if repo.HeadState != HeadStateUnborn {
+		return true
+	}
+	if value, err := s.operations.GetConfig(repo.Path, createInitialCommitKey); err != nil || value != "true" {
+		logger.Infof("⏸️  Not creating a worktree for %s: %s", repo.ID, repo.NotReadyReason)
+		return false
+	}
+
+	if err := s.createInitialCommit(repo.Path, repo.DefaultBranch); err != nil {
+		repo.NotReadyReason = fmt.Sprintf("Failed to create an initial commit on %s: %v", repo.DefaultBranch, err)
+		logger.Warnf("⚠️  %s", repo.NotReadyReason)
+		return false
+	}
+
+	logger.Infof("🌱 Created empty initial commit on %s in %s", repo.DefaultBranch, repo.ID)
+	repo.HeadState = HeadStateBranch
+	repo.NotReadyReason = ""
+	return true
+}
+
+// createInitialCommit points branch at a new commit with an empty tree, refusing to overwrite
+// the branch if it was created in the meantime
+func (s *GitService) createInitialCommit(repoPath, branch string) error {
+	tree, err := s.operations.ExecuteGit(repoPath, "hash-object", "-t", "tree", "-w", "/dev/null")
+	if err != nil {
+		return err
+	}
+	commit, err := s.operations.ExecuteGit(repoPath, "commit-tree", strings.TrimSpace(string(tree)), "-m", "Initial commit")
+	if err != nil {
+		return err
+	}
+	_, err = s.operations.ExecuteGit(repoPath, "update-ref", "refs/heads/"+branch, strings.TrimSpace(string(commit)), "")
+	return err
+}
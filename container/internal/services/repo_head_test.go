@@ -0,0 +1,74 @@
+package services
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/git"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// initHeadTestRepo creates a repository with no commits on branch trunk
+func initHeadTestRepo(t *testing.T) string {
+	repoPath := initUnbornTestRepo(t, filepath.Join(t.TempDir(), "repo"))
+	runTestGit(t, repoPath, "symbolic-ref", "HEAD", "refs/heads/trunk")
+	return repoPath
+}
+
+func TestInspectHead(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	lrm := NewLocalRepoManager(git.NewOperations())
+	repoPath := initHeadTestRepo(t)
+
+	assert.Equal(t, headInspection{State: HeadStateUnborn, Branch: "trunk"}, lrm.inspectHead(repoPath))
+
+	runTestGit(t, repoPath, "commit", "--allow-empty", "-m", "first")
+	first := runTestGit(t, repoPath, "rev-parse", "HEAD")
+	runTestGit(t, repoPath, "commit", "--allow-empty", "-m", "second")
+	runTestGit(t, repoPath, "branch", "release", first)
+	assert.Equal(t, headInspection{State: HeadStateBranch, Branch: "trunk"}, lrm.inspectHead(repoPath))
+
+	// The first commit is on both branches; release is the nearest
+	runTestGit(t, repoPath, "checkout", "--detach", first)
+	assert.Equal(t, headInspection{State: HeadStateDetached, Branch: "release"}, lrm.inspectHead(repoPath))
+
+	// Commits on no branch fall back to init.defaultBranch
+	runTestGit(t, repoPath, "commit", "--allow-empty", "-m", "orphaned")
+	runTestGit(t, repoPath, "config", "init.defaultBranch", "develop")
+	assert.Equal(t, headInspection{State: HeadStateDetached, Branch: "develop"}, lrm.inspectHead(repoPath))
+}
+
+func TestPrepareUnbornRepo(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	operations := git.NewOperations()
+	lrm := NewLocalRepoManager(operations)
+	s := &GitService{operations: operations}
+
+	repoPath := initHeadTestRepo(t)
+	require.NoError(t, os.WriteFile(filepath.Join(repoPath, "staged.txt"), []byte("work in progress\n"), 0644))
+	runTestGit(t, repoPath, "add", "staged.txt")
+
+	repo := &models.Repository{ID: "local/repo", Path: repoPath}
+	applyHeadInspection(repo, lrm.inspectHead(repoPath))
+	assert.Equal(t, "trunk", repo.DefaultBranch)
+	assert.Contains(t, repo.NotReadyReason, createInitialCommitKey)
+
+	assert.False(t, s.prepareUnbornRepo(repo), "no initial commit without consent")
+	_, err := exec.Command("git", "-C", repoPath, "rev-parse", "--verify", "HEAD").Output()
+	assert.Error(t, err)
+
+	runTestGit(t, repoPath, "config", createInitialCommitKey, "true")
+	assert.True(t, s.prepareUnbornRepo(repo))
+	assert.Equal(t, HeadStateBranch, repo.HeadState)
+	assert.Empty(t, repo.NotReadyReason)
+	assert.Empty(t, runTestGit(t, repoPath, "ls-tree", "trunk"), "initial commit is empty")
+	assert.Equal(t, "A  staged.txt", runTestGit(t, repoPath, "status", "--porcelain"), "staged work is untouched")
+}
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/vanpelt/catnip/internal/config"
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// devRepoName is the directory catnip's own checkout is mounted as in the live directory when
+// running in dev mode
+const devRepoName = "catnip"
+
+// ErrProtectedRepository is returned, wrapped in a ProtectedRepositoryError, when a destructive
+// operation on a protected repository wasn't confirmed
+var ErrProtectedRepository = errors.New("repository is protected")
+
+// ProtectedRepositoryError describes a destructive operation refused on a protected repository
+type ProtectedRepositoryError struct {
+	RepoID    string
+	Operation string
+	Reason    string
+}
+
+func (e *ProtectedRepositoryError) Error() string {
+	return fmt.Sprintf("repository %s is protected (%s), confirm to %s", e.RepoID, e.Reason, e.Operation)
+}
+
+func (e *ProtectedRepositoryError) Unwrap() error {
+	return ErrProtectedRepository
+}
+
+// ErrRepositoryInUse is returned, wrapped in a RepositoryInUseError, when a repository with
+// worktrees is deleted without force
+var ErrRepositoryInUse = errors.New("repository has worktrees")
+
+// BlockingWorktree identifies a worktree that keeps its repository from being deleted
+type BlockingWorktree struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Branch string `json:"branch"`
+}
+
+// RepositoryInUseError describes a repository deletion refused because of its worktrees
+type RepositoryInUseError struct {
+	RepoID    string
+	Worktrees []BlockingWorktree
+}
+
+func (e *RepositoryInUseError) Error() string {
+	return fmt.Sprintf("repository %s still has %d worktree(s), delete them first or force the deletion", e.RepoID, len(e.Worktrees))
+}
+
+func (e *RepositoryInUseError) Unwrap() error {
+	return ErrRepositoryInUse
+}
+
+// devRepoPath returns where catnip's own checkout is mounted in dev mode, "" otherwise
+func devRepoPath() string {
+	if os.Getenv("CATNIP_DEV") != "true" || config.Runtime.LiveDir == "" {
+		return ""
+	}
+	return filepath.Join(config.Runtime.LiveDir, devRepoName)
+}
+
+// repositoryProtectionReason explains why the repository at repoPath is protected, "" when it
+// isn't. CATNIP_PROTECTED_REPOS lists additional paths, separated like PATH.
+func repositoryProtectionReason(repoPath string) string {
+	repoPath = filepath.Clean(repoPath)
+	if devPath := devRepoPath(); devPath != "" && repoPath == devPath {
+		return "catnip development repository serving the running code"
+	}
+	for _, path := range filepath.SplitList(os.Getenv("CATNIP_PROTECTED_REPOS")) {
+		if path != "" && filepath.Clean(path) == repoPath {
+			return "listed in CATNIP_PROTECTED_REPOS"
+		}
+	}
+	return ""
+}
+
+// applyRepositoryProtection marks a freshly detected local repository as protected when it
+// should be, logging why
+func applyRepositoryProtection(repo *models.Repository) {
+	repo.ProtectedReason = repositoryProtectionReason(repo.Path)
+	repo.Protected = repo.ProtectedReason != ""
+	if repo.Protected {
+		logger.Infof("🛡️ Protecting %s at %s (%s): force pushes and branch deletion in it need confirmation and cleanup keeps its branches",
+			repo.ID, repo.Path, repo.ProtectedReason)
+	}
+}
+
+// requireConfirmed refuses operation on a protected repository unless confirm is set
+func requireConfirmed(repo *models.Repository, operation string, confirm bool) error {
+	if !repo.Protected || confirm {
+		return nil
+	}
+	return &ProtectedRepositoryError{RepoID: repo.ID, Operation: operation, Reason: repo.ProtectedReason}
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/config"
+	"github.com/vanpelt/catnip/internal/git"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+func TestRepositoryProtectionReason(t *testing.T) {
+	liveDir := config.Runtime.LiveDir
+	t.Cleanup(func() { config.Runtime.LiveDir = liveDir })
+	config.Runtime.LiveDir = "/live"
+
+	t.Setenv("CATNIP_DEV", "")
+	t.Setenv("CATNIP_PROTECTED_REPOS", "")
+	assert.Empty(t, repositoryProtectionReason("/live/catnip"), "only protected in dev mode")
+
+	t.Setenv("CATNIP_DEV", "true")
+	assert.Contains(t, repositoryProtectionReason("/live/catnip/"), "development repository")
+	assert.Empty(t, repositoryProtectionReason("/live/other"))
+
+	t.Setenv("CATNIP_PROTECTED_REPOS", "/srv/a"+string(filepath.ListSeparator)+"/srv/b")
+	assert.Equal(t, "listed in CATNIP_PROTECTED_REPOS", repositoryProtectionReason("/srv/b"))
+
+	repo := &models.Repository{ID: "local/catnip", Path: "/live/catnip"}
+	applyRepositoryProtection(repo)
+	assert.True(t, repo.Protected)
+	repo = &models.Repository{ID: "local/other", Path: "/live/other"}
+	applyRepositoryProtection(repo)
+	assert.False(t, repo.Protected)
+	assert.Empty(t, repo.ProtectedReason)
+}
+
+func TestProtectedRepositoryOperations(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	root := t.TempDir()
+	repoPath := initTestRepo(t, filepath.Join(root, "catnip"), nil)
+	worktreePath := filepath.Join(root, "felix")
+	runTestGit(t, repoPath, "worktree", "add", "-b", "refs/catnip/felix", worktreePath)
+	runTestGit(t, worktreePath, "commit", "--allow-empty", "-m", "Work")
+
+	t.Setenv("CATNIP_PROTECTED_REPOS", repoPath)
+	repo := &models.Repository{ID: "local/catnip", Path: repoPath, DefaultBranch: "main", Available: true}
+	applyRepositoryProtection(repo)
+	require.True(t, repo.Protected)
+
+	s := newTestGitService(t, root)
+	stateManager := s.stateManager
+	require.NoError(t, stateManager.AddRepository(repo))
+	require.NoError(t, stateManager.AddWorktree(&models.Worktree{
+		ID: "wt-felix", RepoID: repo.ID, Name: "catnip/felix", Path: worktreePath, Branch: "refs/catnip/felix", SourceBranch: "main",
+	}))
+
+	// Creating the preview branch is a plain push, replacing it force pushes
+	require.NoError(t, s.CreateWorktreePreview("wt-felix", false))
+	runTestGit(t, worktreePath, "commit", "--allow-empty", "-m", "More work")
+	err := s.CreateWorktreePreview("wt-felix", false)
+	assert.ErrorIs(t, err, ErrProtectedRepository)
+	assert.Contains(t, err.Error(), "confirm to force push catnip/felix")
+	require.NoError(t, s.CreateWorktreePreview("wt-felix", true))
+	assert.Equal(t, "More work", runTestGit(t, repoPath, "log", "-1", "--pretty=format:%s", "catnip/felix"))
+
+	// Deleting the worktree keeps its preview branch
+	worktree, _ := stateManager.GetWorktree("wt-felix")
+	require.NoError(t, git.NewWorktreeManager(s.operations).DeleteWorktree(worktree, repo))
+	runTestGit(t, repoPath, "rev-parse", "--verify", "catnip/felix")
+}
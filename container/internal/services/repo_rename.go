@@ -0,0 +1,287 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/config"
+	"github.com/vanpelt/catnip/internal/git"
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// Git config key selecting what happens when a repository turns out to be renamed or
+// transferred on GitHub. Can be set per repository or globally.
+const repositoryRenamePolicyKey = "catnip.repository.rename-policy"
+
+// RepositoryRenamePolicy decides what happens when a GitHub repository was renamed or transferred
+type RepositoryRenamePolicy string
+
+const (
+	// RenameMigrate moves the repository to its new name right away (the default)
+	RenameMigrate RepositoryRenamePolicy = "migrate"
+	// RenameFlag records a health warning until the migration is requested
+	RenameFlag RepositoryRenamePolicy = "flag"
+)
+
+const (
+	// renameWarningSource is the health warning source of detected renames
+	renameWarningSource = "rename"
+	// renameCheckInterval throttles the rename checks that follow fetches and pushes
+	renameCheckInterval = time.Hour
+	// githubRenameTimeout bounds a rename lookup
+	githubRenameTimeout = 15 * time.Second
+)
+
+// githubRedirectPattern matches the hint git prints when GitHub redirects a renamed repository
+var githubRedirectPattern = regexp.MustCompile(`redirecting to https://github\.com/([^/\s]+/[^/\s]+?)(?:\.git)?/?(?:\s|$)`)
+
+// loadRepositoryRenamePolicy reads the rename policy from git config, falling back to migrate
+// for unset or invalid values
+func loadRepositoryRenamePolicy(getConfig func(key string) (string, error)) RepositoryRenamePolicy {
+	value, err := getConfig(repositoryRenamePolicyKey)
+	if err != nil || value == "" {
+		return RenameMigrate
+	}
+
+	switch policy := RepositoryRenamePolicy(value); policy {
+	case RenameMigrate, RenameFlag:
+		return policy
+	default:
+		logger.Warnf("⚠️  Ignoring invalid %s value %q", repositoryRenamePolicyKey, value)
+		return RenameMigrate
+	}
+}
+
+// redirectedRepoID returns the owner/name git was redirected to, or "" without a redirect
+func redirectedRepoID(output string) string {
+	if matches := githubRedirectPattern.FindStringSubmatch(output); matches != nil {
+		return matches[1]
+	}
+	return ""
+}
+
+// renamedPullRequestURL points a pull request URL of oldID at newID, leaving other URLs alone
+func renamedPullRequestURL(prURL, oldID, newID string) string {
+	prefix := "github.com/" + oldID + "/pull/"
+	index := strings.Index(strings.ToLower(prURL), strings.ToLower(prefix))
+	if index < 0 {
+		return prURL
+	}
+	return prURL[:index] + "github.com/" + newID + "/pull/" + prURL[index+len(prefix):]
+}
+
+// renamedGitHubURL points a GitHub repository URL at newID, keeping its scheme and .git suffix
+func renamedGitHubURL(repoURL, oldID, newID string) string {
+	index := strings.Index(strings.ToLower(repoURL), strings.ToLower(oldID))
+	if index < 0 || !strings.Contains(repoURL, "github.com") {
+		return repoURL
+	}
+	return repoURL[:index] + newID + repoURL[index+len(oldID):]
+}
+
+// githubCanonicalRepoID asks GitHub for the current owner/name of a repository. The API
+// follows renames and transfers, so the answer differs from repoID once it moved.
+func githubCanonicalRepoID(repoID string) (string, error) {
+	limits := git.GitHubRateLimits()
+	if !limits.AllowBackground(git.RateLimitCore) {
+		return "", fmt.Errorf("not looking up %s, the GitHub rate limit is low", repoID)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), githubRenameTimeout)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, "gh", "api", "repos/"+repoID, "--jq", ".full_name").Output()
+	if err != nil {
+		limits.ObserveError(git.RateLimitCore, err, nil)
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return "", fmt.Errorf("failed to look up %s: %s", repoID, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", fmt.Errorf("failed to look up %s: %w", repoID, err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// DetectRepositoryRename returns the new owner/name of a GitHub repository that was renamed or
+// transferred, or "" when it still lives under its ID
+func (s *GitService) DetectRepositoryRename(repo *models.Repository) (string, error) {
+	if s.isLocalRepo(repo.ID) || !strings.Contains(repo.URL, "github.com") {
+		return "", nil
+	}
+
+	// git follows GitHub's redirect and says so on stderr
+	ctx, cancel := context.WithTimeout(context.Background(), githubRenameTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "git", "ls-remote", repo.URL, "HEAD")
+	cmd.Env = append(os.Environ(), "HOME="+config.Runtime.HomeDir)
+	output, err := cmd.CombinedOutput()
+
+	newID := redirectedRepoID(string(output))
+	if err != nil && newID == "" {
+		// Private repositories may need the GitHub CLI's credentials
+		if newID, err = githubCanonicalRepoID(repo.ID); err != nil {
+			return "", err
+		}
+	}
+	if newID == "" || strings.EqualFold(newID, repo.ID) {
+		return "", nil
+	}
+	return newID, nil
+}
+
+// checkRepositoryRename looks for a rename after a fetch or push, at most once per
+// renameCheckInterval per repository, and applies the rename policy
+func (s *GitService) checkRepositoryRename(repoID string) {
+	if s.isLocalRepo(repoID) {
+		return
+	}
+	if last, checked := s.renameChecks.Load(repoID); checked && time.Since(last.(time.Time)) < renameCheckInterval {
+		return
+	}
+	s.renameChecks.Store(repoID, time.Now())
+
+	repo, exists := s.stateManager.GetRepository(repoID)
+	if !exists {
+		return
+	}
+	newID, err := s.DetectRepositoryRename(repo)
+	if err != nil {
+		logger.Debugf("⚠️  Rename check for %s failed: %v", repo.ID, err)
+		return
+	}
+	if newID == "" {
+		return
+	}
+
+	policy := loadRepositoryRenamePolicy(func(key string) (string, error) {
+		return s.operations.GetConfig(repo.Path, key)
+	})
+	if policy == RenameFlag {
+		message := fmt.Sprintf("GitHub repository was renamed or transferred to %s; migrate it with POST /v1/git/repositories/{id}/migrate-rename", newID)
+		if err := s.stateManager.SetRepositoryHealthWarning(repo.ID, renameWarningSource, message); err != nil {
+			logger.Warnf("⚠️  Failed to record rename of %s: %v", repo.ID, err)
+		}
+		return
+	}
+	if _, err := s.MigrateRepositoryRename(repo.ID, newID); err != nil {
+		logger.Warnf("⚠️  Failed to migrate %s to %s: %v", repo.ID, newID, err)
+	}
+}
+
+// MigrateRenamedRepository detects whether a repository was renamed or transferred on GitHub
+// and migrates it to its new name
+func (s *GitService) MigrateRenamedRepository(repoID string) (*models.Repository, error) {
+	repo, exists := s.stateManager.GetRepository(repoID)
+	if !exists {
+		return nil, fmt.Errorf("repository %s not found", repoID)
+	}
+	newID, err := s.DetectRepositoryRename(repo)
+	if err != nil {
+		return nil, err
+	}
+	if newID == "" {
+		return nil, fmt.Errorf("repository %s was not renamed on GitHub", repo.ID)
+	}
+	return s.MigrateRepositoryRename(repo.ID, newID)
+}
+
+// MigrateRepositoryRename moves a repository renamed or transferred on GitHub to newID: the
+// origin URL, the bare repository directory, worktree references, pull request associations
+// and state all switch to the new name, and oldID stays an alias
+func (s *GitService) MigrateRepositoryRename(oldID, newID string) (*models.Repository, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.migrateRepositoryRenameLocked(oldID, newID)
+}
+
+// migrateRepositoryRenameLocked implements MigrateRepositoryRename (must be called with s.mu held)
+func (s *GitService) migrateRepositoryRenameLocked(oldID, newID string) (*models.Repository, error) {
+	repo, exists := s.stateManager.GetRepository(oldID)
+	if !exists {
+		return nil, fmt.Errorf("repository %s not found", oldID)
+	}
+	oldID = repo.ID
+	if existing, exists := s.stateManager.GetRepository(newID); exists && existing.ID != oldID {
+		return nil, fmt.Errorf("repository %s is already checked out", newID)
+	}
+
+	newURL := renamedGitHubURL(repo.URL, oldID, newID)
+	if _, err := s.runGitCommand(repo.Path, "remote", "set-url", "origin", newURL); err != nil {
+		return nil, fmt.Errorf("failed to update origin of %s: %v", oldID, err)
+	}
+
+	// The bare repository is named after the repository, worktrees are repaired to follow it
+	newPath := repo.Path
+	oldName := oldID[strings.LastIndex(oldID, "/")+1:]
+	newName := newID[strings.LastIndex(newID, "/")+1:]
+	if filepath.Base(repo.Path) == oldName+".git" && oldName != newName {
+		candidate := filepath.Join(filepath.Dir(repo.Path), newName+".git")
+		if _, err := os.Stat(candidate); err == nil {
+			logger.Warnf("⚠️  Keeping %s in place, %s already exists", repo.Path, candidate)
+		} else if err := os.Rename(repo.Path, candidate); err != nil {
+			logger.Warnf("⚠️  Failed to rename %s to %s: %v", repo.Path, candidate, err)
+		} else {
+			newPath = candidate
+			s.repairWorktrees(oldID, newPath)
+		}
+	}
+
+	if err := s.stateManager.RenameRepository(oldID, newID, func(repo *models.Repository) {
+		repo.URL = newURL
+		repo.Path = newPath
+		repo.RemoteOrigin = renamedGitHubURL(repo.RemoteOrigin, oldID, newID)
+		delete(repo.HealthWarnings, renameWarningSource)
+	}); err != nil {
+		return nil, err
+	}
+	if prSyncManager := GetPRSyncManager(nil); prSyncManager != nil {
+		prSyncManager.RenameRepository(oldID, newID)
+	}
+
+	logger.Infof("🚚 Migrated repository %s to %s", oldID, newID)
+	if s.eventsEmitter != nil {
+		s.eventsEmitter.EmitRepositoryRenamed(oldID, newID)
+	}
+	repo, _ = s.stateManager.GetRepository(newID)
+	return repo, nil
+}
+
+// repairWorktrees points the worktrees of a repository at its moved bare repository
+func (s *GitService) repairWorktrees(repoID, barePath string) {
+	args := []string{"worktree", "repair"}
+	for _, worktree := range s.stateManager.GetAllWorktrees() {
+		if worktree.RepoID == repoID && worktree.Path != "" {
+			args = append(args, worktree.Path)
+		}
+	}
+	if output, err := s.runGitCommand(barePath, args...); err != nil {
+		logger.Warnf("⚠️  Failed to repair worktrees of %s: %v\n%s", repoID, err, output)
+	}
+}
+
+// findRenamedRepository returns a loaded repository that GitHub now serves as repoID, so a
+// checkout of the new name reuses it instead of cloning a duplicate. Only repositories sharing
+// the owner (renames) or the name (transfers) are checked.
+func (s *GitService) findRenamedRepository(repoID string) *models.Repository {
+	owner, name, _ := strings.Cut(repoID, "/")
+	for _, repo := range s.stateManager.GetAllRepositories() {
+		repoOwner, repoName, _ := strings.Cut(repo.ID, "/")
+		if s.isLocalRepo(repo.ID) || (!strings.EqualFold(repoOwner, owner) && !strings.EqualFold(repoName, name)) {
+			continue
+		}
+		newID, err := s.DetectRepositoryRename(repo)
+		if err != nil {
+			logger.Debugf("⚠️  Rename check for %s failed: %v", repo.ID, err)
+			continue
+		}
+		if strings.EqualFold(newID, repoID) {
+			return repo
+		}
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// renameRecorder records repository rename events; other events are not expected
+type renameRecorder struct {
+	EventsEmitter
+	mu      sync.Mutex
+	renames []string
+}
+
+func (r *renameRecorder) EmitRepositoryRenamed(oldID, newID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.renames = append(r.renames, oldID+" -> "+newID)
+}
+
+func TestRedirectedRepoID(t *testing.T) {
+	assert.Equal(t, "acme/tabby", redirectedRepoID("warning: redirecting to https://github.com/acme/tabby.git/\nabc123\tHEAD\n"))
+	assert.Equal(t, "felix-org/tabby", redirectedRepoID("warning: redirecting to https://github.com/felix-org/tabby/"))
+	assert.Empty(t, redirectedRepoID("abc123\tHEAD\n"))
+}
+
+func TestRenamedURLs(t *testing.T) {
+	assert.Equal(t, "https://github.com/acme/tabby/pull/7", renamedPullRequestURL("https://github.com/Acme/Felix/pull/7", "acme/felix", "acme/tabby"))
+	assert.Equal(t, "https://github.com/other/felix/pull/7", renamedPullRequestURL("https://github.com/other/felix/pull/7", "acme/felix", "acme/tabby"))
+	assert.Equal(t, "https://github.com/newco/felix.git", renamedGitHubURL("https://github.com/acme/felix.git", "acme/felix", "newco/felix"))
+	assert.Equal(t, "/tmp/test-repos/felix", renamedGitHubURL("/tmp/test-repos/felix", "acme/felix", "acme/tabby"))
+}
+
+func TestLoadRepositoryRenamePolicy(t *testing.T) {
+	config := func(value string) func(string) (string, error) {
+		return func(string) (string, error) { return value, nil }
+	}
+	assert.Equal(t, RenameMigrate, loadRepositoryRenamePolicy(config("")))
+	assert.Equal(t, RenameFlag, loadRepositoryRenamePolicy(config("flag")))
+	assert.Equal(t, RenameMigrate, loadRepositoryRenamePolicy(config("sometimes")))
+}
+
+func TestMigrateRepositoryRename(t *testing.T) {
+	root := t.TempDir()
+	sourcePath := initTestRepo(t, filepath.Join(root, "source"), map[string]string{"README.md": "# felix\n"})
+
+	barePath := filepath.Join(root, "repos", "felix.git")
+	worktreePath := filepath.Join(root, "workspace", "felix", "tabby")
+	runTestGit(t, root, "clone", "--bare", sourcePath, barePath)
+	runTestGit(t, barePath, "worktree", "add", "-b", "catnip/tabby", worktreePath, "main")
+
+	s := newTestGitService(t, root)
+	stateManager := s.stateManager
+	require.NoError(t, stateManager.AddRepository(&models.Repository{
+		ID: "acme/felix", URL: "https://github.com/acme/felix.git", Path: barePath,
+		HealthWarnings: map[string]string{renameWarningSource: "renamed"},
+	}))
+	require.NoError(t, stateManager.AddWorktree(&models.Worktree{
+		ID: "wt-tabby", RepoID: "acme/felix", Name: "felix/tabby", Path: worktreePath, Branch: "catnip/tabby",
+		PullRequestURL: "https://github.com/acme/felix/pull/7",
+	}))
+	prSyncManager := GetPRSyncManager(nil)
+	prSyncManager.mutex.Lock()
+	prSyncManager.prStateCache["acme/felix#7"] = &models.PullRequestState{Number: 7, State: "OPEN", Repository: "acme/felix", URL: "https://github.com/acme/felix/pull/7"}
+	prSyncManager.mutex.Unlock()
+
+	recorder := &renameRecorder{}
+	s.eventsEmitter = recorder
+	repo, err := s.MigrateRepositoryRename("acme/felix", "newco/tabby")
+	require.NoError(t, err)
+
+	newBarePath := filepath.Join(root, "repos", "tabby.git")
+	assert.Equal(t, "newco/tabby", repo.ID)
+	assert.Equal(t, []string{"acme/felix"}, repo.Aliases)
+	assert.Equal(t, "https://github.com/newco/tabby.git", repo.URL)
+	assert.Equal(t, newBarePath, repo.Path)
+	assert.Empty(t, repo.HealthWarnings)
+	assert.NoDirExists(t, barePath)
+	assert.Equal(t, "https://github.com/newco/tabby.git", runTestGit(t, newBarePath, "remote", "get-url", "origin"))
+	assert.Equal(t, "catnip/tabby", runTestGit(t, worktreePath, "rev-parse", "--abbrev-ref", "HEAD"), "the worktree follows the moved bare repository")
+
+	// The old ID keeps resolving
+	aliased, exists := stateManager.GetRepository("acme/felix")
+	require.True(t, exists)
+	assert.Equal(t, "newco/tabby", aliased.ID)
+
+	worktree, _ := stateManager.GetWorktree("wt-tabby")
+	assert.Equal(t, "newco/tabby", worktree.RepoID)
+	assert.Equal(t, "https://github.com/newco/tabby/pull/7", worktree.PullRequestURL)
+	assert.Nil(t, prSyncManager.GetPRState("acme/felix", 7))
+	require.NotNil(t, cachedPRState(worktree.PullRequestURL))
+	assert.Equal(t, "newco/tabby", cachedPRState(worktree.PullRequestURL).Repository)
+
+	assert.Equal(t, []string{"acme/felix -> newco/tabby"}, recorder.renames)
+
+	_, err = s.MigrateRepositoryRename("acme/missing", "newco/missing")
+	assert.Error(t, err)
+}
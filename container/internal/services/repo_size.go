@@ -0,0 +1,190 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/git"
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// Git config key holding the largest estimated full repository size, in MiB, that catnip
+// unshallows automatically after a shallow clone. Can be set per repository or globally.
+const unshallowMaxSizeKey = "catnip.clone.unshallow-max-size-mb"
+
+const (
+	// defaultUnshallowMaxSizeMB keeps repositories up to 1 GiB fully fetched in the background
+	defaultUnshallowMaxSizeMB = 1024
+	// repoSizeRefreshInterval is how often the on-disk size of every repository is measured
+	repoSizeRefreshInterval = 30 * time.Minute
+	// repoSizeInitialDelay postpones the first measurement until startup work has settled
+	repoSizeInitialDelay = time.Minute
+	// githubSizeTimeout bounds the GitHub API lookup of a repository's size
+	githubSizeTimeout = 15 * time.Second
+)
+
+// loadUnshallowMaxSizeKB reads the automatic unshallow threshold from git config, keeping
+// the default for unset or invalid values. Zero disables automatic unshallowing.
+func loadUnshallowMaxSizeKB(getConfig func(key string) (string, error)) int64 {
+	if value, err := getConfig(unshallowMaxSizeKey); err == nil && value != "" {
+		if mb, err := strconv.ParseInt(value, 10, 64); err == nil && mb >= 0 {
+			return mb << 10
+		}
+		logger.Warnf("⚠️  Ignoring invalid %s value %q", unshallowMaxSizeKey, value)
+	}
+	return defaultUnshallowMaxSizeMB << 10
+}
+
+// shouldUnshallow decides whether a shallow clone may fetch its full history. Repositories
+// of unknown size are unshallowed, matching the behavior before sizes were tracked.
+func shouldUnshallow(size *models.RepositorySize, maxSizeKB int64) bool {
+	if maxSizeKB == 0 {
+		return false
+	}
+	if size == nil || size.RemoteKB == 0 {
+		return true
+	}
+	return size.RemoteKB <= maxSizeKB
+}
+
+// githubDiskUsageKB asks GitHub for the full size of a repository in KiB
+func githubDiskUsageKB(repoID string) (int64, error) {
+	limits := git.GitHubRateLimits()
+	if !limits.AllowBackground(git.RateLimitGraphQL) {
+		return 0, fmt.Errorf("not querying size of %s, the GitHub rate limit is low", repoID)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), githubSizeTimeout)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, "gh", "repo", "view", repoID, "--json", "diskUsage").Output()
+	if err != nil {
+		limits.ObserveError(git.RateLimitGraphQL, err, nil)
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return 0, fmt.Errorf("failed to query size of %s: %s", repoID, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return 0, fmt.Errorf("failed to query size of %s: %w", repoID, err)
+	}
+
+	var result struct {
+		DiskUsage int64 `json:"diskUsage"`
+	}
+	if err := json.Unmarshal(output, &result); err != nil {
+		return 0, fmt.Errorf("failed to parse size of %s: %w", repoID, err)
+	}
+	return result.DiskUsage, nil
+}
+
+// measureRepositorySize counts the objects of a repository and sums the size of its objects
+// directory, which unlike count-objects includes indexes, commit graphs and temporary packs
+func (s *GitService) measureRepositorySize(repoPath string) (*models.RepositorySize, error) {
+	output, err := s.operations.ExecuteGit(repoPath, "count-objects", "-v")
+	if err != nil {
+		return nil, fmt.Errorf("failed to count objects: %v", err)
+	}
+
+	size := &models.RepositorySize{MeasuredAt: time.Now()}
+	for _, line := range strings.Split(string(output), "\n") {
+		key, value, found := strings.Cut(line, ": ")
+		if !found {
+			continue
+		}
+		n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err != nil {
+			continue
+		}
+		switch key {
+		case "count", "in-pack":
+			size.ObjectCount += n
+		case "packs":
+			size.PackCount = n
+		}
+	}
+
+	output, err = s.operations.ExecuteGit(repoPath, "rev-parse", "--git-path", "objects")
+	if err != nil {
+		return nil, fmt.Errorf("failed to locate objects directory: %v", err)
+	}
+	objectsDir := strings.TrimSpace(string(output))
+	if !filepath.IsAbs(objectsDir) {
+		objectsDir = filepath.Join(repoPath, objectsDir)
+	}
+	var bytes int64
+	_ = filepath.WalkDir(objectsDir, func(_ string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return nil
+		}
+		if info, err := entry.Info(); err == nil {
+			bytes += info.Size()
+		}
+		return nil
+	})
+	size.LocalKB = bytes >> 10
+
+	if output, err := s.operations.ExecuteGit(repoPath, "rev-parse", "--is-shallow-repository"); err == nil {
+		size.Shallow = strings.TrimSpace(string(output)) == "true"
+	}
+	return size, nil
+}
+
+// RefreshRepositorySize measures a repository on disk and stores the result, keeping the
+// last size reported by GitHub
+func (s *GitService) RefreshRepositorySize(repoID string) (*models.RepositorySize, error) {
+	repo, exists := s.stateManager.GetRepository(repoID)
+	if !exists {
+		return nil, fmt.Errorf("repository %s not found", repoID)
+	}
+
+	size, err := s.measureRepositorySize(repo.Path)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.stateManager.UpdateRepository(repoID, func(repo *models.Repository) {
+		if repo.Size != nil {
+			size.RemoteKB = repo.Size.RemoteKB
+		}
+		repo.Size = size
+	}); err != nil {
+		return nil, err
+	}
+	return size, nil
+}
+
+// refreshAllRepositorySizes measures every available repository
+func (s *GitService) refreshAllRepositorySizes() {
+	for _, repo := range s.stateManager.GetAllRepositories() {
+		if !repo.Available {
+			continue
+		}
+		if _, err := s.RefreshRepositorySize(repo.ID); err != nil {
+			logger.Debugf("⚠️  Failed to measure size of %s: %v", repo.ID, err)
+		}
+	}
+}
+
+// startRepositorySizeRefresher measures the on-disk size of all repositories shortly after
+// startup and periodically afterwards
+func (s *GitService) startRepositorySizeRefresher() {
+	initial := time.NewTimer(repoSizeInitialDelay)
+	defer initial.Stop()
+	ticker := time.NewTicker(repoSizeRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-initial.C:
+			s.refreshAllRepositorySizes()
+		case <-ticker.C:
+			s.refreshAllRepositorySizes()
+		case <-s.stopCh:
+			return
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package services
+
+import (
+	"errors"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+func TestUnshallowThreshold(t *testing.T) {
+	config := func(value string, err error) func(string) (string, error) {
+		return func(string) (string, error) { return value, err }
+	}
+	assert.Equal(t, int64(1<<20), loadUnshallowMaxSizeKB(config("", errors.New("unset"))))
+	assert.Equal(t, int64(512<<10), loadUnshallowMaxSizeKB(config("512", nil)))
+	assert.Equal(t, int64(0), loadUnshallowMaxSizeKB(config("0", nil)))
+	assert.Equal(t, int64(1<<20), loadUnshallowMaxSizeKB(config("huge", nil)))
+
+	assert.True(t, shouldUnshallow(nil, 1<<20), "unknown sizes keep unshallowing")
+	assert.True(t, shouldUnshallow(&models.RepositorySize{LocalKB: 4 << 20}, 1<<20))
+	assert.True(t, shouldUnshallow(&models.RepositorySize{RemoteKB: 1 << 20}, 1<<20))
+	assert.False(t, shouldUnshallow(&models.RepositorySize{RemoteKB: 1<<20 + 1}, 1<<20))
+	assert.False(t, shouldUnshallow(&models.RepositorySize{RemoteKB: 1}, 0), "zero disables unshallowing")
+}
+
+func TestRefreshRepositorySize(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	root := t.TempDir()
+	repoPath := initTestRepo(t, filepath.Join(root, "repo"), nil)
+
+	s := newTestGitService(t, root)
+	stateManager := s.stateManager
+	require.NoError(t, stateManager.AddRepository(&models.Repository{
+		ID: "local/repo", Path: repoPath, Size: &models.RepositorySize{RemoteKB: 2048},
+	}))
+
+	size, err := s.RefreshRepositorySize("local/repo")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), size.ObjectCount, "one commit and its empty tree")
+	assert.Zero(t, size.PackCount)
+	assert.False(t, size.Shallow)
+	assert.Equal(t, int64(2048), size.RemoteKB, "the GitHub size is kept")
+
+	runTestGit(t, repoPath, "gc", "--quiet")
+	size, err = s.RefreshRepositorySize("local/repo")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), size.ObjectCount)
+	assert.Equal(t, int64(1), size.PackCount)
+
+	repo, _ := stateManager.GetRepository("local/repo")
+	assert.Equal(t, size, repo.Size)
+
+	_, err = s.RefreshRepositorySize("local/missing")
+	assert.Error(t, err)
+}
@@ -43,6 +43,7 @@ type ActiveSessionInfo struct {
 	StartedAt         time.Time           `json:"started_at"`
 	ResumedAt         *time.Time          `json:"resumed_at,omitempty"`
 	EndedAt           *time.Time          `json:"ended_at,omitempty"`
+	StartedBy         string              `json:"started_by,omitempty"`
 }
 
 // NewSessionService creates a new session service
@@ -141,13 +142,9 @@ func (s *SessionService) LoadSessionState(sessionID string) (*SessionState, erro
 func (s *SessionService) FindSessionByDirectory(workDir string) (*SessionState, error) {
 	// First, try to find the newest session file directly from .claude/projects
 	// Claude stores projects in ~/.claude/projects/{transformed-path}/
-	// where the path is transformed: /workspace/catnip/buddy -> -workspace-catnip-buddy
+	// where the path is transformed: /workspace/catnip/buddy -> -workspace-catnip-buddy (see WorktreePathToProjectDir)
 	homeDir := config.Runtime.HomeDir
-	transformedPath := strings.ReplaceAll(workDir, "/", "-")
-	transformedPath = strings.TrimPrefix(transformedPath, "-")
-	transformedPath = "-" + transformedPath // Add back the leading dash
-
-	claudeProjectsDir := filepath.Join(homeDir, ".claude", "projects", transformedPath)
+	claudeProjectsDir := filepath.Join(homeDir, ".claude", "projects", WorktreePathToProjectDir(workDir))
 	if newestSessionID := s.findNewestClaudeSessionFile(claudeProjectsDir); newestSessionID != "" {
 		// Create a minimal state for the newest session found
 		return &SessionState{
@@ -176,7 +173,7 @@ func (s *SessionService) FindSessionByDirectory(workDir string) (*SessionState,
 			}
 
 			if state != nil &&
-				state.WorkingDirectory == workDir &&
+				config.SamePath(state.WorkingDirectory, workDir) &&
 				state.Agent == "claude" &&
 				state.ClaudeSessionID != "" {
 
@@ -253,11 +250,7 @@ func (s *SessionService) getLastClaudeActivityTime(workDir string) time.Time {
 
 	// Fallback to file modification time method
 	homeDir := config.Runtime.HomeDir
-	transformedPath := strings.ReplaceAll(workDir, "/", "-")
-	transformedPath = strings.TrimPrefix(transformedPath, "-")
-	transformedPath = "-" + transformedPath // Add back the leading dash
-
-	claudeProjectsDir := filepath.Join(homeDir, ".claude", "projects", transformedPath)
+	claudeProjectsDir := filepath.Join(homeDir, ".claude", "projects", WorktreePathToProjectDir(workDir))
 
 	// Check if .claude/projects directory exists
 	if _, err := os.Stat(claudeProjectsDir); os.IsNotExist(err) {
@@ -337,7 +330,7 @@ func (s *SessionService) hasPTYSession(workDir string) bool {
 				continue
 			}
 
-			if actualWorkDir == workDir {
+			if config.SamePath(actualWorkDir, workDir) {
 				// Claude process found and tracked
 				return true
 			}
@@ -565,6 +558,23 @@ func (s *SessionService) StartOrResumeActiveSession(workspaceDir, claudeSessionU
 	return newSession, err
 }
 
+// SetActiveSessionActor records who started the active session for a workspace
+// The first actor wins so resumed sessions keep their original attribution
+func (s *SessionService) SetActiveSessionActor(workspaceDir, actor string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, exists := s.activeSessions[workspaceDir]
+	if !exists {
+		return fmt.Errorf("no active session found for workspace: %s", workspaceDir)
+	}
+	if session.StartedBy != "" && session.StartedBy != DefaultActor {
+		return nil
+	}
+	session.StartedBy = NormalizeActor(actor)
+	return s.saveActiveSessionsState()
+}
+
 // ResumeActiveSession marks an existing session as resumed
 func (s *SessionService) ResumeActiveSession(workspaceDir string) error {
 	s.mu.Lock()
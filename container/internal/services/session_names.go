@@ -0,0 +1,69 @@
+package services
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/git"
+)
+
+// sessionNameReservationTTL bounds how long a generated name stays reserved when the
+// worktree creation that requested it never reports back
+const sessionNameReservationTTL = 10 * time.Minute
+
+// sessionNameReservations holds the fun names handed out for worktrees that are still being
+// created, so concurrent checkouts never pick the same name
+type sessionNameReservations struct {
+	mu    sync.Mutex
+	names map[string]time.Time // workspace name -> reservation expiry
+}
+
+// generateUniqueSessionName picks a name that is neither reserved, nor a branch of the
+// repository, nor the name of an existing worktree in any repository, and reserves it until
+// releaseSessionName is called or the reservation expires
+func (s *GitService) generateUniqueSessionName(repoPath string) string {
+	taken := make(map[string]bool)
+	for _, worktree := range s.stateManager.GetAllWorktrees() {
+		taken[workspaceNameOf(worktree.Name)] = true
+	}
+
+	r := &s.sessionNames
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for name, expiry := range r.names {
+		if now.After(expiry) {
+			delete(r.names, name)
+		}
+	}
+
+	name := git.GenerateUniqueSessionName(func(name string) bool {
+		workspaceName := git.ExtractWorkspaceName(name)
+		if _, reserved := r.names[workspaceName]; reserved || taken[workspaceName] {
+			return true
+		}
+		return s.branchExists(repoPath, name, false)
+	})
+
+	if r.names == nil {
+		r.names = make(map[string]time.Time)
+	}
+	r.names[git.ExtractWorkspaceName(name)] = now.Add(sessionNameReservationTTL)
+	return name
+}
+
+// releaseSessionName drops the reservation of a name once its worktree was created or
+// creation failed. Names that were never reserved are ignored.
+func (s *GitService) releaseSessionName(name string) {
+	r := &s.sessionNames
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.names, git.ExtractWorkspaceName(name))
+}
+
+// workspaceNameOf returns the workspace part of a worktree name like "repo/felix"
+func workspaceNameOf(worktreeName string) string {
+	return worktreeName[strings.LastIndex(worktreeName, "/")+1:]
+}
@@ -0,0 +1,72 @@
+package services
+
+import (
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/git"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+func TestSessionNameReservations(t *testing.T) {
+	root := t.TempDir()
+	s := newTestGitService(t, root)
+
+	first := s.generateUniqueSessionName(root)
+	second := s.generateUniqueSessionName(root)
+	assert.NotEqual(t, first, second, "a reserved name is not handed out again")
+	assert.Contains(t, s.sessionNames.names, git.ExtractWorkspaceName(first))
+
+	s.releaseSessionName(first)
+	s.releaseSessionName(second)
+	assert.Empty(t, s.sessionNames.names)
+
+	assert.Equal(t, "felix", workspaceNameOf("repo/felix"))
+	assert.Equal(t, "felix", workspaceNameOf("felix"))
+}
+
+func TestConcurrentWorktreeCreationPicksDistinctNames(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	root := t.TempDir()
+	t.Setenv("CATNIP_WORKSPACE_DIR", filepath.Join(root, "workspace"))
+	repoPath := initTestRepo(t, filepath.Join(root, "repo"), nil)
+
+	s := newTestGitService(t, root)
+	stateManager := s.stateManager
+	s.worktreeCache = NewWorktreeStatusCache(s.operations, stateManager)
+	t.Cleanup(s.worktreeCache.Stop)
+	repo := &models.Repository{ID: "owner/repo", Path: repoPath, DefaultBranch: "main", Available: true}
+	require.NoError(t, stateManager.AddRepository(repo))
+
+	const count = 20
+	var wg sync.WaitGroup
+	names := make([]string, count)
+	worktrees := make([]*models.Worktree, count)
+	errs := make([]error, count)
+	for i := 0; i < count; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			names[i] = s.generateUniqueSessionName(repoPath)
+			worktrees[i], errs[i] = s.createWorktreeInternalForRepo(repo, "main", names[i], false, nil)
+		}(i)
+	}
+	wg.Wait()
+
+	seen := make(map[string]bool)
+	for i := 0; i < count; i++ {
+		require.NoError(t, errs[i])
+		assert.Equal(t, names[i], worktrees[i].Branch, "worktree kept its reserved name without retrying")
+		assert.False(t, seen[worktrees[i].Name], "duplicate worktree name %s", worktrees[i].Name)
+		seen[worktrees[i].Name] = true
+	}
+	assert.Len(t, stateManager.GetAllWorktrees(), count)
+	assert.Empty(t, s.sessionNames.names, "reservations are released after creation")
+}
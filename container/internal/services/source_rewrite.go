@@ -0,0 +1,118 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// sourceRewriteDeepen is how many commits of history are fetched behind a shallow source tip to
+// tell a fast-forward from a force push
+const sourceRewriteDeepen = 100
+
+// checkSourceRewrite compares the tip of a worktree's remote source branch after a fetch with the
+// tip seen by the previous fetch. A previous tip that isn't an ancestor of the new one means the
+// branch was force-pushed: the worktree is flagged and an event emitted, since commits behind and
+// syncs against the rewritten branch no longer make sense.
+func (s *GitService) checkSourceRewrite(worktreeID string) {
+	worktree, exists := s.stateManager.GetWorktree(worktreeID)
+	if !exists || worktree.SourceBranch == "" {
+		return
+	}
+	ref := "origin/" + worktree.SourceBranch
+	tip, err := s.operations.GetCommitHash(worktree.Path, ref)
+	if err != nil || tip == "" || tip == worktree.SourceTip {
+		return
+	}
+
+	updates := map[string]interface{}{"source_tip": tip}
+	previous := worktree.SourceTip
+	if previous != "" && s.sourceRewritten(worktree, previous, tip) {
+		rewrite := &models.SourceRewrite{
+			Branch:      worktree.SourceBranch,
+			PreviousTip: previous,
+			NewTip:      tip,
+			DetectedAt:  time.Now(),
+		}
+		// Repeated force pushes keep the tip the worktree was actually built on
+		if flagged := worktree.SourceBranchRewritten; flagged != nil {
+			rewrite.PreviousTip = flagged.PreviousTip
+		}
+		updates["source_branch_rewritten"] = rewrite
+		logger.Warnf("⚠️  Source branch %s of %s was force-pushed: %.8s is not an ancestor of %.8s", ref, worktree.Name, previous, tip)
+		defer func() {
+			if s.eventsEmitter != nil {
+				s.eventsEmitter.EmitWorktreeSourceRewritten(worktree.ID, rewrite)
+			}
+		}()
+	}
+	if err := s.stateManager.UpdateWorktree(worktree.ID, updates); err != nil {
+		logger.Warnf("⚠️  Failed to record source tip of %s: %v", worktree.Name, err)
+	}
+}
+
+// sourceRewritten reports whether previous is known not to be an ancestor of tip. Shallow fetches
+// cut the new tip's history off, so it is deepened before concluding; when the ancestry still
+// can't be established, or the previous tip is gone, the update is assumed to be a fast-forward.
+func (s *GitService) sourceRewritten(worktree *models.Worktree, previous, tip string) bool {
+	if _, err := s.operations.ExecuteGit(worktree.Path, "cat-file", "-e", previous+"^{commit}"); err != nil {
+		return false
+	}
+	if s.isAncestor(worktree.Path, previous, tip) {
+		return false
+	}
+	if !s.isShallowRepository(worktree.Path) {
+		return true
+	}
+
+	refSpec := fmt.Sprintf("+refs/heads/%s:refs/remotes/origin/%s", worktree.SourceBranch, worktree.SourceBranch)
+	if _, err := s.operations.ExecuteGit(worktree.Path, "fetch", "origin", refSpec, fmt.Sprintf("--deepen=%d", sourceRewriteDeepen), "--no-tags", "--quiet"); err != nil {
+		logger.Debugf("🔍 Could not deepen %s to check for a force push: %v", worktree.SourceBranch, err)
+		return false
+	}
+	if s.isAncestor(worktree.Path, previous, tip) {
+		return false
+	}
+	// A common ancestor proves the histories diverged; without one the history may just be cut off
+	if _, err := s.operations.ExecuteGit(worktree.Path, "merge-base", previous, tip); err == nil {
+		return true
+	}
+	return !s.isShallowRepository(worktree.Path)
+}
+
+// isAncestor reports whether commit is an ancestor of (or equal to) of
+func (s *GitService) isAncestor(repoPath, commit, of string) bool {
+	_, err := s.operations.ExecuteGit(repoPath, "merge-base", "--is-ancestor", commit, of)
+	return err == nil
+}
+
+// isShallowRepository reports whether the repository's history was cut off by shallow fetches
+func (s *GitService) isShallowRepository(repoPath string) bool {
+	output, err := s.operations.ExecuteGit(repoPath, "rev-parse", "--is-shallow-repository")
+	return err == nil && strings.TrimSpace(string(output)) == "true"
+}
+
+// rebaseOntoRewrittenSource replays only the worktree's own commits, those after the source tip it
+// was built on, onto the rewritten source branch. Without the old tip it falls back to a plain
+// rebase. The rewrite flag is cleared once the rebase succeeds.
+func (s *GitService) rebaseOntoRewrittenSource(worktree *models.Worktree, sourceRef string) error {
+	rewrite := worktree.SourceBranchRewritten
+	args := []string{"rebase", sourceRef}
+	if _, err := s.operations.ExecuteGit(worktree.Path, "cat-file", "-e", rewrite.PreviousTip+"^{commit}"); err == nil {
+		args = []string{"rebase", "--onto", sourceRef, rewrite.PreviousTip}
+	}
+
+	if _, err := s.operations.ExecuteGit(worktree.Path, args...); err != nil {
+		return s.syncError(worktree, "rebase", err)
+	}
+	if err := s.stateManager.UpdateWorktree(worktree.ID, map[string]interface{}{
+		"source_branch_rewritten": (*models.SourceRewrite)(nil),
+	}); err != nil {
+		return err
+	}
+	logger.Infof("🔁 Rebased %s onto rewritten %s", worktree.Name, sourceRef)
+	return nil
+}
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// rewriteRecorder records source rewrite events; other events are not expected
+type rewriteRecorder struct {
+	EventsEmitter
+	rewrites []models.SourceRewrite
+}
+
+func (r *rewriteRecorder) EmitWorktreeSourceRewritten(worktreeID string, rewrite *models.SourceRewrite) {
+	r.rewrites = append(r.rewrites, *rewrite)
+}
+
+func TestSourceBranchForcePush(t *testing.T) {
+	s, stateManager, repoPath, worktreePath := newRecreateTestService(t)
+	recorder := &rewriteRecorder{}
+	s.eventsEmitter = recorder
+
+	root := filepath.Dir(repoPath)
+	upstream := filepath.Join(root, "upstream.git")
+	runTestGit(t, root, "init", "--bare", "-b", "main", upstream)
+	runTestGit(t, repoPath, "remote", "add", "origin", upstream)
+	runTestGit(t, repoPath, "push", "origin", "main")
+	pusher := filepath.Join(root, "pusher")
+	runTestGit(t, root, "clone", upstream, pusher)
+	runTestGit(t, pusher, "config", "user.email", "test@catnip.local")
+	runTestGit(t, pusher, "config", "user.name", "Catnip Test")
+	push := func(file, message string, args ...string) string {
+		require.NoError(t, os.WriteFile(filepath.Join(pusher, file), []byte(message+"\n"), 0644))
+		runTestGit(t, pusher, "add", file)
+		runTestGit(t, pusher, "commit", "-m", message)
+		runTestGit(t, pusher, append([]string{"push", "origin", "main"}, args...)...)
+		return runTestGit(t, pusher, "rev-parse", "HEAD")
+	}
+
+	require.NoError(t, stateManager.AddRepository(&models.Repository{ID: "owner/repo", Path: repoPath}))
+	require.NoError(t, stateManager.AddWorktree(&models.Worktree{
+		ID: "wt-remote", RepoID: "owner/repo", Name: "repo/remote", Path: worktreePath, Branch: "feature/felix", SourceBranch: "main",
+	}))
+	fetch := func() *models.Worktree {
+		worktree, _ := stateManager.GetWorktree("wt-remote")
+		s.fetchLatestReference(worktree)
+		worktree, _ = stateManager.GetWorktree("wt-remote")
+		return worktree
+	}
+
+	first := push("two.txt", "two")
+	assert.Equal(t, first, fetch().SourceTip)
+	second := push("three.txt", "three")
+
+	t.Run("FastForwardThroughShallowFetch", func(t *testing.T) {
+		worktree := fetch()
+		assert.Equal(t, second, worktree.SourceTip)
+		assert.Nil(t, worktree.SourceBranchRewritten)
+		assert.Empty(t, recorder.rewrites)
+	})
+
+	require.NoError(t, os.WriteFile(filepath.Join(worktreePath, "felix.txt"), []byte("felix\n"), 0644))
+	runTestGit(t, worktreePath, "add", "felix.txt")
+	runTestGit(t, worktreePath, "commit", "-m", "felix work")
+	_, err := s.SyncWorktree("wt-remote", SyncOptions{Strategy: "rebase"})
+	require.NoError(t, err)
+
+	runTestGit(t, pusher, "reset", "--hard", "HEAD~1")
+	rewritten := push("three.txt", "three, rewritten", "--force")
+
+	t.Run("FlagsForcePush", func(t *testing.T) {
+		worktree := fetch()
+		assert.Equal(t, rewritten, worktree.SourceTip)
+		require.NotNil(t, worktree.SourceBranchRewritten)
+		assert.Equal(t, second, worktree.SourceBranchRewritten.PreviousTip)
+		assert.Equal(t, rewritten, worktree.SourceBranchRewritten.NewTip)
+		require.Len(t, recorder.rewrites, 1)
+		assert.Equal(t, "main", recorder.rewrites[0].Branch)
+	})
+
+	t.Run("SyncNeedsAcknowledgement", func(t *testing.T) {
+		_, err := s.SyncWorktree("wt-remote", SyncOptions{Strategy: "merge"})
+		var rewrittenErr *models.SourceRewrittenError
+		require.True(t, errors.As(err, &rewrittenErr), "got %v", err)
+		assert.Equal(t, second, rewrittenErr.Rewrite.PreviousTip)
+	})
+
+	t.Run("AcknowledgedSyncRebasesOntoNewTip", func(t *testing.T) {
+		backup, err := s.SyncWorktree("wt-remote", SyncOptions{Strategy: "merge", AcknowledgeRewrite: true})
+		require.NoError(t, err)
+		assert.NotNil(t, backup)
+		assert.Equal(t, "felix work\nthree, rewritten\ntwo\nInitial commit", runTestGit(t, worktreePath, "log", "--format=%s"))
+
+		worktree, _ := stateManager.GetWorktree("wt-remote")
+		assert.Nil(t, worktree.SourceBranchRewritten)
+	})
+}
@@ -0,0 +1,198 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/git"
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// catnipBranchPrefix is the namespace of the branches catnip creates outside refs/catnip/:
+// preview branches in local repositories and catnip/<workspace> branches pushed to origin
+const catnipBranchPrefix = "catnip/"
+
+// getStaleBranchRetention returns how long catnip branches whose worktree is gone are kept while
+// their tip isn't merged, configurable via CATNIP_STALE_BRANCH_DAYS
+func getStaleBranchRetention() time.Duration {
+	if envDays := os.Getenv("CATNIP_STALE_BRANCH_DAYS"); envDays != "" {
+		if days, err := strconv.Atoi(envDays); err == nil && days > 0 {
+			return time.Duration(days) * 24 * time.Hour
+		}
+	}
+	return 30 * 24 * time.Hour // Default: 30 days
+}
+
+// cleanupBaseRef returns the branch cleanups compare catnip branches of repo against: main, or
+// master when there is no main. Empty when neither exists.
+func (s *GitService) cleanupBaseRef(repo *models.Repository) string {
+	for _, ref := range []string{"main", "master"} {
+		if err := s.operations.ShowRef(repo.Path, "refs/heads/"+ref, git.ShowRefOptions{Verify: true, Quiet: true}); err == nil {
+			return ref
+		}
+	}
+	return ""
+}
+
+// workspaceGone reports whether the workspace of a catnip/<workspace> branch is no longer in
+// persisted state. Its refs/catnip/<workspace> ref doesn't count, the refs cleanup deletes it.
+func workspaceGone(branch string, preserved map[string]bool) bool {
+	return !preserved[strings.TrimPrefix(branch, catnipBranchPrefix)]
+}
+
+// staleBranchTip reports whether commit, the tip of a branch whose workspace is gone, is merged
+// into baseRef or was committed longer than the retention ago. Commits missing from repo are
+// never stale, nothing is known about them.
+func (s *GitService) staleBranchTip(repo *models.Repository, baseRef, commit string) bool {
+	output, err := s.operations.ExecuteGit(repo.Path, "show", "-s", "--format=%ct", commit)
+	if err != nil {
+		return false
+	}
+	if committed, err := strconv.ParseInt(strings.TrimSpace(string(output)), 10, 64); err == nil &&
+		time.Since(time.Unix(committed, 0)) > getStaleBranchRetention() {
+		return true
+	}
+	_, err = s.operations.ExecuteGit(repo.Path, "merge-base", "--is-ancestor", commit, baseRef)
+	return err == nil
+}
+
+// cleanupPreviewBranches removes the catnip/<workspace> preview branches of a local repository
+// whose workspace is gone and whose tip is merged or past the retention, adding them to
+// report.PreviewBranches. Branches tracking an upstream are not previews and are left alone.
+// Nothing is deleted when report.DryRun is set.
+func (s *GitService) cleanupPreviewBranches(repo *models.Repository, report *CleanupReport) error {
+	baseRef := s.cleanupBaseRef(repo)
+	if baseRef == "" {
+		return nil
+	}
+	output, err := s.operations.ExecuteGit(repo.Path, "for-each-ref",
+		"--format=%(refname)%09%(objectname)%09%(upstream)", "refs/heads/"+catnipBranchPrefix)
+	if err != nil {
+		return fmt.Errorf("failed to list preview branches: %v", err)
+	}
+	checkedOut, err := s.checkedOutBranches(repo)
+	if err != nil {
+		return err
+	}
+	preserved := s.preservedCatnipRefs()
+	deleted := 0
+
+	// Only newlines are trimmed, TrimSpace would eat the empty upstream of the last branch
+	for _, line := range strings.Split(strings.TrimRight(string(output), "\n"), "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) < 3 || fields[2] != "" {
+			continue
+		}
+		ref, commit := fields[0], fields[1]
+		branch := strings.TrimPrefix(ref, "refs/heads/")
+		if !workspaceGone(branch, preserved) || !s.staleBranchTip(repo, baseRef, commit) {
+			continue
+		}
+
+		if checkedOut[ref] {
+			report.CheckedOut = append(report.CheckedOut, CleanupItem{RepoID: repo.ID, Name: branch})
+			continue
+		}
+		if repo.Protected {
+			report.Pinned = append(report.Pinned, CleanupItem{RepoID: repo.ID, Name: branch})
+			continue
+		}
+		if report.DryRun {
+			report.PreviewBranches = append(report.PreviewBranches, CleanupItem{RepoID: repo.ID, Name: branch})
+			continue
+		}
+
+		if err := s.operations.DeleteBranch(repo.Path, branch, true); err != nil {
+			report.addError(repo.ID, branch, err)
+			continue
+		}
+		report.PreviewBranches = append(report.PreviewBranches, CleanupItem{RepoID: repo.ID, Name: branch})
+		deleted++
+		logger.Debugf("🗑️  Deleted stale preview branch: %s in %s", branch, repo.ID)
+	}
+
+	if deleted > 0 {
+		logger.Infof("✅ Cleaned up %d stale preview branches in %s", deleted, repo.ID)
+	}
+	return nil
+}
+
+// cleanupRemoteBranches removes the catnip/<workspace> branches on the origin of repo whose
+// workspace is gone and whose tip is merged or past the retention, adding them to
+// report.RemoteBranches as origin/<branch>. They are deleted with a single push; branches the
+// remote refuses to delete, e.g. without push rights, are listed as errors. Nothing is deleted
+// when report.DryRun is set.
+func (s *GitService) cleanupRemoteBranches(repo *models.Repository, report *CleanupReport) error {
+	if _, err := s.operations.GetRemoteURL(repo.Path); err != nil {
+		return nil // No origin to clean up
+	}
+	baseRef := s.cleanupBaseRef(repo)
+	if baseRef == "" {
+		return nil
+	}
+	output, err := s.operations.ExecuteGit(repo.Path, "ls-remote", "--heads", "origin", "refs/heads/"+catnipBranchPrefix+"*")
+	if err != nil {
+		return fmt.Errorf("failed to list remote catnip branches: %v", err)
+	}
+	preserved := s.preservedCatnipRefs()
+
+	var refspecs []string
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) < 2 {
+			continue
+		}
+		commit, ref := fields[0], fields[1]
+		branch := strings.TrimPrefix(ref, "refs/heads/")
+		if !workspaceGone(branch, preserved) || !s.staleBranchTip(repo, baseRef, commit) {
+			continue
+		}
+
+		item := CleanupItem{RepoID: repo.ID, Name: "origin/" + branch}
+		if repo.Protected {
+			report.Pinned = append(report.Pinned, item)
+			continue
+		}
+		if report.DryRun {
+			report.RemoteBranches = append(report.RemoteBranches, item)
+			continue
+		}
+		refspecs = append(refspecs, ":"+ref)
+	}
+	if len(refspecs) == 0 {
+		return nil
+	}
+
+	// One push deletes them all, the round trips to the remote are the slow part
+	output, pushErr := s.operations.ExecuteGit(repo.Path, append([]string{"push", "--porcelain", "origin"}, refspecs...)...)
+	deleted := make(map[string]bool)
+	for _, line := range strings.Split(string(output), "\n") {
+		if fields := strings.Split(line, "\t"); len(fields) >= 2 && fields[0] == "-" {
+			deleted[fields[1]] = true
+		}
+	}
+	for _, refspec := range refspecs {
+		branch := strings.TrimPrefix(refspec, ":refs/heads/")
+		item := CleanupItem{RepoID: repo.ID, Name: "origin/" + branch}
+		if !deleted[refspec] {
+			err := pushErr
+			if err == nil {
+				err = fmt.Errorf("remote refused to delete %s", branch)
+			}
+			report.addError(repo.ID, item.Name, err)
+			continue
+		}
+		report.RemoteBranches = append(report.RemoteBranches, item)
+		// Drop the remote-tracking branch along with it, if there is one
+		_, _ = s.operations.ExecuteGit(repo.Path, "update-ref", "-d", "refs/remotes/origin/"+branch)
+	}
+
+	if len(deleted) > 0 {
+		logger.Infof("✅ Cleaned up %d stale remote catnip branches of %s", len(deleted), repo.ID)
+	}
+	return nil
+}
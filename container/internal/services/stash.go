@@ -0,0 +1,70 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/vanpelt/catnip/internal/git"
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// StashWorktree sets the uncommitted changes of a worktree aside, including untracked files
+func (s *GitService) StashWorktree(worktreeID, message string) error {
+	worktree, exists := s.stateManager.GetWorktree(worktreeID)
+	if !exists {
+		return fmt.Errorf("worktree %s not found", worktreeID)
+	}
+	if err := s.stashWorktree(worktree, message); err != nil {
+		return err
+	}
+	s.refreshWorktreeStatusNow(worktreeID, models.StatusTierStandard)
+	return nil
+}
+
+// UnstashWorktree restores the work most recently stashed for a worktree. Changes conflicting
+// with the worktree are returned as a MergeConflictError and the entry is kept.
+func (s *GitService) UnstashWorktree(worktreeID string) error {
+	worktree, exists := s.stateManager.GetWorktree(worktreeID)
+	if !exists {
+		return fmt.Errorf("worktree %s not found", worktreeID)
+	}
+	err := s.unstashWorktree(worktree)
+	s.refreshWorktreeStatusNow(worktreeID, models.StatusTierStandard)
+	return err
+}
+
+// stashWorktree stashes the changes of a worktree under a message attributing the entry to it
+func (s *GitService) stashWorktree(worktree *models.Worktree, message string) error {
+	if !s.operations.IsDirty(worktree.Path) {
+		return fmt.Errorf("worktree %s has no changes to stash", worktree.Name)
+	}
+	if message == "" {
+		message = "stashed by catnip"
+	}
+	if err := s.operations.StashPush(worktree.Path, git.WorktreeStashMessage(worktree.ID, message)); err != nil {
+		return fmt.Errorf("failed to stash changes of %s: %v", worktree.Name, err)
+	}
+	logger.Infof("📦 Stashed changes of worktree %s", worktree.Name)
+	return nil
+}
+
+// unstashWorktree pops the latest entry stashed for a worktree
+func (s *GitService) unstashWorktree(worktree *models.Worktree) error {
+	entries, err := s.operations.StashList(worktree.Path)
+	if err != nil {
+		return fmt.Errorf("failed to list stash entries: %v", err)
+	}
+	stashes := git.WorktreeStashes(entries, worktree.ID)
+	if len(stashes) == 0 {
+		return fmt.Errorf("worktree %s has no stashed changes", worktree.Name)
+	}
+
+	if err := s.operations.StashPop(worktree.Path, stashes[0].Ref); err != nil {
+		if s.isMergeConflict(worktree.Path, err.Error()) {
+			return s.createMergeConflictError("unstash", worktree, err.Error())
+		}
+		return fmt.Errorf("failed to restore stashed changes of %s: %v", worktree.Name, err)
+	}
+	logger.Infof("📦 Restored stashed changes of worktree %s", worktree.Name)
+	return nil
+}
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/git"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+func TestWorktreeStash(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	root := t.TempDir()
+	repoPath := initTestRepo(t, filepath.Join(root, "repo"), map[string]string{"a.txt": "one\n"})
+	worktreePath := filepath.Join(root, "felix")
+	runTestGit(t, repoPath, "worktree", "add", "-b", "feature", worktreePath)
+	require.NoError(t, os.WriteFile(filepath.Join(repoPath, "b.txt"), []byte("upstream\n"), 0644))
+	runTestGit(t, repoPath, "add", "b.txt")
+	runTestGit(t, repoPath, "commit", "-m", "Upstream")
+
+	s := newTestGitService(t, root)
+	operations := s.operations
+	s.conflictResolver = git.NewConflictResolver(operations)
+	require.NoError(t, s.stateManager.AddRepository(&models.Repository{ID: "local/repo", Path: repoPath, DefaultBranch: "main", Available: true}))
+	require.NoError(t, s.stateManager.AddWorktree(&models.Worktree{
+		ID: "wt-felix", RepoID: "local/repo", Name: "repo/felix", Path: worktreePath, Branch: "feature", SourceBranch: "main",
+	}))
+	stashes := func() []git.StashEntry {
+		entries, err := operations.StashList(worktreePath)
+		require.NoError(t, err)
+		return git.WorktreeStashes(entries, "wt-felix")
+	}
+	readFile := func(name string) string {
+		data, err := os.ReadFile(filepath.Join(worktreePath, name))
+		require.NoError(t, err)
+		return string(data)
+	}
+
+	// Staged work blocks a sync unless it is stashed for it
+	require.NoError(t, os.WriteFile(filepath.Join(worktreePath, "a.txt"), []byte("mine\n"), 0644))
+	runTestGit(t, worktreePath, "add", "a.txt")
+	require.NoError(t, os.WriteFile(filepath.Join(worktreePath, "wip.txt"), []byte("wip\n"), 0644))
+	_, err := s.SyncWorktree("wt-felix", SyncOptions{Strategy: "rebase"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "staged changes")
+
+	_, err = s.SyncWorktree("wt-felix", SyncOptions{Strategy: "rebase", AutoStash: true})
+	require.NoError(t, err)
+	assert.Equal(t, "upstream\n", readFile("b.txt"))
+	assert.Equal(t, "mine\n", readFile("a.txt"))
+	assert.Equal(t, "wip\n", readFile("wip.txt"))
+	assert.Empty(t, stashes())
+
+	// Entries are attributed to the worktree that stashed them
+	require.NoError(t, s.StashWorktree("wt-felix", "trying something"))
+	assert.False(t, operations.IsDirty(worktreePath))
+	require.NoError(t, os.WriteFile(filepath.Join(repoPath, "a.txt"), []byte("main\n"), 0644))
+	runTestGit(t, repoPath, "stash")
+	require.Len(t, stashes(), 1)
+	assert.Equal(t, "catnip:wt-felix trying something", stashes()[0].Message)
+	assert.Equal(t, "stash@{1}", stashes()[0].Ref)
+
+	worktree, _ := s.stateManager.GetWorktree("wt-felix")
+	s.gitWorktreeManager.UpdateWorktreeStatus(worktree, models.StatusTierCheap, s.statusRefs())
+	assert.Equal(t, 1, worktree.StashCount)
+
+	err = s.StashWorktree("wt-felix", "")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no changes to stash")
+
+	require.NoError(t, s.UnstashWorktree("wt-felix"))
+	assert.Equal(t, "mine\n", readFile("a.txt"))
+	assert.Empty(t, stashes())
+	assert.Contains(t, runTestGit(t, repoPath, "stash", "list"), "stash@{0}", "other entries are left alone")
+	err = s.UnstashWorktree("wt-felix")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no stashed changes")
+
+	// Restored changes conflicting with the worktree are reported and the entry is kept
+	require.NoError(t, s.StashWorktree("wt-felix", ""))
+	require.NoError(t, os.WriteFile(filepath.Join(worktreePath, "a.txt"), []byte("committed\n"), 0644))
+	runTestGit(t, worktreePath, "commit", "-am", "Conflicting work")
+	err = s.UnstashWorktree("wt-felix")
+	var conflictErr *models.MergeConflictError
+	require.ErrorAs(t, err, &conflictErr)
+	assert.Equal(t, "unstash", conflictErr.Operation)
+	assert.Contains(t, conflictErr.ConflictFiles, "a.txt")
+	assert.Len(t, stashes(), 1)
+}
@@ -0,0 +1,144 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// stateReportFile holds the report of what loading the state healed or lost. It outlives its
+// acknowledgement so exports still carry the last report.
+const stateReportFile = "state-report.json"
+
+// loadStateReport reads the last state report. An acknowledged report is kept for exports, but
+// the next finding starts a new one.
+func (wsm *WorktreeStateManager) loadStateReport() {
+	data, err := os.ReadFile(filepath.Join(wsm.stateDir, stateReportFile))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logger.Warnf("⚠️ Failed to read %s: %v", stateReportFile, err)
+		}
+		return
+	}
+	var report models.StateReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		logger.Warnf("⚠️ Ignoring unreadable %s: %v", stateReportFile, err)
+		return
+	}
+	wsm.stateReport = &report
+}
+
+// recordStateReport adds a finding to the pending state report, starting a new report when
+// there is none or the last one was acknowledged, and persists it (must be called with lock
+// held, or before the manager is shared)
+func (wsm *WorktreeStateManager) recordStateReport(add func(report *models.StateReport)) {
+	if wsm.stateReport == nil || wsm.stateReport.AcknowledgedAt != nil {
+		wsm.stateReport = &models.StateReport{
+			Migrations:   []string{},
+			Quarantined:  []string{},
+			Restored:     []string{},
+			Lost:         []models.StateReportEntry{},
+			Unavailable:  []models.StateReportEntry{},
+			PathRewrites: []models.StatePathRewrite{},
+			Pruned:       []models.StateReportEntry{},
+			Stale:        []models.StateReportEntry{},
+		}
+	}
+	add(wsm.stateReport)
+	wsm.stateReport.At = time.Now()
+	wsm.saveStateReport()
+}
+
+// recordUnavailable adds a repository or worktree to the unavailable entries of the pending
+// state report, once however many restarts find it unavailable
+func (wsm *WorktreeStateManager) recordUnavailable(entry models.StateReportEntry) {
+	if report := wsm.stateReport; report != nil && report.AcknowledgedAt == nil {
+		for _, existing := range report.Unavailable {
+			if existing.Kind == entry.Kind && existing.ID == entry.ID {
+				return
+			}
+		}
+	}
+	wsm.recordStateReport(func(report *models.StateReport) {
+		report.Unavailable = append(report.Unavailable, entry)
+	})
+}
+
+// recordStale adds a worktree to the stale entries of the pending state report, once however
+// many validations find it stale
+func (wsm *WorktreeStateManager) recordStale(entry models.StateReportEntry) {
+	if report := wsm.stateReport; report != nil && report.AcknowledgedAt == nil {
+		for _, existing := range report.Stale {
+			if existing.ID == entry.ID {
+				return
+			}
+		}
+	}
+	wsm.recordStateReport(func(report *models.StateReport) {
+		report.Stale = append(report.Stale, entry)
+	})
+}
+
+// RecordStaleWorktree adds a worktree found stale outside ValidateState to the pending state
+// report
+func (wsm *WorktreeStateManager) RecordStaleWorktree(entry models.StateReportEntry) {
+	wsm.mu.Lock()
+	defer wsm.mu.Unlock()
+	wsm.recordStale(entry)
+}
+
+// saveStateReport persists the state report
+func (wsm *WorktreeStateManager) saveStateReport() {
+	data, err := json.MarshalIndent(wsm.stateReport, "", "  ")
+	if err == nil {
+		err = writeFileAtomic(filepath.Join(wsm.stateDir, stateReportFile), data)
+	}
+	if err != nil {
+		logger.Warnf("⚠️ Failed to save %s: %v", stateReportFile, err)
+	}
+}
+
+// PendingStateReport returns a copy of the state report until it is acknowledged, nil when
+// loading the state found nothing worth reporting
+func (wsm *WorktreeStateManager) PendingStateReport() *models.StateReport {
+	wsm.mu.RLock()
+	defer wsm.mu.RUnlock()
+
+	if wsm.stateReport == nil || wsm.stateReport.AcknowledgedAt != nil {
+		return nil
+	}
+	report := *wsm.stateReport
+	return &report
+}
+
+// AcknowledgeStateReport marks the pending state report as seen, so status stops returning it
+func (wsm *WorktreeStateManager) AcknowledgeStateReport() error {
+	wsm.mu.Lock()
+	defer wsm.mu.Unlock()
+
+	if wsm.stateReport == nil || wsm.stateReport.AcknowledgedAt != nil {
+		return fmt.Errorf("no state report to acknowledge")
+	}
+	now := time.Now()
+	wsm.stateReport.AcknowledgedAt = &now
+	wsm.saveStateReport()
+	wsm.loadErr = nil
+	return nil
+}
+
+// StateLoadError returns the *StateLoadError describing the corrupt state files found on load,
+// nil when the state loaded cleanly or the state report has been acknowledged since
+func (wsm *WorktreeStateManager) StateLoadError() error {
+	wsm.mu.RLock()
+	defer wsm.mu.RUnlock()
+
+	if wsm.loadErr == nil {
+		return nil
+	}
+	return wsm.loadErr
+}
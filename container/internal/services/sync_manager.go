@@ -161,9 +161,11 @@ func (sm *SyncManager) CheckMergeConflicts(repo *models.Repository, worktree *mo
 	return nil, nil
 }
 
-// applySyncStrategy applies merge or rebase strategy
+// applySyncStrategy applies merge, rebase or ff-only strategy
 func (sm *SyncManager) applySyncStrategy(worktree *models.Worktree, strategy, sourceRef string) error {
 	switch strategy {
+	case "ff-only":
+		return sm.operations.MergeFastForwardOnly(worktree.Path, sourceRef)
 	case "merge":
 		return sm.operations.Merge(worktree.Path, sourceRef)
 	case "rebase":
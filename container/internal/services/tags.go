@@ -0,0 +1,154 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/vanpelt/catnip/internal/git"
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// ListTags returns the tags of a repository, latest first
+func (s *GitService) ListTags(repoID string) ([]models.GitTag, error) {
+	s.mu.RLock()
+	repo, exists := s.stateManager.GetRepository(repoID)
+	s.mu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("repository %s not found", repoID)
+	}
+
+	infos, err := s.operations.ListTagDetails(repo.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags of %s: %v", repoID, err)
+	}
+	tags := make([]models.GitTag, 0, len(infos))
+	for _, info := range infos {
+		tags = append(tags, gitTag(info))
+	}
+	return tags, nil
+}
+
+// CreateTag tags the HEAD of a worktree. Annotated tags carry message; tags are shared by all
+// worktrees of the repository, so existing names are refused rather than moved.
+func (s *GitService) CreateTag(worktreeID, name, message string, annotated bool) (*models.GitTag, error) {
+	s.mu.RLock()
+	worktree, exists := s.stateManager.GetWorktree(worktreeID)
+	s.mu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("worktree %s not found", worktreeID)
+	}
+	if err := s.validateTagName(name); err != nil {
+		return nil, err
+	}
+	message = strings.TrimSpace(message)
+	if annotated && message == "" {
+		return nil, fmt.Errorf("annotated tag %s needs a message", name)
+	}
+	if _, err := s.findTag(worktree.Path, name); err == nil {
+		return nil, fmt.Errorf("tag %s already exists", name)
+	}
+
+	var err error
+	if annotated {
+		err = s.operations.CreateAnnotatedTag(worktree.Path, name, "HEAD", message)
+	} else {
+		err = s.operations.CreateTag(worktree.Path, name, "HEAD")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tag %s in %s: %v", name, worktree.Name, err)
+	}
+
+	tag, err := s.findTag(worktree.Path, name)
+	if err != nil {
+		return nil, err
+	}
+	logger.Infof("🏷️ Tagged %.8s of %s as %s", tag.Commit, worktree.Name, name)
+	return tag, nil
+}
+
+// DeleteTag deletes a tag of a repository. Tags already pushed stay on the remote.
+func (s *GitService) DeleteTag(repoID, name string) error {
+	s.mu.RLock()
+	repo, exists := s.stateManager.GetRepository(repoID)
+	s.mu.RUnlock()
+
+	if !exists {
+		return fmt.Errorf("repository %s not found", repoID)
+	}
+	if _, err := s.findTag(repo.Path, name); err != nil {
+		return err
+	}
+	if err := s.operations.DeleteTag(repo.Path, name); err != nil {
+		return fmt.Errorf("failed to delete tag %s: %v", name, err)
+	}
+	logger.Infof("🗑️ Deleted tag %s of %s", name, repoID)
+	return nil
+}
+
+// PushTag pushes a tag to the origin of a worktree's repository, the way branches are pushed.
+// Local repositories need a remote configured.
+func (s *GitService) PushTag(worktreeID, name string) error {
+	s.mu.RLock()
+	worktree, exists := s.stateManager.GetWorktree(worktreeID)
+	s.mu.RUnlock()
+
+	if !exists {
+		return fmt.Errorf("worktree %s not found", worktreeID)
+	}
+	if _, err := s.findTag(worktree.Path, name); err != nil {
+		return err
+	}
+	if s.isLocalRepo(worktree.RepoID) {
+		if _, err := s.getRemoteURL(worktree.Path); err != nil {
+			return fmt.Errorf("repository %s has no remote configured to push tag %s to", worktree.RepoID, name)
+		}
+	}
+
+	if err := s.operations.PushTag(worktree.Path, name, git.PushStrategy{
+		Remote:       "origin",
+		ConvertHTTPS: true,
+	}); err != nil {
+		return err
+	}
+	logger.Infof("📤 Pushed tag %s from %s", name, worktree.Name)
+	return nil
+}
+
+// validateTagName checks a tag name with git check-ref-format, like branch names
+func (s *GitService) validateTagName(name string) error {
+	if name == "" {
+		return fmt.Errorf("tag name is required")
+	}
+	if _, err := s.operations.ExecuteGit("", "check-ref-format", "refs/tags/"+name); err != nil {
+		return fmt.Errorf("invalid tag name %q", name)
+	}
+	return nil
+}
+
+// findTag looks a tag up by name
+func (s *GitService) findTag(repoPath, name string) (*models.GitTag, error) {
+	infos, err := s.operations.ListTagDetails(repoPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags: %v", err)
+	}
+	for _, info := range infos {
+		if info.Name == name {
+			tag := gitTag(info)
+			return &tag, nil
+		}
+	}
+	return nil, fmt.Errorf("tag %s not found", name)
+}
+
+func gitTag(info git.TagInfo) models.GitTag {
+	return models.GitTag{
+		Name:      info.Name,
+		Commit:    info.Commit,
+		Annotated: info.Annotated,
+		Message:   info.Message,
+		CreatedAt: info.Date,
+	}
+}
@@ -0,0 +1,63 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorktreeTags(t *testing.T) {
+	s, _, repoPath, worktreePath := newRecreateTestService(t)
+	require.NoError(t, os.WriteFile(filepath.Join(worktreePath, "app.txt"), []byte("v2\n"), 0644))
+	runTestGit(t, worktreePath, "commit", "-am", "felix v2")
+	head := runTestGit(t, worktreePath, "rev-parse", "HEAD")
+
+	rc, err := s.CreateTag("wt-felix", "v2.0.0-rc1", "Release candidate 1", true)
+	require.NoError(t, err)
+	assert.Equal(t, head, rc.Commit, "tags the worktree's HEAD")
+	assert.True(t, rc.Annotated)
+	assert.Equal(t, "Release candidate 1", rc.Message)
+
+	light, err := s.CreateTag("wt-felix", "felix-snapshot", "", false)
+	require.NoError(t, err)
+	assert.Equal(t, head, light.Commit)
+	assert.False(t, light.Annotated)
+
+	tags, err := s.ListTags("local/repo")
+	require.NoError(t, err)
+	names := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		names = append(names, tag.Name)
+	}
+	assert.ElementsMatch(t, []string{"v2.0.0-rc1", "felix-snapshot"}, names)
+
+	t.Run("RefusesInvalidAndExistingNames", func(t *testing.T) {
+		_, err := s.CreateTag("wt-felix", "bad..name", "", false)
+		assert.ErrorContains(t, err, "invalid tag name")
+		_, err = s.CreateTag("wt-felix", "v2.0.0-rc1", "again", true)
+		assert.ErrorContains(t, err, "already exists")
+		_, err = s.CreateTag("wt-felix", "v2.0.0-rc2", " ", true)
+		assert.ErrorContains(t, err, "needs a message")
+	})
+
+	t.Run("PushesToConfiguredRemote", func(t *testing.T) {
+		assert.ErrorContains(t, s.PushTag("wt-felix", "v2.0.0-rc1"), "no remote configured")
+
+		remotePath := filepath.Join(t.TempDir(), "remote.git")
+		runTestGit(t, repoPath, "init", "--bare", remotePath)
+		runTestGit(t, repoPath, "remote", "add", "origin", remotePath)
+
+		require.NoError(t, s.PushTag("wt-felix", "v2.0.0-rc1"))
+		assert.Contains(t, runTestGit(t, repoPath, "ls-remote", "--tags", "origin"), "refs/tags/v2.0.0-rc1")
+		assert.ErrorContains(t, s.PushTag("wt-felix", "v9"), "tag v9 not found")
+	})
+
+	t.Run("DeletesTags", func(t *testing.T) {
+		require.NoError(t, s.DeleteTag("local/repo", "felix-snapshot"))
+		assert.Empty(t, runTestGit(t, repoPath, "tag", "--list", "felix-snapshot"))
+		assert.ErrorContains(t, s.DeleteTag("local/repo", "felix-snapshot"), "not found")
+	})
+}
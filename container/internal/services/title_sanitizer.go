@@ -0,0 +1,71 @@
+package services
+
+import (
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// defaultMaxTitleLength is the maximum title length in characters, configurable via CATNIP_MAX_TITLE_LENGTH
+	defaultMaxTitleLength = 200
+	// maxNonPrintableRatio is the share of non-printable characters above which a title is treated as binary garbage
+	maxNonPrintableRatio = 0.3
+	// titleEllipsis marks titles that were truncated
+	titleEllipsis = "…"
+)
+
+// titleEscapeRegex matches OSC sequences (terminated by BEL or ST), CSI sequences and other
+// two-character escape sequences emitted by terminals and tools
+var titleEscapeRegex = regexp.MustCompile(`\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)?|(?:\x1b\[|\x{9b})[0-?]*[ -/]*[@-~]|\x1b[@-Z\\-_]`)
+
+// getMaxTitleLength returns the maximum title length, configurable via CATNIP_MAX_TITLE_LENGTH
+func getMaxTitleLength() int {
+	if env := os.Getenv("CATNIP_MAX_TITLE_LENGTH"); env != "" {
+		if length, err := strconv.Atoi(env); err == nil && length > len(titleEllipsis) {
+			return length
+		}
+	}
+	return defaultMaxTitleLength
+}
+
+// SanitizeTitle turns a raw terminal title into something safe to use in commit messages, branch
+// name prompts and the session history. It strips escape sequences and control characters,
+// collapses whitespace and truncates long titles with an ellipsis. It returns an empty string
+// for titles that are empty after cleaning or look like binary garbage.
+func SanitizeTitle(title string) string {
+	title = strings.ToValidUTF8(title, string(utf8.RuneError))
+	title = titleEscapeRegex.ReplaceAllString(title, "")
+
+	total, nonPrintable := 0, 0
+	cleaned := strings.Map(func(r rune) rune {
+		total++
+		switch {
+		case unicode.IsSpace(r):
+			return ' '
+		case r == utf8.RuneError || !unicode.IsPrint(r):
+			nonPrintable++
+			return -1
+		}
+		return r
+	}, title)
+	if total > 0 && float64(nonPrintable)/float64(total) > maxNonPrintableRatio {
+		return ""
+	}
+
+	// Remove the ✳ emoji symbol Claude uses as a spinner and any other common prefix symbols
+	cleaned = strings.ReplaceAll(cleaned, "✳", "")
+	cleaned = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(cleaned), "*"))
+	cleaned = strings.Join(strings.Fields(cleaned), " ")
+
+	maxLength := getMaxTitleLength()
+	if utf8.RuneCountInString(cleaned) > maxLength {
+		runes := []rune(cleaned)
+		cleaned = strings.TrimSpace(string(runes[:maxLength-utf8.RuneCountInString(titleEllipsis)])) + titleEllipsis
+	}
+
+	return cleaned
+}
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSanitizeTitle(t *testing.T) {
+	tests := []struct {
+		name  string
+		title string
+		want  string
+	}{
+		{"plain", "Fix login bug", "Fix login bug"},
+		{"claude spinner", "✳ Fix login bug", "Fix login bug"},
+		{"star prefix", "* Fix login bug", "Fix login bug"},
+		{"ansi colors", "\x1b[1;32mFix\x1b[0m login \x1b[4mbug\x1b[m", "Fix login bug"},
+		{"osc title", "\x1b]0;nested title\x07Fix login bug", "Fix login bug"},
+		{"osc with st", "Fix \x1b]8;;https://example.com\x1b\\login\x1b]8;;\x1b\\ bug", "Fix login bug"},
+		{"newlines and tabs", "Fix\nlogin\r\n\tbug", "Fix login bug"},
+		{"control characters", "Fix\x07 login\x00 bug\x7f", "Fix login bug"},
+		{"collapsed whitespace", "  Fix    login   bug  ", "Fix login bug"},
+		{"only escapes", "\x1b[2J\x1b[H", ""},
+		{"only spinner", "✳", ""},
+		{"binary garbage", "a\x00\x01\x02\x03\x04b\x05\x06\x07", ""},
+		{"invalid utf8", "ab\xff\xfe\xfd\xfc", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, SanitizeTitle(tt.title))
+		})
+	}
+}
+
+func TestSanitizeTitleTruncatesLongTitles(t *testing.T) {
+	long := strings.Repeat("refactor the\tauthentication\nflow\x07 ", 64) // ~2KB
+	assertTruncatedTitle(t, SanitizeTitle(long), defaultMaxTitleLength, "refactor the authentication flow refactor")
+
+	t.Setenv("CATNIP_MAX_TITLE_LENGTH", "20")
+	assertTruncatedTitle(t, SanitizeTitle(long), 20, "refactor the")
+
+	t.Setenv("CATNIP_MAX_TITLE_LENGTH", "invalid")
+	assertTruncatedTitle(t, SanitizeTitle(long), defaultMaxTitleLength, "refactor the authentication flow refactor")
+}
+
+// assertTruncatedTitle checks a truncated title fits in max runes, holds no control characters
+// and still starts with prefix
+func assertTruncatedTitle(t *testing.T, title string, max int, prefix string) {
+	t.Helper()
+	assert.LessOrEqual(t, utf8.RuneCountInString(title), max)
+	assert.True(t, strings.HasPrefix(title, prefix), "title %q keeps its start %q", title, prefix)
+	assert.True(t, strings.HasSuffix(title, titleEllipsis), "title %q is marked as truncated", title)
+	assert.False(t, strings.ContainsFunc(title, unicode.IsControl), "title %q has control characters", title)
+}
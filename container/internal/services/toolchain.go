@@ -0,0 +1,322 @@
+package services
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// Git config key letting worktrees without a setup.sh run the default setup commands of their
+// detected toolchains. Can be set per repository or globally.
+const toolchainSetupDefaultsKey = "catnip.setup.toolchain-defaults"
+
+// packageManagerCommands are the default commands catnip runs for a package manager
+type packageManagerCommands struct {
+	Setup    string // Installs dependencies in a fresh worktree
+	Lockfile string // Regenerates the lockfile after a conflict
+}
+
+var packageManagers = map[string]packageManagerCommands{
+	"npm":     {Setup: "npm ci", Lockfile: "npm install --package-lock-only"},
+	"pnpm":    {Setup: "pnpm install --frozen-lockfile", Lockfile: "pnpm install --lockfile-only"},
+	"yarn":    {Setup: "yarn install", Lockfile: "yarn install"},
+	"bun":     {Setup: "bun install", Lockfile: "bun install"},
+	"go":      {Setup: "go mod download", Lockfile: "go mod tidy"},
+	"cargo":   {Setup: "cargo fetch", Lockfile: "cargo update --workspace"},
+	"uv":      {Setup: "uv sync", Lockfile: "uv lock"},
+	"poetry":  {Setup: "poetry install", Lockfile: "poetry lock"},
+	"pipenv":  {Setup: "pipenv install --dev", Lockfile: "pipenv lock"},
+	"pdm":     {Setup: "pdm install", Lockfile: "pdm lock"},
+	"bundler": {Setup: "bundle install", Lockfile: "bundle lock"},
+}
+
+// Lockfiles identifying a package manager, in order of precedence
+var (
+	nodeLockfiles   = []struct{ file, manager string }{{"pnpm-lock.yaml", "pnpm"}, {"yarn.lock", "yarn"}, {"bun.lock", "bun"}, {"bun.lockb", "bun"}, {"package-lock.json", "npm"}, {"npm-shrinkwrap.json", "npm"}}
+	pythonLockfiles = []struct{ file, manager string }{{"uv.lock", "uv"}, {"poetry.lock", "poetry"}, {"Pipfile.lock", "pipenv"}, {"pdm.lock", "pdm"}}
+)
+
+var (
+	goDirectivePattern     = regexp.MustCompile(`^go\s+(\S+)`)
+	goToolchainPattern     = regexp.MustCompile(`^toolchain\s+go(\S+)`)
+	rustChannelPattern     = regexp.MustCompile(`^channel\s*=\s*"([^"]+)"`)
+	requiresPythonPattern  = regexp.MustCompile(`^requires-python\s*=\s*"([^"]+)"`)
+	pyprojectToolPattern   = regexp.MustCompile(`^\[tool\.(poetry|pdm|uv)[\].]`)
+	packageManagerSplitter = regexp.MustCompile(`[@+]`)
+)
+
+// DetectToolchains inspects the manifest files at the root of a checkout. Only the filesystem
+// is read: package managers are never run and nothing is fetched.
+func DetectToolchains(dir string) []models.Toolchain {
+	var toolchains []models.Toolchain
+	for _, detect := range []func(string) []models.Toolchain{
+		detectNodeToolchains, detectGoToolchain, detectRustToolchain, detectPythonToolchain, detectRubyToolchain, detectJavaToolchain,
+	} {
+		toolchains = append(toolchains, detect(dir)...)
+	}
+	return toolchains
+}
+
+func detectNodeToolchains(dir string) []models.Toolchain {
+	content, err := os.ReadFile(filepath.Join(dir, "package.json"))
+	if err != nil {
+		return nil
+	}
+	var manifest struct {
+		PackageManager  string            `json:"packageManager"`
+		Engines         map[string]string `json:"engines"`
+		Dependencies    map[string]string `json:"dependencies"`
+		DevDependencies map[string]string `json:"devDependencies"`
+	}
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		logger.Debugf("🔍 Ignoring invalid package.json in %s: %v", dir, err)
+	}
+
+	node := models.Toolchain{Name: "node", Manifest: "package.json", Version: firstLine(dir, ".nvmrc", ".node-version")}
+	if node.Version == "" {
+		node.Version = manifest.Engines["node"]
+	}
+	for _, lockfile := range nodeLockfiles {
+		if fileExists(dir, lockfile.file) {
+			node.PackageManager, node.Lockfile = lockfile.manager, lockfile.file
+			break
+		}
+	}
+	// Corepack's packageManager field (e.g. pnpm@9.1.0) is authoritative
+	if manifest.PackageManager != "" {
+		node.PackageManager = packageManagerSplitter.Split(manifest.PackageManager, 2)[0]
+	}
+	if node.PackageManager == "" {
+		node.PackageManager = "npm"
+	}
+	toolchains := []models.Toolchain{node}
+
+	if fileExists(dir, "tsconfig.json") || manifest.DevDependencies["typescript"] != "" || manifest.Dependencies["typescript"] != "" {
+		typescript := models.Toolchain{Name: "typescript", Manifest: "package.json", Version: manifest.DevDependencies["typescript"]}
+		if typescript.Version == "" {
+			typescript.Version = manifest.Dependencies["typescript"]
+		}
+		if fileExists(dir, "tsconfig.json") {
+			typescript.Manifest = "tsconfig.json"
+		}
+		toolchains = append(toolchains, typescript)
+	}
+	return toolchains
+}
+
+func detectGoToolchain(dir string) []models.Toolchain {
+	if !fileExists(dir, "go.mod") {
+		return nil
+	}
+	toolchain := models.Toolchain{Name: "go", PackageManager: "go", Manifest: "go.mod"}
+	if fileExists(dir, "go.sum") {
+		toolchain.Lockfile = "go.sum"
+	}
+	scanLines(filepath.Join(dir, "go.mod"), func(line string) {
+		if match := goToolchainPattern.FindStringSubmatch(line); match != nil {
+			toolchain.Version = match[1]
+		} else if match := goDirectivePattern.FindStringSubmatch(line); match != nil && toolchain.Version == "" {
+			toolchain.Version = match[1]
+		}
+	})
+	return []models.Toolchain{toolchain}
+}
+
+func detectRustToolchain(dir string) []models.Toolchain {
+	if !fileExists(dir, "Cargo.toml") {
+		return nil
+	}
+	toolchain := models.Toolchain{Name: "rust", PackageManager: "cargo", Manifest: "Cargo.toml"}
+	if fileExists(dir, "Cargo.lock") {
+		toolchain.Lockfile = "Cargo.lock"
+	}
+	scanLines(filepath.Join(dir, "rust-toolchain.toml"), func(line string) {
+		if match := rustChannelPattern.FindStringSubmatch(line); match != nil {
+			toolchain.Version = match[1]
+		}
+	})
+	if toolchain.Version == "" {
+		toolchain.Version = firstLine(dir, "rust-toolchain")
+	}
+	return []models.Toolchain{toolchain}
+}
+
+func detectPythonToolchain(dir string) []models.Toolchain {
+	var manifest string
+	for _, candidate := range []string{"pyproject.toml", "Pipfile", "requirements.txt", "setup.py"} {
+		if fileExists(dir, candidate) {
+			manifest = candidate
+			break
+		}
+	}
+	if manifest == "" {
+		return nil
+	}
+
+	toolchain := models.Toolchain{Name: "python", Manifest: manifest, Version: firstLine(dir, ".python-version")}
+	for _, lockfile := range pythonLockfiles {
+		if fileExists(dir, lockfile.file) {
+			toolchain.PackageManager, toolchain.Lockfile = lockfile.manager, lockfile.file
+			break
+		}
+	}
+	scanLines(filepath.Join(dir, "pyproject.toml"), func(line string) {
+		if match := requiresPythonPattern.FindStringSubmatch(line); match != nil && toolchain.Version == "" {
+			toolchain.Version = match[1]
+		} else if match := pyprojectToolPattern.FindStringSubmatch(line); match != nil && toolchain.PackageManager == "" {
+			toolchain.PackageManager = match[1]
+		}
+	})
+	if toolchain.PackageManager == "" && manifest == "Pipfile" {
+		toolchain.PackageManager = "pipenv"
+	}
+	if toolchain.PackageManager == "" {
+		toolchain.PackageManager = "pip"
+	}
+	return []models.Toolchain{toolchain}
+}
+
+func detectRubyToolchain(dir string) []models.Toolchain {
+	if !fileExists(dir, "Gemfile") {
+		return nil
+	}
+	toolchain := models.Toolchain{Name: "ruby", PackageManager: "bundler", Manifest: "Gemfile", Version: firstLine(dir, ".ruby-version")}
+	if fileExists(dir, "Gemfile.lock") {
+		toolchain.Lockfile = "Gemfile.lock"
+	}
+	return []models.Toolchain{toolchain}
+}
+
+func detectJavaToolchain(dir string) []models.Toolchain {
+	switch {
+	case fileExists(dir, "pom.xml"):
+		return []models.Toolchain{{Name: "java", PackageManager: "maven", Manifest: "pom.xml"}}
+	case fileExists(dir, "build.gradle.kts"):
+		return []models.Toolchain{{Name: "java", PackageManager: "gradle", Manifest: "build.gradle.kts"}}
+	case fileExists(dir, "build.gradle"):
+		return []models.Toolchain{{Name: "java", PackageManager: "gradle", Manifest: "build.gradle"}}
+	}
+	return nil
+}
+
+// toolchainSetupScript returns the default setup commands of the detected toolchains joined
+// into a single shell command, or "" when none of them have one
+func toolchainSetupScript(toolchains []models.Toolchain) string {
+	var commands []string
+	for _, toolchain := range toolchains {
+		commands = append(commands, packageManagers[toolchain.PackageManager].Setup)
+	}
+	return strings.Join(uniqueNonEmpty(commands), " && ")
+}
+
+// lockfileCommands maps each conflicted lockfile of a detected toolchain to the command
+// regenerating it
+func lockfileCommands(conflictFiles []string, toolchains []models.Toolchain) map[string]string {
+	var commands map[string]string
+	for _, toolchain := range toolchains {
+		command := packageManagers[toolchain.PackageManager].Lockfile
+		if toolchain.Lockfile == "" || command == "" {
+			continue
+		}
+		for _, file := range conflictFiles {
+			if file == toolchain.Lockfile {
+				if commands == nil {
+					commands = make(map[string]string)
+				}
+				commands[file] = command
+			}
+		}
+	}
+	return commands
+}
+
+// DetectWorktreeToolchains re-runs toolchain detection for a worktree and records the result
+func (s *GitService) DetectWorktreeToolchains(worktreeID string) ([]models.Toolchain, error) {
+	worktree, exists := s.stateManager.GetWorktree(worktreeID)
+	if !exists {
+		return nil, fmt.Errorf("worktree %s not found", worktreeID)
+	}
+
+	toolchains := DetectToolchains(worktree.Path)
+	if err := s.stateManager.UpdateWorktree(worktreeID, map[string]interface{}{"toolchains": toolchains}); err != nil {
+		return nil, err
+	}
+	return toolchains, nil
+}
+
+// defaultSetupScript returns the toolchain setup command for a worktree when toolchain defaults
+// are enabled, or "" to only run a setup.sh from the repository
+func (s *GitService) defaultSetupScript(worktree *models.Worktree) string {
+	if value, err := s.operations.GetConfig(worktree.Path, toolchainSetupDefaultsKey); err != nil || value != "true" {
+		return ""
+	}
+	return toolchainSetupScript(worktree.Toolchains)
+}
+
+// annotateLockfileConflicts adds regeneration commands for conflicted lockfiles
+func (s *GitService) annotateLockfileConflicts(conflict *models.MergeConflictError, worktree *models.Worktree) *models.MergeConflictError {
+	if conflict == nil {
+		return nil
+	}
+	toolchains := worktree.Toolchains
+	if toolchains == nil {
+		toolchains = DetectToolchains(worktree.Path)
+	}
+	conflict.LockfileCommands = lockfileCommands(conflict.ConflictFiles, toolchains)
+	return conflict
+}
+
+func fileExists(dir, name string) bool {
+	info, err := os.Stat(filepath.Join(dir, name))
+	return err == nil && !info.IsDir()
+}
+
+// firstLine returns the trimmed first line of the first existing file
+func firstLine(dir string, names ...string) string {
+	for _, name := range names {
+		file, err := os.Open(filepath.Join(dir, name))
+		if err != nil {
+			continue
+		}
+		scanner := bufio.NewScanner(file)
+		scanner.Scan()
+		line := strings.TrimSpace(scanner.Text())
+		file.Close()
+		if line != "" {
+			return line
+		}
+	}
+	return ""
+}
+
+// scanLines calls fn with every trimmed line of a file, if it exists
+func scanLines(path string, fn func(line string)) {
+	file, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fn(strings.TrimSpace(scanner.Text()))
+	}
+}
+
+func uniqueNonEmpty(values []string) []string {
+	seen := make(map[string]bool)
+	var unique []string
+	for _, value := range values {
+		if value != "" && !seen[value] {
+			seen[value] = true
+			unique = append(unique, value)
+		}
+	}
+	return unique
+}
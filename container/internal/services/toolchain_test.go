@@ -0,0 +1,79 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// writeManifests creates files relative to a new temp dir
+func writeManifests(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+	return dir
+}
+
+func TestDetectToolchains(t *testing.T) {
+	assert.Empty(t, DetectToolchains(writeManifests(t, map[string]string{"README.md": "# felix\n"})))
+
+	pnpm := writeManifests(t, map[string]string{
+		"package.json":   `{"engines": {"node": ">=18"}, "devDependencies": {"typescript": "^5.4.0"}}`,
+		"pnpm-lock.yaml": "lockfileVersion: '9.0'\n",
+		"tsconfig.json":  "{}",
+		".nvmrc":         "20\n",
+	})
+	assert.Equal(t, []models.Toolchain{
+		{Name: "node", PackageManager: "pnpm", Version: "20", Manifest: "package.json", Lockfile: "pnpm-lock.yaml"},
+		{Name: "typescript", Version: "^5.4.0", Manifest: "tsconfig.json"},
+	}, DetectToolchains(pnpm))
+
+	corepack := writeManifests(t, map[string]string{
+		"package.json":      `{"packageManager": "yarn@4.1.0+sha256.abc", "engines": {"node": ">=18"}}`,
+		"package-lock.json": "{}",
+	})
+	assert.Equal(t, []models.Toolchain{
+		{Name: "node", PackageManager: "yarn", Version: ">=18", Manifest: "package.json", Lockfile: "package-lock.json"},
+	}, DetectToolchains(corepack))
+
+	polyglot := writeManifests(t, map[string]string{
+		"go.mod":              "module example.com/felix\n\ngo 1.22\n\ntoolchain go1.22.3\n",
+		"go.sum":              "",
+		"Cargo.toml":          "[workspace]\nmembers = [\"crates/*\"]\n",
+		"Cargo.lock":          "",
+		"rust-toolchain.toml": "[toolchain]\nchannel = \"1.78\"\n",
+		"pyproject.toml":      "[project]\nrequires-python = \">=3.11\"\n\n[tool.poetry]\nname = \"felix\"\n",
+		"poetry.lock":         "",
+	})
+	assert.Equal(t, []models.Toolchain{
+		{Name: "go", PackageManager: "go", Version: "1.22.3", Manifest: "go.mod", Lockfile: "go.sum"},
+		{Name: "rust", PackageManager: "cargo", Version: "1.78", Manifest: "Cargo.toml", Lockfile: "Cargo.lock"},
+		{Name: "python", PackageManager: "poetry", Version: ">=3.11", Manifest: "pyproject.toml", Lockfile: "poetry.lock"},
+	}, DetectToolchains(polyglot))
+
+	pip := writeManifests(t, map[string]string{"requirements.txt": "requests\n", ".python-version": "3.12\n", "Gemfile": ""})
+	assert.Equal(t, []models.Toolchain{
+		{Name: "python", PackageManager: "pip", Version: "3.12", Manifest: "requirements.txt"},
+		{Name: "ruby", PackageManager: "bundler", Manifest: "Gemfile"},
+	}, DetectToolchains(pip))
+}
+
+func TestToolchainDefaults(t *testing.T) {
+	toolchains := []models.Toolchain{
+		{Name: "node", PackageManager: "pnpm", Lockfile: "pnpm-lock.yaml"},
+		{Name: "typescript"},
+		{Name: "python", PackageManager: "pip"},
+		{Name: "rust", PackageManager: "cargo", Lockfile: "Cargo.lock"},
+	}
+	assert.Equal(t, "pnpm install --frozen-lockfile && cargo fetch", toolchainSetupScript(toolchains))
+	assert.Empty(t, toolchainSetupScript(nil))
+
+	assert.Equal(t, map[string]string{"Cargo.lock": "cargo update --workspace"},
+		lockfileCommands([]string{"src/main.rs", "Cargo.lock"}, toolchains))
+	assert.Nil(t, lockfileCommands([]string{"package.json"}, toolchains))
+}
@@ -0,0 +1,279 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+	"github.com/vanpelt/catnip/internal/redact"
+)
+
+const (
+	// validationCommandKey lists the shell commands validating a worktree before it is merged or
+	// pushed for a pull request, one value per command (git config --add)
+	validationCommandKey = "catnip.validate.command"
+	// validationTimeoutKey bounds each validation command, in seconds
+	validationTimeoutKey = "catnip.validate.timeout-seconds"
+	// defaultValidationTimeout applies when validationTimeoutKey is unset or invalid
+	defaultValidationTimeout = 10 * time.Minute
+	// validationOutputLimit is how much of the end of a command's output is kept
+	validationOutputLimit = 16 << 10
+)
+
+// Operations validation runs for
+const (
+	ValidationForMerge       = "merge"
+	ValidationForPullRequest = "pull_request"
+	ValidationOnDemand       = "manual"
+)
+
+// ErrValidationFailed is returned, wrapped in a ValidationFailedError, when a worktree's
+// validation commands fail before a merge or pull request push
+var ErrValidationFailed = errors.New("validation failed")
+
+// ValidationFailedError describes a merge or push blocked by failing validation commands
+type ValidationFailedError struct {
+	WorktreeName string
+	Result       *models.ValidationResult
+}
+
+func (e *ValidationFailedError) Error() string {
+	failed := e.Result.Commands[len(e.Result.Commands)-1]
+	reason := fmt.Sprintf("exited with %d", failed.ExitCode)
+	if failed.TimedOut {
+		reason = "timed out"
+	}
+	message := fmt.Sprintf("validation of %s failed: %q %s", e.WorktreeName, failed.Command, reason)
+	if output := strings.TrimSpace(failed.Output); output != "" {
+		message += "\n" + output
+	}
+	return message
+}
+
+func (e *ValidationFailedError) Unwrap() error {
+	return ErrValidationFailed
+}
+
+// validationCommands returns the validation commands configured for the repository of the
+// worktree at worktreePath, ending with signatureValidationCommand when the repository
+// requires signed commits
+func (s *GitService) validationCommands(worktreePath string) []string {
+	output, _ := s.operations.ExecuteGit(worktreePath, "config", "--get-all", validationCommandKey)
+	var commands []string
+	for _, line := range strings.Split(string(output), "\n") {
+		if command := strings.TrimSpace(line); command != "" {
+			commands = append(commands, command)
+		}
+	}
+	if s.requireSignedCommits(worktreePath) {
+		commands = append(commands, signatureValidationCommand)
+	}
+	return commands
+}
+
+// validationTimeout returns how long each validation command of the worktree at worktreePath
+// may run
+func (s *GitService) validationTimeout(worktreePath string) time.Duration {
+	value, err := s.operations.GetConfig(worktreePath, validationTimeoutKey)
+	if err != nil || value == "" {
+		return defaultValidationTimeout
+	}
+	seconds, err := strconv.Atoi(value)
+	if err != nil || seconds <= 0 {
+		logger.Warnf("⚠️  Ignoring invalid %s value %q", validationTimeoutKey, value)
+		return defaultValidationTimeout
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// lockValidation serializes validation runs per worktree, so concurrent callers wait for the
+// running validation and then reuse its result
+func (s *GitService) lockValidation(worktreeID string) func() {
+	value, _ := s.validationLocks.LoadOrStore(worktreeID, &sync.Mutex{})
+	mutex := value.(*sync.Mutex)
+	mutex.Lock()
+	return mutex.Unlock
+}
+
+// ValidateWorktree runs the repository's validation commands in a worktree, reusing the
+// recorded result while HEAD and the commands are unchanged and the worktree is clean unless
+// force is set. Failing commands are a result, not an error.
+func (s *GitService) ValidateWorktree(worktreeID string, force bool) (*models.ValidationResult, error) {
+	worktree, exists := s.stateManager.GetWorktree(worktreeID)
+	if !exists {
+		return nil, fmt.Errorf("worktree %s not found", worktreeID)
+	}
+	return s.runValidation(worktree, ValidationOnDemand, force)
+}
+
+// requireValidation runs the validation for operation on a worktree and refuses the operation
+// with a ValidationFailedError when it fails. Skipping is recorded on the worktree and in the
+// activity feed.
+func (s *GitService) requireValidation(worktree *models.Worktree, operation string, skip bool) error {
+	if skip {
+		return s.skipValidation(worktree, operation)
+	}
+	result, err := s.runValidation(worktree, operation, false)
+	if err != nil {
+		return err
+	}
+	if result.Status == models.ValidationFailed {
+		return &ValidationFailedError{WorktreeName: worktree.Name, Result: result}
+	}
+	return nil
+}
+
+// skipValidation records that operation went ahead without validating the worktree
+func (s *GitService) skipValidation(worktree *models.Worktree, operation string) error {
+	commands := s.validationCommands(worktree.Path)
+	if len(commands) == 0 {
+		return nil
+	}
+	head, err := s.operations.GetCommitHash(worktree.Path, "HEAD")
+	if err != nil {
+		return fmt.Errorf("failed to read HEAD: %v", err)
+	}
+
+	logger.Warnf("⚠️ Skipping validation of %s before %s at %s", worktree.Name, operation, head)
+	now := time.Now()
+	result := &models.ValidationResult{
+		Status:     models.ValidationSkipped,
+		CommitHash: head,
+		Operation:  operation,
+		StartedAt:  now,
+		FinishedAt: now,
+	}
+	s.recordValidation(worktree, result)
+	s.stateManager.recordWorktreeActivity(ActivityValidationSkipped, worktree, "", operation, "")
+	return nil
+}
+
+// runValidation runs the validation commands in a worktree one after another, stopping at the
+// first failure
+func (s *GitService) runValidation(worktree *models.Worktree, operation string, force bool) (*models.ValidationResult, error) {
+	commands := s.validationCommands(worktree.Path)
+	if len(commands) == 0 {
+		return &models.ValidationResult{Status: models.ValidationNotConfigured}, nil
+	}
+
+	unlock := s.lockValidation(worktree.ID)
+	defer unlock()
+
+	head, err := s.operations.GetCommitHash(worktree.Path, "HEAD")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read HEAD: %v", err)
+	}
+	dirty := s.operations.IsDirty(worktree.Path)
+	if current, exists := s.stateManager.GetWorktree(worktree.ID); exists && !force && !dirty {
+		if cached := current.Validation; reusableValidation(cached, head, commands) {
+			logger.Debugf("✅ Reusing %s validation of %s at %s", cached.Status, worktree.Name, head)
+			return cached, nil
+		}
+	}
+
+	timeout := s.validationTimeout(worktree.Path)
+	result := &models.ValidationResult{
+		Status:     models.ValidationPassed,
+		CommitHash: head,
+		Operation:  operation,
+		StartedAt:  time.Now(),
+	}
+	logger.Infof("🧪 Validating %s before %s (%d commands)", worktree.Name, operation, len(commands))
+	for _, command := range commands {
+		var commandResult models.ValidationCommandResult
+		if command == signatureValidationCommand {
+			commandResult = s.runSignatureValidation(worktree)
+		} else {
+			commandResult = s.runValidationCommand(worktree.Path, command, timeout)
+		}
+		result.Commands = append(result.Commands, commandResult)
+		if commandResult.ExitCode != 0 {
+			result.Status = models.ValidationFailed
+			logger.Warnf("❌ Validation of %s failed: %q exited with %d", worktree.Name, command, commandResult.ExitCode)
+			break
+		}
+	}
+	result.FinishedAt = time.Now()
+
+	// Uncommitted changes were part of what ran, the result can't be tied to HEAD
+	if dirty {
+		result.CommitHash = ""
+	}
+	s.recordValidation(worktree, result)
+	return result, nil
+}
+
+// reusableValidation checks if a recorded validation ran the same commands on head
+func reusableValidation(result *models.ValidationResult, head string, commands []string) bool {
+	if result == nil || result.CommitHash != head || result.Status == models.ValidationSkipped {
+		return false
+	}
+	// Failures stop early, so a failed result ran a prefix of the commands
+	if len(result.Commands) > len(commands) ||
+		(result.Status == models.ValidationPassed && len(result.Commands) != len(commands)) {
+		return false
+	}
+	for i, commandResult := range result.Commands {
+		// Commands are stored with their secrets masked
+		if commandResult.Command != redact.Text(commands[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// runValidationCommand runs a single validation command through the shell in a worktree
+func (s *GitService) runValidationCommand(worktreePath, command string, timeout time.Duration) models.ValidationCommandResult {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Dir = worktreePath
+	cmd.Env = append(os.Environ(), s.DependencyCacheEnv(worktreePath)...)
+	// Don't wait forever on output pipes held open by background processes of a killed command
+	cmd.WaitDelay = 5 * time.Second
+
+	started := time.Now()
+	output, err := cmd.CombinedOutput()
+	// Secrets are masked before the result is stored or broadcast
+	result := models.ValidationCommandResult{
+		Command:    redact.Text(command),
+		DurationMS: time.Since(started).Milliseconds(),
+		Output:     tailOutput(redact.Text(string(output)), validationOutputLimit),
+	}
+	var exitErr *exec.ExitError
+	switch {
+	case ctx.Err() == context.DeadlineExceeded:
+		result.ExitCode = -1
+		result.TimedOut = true
+	case errors.As(err, &exitErr):
+		result.ExitCode = exitErr.ExitCode()
+	case err != nil:
+		result.ExitCode = -1
+		result.Output = tailOutput(result.Output+redact.Text(err.Error()), validationOutputLimit)
+	}
+	return result
+}
+
+// tailOutput keeps the last limit bytes of output, where test and lint failures are reported
+func tailOutput(output string, limit int) string {
+	if len(output) <= limit {
+		return output
+	}
+	return "[output truncated]\n" + output[len(output)-limit:]
+}
+
+// recordValidation stores a validation result on the worktree
+func (s *GitService) recordValidation(worktree *models.Worktree, result *models.ValidationResult) {
+	if err := s.stateManager.UpdateWorktree(worktree.ID, map[string]interface{}{"validation": result}); err != nil {
+		logger.Warnf("⚠️ Failed to record validation of %s: %v", worktree.Name, err)
+	}
+}
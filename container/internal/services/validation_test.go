@@ -0,0 +1,103 @@
+package services
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+func TestWorktreeValidation(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	root := t.TempDir()
+	repoPath := initTestRepo(t, filepath.Join(root, "repo"), nil)
+	worktreePath := filepath.Join(root, "felix")
+	runTestGit(t, repoPath, "worktree", "add", "-b", "refs/catnip/felix", worktreePath)
+
+	s := newTestGitService(t, root)
+	stateManager := s.stateManager
+	repo := &models.Repository{ID: "local/repo", Path: repoPath, DefaultBranch: "main", Available: true}
+	require.NoError(t, stateManager.AddRepository(repo))
+	require.NoError(t, stateManager.AddWorktree(&models.Worktree{
+		ID: "wt-felix", RepoID: repo.ID, Name: "repo/felix", Path: worktreePath, Branch: "refs/catnip/felix", SourceBranch: "main",
+	}))
+
+	// Nothing configured, nothing to run
+	result, err := s.ValidateWorktree("wt-felix", false)
+	require.NoError(t, err)
+	assert.Equal(t, models.ValidationNotConfigured, result.Status)
+
+	runs := filepath.Join(root, "runs")
+	runTestGit(t, repoPath, "config", "--add", validationCommandKey, "test -f ok.txt || { echo missing ok.txt; exit 3; }")
+	runTestGit(t, repoPath, "config", "--add", validationCommandKey, "echo run >> "+runs+" && echo checked")
+	countRuns := func() int {
+		data, _ := os.ReadFile(runs)
+		return strings.Count(string(data), "run")
+	}
+
+	// A failing command blocks the merge and is recorded on the worktree
+	err = s.MergeWorktreeToMain("wt-felix", MergeOptions{})
+	var validationErr *ValidationFailedError
+	require.ErrorAs(t, err, &validationErr)
+	assert.ErrorIs(t, err, ErrValidationFailed)
+	assert.Contains(t, err.Error(), "exited with 3")
+	assert.Contains(t, err.Error(), "missing ok.txt")
+	require.Len(t, validationErr.Result.Commands, 1, "validation stops at the first failure")
+	assert.Equal(t, ValidationForMerge, validationErr.Result.Operation)
+	worktree, _ := stateManager.GetWorktree("wt-felix")
+	require.NotNil(t, worktree.Validation)
+	assert.Equal(t, models.ValidationFailed, worktree.Validation.Status)
+	assert.Equal(t, runTestGit(t, worktreePath, "rev-parse", "HEAD"), worktree.Validation.CommitHash)
+
+	// Fixing it passes, and an unchanged HEAD reuses the result
+	require.NoError(t, os.WriteFile(filepath.Join(worktreePath, "ok.txt"), []byte("ok\n"), 0644))
+	runTestGit(t, worktreePath, "add", "ok.txt")
+	runTestGit(t, worktreePath, "commit", "-m", "Add ok.txt")
+	result, err = s.ValidateWorktree("wt-felix", false)
+	require.NoError(t, err)
+	assert.Equal(t, models.ValidationPassed, result.Status)
+	require.Len(t, result.Commands, 2)
+	assert.Equal(t, "checked\n", result.Commands[1].Output)
+	assert.Equal(t, 1, countRuns())
+
+	worktree, _ = stateManager.GetWorktree("wt-felix")
+	require.NoError(t, s.requireValidation(worktree, ValidationForPullRequest, false))
+	assert.Equal(t, 1, countRuns(), "unchanged HEAD reuses the result")
+	_, err = s.ValidateWorktree("wt-felix", true)
+	require.NoError(t, err)
+	assert.Equal(t, 2, countRuns(), "force runs again")
+
+	// Uncommitted changes are always validated and not tied to HEAD
+	require.NoError(t, os.WriteFile(filepath.Join(worktreePath, "wip.txt"), []byte("wip\n"), 0644))
+	result, err = s.ValidateWorktree("wt-felix", false)
+	require.NoError(t, err)
+	assert.Empty(t, result.CommitHash)
+	_, err = s.ValidateWorktree("wt-felix", false)
+	require.NoError(t, err)
+	assert.Equal(t, 4, countRuns())
+	require.NoError(t, os.Remove(filepath.Join(worktreePath, "wip.txt")))
+
+	// Skipping is recorded
+	require.NoError(t, s.requireValidation(worktree, ValidationForMerge, true))
+	worktree, _ = stateManager.GetWorktree("wt-felix")
+	assert.Equal(t, models.ValidationSkipped, worktree.Validation.Status)
+	assert.Equal(t, 4, countRuns())
+
+	// Commands running past the timeout are killed
+	runTestGit(t, repoPath, "config", validationTimeoutKey, "1")
+	runTestGit(t, repoPath, "config", "--add", validationCommandKey, "exec sleep 10")
+	result, err = s.ValidateWorktree("wt-felix", false)
+	require.NoError(t, err)
+	assert.Equal(t, models.ValidationFailed, result.Status)
+	require.Len(t, result.Commands, 3)
+	assert.True(t, result.Commands[2].TimedOut)
+	assert.Less(t, result.Commands[2].DurationMS, int64(5000))
+}
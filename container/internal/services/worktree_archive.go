@@ -0,0 +1,288 @@
+package services
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// archiveDirName is the directory inside the workspace that worktree archives are written to
+const archiveDirName = ".catnip-archives"
+
+// WorktreeArchive describes a tarball of a worktree's files taken before it was deleted. It is
+// stored as <id>.json next to the <id>.tar.gz it describes.
+type WorktreeArchive struct {
+	ID           string `json:"id"`
+	WorktreeID   string `json:"worktree_id"`
+	WorktreeName string `json:"worktree_name"`
+	RepoID       string `json:"repo_id"`
+	Branch       string `json:"branch"`
+	SourceBranch string `json:"source_branch"`
+	// Commit HEAD pointed at when the archive was taken
+	CommitHash string `json:"commit_hash"`
+	// Whether the worktree had uncommitted changes, which only the archive preserves
+	IsDirty bool `json:"is_dirty"`
+	// Number of files and size in bytes of the tarball
+	Files      int       `json:"files"`
+	Size       int64     `json:"size"`
+	ArchivedAt time.Time `json:"archived_at"`
+	// Absolute path of the tarball
+	Path string `json:"path"`
+}
+
+// getArchiveDir returns the directory worktree archives are kept in, configurable via
+// CATNIP_ARCHIVE_DIR
+func getArchiveDir() string {
+	if dir := os.Getenv("CATNIP_ARCHIVE_DIR"); dir != "" {
+		return dir
+	}
+	return filepath.Join(getWorkspaceDir(), archiveDirName)
+}
+
+// ArchiveWorktree writes the worktree's tracked and untracked files, including uncommitted
+// changes but not ignored files, to a tar.gz in the archive directory together with a metadata
+// file describing where they came from
+func (s *GitService) ArchiveWorktree(worktreeID string) (*WorktreeArchive, error) {
+	worktree, exists := s.stateManager.GetWorktree(worktreeID)
+	if !exists {
+		return nil, fmt.Errorf("worktree %s not found", worktreeID)
+	}
+	if repo, exists := s.stateManager.GetRepository(worktree.RepoID); exists {
+		if err := s.ensureDiskSpace("archive "+worktree.Name, worktreeEstimateBytes(repo)); err != nil {
+			return nil, err
+		}
+	}
+
+	tree, err := s.snapshotWorktreeTree(worktree, true)
+	if err != nil {
+		return nil, err
+	}
+	files, err := s.listTreeFiles(worktree.Path, tree)
+	if err != nil {
+		return nil, err
+	}
+
+	archivedAt := time.Now()
+	archive := &WorktreeArchive{
+		ID:           fmt.Sprintf("%s-%s", strings.ReplaceAll(worktree.Name, "/", "-"), archivedAt.Format("20060102-150405")),
+		WorktreeID:   worktree.ID,
+		WorktreeName: worktree.Name,
+		RepoID:       worktree.RepoID,
+		Branch:       worktree.Branch,
+		SourceBranch: worktree.SourceBranch,
+		Files:        len(files),
+		ArchivedAt:   archivedAt,
+	}
+	if output, err := s.operations.ExecuteGit(worktree.Path, "rev-parse", "HEAD^{commit}"); err == nil {
+		archive.CommitHash = strings.TrimSpace(string(output))
+	}
+	if status, err := s.operations.ExecuteGit(worktree.Path, "status", "--porcelain"); err == nil {
+		archive.IsDirty = strings.TrimSpace(string(status)) != ""
+	}
+
+	dir := getArchiveDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create archive directory: %v", err)
+	}
+	archive.Path = filepath.Join(dir, archive.ID+".tar.gz")
+
+	data, err := s.operations.ExecuteGit(worktree.Path, "archive", "--format=tar.gz", tree)
+	if err != nil {
+		return nil, fmt.Errorf("failed to archive worktree: %v", err)
+	}
+	archive.Size = int64(len(data))
+	if err := writeFileAtomic(archive.Path, data); err != nil {
+		return nil, fmt.Errorf("failed to write archive: %v", err)
+	}
+	metadata, err := json.MarshalIndent(archive, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode archive metadata: %v", err)
+	}
+	if err := writeFileAtomic(filepath.Join(dir, archive.ID+".json"), metadata); err != nil {
+		_ = os.Remove(archive.Path)
+		return nil, fmt.Errorf("failed to write archive metadata: %v", err)
+	}
+
+	logger.Infof("🗄️ Archived worktree %s to %s (%d files)", worktree.Name, archive.Path, archive.Files)
+	return archive, nil
+}
+
+// ListWorktreeArchives returns the archives in the archive directory, newest first
+func (s *GitService) ListWorktreeArchives() ([]*WorktreeArchive, error) {
+	entries, err := os.ReadDir(getArchiveDir())
+	if os.IsNotExist(err) {
+		return []*WorktreeArchive{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive directory: %v", err)
+	}
+
+	archives := []*WorktreeArchive{}
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		archive, err := loadWorktreeArchive(strings.TrimSuffix(entry.Name(), ".json"))
+		if err != nil {
+			logger.Debugf("⚠️  Skipping archive %s: %v", entry.Name(), err)
+			continue
+		}
+		archives = append(archives, archive)
+	}
+	sort.Slice(archives, func(i, j int) bool {
+		return archives[i].ArchivedAt.After(archives[j].ArchivedAt)
+	})
+	return archives, nil
+}
+
+// loadWorktreeArchive reads the metadata of an archive, checking its tarball still exists
+func loadWorktreeArchive(archiveID string) (*WorktreeArchive, error) {
+	if archiveID == "" || archiveID != filepath.Base(archiveID) || strings.HasPrefix(archiveID, ".") {
+		return nil, fmt.Errorf("invalid archive ID %q", archiveID)
+	}
+	dir := getArchiveDir()
+	data, err := os.ReadFile(filepath.Join(dir, archiveID+".json"))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("archive %s not found", archiveID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive %s: %v", archiveID, err)
+	}
+
+	var archive WorktreeArchive
+	if err := json.Unmarshal(data, &archive); err != nil {
+		return nil, fmt.Errorf("failed to parse archive %s: %v", archiveID, err)
+	}
+	// The directory may have moved since, the tarball sits next to its metadata
+	archive.ID = archiveID
+	archive.Path = filepath.Join(dir, archiveID+".tar.gz")
+	if _, err := os.Stat(archive.Path); err != nil {
+		return nil, fmt.Errorf("archive %s has no tarball: %v", archiveID, err)
+	}
+	return &archive, nil
+}
+
+// RestoreWorktreeArchive creates a fresh worktree on a new catnip branch from the archived
+// worktree's source branch and unpacks the archived files on top of it, leaving them as
+// uncommitted changes to review
+func (s *GitService) RestoreWorktreeArchive(archiveID string) (*models.Worktree, error) {
+	archive, err := loadWorktreeArchive(archiveID)
+	if err != nil {
+		return nil, err
+	}
+	org, repo, found := strings.Cut(archive.RepoID, "/")
+	if !found {
+		return nil, fmt.Errorf("archive %s has an invalid repository ID %q", archiveID, archive.RepoID)
+	}
+
+	_, worktree, err := s.CheckoutRepository(org, repo, archive.SourceBranch, DefaultCloneOptions())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create worktree for archive %s: %v", archiveID, err)
+	}
+	if err := extractTarGz(archive.Path, worktree.Path); err != nil {
+		return nil, fmt.Errorf("failed to unpack archive %s into %s: %v", archiveID, worktree.Name, err)
+	}
+
+	logger.Infof("🗄️ Restored archive %s into worktree %s", archiveID, worktree.Name)
+	if restored, exists := s.stateManager.GetWorktree(worktree.ID); exists {
+		return restored, nil
+	}
+	return worktree, nil
+}
+
+// extractTarGz unpacks a tar.gz written by git archive into dir, overwriting existing files.
+// Entries escaping dir are refused.
+func extractTarGz(archivePath, dir string) error {
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	reader := tar.NewReader(gz)
+	for {
+		header, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !filepath.IsLocal(header.Name) {
+			return fmt.Errorf("archive entry %q is outside the worktree", header.Name)
+		}
+		target := filepath.Join(dir, header.Name)
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := makeParentInside(dir, filepath.Join(target, ".")); err != nil {
+				return err
+			}
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			if err := makeParentInside(dir, target); err != nil {
+				return err
+			}
+			_ = os.Remove(target)
+			if err := os.Symlink(header.Linkname, target); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := makeParentInside(dir, target); err != nil {
+				return err
+			}
+			_ = os.Remove(target) // Replaces symlinks instead of writing through them
+			out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(header.Mode)&0777)
+			if err != nil {
+				return err
+			}
+			_, copyErr := io.Copy(out, reader)
+			closeErr := out.Close()
+			if copyErr != nil {
+				return copyErr
+			}
+			if closeErr != nil {
+				return closeErr
+			}
+		}
+	}
+}
+
+// makeParentInside creates the parent directory of target and checks that, with symlinks
+// resolved, it is still inside dir
+func makeParentInside(dir, target string) error {
+	parent := filepath.Dir(target)
+	if err := os.MkdirAll(parent, 0755); err != nil {
+		return err
+	}
+	realDir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return err
+	}
+	realParent, err := filepath.EvalSymlinks(parent)
+	if err != nil {
+		return err
+	}
+	if rel, err := filepath.Rel(realDir, realParent); err != nil || !(rel == "." || filepath.IsLocal(rel)) {
+		return fmt.Errorf("%s leads outside the worktree", target)
+	}
+	return nil
+}
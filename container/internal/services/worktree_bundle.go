@@ -0,0 +1,225 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/logger"
+)
+
+const (
+	// bundleCacheDir holds generated git bundles inside the state directory, per worktree
+	bundleCacheDir = "bundles"
+	// DefaultMaxBundleSizeMB caps the size of a worktree bundle
+	DefaultMaxBundleSizeMB = 512
+	// bundleRateLimit is how many bundles a single actor may fetch per bundleRateWindow
+	bundleRateLimit  = 10
+	bundleRateWindow = time.Minute
+)
+
+// ErrBundleRateLimited is returned when an actor fetched too many bundles recently
+var ErrBundleRateLimited = errors.New("too many bundle requests, try again later")
+
+// bundleBasePattern matches the commit hashes accepted as bundle base
+var bundleBasePattern = regexp.MustCompile(`^[0-9a-f]{7,64}$`)
+
+// GetMaxBundleSize returns the bundle size cap in bytes from CATNIP_MAX_BUNDLE_MB or the default
+func GetMaxBundleSize() int64 {
+	if sizeStr := os.Getenv("CATNIP_MAX_BUNDLE_MB"); sizeStr != "" {
+		if size, err := strconv.Atoi(sizeStr); err == nil && size > 0 {
+			return int64(size) << 20
+		}
+	}
+	return DefaultMaxBundleSizeMB << 20
+}
+
+// WorktreeBundle is a git bundle of a worktree's branch, ready to be served
+type WorktreeBundle struct {
+	// Absolute path of the cached bundle file
+	Path string
+	// Ref the bundle provides, e.g. refs/heads/feature/login
+	Ref string
+	// Commit the ref points to
+	Tip  string
+	Size int64
+	// Commits the receiver must already have; empty for a bundle with the full history
+	Prerequisites []string
+}
+
+// worktreeBundleRegistry serializes bundle generation and tracks recent fetches per actor
+type worktreeBundleRegistry struct {
+	mu        sync.Mutex // Held while a bundle is generated
+	fetchesMu sync.Mutex
+	fetches   map[string][]time.Time // actor -> fetches within bundleRateWindow
+}
+
+// allow records a fetch by actor unless it already used up bundleRateLimit in the window
+func (r *worktreeBundleRegistry) allow(actor string, now time.Time) bool {
+	r.fetchesMu.Lock()
+	defer r.fetchesMu.Unlock()
+
+	if r.fetches == nil {
+		r.fetches = make(map[string][]time.Time)
+	}
+	recent := r.fetches[actor][:0]
+	for _, at := range r.fetches[actor] {
+		if now.Sub(at) < bundleRateWindow {
+			recent = append(recent, at)
+		}
+	}
+	if len(recent) >= bundleRateLimit {
+		r.fetches[actor] = recent
+		return false
+	}
+	r.fetches[actor] = append(recent, now)
+	return true
+}
+
+// GetWorktreeBundle returns a git bundle of the worktree's branch, generated on demand and
+// cached by tip. With base, a commit the receiver already has, only the commits after it are
+// bundled; bases that aren't ancestors of the tip fall back to a full-history bundle. Shallow
+// repositories can't provide the full history, so their bundles list the shallow boundary as
+// prerequisites rather than producing a bundle git can't unpack. The fetch is recorded in the
+// activity log under actor.
+func (s *GitService) GetWorktreeBundle(worktreeID, base, actor string) (*WorktreeBundle, error) {
+	if base != "" && !bundleBasePattern.MatchString(base) {
+		return nil, fmt.Errorf("invalid base %q, expected a commit hash", base)
+	}
+	if !s.bundles.allow(actor, time.Now()) {
+		return nil, ErrBundleRateLimited
+	}
+
+	worktree, exists := s.stateManager.GetWorktree(worktreeID)
+	if !exists {
+		return nil, fmt.Errorf("worktree %s not found", worktreeID)
+	}
+	if repo, exists := s.stateManager.GetRepository(worktree.RepoID); exists {
+		if err := s.ensureDiskSpace("bundle "+worktree.Name, worktreeEstimateBytes(repo)); err != nil {
+			return nil, err
+		}
+	}
+
+	ref := worktree.Branch
+	if !strings.HasPrefix(ref, "refs/") {
+		ref = "refs/heads/" + ref
+	}
+	output, err := s.operations.ExecuteGit(worktree.Path, "rev-parse", ref+"^{commit}")
+	if err != nil {
+		return nil, fmt.Errorf("branch %s of worktree %s has no commits", worktree.Branch, worktree.Name)
+	}
+	bundle := &WorktreeBundle{Ref: ref, Tip: strings.TrimSpace(string(output))}
+
+	if base != "" {
+		if base == bundle.Tip {
+			logger.Debugf("📦 Bundle base of %s is its tip, sending the full history", worktree.Name)
+		} else if _, err := s.operations.ExecuteGit(worktree.Path, "merge-base", "--is-ancestor", base, bundle.Tip); err != nil {
+			logger.Debugf("📦 Bundle base %s isn't an ancestor of %s, sending the full history", base, worktree.Name)
+		} else {
+			bundle.Prerequisites = []string{base}
+		}
+	}
+	if bundle.Prerequisites == nil {
+		bundle.Prerequisites = s.shallowBoundary(worktree.Path)
+	}
+
+	key := sha256.Sum256([]byte(ref + "\n" + strings.Join(bundle.Prerequisites, "\n")))
+	dir := filepath.Join(s.stateManager.stateDir, bundleCacheDir, worktree.ID)
+	bundle.Path = filepath.Join(dir, bundle.Tip+"-"+hex.EncodeToString(key[:6])+".bundle")
+
+	if err := s.createWorktreeBundle(worktree.Path, dir, bundle); err != nil {
+		return nil, err
+	}
+
+	logger.Infof("📦 %s fetched %s of %s at %s as a git bundle (%d KiB)", actor, ref, worktree.Name, bundle.Tip[:7], bundle.Size>>10)
+	s.stateManager.recordWorktreeActivity(ActivityBundleFetched, worktree, actor,
+		fmt.Sprintf("Fetched %s at %s as a git bundle", worktree.Branch, bundle.Tip[:7]), "")
+	return bundle, nil
+}
+
+// createWorktreeBundle writes bundle.Path unless it is already cached, replacing older bundles
+// of the worktree, and fills in its size
+func (s *GitService) createWorktreeBundle(worktreePath, dir string, bundle *WorktreeBundle) error {
+	s.bundles.mu.Lock()
+	defer s.bundles.mu.Unlock()
+
+	maxSize := GetMaxBundleSize()
+	if info, err := os.Stat(bundle.Path); err == nil {
+		bundle.Size = info.Size()
+		if bundle.Size > maxSize {
+			return fmt.Errorf("bundle is %d MiB, over the %d MiB limit", bundle.Size>>20, maxSize>>20)
+		}
+		return nil
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create bundle directory: %v", err)
+	}
+	tmpPath := bundle.Path + ".tmp"
+	defer os.Remove(tmpPath)
+
+	args := []string{"bundle", "create", tmpPath, bundle.Ref}
+	// Include HEAD when it is the bundled tip, so the bundle can be cloned directly
+	if head, err := s.operations.ExecuteGit(worktreePath, "rev-parse", "HEAD"); err == nil && strings.TrimSpace(string(head)) == bundle.Tip {
+		args = append(args, "HEAD")
+	}
+	for _, prerequisite := range bundle.Prerequisites {
+		args = append(args, "^"+prerequisite)
+	}
+	if output, err := s.operations.ExecuteGit(worktreePath, args...); err != nil {
+		return fmt.Errorf("failed to create bundle: %v: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	info, err := os.Stat(tmpPath)
+	if err != nil {
+		return fmt.Errorf("failed to create bundle: %v", err)
+	}
+	bundle.Size = info.Size()
+	if bundle.Size > maxSize {
+		return fmt.Errorf("bundle is %d MiB, over the %d MiB limit", bundle.Size>>20, maxSize>>20)
+	}
+
+	entries, _ := os.ReadDir(dir)
+	for _, entry := range entries {
+		if path := filepath.Join(dir, entry.Name()); path != tmpPath {
+			_ = os.Remove(path)
+		}
+	}
+	if err := os.Rename(tmpPath, bundle.Path); err != nil {
+		return fmt.Errorf("failed to store bundle: %v", err)
+	}
+	return nil
+}
+
+// shallowBoundary returns the shallow boundary commits of the repository behind a worktree,
+// or nil when it has the full history
+func (s *GitService) shallowBoundary(worktreePath string) []string {
+	output, err := s.operations.ExecuteGit(worktreePath, "rev-parse", "--git-common-dir")
+	if err != nil {
+		return nil
+	}
+	commonDir := strings.TrimSpace(string(output))
+	if !filepath.IsAbs(commonDir) {
+		commonDir = filepath.Join(worktreePath, commonDir)
+	}
+	data, err := os.ReadFile(filepath.Join(commonDir, "shallow"))
+	if err != nil {
+		return nil
+	}
+	return strings.Fields(string(data))
+}
+
+// removeWorktreeBundles deletes the cached bundles of a worktree
+func (s *GitService) removeWorktreeBundles(worktreeID string) {
+	if err := os.RemoveAll(filepath.Join(s.stateManager.stateDir, bundleCacheDir, worktreeID)); err != nil {
+		logger.Debugf("⚠️ Failed to remove bundles of worktree %s: %v", worktreeID, err)
+	}
+}
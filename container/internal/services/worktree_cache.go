@@ -2,6 +2,7 @@ package services
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -13,6 +14,7 @@ import (
 	"github.com/vanpelt/catnip/internal/git"
 	"github.com/vanpelt/catnip/internal/logger"
 	"github.com/vanpelt/catnip/internal/models"
+	"github.com/vanpelt/catnip/internal/recovery"
 )
 
 // WorktreeStatusCache provides fast worktree status lookups with background updates
@@ -21,24 +23,41 @@ type WorktreeStatusCache struct {
 	statuses     map[string]*CachedWorktreeStatus // key: worktreeID
 	operations   git.Operations
 	stateManager *WorktreeStateManager        // Central state manager
+	watchMu      sync.Mutex                   // Guards watchers and pollers, which are demoted under other worktrees' c.mu
 	watchers     map[string]*fsnotify.Watcher // key: worktreePath
+	pollers      map[string]chan struct{}     // key: worktreePath, closed to stop polling a worktree that can't be watched
 	ctx          context.Context
 	cancel       context.CancelFunc
-	updateQueue  chan string                             // worktreeID queue for background updates
-	pathResolver func(string) (string, *models.Worktree) // Resolves worktreeID to path and worktree
+	updateQueue  chan string                                           // worktreeID queue for background updates
+	pathResolver func(string) (string, *models.Worktree)               // Resolves worktreeID to path and worktree
+	driftChecker func(*models.Worktree, string, *CachedWorktreeStatus) // Reconciles branch drift before state is updated
+	fetcher      func(*models.Worktree) error                          // Updates the refs counted against at the full tier
+	onChange     func(worktreeID string)                               // Notified of file changes and forced refreshes
 }
 
 // CachedWorktreeStatus represents cached git status for a worktree
 type CachedWorktreeStatus struct {
 	WorktreeID       string    `json:"worktree_id"`
-	IsDirty          *bool     `json:"is_dirty"`       // nil = not cached yet
-	HasConflicts     *bool     `json:"has_conflicts"`  // nil = not cached yet
-	CommitHash       string    `json:"commit_hash"`    // empty = not cached yet
-	CommitCount      *int      `json:"commit_count"`   // nil = not cached yet
-	CommitsBehind    *int      `json:"commits_behind"` // nil = not cached yet
-	Branch           string    `json:"branch"`         // empty = not cached yet
+	IsDirty          *bool     `json:"is_dirty"`          // nil = not cached yet
+	HasConflicts     *bool     `json:"has_conflicts"`     // nil = not cached yet
+	CommitHash       string    `json:"commit_hash"`       // empty = not cached yet
+	CommitCount      *int      `json:"commit_count"`      // nil = not cached yet
+	CommitsBehind    *int      `json:"commits_behind"`    // nil = not cached yet
+	StashCount       *int      `json:"stash_count"`       // nil = not cached yet
+	CommitSignatures *string   `json:"commit_signatures"` // nil = not cached yet
+	Branch           string    `json:"branch"`            // empty = not cached yet
 	LastUpdated      time.Time `json:"last_updated"`
 	UpdateInProgress bool      `json:"update_in_progress"`
+
+	// Divergence from the pull request base branch, nil = no PR base known or not cached yet
+	PullRequestCommitCount   *int `json:"pull_request_commit_count,omitempty"`
+	PullRequestCommitsBehind *int `json:"pull_request_commits_behind,omitempty"`
+
+	// Which tier last refreshed each group of fields, and when
+	Freshness *models.WorktreeStatusFreshness `json:"status_freshness,omitempty"`
+
+	fingerprint           string // statusFingerprint when the working tree was read, empty = invalidated
+	divergenceFingerprint string // statusFingerprint when commits were counted, empty = invalidated
 }
 
 // NewWorktreeStatusCache creates a new worktree status cache
@@ -50,13 +69,14 @@ func NewWorktreeStatusCache(operations git.Operations, stateManager *WorktreeSta
 		operations:   operations,
 		stateManager: stateManager,
 		watchers:     make(map[string]*fsnotify.Watcher),
+		pollers:      make(map[string]chan struct{}),
 		ctx:          ctx,
 		cancel:       cancel,
 		updateQueue:  make(chan string, 100), // Buffer for update requests
 	}
 
-	// Start background update worker
-	go cache.backgroundUpdateWorker()
+	// Start background update worker, restarted should it panic
+	recovery.SafeGoRestartable("worktree-status-refresher", cache.backgroundUpdateWorker, time.Second)
 
 	return cache
 }
@@ -64,11 +84,7 @@ func NewWorktreeStatusCache(operations git.Operations, stateManager *WorktreeSta
 // EnhanceWorktreeWithCache enhances a worktree with cached status if available
 // This is the key method that enables fast ListWorktrees responses
 func (c *WorktreeStatusCache) EnhanceWorktreeWithCache(worktree *models.Worktree) {
-	c.mu.RLock()
-	cached, exists := c.statuses[worktree.ID]
-	c.mu.RUnlock()
-
-	if !exists {
+	if !c.applyCachedStatus(worktree) {
 		// No cache entry - create empty one and queue for background update
 		c.mu.Lock()
 		c.statuses[worktree.ID] = &CachedWorktreeStatus{
@@ -83,7 +99,18 @@ func (c *WorktreeStatusCache) EnhanceWorktreeWithCache(worktree *models.Worktree
 		default:
 			// Queue full - update will happen on next periodic cycle
 		}
-		return
+	}
+}
+
+// applyCachedStatus copies cached status onto a worktree without queueing any refresh.
+// It returns false when the worktree has no cache entry yet.
+func (c *WorktreeStatusCache) applyCachedStatus(worktree *models.Worktree) bool {
+	c.mu.RLock()
+	cached, exists := c.statuses[worktree.ID]
+	c.mu.RUnlock()
+
+	if !exists {
+		return false
 	}
 
 	// Apply cached values to worktree (only if cached)
@@ -102,11 +129,25 @@ func (c *WorktreeStatusCache) EnhanceWorktreeWithCache(worktree *models.Worktree
 	if cached.CommitsBehind != nil {
 		worktree.CommitsBehind = *cached.CommitsBehind
 	}
+	if cached.PullRequestCommitCount != nil {
+		worktree.PullRequestCommitCount = *cached.PullRequestCommitCount
+	}
+	if cached.PullRequestCommitsBehind != nil {
+		worktree.PullRequestCommitsBehind = *cached.PullRequestCommitsBehind
+	}
+	if cached.StashCount != nil {
+		worktree.StashCount = *cached.StashCount
+	}
+	if cached.CommitSignatures != nil {
+		worktree.CommitSignatures = *cached.CommitSignatures
+	}
 	// Only update branch field if worktree hasn't been renamed
 	// If renamed, Branch field shows nice name for UI, don't overwrite with actual git ref
 	if cached.Branch != "" && !worktree.HasBeenRenamed {
 		worktree.Branch = cached.Branch
 	}
+	worktree.StatusFreshness = worktree.StatusFreshness.Merged(cached.Freshness)
+	return true
 }
 
 // IsStatusCached returns true if we have cached status for a worktree
@@ -152,15 +193,14 @@ func (c *WorktreeStatusCache) RemoveWorktree(worktreeID string, worktreePath str
 	defer c.mu.Unlock()
 
 	delete(c.statuses, worktreeID)
-
-	if watcher, exists := c.watchers[worktreePath]; exists {
-		watcher.Close()
-		delete(c.watchers, worktreePath)
-	}
+	c.stopWatchingWorktree(worktreePath)
+	fileWatches.release(WatchSubsystemStatusCache, worktreePath)
 }
 
 // ForceRefresh forces an immediate update of a worktree's status
 func (c *WorktreeStatusCache) ForceRefresh(worktreeID string) {
+	c.Invalidate(worktreeID)
+	c.notifyChange(worktreeID)
 	select {
 	case c.updateQueue <- worktreeID:
 	default:
@@ -168,16 +208,51 @@ func (c *WorktreeStatusCache) ForceRefresh(worktreeID string) {
 	}
 }
 
-// startWatchingWorktree sets up filesystem watching for a worktree
+// Invalidate marks a worktree's cached status as stale, so its next refresh runs git even when
+// HEAD and the index look unchanged
+func (c *WorktreeStatusCache) Invalidate(worktreeID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if cached, exists := c.statuses[worktreeID]; exists {
+		cached.fingerprint = ""
+		cached.divergenceFingerprint = ""
+	}
+}
+
+// Refresh updates a worktree's status at tier now, returning once it is stored. With force set
+// the status is read from git even if nothing seems to have changed since it was cached.
+func (c *WorktreeStatusCache) Refresh(worktreeID string, tier models.StatusTier, force bool) {
+	c.processBatchUpdates(map[string]bool{worktreeID: true}, tier, force)
+}
+
+// QueueRefreshAll queues a background refresh of every cached status at the standard tier
+func (c *WorktreeStatusCache) QueueRefreshAll() {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for worktreeID := range c.statuses {
+		select {
+		case c.updateQueue <- worktreeID:
+		default:
+			return // Queue full - the rest are refreshed on the next periodic cycle
+		}
+	}
+}
+
+// startWatchingWorktree sets up filesystem watching for a worktree, polling it instead when
+// the kernel is out of watches
 func (c *WorktreeStatusCache) startWatchingWorktree(worktreeID, worktreePath string) {
 	gitDir := filepath.Join(worktreePath, ".git")
 	if _, err := os.Stat(gitDir); os.IsNotExist(err) {
 		return // Not a git repository
 	}
 
-	watcher, err := fsnotify.NewWatcher()
+	watcher, err := fileWatches.newWatcher(WatchSubsystemStatusCache, worktreePath)
 	if err != nil {
 		logger.Warnf("⚠️ Failed to create watcher for %s: %v", worktreePath, err)
+		if isWatchLimitError(err) {
+			fileWatches.degrade(WatchSubsystemStatusCache, worktreePath, WatchDegradedLimit, err)
+			c.startPollingWorktree(worktreeID, worktreePath)
+		}
 		return
 	}
 
@@ -191,13 +266,29 @@ func (c *WorktreeStatusCache) startWatchingWorktree(worktreeID, worktreePath str
 
 	for _, path := range watchPaths {
 		if _, err := os.Stat(path); err == nil {
-			if err := watcher.Add(path); err != nil {
+			if err := fileWatches.add(WatchSubsystemStatusCache, worktreePath, watcher, path); err != nil {
 				logger.Warnf("⚠️ Failed to watch %s: %v", path, err)
+				if isWatchLimitError(err) {
+					watcher.Close()
+					fileWatches.degrade(WatchSubsystemStatusCache, worktreePath, WatchDegradedLimit, err)
+					c.startPollingWorktree(worktreeID, worktreePath)
+					return
+				}
 			}
 		}
 	}
 
+	c.watchMu.Lock()
+	c.stopPollingLocked(worktreePath)
+	if previous, exists := c.watchers[worktreePath]; exists {
+		previous.Close()
+	}
 	c.watchers[worktreePath] = watcher
+	c.watchMu.Unlock()
+	fileWatches.setDemote(WatchSubsystemStatusCache, worktreePath, func() {
+		c.stopWatchingWorktree(worktreePath)
+		c.startPollingWorktree(worktreeID, worktreePath)
+	})
 
 	// Start goroutine to handle events
 	go func() {
@@ -213,14 +304,7 @@ func (c *WorktreeStatusCache) startWatchingWorktree(worktreeID, worktreePath str
 				// Filter relevant events
 				if c.isRelevantFileEvent(event) {
 					logger.Debugf("🔍 Git change detected in %s: %s", worktreePath, event.Name)
-
-					// Debounce rapid file changes (configurable via CATNIP_CACHE_DEBOUNCE_MS)
-					time.AfterFunc(getDebounceInterval(), func() {
-						select {
-						case c.updateQueue <- worktreeID:
-						default:
-						}
-					})
+					c.handleWorktreeChange(worktreeID)
 				}
 
 			case err, ok := <-watcher.Errors:
@@ -236,6 +320,88 @@ func (c *WorktreeStatusCache) startWatchingWorktree(worktreeID, worktreePath str
 	}()
 }
 
+// handleWorktreeChange invalidates the status of a worktree whose files changed and queues a
+// refresh once the changes settle
+func (c *WorktreeStatusCache) handleWorktreeChange(worktreeID string) {
+	c.Invalidate(worktreeID)
+	c.notifyChange(worktreeID)
+
+	// Debounce rapid file changes (configurable via CATNIP_CACHE_DEBOUNCE_MS)
+	time.AfterFunc(getDebounceInterval(), func() {
+		select {
+		case c.updateQueue <- worktreeID:
+		default:
+		}
+	})
+}
+
+// startPollingWorktree polls a worktree that can't be watched for changes to its git state and
+// top-level entries. A worktree with a title event since polling started is watched again,
+// rationing watches of less active worktrees.
+func (c *WorktreeStatusCache) startPollingWorktree(worktreeID, worktreePath string) {
+	c.watchMu.Lock()
+	defer c.watchMu.Unlock()
+	if _, polling := c.pollers[worktreePath]; polling {
+		return
+	}
+	stop := make(chan struct{})
+	c.pollers[worktreePath] = stop
+
+	fingerprint := func() string {
+		root := pathFingerprint(worktreePath)
+		if root == "" {
+			return ""
+		}
+		return root + statusFingerprint(worktreePath)
+	}
+	rewatch := func() {
+		since := fileWatches.degradedSince(WatchSubsystemStatusCache, worktreePath)
+		if since.IsZero() || !fileWatches.lastActivity(worktreePath).After(since) {
+			return
+		}
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if _, exists := c.statuses[worktreeID]; exists {
+			c.startWatchingWorktree(worktreeID, worktreePath)
+		}
+	}
+	recovery.SafeGo("worktree-status-poller:"+worktreePath, func() {
+		pollForChanges(mergeStop(stop, c.ctx.Done()), fingerprint, func() { c.handleWorktreeChange(worktreeID) }, rewatch)
+	})
+}
+
+// stopWatchingWorktree closes the watcher and stops the poller of a worktree
+func (c *WorktreeStatusCache) stopWatchingWorktree(worktreePath string) {
+	c.watchMu.Lock()
+	defer c.watchMu.Unlock()
+	if watcher, exists := c.watchers[worktreePath]; exists {
+		watcher.Close()
+		delete(c.watchers, worktreePath)
+	}
+	c.stopPollingLocked(worktreePath)
+}
+
+// stopPollingLocked stops polling a worktree (must be called with c.watchMu held)
+func (c *WorktreeStatusCache) stopPollingLocked(worktreePath string) {
+	if stop, exists := c.pollers[worktreePath]; exists {
+		close(stop)
+		delete(c.pollers, worktreePath)
+	}
+}
+
+// mergeStop returns a channel closed once either a or b is closed
+func mergeStop(a <-chan struct{}, b <-chan struct{}) <-chan struct{} {
+	merged := make(chan struct{})
+	go func() {
+		defer close(merged)
+		select {
+		case <-a:
+		case <-b:
+		}
+	}()
+	return merged
+}
+
 // isRelevantFileEvent determines if a filesystem event should trigger a cache update
 func (c *WorktreeStatusCache) isRelevantFileEvent(event fsnotify.Event) bool {
 	name := filepath.Base(event.Name)
@@ -278,6 +444,28 @@ func getBatchInterval() time.Duration {
 	return 100 * time.Millisecond // Default: 100ms
 }
 
+// getStatusWorkers returns how many worktree statuses are refreshed concurrently, configurable
+// via CATNIP_CACHE_WORKERS
+func getStatusWorkers() int {
+	if envWorkers := os.Getenv("CATNIP_CACHE_WORKERS"); envWorkers != "" {
+		if workers, err := strconv.Atoi(envWorkers); err == nil && workers > 0 {
+			return workers
+		}
+	}
+	return 6 // Default: 6
+}
+
+// getStatusCacheTTL returns how long a cached status is trusted while HEAD and the index are
+// unchanged, configurable via CATNIP_STATUS_CACHE_TTL in seconds (0 always runs git)
+func getStatusCacheTTL() time.Duration {
+	if envSeconds := os.Getenv("CATNIP_STATUS_CACHE_TTL"); envSeconds != "" {
+		if seconds, err := strconv.Atoi(envSeconds); err == nil && seconds >= 0 {
+			return time.Duration(seconds) * time.Second
+		}
+	}
+	return 2 * time.Minute // Default: 2 minutes
+}
+
 // backgroundUpdateWorker processes the update queue
 func (c *WorktreeStatusCache) backgroundUpdateWorker() {
 	ticker := time.NewTicker(30 * time.Second) // Periodic full refresh
@@ -306,13 +494,13 @@ func (c *WorktreeStatusCache) backgroundUpdateWorker() {
 
 		case <-batchTimer.C:
 			if len(pendingUpdates) > 0 {
-				c.processBatchUpdates(pendingUpdates)
+				c.processBatchUpdates(pendingUpdates, models.StatusTierStandard, false)
 				pendingUpdates = make(map[string]bool)
 			}
 
 		case <-ticker.C:
 			// Periodic refresh of all cached statuses
-			c.refreshAllStatuses()
+			c.refreshAllStatuses(models.StatusTierStandard, false)
 
 		case <-c.ctx.Done():
 			return
@@ -320,56 +508,97 @@ func (c *WorktreeStatusCache) backgroundUpdateWorker() {
 	}
 }
 
-// processBatchUpdates processes a batch of worktree updates efficiently
-func (c *WorktreeStatusCache) processBatchUpdates(worktreeIDs map[string]bool) {
-	updates := make(map[string]*CachedWorktreeStatus)
+// processBatchUpdates refreshes a batch of worktrees at tier on a bounded pool of workers, then
+// writes the statuses that changed to the state manager in one go. Unless force is set,
+// worktrees whose cached status is still fresh at that tier are skipped.
+func (c *WorktreeStatusCache) processBatchUpdates(worktreeIDs map[string]bool, tier models.StatusTier, force bool) {
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		updates = make(map[string]*CachedWorktreeStatus)
+		slots   = make(chan struct{}, getStatusWorkers())
+	)
 
 	for worktreeID := range worktreeIDs {
-		if status := c.updateWorktreeStatus(worktreeID); status != nil {
-			updates[worktreeID] = status
-		}
+		worktreeID := worktreeID
+		slots <- struct{}{}
+		wg.Add(1)
+		recovery.SafeGoWithCleanup("worktree-status:"+worktreeID, func() {
+			if status := c.updateWorktreeStatus(worktreeID, tier, force); status != nil {
+				mu.Lock()
+				updates[worktreeID] = status
+				mu.Unlock()
+			}
+		}, func() {
+			<-slots
+			wg.Done()
+		})
 	}
+	wg.Wait()
 
-	if len(updates) > 0 {
-		// Update state manager with batch updates
-		if c.stateManager != nil {
-			// Convert cached updates to state manager format
-			stateUpdates := make(map[string]map[string]interface{})
-			for worktreeID, cached := range updates {
-				stateUpdate := make(map[string]interface{})
-				if cached.IsDirty != nil {
-					stateUpdate["is_dirty"] = *cached.IsDirty
-				}
-				if cached.HasConflicts != nil {
-					stateUpdate["has_conflicts"] = *cached.HasConflicts
-				}
-				if cached.CommitHash != "" {
-					stateUpdate["commit_hash"] = cached.CommitHash
-				}
-				if cached.CommitCount != nil {
-					stateUpdate["commit_count"] = *cached.CommitCount
-				}
-				if cached.CommitsBehind != nil {
-					stateUpdate["commits_behind"] = *cached.CommitsBehind
-				}
-				if cached.Branch != "" {
-					stateUpdate["branch"] = cached.Branch
-				}
-				if len(stateUpdate) > 0 {
-					stateUpdates[worktreeID] = stateUpdate
-				}
-			}
-			if len(stateUpdates) > 0 {
-				if err := c.stateManager.BatchUpdateWorktrees(stateUpdates); err != nil {
-					logger.Warnf("⚠️ Failed to batch update worktrees in state: %v", err)
-				}
-			}
+	if len(updates) == 0 || c.stateManager == nil {
+		return
+	}
+
+	// Convert cached updates to state manager format, skipping what the state already has
+	stateUpdates := make(map[string]map[string]interface{})
+	for worktreeID, cached := range updates {
+		if stateUpdate := changedStatusFields(c.stateManager, worktreeID, cached); len(stateUpdate) > 0 {
+			stateUpdates[worktreeID] = stateUpdate
 		}
 	}
+	if len(stateUpdates) > 0 {
+		if err := c.stateManager.BatchUpdateWorktrees(stateUpdates); err != nil {
+			logger.Warnf("⚠️ Failed to batch update worktrees in state: %v", err)
+		}
+	}
+}
+
+// changedStatusFields returns the fields of a refreshed status that differ from the worktree in
+// state, in state manager update format
+func changedStatusFields(stateManager *WorktreeStateManager, worktreeID string, cached *CachedWorktreeStatus) map[string]interface{} {
+	worktree, exists := stateManager.GetWorktree(worktreeID)
+	if !exists {
+		return nil
+	}
+
+	stateUpdate := make(map[string]interface{})
+	if cached.IsDirty != nil && *cached.IsDirty != worktree.IsDirty {
+		stateUpdate["is_dirty"] = *cached.IsDirty
+	}
+	if cached.HasConflicts != nil && *cached.HasConflicts != worktree.HasConflicts {
+		stateUpdate["has_conflicts"] = *cached.HasConflicts
+	}
+	if cached.CommitHash != "" && cached.CommitHash != worktree.CommitHash {
+		stateUpdate["commit_hash"] = cached.CommitHash
+	}
+	if cached.CommitCount != nil && *cached.CommitCount != worktree.CommitCount {
+		stateUpdate["commit_count"] = *cached.CommitCount
+	}
+	if cached.CommitsBehind != nil && *cached.CommitsBehind != worktree.CommitsBehind {
+		stateUpdate["commits_behind"] = *cached.CommitsBehind
+	}
+	if cached.PullRequestCommitCount != nil && *cached.PullRequestCommitCount != worktree.PullRequestCommitCount {
+		stateUpdate["pull_request_commit_count"] = *cached.PullRequestCommitCount
+	}
+	if cached.PullRequestCommitsBehind != nil && *cached.PullRequestCommitsBehind != worktree.PullRequestCommitsBehind {
+		stateUpdate["pull_request_commits_behind"] = *cached.PullRequestCommitsBehind
+	}
+	if cached.StashCount != nil && *cached.StashCount != worktree.StashCount {
+		stateUpdate["stash_count"] = *cached.StashCount
+	}
+	if cached.CommitSignatures != nil && *cached.CommitSignatures != worktree.CommitSignatures {
+		stateUpdate["commit_signatures"] = *cached.CommitSignatures
+	}
+	if cached.Branch != "" && cached.Branch != worktree.Branch {
+		stateUpdate["branch"] = cached.Branch
+	}
+	return stateUpdate
 }
 
-// updateWorktreeStatus updates a single worktree's cached status
-func (c *WorktreeStatusCache) updateWorktreeStatus(worktreeID string) *CachedWorktreeStatus {
+// updateWorktreeStatus updates a single worktree's cached status, returning nil if it was
+// skipped
+func (c *WorktreeStatusCache) updateWorktreeStatus(worktreeID string, tier models.StatusTier, force bool) *CachedWorktreeStatus {
 	c.mu.RLock()
 	cached, exists := c.statuses[worktreeID]
 	if !exists {
@@ -396,7 +625,7 @@ func (c *WorktreeStatusCache) updateWorktreeStatus(worktreeID string) *CachedWor
 
 	// We need the actual worktree path - this requires lookup from GitService
 	// For now, we'll implement this as a callback pattern
-	return c.updateWorktreeStatusInternal(worktreeID, cached)
+	return c.updateWorktreeStatusInternal(worktreeID, cached, tier, force)
 }
 
 // SetWorktreePathResolver allows the GitService to provide worktree path resolution
@@ -406,9 +635,46 @@ func (c *WorktreeStatusCache) SetWorktreePathResolver(resolver func(string) (str
 	c.pathResolver = resolver
 }
 
-// updateWorktreeStatusInternal performs the actual git operations
-func (c *WorktreeStatusCache) updateWorktreeStatusInternal(worktreeID string, cached *CachedWorktreeStatus) *CachedWorktreeStatus {
-	if c.pathResolver == nil {
+// SetChangeListener registers a function called as soon as files of a worktree change or its
+// status is refreshed after a git operation, before the debounced status update runs
+func (c *WorktreeStatusCache) SetChangeListener(listener func(worktreeID string)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onChange = listener
+}
+
+// notifyChange calls the change listener, if any
+func (c *WorktreeStatusCache) notifyChange(worktreeID string) {
+	c.mu.RLock()
+	listener := c.onChange
+	c.mu.RUnlock()
+	if listener != nil {
+		listener(worktreeID)
+	}
+}
+
+// SetBranchDriftChecker allows the GitService to reconcile the checked-out branch with state
+// before a refreshed status is stored. The checker may clear the status branch to keep it
+// from overwriting state.
+func (c *WorktreeStatusCache) SetBranchDriftChecker(checker func(worktree *models.Worktree, worktreePath string, status *CachedWorktreeStatus)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.driftChecker = checker
+}
+
+// SetStatusFetcher allows the GitService to fetch the branches a worktree is counted against
+// before a full tier refresh
+func (c *WorktreeStatusCache) SetStatusFetcher(fetcher func(worktree *models.Worktree) error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.fetcher = fetcher
+}
+
+// updateWorktreeStatusInternal performs the git operations of tier (see models.StatusFieldTiers),
+// unless the fields of tier were read within the TTL and HEAD, the branch it points to and the
+// index haven't been touched since. Full tier refreshes always run, the remote is never fresh.
+func (c *WorktreeStatusCache) updateWorktreeStatusInternal(worktreeID string, cached *CachedWorktreeStatus, tier models.StatusTier, force bool) *CachedWorktreeStatus {
+	if c.pathResolver == nil || !tier.Includes(models.StatusTierCheap) {
 		return cached // Can't update without path resolver
 	}
 
@@ -417,16 +683,33 @@ func (c *WorktreeStatusCache) updateWorktreeStatusInternal(worktreeID string, ca
 		return cached // Worktree not found
 	}
 
+	// Taken before running git, so changes made meanwhile are picked up by the next refresh
+	fingerprint := statusFingerprint(worktreePath)
+	c.mu.RLock()
+	freshness := cached.Freshness
+	fresh := !force && !tier.Includes(models.StatusTierFull) && fingerprint != "" && freshness != nil &&
+		cached.fingerprint == fingerprint && isRecent(freshness.WorkingTree)
+	if fresh && tier.Includes(models.StatusTierStandard) {
+		fresh = cached.divergenceFingerprint == fingerprint && isRecent(freshness.Divergence)
+	}
+	c.mu.RUnlock()
+	if fresh {
+		return nil
+	}
+
 	// Perform the expensive git operations
 
-	// Check if dirty
-	isDirty := c.operations.IsDirty(worktreePath)
+	// Check if dirty and for conflicts
+	isDirty, hasConflicts := c.operations.GetWorkingTreeStatus(worktreePath)
 	cached.IsDirty = &isDirty
-
-	// Check for conflicts
-	hasConflicts := c.operations.HasConflicts(worktreePath)
 	cached.HasConflicts = &hasConflicts
 
+	// Count the work stashed for the worktree
+	if entries, err := c.operations.StashList(worktreePath); err == nil {
+		stashCount := len(git.WorktreeStashes(entries, worktreeID))
+		cached.StashCount = &stashCount
+	}
+
 	// Get current commit hash
 	if commitHash, err := c.operations.GetCommitHash(worktreePath, "HEAD"); err == nil {
 		cached.CommitHash = commitHash
@@ -437,21 +720,50 @@ func (c *WorktreeStatusCache) updateWorktreeStatusInternal(worktreeID string, ca
 		cached.Branch = branch
 	}
 
+	if c.driftChecker != nil {
+		c.driftChecker(worktree, worktreePath, cached)
+	}
+
+	fetched := true
+	if tier.Includes(models.StatusTierFull) && c.fetcher != nil {
+		if err := c.fetcher(worktree); err != nil {
+			logger.Warnf("⚠️ Failed to fetch for the status of %s, counting against the refs at hand: %v", worktree.Name, err)
+			fetched = false
+		}
+	}
+	if tier.Includes(models.StatusTierStandard) {
+		c.countDivergence(worktree, worktreePath, cached)
+	}
+
+	cached.LastUpdated = time.Now()
+
+	// Store updated status
+	c.mu.Lock()
+	cached.Freshness = cached.Freshness.Stamped(tier, cached.LastUpdated, fetched)
+	cached.fingerprint = fingerprint
+	if tier.Includes(models.StatusTierStandard) {
+		cached.divergenceFingerprint = fingerprint
+	}
+	c.statuses[worktreeID] = cached
+	c.mu.Unlock()
+
+	return cached
+}
+
+// isRecent reports whether a group of status fields was refreshed within the status cache TTL
+func isRecent(freshness *models.StatusFreshness) bool {
+	return freshness != nil && time.Since(freshness.RefreshedAt) < getStatusCacheTTL()
+}
+
+// countDivergence counts the commits a worktree is ahead and behind its source branch and its
+// pull request base
+func (c *WorktreeStatusCache) countDivergence(worktree *models.Worktree, worktreePath string, cached *CachedWorktreeStatus) {
 	// Count commits ahead and behind (only if we have source branch info)
 	if worktree.SourceBranch != "" {
-		sourceRef := worktree.SourceBranch
-		if !strings.HasPrefix(sourceRef, "origin/") {
-			// For local repos, use the branch directly since it's the source of truth
-			// For remote repos, try origin/ prefix first, fallback to local branch
-			if !strings.Contains(worktree.RepoID, "local/") {
-				remoteRef := "origin/" + sourceRef
-				// Check if remote reference exists by trying to resolve it
-				if _, err := c.operations.ExecuteGit(worktreePath, "rev-parse", "--verify", remoteRef); err == nil {
-					sourceRef = remoteRef
-				}
-				// If remote ref doesn't exist, sourceRef remains as local branch (fallback)
-			}
-			// For local repos, use sourceRef as-is (no prefix needed)
+		// Worktrees created at a tag or commit count from it
+		sourceRef := worktree.SourceRef
+		if sourceRef == "" {
+			sourceRef = c.comparisonRef(worktree, worktreePath, worktree.SourceBranch)
 		}
 
 		// Count commits ahead
@@ -463,29 +775,48 @@ func (c *WorktreeStatusCache) updateWorktreeStatusInternal(worktreeID string, ca
 		if count, err := c.operations.GetCommitCount(worktreePath, "HEAD", sourceRef); err == nil {
 			cached.CommitsBehind = &count
 		}
-	}
-
-	cached.LastUpdated = time.Now()
-
-	// Removed noisy worktree update logs
 
-	// Store updated status
-	c.mu.Lock()
-	c.statuses[worktreeID] = cached
-	c.mu.Unlock()
+		if signatures, err := commitSignatures(c.operations, worktreePath, sourceRef+"..HEAD"); err == nil {
+			summary := summarizeSignatures(signatures)
+			cached.CommitSignatures = &summary
+		}
+	}
 
-	// Update state manager with individual status
-	if c.stateManager != nil {
-		if err := c.stateManager.UpdateWorktreeStatus(worktreeID, cached); err != nil {
-			logger.Warnf("⚠️ Failed to update worktree status in state: %v", err)
+	// A pull request may target another branch than the source branch, e.g. after being
+	// retargeted on GitHub, so its divergence is counted separately
+	if worktree.PullRequestURL != "" && worktree.PullRequestBaseBranch != "" {
+		baseRef := c.comparisonRef(worktree, worktreePath, worktree.PullRequestBaseBranch)
+		if count, err := c.operations.GetCommitCount(worktreePath, baseRef, "HEAD"); err == nil {
+			cached.PullRequestCommitCount = &count
+		}
+		if count, err := c.operations.GetCommitCount(worktreePath, "HEAD", baseRef); err == nil {
+			cached.PullRequestCommitsBehind = &count
 		}
 	}
+}
 
-	return cached
+// comparisonRef returns the reference ahead and behind counts compare a branch against
+func (c *WorktreeStatusCache) comparisonRef(worktree *models.Worktree, worktreePath, branch string) string {
+	if strings.HasPrefix(branch, "origin/") || strings.Contains(worktree.RepoID, "local/") {
+		// For local repos, use the branch directly since it's the source of truth
+		return branch
+	}
+	// For remote repos, try origin/ prefix first, fallback to local branch
+	remoteRef := "origin/" + branch
+	if _, err := c.operations.ExecuteGit(worktreePath, "rev-parse", "--verify", remoteRef); err == nil {
+		return remoteRef
+	}
+	return branch
+}
+
+// RefreshAll refreshes every cached status at tier now, returning once the results are stored.
+// With force set statuses are read from git even if nothing seems to have changed.
+func (c *WorktreeStatusCache) RefreshAll(tier models.StatusTier, force bool) {
+	c.refreshAllStatuses(tier, force)
 }
 
 // refreshAllStatuses refreshes all cached statuses periodically
-func (c *WorktreeStatusCache) refreshAllStatuses() {
+func (c *WorktreeStatusCache) refreshAllStatuses(tier models.StatusTier, force bool) {
 	c.mu.RLock()
 	worktreeIDs := make([]string, 0, len(c.statuses))
 	for worktreeID := range c.statuses {
@@ -503,24 +834,85 @@ func (c *WorktreeStatusCache) refreshAllStatuses() {
 		pendingUpdates[worktreeID] = true
 	}
 
-	c.processBatchUpdates(pendingUpdates)
+	c.processBatchUpdates(pendingUpdates, tier, force)
+}
+
+// statusFingerprint summarizes the modification times and sizes of the files git touches when
+// a worktree's HEAD, branch or index change, or returns "" if its git directory can't be found
+func statusFingerprint(worktreePath string) string {
+	gitDir := filepath.Join(worktreePath, ".git")
+	info, err := os.Stat(gitDir)
+	if err != nil {
+		return ""
+	}
+	if !info.IsDir() {
+		// Linked worktrees have a .git file pointing at their git directory
+		content, err := os.ReadFile(gitDir)
+		if err != nil || !strings.HasPrefix(string(content), "gitdir: ") {
+			return ""
+		}
+		gitDir = strings.TrimSpace(strings.TrimPrefix(string(content), "gitdir: "))
+		if !filepath.IsAbs(gitDir) {
+			gitDir = filepath.Join(worktreePath, gitDir)
+		}
+	}
+
+	// Branches live in the common directory shared by all worktrees of a repository
+	commonDir := gitDir
+	if content, err := os.ReadFile(filepath.Join(gitDir, "commondir")); err == nil {
+		commonDir = strings.TrimSpace(string(content))
+		if !filepath.IsAbs(commonDir) {
+			commonDir = filepath.Join(gitDir, commonDir)
+		}
+	}
+
+	files := []string{
+		filepath.Join(gitDir, "HEAD"),
+		filepath.Join(gitDir, "index"),
+		filepath.Join(gitDir, "MERGE_HEAD"),
+		filepath.Join(commonDir, "packed-refs"),
+	}
+	if head, err := os.ReadFile(filepath.Join(gitDir, "HEAD")); err == nil && strings.HasPrefix(string(head), "ref: ") {
+		ref := strings.TrimSpace(strings.TrimPrefix(string(head), "ref: "))
+		files = append(files, filepath.Join(commonDir, filepath.FromSlash(ref)))
+	}
+
+	var fingerprint strings.Builder
+	for _, file := range files {
+		if info, err := os.Stat(file); err == nil {
+			fmt.Fprintf(&fingerprint, "%d:%d;", info.ModTime().UnixNano(), info.Size())
+		} else {
+			fingerprint.WriteString("-;")
+		}
+	}
+	return fingerprint.String()
 }
 
 // Stop shuts down the cache and all watchers
 func (c *WorktreeStatusCache) Stop() {
 	c.cancel()
 
-	c.mu.Lock()
-	defer c.mu.Unlock()
+	c.watchMu.Lock()
+	defer c.watchMu.Unlock()
 
-	for _, watcher := range c.watchers {
+	for path, watcher := range c.watchers {
 		watcher.Close()
+		fileWatches.release(WatchSubsystemStatusCache, path)
 	}
 	c.watchers = make(map[string]*fsnotify.Watcher)
+	for path, stop := range c.pollers {
+		close(stop)
+		fileWatches.release(WatchSubsystemStatusCache, path)
+	}
+	c.pollers = make(map[string]chan struct{})
 }
 
 // GetCacheStats returns cache statistics for monitoring
 func (c *WorktreeStatusCache) GetCacheStats() map[string]interface{} {
+	c.watchMu.Lock()
+	activeWatchers, pollingWorktrees := len(c.watchers), len(c.pollers)
+	c.watchMu.Unlock()
+
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
@@ -534,9 +926,10 @@ func (c *WorktreeStatusCache) GetCacheStats() map[string]interface{} {
 	}
 
 	return map[string]interface{}{
-		"total_entries":   totalEntries,
-		"cached_entries":  cachedEntries,
-		"cache_ratio":     float64(cachedEntries) / float64(totalEntries),
-		"active_watchers": len(c.watchers),
+		"total_entries":     totalEntries,
+		"cached_entries":    cachedEntries,
+		"cache_ratio":       float64(cachedEntries) / float64(totalEntries),
+		"active_watchers":   activeWatchers,
+		"polling_worktrees": pollingWorktrees,
 	}
 }
@@ -0,0 +1,273 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/git"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// batchRecorder records batch status events, ignores worktree creation and updates and doesn't
+// expect other events
+type batchRecorder struct {
+	EventsEmitter
+	mu      sync.Mutex
+	batches []map[string]*CachedWorktreeStatus
+}
+
+func (r *batchRecorder) EmitWorktreeBatchUpdated(updates map[string]*CachedWorktreeStatus) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.batches = append(r.batches, updates)
+}
+
+func (r *batchRecorder) EmitWorktreeCreated(*models.Worktree) {}
+
+func (r *batchRecorder) EmitWorktreeUpdated(string, map[string]interface{}) {}
+
+func TestProcessBatchUpdates(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	t.Setenv("CATNIP_CACHE_WORKERS", "3")
+
+	root := t.TempDir()
+	events := &batchRecorder{}
+	stateManager := NewWorktreeStateManager(filepath.Join(root, "state"), events)
+	t.Cleanup(stateManager.Stop)
+	require.NoError(t, stateManager.AddRepository(&models.Repository{ID: "local/repo", Path: root}))
+
+	cache := NewWorktreeStatusCache(git.NewOperations(), stateManager)
+	t.Cleanup(cache.Stop)
+
+	// Count concurrent status refreshes through the path resolver
+	var running, peak int32
+	cache.SetWorktreePathResolver(func(worktreeID string) (string, *models.Worktree) {
+		now := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			seen := atomic.LoadInt32(&peak)
+			if now <= seen || atomic.CompareAndSwapInt32(&peak, seen, now) {
+				break
+			}
+		}
+		worktree, _ := stateManager.GetWorktree(worktreeID)
+		return worktree.Path, worktree
+	})
+
+	ids := make(map[string]bool)
+	for i := 0; i < 8; i++ {
+		id := fmt.Sprintf("wt-%d", i)
+		path := initTestRepo(t, filepath.Join(root, id), nil)
+		require.NoError(t, stateManager.AddWorktree(&models.Worktree{
+			ID: id, RepoID: "local/repo", Name: id, Path: path, Branch: "main",
+		}))
+		cache.statuses[id] = &CachedWorktreeStatus{WorktreeID: id}
+		ids[id] = true
+	}
+	events.batches = nil
+
+	cache.processBatchUpdates(ids, models.StatusTierStandard, false)
+	assert.LessOrEqual(t, peak, int32(3), "refreshes are bounded by CATNIP_CACHE_WORKERS")
+	require.Len(t, events.batches, 1, "results are written back in one batch")
+	assert.Len(t, events.batches[0], 8)
+	for id := range ids {
+		worktree, _ := stateManager.GetWorktree(id)
+		assert.Equal(t, runTestGit(t, worktree.Path, "rev-parse", "HEAD"), worktree.CommitHash)
+	}
+
+	// Nothing changed, nothing is written
+	cache.processBatchUpdates(ids, models.StatusTierStandard, false)
+	assert.Len(t, events.batches, 1)
+
+	// Only the worktree that changed is written
+	runTestGit(t, filepath.Join(root, "wt-3"), "commit", "--allow-empty", "-m", "Work")
+	cache.processBatchUpdates(ids, models.StatusTierStandard, false)
+	require.Len(t, events.batches, 2)
+	assert.Len(t, events.batches[1], 1)
+	assert.Contains(t, events.batches[1], "wt-3")
+}
+
+func TestStatusCacheFreshness(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	root := t.TempDir()
+	events := &batchRecorder{}
+	stateManager := NewWorktreeStateManager(filepath.Join(root, "state"), events)
+	t.Cleanup(stateManager.Stop)
+	repoPath := initTestRepo(t, filepath.Join(root, "repo"), nil)
+	worktreePath := filepath.Join(root, "felix")
+	runTestGit(t, repoPath, "worktree", "add", "-b", "refs/catnip/felix", worktreePath)
+	require.NoError(t, stateManager.AddRepository(&models.Repository{ID: "local/repo", Path: repoPath}))
+	require.NoError(t, stateManager.AddWorktree(&models.Worktree{
+		ID: "wt-felix", RepoID: "local/repo", Name: "repo/felix", Path: worktreePath, Branch: "refs/catnip/felix", SourceBranch: "main",
+	}))
+
+	cache := NewWorktreeStatusCache(git.NewOperations(), stateManager)
+	t.Cleanup(cache.Stop)
+	var reads int32
+	cache.SetWorktreePathResolver(func(worktreeID string) (string, *models.Worktree) {
+		worktree, _ := stateManager.GetWorktree(worktreeID)
+		return worktree.Path, worktree
+	})
+	cache.SetBranchDriftChecker(func(*models.Worktree, string, *CachedWorktreeStatus) {
+		atomic.AddInt32(&reads, 1)
+	})
+	cache.statuses["wt-felix"] = &CachedWorktreeStatus{WorktreeID: "wt-felix"}
+
+	cache.Refresh("wt-felix", models.StatusTierStandard, false)
+	require.Equal(t, int32(1), reads)
+
+	// Unchanged HEAD, branch and index within the TTL skip git
+	cache.Refresh("wt-felix", models.StatusTierStandard, false)
+	assert.Equal(t, int32(1), reads)
+
+	// Commits move the branch of the linked worktree
+	runTestGit(t, worktreePath, "commit", "--allow-empty", "-m", "Work")
+	cache.Refresh("wt-felix", models.StatusTierStandard, false)
+	assert.Equal(t, int32(2), reads)
+	worktree, _ := stateManager.GetWorktree("wt-felix")
+	assert.Equal(t, 1, worktree.CommitCount)
+
+	// Untracked files aren't part of the fingerprint, until invalidated
+	require.NoError(t, os.WriteFile(filepath.Join(worktreePath, "wip.txt"), []byte("wip\n"), 0644))
+	cache.Refresh("wt-felix", models.StatusTierStandard, false)
+	assert.Equal(t, int32(2), reads)
+	cache.Invalidate("wt-felix")
+	cache.Refresh("wt-felix", models.StatusTierStandard, false)
+	assert.Equal(t, int32(3), reads)
+	worktree, _ = stateManager.GetWorktree("wt-felix")
+	assert.True(t, worktree.IsDirty)
+
+	// Forced refreshes and expired entries always run git
+	cache.Refresh("wt-felix", models.StatusTierStandard, true)
+	assert.Equal(t, int32(4), reads)
+	t.Setenv("CATNIP_STATUS_CACHE_TTL", "0")
+	cache.Refresh("wt-felix", models.StatusTierStandard, false)
+	assert.Equal(t, int32(5), reads)
+}
+
+func TestStatusCacheTiers(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	root := t.TempDir()
+	events := &batchRecorder{}
+	stateManager := NewWorktreeStateManager(filepath.Join(root, "state"), events)
+	t.Cleanup(stateManager.Stop)
+	repoPath := initTestRepo(t, filepath.Join(root, "repo"), nil)
+	worktreePath := filepath.Join(root, "felix")
+	runTestGit(t, repoPath, "worktree", "add", "-b", "refs/catnip/felix", worktreePath)
+	runTestGit(t, worktreePath, "commit", "--allow-empty", "-m", "Work")
+	runTestGit(t, repoPath, "commit", "--allow-empty", "-m", "Main work")
+	require.NoError(t, os.WriteFile(filepath.Join(worktreePath, "wip.txt"), []byte("wip\n"), 0644))
+	require.NoError(t, stateManager.AddRepository(&models.Repository{ID: "local/repo", Path: repoPath}))
+	require.NoError(t, stateManager.AddWorktree(&models.Worktree{
+		ID: "wt-felix", RepoID: "local/repo", Name: "repo/felix", Path: worktreePath, Branch: "refs/catnip/felix", SourceBranch: "main",
+		PullRequestURL: "https://github.com/owner/repo/pull/1", PullRequestBaseBranch: "main",
+	}))
+
+	cache := NewWorktreeStatusCache(git.NewOperations(), stateManager)
+	t.Cleanup(cache.Stop)
+	var reads, fetches int32
+	cache.SetWorktreePathResolver(func(worktreeID string) (string, *models.Worktree) {
+		worktree, _ := stateManager.GetWorktree(worktreeID)
+		return worktree.Path, worktree
+	})
+	cache.SetBranchDriftChecker(func(*models.Worktree, string, *CachedWorktreeStatus) {
+		atomic.AddInt32(&reads, 1)
+	})
+	cache.SetStatusFetcher(func(*models.Worktree) error {
+		atomic.AddInt32(&fetches, 1)
+		return nil
+	})
+
+	// Every field starts out stale in state, so refreshed fields are told apart by their value
+	stale := map[string]interface{}{
+		"is_dirty": false, "has_conflicts": true, "stash_count": 7, "branch": "stale", "commit_hash": "stale",
+		"commit_count": 99, "commits_behind": 99, "pull_request_commit_count": 99, "pull_request_commits_behind": 99,
+	}
+	require.Len(t, stale, len(models.StatusFieldTiers))
+
+	for _, tier := range []models.StatusTier{models.StatusTierNone, models.StatusTierCheap, models.StatusTierStandard, models.StatusTierFull} {
+		t.Run(string(tier), func(t *testing.T) {
+			require.NoError(t, stateManager.UpdateWorktree("wt-felix", stale))
+			cache.statuses["wt-felix"] = &CachedWorktreeStatus{WorktreeID: "wt-felix"}
+			atomic.StoreInt32(&fetches, 0)
+
+			cache.Refresh("wt-felix", tier, false)
+			worktree, _ := stateManager.GetWorktree("wt-felix")
+			data, err := json.Marshal(worktree)
+			require.NoError(t, err)
+			var after map[string]interface{}
+			require.NoError(t, json.Unmarshal(data, &after))
+			for field, fieldTier := range models.StatusFieldTiers {
+				staleValue := fmt.Sprint(stale[field])
+				if tier.Includes(fieldTier) {
+					assert.NotEqual(t, staleValue, fmt.Sprint(after[field]), "%s is refreshed at the %s tier", field, fieldTier)
+				} else {
+					assert.Equal(t, staleValue, fmt.Sprint(after[field]), "%s is left alone below the %s tier", field, fieldTier)
+				}
+			}
+
+			if tier.Includes(models.StatusTierFull) {
+				assert.Equal(t, int32(1), fetches)
+			} else {
+				assert.Zero(t, fetches, "only the full tier fetches")
+			}
+			if tier.Includes(models.StatusTierStandard) {
+				assert.Equal(t, 1, worktree.CommitCount)
+				assert.Equal(t, 1, worktree.CommitsBehind)
+			}
+
+			listed := *worktree
+			listed.StatusFreshness = nil
+			cache.applyCachedStatus(&listed)
+			if tier == models.StatusTierNone {
+				assert.Nil(t, listed.StatusFreshness)
+				return
+			}
+			require.NotNil(t, listed.StatusFreshness)
+			require.NotNil(t, listed.StatusFreshness.WorkingTree)
+			assert.Equal(t, tier, listed.StatusFreshness.WorkingTree.Tier)
+			assert.Equal(t, tier.Includes(models.StatusTierStandard), listed.StatusFreshness.Divergence != nil)
+			assert.Equal(t, tier.Includes(models.StatusTierFull), listed.StatusFreshness.Remote != nil)
+		})
+	}
+
+	t.Run("FreshOnlyAtTheTierRead", func(t *testing.T) {
+		cache.statuses["wt-felix"] = &CachedWorktreeStatus{WorktreeID: "wt-felix"}
+		atomic.StoreInt32(&reads, 0)
+
+		cache.Refresh("wt-felix", models.StatusTierCheap, false)
+		cache.Refresh("wt-felix", models.StatusTierCheap, false)
+		assert.Equal(t, int32(1), reads, "cheap fields are fresh")
+		cache.Refresh("wt-felix", models.StatusTierStandard, false)
+		assert.Equal(t, int32(2), reads, "counts were never read")
+		cache.Refresh("wt-felix", models.StatusTierStandard, false)
+		cache.Refresh("wt-felix", models.StatusTierCheap, false)
+		assert.Equal(t, int32(2), reads, "a standard refresh covers the cheap tier")
+		cache.Refresh("wt-felix", models.StatusTierFull, false)
+		assert.Equal(t, int32(3), reads, "the remote is never fresh")
+
+		// A commit after the counts were read makes them stale even when a cheap refresh ran since
+		runTestGit(t, worktreePath, "commit", "--allow-empty", "-m", "More work")
+		cache.Refresh("wt-felix", models.StatusTierCheap, false)
+		cache.Refresh("wt-felix", models.StatusTierStandard, false)
+		assert.Equal(t, int32(5), reads)
+		worktree, _ := stateManager.GetWorktree("wt-felix")
+		assert.Equal(t, 2, worktree.CommitCount)
+	})
+}
@@ -0,0 +1,132 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/git"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// defaultWorktreeCommitsLimit is the page size of GetWorktreeCommits when none is given
+const defaultWorktreeCommitsLimit = 50
+
+// worktreeCommitFormat starts each commit of git log with a record separator, so the --numstat
+// lines following a commit stay with it
+const worktreeCommitFormat = "%x1e%H%x00%an%x00%ae%x00%aI%x00" + commitSignatureFormat + "%x00%s"
+
+// GetWorktreeCommits returns a page of the commits a worktree made since branching from its
+// source branch, newest first. A limit of 0 uses defaultWorktreeCommitsLimit. With withStats
+// each commit lists the lines it changed per file.
+func (s *GitService) GetWorktreeCommits(worktreeID string, limit, offset int, withStats bool) (*models.WorktreeCommits, error) {
+	s.mu.RLock()
+	worktree, exists := s.stateManager.GetWorktree(worktreeID)
+	s.mu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("worktree %s not found", worktreeID)
+	}
+	if limit < 0 || offset < 0 {
+		return nil, fmt.Errorf("invalid page, limit and offset must not be negative")
+	}
+	if limit == 0 {
+		limit = defaultWorktreeCommitsLimit
+	}
+
+	sourceRef := s.getSourceRef(worktree)
+	total, err := s.operations.GetCommitCount(worktree.Path, sourceRef, "HEAD")
+	if err != nil {
+		return nil, fmt.Errorf("failed to count commits since %s: %v", sourceRef, err)
+	}
+
+	args := []string{"log", "--format=" + worktreeCommitFormat, fmt.Sprintf("--skip=%d", offset), fmt.Sprintf("--max-count=%d", limit)}
+	if withStats {
+		args = append(args, "--numstat", "--no-renames")
+	}
+	output, err := runSignatureLog(s.operations, worktree.Path, append(args, sourceRef+"..HEAD", "--")...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list commits since %s: %v", sourceRef, err)
+	}
+
+	commits := parseWorktreeCommits(string(output))
+	if withStats {
+		s.classifyCommitFiles(worktree.Path, commits)
+	}
+	return &models.WorktreeCommits{
+		WorktreeID: worktree.ID,
+		SourceRef:  sourceRef,
+		Commits:    commits,
+		Total:      total,
+		Offset:     offset,
+		Limit:      limit,
+		HasMore:    offset+len(commits) < total,
+	}, nil
+}
+
+// parseWorktreeCommits parses git log output in worktreeCommitFormat, with or without --numstat
+func parseWorktreeCommits(output string) []models.WorktreeCommit {
+	commits := []models.WorktreeCommit{}
+	for _, record := range strings.Split(output, "\x1e") {
+		lines := strings.Split(record, "\n")
+		fields := strings.SplitN(lines[0], "\x00", 8)
+		if len(fields) < 8 {
+			continue
+		}
+		timestamp, _ := time.Parse(time.RFC3339, fields[3])
+		_, isCheckpoint := git.ParseCheckpointSubject(fields[7])
+		commit := models.WorktreeCommit{
+			Hash:         fields[0],
+			Author:       fields[1],
+			AuthorEmail:  fields[2],
+			Timestamp:    timestamp,
+			Subject:      fields[7],
+			IsCheckpoint: isCheckpoint,
+			Signature:    parseCommitSignature(fields[4], fields[5], fields[6]),
+		}
+
+		for _, line := range lines[1:] {
+			stat := strings.SplitN(line, "\t", 3)
+			if len(stat) < 3 {
+				continue
+			}
+			file := models.CommitFileStat{Path: stat[2], Binary: stat[0] == "-"}
+			file.Additions, _ = strconv.Atoi(stat[0])
+			file.Deletions, _ = strconv.Atoi(stat[1])
+			commit.Files = append(commit.Files, file)
+		}
+		commits = append(commits, commit)
+	}
+	return commits
+}
+
+// classifyCommitFiles flags the generated files of commits listed with stats and sums each
+// commit's lines in total and in files written by hand
+func (s *GitService) classifyCommitFiles(worktreePath string, commits []models.WorktreeCommit) {
+	var paths []string
+	for _, commit := range commits {
+		for _, file := range commit.Files {
+			paths = append(paths, file.Path)
+		}
+	}
+	generated := s.generatedFiles(worktreePath, paths)
+
+	for i := range commits {
+		commit := &commits[i]
+		commit.Stats, commit.AuthoredStats = &models.DiffStats{}, &models.DiffStats{}
+		for j := range commit.Files {
+			file := &commit.Files[j]
+			file.Generated = generated[file.Path]
+			stats := []*models.DiffStats{commit.Stats}
+			if !file.Generated {
+				stats = append(stats, commit.AuthoredStats)
+			}
+			for _, stat := range stats {
+				stat.Files++
+				stat.Additions += file.Additions
+				stat.Deletions += file.Deletions
+			}
+		}
+	}
+}
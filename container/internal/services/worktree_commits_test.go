@@ -0,0 +1,82 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+func TestGetWorktreeCommits(t *testing.T) {
+	s, stateManager, _, worktreePath := newRecreateTestService(t)
+
+	commit := func(file, content, subject, date string) string {
+		require.NoError(t, os.WriteFile(filepath.Join(worktreePath, file), []byte(content), 0644))
+		runTestGit(t, worktreePath, "add", file)
+		runTestGit(t, worktreePath, "commit", "-m", subject, "--date", date)
+		return runTestGit(t, worktreePath, "rev-parse", "HEAD")
+	}
+	commit("login.ts", "one\n", "Add login checkpoint: 1", "2025-01-01T09:00:00Z")
+	commit("login.ts", "one\ntwo\n", "Add login checkpoint: 2", "2025-01-01T09:05:00Z")
+	titled := commit("logo.png", "\x00\x01", "Add logo", "2025-01-01T09:10:00Z")
+	commit("app.txt", "v2\n", "Fix tests checkpoint: 1", "2025-01-01T09:20:00Z")
+
+	page, err := s.GetWorktreeCommits("wt-felix", 3, 0, false)
+	require.NoError(t, err)
+	assert.Equal(t, "main", page.SourceRef)
+	assert.Equal(t, 4, page.Total)
+	assert.True(t, page.HasMore)
+	require.Len(t, page.Commits, 3)
+	assert.Equal(t, "Fix tests checkpoint: 1", page.Commits[0].Subject, "newest first")
+	assert.True(t, page.Commits[0].IsCheckpoint)
+	assert.False(t, page.Commits[1].IsCheckpoint)
+	assert.Equal(t, "Test", page.Commits[1].Author)
+	assert.Equal(t, time.Date(2025, 1, 1, 9, 10, 0, 0, time.UTC), page.Commits[1].Timestamp.UTC())
+	assert.Nil(t, page.Commits[1].Files, "stats are opt-in")
+
+	page, err = s.GetWorktreeCommits("wt-felix", 3, 3, true)
+	require.NoError(t, err)
+	assert.False(t, page.HasMore)
+	require.Len(t, page.Commits, 1)
+	assert.Equal(t, []models.CommitFileStat{{Path: "login.ts", Additions: 1}}, page.Commits[0].Files)
+
+	page, err = s.GetWorktreeCommits("wt-felix", 0, 0, true)
+	require.NoError(t, err)
+	assert.Equal(t, defaultWorktreeCommitsLimit, page.Limit)
+	assert.Equal(t, []models.CommitFileStat{{Path: "logo.png", Binary: true}}, page.Commits[1].Files)
+
+	_, err = s.GetWorktreeCommits("wt-felix", -1, 0, false)
+	assert.Error(t, err)
+	_, err = s.GetWorktreeCommits("missing", 0, 0, false)
+	assert.ErrorContains(t, err, "not found")
+
+	t.Run("SessionTitles", func(t *testing.T) {
+		at := func(clock string) time.Time {
+			parsed, err := time.Parse(time.RFC3339, "2025-01-01T"+clock+"Z")
+			require.NoError(t, err)
+			return parsed
+		}
+		sessions := &SessionService{stateDir: t.TempDir(), activeSessions: map[string]*ActiveSessionInfo{worktreePath: {
+			TitleHistory: []models.TitleEntry{
+				{Title: "Add login", Timestamp: at("08:59:00")},
+				{Title: "Add login checkpoint: 1", Timestamp: at("09:00:00.400")},
+				{Title: "Design a logo", Timestamp: at("09:12:00"), CommitHash: titled},
+				{Title: "Fix tests", Timestamp: at("09:15:00")},
+			},
+		}}}
+		monitor := NewClaudeMonitorService(s, sessions, nil, stateManager)
+
+		page, err := s.GetWorktreeCommits("wt-felix", 0, 0, false)
+		require.NoError(t, err)
+		monitor.AnnotateCommitSessions(worktreePath, page.Commits)
+		var titles []string
+		for _, commit := range page.Commits {
+			titles = append(titles, commit.SessionTitle)
+		}
+		assert.Equal(t, []string{"Fix tests", "Design a logo", "Add login", "Add login"}, titles)
+	})
+}
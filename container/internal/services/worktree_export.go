@@ -0,0 +1,444 @@
+package services
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/config"
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+const (
+	// exportManifestDir holds the manifests of directory exports inside the state directory
+	exportManifestDir = "exports"
+	// exportPathBatch bounds the number of paths passed to a single git archive call
+	exportPathBatch = 500
+	// gitModeSubmodule is the ls-tree mode of submodule entries, which have no content to export
+	gitModeSubmodule = "160000"
+)
+
+// Export formats reported in WorktreeExportResult
+const (
+	ExportFormatDirectory = "directory"
+	ExportFormatTar       = "tar"
+)
+
+// WorktreeExportResult describes a finished worktree export
+type WorktreeExportResult struct {
+	// Absolute path of the exported directory or tarball
+	Path   string `json:"path"`
+	Format string `json:"format"`
+	// Git tree the export was materialized from
+	Tree string `json:"tree"`
+	// Number of files in the exported tree
+	Files int `json:"files"`
+	// Files written and removed by this run; a full export writes every file
+	Written int `json:"written"`
+	Removed int `json:"removed"`
+	// Whether only the changes since the previous export were applied
+	Incremental bool `json:"incremental"`
+	// Why the exported worktree was created
+	CreationContext *models.CreationContext `json:"creation_context,omitempty"`
+}
+
+// exportManifest records what a directory export last wrote, so the next run only touches changes
+type exportManifest struct {
+	Dest       string            `json:"dest"`
+	Tree       string            `json:"tree"`
+	Files      map[string]string `json:"files"` // path -> "<mode> <blob>"
+	ExportedAt time.Time         `json:"exported_at"`
+	// Creation context of the exported worktree, so an export can be traced back to its session
+	CreationContext *models.CreationContext `json:"creation_context,omitempty"`
+}
+
+// getExportRoot returns the directory exports are confined to, configurable via CATNIP_EXPORT_ROOT
+func getExportRoot() string {
+	if root := os.Getenv("CATNIP_EXPORT_ROOT"); root != "" {
+		return root
+	}
+	return filepath.Join(config.Runtime.VolumeDir, "exports")
+}
+
+// resolveExportPath resolves dest (relative paths are taken relative to the export root) and
+// refuses destinations outside the export root, including ones escaping it through symlinks
+func resolveExportPath(root, dest string) (string, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return "", fmt.Errorf("invalid export root: %v", err)
+	}
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return "", fmt.Errorf("failed to create export root: %v", err)
+	}
+	if realRoot, err := filepath.EvalSymlinks(root); err == nil {
+		root = realRoot
+	}
+
+	if dest == "" {
+		return "", fmt.Errorf("export destination is required")
+	}
+	if !filepath.IsAbs(dest) {
+		dest = filepath.Join(root, dest)
+	}
+	dest = filepath.Clean(dest)
+
+	// Resolve symlinks of the deepest existing ancestor so links can't point the export elsewhere
+	existing, rest := dest, ""
+	for {
+		if _, err := os.Lstat(existing); err == nil {
+			break
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			break
+		}
+		rest = filepath.Join(filepath.Base(existing), rest)
+		existing = parent
+	}
+	if realExisting, err := filepath.EvalSymlinks(existing); err == nil {
+		dest = filepath.Join(realExisting, rest)
+	}
+
+	rel, err := filepath.Rel(root, dest)
+	if err != nil || rel == "." || !filepath.IsLocal(rel) {
+		return "", fmt.Errorf("export destination %s is outside the export root %s", dest, root)
+	}
+	return dest, nil
+}
+
+// snapshotWorktreeTree writes the worktree's current content to a git tree using a throwaway
+// index, so uncommitted changes are included and the worktree's own index is left untouched.
+// Untracked files that aren't ignored are included when includeUntracked is set. Snapshots
+// are paused while the workspace volume is nearly full.
+func (s *GitService) snapshotWorktreeTree(worktree *models.Worktree, includeUntracked bool) (string, error) {
+	if err := s.pausedForDiskSpace("snapshot " + worktree.Name); err != nil {
+		return "", err
+	}
+	dir := filepath.Join(s.stateManager.stateDir, exportManifestDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create export directory: %v", err)
+	}
+
+	indexFile := filepath.Join(dir, worktree.ID+".index")
+	defer os.Remove(indexFile)
+	env := []string{"GIT_INDEX_FILE=" + indexFile}
+
+	if _, err := s.operations.ExecuteGitWithEnv(worktree.Path, env, 0, "read-tree", "HEAD"); err != nil {
+		// Branches without commits start from an empty tree
+		if _, err := s.operations.ExecuteGitWithEnv(worktree.Path, env, 0, "read-tree", "--empty"); err != nil {
+			return "", fmt.Errorf("failed to snapshot worktree: %v", err)
+		}
+	}
+	addArgs := []string{"add", "-u"}
+	if includeUntracked {
+		addArgs = []string{"add", "-A"}
+	}
+	if _, err := s.operations.ExecuteGitWithEnv(worktree.Path, env, 0, addArgs...); err != nil {
+		return "", fmt.Errorf("failed to snapshot worktree: %v", err)
+	}
+	output, err := s.operations.ExecuteGitWithEnv(worktree.Path, env, 0, "write-tree")
+	if err != nil {
+		return "", fmt.Errorf("failed to snapshot worktree: %v", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// listTreeFiles returns the files of a tree as path -> "<mode> <blob>", skipping submodules
+func (s *GitService) listTreeFiles(worktreePath, tree string) (map[string]string, error) {
+	output, err := s.operations.ExecuteGit(worktreePath, "ls-tree", "-r", "-z", "--full-tree", tree)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list exported tree: %v", err)
+	}
+
+	files := make(map[string]string)
+	for _, line := range strings.Split(string(output), "\x00") {
+		info, path, found := strings.Cut(line, "\t")
+		if !found {
+			continue
+		}
+		fields := strings.Fields(info) // mode, type, object
+		if len(fields) != 3 || fields[0] == gitModeSubmodule {
+			continue
+		}
+		files[path] = fields[0] + " " + fields[2]
+	}
+	return files, nil
+}
+
+// ExportWorktreeTree materializes the current content of a worktree without any .git metadata,
+// preserving file modes and symlinks, for tools that can't handle worktree .git files. When dest
+// ends in .tar a tarball is written; otherwise dest is a directory that is updated incrementally:
+// repeated exports only write files whose content or mode changed and remove deleted files.
+// Destinations must be inside the export root (CATNIP_EXPORT_ROOT).
+func (s *GitService) ExportWorktreeTree(worktreeID, dest string, includeUntracked bool) (*WorktreeExportResult, error) {
+	worktree, exists := s.stateManager.GetWorktree(worktreeID)
+	if !exists {
+		return nil, fmt.Errorf("worktree %s not found", worktreeID)
+	}
+
+	destPath, err := resolveExportPath(getExportRoot(), dest)
+	if err != nil {
+		return nil, err
+	}
+	if rel, err := filepath.Rel(worktree.Path, destPath); err == nil && filepath.IsLocal(rel) {
+		return nil, fmt.Errorf("export destination %s is inside the worktree", destPath)
+	}
+
+	tree, err := s.snapshotWorktreeTree(worktree, includeUntracked)
+	if err != nil {
+		return nil, err
+	}
+	files, err := s.listTreeFiles(worktree.Path, tree)
+	if err != nil {
+		return nil, err
+	}
+	result := &WorktreeExportResult{Path: destPath, Tree: tree, Files: len(files), CreationContext: worktree.CreationContext}
+
+	if strings.HasSuffix(destPath, ".tar") {
+		result.Format = ExportFormatTar
+		if err := s.exportTarball(worktree.Path, tree, destPath); err != nil {
+			return nil, err
+		}
+		result.Written = len(files)
+	} else {
+		result.Format = ExportFormatDirectory
+		if err := s.exportDirectory(worktree, tree, files, destPath, result); err != nil {
+			return nil, err
+		}
+	}
+
+	logger.Infof("📦 Exported worktree %s to %s (%d written, %d removed)", worktree.Name, destPath, result.Written, result.Removed)
+	return result, nil
+}
+
+// exportTarball writes the tree as a tarball, replacing the destination atomically
+func (s *GitService) exportTarball(worktreePath, tree, destPath string) error {
+	archive, err := s.operations.ExecuteGit(worktreePath, "archive", "--format=tar", tree)
+	if err != nil {
+		return fmt.Errorf("failed to archive worktree: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+		return fmt.Errorf("failed to create export directory: %v", err)
+	}
+	tmpPath := destPath + ".tmp"
+	if err := os.WriteFile(tmpPath, archive, 0644); err != nil {
+		return fmt.Errorf("failed to write export: %v", err)
+	}
+	if err := os.Rename(tmpPath, destPath); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("failed to write export: %v", err)
+	}
+	return nil
+}
+
+// exportDirectory brings a directory export up to date with the tree, using the manifest of the
+// previous export to limit the work to changed files
+func (s *GitService) exportDirectory(worktree *models.Worktree, tree string, files map[string]string, destPath string, result *WorktreeExportResult) error {
+	manifestPath := s.exportManifestPath(worktree.ID, destPath)
+	previous := loadExportManifest(manifestPath)
+	if _, err := os.Stat(destPath); err != nil {
+		previous = nil // Destination was removed; start over
+	}
+	if err := os.MkdirAll(destPath, 0755); err != nil {
+		return fmt.Errorf("failed to create export directory: %v", err)
+	}
+
+	var changed []string
+	if previous != nil {
+		result.Incremental = true
+		for path, entry := range files {
+			if previous.Files[path] != entry {
+				changed = append(changed, path)
+			}
+		}
+		for path := range previous.Files {
+			if _, exists := files[path]; exists {
+				continue
+			}
+			if err := removeExportedFile(destPath, path); err != nil {
+				return err
+			}
+			result.Removed++
+		}
+	}
+
+	if previous == nil {
+		if err := s.extractArchive(worktree.Path, tree, destPath, nil); err != nil {
+			return err
+		}
+		result.Written = len(files)
+	} else {
+		for start := 0; start < len(changed); start += exportPathBatch {
+			end := min(start+exportPathBatch, len(changed))
+			if err := s.extractArchive(worktree.Path, tree, destPath, changed[start:end]); err != nil {
+				return err
+			}
+		}
+		result.Written = len(changed)
+	}
+
+	manifest := &exportManifest{Dest: destPath, Tree: tree, Files: files, ExportedAt: time.Now(), CreationContext: worktree.CreationContext}
+	return saveExportManifest(manifestPath, manifest)
+}
+
+// extractArchive extracts the given paths (or the whole tree when paths is nil) into destPath
+func (s *GitService) extractArchive(worktreePath, tree, destPath string, paths []string) error {
+	args := []string{"--literal-pathspecs", "archive", "--format=tar", tree}
+	if paths != nil {
+		args = append(append(args, "--"), paths...)
+	}
+	archive, err := s.operations.ExecuteGit(worktreePath, args...)
+	if err != nil {
+		return fmt.Errorf("failed to archive worktree: %v", err)
+	}
+
+	reader := tar.NewReader(bytes.NewReader(archive))
+	for {
+		header, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read archive: %v", err)
+		}
+		if err := writeExportEntry(destPath, header, reader); err != nil {
+			return err
+		}
+	}
+}
+
+// writeExportEntry writes a single archive entry below destPath, replacing whatever was there
+func writeExportEntry(destPath string, header *tar.Header, content io.Reader) error {
+	name := strings.TrimSuffix(header.Name, "/")
+	if header.Typeflag != tar.TypeDir && header.Typeflag != tar.TypeReg && header.Typeflag != tar.TypeSymlink {
+		return nil // Global pax headers carry the commit id only
+	}
+	if !filepath.IsLocal(name) {
+		return fmt.Errorf("refusing to export unsafe path %q", header.Name)
+	}
+	target := filepath.Join(destPath, name)
+	if err := ensureNoSymlinkParents(destPath, name); err != nil {
+		return err
+	}
+
+	if header.Typeflag == tar.TypeDir {
+		if info, err := os.Lstat(target); err == nil && !info.IsDir() {
+			_ = os.Remove(target)
+		}
+		return os.MkdirAll(target, 0755)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return fmt.Errorf("failed to create export directory: %v", err)
+	}
+	if err := os.RemoveAll(target); err != nil {
+		return fmt.Errorf("failed to replace %s: %v", target, err)
+	}
+
+	if header.Typeflag == tar.TypeSymlink {
+		return os.Symlink(header.Linkname, target)
+	}
+
+	mode := os.FileMode(0644)
+	if header.Mode&0111 != 0 {
+		mode = 0755
+	}
+	file, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %v", target, err)
+	}
+	if _, err := io.Copy(file, content); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write %s: %v", target, err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %v", target, err)
+	}
+	return os.Chmod(target, mode)
+}
+
+// ensureNoSymlinkParents removes symlinks standing in for parent directories of name, which a
+// previous export could have left behind, so writes can't be redirected outside destPath
+func ensureNoSymlinkParents(destPath, name string) error {
+	current := destPath
+	parts := strings.Split(filepath.Dir(name), string(filepath.Separator))
+	for _, part := range parts {
+		if part == "." {
+			continue
+		}
+		current = filepath.Join(current, part)
+		info, err := os.Lstat(current)
+		if err != nil {
+			return nil // Doesn't exist yet, so neither do deeper components
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			if err := os.Remove(current); err != nil {
+				return fmt.Errorf("refusing to export through symlink %s: %v", current, err)
+			}
+			return nil
+		}
+	}
+	return nil
+}
+
+// removeExportedFile deletes a file written by a previous export and prunes empty directories
+func removeExportedFile(destPath, name string) error {
+	if !filepath.IsLocal(name) {
+		return nil
+	}
+	target := filepath.Join(destPath, name)
+	if err := os.Remove(target); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove %s: %v", target, err)
+	}
+	for dir := filepath.Dir(target); dir != destPath && strings.HasPrefix(dir, destPath); dir = filepath.Dir(dir) {
+		if os.Remove(dir) != nil {
+			break // Not empty
+		}
+	}
+	return nil
+}
+
+// exportManifestPath returns where the manifest of an export destination is stored
+func (s *GitService) exportManifestPath(worktreeID, destPath string) string {
+	sum := sha256.Sum256([]byte(destPath))
+	return filepath.Join(s.stateManager.stateDir, exportManifestDir, worktreeID, hex.EncodeToString(sum[:8])+".json")
+}
+
+// loadExportManifest reads an export manifest, returning nil when there is none
+func loadExportManifest(path string) *exportManifest {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var manifest exportManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		logger.Warnf("⚠️  Ignoring unreadable export manifest %s: %v", path, err)
+		return nil
+	}
+	return &manifest
+}
+
+// saveExportManifest writes an export manifest
+func saveExportManifest(path string, manifest *exportManifest) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create export manifest directory: %v", err)
+	}
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return fmt.Errorf("failed to encode export manifest: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write export manifest: %v", err)
+	}
+	return nil
+}
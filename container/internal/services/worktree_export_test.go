@@ -0,0 +1,111 @@
+package services
+
+import (
+	"archive/tar"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+func TestResolveExportPath(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	require.NoError(t, os.Symlink(outside, filepath.Join(root, "escape")))
+
+	dest, err := resolveExportPath(root, "index/felix")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(root, "index", "felix"), dest)
+
+	for _, bad := range []string{"", ".", "../elsewhere", outside, "escape/felix"} {
+		_, err := resolveExportPath(root, bad)
+		assert.Error(t, err, "destination %q must be refused", bad)
+	}
+}
+
+func TestExportWorktreeTree(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	root := t.TempDir()
+	exportRoot := filepath.Join(root, "exports")
+	t.Setenv("CATNIP_EXPORT_ROOT", exportRoot)
+
+	repoPath := initUnbornTestRepo(t, filepath.Join(root, "repo"))
+	require.NoError(t, os.WriteFile(filepath.Join(repoPath, "README.md"), []byte("# felix\n"), 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(repoPath, "bin"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(repoPath, "bin", "run.sh"), []byte("#!/bin/sh\n"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(repoPath, "old.txt"), []byte("old\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(repoPath, ".gitignore"), []byte("*.log\n"), 0644))
+	require.NoError(t, os.Symlink("README.md", filepath.Join(repoPath, "link.md")))
+	runTestGit(t, repoPath, "add", "-A")
+	runTestGit(t, repoPath, "commit", "-m", "Initial commit")
+
+	// Uncommitted, untracked and ignored content
+	require.NoError(t, os.WriteFile(filepath.Join(repoPath, "README.md"), []byte("# felix v2\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(repoPath, "notes.txt"), []byte("draft\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(repoPath, "debug.log"), []byte("noise\n"), 0644))
+
+	s := newTestGitService(t, root)
+	stateManager := s.stateManager
+	require.NoError(t, stateManager.AddRepository(&models.Repository{ID: "local/repo", Path: repoPath}))
+	require.NoError(t, stateManager.AddWorktree(&models.Worktree{ID: "wt-felix", RepoID: "local/repo", Name: "felix", Path: repoPath}))
+
+	result, err := s.ExportWorktreeTree("wt-felix", "felix", false)
+	require.NoError(t, err)
+	dest := filepath.Join(exportRoot, "felix")
+	assert.False(t, result.Incremental)
+	assert.Equal(t, 5, result.Files)
+
+	content, err := os.ReadFile(filepath.Join(dest, "README.md"))
+	require.NoError(t, err)
+	assert.Equal(t, "# felix v2\n", string(content), "uncommitted changes are exported")
+	info, err := os.Stat(filepath.Join(dest, "bin", "run.sh"))
+	require.NoError(t, err)
+	assert.NotZero(t, info.Mode()&0111, "executable bit is preserved")
+	target, err := os.Readlink(filepath.Join(dest, "link.md"))
+	require.NoError(t, err)
+	assert.Equal(t, "README.md", target)
+	assert.NoFileExists(t, filepath.Join(dest, "notes.txt"))
+	assert.NoFileExists(t, filepath.Join(dest, ".git"))
+
+	// Incremental run with untracked files: only the changes are applied
+	require.NoError(t, os.Remove(filepath.Join(repoPath, "old.txt")))
+	result, err = s.ExportWorktreeTree("wt-felix", "felix", true)
+	require.NoError(t, err)
+	assert.True(t, result.Incremental)
+	assert.Equal(t, 1, result.Written)
+	assert.Equal(t, 1, result.Removed)
+	assert.FileExists(t, filepath.Join(dest, "notes.txt"))
+	assert.NoFileExists(t, filepath.Join(dest, "old.txt"))
+	assert.NoFileExists(t, filepath.Join(dest, "debug.log"), "ignored files are never exported")
+
+	result, err = s.ExportWorktreeTree("wt-felix", "felix", true)
+	require.NoError(t, err)
+	assert.Zero(t, result.Written)
+
+	// Tarball export
+	result, err = s.ExportWorktreeTree("wt-felix", "felix.tar", false)
+	require.NoError(t, err)
+	assert.Equal(t, ExportFormatTar, result.Format)
+	file, err := os.Open(filepath.Join(exportRoot, "felix.tar"))
+	require.NoError(t, err)
+	defer file.Close()
+	var names []string
+	reader := tar.NewReader(file)
+	for header, err := reader.Next(); err == nil; header, err = reader.Next() {
+		if header.Typeflag != tar.TypeXGlobalHeader {
+			names = append(names, header.Name)
+		}
+	}
+	assert.Contains(t, names, "bin/run.sh")
+	assert.NotContains(t, names, "notes.txt")
+
+	_, err = s.ExportWorktreeTree("wt-felix", filepath.Join(root, "elsewhere"), false)
+	assert.Error(t, err)
+}
@@ -0,0 +1,436 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// Outcomes of a member in a worktree group operation
+const (
+	WorktreeGroupSucceeded = "succeeded"
+	WorktreeGroupSkipped   = "skipped"
+	WorktreeGroupFailed    = "failed"
+)
+
+// worktreeGroupSection is the marked pull request body section linking a group's pull requests
+const worktreeGroupSection = "group"
+
+var worktreeGroupSectionPattern = regexp.MustCompile(`(?s)<!-- catnip:group -->.*?<!-- /catnip:group -->`)
+
+// WorktreeGroupResult is the outcome of a worktree group operation for one member
+type WorktreeGroupResult struct {
+	WorktreeID string `json:"worktree_id"`
+	Name       string `json:"name"`
+	RepoID     string `json:"repo_id"`
+	Outcome    string `json:"outcome"`
+	URL        string `json:"url,omitempty"`
+	// Why the member was skipped or failed
+	Reason string `json:"reason,omitempty"`
+}
+
+// WorktreeGroupReport lists what a worktree group operation did to every member, in member order
+type WorktreeGroupReport struct {
+	GroupID string                `json:"group_id"`
+	Results []WorktreeGroupResult `json:"results"`
+}
+
+// WorktreeGroupMemberStatus summarizes the worktree of one group member
+type WorktreeGroupMemberStatus struct {
+	models.WorktreeGroupMember
+	// Whether the member's worktree was deleted outside the group
+	Missing          bool   `json:"missing"`
+	Name             string `json:"name,omitempty"`
+	WorktreeBranch   string `json:"worktree_branch,omitempty"`
+	CommitCount      int    `json:"commit_count"`
+	CommitsBehind    int    `json:"commits_behind"`
+	IsDirty          bool   `json:"is_dirty"`
+	HasConflicts     bool   `json:"has_conflicts"`
+	PullRequestURL   string `json:"pull_request_url,omitempty"`
+	PullRequestState string `json:"pull_request_state,omitempty"`
+}
+
+// WorktreeGroupStatus summarizes a worktree group across its members
+type WorktreeGroupStatus struct {
+	Group   *models.WorktreeGroup       `json:"group"`
+	Members []WorktreeGroupMemberStatus `json:"members"`
+}
+
+// AddWorktreeGroup stores a new worktree group
+func (wsm *WorktreeStateManager) AddWorktreeGroup(group *models.WorktreeGroup) error {
+	wsm.mu.Lock()
+	defer wsm.mu.Unlock()
+
+	if wsm.groups == nil {
+		wsm.groups = make(map[string]*models.WorktreeGroup)
+	}
+	wsm.groups[group.ID] = group
+	return wsm.saveStateInternal()
+}
+
+// GetWorktreeGroup returns a copy of a worktree group
+func (wsm *WorktreeStateManager) GetWorktreeGroup(id string) (*models.WorktreeGroup, bool) {
+	wsm.mu.RLock()
+	defer wsm.mu.RUnlock()
+
+	group, exists := wsm.groups[id]
+	if !exists {
+		return nil, false
+	}
+	return copyWorktreeGroup(group), true
+}
+
+// GetAllWorktreeGroups returns copies of all worktree groups, oldest first
+func (wsm *WorktreeStateManager) GetAllWorktreeGroups() []*models.WorktreeGroup {
+	wsm.mu.RLock()
+	defer wsm.mu.RUnlock()
+
+	groups := make([]*models.WorktreeGroup, 0, len(wsm.groups))
+	for _, group := range wsm.groups {
+		groups = append(groups, copyWorktreeGroup(group))
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if !groups[i].CreatedAt.Equal(groups[j].CreatedAt) {
+			return groups[i].CreatedAt.Before(groups[j].CreatedAt)
+		}
+		return groups[i].ID < groups[j].ID
+	})
+	return groups
+}
+
+// DeleteWorktreeGroup forgets a worktree group, leaving its worktrees alone
+func (wsm *WorktreeStateManager) DeleteWorktreeGroup(id string) error {
+	wsm.mu.Lock()
+	defer wsm.mu.Unlock()
+
+	if _, exists := wsm.groups[id]; !exists {
+		return fmt.Errorf("worktree group %s not found", id)
+	}
+	delete(wsm.groups, id)
+	return wsm.saveStateInternal()
+}
+
+func copyWorktreeGroup(group *models.WorktreeGroup) *models.WorktreeGroup {
+	copied := *group
+	copied.Members = append([]models.WorktreeGroupMember(nil), group.Members...)
+	return &copied
+}
+
+// CreateWorktreeGroup creates a worktree for every member, each from its repository and source
+// branch, and links them into a group. Members are kept in the given order, which is the order
+// the group is merged in. If any worktree can't be created the ones already created are deleted.
+func (s *GitService) CreateWorktreeGroup(name string, members []models.WorktreeGroupMember) (*models.WorktreeGroup, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("worktree group name is required")
+	}
+	if len(members) < 2 {
+		return nil, fmt.Errorf("a worktree group needs at least 2 repositories")
+	}
+	seen := make(map[string]bool, len(members))
+	for _, member := range members {
+		if !strings.Contains(member.RepoID, "/") {
+			return nil, fmt.Errorf("invalid repository %q, expected org/repo", member.RepoID)
+		}
+		if seen[member.RepoID] {
+			return nil, fmt.Errorf("repository %s is listed more than once", member.RepoID)
+		}
+		seen[member.RepoID] = true
+	}
+
+	group := &models.WorktreeGroup{ID: uuid.New().String(), Name: name, CreatedAt: time.Now()}
+	rollback := func() {
+		for _, member := range group.Members {
+			if _, err := s.DeleteWorktree(member.WorktreeID); err != nil {
+				logger.Warnf("⚠️  Failed to delete worktree %s of unfinished group %s: %v", member.WorktreeID, name, err)
+			}
+		}
+	}
+	for _, member := range members {
+		org, repo, _ := strings.Cut(member.RepoID, "/")
+		_, worktree, err := s.CheckoutRepository(org, repo, member.Branch, DefaultCloneOptions())
+		if err != nil {
+			rollback()
+			return nil, fmt.Errorf("failed to create worktree for %s: %v", member.RepoID, err)
+		}
+		member.WorktreeID = worktree.ID
+		if member.Branch == "" {
+			member.Branch = worktree.SourceBranch
+		}
+		group.Members = append(group.Members, member)
+	}
+
+	for _, member := range group.Members {
+		if err := s.stateManager.UpdateWorktree(member.WorktreeID, map[string]interface{}{"group_id": group.ID}); err != nil {
+			rollback()
+			return nil, err
+		}
+	}
+	if err := s.stateManager.AddWorktreeGroup(group); err != nil {
+		rollback()
+		return nil, err
+	}
+	logger.Infof("🧩 Created worktree group %s with %d worktrees", name, len(group.Members))
+	return group, nil
+}
+
+// ListWorktreeGroups returns all worktree groups, oldest first
+func (s *GitService) ListWorktreeGroups() []*models.WorktreeGroup {
+	return s.stateManager.GetAllWorktreeGroups()
+}
+
+// GetWorktreeGroupStatus summarizes the worktrees of a group, flagging members whose worktree
+// was deleted outside the group
+func (s *GitService) GetWorktreeGroupStatus(groupID string) (*WorktreeGroupStatus, error) {
+	group, exists := s.stateManager.GetWorktreeGroup(groupID)
+	if !exists {
+		return nil, fmt.Errorf("worktree group %s not found", groupID)
+	}
+
+	status := &WorktreeGroupStatus{Group: group, Members: []WorktreeGroupMemberStatus{}}
+	for _, member := range group.Members {
+		memberStatus := WorktreeGroupMemberStatus{WorktreeGroupMember: member}
+		worktree, exists := s.stateManager.GetWorktree(member.WorktreeID)
+		if !exists {
+			memberStatus.Missing = true
+		} else {
+			memberStatus.Name = worktree.Name
+			memberStatus.WorktreeBranch = worktree.Branch
+			memberStatus.CommitCount = worktree.CommitCount
+			memberStatus.CommitsBehind = worktree.CommitsBehind
+			memberStatus.IsDirty = worktree.IsDirty
+			memberStatus.HasConflicts = worktree.HasConflicts
+			memberStatus.PullRequestURL = worktree.PullRequestURL
+			memberStatus.PullRequestState = worktree.PullRequestState
+		}
+		status.Members = append(status.Members, memberStatus)
+	}
+	return status, nil
+}
+
+// groupWorktrees returns a group and the worktrees of its members in member order, failing when
+// a member's worktree is gone
+func (s *GitService) groupWorktrees(groupID string) (*models.WorktreeGroup, []*models.Worktree, error) {
+	group, exists := s.stateManager.GetWorktreeGroup(groupID)
+	if !exists {
+		return nil, nil, fmt.Errorf("worktree group %s not found", groupID)
+	}
+	worktrees := make([]*models.Worktree, 0, len(group.Members))
+	for _, member := range group.Members {
+		worktree, exists := s.stateManager.GetWorktree(member.WorktreeID)
+		if !exists {
+			return nil, nil, fmt.Errorf("worktree %s of %s in group %s not found", member.WorktreeID, member.RepoID, group.Name)
+		}
+		worktrees = append(worktrees, worktree)
+	}
+	return group, worktrees, nil
+}
+
+func worktreeGroupResult(worktree *models.Worktree, outcome, reason string) WorktreeGroupResult {
+	return WorktreeGroupResult{WorktreeID: worktree.ID, Name: worktree.Name, RepoID: worktree.RepoID, Outcome: outcome, Reason: reason}
+}
+
+// precheckWorktreeGroup runs check on every member. When any fails, the returned report has
+// the failures and marks the other members skipped, and nothing should be done to the group.
+func precheckWorktreeGroup(group *models.WorktreeGroup, worktrees []*models.Worktree, check func(*models.Worktree) string) *WorktreeGroupReport {
+	problems := make([]string, len(worktrees))
+	failed := false
+	for i, worktree := range worktrees {
+		if problems[i] = check(worktree); problems[i] != "" {
+			failed = true
+		}
+	}
+	if !failed {
+		return nil
+	}
+
+	report := &WorktreeGroupReport{GroupID: group.ID, Results: []WorktreeGroupResult{}}
+	for i, worktree := range worktrees {
+		if problems[i] != "" {
+			report.Results = append(report.Results, worktreeGroupResult(worktree, WorktreeGroupFailed, problems[i]))
+		} else {
+			report.Results = append(report.Results, worktreeGroupResult(worktree, WorktreeGroupSkipped, "another member of the group can't proceed"))
+		}
+	}
+	return report
+}
+
+// conflictProblem describes the outcome of a conflict check, empty when it is clean
+func conflictProblem(conflict *models.MergeConflictError, err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	if conflict != nil {
+		return fmt.Sprintf("would conflict in %s", strings.Join(conflict.ConflictFiles, ", "))
+	}
+	return ""
+}
+
+// SyncWorktreeGroup syncs every member of a group with its source branch. The members are
+// checked for conflicts first, and none is synced when any would conflict.
+func (s *GitService) SyncWorktreeGroup(groupID, strategy string) (*WorktreeGroupReport, error) {
+	group, worktrees, err := s.groupWorktrees(groupID)
+	if err != nil {
+		return nil, err
+	}
+	if report := precheckWorktreeGroup(group, worktrees, func(worktree *models.Worktree) string {
+		return conflictProblem(s.CheckSyncConflicts(worktree.ID))
+	}); report != nil {
+		return report, nil
+	}
+
+	report := &WorktreeGroupReport{GroupID: group.ID, Results: []WorktreeGroupResult{}}
+	for _, worktree := range worktrees {
+		if _, err := s.SyncWorktree(worktree.ID, SyncOptions{Strategy: strategy, Base: SyncBaseSource}); err != nil {
+			report.Results = append(report.Results, worktreeGroupResult(worktree, WorktreeGroupFailed, err.Error()))
+			continue
+		}
+		report.Results = append(report.Results, worktreeGroupResult(worktree, WorktreeGroupSucceeded, ""))
+	}
+	return report, nil
+}
+
+// CreateWorktreeGroupPullRequests opens a pull request for every member that has commits and
+// none yet, then updates the body of every member's pull request with a section linking the
+// pull requests of the whole group
+func (s *GitService) CreateWorktreeGroupPullRequests(groupID string, draft bool) (*WorktreeGroupReport, error) {
+	group, worktrees, err := s.groupWorktrees(groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &WorktreeGroupReport{GroupID: group.ID, Results: []WorktreeGroupResult{}}
+	var lastCreated time.Time
+	for _, worktree := range worktrees {
+		bulk := s.bulkPullRequest(worktree, BulkPullRequestOptions{Draft: draft, TextMode: PullRequestTextSession}, &lastCreated)
+		result := worktreeGroupResult(worktree, WorktreeGroupFailed, bulk.Reason)
+		switch bulk.Outcome {
+		case BulkPullRequestCreated:
+			result.Outcome = WorktreeGroupSucceeded
+		case BulkPullRequestSkipped:
+			result.Outcome = WorktreeGroupSkipped
+		}
+		if current, exists := s.stateManager.GetWorktree(worktree.ID); exists {
+			result.URL = current.PullRequestURL
+		}
+		report.Results = append(report.Results, result)
+	}
+
+	// Second pass, now that every URL is known
+	section := worktreeGroupPullRequestSection(group, report.Results)
+	for i, result := range report.Results {
+		if result.URL == "" {
+			continue
+		}
+		worktree, exists := s.stateManager.GetWorktree(result.WorktreeID)
+		if !exists {
+			continue
+		}
+		body := replaceWorktreeGroupSection(worktree.PullRequestBody, section)
+		if body == worktree.PullRequestBody {
+			continue
+		}
+		if _, err := s.UpdatePullRequest(worktree.ID, worktree.PullRequestTitle, body, PullRequestOptions{}); err != nil {
+			report.Results[i].Outcome = WorktreeGroupFailed
+			report.Results[i].Reason = fmt.Sprintf("failed to link the group's pull requests: %v", err)
+		}
+	}
+	return report, nil
+}
+
+// worktreeGroupPullRequestSection lists the pull requests of a group for their bodies
+func worktreeGroupPullRequestSection(group *models.WorktreeGroup, results []WorktreeGroupResult) string {
+	lines := []string{fmt.Sprintf("Part of **%s**, which spans %d repositories:", group.Name, len(results))}
+	for _, result := range results {
+		link := result.URL
+		if link == "" {
+			link = "no pull request"
+		}
+		lines = append(lines, fmt.Sprintf("- %s: %s", result.RepoID, link))
+	}
+	content := strings.Join(lines, "\n")
+	return fmt.Sprintf("<!-- catnip:%s -->\n%s\n<!-- /catnip:%s -->", worktreeGroupSection, content, worktreeGroupSection)
+}
+
+// replaceWorktreeGroupSection puts the group section into a pull request body, replacing the one
+// from an earlier run
+func replaceWorktreeGroupSection(body, section string) string {
+	if worktreeGroupSectionPattern.MatchString(body) {
+		return worktreeGroupSectionPattern.ReplaceAllLiteralString(body, section)
+	}
+	if strings.TrimSpace(body) == "" {
+		return section
+	}
+	return strings.TrimRight(body, "\n") + "\n\n" + section
+}
+
+// MergeWorktreeGroup merges the members of a group into their source branches in member order.
+// Every member must be a local repository without merge conflicts, otherwise none is merged;
+// once a merge fails the remaining members are skipped.
+func (s *GitService) MergeWorktreeGroup(groupID string, squash bool) (*WorktreeGroupReport, error) {
+	group, worktrees, err := s.groupWorktrees(groupID)
+	if err != nil {
+		return nil, err
+	}
+	if report := precheckWorktreeGroup(group, worktrees, func(worktree *models.Worktree) string {
+		return conflictProblem(s.CheckMergeConflicts(worktree.ID))
+	}); report != nil {
+		return report, nil
+	}
+
+	report := &WorktreeGroupReport{GroupID: group.ID, Results: []WorktreeGroupResult{}}
+	var failed *models.Worktree
+	for _, worktree := range worktrees {
+		if failed != nil {
+			report.Results = append(report.Results, worktreeGroupResult(worktree, WorktreeGroupSkipped, fmt.Sprintf("merge of %s failed", failed.Name)))
+			continue
+		}
+		if err := s.MergeWorktreeToMain(worktree.ID, MergeOptions{Squash: squash}); err != nil {
+			failed = worktree
+			report.Results = append(report.Results, worktreeGroupResult(worktree, WorktreeGroupFailed, err.Error()))
+			continue
+		}
+		report.Results = append(report.Results, worktreeGroupResult(worktree, WorktreeGroupSucceeded, ""))
+	}
+	return report, nil
+}
+
+// DeleteWorktreeGroup deletes a group. With deleteMembers its worktrees are deleted too,
+// otherwise they are only unlinked from the group.
+func (s *GitService) DeleteWorktreeGroup(groupID string, deleteMembers bool) (*WorktreeGroupReport, error) {
+	group, exists := s.stateManager.GetWorktreeGroup(groupID)
+	if !exists {
+		return nil, fmt.Errorf("worktree group %s not found", groupID)
+	}
+
+	report := &WorktreeGroupReport{GroupID: group.ID, Results: []WorktreeGroupResult{}}
+	for _, member := range group.Members {
+		worktree, exists := s.stateManager.GetWorktree(member.WorktreeID)
+		if !exists {
+			report.Results = append(report.Results, WorktreeGroupResult{WorktreeID: member.WorktreeID, RepoID: member.RepoID, Outcome: WorktreeGroupSkipped, Reason: "worktree not found"})
+			continue
+		}
+		if deleteMembers {
+			if _, err := s.DeleteWorktree(worktree.ID); err != nil {
+				report.Results = append(report.Results, worktreeGroupResult(worktree, WorktreeGroupFailed, err.Error()))
+				continue
+			}
+		} else if err := s.stateManager.UpdateWorktree(worktree.ID, map[string]interface{}{"group_id": ""}); err != nil {
+			report.Results = append(report.Results, worktreeGroupResult(worktree, WorktreeGroupFailed, err.Error()))
+			continue
+		}
+		report.Results = append(report.Results, worktreeGroupResult(worktree, WorktreeGroupSucceeded, ""))
+	}
+
+	if err := s.stateManager.DeleteWorktreeGroup(group.ID); err != nil {
+		return nil, err
+	}
+	logger.Infof("🧩 Deleted worktree group %s", group.Name)
+	return report, nil
+}
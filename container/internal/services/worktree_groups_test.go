@@ -0,0 +1,93 @@
+package services
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/git"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+func TestWorktreeGroups(t *testing.T) {
+	s, stateManager, repoPath, _ := newRecreateTestService(t)
+	s.conflictResolver = git.NewConflictResolver(s.operations)
+	require.NoError(t, stateManager.AddRepository(&models.Repository{ID: "acme/api", Path: repoPath}))
+	require.NoError(t, stateManager.AddWorktree(&models.Worktree{ID: "wt-api", RepoID: "acme/api", Name: "api/felix", Path: t.TempDir(), Branch: "feature/felix", SourceBranch: "main"}))
+	group := &models.WorktreeGroup{
+		ID:   "group-1",
+		Name: "felix-mode",
+		Members: []models.WorktreeGroupMember{
+			{RepoID: "local/repo", Branch: "main", WorktreeID: "wt-felix"},
+			{RepoID: "acme/api", Branch: "main", WorktreeID: "wt-api"},
+		},
+		CreatedAt: time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC),
+	}
+	require.NoError(t, stateManager.AddWorktreeGroup(group))
+	for _, id := range []string{"wt-felix", "wt-api"} {
+		require.NoError(t, stateManager.UpdateWorktree(id, map[string]interface{}{"group_id": group.ID}))
+	}
+
+	t.Run("Persisted", func(t *testing.T) {
+		reloaded := NewWorktreeStateManager(stateManager.stateDir, nil)
+		t.Cleanup(reloaded.Stop)
+		loaded, exists := reloaded.GetWorktreeGroup(group.ID)
+		require.True(t, exists)
+		assert.Equal(t, group, loaded)
+		worktree, _ := reloaded.GetWorktree("wt-api")
+		assert.Equal(t, group.ID, worktree.GroupID)
+	})
+
+	t.Run("CreateValidatesMembers", func(t *testing.T) {
+		_, err := s.CreateWorktreeGroup("solo", []models.WorktreeGroupMember{{RepoID: "acme/api"}})
+		assert.ErrorContains(t, err, "at least 2 repositories")
+		_, err = s.CreateWorktreeGroup("twice", []models.WorktreeGroupMember{{RepoID: "acme/api"}, {RepoID: "acme/api"}})
+		assert.ErrorContains(t, err, "more than once")
+		assert.Len(t, s.ListWorktreeGroups(), 1)
+	})
+
+	t.Run("MergeRefusedUnlessEveryMemberCanMerge", func(t *testing.T) {
+		head := runTestGit(t, repoPath, "rev-parse", "main")
+		report, err := s.MergeWorktreeGroup(group.ID, true)
+		require.NoError(t, err)
+		require.Len(t, report.Results, 2)
+		assert.Equal(t, WorktreeGroupSkipped, report.Results[0].Outcome)
+		assert.Equal(t, WorktreeGroupFailed, report.Results[1].Outcome)
+		assert.Contains(t, report.Results[1].Reason, "only supported for local repositories")
+		assert.Equal(t, head, runTestGit(t, repoPath, "rev-parse", "main"), "nothing merged")
+	})
+
+	t.Run("LinkSectionReplacesPreviousOne", func(t *testing.T) {
+		section := worktreeGroupPullRequestSection(group, []WorktreeGroupResult{
+			{RepoID: "local/repo", URL: "https://github.com/acme/repo/pull/7"},
+			{RepoID: "acme/api"},
+		})
+		assert.Equal(t, "<!-- catnip:group -->\nPart of **felix-mode**, which spans 2 repositories:\n- local/repo: https://github.com/acme/repo/pull/7\n- acme/api: no pull request\n<!-- /catnip:group -->", section)
+
+		body := replaceWorktreeGroupSection("Adds felix mode\n", section)
+		assert.Equal(t, "Adds felix mode\n\n"+section, body)
+		updated := worktreeGroupPullRequestSection(group, []WorktreeGroupResult{{RepoID: "local/repo"}, {RepoID: "acme/api"}})
+		assert.Equal(t, "Adds felix mode\n\n"+updated, replaceWorktreeGroupSection(body, updated))
+	})
+
+	t.Run("DeleteUnlinksMembers", func(t *testing.T) {
+		require.NoError(t, stateManager.DeleteWorktree("wt-api"))
+		status, err := s.GetWorktreeGroupStatus(group.ID)
+		require.NoError(t, err)
+		assert.False(t, status.Members[0].Missing)
+		assert.Equal(t, "repo/felix", status.Members[0].Name)
+		assert.True(t, status.Members[1].Missing)
+
+		report, err := s.DeleteWorktreeGroup(group.ID, false)
+		require.NoError(t, err)
+		assert.Equal(t, WorktreeGroupSucceeded, report.Results[0].Outcome)
+		assert.Equal(t, WorktreeGroupSkipped, report.Results[1].Outcome)
+		worktree, exists := stateManager.GetWorktree("wt-felix")
+		require.True(t, exists, "members are kept")
+		assert.Empty(t, worktree.GroupID)
+		assert.Empty(t, s.ListWorktreeGroups())
+		assert.NoFileExists(t, filepath.Join(stateManager.stateDir, entityStateFile(stateGroupsDir, group.ID)))
+	})
+}
@@ -0,0 +1,261 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// Severities of worktree health findings, most severe first
+const (
+	HealthSeverityError   = "error"
+	HealthSeverityWarning = "warning"
+	HealthSeverityInfo    = "info"
+)
+
+// Remediation actions of health findings, each backed by an existing endpoint
+const (
+	// POST /v1/git/worktrees/{id}/recreate
+	HealthActionRecreate = "recreate"
+	// POST /v1/git/worktrees/{id}/remote-config/repair
+	HealthActionRepairRemoteConfig = "repair-remote-config"
+	// POST /v1/git/worktrees/{id}/branch-drift/acknowledge
+	HealthActionAcknowledgeBranchDrift = "acknowledge-branch-drift"
+	// POST /v1/git/worktrees/{id}/sync
+	HealthActionSync = "sync"
+	// POST /v1/git/worktrees/{id}/validate
+	HealthActionValidate = "validate"
+	// POST /v1/git/caches/trim
+	HealthActionTrimCaches = "trim-caches"
+)
+
+const (
+	// worktreeHealthCheckInterval is how often every worktree is checked for new findings
+	worktreeHealthCheckInterval = 5 * time.Minute
+	// staleBaseWarningCommits is how far behind its source branch a worktree gets before being
+	// behind is a warning rather than a note
+	staleBaseWarningCommits = 50
+)
+
+// healthSeverityRank orders findings, most severe first
+var healthSeverityRank = map[string]int{
+	HealthSeverityError:   0,
+	HealthSeverityWarning: 1,
+	HealthSeverityInfo:    2,
+}
+
+// worktreeHealthTracker remembers the findings of the last periodic check of each worktree,
+// so only new findings are broadcast
+type worktreeHealthTracker struct {
+	mu    sync.Mutex
+	known map[string]map[string]bool // worktree ID -> finding codes
+}
+
+// update records the latest check results and returns those with findings the previous check
+// didn't report. Worktrees missing from results are forgotten.
+func (t *worktreeHealthTracker) update(results []*models.WorktreeHealth) []*models.WorktreeHealth {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	known := make(map[string]map[string]bool, len(results))
+	var changed []*models.WorktreeHealth
+	for _, health := range results {
+		codes := make(map[string]bool, len(health.Findings))
+		isNew := false
+		for _, finding := range health.Findings {
+			codes[finding.Code] = true
+			if !t.known[health.WorktreeID][finding.Code] {
+				isNew = true
+			}
+		}
+		known[health.WorktreeID] = codes
+		if isNew {
+			changed = append(changed, health)
+		}
+	}
+	t.known = known
+	return changed
+}
+
+// CheckWorktreeHealth lists everything known to be wrong with a worktree. Findings come from
+// cached state where possible; the worktree path and its upstream branch are probed.
+func (s *GitService) CheckWorktreeHealth(worktreeID string) (*models.WorktreeHealth, error) {
+	worktree, exists := s.stateManager.GetWorktree(worktreeID)
+	if !exists {
+		return nil, fmt.Errorf("worktree %s not found", worktreeID)
+	}
+	return s.checkWorktreeHealth(worktree), nil
+}
+
+// CheckAllWorktrees checks the health of every worktree and broadcasts the results of worktrees
+// with findings the previous call didn't report
+func (s *GitService) CheckAllWorktrees() []*models.WorktreeHealth {
+	worktrees := s.stateManager.GetAllWorktrees()
+	results := make([]*models.WorktreeHealth, 0, len(worktrees))
+	for _, worktree := range worktrees {
+		results = append(results, s.checkWorktreeHealth(worktree))
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].WorktreeID < results[j].WorktreeID })
+
+	for _, health := range s.health.update(results) {
+		logger.Infof("🩺 Worktree %s has new health findings: %s", health.WorktreeID, healthFindingCodes(health.Findings))
+		if s.eventsEmitter != nil {
+			s.eventsEmitter.EmitWorktreeHealth(health)
+		}
+	}
+	return results
+}
+
+// checkWorktreeHealth collects the findings of a worktree
+func (s *GitService) checkWorktreeHealth(worktree *models.Worktree) *models.WorktreeHealth {
+	health := &models.WorktreeHealth{WorktreeID: worktree.ID, Findings: []models.HealthFinding{}, CheckedAt: time.Now()}
+	add := func(severity, code, action, format string, args ...interface{}) {
+		health.Findings = append(health.Findings, models.HealthFinding{
+			Severity: severity,
+			Code:     code,
+			Message:  fmt.Sprintf(format, args...),
+			Action:   action,
+		})
+	}
+
+	repo, repoExists := s.stateManager.GetRepository(worktree.RepoID)
+	if !repoExists || !repo.Available {
+		add(HealthSeverityError, "repository_unavailable", "", "Repository %s is not available", worktree.RepoID)
+	}
+	warnings := s.stateManager.RepositoryHealthWarnings(worktree.RepoID)
+	sources := make([]string, 0, len(warnings))
+	for source := range warnings {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+	for _, source := range sources {
+		message := warnings[source]
+		switch {
+		case source == remoteConfigWarningSource:
+			add(HealthSeverityWarning, "remote_config_drift", HealthActionRepairRemoteConfig, "%s", message)
+		case source == renameWarningSource:
+			add(HealthSeverityWarning, "repository_renamed", "", "%s", message)
+		case strings.HasPrefix(source, mirrorWarningSource("")):
+			add(HealthSeverityWarning, "mirror_failing", "", "%s", message)
+		default:
+			add(HealthSeverityWarning, "repository_warning", "", "%s", message)
+		}
+	}
+	if disk := s.DiskStatus(); disk.State == DiskStateLow {
+		add(HealthSeverityWarning, "low_disk_space", HealthActionTrimCaches, "Workspace volume is low on space: %d MB free", disk.FreeBytes>>20)
+	}
+
+	// Nothing else can be checked without a checkout
+	if _, err := os.Stat(worktree.Path); err != nil {
+		add(HealthSeverityError, "missing_path", HealthActionRecreate, "Worktree directory %s is missing", worktree.Path)
+		sortHealthFindings(health.Findings)
+		return health
+	}
+
+	for _, degradation := range fileWatches.degradations(worktree.Path) {
+		cause := "the inotify limit was reached"
+		if degradation.Reason == WatchDegradedRationed {
+			cause = "its file watches went to more active worktrees"
+		}
+		add(HealthSeverityWarning, "watch_degraded", "", "%s polls for changes every %s instead of watching them: %s", degradation.Subsystem, watchPollInterval(), cause)
+	}
+	if worktree.HasConflicts {
+		add(HealthSeverityError, "conflicts", "", "Worktree has unresolved conflicts")
+	}
+	if drift := worktree.ManualIntervention; drift != nil {
+		actual := drift.ActualBranch
+		if actual == "" {
+			actual = "a detached HEAD"
+		}
+		add(HealthSeverityWarning, "branch_drift", HealthActionAcknowledgeBranchDrift, "Branch was changed from %s to %s outside catnip", drift.ExpectedBranch, actual)
+	}
+	if validation := worktree.Validation; validation != nil && validation.Status == "failed" {
+		add(HealthSeverityWarning, "validation_failed", HealthActionValidate, "Validation commands failed at %s", shortCommit(validation.CommitHash))
+	}
+	if ahead, behind, diverged := s.upstreamDivergence(worktree); diverged {
+		add(HealthSeverityWarning, "diverged_upstream", "", "Branch %s and origin/%s diverged: %d local and %d remote commits", worktree.Branch, worktree.Branch, ahead, behind)
+	}
+	if rewrite := worktree.SourceBranchRewritten; rewrite != nil {
+		add(HealthSeverityWarning, "source_rewritten", HealthActionSync, "Source branch %s was force-pushed, sync acknowledging the rewrite to rebase onto it", rewrite.Branch)
+	} else if worktree.CommitsBehind >= staleBaseWarningCommits {
+		add(HealthSeverityWarning, "stale_base", HealthActionSync, "%d commits behind %s", worktree.CommitsBehind, worktree.SourceBranch)
+	} else if worktree.CommitsBehind > 0 {
+		add(HealthSeverityInfo, "stale_base", HealthActionSync, "%d commits behind %s", worktree.CommitsBehind, worktree.SourceBranch)
+	}
+	if len(worktree.IgnoreSuggestions) > 0 {
+		patterns := make([]string, 0, len(worktree.IgnoreSuggestions))
+		for _, suggestion := range worktree.IgnoreSuggestions {
+			patterns = append(patterns, suggestion.Pattern)
+		}
+		add(HealthSeverityInfo, "ignore_suggestion", "", "Checkpoints keep committing %s", strings.Join(patterns, ", "))
+	}
+
+	sortHealthFindings(health.Findings)
+	return health
+}
+
+// upstreamDivergence compares a worktree's branch with its remote tracking branch, reporting
+// whether both have commits the other lacks
+func (s *GitService) upstreamDivergence(worktree *models.Worktree) (ahead, behind int, diverged bool) {
+	if worktree.Branch == "" || strings.HasPrefix(worktree.Branch, "refs/") || !s.operations.BranchExists(worktree.Path, worktree.Branch, true) {
+		return 0, 0, false
+	}
+	upstream := "origin/" + worktree.Branch
+	ahead, err := s.operations.GetCommitCount(worktree.Path, upstream, "HEAD")
+	if err != nil {
+		return 0, 0, false
+	}
+	behind, err = s.operations.GetCommitCount(worktree.Path, "HEAD", upstream)
+	if err != nil {
+		return 0, 0, false
+	}
+	return ahead, behind, ahead > 0 && behind > 0
+}
+
+// startWorktreeHealthMonitor periodically checks every worktree until the service stops
+func (s *GitService) startWorktreeHealthMonitor() {
+	ticker := time.NewTicker(worktreeHealthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.CheckAllWorktrees()
+		case <-s.stopCh:
+			return
+		}
+	}
+}
+
+// sortHealthFindings orders findings by severity, then code
+func sortHealthFindings(findings []models.HealthFinding) {
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Severity != findings[j].Severity {
+			return healthSeverityRank[findings[i].Severity] < healthSeverityRank[findings[j].Severity]
+		}
+		return findings[i].Code < findings[j].Code
+	})
+}
+
+// healthFindingCodes lists the codes of findings for logs
+func healthFindingCodes(findings []models.HealthFinding) string {
+	codes := make([]string, 0, len(findings))
+	for _, finding := range findings {
+		codes = append(codes, finding.Code)
+	}
+	return strings.Join(codes, ", ")
+}
+
+// shortCommit abbreviates a commit hash for messages
+func shortCommit(hash string) string {
+	if len(hash) > 7 {
+		return hash[:7]
+	}
+	return hash
+}
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// healthRecorder records worktree health events; other events are not expected
+type healthRecorder struct {
+	EventsEmitter
+	events []*models.WorktreeHealth
+}
+
+func (r *healthRecorder) EmitWorktreeHealth(health *models.WorktreeHealth) {
+	r.events = append(r.events, health)
+}
+
+func findingCodes(health *models.WorktreeHealth) []string {
+	codes := make([]string, 0, len(health.Findings))
+	for _, finding := range health.Findings {
+		codes = append(codes, finding.Code)
+	}
+	return codes
+}
+
+func TestWorktreeHealth(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	root := t.TempDir()
+	repoPath := initTestRepo(t, filepath.Join(root, "repo"), map[string]string{"a.txt": "one\n"})
+	worktreePath := filepath.Join(root, "felix")
+	runTestGit(t, repoPath, "worktree", "add", "-b", "feature", worktreePath)
+
+	s := newTestGitService(t, root)
+	stateManager := s.stateManager
+	require.NoError(t, stateManager.AddRepository(&models.Repository{ID: "local/repo", Path: repoPath, DefaultBranch: "main", Available: true}))
+	require.NoError(t, stateManager.AddWorktree(&models.Worktree{
+		ID: "wt-felix", RepoID: "local/repo", Name: "repo/felix", Path: worktreePath, Branch: "feature", SourceBranch: "main",
+	}))
+	recorder := &healthRecorder{}
+	s.eventsEmitter = recorder
+
+	health, err := s.CheckWorktreeHealth("wt-felix")
+	require.NoError(t, err)
+	assert.Empty(t, health.Findings)
+	s.CheckAllWorktrees()
+	assert.Empty(t, recorder.events, "healthy worktrees aren't broadcast")
+
+	// Findings from cached state come with the action that fixes them
+	require.NoError(t, stateManager.SetRepositoryHealthWarning("local/repo", remoteConfigWarningSource, "origin points at a different repository"))
+	require.NoError(t, stateManager.UpdateWorktree("wt-felix", map[string]interface{}{
+		"commits_behind":      3,
+		"manual_intervention": &models.BranchDrift{ExpectedBranch: "feature", ActualBranch: "main"},
+	}))
+	health, err = s.CheckWorktreeHealth("wt-felix")
+	require.NoError(t, err)
+	assert.Equal(t, []models.HealthFinding{
+		{Severity: HealthSeverityWarning, Code: "branch_drift", Message: "Branch was changed from feature to main outside catnip", Action: HealthActionAcknowledgeBranchDrift},
+		{Severity: HealthSeverityWarning, Code: "remote_config_drift", Message: "origin points at a different repository", Action: HealthActionRepairRemoteConfig},
+		{Severity: HealthSeverityInfo, Code: "stale_base", Message: "3 commits behind main", Action: HealthActionSync},
+	}, health.Findings)
+
+	// The periodic check only broadcasts findings it hasn't reported yet
+	s.CheckAllWorktrees()
+	require.Len(t, recorder.events, 1)
+	s.CheckAllWorktrees()
+	require.Len(t, recorder.events, 1)
+
+	// Local and remote commits the other side lacks are probed
+	require.NoError(t, os.WriteFile(filepath.Join(worktreePath, "a.txt"), []byte("local\n"), 0644))
+	runTestGit(t, worktreePath, "commit", "-am", "Local work")
+	remote := strings.TrimSpace(runTestGit(t, worktreePath, "commit-tree", "HEAD~1^{tree}", "-p", "HEAD~1", "-m", "Pushed elsewhere"))
+	runTestGit(t, worktreePath, "update-ref", "refs/remotes/origin/feature", remote)
+	s.CheckAllWorktrees()
+	require.Len(t, recorder.events, 2)
+	assert.Equal(t, []string{"branch_drift", "diverged_upstream", "remote_config_drift", "stale_base"}, findingCodes(recorder.events[1]))
+
+	// Without a checkout only the missing directory and repository findings are left
+	require.NoError(t, os.RemoveAll(worktreePath))
+	health, err = s.CheckWorktreeHealth("wt-felix")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"missing_path", "remote_config_drift"}, findingCodes(health))
+	assert.Equal(t, HealthActionRecreate, health.Findings[0].Action)
+	assert.Equal(t, HealthSeverityError, health.Findings[0].Severity)
+
+	_, err = s.CheckWorktreeHealth("wt-oscar")
+	assert.Error(t, err)
+}
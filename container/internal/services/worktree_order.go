@@ -0,0 +1,179 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// Orders worktree lists can be sorted in. Ties are broken by the default order, then by ID.
+const (
+	// WorktreeSortLastAccessed lists the most recently accessed worktrees first, then by name
+	WorktreeSortLastAccessed = "lastAccessed"
+	// WorktreeSortName lists worktrees by name
+	WorktreeSortName = "name"
+	// WorktreeSortCommitsAhead lists the worktrees with the most commits ahead of their source first
+	WorktreeSortCommitsAhead = "commitsAhead"
+)
+
+// ErrInvalidWorktreeListOptions is returned for unknown sort orders, negative limits and offsets
+// and cursors that can't be used
+var ErrInvalidWorktreeListOptions = errors.New("invalid worktree list options")
+
+// WorktreeListOptions selects the order and page of a worktree list. Zero values list
+// everything in the default order.
+type WorktreeListOptions struct {
+	SortBy string
+	// Maximum number of worktrees to return, 0 for no limit
+	Limit int
+	// Number of worktrees to skip, can't be combined with Cursor
+	Offset int
+	// Continues after the last worktree of a previous page, see WorktreePage.NextCursor
+	Cursor string
+}
+
+// WorktreePage is a page of a worktree list
+type WorktreePage struct {
+	Worktrees []*models.Worktree
+	// Number of worktrees in the whole list
+	Total int
+	// Cursor for the page after this one, empty on the last page
+	NextCursor string
+}
+
+// worktreeCursor records the sort keys of the last worktree of a page, so the next page
+// continues after its position even when worktrees were added or removed in between
+type worktreeCursor struct {
+	SortBy       string    `json:"s"`
+	ID           string    `json:"i"`
+	Name         string    `json:"n"`
+	LastAccessed time.Time `json:"a"`
+	CommitCount  int       `json:"c"`
+}
+
+// SortWorktrees sorts worktrees in place. An empty sortBy uses WorktreeSortLastAccessed.
+func SortWorktrees(worktrees []*models.Worktree, sortBy string) error {
+	less, err := worktreeLess(sortBy)
+	if err != nil {
+		return err
+	}
+	sort.SliceStable(worktrees, func(i, j int) bool {
+		return less(worktreeCursorOf(worktrees[i], sortBy), worktreeCursorOf(worktrees[j], sortBy))
+	})
+	return nil
+}
+
+// PageWorktrees sorts worktrees and returns the page options select
+func PageWorktrees(worktrees []*models.Worktree, options WorktreeListOptions) (*WorktreePage, error) {
+	if options.Limit < 0 || options.Offset < 0 {
+		return nil, fmt.Errorf("%w: limit and offset can't be negative", ErrInvalidWorktreeListOptions)
+	}
+	if options.Cursor != "" && options.Offset > 0 {
+		return nil, fmt.Errorf("%w: use either cursor or offset", ErrInvalidWorktreeListOptions)
+	}
+	if options.SortBy == "" {
+		options.SortBy = WorktreeSortLastAccessed
+	}
+
+	sorted := append([]*models.Worktree(nil), worktrees...)
+	if err := SortWorktrees(sorted, options.SortBy); err != nil {
+		return nil, err
+	}
+	page := &WorktreePage{Total: len(sorted)}
+
+	start := options.Offset
+	if options.Cursor != "" {
+		after, err := decodeWorktreeCursor(options.Cursor, options.SortBy)
+		if err != nil {
+			return nil, err
+		}
+		less, _ := worktreeLess(options.SortBy)
+		start = sort.Search(len(sorted), func(i int) bool {
+			return less(after, worktreeCursorOf(sorted[i], options.SortBy))
+		})
+	}
+	if start > len(sorted) {
+		start = len(sorted)
+	}
+	end := len(sorted)
+	if options.Limit > 0 && start+options.Limit < end {
+		end = start + options.Limit
+	}
+	page.Worktrees = sorted[start:end]
+
+	if end < len(sorted) && end > start {
+		page.NextCursor = encodeWorktreeCursor(worktreeCursorOf(sorted[end-1], options.SortBy))
+	}
+	return page, nil
+}
+
+// worktreeLess returns the ordering of sortBy over worktree sort keys
+func worktreeLess(sortBy string) (func(a, b worktreeCursor) bool, error) {
+	byDefault := func(a, b worktreeCursor) bool {
+		if !a.LastAccessed.Equal(b.LastAccessed) {
+			return a.LastAccessed.After(b.LastAccessed)
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.ID < b.ID
+	}
+
+	switch sortBy {
+	case "", WorktreeSortLastAccessed:
+		return byDefault, nil
+	case WorktreeSortName:
+		return func(a, b worktreeCursor) bool {
+			if a.Name != b.Name {
+				return a.Name < b.Name
+			}
+			return byDefault(a, b)
+		}, nil
+	case WorktreeSortCommitsAhead:
+		return func(a, b worktreeCursor) bool {
+			if a.CommitCount != b.CommitCount {
+				return a.CommitCount > b.CommitCount
+			}
+			return byDefault(a, b)
+		}, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown sort order %q, use %s, %s or %s", ErrInvalidWorktreeListOptions,
+			sortBy, WorktreeSortLastAccessed, WorktreeSortName, WorktreeSortCommitsAhead)
+	}
+}
+
+// worktreeCursorOf returns the sort keys of a worktree
+func worktreeCursorOf(worktree *models.Worktree, sortBy string) worktreeCursor {
+	return worktreeCursor{
+		SortBy:       sortBy,
+		ID:           worktree.ID,
+		Name:         worktree.Name,
+		LastAccessed: worktree.LastAccessed,
+		CommitCount:  worktree.CommitCount,
+	}
+}
+
+func encodeWorktreeCursor(cursor worktreeCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeWorktreeCursor(encoded, sortBy string) (worktreeCursor, error) {
+	var cursor worktreeCursor
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err == nil {
+		err = json.Unmarshal(data, &cursor)
+	}
+	if err != nil {
+		return cursor, fmt.Errorf("%w: malformed cursor", ErrInvalidWorktreeListOptions)
+	}
+	if cursor.SortBy != sortBy {
+		return cursor, fmt.Errorf("%w: cursor belongs to a list sorted by %q", ErrInvalidWorktreeListOptions, cursor.SortBy)
+	}
+	return cursor, nil
+}
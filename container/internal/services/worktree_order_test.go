@@ -0,0 +1,116 @@
+package services
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/git"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+func worktreeIDs(worktrees []*models.Worktree) []string {
+	ids := make([]string, 0, len(worktrees))
+	for _, worktree := range worktrees {
+		ids = append(ids, worktree.ID)
+	}
+	return ids
+}
+
+func TestListWorktreesOrder(t *testing.T) {
+	root := t.TempDir()
+	stateManager := NewWorktreeStateManager(filepath.Join(root, "state"), nil)
+	t.Cleanup(stateManager.Stop)
+	cache := NewWorktreeStatusCache(git.NewOperations(), stateManager)
+	t.Cleanup(cache.Stop)
+	s := &GitService{stateManager: stateManager, worktreeCache: cache}
+	require.NoError(t, stateManager.AddRepository(&models.Repository{ID: "owner/repo", Path: filepath.Join(root, "repo")}))
+
+	// felix and luna were accessed at the same time, so their names decide
+	accessed := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	for _, worktree := range []struct {
+		id, name string
+		accessed time.Time
+		commits  int
+	}{
+		{"wt-milo", "repo/milo", accessed.Add(-time.Hour), 5},
+		{"wt-luna", "repo/luna", accessed, 1},
+		{"wt-felix", "repo/felix", accessed, 1},
+		{"wt-oscar", "repo/oscar", accessed.Add(time.Hour), 0},
+	} {
+		require.NoError(t, stateManager.AddWorktree(&models.Worktree{
+			ID: worktree.id, RepoID: "owner/repo", Name: worktree.name, Path: filepath.Join(root, worktree.id),
+			LastAccessed: worktree.accessed, CommitCount: worktree.commits,
+		}))
+		cache.statuses[worktree.id] = &CachedWorktreeStatus{WorktreeID: worktree.id}
+	}
+
+	expected := []string{"wt-oscar", "wt-felix", "wt-luna", "wt-milo"}
+	summaryHash := s.GetWorktreesSummary().Hash
+	for i := 0; i < 20; i++ {
+		// Status updates in between don't reorder the list or change the summary
+		dirty := i%2 == 0
+		require.NoError(t, stateManager.UpdateWorktree(expected[i%len(expected)], map[string]interface{}{"is_dirty": dirty}))
+		require.NoError(t, stateManager.UpdateWorktree(expected[i%len(expected)], map[string]interface{}{"is_dirty": false}))
+		require.Equal(t, expected, worktreeIDs(s.ListWorktrees()))
+		require.Equal(t, summaryHash, s.GetWorktreesSummary().Hash)
+	}
+
+	t.Run("sort orders", func(t *testing.T) {
+		page, err := PageWorktrees(s.ListWorktrees(), WorktreeListOptions{SortBy: WorktreeSortName})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"wt-felix", "wt-luna", "wt-milo", "wt-oscar"}, worktreeIDs(page.Worktrees))
+
+		page, err = PageWorktrees(s.ListWorktrees(), WorktreeListOptions{SortBy: WorktreeSortCommitsAhead})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"wt-milo", "wt-felix", "wt-luna", "wt-oscar"}, worktreeIDs(page.Worktrees))
+
+		_, err = PageWorktrees(s.ListWorktrees(), WorktreeListOptions{SortBy: "size"})
+		assert.ErrorIs(t, err, ErrInvalidWorktreeListOptions)
+	})
+
+	t.Run("offset pagination", func(t *testing.T) {
+		page, err := PageWorktrees(s.ListWorktrees(), WorktreeListOptions{Limit: 3, Offset: 2})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"wt-luna", "wt-milo"}, worktreeIDs(page.Worktrees))
+		assert.Equal(t, 4, page.Total)
+		assert.Empty(t, page.NextCursor)
+
+		page, err = PageWorktrees(s.ListWorktrees(), WorktreeListOptions{Offset: 10})
+		require.NoError(t, err)
+		assert.Empty(t, page.Worktrees)
+
+		_, err = PageWorktrees(s.ListWorktrees(), WorktreeListOptions{Limit: -1})
+		assert.ErrorIs(t, err, ErrInvalidWorktreeListOptions)
+	})
+
+	t.Run("cursor pagination", func(t *testing.T) {
+		var seen []string
+		options := WorktreeListOptions{Limit: 2}
+		first, err := PageWorktrees(s.ListWorktrees(), options)
+		require.NoError(t, err)
+		seen = append(seen, worktreeIDs(first.Worktrees)...)
+		require.NotEmpty(t, first.NextCursor)
+
+		// A worktree added before the cursor's position doesn't shift the next page
+		require.NoError(t, stateManager.AddWorktree(&models.Worktree{
+			ID: "wt-nala", RepoID: "owner/repo", Name: "repo/nala", Path: filepath.Join(root, "wt-nala"), LastAccessed: accessed.Add(2 * time.Hour),
+		}))
+		cache.statuses["wt-nala"] = &CachedWorktreeStatus{WorktreeID: "wt-nala"}
+		options.Cursor = first.NextCursor
+		second, err := PageWorktrees(s.ListWorktrees(), options)
+		require.NoError(t, err)
+		seen = append(seen, worktreeIDs(second.Worktrees)...)
+		assert.Empty(t, second.NextCursor)
+		assert.Equal(t, 5, second.Total)
+		assert.Equal(t, expected, seen)
+
+		_, err = PageWorktrees(s.ListWorktrees(), WorktreeListOptions{SortBy: WorktreeSortName, Cursor: first.NextCursor})
+		assert.ErrorIs(t, err, ErrInvalidWorktreeListOptions, "cursors belong to one order")
+		_, err = PageWorktrees(s.ListWorktrees(), WorktreeListOptions{Cursor: fmt.Sprintf("%s!", first.NextCursor)})
+		assert.ErrorIs(t, err, ErrInvalidWorktreeListOptions)
+	})
+}
@@ -0,0 +1,695 @@
+package services
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+const (
+	// patchStagingDir holds uploaded patches inside the state directory, per worktree
+	patchStagingDir = "patches"
+	// DefaultMaxPatchSizeMB caps the size of a patch applied to a worktree
+	DefaultMaxPatchSizeMB = 256
+	// patchUploadTTL is how long a chunked upload is kept after its last chunk
+	patchUploadTTL = time.Hour
+	// patchApplyTimeout bounds one git apply run
+	patchApplyTimeout = 5 * time.Minute
+)
+
+// Formats of a patch applied to a worktree
+const (
+	PatchFormatDiff        = "diff"
+	PatchFormatFormatPatch = "format-patch"
+)
+
+// ErrPatchTooLarge is returned when a patch is over the size cap
+var ErrPatchTooLarge = errors.New("patch is too large")
+
+var (
+	// patchUploadIDPattern matches the IDs handed out by BeginPatchUpload
+	patchUploadIDPattern = regexp.MustCompile(`^[0-9a-f]{16}$`)
+	// formatPatchFromPattern matches the line starting every mail of git format-patch
+	formatPatchFromPattern = regexp.MustCompile(`^From [0-9a-f]{40,64} `)
+	// hunkHeaderPattern matches a hunk header, capturing its old and new line counts
+	hunkHeaderPattern = regexp.MustCompile(`^@@ -\d+(?:,(\d+))? \+\d+(?:,(\d+))? @@`)
+	// patchSubjectPrefixPattern matches the [PATCH n/m] prefix of a format-patch subject
+	patchSubjectPrefixPattern = regexp.MustCompile(`^(\[[^]]*\]\s*)+`)
+)
+
+// GetMaxPatchSize returns the patch size cap in bytes from CATNIP_MAX_PATCH_MB or the default
+func GetMaxPatchSize() int64 {
+	if sizeStr := os.Getenv("CATNIP_MAX_PATCH_MB"); sizeStr != "" {
+		if size, err := strconv.Atoi(sizeStr); err == nil && size > 0 {
+			return int64(size) << 20
+		}
+	}
+	return DefaultMaxPatchSizeMB << 20
+}
+
+// PatchApplyOptions controls how ApplyPatchToWorktree applies a patch
+type PatchApplyOptions struct {
+	// Fall back to a three-way merge for hunks that don't apply, leaving conflicts to resolve
+	// instead of refusing the patch
+	ThreeWay bool
+	// Commit the patched files afterwards
+	Commit bool
+	// Commit message, defaulting to the subjects of a format-patch series
+	Message string
+	// Only check the patch, reporting what applying it would do
+	DryRun bool
+	// Who applied the patch, for the activity feed
+	Actor string
+}
+
+// patchInfo is what parsePatch learns from a patch before git sees it
+type patchInfo struct {
+	format   string
+	patches  int
+	subjects []string
+	authors  []string
+	paths    []string
+}
+
+// parsePatch reads a unified diff or a git format-patch series, collecting the paths it touches
+// and, for format-patch, the subjects and authors of its mails. Hunk bodies are skipped by their
+// line counts so their content is never mistaken for headers.
+func parsePatch(r io.Reader) (*patchInfo, error) {
+	info := &patchInfo{format: PatchFormatDiff}
+	seen := make(map[string]bool)
+	addPath := func(path string) {
+		if path != "" && !seen[path] {
+			seen[path] = true
+			info.paths = append(info.paths, path)
+		}
+	}
+
+	reader := bufio.NewReader(r)
+	// Lines of the current hunk still to come, on the old and new side
+	oldLeft, newLeft := 0, 0
+	// Set between the From line of a mail and its first diff, headers until the first blank line
+	inMessage, inHeaders := false, false
+	for {
+		line, err := reader.ReadString('\n')
+		if line == "" && err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+
+		if oldLeft > 0 || newLeft > 0 {
+			switch {
+			case strings.HasPrefix(line, "-"):
+				oldLeft--
+			case strings.HasPrefix(line, "+"):
+				newLeft--
+			case strings.HasPrefix(line, "\\"):
+			default:
+				// Context, possibly with its leading space stripped by an editor
+				oldLeft--
+				newLeft--
+			}
+			continue
+		}
+
+		if formatPatchFromPattern.MatchString(line) {
+			info.format = PatchFormatFormatPatch
+			info.patches++
+			inMessage, inHeaders = true, true
+			continue
+		}
+		if inMessage {
+			switch {
+			case strings.HasPrefix(line, "diff --git "):
+				inMessage = false
+			case line == "":
+				inHeaders = false
+				continue
+			case inHeaders && strings.HasPrefix(line, "Subject: "):
+				info.subjects = append(info.subjects, readPatchSubject(reader, line))
+				continue
+			case inHeaders && strings.HasPrefix(line, "From: "):
+				info.authors = append(info.authors, decodeMailHeader(strings.TrimPrefix(line, "From: ")))
+				continue
+			default:
+				continue
+			}
+		}
+
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			for _, path := range diffGitPaths(strings.TrimPrefix(line, "diff --git ")) {
+				addPath(path)
+			}
+		case strings.HasPrefix(line, "--- "), strings.HasPrefix(line, "+++ "):
+			path, err := patchHeaderPath(line[4:])
+			if err != nil {
+				return nil, err
+			}
+			addPath(path)
+		case strings.HasPrefix(line, "rename from "), strings.HasPrefix(line, "rename to "),
+			strings.HasPrefix(line, "copy from "), strings.HasPrefix(line, "copy to "):
+			_, name, _ := strings.Cut(line, " ")
+			_, name, _ = strings.Cut(name, " ")
+			path, err := unquotePatchPath(name)
+			if err != nil {
+				return nil, err
+			}
+			addPath(path)
+		case strings.HasPrefix(line, "Binary files ") && strings.HasSuffix(line, " differ"):
+			return nil, fmt.Errorf("patch has binary changes without their content, create it with git diff --binary or git format-patch")
+		case strings.HasPrefix(line, "@@ "):
+			match := hunkHeaderPattern.FindStringSubmatch(line)
+			if match == nil {
+				return nil, fmt.Errorf("invalid hunk header %q", line)
+			}
+			oldLeft, newLeft = 1, 1
+			if match[1] != "" {
+				oldLeft, _ = strconv.Atoi(match[1])
+			}
+			if match[2] != "" {
+				newLeft, _ = strconv.Atoi(match[2])
+			}
+		}
+	}
+
+	if len(info.paths) == 0 {
+		return nil, fmt.Errorf("no changes found, expected a unified diff or git format-patch output")
+	}
+	return info, nil
+}
+
+// readPatchSubject reads a Subject header, unfolding its continuation lines, and strips the
+// [PATCH n/m] prefix git format-patch adds
+func readPatchSubject(reader *bufio.Reader, line string) string {
+	subject := strings.TrimPrefix(line, "Subject: ")
+	for {
+		next, err := reader.Peek(1)
+		if err != nil || (next[0] != ' ' && next[0] != '\t') {
+			break
+		}
+		continuation, _ := reader.ReadString('\n')
+		subject += " " + strings.TrimSpace(continuation)
+	}
+	subject = decodeMailHeader(subject)
+	return strings.TrimSpace(patchSubjectPrefixPattern.ReplaceAllString(subject, ""))
+}
+
+// decodeMailHeader decodes the RFC 2047 encoded words git format-patch uses for non-ASCII headers
+func decodeMailHeader(value string) string {
+	decoded, err := new(mime.WordDecoder).DecodeHeader(value)
+	if err != nil {
+		return value
+	}
+	return decoded
+}
+
+// diffGitPaths returns the paths of a diff --git header, "a/x b/y". Unquoted names are split
+// where both halves name the same file; other renames are covered by their rename lines.
+func diffGitPaths(names string) []string {
+	if strings.HasPrefix(names, `"`) {
+		var paths []string
+		for names != "" {
+			name, rest, err := cutQuotedPatchPath(names)
+			if err != nil {
+				return paths
+			}
+			paths = append(paths, stripPatchPrefix(name))
+			names = strings.TrimLeft(rest, " ")
+		}
+		return paths
+	}
+	if half := len(names) / 2; len(names)%2 == 1 && names[half] == ' ' {
+		if oldName, newName := stripPatchPrefix(names[:half]), stripPatchPrefix(names[half+1:]); oldName == newName {
+			return []string{oldName}
+		}
+	}
+	if oldName, newName, found := strings.Cut(names, " b/"); found {
+		return []string{stripPatchPrefix(oldName), newName}
+	}
+	return nil
+}
+
+// patchHeaderPath returns the path of a ---/+++ header line, without its a/ or b/ prefix and
+// trailing timestamp; empty for /dev/null
+func patchHeaderPath(name string) (string, error) {
+	if !strings.HasPrefix(name, `"`) {
+		name, _, _ = strings.Cut(name, "\t")
+	}
+	path, err := unquotePatchPath(name)
+	if err != nil || path == "/dev/null" {
+		return "", err
+	}
+	return stripPatchPrefix(path), nil
+}
+
+// unquotePatchPath unquotes a path git quoted for containing special characters
+func unquotePatchPath(name string) (string, error) {
+	if !strings.HasPrefix(name, `"`) {
+		return name, nil
+	}
+	path, _, err := cutQuotedPatchPath(name)
+	return path, err
+}
+
+// cutQuotedPatchPath unquotes the C-style quoted path at the start of s and returns the rest
+func cutQuotedPatchPath(s string) (path, rest string, err error) {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			path, err := strconv.Unquote(s[:i+1])
+			if err != nil {
+				return "", "", fmt.Errorf("invalid quoted path %s", s[:i+1])
+			}
+			return path, s[i+1:], nil
+		}
+	}
+	return "", "", fmt.Errorf("unterminated quoted path %s", s)
+}
+
+// stripPatchPrefix drops the first component of a patch path, like git apply -p1
+func stripPatchPrefix(path string) string {
+	if _, rest, found := strings.Cut(path, "/"); found {
+		return rest
+	}
+	return path
+}
+
+// validatePatchPath refuses patch paths that would write outside a worktree: absolute paths,
+// .. components, the .git directory and paths leading through a symlink
+func validatePatchPath(worktreePath, path string) error {
+	if path == "" || strings.ContainsRune(path, 0) {
+		return fmt.Errorf("patch has an invalid path %q", path)
+	}
+	if strings.HasPrefix(path, "/") || filepath.IsAbs(path) {
+		return fmt.Errorf("patch path %q is absolute", path)
+	}
+	components := strings.Split(path, "/")
+	for _, component := range components {
+		if component == ".." {
+			return fmt.Errorf("patch path %q leaves the worktree", path)
+		}
+		if strings.EqualFold(component, ".git") {
+			return fmt.Errorf("patch path %q is inside the .git directory", path)
+		}
+	}
+	dir := worktreePath
+	for _, component := range components[:len(components)-1] {
+		dir = filepath.Join(dir, component)
+		info, err := os.Lstat(dir)
+		if err != nil {
+			break
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("patch path %q leads through symlink %s", path, strings.TrimPrefix(dir, worktreePath+"/"))
+		}
+	}
+	return nil
+}
+
+// patchCommitMessage returns the message a patch is committed with: the given one, or the
+// subject of a format-patch mail, with the subjects of a series listed below a summary
+func patchCommitMessage(info *patchInfo, message string) (string, error) {
+	if message = strings.TrimSpace(message); message != "" {
+		return message, nil
+	}
+	switch len(info.subjects) {
+	case 0:
+		return "", fmt.Errorf("a commit message is required to commit a plain diff")
+	case 1:
+		return info.subjects[0], nil
+	}
+	lines := make([]string, 0, len(info.subjects))
+	for _, subject := range info.subjects {
+		lines = append(lines, "* "+subject)
+	}
+	return fmt.Sprintf("Apply %d patches\n\n%s", len(info.subjects), strings.Join(lines, "\n")), nil
+}
+
+// patchAuthor returns the author of a format-patch series when every mail has the same one
+func patchAuthor(info *patchInfo) string {
+	if len(info.authors) == 0 {
+		return ""
+	}
+	for _, author := range info.authors[1:] {
+		if author != info.authors[0] {
+			return ""
+		}
+	}
+	if address, err := mail.ParseAddress(info.authors[0]); err == nil && address.Name != "" {
+		return fmt.Sprintf("%s <%s>", address.Name, address.Address)
+	}
+	return ""
+}
+
+// patchDir returns the directory staging patches for a worktree
+func (s *GitService) patchDir(worktreeID string) string {
+	return filepath.Join(s.stateManager.stateDir, patchStagingDir, worktreeID)
+}
+
+// stagePatch streams a patch into a file of the worktree's staging directory, refusing patches
+// over the size cap
+func (s *GitService) stagePatch(worktreeID string, patch io.Reader) (string, error) {
+	dir := s.patchDir(worktreeID)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create patch directory: %v", err)
+	}
+	file, err := os.CreateTemp(dir, "apply-*.patch")
+	if err != nil {
+		return "", fmt.Errorf("failed to stage patch: %v", err)
+	}
+	defer file.Close()
+
+	maxSize := GetMaxPatchSize()
+	written, err := io.Copy(file, io.LimitReader(patch, maxSize+1))
+	if err == nil && written > maxSize {
+		err = fmt.Errorf("%w: over the %d MiB limit", ErrPatchTooLarge, maxSize>>20)
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		if errors.Is(err, ErrPatchTooLarge) {
+			return "", err
+		}
+		return "", fmt.Errorf("failed to stage patch: %v", err)
+	}
+	return file.Name(), nil
+}
+
+// ApplyPatchToWorktree applies a unified diff or a git format-patch series, git binary patches
+// included, to the working tree and index of a worktree. Every path the patch touches has to
+// stay inside the worktree. With ThreeWay, hunks that don't apply cleanly are merged and
+// conflicts are returned as a MergeConflictError with operation "patch", left in the worktree to
+// resolve; otherwise a patch that doesn't apply changes nothing. Commit commits the patched
+// files unless there were conflicts. DryRun only checks the patch.
+func (s *GitService) ApplyPatchToWorktree(worktreeID string, patch io.Reader, opts PatchApplyOptions) (*models.PatchApplyResult, error) {
+	worktree, exists := s.stateManager.GetWorktree(worktreeID)
+	if !exists {
+		return nil, fmt.Errorf("worktree %s not found", worktreeID)
+	}
+	path, err := s.stagePatch(worktreeID, patch)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(path)
+	return s.applyStagedPatch(worktree, path, opts)
+}
+
+// applyStagedPatch implements ApplyPatchToWorktree for a patch staged at patchPath
+func (s *GitService) applyStagedPatch(worktree *models.Worktree, patchPath string, opts PatchApplyOptions) (*models.PatchApplyResult, error) {
+	file, err := os.Open(patchPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read patch: %v", err)
+	}
+	info, err := parsePatch(file)
+	file.Close()
+	if err != nil {
+		return nil, fmt.Errorf("invalid patch: %v", err)
+	}
+	for _, path := range info.paths {
+		if err := validatePatchPath(worktree.Path, path); err != nil {
+			return nil, err
+		}
+	}
+	message := ""
+	if opts.Commit {
+		if message, err = patchCommitMessage(info, opts.Message); err != nil {
+			return nil, err
+		}
+	}
+
+	defer s.shareRepository(worktree.Path)()
+	if s.gitOperationInProgress(worktree.Path) {
+		return nil, fmt.Errorf("cannot apply a patch to %s while a merge, rebase or cherry-pick is in progress, finish or abort it first", worktree.Name)
+	}
+
+	result := &models.PatchApplyResult{WorktreeID: worktree.ID, Format: info.format, Patches: max(info.patches, 1), Files: []string{}, DryRun: opts.DryRun}
+	if err := s.patchNumstat(worktree.Path, patchPath, result); err != nil {
+		return nil, err
+	}
+
+	args := []string{"apply"}
+	if opts.ThreeWay {
+		args = append(args, "--3way")
+	} else {
+		args = append(args, "--index")
+	}
+	if opts.DryRun {
+		args = append(args, "--check")
+	}
+	output, err := s.operations.ExecuteGitWithTimeout(worktree.Path, patchApplyTimeout, append(args, patchPath)...)
+	if opts.DryRun {
+		if err != nil {
+			return nil, fmt.Errorf("patch does not apply to %s: %s", worktree.Name, gitStderr(err))
+		}
+		if opts.ThreeWay {
+			// git apply --3way --check succeeds for patches that would conflict, naming the
+			// files on stderr only
+			_, stderr, _ := s.operations.ExecuteGitWithStdErr(worktree.Path, append(args, patchPath)...)
+			for _, line := range strings.Split(string(stderr), "\n") {
+				if name, found := strings.CutPrefix(line, "Applied patch to '"); found && strings.HasSuffix(name, "' with conflicts.") {
+					result.ConflictFiles = append(result.ConflictFiles, strings.TrimSuffix(name, "' with conflicts."))
+				}
+			}
+		}
+		return result, nil
+	}
+
+	defer func() {
+		if s.worktreeCache != nil {
+			s.worktreeCache.ForceRefresh(worktree.ID)
+		}
+	}()
+	if err != nil {
+		combined := string(output) + err.Error()
+		if opts.ThreeWay && (strings.Contains(combined, "with conflicts") || s.isMergeConflict(worktree.Path, combined)) {
+			conflict := s.createMergeConflictError("patch", worktree, combined)
+			conflict.Message = fmt.Sprintf("Patch applied to worktree '%s' with conflicts in %d files, nothing was committed. Resolve them in the terminal and commit.",
+				worktree.Name, len(conflict.ConflictFiles))
+			s.recordPatchApplied(worktree, opts.Actor, result, "with conflicts")
+			return nil, conflict
+		}
+		return nil, fmt.Errorf("patch does not apply to %s: %s", worktree.Name, gitStderr(err))
+	}
+
+	if opts.Commit {
+		args := []string{"commit", "-m", message}
+		if author := patchAuthor(info); author != "" {
+			args = append(args, "--author", author)
+		}
+		args = append(append(args, "--"), info.paths...)
+		if output, err := s.runGitCommitWithGPGFallback(worktree.Path, args...); err != nil {
+			return nil, fmt.Errorf("patch applied to %s but committing it failed: %s %s", worktree.Name, strings.TrimSpace(string(output)), gitStderr(err))
+		}
+		result.Commit, _ = s.operations.GetCommitHash(worktree.Path, "HEAD")
+	}
+
+	s.recordPatchApplied(worktree, opts.Actor, result, "")
+	return result, nil
+}
+
+// gitStderr returns what a failed git command printed on stderr, or the error itself
+func gitStderr(err error) string {
+	if _, stderr, found := strings.Cut(err.Error(), "stderr: "); found && strings.TrimSpace(stderr) != "" {
+		return strings.TrimSpace(stderr)
+	}
+	return err.Error()
+}
+
+// patchNumstat fills the files and line counts of a result from git apply --numstat, which
+// reads the patch without applying it
+func (s *GitService) patchNumstat(worktreePath, patchPath string, result *models.PatchApplyResult) error {
+	output, err := s.operations.ExecuteGitWithTimeout(worktreePath, patchApplyTimeout, "apply", "--numstat", patchPath)
+	if err != nil {
+		return fmt.Errorf("invalid patch: %s", gitStderr(err))
+	}
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		path, err := unquotePatchPath(fields[2])
+		if err != nil {
+			path = fields[2]
+		}
+		result.Files = append(result.Files, path)
+		if fields[0] == "-" {
+			result.BinaryFiles = append(result.BinaryFiles, path)
+			continue
+		}
+		additions, _ := strconv.Atoi(fields[0])
+		deletions, _ := strconv.Atoi(fields[1])
+		result.Additions += additions
+		result.Deletions += deletions
+	}
+	return nil
+}
+
+// recordPatchApplied logs a patch applied to a worktree and adds it to the activity feed
+func (s *GitService) recordPatchApplied(worktree *models.Worktree, actor string, result *models.PatchApplyResult, outcome string) {
+	summary := fmt.Sprintf("Applied a patch to %d files (+%d -%d)", len(result.Files), result.Additions, result.Deletions)
+	if result.Patches > 1 {
+		summary = fmt.Sprintf("Applied %d patches to %d files (+%d -%d)", result.Patches, len(result.Files), result.Additions, result.Deletions)
+	}
+	switch {
+	case outcome != "":
+		summary += " " + outcome
+	case result.Commit != "":
+		summary += ", committed as " + result.Commit[:min(len(result.Commit), 7)]
+	}
+	logger.Infof("🩹 %s: %s in %s", actor, summary, worktree.Name)
+	s.stateManager.recordWorktreeActivity(ActivityPatchApplied, worktree, actor, summary, "")
+}
+
+// patchUploadPath returns the file of a chunked upload to a worktree
+func (s *GitService) patchUploadPath(worktreeID, uploadID string) (string, error) {
+	if !patchUploadIDPattern.MatchString(uploadID) {
+		return "", fmt.Errorf("patch upload %s not found", uploadID)
+	}
+	return filepath.Join(s.patchDir(worktreeID), "upload-"+uploadID+".patch"), nil
+}
+
+// patchUpload describes the chunked upload stored at path
+func patchUpload(worktreeID, uploadID, path string) (*models.PatchUpload, error) {
+	stat, err := os.Stat(path)
+	if err != nil || time.Since(stat.ModTime()) > patchUploadTTL {
+		return nil, fmt.Errorf("patch upload %s not found", uploadID)
+	}
+	return &models.PatchUpload{
+		ID:         uploadID,
+		WorktreeID: worktreeID,
+		Size:       stat.Size(),
+		MaxSize:    GetMaxPatchSize(),
+		ExpiresAt:  stat.ModTime().Add(patchUploadTTL),
+	}, nil
+}
+
+// removeExpiredPatchUploads deletes the uploads to a worktree that saw no chunk for patchUploadTTL
+func (s *GitService) removeExpiredPatchUploads(worktreeID string) {
+	paths, _ := filepath.Glob(filepath.Join(s.patchDir(worktreeID), "upload-*.patch"))
+	for _, path := range paths {
+		if stat, err := os.Stat(path); err == nil && time.Since(stat.ModTime()) > patchUploadTTL {
+			os.Remove(path)
+		}
+	}
+}
+
+// BeginPatchUpload starts a chunked upload of a patch too large for one request. Chunks are
+// added in order with AppendPatchUpload and the upload is applied with ApplyPatchUpload; it is
+// discarded after an hour without chunks.
+func (s *GitService) BeginPatchUpload(worktreeID string) (*models.PatchUpload, error) {
+	if _, exists := s.stateManager.GetWorktree(worktreeID); !exists {
+		return nil, fmt.Errorf("worktree %s not found", worktreeID)
+	}
+	s.removeExpiredPatchUploads(worktreeID)
+
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+	uploadID := hex.EncodeToString(buf)
+	path, _ := s.patchUploadPath(worktreeID, uploadID)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create patch directory: %v", err)
+	}
+	if err := os.WriteFile(path, nil, 0644); err != nil {
+		return nil, fmt.Errorf("failed to start patch upload: %v", err)
+	}
+	return patchUpload(worktreeID, uploadID, path)
+}
+
+// AppendPatchUpload adds a chunk to an upload at offset, which has to be the size received so
+// far, so a retried chunk is refused instead of being appended twice
+func (s *GitService) AppendPatchUpload(worktreeID, uploadID string, offset int64, chunk io.Reader) (*models.PatchUpload, error) {
+	path, err := s.patchUploadPath(worktreeID, uploadID)
+	if err != nil {
+		return nil, err
+	}
+	s.patchUploadMu.Lock()
+	defer s.patchUploadMu.Unlock()
+
+	upload, err := patchUpload(worktreeID, uploadID, path)
+	if err != nil {
+		return nil, err
+	}
+	if offset != upload.Size {
+		return nil, fmt.Errorf("chunk offset %d doesn't match the %d bytes received", offset, upload.Size)
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open patch upload: %v", err)
+	}
+	written, err := io.Copy(file, io.LimitReader(chunk, upload.MaxSize-upload.Size+1))
+	file.Close()
+	if err == nil && upload.Size+written > upload.MaxSize {
+		err = fmt.Errorf("%w: over the %d MiB limit", ErrPatchTooLarge, upload.MaxSize>>20)
+	}
+	if err != nil {
+		// A partial chunk is dropped so it can be retried at the same offset
+		_ = os.Truncate(path, upload.Size)
+		if errors.Is(err, ErrPatchTooLarge) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to store chunk: %v", err)
+	}
+	return patchUpload(worktreeID, uploadID, path)
+}
+
+// ApplyPatchUpload applies a completed chunked upload like ApplyPatchToWorktree. The upload is
+// kept after a dry run and removed otherwise.
+func (s *GitService) ApplyPatchUpload(worktreeID, uploadID string, opts PatchApplyOptions) (*models.PatchApplyResult, error) {
+	worktree, exists := s.stateManager.GetWorktree(worktreeID)
+	if !exists {
+		return nil, fmt.Errorf("worktree %s not found", worktreeID)
+	}
+	path, err := s.patchUploadPath(worktreeID, uploadID)
+	if err != nil {
+		return nil, err
+	}
+	s.patchUploadMu.Lock()
+	_, err = patchUpload(worktreeID, uploadID, path)
+	s.patchUploadMu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	if !opts.DryRun {
+		defer os.Remove(path)
+	}
+	return s.applyStagedPatch(worktree, path, opts)
+}
+
+// DiscardPatchUpload removes a chunked upload without applying it
+func (s *GitService) DiscardPatchUpload(worktreeID, uploadID string) error {
+	path, err := s.patchUploadPath(worktreeID, uploadID)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("patch upload %s not found", uploadID)
+	}
+	return nil
+}
+
+// removeWorktreePatches deletes the staged patches and uploads of a worktree
+func (s *GitService) removeWorktreePatches(worktreeID string) {
+	if err := os.RemoveAll(s.patchDir(worktreeID)); err != nil {
+		logger.Debugf("⚠️ Failed to remove patches of worktree %s: %v", worktreeID, err)
+	}
+}
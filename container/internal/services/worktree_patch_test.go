@@ -0,0 +1,215 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/git"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+func TestParsePatch(t *testing.T) {
+	series := `From 1a2b3c4d5e6f7a8b9c0d1e2f3a4b5c6d7e8f9a0b Mon Sep 17 00:00:00 2001
+From: Felix Cat <felix@catnip.local>
+Date: Tue, 1 Oct 2024 10:00:00 +0000
+Subject: [PATCH 1/2] Teach the app
+ to purr
+
+--- a/not/a/header
+---
+ app.txt | 2 +-
+ 1 file changed, 1 insertion(+), 1 deletion(-)
+
+diff --git a/app.txt b/app.txt
+index 626799f..8c1384d 100644
+--- a/app.txt
++++ b/app.txt
+@@ -1,2 +1,2 @@
+--- v1
++++ v2
+ keep
+--
+2.39.5
+
+From 2b3c4d5e6f7a8b9c0d1e2f3a4b5c6d7e8f9a0b1c Mon Sep 17 00:00:00 2001
+From: Felix Cat <felix@catnip.local>
+Subject: [PATCH 2/2] =?UTF-8?q?Rename=20caf=C3=A9?=
+
+diff --git "a/caf\303\251.txt" b/cafe.txt
+similarity index 100%
+rename from "caf\303\251.txt"
+rename to cafe.txt
+diff --git a/logo.png b/logo.png
+new file mode 100644
+index 0000000..1b2c3d4
+GIT binary patch
+literal 4
+LcmZ?wbhEZ@kcx
+
+literal 0
+HcmV?d00001
+
+--
+2.39.5
+`
+	info, err := parsePatch(strings.NewReader(series))
+	require.NoError(t, err)
+	assert.Equal(t, PatchFormatFormatPatch, info.format)
+	assert.Equal(t, 2, info.patches)
+	assert.Equal(t, []string{"Teach the app to purr", "Rename café"}, info.subjects)
+	assert.Equal(t, []string{"app.txt", "café.txt", "cafe.txt", "logo.png"}, info.paths, "hunk lines and the message aren't headers")
+	assert.Equal(t, "Felix Cat <felix@catnip.local>", patchAuthor(info))
+
+	message, err := patchCommitMessage(info, "")
+	require.NoError(t, err)
+	assert.Equal(t, "Apply 2 patches\n\n* Teach the app to purr\n* Rename café", message)
+
+	info, err = parsePatch(strings.NewReader("--- app.txt.orig\t2024-10-01 10:00:00\n+++ app.txt\t2024-10-01 10:00:01\n@@ -1 +1 @@\n-v1\n+v2\n"))
+	require.NoError(t, err)
+	assert.Equal(t, PatchFormatDiff, info.format)
+	_, err = patchCommitMessage(info, " ")
+	assert.ErrorContains(t, err, "commit message is required")
+
+	_, err = parsePatch(strings.NewReader("diff --git a/logo.png b/logo.png\nBinary files a/logo.png and b/logo.png differ\n"))
+	assert.ErrorContains(t, err, "without their content")
+	_, err = parsePatch(strings.NewReader("just some text\n"))
+	assert.ErrorContains(t, err, "no changes found")
+}
+
+func TestValidatePatchPath(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "src"), 0755))
+	require.NoError(t, os.Symlink(t.TempDir(), filepath.Join(dir, "outside")))
+
+	assert.NoError(t, validatePatchPath(dir, "src/app.go"))
+	assert.NoError(t, validatePatchPath(dir, "new/dir/file.txt"))
+	assert.NoError(t, validatePatchPath(dir, "outside"), "the symlink itself may be changed")
+	assert.ErrorContains(t, validatePatchPath(dir, "/etc/passwd"), "absolute")
+	assert.ErrorContains(t, validatePatchPath(dir, "src/../../escape"), "leaves the worktree")
+	assert.ErrorContains(t, validatePatchPath(dir, ".git/hooks/pre-commit"), ".git directory")
+	assert.ErrorContains(t, validatePatchPath(dir, "sub/.GIT/config"), ".git directory")
+	assert.ErrorContains(t, validatePatchPath(dir, "outside/file.txt"), "symlink outside")
+}
+
+func TestApplyPatchToWorktree(t *testing.T) {
+	s, stateManager, repoPath, worktreePath := newRecreateTestService(t)
+	s.conflictResolver = git.NewConflictResolver(s.operations)
+	s.worktreeCache = NewWorktreeStatusCache(s.operations, stateManager)
+	t.Cleanup(s.worktreeCache.Stop)
+
+	// Patches are made in the repository and applied to the worktree
+	makePatch := func(args ...string) string {
+		runTestGit(t, repoPath, "add", "-A")
+		runTestGit(t, repoPath, "commit", "-q", "-m", "Update the app")
+		patch := runTestGit(t, repoPath, args...) + "\n"
+		runTestGit(t, repoPath, "reset", "-q", "--hard", "HEAD~1")
+		return patch
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(repoPath, "app.txt"), []byte("v2\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(repoPath, "logo.bin"), []byte{0, 1, 2, 0xff}, 0644))
+	formatPatch := makePatch("format-patch", "--stdout", "-1", "HEAD")
+
+	t.Run("DryRunWritesNothing", func(t *testing.T) {
+		result, err := s.ApplyPatchToWorktree("wt-felix", strings.NewReader(formatPatch), PatchApplyOptions{ThreeWay: true, DryRun: true})
+		require.NoError(t, err)
+		assert.True(t, result.DryRun)
+		assert.Equal(t, PatchFormatFormatPatch, result.Format)
+		assert.Equal(t, []string{"app.txt", "logo.bin"}, result.Files)
+		assert.Equal(t, []string{"logo.bin"}, result.BinaryFiles)
+		assert.Equal(t, 1, result.Additions)
+		assert.Equal(t, 1, result.Deletions)
+		assert.Empty(t, result.ConflictFiles)
+		assert.NoFileExists(t, filepath.Join(worktreePath, "logo.bin"))
+	})
+
+	t.Run("CommitsWithTheMailsSubjectAndAuthor", func(t *testing.T) {
+		result, err := s.ApplyPatchToWorktree("wt-felix", strings.NewReader(formatPatch), PatchApplyOptions{ThreeWay: true, Commit: true, Actor: "alice"})
+		require.NoError(t, err)
+		require.NotEmpty(t, result.Commit)
+		assert.Equal(t, result.Commit, runTestGit(t, worktreePath, "rev-parse", "HEAD"))
+		assert.Equal(t, "Update the app|Test", runTestGit(t, worktreePath, "log", "-1", "--format=%s|%an"))
+		data, err := os.ReadFile(filepath.Join(worktreePath, "logo.bin"))
+		require.NoError(t, err)
+		assert.Equal(t, []byte{0, 1, 2, 0xff}, data, "binary literal applied")
+		assert.Empty(t, runTestGit(t, worktreePath, "status", "--porcelain"))
+
+		activity, err := s.GetRepositoryActivity("local/repo", time.Now().Add(-time.Hour), time.Now().Add(time.Minute))
+		require.NoError(t, err)
+		var summaries []string
+		for _, entry := range activity.Events {
+			if entry.Kind == ActivityPatchApplied {
+				summaries = append(summaries, entry.Actor+": "+entry.Summary)
+			}
+		}
+		assert.Equal(t, []string{"alice: Applied a patch to 2 files (+1 -1), committed as " + result.Commit[:7]}, summaries)
+	})
+
+	t.Run("ConflictsAreLeftToResolve", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(filepath.Join(repoPath, "app.txt"), []byte("v1\nfrom repo\n"), 0644))
+		runTestGit(t, repoPath, "commit", "-q", "-am", "Extend the app")
+		require.NoError(t, os.WriteFile(filepath.Join(repoPath, "app.txt"), []byte("v3\nfrom repo\n"), 0644))
+		diff := makePatch("diff", "HEAD~1", "HEAD")
+
+		_, err := s.ApplyPatchToWorktree("wt-felix", strings.NewReader(diff), PatchApplyOptions{ThreeWay: false})
+		assert.ErrorContains(t, err, "patch does not apply")
+		assert.Empty(t, runTestGit(t, worktreePath, "status", "--porcelain"), "a failed apply changes nothing")
+
+		result, err := s.ApplyPatchToWorktree("wt-felix", strings.NewReader(diff), PatchApplyOptions{ThreeWay: true, DryRun: true})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"app.txt"}, result.ConflictFiles)
+
+		_, err = s.ApplyPatchToWorktree("wt-felix", strings.NewReader(diff), PatchApplyOptions{ThreeWay: true, Commit: true, Message: "Apply v3"})
+		var conflict *models.MergeConflictError
+		require.ErrorAs(t, err, &conflict)
+		assert.Equal(t, "patch", conflict.Operation)
+		assert.Equal(t, []string{"app.txt"}, conflict.ConflictFiles)
+		assert.Equal(t, "UU app.txt", runTestGit(t, worktreePath, "status", "--porcelain"), "nothing committed")
+		runTestGit(t, worktreePath, "reset", "-q", "--hard")
+	})
+
+	t.Run("UnsafePathsAreRefused", func(t *testing.T) {
+		patch := "diff --git a/.git/hooks/post-checkout b/.git/hooks/post-checkout\nnew file mode 100755\n--- /dev/null\n+++ b/.git/hooks/post-checkout\n@@ -0,0 +1 @@\n+#!/bin/sh\n"
+		_, err := s.ApplyPatchToWorktree("wt-felix", strings.NewReader(patch), PatchApplyOptions{ThreeWay: true})
+		assert.ErrorContains(t, err, ".git directory")
+		_, err = s.ApplyPatchToWorktree("wt-missing", strings.NewReader(patch), PatchApplyOptions{})
+		assert.ErrorContains(t, err, "not found")
+	})
+
+	t.Run("ChunkedUpload", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(filepath.Join(repoPath, "notes.txt"), []byte("purr\n"), 0644))
+		diff := makePatch("diff", "HEAD~1", "HEAD")
+
+		upload, err := s.BeginPatchUpload("wt-felix")
+		require.NoError(t, err)
+		half := len(diff) / 2
+		upload, err = s.AppendPatchUpload("wt-felix", upload.ID, 0, strings.NewReader(diff[:half]))
+		require.NoError(t, err)
+		assert.Equal(t, int64(half), upload.Size)
+		_, err = s.AppendPatchUpload("wt-felix", upload.ID, 0, strings.NewReader(diff[:half]))
+		assert.ErrorContains(t, err, "doesn't match", "a retried chunk isn't appended twice")
+		_, err = s.AppendPatchUpload("wt-felix", upload.ID, int64(half), strings.NewReader(diff[half:]))
+		require.NoError(t, err)
+
+		result, err := s.ApplyPatchUpload("wt-felix", upload.ID, PatchApplyOptions{ThreeWay: true})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"notes.txt"}, result.Files)
+		assert.Empty(t, result.Commit)
+		assert.Equal(t, "A  notes.txt", runTestGit(t, worktreePath, "status", "--porcelain"))
+		_, err = s.ApplyPatchUpload("wt-felix", upload.ID, PatchApplyOptions{})
+		assert.ErrorContains(t, err, "not found", "applied uploads are removed")
+		_, err = s.AppendPatchUpload("wt-felix", "../../etc", 0, strings.NewReader(""))
+		assert.ErrorContains(t, err, "not found")
+
+		t.Setenv("CATNIP_MAX_PATCH_MB", "1")
+		upload, err = s.BeginPatchUpload("wt-felix")
+		require.NoError(t, err)
+		_, err = s.AppendPatchUpload("wt-felix", upload.ID, 0, strings.NewReader(strings.Repeat("x", 1<<20+1)))
+		assert.ErrorIs(t, err, ErrPatchTooLarge)
+		require.NoError(t, s.DiscardPatchUpload("wt-felix", upload.ID))
+	})
+}
@@ -0,0 +1,250 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vanpelt/catnip/internal/config"
+	"github.com/vanpelt/catnip/internal/git"
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// What reconciliation does with worktrees in state that git no longer has registered
+const (
+	// ReconcileMissingFlag keeps them in state, flagged missing
+	ReconcileMissingFlag = "flag"
+	// ReconcileMissingRemove drops them from state, leaving their branch alone
+	ReconcileMissingRemove = "remove"
+)
+
+// worktreeImportGrace is how long a git worktree must have existed before reconciliation imports
+// it, so worktrees catnip is still adding aren't imported a second time
+var worktreeImportGrace = time.Minute
+
+// GetReconcileMissingMode returns what reconciliation does with worktrees git no longer knows,
+// from CATNIP_RECONCILE_MISSING (flag or remove, default flag)
+func GetReconcileMissingMode() string {
+	if strings.EqualFold(strings.TrimSpace(os.Getenv("CATNIP_RECONCILE_MISSING")), ReconcileMissingRemove) {
+		return ReconcileMissingRemove
+	}
+	return ReconcileMissingFlag
+}
+
+// ReconcileWorktrees compares the worktrees in state with git worktree list of every repository
+// on disk. Worktrees git no longer knows, e.g. after git worktree remove ran in a shell, are
+// flagged missing or removed from state depending on mode (ReconcileMissingFlag or
+// ReconcileMissingRemove, empty for CATNIP_RECONCILE_MISSING); worktrees git knows but state
+// doesn't are imported when they are in the workspace directory, and flagged otherwise.
+func (s *GitService) ReconcileWorktrees(mode string) (*models.WorktreeReconciliation, error) {
+	return s.reconcileWorktrees(mode, worktreeImportGrace)
+}
+
+// reconcileWorktrees is ReconcileWorktrees, importing git worktrees older than importGrace.
+// Nothing else adds worktrees on boot, so the grace only applies to a running server.
+func (s *GitService) reconcileWorktrees(mode string, importGrace time.Duration) (*models.WorktreeReconciliation, error) {
+	switch mode {
+	case "":
+		mode = GetReconcileMissingMode()
+	case ReconcileMissingFlag, ReconcileMissingRemove:
+	default:
+		return nil, fmt.Errorf("invalid missing worktree mode %q, expected %s or %s", mode, ReconcileMissingFlag, ReconcileMissingRemove)
+	}
+
+	result := &models.WorktreeReconciliation{
+		MissingMode: mode,
+		Added:       []models.StateReportEntry{},
+		Removed:     []models.StateReportEntry{},
+		Flagged:     []models.StateReportEntry{},
+		Recovered:   []models.StateReportEntry{},
+	}
+	repos := s.stateManager.GetAllRepositories()
+	ids := make([]string, 0, len(repos))
+	for id := range repos {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		// Repositories gone from disk are reported as unavailable by RestoreState
+		if _, err := os.Stat(repos[id].Path); err != nil {
+			continue
+		}
+		s.reconcileRepositoryWorktrees(repos[id], mode, importGrace, result)
+	}
+
+	for _, entries := range [][]models.StateReportEntry{result.Added, result.Removed, result.Flagged, result.Recovered} {
+		sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	}
+	if changes := len(result.Added) + len(result.Removed) + len(result.Flagged); changes > 0 {
+		logger.Infof("🔄 Reconciled worktrees with git: %d imported, %d removed, %d flagged",
+			len(result.Added), len(result.Removed), len(result.Flagged))
+	}
+	return result, nil
+}
+
+// reconcileRepositoryWorktrees reconciles the worktrees of one repository, adding to result
+func (s *GitService) reconcileRepositoryWorktrees(repo *models.Repository, mode string, importGrace time.Duration, result *models.WorktreeReconciliation) {
+	registered, err := s.operations.ListWorktrees(repo.Path)
+	if err != nil {
+		logger.Warnf("⚠️ Failed to list the git worktrees of %s, not reconciling them: %v", repo.ID, err)
+		return
+	}
+	registeredPaths := make(map[string]bool, len(registered))
+	for _, info := range registered {
+		registeredPaths[config.CanonicalPath(info.Path)] = true
+	}
+
+	known := make(map[string]bool)
+	names := make(map[string]bool)
+	for _, worktree := range s.stateManager.GetAllWorktrees() {
+		names[worktree.Name] = true
+		if worktree.RepoID != repo.ID {
+			continue
+		}
+		path := config.CanonicalPath(worktree.Path)
+		known[path] = true
+		entry := models.StateReportEntry{Kind: "worktree", ID: worktree.ID, Name: worktree.Name}
+
+		if registeredPaths[path] {
+			if worktree.Missing {
+				if err := s.stateManager.UpdateWorktree(worktree.ID, map[string]interface{}{"missing": false}); err != nil {
+					logger.Warnf("⚠️ Failed to clear the missing flag of %s: %v", worktree.Name, err)
+					continue
+				}
+				logger.Infof("✅ Worktree %s is registered with git again", worktree.Name)
+				entry.Reason = "registered with git again"
+				result.Recovered = append(result.Recovered, entry)
+			}
+			continue
+		}
+
+		entry.Reason = fmt.Sprintf("git has no worktree at %s", worktree.Path)
+		if mode == ReconcileMissingRemove {
+			logger.Warnf("🧹 Removing worktree %s from state: %s", worktree.Name, entry.Reason)
+			s.forgetWorktree(worktree)
+			result.Removed = append(result.Removed, entry)
+			continue
+		}
+		if !worktree.Missing {
+			logger.Warnf("⚠️ Flagging worktree %s as missing: %s", worktree.Name, entry.Reason)
+			if err := s.stateManager.UpdateWorktree(worktree.ID, map[string]interface{}{"missing": true}); err != nil {
+				logger.Warnf("⚠️ Failed to flag worktree %s as missing: %v", worktree.Name, err)
+				continue
+			}
+			s.stateManager.RecordStaleWorktree(entry)
+		}
+		result.Flagged = append(result.Flagged, entry)
+	}
+
+	var imported []*models.Worktree
+	for _, info := range registered {
+		path := config.CanonicalPath(info.Path)
+		// The main checkout of a local repository is listed first; locked worktrees include
+		// the checkouts a recreation set aside
+		if info.Bare || info.Prunable || info.Locked || known[path] || config.SamePath(info.Path, repo.Path) {
+			continue
+		}
+		worktree, reason := s.importableWorktree(repo, info, importGrace, names)
+		if worktree == nil {
+			if reason != "" {
+				result.Flagged = append(result.Flagged, models.StateReportEntry{Kind: "git_worktree", ID: info.Path, Name: filepath.Base(info.Path), Reason: reason})
+			}
+			continue
+		}
+		names[worktree.Name] = true
+		imported = append(imported, worktree)
+	}
+	if len(imported) == 0 {
+		return
+	}
+	if err := s.stateManager.AddWorktrees(imported); err != nil {
+		logger.Warnf("⚠️ Failed to import the git worktrees of %s: %v", repo.ID, err)
+		return
+	}
+	for _, worktree := range imported {
+		logger.Infof("📥 Imported git worktree %s at %s on %s", worktree.Name, worktree.Path, worktree.Branch)
+		s.startWorktree(worktree, false)
+		result.Added = append(result.Added, models.StateReportEntry{Kind: "worktree", ID: worktree.ID, Name: worktree.Name, Reason: "found in git worktree list"})
+	}
+}
+
+// importableWorktree synthesizes the model of a git worktree that isn't in state, or returns why
+// it can't be imported. Both are empty for worktrees younger than importGrace. Checkouts outside
+// the workspace directory belong to someone else, like a user's second checkout of a local
+// repository, and are never imported.
+func (s *GitService) importableWorktree(repo *models.Repository, info git.WorktreeInfo, importGrace time.Duration, names map[string]bool) (*models.Worktree, string) {
+	if workspaceDir := getWorkspaceDir(); !isWithinDir(workspaceDir, info.Path) {
+		return nil, fmt.Sprintf("%s is outside the workspace %s, catnip only imports worktrees there", info.Path, workspaceDir)
+	}
+	gitFile, err := os.Stat(filepath.Join(info.Path, ".git"))
+	if err != nil {
+		return nil, fmt.Sprintf("%s has no .git, it isn't a git checkout", info.Path)
+	}
+	if time.Since(gitFile.ModTime()) < importGrace {
+		logger.Debugf("⏳ Not importing git worktree %s yet, it was just added", info.Path)
+		return nil, ""
+	}
+	if info.Detached || info.Branch == "" {
+		return nil, "HEAD is detached, there is no branch to track"
+	}
+
+	prefix := filepath.Base(repo.Path)
+	if !strings.HasPrefix(repo.ID, "local/") {
+		prefix = repo.ID[strings.LastIndex(repo.ID, "/")+1:]
+	}
+	name := prefix + "/" + filepath.Base(info.Path)
+	if names[name] {
+		return nil, fmt.Sprintf("another worktree is already named %s", name)
+	}
+
+	branch := strings.TrimPrefix(info.Branch, "refs/heads/")
+	source := s.orphanBranchBase(repo)
+	worktree := &models.Worktree{
+		ID:           uuid.New().String(),
+		RepoID:       repo.ID,
+		Name:         name,
+		Path:         info.Path,
+		Branch:       branch,
+		SourceBranch: source,
+		CommitHash:   info.Commit,
+		CreatedAt:    gitFile.ModTime(),
+		LastAccessed: time.Now(),
+		Notes:        []string{"Imported from a git worktree catnip didn't create"},
+	}
+	if source != "" && source != branch {
+		if output, err := s.operations.ExecuteGit(info.Path, "merge-base", source, "HEAD"); err == nil {
+			worktree.CommitHash = strings.TrimSpace(string(output))
+		}
+		if count, err := s.operations.GetCommitCount(info.Path, source, "HEAD"); err == nil {
+			worktree.CommitCount = count
+		}
+	}
+	worktree.Toolchains = DetectToolchains(info.Path)
+	worktree.CreationContext = autoCreationContext(worktree)
+	return worktree, ""
+}
+
+// forgetWorktree removes a worktree git no longer has from state and stops watching it, leaving
+// its branch and any files alone
+func (s *GitService) forgetWorktree(worktree *models.Worktree) {
+	if s.worktreeCache != nil {
+		s.worktreeCache.RemoveWorktree(worktree.ID, worktree.Path)
+	}
+	if s.commitSync != nil {
+		s.commitSync.RemoveWorktreeWatcher(worktree.Path)
+	}
+	s.diffCache.forget(worktree.ID)
+	s.removeWorktreeBundles(worktree.ID)
+	s.removeWorktreePatches(worktree.ID)
+	if err := s.stateManager.DeleteWorktree(worktree.ID); err != nil {
+		logger.Warnf("⚠️ Failed to delete worktree %s from state: %v", worktree.Name, err)
+	}
+	if s.claudeMonitor != nil {
+		s.claudeMonitor.OnWorktreeDeleted(worktree.ID, worktree.Path)
+	}
+}
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReconcileWorktrees(t *testing.T) {
+	s, stateManager, repoPath, worktreePath := newRecreateTestService(t)
+	s.worktreeCache = NewWorktreeStatusCache(s.operations, stateManager)
+	t.Cleanup(s.worktreeCache.Stop)
+	stateManager.SetWorktreeRestorer(s)
+
+	// A worktree added and one removed in a shell
+	lunaPath := filepath.Join(filepath.Dir(worktreePath), "luna")
+	runTestGit(t, repoPath, "worktree", "add", "-q", "-b", "luna", lunaPath)
+	require.NoError(t, os.WriteFile(filepath.Join(lunaPath, "luna.txt"), []byte("meow\n"), 0644))
+	runTestGit(t, lunaPath, "add", "luna.txt")
+	runTestGit(t, lunaPath, "commit", "-q", "-m", "Add luna")
+	runTestGit(t, repoPath, "worktree", "remove", worktreePath)
+
+	t.Run("FlagsWorktreesGitForgot", func(t *testing.T) {
+		result, err := s.ReconcileWorktrees("")
+		require.NoError(t, err)
+		assert.Equal(t, ReconcileMissingFlag, result.MissingMode)
+		assert.Empty(t, result.Added, "worktrees that were just added may still be on their way into state")
+		require.Len(t, result.Flagged, 1)
+		assert.Equal(t, "repo/felix", result.Flagged[0].Name)
+		assert.Contains(t, result.Flagged[0].Reason, "git has no worktree at")
+
+		felix, _ := stateManager.GetWorktree("wt-felix")
+		assert.True(t, felix.Missing)
+		report := stateManager.PendingStateReport()
+		require.NotNil(t, report)
+		require.Len(t, report.Stale, 1)
+		assert.Equal(t, "wt-felix", report.Stale[0].ID)
+
+		require.NoError(t, stateManager.RestoreState())
+		assert.NoDirExists(t, worktreePath, "missing worktrees aren't recreated")
+		assert.Empty(t, stateManager.ValidateState().Stale)
+	})
+
+	t.Run("ImportsGitWorktrees", func(t *testing.T) {
+		old := time.Now().Add(-time.Hour)
+		require.NoError(t, os.Chtimes(filepath.Join(lunaPath, ".git"), old, old))
+
+		result, err := s.ReconcileWorktrees("")
+		require.NoError(t, err)
+		require.Len(t, result.Added, 1)
+		assert.Equal(t, "repo/luna", result.Added[0].Name)
+		assert.Len(t, result.Flagged, 1, "felix is still missing")
+
+		luna, exists := stateManager.GetWorktree(result.Added[0].ID)
+		require.True(t, exists)
+		assert.Equal(t, "local/repo", luna.RepoID)
+		assert.Equal(t, "luna", luna.Branch)
+		assert.Equal(t, "main", luna.SourceBranch)
+		assert.Equal(t, 1, luna.CommitCount)
+		assert.Equal(t, runTestGit(t, repoPath, "rev-parse", "main"), luna.CommitHash)
+		assert.WithinDuration(t, old, luna.CreatedAt, time.Second)
+
+		result, err = s.ReconcileWorktrees("")
+		require.NoError(t, err)
+		assert.Empty(t, result.Added, "imported once")
+	})
+
+	t.Run("LeavesGitWorktreesOutsideTheWorkspaceAlone", func(t *testing.T) {
+		outsidePath := filepath.Join(t.TempDir(), "elsewhere")
+		runTestGit(t, repoPath, "worktree", "add", "-q", "-b", "elsewhere", outsidePath)
+		old := time.Now().Add(-time.Hour)
+		require.NoError(t, os.Chtimes(filepath.Join(outsidePath, ".git"), old, old))
+		defer runTestGit(t, repoPath, "worktree", "remove", outsidePath)
+
+		result, err := s.ReconcileWorktrees("")
+		require.NoError(t, err)
+		assert.Empty(t, result.Added)
+		require.Len(t, result.Flagged, 2, "felix is still missing")
+		assert.Equal(t, "elsewhere", result.Flagged[0].Name)
+		assert.Contains(t, result.Flagged[0].Reason, "is outside the workspace")
+		for _, worktree := range stateManager.GetAllWorktrees() {
+			assert.NotEqual(t, "elsewhere", worktree.Branch)
+		}
+	})
+
+	t.Run("RecoversWorktreesGitKnowsAgain", func(t *testing.T) {
+		runTestGit(t, repoPath, "worktree", "add", "-q", worktreePath, "feature/felix")
+		result, err := s.ReconcileWorktrees("")
+		require.NoError(t, err)
+		require.Len(t, result.Recovered, 1)
+		assert.Equal(t, "wt-felix", result.Recovered[0].ID)
+		assert.Empty(t, result.Flagged)
+		felix, _ := stateManager.GetWorktree("wt-felix")
+		assert.False(t, felix.Missing)
+	})
+
+	t.Run("RemovesWorktreesGitForgot", func(t *testing.T) {
+		runTestGit(t, repoPath, "worktree", "remove", worktreePath)
+		_, err := s.ReconcileWorktrees("drop")
+		assert.ErrorContains(t, err, "invalid missing worktree mode")
+
+		t.Setenv("CATNIP_RECONCILE_MISSING", "remove")
+		result, err := s.ReconcileWorktrees("")
+		require.NoError(t, err)
+		require.Len(t, result.Removed, 1)
+		assert.Equal(t, "repo/felix", result.Removed[0].Name)
+		_, exists := stateManager.GetWorktree("wt-felix")
+		assert.False(t, exists)
+		assert.NotEmpty(t, runTestGit(t, repoPath, "rev-parse", "feature/felix"), "the branch is kept")
+	})
+}
@@ -0,0 +1,404 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/config"
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+	"github.com/vanpelt/catnip/internal/recovery"
+)
+
+const (
+	// recreateJournalDir holds in-progress recreations (journal and patch) inside the state directory
+	recreateJournalDir = "recreate"
+	// trashDirName is the directory inside the workspace that soft-deleted worktree checkouts are moved to
+	trashDirName = ".catnip-trash"
+)
+
+// Phases of a worktree recreation, in order. The journal records the last completed phase
+// so an interrupted recreation resumes where it stopped.
+const (
+	recreatePhasePrepared    = "prepared"     // Uncommitted changes captured, sessions stopped
+	recreatePhaseSoftDeleted = "soft_deleted" // Old checkout moved to the trash
+	recreatePhaseCreated     = "created"      // Fresh checkout created at the worktree path
+	recreatePhaseTransplant  = "transplanted" // Uncommitted changes applied to the fresh checkout
+)
+
+// recreateJournal is the persisted progress of a worktree recreation
+type recreateJournal struct {
+	WorktreeID      string    `json:"worktree_id"`
+	Phase           string    `json:"phase"`
+	Path            string    `json:"path"`
+	TrashPath       string    `json:"trash_path"`
+	Ref             string    `json:"ref"` // Full ref HEAD pointed at, empty when detached
+	Commit          string    `json:"commit"`
+	KeepUncommitted bool      `json:"keep_uncommitted"`
+	PatchFile       string    `json:"patch_file,omitempty"`
+	StartedAt       time.Time `json:"started_at"`
+}
+
+// RecreateWorktreeResult describes a finished worktree recreation
+type RecreateWorktreeResult struct {
+	Worktree *models.Worktree `json:"worktree"`
+	// Where the previous checkout was moved to; it can be inspected or removed by hand
+	SoftDeletedPath string `json:"soft_deleted_path"`
+	// Processes that were running in the previous checkout and were terminated
+	TerminatedProcesses []string `json:"terminated_processes"`
+	// Whether uncommitted changes were carried over to the fresh checkout
+	TransplantedChanges bool `json:"transplanted_changes"`
+	// Patch file kept when uncommitted changes could not be applied cleanly
+	UnappliedPatch string `json:"unapplied_patch,omitempty"`
+	// Whether an interrupted recreation was resumed
+	Resumed bool `json:"resumed"`
+}
+
+// RecreateWorktree replaces a worktree's checkout with a fresh one for the same branch while
+// keeping its model (branch association, PR link, titles, todos) intact. With keepUncommitted,
+// uncommitted and untracked changes are carried over as a binary patch. The old checkout is
+// moved to the workspace trash rather than deleted. Progress is journaled in the state
+// directory, so calling RecreateWorktree again after an interruption resumes the operation.
+func (s *GitService) RecreateWorktree(worktreeID string, keepUncommitted bool) (*RecreateWorktreeResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	worktree, exists := s.stateManager.GetWorktree(worktreeID)
+	if !exists {
+		return nil, fmt.Errorf("worktree %s not found", worktreeID)
+	}
+	repo, exists := s.stateManager.GetRepository(worktree.RepoID)
+	if !exists {
+		return nil, fmt.Errorf("repository %s not found", worktree.RepoID)
+	}
+
+	// SAFETY CHECK: same rule as DeleteWorktree, never move checkouts outside the managed workspace
+	workspaceDir := config.Runtime.WorkspaceDir
+	if workspaceDir != "" && !isWithinDir(workspaceDir, worktree.Path) && !s.isTemporaryPath(worktree.Path) {
+		return nil, fmt.Errorf("cannot recreate worktree %s: path %s is outside managed workspace directory %s", worktree.Name, worktree.Path, workspaceDir)
+	}
+
+	result := &RecreateWorktreeResult{TerminatedProcesses: []string{}}
+	journal, err := s.loadRecreateJournal(worktreeID)
+	if err != nil {
+		return nil, err
+	}
+	if journal != nil {
+		result.Resumed = true
+		logger.Infof("🔁 Resuming recreation of worktree %s from phase %s", worktree.Name, journal.Phase)
+	} else {
+		journal, err = s.prepareRecreate(worktree, repo, keepUncommitted, result)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Stop watching the old checkout before it moves; the watchers are restarted on the new one
+	if s.worktreeCache != nil {
+		s.worktreeCache.RemoveWorktree(worktree.ID, worktree.Path)
+	}
+	if s.commitSync != nil {
+		s.commitSync.RemoveWorktreeWatcher(worktree.Path)
+	}
+	s.diffCache.invalidate(worktree.ID)
+	if s.claudeMonitor != nil {
+		s.claudeMonitor.OnWorktreeDeleted(worktree.ID, worktree.Path)
+	}
+
+	if journal.Phase == recreatePhasePrepared {
+		if err := s.softDeleteCheckout(repo, journal); err != nil {
+			return nil, err
+		}
+		if err := s.advanceRecreateJournal(journal, recreatePhaseSoftDeleted); err != nil {
+			return nil, err
+		}
+	}
+	if journal.Phase == recreatePhaseSoftDeleted {
+		if err := s.createFreshCheckout(repo, journal); err != nil {
+			return nil, err
+		}
+		if err := s.advanceRecreateJournal(journal, recreatePhaseCreated); err != nil {
+			return nil, err
+		}
+	}
+	if journal.Phase == recreatePhaseCreated {
+		if journal.PatchFile != "" {
+			if _, err := s.operations.ExecuteGit(journal.Path, "apply", "--binary", journal.PatchFile); err != nil {
+				logger.Warnf("⚠️  Failed to transplant uncommitted changes into %s, keeping %s: %v", worktree.Name, journal.PatchFile, err)
+				result.UnappliedPatch = journal.PatchFile
+			} else {
+				result.TransplantedChanges = true
+			}
+		}
+		if err := s.advanceRecreateJournal(journal, recreatePhaseTransplant); err != nil {
+			return nil, err
+		}
+	}
+
+	s.finishRecreate(worktree, journal, result.UnappliedPatch == "")
+	result.SoftDeletedPath = journal.TrashPath
+	result.Worktree, _ = s.stateManager.GetWorktree(worktreeID)
+
+	logger.Infof("✅ Recreated worktree %s from scratch (old checkout in %s)", worktree.Name, journal.TrashPath)
+	return result, nil
+}
+
+// prepareRecreate captures uncommitted changes, terminates processes running in the old checkout
+// and writes the initial journal
+func (s *GitService) prepareRecreate(worktree *models.Worktree, repo *models.Repository, keepUncommitted bool, result *RecreateWorktreeResult) (*recreateJournal, error) {
+	journal := &recreateJournal{
+		WorktreeID:      worktree.ID,
+		Path:            worktree.Path,
+		KeepUncommitted: keepUncommitted,
+		StartedAt:       time.Now(),
+	}
+
+	if ref, err := s.operations.ExecuteGit(worktree.Path, "symbolic-ref", "-q", "HEAD"); err == nil {
+		journal.Ref = strings.TrimSpace(string(ref))
+	} else if worktree.Branch != "" {
+		// HEAD may be unreadable in a damaged checkout, fall back to the branch from the model
+		journal.Ref = worktree.Branch
+		if !strings.HasPrefix(journal.Ref, "refs/") {
+			journal.Ref = "refs/heads/" + journal.Ref
+		}
+	}
+
+	// The ^{commit} suffix makes rev-parse print the resolved hash (go-git's --verify does not)
+	commit, err := s.operations.ExecuteGit(worktree.Path, "rev-parse", "HEAD^{commit}")
+	if err != nil && journal.Ref != "" {
+		commit, err = s.operations.ExecuteGit(repo.Path, "rev-parse", journal.Ref+"^{commit}")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve HEAD of worktree %s: %v", worktree.Name, err)
+	}
+	journal.Commit = strings.TrimSpace(string(commit))
+
+	trashName := fmt.Sprintf("%s-%s", strings.ReplaceAll(worktree.Name, "/", "-"), journal.StartedAt.Format("20060102-150405"))
+	journal.TrashPath = filepath.Join(getWorkspaceDir(), trashDirName, trashName)
+
+	if keepUncommitted {
+		patchFile, err := s.captureUncommittedPatch(worktree)
+		if err != nil {
+			return nil, err
+		}
+		journal.PatchFile = patchFile
+	}
+
+	result.TerminatedProcesses = s.processesInPath(worktree.Path)
+	s.cleanupActiveSessions(worktree.Path)
+
+	if err := s.advanceRecreateJournal(journal, recreatePhasePrepared); err != nil {
+		return nil, err
+	}
+	return journal, nil
+}
+
+// captureUncommittedPatch writes staged, unstaged and untracked changes of a worktree to a
+// binary patch in the journal directory. A throwaway index is used so the worktree's own
+// (possibly broken) index is left untouched. Returns "" when there is nothing to capture.
+func (s *GitService) captureUncommittedPatch(worktree *models.Worktree) (string, error) {
+	dir := filepath.Join(s.stateManager.stateDir, recreateJournalDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create recreate directory: %v", err)
+	}
+
+	indexFile := filepath.Join(dir, worktree.ID+".index")
+	defer os.Remove(indexFile)
+	env := []string{"GIT_INDEX_FILE=" + indexFile}
+
+	if _, err := s.operations.ExecuteGitWithEnv(worktree.Path, env, 0, "read-tree", "HEAD"); err != nil {
+		return "", fmt.Errorf("failed to capture uncommitted changes: %v", err)
+	}
+	if _, err := s.operations.ExecuteGitWithEnv(worktree.Path, env, 0, "add", "-A"); err != nil {
+		return "", fmt.Errorf("failed to capture uncommitted changes: %v", err)
+	}
+	patch, err := s.operations.ExecuteGitWithEnv(worktree.Path, env, 0, "diff", "--cached", "--binary", "HEAD")
+	if err != nil {
+		return "", fmt.Errorf("failed to capture uncommitted changes: %v", err)
+	}
+	if len(patch) == 0 {
+		return "", nil
+	}
+
+	patchFile := filepath.Join(dir, worktree.ID+".patch")
+	if err := os.WriteFile(patchFile, patch, 0644); err != nil {
+		return "", fmt.Errorf("failed to save uncommitted changes: %v", err)
+	}
+	return patchFile, nil
+}
+
+// softDeleteCheckout moves the old checkout into the workspace trash as a locked, detached
+// worktree so it stays inspectable and no longer holds the branch
+func (s *GitService) softDeleteCheckout(repo *models.Repository, journal *recreateJournal) error {
+	if _, err := os.Stat(journal.Path); os.IsNotExist(err) {
+		return nil // Already moved
+	}
+	if err := os.MkdirAll(filepath.Dir(journal.TrashPath), 0755); err != nil {
+		return fmt.Errorf("failed to create trash directory: %v", err)
+	}
+
+	if _, err := s.operations.ExecuteGit(repo.Path, "worktree", "move", journal.Path, journal.TrashPath); err != nil {
+		// git refuses to move some damaged worktrees; move the directory and repair the link instead
+		logger.Debugf("⚠️  git worktree move failed for %s, falling back to rename: %v", journal.Path, err)
+		if renameErr := os.Rename(journal.Path, journal.TrashPath); renameErr != nil {
+			return fmt.Errorf("failed to move old checkout to trash: %v", renameErr)
+		}
+		if _, err := s.operations.ExecuteGit(repo.Path, "worktree", "repair", journal.TrashPath); err != nil {
+			logger.Warnf("⚠️  Failed to repair trashed worktree %s: %v", journal.TrashPath, err)
+		}
+	}
+
+	// Detach without touching the index so the branch can be checked out again
+	if _, err := s.operations.ExecuteGit(journal.TrashPath, "update-ref", "--no-deref", "HEAD", journal.Commit); err != nil {
+		logger.Warnf("⚠️  Failed to detach trashed worktree %s: %v", journal.TrashPath, err)
+	}
+	if _, err := s.operations.ExecuteGit(repo.Path, "worktree", "lock", "--reason", "catnip: replaced by a recreated checkout", journal.TrashPath); err != nil {
+		logger.Debugf("⚠️  Failed to lock trashed worktree %s: %v", journal.TrashPath, err)
+	}
+	return nil
+}
+
+// createFreshCheckout creates a new worktree at the original path on the original branch
+func (s *GitService) createFreshCheckout(repo *models.Repository, journal *recreateJournal) error {
+	if _, err := os.Stat(filepath.Join(journal.Path, ".git")); err == nil {
+		return nil // Created before the interruption
+	}
+
+	// A ref may have moved while the recreation was interrupted; prefer its current commit
+	target := journal.Commit
+	if journal.Ref != "" {
+		if output, err := s.operations.ExecuteGit(repo.Path, "rev-parse", journal.Ref+"^{commit}"); err == nil {
+			target = strings.TrimSpace(string(output))
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(journal.Path), 0755); err != nil {
+		return fmt.Errorf("failed to create worktree parent directory: %v", err)
+	}
+	if _, err := s.operations.ExecuteGit(repo.Path, "worktree", "add", "--detach", journal.Path, target); err != nil {
+		return fmt.Errorf("failed to create fresh checkout: %v", err)
+	}
+	if journal.Ref != "" {
+		if _, err := s.operations.ExecuteGit(journal.Path, "symbolic-ref", "HEAD", journal.Ref); err != nil {
+			return fmt.Errorf("failed to check out %s in fresh checkout: %v", journal.Ref, err)
+		}
+	}
+	return nil
+}
+
+// finishRecreate restarts watchers and setup for the fresh checkout, refreshes the model's
+// status and removes the journal
+func (s *GitService) finishRecreate(worktree *models.Worktree, journal *recreateJournal, removePatch bool) {
+	if s.worktreeCache != nil {
+		s.worktreeCache.AddWorktree(worktree.ID, worktree.Path)
+	}
+	if s.commitSync != nil {
+		s.commitSync.AddWorktreeWatcher(worktree.Path)
+	}
+	if s.claudeMonitor != nil {
+		s.claudeMonitor.OnWorktreeCreated(worktree.ID, worktree.Path)
+	}
+
+	s.gitWorktreeManager.UpdateWorktreeStatus(worktree, models.StatusTierStandard, s.statusRefs())
+
+	if s.setupExecutor != nil {
+		recovery.SafeGo("setup-script:"+worktree.Path, func() {
+			s.setupExecutor.ExecuteSetupScript(worktree.Path, s.defaultSetupScript(worktree), s.DependencyCacheEnv(worktree.Path))
+		})
+	}
+	s.scheduleWorktreeSetup(worktree)
+
+	if removePatch && journal.PatchFile != "" {
+		_ = os.Remove(journal.PatchFile)
+	}
+	if err := os.Remove(s.recreateJournalPath(journal.WorktreeID)); err != nil && !os.IsNotExist(err) {
+		logger.Warnf("⚠️  Failed to remove recreate journal for %s: %v", worktree.Name, err)
+	}
+}
+
+// processesInPath lists the processes whose command line references path, as "pid command"
+func (s *GitService) processesInPath(path string) []string {
+	output, err := s.execCommand("pgrep", "-af", path).Output()
+	if err != nil {
+		return []string{}
+	}
+
+	processes := []string{}
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			processes = append(processes, line)
+		}
+	}
+	return processes
+}
+
+func (s *GitService) recreateJournalPath(worktreeID string) string {
+	return filepath.Join(s.stateManager.stateDir, recreateJournalDir, worktreeID+".json")
+}
+
+// loadRecreateJournal returns the journal of an interrupted recreation, or nil if there is none
+func (s *GitService) loadRecreateJournal(worktreeID string) (*recreateJournal, error) {
+	data, err := os.ReadFile(s.recreateJournalPath(worktreeID))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read recreate journal: %v", err)
+	}
+
+	var journal recreateJournal
+	if err := json.Unmarshal(data, &journal); err != nil {
+		return nil, fmt.Errorf("failed to parse recreate journal: %v", err)
+	}
+	return &journal, nil
+}
+
+// advanceRecreateJournal records a completed phase
+func (s *GitService) advanceRecreateJournal(journal *recreateJournal, phase string) error {
+	journal.Phase = phase
+	data, err := json.MarshalIndent(journal, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode recreate journal: %v", err)
+	}
+
+	path := s.recreateJournalPath(journal.WorktreeID)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create recreate directory: %v", err)
+	}
+	// Write then rename so a crash never leaves a truncated journal behind
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write recreate journal: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write recreate journal: %v", err)
+	}
+	return nil
+}
+
+// resumeInterruptedRecreations finishes recreations that were interrupted by a restart
+func (s *GitService) resumeInterruptedRecreations() {
+	entries, err := os.ReadDir(filepath.Join(s.stateManager.stateDir, recreateJournalDir))
+	if err != nil {
+		return
+	}
+
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		worktreeID := strings.TrimSuffix(entry.Name(), ".json")
+		if _, exists := s.stateManager.GetWorktree(worktreeID); !exists {
+			logger.Warnf("⚠️  Dropping recreate journal for unknown worktree %s", worktreeID)
+			_ = os.Remove(filepath.Join(s.stateManager.stateDir, recreateJournalDir, entry.Name()))
+			continue
+		}
+		if _, err := s.RecreateWorktree(worktreeID, false); err != nil {
+			logger.Warnf("⚠️  Failed to resume recreation of worktree %s: %v", worktreeID, err)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+func newRecreateTestService(t *testing.T) (*GitService, *WorktreeStateManager, string, string) {
+	t.Helper()
+	root := t.TempDir()
+	t.Setenv("CATNIP_WORKSPACE_DIR", root)
+
+	repoPath := initTestRepo(t, filepath.Join(root, "repo"), map[string]string{"app.txt": "v1\n"})
+
+	worktreePath := filepath.Join(root, "wt", "felix")
+	runTestGit(t, repoPath, "worktree", "add", "-b", "feature/felix", worktreePath)
+
+	s := newTestGitService(t, root)
+	stateManager := s.stateManager
+	require.NoError(t, stateManager.AddRepository(&models.Repository{ID: "local/repo", Path: repoPath}))
+	require.NoError(t, stateManager.AddWorktree(&models.Worktree{
+		ID:             "wt-felix",
+		RepoID:         "local/repo",
+		Name:           "repo/felix",
+		Path:           worktreePath,
+		Branch:         "feature/felix",
+		SourceBranch:   "main",
+		PullRequestURL: "https://github.com/owner/repo/pull/7",
+	}))
+	return s, stateManager, repoPath, worktreePath
+}
+
+func TestRecreateWorktree(t *testing.T) {
+	s, stateManager, _, worktreePath := newRecreateTestService(t)
+
+	require.NoError(t, os.WriteFile(filepath.Join(worktreePath, "app.txt"), []byte("v2\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(worktreePath, "notes.txt"), []byte("untracked\n"), 0644))
+
+	result, err := s.RecreateWorktree("wt-felix", true)
+	require.NoError(t, err)
+	assert.False(t, result.Resumed)
+	assert.True(t, result.TransplantedChanges)
+	assert.Empty(t, result.UnappliedPatch)
+
+	t.Run("FreshCheckoutOnSameBranch", func(t *testing.T) {
+		assert.Equal(t, "refs/heads/feature/felix", runTestGit(t, worktreePath, "symbolic-ref", "HEAD"))
+		content, err := os.ReadFile(filepath.Join(worktreePath, "app.txt"))
+		require.NoError(t, err)
+		assert.Equal(t, "v2\n", string(content))
+		assert.FileExists(t, filepath.Join(worktreePath, "notes.txt"))
+		assert.Contains(t, runTestGit(t, worktreePath, "status", "--porcelain"), "?? notes.txt")
+	})
+
+	t.Run("OldCheckoutSoftDeleted", func(t *testing.T) {
+		require.NotEmpty(t, result.SoftDeletedPath)
+		assert.FileExists(t, filepath.Join(result.SoftDeletedPath, "notes.txt"))
+		assert.Contains(t, runTestGit(t, result.SoftDeletedPath, "rev-parse", "--abbrev-ref", "HEAD"), "HEAD", "trashed checkout must be detached")
+	})
+
+	t.Run("MetadataPreserved", func(t *testing.T) {
+		worktree, _ := stateManager.GetWorktree("wt-felix")
+		assert.Equal(t, worktreePath, worktree.Path)
+		assert.Equal(t, "feature/felix", worktree.Branch)
+		assert.Equal(t, "https://github.com/owner/repo/pull/7", worktree.PullRequestURL)
+		assert.NoFileExists(t, s.recreateJournalPath("wt-felix"))
+	})
+}
+
+func TestRecreateWorktreeResumes(t *testing.T) {
+	s, stateManager, repoPath, worktreePath := newRecreateTestService(t)
+	worktree, _ := stateManager.GetWorktree("wt-felix")
+	repo, _ := stateManager.GetRepository("local/repo")
+
+	// Simulate an interruption right after the old checkout was moved away
+	result := &RecreateWorktreeResult{}
+	journal, err := s.prepareRecreate(worktree, repo, false, result)
+	require.NoError(t, err)
+	require.NoError(t, s.softDeleteCheckout(repo, journal))
+	require.NoError(t, s.advanceRecreateJournal(journal, recreatePhaseSoftDeleted))
+	assert.NoDirExists(t, worktreePath)
+
+	resumed, err := s.RecreateWorktree("wt-felix", false)
+	require.NoError(t, err)
+	assert.True(t, resumed.Resumed)
+	assert.Equal(t, journal.TrashPath, resumed.SoftDeletedPath)
+	assert.Equal(t, "refs/heads/feature/felix", runTestGit(t, worktreePath, "symbolic-ref", "HEAD"))
+	assert.Contains(t, runTestGit(t, repoPath, "worktree", "list"), worktreePath)
+	assert.NoFileExists(t, s.recreateJournalPath("wt-felix"))
+}
@@ -0,0 +1,185 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/vanpelt/catnip/internal/config"
+	"github.com/vanpelt/catnip/internal/git"
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// worktreeNamePattern matches the names a worktree can be renamed to: a single directory name
+// that is also a valid ref component
+var worktreeNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*(\.[A-Za-z0-9_-]+)*$`)
+
+// RenameWorktree gives a worktree a new name: its directory under the workspace is moved with
+// git worktree move, its name and path change in the state, and a checkout still on its
+// refs/catnip/<name> ref moves to the ref of the new name. The ID is a UUID and stays the
+// same. The current symlink follows the move and watchers are re-registered on the new path.
+// Worktrees with a running terminal or agent session are refused, since those processes
+// would keep working in a directory that no longer exists.
+func (s *GitService) RenameWorktree(worktreeID, newName string) (*models.Worktree, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	worktree, exists := s.stateManager.GetWorktree(worktreeID)
+	if !exists {
+		return nil, fmt.Errorf("worktree %s not found", worktreeID)
+	}
+	repo, exists := s.stateManager.GetRepository(worktree.RepoID)
+	if !exists {
+		return nil, fmt.Errorf("repository %s not found", worktree.RepoID)
+	}
+	if !worktreeNamePattern.MatchString(newName) || strings.HasSuffix(newName, ".lock") {
+		return nil, fmt.Errorf("invalid worktree name %q, use letters, digits, '-', '_' and '.'", newName)
+	}
+
+	// SAFETY CHECK: same rule as DeleteWorktree, never move checkouts outside the managed workspace
+	workspaceDir := config.Runtime.WorkspaceDir
+	if workspaceDir != "" && !isWithinDir(workspaceDir, worktree.Path) && !s.isTemporaryPath(worktree.Path) {
+		return nil, fmt.Errorf("cannot rename worktree %s: path %s is outside managed workspace directory %s", worktree.Name, worktree.Path, workspaceDir)
+	}
+
+	oldName, oldPath, oldBranch := worktree.Name, worktree.Path, worktree.Branch
+	prefix := oldName[:strings.LastIndex(oldName, "/")+1]
+	newPath := filepath.Join(filepath.Dir(oldPath), newName)
+	if newPath == oldPath {
+		return worktree, nil
+	}
+	if _, err := os.Lstat(newPath); err == nil {
+		return nil, fmt.Errorf("cannot rename worktree %s: %s already exists", worktree.Name, newPath)
+	}
+	if s.sessionChecker != nil && s.sessionChecker.HasSessionsIn(oldPath) {
+		return nil, fmt.Errorf("cannot rename worktree %s while a terminal session is running in it, close its sessions first", worktree.Name)
+	}
+
+	// HEAD stays on the catnip ref after a branch is graduated, the ref follows the name
+	oldRef, newRef := "", ""
+	if head, err := s.operations.ExecuteGit(oldPath, "symbolic-ref", "-q", "HEAD"); err == nil {
+		if ref := strings.TrimSpace(string(head)); ref == "refs/catnip/"+strings.TrimPrefix(oldName, prefix) {
+			oldRef, newRef = ref, "refs/catnip/"+newName
+			if _, err := s.operations.ExecuteGit(repo.Path, "rev-parse", newRef+"^{commit}"); err == nil {
+				return nil, fmt.Errorf("cannot rename worktree %s: %s already exists", worktree.Name, newRef)
+			}
+		}
+	}
+	branch := worktree.Branch
+	if newRef != "" {
+		branch = newRef
+	}
+	if err := git.ValidateWorktreePath(repo.Path, newPath, branch, s.worktreeMaxPathLength(repo.Path)); err != nil {
+		return nil, err
+	}
+
+	if s.worktreeCache != nil {
+		s.worktreeCache.RemoveWorktree(worktree.ID, oldPath)
+	}
+	if s.claudeMonitor != nil {
+		s.claudeMonitor.OnWorktreeDeleted(worktree.ID, oldPath)
+	}
+	if _, err := s.operations.ExecuteGit(repo.Path, "worktree", "move", oldPath, newPath); err != nil {
+		s.watchWorktree(worktree.ID, oldPath)
+		return nil, fmt.Errorf("failed to move worktree %s: %v", worktree.Name, err)
+	}
+	s.diffCache.invalidate(worktree.ID)
+
+	updates := map[string]interface{}{
+		"name": prefix + newName,
+		"path": newPath,
+	}
+	refMoved := false
+	if oldRef != "" {
+		if err := s.moveCatnipRef(repo, newPath, oldRef, newRef); err != nil {
+			logger.Warnf("⚠️  Worktree %s moved to %s but its ref stayed on %s: %v", worktree.Name, newPath, oldRef, err)
+		} else {
+			refMoved = true
+			if oldBranch == oldRef {
+				updates["branch"] = newRef
+			}
+		}
+	}
+	if err := s.stateManager.UpdateWorktree(worktree.ID, updates); err != nil {
+		s.undoWorktreeMove(repo, worktree.ID, oldName, oldPath, oldBranch, newPath, oldRef, newRef, refMoved)
+		return nil, fmt.Errorf("failed to record rename of worktree %s: %v", oldName, err)
+	}
+
+	currentPath := filepath.Join(getWorkspaceDir(), "current")
+	if target, err := os.Readlink(currentPath); err == nil && filepath.Clean(target) == oldPath {
+		if err := s.updateCurrentSymlink(newPath); err != nil {
+			logger.Warnf("⚠️  Failed to point %s at %s: %v", currentPath, newPath, err)
+		}
+	}
+	if s.commitSync != nil {
+		s.commitSync.MoveWorktreeWatcher(oldPath, newPath)
+	}
+	s.watchWorktree(worktree.ID, newPath)
+
+	worktree, _ = s.stateManager.GetWorktree(worktree.ID)
+	logger.Infof("🏷️ Renamed worktree %s to %s (%s)", oldName, worktree.Name, newPath)
+	return worktree, nil
+}
+
+// undoWorktreeMove puts a renamed worktree back at oldPath on its old ref when its new name
+// couldn't be saved, so the checkout matches the state again, and watches it there
+func (s *GitService) undoWorktreeMove(repo *models.Repository, worktreeID, oldName, oldPath, oldBranch, newPath, oldRef, newRef string, refMoved bool) {
+	if refMoved {
+		if err := s.moveCatnipRef(repo, newPath, newRef, oldRef); err != nil {
+			logger.Warnf("⚠️  Failed to move the ref of worktree %s back to %s: %v", oldName, oldRef, err)
+		}
+	}
+	path := oldPath
+	if output, err := s.operations.ExecuteGit(repo.Path, "worktree", "move", newPath, oldPath); err != nil {
+		logger.Errorf("❌ Failed to move worktree %s back to %s, it stays at %s: %v\n%s", oldName, oldPath, newPath, err, output)
+		path = newPath
+	}
+	// The update may have been applied in memory before saving it failed
+	if err := s.stateManager.UpdateWorktree(worktreeID, map[string]interface{}{"name": oldName, "path": path, "branch": oldBranch}); err != nil {
+		logger.Warnf("⚠️  Failed to restore the state of worktree %s: %v", oldName, err)
+	}
+	s.diffCache.invalidate(worktreeID)
+	s.watchWorktree(worktreeID, path)
+}
+
+// watchWorktree registers a worktree's status watchers and Claude monitoring at path
+func (s *GitService) watchWorktree(worktreeID, path string) {
+	if s.worktreeCache != nil {
+		s.worktreeCache.AddWorktree(worktreeID, path)
+	}
+	if s.claudeMonitor != nil {
+		s.claudeMonitor.OnWorktreeCreated(worktreeID, path)
+	}
+}
+
+// moveCatnipRef points the checkout at worktreePath from oldRef to newRef, carrying over the
+// branch mapping recorded for oldRef
+func (s *GitService) moveCatnipRef(repo *models.Repository, worktreePath, oldRef, newRef string) error {
+	commit, err := s.operations.ExecuteGit(worktreePath, "rev-parse", oldRef+"^{commit}")
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %v", oldRef, err)
+	}
+	if _, err := s.operations.ExecuteGit(repo.Path, "update-ref", newRef, strings.TrimSpace(string(commit)), ""); err != nil {
+		return fmt.Errorf("failed to create %s: %v", newRef, err)
+	}
+	if _, err := s.operations.ExecuteGit(worktreePath, "symbolic-ref", "HEAD", newRef); err != nil {
+		return fmt.Errorf("failed to check out %s: %v", newRef, err)
+	}
+	if _, err := s.operations.ExecuteGit(repo.Path, "update-ref", "-d", oldRef); err != nil {
+		logger.Warnf("⚠️  Failed to delete old catnip ref %s: %v", oldRef, err)
+	}
+
+	oldKey := fmt.Sprintf("catnip.branch-map.%s", strings.ReplaceAll(oldRef, "/", "."))
+	if niceBranch, err := s.operations.GetConfig(repo.Path, oldKey); err == nil && niceBranch != "" {
+		newKey := fmt.Sprintf("catnip.branch-map.%s", strings.ReplaceAll(newRef, "/", "."))
+		if err := s.operations.SetConfig(repo.Path, newKey, niceBranch); err != nil {
+			logger.Warnf("⚠️  Failed to carry over branch mapping of %s: %v", oldRef, err)
+		} else {
+			_ = s.operations.UnsetConfig(repo.Path, oldKey)
+		}
+	}
+	return nil
+}
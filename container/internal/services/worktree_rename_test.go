@@ -0,0 +1,41 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenameWorktreeMovesBackWhenTheStateCantBeSaved(t *testing.T) {
+	s, stateManager, repoPath, worktreePath := newRecreateTestService(t)
+	s.worktreeCache = NewWorktreeStatusCache(s.operations, stateManager)
+	t.Cleanup(s.worktreeCache.Stop)
+	runTestGit(t, repoPath, "update-ref", "refs/catnip/felix", "feature/felix")
+	runTestGit(t, worktreePath, "symbolic-ref", "HEAD", "refs/catnip/felix")
+	require.NoError(t, stateManager.UpdateWorktree("wt-felix", map[string]interface{}{"branch": "refs/catnip/felix"}))
+
+	// A file where the worktree state directory belongs makes saving fail
+	worktreesDir := filepath.Join(stateManager.stateDir, stateWorktreesDir)
+	require.NoError(t, os.RemoveAll(worktreesDir))
+	require.NoError(t, os.WriteFile(worktreesDir, nil, 0644))
+
+	_, err := s.RenameWorktree("wt-felix", "tabby")
+	assert.ErrorContains(t, err, "failed to record rename of worktree repo/felix")
+
+	assert.DirExists(t, worktreePath)
+	assert.NoDirExists(t, filepath.Join(filepath.Dir(worktreePath), "tabby"))
+	assert.Contains(t, runTestGit(t, repoPath, "worktree", "list"), worktreePath)
+	assert.Equal(t, "refs/catnip/felix", runTestGit(t, worktreePath, "symbolic-ref", "HEAD"))
+	worktree, _ := stateManager.GetWorktree("wt-felix")
+	assert.Equal(t, "repo/felix", worktree.Name)
+	assert.Equal(t, worktreePath, worktree.Path)
+	assert.Equal(t, "refs/catnip/felix", worktree.Branch)
+
+	s.worktreeCache.mu.RLock()
+	_, watched := s.worktreeCache.statuses["wt-felix"]
+	s.worktreeCache.mu.RUnlock()
+	assert.True(t, watched, "the worktree is watched again")
+}
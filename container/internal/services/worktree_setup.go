@@ -0,0 +1,234 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+	"github.com/vanpelt/catnip/internal/recovery"
+	"github.com/vanpelt/catnip/internal/redact"
+)
+
+const (
+	// setupLogDir holds the output of each worktree's setup commands inside the state directory
+	setupLogDir = "setup-logs"
+	// setupCommandTimeout bounds one setup command, generous enough for dependency installs
+	setupCommandTimeout = 30 * time.Minute
+)
+
+// ErrSetupRunning is returned when setup of a worktree is started while it is still running
+var ErrSetupRunning = errors.New("setup is already running")
+
+// worktreeSetupRun is a running setup of a worktree, cancelled when the worktree is deleted
+type worktreeSetupRun struct {
+	cancel context.CancelFunc
+}
+
+// SetRepositorySetupCommands sets the shell commands run in every new worktree of a repository
+// after checkout, in order. Blank commands are dropped; an empty list turns setup off.
+func (s *GitService) SetRepositorySetupCommands(repoID string, commands []string) (*models.Repository, error) {
+	if _, exists := s.stateManager.GetRepository(repoID); !exists {
+		return nil, fmt.Errorf("repository %s not found", repoID)
+	}
+
+	var cleaned []string
+	for _, command := range commands {
+		if command = strings.TrimSpace(command); command != "" {
+			cleaned = append(cleaned, command)
+		}
+	}
+	if err := s.stateManager.UpdateRepository(repoID, func(repo *models.Repository) {
+		repo.SetupCommands = cleaned
+	}); err != nil {
+		return nil, err
+	}
+
+	logger.Infof("🧰 %s runs %d setup commands in new worktrees", repoID, len(cleaned))
+	repo, _ := s.stateManager.GetRepository(repoID)
+	return repo, nil
+}
+
+// RunWorktreeSetup runs the repository's setup commands in a worktree again, in the background.
+// The worktree's setup_status follows the run and its output replaces the setup log.
+func (s *GitService) RunWorktreeSetup(worktreeID string) error {
+	worktree, exists := s.stateManager.GetWorktree(worktreeID)
+	if !exists {
+		return fmt.Errorf("worktree %s not found", worktreeID)
+	}
+	started, err := s.startWorktreeSetup(worktree)
+	if err != nil {
+		return err
+	}
+	if !started {
+		return fmt.Errorf("repository %s has no setup commands", worktree.RepoID)
+	}
+	return nil
+}
+
+// WorktreeSetupLog returns the output of the last setup run of a worktree, which grows while
+// setup runs
+func (s *GitService) WorktreeSetupLog(worktreeID string) ([]byte, error) {
+	if _, exists := s.stateManager.GetWorktree(worktreeID); !exists {
+		return nil, fmt.Errorf("worktree %s not found", worktreeID)
+	}
+	content, err := os.ReadFile(s.setupLogPath(worktreeID))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("setup log of worktree %s not found", worktreeID)
+	}
+	return content, err
+}
+
+// setupLogPath returns where the setup output of a worktree is written
+func (s *GitService) setupLogPath(worktreeID string) string {
+	return filepath.Join(s.stateManager.stateDir, setupLogDir, worktreeID+".log")
+}
+
+// scheduleWorktreeSetup starts the repository's setup commands in a worktree that was just
+// created, if it has any
+func (s *GitService) scheduleWorktreeSetup(worktree *models.Worktree) {
+	if _, err := s.startWorktreeSetup(worktree); err != nil {
+		logger.Warnf("⚠️ Failed to start setup of %s: %v", worktree.Name, err)
+	}
+}
+
+// startWorktreeSetup marks the setup of a worktree pending and runs the repository's setup
+// commands in the background, reporting false when the repository has none
+func (s *GitService) startWorktreeSetup(worktree *models.Worktree) (bool, error) {
+	repo, exists := s.stateManager.GetRepository(worktree.RepoID)
+	if !exists || len(repo.SetupCommands) == 0 {
+		return false, nil
+	}
+	commands := append([]string(nil), repo.SetupCommands...)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	run := &worktreeSetupRun{cancel: cancel}
+	if _, running := s.setupRuns.LoadOrStore(worktree.ID, run); running {
+		cancel()
+		return false, ErrSetupRunning
+	}
+	s.setSetupStatus(worktree.ID, models.SetupPending)
+
+	worktreeID, worktreePath := worktree.ID, worktree.Path
+	recovery.SafeGo("worktree-setup:"+worktreeID, func() {
+		defer s.setupRuns.CompareAndDelete(worktreeID, run)
+		defer cancel()
+		s.runWorktreeSetup(ctx, worktreeID, worktreePath, commands)
+	})
+	return true, nil
+}
+
+// runWorktreeSetup runs setup commands in a worktree until one fails, writing their output to
+// the worktree's setup log with secrets masked
+func (s *GitService) runWorktreeSetup(ctx context.Context, worktreeID, worktreePath string, commands []string) {
+	status := models.SetupFailed
+	// Deferred first, so the status only changes once the log is complete
+	defer func() { s.setSetupStatus(worktreeID, status) }()
+	s.setSetupStatus(worktreeID, models.SetupRunning)
+
+	logPath := s.setupLogPath(worktreeID)
+	if err := os.MkdirAll(filepath.Dir(logPath), 0755); err != nil {
+		logger.Warnf("⚠️ Failed to create setup log directory: %v", err)
+		return
+	}
+	logFile, err := os.Create(logPath)
+	if err != nil {
+		logger.Warnf("⚠️ Failed to create setup log %s: %v", logPath, err)
+		return
+	}
+	defer logFile.Close()
+	output := redact.NewWriter(logFile, redact.Default())
+	defer output.Close()
+
+	logger.Infof("🧰 Running %d setup commands in %s", len(commands), worktreePath)
+	for _, command := range commands {
+		fmt.Fprintf(output, "$ %s\n", command)
+		started := time.Now()
+		if err := s.runSetupCommand(ctx, worktreePath, command, output); err != nil {
+			fmt.Fprintf(output, "\n❌ Setup failed: %v\n", err)
+			logger.Warnf("❌ Setup command %q failed in %s: %v", redact.Text(command), worktreePath, err)
+			return
+		}
+		fmt.Fprintf(output, "✅ Done in %s\n\n", time.Since(started).Round(time.Millisecond))
+	}
+	status = models.SetupSucceeded
+	logger.Infof("✅ Setup completed in %s", worktreePath)
+}
+
+// runSetupCommand runs a single setup command through the shell in a worktree
+func (s *GitService) runSetupCommand(ctx context.Context, worktreePath, command string, output *redact.Writer) error {
+	ctx, cancel := context.WithTimeout(ctx, setupCommandTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Dir = worktreePath
+	cmd.Env = append(os.Environ(), s.DependencyCacheEnv(worktreePath)...)
+	cmd.Stdout = output
+	cmd.Stderr = output
+	// Don't wait forever on output pipes held open by background processes of a killed command
+	cmd.WaitDelay = 5 * time.Second
+
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return fmt.Errorf("timed out after %s", setupCommandTimeout)
+	case ctx.Err() != nil:
+		return fmt.Errorf("cancelled")
+	case errors.As(err, &exitErr):
+		return fmt.Errorf("exited with %d", exitErr.ExitCode())
+	}
+	return err
+}
+
+// setSetupStatus records the setup progress of a worktree
+func (s *GitService) setSetupStatus(worktreeID, status string) {
+	if err := s.stateManager.UpdateWorktree(worktreeID, map[string]interface{}{"setup_status": status}); err != nil {
+		logger.Debugf("⚠️ Failed to record setup status of %s: %v", worktreeID, err)
+	}
+}
+
+// WorktreeSetupStatus returns the setup progress of a worktree, read under the state lock so it
+// can be polled while setup runs
+func (wsm *WorktreeStateManager) WorktreeSetupStatus(worktreeID string) string {
+	wsm.mu.RLock()
+	defer wsm.mu.RUnlock()
+	if worktree, exists := wsm.worktrees[worktreeID]; exists {
+		return worktree.SetupStatus
+	}
+	return ""
+}
+
+// stopWorktreeSetup cancels the running setup of a worktree that is being deleted and removes
+// its setup log
+func (s *GitService) stopWorktreeSetup(worktreeID string) {
+	if value, ok := s.setupRuns.LoadAndDelete(worktreeID); ok {
+		value.(*worktreeSetupRun).cancel()
+	}
+	if err := os.Remove(s.setupLogPath(worktreeID)); err != nil && !os.IsNotExist(err) {
+		logger.Debugf("⚠️ Failed to remove setup log of %s: %v", worktreeID, err)
+	}
+}
+
+// failInterruptedSetups marks setups that were pending or running when catnip stopped as
+// failed, as nothing will finish them
+func (s *GitService) failInterruptedSetups() {
+	for _, worktree := range s.stateManager.GetAllWorktrees() {
+		if worktree.SetupStatus != models.SetupPending && worktree.SetupStatus != models.SetupRunning {
+			continue
+		}
+		if logFile, err := os.OpenFile(s.setupLogPath(worktree.ID), os.O_APPEND|os.O_WRONLY, 0644); err == nil {
+			fmt.Fprintf(logFile, "\n❌ Setup was interrupted by a restart\n")
+			logFile.Close()
+		}
+		s.setSetupStatus(worktree.ID, models.SetupFailed)
+	}
+}
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+func TestWorktreeSetup(t *testing.T) {
+	s, stateManager, _, worktreePath := newRecreateTestService(t)
+	setupStatus := func() string {
+		return stateManager.WorktreeSetupStatus("wt-felix")
+	}
+	waitForSetup := func() string {
+		require.Eventually(t, func() bool {
+			status := setupStatus()
+			return status == models.SetupSucceeded || status == models.SetupFailed
+		}, 10*time.Second, 20*time.Millisecond)
+		return setupStatus()
+	}
+
+	// Without setup commands there is nothing to run
+	assert.ErrorContains(t, s.RunWorktreeSetup("wt-felix"), "has no setup commands")
+	assert.Empty(t, setupStatus())
+	_, err := s.WorktreeSetupLog("wt-felix")
+	assert.ErrorContains(t, err, "not found")
+
+	repo, err := s.SetRepositorySetupCommands("local/repo", []string{"echo installing > deps.txt", " ", "cat deps.txt"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"echo installing > deps.txt", "cat deps.txt"}, repo.SetupCommands)
+	_, err = s.SetRepositorySetupCommands("local/missing", []string{"true"})
+	assert.ErrorContains(t, err, "not found")
+
+	t.Run("Succeeds", func(t *testing.T) {
+		worktree, _ := stateManager.GetWorktree("wt-felix")
+		s.scheduleWorktreeSetup(worktree)
+		assert.Equal(t, models.SetupSucceeded, waitForSetup())
+		assert.FileExists(t, filepath.Join(worktreePath, "deps.txt"), "commands run in the worktree")
+
+		log, err := s.WorktreeSetupLog("wt-felix")
+		require.NoError(t, err)
+		assert.Contains(t, string(log), "$ echo installing > deps.txt\n")
+		assert.Contains(t, string(log), "$ cat deps.txt\ninstalling\n")
+	})
+
+	t.Run("StopsAtFailure", func(t *testing.T) {
+		_, err := s.SetRepositorySetupCommands("local/repo", []string{"echo broken; exit 4", "touch never.txt"})
+		require.NoError(t, err)
+		require.NoError(t, s.RunWorktreeSetup("wt-felix"))
+		assert.Equal(t, models.SetupFailed, waitForSetup())
+		assert.NoFileExists(t, filepath.Join(worktreePath, "never.txt"))
+
+		log, err := s.WorktreeSetupLog("wt-felix")
+		require.NoError(t, err)
+		assert.Contains(t, string(log), "broken\n")
+		assert.Contains(t, string(log), "exited with 4")
+		assert.NotContains(t, string(log), "installing", "a new run replaces the log")
+	})
+
+	t.Run("OneRunAtATime", func(t *testing.T) {
+		_, err := s.SetRepositorySetupCommands("local/repo", []string{"sleep 1"})
+		require.NoError(t, err)
+		require.NoError(t, s.RunWorktreeSetup("wt-felix"))
+		assert.ErrorIs(t, s.RunWorktreeSetup("wt-felix"), ErrSetupRunning)
+		assert.Equal(t, models.SetupSucceeded, waitForSetup())
+	})
+
+	t.Run("RestartFailsInterruptedSetup", func(t *testing.T) {
+		require.NoError(t, stateManager.UpdateWorktree("wt-felix", map[string]interface{}{"setup_status": models.SetupRunning}))
+		s.failInterruptedSetups()
+		assert.Equal(t, models.SetupFailed, setupStatus())
+		log, err := s.WorktreeSetupLog("wt-felix")
+		require.NoError(t, err)
+		assert.Contains(t, string(log), "interrupted by a restart")
+	})
+
+	t.Run("DeletingWorktreeRemovesLog", func(t *testing.T) {
+		s.stopWorktreeSetup("wt-felix")
+		_, err := os.Stat(s.setupLogPath("wt-felix"))
+		assert.True(t, os.IsNotExist(err))
+	})
+}
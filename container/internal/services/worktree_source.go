@@ -0,0 +1,69 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/vanpelt/catnip/internal/git"
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// SetWorktreeSourceBranch changes the branch a worktree is compared against, synced with and
+// merged into. Branches of remote repositories are fetched from origin first. Ahead/behind counts
+// are recomputed right away, and worktrees created at a tag or commit follow the branch from now on.
+func (s *GitService) SetWorktreeSourceBranch(worktreeID, newSource string) (*models.Worktree, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	worktree, exists := s.stateManager.GetWorktree(worktreeID)
+	if !exists {
+		return nil, fmt.Errorf("worktree %s not found", worktreeID)
+	}
+	newSource = strings.TrimPrefix(strings.TrimSpace(newSource), "refs/heads/")
+	if newSource == "" {
+		return nil, fmt.Errorf("invalid source branch, a branch name is required")
+	}
+	if newSource == worktree.Branch {
+		return nil, fmt.Errorf("invalid source branch %s, it is the worktree's own branch", newSource)
+	}
+	if s.gitOperationInProgress(worktree.Path) {
+		return nil, fmt.Errorf("cannot change source branch of %s while a merge or rebase is in progress, finish or abort it first", worktree.Name)
+	}
+
+	if s.isLocalRepo(worktree.RepoID) {
+		if !s.branchExists(worktree.Path, newSource, false) {
+			return nil, fmt.Errorf("invalid source branch %s, no such branch in %s", newSource, worktree.RepoID)
+		}
+	} else {
+		if err := s.fetchBranch(worktree.Path, git.FetchStrategy{Branch: newSource}); err != nil {
+			logger.Debugf("Failed to fetch %s for worktree %s: %v", newSource, worktree.Name, err)
+		}
+		if !s.branchExists(worktree.Path, newSource, true) && !s.branchExists(worktree.Path, newSource, false) {
+			return nil, fmt.Errorf("invalid source branch %s, no such branch in %s", newSource, worktree.RepoID)
+		}
+	}
+
+	previous := worktree.SourceBranch
+	updates := map[string]interface{}{"source_branch": newSource}
+	if worktree.SourceRef != "" {
+		updates["source_ref"] = ""
+	}
+	if err := s.stateManager.UpdateWorktree(worktreeID, updates); err != nil {
+		return nil, fmt.Errorf("failed to update worktree state: %v", err)
+	}
+
+	s.gitWorktreeManager.UpdateWorktreeStatus(worktree, models.StatusTierStandard, s.statusRefs())
+	if err := s.stateManager.UpdateWorktree(worktreeID, map[string]interface{}{
+		"commit_count":   worktree.CommitCount,
+		"commits_behind": worktree.CommitsBehind,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to update worktree state: %v", err)
+	}
+	if s.worktreeCache != nil {
+		s.worktreeCache.ForceRefresh(worktreeID)
+	}
+
+	logger.Infof("🔀 Changed source branch of %s from %s to %s: %d ahead, %d behind", worktree.Name, previous, newSource, worktree.CommitCount, worktree.CommitsBehind)
+	return worktree, nil
+}
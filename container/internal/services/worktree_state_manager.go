@@ -1,16 +1,21 @@
 package services
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/vanpelt/catnip/internal/config"
 	"github.com/vanpelt/catnip/internal/logger"
 	"github.com/vanpelt/catnip/internal/models"
+	"github.com/vanpelt/catnip/internal/recovery"
+	"github.com/vanpelt/catnip/internal/redact"
 )
 
 // GitOperations interface for branch renaming operations
@@ -24,7 +29,7 @@ type GitOperations interface {
 
 // WorktreeRestorer interface for recreating worktrees during state restoration
 type WorktreeRestorer interface {
-	RecreateWorktree(worktree *models.Worktree, repo *models.Repository) error
+	RestoreWorktree(worktree *models.Worktree, repo *models.Repository) error
 }
 
 // WorktreeStateChange represents a change to worktree state
@@ -59,6 +64,25 @@ type WorktreeStateManager struct {
 
 	// PR state updates from sync manager
 	prUpdateChan chan PRStateUpdate
+
+	// Content of each state file as last written or loaded, keyed by path relative to stateDir
+	persisted  map[string][]byte
+	writeStats StateWriteStats
+
+	// Audit log of worktree lifecycle events, behind the repository activity feed
+	activity *ActivityLog
+
+	// Merges into the source branch of each repository, oldest first
+	merges map[string][]models.MergeLedgerEntry
+
+	// Worktree groups spanning several repositories, by ID
+	groups map[string]*models.WorktreeGroup
+
+	// What loading the state healed or lost, persisted in stateReportFile
+	stateReport *models.StateReport
+
+	// Corrupt state files found by loadState, until the state report is acknowledged
+	loadErr *StateLoadError
 }
 
 // worktreeFieldState tracks all fields we care about for change detection
@@ -93,12 +117,22 @@ func NewWorktreeStateManager(stateDir string, eventsEmitter EventsEmitter) *Work
 		previousState: make(map[string]worktreeFieldState),
 		stopChan:      make(chan struct{}),
 		prUpdateChan:  make(chan PRStateUpdate, 100), // Buffered channel for PR updates
+		activity:      NewActivityLog(filepath.Join(stateDir, stateActivityDir)),
+		merges:        make(map[string][]models.MergeLedgerEntry),
+		groups:        make(map[string]*models.WorktreeGroup),
 	}
 
 	// Load existing state
+	wsm.loadStateReport()
 	if err := wsm.loadState(); err != nil {
-		logger.Warnf("⚠️ Failed to load state: %v", err)
+		var loadErr *StateLoadError
+		if errors.As(err, &loadErr) {
+			logger.Errorf("❌ Persisted state is degraded: %v", err)
+		} else {
+			logger.Warnf("⚠️ Failed to load state: %v", err)
+		}
 	}
+	wsm.canonicalizeWorktreePaths()
 
 	// Start PR update processor
 	go wsm.startPRUpdateProcessor()
@@ -122,6 +156,9 @@ func (wsm *WorktreeStateManager) startPRUpdateProcessor() {
 			logger.Warnf("Failed to update PR state for worktree %s: %v", update.WorktreeID, err)
 		} else {
 			logger.Debugf("Successfully updated PR state for worktree %s to %s", update.WorktreeID, update.PRState)
+			if worktree, exists := wsm.GetWorktree(update.WorktreeID); exists && update.PRState == "MERGED" {
+				wsm.recordWorktreeActivity(ActivityPullRequestMerged, worktree, "", worktree.PullRequestTitle, worktree.PullRequestURL)
+			}
 		}
 	}
 
@@ -144,8 +181,8 @@ func (wsm *WorktreeStateManager) SetSessionService(sessionService *SessionServic
 	wsm.sessionService = sessionService
 	wsm.mu.Unlock()
 
-	// Start periodic Claude activity state checking
-	go wsm.startClaudeActivitySync()
+	// Start periodic Claude activity state checking, restarted should it panic
+	recovery.SafeGoRestartable("claude-sessions-watcher", wsm.startClaudeActivitySync, time.Second)
 }
 
 // SetWorktreeRestorer sets the worktree restorer for state restoration
@@ -164,8 +201,19 @@ func (wsm *WorktreeStateManager) Stop() {
 func (wsm *WorktreeStateManager) GetRepository(repoID string) (*models.Repository, bool) {
 	wsm.mu.RLock()
 	defer wsm.mu.RUnlock()
-	repo, exists := wsm.repositories[repoID]
-	return repo, exists
+	if repo, exists := wsm.repositories[repoID]; exists {
+		return repo, true
+	}
+
+	// IDs of renamed repositories keep resolving
+	for _, repo := range wsm.repositories {
+		for _, alias := range repo.Aliases {
+			if alias == repoID {
+				return repo, true
+			}
+		}
+	}
+	return nil, false
 }
 
 // GetWorktree returns a worktree by ID
@@ -176,6 +224,25 @@ func (wsm *WorktreeStateManager) GetWorktree(worktreeID string) (*models.Worktre
 	return wt, exists
 }
 
+// FindWorktreeByPath returns the worktree at path, comparing paths in canonical form so symlinked
+// and differently cased spellings of the same directory match
+func (wsm *WorktreeStateManager) FindWorktreeByPath(path string) (*models.Worktree, bool) {
+	if path == "" {
+		return nil, false
+	}
+	key := config.PathKey(path)
+
+	wsm.mu.RLock()
+	defer wsm.mu.RUnlock()
+	for _, wt := range wsm.worktrees {
+		if wt.Path == path || (wt.Path != "" && config.PathKey(wt.Path) == key) {
+			wtCopy := *wt
+			return &wtCopy, true
+		}
+	}
+	return nil, false
+}
+
 // GetAllWorktrees returns all worktrees
 func (wsm *WorktreeStateManager) GetAllWorktrees() map[string]*models.Worktree {
 	wsm.mu.RLock()
@@ -218,6 +285,150 @@ func (wsm *WorktreeStateManager) AddRepository(repo *models.Repository) error {
 	return wsm.saveStateInternal()
 }
 
+// UpdateRepository applies update to a stored repository under the state lock and saves state
+func (wsm *WorktreeStateManager) UpdateRepository(repoID string, update func(repo *models.Repository)) error {
+	wsm.mu.Lock()
+	defer wsm.mu.Unlock()
+
+	repo, exists := wsm.repositories[repoID]
+	if !exists {
+		return fmt.Errorf("repository %s not found", repoID)
+	}
+
+	update(repo)
+	return wsm.saveStateInternal()
+}
+
+// RenameRepository moves a repository to newID, keeping oldID as an alias so existing links
+// still resolve. Its worktrees follow, with their pull request URLs pointed at the new name.
+func (wsm *WorktreeStateManager) RenameRepository(oldID, newID string, update func(repo *models.Repository)) error {
+	wsm.mu.Lock()
+	defer wsm.mu.Unlock()
+
+	repo, exists := wsm.repositories[oldID]
+	if !exists {
+		return fmt.Errorf("repository %s not found", oldID)
+	}
+	if _, exists := wsm.repositories[newID]; exists {
+		return fmt.Errorf("repository %s already exists", newID)
+	}
+
+	update(repo)
+	aliases := []string{}
+	for _, alias := range repo.Aliases {
+		if alias != newID && alias != oldID {
+			aliases = append(aliases, alias)
+		}
+	}
+	repo.ID = newID
+	repo.Aliases = append(aliases, oldID)
+	delete(wsm.repositories, oldID)
+	wsm.repositories[newID] = repo
+	if merges, exists := wsm.merges[oldID]; exists {
+		for i := range merges {
+			merges[i].RepoID = newID
+		}
+		delete(wsm.merges, oldID)
+		wsm.merges[newID] = merges
+	}
+	for _, group := range wsm.groups {
+		for i := range group.Members {
+			if group.Members[i].RepoID == oldID {
+				group.Members[i].RepoID = newID
+			}
+		}
+	}
+
+	for _, worktree := range wsm.worktrees {
+		if worktree.RepoID == oldID {
+			worktree.RepoID = newID
+			worktree.PullRequestURL = renamedPullRequestURL(worktree.PullRequestURL, oldID, newID)
+		}
+	}
+
+	return wsm.saveStateInternal()
+}
+
+// SetRepositoryHealthWarning records (or clears, when message is empty) a health warning
+// for a repository. A repository:health_warning event is emitted when a warning is raised.
+func (wsm *WorktreeStateManager) SetRepositoryHealthWarning(repoID, source, message string) error {
+	wsm.mu.Lock()
+	defer wsm.mu.Unlock()
+
+	repo, exists := wsm.repositories[repoID]
+	if !exists {
+		return fmt.Errorf("repository %s not found", repoID)
+	}
+
+	previous, hadWarning := repo.HealthWarnings[source]
+	if message == "" {
+		if !hadWarning {
+			return nil
+		}
+		delete(repo.HealthWarnings, source)
+		if len(repo.HealthWarnings) == 0 {
+			repo.HealthWarnings = nil
+		}
+		return wsm.saveStateInternal()
+	}
+
+	if hadWarning && previous == message {
+		return nil
+	}
+	if repo.HealthWarnings == nil {
+		repo.HealthWarnings = make(map[string]string)
+	}
+	repo.HealthWarnings[source] = message
+	if err := wsm.saveStateInternal(); err != nil {
+		return err
+	}
+
+	if !hadWarning && wsm.eventsEmitter != nil {
+		wsm.eventsEmitter.EmitRepositoryHealthWarning(repoID, source, message)
+	}
+	return nil
+}
+
+// RepositoryHealthWarnings returns a copy of the active health warnings of a repository
+func (wsm *WorktreeStateManager) RepositoryHealthWarnings(repoID string) map[string]string {
+	wsm.mu.RLock()
+	defer wsm.mu.RUnlock()
+
+	repo, exists := wsm.repositories[repoID]
+	if !exists || len(repo.HealthWarnings) == 0 {
+		return nil
+	}
+	warnings := make(map[string]string, len(repo.HealthWarnings))
+	for source, message := range repo.HealthWarnings {
+		warnings[source] = message
+	}
+	return warnings
+}
+
+// RecordOperationTiming adds a timing sample for an operation to the rolling windows of the
+// worktree and its repository (either may be empty) and emits a worktree update event
+func (wsm *WorktreeStateManager) RecordOperationTiming(worktreeID, repoID, operation string, sample models.OperationTimingSample) error {
+	wsm.mu.Lock()
+	defer wsm.mu.Unlock()
+
+	var updates map[string]interface{}
+	if worktree, exists := wsm.worktrees[worktreeID]; exists {
+		worktree.OperationTimings = withOperationTimingSample(worktree.OperationTimings, operation, sample)
+		updates = map[string]interface{}{"operation_timings": worktree.OperationTimings}
+	}
+	if repo, exists := wsm.repositories[repoID]; exists {
+		repo.OperationTimings = withOperationTimingSample(repo.OperationTimings, operation, sample)
+	}
+
+	if err := wsm.saveStateInternal(); err != nil {
+		return err
+	}
+	if updates != nil && wsm.eventsEmitter != nil {
+		wsm.eventsEmitter.EmitWorktreeUpdated(worktreeID, updates)
+	}
+	return nil
+}
+
 // IsRepositoryAvailable checks if a repository is available for operations
 func (wsm *WorktreeStateManager) IsRepositoryAvailable(repoID string) bool {
 	wsm.mu.RLock()
@@ -229,28 +440,42 @@ func (wsm *WorktreeStateManager) IsRepositoryAvailable(repoID string) bool {
 
 // AddWorktree adds a new worktree
 func (wsm *WorktreeStateManager) AddWorktree(worktree *models.Worktree) error {
+	return wsm.AddWorktrees([]*models.Worktree{worktree})
+}
+
+// AddWorktrees adds several new worktrees, saving state once. Nothing is added if the
+// repository of any of them isn't available.
+func (wsm *WorktreeStateManager) AddWorktrees(worktrees []*models.Worktree) error {
 	wsm.mu.Lock()
 	defer wsm.mu.Unlock()
 
-	// Check if the associated repository is available
-	repo, repoExists := wsm.repositories[worktree.RepoID]
-	if !repoExists {
-		return fmt.Errorf("repository %s not found", worktree.RepoID)
-	}
-	if !repo.Available {
-		return fmt.Errorf("repository %s is not available", worktree.RepoID)
+	// Check if the associated repositories are available
+	for _, worktree := range worktrees {
+		repo, repoExists := wsm.repositories[worktree.RepoID]
+		if !repoExists {
+			return fmt.Errorf("repository %s not found", worktree.RepoID)
+		}
+		if !repo.Available {
+			return fmt.Errorf("repository %s is not available", worktree.RepoID)
+		}
 	}
 
-	wsm.worktrees[worktree.ID] = worktree
+	for _, worktree := range worktrees {
+		worktree.Path = config.CanonicalPath(worktree.Path)
+		wsm.worktrees[worktree.ID] = worktree
+	}
 
 	// Save state
 	if err := wsm.saveStateInternal(); err != nil {
 		return err
 	}
+	for _, worktree := range worktrees {
+		wsm.recordWorktreeActivity(ActivityWorktreeCreated, worktree, worktree.CreatedBy, "from "+worktree.SourceBranch, "")
 
-	// Emit created event
-	if wsm.eventsEmitter != nil {
-		wsm.eventsEmitter.EmitWorktreeCreated(worktree)
+		// Emit created event
+		if wsm.eventsEmitter != nil {
+			wsm.eventsEmitter.EmitWorktreeCreated(worktree)
+		}
 	}
 
 	return nil
@@ -269,6 +494,14 @@ func (wsm *WorktreeStateManager) UpdateWorktree(worktreeID string, updates map[s
 	// Apply updates based on field names
 	for field, value := range updates {
 		switch field {
+		case "name":
+			if v, ok := value.(string); ok {
+				worktree.Name = v
+			}
+		case "path":
+			if v, ok := value.(string); ok {
+				worktree.Path = config.CanonicalPath(v)
+			}
 		case "branch":
 			if v, ok := value.(string); ok {
 				worktree.Branch = v
@@ -277,6 +510,10 @@ func (wsm *WorktreeStateManager) UpdateWorktree(worktreeID string, updates map[s
 			if v, ok := value.(string); ok {
 				worktree.SourceBranch = v
 			}
+		case "source_ref":
+			if v, ok := value.(string); ok {
+				worktree.SourceRef = v
+			}
 		case "commit_hash":
 			if v, ok := value.(string); ok {
 				worktree.CommitHash = v
@@ -289,6 +526,14 @@ func (wsm *WorktreeStateManager) UpdateWorktree(worktreeID string, updates map[s
 			if v, ok := value.(int); ok {
 				worktree.CommitsBehind = v
 			}
+		case "stash_count":
+			if v, ok := value.(int); ok {
+				worktree.StashCount = v
+			}
+		case "commit_signatures":
+			if v, ok := value.(string); ok {
+				worktree.CommitSignatures = v
+			}
 		case "is_dirty":
 			if v, ok := value.(bool); ok {
 				worktree.IsDirty = v
@@ -297,10 +542,26 @@ func (wsm *WorktreeStateManager) UpdateWorktree(worktreeID string, updates map[s
 			if v, ok := value.(bool); ok {
 				worktree.HasConflicts = v
 			}
+		case "created_by":
+			if v, ok := value.(string); ok {
+				worktree.CreatedBy = v
+			}
 		case "pull_request_url":
 			if v, ok := value.(string); ok {
 				worktree.PullRequestURL = v
 			}
+		case "pull_request_base_branch":
+			if v, ok := value.(string); ok {
+				worktree.PullRequestBaseBranch = v
+			}
+		case "pull_request_commit_count":
+			if v, ok := value.(int); ok {
+				worktree.PullRequestCommitCount = v
+			}
+		case "pull_request_commits_behind":
+			if v, ok := value.(int); ok {
+				worktree.PullRequestCommitsBehind = v
+			}
 		case "pull_request_title":
 			if v, ok := value.(string); ok {
 				worktree.PullRequestTitle = v
@@ -349,6 +610,74 @@ func (wsm *WorktreeStateManager) UpdateWorktree(worktreeID string, updates map[s
 			if v, ok := value.(string); ok {
 				worktree.PullRequestState = v
 			}
+		case "checkpoints_held":
+			if v, ok := value.(bool); ok {
+				worktree.CheckpointsHeld = v
+			}
+		case "checkpoint_config":
+			if v, ok := value.(*models.CheckpointConfig); ok {
+				worktree.CheckpointConfig = v
+			}
+		case "source_tip":
+			if v, ok := value.(string); ok {
+				worktree.SourceTip = v
+			}
+		case "source_branch_rewritten":
+			if v, ok := value.(*models.SourceRewrite); ok {
+				worktree.SourceBranchRewritten = v
+			}
+		case "group_id":
+			if v, ok := value.(string); ok {
+				worktree.GroupID = v
+			}
+		case "run_hooks_on_checkpoint":
+			if v, ok := value.(bool); ok {
+				worktree.RunHooksOnCheckpoint = v
+			}
+		case "last_checkpoint_error":
+			if v, ok := value.(*models.CheckpointError); ok {
+				worktree.LastCheckpointError = v
+			}
+		case "secret_keys":
+			if v, ok := value.([]string); ok {
+				worktree.SecretKeys = v
+			}
+		case "setup_status":
+			if v, ok := value.(string); ok {
+				worktree.SetupStatus = v
+			}
+		case "handed_off":
+			if v, ok := value.(*models.WorktreeHandoff); ok {
+				worktree.HandedOff = v
+			}
+		case "branch_rename":
+			if v, ok := value.(*models.BranchRenameOutcome); ok {
+				worktree.BranchRename = v
+			}
+		case "manual_intervention":
+			if v, ok := value.(*models.BranchDrift); ok {
+				worktree.ManualIntervention = v
+			}
+		case "toolchains":
+			if v, ok := value.([]models.Toolchain); ok {
+				worktree.Toolchains = v
+			}
+		case "ignore_suggestions":
+			if v, ok := value.([]models.IgnoreSuggestion); ok {
+				worktree.IgnoreSuggestions = v
+			}
+		case "creation_context":
+			if v, ok := value.(*models.CreationContext); ok {
+				worktree.CreationContext = v
+			}
+		case "validation":
+			if v, ok := value.(*models.ValidationResult); ok {
+				worktree.Validation = v
+			}
+		case "missing":
+			if v, ok := value.(bool); ok {
+				worktree.Missing = v
+			}
 		}
 	}
 
@@ -392,6 +721,18 @@ func (wsm *WorktreeStateManager) UpdateWorktreeStatus(worktreeID string, status
 	if status.CommitsBehind != nil {
 		updates["commits_behind"] = *status.CommitsBehind
 	}
+	if status.PullRequestCommitCount != nil {
+		updates["pull_request_commit_count"] = *status.PullRequestCommitCount
+	}
+	if status.PullRequestCommitsBehind != nil {
+		updates["pull_request_commits_behind"] = *status.PullRequestCommitsBehind
+	}
+	if status.StashCount != nil {
+		updates["stash_count"] = *status.StashCount
+	}
+	if status.CommitSignatures != nil {
+		updates["commit_signatures"] = *status.CommitSignatures
+	}
 	if status.Branch != "" {
 		updates["branch"] = status.Branch
 	}
@@ -412,11 +753,13 @@ func (wsm *WorktreeStateManager) DeleteWorktree(worktreeID string) error {
 	// Delete from state
 	delete(wsm.worktrees, worktreeID)
 	delete(wsm.previousState, worktreeID)
+	redact.Default().SetKeys(worktreeID, nil)
 
 	// Save state
 	if err := wsm.saveStateInternal(); err != nil {
 		return err
 	}
+	wsm.recordWorktreeActivity(ActivityWorktreeDeleted, worktree, "", "", "")
 
 	// Emit deleted event
 	if wsm.eventsEmitter != nil {
@@ -431,13 +774,26 @@ func (wsm *WorktreeStateManager) DeleteRepository(repoID string) error {
 	wsm.mu.Lock()
 	defer wsm.mu.Unlock()
 
-	_, exists := wsm.repositories[repoID]
+	repo, exists := wsm.repositories[repoID]
 	if !exists {
 		return fmt.Errorf("repository %s not found", repoID)
 	}
 
 	// Delete from state
 	delete(wsm.repositories, repoID)
+	delete(wsm.merges, repoID)
+
+	// Its ID and aliases must not keep resolving to another repository that claimed them
+	forgotten := append([]string{repoID}, repo.Aliases...)
+	for _, other := range wsm.repositories {
+		aliases := other.Aliases[:0]
+		for _, alias := range other.Aliases {
+			if !slices.Contains(forgotten, alias) {
+				aliases = append(aliases, alias)
+			}
+		}
+		other.Aliases = aliases
+	}
 
 	// Save state
 	if err := wsm.saveStateInternal(); err != nil {
@@ -478,6 +834,22 @@ func (wsm *WorktreeStateManager) BatchUpdateWorktrees(updates map[string]map[str
 				if v, ok := value.(int); ok {
 					worktree.CommitsBehind = v
 				}
+			case "pull_request_commit_count":
+				if v, ok := value.(int); ok {
+					worktree.PullRequestCommitCount = v
+				}
+			case "pull_request_commits_behind":
+				if v, ok := value.(int); ok {
+					worktree.PullRequestCommitsBehind = v
+				}
+			case "stash_count":
+				if v, ok := value.(int); ok {
+					worktree.StashCount = v
+				}
+			case "commit_signatures":
+				if v, ok := value.(string); ok {
+					worktree.CommitSignatures = v
+				}
 			case "is_dirty":
 				if v, ok := value.(bool); ok {
 					worktree.IsDirty = v
@@ -540,6 +912,22 @@ func (wsm *WorktreeStateManager) BatchUpdateWorktrees(updates map[string]map[str
 				cached.CommitsBehind = &v
 				hasGitStatusUpdates = true
 			}
+			if v, ok := worktreeUpdates["pull_request_commit_count"].(int); ok {
+				cached.PullRequestCommitCount = &v
+				hasGitStatusUpdates = true
+			}
+			if v, ok := worktreeUpdates["pull_request_commits_behind"].(int); ok {
+				cached.PullRequestCommitsBehind = &v
+				hasGitStatusUpdates = true
+			}
+			if v, ok := worktreeUpdates["stash_count"].(int); ok {
+				cached.StashCount = &v
+				hasGitStatusUpdates = true
+			}
+			if v, ok := worktreeUpdates["commit_signatures"].(string); ok {
+				cached.CommitSignatures = &v
+				hasGitStatusUpdates = true
+			}
 			if v, ok := worktreeUpdates["branch"].(string); ok {
 				cached.Branch = v
 				hasGitStatusUpdates = true
@@ -561,96 +949,6 @@ func (wsm *WorktreeStateManager) BatchUpdateWorktrees(updates map[string]map[str
 	return nil
 }
 
-// saveStateInternal saves state to disk (must be called with lock held)
-func (wsm *WorktreeStateManager) saveStateInternal() error {
-	// Include PR states in saved state - we'll get them from the PR sync manager
-	prStates := make(map[string]*models.PullRequestState)
-	// Get PR states from the sync manager if it exists
-	if prSyncManager := GetPRSyncManager(nil); prSyncManager != nil {
-		prStates = prSyncManager.GetAllPRStates()
-	}
-
-	state := map[string]interface{}{
-		"repositories":        wsm.repositories,
-		"worktrees":           wsm.worktrees,
-		"pull_request_states": prStates,
-	}
-
-	data, err := json.MarshalIndent(state, "", "  ")
-	if err != nil {
-		return err
-	}
-
-	stateFile := filepath.Join(wsm.stateDir, "state.json")
-	backupFile := filepath.Join(wsm.stateDir, "state.json.backup")
-
-	if err := os.MkdirAll(wsm.stateDir, 0755); err != nil {
-		return err
-	}
-
-	// Create backup of existing state file before writing new one
-	if _, err := os.Stat(stateFile); err == nil {
-		// State file exists, create backup
-		if err := copyFile(stateFile, backupFile); err != nil {
-			logger.Warnf("⚠️ Failed to create state backup: %v", err)
-			// Continue with write anyway - backup failure shouldn't prevent state saving
-		}
-	}
-
-	return os.WriteFile(stateFile, data, 0644)
-}
-
-// loadState loads state from disk
-func (wsm *WorktreeStateManager) loadState() error {
-	stateFile := filepath.Join(wsm.stateDir, "state.json")
-	data, err := os.ReadFile(stateFile)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return nil // No state to load
-		}
-		return err
-	}
-
-	var state map[string]json.RawMessage
-	if err := json.Unmarshal(data, &state); err != nil {
-		return err
-	}
-
-	// Load repositories
-	if reposData, exists := state["repositories"]; exists {
-		var repos map[string]*models.Repository
-		if err := json.Unmarshal(reposData, &repos); err == nil {
-			wsm.repositories = repos
-		}
-	}
-
-	// Load worktrees
-	if worktreesData, exists := state["worktrees"]; exists {
-		var worktrees map[string]*models.Worktree
-		if err := json.Unmarshal(worktreesData, &worktrees); err == nil {
-			wsm.worktrees = worktrees
-
-			// Initialize previous state for change detection
-			for id, wt := range worktrees {
-				wsm.previousState[id] = wsm.captureFieldState(wt)
-			}
-		}
-	}
-
-	// Load pull request states - we'll pass them to the PR sync manager
-	if prStatesData, exists := state["pull_request_states"]; exists {
-		var prStates map[string]*models.PullRequestState
-		if err := json.Unmarshal(prStatesData, &prStates); err == nil {
-			// Initialize PR sync manager with loaded states if it exists
-			if prSyncManager := GetPRSyncManager(nil); prSyncManager != nil {
-				prSyncManager.LoadStatesFromData(prStates)
-			}
-		}
-	}
-
-	return nil
-}
-
 // RestoreState recreates worktrees from persisted state on boot
 func (wsm *WorktreeStateManager) RestoreState() error {
 	wsm.mu.Lock()
@@ -672,6 +970,7 @@ func (wsm *WorktreeStateManager) RestoreState() error {
 		if _, err := os.Stat(repoPath); err != nil {
 			logger.Warnf("⚠️ Repository %s not available at %s, marking as unavailable", repoID, repoPath)
 			repo.Available = false
+			wsm.recordUnavailable(models.StateReportEntry{Kind: "repository", ID: repoID, Reason: fmt.Sprintf("path %s no longer exists", repoPath)})
 		} else {
 			logger.Debugf("✅ Repository %s found at %s", repoID, repoPath)
 			repo.Available = true
@@ -691,6 +990,7 @@ func (wsm *WorktreeStateManager) RestoreState() error {
 		repo, repoExists := wsm.repositories[worktree.RepoID]
 		if !repoExists {
 			logger.Warnf("⚠️ Worktree %s references missing repository %s, skipping", worktree.Name, worktree.RepoID)
+			wsm.recordUnavailable(models.StateReportEntry{Kind: "worktree", ID: worktree.ID, Name: worktree.Name, Reason: fmt.Sprintf("repository %s is missing", worktree.RepoID)})
 			skippedCount++
 			continue
 		}
@@ -701,6 +1001,13 @@ func (wsm *WorktreeStateManager) RestoreState() error {
 			continue
 		}
 
+		// git forgot it, recreating it would undo a deliberate git worktree remove
+		if worktree.Missing {
+			logger.Debugf("⏭️ Worktree %s is missing from git, not restoring it", worktree.Name)
+			skippedCount++
+			continue
+		}
+
 		// Check if worktree directory still exists
 		if _, err := os.Stat(worktree.Path); err == nil {
 			logger.Debugf("✅ Worktree %s already exists at %s, no restoration needed", worktree.Name, worktree.Path)
@@ -718,8 +1025,8 @@ func (wsm *WorktreeStateManager) RestoreState() error {
 		}
 
 		// Attempt to recreate the worktree
-		logger.Debugf("🔧 Calling RecreateWorktree for %s", worktree.Name)
-		if err := wsm.worktreeRestorer.RecreateWorktree(worktree, repo); err != nil {
+		logger.Debugf("🔧 Calling RestoreWorktree for %s", worktree.Name)
+		if err := wsm.worktreeRestorer.RestoreWorktree(worktree, repo); err != nil {
 			logger.Errorf("❌ Failed to restore worktree %s: %v", worktree.Name, err)
 
 			// Mark the worktree as failed/unavailable but don't fail the boot process
@@ -727,6 +1034,7 @@ func (wsm *WorktreeStateManager) RestoreState() error {
 			logger.Warnf("⚠️ Marking worktree %s as unavailable due to restoration failure", worktree.Name)
 			// Note: We could add an "Available" field to the Worktree model in the future
 			// For now, we just log the failure and continue
+			wsm.recordUnavailable(models.StateReportEntry{Kind: "worktree", ID: worktree.ID, Name: worktree.Name, Reason: fmt.Sprintf("restore failed: %v", err)})
 
 			failedCount++
 			continue
@@ -747,6 +1055,76 @@ func (wsm *WorktreeStateManager) RestoreState() error {
 	return nil
 }
 
+// ValidateState cross-checks the loaded worktrees against the filesystem, after RestoreState had
+// its chance to recreate missing checkouts. Worktrees of a repository that is no longer in state
+// can never be restored and are pruned, unless the repository's state file was found corrupt.
+// Worktrees whose directory is missing or has no .git are kept and flagged as stale in the state
+// report, so they can be recreated or deleted. Worktrees of unavailable repositories are left
+// alone, their repository is reported already.
+func (wsm *WorktreeStateManager) ValidateState() *models.StateValidation {
+	wsm.mu.Lock()
+	defer wsm.mu.Unlock()
+
+	corrupt := make(map[string]bool)
+	if wsm.loadErr != nil {
+		for _, name := range wsm.loadErr.Corrupt {
+			corrupt[name] = true
+		}
+	}
+
+	result := &models.StateValidation{Pruned: []models.StateReportEntry{}, Stale: []models.StateReportEntry{}}
+	for id, worktree := range wsm.worktrees {
+		entry := models.StateReportEntry{Kind: "worktree", ID: id, Name: worktree.Name}
+		repo, repoExists := wsm.repositories[worktree.RepoID]
+		switch {
+		case !repoExists && corrupt[entityStateFile(stateRepositoriesDir, worktree.RepoID)]:
+			entry.Reason = fmt.Sprintf("repository %s could not be loaded", worktree.RepoID)
+		case !repoExists:
+			entry.Reason = fmt.Sprintf("repository %s is no longer in state", worktree.RepoID)
+			logger.Warnf("🧹 Pruning worktree %s from state: %s", worktree.Name, entry.Reason)
+			delete(wsm.worktrees, id)
+			delete(wsm.previousState, id)
+			wsm.recordWorktreeActivity(ActivityWorktreeDeleted, worktree, "", "pruned from state, its repository is gone", "")
+			if wsm.eventsEmitter != nil {
+				wsm.eventsEmitter.EmitWorktreeDeleted(id, worktree.Name)
+			}
+			result.Pruned = append(result.Pruned, entry)
+			continue
+		case !repo.Available, worktree.Missing:
+			// Missing worktrees were flagged when git was found not to know them
+			continue
+		default:
+			if _, err := os.Stat(worktree.Path); err != nil {
+				entry.Reason = fmt.Sprintf("path %s no longer exists", worktree.Path)
+			} else if _, err := os.Stat(filepath.Join(worktree.Path, ".git")); err != nil {
+				entry.Reason = fmt.Sprintf("path %s has no .git, it isn't a git checkout", worktree.Path)
+			}
+		}
+		if entry.Reason != "" {
+			logger.Warnf("⚠️ Worktree %s is stale: %s", worktree.Name, entry.Reason)
+			result.Stale = append(result.Stale, entry)
+		}
+	}
+	sortEntries := func(entries []models.StateReportEntry) {
+		sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	}
+	sortEntries(result.Pruned)
+	sortEntries(result.Stale)
+
+	if len(result.Pruned) > 0 {
+		if err := wsm.saveStateInternal(); err != nil {
+			logger.Warnf("⚠️ Failed to save state after pruning worktrees: %v", err)
+		}
+		wsm.recordStateReport(func(report *models.StateReport) {
+			report.Pruned = append(report.Pruned, result.Pruned...)
+		})
+	}
+	for _, entry := range result.Stale {
+		wsm.recordStale(entry)
+	}
+	return result
+}
+
 // captureFieldState captures the current state of worktree fields
 func (wsm *WorktreeStateManager) captureFieldState(wt *models.Worktree) worktreeFieldState {
 	state := worktreeFieldState{
@@ -790,6 +1168,13 @@ func (wsm *WorktreeStateManager) SetEventsEmitter(emitter EventsEmitter) {
 	wsm.eventsEmitter = emitter
 }
 
+// getEventsEmitter returns the connected events emitter, if any
+func (wsm *WorktreeStateManager) getEventsEmitter() EventsEmitter {
+	wsm.mu.RLock()
+	defer wsm.mu.RUnlock()
+	return wsm.eventsEmitter
+}
+
 // startClaudeActivitySync periodically checks and updates Claude activity states
 func (wsm *WorktreeStateManager) startClaudeActivitySync() {
 	logger.Debug("🔄 Starting Claude activity state sync")
@@ -863,8 +1248,9 @@ func (wsm *WorktreeStateManager) syncClaudeActivityStates() {
 }
 
 // RenameWorktreeBranch is the centralized method for renaming catnip branches to nice names
-// This is the ONLY place where branch renaming should happen
-func (wsm *WorktreeStateManager) RenameWorktreeBranch(worktreeID, niceBranchName string, gitOperations GitOperations) error {
+// This is the ONLY place where branch renaming should happen. The optional outcome describes
+// how a branch name collision was resolved and is reported with the rename event.
+func (wsm *WorktreeStateManager) RenameWorktreeBranch(worktreeID, niceBranchName string, gitOperations GitOperations, outcome *models.BranchRenameOutcome) error {
 	wsm.mu.Lock()
 	defer wsm.mu.Unlock()
 
@@ -912,6 +1298,9 @@ func (wsm *WorktreeStateManager) RenameWorktreeBranch(worktreeID, niceBranchName
 	logger.Debugf("🔄 Updating worktree state: Branch %s -> %s (git HEAD stays on %s)", worktree.Branch, niceBranchName, originalBranch)
 	worktree.Branch = niceBranchName // This is what the UI displays
 	worktree.HasBeenRenamed = true   // This prevents further renames
+	if outcome != nil {
+		worktree.BranchRename = outcome
+	}
 
 	// Save state directly
 	if err := wsm.saveStateInternal(); err != nil {
@@ -924,12 +1313,64 @@ func (wsm *WorktreeStateManager) RenameWorktreeBranch(worktreeID, niceBranchName
 			"branch":           niceBranchName,
 			"has_been_renamed": true,
 		}
+		if outcome != nil {
+			updates["branch_rename"] = outcome
+		}
 		// No need to filter here since we're explicitly setting the nice branch name
 		wsm.eventsEmitter.EmitWorktreeUpdated(worktreeID, updates)
+		if outcome != nil {
+			wsm.eventsEmitter.EmitWorktreeBranchRename(worktreeID, outcome)
+		}
 	}
 
 	logger.Infof("✅ Successfully renamed branch display: %s -> %q for worktree %s (git HEAD remains on %s)",
 		originalBranch, niceBranchName, worktreeID, originalBranch)
+
+	// The pull request follows the branch, refresh what GitHub reports for it
+	if worktree.PullRequestURL != "" {
+		GetPRSyncManager(nil).RefreshPullRequest(worktree.PullRequestURL)
+	}
+	return nil
+}
+
+// LinkRelatedWorktrees records two worktrees as related to each other, adding a note to both
+func (wsm *WorktreeStateManager) LinkRelatedWorktrees(worktreeID, otherID, note string) error {
+	wsm.mu.Lock()
+	defer wsm.mu.Unlock()
+
+	worktree, exists := wsm.worktrees[worktreeID]
+	if !exists {
+		return fmt.Errorf("worktree %s not found", worktreeID)
+	}
+	other, exists := wsm.worktrees[otherID]
+	if !exists {
+		return fmt.Errorf("worktree %s not found", otherID)
+	}
+
+	linked := []*models.Worktree{worktree, other}
+	for i, wt := range linked {
+		relatedID := linked[1-i].ID
+		if !slices.Contains(wt.RelatedWorktreeIDs, relatedID) {
+			wt.RelatedWorktreeIDs = append(slices.Clone(wt.RelatedWorktreeIDs), relatedID)
+		}
+		if note != "" {
+			wt.Notes = append(slices.Clone(wt.Notes), note)
+		}
+	}
+
+	if err := wsm.saveStateInternal(); err != nil {
+		return fmt.Errorf("failed to save worktree state: %v", err)
+	}
+
+	if wsm.eventsEmitter != nil {
+		for _, wt := range linked {
+			wsm.eventsEmitter.EmitWorktreeUpdated(wt.ID, map[string]interface{}{
+				"related_worktree_ids": wt.RelatedWorktreeIDs,
+				"notes":                wt.Notes,
+			})
+		}
+	}
+
 	return nil
 }
 
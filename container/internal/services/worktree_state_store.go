@@ -0,0 +1,479 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/vanpelt/catnip/internal/config"
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// State directory layout. Each repository and worktree lives in its own file so a change only
+// rewrites the entity that changed; the index lists the entities and the layout version.
+const (
+	legacyStateFile      = "state.json"
+	stateIndexFile       = "index.json"
+	statePRStatesFile    = "pull_request_states.json"
+	stateRepositoriesDir = "repositories"
+	stateWorktreesDir    = "worktrees"
+	stateActivityDir     = "activity"
+	stateMergesDir       = "merges"
+	stateGroupsDir       = "groups"
+	stateLayoutVersion   = 2
+	// corruptStateSuffix is appended to state files that can't be parsed, which are then skipped
+	corruptStateSuffix = ".corrupt"
+	// stateBackupSuffix names the previous version of a state file, loaded when the file itself
+	// can't be parsed
+	stateBackupSuffix = ".bak"
+)
+
+// StateLoadError reports state files loading found corrupt. The rest of the state loaded; files
+// restored from their backup may miss their last change, the others are lost.
+type StateLoadError struct {
+	// Corrupt state files, relative to the state directory
+	Corrupt []string
+	// Those of them restored from their previous version
+	Restored []string
+}
+
+func (e *StateLoadError) Error() string {
+	lost := len(e.Corrupt) - len(e.Restored)
+	return fmt.Sprintf("%d corrupt state files, %d restored from backup and %d lost: %s",
+		len(e.Corrupt), len(e.Restored), lost, strings.Join(e.Corrupt, ", "))
+}
+
+// stateIndex is the content of index.json
+type stateIndex struct {
+	Version      int      `json:"version"`
+	Repositories []string `json:"repositories"`
+	Worktrees    []string `json:"worktrees"`
+}
+
+// StateWriteStats counts the state files written since the state manager was created
+type StateWriteStats struct {
+	Files int64 `json:"files"`
+	Bytes int64 `json:"bytes"`
+}
+
+// GetWriteStats returns how much state has been written to disk
+func (wsm *WorktreeStateManager) GetWriteStats() StateWriteStats {
+	wsm.mu.RLock()
+	defer wsm.mu.RUnlock()
+	return wsm.writeStats
+}
+
+// entityStateFile returns the state file of an entity, relative to the state directory. IDs
+// like "local/repo" are escaped into a single file name.
+func entityStateFile(dir, id string) string {
+	return filepath.Join(dir, url.PathEscape(id)+".json")
+}
+
+// saveStateInternal saves state to disk, writing only the files whose content changed since
+// they were last written or loaded (must be called with lock held)
+func (wsm *WorktreeStateManager) saveStateInternal() error {
+	files := make(map[string][]byte, len(wsm.repositories)+len(wsm.worktrees)+2)
+	index := stateIndex{Version: stateLayoutVersion, Repositories: []string{}, Worktrees: []string{}}
+
+	for id, repo := range wsm.repositories {
+		data, err := json.MarshalIndent(repo, "", "  ")
+		if err != nil {
+			return err
+		}
+		files[entityStateFile(stateRepositoriesDir, id)] = data
+		index.Repositories = append(index.Repositories, id)
+	}
+	for id, worktree := range wsm.worktrees {
+		data, err := json.MarshalIndent(worktree, "", "  ")
+		if err != nil {
+			return err
+		}
+		files[entityStateFile(stateWorktreesDir, id)] = data
+		index.Worktrees = append(index.Worktrees, id)
+	}
+	for repoID, merges := range wsm.merges {
+		if len(merges) == 0 {
+			continue
+		}
+		data, err := json.MarshalIndent(merges, "", "  ")
+		if err != nil {
+			return err
+		}
+		files[entityStateFile(stateMergesDir, repoID)] = data
+	}
+	for id, group := range wsm.groups {
+		data, err := json.MarshalIndent(group, "", "  ")
+		if err != nil {
+			return err
+		}
+		files[entityStateFile(stateGroupsDir, id)] = data
+	}
+	sort.Strings(index.Repositories)
+	sort.Strings(index.Worktrees)
+
+	// Include PR states - we'll get them from the PR sync manager
+	prStates := make(map[string]*models.PullRequestState)
+	if prSyncManager := GetPRSyncManager(nil); prSyncManager != nil {
+		prStates = prSyncManager.GetAllPRStates()
+	}
+	data, err := json.MarshalIndent(prStates, "", "  ")
+	if err != nil {
+		return err
+	}
+	files[statePRStatesFile] = data
+
+	if data, err = json.MarshalIndent(index, "", "  "); err != nil {
+		return err
+	}
+	files[stateIndexFile] = data
+
+	return wsm.writeStateFiles(files)
+}
+
+// writeStateFiles atomically writes the changed files, the index last so it never lists an
+// entity that isn't on disk yet, then removes the files of deleted entities
+func (wsm *WorktreeStateManager) writeStateFiles(files map[string][]byte) error {
+	for _, dir := range []string{stateRepositoriesDir, stateWorktreesDir, stateMergesDir, stateGroupsDir} {
+		if err := os.MkdirAll(filepath.Join(wsm.stateDir, dir), 0755); err != nil {
+			return err
+		}
+	}
+	if wsm.persisted == nil {
+		wsm.persisted = make(map[string][]byte)
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		if name != stateIndexFile {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	names = append(names, stateIndexFile)
+
+	for _, name := range names {
+		data := files[name]
+		if previous, written := wsm.persisted[name]; written && bytes.Equal(previous, data) {
+			continue
+		}
+		if err := writeStateFileWithBackup(filepath.Join(wsm.stateDir, name), data); err != nil {
+			return err
+		}
+		wsm.persisted[name] = data
+		wsm.writeStats.Files++
+		wsm.writeStats.Bytes += int64(len(data))
+	}
+
+	for name := range wsm.persisted {
+		if _, exists := files[name]; exists {
+			continue
+		}
+		if err := os.Remove(filepath.Join(wsm.stateDir, name)); err != nil && !os.IsNotExist(err) {
+			logger.Warnf("⚠️ Failed to remove state file %s: %v", name, err)
+			continue
+		}
+		_ = os.Remove(filepath.Join(wsm.stateDir, name+stateBackupSuffix))
+		delete(wsm.persisted, name)
+	}
+	return nil
+}
+
+// writeFileAtomic writes data next to path and renames it into place
+func writeFileAtomic(path string, data []byte) error {
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
+
+// writeStateFileWithBackup atomically replaces a state file, keeping the version it replaces as
+// its .bak. The backup is a hard link, so the file itself is never missing.
+func writeStateFileWithBackup(path string, data []byte) error {
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	backupPath := path + stateBackupSuffix
+	if err := os.Remove(backupPath); err != nil && !os.IsNotExist(err) {
+		logger.Debugf("⚠️ Failed to remove state backup %s: %v", backupPath, err)
+	}
+	if err := os.Link(path, backupPath); err != nil && !os.IsNotExist(err) {
+		logger.Debugf("⚠️ Failed to back up state file %s: %v", path, err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
+
+// loadState composes the state directory back into memory. Files that can't be parsed are
+// quarantined with a .corrupt suffix and replaced by their backup when it parses, or skipped.
+// A legacy state.json is migrated once. Corrupt files are returned as a *StateLoadError after
+// everything else loaded.
+func (wsm *WorktreeStateManager) loadState() error {
+	if _, err := os.Stat(filepath.Join(wsm.stateDir, stateIndexFile)); os.IsNotExist(err) {
+		return wsm.migrateLegacyState()
+	}
+	wsm.persisted = make(map[string][]byte)
+
+	var index stateIndex
+	if data, ok := wsm.readStateFile(stateIndexFile, &index); ok {
+		wsm.persisted[stateIndexFile] = data
+	}
+
+	for _, name := range wsm.listStateFiles(stateRepositoriesDir) {
+		var repo *models.Repository
+		if data, ok := wsm.readStateFile(name, &repo); ok && repo != nil && repo.ID != "" {
+			wsm.repositories[repo.ID] = repo
+			wsm.persisted[name] = data
+		}
+	}
+	for _, name := range wsm.listStateFiles(stateWorktreesDir) {
+		var worktree *models.Worktree
+		if data, ok := wsm.readStateFile(name, &worktree); ok && worktree != nil && worktree.ID != "" {
+			wsm.worktrees[worktree.ID] = worktree
+			wsm.previousState[worktree.ID] = wsm.captureFieldState(worktree)
+			wsm.persisted[name] = data
+		}
+	}
+
+	for _, name := range wsm.listStateFiles(stateMergesDir) {
+		var merges []models.MergeLedgerEntry
+		if data, ok := wsm.readStateFile(name, &merges); ok && len(merges) > 0 {
+			if wsm.merges == nil {
+				wsm.merges = make(map[string][]models.MergeLedgerEntry)
+			}
+			wsm.merges[merges[0].RepoID] = merges
+			wsm.persisted[name] = data
+		}
+	}
+	for _, name := range wsm.listStateFiles(stateGroupsDir) {
+		var group *models.WorktreeGroup
+		if data, ok := wsm.readStateFile(name, &group); ok && group != nil && group.ID != "" {
+			if wsm.groups == nil {
+				wsm.groups = make(map[string]*models.WorktreeGroup)
+			}
+			wsm.groups[group.ID] = group
+			wsm.persisted[name] = data
+		}
+	}
+
+	loaded := stateIndex{Version: index.Version, Repositories: []string{}, Worktrees: []string{}}
+	for _, id := range index.Repositories {
+		if _, exists := wsm.repositories[id]; exists {
+			loaded.Repositories = append(loaded.Repositories, id)
+			continue
+		}
+		logger.Warnf("⚠️ Repository %s is listed in the state index but could not be loaded", id)
+		wsm.recordStateReport(func(report *models.StateReport) {
+			report.Lost = append(report.Lost, models.StateReportEntry{Kind: "repository", ID: id, Reason: "listed in the state index but could not be loaded"})
+		})
+	}
+	for _, id := range index.Worktrees {
+		if _, exists := wsm.worktrees[id]; exists {
+			loaded.Worktrees = append(loaded.Worktrees, id)
+			continue
+		}
+		logger.Warnf("⚠️ Worktree %s is listed in the state index but could not be loaded", id)
+		wsm.recordStateReport(func(report *models.StateReport) {
+			report.Lost = append(report.Lost, models.StateReportEntry{Kind: "worktree", ID: id, Reason: "listed in the state index but could not be loaded"})
+		})
+	}
+	// Drop lost entries from the index, so each loss is reported once
+	if len(loaded.Repositories) < len(index.Repositories) || len(loaded.Worktrees) < len(index.Worktrees) {
+		if data, err := json.MarshalIndent(loaded, "", "  "); err == nil {
+			if err := writeFileAtomic(filepath.Join(wsm.stateDir, stateIndexFile), data); err != nil {
+				logger.Warnf("⚠️ Failed to drop lost entries from the state index: %v", err)
+			} else {
+				wsm.persisted[stateIndexFile] = data
+			}
+		}
+	}
+
+	var prStates map[string]*models.PullRequestState
+	if data, ok := wsm.readStateFile(statePRStatesFile, &prStates); ok {
+		wsm.persisted[statePRStatesFile] = data
+		// Initialize PR sync manager with loaded states if it exists
+		if prSyncManager := GetPRSyncManager(nil); prSyncManager != nil {
+			prSyncManager.LoadStatesFromData(prStates)
+		}
+	}
+
+	if wsm.loadErr != nil {
+		return wsm.loadErr
+	}
+	return nil
+}
+
+// listStateFiles returns the .json files of a state subdirectory, relative to the state directory
+func (wsm *WorktreeStateManager) listStateFiles(dir string) []string {
+	entries, err := os.ReadDir(filepath.Join(wsm.stateDir, dir))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logger.Warnf("⚠️ Failed to list state directory %s: %v", dir, err)
+		}
+		return nil
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".json") {
+			names = append(names, filepath.Join(dir, entry.Name()))
+		}
+	}
+	return names
+}
+
+// readStateFile parses a state file into v and returns its content. Missing files return false;
+// unparseable ones are quarantined and restored from their backup, returning false when it
+// doesn't parse either.
+func (wsm *WorktreeStateManager) readStateFile(name string, v interface{}) ([]byte, bool) {
+	path := filepath.Join(wsm.stateDir, name)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logger.Warnf("⚠️ Failed to read state file %s: %v", name, err)
+		}
+		return nil, false
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		wsm.quarantineStateFile(name, err)
+		return wsm.restoreStateBackup(name, v)
+	}
+	return data, true
+}
+
+// restoreStateBackup parses the backup of a quarantined state file into v and puts it back in
+// place of the file
+func (wsm *WorktreeStateManager) restoreStateBackup(name string, v interface{}) ([]byte, bool) {
+	path := filepath.Join(wsm.stateDir, name)
+	data, err := os.ReadFile(path + stateBackupSuffix)
+	if err != nil {
+		return nil, false
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		logger.Errorf("❌ Backup of state file %s is corrupt too: %v", name, err)
+		return nil, false
+	}
+	if err := writeFileAtomic(path, data); err != nil {
+		logger.Errorf("❌ Failed to restore state file %s from its backup: %v", name, err)
+	} else {
+		logger.Errorf("❌ Restored state file %s from its backup, its last change may be lost", name)
+	}
+	wsm.loadErr.Restored = append(wsm.loadErr.Restored, name)
+	wsm.recordStateReport(func(report *models.StateReport) {
+		report.Restored = append(report.Restored, name)
+	})
+	return data, true
+}
+
+// quarantineStateFile renames a corrupt state file out of the way so the rest of the state loads
+func (wsm *WorktreeStateManager) quarantineStateFile(name string, cause error) {
+	if wsm.loadErr == nil {
+		wsm.loadErr = &StateLoadError{}
+	}
+	wsm.loadErr.Corrupt = append(wsm.loadErr.Corrupt, name)
+	path := filepath.Join(wsm.stateDir, name)
+	if err := os.Rename(path, path+corruptStateSuffix); err != nil {
+		logger.Errorf("❌ State file %s is corrupt (%v) and could not be quarantined: %v", name, cause, err)
+		return
+	}
+	logger.Errorf("❌ State file %s is corrupt (%v), moved it to %s%s", name, cause, name, corruptStateSuffix)
+	wsm.recordStateReport(func(report *models.StateReport) {
+		report.Quarantined = append(report.Quarantined, name+corruptStateSuffix)
+	})
+}
+
+// migrateLegacyState splits a single-file state.json into the state directory layout and keeps
+// the original as state.json.migrated
+func (wsm *WorktreeStateManager) migrateLegacyState() error {
+	stateFile := filepath.Join(wsm.stateDir, legacyStateFile)
+	data, err := os.ReadFile(stateFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil // No state to load
+		}
+		return err
+	}
+
+	var state map[string]json.RawMessage
+	if err := json.Unmarshal(data, &state); err != nil {
+		wsm.quarantineStateFile(legacyStateFile, err)
+		return fmt.Errorf("failed to parse %s: %v", legacyStateFile, err)
+	}
+
+	if reposData, exists := state["repositories"]; exists {
+		var repos map[string]*models.Repository
+		if err := json.Unmarshal(reposData, &repos); err == nil && repos != nil {
+			wsm.repositories = repos
+		}
+	}
+
+	if worktreesData, exists := state["worktrees"]; exists {
+		var worktrees map[string]*models.Worktree
+		if err := json.Unmarshal(worktreesData, &worktrees); err == nil && worktrees != nil {
+			wsm.worktrees = worktrees
+
+			// Initialize previous state for change detection
+			for id, wt := range worktrees {
+				wsm.previousState[id] = wsm.captureFieldState(wt)
+			}
+		}
+	}
+
+	if prStatesData, exists := state["pull_request_states"]; exists {
+		var prStates map[string]*models.PullRequestState
+		if err := json.Unmarshal(prStatesData, &prStates); err == nil {
+			if prSyncManager := GetPRSyncManager(nil); prSyncManager != nil {
+				prSyncManager.LoadStatesFromData(prStates)
+			}
+		}
+	}
+
+	if err := wsm.saveStateInternal(); err != nil {
+		return fmt.Errorf("failed to migrate %s: %v", legacyStateFile, err)
+	}
+	if err := os.Rename(stateFile, stateFile+".migrated"); err != nil {
+		logger.Warnf("⚠️ Failed to rename migrated %s: %v", legacyStateFile, err)
+	}
+	logger.Infof("📦 Migrated %s into per-entity state files (%d repositories, %d worktrees)", legacyStateFile, len(wsm.repositories), len(wsm.worktrees))
+	wsm.recordStateReport(func(report *models.StateReport) {
+		report.Migrations = append(report.Migrations, fmt.Sprintf("split %s into per-entity state files (%d repositories, %d worktrees)", legacyStateFile, len(wsm.repositories), len(wsm.worktrees)))
+	})
+	return nil
+}
+
+// canonicalizeWorktreePaths rewrites worktree paths recorded before paths were stored in
+// canonical form, e.g. through a symlinked workspace directory
+func (wsm *WorktreeStateManager) canonicalizeWorktreePaths() {
+	migrated := 0
+	for _, worktree := range wsm.worktrees {
+		if canonical := config.CanonicalPath(worktree.Path); canonical != worktree.Path {
+			logger.Infof("📦 Recording worktree %s path %s as %s", worktree.Name, worktree.Path, canonical)
+			rewrite := models.StatePathRewrite{WorktreeID: worktree.ID, Name: worktree.Name, From: worktree.Path, To: canonical}
+			wsm.recordStateReport(func(report *models.StateReport) {
+				report.PathRewrites = append(report.PathRewrites, rewrite)
+			})
+			worktree.Path = canonical
+			migrated++
+		}
+	}
+	if migrated == 0 {
+		return
+	}
+	if err := wsm.saveStateInternal(); err != nil {
+		logger.Warnf("⚠️ Failed to save canonical worktree paths: %v", err)
+	}
+}
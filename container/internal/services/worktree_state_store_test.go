@@ -0,0 +1,320 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// newStoreTestManager creates a state manager with a repository and n worktrees in stateDir
+func newStoreTestManager(t testing.TB, stateDir string, n int) *WorktreeStateManager {
+	stateManager := NewWorktreeStateManager(stateDir, nil)
+	t.Cleanup(stateManager.Stop)
+	require.NoError(t, stateManager.AddRepository(&models.Repository{ID: "local/repo", Path: "/live/repo"}))
+	for i := 0; i < n; i++ {
+		require.NoError(t, stateManager.AddWorktree(&models.Worktree{
+			ID: fmt.Sprintf("wt-%d", i), RepoID: "local/repo", Name: fmt.Sprintf("repo/felix-%d", i), Branch: "main",
+		}))
+	}
+	return stateManager
+}
+
+func TestStateStorePerEntityFiles(t *testing.T) {
+	stateDir := t.TempDir()
+	stateManager := newStoreTestManager(t, stateDir, 3)
+
+	assert.FileExists(t, filepath.Join(stateDir, "repositories", "local%2Frepo.json"))
+	assert.FileExists(t, filepath.Join(stateDir, "worktrees", "wt-1.json"))
+	var index stateIndex
+	data, err := os.ReadFile(filepath.Join(stateDir, stateIndexFile))
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &index))
+	assert.Equal(t, stateIndex{Version: stateLayoutVersion, Repositories: []string{"local/repo"}, Worktrees: []string{"wt-0", "wt-1", "wt-2"}}, index)
+
+	// An update rewrites only the changed worktree
+	before := stateManager.GetWriteStats()
+	require.NoError(t, stateManager.UpdateWorktree("wt-1", map[string]interface{}{"commit_count": 4}))
+	after := stateManager.GetWriteStats()
+	assert.Equal(t, int64(1), after.Files-before.Files)
+
+	require.NoError(t, stateManager.UpdateWorktree("wt-1", map[string]interface{}{"commit_count": 4}))
+	assert.Equal(t, after, stateManager.GetWriteStats(), "unchanged state is not rewritten")
+
+	require.NoError(t, stateManager.DeleteWorktree("wt-2"))
+	assert.NoFileExists(t, filepath.Join(stateDir, "worktrees", "wt-2.json"))
+
+	// A fresh manager composes the directory back and writes nothing until something changes
+	reloaded := NewWorktreeStateManager(stateDir, nil)
+	t.Cleanup(reloaded.Stop)
+	worktree, exists := reloaded.GetWorktree("wt-1")
+	require.True(t, exists)
+	assert.Equal(t, 4, worktree.CommitCount)
+	assert.Len(t, reloaded.GetAllWorktrees(), 2)
+	_, exists = reloaded.GetRepository("local/repo")
+	assert.True(t, exists)
+	require.NoError(t, reloaded.UpdateWorktree("wt-0", map[string]interface{}{"is_dirty": false}))
+	assert.Equal(t, StateWriteStats{}, reloaded.GetWriteStats())
+}
+
+func TestStateStoreQuarantinesCorruptFiles(t *testing.T) {
+	stateDir := t.TempDir()
+	newStoreTestManager(t, stateDir, 2)
+
+	corrupt := filepath.Join(stateDir, "worktrees", "wt-0.json")
+	require.NoError(t, os.WriteFile(corrupt, []byte(`{"id": "wt-0", "name":`), 0644))
+
+	stateManager := NewWorktreeStateManager(stateDir, nil)
+	t.Cleanup(stateManager.Stop)
+	_, exists := stateManager.GetWorktree("wt-0")
+	assert.False(t, exists)
+	_, exists = stateManager.GetWorktree("wt-1")
+	assert.True(t, exists, "the other worktrees still load")
+	assert.NoFileExists(t, corrupt)
+	assert.FileExists(t, corrupt+corruptStateSuffix)
+}
+
+func TestStateStoreMigratesLegacyStateFile(t *testing.T) {
+	stateDir := t.TempDir()
+	legacy := map[string]interface{}{
+		"repositories": map[string]*models.Repository{
+			"acme/felix": {ID: "acme/felix", Path: "/volume/repos/acme/felix.git", Available: true},
+		},
+		"worktrees": map[string]*models.Worktree{
+			"wt-felix": {ID: "wt-felix", RepoID: "acme/felix", Name: "felix/tabby", Branch: "feature/login", CommitCount: 2},
+		},
+		"pull_request_states": map[string]*models.PullRequestState{},
+	}
+	data, err := json.Marshal(legacy)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(stateDir, legacyStateFile), data, 0644))
+
+	stateManager := NewWorktreeStateManager(stateDir, nil)
+	t.Cleanup(stateManager.Stop)
+	worktree, exists := stateManager.GetWorktree("wt-felix")
+	require.True(t, exists)
+	assert.Equal(t, "feature/login", worktree.Branch)
+	assert.Equal(t, 2, worktree.CommitCount)
+
+	assert.NoFileExists(t, filepath.Join(stateDir, legacyStateFile))
+	assert.FileExists(t, filepath.Join(stateDir, legacyStateFile+".migrated"))
+	assert.FileExists(t, filepath.Join(stateDir, "repositories", "acme%2Ffelix.json"))
+	assert.FileExists(t, filepath.Join(stateDir, "worktrees", "wt-felix.json"))
+	assert.FileExists(t, filepath.Join(stateDir, stateIndexFile))
+}
+
+// BenchmarkStateStatusRefresh measures the bytes written when status refreshes update one
+// worktree at a time, next to what rewriting the whole legacy state.json would have written
+func BenchmarkStateStatusRefresh(b *testing.B) {
+	stateManager := newStoreTestManager(b, b.TempDir(), 50)
+	before := stateManager.GetWriteStats()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		commitCount := i
+		dirty := i%2 == 0
+		if err := stateManager.UpdateWorktreeStatus(fmt.Sprintf("wt-%d", i%50), &CachedWorktreeStatus{
+			CommitCount: &commitCount, IsDirty: &dirty,
+		}); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.StopTimer()
+
+	stateManager.mu.RLock()
+	legacy, err := json.MarshalIndent(map[string]interface{}{
+		"repositories":        stateManager.repositories,
+		"worktrees":           stateManager.worktrees,
+		"pull_request_states": map[string]*models.PullRequestState{},
+	}, "", "  ")
+	stateManager.mu.RUnlock()
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	written := stateManager.GetWriteStats().Bytes - before.Bytes
+	b.ReportMetric(float64(written)/float64(b.N), "written-B/op")
+	b.ReportMetric(float64(len(legacy)), "legacy-B/op")
+}
+
+func TestStateStoreCanonicalWorktreePaths(t *testing.T) {
+	root, err := filepath.EvalSymlinks(t.TempDir())
+	require.NoError(t, err)
+	realWorkspace := filepath.Join(root, "volume", "workspace")
+	require.NoError(t, os.MkdirAll(filepath.Join(realWorkspace, "repo", "felix"), 0755))
+	workspace := filepath.Join(root, "workspace")
+	require.NoError(t, os.Symlink(realWorkspace, workspace))
+
+	// Worktrees recorded through the symlink are migrated when state is loaded
+	stateDir := filepath.Join(root, "state")
+	stateManager := newStoreTestManager(t, stateDir, 1)
+	stateManager.worktrees["wt-0"].Path = filepath.Join(workspace, "repo", "felix")
+	require.NoError(t, stateManager.saveStateInternal())
+
+	reloaded := NewWorktreeStateManager(stateDir, nil)
+	t.Cleanup(reloaded.Stop)
+	worktree, _ := reloaded.GetWorktree("wt-0")
+	assert.Equal(t, filepath.Join(realWorkspace, "repo", "felix"), worktree.Path)
+	data, err := os.ReadFile(filepath.Join(stateDir, "worktrees", "wt-0.json"))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), realWorkspace, "the migrated path is persisted")
+
+	// New worktrees are stored canonical and found by any spelling of their path
+	require.NoError(t, reloaded.AddWorktree(&models.Worktree{
+		ID: "wt-1", RepoID: "local/repo", Name: "repo/Felix-2", Path: filepath.Join(workspace, "repo", "Felix-2"), Branch: "main",
+	}))
+	worktree, _ = reloaded.GetWorktree("wt-1")
+	assert.Equal(t, filepath.Join(realWorkspace, "repo", "Felix-2"), worktree.Path)
+	found, exists := reloaded.FindWorktreeByPath(filepath.Join(workspace, "repo", "felix") + "/")
+	require.True(t, exists)
+	assert.Equal(t, "wt-0", found.ID)
+
+	_, exists = reloaded.FindWorktreeByPath(filepath.Join(workspace, "repo", "felix-2"))
+	assert.False(t, exists)
+	t.Setenv("CATNIP_CASE_INSENSITIVE_PATHS", "true")
+	found, exists = reloaded.FindWorktreeByPath(filepath.Join(workspace, "REPO", "felix-2"))
+	require.True(t, exists)
+	assert.Equal(t, "wt-1", found.ID)
+}
+
+func TestStateStoreReportsRecoveries(t *testing.T) {
+	stateDir := t.TempDir()
+	stateManager := newStoreTestManager(t, stateDir, 2)
+	assert.Nil(t, stateManager.PendingStateReport(), "a clean load has nothing to report")
+
+	require.NoError(t, os.WriteFile(filepath.Join(stateDir, "worktrees", "wt-0.json"), []byte(`{"id":`), 0644))
+	stateManager = NewWorktreeStateManager(stateDir, nil)
+	t.Cleanup(stateManager.Stop)
+	report := stateManager.PendingStateReport()
+	require.NotNil(t, report)
+	assert.Equal(t, []string{filepath.Join("worktrees", "wt-0.json") + corruptStateSuffix}, report.Quarantined)
+	assert.Equal(t, []models.StateReportEntry{{Kind: "worktree", ID: "wt-0", Reason: "listed in the state index but could not be loaded"}}, report.Lost)
+
+	// The report survives restarts until acknowledged
+	reloaded := NewWorktreeStateManager(stateDir, nil)
+	t.Cleanup(reloaded.Stop)
+	require.NotNil(t, reloaded.PendingStateReport())
+	assert.Len(t, reloaded.PendingStateReport().Quarantined, 1)
+
+	require.NoError(t, reloaded.AcknowledgeStateReport())
+	assert.Nil(t, reloaded.PendingStateReport())
+	assert.Error(t, reloaded.AcknowledgeStateReport())
+	assert.FileExists(t, filepath.Join(stateDir, stateReportFile), "kept for exports")
+
+	// A later finding starts a new report
+	require.NoError(t, os.WriteFile(filepath.Join(stateDir, "worktrees", "wt-1.json"), []byte(`{"id":`), 0644))
+	restarted := NewWorktreeStateManager(stateDir, nil)
+	t.Cleanup(restarted.Stop)
+	report = restarted.PendingStateReport()
+	require.NotNil(t, report)
+	assert.Equal(t, []string{filepath.Join("worktrees", "wt-1.json") + corruptStateSuffix}, report.Quarantined)
+	assert.Equal(t, []models.StateReportEntry{{Kind: "worktree", ID: "wt-1", Reason: "listed in the state index but could not be loaded"}}, report.Lost, "acknowledged losses are not reported again")
+
+	// Migrating a legacy state.json is reported too
+	legacyDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(legacyDir, legacyStateFile), []byte(`{"repositories": {}}`), 0644))
+	migrated := NewWorktreeStateManager(legacyDir, nil)
+	t.Cleanup(migrated.Stop)
+	require.NotNil(t, migrated.PendingStateReport())
+	assert.Len(t, migrated.PendingStateReport().Migrations, 1)
+}
+
+func TestStateStoreRestoresFromBackup(t *testing.T) {
+	stateDir := t.TempDir()
+	stateManager := newStoreTestManager(t, stateDir, 2)
+	require.NoError(t, stateManager.UpdateWorktree("wt-0", map[string]interface{}{"commit_count": 3}))
+	require.NoError(t, stateManager.UpdateWorktree("wt-0", map[string]interface{}{"commit_count": 4}))
+	name := filepath.Join("worktrees", "wt-0.json")
+	assert.FileExists(t, filepath.Join(stateDir, name+stateBackupSuffix))
+
+	// The container died mid-write
+	require.NoError(t, os.WriteFile(filepath.Join(stateDir, name), []byte(`{"id": "wt-0", "na`), 0644))
+	reloaded := NewWorktreeStateManager(stateDir, nil)
+	t.Cleanup(reloaded.Stop)
+	worktree, exists := reloaded.GetWorktree("wt-0")
+	require.True(t, exists, "loaded from the backup")
+	assert.Equal(t, 3, worktree.CommitCount, "the backup misses the last change")
+	assert.FileExists(t, filepath.Join(stateDir, name+corruptStateSuffix))
+	data, err := os.ReadFile(filepath.Join(stateDir, name))
+	require.NoError(t, err)
+	assert.True(t, json.Valid(data), "the backup is put back in place")
+
+	report := reloaded.PendingStateReport()
+	require.NotNil(t, report)
+	assert.Equal(t, []string{name}, report.Restored)
+	assert.Empty(t, report.Lost)
+	var loadErr *StateLoadError
+	require.ErrorAs(t, reloaded.StateLoadError(), &loadErr)
+	assert.Equal(t, []string{name}, loadErr.Corrupt)
+	assert.Equal(t, []string{name}, loadErr.Restored)
+
+	s := &GitService{stateManager: reloaded}
+	status := s.GetStatus()
+	assert.True(t, status.Degraded)
+	assert.Equal(t, "1 corrupt state files, 1 restored from backup and 0 lost: "+name, status.DegradedReason)
+	require.NoError(t, reloaded.AcknowledgeStateReport())
+	assert.False(t, s.GetStatus().Degraded)
+
+	// Deleting a worktree removes its backup too
+	require.NoError(t, reloaded.UpdateWorktree("wt-1", map[string]interface{}{"commit_count": 1}))
+	require.NoError(t, reloaded.DeleteWorktree("wt-1"))
+	assert.NoFileExists(t, filepath.Join(stateDir, "worktrees", "wt-1.json"+stateBackupSuffix))
+}
+
+func TestValidateState(t *testing.T) {
+	root := t.TempDir()
+	stateDir := filepath.Join(root, "state")
+	stateManager := NewWorktreeStateManager(stateDir, nil)
+	t.Cleanup(stateManager.Stop)
+	for _, id := range []string{"local/repo", "local/away", "local/gone"} {
+		require.NoError(t, stateManager.AddRepository(&models.Repository{ID: id, Path: root, Available: true}))
+	}
+
+	checkout := filepath.Join(root, "felix")
+	require.NoError(t, os.MkdirAll(checkout, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(checkout, ".git"), []byte("gitdir: "+root+"\n"), 0644))
+	leftover := filepath.Join(root, "leftover")
+	require.NoError(t, os.MkdirAll(leftover, 0755))
+	for _, worktree := range []*models.Worktree{
+		{ID: "wt-ok", RepoID: "local/repo", Name: "repo/felix", Path: checkout},
+		{ID: "wt-leftover", RepoID: "local/repo", Name: "repo/leftover", Path: leftover},
+		{ID: "wt-missing", RepoID: "local/repo", Name: "repo/missing", Path: filepath.Join(root, "missing")},
+		{ID: "wt-away", RepoID: "local/away", Name: "away/tabby", Path: "/live/away/tabby"},
+		{ID: "wt-ghost", RepoID: "local/gone", Name: "gone/ghost", Path: filepath.Join(root, "ghost")},
+	} {
+		require.NoError(t, stateManager.AddWorktree(worktree))
+	}
+	// A repository whose state file vanished leaves its worktrees behind
+	require.NoError(t, os.Remove(filepath.Join(stateDir, "repositories", "local%2Fgone.json")))
+	stateManager = NewWorktreeStateManager(stateDir, nil)
+	t.Cleanup(stateManager.Stop)
+	require.NoError(t, stateManager.UpdateRepository("local/away", func(repo *models.Repository) { repo.Available = false }))
+
+	validation := stateManager.ValidateState()
+	assert.Equal(t, []models.StateReportEntry{
+		{Kind: "worktree", ID: "wt-ghost", Name: "gone/ghost", Reason: "repository local/gone is no longer in state"},
+	}, validation.Pruned)
+	assert.Equal(t, []models.StateReportEntry{
+		{Kind: "worktree", ID: "wt-leftover", Name: "repo/leftover", Reason: "path " + leftover + " has no .git, it isn't a git checkout"},
+		{Kind: "worktree", ID: "wt-missing", Name: "repo/missing", Reason: "path " + filepath.Join(root, "missing") + " no longer exists"},
+	}, validation.Stale, "worktrees of unavailable repositories are left alone")
+
+	_, exists := stateManager.GetWorktree("wt-ghost")
+	assert.False(t, exists)
+	_, exists = stateManager.GetWorktree("wt-leftover")
+	assert.True(t, exists, "stale worktrees are kept")
+	report := stateManager.PendingStateReport()
+	require.NotNil(t, report)
+	assert.Equal(t, validation.Pruned, report.Pruned)
+	assert.Equal(t, validation.Stale, report.Stale)
+
+	// Validating again flags the same worktrees once
+	stateManager.ValidateState()
+	assert.Len(t, stateManager.PendingStateReport().Stale, 2)
+	assert.Len(t, stateManager.PendingStateReport().Pruned, 1)
+}
@@ -0,0 +1,90 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+)
+
+// EventSequencer is implemented by events emitters that number the events they broadcast
+type EventSequencer interface {
+	LastEventSequence() uint64
+}
+
+// WorktreesSummary is a compact view of all worktrees for clients that poll frequently.
+// Detailed worktree data is only available from the full worktree list.
+type WorktreesSummary struct {
+	// Total number of worktrees
+	Total int `json:"total" example:"5"`
+	// Worktrees with uncommitted changes
+	Dirty int `json:"dirty" example:"2"`
+	// Worktrees in a conflicted state
+	Conflicted int `json:"conflicted" example:"0"`
+	// Worktrees with an associated pull request
+	WithPullRequests int `json:"with_pull_requests" example:"3"`
+	// Worktrees whose pull request has failing checks
+	FailingChecks int `json:"failing_checks" example:"1"`
+	// Sequence number of the last event broadcast to SSE clients
+	LastEventSequence uint64 `json:"last_event_sequence" example:"1042"`
+	// Hash of the full worktree list state, changes whenever the detailed list would change
+	Hash string `json:"hash" example:"9f86d081884c7d65"`
+}
+
+// GetWorktreesSummary returns counts and change indicators for all worktrees. It is served
+// purely from in-memory state and the status cache: it never runs git, never queues status
+// refreshes and never saves state, so it is safe to call at high frequency.
+func (s *GitService) GetWorktreesSummary() *WorktreesSummary {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	allWorktrees := s.stateManager.GetAllWorktrees()
+	ids := make([]string, 0, len(allWorktrees))
+	for id := range allWorktrees {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	summary := &WorktreesSummary{Total: len(ids)}
+	hasher := sha256.New()
+	encoder := json.NewEncoder(hasher)
+
+	for _, id := range ids {
+		wt := allWorktrees[id]
+		if s.worktreeCache != nil {
+			s.worktreeCache.applyCachedStatus(wt)
+		}
+
+		checksState := ""
+		if wt.PullRequestURL != "" {
+			if prState := cachedPRState(wt.PullRequestURL); prState != nil {
+				wt.PullRequestState = prState.State
+				wt.PullRequestLastSynced = &prState.LastSynced
+				checksState = prState.ChecksState
+			}
+		}
+
+		if wt.IsDirty {
+			summary.Dirty++
+		}
+		if wt.HasConflicts {
+			summary.Conflicted++
+		}
+		if wt.PullRequestURL != "" {
+			summary.WithPullRequests++
+			if checksState == "FAILURE" || checksState == "ERROR" {
+				summary.FailingChecks++
+			}
+		}
+
+		_ = encoder.Encode(wt)
+		_ = encoder.Encode(checksState)
+	}
+
+	if sequencer, ok := s.eventsEmitter.(EventSequencer); ok {
+		summary.LastEventSequence = sequencer.LastEventSequence()
+	}
+	summary.Hash = hex.EncodeToString(hasher.Sum(nil))[:16]
+
+	return summary
+}
@@ -0,0 +1,47 @@
+package services
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+func TestGetWorktreesSummary(t *testing.T) {
+	root := t.TempDir()
+	stateManager := NewWorktreeStateManager(filepath.Join(root, "state"), nil)
+	t.Cleanup(stateManager.Stop)
+
+	require.NoError(t, stateManager.AddRepository(&models.Repository{ID: "owner/repo", Path: filepath.Join(root, "repo")}))
+	require.NoError(t, stateManager.AddWorktree(&models.Worktree{ID: "wt-felix", RepoID: "owner/repo", Name: "felix", IsDirty: true}))
+	require.NoError(t, stateManager.AddWorktree(&models.Worktree{ID: "wt-luna", RepoID: "owner/repo", Name: "luna", HasConflicts: true,
+		PullRequestURL: "https://github.com/owner/repo/pull/7"}))
+	require.NoError(t, stateManager.AddWorktree(&models.Worktree{ID: "wt-milo", RepoID: "owner/repo", Name: "milo",
+		PullRequestURL: "https://github.com/owner/repo/pull/8"}))
+
+	prSyncManager := GetPRSyncManager(nil)
+	prSyncManager.LoadStatesFromData(map[string]*models.PullRequestState{
+		"owner/repo#7": {Number: 7, State: "OPEN", Repository: "owner/repo", ChecksState: "FAILURE"},
+		"owner/repo#8": {Number: 8, State: "OPEN", Repository: "owner/repo", ChecksState: "SUCCESS"},
+	})
+	t.Cleanup(func() { prSyncManager.LoadStatesFromData(nil) })
+
+	s := &GitService{stateManager: stateManager}
+	summary := s.GetWorktreesSummary()
+	assert.Equal(t, 3, summary.Total)
+	assert.Equal(t, 1, summary.Dirty)
+	assert.Equal(t, 1, summary.Conflicted)
+	assert.Equal(t, 2, summary.WithPullRequests)
+	assert.Equal(t, 1, summary.FailingChecks)
+	assert.Len(t, summary.Hash, 16)
+	assert.Equal(t, summary.Hash, s.GetWorktreesSummary().Hash, "hash is stable while nothing changes")
+
+	prSyncManager.LoadStatesFromData(map[string]*models.PullRequestState{
+		"owner/repo#7": {Number: 7, State: "OPEN", Repository: "owner/repo", ChecksState: "SUCCESS"},
+	})
+	changed := s.GetWorktreesSummary()
+	assert.Equal(t, 0, changed.FailingChecks)
+	assert.NotEqual(t, summary.Hash, changed.Hash)
+}
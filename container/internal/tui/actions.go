@@ -0,0 +1,371 @@
+package tui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/vanpelt/catnip/internal/tui/components"
+)
+
+// Action groups, in the order the help overlay lists them
+const (
+	actionGroupGlobal   = "Global"
+	actionGroupLogs     = "Logs"
+	actionGroupTerminal = "Terminal"
+)
+
+// Action IDs referenced outside the registry
+const (
+	actionCommandPalette = "palette.open"
+	actionLogsSearch     = "logs.search"
+	actionLogsClear      = "logs.clear"
+	actionShellNew       = "shell.new"
+	actionShellObserve   = "shell.observe"
+	actionShellRequest   = "shell.request-control"
+	actionShellDetach    = "shell.detach"
+	actionShellGrant     = "shell.grant-control"
+	actionShellDeny      = "shell.deny-control"
+)
+
+// Action is a user command. Keybindings and the command palette both run actions, and the
+// help overlay lists them, so the three stay in sync.
+type Action struct {
+	ID    string
+	Title string
+	Group string
+	// Keybinding ("" for actions only reachable from the palette)
+	Key string
+	// Label of the input the action asks for first ("" when it needs none)
+	Prompt string
+	// Available reports whether the action applies in the current context (nil means always)
+	Available func(m *Model) bool
+	// Run performs the action, with the prompted input when Prompt is set
+	Run func(m *Model, input string) (*Model, tea.Cmd)
+}
+
+// actionRegistry returns every action. It is a function rather than a variable because
+// several actions open overlays that list the registry.
+func actionRegistry() []Action {
+	return []Action{
+		// Global actions, dispatched by handleGlobalKeys
+		{ID: actionCommandPalette, Title: "Command palette", Group: actionGroupGlobal, Key: components.KeyCommandPalette,
+			Available: func(m *Model) bool { return !m.showCommandPalette },
+			Run: func(m *Model, _ string) (*Model, tea.Cmd) {
+				m.showCommandPalette = true
+				m.commandPalette = components.NewPalette("⌘ Command Palette", availablePaletteItems(m))
+				return m, nil
+			}},
+		{ID: "help.show", Title: "Keyboard shortcuts", Group: actionGroupGlobal, Key: components.KeyHelp,
+			Run: func(m *Model, _ string) (*Model, tea.Cmd) {
+				m.showHelp = true
+				return m, nil
+			}},
+		{ID: "view.overview", Title: "Go to overview", Group: actionGroupGlobal, Key: components.KeyOverview,
+			Run: func(m *Model, _ string) (*Model, tea.Cmd) {
+				if m.currentView != OverviewView {
+					m.SwitchToView(OverviewView)
+				}
+				return m, nil
+			}},
+		{ID: "view.logs", Title: "Go to logs", Group: actionGroupGlobal, Key: components.KeyLogs,
+			Run: runShowLogs},
+		{ID: "view.shell", Title: "Open terminal", Group: actionGroupGlobal, Key: components.KeyShell,
+			Run: runShowShell},
+		{ID: "shell.new-named", Title: "New terminal session...", Group: actionGroupGlobal, Prompt: "Session name",
+			Run: func(m *Model, name string) (*Model, tea.Cmd) {
+				if globalShellManager != nil && globalShellManager.GetSession(name) != nil {
+					return m.views[ShellView].(*ShellViewImpl).switchToShellSession(m, name), nil
+				}
+				return m.views[OverviewView].(*OverviewViewImpl).startShellSession(m, name)
+			}},
+		{ID: "browser.open", Title: "Open in browser", Group: actionGroupGlobal, Key: components.KeyOpenBrowser,
+			Run: runOpenBrowser},
+		{ID: "browser.open-port", Title: "Open port in browser...", Group: actionGroupGlobal, Prompt: "Port",
+			Available: func(m *Model) bool { return m.appHealthy },
+			Run: func(m *Model, port string) (*Model, tea.Cmd) {
+				if n, err := strconv.Atoi(port); err != nil || n <= 0 || n > 65535 {
+					m.err = fmt.Errorf("invalid port %q", port)
+					return m, nil
+				}
+				overviewView := m.views[OverviewView].(*OverviewViewImpl)
+				url := fmt.Sprintf("%s/%s", m.getBaseURL(""), port)
+				go func() {
+					_ = overviewView.openBrowser(url)
+				}()
+				return m, nil
+			}},
+		{ID: actionStateReportDismiss, Title: "Dismiss state recovery report", Group: actionGroupGlobal,
+			Available: func(m *Model) bool { return m.stateReport != nil },
+			Run: func(m *Model, _ string) (*Model, tea.Cmd) {
+				m.stateReport = nil
+				return m, m.acknowledgeStateReport()
+			}},
+		{ID: "app.quit", Title: "Quit", Group: actionGroupGlobal, Key: components.KeyQuit,
+			Run: func(m *Model, _ string) (*Model, tea.Cmd) {
+				m.quitRequested = true
+				return m, tea.Quit
+			}},
+
+		// Logs view
+		{ID: actionLogsSearch, Title: "Search logs", Group: actionGroupLogs, Key: components.KeyLogsSearch,
+			Available: func(m *Model) bool { return m.currentView == LogsView && !m.searchMode },
+			Run: func(m *Model, _ string) (*Model, tea.Cmd) {
+				m.searchMode = true
+				return m, m.searchInput.Focus()
+			}},
+		{ID: actionLogsClear, Title: "Clear log filter", Group: actionGroupLogs, Key: components.KeyLogsClear,
+			Available: func(m *Model) bool { return m.currentView == LogsView && m.searchPattern != "" },
+			Run: func(m *Model, _ string) (*Model, tea.Cmd) {
+				m.searchPattern = ""
+				m.searchInput.SetValue("")
+				return m.views[LogsView].(*LogsViewImpl).updateLogFilter(m), nil
+			}},
+
+		// Terminal session list
+		{ID: actionShellNew, Title: "New terminal session", Group: actionGroupTerminal, Key: components.KeyShellNewSession,
+			Available: inSessionList,
+			Run: func(m *Model, _ string) (*Model, tea.Cmd) {
+				m.showSessionList = false
+				return m.views[ShellView].(*ShellViewImpl).createNewShellSessionWithCmd(m)
+			}},
+		{ID: actionShellObserve, Title: "Observe a worktree's Claude session", Group: actionGroupTerminal, Key: components.KeyShellObserve,
+			Available: inSessionList,
+			Run: func(m *Model, _ string) (*Model, tea.Cmd) {
+				m.showSessionList = false
+				m.showObservePicker = true
+				m.observeWorktrees = nil
+				m.observeDetails = nil
+				m.observeErr = nil
+				return m, m.fetchObservableWorktrees()
+			}},
+
+		// Observed worktree session
+		{ID: actionShellRequest, Title: "Request control of the worktree session", Group: actionGroupTerminal, Key: components.KeyShellRequestControl,
+			Available: observingReadOnly,
+			Run: func(m *Model, _ string) (*Model, tea.Cmd) {
+				return m.views[ShellView].(*ShellViewImpl).requestControl(m), nil
+			}},
+		{ID: actionShellDetach, Title: "Stop observing the worktree session", Group: actionGroupTerminal, Key: components.KeyShellDetach,
+			Available: observingReadOnly,
+			Run: func(m *Model, _ string) (*Model, tea.Cmd) {
+				return m.views[ShellView].(*ShellViewImpl).detach(m), nil
+			}},
+		{ID: actionShellGrant, Title: "Grant control to the observer", Group: actionGroupTerminal, Key: components.KeyShellGrantControl,
+			Available: controlRequested,
+			Run: func(m *Model, _ string) (*Model, tea.Cmd) {
+				return m.views[ShellView].(*ShellViewImpl).answerControlRequest(m, true), nil
+			}},
+		{ID: actionShellDeny, Title: "Deny control to the observer", Group: actionGroupTerminal, Key: components.KeyShellDenyControl,
+			Available: controlRequested,
+			Run: func(m *Model, _ string) (*Model, tea.Cmd) {
+				return m.views[ShellView].(*ShellViewImpl).answerControlRequest(m, false), nil
+			}},
+	}
+}
+
+// findAction returns the registered action with id
+func findAction(id string) (Action, bool) {
+	for _, action := range actionRegistry() {
+		if action.ID == id {
+			return action, true
+		}
+	}
+	return Action{}, false
+}
+
+// globalActionForKey returns the global action bound to key
+func globalActionForKey(key string) (Action, bool) {
+	for _, action := range actionRegistry() {
+		if action.Group == actionGroupGlobal && action.Key == key {
+			return action, true
+		}
+	}
+	return Action{}, false
+}
+
+// runAction runs an action if it is available, asking for its input first when it needs some
+func (m *Model) runAction(id string) (*Model, tea.Cmd) {
+	action, ok := findAction(id)
+	if !ok || (action.Available != nil && !action.Available(m)) {
+		return m, nil
+	}
+	if action.Prompt != "" {
+		m.showActionPrompt = true
+		m.pendingActionID = action.ID
+		m.actionPrompt = components.NewPrompt(action.Title, action.Prompt)
+		return m, nil
+	}
+	return action.Run(m, "")
+}
+
+// availablePaletteItems lists the actions available in the current context
+func availablePaletteItems(m *Model) []components.PaletteItem {
+	var items []components.PaletteItem
+	for _, action := range actionRegistry() {
+		if action.Available != nil && !action.Available(m) {
+			continue
+		}
+		items = append(items, components.PaletteItem{ID: action.ID, Title: action.Title, Key: action.Key})
+	}
+	return items
+}
+
+// handleOverlayKeys routes keys to the command palette, action prompt or help overlay while one
+// is open. Quit keys still reach the global handler.
+func (m Model) handleOverlayKeys(msg tea.KeyMsg) (*Model, tea.Cmd, bool) {
+	keyStr := msg.String()
+	if keyStr == components.KeyQuit || keyStr == components.KeyQuitAlt {
+		return &m, nil, false
+	}
+
+	switch {
+	case m.showCommandPalette:
+		var result components.OverlayResult
+		var cmd tea.Cmd
+		m.commandPalette, result, cmd = m.commandPalette.HandleKey(msg)
+		switch result {
+		case components.OverlayCancelled:
+			m.showCommandPalette = false
+		case components.OverlaySubmitted:
+			m.showCommandPalette = false
+			item, _ := m.commandPalette.Selected()
+			newModel, cmd := m.runAction(item.ID)
+			return newModel, cmd, true
+		}
+		return &m, cmd, true
+
+	case m.showActionPrompt:
+		var result components.OverlayResult
+		var cmd tea.Cmd
+		m.actionPrompt, result, cmd = m.actionPrompt.HandleKey(msg)
+		switch result {
+		case components.OverlayCancelled:
+			m.showActionPrompt = false
+		case components.OverlaySubmitted:
+			m.showActionPrompt = false
+			if action, ok := findAction(m.pendingActionID); ok {
+				newModel, cmd := action.Run(&m, m.actionPrompt.Value())
+				return newModel, cmd, true
+			}
+		}
+		return &m, cmd, true
+
+	case m.showHelp:
+		switch keyStr {
+		case components.KeyEscape, components.KeyEnter, components.KeyHelp:
+			m.showHelp = false
+		}
+		return &m, nil, true
+	}
+
+	return &m, nil, false
+}
+
+// renderHelp renders the keyboard shortcut overlay from the action registry
+func (m Model) renderHelp() string {
+	groups := []string{actionGroupGlobal, actionGroupLogs, actionGroupTerminal}
+	var lines []string
+	for _, group := range groups {
+		var entries []string
+		for _, action := range actionRegistry() {
+			if action.Group == group && action.Key != "" {
+				padding := strings.Repeat(" ", max(1, 11-len(action.Key)))
+				entries = append(entries, "  "+components.KeyHighlightStyle.Render(action.Key)+padding+action.Title)
+			}
+		}
+		if len(entries) == 0 {
+			continue
+		}
+		if len(lines) > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, components.SubHeaderStyle.Render(group))
+		lines = append(lines, entries...)
+	}
+	lines = append(lines, "", components.MutedStyle.Render("Esc: Close • Ctrl+K: all actions"))
+
+	title := components.OverlayTitleStyle.Render("⌨️  Keyboard Shortcuts")
+	return components.OverlayBoxStyle.Render(title + "\n\n" + strings.Join(lines, "\n"))
+}
+
+// overlayWidth is the width of the palette and prompt overlays
+func (m Model) overlayWidth() int {
+	width := 64
+	if m.width-4 < width {
+		width = m.width - 4
+	}
+	return width
+}
+
+func inSessionList(m *Model) bool {
+	return m.currentView == ShellView && m.showSessionList
+}
+
+func observingReadOnly(m *Model) bool {
+	session := currentShellSession(m)
+	return m.currentView == ShellView && session != nil && session.Observing && session.ReadOnly
+}
+
+func controlRequested(m *Model) bool {
+	session := currentShellSession(m)
+	return m.currentView == ShellView && session != nil && session.IncomingRequest != ""
+}
+
+// runShowLogs switches to the logs view and refreshes the logs
+func runShowLogs(m *Model, _ string) (*Model, tea.Cmd) {
+	if m.currentView == LogsView {
+		return m, nil
+	}
+	m.SwitchToView(LogsView)
+	// Update viewport size and content when switching to logs
+	if m.height > 0 {
+		headerHeight := 4
+		m.logsViewport.Width = m.width - 4
+		m.logsViewport.Height = m.height - headerHeight
+	}
+	// Update log filter and fetch logs
+	logsView := m.views[LogsView].(*LogsViewImpl)
+	m = logsView.updateLogFilter(m)
+	return m, m.fetchLogs()
+}
+
+// runShowShell switches to the shell view, listing the sessions when there are any
+func runShowShell(m *Model, _ string) (*Model, tea.Cmd) {
+	if m.currentView == ShellView {
+		return m, nil
+	}
+	// Check if we have existing sessions
+	if globalShellManager != nil && len(globalShellManager.sessions) > 0 {
+		m.showSessionList = true
+		m.SwitchToView(ShellView)
+		return m, nil
+	}
+	// Create new session
+	overviewView := m.views[OverviewView].(*OverviewViewImpl)
+	return overviewView.createNewShellSessionWithCmd(m)
+}
+
+// runOpenBrowser opens the browser with the port selection overlay if there are ports, or
+// directly if only the main app is up
+func runOpenBrowser(m *Model, _ string) (*Model, tea.Cmd) {
+	if len(m.ports) > 0 {
+		// Show port selector overlay
+		m.showPortSelector = true
+		m.selectedPortIndex = 0 // Default to first port
+	} else if m.appHealthy {
+		// No other ports, open main app directly
+		overviewView := m.views[OverviewView].(*OverviewViewImpl)
+		url := withBrowserToken(m.getBaseURL(""))
+		go func() {
+			_ = overviewView.openBrowser(url)
+		}()
+	} else {
+		// App is not ready, show bold feedback
+		m.bootingBold = true
+		m.bootingBoldTimer = time.Now()
+	}
+	return m, nil
+}
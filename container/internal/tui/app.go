@@ -61,6 +61,7 @@ type WorktreeState struct {
 	Path                string                     `json:"path"`
 	Name                string                     `json:"name"`
 	ClaudeActivityState models.ClaudeActivityState `json:"claude_activity_state"`
+	CreatedBy           string                     `json:"created_by,omitempty"`
 	// Add other fields as needed
 }
 
@@ -126,14 +127,17 @@ func (a *App) addWorktree(worktreeData map[string]interface{}) {
 		activityState = models.ClaudeActivityState(state)
 	}
 
+	createdBy, _ := worktreeData["created_by"].(string)
+
 	a.worktrees[id] = &WorktreeState{
 		ID:                  id,
 		Path:                path,
 		Name:                name,
 		ClaudeActivityState: activityState,
+		CreatedBy:           createdBy,
 	}
 
-	debugLog("Worktree added: %s (ID: %s) -> %s", path, id, activityState)
+	debugLog("Worktree added: %s (ID: %s, created by: %s) -> %s", path, id, createdBy, activityState)
 }
 
 // updateWorktree updates or creates a worktree in the state
@@ -157,6 +161,9 @@ func (a *App) updateWorktree(worktreeID string, updates map[string]interface{})
 	if activityState, ok := updates["claude_activity_state"].(string); ok {
 		worktree.ClaudeActivityState = models.ClaudeActivityState(activityState)
 	}
+	if createdBy, ok := updates["created_by"].(string); ok {
+		worktree.CreatedBy = createdBy
+	}
 
 	debugLog("Worktree updated: %s (ID: %s) -> %s", worktree.Path, worktreeID, worktree.ClaudeActivityState)
 }
@@ -204,11 +211,13 @@ func (a *App) loadInitialWorktrees(m *Model) {
 	for _, wt := range worktrees {
 		if id, ok := wt["id"].(string); ok {
 			if path, ok := wt["path"].(string); ok {
+				createdBy, _ := wt["created_by"].(string)
 				a.worktrees[id] = &WorktreeState{
 					ID:                  id,
 					Path:                path,
 					Name:                wt["name"].(string),
 					ClaudeActivityState: models.ClaudeInactive, // Default to inactive
+					CreatedBy:           createdBy,
 				}
 				debugLog("Loaded worktree: %s -> %s", id, path)
 			}
@@ -406,6 +415,16 @@ func (m Model) View() string {
 		result = m.overlayOnContent(result, overlay)
 	}
 
+	// Overlay the command palette, action prompt or help
+	switch {
+	case m.showCommandPalette:
+		result = m.overlayOnContent(result, m.commandPalette.View(m.overlayWidth()))
+	case m.showActionPrompt:
+		result = m.overlayOnContent(result, m.actionPrompt.View(m.overlayWidth()))
+	case m.showHelp:
+		result = m.overlayOnContent(result, m.renderHelp())
+	}
+
 	return result
 }
 
@@ -420,13 +439,13 @@ func (m Model) renderFooter() string {
 		}
 		return footerStyle.Render("Initializing container... Press Ctrl+Q to quit")
 	case OverviewView:
-		return footerStyle.Render("Ctrl+L: logs | Ctrl+T: terminal | Ctrl+B: browser | Ctrl+Q: quit")
+		return footerStyle.Render("Ctrl+K: commands | Ctrl+L: logs | Ctrl+T: terminal | Ctrl+B: browser | Ctrl+Q: quit")
 	case ShellView:
 		scrollKey := "Alt"
 		if runtime.GOOS == "darwin" {
 			scrollKey = "Option"
 		}
-		return footerStyle.Render(fmt.Sprintf("Ctrl+O: overview | Ctrl+L: logs | Ctrl+B: browser | Ctrl+Q: quit | %s+↑↓/PgUp/PgDn: scroll", scrollKey))
+		return footerStyle.Render(fmt.Sprintf("Ctrl+K: commands | Ctrl+O: overview | Ctrl+L: logs | Ctrl+B: browser | Ctrl+Q: quit | %s+↑↓/PgUp/PgDn: scroll", scrollKey))
 	case LogsView:
 		if m.searchMode {
 			// Replace footer with search input
@@ -435,9 +454,9 @@ func (m Model) renderFooter() string {
 			return footerStyle.Render(searchContent)
 		} else {
 			if m.searchPattern != "" {
-				return footerStyle.Render("/ search, c clear filter, ↑↓ scroll, Ctrl+O overview, Ctrl+B browser, Ctrl+Q quit • Streaming filtered logs")
+				return footerStyle.Render("/ search, c clear filter, ↑↓ scroll, Ctrl+K commands, Ctrl+O overview, Ctrl+Q quit • Streaming filtered logs")
 			} else {
-				return footerStyle.Render("/ search, c clear filter, ↑↓ scroll, Ctrl+O overview, Ctrl+B browser, Ctrl+Q quit • Auto-refresh: ON")
+				return footerStyle.Render("/ search, c clear filter, ↑↓ scroll, Ctrl+K commands, Ctrl+O overview, Ctrl+Q quit • Auto-refresh: ON")
 			}
 		}
 	}
@@ -512,22 +531,9 @@ func (m Model) renderPortSelector() string {
 	content := append(menuItems, instructions...)
 	menuContent := strings.Join(content, "\n")
 
-	// Style the menu box
-	boxStyle := lipgloss.NewStyle().
-		Border(lipgloss.RoundedBorder()).
-		BorderForeground(lipgloss.Color("62")).
-		Padding(1, 2).
-		Background(lipgloss.Color("235")).
-		Foreground(lipgloss.Color("15"))
-
-	titleStyle := lipgloss.NewStyle().
-		Bold(true).
-		Foreground(lipgloss.Color("39")).
-		Align(lipgloss.Center)
-
-	title := titleStyle.Render("🌐 Select Browser Target")
+	title := components.OverlayTitleStyle.Render("🌐 Select Browser Target")
 
-	return boxStyle.Render(title + "\n\n" + menuContent)
+	return components.OverlayBoxStyle.Render(title + "\n\n" + menuContent)
 }
 
 // overlayOnContent centers an overlay on top of the main content
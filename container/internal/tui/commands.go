@@ -2,6 +2,10 @@ package tui
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
 	"strings"
 	"time"
 
@@ -116,6 +120,65 @@ func (m *Model) fetchHealthStatus() tea.Cmd {
 	}
 }
 
+// fetchObservableWorktrees lists worktree names whose Claude sessions can be observed
+func (m *Model) fetchObservableWorktrees() tea.Cmd {
+	return func() tea.Msg {
+		client := m.createAuthenticatedClient(5 * time.Second)
+		resp, err := client.Get(fmt.Sprintf("%s/v1/git/worktrees", m.getBaseURL("")))
+		if err != nil {
+			return observeWorktreesMsg{err: err}
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return observeWorktreesMsg{err: fmt.Errorf("failed to list worktrees: HTTP %d", resp.StatusCode)}
+		}
+
+		var worktrees []struct {
+			ID           string `json:"id"`
+			Name         string `json:"name"`
+			Path         string `json:"path"`
+			Branch       string `json:"branch"`
+			BranchRename *struct {
+				Outcome string `json:"outcome"`
+				Reason  string `json:"reason"`
+				Message string `json:"message"`
+			} `json:"branch_rename"`
+			Todos        []worktreeTodo `json:"todos"`
+			SessionTitle *struct {
+				Title string `json:"title"`
+			} `json:"session_title"`
+			ActivityState string   `json:"claude_activity_state"`
+			HandedOff     *handoff `json:"handed_off"`
+			CreatedBy     string   `json:"created_by"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&worktrees); err != nil {
+			return observeWorktreesMsg{err: err}
+		}
+
+		names := make([]string, 0, len(worktrees))
+		branchNotes := make(map[string]string)
+		details := make(map[string]*worktreeDetail)
+		for _, wt := range worktrees {
+			if wt.Name == "" {
+				continue
+			}
+			names = append(names, wt.Name)
+			detail := &worktreeDetail{ID: wt.ID, Path: wt.Path, ActivityState: wt.ActivityState, CreatedBy: wt.CreatedBy, Todos: wt.Todos, HandedOff: wt.HandedOff}
+			if wt.SessionTitle != nil {
+				detail.Title = wt.SessionTitle.Title
+			}
+			details[wt.Name] = detail
+			// Explain why the branch kept its catnip name after the last rename attempt
+			if rename := wt.BranchRename; rename != nil && (rename.Outcome == "skipped" || rename.Outcome == "failed") {
+				branchNotes[wt.Name] = fmt.Sprintf("%s kept its name, rename %s (%s): %s", wt.Branch, rename.Outcome, rename.Reason, rename.Message)
+			}
+		}
+		sort.Strings(names)
+		return observeWorktreesMsg{names: names, branchNotes: branchNotes, details: details}
+	}
+}
+
 // Batch commands for initialization
 func (m *Model) initCommands() tea.Cmd {
 	var commands []tea.Cmd
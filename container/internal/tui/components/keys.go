@@ -19,6 +19,10 @@ const (
 	// Browser shortcuts
 	KeyOpenBrowser = "ctrl+b"
 
+	// Command palette and keyboard shortcut help
+	KeyCommandPalette = "ctrl+k"
+	KeyHelp           = "f1"
+
 	// Common keys
 	KeyEscape    = "esc"
 	KeyEnter     = "enter"
@@ -70,6 +74,18 @@ const (
 
 	// Session management (only in session list mode)
 	KeyShellNewSession = "n"
+	KeyShellObserve    = "o" // Observe a worktree's Claude session read-only
+
+	// Observe picker: run the remediation of the selected worktree's first fixable health finding
+	KeyShellHealthFix = "a"
+
+	// Observe mode (only while observing without control)
+	KeyShellRequestControl = "ctrl+r"
+	KeyShellDetach         = "ctrl+x"
+
+	// Answering an observer's control request
+	KeyShellGrantControl = "alt+y"
+	KeyShellDenyControl  = "alt+n"
 )
 
 // Control keys
@@ -84,7 +100,7 @@ const (
 // IsGlobalNavigationKey checks if a key is a global navigation command
 func IsGlobalNavigationKey(key string) bool {
 	switch key {
-	case KeyQuit, KeyQuitAlt, KeyOverview, KeyLogs, KeyShell, KeyOpenBrowser:
+	case KeyQuit, KeyQuitAlt, KeyOverview, KeyLogs, KeyShell, KeyOpenBrowser, KeyCommandPalette, KeyHelp:
 		return true
 	}
 	return false
@@ -0,0 +1,245 @@
+package components
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// paletteVisibleItems is how many matches a palette shows at once
+const paletteVisibleItems = 10
+
+// PaletteItem is one entry of a Palette
+type PaletteItem struct {
+	ID    string
+	Title string
+	// Keybinding shown next to the entry ("" when the entry has none)
+	Key string
+}
+
+// OverlayResult tells the caller what a key press did to an overlay
+type OverlayResult int
+
+const (
+	// OverlayContinue means the overlay stays open
+	OverlayContinue OverlayResult = iota
+	// OverlaySubmitted means the user confirmed the selection or input
+	OverlaySubmitted
+	// OverlayCancelled means the user closed the overlay
+	OverlayCancelled
+)
+
+// Palette is a list of items filtered by fuzzy search as the user types
+type Palette struct {
+	title    string
+	input    textinput.Model
+	items    []PaletteItem
+	matches  []PaletteItem
+	selected int
+}
+
+// NewPalette creates a focused palette over items
+func NewPalette(title string, items []PaletteItem) Palette {
+	input := textinput.New()
+	input.Placeholder = "Type to search..."
+	input.Prompt = "> "
+	input.Focus()
+
+	p := Palette{title: title, input: input, items: items}
+	p.filter()
+	return p
+}
+
+// HandleKey moves the selection, submits, cancels or edits the search query
+func (p Palette) HandleKey(msg tea.KeyMsg) (Palette, OverlayResult, tea.Cmd) {
+	switch msg.String() {
+	case KeyEscape:
+		return p, OverlayCancelled, nil
+	case KeyEnter:
+		if _, ok := p.Selected(); ok {
+			return p, OverlaySubmitted, nil
+		}
+		return p, OverlayContinue, nil
+	case KeyUp, "ctrl+p":
+		if len(p.matches) > 0 {
+			p.selected = (p.selected - 1 + len(p.matches)) % len(p.matches)
+		}
+		return p, OverlayContinue, nil
+	case KeyDown, "ctrl+n":
+		if len(p.matches) > 0 {
+			p.selected = (p.selected + 1) % len(p.matches)
+		}
+		return p, OverlayContinue, nil
+	}
+
+	var cmd tea.Cmd
+	query := p.input.Value()
+	p.input, cmd = p.input.Update(msg)
+	if p.input.Value() != query {
+		p.filter()
+	}
+	return p, OverlayContinue, cmd
+}
+
+// Selected returns the highlighted item, if any item matches
+func (p Palette) Selected() (PaletteItem, bool) {
+	if p.selected < 0 || p.selected >= len(p.matches) {
+		return PaletteItem{}, false
+	}
+	return p.matches[p.selected], true
+}
+
+// Matches returns the items matching the query, best match first
+func (p Palette) Matches() []PaletteItem {
+	return p.matches
+}
+
+// filter recomputes the matches for the current query and resets the selection
+func (p *Palette) filter() {
+	query := p.input.Value()
+	type scored struct {
+		item  PaletteItem
+		score int
+	}
+	var results []scored
+	for _, item := range p.items {
+		if score, ok := FuzzyScore(query, item.Title); ok {
+			results = append(results, scored{item, score})
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].score > results[j].score })
+
+	p.matches = make([]PaletteItem, len(results))
+	for i, result := range results {
+		p.matches[i] = result.item
+	}
+	p.selected = 0
+}
+
+// View renders the palette as an overlay box of the given width
+func (p Palette) View(width int) string {
+	lines := []string{p.input.View(), ""}
+
+	// Keep the selection in a window of paletteVisibleItems entries
+	start := 0
+	if p.selected >= paletteVisibleItems {
+		start = p.selected - paletteVisibleItems + 1
+	}
+	end := start + paletteVisibleItems
+	if end > len(p.matches) {
+		end = len(p.matches)
+	}
+
+	innerWidth := width - 4 // horizontal padding
+	for i := start; i < end; i++ {
+		item := p.matches[i]
+		prefix := "  "
+		if i == p.selected {
+			prefix = "▶ "
+		}
+		key := KeyHighlightStyle.Render(item.Key)
+		gap := innerWidth - lipgloss.Width(prefix+item.Title) - lipgloss.Width(key)
+		if gap < 1 {
+			gap = 1
+		}
+		lines = append(lines, prefix+item.Title+strings.Repeat(" ", gap)+key)
+	}
+	if len(p.matches) == 0 {
+		lines = append(lines, MutedStyle.Render("  No matching actions"))
+	} else if len(p.matches) > paletteVisibleItems {
+		lines = append(lines, MutedStyle.Render(fmt.Sprintf("  %d of %d", p.selected+1, len(p.matches))))
+	}
+	lines = append(lines, "", MutedStyle.Render("↑↓: Navigate • Enter: Run • Esc: Cancel"))
+
+	return OverlayBoxStyle.Width(width).Render(OverlayTitleStyle.Render(p.title) + "\n\n" + strings.Join(lines, "\n"))
+}
+
+// Prompt asks for a single line of input in an overlay box
+type Prompt struct {
+	title string
+	input textinput.Model
+}
+
+// NewPrompt creates a focused prompt asking for label
+func NewPrompt(title, label string) Prompt {
+	input := textinput.New()
+	input.Prompt = label + ": "
+	input.Focus()
+	return Prompt{title: title, input: input}
+}
+
+// HandleKey submits, cancels or edits the input
+func (p Prompt) HandleKey(msg tea.KeyMsg) (Prompt, OverlayResult, tea.Cmd) {
+	switch msg.String() {
+	case KeyEscape:
+		return p, OverlayCancelled, nil
+	case KeyEnter:
+		if p.Value() == "" {
+			return p, OverlayContinue, nil
+		}
+		return p, OverlaySubmitted, nil
+	}
+
+	var cmd tea.Cmd
+	p.input, cmd = p.input.Update(msg)
+	return p, OverlayContinue, cmd
+}
+
+// Value returns the trimmed input
+func (p Prompt) Value() string {
+	return strings.TrimSpace(p.input.Value())
+}
+
+// View renders the prompt as an overlay box of the given width
+func (p Prompt) View(width int) string {
+	content := p.input.View() + "\n\n" + MutedStyle.Render("Enter: Confirm • Esc: Cancel")
+	return OverlayBoxStyle.Width(width).Render(OverlayTitleStyle.Render(p.title) + "\n\n" + content)
+}
+
+// FuzzyScore matches query against text as a case-insensitive subsequence. Higher scores are
+// better matches: characters matched in a row and at word starts count extra.
+func FuzzyScore(query, text string) (int, bool) {
+	queryRunes := []rune(strings.ToLower(strings.TrimSpace(query)))
+	textRunes := []rune(strings.ToLower(text))
+	if len(queryRunes) == 0 {
+		return 0, true
+	}
+
+	// Matching greedily from each occurrence of the first character keeps "log" from
+	// settling for the "l" of "Clear" in "Clear log filter"
+	best, found := 0, false
+	for start, r := range textRunes {
+		if r != queryRunes[0] {
+			continue
+		}
+		if score, ok := fuzzyScoreFrom(queryRunes, textRunes, start); ok && (!found || score > best) {
+			best, found = score, true
+		}
+	}
+	return best, found
+}
+
+// fuzzyScoreFrom greedily matches query against text starting at start
+func fuzzyScoreFrom(query, text []rune, start int) (int, bool) {
+	score, matched, previous := 0, 0, -2
+	for i := start; i < len(text) && matched < len(query); i++ {
+		if text[i] != query[matched] {
+			continue
+		}
+		score++
+		if i == previous+1 {
+			score += 3
+		}
+		if i == 0 || !unicode.IsLetter(text[i-1]) && !unicode.IsDigit(text[i-1]) {
+			score += 2
+		}
+		previous = i
+		matched++
+	}
+	return score, matched == len(query)
+}
@@ -0,0 +1,75 @@
+package components
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFuzzyScore(t *testing.T) {
+	_, ok := FuzzyScore("", "Go to logs")
+	assert.True(t, ok, "an empty query matches everything")
+
+	_, ok = FuzzyScore("gtl", "Go to logs")
+	assert.True(t, ok)
+	_, ok = FuzzyScore("lgo", "Go to logs")
+	assert.False(t, ok, "characters must appear in order")
+
+	prefix, _ := FuzzyScore("log", "Go to logs")
+	scattered, _ := FuzzyScore("log", "Clear log filter")
+	spread, _ := FuzzyScore("log", "Go to overview and close it")
+	assert.Equal(t, prefix, scattered)
+	assert.Greater(t, prefix, spread, "consecutive word-start matches rank higher")
+}
+
+func typeText(p Palette, text string) Palette {
+	for _, r := range text {
+		p, _, _ = p.HandleKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+	}
+	return p
+}
+
+func TestPaletteFiltersAndSelects(t *testing.T) {
+	p := NewPalette("Commands", []PaletteItem{
+		{ID: "overview", Title: "Go to overview", Key: "ctrl+o"},
+		{ID: "logs", Title: "Go to logs", Key: "ctrl+l"},
+		{ID: "clear", Title: "Clear log filter", Key: "c"},
+	})
+	assert.Len(t, p.Matches(), 3)
+
+	p = typeText(p, "log")
+	require.Len(t, p.Matches(), 2)
+	selected, ok := p.Selected()
+	require.True(t, ok)
+	assert.Equal(t, "logs", selected.ID)
+
+	// Selection wraps around
+	p, result, _ := p.HandleKey(tea.KeyMsg{Type: tea.KeyDown})
+	assert.Equal(t, OverlayContinue, result)
+	p, _, _ = p.HandleKey(tea.KeyMsg{Type: tea.KeyDown})
+	selected, _ = p.Selected()
+	assert.Equal(t, "logs", selected.ID)
+
+	_, result, _ = p.HandleKey(tea.KeyMsg{Type: tea.KeyEnter})
+	assert.Equal(t, OverlaySubmitted, result)
+
+	p = typeText(p, "zzz")
+	assert.Empty(t, p.Matches())
+	_, result, _ = p.HandleKey(tea.KeyMsg{Type: tea.KeyEnter})
+	assert.Equal(t, OverlayContinue, result, "nothing to run without a match")
+	_, result, _ = p.HandleKey(tea.KeyMsg{Type: tea.KeyEsc})
+	assert.Equal(t, OverlayCancelled, result)
+}
+
+func TestPromptRequiresInput(t *testing.T) {
+	p := NewPrompt("Open port", "Port")
+	_, result, _ := p.HandleKey(tea.KeyMsg{Type: tea.KeyEnter})
+	assert.Equal(t, OverlayContinue, result)
+
+	p, _, _ = p.HandleKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("3000")})
+	_, result, _ = p.HandleKey(tea.KeyMsg{Type: tea.KeyEnter})
+	assert.Equal(t, OverlaySubmitted, result)
+	assert.Equal(t, "3000", p.Value())
+}
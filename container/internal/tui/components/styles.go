@@ -74,10 +74,31 @@ var (
 				Background(lipgloss.Color(ColorMuted)).
 				Padding(0, 1)
 
+	ObservingBannerStyle = lipgloss.NewStyle().
+				Foreground(lipgloss.Color("0")).
+				Background(lipgloss.Color(ColorWarning)).
+				Bold(true).
+				Padding(0, 1)
+
 	CenteredStyle = lipgloss.NewStyle().
 			Align(lipgloss.Center)
 )
 
+// Overlay styles (port selector, command palette, prompts, help)
+var (
+	OverlayBoxStyle = lipgloss.NewStyle().
+			Border(lipgloss.RoundedBorder()).
+			BorderForeground(lipgloss.Color("62")).
+			Padding(1, 2).
+			Background(lipgloss.Color("235")).
+			Foreground(lipgloss.Color("15"))
+
+	OverlayTitleStyle = lipgloss.NewStyle().
+				Bold(true).
+				Foreground(lipgloss.Color("39")).
+				Align(lipgloss.Center)
+)
+
 // ApplyWidth applies width to a style and returns a new style
 func ApplyWidth(style lipgloss.Style, width int) lipgloss.Style {
 	return style.Width(width - 2)
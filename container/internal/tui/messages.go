@@ -1,6 +1,10 @@
 package tui
 
-import "time"
+import (
+	"time"
+
+	"github.com/vanpelt/catnip/internal/models"
+)
 
 // Core message types
 type tickMsg time.Time
@@ -26,6 +30,35 @@ type shellErrorMsg struct {
 	sessionID string
 	err       error
 }
+type shellControlMsg struct {
+	sessionID string
+	msgType   string
+}
+type observeWorktreesMsg struct {
+	names       []string
+	branchNotes map[string]string
+	details     map[string]*worktreeDetail
+	err         error
+}
+type sessionSummaryMsg struct {
+	name    string
+	summary *sessionSummary // nil when Claude never ran in the worktree
+	err     error
+}
+type worktreeHealthMsg struct {
+	name     string
+	findings []healthFinding
+	err      error
+}
+type healthFixMsg struct {
+	name   string
+	action string
+	err    error
+}
+type stateReportMsg struct {
+	report *models.StateReport // nil when there is nothing to report
+	err    error
+}
 
 // SSE event messages
 type sseConnectedMsg struct{}
@@ -33,6 +66,18 @@ type sseDisconnectedMsg struct{}
 type sseErrorMsg struct {
 	err error
 }
+type sseWorktreeTodosMsg struct {
+	worktreeID string
+	todos      []worktreeTodo
+}
+type sseSessionTitleMsg struct {
+	worktreeID string
+	title      string
+}
+type sseWorktreeActivityMsg struct {
+	worktreeID    string
+	activityState string
+}
 type ssePortOpenedMsg struct {
 	port     int
 	service  string
@@ -12,7 +12,9 @@ import (
 	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/vanpelt/catnip/internal/models"
 	"github.com/vanpelt/catnip/internal/services"
+	"github.com/vanpelt/catnip/internal/tui/components"
 )
 
 // ViewType represents the different views in the application
@@ -104,6 +106,9 @@ type Model struct {
 	bootingBold      bool
 	bootingBoldTimer time.Time
 
+	// What loading the state at startup healed or lost, until dismissed
+	stateReport *models.StateReport
+
 	// Enhanced logs view
 	logsViewport  viewport.Model
 	searchInput   textinput.Model
@@ -118,15 +123,30 @@ type Model struct {
 	shellSessions    map[string]*PTYClient
 	showSessionList  bool
 	currentSessionID string
-	shellConnecting  bool
-	shellSpinner     spinner.Model
-	shellLastInput   time.Time
-	terminalEmulator *TerminalEmulator
+	// Observe mode worktree picker
+	showObservePicker bool
+	observeWorktrees  []string
+	observeErr        error
+	observeNotes      map[string]string // why listed worktrees' branches kept their names
+	observeDetails    map[string]*worktreeDetail
+	observeCursor     int
+	shellConnecting   bool
+	shellSpinner      spinner.Model
+	shellLastInput    time.Time
+	terminalEmulator  *TerminalEmulator
 
 	// Port selector overlay
 	showPortSelector  bool
 	selectedPortIndex int
 
+	// Command palette, the input prompt of actions that need a parameter, and help overlay
+	showCommandPalette bool
+	commandPalette     components.Palette
+	showActionPrompt   bool
+	actionPrompt       components.Prompt
+	pendingActionID    string
+	showHelp           bool
+
 	// SSE connection state
 	sseConnected bool
 	sseStarted   bool
@@ -171,6 +191,10 @@ func NewModelWithInitialization(containerService *services.ContainerService, con
 	if hostURL := os.Getenv("CATNIP_HOST_URL"); hostURL != "" {
 		baseURL = hostURL
 	}
+	// CATNIP_BASE_URL is the external URL when catnip sits behind a reverse proxy (e.g. Caddy on /catnip/)
+	if externalURL := os.Getenv("CATNIP_BASE_URL"); externalURL != "" {
+		baseURL = strings.TrimRight(externalURL, "/")
+	}
 
 	m := &Model{
 		containerService: containerService,
@@ -246,6 +270,31 @@ func (m *Model) createAuthenticatedClient(timeout time.Duration) *http.Client {
 		}
 	}
 
+	// Send the catnip API token alongside any codespace credentials
+	withAPIToken(client)
+
+	return client
+}
+
+// newAPIClient creates an HTTP client that sends the catnip API token
+func newAPIClient(timeout time.Duration) *http.Client {
+	return withAPIToken(&http.Client{Timeout: timeout})
+}
+
+// withAPIToken wraps the client transport so every request carries the catnip API token
+func withAPIToken(client *http.Client) *http.Client {
+	apiToken := services.ReadAPIToken()
+	if apiToken == "" {
+		return client
+	}
+	base := client.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	client.Transport = &apiTokenTransport{
+		token: apiToken,
+		base:  base,
+	}
 	return client
 }
 
@@ -334,3 +383,14 @@ func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 	req.Header.Set("Authorization", "Bearer "+t.token)
 	return t.base.RoundTrip(req)
 }
+
+// apiTokenTransport adds the catnip API token to every request
+type apiTokenTransport struct {
+	token string
+	base  http.RoundTripper
+}
+
+func (t *apiTokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req.Header.Set(services.APITokenHeader, t.token)
+	return t.base.RoundTrip(req)
+}
@@ -56,7 +56,7 @@ func NewPortForwardManager(backendBaseURL string) *PortForwardManager {
 		sshAddress:     "127.0.0.1:2222",
 		keyPath:        keyPath,
 		forwards:       make(map[int]*activeForward),
-		httpClient:     &http.Client{Timeout: 2 * time.Second},
+		httpClient:     newAPIClient(2 * time.Second),
 	}
 }
 
@@ -3,17 +3,23 @@ package tui
 import (
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"net/url"
+	"strings"
 	"sync"
 
 	"github.com/gorilla/websocket"
+	"github.com/vanpelt/catnip/internal/services"
 )
 
 type PTYClient struct {
 	conn      *websocket.Conn
 	sessionID string
+	agent     string
+	observe   bool // Attach read-only to an existing session without displacing its terminal
 	mu        sync.Mutex
 	onMessage func([]byte)
+	onControl func(PTYControlMessage)
 	onError   func(error)
 	done      chan struct{}
 }
@@ -24,6 +30,15 @@ type ResizeMessage struct {
 	Rows int    `json:"rows"`
 }
 
+// PTYControlMessage is a JSON control message exchanged with the PTY server
+// (read-only status, control requests and their answers)
+type PTYControlMessage struct {
+	Type    string          `json:"type"`
+	Data    json.RawMessage `json:"data,omitempty"`
+	ConnID  string          `json:"connId,omitempty"`
+	Granted bool            `json:"granted,omitempty"`
+}
+
 func NewPTYClient(sessionID string) *PTYClient {
 	return &PTYClient{
 		sessionID: sessionID,
@@ -31,6 +46,17 @@ func NewPTYClient(sessionID string) *PTYClient {
 	}
 }
 
+// NewObserverPTYClient creates a client that observes an existing agent session (e.g. a
+// worktree's Claude PTY) without sending input until control is granted
+func NewObserverPTYClient(sessionID, agent string) *PTYClient {
+	return &PTYClient{
+		sessionID: sessionID,
+		agent:     agent,
+		observe:   true,
+		done:      make(chan struct{}),
+	}
+}
+
 func (p *PTYClient) Connect(baseURL string) error {
 	u, err := url.Parse(baseURL)
 	if err != nil {
@@ -45,15 +71,28 @@ func (p *PTYClient) Connect(baseURL string) error {
 		u.Scheme = "wss"
 	}
 
-	u.Path = "/v1/pty"
+	// Keep any subpath prefix (e.g. https://host/catnip) from CATNIP_BASE_URL
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/v1/pty"
 	q := u.Query()
 	q.Set("session", p.sessionID)
+	if p.agent != "" {
+		q.Set("agent", p.agent)
+	}
+	if p.observe {
+		q.Set("observe", "true")
+	}
 	u.RawQuery = q.Encode()
 
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	p.conn, _, err = websocket.DefaultDialer.Dial(u.String(), nil)
+	var header http.Header
+	if token := services.ReadAPIToken(); token != "" {
+		header = http.Header{}
+		header.Set(services.APITokenHeader, token)
+	}
+
+	p.conn, _, err = websocket.DefaultDialer.Dial(u.String(), header)
 	if err != nil {
 		return fmt.Errorf("failed to connect to PTY: %w", err)
 	}
@@ -77,6 +116,15 @@ func (p *PTYClient) readLoop() {
 			return
 		}
 
+		// PTY output arrives as binary frames; JSON control messages as text
+		if messageType == websocket.TextMessage && p.onControl != nil {
+			var control PTYControlMessage
+			if err := json.Unmarshal(message, &control); err == nil && control.Type != "" {
+				p.onControl(control)
+				continue
+			}
+		}
+
 		if messageType == websocket.BinaryMessage || messageType == websocket.TextMessage {
 			if p.onMessage != nil {
 				p.onMessage(message)
@@ -111,6 +159,24 @@ func (p *PTYClient) Resize(cols, rows int) error {
 	return p.Send(data)
 }
 
+// RequestControl asks the server for write access to an observed session
+func (p *PTYClient) RequestControl() error {
+	return p.sendControl(PTYControlMessage{Type: "request-control"})
+}
+
+// RespondToControlRequest answers an observer's request for control of this session
+func (p *PTYClient) RespondToControlRequest(connID string, granted bool) error {
+	return p.sendControl(PTYControlMessage{Type: "control-response", ConnID: connID, Granted: granted})
+}
+
+func (p *PTYClient) sendControl(msg PTYControlMessage) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return p.Send(data)
+}
+
 func (p *PTYClient) Close() error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
@@ -127,6 +193,11 @@ func (p *PTYClient) SetMessageHandler(handler func([]byte)) {
 	p.onMessage = handler
 }
 
+// SetControlHandler routes JSON control messages to handler instead of the output handler
+func (p *PTYClient) SetControlHandler(handler func(PTYControlMessage)) {
+	p.onControl = handler
+}
+
 func (p *PTYClient) SetErrorHandler(handler func(error)) {
 	p.onError = handler
 }
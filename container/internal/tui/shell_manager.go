@@ -1,6 +1,7 @@
 package tui
 
 import (
+	"encoding/json"
 	"fmt"
 	"time"
 
@@ -19,8 +20,18 @@ type ShellSession struct {
 	Output    []byte
 	Connected bool
 	Error     error
+	// Observe mode: attached read-only to a worktree's Claude session
+	Observing        bool
+	ObservedWorktree string
+	ReadOnly         bool
+	ControlRequested bool   // We asked the writer for control and are waiting
+	ControlDenied    bool   // The last control request was denied
+	IncomingRequest  string // Connection ID of an observer asking us for control
 }
 
+// observerSessionPrefix marks shell manager keys of observe-mode sessions
+const observerSessionPrefix = "observe:"
+
 var globalShellManager *ShellManager
 
 func InitShellManager(p *tea.Program) {
@@ -50,6 +61,8 @@ func (sm *ShellManager) CreateSession(sessionID string) *ShellSession {
 		}
 	})
 
+	sm.attachControlHandler(session)
+
 	session.Client.SetErrorHandler(func(err error) {
 		session.Error = err
 		if sm.program != nil {
@@ -63,6 +76,78 @@ func (sm *ShellManager) CreateSession(sessionID string) *ShellSession {
 	return session
 }
 
+// CreateObserverSession creates a read-only session attached to a worktree's Claude PTY.
+// Output is kept per session so each attachment scrolls independently.
+func (sm *ShellManager) CreateObserverSession(worktreeName string) *ShellSession {
+	sessionID := observerSessionPrefix + worktreeName
+	if existing, exists := sm.sessions[sessionID]; exists {
+		return existing
+	}
+
+	session := &ShellSession{
+		ID:               sessionID,
+		Client:           NewObserverPTYClient(worktreeName, "claude"),
+		Output:           []byte{},
+		Observing:        true,
+		ObservedWorktree: worktreeName,
+		ReadOnly:         true,
+	}
+	sm.sessions[sessionID] = session
+
+	session.Client.SetMessageHandler(func(data []byte) {
+		session.Output = append(session.Output, data...)
+		if sm.program != nil {
+			sm.program.Send(shellOutputMsg{sessionID: sessionID, data: data})
+		}
+	})
+	sm.attachControlHandler(session)
+	session.Client.SetErrorHandler(func(err error) {
+		session.Error = err
+		if sm.program != nil {
+			sm.program.Send(shellErrorMsg{sessionID: sessionID, err: err})
+		}
+	})
+
+	return session
+}
+
+// attachControlHandler tracks write access and control requests for a session
+func (sm *ShellManager) attachControlHandler(session *ShellSession) {
+	session.Client.SetControlHandler(func(msg PTYControlMessage) {
+		switch msg.Type {
+		case "read-only":
+			var readOnly bool
+			_ = json.Unmarshal(msg.Data, &readOnly)
+			session.ReadOnly = readOnly
+			if !readOnly {
+				session.ControlRequested = false
+				session.ControlDenied = false
+			}
+		case "control-request":
+			session.IncomingRequest = msg.ConnID
+		case "control-denied":
+			session.ControlRequested = false
+			session.ControlDenied = true
+		default:
+			return
+		}
+		if sm.program != nil {
+			sm.program.Send(shellControlMsg{sessionID: session.ID, msgType: msg.Type})
+		}
+	})
+}
+
+// CloseSession detaches from a session and forgets it. Only the connection is closed;
+// the PTY keeps running on the server.
+func (sm *ShellManager) CloseSession(sessionID string) {
+	if session, exists := sm.sessions[sessionID]; exists {
+		delete(sm.sessions, sessionID)
+		if session.Client != nil {
+			_ = session.Client.Close()
+		}
+	}
+}
+
 func (sm *ShellManager) ConnectSession(sessionID string, baseURL string) error {
 	if session, exists := sm.sessions[sessionID]; exists {
 		if baseURL == "" {
@@ -119,3 +204,33 @@ func createAndConnectShell(sessionID string, width, height int, baseURL string)
 		return nil
 	}
 }
+
+// observeWorktreeSession attaches read-only to the Claude session of a worktree.
+// The observer never resizes the PTY so the attached terminal keeps its layout.
+func observeWorktreeSession(worktreeName, baseURL string) tea.Cmd {
+	return func() tea.Msg {
+		sessionID := observerSessionPrefix + worktreeName
+		if globalShellManager == nil {
+			return shellErrorMsg{
+				sessionID: sessionID,
+				err:       fmt.Errorf("shell manager not initialized"),
+			}
+		}
+
+		session := globalShellManager.CreateObserverSession(worktreeName)
+		if session.Connected {
+			return nil
+		}
+
+		go func() {
+			if err := globalShellManager.ConnectSession(sessionID, baseURL); err != nil {
+				debugLog("Failed to observe worktree %s: %v", worktreeName, err)
+				if globalShellManager.program != nil {
+					globalShellManager.program.Send(shellErrorMsg{sessionID: sessionID, err: err})
+				}
+			}
+		}()
+
+		return nil
+	}
+}
@@ -54,6 +54,8 @@ const (
 	WorktreeUpdatedEvent      = "worktree:updated"
 	WorktreeBatchUpdatedEvent = "worktree:batch_updated"
 	WorktreeCreatedEvent      = "worktree:created"
+	WorktreeTodosUpdatedEvent = "worktree:todos_updated"
+	SessionTitleUpdatedEvent  = "session:title_updated"
 )
 
 // SSE event messages are defined in messages.go
@@ -337,6 +339,7 @@ func (c *SSEClient) processEvent(data string) {
 				if c.onWorktreeUpdateWithID != nil {
 					c.onWorktreeUpdateWithID(worktreeID, updates)
 				}
+				c.sendWorktreeActivity(worktreeID, updates)
 			}
 		}
 
@@ -352,6 +355,7 @@ func (c *SSEClient) processEvent(data string) {
 						if c.onWorktreeUpdateWithID != nil {
 							c.onWorktreeUpdateWithID(worktreeID, updateMap)
 						}
+						c.sendWorktreeActivity(worktreeID, updateMap)
 					}
 				}
 			}
@@ -373,8 +377,46 @@ func (c *SSEClient) processEvent(data string) {
 			}
 		}
 
+	case WorktreeTodosUpdatedEvent:
+		var payload struct {
+			WorktreeID string         `json:"worktree_id"`
+			Todos      []worktreeTodo `json:"todos"`
+		}
+		if err := decodePayload(msg.Event.Payload, &payload); err == nil && c.program != nil {
+			c.program.Send(sseWorktreeTodosMsg{worktreeID: payload.WorktreeID, todos: payload.Todos})
+		}
+
+	case SessionTitleUpdatedEvent:
+		var payload struct {
+			WorktreeID   string `json:"worktree_id"`
+			SessionTitle *struct {
+				Title string `json:"title"`
+			} `json:"session_title"`
+		}
+		if err := decodePayload(msg.Event.Payload, &payload); err == nil && payload.SessionTitle != nil && c.program != nil {
+			c.program.Send(sseSessionTitleMsg{worktreeID: payload.WorktreeID, title: payload.SessionTitle.Title})
+		}
+
 	default:
 		// Log other event types for now
 		debugLog("SSE event received: %s", msg.Event.Type)
 	}
 }
+
+// sendWorktreeActivity forwards a change of a worktree's Claude activity state to the program
+func (c *SSEClient) sendWorktreeActivity(worktreeID string, updates map[string]interface{}) {
+	activityState, ok := updates["claude_activity_state"].(string)
+	if !ok || c.program == nil {
+		return
+	}
+	c.program.Send(sseWorktreeActivityMsg{worktreeID: worktreeID, activityState: activityState})
+}
+
+// decodePayload converts a generically decoded event payload into a typed struct
+func decodePayload(payload interface{}, v interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,111 @@
+package tui
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/vanpelt/catnip/internal/models"
+	"github.com/vanpelt/catnip/internal/tui/components"
+)
+
+// actionStateReportDismiss acknowledges the state report shown in the overview banner
+const actionStateReportDismiss = "state-report.dismiss"
+
+// fetchStateReport loads what loading the persisted state at startup healed or lost, nil when
+// there is nothing unacknowledged to report
+func (m *Model) fetchStateReport() tea.Cmd {
+	return func() tea.Msg {
+		client := m.createAuthenticatedClient(5 * time.Second)
+		resp, err := client.Get(fmt.Sprintf("%s/v1/git/status", m.getBaseURL("")))
+		if err != nil {
+			return stateReportMsg{err: err}
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return stateReportMsg{err: fmt.Errorf("failed to load git status: HTTP %d", resp.StatusCode)}
+		}
+
+		var status models.GitStatus
+		if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+			return stateReportMsg{err: err}
+		}
+		return stateReportMsg{report: status.StateReport}
+	}
+}
+
+// acknowledgeStateReport dismisses the state report on the server
+func (m *Model) acknowledgeStateReport() tea.Cmd {
+	return func() tea.Msg {
+		client := m.createAuthenticatedClient(5 * time.Second)
+		resp, err := client.Post(m.getBaseURL("")+"/v1/git/status/state-report/acknowledge", "application/json", bytes.NewBufferString("{}"))
+		if err != nil {
+			return stateReportMsg{err: err}
+		}
+		defer resp.Body.Close()
+
+		// Already acknowledged elsewhere is as good as acknowledged here
+		if resp.StatusCode >= http.StatusBadRequest && resp.StatusCode != http.StatusNotFound {
+			return stateReportMsg{err: fmt.Errorf("failed to dismiss state report: HTTP %d", resp.StatusCode)}
+		}
+		return stateReportMsg{}
+	}
+}
+
+func (m Model) handleStateReport(msg stateReportMsg) (tea.Model, tea.Cmd) {
+	if msg.err != nil {
+		// Keep showing the last known report, the banner is informational
+		debugLog("State report: %v", msg.err)
+		return m, nil
+	}
+	m.stateReport = msg.report
+	return m, nil
+}
+
+// renderStateReportBanner returns the overview lines summarizing the state report, none when
+// there is nothing to report
+func renderStateReportBanner(report *models.StateReport) []string {
+	if report == nil {
+		return nil
+	}
+
+	lines := []string{components.ErrorStyle.Render("⚠️  State recovered at startup")}
+	for _, migration := range report.Migrations {
+		lines = append(lines, fmt.Sprintf("  Migrated: %s", migration))
+	}
+	for _, file := range report.Quarantined {
+		lines = append(lines, fmt.Sprintf("  Quarantined corrupt file: %s", file))
+	}
+	for _, file := range report.Restored {
+		lines = append(lines, fmt.Sprintf("  Restored from backup, last change may be missing: %s", file))
+	}
+	for _, entry := range report.Lost {
+		lines = append(lines, fmt.Sprintf("  Lost %s %s: %s", entry.Kind, stateReportEntryName(entry), entry.Reason))
+	}
+	for _, entry := range report.Unavailable {
+		lines = append(lines, fmt.Sprintf("  Unavailable %s %s: %s", entry.Kind, stateReportEntryName(entry), entry.Reason))
+	}
+	for _, entry := range report.Pruned {
+		lines = append(lines, fmt.Sprintf("  Pruned %s %s: %s", entry.Kind, stateReportEntryName(entry), entry.Reason))
+	}
+	for _, entry := range report.Stale {
+		lines = append(lines, fmt.Sprintf("  Stale %s %s: %s", entry.Kind, stateReportEntryName(entry), entry.Reason))
+	}
+	for _, rewrite := range report.PathRewrites {
+		lines = append(lines, fmt.Sprintf("  Moved %s: %s → %s", rewrite.Name, rewrite.From, rewrite.To))
+	}
+	lines = append(lines, components.MutedStyle.Render("  Dismiss it from the command palette"), "")
+	return lines
+}
+
+// stateReportEntryName prefers the display name of an entry over its ID
+func stateReportEntryName(entry models.StateReportEntry) string {
+	if entry.Name != "" {
+		return entry.Name
+	}
+	return entry.ID
+}
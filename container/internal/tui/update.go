@@ -47,6 +47,8 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m.handlePorts(msg)
 	case healthStatusMsg:
 		return m.handleHealthStatus(msg)
+	case stateReportMsg:
+		return m.handleStateReport(msg)
 	case errMsg:
 		return m.handleError(msg)
 	case quitMsg:
@@ -90,7 +92,12 @@ func (m Model) handleWindowResize(msg tea.WindowSizeMsg) (tea.Model, tea.Cmd) {
 func (m Model) handleKeyMessage(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	debugLog("KeyMsg received: %s", msg.String())
 
-	// Handle global navigation keys first (available in all views)
+	// Open overlays (command palette, action prompt, help) take keys first
+	if newModel, cmd, handled := m.handleOverlayKeys(msg); handled {
+		return *newModel, cmd
+	}
+
+	// Handle global navigation keys next (available in all views)
 	if newModel, cmd, handled := m.handleGlobalKeys(msg); handled {
 		return *newModel, cmd
 	}
@@ -104,66 +111,14 @@ func (m Model) handleKeyMessage(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 func (m Model) handleGlobalKeys(msg tea.KeyMsg) (*Model, tea.Cmd, bool) {
 	keyStr := msg.String()
 
-	switch keyStr {
-	case components.KeyQuit, components.KeyQuitAlt:
-		m.quitRequested = true
-		return &m, tea.Quit, true
-
-	case components.KeyOverview:
-		if m.currentView != OverviewView {
-			m.SwitchToView(OverviewView)
-		}
-		return &m, nil, true
-
-	case components.KeyLogs:
-		if m.currentView != LogsView {
-			m.SwitchToView(LogsView)
-			// Update viewport size and content when switching to logs
-			if m.height > 0 {
-				headerHeight := 4
-				m.logsViewport.Width = m.width - 4
-				m.logsViewport.Height = m.height - headerHeight
-			}
-			// Update log filter and fetch logs
-			logsView := m.views[LogsView].(*LogsViewImpl)
-			m = *logsView.updateLogFilter(&m)
-			return &m, m.fetchLogs(), true
-		}
-		return &m, nil, true
-
-	case components.KeyShell:
-		if m.currentView != ShellView {
-			// Check if we have existing sessions
-			if globalShellManager != nil && len(globalShellManager.sessions) > 0 {
-				m.showSessionList = true
-				m.SwitchToView(ShellView)
-			} else {
-				// Create new session
-				overviewView := m.views[OverviewView].(*OverviewViewImpl)
-				newModel, cmd := overviewView.createNewShellSessionWithCmd(&m)
-				return newModel, cmd, true
-			}
-		}
-		return &m, nil, true
-
-	case components.KeyOpenBrowser:
-		// Open browser with port selection overlay if multiple ports, or directly if only main app
-		if len(m.ports) > 0 {
-			// Show port selector overlay
-			m.showPortSelector = true
-			m.selectedPortIndex = 0 // Default to first port
-		} else if m.appHealthy {
-			// No other ports, open main app directly
-			overviewView := m.views[OverviewView].(*OverviewViewImpl)
-			go func() {
-				_ = overviewView.openBrowser(m.getBaseURL(""))
-			}()
-		} else {
-			// App is not ready, show bold feedback
-			m.bootingBold = true
-			m.bootingBoldTimer = time.Now()
-		}
-		return &m, nil, true
+	// Global keys run their registered action
+	if action, ok := globalActionForKey(keyStr); ok {
+		newModel, cmd := m.runAction(action.ID)
+		return newModel, cmd, true
+	}
+	if keyStr == components.KeyQuitAlt {
+		newModel, cmd := m.runAction("app.quit")
+		return newModel, cmd, true
 	}
 
 	// Handle port selector overlay if active
@@ -343,19 +298,24 @@ func (m Model) handleHealthStatus(msg healthStatusMsg) (tea.Model, tea.Cmd) {
 		debugLog("Started SSE client after health check passed")
 	}
 
+	var cmd tea.Cmd
+	if m.appHealthy && !wasHealthy {
+		cmd = m.fetchStateReport()
+	}
+
 	// Auto-open browser when app becomes healthy for the first time
 	if m.appHealthy && !wasHealthy && !m.browserOpened {
 		m.browserOpened = true
 		overviewView := m.views[OverviewView].(*OverviewViewImpl)
 		baseURL := m.getBaseURL("")
-		if err := overviewView.openBrowser(baseURL); err != nil {
+		if err := overviewView.openBrowser(withBrowserToken(baseURL)); err != nil {
 			debugLog("Failed to open browser: %v", err)
 		} else {
 			debugLog("Automatically opened browser at %s", baseURL)
 		}
 	}
 
-	return m, nil
+	return m, cmd
 }
 
 func (m Model) handleError(msg errMsg) (tea.Model, tea.Cmd) {
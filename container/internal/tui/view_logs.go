@@ -62,15 +62,10 @@ func (v *LogsViewImpl) HandleKey(m *Model, msg tea.KeyMsg) (*Model, tea.Cmd) {
 	// Handle view-specific logs navigation keys
 	switch msg.String() {
 	case components.KeyLogsSearch:
-		m.searchMode = true
-		cmd := m.searchInput.Focus()
-		return m, cmd
+		return m.runAction(actionLogsSearch)
 
 	case components.KeyLogsClear:
-		m.searchPattern = ""
-		m.searchInput.SetValue("")
-		m = v.updateLogFilter(m)
-		return m, nil
+		return m.runAction(actionLogsClear)
 
 	case components.KeyUp, components.KeyVimUp:
 		m.logsViewport.ScrollUp(1)
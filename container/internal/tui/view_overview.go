@@ -3,6 +3,7 @@ package tui
 import (
 	"fmt"
 	"net/http"
+	"net/url"
 	"os/exec"
 	"runtime"
 	"strings"
@@ -10,6 +11,7 @@ import (
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/vanpelt/catnip/internal/services"
 	"github.com/vanpelt/catnip/internal/tui/components"
 )
 
@@ -53,6 +55,8 @@ func (v *OverviewViewImpl) Render(m *Model) string {
 
 	var sections []string
 
+	sections = append(sections, renderStateReportBanner(m.stateReport)...)
+
 	// Container Status
 	sections = append(sections, components.SectionHeaderStyle.Render("📦 Container Status"))
 
@@ -194,6 +198,11 @@ func (v *OverviewViewImpl) createNewShellSessionWithCmd(m *Model) (*Model, tea.C
 	} else {
 		sessionID = fmt.Sprintf("shell-%d", time.Now().Unix())
 	}
+	return v.startShellSession(m, sessionID)
+}
+
+// startShellSession switches to the shell view and connects a new shell session
+func (v *OverviewViewImpl) startShellSession(m *Model, sessionID string) (*Model, tea.Cmd) {
 	m.currentSessionID = sessionID
 	m.SwitchToView(ShellView)
 	m.shellOutput = ""
@@ -260,6 +269,15 @@ func (v *OverviewViewImpl) renderWithASCIIView(m *Model, content string) string
 	return result
 }
 
+// withBrowserToken appends the API token so the browser UI gets a login cookie on first load
+func withBrowserToken(baseURL string) string {
+	token := services.ReadAPIToken()
+	if token == "" {
+		return baseURL
+	}
+	return baseURL + "/?token=" + url.QueryEscape(token)
+}
+
 func (v *OverviewViewImpl) openBrowser(url string) error {
 	var cmd *exec.Cmd
 
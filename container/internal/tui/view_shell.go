@@ -3,6 +3,7 @@ package tui
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
@@ -34,6 +35,61 @@ func (v *ShellViewImpl) Update(m *Model, msg tea.Msg) (*Model, tea.Cmd) {
 		if msg.sessionID == m.currentSessionID {
 			return v.handleShellError(m, msg)
 		}
+	case shellControlMsg:
+		// Attached: an observer may never get output while Claude is idle
+		if msg.sessionID == m.currentSessionID && msg.msgType == "read-only" {
+			m.shellConnecting = false
+		}
+		return m, nil
+	case observeWorktreesMsg:
+		m.observeWorktrees = msg.names
+		m.observeNotes = msg.branchNotes
+		m.observeDetails = msg.details
+		m.observeErr = msg.err
+		m.observeCursor = 0
+		return m, m.refreshSelectedDetail()
+	case sessionSummaryMsg:
+		// Keep the last known summary when it can't be refreshed
+		if detail := m.observeDetails[msg.name]; detail != nil && msg.err == nil {
+			detail.Summary = msg.summary
+		}
+		return m, nil
+	case worktreeHealthMsg:
+		// Keep the last known findings when they can't be refreshed
+		if detail := m.observeDetails[msg.name]; detail != nil && msg.err == nil {
+			detail.Health = msg.findings
+		}
+		return m, nil
+	case healthFixMsg:
+		detail := m.observeDetails[msg.name]
+		if detail == nil {
+			return m, nil
+		}
+		detail.HealthFixing = ""
+		detail.HealthFixErr = msg.err
+		return m, m.fetchWorktreeHealth(msg.name, detail.ID)
+	case sseWorktreeTodosMsg:
+		if _, detail := m.observeDetailByID(msg.worktreeID); detail != nil {
+			detail.Todos = msg.todos
+		}
+		return m, nil
+	case sseSessionTitleMsg:
+		if name, detail := m.observeDetailByID(msg.worktreeID); detail != nil {
+			detail.Title = msg.title
+			if selected, _ := m.selectedObserveDetail(); m.showObservePicker && selected == name {
+				// A new title starts a new turn
+				return m, m.refreshSelectedDetail()
+			}
+		}
+		return m, nil
+	case sseWorktreeActivityMsg:
+		if name, detail := m.observeDetailByID(msg.worktreeID); detail != nil && detail.ActivityState != msg.activityState {
+			detail.ActivityState = msg.activityState
+			if selected, _ := m.selectedObserveDetail(); m.showObservePicker && selected == name {
+				return m, m.refreshSelectedDetail()
+			}
+		}
+		return m, nil
 	}
 
 	// Update shell viewport
@@ -45,6 +101,45 @@ func (v *ShellViewImpl) Update(m *Model, msg tea.Msg) (*Model, tea.Cmd) {
 // HandleKey processes key messages for the shell view
 // Note: Global navigation keys (Ctrl+O, Ctrl+Q, Ctrl+T, etc.) are handled in the global handler
 func (v *ShellViewImpl) HandleKey(m *Model, msg tea.KeyMsg) (*Model, tea.Cmd) {
+	// Handle the worktree picker for observe mode
+	if m.showObservePicker {
+		switch msg.String() {
+		case components.KeyEscape:
+			m.showObservePicker = false
+			m.showSessionList = true
+			return m, nil
+		case components.KeyUp, components.KeyVimUp:
+			if m.observeCursor > 0 {
+				m.observeCursor--
+				return m, m.refreshSelectedDetail()
+			}
+		case components.KeyDown, components.KeyVimDown:
+			if m.observeCursor < len(m.observeWorktrees)-1 && m.observeCursor < 8 {
+				m.observeCursor++
+				return m, m.refreshSelectedDetail()
+			}
+		case components.KeyShellHealthFix:
+			name, detail := m.selectedObserveDetail()
+			if finding := detail.fixableFinding(); finding != nil && detail.HealthFixing == "" {
+				detail.HealthFixing = finding.Action
+				detail.HealthFixErr = nil
+				return m, m.runHealthFix(name, detail.ID, finding.Action)
+			}
+		case components.KeyEnter:
+			if name, _ := m.selectedObserveDetail(); name != "" {
+				m.showObservePicker = false
+				return v.startObserving(m, name)
+			}
+		case "1", "2", "3", "4", "5", "6", "7", "8", "9":
+			i := int(msg.String()[0] - '1')
+			if i < len(m.observeWorktrees) {
+				m.showObservePicker = false
+				return v.startObserving(m, m.observeWorktrees[i])
+			}
+		}
+		return m, nil
+	}
+
 	// Handle session list navigation
 	if m.showSessionList {
 		switch msg.String() {
@@ -53,9 +148,9 @@ func (v *ShellViewImpl) HandleKey(m *Model, msg tea.KeyMsg) (*Model, tea.Cmd) {
 			m.SwitchToView(OverviewView)
 			return m, nil
 		case components.KeyShellNewSession:
-			m.showSessionList = false
-			newModel, cmd := v.createNewShellSessionWithCmd(m)
-			return newModel, cmd
+			return m.runAction(actionShellNew)
+		case components.KeyShellObserve:
+			return m.runAction(actionShellObserve)
 		case "1", "2", "3", "4", "5", "6", "7", "8", "9":
 			// Handle plain number keys for session selection when in session list mode
 			i := int(msg.String()[0] - '1')
@@ -76,6 +171,29 @@ func (v *ShellViewImpl) HandleKey(m *Model, msg tea.KeyMsg) (*Model, tea.Cmd) {
 		}
 	}
 
+	session := currentShellSession(m)
+
+	// Answer an observer asking for control of this session
+	if session != nil && session.IncomingRequest != "" {
+		switch msg.String() {
+		case components.KeyShellGrantControl:
+			return m.runAction(actionShellGrant)
+		case components.KeyShellDenyControl:
+			return m.runAction(actionShellDeny)
+		}
+	}
+
+	// Observing without control: only scrolling, requesting control and detaching
+	observing := session != nil && session.Observing && session.ReadOnly
+	if observing {
+		switch msg.String() {
+		case components.KeyShellRequestControl:
+			return m.runAction(actionShellRequest)
+		case components.KeyShellDetach:
+			return m.runAction(actionShellDetach)
+		}
+	}
+
 	// Handle shell view-specific keys (Alt-modified for scrolling)
 	switch msg.String() {
 	case components.KeyShellScrollUp:
@@ -103,6 +221,10 @@ func (v *ShellViewImpl) HandleKey(m *Model, msg tea.KeyMsg) (*Model, tea.Cmd) {
 		return m, nil
 
 	default:
+		// Observers never inject keystrokes
+		if observing {
+			return m, nil
+		}
 		// Forward all other input to PTY
 		// Global navigation keys are handled before this point, so they won't interfere
 		v.forwardPty(m, msg)
@@ -123,9 +245,9 @@ func (v *ShellViewImpl) HandleResize(m *Model, msg tea.WindowSizeMsg) (*Model, t
 		m.terminalEmulator.Resize(terminalWidth, m.shellViewport.Height)
 	}
 
-	// Send resize to PTY
+	// Send resize to PTY (observers leave the observed terminal's size alone)
 	if globalShellManager != nil {
-		if session := globalShellManager.GetSession(m.currentSessionID); session != nil && session.Client != nil {
+		if session := globalShellManager.GetSession(m.currentSessionID); session != nil && session.Client != nil && !session.Observing {
 			go func(width, height int) {
 				if err := session.Client.Resize(width, height); err != nil {
 					debugLog("Failed to resize PTY: %v", err)
@@ -139,13 +261,14 @@ func (v *ShellViewImpl) HandleResize(m *Model, msg tea.WindowSizeMsg) (*Model, t
 
 // Render generates the shell view content
 func (v *ShellViewImpl) Render(m *Model) string {
+	if m.showObservePicker {
+		return v.renderObservePicker(m)
+	}
 	if m.showSessionList {
 		return v.renderSessionList(m)
 	}
 
-	// Header with session info
-	headerStyle := components.ShellHeaderStyle.Width(m.width - 2)
-	header := headerStyle.Render(fmt.Sprintf("Shell Session: %s | Press Ctrl+O to return to overview", m.currentSessionID))
+	header := v.renderHeader(m)
 
 	// If connecting, show spinner
 	if m.shellConnecting {
@@ -155,6 +278,9 @@ func (v *ShellViewImpl) Render(m *Model) string {
 			Height(m.height - 6)
 
 		connectingContent := fmt.Sprintf("%s Connecting to shell...\n\nPlease wait while we establish a connection to the container.", m.shellSpinner.View())
+		if strings.HasPrefix(m.currentSessionID, observerSessionPrefix) {
+			connectingContent = fmt.Sprintf("%s Attaching to %s...", m.shellSpinner.View(), strings.TrimPrefix(m.currentSessionID, observerSessionPrefix))
+		}
 		return fmt.Sprintf("%s\n%s", header, connectingStyle.Render(connectingContent))
 	}
 
@@ -166,6 +292,171 @@ func (v *ShellViewImpl) Render(m *Model) string {
 
 // Helper methods
 
+// renderHeader renders the session header, with the OBSERVING banner for observe mode
+// and a prompt when an observer asks this session for control
+func (v *ShellViewImpl) renderHeader(m *Model) string {
+	var session *ShellSession
+	if globalShellManager != nil {
+		session = globalShellManager.GetSession(m.currentSessionID)
+	}
+
+	var header string
+	if session != nil && session.Observing && session.ReadOnly {
+		status := "Ctrl+R request control"
+		if session.ControlRequested {
+			status = "Control requested, waiting for the attached terminal..."
+		} else if session.ControlDenied {
+			status = "Control request denied | Ctrl+R ask again"
+		}
+		header = components.ObservingBannerStyle.Width(m.width - 2).Render(
+			fmt.Sprintf("👀 OBSERVING %s (read-only) | %s | Ctrl+X detach | Ctrl+O overview", session.ObservedWorktree, status))
+	} else {
+		header = components.ShellHeaderStyle.Width(m.width - 2).Render(
+			fmt.Sprintf("Shell Session: %s | Press Ctrl+O to return to overview", m.currentSessionID))
+	}
+
+	if session != nil && session.IncomingRequest != "" {
+		header += "\n" + components.ObservingBannerStyle.Width(m.width-2).Render(
+			"🙋 An observer is requesting control of this session | Alt+Y grant | Alt+N deny")
+	}
+	return header
+}
+
+// renderObservePicker renders the worktree list of observe mode with the detail pane of the
+// selected worktree, next to the list on wide terminals and below it otherwise
+func (v *ShellViewImpl) renderObservePicker(m *Model) string {
+	listWidth := m.width - 4
+	detailWidth := m.width - 8
+	sideBySide := m.width >= detailPaneMinWidth
+	if sideBySide {
+		listWidth = m.width / 2
+		detailWidth = m.width - listWidth - 6
+	}
+	listStyle := lipgloss.NewStyle().
+		Padding(1, 2).
+		Width(listWidth)
+
+	var content strings.Builder
+	content.WriteString("Observe a worktree's Claude session (read-only):\n\n")
+
+	switch {
+	case m.observeErr != nil:
+		content.WriteString(fmt.Sprintf("  Failed to load worktrees: %v\n", m.observeErr))
+	case m.observeWorktrees == nil:
+		content.WriteString(fmt.Sprintf("  %s Loading worktrees...\n", m.shellSpinner.View()))
+	case len(m.observeWorktrees) == 0:
+		content.WriteString("  No worktrees found\n")
+	default:
+		for i, name := range m.observeWorktrees {
+			if i >= 9 {
+				content.WriteString(fmt.Sprintf("  ... and %d more\n", len(m.observeWorktrees)-9))
+				break
+			}
+			cursor := " "
+			if i == m.observeCursor {
+				cursor = components.KeyHighlightStyle.Render("▶")
+			}
+			marker := ""
+			if detail := m.observeDetails[name]; detail != nil && detail.HandedOff != nil {
+				marker = " " + components.KeyHighlightStyle.Render("🤝 handed off")
+			}
+			content.WriteString(fmt.Sprintf(" %s%d. %s%s\n", cursor, i+1, name, marker))
+			if note := m.observeNotes[name]; note != "" {
+				content.WriteString(fmt.Sprintf("     ⏭️  %s\n", note))
+			}
+		}
+	}
+
+	help := "\n  ↑/↓ Select | Enter Observe | ESC Back\n"
+	if _, detail := m.selectedObserveDetail(); detail.fixableFinding() != nil {
+		help = "\n  ↑/↓ Select | Enter Observe | a Fix | ESC Back\n"
+	}
+	content.WriteString(help)
+	list := listStyle.Render(content.String())
+
+	name, detail := m.selectedObserveDetail()
+	if name == "" {
+		return list
+	}
+	pane := lipgloss.NewStyle().
+		Padding(1, 2).
+		Render(renderWorktreeDetail(name, detail, detailWidth, time.Now()))
+	if sideBySide {
+		return lipgloss.JoinHorizontal(lipgloss.Top, list, pane)
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, list, pane)
+}
+
+// currentShellSession returns the session shown in the shell view, if any
+func currentShellSession(m *Model) *ShellSession {
+	if globalShellManager == nil {
+		return nil
+	}
+	return globalShellManager.GetSession(m.currentSessionID)
+}
+
+// answerControlRequest grants or denies the control request of an observer
+func (v *ShellViewImpl) answerControlRequest(m *Model, granted bool) *Model {
+	session := currentShellSession(m)
+	if session == nil || session.IncomingRequest == "" {
+		return m
+	}
+	connID := session.IncomingRequest
+	session.IncomingRequest = ""
+	go func(client *PTYClient) {
+		if err := client.RespondToControlRequest(connID, granted); err != nil {
+			debugLog("Failed to answer control request: %v", err)
+		}
+	}(session.Client)
+	return m
+}
+
+// requestControl asks the controlling client of an observed session for control
+func (v *ShellViewImpl) requestControl(m *Model) *Model {
+	session := currentShellSession(m)
+	if session == nil {
+		return m
+	}
+	session.ControlRequested = true
+	session.ControlDenied = false
+	go func(client *PTYClient) {
+		if err := client.RequestControl(); err != nil {
+			debugLog("Failed to request control: %v", err)
+		}
+	}(session.Client)
+	return m
+}
+
+// detach stops observing; only our connection goes away, the observed session keeps running
+func (v *ShellViewImpl) detach(m *Model) *Model {
+	if session := currentShellSession(m); session != nil {
+		globalShellManager.CloseSession(session.ID)
+	}
+	m.currentSessionID = ""
+	m.SwitchToView(OverviewView)
+	return m
+}
+
+// startObserving attaches to a worktree's Claude session in observe mode
+func (v *ShellViewImpl) startObserving(m *Model, worktreeName string) (*Model, tea.Cmd) {
+	sessionID := observerSessionPrefix + worktreeName
+	if globalShellManager != nil {
+		if session := globalShellManager.GetSession(sessionID); session != nil && session.Connected {
+			return v.switchToShellSession(m, sessionID), nil
+		}
+	}
+
+	m.currentSessionID = sessionID
+	m.SwitchToView(ShellView)
+	m.shellOutput = ""
+	m.shellConnecting = true
+	if m.terminalEmulator != nil {
+		m.terminalEmulator.Clear()
+	}
+
+	return m, observeWorktreeSession(worktreeName, m.getBaseURL(""))
+}
+
 func (v *ShellViewImpl) renderSessionList(m *Model) string {
 	listStyle := lipgloss.NewStyle().
 		Padding(1, 2).
@@ -181,18 +472,27 @@ func (v *ShellViewImpl) renderSessionList(m *Model) string {
 			if session.Connected {
 				status = "connected"
 			}
+			if session.Observing {
+				status = "observing, " + status
+			}
 			content.WriteString(fmt.Sprintf("  %d. %s (%s)\n", i, sessionID, status))
 			i++
 		}
 	}
 
 	content.WriteString("\n  n. Create new session")
+	content.WriteString("\n  o. Observe a worktree's Claude session")
 	content.WriteString("\n  ESC. Cancel\n")
 
 	return listStyle.Render(content.String())
 }
 
 func (v *ShellViewImpl) handleShellOutput(m *Model, msg shellOutputMsg) (*Model, tea.Cmd) {
+	// Output of background sessions is kept in their own buffers and replayed on switch
+	if msg.sessionID != m.currentSessionID {
+		return m, nil
+	}
+
 	// First output means we're connected
 	if m.shellConnecting {
 		m.shellConnecting = false
@@ -0,0 +1,359 @@
+package tui
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/vanpelt/catnip/internal/tui/components"
+)
+
+// detailPaneMinWidth is the terminal width from which the detail pane is shown next to the
+// worktree list instead of below it
+const detailPaneMinWidth = 100
+
+// worktreeTodo is an item of the todo list Claude keeps for a worktree
+type worktreeTodo struct {
+	Content string `json:"content"`
+	Status  string `json:"status"`
+}
+
+// worktreeDetail is the last known Claude state of a worktree, kept after its session ends
+type worktreeDetail struct {
+	ID            string
+	Path          string
+	Title         string
+	ActivityState string
+	CreatedBy     string // actor who created the worktree, empty when catnip did
+	Todos         []worktreeTodo
+	HandedOff     *handoff        // nil unless a human took the worktree over
+	Summary       *sessionSummary // nil until fetched
+	Health        []healthFinding // nil until fetched
+	HealthFixing  string          // remediation action running, if any
+	HealthFixErr  error           // outcome of the last remediation action
+}
+
+// handoff records who took a worktree over from catnip's automation
+type handoff struct {
+	By            string    `json:"by"`
+	At            time.Time `json:"at"`
+	PreviewBranch string    `json:"preview_branch"`
+}
+
+// healthFinding is a problem reported by GET /v1/git/worktrees/{id}/health
+type healthFinding struct {
+	Severity string `json:"severity"`
+	Code     string `json:"code"`
+	Message  string `json:"message"`
+	Action   string `json:"action"`
+}
+
+// healthFixEndpoints are the endpoints remediation actions are posted to, %s being the worktree ID
+var healthFixEndpoints = map[string]string{
+	"recreate":                 "/v1/git/worktrees/%s/recreate",
+	"repair-remote-config":     "/v1/git/worktrees/%s/remote-config/repair",
+	"acknowledge-branch-drift": "/v1/git/worktrees/%s/branch-drift/acknowledge",
+	"sync":                     "/v1/git/worktrees/%s/sync",
+	"validate":                 "/v1/git/worktrees/%s/validate",
+	"trim-caches":              "/v1/git/caches/trim",
+}
+
+// fixableFinding returns the first finding of a worktree whose remediation action the TUI can run
+func (d *worktreeDetail) fixableFinding() *healthFinding {
+	if d == nil {
+		return nil
+	}
+	for i := range d.Health {
+		if _, ok := healthFixEndpoints[d.Health[i].Action]; ok {
+			return &d.Health[i]
+		}
+	}
+	return nil
+}
+
+// sessionSummary holds the fields of GET /v1/claude/session shown in the detail pane
+type sessionSummary struct {
+	Header       *string  `json:"header"`
+	IsActive     bool     `json:"isActive"`
+	TurnCount    int      `json:"turnCount"`
+	LastDuration *int     `json:"lastDuration"`
+	LastCost     *float64 `json:"lastCost"`
+	AllSessions  []struct {
+		LastModified time.Time `json:"lastModified"`
+	} `json:"allSessions"`
+}
+
+// lastActivity returns when a session file of the worktree was last written
+func (s *sessionSummary) lastActivity() time.Time {
+	var last time.Time
+	for _, session := range s.AllSessions {
+		if session.LastModified.After(last) {
+			last = session.LastModified
+		}
+	}
+	return last
+}
+
+// selectedObserveDetail returns the detail of the worktree under the picker cursor, if any
+func (m *Model) selectedObserveDetail() (string, *worktreeDetail) {
+	if m.observeCursor < 0 || m.observeCursor >= len(m.observeWorktrees) {
+		return "", nil
+	}
+	name := m.observeWorktrees[m.observeCursor]
+	return name, m.observeDetails[name]
+}
+
+// fetchSessionSummary loads the Claude session summary of a worktree for the detail pane
+func (m *Model) fetchSessionSummary(name, path string) tea.Cmd {
+	return func() tea.Msg {
+		client := m.createAuthenticatedClient(5 * time.Second)
+		resp, err := client.Get(fmt.Sprintf("%s/v1/claude/session?worktree_path=%s", m.getBaseURL(""), url.QueryEscape(path)))
+		if err != nil {
+			return sessionSummaryMsg{name: name, err: err}
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode == http.StatusNotFound {
+			// Claude never ran in this worktree
+			return sessionSummaryMsg{name: name}
+		}
+		if resp.StatusCode != http.StatusOK {
+			return sessionSummaryMsg{name: name, err: fmt.Errorf("failed to load session summary: HTTP %d", resp.StatusCode)}
+		}
+
+		var summary sessionSummary
+		if err := json.NewDecoder(resp.Body).Decode(&summary); err != nil {
+			return sessionSummaryMsg{name: name, err: err}
+		}
+		return sessionSummaryMsg{name: name, summary: &summary}
+	}
+}
+
+// refreshSelectedDetail fetches the session summary and health of the worktree under the picker cursor
+func (m *Model) refreshSelectedDetail() tea.Cmd {
+	name, detail := m.selectedObserveDetail()
+	if detail == nil || detail.Path == "" {
+		return nil
+	}
+	return tea.Batch(m.fetchSessionSummary(name, detail.Path), m.fetchWorktreeHealth(name, detail.ID))
+}
+
+// fetchWorktreeHealth loads the health findings of a worktree for the detail pane
+func (m *Model) fetchWorktreeHealth(name, worktreeID string) tea.Cmd {
+	return func() tea.Msg {
+		client := m.createAuthenticatedClient(10 * time.Second)
+		resp, err := client.Get(fmt.Sprintf("%s/v1/git/worktrees/%s/health", m.getBaseURL(""), url.PathEscape(worktreeID)))
+		if err != nil {
+			return worktreeHealthMsg{name: name, err: err}
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return worktreeHealthMsg{name: name, err: fmt.Errorf("failed to check worktree health: HTTP %d", resp.StatusCode)}
+		}
+
+		var health struct {
+			Findings []healthFinding `json:"findings"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&health); err != nil {
+			return worktreeHealthMsg{name: name, err: err}
+		}
+		if health.Findings == nil {
+			health.Findings = []healthFinding{}
+		}
+		return worktreeHealthMsg{name: name, findings: health.Findings}
+	}
+}
+
+// runHealthFix posts a health finding's remediation action to its endpoint
+func (m *Model) runHealthFix(name, worktreeID, action string) tea.Cmd {
+	return func() tea.Msg {
+		endpoint := healthFixEndpoints[action]
+		if strings.Contains(endpoint, "%s") {
+			endpoint = fmt.Sprintf(endpoint, url.PathEscape(worktreeID))
+		}
+		// Recreating, syncing and validating can take a while
+		client := m.createAuthenticatedClient(5 * time.Minute)
+		resp, err := client.Post(m.getBaseURL("")+endpoint, "application/json", bytes.NewBufferString("{}"))
+		if err != nil {
+			return healthFixMsg{name: name, action: action, err: err}
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode >= http.StatusBadRequest {
+			var failure struct {
+				Error string `json:"error"`
+			}
+			_ = json.NewDecoder(resp.Body).Decode(&failure)
+			if failure.Error == "" {
+				failure.Error = fmt.Sprintf("HTTP %d", resp.StatusCode)
+			}
+			return healthFixMsg{name: name, action: action, err: fmt.Errorf("%s failed: %s", action, failure.Error)}
+		}
+		return healthFixMsg{name: name, action: action}
+	}
+}
+
+// observeDetailByID returns the detail of a listed worktree by its ID
+func (m *Model) observeDetailByID(worktreeID string) (string, *worktreeDetail) {
+	for name, detail := range m.observeDetails {
+		if detail.ID == worktreeID {
+			return name, detail
+		}
+	}
+	return "", nil
+}
+
+// renderWorktreeDetail renders the detail pane of a worktree within width columns
+func renderWorktreeDetail(name string, detail *worktreeDetail, width int, now time.Time) string {
+	if width < 20 {
+		width = 20
+	}
+	wrap := lipgloss.NewStyle().Width(width)
+
+	var b strings.Builder
+	b.WriteString(components.SectionHeaderStyle.Render(name) + "\n")
+	if detail == nil {
+		return b.String()
+	}
+
+	if detail.CreatedBy != "" {
+		b.WriteString(wrap.Render(components.MutedStyle.Render("👤 Created by "+detail.CreatedBy)) + "\n")
+	}
+
+	if h := detail.HandedOff; h != nil {
+		line := fmt.Sprintf("🤝 Handed off to %s %s ago, automation paused", h.By, formatIdle(now.Sub(h.At)))
+		if h.PreviewBranch != "" {
+			line += ", preview on " + h.PreviewBranch
+		}
+		b.WriteString(wrap.Render(components.KeyHighlightStyle.Render(line)) + "\n")
+	}
+
+	if health := renderHealthFindings(detail, wrap); health != "" {
+		b.WriteString(health + "\n")
+	}
+
+	title := detail.Title
+	if title == "" && detail.Summary != nil && detail.Summary.Header != nil {
+		title = *detail.Summary.Header
+	}
+	if title != "" {
+		b.WriteString(wrap.Render("📝 "+title) + "\n")
+	}
+
+	sessionEnded := detail.ActivityState == "" || detail.ActivityState == "inactive"
+	switch {
+	case sessionEnded && detail.Summary == nil && title == "" && len(detail.Todos) == 0:
+		b.WriteString(components.MutedStyle.Render("No Claude session yet") + "\n")
+		return b.String()
+	case sessionEnded:
+		b.WriteString(wrap.Render(components.MutedStyle.Render("⏹  Session ended, showing the last known state")) + "\n")
+	case detail.ActivityState == "active":
+		b.WriteString("🟢 Claude is working\n")
+	default:
+		idle := "🟡 Idle"
+		if detail.Summary != nil {
+			if last := detail.Summary.lastActivity(); !last.IsZero() {
+				idle += " for " + formatIdle(now.Sub(last))
+			}
+		}
+		b.WriteString(idle + "\n")
+	}
+
+	if summary := detail.Summary; summary != nil {
+		stats := []string{fmt.Sprintf("%d turns", summary.TurnCount)}
+		if summary.LastDuration != nil {
+			stats = append(stats, "last session "+formatIdle(time.Duration(*summary.LastDuration)*time.Second))
+		}
+		if summary.LastCost != nil {
+			stats = append(stats, fmt.Sprintf("$%.2f", *summary.LastCost))
+		}
+		b.WriteString(wrap.Render(components.MutedStyle.Render(strings.Join(stats, " • "))) + "\n")
+	}
+
+	if len(detail.Todos) > 0 {
+		done := 0
+		for _, todo := range detail.Todos {
+			if todo.Status == "completed" {
+				done++
+			}
+		}
+		b.WriteString(fmt.Sprintf("\nTodos (%d/%d)\n", done, len(detail.Todos)))
+
+		// Wrapped lines are indented under the text, past the checkbox
+		item := lipgloss.NewStyle().Width(width - 4)
+		for _, todo := range detail.Todos {
+			box := "[ ]"
+			switch todo.Status {
+			case "completed":
+				box = "[x]"
+			case "in_progress":
+				box = "[~]"
+			}
+			lines := strings.Split(item.Render(todo.Content), "\n")
+			for i, line := range lines {
+				prefix := "    "
+				if i == 0 {
+					prefix = box + " "
+				}
+				line = strings.TrimRight(prefix+line, " ")
+				if todo.Status == "completed" {
+					line = components.MutedStyle.Render(line)
+				}
+				b.WriteString(line + "\n")
+			}
+		}
+	}
+	return b.String()
+}
+
+// renderHealthFindings renders the health findings of a worktree, with the key fixing the first
+// fixable one, or "" when nothing is wrong
+func renderHealthFindings(detail *worktreeDetail, wrap lipgloss.Style) string {
+	if len(detail.Health) == 0 && detail.HealthFixing == "" && detail.HealthFixErr == nil {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("Health\n")
+	fixable := detail.fixableFinding()
+	for i, finding := range detail.Health {
+		icon := "ℹ️ "
+		switch finding.Severity {
+		case "error":
+			icon = "❌"
+		case "warning":
+			icon = "⚠️ "
+		}
+		b.WriteString(wrap.Render(icon+" "+finding.Message) + "\n")
+		if fixable == &detail.Health[i] && detail.HealthFixing == "" {
+			b.WriteString(components.MutedStyle.Render("   a: "+finding.Action) + "\n")
+		}
+	}
+	if detail.HealthFixing != "" {
+		b.WriteString(components.MutedStyle.Render("   Running "+detail.HealthFixing+"...") + "\n")
+	}
+	if detail.HealthFixErr != nil {
+		b.WriteString(wrap.Render(components.ErrorStyle.Render(detail.HealthFixErr.Error())) + "\n")
+	}
+
+	return b.String()
+}
+
+// formatIdle formats a duration for the detail pane, e.g. "45s", "12m" or "3h 5m"
+func formatIdle(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	default:
+		return fmt.Sprintf("%dh %dm", int(d.Hours()), int(d.Minutes())%60)
+	}
+}
@@ -72,8 +72,8 @@ export function GitCheckout() {
       setProgress(`Checking out ${repoDisplay}...`);
 
       const url = branch
-        ? `/v1/git/checkout/${org}/${repo}?branch=${branch}`
-        : `/v1/git/checkout/${org}/${repo}`;
+        ? `/v1/git/checkout/${org}/${repo}?branch=${branch}&source=ui`
+        : `/v1/git/checkout/${org}/${repo}?source=ui`;
 
       const response = await fetch(url, {
         method: "POST",
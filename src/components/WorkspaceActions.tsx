@@ -31,11 +31,16 @@ import {
   Trash2,
   Code2,
   GitBranch,
+  RotateCcw,
 } from "lucide-react";
+import { toast } from "sonner";
 import {
   type Worktree,
   type PullRequestInfo,
   type LocalRepository,
+  type OperationEstimate,
+  formatOperationEstimate,
+  gitApi,
 } from "@/lib/git-api";
 // ConflictStatus type moved - conflicts now tracked directly on worktree.has_conflicts
 
@@ -87,6 +92,22 @@ export function WorkspaceActions({
   const [showSshDialog, setShowSshDialog] = useState(false);
   const [showGitDialog, setShowGitDialog] = useState(false);
   const [prDialogOpen, setPrDialogOpen] = useState(false);
+  const [syncEstimate, setSyncEstimate] = useState<OperationEstimate | null>(
+    null,
+  );
+
+  // Fetch the sync estimate (including slow-operation warnings) when the menu opens
+  const handleMenuOpenChange = (open: boolean) => {
+    if (!open || !onSync) return;
+    void gitApi.getOperationEstimates(worktree.id).then((estimates) => {
+      setSyncEstimate(estimates?.estimates.sync ?? null);
+    });
+  };
+
+  const syncAverageMs =
+    syncEstimate?.estimated_ms ??
+    worktree.operation_timings?.sync?.average_ms ??
+    0;
 
   const handleDeleteClick = (e?: React.MouseEvent) => {
     if (e) {
@@ -106,6 +127,36 @@ export function WorkspaceActions({
     }
   };
 
+  const handleRecreate = async () => {
+    if (
+      !window.confirm(
+        `Recreate ${worktree.name} from a fresh checkout? Running processes in it will be stopped and the old checkout moved to the trash.`,
+      )
+    ) {
+      return;
+    }
+    const keepUncommitted =
+      !worktree.is_dirty ||
+      window.confirm("Carry uncommitted changes over to the fresh checkout?");
+    try {
+      const result = await gitApi.recreateWorktree(
+        worktree.id,
+        keepUncommitted,
+      );
+      if (result.unapplied_patch) {
+        toast.warning(
+          `Recreated ${worktree.name}, but uncommitted changes could not be applied. They were saved to ${result.unapplied_patch}`,
+        );
+      } else {
+        toast.success(`Recreated ${worktree.name} from scratch`);
+      }
+    } catch (error) {
+      toast.error(
+        error instanceof Error ? error.message : "Failed to recreate worktree",
+      );
+    }
+  };
+
   const handleOpenInCursor = () => {
     const workspacePath = worktree.path.startsWith("/workspace")
       ? worktree.path
@@ -124,7 +175,7 @@ export function WorkspaceActions({
 
   return (
     <>
-      <DropdownMenu>
+      <DropdownMenu onOpenChange={handleMenuOpenChange}>
         <DropdownMenuTrigger asChild>
           <Button
             variant="ghost"
@@ -158,10 +209,26 @@ export function WorkspaceActions({
                     <RefreshCw size={16} />
                   )}
                   Sync with {worktree.source_branch}
-                  {isSyncing && (
-                    <span className="ml-2 text-xs">Syncing...</span>
+                  {isSyncing ? (
+                    <span className="ml-2 text-xs">
+                      Syncing...
+                      {syncAverageMs > 0 &&
+                        ` (usually ${formatOperationEstimate(syncAverageMs)})`}
+                    </span>
+                  ) : (
+                    syncAverageMs > 0 && (
+                      <span className="ml-2 text-xs text-muted-foreground">
+                        {`usually takes ${formatOperationEstimate(syncAverageMs)} here`}
+                      </span>
+                    )
                   )}
                 </DropdownMenuItem>
+                {syncEstimate?.warning && (
+                  <div className="flex items-center gap-2 px-2 pb-1 text-xs text-amber-600">
+                    <AlertTriangle size={12} />
+                    {syncEstimate.warning}
+                  </div>
+                )}
 
                 {worktree.has_conflicts && (
                   <DropdownMenuItem asChild>
@@ -343,6 +410,11 @@ export function WorkspaceActions({
 
           {mode === "worktree" && <DropdownMenuSeparator />}
 
+          <DropdownMenuItem onClick={() => void handleRecreate()}>
+            <RotateCcw size={16} />
+            Recreate from Scratch
+          </DropdownMenuItem>
+
           {/* Delete action */}
           {(onDelete || onConfirmDelete) && (
             <DropdownMenuItem
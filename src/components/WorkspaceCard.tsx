@@ -4,7 +4,7 @@ import { Badge } from "@/components/ui/badge";
 import { Textarea } from "@/components/ui/textarea";
 import { Skeleton } from "@/components/ui/skeleton";
 import { GitBranch, Copy, Check, Clock, Loader2 } from "lucide-react";
-import { type Worktree } from "@/lib/git-api";
+import { retargetedPullRequestBase, type Worktree } from "@/lib/git-api";
 import { getRelativeTime } from "@/lib/git-utils";
 import { useState } from "react";
 import { WorkspaceActions as SharedWorkspaceActions } from "@/components/WorkspaceActions";
@@ -18,6 +18,7 @@ export function WorkspaceCard({ worktree, onDelete }: WorkspaceCardProps) {
   const [prompt, setPrompt] = useState("");
   const [isAnimating, setIsAnimating] = useState(false);
   const navigate = useNavigate();
+  const prBase = retargetedPullRequestBase(worktree);
 
   const navigateToClaude = (e: React.SyntheticEvent) => {
     e.preventDefault();
@@ -187,6 +188,13 @@ export function WorkspaceCard({ worktree, onDelete }: WorkspaceCardProps) {
               In sync with {worktree.source_branch}
             </div>
           )}
+          {prBase && (
+            <div className="text-xs text-orange-600">
+              PR targets {prBase}
+              {(worktree.pull_request_commits_behind ?? 0) > 0 &&
+                `, ${worktree.pull_request_commits_behind} commits behind`}
+            </div>
+          )}
         </div>
       </div>
     </div>
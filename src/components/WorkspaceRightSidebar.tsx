@@ -1,4 +1,4 @@
-import { useMemo } from "react";
+import { useEffect, useMemo } from "react";
 import {
   FileText,
   GitBranch,
@@ -12,6 +12,8 @@ import {
   Terminal,
   Globe,
   ExternalLink,
+  XCircle,
+  Info,
 } from "lucide-react";
 import {
   Sidebar,
@@ -45,7 +47,14 @@ import {
 } from "@/components/ui/alert-dialog";
 import { WorkspaceActions } from "@/components/WorkspaceActions";
 import { useAppStore } from "@/stores/appStore";
-import type { Worktree, LocalRepository } from "@/lib/git-api";
+import { toast } from "sonner";
+import {
+  gitApi,
+  type Worktree,
+  type LocalRepository,
+  type HealthAction,
+  type HealthFinding,
+} from "@/lib/git-api";
 
 interface WorkspaceRightSidebarProps {
   worktree: Worktree;
@@ -109,6 +118,107 @@ function GitStatus({ worktree }: { worktree: Worktree }) {
   );
 }
 
+const healthActionLabels: Record<HealthAction, string> = {
+  recreate: "Recreate",
+  "repair-remote-config": "Repair",
+  "acknowledge-branch-drift": "Acknowledge",
+  sync: "Sync",
+  validate: "Validate",
+  "trim-caches": "Trim caches",
+};
+
+function WorktreeHealthFindings({ worktree }: { worktree: Worktree }) {
+  const [findings, setFindings] = useState<HealthFinding[]>([]);
+  const [runningAction, setRunningAction] = useState<HealthAction | null>(
+    null,
+  );
+
+  const refresh = async () => {
+    const health = await gitApi.getWorktreeHealth(worktree.id);
+    if (health) {
+      setFindings(health.findings);
+    }
+  };
+
+  // Re-check whenever the worktree's git state changes
+  useEffect(() => {
+    void refresh();
+    // eslint-disable-next-line react-hooks/exhaustive-deps
+  }, [
+    worktree.id,
+    worktree.commit_hash,
+    worktree.commits_behind,
+    worktree.has_conflicts,
+    worktree.manual_intervention,
+  ]);
+
+  const runAction = async (action: HealthAction) => {
+    setRunningAction(action);
+    try {
+      await gitApi.runHealthAction(worktree.id, action);
+      toast.success(`${healthActionLabels[action]} finished`);
+    } catch (error) {
+      toast.error(error instanceof Error ? error.message : String(error));
+    } finally {
+      setRunningAction(null);
+      await refresh();
+    }
+  };
+
+  if (findings.length === 0) {
+    return null;
+  }
+
+  return (
+    <>
+      <SidebarGroup>
+        <SidebarGroupLabel>Health</SidebarGroupLabel>
+        <SidebarGroupContent>
+          <div className="space-y-2">
+            {findings.map((finding) => (
+              <div
+                key={finding.code}
+                className="flex items-start gap-2 px-2"
+              >
+                {finding.severity === "error" ? (
+                  <XCircle
+                    className="h-3 w-3 text-red-500 mt-0.5 flex-shrink-0"
+                  />
+                ) : finding.severity === "warning" ? (
+                  <AlertCircle
+                    className="h-3 w-3 text-yellow-500 mt-0.5 flex-shrink-0"
+                  />
+                ) : (
+                  <Info
+                    className="h-3 w-3 text-muted-foreground mt-0.5 flex-shrink-0"
+                  />
+                )}
+                <div className="flex-1 min-w-0 space-y-1">
+                  <p className="text-xs break-words">{finding.message}</p>
+                  {finding.action && (
+                    <Button
+                      variant="outline"
+                      size="sm"
+                      className="h-6 text-xs"
+                      disabled={runningAction !== null}
+                      onClick={() => void runAction(finding.action!)}
+                    >
+                      {runningAction === finding.action
+                        ? "Running..."
+                        : healthActionLabels[finding.action]}
+                    </Button>
+                  )}
+                </div>
+              </div>
+            ))}
+          </div>
+        </SidebarGroupContent>
+      </SidebarGroup>
+      <SidebarSeparator className="mx-0" />
+    </>
+  );
+}
+
 function TodosList({ worktree }: { worktree: Worktree }) {
   const todos = worktree.todos || [];
 
@@ -718,6 +828,7 @@ export function WorkspaceRightSidebar({
       <SidebarContent>
         <GitStatus worktree={worktree} />
         <SidebarSeparator className="mx-0" />
+        <WorktreeHealthFindings worktree={worktree} />
         <TodosList worktree={worktree} />
         <SidebarSeparator className="mx-0" />
         <ChangedFiles
@@ -185,6 +185,24 @@ function StatusBadges({
           Clean
         </Badge>
       )}
+      {worktree.handed_off && (
+        <Badge
+          variant="secondary"
+          className="text-xs bg-violet-100 text-violet-800 border-violet-200"
+          title="Checkpoints, branch renames and commit syncing are paused"
+        >
+          Handed off to {worktree.handed_off.by}
+        </Badge>
+      )}
+      {worktree.source_branch_rewritten && (
+        <Badge
+          variant="secondary"
+          className="text-xs bg-red-100 text-red-800 border-red-200"
+          title="Sync acknowledging the rewrite to rebase onto the new tip"
+        >
+          {worktree.source_branch_rewritten.branch} force-pushed
+        </Badge>
+      )}
       {worktree.cache_status?.is_loading && (
         <Badge variant="secondary" className="text-xs">
           <Loader2 className="w-3 h-3 mr-1 animate-spin" />
@@ -572,6 +590,18 @@ export function WorktreeRow({
               source branch:{" "}
               <span className="font-bold">{worktree.source_branch}</span>
             </span>
+            {worktree.creation_context && (
+              <span
+                className="text-xs truncate max-w-xs"
+                title={worktree.creation_context.prompt}
+              >
+                via {worktree.creation_context.source}
+                {worktree.creation_context.issue &&
+                  ` for ${worktree.creation_context.issue}`}
+                {worktree.creation_context.prompt &&
+                  `: ${worktree.creation_context.prompt}`}
+              </span>
+            )}
             {!worktree.cache_status?.is_cached &&
             worktree.commit_count === undefined ? (
               <Skeleton className="w-16 h-4" />
@@ -54,8 +54,8 @@ export function useGitActions(gitStateActions: GitStateActions) {
 
       const { org, repo } = parsedUrl;
       const checkoutUrl = branch
-        ? `/v1/git/checkout/${org}/${repo}?branch=${encodeURIComponent(branch)}`
-        : `/v1/git/checkout/${org}/${repo}`;
+        ? `/v1/git/checkout/${org}/${repo}?branch=${encodeURIComponent(branch)}&source=ui`
+        : `/v1/git/checkout/${org}/${repo}?source=ui`;
       const response = await fetch(checkoutUrl, {
         method: "POST",
       });
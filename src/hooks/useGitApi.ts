@@ -173,6 +173,7 @@ export function useGitApi() {
       if (branch && branch !== "main") {
         url.searchParams.set("branch", branch);
       }
+      url.searchParams.set("source", "ui");
       console.log("Making request to:", url.toString());
 
       const response = await fetch(url.toString(), {
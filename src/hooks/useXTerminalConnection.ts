@@ -490,6 +490,19 @@ export function useXTerminalConnection({
           } else if (msg.type === "read-only") {
             setIsReadOnly(msg.data === true);
             return;
+          } else if (msg.type === "control-request") {
+            // A read-only observer (e.g. the TUI) wants to take over this terminal
+            const granted = window.confirm(
+              "Another client observing this session is requesting control. Hand over control?",
+            );
+            ws.send(
+              JSON.stringify({
+                type: "control-response",
+                connId: msg.connId,
+                granted,
+              }),
+            );
+            return;
           } else if (msg.type === "session-restarting") {
             // Backend is restarting the session - prepare for full reset
             isSessionRestarting.current = true;
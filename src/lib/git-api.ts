@@ -4,6 +4,81 @@ import { fetchWithTimeout, TimeoutError } from "./fetch-with-timeout";
 export interface GitStatus {
   repositories?: Record<string, LocalRepository>;
   worktree_count?: number;
+  state_report?: StateReport;
+  // Set when state files were found corrupt on load, until the report is
+  // acknowledged
+  degraded?: boolean;
+  degraded_reason?: string;
+  github_rate_limit?: GitHubRateLimitStatus;
+}
+
+// How much spending of the GitHub API budget is held back: slow stretches
+// polling, paused also queues pull request operations unless urgent
+export type GitHubThrottle = "normal" | "slow" | "paused" | "exhausted";
+
+export interface GitHubRateLimitResource {
+  resource: "core" | "graphql";
+  limit: number;
+  remaining: number;
+  used: number;
+  reset_at: string;
+  updated_at: string;
+  throttle: GitHubThrottle;
+}
+
+// Pull request operation waiting for the GitHub rate limit to reset
+export interface QueuedGitHubOperation {
+  id: string;
+  kind: "create_pr" | "update_pr";
+  worktree_id: string;
+  queued_at: string;
+  run_at: string;
+  status: "queued" | "running" | "done" | "failed";
+  error?: string;
+  url?: string;
+}
+
+export interface GitHubRateLimitStatus {
+  throttle: GitHubThrottle;
+  resources: GitHubRateLimitResource[];
+  queued?: QueuedGitHubOperation[];
+}
+
+export interface StateReportEntry {
+  // git_worktree is a git worktree that isn't in state, its id is the path
+  kind: "repository" | "worktree" | "git_worktree";
+  id: string;
+  name?: string;
+  reason: string;
+}
+
+// What loading the persisted state at startup healed or lost, until acknowledged
+export interface StateReport {
+  at: string;
+  migrations: string[];
+  quarantined: string[];
+  // Quarantined files replaced by their backup, possibly missing a change
+  restored?: string[];
+  lost: StateReportEntry[];
+  unavailable: StateReportEntry[];
+  path_rewrites: {
+    worktree_id: string;
+    name: string;
+    from: string;
+    to: string;
+  }[];
+  pruned?: StateReportEntry[];
+  stale?: StateReportEntry[];
+  acknowledged_at?: string;
+}
+
+// Worktrees imported, removed or flagged after comparing state with git worktree list
+export interface WorktreeReconciliation {
+  missing_mode: "flag" | "remove";
+  added: StateReportEntry[];
+  removed: StateReportEntry[];
+  flagged: StateReportEntry[];
+  recovered: StateReportEntry[];
 }
 
 export interface TitleEntry {
@@ -36,10 +111,18 @@ export interface Worktree {
   name: string;
   branch: string;
   source_branch: string;
+  source_ref?: string;
   path: string;
   commit_hash: string;
   commit_count: number;
   commits_behind: number;
+  source_tip?: string;
+  source_branch_rewritten?: SourceRewrite;
+  group_id?: string;
+  stash_count?: number;
+  commit_signatures?: CommitSignatureSummary;
+  // Bytes on disk, when worktrees were listed with disk usage
+  disk_usage_bytes?: number;
   is_dirty: boolean;
   has_conflicts: boolean;
   dirty_files?: DirtyFile[];
@@ -55,12 +138,632 @@ export interface Worktree {
   pull_request_body?: string;
   pull_request_state?: string;
   pull_request_last_synced?: string;
+  pull_request_base_branch?: string;
+  pull_request_commit_count?: number;
+  pull_request_commits_behind?: number;
   todos?: Todo[];
   latest_claude_message?: string;
   latest_claude_message_type?: string;
   latest_claude_message_timestamp?: number;
   latest_user_prompt?: string;
   latest_session_title?: string;
+  checkpoints_held?: boolean;
+  checkpoint_config?: CheckpointConfig;
+  run_hooks_on_checkpoint?: boolean;
+  last_checkpoint_error?: CheckpointError;
+  secret_keys?: string[];
+  setup_status?: "pending" | "running" | "succeeded" | "failed";
+  handed_off?: WorktreeHandoff;
+  operation_timings?: Record<string, OperationTimings>;
+  branch_rename?: BranchRenameOutcome;
+  related_worktree_ids?: string[];
+  notes?: string[];
+  manual_intervention?: BranchDrift;
+  toolchains?: Toolchain[];
+  ignore_suggestions?: IgnoreSuggestion[];
+  creation_context?: CreationContext;
+  validation?: ValidationResult;
+  status_freshness?: WorktreeStatusFreshness;
+  // git no longer knows the worktree, it isn't recreated on boot
+  missing?: boolean;
+}
+
+export type StatusTier = "none" | "cheap" | "standard" | "full";
+
+export interface StatusFreshness {
+  tier: StatusTier;
+  refreshed_at: string;
+}
+
+export interface WorktreeStatusFreshness {
+  working_tree?: StatusFreshness;
+  divergence?: StatusFreshness;
+  remote?: StatusFreshness;
+}
+
+export interface ValidationCommandResult {
+  command: string;
+  exit_code: number;
+  timed_out?: boolean;
+  duration_ms: number;
+  output?: string;
+}
+
+export interface ValidationResult {
+  status: "passed" | "failed" | "skipped" | "not_configured";
+  commit_hash?: string;
+  operation?: "merge" | "pull_request" | "manual";
+  commands?: ValidationCommandResult[];
+  started_at: string;
+  finished_at: string;
+}
+
+export interface CreationContext {
+  source: "ui" | "tui" | "api" | "auto";
+  prompt?: string;
+  issue?: string;
+  template?: string;
+  actor?: string;
+  created_at: string;
+}
+
+export interface IgnoreSuggestion {
+  pattern: string;
+  files: number;
+  checkpoints: number;
+}
+
+// catnipignore is refused for local repositories, use gitignore for them
+export type IgnoreTarget = "catnipignore" | "gitignore";
+
+export interface OperationBackup {
+  id: string;
+  worktree_id: string;
+  ref: string;
+  commit: string;
+  subject?: string;
+  operation?: string;
+  created_at: string;
+  expires_at: string;
+}
+
+export interface Toolchain {
+  name: string;
+  package_manager?: string;
+  version?: string;
+  manifest: string;
+  lockfile?: string;
+}
+
+export interface BranchDrift {
+  expected_branch: string;
+  actual_branch: string;
+  expected_commit?: string;
+  actual_commit?: string;
+  adopted?: boolean;
+  detected_at: string;
+}
+
+// A force push of a worktree's remote source branch
+export interface SourceRewrite {
+  branch: string;
+  previous_tip: string;
+  new_tip: string;
+  detected_at: string;
+}
+
+export type SyncStrategy = "rebase" | "merge" | "ff-only";
+
+// What a worktree syncs against: its source branch, or the branch its PR targets on GitHub
+export type SyncBase = "source" | "pull_request";
+
+// A merge to main prepared for reviewing its commit message
+export interface MergePreview {
+  token: string;
+  worktree_id: string;
+  mode: "squash" | "merge";
+  target_branch: string;
+  message: string;
+  diff_stat: string;
+  head_commit: string;
+  expires_at: string;
+}
+
+export type HealthAction =
+  | "recreate"
+  | "repair-remote-config"
+  | "acknowledge-branch-drift"
+  | "sync"
+  | "validate"
+  | "trim-caches";
+
+export interface HealthFinding {
+  severity: "error" | "warning" | "info";
+  code: string;
+  message: string;
+  action?: HealthAction;
+}
+
+export interface WorktreeHealth {
+  worktree_id: string;
+  findings: HealthFinding[];
+  checked_at: string;
+}
+
+// Endpoints the remediation actions of health findings are posted to
+const healthActionEndpoints: Record<HealthAction, (id: string) => string> = {
+  recreate: (id) => `/v1/git/worktrees/${id}/recreate`,
+  "repair-remote-config": (id) =>
+    `/v1/git/worktrees/${id}/remote-config/repair`,
+  "acknowledge-branch-drift": (id) =>
+    `/v1/git/worktrees/${id}/branch-drift/acknowledge`,
+  sync: (id) => `/v1/git/worktrees/${id}/sync`,
+  validate: (id) => `/v1/git/worktrees/${id}/validate`,
+  "trim-caches": () => "/v1/git/caches/trim",
+};
+
+export interface WorktreeHandoff {
+  by: string;
+  at: string;
+  checkpoint?: string;
+  preview_branch?: string;
+}
+
+export interface CheckpointError {
+  message: string;
+  hook_output?: string;
+  committed: boolean;
+  at: string;
+}
+
+export interface DebugTimelineEntry {
+  time: string;
+  kind: "title_event" | "title_commit" | "checkpoint_timer" | "graduation";
+  source?: string;
+  title?: string;
+  outcome: string;
+  reason?: string;
+  commit?: string;
+}
+
+export interface CheckpointConfig {
+  enabled: boolean;
+  timeout_seconds?: number;
+}
+
+export interface BranchCheckoutResult {
+  branch: string;
+  worktree?: Worktree;
+  error?: string;
+}
+
+export interface BranchRenameOutcome {
+  trigger?: "title" | "todo" | "manual";
+  policy?: "suffix" | "skip" | "adopt";
+  outcome: "renamed" | "suffixed" | "skipped" | "adopted" | "failed";
+  reason?: string;
+  requested_branch?: string;
+  branch: string;
+  colliding_worktree_id?: string;
+  message?: string;
+  at: string;
+}
+
+export interface RecreateWorktreeResult {
+  worktree: Worktree;
+  soft_deleted_path: string;
+  terminated_processes: string[];
+  transplanted_changes: boolean;
+  unapplied_patch?: string;
+  resumed: boolean;
+}
+
+export interface WorktreeArchive {
+  id: string;
+  worktree_id: string;
+  worktree_name: string;
+  repo_id: string;
+  branch: string;
+  source_branch: string;
+  commit_hash: string;
+  is_dirty: boolean;
+  files: number;
+  size: number;
+  archived_at: string;
+  path: string;
+}
+
+export interface WorktreeExportResult {
+  path: string;
+  format: "directory" | "tar";
+  tree: string;
+  files: number;
+  written: number;
+  removed: number;
+  incremental: boolean;
+}
+
+export interface ReviewComment {
+  author: string;
+  body: string;
+  url?: string;
+  created_at: string;
+}
+
+export interface ReviewThread {
+  path: string;
+  line?: number;
+  outdated?: boolean;
+  comments: ReviewComment[];
+}
+
+export interface ContextPackFile {
+  path: string;
+  change_type: string;
+  additions: number;
+  deletions: number;
+  diff?: string;
+  truncated?: boolean;
+  omitted?: "binary" | "generated" | "budget";
+}
+
+export interface WorktreeContextPack {
+  worktree_id: string;
+  worktree_name: string;
+  repository: string;
+  branch: string;
+  source_branch: string;
+  commit_hash?: string;
+  generated_at: string;
+  max_tokens: number;
+  estimated_tokens: number;
+  creation_context?: CreationContext;
+  layout: {
+    files: number;
+    entries: { path: string; files: number }[];
+    toolchains?: Toolchain[];
+  };
+  diff: {
+    summary?: string;
+    total_files: number;
+    total_additions: number;
+    total_deletions: number;
+    files: ContextPackFile[];
+  };
+  todos?: Todo[];
+  titles?: TitleEntry[];
+  latest_prompt?: string;
+  failed_validation?: ValidationResult;
+  pull_request?: {
+    url: string;
+    title?: string;
+    state?: string;
+    checks_state?: string;
+    review_threads?: ReviewThread[];
+  };
+  truncated?: string[];
+  notes?: string[];
+  markdown?: string;
+  written_to?: string;
+}
+
+export interface OperationTimingSample {
+  at: string;
+  duration_ms: number;
+  network_ms: number;
+  local_ms: number;
+  success: boolean;
+}
+
+export interface OperationTimings {
+  samples: OperationTimingSample[];
+  average_ms: number;
+  average_network_ms: number;
+  average_local_ms: number;
+}
+
+export interface OperationEstimate {
+  operation: string;
+  estimated_ms: number;
+  network_ms: number;
+  local_ms: number;
+  sample_count: number;
+  from_repository?: boolean;
+  warning?: string;
+}
+
+export interface OperationEstimates {
+  worktree_id: string;
+  is_shallow: boolean;
+  repository_size_kb: number;
+  estimates: Record<string, OperationEstimate>;
+}
+
+// Compact worktree counts for polling; fetch the full list when hash changes
+export interface WorktreesSummary {
+  total: number;
+  dirty: number;
+  conflicted: number;
+  with_pull_requests: number;
+  failing_checks: number;
+  last_event_sequence: number;
+  hash: string;
+}
+
+// A git config entry that differs from the setup catnip expects
+export interface RemoteConfigDrift {
+  path: string;
+  scope: string;
+  key: string;
+  expected?: string;
+  actual?: string;
+}
+
+export interface RemoteConfigReport {
+  repo_id: string;
+  worktree_id?: string;
+  drift: RemoteConfigDrift[];
+  repaired?: string[];
+}
+
+// A catnip branch, ref or config mapping handled by a repository cleanup
+export interface CleanupItem {
+  repo_id: string;
+  name: string;
+  error?: string;
+}
+
+export interface CleanupReport {
+  dry_run: boolean;
+  branches: CleanupItem[];
+  preview_branches: CleanupItem[];
+  remote_branches: CleanupItem[];
+  refs: CleanupItem[];
+  config_mappings: CleanupItem[];
+  checked_out: CleanupItem[];
+  errors: CleanupItem[];
+}
+
+export interface MergedWorktree {
+  id: string;
+  name: string;
+  branch: string;
+  reason: "branch_missing" | "listed_merged";
+}
+
+export interface BulkPullRequestRequest {
+  repo_id?: string;
+  worktree_ids?: string[];
+  draft?: boolean;
+  sync_first?: boolean;
+  // ff-only (default) skips worktrees that diverged from their source branch
+  sync_strategy?: "ff-only" | "merge" | "rebase";
+  text_mode?: "session" | "commits";
+}
+
+export interface BulkPullRequestResult {
+  worktree_id: string;
+  name: string;
+  outcome: "created" | "skipped" | "failed";
+  url?: string;
+  reason?: string;
+}
+
+export interface BulkPullRequestReport {
+  created: string[];
+  results: BulkPullRequestResult[];
+}
+
+export interface WorktreeGroupMember {
+  repo_id: string;
+  branch: string;
+  worktree_id: string;
+}
+
+export interface WorktreeGroup {
+  id: string;
+  name: string;
+  members: WorktreeGroupMember[];
+  created_at: string;
+}
+
+export interface WorktreeGroupMemberStatus extends WorktreeGroupMember {
+  missing: boolean;
+  name?: string;
+  worktree_branch?: string;
+  commit_count: number;
+  commits_behind: number;
+  is_dirty: boolean;
+  has_conflicts: boolean;
+  pull_request_url?: string;
+  pull_request_state?: string;
+}
+
+export interface WorktreeGroupStatus {
+  group: WorktreeGroup;
+  members: WorktreeGroupMemberStatus[];
+}
+
+export interface WorktreeGroupResult {
+  worktree_id: string;
+  name: string;
+  repo_id: string;
+  outcome: "succeeded" | "skipped" | "failed";
+  url?: string;
+  reason?: string;
+}
+
+export interface WorktreeGroupReport {
+  group_id: string;
+  results: WorktreeGroupResult[];
+}
+
+export interface CredentialHostStatus {
+  host: string;
+  helpers: string[];
+  problem?: string;
+  verified: boolean;
+  verify_error?: string;
+}
+
+export interface CredentialHelperReport {
+  git_version: string;
+  hosts: CredentialHostStatus[];
+  changed?: string[];
+  verified: boolean;
+  checked_at: string;
+}
+
+export interface DependencyCacheUsage {
+  name: string;
+  env_var: string;
+  path: string;
+  size_kb: number;
+  last_used?: string;
+}
+
+export interface DependencyCacheReport {
+  caches: DependencyCacheUsage[];
+  total_kb: number;
+  max_size_kb: number;
+  removed?: string[];
+  freed_kb?: number;
+}
+
+export type ActivityKind =
+  | "worktree_created"
+  | "worktree_deleted"
+  | "session_title"
+  | "commit"
+  | "pull_request_opened"
+  | "pull_request_merged"
+  | "merged"
+  | "bundle_fetched"
+  | "operation_backup"
+  | "backup_restored"
+  | "handed_off"
+  | "automation_resumed"
+  | "patch_applied";
+
+export interface ActivityEntry {
+  time: string;
+  kind: ActivityKind;
+  repo_id: string;
+  worktree_id?: string;
+  worktree_name?: string;
+  actor?: string;
+  summary: string;
+  url?: string;
+}
+
+export interface WorktreeActivity {
+  id: string;
+  name: string;
+  branch?: string;
+  pull_request_url?: string;
+  pull_request_state?: string;
+  commits: number;
+  files_changed: number;
+  events: ActivityEntry[];
+}
+
+// Activity feed of a repository over a period, e.g. for standup summaries
+export interface RepositoryActivity {
+  repo_id: string;
+  since: string;
+  until: string;
+  totals: {
+    sessions_active: number;
+    commits: number;
+    files_changed: number;
+    pull_requests_opened: number;
+    pull_requests_merged: number;
+  };
+  worktrees: WorktreeActivity[];
+  events: ActivityEntry[];
+}
+
+// A worktree keeping its repository from being deleted
+export interface BlockingWorktree {
+  id: string;
+  name: string;
+  branch: string;
+}
+
+export type DeleteRepositoryResult =
+  | { deleted: true }
+  | { deleted: false; blocking: BlockingWorktree[] };
+
+// A worktree merged into its source branch, linked from the merge commit's Catnip-* trailers
+export interface MergeLedgerEntry {
+  commit_hash: string;
+  repo_id: string;
+  mode: "squash" | "merge";
+  target_branch: string;
+  branch: string;
+  head_commit: string;
+  worktree_id: string;
+  worktree_name: string;
+  session_id?: string;
+  session_title?: string;
+  pull_request_url?: string;
+  created_by?: string;
+  commit_count: number;
+  diff_stat: string;
+  commit_signatures?: CommitSignatureSummary;
+  signatures?: (CommitSignature & { hash: string })[];
+  worktree_created_at: string;
+  merged_at: string;
+}
+
+// A long operation (clone, merge, cleanup, bulk pull requests) from the persisted operation
+// journal; operations a restart interrupted report how they were recovered
+export interface Operation {
+  id: string;
+  kind: "clone" | "merge" | "cleanup_merged" | "bulk_pull_requests";
+  target: string;
+  params?: Record<string, string>;
+  state: "running" | "succeeded" | "failed" | "interrupted";
+  phases: { name: string; at: string }[];
+  error?: string;
+  recovery?: "resuming" | "resumed" | "rolled_back" | "none";
+  started_at: string;
+  finished_at?: string;
+}
+
+// The branch a worktree's pull request targets when it's not the source branch, e.g. after the
+// PR was retargeted on GitHub; ahead/behind counts against it are in pull_request_commit_*
+export function retargetedPullRequestBase(
+  worktree: Worktree,
+): string | undefined {
+  if (
+    worktree.pull_request_url &&
+    worktree.pull_request_base_branch &&
+    worktree.pull_request_base_branch !== worktree.source_branch
+  ) {
+    return worktree.pull_request_base_branch;
+  }
+  return undefined;
+}
+
+// Formats an average duration as a short hint, e.g. "~35s" or "~2m"
+export function formatOperationEstimate(ms: number): string {
+  const seconds = Math.max(1, Math.round(ms / 1000));
+  if (seconds < 90) {
+    return `~${seconds}s`;
+  }
+  return `~${Math.round(seconds / 60)}m`;
+}
+
+// Formats a size in KiB, e.g. "512 KB", "12.3 MB" or "1.2 GB"
+export function formatRepositorySize(kb: number): string {
+  if (kb < 1024) {
+    return `${kb} KB`;
+  }
+  if (kb < 1024 * 1024) {
+    return `${(kb / 1024).toFixed(1)} MB`;
+  }
+  return `${(kb / (1024 * 1024)).toFixed(1)} GB`;
 }
 
 interface Owner {
@@ -87,47 +790,342 @@ export interface LocalRepository {
   path: string;
   url: string;
   available: boolean;
+  head_state?: "branch" | "detached" | "unborn";
+  not_ready_reason?: string;
+  toolchains?: Toolchain[];
   remote_origin?: string;
   has_github_remote?: boolean;
+  // Set for the catnip repository in dev mode and paths in CATNIP_PROTECTED_REPOS
+  protected?: boolean;
+  protected_reason?: string;
+  mirrors?: RepositoryMirror[];
+  health_warnings?: Record<string, string>;
+  operation_timings?: Record<string, OperationTimings>;
+  size?: RepositorySize;
+  // When git gc last ran on the repository
+  last_gc?: string;
+  // Bytes on disk without worktrees, when status was fetched with disk usage
+  disk_usage_bytes?: number;
+  // Previous IDs of a repository renamed or transferred on GitHub
+  aliases?: string[];
+  issue_automation?: IssueAutomation;
+  pull_request_template?: PullRequestTemplateSettings;
+  // Whether the clone has its full history, unset when unknown
+  history?: "shallow" | "unshallowing" | "full";
+  // Every branch of origin is fetched and kept current in the background
+  mirror?: boolean;
+  // When a mirrored repository was last updated from origin
+  last_remote_update?: string;
+  setup_commands?: string[];
 }
 
-interface FileDiff {
-  file_path: string;
-  change_type: string;
-  old_content?: string;
-  new_content?: string;
-  diff_text?: string;
-  is_expanded: boolean;
+// How catnip's generated content is combined with the repository's pull
+// request template
+export interface PullRequestTemplateSettings {
+  mode: "append" | "sections" | "off";
+  // File name within a PULL_REQUEST_TEMPLATE/ directory of templates
+  template?: string;
 }
 
-export interface WorktreeDiffStats {
-  summary: string;
-  file_diffs: FileDiff[];
-  total_files: number;
-  worktree_id: string;
-  worktree_name: string;
-  source_branch: string;
-  fork_commit: string;
+export interface PullRequestBodyPreview {
+  body: string;
+  // Template used, relative to the worktree
+  template?: string;
+  templates: string[];
+  mode: PullRequestTemplateSettings["mode"];
+  // Why a template that was found wasn't used
+  warning?: string;
 }
 
-export interface PullRequestInfo {
-  has_commits_ahead: boolean;
-  exists: boolean;
-  title?: string;
-  body?: string;
-  number?: number;
-  url?: string;
+// Automatic worktree creation for GitHub issues carrying a label
+export interface IssueAutomation {
+  enabled: boolean;
+  label: string;
+  branch?: string;
+  // Issues seen with the label, keyed by number; entries are never removed
+  issues?: Record<string, AutomatedIssue>;
+  last_polled_at?: string;
+  last_error?: string;
 }
 
-export interface ErrorHandler {
-  setErrorAlert: (alert: {
-    open: boolean;
-    title: string;
-    description: string;
-    worktreeName?: string;
-    conflictFiles?: string[];
-    operation?: string;
-  }) => void;
+export interface AutomatedIssue {
+  number: number;
+  title: string;
+  url: string;
+  worktree_id?: string;
+  worktree_name?: string;
+  linked_at?: string;
+  commented: boolean;
+  // Why automation stopped for the issue; its worktree is kept
+  stopped?: "unlabeled" | "closed";
+  last_error?: string;
+}
+
+// Repository size reported by GitHub (remote_kb) and measured on disk
+export interface RepositorySize {
+  remote_kb?: number;
+  local_kb: number;
+  object_count: number;
+  pack_count: number;
+  shallow: boolean;
+  measured_at: string;
+}
+
+export interface ObjectStats {
+  loose_objects: number;
+  loose_kb: number;
+  packs: number;
+  pack_kb: number;
+  garbage_kb?: number;
+}
+
+// Which git gc ran on a repository and why
+export interface RepositoryMaintenance {
+  repo_id: string;
+  action: "none" | "auto" | "full";
+  reason?: string;
+  before: ObjectStats;
+  after?: ObjectStats;
+  duration_ms: number;
+}
+
+// Space repositories and their worktrees take on disk, largest first
+export interface DiskUsage {
+  repositories: RepositoryDiskUsage[];
+  repository_bytes: number;
+  worktree_bytes: number;
+  total_bytes: number;
+  measured_at: string;
+  duration_ms: number;
+}
+
+export interface RepositoryDiskUsage {
+  repo_id: string;
+  path?: string;
+  // Includes the objects the repository's worktrees share
+  bytes: number;
+  worktree_bytes: number;
+  total_bytes: number;
+  worktrees: WorktreeDiskUsage[];
+}
+
+export interface WorktreeDiskUsage {
+  worktree_id: string;
+  name: string;
+  path: string;
+  bytes: number;
+  missing?: boolean;
+}
+
+export interface OrphanBranch {
+  branch: string;
+  ref: string;
+  commit: string;
+  commits_ahead: number;
+  committed_at: string;
+  catnip?: boolean;
+}
+
+export interface RepositoryMirror {
+  name: string;
+  url: string;
+  credential?: string;
+  last_success?: string;
+  last_error?: string;
+  last_error_at?: string;
+  consecutive_failures: number;
+}
+
+interface FileDiff {
+  file_path: string;
+  old_path?: string;
+  change_type: string;
+  similarity?: number;
+  old_content?: string;
+  new_content?: string;
+  diff_text?: string;
+  additions?: number;
+  deletions?: number;
+  binary?: boolean;
+  is_expanded: boolean;
+  generated?: boolean;
+}
+
+// Files and lines a change touched; authored_stats leave out generated files
+export interface DiffStats {
+  files: number;
+  additions: number;
+  deletions: number;
+}
+
+export interface WorktreeDiffStats {
+  summary: string;
+  file_diffs: FileDiff[];
+  total_files: number;
+  total_additions: number;
+  total_deletions: number;
+  authored_stats: DiffStats;
+  worktree_id: string;
+  worktree_name: string;
+  source_branch: string;
+  fork_commit: string;
+  base_ref?: string;
+}
+
+// A page of the files changed in a worktree, without contents; fetch the diff of a file with
+// gitApi.fetchWorktreeFileDiff
+export interface WorktreeDiffSummary {
+  worktree_id: string;
+  worktree_name: string;
+  source_branch: string;
+  fork_commit: string;
+  files: FileDiff[];
+  total_files: number;
+  total_additions: number;
+  total_deletions: number;
+  authored_stats: DiffStats;
+  offset: number;
+  limit: number;
+  has_more: boolean;
+}
+
+export interface CommitFileStat {
+  path: string;
+  additions: number;
+  deletions: number;
+  binary?: boolean;
+  generated?: boolean;
+}
+
+export type CommitSignatureSummary = "all_signed" | "mixed" | "none";
+
+export interface CommitSignature {
+  status: "good" | "bad" | "unknown" | "unsigned";
+  signer?: string;
+  key?: string;
+}
+
+export interface WorktreeCommit {
+  hash: string;
+  author: string;
+  author_email: string;
+  timestamp: string;
+  subject: string;
+  is_checkpoint: boolean;
+  session_title?: string;
+  signature: CommitSignature;
+  files?: CommitFileStat[];
+  stats?: DiffStats;
+  authored_stats?: DiffStats;
+}
+
+export interface WorktreeCommits {
+  worktree_id: string;
+  source_ref: string;
+  commits: WorktreeCommit[];
+  total: number;
+  offset: number;
+  limit: number;
+  has_more: boolean;
+}
+
+export interface CherryPickedCommit {
+  source: string;
+  commit: string;
+}
+
+export interface CherryPickResult {
+  worktree_id: string;
+  applied: CherryPickedCommit[];
+  skipped: string[];
+  head: string;
+}
+
+export interface PatchApplyResult {
+  worktree_id: string;
+  format: "diff" | "format-patch";
+  patches: number;
+  files: string[];
+  additions: number;
+  deletions: number;
+  binary_files?: string[];
+  dry_run: boolean;
+  conflict_files?: string[];
+  commit?: string;
+}
+
+export interface PatchUpload {
+  id: string;
+  worktree_id: string;
+  size: number;
+  max_size: number;
+  expires_at: string;
+}
+
+export interface PatchApplyOptions {
+  // Merge hunks that don't apply, leaving conflicts (default true)
+  threeWay?: boolean;
+  commit?: boolean;
+  message?: string;
+  dryRun?: boolean;
+}
+
+// Patches larger than this are uploaded in chunks of this size, under the
+// server's request size limit
+const PATCH_CHUNK_SIZE = 3 << 20;
+
+export interface GitTag {
+  name: string;
+  commit: string;
+  annotated: boolean;
+  message?: string;
+  created_at: string;
+}
+
+export interface ConflictBlob {
+  hash: string;
+  mode: string;
+  size: number;
+  content: string;
+  truncated?: boolean;
+  binary?: boolean;
+}
+
+export interface ConflictFile {
+  path: string;
+  status: string;
+  base?: ConflictBlob;
+  ours?: ConflictBlob;
+  theirs?: ConflictBlob;
+}
+
+export type ConflictResolution = "ours" | "theirs" | "content";
+
+export interface WorktreeConflicts {
+  worktree_id: string;
+  operation: "" | "merge" | "rebase" | "cherry-pick";
+  files: ConflictFile[];
+}
+
+export interface PullRequestInfo {
+  has_commits_ahead: boolean;
+  exists: boolean;
+  title?: string;
+  body?: string;
+  number?: number;
+  url?: string;
+}
+
+export interface ErrorHandler {
+  setErrorAlert: (alert: {
+    open: boolean;
+    title: string;
+    description: string;
+    worktreeName?: string;
+    conflictFiles?: string[];
+    lockfileCommands?: Record<string, string>;
+    operation?: string;
+  }) => void;
 }
 
 export interface AppSettings {
@@ -162,9 +1160,12 @@ export const gitApi = {
     }
   },
 
-  async fetchWorktrees(): Promise<Worktree[]> {
+  async fetchWorktrees(status?: StatusTier): Promise<Worktree[]> {
     try {
-      const response = await fetchWithTimeout("/v1/git/worktrees", {
+      const url = status
+        ? `/v1/git/worktrees?status=${status}`
+        : "/v1/git/worktrees";
+      const response = await fetchWithTimeout(url, {
         timeout: 30000,
       });
       if (response.ok) {
@@ -386,21 +1387,158 @@ export const gitApi = {
   // MUTATION OPERATIONS
   // These methods perform server-side operations and are used by the useGitApi hook.
 
-  async deleteWorktree(id: string): Promise<void> {
-    const response = await fetch(`/v1/git/worktrees/${id}`, {
+  async deleteWorktree(id: string, archive = false): Promise<void> {
+    const query = archive ? "?archive=true" : "";
+    const response = await fetch(`/v1/git/worktrees/${id}${query}`, {
       method: "DELETE",
     });
     if (!response.ok) {
-      throw new Error("Failed to delete worktree");
+      const errorData = await response.json().catch(() => ({}));
+      throw new Error(errorData.error || "Failed to delete worktree");
+    }
+  },
+
+  async listWorktreeArchives(): Promise<WorktreeArchive[]> {
+    const response = await fetch("/v1/git/archives");
+    if (!response.ok) {
+      throw new Error("Failed to list worktree archives");
+    }
+    return await response.json();
+  },
+
+  async restoreWorktreeArchive(archiveId: string): Promise<Worktree> {
+    const response = await fetch(
+      `/v1/git/archives/${encodeURIComponent(archiveId)}/restore`,
+      { method: "POST" },
+    );
+    if (!response.ok) {
+      const errorData = await response.json().catch(() => ({}));
+      throw new Error(errorData.error || "Failed to restore worktree archive");
+    }
+    return await response.json();
+  },
+
+  async recreateWorktree(
+    id: string,
+    keepUncommitted: boolean,
+  ): Promise<RecreateWorktreeResult> {
+    const response = await fetch(`/v1/git/worktrees/${id}/recreate`, {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({ keep_uncommitted: keepUncommitted }),
+    });
+    if (!response.ok) {
+      const errorData = await response.json().catch(() => ({}));
+      throw new Error(errorData.error || "Failed to recreate worktree");
+    }
+    return await response.json();
+  },
+
+  async validateWorktree(id: string, force = false): Promise<ValidationResult> {
+    const response = await fetch(
+      `/v1/git/worktrees/${id}/validate${force ? "?force=true" : ""}`,
+      { method: "POST" },
+    );
+    if (!response.ok) {
+      const errorData = await response.json().catch(() => ({}));
+      throw new Error(errorData.error || "Failed to validate worktree");
+    }
+    return await response.json();
+  },
+
+  async renameWorktree(id: string, name: string): Promise<Worktree> {
+    const response = await fetch(`/v1/git/worktrees/${id}/rename`, {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({ name }),
+    });
+    if (!response.ok) {
+      const errorData = await response.json().catch(() => ({}));
+      throw new Error(errorData.error || "Failed to rename worktree");
+    }
+    return await response.json();
+  },
+
+  async setWorktreeSourceBranch(
+    id: string,
+    sourceBranch: string,
+  ): Promise<Worktree> {
+    const response = await fetch(`/v1/git/worktrees/${id}/source-branch`, {
+      method: "PUT",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({ source_branch: sourceBranch }),
+    });
+    if (!response.ok) {
+      const errorData = await response.json().catch(() => ({}));
+      throw new Error(errorData.error || "Failed to change source branch");
+    }
+    return await response.json();
+  },
+
+  async exportWorktree(
+    id: string,
+    dest: string,
+    includeUntracked: boolean,
+  ): Promise<WorktreeExportResult> {
+    const response = await fetch(`/v1/git/worktrees/${id}/export`, {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({ dest, include_untracked: includeUntracked }),
+    });
+    if (!response.ok) {
+      const errorData = await response.json().catch(() => ({}));
+      throw new Error(errorData.error || "Failed to export worktree");
     }
+    return await response.json();
+  },
+
+  async getContextPack(
+    id: string,
+    maxTokens?: number,
+  ): Promise<WorktreeContextPack> {
+    const query = maxTokens ? `?max_tokens=${maxTokens}` : "";
+    const response = await fetch(
+      `/v1/git/worktrees/${id}/context-pack${query}`,
+    );
+    if (!response.ok) {
+      const errorData = await response.json().catch(() => ({}));
+      throw new Error(errorData.error || "Failed to generate context pack");
+    }
+    return await response.json();
+  },
+
+  async writeContextPack(
+    id: string,
+    maxTokens?: number,
+  ): Promise<WorktreeContextPack> {
+    const query = maxTokens ? `?max_tokens=${maxTokens}` : "";
+    const response = await fetch(
+      `/v1/git/worktrees/${id}/context-pack${query}`,
+      { method: "POST" },
+    );
+    if (!response.ok) {
+      const errorData = await response.json().catch(() => ({}));
+      throw new Error(errorData.error || "Failed to write context pack");
+    }
+    return await response.json();
   },
 
-  async syncWorktree(id: string, errorHandler: ErrorHandler): Promise<boolean> {
+  async syncWorktree(
+    id: string,
+    errorHandler: ErrorHandler,
+    strategy: SyncStrategy = "rebase",
+    base: SyncBase = "source",
+    acknowledgeRewrite = false,
+  ): Promise<boolean> {
     try {
       const response = await fetch(`/v1/git/worktrees/${id}/sync`, {
         method: "POST",
         headers: { "Content-Type": "application/json" },
-        body: JSON.stringify({ strategy: "rebase" }),
+        body: JSON.stringify({
+          strategy,
+          base,
+          acknowledge_rewrite: acknowledgeRewrite,
+        }),
       });
 
       if (response.ok) {
@@ -418,7 +1556,26 @@ export const gitApi = {
             description: "", // Will be set by the enhanced handler
             worktreeName,
             conflictFiles,
-            operation: "rebase",
+            lockfileCommands: errorData.lockfile_commands,
+            operation: strategy,
+          });
+          return false;
+        }
+
+        if (errorData.error === "non_fast_forward") {
+          errorHandler.setErrorAlert({
+            open: true,
+            title: `Cannot Fast-Forward ${errorData.worktree_name}`,
+            description: `The worktree has ${errorData.ahead} local commit(s) not in ${errorData.source_ref} and is ${errorData.behind} commit(s) behind. Sync with rebase to replay your commits on top instead.`,
+          });
+          return false;
+        }
+
+        if (errorData.error === "source_rewritten") {
+          errorHandler.setErrorAlert({
+            open: true,
+            title: `${errorData.rewrite.branch} Was Force-Pushed`,
+            description: `The source branch of ${errorData.worktree_name} was rewritten, so a normal sync would replay the old commits. Sync again acknowledging the rewrite to rebase only your commits onto the new tip.`,
           });
           return false;
         }
@@ -473,6 +1630,7 @@ export const gitApi = {
             description: "", // Will be set by the enhanced handler
             worktreeName,
             conflictFiles,
+            lockfileCommands: errorData.lockfile_commands,
             operation: "merge",
           });
           return false;
@@ -496,6 +1654,92 @@ export const gitApi = {
     }
   },
 
+  async prepareMerge(
+    id: string,
+    squash: boolean,
+    errorHandler: ErrorHandler,
+  ): Promise<MergePreview | null> {
+    try {
+      const response = await fetch(`/v1/git/worktrees/${id}/merge/prepare`, {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify({ mode: squash ? "squash" : "merge" }),
+      });
+      if (response.ok) {
+        return await response.json();
+      }
+      const errorData = await response.json();
+      errorHandler.setErrorAlert({
+        open: true,
+        title: "Merge Failed",
+        description: `Failed to prepare merge: ${errorData.error}`,
+      });
+      return null;
+    } catch (error) {
+      console.error("Failed to prepare merge:", error);
+      errorHandler.setErrorAlert({
+        open: true,
+        title: "Merge Failed",
+        description: `Failed to prepare merge: ${error}`,
+      });
+      return null;
+    }
+  },
+
+  async completeMerge(
+    preview: MergePreview,
+    worktreeName: string,
+    message: string,
+    errorHandler: ErrorHandler,
+    autoCleanup = true,
+  ): Promise<boolean> {
+    try {
+      const url = `/v1/git/merges/${preview.token}/complete?auto_cleanup=${autoCleanup}`;
+      const response = await fetch(url, {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify({ message }),
+      });
+
+      if (response.ok) {
+        const mergeType =
+          preview.mode === "squash" ? "squash merged" : "merged";
+        toast.success(
+          `Successfully ${mergeType} ${worktreeName} to ${preview.target_branch}`,
+        );
+        return true;
+      }
+      const errorData = await response.json();
+      if (errorData.error === "merge_conflict") {
+        errorHandler.setErrorAlert({
+          open: true,
+          title: `Merge Conflict in ${worktreeName}`,
+          description: "", // Will be set by the enhanced handler
+          worktreeName,
+          conflictFiles: errorData.conflict_files || [],
+          lockfileCommands: errorData.lockfile_commands,
+          operation: "merge",
+        });
+        return false;
+      }
+
+      errorHandler.setErrorAlert({
+        open: true,
+        title: "Merge Failed",
+        description: `Failed to merge worktree: ${errorData.error}`,
+      });
+      return false;
+    } catch (error) {
+      console.error("Failed to complete merge:", error);
+      errorHandler.setErrorAlert({
+        open: true,
+        title: "Merge Failed",
+        description: `Failed to merge worktree: ${error}`,
+      });
+      return false;
+    }
+  },
+
   async createWorktreePreview(
     id: string,
     errorHandler: ErrorHandler,
@@ -545,19 +1789,31 @@ export const gitApi = {
 
   async fetchWorktreeDiffStats(
     worktreeId: string,
+    ref?: string,
   ): Promise<WorktreeDiffStats | null> {
     try {
-      const response = await fetch(`/v1/git/worktrees/${worktreeId}/diff`);
+      const query = ref ? `?ref=${encodeURIComponent(ref)}` : "";
+      const response = await fetch(
+        `/v1/git/worktrees/${worktreeId}/diff${query}`,
+      );
       if (response.ok) {
         const data = await response.json();
         return {
           summary: data?.summary || "",
           file_diffs: data?.file_diffs || [],
           total_files: data?.total_files || 0,
+          total_additions: data?.total_additions || 0,
+          total_deletions: data?.total_deletions || 0,
+          authored_stats: data?.authored_stats || {
+            files: 0,
+            additions: 0,
+            deletions: 0,
+          },
           worktree_id: data?.worktree_id || "",
           worktree_name: data?.worktree_name || "",
           source_branch: data?.source_branch || "",
           fork_commit: data?.fork_commit || "",
+          base_ref: data?.base_ref,
         };
       }
       return null;
@@ -567,6 +1823,72 @@ export const gitApi = {
     }
   },
 
+  async fetchWorktreeDiffSummary(
+    worktreeId: string,
+    limit?: number,
+    offset?: number,
+    expandGenerated?: boolean,
+  ): Promise<WorktreeDiffSummary | null> {
+    try {
+      const params = new URLSearchParams();
+      if (limit) params.set("limit", String(limit));
+      if (offset) params.set("offset", String(offset));
+      if (expandGenerated) params.set("expand_generated", "true");
+      const response = await fetch(
+        `/v1/git/worktrees/${worktreeId}/diff/summary?${params}`,
+      );
+      if (response.ok) {
+        return await response.json();
+      }
+      return null;
+    } catch (error) {
+      console.error(`Failed to fetch diff summary for ${worktreeId}:`, error);
+      return null;
+    }
+  },
+
+  async fetchWorktreeFileDiff(
+    worktreeId: string,
+    path: string,
+  ): Promise<FileDiff | null> {
+    try {
+      const response = await fetch(
+        `/v1/git/worktrees/${worktreeId}/diff/file?path=${encodeURIComponent(path)}`,
+      );
+      if (response.ok) {
+        return await response.json();
+      }
+      return null;
+    } catch (error) {
+      console.error(`Failed to fetch diff of ${path} in ${worktreeId}:`, error);
+      return null;
+    }
+  },
+
+  async fetchWorktreeCommits(
+    worktreeId: string,
+    limit?: number,
+    offset?: number,
+    stats = false,
+  ): Promise<WorktreeCommits | null> {
+    try {
+      const params = new URLSearchParams();
+      if (limit) params.set("limit", String(limit));
+      if (offset) params.set("offset", String(offset));
+      if (stats) params.set("stats", "true");
+      const response = await fetch(
+        `/v1/git/worktrees/${worktreeId}/commits?${params}`,
+      );
+      if (response.ok) {
+        return await response.json();
+      }
+      return null;
+    } catch (error) {
+      console.error(`Failed to fetch commits of ${worktreeId}:`, error);
+      return null;
+    }
+  },
+
   // NOTE: Conflict checking and batch diff stats removed
   // Conflicts are now tracked via SSE events in worktree.has_conflicts
   // Individual diff stats still available via fetchWorktreeDiffStats if needed
@@ -577,15 +1899,22 @@ export const gitApi = {
     title: string,
     body: string,
     errorHandler: ErrorHandler,
+    urgent = false,
   ): Promise<boolean> {
     try {
       const response = await fetch(`/v1/git/worktrees/${worktreeId}/pr`, {
         method: "POST",
         headers: { "Content-Type": "application/json" },
-        body: JSON.stringify({ title, body }),
+        body: JSON.stringify({ title, body, urgent }),
       });
 
-      if (response.ok) {
+      if (response.status === 202) {
+        const queued: QueuedGitHubOperation = await response.json();
+        toast.info(
+          `GitHub rate limit is low, pull request will be created at ${new Date(queued.run_at).toLocaleTimeString()}`,
+        );
+        return true;
+      } else if (response.ok) {
         const prData = await response.json();
         toast.success(
           `Pull request created! PR #${prData.number}: ${prData.title}`,
@@ -616,15 +1945,22 @@ export const gitApi = {
     title: string,
     body: string,
     errorHandler: ErrorHandler,
+    urgent = false,
   ): Promise<boolean> {
     try {
       const response = await fetch(`/v1/git/worktrees/${worktreeId}/pr`, {
         method: "PUT",
         headers: { "Content-Type": "application/json" },
-        body: JSON.stringify({ title, body }),
+        body: JSON.stringify({ title, body, urgent }),
       });
 
-      if (response.ok) {
+      if (response.status === 202) {
+        const queued: QueuedGitHubOperation = await response.json();
+        toast.info(
+          `GitHub rate limit is low, pull request will be updated at ${new Date(queued.run_at).toLocaleTimeString()}`,
+        );
+        return true;
+      } else if (response.ok) {
         const prData = await response.json();
         toast.success(
           `Pull request updated! PR #${prData.number}: ${prData.title}`,
@@ -650,6 +1986,1243 @@ export const gitApi = {
     }
   },
 
+  async getWorktreesSummary(): Promise<WorktreesSummary | null> {
+    try {
+      const response = await fetch("/v1/git/worktrees/summary");
+      if (response.ok) {
+        return await response.json();
+      }
+      return null;
+    } catch (error) {
+      console.error("Failed to get worktrees summary:", error);
+      return null;
+    }
+  },
+
+  async getRemoteConfig(repoId: string): Promise<RemoteConfigReport | null> {
+    try {
+      const response = await fetch(
+        `/v1/git/repositories/${encodeURIComponent(repoId)}/remote-config`,
+      );
+      if (response.ok) {
+        return await response.json();
+      }
+      return null;
+    } catch (error) {
+      console.error("Failed to check remote config:", error);
+      return null;
+    }
+  },
+
+  async getRepositoryActivity(
+    repoId: string,
+    range: { since?: string; until?: string } = {},
+  ): Promise<RepositoryActivity | null> {
+    try {
+      const params = new URLSearchParams();
+      if (range.since) params.set("since", range.since);
+      if (range.until) params.set("until", range.until);
+      const response = await fetch(
+        `/v1/git/repositories/${encodeURIComponent(repoId)}/activity?${params}`,
+      );
+      if (response.ok) {
+        return await response.json();
+      }
+      return null;
+    } catch (error) {
+      console.error("Failed to get repository activity:", error);
+      return null;
+    }
+  },
+
+  async setIssueAutomation(
+    repoId: string,
+    settings: { enabled: boolean; label?: string; branch?: string },
+  ): Promise<IssueAutomation> {
+    const response = await fetch(
+      `/v1/git/repositories/${encodeURIComponent(repoId)}/issue-automation`,
+      {
+        method: "PUT",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify(settings),
+      },
+    );
+    if (!response.ok) {
+      const errorData = await response.json().catch(() => ({}));
+      throw new Error(
+        errorData.error || "Failed to configure issue automation",
+      );
+    }
+    return await response.json();
+  },
+
+  async setPullRequestTemplateSettings(
+    repoId: string,
+    settings: PullRequestTemplateSettings,
+  ): Promise<PullRequestTemplateSettings> {
+    const response = await fetch(
+      `/v1/git/repositories/${encodeURIComponent(repoId)}/pr-template`,
+      {
+        method: "PUT",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify(settings),
+      },
+    );
+    if (!response.ok) {
+      const errorData = await response.json().catch(() => ({}));
+      throw new Error(
+        errorData.error || "Failed to configure pull request template",
+      );
+    }
+    return await response.json();
+  },
+
+  async previewPullRequestBody(
+    worktreeId: string,
+    body: string,
+  ): Promise<PullRequestBodyPreview> {
+    const response = await fetch(`/v1/git/worktrees/${worktreeId}/pr/preview`, {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({ body }),
+    });
+    if (!response.ok) {
+      const errorData = await response.json().catch(() => ({}));
+      throw new Error(errorData.error || "Failed to preview pull request");
+    }
+    return await response.json();
+  },
+
+  async pollIssueAutomation(repoId: string): Promise<AutomatedIssue[]> {
+    const response = await fetch(
+      `/v1/git/repositories/${encodeURIComponent(repoId)}/issue-automation/poll`,
+      { method: "POST" },
+    );
+    if (!response.ok) {
+      const errorData = await response.json().catch(() => ({}));
+      throw new Error(errorData.error || "Failed to poll labeled issues");
+    }
+    return await response.json();
+  },
+
+  async deleteRepository(
+    repoId: string,
+    force: boolean,
+    errorHandler: ErrorHandler,
+  ): Promise<DeleteRepositoryResult | null> {
+    try {
+      const response = await fetch(
+        `/v1/git/repositories/${encodeURIComponent(repoId)}?force=${force}`,
+        { method: "DELETE" },
+      );
+      if (response.ok) {
+        toast.success(`Deleted ${repoId}`);
+        return { deleted: true };
+      }
+      const errorData = await response.json();
+      if (response.status === 409 && errorData.error === "repository_in_use") {
+        return { deleted: false, blocking: errorData.worktrees ?? [] };
+      }
+      errorHandler.setErrorAlert({
+        open: true,
+        title: "Delete Repository Failed",
+        description: `Failed to delete ${repoId}: ${errorData.error || "Unknown error"}`,
+      });
+      return null;
+    } catch (error) {
+      console.error(`Failed to delete repository ${repoId}:`, error);
+      errorHandler.setErrorAlert({
+        open: true,
+        title: "Delete Repository Failed",
+        description: `Failed to delete ${repoId}: ${error}`,
+      });
+      return null;
+    }
+  },
+
+  async getMergeLedger(
+    repoId: string,
+    limit?: number,
+  ): Promise<MergeLedgerEntry[]> {
+    try {
+      const params = new URLSearchParams();
+      if (limit) params.set("limit", String(limit));
+      const response = await fetch(
+        `/v1/git/repositories/${encodeURIComponent(repoId)}/merges?${params}`,
+      );
+      if (response.ok) {
+        return await response.json();
+      }
+      return [];
+    } catch (error) {
+      console.error("Failed to get merge ledger:", error);
+      return [];
+    }
+  },
+
+  // Handing off suspends checkpoints, branch renames and commit syncing until
+  // the worktree is handed back
+  async setWorktreeHandoff(
+    worktreeId: string,
+    handedOff: boolean,
+    preview = false,
+  ): Promise<Worktree> {
+    const response = await fetch(`/v1/git/worktrees/${worktreeId}/handoff`, {
+      method: "PUT",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({ handed_off: handedOff, preview }),
+    });
+    if (!response.ok) {
+      const errorData = await response.json().catch(() => ({}));
+      throw new Error(errorData.error || "Failed to update handoff");
+    }
+    return await response.json();
+  },
+
+  // Checkpoints skip commit hooks unless enabled; failing hooks are reported
+  // in last_checkpoint_error and the checkpoint is committed without them
+  async setCheckpointHooks(
+    worktreeId: string,
+    enabled: boolean,
+  ): Promise<Worktree> {
+    const response = await fetch(
+      `/v1/git/worktrees/${worktreeId}/checkpoint-hooks`,
+      {
+        method: "PUT",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify({ enabled }),
+      },
+    );
+    if (!response.ok) {
+      const errorData = await response.json().catch(() => ({}));
+      throw new Error(errorData.error || "Failed to update checkpoint hooks");
+    }
+    return await response.json();
+  },
+
+  // Environment variables whose values are masked as their first 4
+  // characters followed by [REDACTED], in addition to sensitive names
+  async setWorktreeSecretKeys(
+    worktreeId: string,
+    keys: string[],
+  ): Promise<Worktree> {
+    const response = await fetch(
+      `/v1/git/worktrees/${worktreeId}/secret-keys`,
+      {
+        method: "PUT",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify({ keys }),
+      },
+    );
+    if (!response.ok) {
+      const errorData = await response.json().catch(() => ({}));
+      throw new Error(errorData.error || "Failed to update secret keys");
+    }
+    return await response.json();
+  },
+
+  async runWorktreeSetup(worktreeId: string): Promise<Worktree> {
+    const response = await fetch(`/v1/git/worktrees/${worktreeId}/setup`, {
+      method: "POST",
+    });
+    if (!response.ok) {
+      const errorData = await response.json().catch(() => ({}));
+      throw new Error(errorData.error || "Failed to run setup");
+    }
+    return await response.json();
+  },
+
+  async getWorktreeSetupLog(worktreeId: string): Promise<string> {
+    const response = await fetch(`/v1/git/worktrees/${worktreeId}/setup/log`);
+    if (!response.ok) {
+      const errorData = await response.json().catch(() => ({}));
+      throw new Error(errorData.error || "Failed to get setup log");
+    }
+    return await response.text();
+  },
+
+  async getDebugTimeline(
+    worktreeId: string,
+    since?: string,
+  ): Promise<DebugTimelineEntry[]> {
+    try {
+      const params = new URLSearchParams();
+      if (since) params.set("since", since);
+      const response = await fetch(
+        `/v1/git/worktrees/${worktreeId}/debug/timeline?${params}`,
+      );
+      if (response.ok) {
+        return await response.json();
+      }
+      return [];
+    } catch (error) {
+      console.error(`Failed to fetch debug timeline of ${worktreeId}:`, error);
+      return [];
+    }
+  },
+
+  async listTags(repoId: string): Promise<GitTag[]> {
+    try {
+      const response = await fetch(
+        `/v1/git/repositories/${encodeURIComponent(repoId)}/tags`,
+      );
+      if (response.ok) {
+        return await response.json();
+      }
+      return [];
+    } catch (error) {
+      console.error("Failed to list tags:", error);
+      return [];
+    }
+  },
+
+  async createTag(
+    worktreeId: string,
+    name: string,
+    message = "",
+    annotated = false,
+  ): Promise<GitTag> {
+    const response = await fetch(`/v1/git/worktrees/${worktreeId}/tags`, {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({ name, message, annotated }),
+    });
+    if (!response.ok) {
+      const errorData = await response.json().catch(() => ({}));
+      throw new Error(errorData.error || `Failed to create tag ${name}`);
+    }
+    return await response.json();
+  },
+
+  async pushTag(worktreeId: string, name: string): Promise<void> {
+    const response = await fetch(`/v1/git/worktrees/${worktreeId}/tags/push`, {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({ name }),
+    });
+    if (!response.ok) {
+      const errorData = await response.json().catch(() => ({}));
+      throw new Error(errorData.error || `Failed to push tag ${name}`);
+    }
+  },
+
+  async deleteTag(repoId: string, name: string): Promise<void> {
+    const response = await fetch(
+      `/v1/git/repositories/${encodeURIComponent(repoId)}/tags/${encodeURIComponent(name)}`,
+      { method: "DELETE" },
+    );
+    if (!response.ok) {
+      const errorData = await response.json().catch(() => ({}));
+      throw new Error(errorData.error || `Failed to delete tag ${name}`);
+    }
+  },
+
+  async listOperations(): Promise<Operation[]> {
+    try {
+      const response = await fetch("/v1/git/operations");
+      if (response.ok) {
+        return await response.json();
+      }
+      return [];
+    } catch (error) {
+      console.error("Failed to list operations:", error);
+      return [];
+    }
+  },
+
+  async getOperation(id: string): Promise<Operation | null> {
+    try {
+      const response = await fetch(
+        `/v1/git/operations/${encodeURIComponent(id)}`,
+      );
+      if (response.ok) {
+        return await response.json();
+      }
+      return null;
+    } catch (error) {
+      console.error("Failed to get operation:", error);
+      return null;
+    }
+  },
+
+  async getMergeByCommit(
+    repoId: string,
+    commit: string,
+  ): Promise<MergeLedgerEntry | null> {
+    try {
+      const response = await fetch(
+        `/v1/git/repositories/${encodeURIComponent(repoId)}/merges/${encodeURIComponent(commit)}`,
+      );
+      if (response.ok) {
+        return await response.json();
+      }
+      return null;
+    } catch (error) {
+      console.error("Failed to look up merge of commit:", error);
+      return null;
+    }
+  },
+
+  async repairRemoteConfig(
+    target: { repoId: string } | { worktreeId: string },
+    errorHandler: ErrorHandler,
+  ): Promise<RemoteConfigReport | null> {
+    const url =
+      "repoId" in target
+        ? `/v1/git/repositories/${encodeURIComponent(target.repoId)}/remote-config/repair`
+        : `/v1/git/worktrees/${target.worktreeId}/remote-config/repair`;
+    try {
+      const response = await fetch(url, { method: "POST" });
+      if (response.ok) {
+        const report: RemoteConfigReport = await response.json();
+        toast.success(
+          report.repaired?.length
+            ? `Repaired ${report.repaired.join(", ")}`
+            : "Git remote config is already up to date",
+        );
+        return report;
+      }
+      const errorData = await response.json();
+      errorHandler.setErrorAlert({
+        open: true,
+        title: "Remote Config Repair Failed",
+        description: `Failed to repair remote config: ${errorData.error || "Unknown error"}`,
+      });
+      return null;
+    } catch (error) {
+      console.error("Failed to repair remote config:", error);
+      errorHandler.setErrorAlert({
+        open: true,
+        title: "Remote Config Repair Failed",
+        description: `Failed to repair remote config: ${error}`,
+      });
+      return null;
+    }
+  },
+
+  async previewMergedWorktreeCleanup(
+    errorHandler: ErrorHandler,
+  ): Promise<MergedWorktree[] | null> {
+    try {
+      const response = await fetch("/v1/git/worktrees/cleanup?dry_run=true", {
+        method: "POST",
+      });
+      if (response.ok) {
+        const data: { worktrees: MergedWorktree[] } = await response.json();
+        return data.worktrees;
+      }
+      const errorData = await response.json();
+      errorHandler.setErrorAlert({
+        open: true,
+        title: "Cleanup Preview Failed",
+        description: `Failed to find merged worktrees: ${errorData.error || "Unknown error"}`,
+      });
+      return null;
+    } catch (error) {
+      console.error("Failed to find merged worktrees:", error);
+      errorHandler.setErrorAlert({
+        open: true,
+        title: "Cleanup Preview Failed",
+        description: `Failed to find merged worktrees: ${error}`,
+      });
+      return null;
+    }
+  },
+
+  async cleanupMergedWorktrees(
+    worktreeIds: string[],
+    errorHandler: ErrorHandler,
+  ): Promise<string[] | null> {
+    try {
+      const response = await fetch("/v1/git/worktrees/cleanup", {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify({ worktree_ids: worktreeIds }),
+      });
+      const data = await response.json();
+      if (response.ok) {
+        toast.success(`Removed ${data.cleaned_count} merged worktrees`);
+        return data.cleaned_names ?? [];
+      }
+      errorHandler.setErrorAlert({
+        open: true,
+        title: "Worktree Cleanup Failed",
+        description: `Removed ${data.cleaned_count ?? 0} worktrees: ${data.error || "Unknown error"}`,
+      });
+      return data.cleaned_names ?? null;
+    } catch (error) {
+      console.error("Failed to clean up merged worktrees:", error);
+      errorHandler.setErrorAlert({
+        open: true,
+        title: "Worktree Cleanup Failed",
+        description: `Failed to clean up merged worktrees: ${error}`,
+      });
+      return null;
+    }
+  },
+
+  async reconcileWorktrees(
+    missing?: "flag" | "remove",
+  ): Promise<WorktreeReconciliation> {
+    const query = missing ? `?missing=${missing}` : "";
+    const response = await fetch(`/v1/git/worktrees/reconcile${query}`, {
+      method: "POST",
+    });
+    if (!response.ok) {
+      const errorData = await response.json().catch(() => ({}));
+      throw new Error(errorData.error || "Failed to reconcile worktrees");
+    }
+    return await response.json();
+  },
+
+  async getGitHubRateLimit(refresh = false): Promise<GitHubRateLimitStatus> {
+    const query = refresh ? "?refresh=true" : "";
+    const response = await fetch(`/v1/git/github/rate-limit${query}`);
+    if (!response.ok) {
+      const errorData = await response.json().catch(() => ({}));
+      throw new Error(errorData.error || "Failed to get GitHub rate limit");
+    }
+    return await response.json();
+  },
+
+  async createPullRequestsBulk(
+    request: BulkPullRequestRequest,
+    errorHandler: ErrorHandler,
+  ): Promise<BulkPullRequestReport | null> {
+    try {
+      const response = await fetch("/v1/git/pull-requests/bulk", {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify(request),
+      });
+      if (response.ok) {
+        const report: BulkPullRequestReport = await response.json();
+        const failed = report.results.filter(
+          (result) => result.outcome === "failed",
+        ).length;
+        const summary = `Opened ${report.created.length} of ${report.results.length} pull requests`;
+        if (failed) {
+          toast.warning(`${summary}, ${failed} failed`);
+        } else {
+          toast.success(summary);
+        }
+        return report;
+      }
+      const errorData = await response.json();
+      errorHandler.setErrorAlert({
+        open: true,
+        title: "Bulk Pull Requests Failed",
+        description: `Failed to create pull requests: ${errorData.error || "Unknown error"}`,
+      });
+      return null;
+    } catch (error) {
+      console.error("Failed to create pull requests:", error);
+      errorHandler.setErrorAlert({
+        open: true,
+        title: "Bulk Pull Requests Failed",
+        description: `Failed to create pull requests: ${error}`,
+      });
+      return null;
+    }
+  },
+
+  async createWorktreeGroup(
+    name: string,
+    members: { repo_id: string; branch?: string }[],
+    errorHandler: ErrorHandler,
+  ): Promise<WorktreeGroup | null> {
+    try {
+      const response = await fetch("/v1/git/worktree-groups", {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify({ name, members }),
+      });
+      if (response.ok) {
+        const group: WorktreeGroup = await response.json();
+        toast.success(
+          `Created ${group.members.length} worktrees for ${group.name}`,
+        );
+        return group;
+      }
+      const errorData = await response.json();
+      errorHandler.setErrorAlert({
+        open: true,
+        title: "Worktree Group Failed",
+        description: `Failed to create worktree group: ${errorData.error || "Unknown error"}`,
+      });
+      return null;
+    } catch (error) {
+      console.error("Failed to create worktree group:", error);
+      errorHandler.setErrorAlert({
+        open: true,
+        title: "Worktree Group Failed",
+        description: `Failed to create worktree group: ${error}`,
+      });
+      return null;
+    }
+  },
+
+  async listWorktreeGroups(): Promise<WorktreeGroup[]> {
+    try {
+      const response = await fetch("/v1/git/worktree-groups");
+      if (response.ok) {
+        return await response.json();
+      }
+      return [];
+    } catch (error) {
+      console.error("Failed to fetch worktree groups:", error);
+      return [];
+    }
+  },
+
+  async getWorktreeGroup(groupId: string): Promise<WorktreeGroupStatus | null> {
+    try {
+      const response = await fetch(`/v1/git/worktree-groups/${groupId}`);
+      if (response.ok) {
+        return await response.json();
+      }
+      return null;
+    } catch (error) {
+      console.error(`Failed to fetch worktree group ${groupId}:`, error);
+      return null;
+    }
+  },
+
+  async runWorktreeGroupAction(
+    groupId: string,
+    action: "sync" | "pr" | "merge",
+    options: { strategy?: string; draft?: boolean; squash?: boolean },
+    errorHandler: ErrorHandler,
+  ): Promise<WorktreeGroupReport | null> {
+    try {
+      const response = await fetch(
+        `/v1/git/worktree-groups/${groupId}/${action}`,
+        {
+          method: "POST",
+          headers: { "Content-Type": "application/json" },
+          body: JSON.stringify(options),
+        },
+      );
+      if (response.ok) {
+        const report: WorktreeGroupReport = await response.json();
+        const failed = report.results.filter(
+          (result) => result.outcome === "failed",
+        ).length;
+        if (failed) {
+          toast.warning(`${failed} of ${report.results.length} members failed`);
+        } else {
+          toast.success(`Updated ${report.results.length} worktrees`);
+        }
+        return report;
+      }
+      const errorData = await response.json();
+      errorHandler.setErrorAlert({
+        open: true,
+        title: "Worktree Group Failed",
+        description: `Failed to ${action} worktree group: ${errorData.error || "Unknown error"}`,
+      });
+      return null;
+    } catch (error) {
+      console.error(`Failed to ${action} worktree group:`, error);
+      errorHandler.setErrorAlert({
+        open: true,
+        title: "Worktree Group Failed",
+        description: `Failed to ${action} worktree group: ${error}`,
+      });
+      return null;
+    }
+  },
+
+  async deleteWorktreeGroup(
+    groupId: string,
+    deleteMembers: boolean,
+  ): Promise<WorktreeGroupReport | null> {
+    try {
+      const response = await fetch(
+        `/v1/git/worktree-groups/${groupId}?delete_members=${deleteMembers}`,
+        { method: "DELETE" },
+      );
+      if (response.ok) {
+        return await response.json();
+      }
+      return null;
+    } catch (error) {
+      console.error(`Failed to delete worktree group ${groupId}:`, error);
+      return null;
+    }
+  },
+
+  async cleanupRepository(
+    repoId: string,
+    errorHandler: ErrorHandler,
+    dryRun = false,
+  ): Promise<CleanupReport | null> {
+    try {
+      const response = await fetch(
+        `/v1/git/repositories/${encodeURIComponent(repoId)}/cleanup?dry_run=${dryRun}`,
+        { method: "POST" },
+      );
+      if (response.ok) {
+        const report: CleanupReport = await response.json();
+        const removed =
+          report.branches.length +
+          report.preview_branches.length +
+          report.remote_branches.length +
+          report.refs.length +
+          report.config_mappings.length;
+        const summary = `${report.dry_run ? "Would remove" : "Removed"} ${report.branches.length} branches, ${report.preview_branches.length} preview branches, ${report.remote_branches.length} remote branches, ${report.refs.length} refs and ${report.config_mappings.length} config mappings`;
+        if (report.errors.length) {
+          toast.warning(`${summary}, ${report.errors.length} failed`);
+        } else if (removed) {
+          toast.success(summary);
+        } else {
+          toast.success("Nothing to clean up");
+        }
+        return report;
+      }
+      const errorData = await response.json();
+      errorHandler.setErrorAlert({
+        open: true,
+        title: "Repository Cleanup Failed",
+        description: `Failed to clean up repository: ${errorData.error || "Unknown error"}`,
+      });
+      return null;
+    } catch (error) {
+      console.error("Failed to clean up repository:", error);
+      errorHandler.setErrorAlert({
+        open: true,
+        title: "Repository Cleanup Failed",
+        description: `Failed to clean up repository: ${error}`,
+      });
+      return null;
+    }
+  },
+
+  async repairCredentials(
+    errorHandler: ErrorHandler,
+  ): Promise<CredentialHelperReport | null> {
+    try {
+      const response = await fetch("/v1/git/credentials/repair", {
+        method: "POST",
+      });
+      if (response.ok) {
+        const report: CredentialHelperReport = await response.json();
+        if (report.verified) {
+          toast.success(
+            report.changed?.length
+              ? `Repaired ${report.changed.join(", ")}`
+              : "Git credentials are already set up",
+          );
+        } else {
+          toast.error("Git credentials are still not working");
+        }
+        return report;
+      }
+      const errorData = await response.json();
+      errorHandler.setErrorAlert({
+        open: true,
+        title: "Credential Repair Failed",
+        description: `Failed to repair git credentials: ${errorData.error || "Unknown error"}`,
+      });
+      return null;
+    } catch (error) {
+      console.error("Failed to repair git credentials:", error);
+      errorHandler.setErrorAlert({
+        open: true,
+        title: "Credential Repair Failed",
+        description: `Failed to repair git credentials: ${error}`,
+      });
+      return null;
+    }
+  },
+
+  async detectToolchains(worktreeId: string): Promise<Toolchain[]> {
+    const response = await fetch(
+      `/v1/git/worktrees/${worktreeId}/toolchains/detect`,
+      { method: "POST" },
+    );
+    if (!response.ok) {
+      const errorData = await response.json().catch(() => ({}));
+      throw new Error(errorData.error || "Failed to detect toolchains");
+    }
+    return await response.json();
+  },
+
+  async getDependencyCaches(): Promise<DependencyCacheReport | null> {
+    try {
+      const response = await fetch("/v1/git/caches");
+      if (response.ok) {
+        return await response.json();
+      }
+      return null;
+    } catch (error) {
+      console.error("Failed to get package caches:", error);
+      return null;
+    }
+  },
+
+  async trimDependencyCaches(): Promise<DependencyCacheReport> {
+    const response = await fetch("/v1/git/caches/trim", { method: "POST" });
+    if (!response.ok) {
+      const errorData = await response.json().catch(() => ({}));
+      throw new Error(errorData.error || "Failed to trim package caches");
+    }
+    const report: DependencyCacheReport = await response.json();
+    toast.success(
+      report.removed?.length
+        ? `Freed ${Math.round((report.freed_kb ?? 0) / 1024)} MiB of package caches`
+        : "Package caches are within their cap",
+    );
+    return report;
+  },
+
+  async applyIgnoreSuggestion(
+    worktreeId: string,
+    pattern: string,
+    target: IgnoreTarget = "catnipignore",
+    squash = false,
+  ): Promise<Worktree & { backup?: OperationBackup }> {
+    const response = await fetch(
+      `/v1/git/worktrees/${worktreeId}/ignore-suggestions/apply`,
+      {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify({ pattern, target, squash }),
+      },
+    );
+    if (!response.ok) {
+      const errorData = await response.json().catch(() => ({}));
+      throw new Error(errorData.error || "Failed to apply ignore suggestion");
+    }
+    return await response.json();
+  },
+
+  async dismissIgnoreSuggestion(
+    worktreeId: string,
+    pattern: string,
+  ): Promise<void> {
+    const response = await fetch(
+      `/v1/git/worktrees/${worktreeId}/ignore-suggestions/dismiss`,
+      {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify({ pattern }),
+      },
+    );
+    if (!response.ok) {
+      const errorData = await response.json().catch(() => ({}));
+      throw new Error(errorData.error || "Failed to dismiss ignore suggestion");
+    }
+  },
+
+  async listOperationBackups(worktreeId: string): Promise<OperationBackup[]> {
+    const response = await fetch(`/v1/git/worktrees/${worktreeId}/backups`);
+    if (!response.ok) {
+      const errorData = await response.json().catch(() => ({}));
+      throw new Error(errorData.error || "Failed to list backups");
+    }
+    return await response.json();
+  },
+
+  async restoreOperationBackup(
+    worktreeId: string,
+    backupId: string,
+  ): Promise<OperationBackup> {
+    const response = await fetch(
+      `/v1/git/worktrees/${worktreeId}/backups/${encodeURIComponent(backupId)}/restore`,
+      { method: "POST" },
+    );
+    if (!response.ok) {
+      const errorData = await response.json().catch(() => ({}));
+      throw new Error(errorData.error || "Failed to restore backup");
+    }
+    const data = await response.json();
+    return data.backup;
+  },
+
+  async fetchWorktreeConflicts(worktreeId: string): Promise<WorktreeConflicts> {
+    const response = await fetch(`/v1/git/worktrees/${worktreeId}/conflicts`);
+    if (!response.ok) {
+      const errorData = await response.json().catch(() => ({}));
+      throw new Error(errorData.error || "Failed to load conflicts");
+    }
+    return await response.json();
+  },
+
+  async resolveWorktreeConflict(
+    worktreeId: string,
+    file: string,
+    resolution: ConflictResolution,
+    content?: string,
+  ): Promise<WorktreeConflicts> {
+    const response = await fetch(
+      `/v1/git/worktrees/${worktreeId}/conflicts/resolve`,
+      {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify({ file, resolution, content }),
+      },
+    );
+    if (!response.ok) {
+      const errorData = await response.json().catch(() => ({}));
+      throw new Error(errorData.error || `Failed to resolve ${file}`);
+    }
+    return await response.json();
+  },
+
+  async continueSync(worktreeId: string): Promise<void> {
+    const response = await fetch(
+      `/v1/git/worktrees/${worktreeId}/sync/continue`,
+      { method: "POST" },
+    );
+    if (!response.ok) {
+      const errorData = await response.json().catch(() => ({}));
+      // A rebase stopping at the next conflicting commit has new conflicts to resolve
+      throw new Error(
+        errorData.error === "merge_conflict"
+          ? errorData.message
+          : errorData.error || "Failed to continue sync",
+      );
+    }
+  },
+
+  async abortSync(worktreeId: string): Promise<void> {
+    const response = await fetch(`/v1/git/worktrees/${worktreeId}/sync/abort`, {
+      method: "POST",
+    });
+    if (!response.ok) {
+      const errorData = await response.json().catch(() => ({}));
+      throw new Error(errorData.error || "Failed to abort sync");
+    }
+  },
+
+  // Commits are resolved against the source worktree's branch and applied in
+  // branch order. Conflicts leave the cherry-pick in progress, resolved through
+  // the conflicts endpoints and continueSync, or abandoned with abortCherryPick.
+  async cherryPickCommits(
+    worktreeId: string,
+    sourceWorktreeId: string,
+    commits: string[],
+  ): Promise<CherryPickResult> {
+    const response = await fetch(
+      `/v1/git/worktrees/${worktreeId}/cherry-pick`,
+      {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify({ source_worktree_id: sourceWorktreeId, commits }),
+      },
+    );
+    if (!response.ok) {
+      const errorData = await response.json().catch(() => ({}));
+      throw new Error(
+        errorData.error === "merge_conflict"
+          ? errorData.message
+          : errorData.error || "Failed to cherry-pick commits",
+      );
+    }
+    return await response.json();
+  },
+
+  async abortCherryPick(worktreeId: string): Promise<void> {
+    const response = await fetch(
+      `/v1/git/worktrees/${worktreeId}/cherry-pick/abort`,
+      { method: "POST" },
+    );
+    if (!response.ok) {
+      const errorData = await response.json().catch(() => ({}));
+      throw new Error(errorData.error || "Failed to abort cherry-pick");
+    }
+  },
+
+  // Applies a unified diff or git format-patch output to a worktree, uploading
+  // it in chunks when it is large. Conflicts of a three-way apply are left in
+  // the worktree and thrown as the merge conflict message.
+  async applyPatch(
+    worktreeId: string,
+    patch: Blob | string,
+    options: PatchApplyOptions = {},
+  ): Promise<PatchApplyResult> {
+    const body = typeof patch === "string" ? new Blob([patch]) : patch;
+    const params = new URLSearchParams({
+      three_way: String(options.threeWay ?? true),
+      commit: String(options.commit ?? false),
+      dry_run: String(options.dryRun ?? false),
+    });
+    if (options.message) {
+      params.set("message", options.message);
+    }
+    const base = `/v1/git/worktrees/${worktreeId}/patch`;
+    const fail = async (response: Response, fallback: string) => {
+      const errorData = await response.json().catch(() => ({}));
+      throw new Error(
+        errorData.error === "merge_conflict"
+          ? errorData.message
+          : errorData.error || fallback,
+      );
+    };
+
+    let url = `${base}?${params}`;
+    if (body.size <= PATCH_CHUNK_SIZE) {
+      const response = await fetch(url, { method: "POST", body });
+      if (!response.ok) {
+        await fail(response, "Failed to apply patch");
+      }
+      return await response.json();
+    }
+
+    const started = await fetch(`${base}/uploads`, { method: "POST" });
+    if (!started.ok) {
+      await fail(started, "Failed to upload patch");
+    }
+    const upload: PatchUpload = await started.json();
+    for (let offset = 0; offset < body.size; offset += PATCH_CHUNK_SIZE) {
+      const response = await fetch(
+        `${base}/uploads/${upload.id}?offset=${offset}`,
+        {
+          method: "PUT",
+          body: body.slice(offset, offset + PATCH_CHUNK_SIZE),
+        },
+      );
+      if (!response.ok) {
+        await fetch(`${base}/uploads/${upload.id}`, { method: "DELETE" });
+        await fail(response, "Failed to upload patch");
+      }
+    }
+    url = `${base}/uploads/${upload.id}/apply?${params}`;
+    const response = await fetch(url, { method: "POST" });
+    if (!response.ok) {
+      await fail(response, "Failed to apply patch");
+    }
+    return await response.json();
+  },
+
+  async acknowledgeStateReport(): Promise<void> {
+    const response = await fetch("/v1/git/status/state-report/acknowledge", {
+      method: "POST",
+    });
+    // Already acknowledged elsewhere
+    if (!response.ok && response.status !== 404) {
+      const errorData = await response.json().catch(() => ({}));
+      throw new Error(errorData.error || "Failed to dismiss state report");
+    }
+  },
+
+  async getWorktreeHealth(worktreeId: string): Promise<WorktreeHealth | null> {
+    try {
+      const response = await fetch(`/v1/git/worktrees/${worktreeId}/health`);
+      if (!response.ok) {
+        return null;
+      }
+      return await response.json();
+    } catch (error) {
+      console.error("Failed to check worktree health:", error);
+      return null;
+    }
+  },
+
+  async getDiskUsage(refresh = false): Promise<DiskUsage | null> {
+    try {
+      const query = refresh ? "?refresh=true" : "";
+      const response = await fetch(`/v1/git/disk-usage${query}`);
+      if (!response.ok) {
+        return null;
+      }
+      return await response.json();
+    } catch (error) {
+      console.error("Failed to fetch disk usage:", error);
+      return null;
+    }
+  },
+
+  async runHealthAction(
+    worktreeId: string,
+    action: HealthAction,
+  ): Promise<void> {
+    const response = await fetch(healthActionEndpoints[action](worktreeId), {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: "{}",
+    });
+    if (!response.ok) {
+      const errorData = await response.json().catch(() => ({}));
+      throw new Error(errorData.error || `Failed to run ${action}`);
+    }
+  },
+
+  async setCheckpointConfig(
+    worktreeId: string,
+    config: CheckpointConfig,
+  ): Promise<Worktree> {
+    const response = await fetch(
+      `/v1/git/worktrees/${worktreeId}/checkpoint-config`,
+      {
+        method: "PUT",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify(config),
+      },
+    );
+    if (!response.ok) {
+      const errorData = await response.json().catch(() => ({}));
+      throw new Error(errorData.error || "Failed to update checkpoint settings");
+    }
+    return response.json();
+  },
+
+  async checkoutBranches(
+    org: string,
+    repo: string,
+    branches: string[],
+  ): Promise<BranchCheckoutResult[]> {
+    const response = await fetch(
+      `/v1/git/checkout/${encodeURIComponent(org)}/${encodeURIComponent(repo)}/branches?source=ui`,
+      {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify({ branches }),
+      },
+    );
+    if (!response.ok) {
+      const errorData = await response.json().catch(() => ({}));
+      throw new Error(errorData.error || "Failed to check out branches");
+    }
+    const data: { results: BranchCheckoutResult[] } = await response.json();
+    return data.results;
+  },
+
+  async runRepositoryMaintenance(
+    repoId: string,
+  ): Promise<RepositoryMaintenance> {
+    const response = await fetch(
+      `/v1/git/repositories/${encodeURIComponent(repoId)}/maintenance`,
+      { method: "POST" },
+    );
+    if (!response.ok) {
+      const errorData = await response.json().catch(() => ({}));
+      throw new Error(errorData.error || "Failed to run maintenance");
+    }
+    return response.json();
+  },
+
+  async setRepositoryMirrorMode(
+    repoId: string,
+    enabled: boolean,
+  ): Promise<LocalRepository> {
+    const response = await fetch(
+      `/v1/git/repositories/${encodeURIComponent(repoId)}/mirror-mode`,
+      {
+        method: "PUT",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify({ enabled }),
+      },
+    );
+    if (!response.ok) {
+      const errorData = await response.json().catch(() => ({}));
+      throw new Error(errorData.error || "Failed to set mirror mode");
+    }
+    return response.json();
+  },
+
+  // Commands run in every new worktree after checkout; progress is reported
+  // in the worktree's setup_status
+  async setRepositorySetupCommands(
+    repoId: string,
+    commands: string[],
+  ): Promise<LocalRepository> {
+    const response = await fetch(
+      `/v1/git/repositories/${encodeURIComponent(repoId)}/setup-commands`,
+      {
+        method: "PUT",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify({ commands }),
+      },
+    );
+    if (!response.ok) {
+      const errorData = await response.json().catch(() => ({}));
+      throw new Error(errorData.error || "Failed to set setup commands");
+    }
+    return response.json();
+  },
+
+  async listOrphanBranches(repoId: string): Promise<OrphanBranch[]> {
+    try {
+      const response = await fetch(
+        `/v1/git/repositories/${encodeURIComponent(repoId)}/orphan-branches`,
+      );
+      if (response.ok) {
+        return await response.json();
+      }
+      return [];
+    } catch (error) {
+      console.error("Failed to list orphan branches:", error);
+      return [];
+    }
+  },
+
+  async adoptOrphanBranch(repoId: string, branch: string): Promise<Worktree> {
+    const response = await fetch(
+      `/v1/git/repositories/${encodeURIComponent(repoId)}/orphan-branches/adopt?source=ui`,
+      {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify({ branch }),
+      },
+    );
+    if (!response.ok) {
+      const errorData = await response.json().catch(() => ({}));
+      throw new Error(errorData.error || "Failed to adopt branch");
+    }
+    return response.json();
+  },
+
+  async acknowledgeBranchDrift(
+    worktreeId: string,
+    errorHandler: ErrorHandler,
+  ): Promise<Worktree | null> {
+    try {
+      const response = await fetch(
+        `/v1/git/worktrees/${worktreeId}/branch-drift/acknowledge`,
+        { method: "POST" },
+      );
+      if (response.ok) {
+        const worktree: Worktree = await response.json();
+        toast.success(`Now tracking ${worktree.branch}`);
+        return worktree;
+      }
+      const errorData = await response.json();
+      errorHandler.setErrorAlert({
+        open: true,
+        title: "Acknowledge Failed",
+        description: `Failed to acknowledge branch change: ${errorData.error || "Unknown error"}`,
+      });
+      return null;
+    } catch (error) {
+      console.error("Failed to acknowledge branch drift:", error);
+      errorHandler.setErrorAlert({
+        open: true,
+        title: "Acknowledge Failed",
+        description: `Failed to acknowledge branch change: ${error}`,
+      });
+      return null;
+    }
+  },
+
+  async getOperationEstimates(
+    worktreeId: string,
+  ): Promise<OperationEstimates | null> {
+    try {
+      const response = await fetch(
+        `/v1/git/worktrees/${worktreeId}/operation-estimates`,
+      );
+      if (response.ok) {
+        return await response.json();
+      }
+      return null;
+    } catch (error) {
+      console.error("Failed to get operation estimates:", error);
+      return null;
+    }
+  },
+
   async getPullRequestInfo(
     worktreeId: string,
   ): Promise<PullRequestInfo | null> {
@@ -679,6 +3252,7 @@ export const gitApi = {
         body: JSON.stringify({
           template_id: templateId,
           project_name: projectName,
+          source: "ui",
         }),
       });
 
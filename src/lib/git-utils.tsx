@@ -110,13 +110,20 @@ export const parseGitUrl = (url: string) => {
 export const createMergeConflictPrompt = (
   operation: string,
   conflictFiles: string[],
+  lockfileCommands: Record<string, string> = {},
 ) => {
   const conflictText =
     conflictFiles.length > 0
       ? `Conflicts in: ${conflictFiles.join(", ")}`
       : "Multiple files have conflicts";
+  const lockfileText = Object.entries(lockfileCommands)
+    .map(
+      ([file, command]) =>
+        ` Instead of merging ${file} by hand, resolve its manifest and regenerate it with \`${command}\`.`,
+    )
+    .join("");
 
-  return `I have a merge conflict during a ${operation} operation. ${conflictText}. Please help me resolve these conflicts by examining the files, understanding the conflicting changes, and providing a resolution strategy.`;
+  return `I have a merge conflict during a ${operation} operation. ${conflictText}.${lockfileText} Please help me resolve these conflicts by examining the files, understanding the conflicting changes, and providing a resolution strategy.`;
 };
 
 /**
@@ -26,7 +26,11 @@ import {
   Check,
 } from "lucide-react";
 // Utility imports removed - not used in current implementation
-import { type LocalRepository, gitApi } from "@/lib/git-api";
+import {
+  type LocalRepository,
+  formatRepositorySize,
+  gitApi,
+} from "@/lib/git-api";
 import { useHighlight } from "@/hooks/useHighlight";
 import { useAppStore } from "@/stores/appStore";
 import { useGitApi } from "@/hooks/useGitApi";
@@ -656,6 +660,17 @@ function GitPage() {
                           )}
                         </div>
 
+                        {repo.size && (
+                          <p className="text-xs text-muted-foreground">
+                            {formatRepositorySize(repo.size.local_kb)} on disk
+                            {repo.size.remote_kb
+                              ? ` · ${formatRepositorySize(repo.size.remote_kb)} on GitHub`
+                              : ""}
+                            {` · ${repo.size.object_count.toLocaleString()} objects`}
+                            {repo.size.shallow ? " · shallow" : ""}
+                          </p>
+                        )}
+
                         {repo.id.startsWith("local/") ? (
                           <div className="space-y-1">
                             <p className="text-sm text-muted-foreground">
@@ -1,4 +1,5 @@
 import { create } from "zustand";
+import { toast } from "sonner";
 import { subscribeWithSelector } from "zustand/middleware";
 import type { AppEvent, SSEMessage } from "../types/events";
 import type {
@@ -354,6 +355,7 @@ export const useAppStore = create<AppState>()(
               is_dirty: event.payload.status.is_dirty,
               commit_count: event.payload.status.commit_count,
               commits_behind: event.payload.status.commits_behind,
+              stash_count: event.payload.status.stash_count,
               has_conflicts: event.payload.status.has_conflicts,
               cache_status: {
                 is_cached: event.payload.status.is_cached,
@@ -379,6 +381,14 @@ export const useAppStore = create<AppState>()(
                 is_dirty: status.is_dirty,
                 commit_count: status.commit_count,
                 commits_behind: status.commits_behind,
+                ...(status.pull_request_commit_count !== undefined && {
+                  pull_request_commit_count: status.pull_request_commit_count,
+                }),
+                ...(status.pull_request_commits_behind !== undefined && {
+                  pull_request_commits_behind:
+                    status.pull_request_commits_behind,
+                }),
+                stash_count: status.stash_count,
                 has_conflicts: status.has_conflicts,
                 ...((status as any).pull_request_state && {
                   pull_request_state: (status as any).pull_request_state,
@@ -491,6 +501,41 @@ export const useAppStore = create<AppState>()(
           break;
         }
 
+        case "worktree:branch_rename": {
+          const { worktree_id, outcome } = event.payload;
+          const updatedWorktrees = new Map(worktrees);
+          const existingWorktree = updatedWorktrees.get(worktree_id);
+          if (existingWorktree) {
+            updatedWorktrees.set(worktree_id, {
+              ...existingWorktree,
+              branch_rename: outcome,
+            });
+            set({ worktrees: updatedWorktrees });
+          }
+          if (outcome.outcome === "skipped" || outcome.outcome === "failed") {
+            toast.warning(
+              `${existingWorktree?.name ?? "Worktree"} kept its branch name`,
+              { description: outcome.message ?? outcome.reason },
+            );
+          }
+          break;
+        }
+
+        case "worktree:pull_request_created": {
+          const { worktree_id, pull_request } = event.payload;
+          const updatedWorktrees = new Map(worktrees);
+          const existingWorktree = updatedWorktrees.get(worktree_id);
+          if (existingWorktree) {
+            updatedWorktrees.set(worktree_id, {
+              ...existingWorktree,
+              pull_request_url: pull_request.url,
+              pull_request_title: pull_request.title,
+            });
+            set({ worktrees: updatedWorktrees });
+          }
+          break;
+        }
+
         case "worktree:todos_updated": {
           const updatedWorktrees = new Map(worktrees);
           const existingWorktree = updatedWorktrees.get(
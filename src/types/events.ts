@@ -1,3 +1,5 @@
+import type { StateReport } from "@/lib/git-api";
+
 export interface PortOpenedEvent {
   type: "port:opened";
   payload: {
@@ -81,6 +83,7 @@ export interface WorktreeStatusUpdatedEvent {
       is_dirty: boolean;
       commit_count: number;
       commits_behind: number;
+      stash_count?: number;
       has_conflicts: boolean;
       is_cached: boolean;
       is_loading: boolean;
@@ -98,6 +101,9 @@ export interface WorktreeBatchUpdatedEvent {
         is_dirty: boolean;
         commit_count: number;
         commits_behind: number;
+        pull_request_commit_count?: number;
+        pull_request_commits_behind?: number;
+        stash_count?: number;
         has_conflicts: boolean;
         is_cached: boolean;
         is_loading: boolean;
@@ -127,6 +133,7 @@ export interface WorktreeUpdatedEvent {
   payload: {
     worktree_id: string;
     updates: Record<string, any>;
+    actor?: string;
   };
 }
 
@@ -134,6 +141,7 @@ export interface WorktreeCreatedEvent {
   type: "worktree:created";
   payload: {
     worktree: any; // Will match the Worktree interface
+    actor?: string;
   };
 }
 
@@ -142,6 +150,7 @@ export interface WorktreeDeletedEvent {
   payload: {
     worktree_id: string;
     worktree_name: string;
+    actor?: string;
   };
 }
 
@@ -176,6 +185,193 @@ export interface SessionTitleUpdatedEvent {
   };
 }
 
+export interface RepositoryHealthWarningEvent {
+  type: "repository:health_warning";
+  payload: {
+    repo_id: string;
+    source: string;
+    message: string;
+  };
+}
+
+export interface RepositoryRenamedEvent {
+  type: "repository:renamed";
+  payload: {
+    old_id: string;
+    new_id: string;
+  };
+}
+
+export interface SystemPanicEvent {
+  type: "system:panic";
+  payload: {
+    subsystem: string;
+    goroutine: string;
+    message: string;
+    panics: number;
+  };
+}
+
+export interface SystemDiskStatusEvent {
+  type: "system:disk_status";
+  payload: {
+    disk: {
+      path: string;
+      free_bytes: number;
+      total_bytes: number;
+      warning_bytes: number;
+      state: "ok" | "low" | "unknown";
+      warning?: string;
+      checked_at: string;
+    };
+  };
+}
+
+export interface SystemStateReportEvent {
+  type: "system:state_report";
+  payload: {
+    report: StateReport;
+  };
+}
+
+export interface EventsGapEvent {
+  type: "events:gap";
+  payload: {
+    dropped: number;
+    resume_seq: number;
+  };
+}
+
+export interface WorktreeMergeEvent {
+  type:
+    | "worktree:merge_prepared"
+    | "worktree:merge_completed"
+    | "worktree:merge_expired";
+  payload: {
+    worktree_id: string;
+    actor?: string;
+    preview: {
+      token: string;
+      worktree_id: string;
+      mode: "squash" | "merge";
+      target_branch: string;
+      message: string;
+      diff_stat: string;
+      head_commit: string;
+      expires_at: string;
+    };
+  };
+}
+
+export interface WorktreeBranchDriftEvent {
+  type: "worktree:branch_drift";
+  payload: {
+    worktree_id: string;
+    drift: {
+      expected_branch: string;
+      actual_branch: string;
+      expected_commit?: string;
+      actual_commit?: string;
+      adopted?: boolean;
+      detected_at: string;
+    };
+  };
+}
+
+export interface WorktreeSourceRewrittenEvent {
+  type: "worktree:source_rewritten";
+  payload: {
+    worktree_id: string;
+    rewrite: {
+      branch: string;
+      previous_tip: string;
+      new_tip: string;
+      detected_at: string;
+    };
+  };
+}
+
+export interface WorktreeBranchRenameEvent {
+  type: "worktree:branch_rename";
+  payload: {
+    worktree_id: string;
+    actor?: string;
+    outcome: {
+      trigger?: "title" | "todo" | "manual";
+      outcome: "renamed" | "suffixed" | "skipped" | "adopted" | "failed";
+      reason?: string;
+      requested_branch?: string;
+      branch: string;
+      message?: string;
+      at: string;
+    };
+  };
+}
+
+export interface WorktreePullRequestCreatedEvent {
+  type: "worktree:pull_request_created";
+  payload: {
+    worktree_id: string;
+    actor?: string;
+    pull_request: {
+      number: number;
+      url: string;
+      title: string;
+      body: string;
+      head_branch: string;
+      base_branch: string;
+      repository: string;
+    };
+  };
+}
+
+export interface WorktreeHealthEvent {
+  type: "worktree:health";
+  payload: {
+    worktree_id: string;
+    findings: {
+      severity: "error" | "warning" | "info";
+      code: string;
+      message: string;
+      action?: string;
+    }[];
+  };
+}
+
+export interface WorktreeFromIssueEvent {
+  type: "worktree:from_issue";
+  payload: {
+    worktree_id: string;
+    worktree_name: string;
+    worktree_path: string;
+    repo_id: string;
+    issue: {
+      number: number;
+      title: string;
+      url: string;
+    };
+    // Issue title and body, the starting point for a session
+    prompt?: string;
+  };
+}
+
+export interface WorktreePullRequestStatusEvent {
+  type: "worktree:pull_request_status";
+  payload: {
+    worktree_id: string;
+    pull_request: {
+      number: number;
+      state: string;
+      repository: string;
+      url: string;
+      title: string;
+      checks_state?: string;
+      last_synced: string;
+      worktree_ids: string[] | null;
+    };
+  };
+}
+
 export interface SessionStoppedEvent {
   type: "session:stopped";
   payload: {
@@ -226,6 +422,20 @@ export type AppEvent =
   | WorktreeDeletedEvent
   | WorktreeTodosUpdatedEvent
   | SessionTitleUpdatedEvent
+  | RepositoryHealthWarningEvent
+  | RepositoryRenamedEvent
+  | SystemPanicEvent
+  | SystemDiskStatusEvent
+  | SystemStateReportEvent
+  | EventsGapEvent
+  | WorktreeBranchDriftEvent
+  | WorktreeSourceRewrittenEvent
+  | WorktreeBranchRenameEvent
+  | WorktreePullRequestCreatedEvent
+  | WorktreePullRequestStatusEvent
+  | WorktreeHealthEvent
+  | WorktreeFromIssueEvent
+  | WorktreeMergeEvent
   | SessionStoppedEvent
   | NotificationEvent
   | ClaudeMessageEvent;
@@ -234,4 +444,5 @@ export interface SSEMessage {
   event: AppEvent;
   timestamp: number;
   id: string;
+  seq?: number;
 }